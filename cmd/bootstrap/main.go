@@ -0,0 +1,94 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package provisions the application state of a new deployment without
+// Terraform: it runs the migrations, creates the revision token keys, and an
+// export signing key and config. It is safe to run again.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/exposure-notifications-server/internal/bootstrap"
+	"github.com/google/exposure-notifications-server/internal/buildinfo"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger := logging.NewLoggerFromEnv().
+		With("build_id", buildinfo.BuildID).
+		With("build_tag", buildinfo.BuildTag)
+	ctx = logging.WithLogger(ctx, logger)
+
+	defer func() {
+		done()
+		if r := recover(); r != nil {
+			logger.Fatalw("application panic", "panic", r)
+		}
+	}()
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger.Info("successful shutdown")
+}
+
+func realMain(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	// The schema is expected to be behind until the migrations have run.
+	config := bootstrap.Config{
+		Database: database.Config{SchemaCheck: database.SchemaCheckOff},
+	}
+	env, err := setup.Setup(ctx, &config)
+	if err != nil {
+		return fmt.Errorf("setup.Setup: %w", err)
+	}
+	defer env.Close(ctx)
+
+	logger.Info("running migrations")
+	if err := bootstrap.Migrate(config.Database.ConnectionURL(), config.MigrationTimeout); err != nil {
+		return fmt.Errorf("bootstrap.Migrate: %w", err)
+	}
+
+	result, err := bootstrap.Bootstrap(ctx, &config, env.Database(), env.KeyManager())
+	if err != nil {
+		return fmt.Errorf("bootstrap.Bootstrap: %w", err)
+	}
+	for _, created := range result.Created {
+		logger.Infow("created", "state", created)
+	}
+
+	// The services need these values, so they are printed rather than logged.
+	fmt.Printf("REVISION_TOKEN_KEY_ID=%s\n", result.RevisionTokenKeyID)
+	if len(result.RevisionTokenAAD) > 0 {
+		fmt.Printf("REVISION_TOKEN_AAD=%s\n", base64.StdEncoding.EncodeToString(result.RevisionTokenAAD))
+	}
+	if result.ExportConfigID != 0 {
+		fmt.Printf("# export config %d, signature info %d\n", result.ExportConfigID, result.SignatureInfoID)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package runs the entire key server locally with a throwaway database,
+// seeded with development data, and serves generated export files over HTTP.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/google/exposure-notifications-server/internal/buildinfo"
+	"github.com/google/exposure-notifications-server/internal/devserver"
+	"github.com/google/exposure-notifications-server/internal/monolith"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/server"
+	"github.com/sethvargo/go-envconfig"
+	"golang.org/x/sync/errgroup"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger := logging.NewLoggerFromEnv().
+		With("build_id", buildinfo.BuildID).
+		With("build_tag", buildinfo.BuildTag)
+	ctx = logging.WithLogger(ctx, logger)
+
+	defer func() {
+		done()
+		if r := recover(); r != nil {
+			logger.Fatalw("application panic", "panic", r)
+		}
+	}()
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Info("successful shutdown")
+}
+
+func realMain(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	keysDir, err := filepath.Abs(filepath.Join("local", "keys"))
+	if err != nil {
+		return fmt.Errorf("failed to determine keys directory: %w", err)
+	}
+	lookuper := devserver.DefaultLookuper(keysDir)
+
+	// Process the configuration once to determine whether a database needs to be
+	// started. It is processed again during setup.
+	var config devserver.Config
+	if err := envconfig.ProcessWith(ctx, &config, lookuper); err != nil {
+		return fmt.Errorf("failed to process config: %w", err)
+	}
+
+	if config.StartDatabase {
+		logger.Infow("starting database", "image", config.PostgresImage)
+		db, err := devserver.StartDatabase(ctx, config.PostgresImage)
+		if err != nil {
+			return fmt.Errorf("devserver.StartDatabase: %w", err)
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Errorw("failed to stop database", "error", err)
+			}
+		}()
+		lookuper = envconfig.MultiLookuper(db.Lookuper(), lookuper)
+	}
+
+	config = devserver.Config{}
+	env, err := setup.SetupWith(ctx, &config, lookuper)
+	if err != nil {
+		return fmt.Errorf("setup.Setup: %w", err)
+	}
+	defer env.Close(ctx)
+
+	logger.Infow("running migrations", "dir", config.MigrationsDir)
+	if err := devserver.Migrate(config.MigrationsDir, config.Database.ConnectionURL()); err != nil {
+		return fmt.Errorf("devserver.Migrate: %w", err)
+	}
+
+	if config.Seed {
+		logger.Info("seeding database")
+		if err := devserver.Bootstrap(ctx, &config, env); err != nil {
+			return fmt.Errorf("devserver.Bootstrap: %w", err)
+		}
+	}
+
+	monolithServer, err := monolith.NewServer(ctx, &config.Config, env)
+	if err != nil {
+		return fmt.Errorf("monolith.NewServer: %w", err)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	srv, err := server.New(config.Port)
+	if err != nil {
+		return fmt.Errorf("server.New: %w", err)
+	}
+	logger.Infof("listening on :%s", config.Port)
+	eg.Go(func() error {
+		return srv.ServeHTTPHandler(ctx, monolithServer.Routes(ctx))
+	})
+
+	if handler := monolithServer.AdminRoutes(ctx); handler != nil {
+		adminSrv, err := server.New(config.AdminPort)
+		if err != nil {
+			return fmt.Errorf("server.New: %w", err)
+		}
+		logger.Infof("admin console listening on :%s", config.AdminPort)
+		eg.Go(func() error {
+			return adminSrv.ServeHTTPHandler(ctx, handler)
+		})
+	}
+
+	exportSrv, err := server.New(config.ExportPort)
+	if err != nil {
+		return fmt.Errorf("server.New: %w", err)
+	}
+	logger.Infof("serving exports from %s on :%s", config.ExportRoot, config.ExportPort)
+	eg.Go(func() error {
+		return exportSrv.ServeHTTPHandler(ctx, http.FileServer(http.Dir(config.ExportRoot)))
+	})
+
+	return eg.Wait()
+}
@@ -21,14 +21,8 @@ import (
 	"os/signal"
 	"syscall"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-
-	"go.opencensus.io/plugin/ocgrpc"
-
 	"github.com/google/exposure-notifications-server/internal/buildinfo"
 	"github.com/google/exposure-notifications-server/internal/federationout"
-	"github.com/google/exposure-notifications-server/internal/pb/federation"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	_ "github.com/google/exposure-notifications-server/pkg/observability"
@@ -69,25 +63,11 @@ func realMain(ctx context.Context) error {
 	}
 	defer env.Close(ctx)
 
-	federationServer := federationout.NewServer(env, &config)
-
-	var sopts []grpc.ServerOption
-	if config.TLSCertFile != "" && config.TLSKeyFile != "" {
-		creds, err := credentials.NewServerTLSFromFile(config.TLSCertFile, config.TLSKeyFile)
-		if err != nil {
-			return fmt.Errorf("failed to create credentials: %w", err)
-		}
-		sopts = append(sopts, grpc.Creds(creds))
-	}
-
-	if !config.AllowAnyClient {
-		sopts = append(sopts, grpc.UnaryInterceptor(federationServer.(*federationout.Server).AuthInterceptor))
+	grpcServer, err := federationout.NewGRPCServer(env, &config)
+	if err != nil {
+		return fmt.Errorf("federationout.NewGRPCServer: %w", err)
 	}
 
-	sopts = append(sopts, grpc.StatsHandler(&ocgrpc.ServerHandler{}))
-	grpcServer := grpc.NewServer(sopts...)
-	federation.RegisterFederationServer(grpcServer, federationServer)
-
 	srv, err := server.New(config.Port)
 	if err != nil {
 		return fmt.Errorf("server.New: %w", err)
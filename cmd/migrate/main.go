@@ -66,7 +66,8 @@ func main() {
 func realMain(ctx context.Context) error {
 	logger := logging.FromContext(ctx)
 
-	var config database.Config
+	// The schema is expected to be behind until the migrations have run.
+	config := database.Config{SchemaCheck: database.SchemaCheckOff}
 	env, err := setup.Setup(ctx, &config)
 	if err != nil {
 		return fmt.Errorf("failed to setup database: %w", err)
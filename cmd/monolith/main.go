@@ -0,0 +1,111 @@
+// Copyright 2020 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package runs all of the key server services as a single binary.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/exposure-notifications-server/internal/buildinfo"
+	"github.com/google/exposure-notifications-server/internal/monolith"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	_ "github.com/google/exposure-notifications-server/pkg/observability"
+	"github.com/google/exposure-notifications-server/pkg/server"
+	"golang.org/x/sync/errgroup"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger := logging.NewLoggerFromEnv().
+		With("build_id", buildinfo.BuildID).
+		With("build_tag", buildinfo.BuildTag)
+	ctx = logging.WithLogger(ctx, logger)
+
+	defer func() {
+		done()
+		if r := recover(); r != nil {
+			logger.Fatalw("application panic", "panic", r)
+		}
+	}()
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Info("successful shutdown")
+}
+
+func realMain(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	var config monolith.Config
+	env, err := setup.Setup(ctx, &config)
+	if err != nil {
+		return fmt.Errorf("setup.Setup: %w", err)
+	}
+	defer env.Close(ctx)
+
+	monolithServer, err := monolith.NewServer(ctx, &config, env)
+	if err != nil {
+		return fmt.Errorf("monolith.NewServer: %w", err)
+	}
+
+	// If any server fails, stop all of them.
+	eg, ctx := errgroup.WithContext(ctx)
+
+	srv, err := server.New(config.Port)
+	if err != nil {
+		return fmt.Errorf("server.New: %w", err)
+	}
+	logger.Infof("listening on :%s", config.Port)
+	eg.Go(func() error {
+		return srv.ServeHTTPHandler(ctx, monolithServer.Routes(ctx))
+	})
+
+	if handler := monolithServer.AdminRoutes(ctx); handler != nil {
+		adminSrv, err := server.New(config.AdminPort)
+		if err != nil {
+			return fmt.Errorf("server.New: %w", err)
+		}
+		logger.Infof("admin console listening on :%s", config.AdminPort)
+		eg.Go(func() error {
+			return adminSrv.ServeHTTPHandler(ctx, handler)
+		})
+	}
+
+	grpcServer, err := monolithServer.FederationServer()
+	if err != nil {
+		return fmt.Errorf("monolith.FederationServer: %w", err)
+	}
+	if grpcServer != nil {
+		federationSrv, err := server.New(config.FederationPort)
+		if err != nil {
+			return fmt.Errorf("server.New: %w", err)
+		}
+		logger.Infof("federation listening on :%s", config.FederationPort)
+		eg.Go(func() error {
+			return federationSrv.ServeGRPC(ctx, grpcServer)
+		})
+	}
+
+	return eg.Wait()
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abuse
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/abuse/model"
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"github.com/google/exposure-notifications-server/pkg/secrets"
+)
+
+// Compile-time check to assert this config matches requirements.
+var (
+	_ setup.DatabaseConfigProvider              = (*Config)(nil)
+	_ setup.SecretManagerConfigProvider         = (*Config)(nil)
+	_ setup.ObservabilityExporterConfigProvider = (*Config)(nil)
+)
+
+// Config represents the configuration and associated environment variables for
+// the abuse detector.
+type Config struct {
+	Database              database.Config
+	SecretManager         secrets.Config
+	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
+
+	Port string `env:"PORT, default=8080"`
+
+	// Window is the recent period that is checked for anomalies, and Baseline
+	// the period before it that the window is compared to.
+	Window   time.Duration `env:"WINDOW, default=1h"`
+	Baseline time.Duration `env:"BASELINE, default=168h"`
+
+	// MinRequests is the number of requests needed in both the window and the
+	// baseline before their distributions are compared.
+	MinRequests int64 `env:"MIN_REQUESTS, default=20"`
+
+	// The thresholds of the individual signals. A threshold of 0 disables the
+	// signal.
+	VolumeFactor           float64 `env:"VOLUME_FACTOR, default=5"`
+	KeysPerRequestDistance float64 `env:"KEYS_PER_REQUEST_DISTANCE, default=0.5"`
+	FingerprintShare       float64 `env:"FINGERPRINT_SHARE, default=0.5"`
+	SkewedShare            float64 `env:"SKEWED_SHARE, default=0.25"`
+	RegionMismatches       int64   `env:"REGION_MISMATCHES, default=10"`
+
+	// AutoThrottle rejects publish requests for an app for ThrottleDuration
+	// when a new finding is reported for it. By default findings are only
+	// reported.
+	AutoThrottle     bool          `env:"AUTO_THROTTLE, default=false"`
+	ThrottleDuration time.Duration `env:"THROTTLE_DURATION, default=1h"`
+}
+
+func (c *Config) DatabaseConfig() *database.Config {
+	return &c.Database
+}
+
+func (c *Config) SecretManagerConfig() *secrets.Config {
+	return &c.SecretManager
+}
+
+func (c *Config) ObservabilityExporterConfig() *observability.Config {
+	return &c.ObservabilityExporter
+}
+
+// Validate checks the detector configuration.
+func (c *Config) Validate() error {
+	if c.Window < time.Hour || c.Window%time.Hour != 0 {
+		return fmt.Errorf("WINDOW must be a positive number of hours, got %v", c.Window)
+	}
+	if c.Baseline < c.Window || c.Baseline%time.Hour != 0 {
+		return fmt.Errorf("BASELINE must be a number of hours at least as long as WINDOW, got %v", c.Baseline)
+	}
+	if c.AutoThrottle && c.ThrottleDuration <= 0 {
+		return fmt.Errorf("THROTTLE_DURATION must be positive, got %v", c.ThrottleDuration)
+	}
+	return nil
+}
+
+// Thresholds returns the configured signal thresholds.
+func (c *Config) Thresholds() *model.Thresholds {
+	return &model.Thresholds{
+		MinRequests:            c.MinRequests,
+		VolumeFactor:           c.VolumeFactor,
+		KeysPerRequestDistance: c.KeysPerRequestDistance,
+		FingerprintShare:       c.FingerprintShare,
+		SkewedShare:            c.SkewedShare,
+		RegionMismatches:       c.RegionMismatches,
+	}
+}
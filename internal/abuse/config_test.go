@@ -0,0 +1,63 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abuse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		config *Config
+		err    bool
+	}{
+		{
+			name:   "default",
+			config: &Config{Window: time.Hour, Baseline: 168 * time.Hour},
+		},
+		{
+			name:   "partial_hour_window",
+			config: &Config{Window: 90 * time.Minute, Baseline: 168 * time.Hour},
+			err:    true,
+		},
+		{
+			name:   "short_baseline",
+			config: &Config{Window: 2 * time.Hour, Baseline: time.Hour},
+			err:    true,
+		},
+		{
+			name:   "auto_throttle_without_duration",
+			config: &Config{Window: time.Hour, Baseline: 168 * time.Hour, AutoThrottle: true},
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.config.Validate()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
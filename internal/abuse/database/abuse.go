@@ -0,0 +1,326 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface for publish abuse detection.
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/abuse/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type AbuseDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *AbuseDB {
+	return &AbuseDB{
+		db: db,
+	}
+}
+
+// RecordPublish adds the observation to the stats for its app and hour.
+func (db *AbuseDB) RecordPublish(ctx context.Context, o *model.Observation) error {
+	if o.AppPackageName == "" {
+		return fmt.Errorf("missing app package name")
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		stats := model.InitHour(o.AppPackageName, o.Time)
+
+		row := tx.QueryRow(ctx, `
+			SELECT
+				app_package_name, hour, requests, keys, keys_per_request, rejected_keys, skewed_requests, region_mismatches
+			FROM
+				PublishAbuseStats
+			WHERE
+				app_package_name = $1 AND hour = $2
+			FOR UPDATE
+		`, stats.AppPackageName, stats.Hour)
+		if err := scanOneHourlyStats(row, stats); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to read abuse stats: %w", err)
+		}
+
+		stats.Add(o)
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO
+				PublishAbuseStats
+				(app_package_name, hour, requests, keys, keys_per_request, rejected_keys, skewed_requests, region_mismatches)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (tenant, app_package_name, hour) DO
+				UPDATE
+				SET requests = $3, keys = $4, keys_per_request = $5, rejected_keys = $6, skewed_requests = $7, region_mismatches = $8
+		`, stats.AppPackageName, stats.Hour, stats.Requests, stats.Keys, stats.KeysPerRequest,
+			stats.RejectedKeys, stats.SkewedRequests, stats.RegionMismatches); err != nil {
+			return fmt.Errorf("failed to update abuse stats: %w", err)
+		}
+
+		if o.Fingerprint == "" {
+			return nil
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO
+				PublishFingerprint (app_package_name, hour, fingerprint, requests)
+			VALUES
+				($1, $2, $3, 1)
+			ON CONFLICT (tenant, app_package_name, hour, fingerprint) DO
+				UPDATE
+				SET requests = PublishFingerprint.requests + 1
+		`, stats.AppPackageName, stats.Hour, o.Fingerprint); err != nil {
+			return fmt.Errorf("failed to update publish fingerprint: %w", err)
+		}
+		return nil
+	})
+}
+
+func scanOneHourlyStats(row pgx.Row, stats *model.HourlyStats) error {
+	return row.Scan(&stats.AppPackageName, &stats.Hour, &stats.Requests, &stats.Keys,
+		&stats.KeysPerRequest, &stats.RejectedKeys, &stats.SkewedRequests, &stats.RegionMismatches)
+}
+
+// ListActiveApps returns the apps with publish requests at or after since.
+func (db *AbuseDB) ListActiveApps(ctx context.Context, since time.Time) ([]string, error) {
+	var apps []string
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT DISTINCT
+				app_package_name
+			FROM
+				PublishAbuseStats
+			WHERE
+				hour >= $1
+			ORDER BY app_package_name
+		`, since.UTC().Truncate(time.Hour))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var app string
+			if err := rows.Scan(&app); err != nil {
+				return err
+			}
+			apps = append(apps, app)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list active apps: %w", err)
+	}
+
+	return apps, nil
+}
+
+// ReadStats returns the stats of the app for the hours in [from, to), ordered
+// by hour.
+func (db *AbuseDB) ReadStats(ctx context.Context, appPackageName string, from, to time.Time) ([]*model.HourlyStats, error) {
+	var results []*model.HourlyStats
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				app_package_name, hour, requests, keys, keys_per_request, rejected_keys, skewed_requests, region_mismatches
+			FROM
+				PublishAbuseStats
+			WHERE
+				app_package_name = $1 AND hour >= $2 AND hour < $3
+			ORDER BY hour ASC
+		`, appPackageName, from.UTC().Truncate(time.Hour), to.UTC().Truncate(time.Hour))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// The time the hour is initialized to doesn't matter, the scan will
+			// override it.
+			stats := model.InitHour(appPackageName, from)
+			if err := scanOneHourlyStats(rows, stats); err != nil {
+				return err
+			}
+			results = append(results, stats)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read abuse stats: %w", err)
+	}
+
+	return results, nil
+}
+
+// TopFingerprint returns the most common fingerprint of the app's requests in
+// the hours in [from, to), and the number of requests with it.
+func (db *AbuseDB) TopFingerprint(ctx context.Context, appPackageName string, from, to time.Time) (string, int64, error) {
+	var fingerprint string
+	var requests int64
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				fingerprint, SUM(requests)
+			FROM
+				PublishFingerprint
+			WHERE
+				app_package_name = $1 AND hour >= $2 AND hour < $3
+			GROUP BY fingerprint
+			ORDER BY 2 DESC, 1
+			LIMIT 1
+		`, appPackageName, from.UTC().Truncate(time.Hour), to.UTC().Truncate(time.Hour))
+		if err := row.Scan(&fingerprint, &requests); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return "", 0, fmt.Errorf("failed to read publish fingerprints: %w", err)
+	}
+
+	return fingerprint, requests, nil
+}
+
+// InsertFinding records the finding and sets its ID. It returns false, and
+// records nothing, if the app already has an unresolved finding for the same
+// signal.
+func (db *AbuseDB) InsertFinding(ctx context.Context, f *model.Finding) (bool, error) {
+	inserted := false
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				AbuseFinding (app_package_name, detected_at, signal, observed, baseline, detail)
+			SELECT
+				$1, $2, $3, $4, $5, $6
+			WHERE NOT EXISTS (
+				SELECT 1 FROM AbuseFinding WHERE app_package_name = $1 AND signal = $3 AND NOT resolved
+			)
+			RETURNING id
+		`, f.AppPackageName, f.DetectedAt, string(f.Signal), f.Observed, f.Baseline, f.Detail)
+		if err := row.Scan(&f.ID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		inserted = true
+		return nil
+	}); err != nil {
+		return false, fmt.Errorf("failed to insert abuse finding: %w", err)
+	}
+
+	return inserted, nil
+}
+
+// ListUnresolvedFindings returns the unresolved findings of the app, or of all
+// apps if appPackageName is empty, newest first.
+func (db *AbuseDB) ListUnresolvedFindings(ctx context.Context, appPackageName string) ([]*model.Finding, error) {
+	var findings []*model.Finding
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				id, app_package_name, detected_at, signal, observed, baseline, detail, resolved
+			FROM
+				AbuseFinding
+			WHERE
+				NOT resolved AND ($1 = '' OR app_package_name = $1)
+			ORDER BY detected_at DESC, id DESC
+		`, appPackageName)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var f model.Finding
+			var signal string
+			if err := rows.Scan(&f.ID, &f.AppPackageName, &f.DetectedAt, &signal,
+				&f.Observed, &f.Baseline, &f.Detail, &f.Resolved); err != nil {
+				return err
+			}
+			f.Signal = model.Signal(signal)
+			findings = append(findings, &f)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list abuse findings: %w", err)
+	}
+
+	return findings, nil
+}
+
+// ThrottleApp rejects publish requests for the app until the given time. An
+// existing, later throttle is kept.
+func (db *AbuseDB) ThrottleApp(ctx context.Context, appPackageName string, until time.Time) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			UPDATE
+				AuthorizedApp
+			SET
+				throttled_until = GREATEST(COALESCE(throttled_until, $2), $2)
+			WHERE
+				LOWER(app_package_name) = LOWER($1)
+		`, appPackageName, until); err != nil {
+			return fmt.Errorf("failed to throttle app: %w", err)
+		}
+		return nil
+	})
+}
+
+// Resolve marks the app's findings as resolved and lifts any throttle.
+func (db *AbuseDB) Resolve(ctx context.Context, appPackageName string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			UPDATE AbuseFinding SET resolved = true WHERE app_package_name = $1 AND NOT resolved
+		`, appPackageName); err != nil {
+			return fmt.Errorf("failed to resolve abuse findings: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE AuthorizedApp SET throttled_until = NULL WHERE LOWER(app_package_name) = LOWER($1)
+		`, appPackageName); err != nil {
+			return fmt.Errorf("failed to lift throttle: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteStatsBefore deletes the stats and fingerprints of hours before the
+// given time. Returns the number of stats records deleted.
+func (db *AbuseDB) DeleteStatsBefore(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM PublishAbuseStats WHERE hour < $1`, before)
+		if err != nil {
+			return fmt.Errorf("deleting abuse stats: %w", err)
+		}
+		count = result.RowsAffected()
+
+		if _, err := tx.Exec(ctx, `DELETE FROM PublishFingerprint WHERE hour < $1`, before); err != nil {
+			return fmt.Errorf("deleting publish fingerprints: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
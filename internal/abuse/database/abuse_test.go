@@ -0,0 +1,158 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/abuse/model"
+	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestRecordPublish(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	abuseDB := New(testDB)
+
+	now := time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+	observations := []*model.Observation{
+		{AppPackageName: "app", Time: now, Keys: 14, Fingerprint: "a"},
+		{AppPackageName: "app", Time: now, Keys: 14, Fingerprint: "a"},
+		{AppPackageName: "app", Time: now, Keys: 3, RejectedKeys: 1, Skewed: true, Fingerprint: "b"},
+		{AppPackageName: "app", Time: now, Keys: 2, RegionMismatch: true},
+		{AppPackageName: "other", Time: now.Add(-2 * time.Hour), Keys: 1, Fingerprint: "c"},
+	}
+	for _, o := range observations {
+		if err := abuseDB.RecordPublish(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	apps, err := abuseDB.ListActiveApps(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 1 || apps[0] != "app" {
+		t.Errorf("expected only app to be active, got %v", apps)
+	}
+
+	stats, err := abuseDB.ReadStats(ctx, "app", now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 hour of stats, got %d", len(stats))
+	}
+	got := stats[0]
+	if got.Requests != 4 || got.Keys != 33 || got.RejectedKeys != 1 || got.SkewedRequests != 1 || got.RegionMismatches != 1 {
+		t.Errorf("unexpected stats: %#v", got)
+	}
+	if got.KeysPerRequest[14] != 2 || got.KeysPerRequest[3] != 1 {
+		t.Errorf("unexpected keys per request: %v", got.KeysPerRequest)
+	}
+
+	fingerprint, requests, err := abuseDB.TopFingerprint(ctx, "app", now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint != "a" || requests != 2 {
+		t.Errorf("expected fingerprint a with 2 requests, got %q with %d", fingerprint, requests)
+	}
+
+	deleted, err := abuseDB.DeleteStatsBefore(ctx, now.Truncate(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 record deleted, got %d", deleted)
+	}
+}
+
+func TestFindings(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	abuseDB := New(testDB)
+
+	app := aamodel.NewAuthorizedApp()
+	app.AppPackageName = "app"
+	app.AllowedRegions["US"] = struct{}{}
+	app.BypassHealthAuthorityVerification = true
+	if err := aadb.New(testDB).InsertAuthorizedApp(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	finding := &model.Finding{
+		AppPackageName: "app",
+		DetectedAt:     now,
+		Signal:         model.SignalVolume,
+		Observed:       100,
+		Baseline:       10,
+		Detail:         "detail",
+	}
+	if ok, err := abuseDB.InsertFinding(ctx, finding); err != nil || !ok {
+		t.Fatalf("expected finding to be inserted, got %t, %v", ok, err)
+	}
+
+	// The same signal is not reported twice while unresolved.
+	dup := *finding
+	if ok, err := abuseDB.InsertFinding(ctx, &dup); err != nil || ok {
+		t.Fatalf("expected duplicate finding to be skipped, got %t, %v", ok, err)
+	}
+
+	findings, err := abuseDB.ListUnresolvedFindings(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].ID != finding.ID || findings[0].Signal != model.SignalVolume {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+
+	if err := abuseDB.ThrottleApp(ctx, "app", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := aadb.New(testDB).GetAuthorizedApp(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.IsThrottled(now) {
+		t.Errorf("expected app to be throttled until %v, got %v", now.Add(time.Hour), got.ThrottledUntil)
+	}
+
+	if err := abuseDB.Resolve(ctx, "app"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = aadb.New(testDB).GetAuthorizedApp(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IsThrottled(now) {
+		t.Errorf("expected throttle to be lifted, got %v", got.ThrottledUntil)
+	}
+	findings, err = abuseDB.ListUnresolvedFindings(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected findings to be resolved, got %v", findings)
+	}
+}
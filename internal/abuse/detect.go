@@ -0,0 +1,174 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abuse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/abuse/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/hashicorp/go-multierror"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// Global lock id for abuse detection.
+const lockID = "abuse-detector-lock"
+
+func (s *Server) handleDetect() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		logger := logging.FromContext(ctx).Named("handleDetect").
+			With("lock", lockID)
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		unlock, err := s.db.Lock(ctx, lockID, 5*time.Minute)
+		if err != nil {
+			if errors.Is(err, database.ErrAlreadyLocked) {
+				logger.Debugw("skipping (already locked)")
+				s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("too early"))
+				return
+			}
+			logger.Errorw("failed to obtain lock", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				logger.Errorw("failed to unlock", "error", err)
+			}
+		}()
+
+		if err := s.detect(ctx, time.Now().UTC()); err != nil {
+			logger.Errorw("failed to detect abuse", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		stats.Record(ctx, mSuccess.M(1))
+		s.h.RenderJSON(w, http.StatusOK, nil)
+	})
+}
+
+// periods returns the window and baseline periods, in whole hours, for a run
+// at the given time. The current hour is part of the window.
+func (s *Server) periods(now time.Time) (windowStart, baselineStart, end time.Time) {
+	end = now.UTC().Truncate(time.Hour).Add(time.Hour)
+	windowStart = end.Add(-s.config.Window)
+	baselineStart = windowStart.Add(-s.config.Baseline)
+	return windowStart, baselineStart, end
+}
+
+// detect checks each app with recent publish requests and records any new
+// findings. Stats older than the baseline are deleted afterwards.
+func (s *Server) detect(ctx context.Context, now time.Time) error {
+	logger := logging.FromContext(ctx).Named("detect")
+
+	windowStart, baselineStart, _ := s.periods(now)
+	apps, err := s.abuseDB.ListActiveApps(ctx, windowStart)
+	if err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	for _, app := range apps {
+		if err := s.detectApp(ctx, app, now); err != nil {
+			result = multierror.Append(result, fmt.Errorf("app %v: %w", app, err))
+		}
+	}
+
+	count, err := s.abuseDB.DeleteStatsBefore(ctx, baselineStart)
+	if err != nil {
+		result = multierror.Append(result, fmt.Errorf("failed to delete old stats: %w", err))
+	} else {
+		logger.Debugw("deleted old abuse stats", "count", count)
+	}
+
+	return result.ErrorOrNil()
+}
+
+// detectApp compares the window of the app to its baseline.
+func (s *Server) detectApp(ctx context.Context, app string, now time.Time) error {
+	logger := logging.FromContext(ctx).Named("detectApp").
+		With("app_package_name", app)
+
+	windowStart, baselineStart, end := s.periods(now)
+
+	window, err := s.summarize(ctx, app, windowStart, end)
+	if err != nil {
+		return err
+	}
+	baseline, err := s.summarize(ctx, app, baselineStart, windowStart)
+	if err != nil {
+		return err
+	}
+
+	newFindings := 0
+	for _, f := range model.Detect(window, baseline, s.config.Thresholds()) {
+		f.AppPackageName = app
+		f.DetectedAt = now
+
+		inserted, err := s.abuseDB.InsertFinding(ctx, f)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			continue
+		}
+		newFindings++
+
+		logger.Warnw("possible publish abuse",
+			"signal", f.Signal,
+			"observed", f.Observed,
+			"baseline", f.Baseline,
+			"detail", f.Detail)
+		if err := stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(signalTag, string(f.Signal))}, mFindings.M(1)); err != nil {
+			logger.Errorw("failed to record stats", "error", err)
+		}
+	}
+
+	if newFindings > 0 && s.config.AutoThrottle {
+		until := now.Add(s.config.ThrottleDuration)
+		if err := s.abuseDB.ThrottleApp(ctx, app, until); err != nil {
+			return err
+		}
+		logger.Warnw("throttled publish requests", "until", until)
+		stats.Record(ctx, mThrottled.M(1))
+	}
+
+	return nil
+}
+
+// summarize aggregates the stats of the app for the hours in [from, to).
+func (s *Server) summarize(ctx context.Context, app string, from, to time.Time) (*model.Summary, error) {
+	hours, err := s.abuseDB.ReadStats(ctx, app, from, to)
+	if err != nil {
+		return nil, err
+	}
+	summary := model.Summarize(hours, int(to.Sub(from)/time.Hour))
+
+	summary.TopFingerprint, summary.TopFingerprintRequests, err = s.abuseDB.TopFingerprint(ctx, app, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abuse
+
+import (
+	"testing"
+	"time"
+
+	abusedb "github.com/google/exposure-notifications-server/internal/abuse/database"
+	"github.com/google/exposure-notifications-server/internal/abuse/model"
+	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	app := aamodel.NewAuthorizedApp()
+	app.AppPackageName = "app"
+	app.AllowedRegions["US"] = struct{}{}
+	app.BypassHealthAuthorityVerification = true
+	if err := aadb.New(testDB).InsertAuthorizedApp(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	abuseDB := abusedb.New(testDB)
+
+	// A day of normal uploads, then a burst of the same upload.
+	for h := 1; h <= 24; h++ {
+		for i := 0; i < 2; i++ {
+			if err := abuseDB.RecordPublish(ctx, &model.Observation{
+				AppPackageName: "app",
+				Time:           now.Add(-time.Duration(h) * time.Hour),
+				Keys:           14,
+				Fingerprint:    "normal",
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for i := 0; i < 50; i++ {
+		if err := abuseDB.RecordPublish(ctx, &model.Observation{
+			AppPackageName: "app",
+			Time:           now,
+			Keys:           1,
+			Fingerprint:    "replayed",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := serverenv.New(ctx, serverenv.WithDatabase(testDB))
+	server, err := NewServer(&Config{
+		Window:                 time.Hour,
+		Baseline:               168 * time.Hour,
+		MinRequests:            20,
+		VolumeFactor:           5,
+		KeysPerRequestDistance: 0.5,
+		FingerprintShare:       0.5,
+		AutoThrottle:           true,
+		ThrottleDuration:       time.Hour,
+	}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.detect(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := abuseDB.ListUnresolvedFindings(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[model.Signal]bool)
+	for _, f := range findings {
+		got[f.Signal] = true
+	}
+	for _, want := range []model.Signal{model.SignalVolume, model.SignalKeysPerRequest, model.SignalRepeatedPattern} {
+		if !got[want] {
+			t.Errorf("expected a %v finding, got %v", want, findings)
+		}
+	}
+
+	authorizedApp, err := aadb.New(testDB).GetAuthorizedApp(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !authorizedApp.IsThrottled(now) {
+		t.Errorf("expected app to be throttled")
+	}
+
+	// A second run does not report the same findings again.
+	if err := server.detect(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	again, err := abuseDB.ListUnresolvedFindings(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(findings) {
+		t.Errorf("expected %d findings, got %d", len(findings), len(again))
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction for publish abuse detection.
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+// MaxKeysBucket is the largest keys-per-request bucket. Requests with more
+// keys are counted in this bucket.
+const MaxKeysBucket = 30
+
+// Signal names a kind of anomaly in the publish metadata of an authorized app.
+type Signal string
+
+const (
+	// SignalVolume is an unusual number of publish requests.
+	SignalVolume Signal = "volume"
+	// SignalKeysPerRequest is an unusual distribution of keys per request.
+	SignalKeysPerRequest Signal = "keys_per_request"
+	// SignalRepeatedPattern is many requests with identical key intervals and
+	// symptom onset, as produced by a script replaying the same upload.
+	SignalRepeatedPattern Signal = "repeated_pattern"
+	// SignalClockSkew is many requests with keys that start in the future.
+	SignalClockSkew Signal = "clock_skew"
+	// SignalRegionMismatch is requests for regions the app may not publish to.
+	SignalRegionMismatch Signal = "region_mismatch"
+)
+
+// Observation is the metadata of a single publish request.
+type Observation struct {
+	AppPackageName string
+	Time           time.Time
+
+	// Keys is the number of keys in the request, RejectedKeys the number that
+	// failed validation.
+	Keys         int
+	RejectedKeys int
+
+	// Skewed is true if any key starts in the future.
+	Skewed bool
+
+	// RegionMismatch is true if the request was rejected because it named a
+	// region the app is not allowed to publish to.
+	RegionMismatch bool
+
+	// Fingerprint identifies the shape of the request, see Fingerprint. It is
+	// empty if the request was rejected before the keys were read.
+	Fingerprint string
+}
+
+// NewObservation builds the observation for a publish request received at
+// the given time.
+func NewObservation(appPackageName string, data *verifyapi.Publish, now time.Time) *Observation {
+	current := publishmodel.IntervalNumber(now)
+	skewed := false
+	for _, k := range data.Keys {
+		if k.IntervalNumber > current {
+			skewed = true
+			break
+		}
+	}
+
+	return &Observation{
+		AppPackageName: appPackageName,
+		Time:           now,
+		Keys:           len(data.Keys),
+		Skewed:         skewed,
+		Fingerprint:    Fingerprint(data),
+	}
+}
+
+// Fingerprint returns a digest of the key intervals, transmission risks and
+// symptom onset of the request, relative to its newest key. Uploads from
+// different people rarely share a fingerprint beyond the common shapes, so a
+// fingerprint that dominates an app's traffic suggests the same upload is
+// being replayed with fresh keys.
+func Fingerprint(data *verifyapi.Publish) string {
+	if len(data.Keys) == 0 {
+		return ""
+	}
+
+	var newest int32
+	for _, k := range data.Keys {
+		if k.IntervalNumber > newest {
+			newest = k.IntervalNumber
+		}
+	}
+
+	parts := make([]string, 0, len(data.Keys)+1)
+	for _, k := range data.Keys {
+		parts = append(parts, fmt.Sprintf("%d/%d/%d", newest-k.IntervalNumber, k.IntervalCount, k.TransmissionRisk))
+	}
+	sort.Strings(parts)
+
+	onset := "-"
+	if data.SymptomOnsetInterval > 0 {
+		onset = strconv.FormatInt(int64(newest-data.SymptomOnsetInterval), 10)
+	}
+	parts = append(parts, "onset="+onset)
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, ",")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// HourlyStats is the publish metadata of an authorized app for an hour.
+type HourlyStats struct {
+	AppPackageName   string
+	Hour             time.Time
+	Requests         int64
+	Keys             int64
+	KeysPerRequest   []int64
+	RejectedKeys     int64
+	SkewedRequests   int64
+	RegionMismatches int64
+}
+
+// InitHour creates an empty HourlyStats for the hour containing t.
+func InitHour(appPackageName string, t time.Time) *HourlyStats {
+	return &HourlyStats{
+		AppPackageName: appPackageName,
+		Hour:           t.UTC().Truncate(time.Hour),
+		KeysPerRequest: make([]int64, MaxKeysBucket+1),
+	}
+}
+
+// Add adds the observation to the hour.
+func (h *HourlyStats) Add(o *Observation) {
+	if len(h.KeysPerRequest) < MaxKeysBucket+1 {
+		grown := make([]int64, MaxKeysBucket+1)
+		copy(grown, h.KeysPerRequest)
+		h.KeysPerRequest = grown
+	}
+
+	h.Requests++
+	h.Keys += int64(o.Keys)
+	h.RejectedKeys += int64(o.RejectedKeys)
+	if o.Skewed {
+		h.SkewedRequests++
+	}
+	if o.RegionMismatch {
+		h.RegionMismatches++
+		// The keys of these requests are never read, so they are not part of
+		// the distribution.
+		return
+	}
+
+	bucket := o.Keys
+	if bucket > MaxKeysBucket {
+		bucket = MaxKeysBucket
+	}
+	h.KeysPerRequest[bucket]++
+}
+
+// Summary aggregates the hourly stats of an app over a period.
+type Summary struct {
+	Hours            int
+	Requests         int64
+	Keys             int64
+	KeysPerRequest   []int64
+	RejectedKeys     int64
+	SkewedRequests   int64
+	RegionMismatches int64
+
+	// TopFingerprint is the most common fingerprint, and
+	// TopFingerprintRequests the number of requests with it.
+	TopFingerprint         string
+	TopFingerprintRequests int64
+}
+
+// Summarize aggregates the given hours, which span a period of the given
+// number of hours.
+func Summarize(stats []*HourlyStats, hours int) *Summary {
+	s := &Summary{
+		Hours:          hours,
+		KeysPerRequest: make([]int64, MaxKeysBucket+1),
+	}
+	for _, h := range stats {
+		s.Requests += h.Requests
+		s.Keys += h.Keys
+		s.RejectedKeys += h.RejectedKeys
+		s.SkewedRequests += h.SkewedRequests
+		s.RegionMismatches += h.RegionMismatches
+		for i := 0; i < len(h.KeysPerRequest) && i < len(s.KeysPerRequest); i++ {
+			s.KeysPerRequest[i] += h.KeysPerRequest[i]
+		}
+	}
+	return s
+}
+
+// RequestsPerHour is the mean number of requests per hour.
+func (s *Summary) RequestsPerHour() float64 {
+	if s.Hours <= 0 {
+		return 0
+	}
+	return float64(s.Requests) / float64(s.Hours)
+}
+
+// KeysPerRequestMean is the mean number of keys per request.
+func (s *Summary) KeysPerRequestMean() float64 {
+	return ratio(s.Keys, s.Requests)
+}
+
+// SkewedShare is the share of requests with keys that start in the future.
+func (s *Summary) SkewedShare() float64 {
+	return ratio(s.SkewedRequests, s.Requests)
+}
+
+// TopFingerprintShare is the share of requests with the most common
+// fingerprint.
+func (s *Summary) TopFingerprintShare() float64 {
+	return ratio(s.TopFingerprintRequests, s.Requests)
+}
+
+func ratio(a, b int64) float64 {
+	if b <= 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Distance returns the total variation distance between two keys-per-request
+// distributions, from 0 (identical) to 1 (disjoint).
+func Distance(a, b []int64) float64 {
+	var totalA, totalB int64
+	for _, v := range a {
+		totalA += v
+	}
+	for _, v := range b {
+		totalB += v
+	}
+	if totalA == 0 || totalB == 0 {
+		return 0
+	}
+
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		var pa, pb float64
+		if i < len(a) {
+			pa = float64(a[i]) / float64(totalA)
+		}
+		if i < len(b) {
+			pb = float64(b[i]) / float64(totalB)
+		}
+		sum += math.Abs(pa - pb)
+	}
+	return sum / 2
+}
+
+// Thresholds configure when the detector reports a finding.
+type Thresholds struct {
+	// MinRequests is the number of requests needed in both the window and the
+	// baseline before they are compared.
+	MinRequests int64
+	// VolumeFactor is how many times the baseline hourly request rate the
+	// window rate may reach.
+	VolumeFactor float64
+	// KeysPerRequestDistance is the largest allowed distance between the
+	// window and baseline keys-per-request distributions.
+	KeysPerRequestDistance float64
+	// FingerprintShare is the largest share of window requests that may have
+	// the same fingerprint.
+	FingerprintShare float64
+	// SkewedShare is the largest share of window requests that may have keys
+	// starting in the future.
+	SkewedShare float64
+	// RegionMismatches is the number of requests for regions the app may not
+	// publish to that are reported.
+	RegionMismatches int64
+}
+
+// Finding is an anomaly found in the publish metadata of an authorized app.
+type Finding struct {
+	ID             int64
+	AppPackageName string
+	DetectedAt     time.Time
+	Signal         Signal
+	Observed       float64
+	Baseline       float64
+	Detail         string
+	Resolved       bool
+}
+
+// Detect compares the window to the baseline and returns the anomalies found.
+// The findings are not yet associated with an app or time.
+func Detect(window, baseline *Summary, t *Thresholds) []*Finding {
+	var findings []*Finding
+
+	// Region mismatches are never expected, so they don't need a baseline.
+	if t.RegionMismatches > 0 && window.RegionMismatches >= t.RegionMismatches {
+		findings = append(findings, &Finding{
+			Signal:   SignalRegionMismatch,
+			Observed: float64(window.RegionMismatches),
+			Baseline: ratio(baseline.RegionMismatches, int64(baseline.Hours)) * float64(window.Hours),
+			Detail:   fmt.Sprintf("%d requests for regions the app is not allowed to publish to", window.RegionMismatches),
+		})
+	}
+
+	if window.Requests < t.MinRequests {
+		return findings
+	}
+
+	if share, base := window.TopFingerprintShare(), baseline.TopFingerprintShare(); t.FingerprintShare > 0 && share >= t.FingerprintShare && share > base {
+		findings = append(findings, &Finding{
+			Signal:   SignalRepeatedPattern,
+			Observed: share,
+			Baseline: base,
+			Detail:   fmt.Sprintf("%d of %d requests share fingerprint %s", window.TopFingerprintRequests, window.Requests, window.TopFingerprint),
+		})
+	}
+
+	if share, base := window.SkewedShare(), baseline.SkewedShare(); t.SkewedShare > 0 && share >= t.SkewedShare && share > base {
+		findings = append(findings, &Finding{
+			Signal:   SignalClockSkew,
+			Observed: share,
+			Baseline: base,
+			Detail:   fmt.Sprintf("%d of %d requests have keys starting in the future", window.SkewedRequests, window.Requests),
+		})
+	}
+
+	// The remaining signals are relative to the app's normal traffic.
+	if baseline.Requests < t.MinRequests {
+		return findings
+	}
+
+	if rate, base := window.RequestsPerHour(), baseline.RequestsPerHour(); t.VolumeFactor > 0 && rate > base*t.VolumeFactor {
+		findings = append(findings, &Finding{
+			Signal:   SignalVolume,
+			Observed: rate,
+			Baseline: base,
+			Detail:   fmt.Sprintf("%.1f requests per hour, %.1f times the baseline", rate, rate/base),
+		})
+	}
+
+	if d := Distance(window.KeysPerRequest, baseline.KeysPerRequest); t.KeysPerRequestDistance > 0 && d > t.KeysPerRequestDistance {
+		findings = append(findings, &Finding{
+			Signal:   SignalKeysPerRequest,
+			Observed: window.KeysPerRequestMean(),
+			Baseline: baseline.KeysPerRequestMean(),
+			Detail:   fmt.Sprintf("keys per request distribution is %.2f from the baseline", d),
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	upload := func(start, onset int32) *verifyapi.Publish {
+		return &verifyapi.Publish{
+			Keys: []verifyapi.ExposureKey{
+				{Key: "a", IntervalNumber: start, IntervalCount: 144},
+				{Key: "b", IntervalNumber: start + 144, IntervalCount: 144},
+			},
+			SymptomOnsetInterval: onset,
+		}
+	}
+
+	if got := Fingerprint(&verifyapi.Publish{}); got != "" {
+		t.Errorf("expected empty fingerprint without keys, got %q", got)
+	}
+
+	// The same shape on another day has the same fingerprint.
+	a := Fingerprint(upload(1000, 1000))
+	if b := Fingerprint(upload(2000, 2000)); a != b {
+		t.Errorf("expected fingerprints to match: %q != %q", a, b)
+	}
+
+	// A different onset does not.
+	if c := Fingerprint(upload(1000, 856)); a == c {
+		t.Errorf("expected fingerprints to differ, both %q", a)
+	}
+}
+
+func TestNewObservation(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	current := publishmodel.IntervalNumber(now)
+
+	cases := []struct {
+		name   string
+		start  int32
+		skewed bool
+	}{
+		{name: "past", start: current - 144, skewed: false},
+		{name: "current", start: current, skewed: false},
+		{name: "future", start: current + 1, skewed: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := NewObservation("app", &verifyapi.Publish{
+				Keys: []verifyapi.ExposureKey{{Key: "a", IntervalNumber: tc.start, IntervalCount: 144}},
+			}, now)
+			if got, want := o.Skewed, tc.skewed; got != want {
+				t.Errorf("expected skewed %t, got %t", want, got)
+			}
+			if got, want := o.Keys, 1; got != want {
+				t.Errorf("expected %d keys, got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestHourlyStats_Add(t *testing.T) {
+	t.Parallel()
+
+	h := InitHour("app", time.Date(2021, 6, 1, 12, 34, 0, 0, time.UTC))
+	if got, want := h.Hour, time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected hour %v, got %v", want, got)
+	}
+
+	h.Add(&Observation{Keys: 3, RejectedKeys: 1})
+	h.Add(&Observation{Keys: 100, Skewed: true})
+	h.Add(&Observation{Keys: 14, RegionMismatch: true})
+
+	if got, want := h.Requests, int64(3); got != want {
+		t.Errorf("expected %d requests, got %d", want, got)
+	}
+	if got, want := h.Keys, int64(117); got != want {
+		t.Errorf("expected %d keys, got %d", want, got)
+	}
+	if got, want := h.KeysPerRequest[3], int64(1); got != want {
+		t.Errorf("expected %d requests with 3 keys, got %d", want, got)
+	}
+	if got, want := h.KeysPerRequest[MaxKeysBucket], int64(1); got != want {
+		t.Errorf("expected %d requests in the last bucket, got %d", want, got)
+	}
+	if got, want := h.KeysPerRequest[14], int64(0); got != want {
+		t.Errorf("expected region mismatches to be left out of the distribution, got %d", got)
+	}
+	if h.RejectedKeys != 1 || h.SkewedRequests != 1 || h.RegionMismatches != 1 {
+		t.Errorf("unexpected counts: %#v", h)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		a, b []int64
+		want float64
+	}{
+		{name: "identical", a: []int64{1, 2, 3}, b: []int64{2, 4, 6}, want: 0},
+		{name: "disjoint", a: []int64{1, 0}, b: []int64{0, 1}, want: 1},
+		{name: "half", a: []int64{1, 1}, b: []int64{0, 1}, want: 0.5},
+		{name: "empty", a: []int64{0, 0}, b: []int64{1, 1}, want: 0},
+		{name: "different_lengths", a: []int64{1}, b: []int64{0, 1}, want: 1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Distance(tc.a, tc.b); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	thresholds := &Thresholds{
+		MinRequests:            10,
+		VolumeFactor:           5,
+		KeysPerRequestDistance: 0.5,
+		FingerprintShare:       0.5,
+		SkewedShare:            0.25,
+		RegionMismatches:       5,
+	}
+
+	// A baseline of a week with 10 requests an hour, mostly with 14 keys.
+	baselineHours := make([]*HourlyStats, 0, 168)
+	for i := 0; i < 168; i++ {
+		h := InitHour("app", time.Time{})
+		for j := 0; j < 10; j++ {
+			h.Add(&Observation{Keys: 14})
+		}
+		baselineHours = append(baselineHours, h)
+	}
+	baseline := Summarize(baselineHours, 168)
+	baseline.TopFingerprintRequests = 100
+
+	window := func(requests, keys int, o Observation) *Summary {
+		h := InitHour("app", time.Time{})
+		for i := 0; i < requests; i++ {
+			o := o
+			o.Keys = keys
+			h.Add(&o)
+		}
+		return Summarize([]*HourlyStats{h}, 1)
+	}
+
+	cases := []struct {
+		name   string
+		window *Summary
+		want   []Signal
+	}{
+		{
+			name:   "normal",
+			window: window(12, 14, Observation{}),
+		},
+		{
+			name:   "too_few_requests",
+			window: window(5, 1, Observation{Skewed: true}),
+		},
+		{
+			name:   "volume",
+			window: window(60, 14, Observation{}),
+			want:   []Signal{SignalVolume},
+		},
+		{
+			name:   "keys_per_request",
+			window: window(12, 1, Observation{}),
+			want:   []Signal{SignalKeysPerRequest},
+		},
+		{
+			name:   "clock_skew",
+			window: window(12, 14, Observation{Skewed: true}),
+			want:   []Signal{SignalClockSkew},
+		},
+		{
+			name:   "region_mismatch",
+			window: window(5, 14, Observation{RegionMismatch: true}),
+			want:   []Signal{SignalRegionMismatch},
+		},
+		{
+			name: "repeated_pattern",
+			window: func() *Summary {
+				s := window(12, 14, Observation{})
+				s.TopFingerprint = "abc"
+				s.TopFingerprintRequests = 10
+				return s
+			}(),
+			want: []Signal{SignalRepeatedPattern},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			findings := Detect(tc.window, baseline, thresholds)
+			got := make([]Signal, 0, len(findings))
+			for _, f := range findings {
+				got = append(got, f.Signal)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package abuse implements the API handlers for detecting anomalous publish
+// patterns.
+package abuse
+
+import (
+	"context"
+	"fmt"
+
+	abusedb "github.com/google/exposure-notifications-server/internal/abuse/database"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/render"
+	"github.com/google/exposure-notifications-server/pkg/server"
+	"github.com/gorilla/mux"
+)
+
+// Server hosts end points to detect abuse.
+type Server struct {
+	config  *Config
+	env     *serverenv.ServerEnv
+	db      *database.DB
+	abuseDB *abusedb.AbuseDB
+	h       *render.Renderer
+}
+
+// NewServer creates a Server that compares recent publish metadata of each
+// authorized app to its baseline.
+func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
+	if env.Database() == nil {
+		return nil, fmt.Errorf("missing database in server environment")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation: %w", err)
+	}
+
+	db := env.Database()
+	return &Server{
+		config:  cfg,
+		env:     env,
+		db:      db,
+		abuseDB: abusedb.New(db),
+		h:       render.NewRenderer(),
+	}, nil
+}
+
+// Routes defines and returns the routes for this server.
+func (s *Server) Routes(ctx context.Context) *mux.Router {
+	logger := logging.FromContext(ctx).Named("abuse")
+
+	r := mux.NewRouter()
+	s.config.Middleware.Use(r, logger)
+
+	r.Handle("/health", server.HandleHealthz(s.env.Database()))
+	r.Handle("/detect-abuse", s.handleDetect())
+
+	return r
+}
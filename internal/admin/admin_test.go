@@ -30,6 +30,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/region"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/keys"
@@ -68,7 +69,9 @@ func newTestServer(t testing.TB) (*serverenv.ServerEnv, *Server) {
 		serverenv.WithDatabase(testDB),
 		serverenv.WithKeyManager(keys))
 
-	config := &Config{}
+	config := &Config{
+		Regions: region.Config{CustomRegions: []string{"TEST", "NOT-TEST"}},
+	}
 
 	server, err := NewServer(config, env)
 	if err != nil {
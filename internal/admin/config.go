@@ -21,6 +21,9 @@ import (
 	"fmt"
 	"html/template"
 
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/i18n"
+	"github.com/google/exposure-notifications-server/internal/region"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -43,8 +46,29 @@ type Config struct {
 	KeyManager    keys.Config
 	SecretManager secrets.Config
 	Storage       storage.Config
+	I18n          i18n.Config
+	Regions       region.Config
+
+	// ExportPreview holds the export service settings that export config
+	// previews use. They should match the export service.
+	ExportPreview export.PreviewConfig
 
 	Port string `env:"PORT, default=8080"`
+
+	// AllowedCIDRs restricts the console to clients on these networks, in CIDR
+	// notation. If empty, clients from any network are allowed. The health
+	// check is always allowed.
+	AllowedCIDRs []string `env:"ALLOWED_CIDRS"`
+
+	// TrustedProxyCount is the number of proxies in front of the console that
+	// append to the X-Forwarded-For header. If 0, the address of the
+	// connection is used.
+	TrustedProxyCount int `env:"TRUSTED_PROXY_COUNT, default=0"`
+
+	// GraphQLTokens are the bearer tokens accepted by the read-only GraphQL API
+	// at /api/graphql. If empty, the API is disabled. The value may come from
+	// secret://.
+	GraphQLTokens []string `env:"GRAPHQL_TOKENS"`
 }
 
 func (c *Config) DatabaseConfig() *database.Config {
@@ -63,10 +87,15 @@ func (c *Config) BlobstoreConfig() *storage.Config {
 	return &c.Storage
 }
 
+// TemplateRenderer parses the admin templates. In addition to
+// TemplateFuncMap, templates can call "tenant" to get the configured database
+// tenant.
 func (c *Config) TemplateRenderer() (*template.Template, error) {
+	tenant := c.Database.Tenant
 	tmpl, err := template.New("").
 		Option("missingkey=zero").
 		Funcs(TemplateFuncMap).
+		Funcs(template.FuncMap{"tenant": func() string { return tenant }}).
 		ParseFS(templatesFS, "templates/*.html")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates from fs: %w", err)
@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/i18n"
 )
 
 func testRenderTemplate(tb testing.TB, name string, p TemplateMap) string {
@@ -88,3 +90,48 @@ func TestConfig_TemplateRenderer(t *testing.T) {
 		t.Errorf("expected %q to contain %q", got, want)
 	}
 }
+
+func TestConfig_TemplateRenderer_Tenant(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{}
+	cfg.Database.Tenant = "jurisdiction-a"
+	tmpl, err := cfg.TemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "top", TemplateMap{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "Tenant: jurisdiction-a"; !strings.Contains(got, want) {
+		t.Errorf("expected %q to contain %q", got, want)
+	}
+}
+
+func TestConfig_TemplateRenderer_Locale(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{}
+	cfg.Database.Tenant = "jurisdiction-a"
+	tmpl, err := cfg.TemplateRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := i18n.Load(&i18n.Config{DefaultLocale: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "top", TemplateMap{"locale": catalog.Match("es-MX")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<html lang="es">`, "Inicio", "Inquilino: jurisdiction-a"} {
+		if got := b.String(); !strings.Contains(got, want) {
+			t.Errorf("expected %q to contain %q", got, want)
+		}
+	}
+}
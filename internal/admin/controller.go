@@ -19,6 +19,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/i18n"
 )
 
 // Controller is the interfactor for controllers that can be pluggied into Gin
@@ -29,6 +30,15 @@ type Controller interface {
 
 func ErrorPage(c *gin.Context, messages ...string) {
 	log.Printf("error: %v", messages)
-	c.HTML(http.StatusInternalServerError, "error", gin.H{"error": messages})
+	m := TemplateMap{}
+	m.AddErrors(messages...)
+	renderHTML(c, http.StatusInternalServerError, "error", m)
 	c.Abort()
 }
+
+// renderHTML renders the template with the locale of the request, which
+// templates pass to the "t" function to translate messages.
+func renderHTML(c *gin.Context, code int, name string, m TemplateMap) {
+	m["locale"] = i18n.FromContext(c.Request.Context())
+	c.HTML(code, name, m)
+}
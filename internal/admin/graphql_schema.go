@@ -0,0 +1,329 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	exdb "github.com/google/exposure-notifications-server/internal/export/database"
+	eidb "github.com/google/exposure-notifications-server/internal/exportimport/database"
+	"github.com/google/exposure-notifications-server/internal/graphql"
+	mirrordb "github.com/google/exposure-notifications-server/internal/mirror/database"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	pubmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	trdb "github.com/google/exposure-notifications-server/internal/travelrule/database"
+	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	hamodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// newGraphQLSchema builds the schema of the read-only GraphQL API. It covers
+// configuration and statistics only: exposure keys and secrets, like the
+// request signing secret of authorized apps, are deliberately not part of it.
+func (s *Server) newGraphQLSchema() (*graphql.Schema, error) {
+	authorizedApp := &graphql.Object{
+		Name: "AuthorizedApp",
+		Fields: map[string]*graphql.Field{
+			"appPackageName": {Type: graphql.String},
+			"allowedRegions": {
+				Type: "[String]",
+				Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					return source.(*aamodel.AuthorizedApp).AllAllowedRegions(), nil
+				},
+			},
+			"allowedHealthAuthorityIDs": {
+				Type: "[ID]",
+				Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					ids := make([]int64, 0, len(source.(*aamodel.AuthorizedApp).AllowedHealthAuthorityIDs))
+					for id := range source.(*aamodel.AuthorizedApp).AllowedHealthAuthorityIDs {
+						ids = append(ids, id)
+					}
+					sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+					return ids, nil
+				},
+			},
+			"bypassHealthAuthorityVerification": {Type: graphql.Boolean},
+			"bypassRevisionToken":               {Type: graphql.Boolean},
+			"detailedPublishResponse":           {Type: graphql.Boolean},
+			"allowedCIDRs":                      {Type: "[String]"},
+			"sameDayKeyPolicy":                  {Type: graphql.String},
+			"disabled":                          {Type: graphql.Boolean},
+			"testData":                          {Type: graphql.Boolean},
+			"throttledUntil":                    {Type: graphql.Time},
+		},
+	}
+
+	healthAuthorityKey := &graphql.Object{
+		Name: "HealthAuthorityKey",
+		Fields: map[string]*graphql.Field{
+			"version":      {Type: graphql.String},
+			"from":         {Type: graphql.Time},
+			"thru":         {Type: graphql.Time},
+			"publicKeyPEM": {Type: graphql.String},
+			"stage":        {Type: graphql.String},
+			"algorithm":    {Type: graphql.String},
+		},
+	}
+
+	healthAuthorityStats := &graphql.Object{
+		Name: "HealthAuthorityStats",
+		Fields: map[string]*graphql.Field{
+			"hour":                  {Type: graphql.Time},
+			"publishCount":          {Type: "[Int]"},
+			"tekCount":              {Type: graphql.Int},
+			"revisionCount":         {Type: graphql.Int},
+			"oldestTekDays":         {Type: "[Int]"},
+			"onsetAgeDays":          {Type: "[Int]"},
+			"missingOnset":          {Type: graphql.Int},
+			"keysPerUpload":         {Type: "[Int]"},
+			"confirmedOnsetAgeDays": {Type: "[Int]"},
+			"likelyOnsetAgeDays":    {Type: "[Int]"},
+		},
+	}
+
+	healthAuthority := &graphql.Object{
+		Name: "HealthAuthority",
+		Fields: map[string]*graphql.Field{
+			"id":                   {Type: graphql.ID},
+			"issuer":               {Type: graphql.String},
+			"audience":             {Type: graphql.String},
+			"name":                 {Type: graphql.String},
+			"jwksURI":              {Type: graphql.String},
+			"enableStatsAPI":       {Type: graphql.Boolean},
+			"jurisdiction":         {Type: graphql.String},
+			"exportDelay":          {Type: graphql.String},
+			"stage":                {Type: graphql.String},
+			"verificationProvider": {Type: graphql.String},
+			"hmacSchemes":          {Type: "[String]"},
+			"keys": {
+				Type: "[HealthAuthorityKey]",
+				Resolve: func(ctx context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					return hadb.New(s.env.Database()).GetHealthAuthorityKeys(ctx, source.(*hamodel.HealthAuthority))
+				},
+			},
+			"stats": {
+				Type: "[HealthAuthorityStats]",
+				Args: map[string]string{"hours": graphql.Int},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					stats, err := publishdb.New(s.env.Database()).ReadStats(ctx, source.(*hamodel.HealthAuthority).ID)
+					if err != nil {
+						return nil, err
+					}
+					hours, ok := args["hours"].(int64)
+					if !ok {
+						return stats, nil
+					}
+					return statsSince(stats, time.Now().UTC().Add(-time.Duration(hours)*time.Hour)), nil
+				},
+			},
+		},
+	}
+
+	exportConfig := &graphql.Object{
+		Name: "ExportConfig",
+		Fields: map[string]*graphql.Field{
+			"configID":             {Type: graphql.ID},
+			"bucketName":           {Type: graphql.String},
+			"filenameRoot":         {Type: graphql.String},
+			"period":               {Type: graphql.String},
+			"outputRegion":         {Type: graphql.String},
+			"inputRegions":         {Type: "[String]"},
+			"excludeRegions":       {Type: "[String]"},
+			"includeTravelers":     {Type: graphql.Boolean},
+			"onlyNonTravelers":     {Type: graphql.Boolean},
+			"from":                 {Type: graphql.Time},
+			"thru":                 {Type: graphql.Time},
+			"signatureInfoIDs":     {Type: "[ID]"},
+			"maxRecordsOverride":   {Type: graphql.Int},
+			"maxBatchKeys":         {Type: graphql.Int},
+			"minRecordsOverride":   {Type: graphql.Int},
+			"smallBatchPolicy":     {Type: graphql.String},
+			"encryptionKeyID":      {Type: graphql.String},
+			"includeJurisdictions": {Type: "[String]"},
+			"excludeJurisdictions": {Type: "[String]"},
+			"parentConfigID":       {Type: graphql.ID},
+			"filterRegions":        {Type: "[String]"},
+			"filterReportTypes":    {Type: "[String]"},
+			"excludeRevised":       {Type: graphql.Boolean},
+			"testData":             {Type: graphql.Boolean},
+			"cacheControl":         {Type: graphql.String},
+			"storageClass":         {Type: graphql.String},
+		},
+	}
+
+	signatureInfo := &graphql.Object{
+		Name: "SignatureInfo",
+		Fields: map[string]*graphql.Field{
+			"id":                {Type: graphql.ID},
+			"signingKey":        {Type: graphql.String},
+			"signingKeyVersion": {Type: graphql.String},
+			"signingKeyID":      {Type: graphql.String},
+			"endTimestamp":      {Type: graphql.Time},
+		},
+	}
+
+	exportImporter := &graphql.Object{
+		Name: "ExportImporter",
+		Fields: map[string]*graphql.Field{
+			"id":             {Type: graphql.ID},
+			"indexFile":      {Type: graphql.String},
+			"exportRoot":     {Type: graphql.String},
+			"region":         {Type: graphql.String},
+			"traveler":       {Type: graphql.Boolean},
+			"from":           {Type: graphql.Time},
+			"thru":           {Type: graphql.Time},
+			"strictness":     {Type: graphql.String},
+			"lastBatchStart": {Type: graphql.Time},
+			"lastBatchEnd":   {Type: graphql.Time},
+		},
+	}
+
+	mirror := &graphql.Object{
+		Name: "Mirror",
+		Fields: map[string]*graphql.Field{
+			"id":                 {Type: graphql.ID},
+			"indexFile":          {Type: graphql.String},
+			"exportRoot":         {Type: graphql.String},
+			"cloudStorageBucket": {Type: graphql.String},
+			"filenameRoot":       {Type: graphql.String},
+			"filenameRewrite":    {Type: graphql.String},
+		},
+	}
+
+	travelRule := &graphql.Object{
+		Name: "TravelRule",
+		Fields: map[string]*graphql.Field{
+			"id":                {Type: graphql.ID},
+			"name":              {Type: graphql.String},
+			"inputRegions":      {Type: "[String]"},
+			"travelersOnly":     {Type: graphql.Boolean},
+			"outputRegions":     {Type: "[String]"},
+			"applyToExport":     {Type: graphql.Boolean},
+			"applyToFederation": {Type: graphql.Boolean},
+		},
+	}
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: map[string]*graphql.Field{
+			"authorizedApps": {
+				Type: "[AuthorizedApp]",
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return aadb.New(s.env.Database()).ListAuthorizedApps(ctx)
+				},
+			},
+			"authorizedApp": {
+				Type: "AuthorizedApp",
+				Args: map[string]string{"appPackageName": "String!"},
+				Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					return aadb.New(s.env.Database()).GetAuthorizedApp(ctx, args["appPackageName"].(string))
+				},
+			},
+			"healthAuthorities": {
+				Type: "[HealthAuthority]",
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return hadb.New(s.env.Database()).ListAllHealthAuthoritiesWithoutKeys(ctx)
+				},
+			},
+			"healthAuthority": {
+				Type: "HealthAuthority",
+				Args: map[string]string{"id": "ID!"},
+				Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					id, err := parseGraphQLID(args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return notFoundAsNull(hadb.New(s.env.Database()).GetHealthAuthorityByID(ctx, id))
+				},
+			},
+			"exportConfigs": {
+				Type: "[ExportConfig]",
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return exdb.New(s.env.Database()).GetAllExportConfigs(ctx)
+				},
+			},
+			"exportConfig": {
+				Type: "ExportConfig",
+				Args: map[string]string{"configID": "ID!"},
+				Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					id, err := parseGraphQLID(args["configID"])
+					if err != nil {
+						return nil, err
+					}
+					return notFoundAsNull(exdb.New(s.env.Database()).GetExportConfig(ctx, id))
+				},
+			},
+			"signatureInfos": {
+				Type: "[SignatureInfo]",
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return exdb.New(s.env.Database()).ListAllSignatureInfos(ctx)
+				},
+			},
+			"exportImporters": {
+				Type: "[ExportImporter]",
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return eidb.New(s.env.Database()).ListConfigs(ctx)
+				},
+			},
+			"mirrors": {
+				Type: "[Mirror]",
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return mirrordb.New(s.env.Database()).Mirrors(ctx)
+				},
+			},
+			"travelRules": {
+				Type: "[TravelRule]",
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return trdb.New(s.env.Database()).ListTravelRules(ctx)
+				},
+			},
+		},
+	}
+
+	return graphql.NewSchema(query, authorizedApp, healthAuthority, healthAuthorityKey,
+		healthAuthorityStats, exportConfig, signatureInfo, exportImporter, mirror, travelRule)
+}
+
+// statsSince returns the stats for hours at or after since. The stats are
+// ordered by hour.
+func statsSince(stats []*pubmodel.HealthAuthorityStats, since time.Time) []*pubmodel.HealthAuthorityStats {
+	i := sort.Search(len(stats), func(i int) bool {
+		return !stats[i].Hour.Before(since)
+	})
+	return stats[i:]
+}
+
+func parseGraphQLID(v interface{}) (int64, error) {
+	id, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", v)
+	}
+	return id, nil
+}
+
+// notFoundAsNull resolves lookups of records that do not exist as null.
+func notFoundAsNull(v interface{}, err error) (interface{}, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return v, err
+}
@@ -19,12 +19,16 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	abusedb "github.com/google/exposure-notifications-server/internal/abuse/database"
 	"github.com/google/exposure-notifications-server/internal/authorizedapp/database"
 	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
 	"github.com/google/exposure-notifications-server/internal/project"
+	pubmodel "github.com/google/exposure-notifications-server/internal/publish/model"
 	verdb "github.com/google/exposure-notifications-server/internal/verification/database"
 )
 
@@ -62,6 +66,12 @@ func (s *Server) HandleAuthorizedAppsSave() func(c *gin.Context) {
 			form.PopulateAuthorizedApp(authApp)
 
 			errors := authApp.Validate()
+			errors = append(errors, s.normalizeAppRegions(authApp)...)
+			if p := authApp.SameDayKeyPolicy; p != "" {
+				if _, err := pubmodel.ParseSameDayKeyPolicy(p); err != nil {
+					errors = append(errors, fmt.Sprintf("Invalid same day key policy %q", p))
+				}
+			}
 			if len(errors) > 0 {
 				m.AddErrors(errors...)
 				m["app"] = authApp
@@ -70,7 +80,7 @@ func (s *Server) HandleAuthorizedAppsSave() func(c *gin.Context) {
 					ErrorPage(c, err.Error())
 					return
 				}
-				c.HTML(http.StatusOK, "authorizedapp", m)
+				renderHTML(c, http.StatusOK, "authorizedapp", m)
 				return
 			}
 
@@ -78,7 +88,7 @@ func (s *Server) HandleAuthorizedAppsSave() func(c *gin.Context) {
 				if err := aadb.UpdateAuthorizedApp(ctx, priorKey, authApp); err != nil {
 					m.AddErrors(fmt.Sprintf("Error removing old version: %v", err))
 					m["app"] = authApp
-					c.HTML(http.StatusOK, "authorizedapp", m)
+					renderHTML(c, http.StatusOK, "authorizedapp", m)
 					return
 				}
 				m.AddSuccess(fmt.Sprintf("Updated authorized app: %v", authApp.AppPackageName))
@@ -97,7 +107,34 @@ func (s *Server) HandleAuthorizedAppsSave() func(c *gin.Context) {
 
 			m["app"] = authApp
 			m["previousKey"] = base64.StdEncoding.EncodeToString([]byte(authApp.AppPackageName))
-			c.HTML(http.StatusOK, "authorizedapp", m)
+			renderHTML(c, http.StatusOK, "authorizedapp", m)
+			return
+		} else if form.Action == "resolve-abuse" {
+			priorKey := form.PriorKey()
+
+			if err := abusedb.New(s.env.Database()).Resolve(ctx, priorKey); err != nil {
+				ErrorPage(c, fmt.Sprintf("Error resolving abuse findings: %v", err))
+				return
+			}
+
+			authApp, err := aadb.GetAuthorizedApp(ctx, priorKey)
+			if err != nil {
+				ErrorPage(c, err.Error())
+				return
+			}
+			if authApp == nil {
+				ErrorPage(c, "Unknown authorized app")
+				return
+			}
+			if err := addHealthAuthorityInfo(ctx, verdb.New(s.env.Database()), authApp, m); err != nil {
+				ErrorPage(c, err.Error())
+				return
+			}
+
+			m.AddSuccess(fmt.Sprintf("Resolved abuse findings for `%v`", priorKey))
+			m["app"] = authApp
+			m["previousKey"] = form.FormKey
+			renderHTML(c, http.StatusOK, "authorizedapp", m)
 			return
 		} else if form.Action == "delete" {
 			priorKey := form.PriorKey()
@@ -115,7 +152,7 @@ func (s *Server) HandleAuthorizedAppsSave() func(c *gin.Context) {
 
 			m.AddSuccess(fmt.Sprintf("Successfully deleted app `%v`", priorKey))
 			m["app"] = authorizedApp
-			c.HTML(http.StatusOK, "authorizedapp", m)
+			renderHTML(c, http.StatusOK, "authorizedapp", m)
 			return
 		}
 
@@ -146,6 +183,15 @@ func (s *Server) HandleAuthorizedAppsShow() func(c *gin.Context) {
 				ErrorPage(c, "error loading authorized app")
 				return
 			}
+
+			// Load the abuse findings.
+			findings, err := abusedb.New(s.env.Database()).ListUnresolvedFindings(ctx, authorizedApp.AppPackageName)
+			if err != nil {
+				ErrorPage(c, err.Error())
+				return
+			}
+			m["abuseFindings"] = findings
+			m["throttled"] = authorizedApp.IsThrottled(time.Now())
 		}
 
 		// Load the health authorities.
@@ -156,7 +202,7 @@ func (s *Server) HandleAuthorizedAppsShow() func(c *gin.Context) {
 
 		m["app"] = authorizedApp
 		m["previousKey"] = base64.StdEncoding.EncodeToString([]byte(authorizedApp.AppPackageName))
-		c.HTML(http.StatusOK, "authorizedapp", m)
+		renderHTML(c, http.StatusOK, "authorizedapp", m)
 	}
 }
 
@@ -188,6 +234,12 @@ type authorizedAppFormData struct {
 	AllowedRegions                    string  `form:"regions"`
 	BypassHealthAuthorityVerification bool    `form:"bypass-health-authority-verification"`
 	BypassRevisionToken               bool    `form:"bypass-revision-token"`
+	DetailedPublishResponse           bool    `form:"detailed-publish-response"`
+	RequestSigningSecret              string  `form:"request-signing-secret"`
+	AllowedCIDRs                      string  `form:"allowed-cidrs"`
+	SameDayKeyPolicy                  string  `form:"same-day-key-policy"`
+	Disabled                          bool    `form:"disabled"`
+	TestData                          bool    `form:"test-data"`
 	HealthAuthorityIDs                []int64 `form:"health-authorities"`
 }
 
@@ -218,4 +270,33 @@ func (f *authorizedAppFormData) PopulateAuthorizedApp(a *model.AuthorizedApp) {
 	}
 	a.BypassHealthAuthorityVerification = f.BypassHealthAuthorityVerification
 	a.BypassRevisionToken = f.BypassRevisionToken
+	a.DetailedPublishResponse = f.DetailedPublishResponse
+	a.RequestSigningSecret = strings.TrimSpace(f.RequestSigningSecret)
+	a.AllowedCIDRs = nil
+	for _, cidr := range strings.Split(f.AllowedCIDRs, "\n") {
+		if cidr = project.TrimSpaceAndNonPrintable(cidr); cidr != "" {
+			a.AllowedCIDRs = append(a.AllowedCIDRs, cidr)
+		}
+	}
+	a.SameDayKeyPolicy = strings.ToUpper(strings.TrimSpace(f.SameDayKeyPolicy))
+	a.Disabled = f.Disabled
+	a.TestData = f.TestData
+}
+
+// normalizeAppRegions normalizes the allowed regions of the app, and returns
+// an error for each unknown region.
+func (s *Server) normalizeAppRegions(a *model.AuthorizedApp) []string {
+	var errs []string
+	regions := make(map[string]struct{}, len(a.AllowedRegions))
+	for r := range a.AllowedRegions {
+		region, err := s.regions.Normalize(r)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		regions[region] = struct{}{}
+	}
+	sort.Strings(errs)
+	a.AllowedRegions = regions
+	return errs
 }
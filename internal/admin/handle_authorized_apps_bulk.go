@@ -0,0 +1,408 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	pubmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	verdb "github.com/google/exposure-notifications-server/internal/verification/database"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+)
+
+// HandleAuthorizedAppsBulkShow shows the bulk import page for authorized apps.
+func (s *Server) HandleAuthorizedAppsBulkShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		m := TemplateMap{}
+		m.AddTitle("Bulk authorized apps")
+		renderHTML(c, http.StatusOK, "authorizedapps-bulk", m)
+	}
+}
+
+// HandleAuthorizedAppsBulkSave handles the CSV import and the bulk disable and
+// enable actions for authorized apps.
+func (s *Server) HandleAuthorizedAppsBulkSave() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form bulkAppsFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+		m.AddTitle("Bulk authorized apps")
+		m["form"] = form
+
+		switch form.Action {
+		case "import":
+			data := form.CSV
+			if fh, err := c.FormFile("csv-file"); err == nil {
+				f, err := fh.Open()
+				if err != nil {
+					ErrorPage(c, fmt.Sprintf("Error reading uploaded file: %v", err))
+					return
+				}
+				b, err := io.ReadAll(f)
+				f.Close()
+				if err != nil {
+					ErrorPage(c, fmt.Sprintf("Error reading uploaded file: %v", err))
+					return
+				}
+				data = string(b)
+			}
+
+			apps, err := parseBulkAppsCSV(strings.NewReader(data))
+			if err != nil {
+				m.AddErrors(err.Error())
+				break
+			}
+			result, errs, err := s.upsertBulkApps(ctx, apps)
+			if err != nil {
+				ErrorPage(c, err.Error())
+				return
+			}
+			if len(errs) > 0 {
+				m.AddErrors(errs...)
+				break
+			}
+			m.AddSuccess(fmt.Sprintf("Created %d and updated %d authorized apps", result.Inserted, result.Updated))
+		case "disable", "enable":
+			names := splitBulkList(form.Apps)
+			if len(names) == 0 {
+				m.AddErrors("No authorized apps provided")
+				break
+			}
+			missing, err := database.New(s.env.Database()).SetAuthorizedAppsDisabled(ctx, names, form.Action == "disable")
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Error updating authorized apps: %v", err))
+				return
+			}
+			if len(missing) > 0 {
+				m.AddErrors(fmt.Sprintf("Unknown authorized apps: %s", strings.Join(missing, ", ")))
+			}
+			m.AddSuccess(fmt.Sprintf("Updated %d authorized apps (%sd)", len(names)-len(missing), form.Action))
+		default:
+			ErrorPage(c, "Invalid form action")
+			return
+		}
+
+		renderHTML(c, http.StatusOK, "authorizedapps-bulk", m)
+	}
+}
+
+// HandleAuthorizedAppsBulkAPI creates or updates the authorized apps in the
+// JSON request body.
+func (s *Server) HandleAuthorizedAppsBulkAPI() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req bulkAppsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"errors": []string{err.Error()}})
+			return
+		}
+
+		result, errs, err := s.upsertBulkApps(c.Request.Context(), req.Apps)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"errors": []string{err.Error()}})
+			return
+		}
+		if len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"errors": errs})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"inserted": result.Inserted, "updated": result.Updated})
+	}
+}
+
+// HandleAuthorizedAppsDisableAPI disables or enables the authorized apps in
+// the JSON request body.
+func (s *Server) HandleAuthorizedAppsDisableAPI() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req bulkDisableRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"errors": []string{err.Error()}})
+			return
+		}
+		if len(req.Apps) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"errors": []string{"no authorized apps provided"}})
+			return
+		}
+
+		missing, err := database.New(s.env.Database()).SetAuthorizedAppsDisabled(c.Request.Context(), req.Apps, req.Disabled)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"errors": []string{err.Error()}})
+			return
+		}
+		if missing == nil {
+			missing = []string{}
+		}
+		c.JSON(http.StatusOK, gin.H{"updated": len(req.Apps) - len(missing), "notFound": missing})
+	}
+}
+
+// upsertBulkApps validates and saves the given apps. Validation errors are
+// returned separately from errors saving the apps, no apps are saved if there
+// are any.
+func (s *Server) upsertBulkApps(ctx context.Context, apps []*bulkApp) (*database.UpsertAuthorizedAppsResult, []string, error) {
+	if len(apps) == 0 {
+		return nil, []string{"No authorized apps provided"}, nil
+	}
+
+	has, err := verdb.New(s.env.Database()).ListAllHealthAuthoritiesWithoutKeys(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading health authorities: %w", err)
+	}
+
+	authApps, errs := toAuthorizedApps(apps, has, s.regions)
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	result, err := database.New(s.env.Database()).UpsertAuthorizedApps(ctx, authApps)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error saving authorized apps: %w", err)
+	}
+	return result, nil, nil
+}
+
+type bulkAppsFormData struct {
+	Action string `form:"action" binding:"required"`
+	CSV    string `form:"csv"`
+	Apps   string `form:"apps"`
+}
+
+type bulkAppsRequest struct {
+	Apps []*bulkApp `json:"apps"`
+}
+
+type bulkDisableRequest struct {
+	Apps     []string `json:"apps"`
+	Disabled bool     `json:"disabled"`
+}
+
+// bulkApp is an authorized app in a bulk import. Health authorities are
+// referenced by issuer or ID, since IDs differ between environments.
+type bulkApp struct {
+	AppPackageName                    string   `json:"appPackageName"`
+	Regions                           []string `json:"regions"`
+	HealthAuthorities                 []string `json:"healthAuthorities"`
+	BypassHealthAuthorityVerification bool     `json:"bypassHealthAuthorityVerification"`
+	BypassRevisionToken               bool     `json:"bypassRevisionToken"`
+	DetailedPublishResponse           bool     `json:"detailedPublishResponse"`
+	SameDayKeyPolicy                  string   `json:"sameDayKeyPolicy"`
+	Disabled                          bool     `json:"disabled"`
+	TestData                          bool     `json:"testData"`
+}
+
+// bulkAppColumns are the CSV columns of a bulk import, in their default order.
+var bulkAppColumns = []string{
+	"app_package_name",
+	"regions",
+	"health_authorities",
+	"bypass_health_authority_verification",
+	"bypass_revision_token",
+	"detailed_publish_response",
+	"same_day_key_policy",
+	"disabled",
+	"test_data",
+}
+
+// parseBulkAppsCSV parses a bulk import of authorized apps. The first row is a
+// header naming the columns, which can be in any order. The app_package_name
+// and regions columns are required. Lists in a cell are separated by spaces or
+// semicolons.
+func parseBulkAppsCSV(r io.Reader) ([]*bulkApp, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = 0
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("CSV is empty")
+		}
+		return nil, fmt.Errorf("failed to parse CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ReplaceAll(strings.ToLower(project.TrimSpaceAndNonPrintable(name)), "-", "_")
+		if !isBulkAppColumn(name) {
+			return nil, fmt.Errorf("unknown CSV column %q, expected one of %s", name, strings.Join(bulkAppColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("CSV column %q is listed more than once", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range bulkAppColumns[:2] {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV is missing the %q column", required)
+		}
+	}
+
+	var apps []*bulkApp
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		cell := func(name string) string {
+			i, ok := columns[name]
+			if !ok {
+				return ""
+			}
+			return project.TrimSpaceAndNonPrintable(record[i])
+		}
+		boolCell := func(name string) (bool, error) {
+			v := cell(name)
+			if v == "" {
+				return false, nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return false, fmt.Errorf("line %d: invalid %s %q", line, name, v)
+			}
+			return b, nil
+		}
+
+		app := &bulkApp{
+			AppPackageName:    cell("app_package_name"),
+			Regions:           splitBulkList(cell("regions")),
+			HealthAuthorities: splitBulkList(cell("health_authorities")),
+			SameDayKeyPolicy:  cell("same_day_key_policy"),
+		}
+		if app.BypassHealthAuthorityVerification, err = boolCell("bypass_health_authority_verification"); err != nil {
+			return nil, err
+		}
+		if app.BypassRevisionToken, err = boolCell("bypass_revision_token"); err != nil {
+			return nil, err
+		}
+		if app.DetailedPublishResponse, err = boolCell("detailed_publish_response"); err != nil {
+			return nil, err
+		}
+		if app.Disabled, err = boolCell("disabled"); err != nil {
+			return nil, err
+		}
+		if app.TestData, err = boolCell("test_data"); err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+func isBulkAppColumn(name string) bool {
+	for _, c := range bulkAppColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitBulkList splits a list separated by whitespace, commas, or semicolons.
+func splitBulkList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+}
+
+// toAuthorizedApps converts bulk apps to authorized apps, resolving health
+// authorities by issuer or ID and normalizing regions. It returns all
+// validation errors.
+func toAuthorizedApps(apps []*bulkApp, has []*vermodel.HealthAuthority, regions *region.Validator) ([]*model.AuthorizedApp, []string) {
+	byIssuer := make(map[string]int64, len(has))
+	byID := make(map[int64]struct{}, len(has))
+	for _, ha := range has {
+		byIssuer[ha.Issuer] = ha.ID
+		byID[ha.ID] = struct{}{}
+	}
+
+	var errs []string
+	seen := make(map[string]struct{}, len(apps))
+	authApps := make([]*model.AuthorizedApp, 0, len(apps))
+	for i, app := range apps {
+		name := project.TrimSpaceAndNonPrintable(app.AppPackageName)
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		} else if _, ok := seen[strings.ToLower(name)]; ok {
+			errs = append(errs, fmt.Sprintf("%s: listed more than once", name))
+		}
+		seen[strings.ToLower(name)] = struct{}{}
+
+		a := model.NewAuthorizedApp()
+		a.AppPackageName = project.TrimSpaceAndNonPrintable(app.AppPackageName)
+		for _, r := range app.Regions {
+			if r = project.TrimSpaceAndNonPrintable(r); r == "" {
+				continue
+			}
+			normalized, err := regions.Normalize(r)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+				continue
+			}
+			a.AllowedRegions[normalized] = struct{}{}
+		}
+		for _, ref := range app.HealthAuthorities {
+			if id, ok := byIssuer[ref]; ok {
+				a.AllowedHealthAuthorityIDs[id] = struct{}{}
+				continue
+			}
+			if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+				if _, ok := byID[id]; ok {
+					a.AllowedHealthAuthorityIDs[id] = struct{}{}
+					continue
+				}
+			}
+			errs = append(errs, fmt.Sprintf("%s: unknown health authority %q", name, ref))
+		}
+		a.BypassHealthAuthorityVerification = app.BypassHealthAuthorityVerification
+		a.BypassRevisionToken = app.BypassRevisionToken
+		a.DetailedPublishResponse = app.DetailedPublishResponse
+		a.Disabled = app.Disabled
+		a.TestData = app.TestData
+		if p := strings.TrimSpace(app.SameDayKeyPolicy); p != "" {
+			policy, err := pubmodel.ParseSameDayKeyPolicy(p)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: invalid same day key policy %q", name, p))
+			}
+			a.SameDayKeyPolicy = string(policy)
+		}
+
+		for _, err := range a.Validate() {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+		}
+		authApps = append(authApps, a)
+	}
+	return authApps, errs
+}
@@ -0,0 +1,253 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/region"
+	verdb "github.com/google/exposure-notifications-server/internal/verification/database"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBulkAppsCSV(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		csv  string
+		exp  []*bulkApp
+		err  string
+	}{
+		{
+			name: "all_columns",
+			csv: "app_package_name,regions,health_authorities,bypass_health_authority_verification,bypass_revision_token,detailed_publish_response,same_day_key_policy,disabled,test_data\n" +
+				"com.example.a,US;CA,iss-a iss-b,true,false,1,release,true,true\n",
+			exp: []*bulkApp{
+				{
+					AppPackageName:                    "com.example.a",
+					Regions:                           []string{"US", "CA"},
+					HealthAuthorities:                 []string{"iss-a", "iss-b"},
+					BypassHealthAuthorityVerification: true,
+					DetailedPublishResponse:           true,
+					SameDayKeyPolicy:                  "release",
+					Disabled:                          true,
+					TestData:                          true,
+				},
+			},
+		},
+		{
+			name: "reordered_and_optional_columns",
+			csv:  "Regions, App-Package-Name\nUS,com.example.a\n\"MX CA\",com.example.b\n",
+			exp: []*bulkApp{
+				{AppPackageName: "com.example.a", Regions: []string{"US"}, HealthAuthorities: []string{}},
+				{AppPackageName: "com.example.b", Regions: []string{"MX", "CA"}, HealthAuthorities: []string{}},
+			},
+		},
+		{
+			name: "empty",
+			csv:  "",
+			err:  "CSV is empty",
+		},
+		{
+			name: "missing_regions",
+			csv:  "app_package_name\ncom.example.a\n",
+			err:  `missing the "regions" column`,
+		},
+		{
+			name: "unknown_column",
+			csv:  "app_package_name,regions,secret\n",
+			err:  `unknown CSV column "secret"`,
+		},
+		{
+			name: "duplicate_column",
+			csv:  "app_package_name,regions,regions\n",
+			err:  "listed more than once",
+		},
+		{
+			name: "invalid_bool",
+			csv:  "app_package_name,regions,disabled\ncom.example.a,US,maybe\n",
+			err:  `line 2: invalid disabled "maybe"`,
+		},
+		{
+			name: "wrong_field_count",
+			csv:  "app_package_name,regions\ncom.example.a\n",
+			err:  "wrong number of fields",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseBulkAppsCSV(strings.NewReader(tc.csv))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestToAuthorizedApps(t *testing.T) {
+	t.Parallel()
+
+	has := []*vermodel.HealthAuthority{
+		{ID: 1, Issuer: "iss-a"},
+		{ID: 2, Issuer: "iss-b"},
+	}
+
+	cases := []struct {
+		name string
+		apps []*bulkApp
+		exp  []*model.AuthorizedApp
+		errs []string
+	}{
+		{
+			name: "valid",
+			apps: []*bulkApp{
+				{
+					AppPackageName:    "com.example.a",
+					Regions:           []string{"US"},
+					HealthAuthorities: []string{"iss-a", "2"},
+					SameDayKeyPolicy:  "release",
+					Disabled:          true,
+					TestData:          true,
+				},
+			},
+			exp: []*model.AuthorizedApp{
+				{
+					AppPackageName:            "com.example.a",
+					AllowedRegions:            map[string]struct{}{"US": {}},
+					AllowedHealthAuthorityIDs: map[int64]struct{}{1: {}, 2: {}},
+					SameDayKeyPolicy:          "RELEASE",
+					Disabled:                  true,
+					TestData:                  true,
+				},
+			},
+		},
+		{
+			name: "invalid",
+			apps: []*bulkApp{
+				{AppPackageName: "com.example.a", Regions: []string{"US"}, HealthAuthorities: []string{"iss-c", "3"}},
+				{AppPackageName: "com.example.b", SameDayKeyPolicy: "sometimes"},
+				{AppPackageName: "COM.example.a", Regions: []string{"US"}},
+				{Regions: []string{"US"}},
+			},
+			errs: []string{
+				`com.example.a: unknown health authority "iss-c"`,
+				`com.example.a: unknown health authority "3"`,
+				`com.example.b: invalid same day key policy "sometimes"`,
+				"com.example.b: Regions list cannot be empty",
+				"COM.example.a: listed more than once",
+				"#4: Health Authority ID cannot be empty",
+			},
+		},
+		{
+			name: "regions",
+			apps: []*bulkApp{
+				{AppPackageName: "com.example.a", Regions: []string{"us", " ca-on "}, HealthAuthorities: []string{"iss-a"}},
+				{AppPackageName: "com.example.b", Regions: []string{"USA", "US"}, HealthAuthorities: []string{"iss-a"}},
+			},
+			errs: []string{
+				`com.example.b: unknown region "USA", did you mean "US"?`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, errs := toAuthorizedApps(tc.apps, has, region.New(&region.Config{}))
+			if diff := cmp.Diff(tc.errs, errs); diff != "" {
+				t.Fatalf("errors mismatch (-want, +got):\n%s", diff)
+			}
+			if tc.exp == nil {
+				return
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderAuthorizedAppsBulk(t *testing.T) {
+	t.Parallel()
+
+	m := TemplateMap{}
+	m["form"] = bulkAppsFormData{Action: "import", CSV: "app_package_name,regions", Apps: "com.example.a"}
+	testRenderTemplate(t, "authorizedapps-bulk", m)
+}
+
+func TestHandleAuthorizedAppsBulkAPI(t *testing.T) {
+	t.Parallel()
+	ctx := project.TestContext(t)
+
+	env, s := newTestServer(t)
+	db := env.Database()
+
+	ha := &vermodel.HealthAuthority{Issuer: "iss-a", Audience: "aud", Name: "a"}
+	if err := verdb.New(db).AddHealthAuthority(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newHTTPServer(t, http.MethodPost, "/", s.HandleAuthorizedAppsBulkAPI())
+	body := `{"apps": [{"appPackageName": "com.example.bulk", "regions": ["US"], "healthAuthorities": ["iss-a"]}]}`
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Errorf("expected status %d to be %d", got, want)
+	}
+	mustFindStrings(t, resp, `"inserted":1`)
+
+	app, err := database.New(db).GetAuthorizedApp(ctx, "com.example.bulk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app == nil {
+		t.Fatal("expected app to be created")
+	}
+	if _, ok := app.AllowedHealthAuthorityIDs[ha.ID]; !ok {
+		t.Errorf("expected app to allow health authority %d, got %v", ha.ID, app.AllowedHealthAuthorityIDs)
+	}
+}
@@ -20,6 +20,9 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
+
+	abusemodel "github.com/google/exposure-notifications-server/internal/abuse/model"
 
 	"github.com/google/exposure-notifications-server/internal/authorizedapp/database"
 	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
@@ -36,6 +39,22 @@ func TestRenderAuthorizedApps(t *testing.T) {
 	testRenderTemplate(t, "authorizedapp", m)
 }
 
+func TestRenderAuthorizedApps_AbuseFindings(t *testing.T) {
+	t.Parallel()
+
+	m := TemplateMap{}
+	authorizedApp := model.NewAuthorizedApp()
+	authorizedApp.AppPackageName = "foo.bar"
+	authorizedApp.ThrottledUntil = time.Now().Add(time.Hour)
+	m["app"] = authorizedApp
+	m["throttled"] = true
+	m["abuseFindings"] = []*abusemodel.Finding{
+		{AppPackageName: "foo.bar", DetectedAt: time.Now(), Signal: abusemodel.SignalRepeatedPattern, Observed: 0.9, Detail: "same upload"},
+	}
+
+	testRenderTemplate(t, "authorizedapp", m)
+}
+
 func TestHandleAuthorizedAppsShow(t *testing.T) {
 	t.Parallel()
 	ctx := project.TestContext(t)
@@ -142,6 +161,16 @@ func TestHandleAuthorizedAppsSave(t *testing.T) {
 			},
 			want: []string{"foo.bar.app1"},
 		},
+		{
+			name: "create_unknown_region",
+			form: &authorizedAppFormData{
+				Action:         "save",
+				FormKey:        "",
+				AppPackageName: "foo.bar.app3",
+				AllowedRegions: "USA",
+			},
+			want: []string{`unknown region &#34;USA&#34;, did you mean &#34;US&#34;?`},
+		},
 		{
 			name: "update_unknown",
 			form: &authorizedAppFormData{
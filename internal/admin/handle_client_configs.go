@@ -0,0 +1,153 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/clientconfig/database"
+	"github.com/google/exposure-notifications-server/internal/clientconfig/model"
+)
+
+// HandleClientConfigsSave handles the create/update/delete actions for client
+// configurations. The app "0" creates a new configuration.
+func (s *Server) HandleClientConfigsSave() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form clientConfigFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		db := database.New(s.env.Database())
+		config := &model.ClientConfig{
+			AppPackageName: form.AppPackageName,
+		}
+		if app := c.Param("app"); app != "0" {
+			var err error
+			config, err = db.GetClientConfig(ctx, app)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Error loading client config: %v", err))
+				return
+			}
+		}
+
+		switch form.Action {
+		case "delete":
+			if err := db.DeleteClientConfig(ctx, config.AppPackageName); err != nil {
+				ErrorPage(c, fmt.Sprintf("Failed to delete client config: %v", err))
+				return
+			}
+			c.Redirect(http.StatusSeeOther, "/")
+			c.Abort()
+			return
+		case "save":
+			if err := form.PopulateClientConfig(config); err != nil {
+				ErrorPage(c, fmt.Sprintf("Invalid client config: %v", err))
+				return
+			}
+			if err := db.SaveClientConfig(ctx, config); err != nil {
+				ErrorPage(c, fmt.Sprintf("Error writing client config: %v", err))
+				return
+			}
+		default:
+			ErrorPage(c, "Invalid form action")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, "/client-configs/"+url.PathEscape(config.AppPackageName))
+		c.Abort()
+	}
+}
+
+// HandleClientConfigsShow handles the show action for client configurations.
+func (s *Server) HandleClientConfigsShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		config := &model.ClientConfig{}
+		if app := c.Param("app"); app != "0" {
+			var err error
+			config, err = database.New(s.env.Database()).GetClientConfig(ctx, app)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Error loading client config: %v", err))
+				return
+			}
+		}
+
+		m["config"] = config
+		renderHTML(c, http.StatusOK, "clientconfig", m)
+	}
+}
+
+type clientConfigFormData struct {
+	Action string `form:"action" binding:"required"`
+
+	// AppPackageName is only used for new configurations.
+	AppPackageName string `form:"app-package-name"`
+
+	RiskWeights string `form:"risk-weights"`
+	Endpoints   string `form:"endpoints"`
+	Features    string `form:"features"`
+}
+
+// PopulateClientConfig parses the name=value lines of the form into the
+// configuration.
+func (f *clientConfigFormData) PopulateClientConfig(c *model.ClientConfig) error {
+	weights, err := splitKeyValues(f.RiskWeights, "risk weight")
+	if err != nil {
+		return err
+	}
+	c.RiskWeights = nil
+	for name, raw := range weights {
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid risk weight %q: %w", name, err)
+		}
+		if c.RiskWeights == nil {
+			c.RiskWeights = make(map[string]float64, len(weights))
+		}
+		c.RiskWeights[name] = weight
+	}
+
+	c.Endpoints, err = splitKeyValues(f.Endpoints, "endpoint")
+	if err != nil {
+		return err
+	}
+
+	features, err := splitKeyValues(f.Features, "feature")
+	if err != nil {
+		return err
+	}
+	c.Features = nil
+	for name, raw := range features {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid feature %q: %w", name, err)
+		}
+		if c.Features == nil {
+			c.Features = make(map[string]bool, len(features))
+		}
+		c.Features[name] = enabled
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/clientconfig/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderClientConfigs(t *testing.T) {
+	t.Parallel()
+
+	for _, config := range []*model.ClientConfig{
+		{},
+		{
+			AppPackageName: "com.example.app",
+			RiskWeights:    map[string]float64{"attenuationNear": 1.5},
+			Endpoints:      map[string]string{"publish": "https://publish.example.com/v1/publish"},
+			Features:       map[string]bool{"selfReport": true},
+			Version:        2,
+			UpdatedAt:      time.Now().UTC(),
+		},
+	} {
+		m := TemplateMap{}
+		m["config"] = config
+		testRenderTemplate(t, "clientconfig", m)
+	}
+}
+
+func TestPopulateClientConfig(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		form *clientConfigFormData
+		want *model.ClientConfig
+		err  bool
+	}{
+		{
+			name: "valid",
+			form: &clientConfigFormData{
+				RiskWeights: "attenuationNear = 1.5\r\n\nreportTypeConfirmed=1",
+				Endpoints:   "publish=https://publish.example.com/v1/publish?a=b",
+				Features:    "selfReport=true\nchaff=false",
+			},
+			want: &model.ClientConfig{
+				RiskWeights: map[string]float64{"attenuationNear": 1.5, "reportTypeConfirmed": 1},
+				Endpoints:   map[string]string{"publish": "https://publish.example.com/v1/publish?a=b"},
+				Features:    map[string]bool{"selfReport": true, "chaff": false},
+			},
+		},
+		{
+			name: "empty",
+			form: &clientConfigFormData{},
+			want: &model.ClientConfig{},
+		},
+		{
+			name: "invalid_weight",
+			form: &clientConfigFormData{RiskWeights: "attenuationNear=high"},
+			err:  true,
+		},
+		{
+			name: "invalid_feature",
+			form: &clientConfigFormData{Features: "selfReport=maybe"},
+			err:  true,
+		},
+		{
+			name: "missing_value",
+			form: &clientConfigFormData{Endpoints: "publish"},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got model.ClientConfig
+			err := tc.form.PopulateClientConfig(&got)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, &got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+)
+
+// HandleExportAuditList lists the latest export audit of each location, and
+// the findings of the location selected by the bucket and root query
+// parameters.
+func (s *Server) HandleExportAuditList() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		exportDB := exportdatabase.New(s.env.Database())
+		audits, err := exportDB.ListAudits(ctx)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error loading export audits: %v", err))
+			return
+		}
+
+		bucket := strings.TrimSpace(c.Query("bucket"))
+		root := strings.TrimSpace(c.Query("root"))
+		if bucket != "" {
+			findings, err := exportDB.ListAuditFindings(ctx, bucket, root)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Error loading export audit findings: %v", err))
+				return
+			}
+			m["findings"] = findings
+		}
+
+		m["bucket"] = bucket
+		m["root"] = root
+		m["audits"] = audits
+		m.AddTitle("Export audit")
+		renderHTML(c, http.StatusOK, "export-audit", m)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+)
+
+func TestRenderExportAudit(t *testing.T) {
+	t.Parallel()
+
+	audits := []*model.ExportAudit{
+		{
+			BucketName:   "bucket",
+			FilenameRoot: "root",
+			ConfigIDs:    []int64{1, 2},
+			AuditedAt:    time.Now(),
+			Files:        10,
+			Objects:      11,
+			Counts: map[string]int{
+				model.AuditMissing:      0,
+				model.AuditOrphan:       1,
+				model.AuditSizeMismatch: 0,
+			},
+		},
+	}
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		m := TemplateMap{}
+		m["audits"] = audits
+		testRenderTemplate(t, "export-audit", m)
+	})
+
+	t.Run("findings", func(t *testing.T) {
+		t.Parallel()
+
+		m := TemplateMap{}
+		m["audits"] = audits
+		m["bucket"] = "bucket"
+		m["root"] = "root"
+		m["findings"] = []*model.ExportAuditFinding{
+			{ID: 1, Filename: "root/orphan.zip", Kind: model.AuditOrphan, ActualSize: 10, Repair: model.AuditRepairDeleted},
+		}
+		testRenderTemplate(t, "export-audit", m)
+	})
+}
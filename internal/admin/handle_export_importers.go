@@ -26,6 +26,10 @@ import (
 	"github.com/google/exposure-notifications-server/internal/exportimport/model"
 )
 
+// maxQuarantinedImportFiles is the number of the most recently quarantined
+// files shown for an export importer.
+const maxQuarantinedImportFiles = 50
+
 // HandleExportImportersSave handles the create/update actions for export
 // importers.
 func (s *Server) HandleExportImportersSave() func(c *gin.Context) {
@@ -50,6 +54,12 @@ func (s *Server) HandleExportImportersSave() func(c *gin.Context) {
 			ErrorPage(c, fmt.Sprintf("failed to build export importer config: %s", err))
 			return
 		}
+		region, err := s.regions.Normalize(record.Region)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("failed to build export importer config: %s", err))
+			return
+		}
+		record.Region = region
 
 		fn := db.AddConfig
 		if record.ID != 0 {
@@ -81,14 +91,20 @@ func (s *Server) HandleExportImportersShow() func(c *gin.Context) {
 			return
 		}
 
-		// Load public keys
+		// Load public keys and quarantined files
 		var publicKeys []*model.ImportFilePublicKey
+		var quarantined []*model.ImportFileQuarantine
 		if c.Param("id") != "0" {
 			publicKeys, err = db.AllPublicKeys(ctx, record)
 			if err != nil {
 				ErrorPage(c, fmt.Sprintf("Failed to load public keys: %s", err))
 				return
 			}
+			quarantined, err = db.ListQuarantinedImportFiles(ctx, record, maxQuarantinedImportFiles)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Failed to load quarantined files: %s", err))
+				return
+			}
 		}
 
 		m := make(TemplateMap)
@@ -96,7 +112,8 @@ func (s *Server) HandleExportImportersShow() func(c *gin.Context) {
 		m["model"] = record
 		m["keys"] = publicKeys
 		m["newkey"] = &model.ImportFilePublicKey{}
-		c.HTML(http.StatusOK, "export-importer", m)
+		m["quarantined"] = quarantined
+		renderHTML(c, http.StatusOK, "export-importer", m)
 		c.Abort()
 	}
 }
@@ -122,6 +139,7 @@ type exportImporterFormData struct {
 	ExportRoot string `form:"export-root"`
 	Region     string `form:"region"`
 	Travelers  bool   `form:"travelers"`
+	Strictness string `form:"strictness"`
 
 	// FromDate and FromTime are combined into FromTimestamp.
 	FromDate string `form:"from-date"`
@@ -158,6 +176,10 @@ func (f *exportImporterFormData) BuildExportImporterModel(c *model.ExportImport)
 
 	c.Traveler = f.Travelers
 
+	if val := f.Strictness; val != "" {
+		c.Strictness = val
+	}
+
 	if !from.IsZero() {
 		c.From = from
 	} else {
@@ -39,6 +39,26 @@ func TestRenderExportImporters(t *testing.T) {
 	testRenderTemplate(t, "export-importer", m)
 }
 
+func TestRenderExportImporters_Quarantined(t *testing.T) {
+	t.Parallel()
+
+	m := TemplateMap{}
+	m["model"] = &model.ExportImport{ID: 1}
+	m["newkey"] = &model.ImportFilePublicKey{}
+	m["quarantined"] = []*model.ImportFileQuarantine{
+		{
+			ImportFileID:   1,
+			ExportImportID: 1,
+			ZipFilename:    "https://example.com/a.zip",
+			Reason:         "compression_ratio",
+			Detail:         "export archive too large",
+			QuarantinedAt:  time.Now().UTC(),
+		},
+	}
+
+	testRenderTemplate(t, "export-importer", m)
+}
+
 func TestBuildExportImporterModel(t *testing.T) {
 	t.Parallel()
 
@@ -72,6 +92,16 @@ func TestBuildExportImporterModel(t *testing.T) {
 				Thru:       &thru,
 			},
 		},
+		{
+			name: "strictness",
+			form: &exportImporterFormData{
+				Strictness: model.StrictnessBatch,
+			},
+			exp: &model.ExportImport{
+				From:       time.Now().UTC().Add(1 * time.Minute),
+				Strictness: model.StrictnessBatch,
+			},
+		},
 		{
 			name: "bad_from",
 			form: &exportImporterFormData{
@@ -0,0 +1,89 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/export/database"
+)
+
+const (
+	// defaultPreviewHours and maxPreviewHours bound the window of an export
+	// preview.
+	defaultPreviewHours = 24
+	maxPreviewHours     = 14 * 24
+)
+
+// HandleExportsPreview handles the preview action for exports. It runs the
+// batching queries of the export config over the last hours, without writing
+// anything, and reports the batches and files it would produce.
+func (s *Server) HandleExportsPreview() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		hours, err := parsePreviewHours(c.Query("hours"))
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		db := database.New(s.env.Database())
+		record, err := s.getExportConfig(ctx, db, c.Param("id"))
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Failed to load export config: %s", err))
+			return
+		}
+		if record.ConfigID == 0 {
+			ErrorPage(c, "Save the export config before previewing it.")
+			return
+		}
+
+		preview, err := export.PreviewExportConfig(ctx, s.env.Database(), &s.config.ExportPreview,
+			record, time.Duration(hours)*time.Hour, time.Now().UTC())
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Failed to preview export config: %v", err))
+			return
+		}
+
+		m := TemplateMap{}
+		m["export"] = record
+		m["preview"] = preview
+		m["hours"] = hours
+		m.AddTitle(fmt.Sprintf("Preview of export config %d", record.ConfigID))
+		renderHTML(c, http.StatusOK, "export-preview", m)
+	}
+}
+
+// parsePreviewHours parses the window of a preview. An empty value is the
+// default window.
+func parsePreviewHours(raw string) (int, error) {
+	if raw == "" {
+		return defaultPreviewHours, nil
+	}
+	hours, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse hours %q: %w", raw, err)
+	}
+	if hours < 1 || hours > maxPreviewHours {
+		return 0, fmt.Errorf("hours must be between 1 and %d", maxPreviewHours)
+	}
+	return hours, nil
+}
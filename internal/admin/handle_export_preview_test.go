@@ -0,0 +1,107 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+)
+
+func TestRenderExportPreview(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 12, 10, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		preview *export.Preview
+		want    []string
+	}{
+		{
+			name: "empty",
+			preview: &export.Preview{
+				Start:          start,
+				End:            start.Add(2 * time.Hour),
+				Batches:        []*export.PreviewBatch{{Start: start, End: start.Add(time.Hour)}, {Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)}},
+				MissingRegions: []string{"US"},
+			},
+			want: []string{"would be empty", "<code>US</code>"},
+		},
+		{
+			name: "keys",
+			preview: &export.Preview{
+				Start: start,
+				End:   start.Add(2 * time.Hour),
+				Batches: []*export.PreviewBatch{
+					{Start: start, End: start.Add(time.Hour)},
+					{Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Keys: 3, Files: []int{2, 1}, PaddedTo: 10, Regions: []string{"CA", "US"}},
+				},
+				Regions: []string{"CA", "US"},
+			},
+			want: []string{"1 of the 2 batches have no keys", "2, 1", "padded to at least 10", "<code>CA, US</code>"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := TemplateMap{}
+			m["export"] = &model.ExportConfig{ConfigID: 1}
+			m["preview"] = tc.preview
+			m["hours"] = 2
+
+			got := testRenderTemplate(t, "export-preview", m)
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePreviewHours(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		raw  string
+		want int
+		err  bool
+	}{
+		{raw: "", want: defaultPreviewHours},
+		{raw: "6", want: 6},
+		{raw: "336", want: 336},
+		{raw: "0", err: true},
+		{raw: "337", err: true},
+		{raw: "day", err: true},
+	}
+
+	for _, tc := range cases {
+		got, err := parsePreviewHours(tc.raw)
+		if (err != nil) != tc.err {
+			t.Errorf("parsePreviewHours(%q): expected error %t, got %v", tc.raw, tc.err, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parsePreviewHours(%q) = %d, expected %d", tc.raw, got, tc.want)
+		}
+	}
+}
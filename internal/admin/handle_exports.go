@@ -54,6 +54,10 @@ func (s *Server) HandleExportsSave() func(c *gin.Context) {
 			ErrorPage(c, fmt.Sprintf("error processing export config: %v", err))
 			return
 		}
+		if err := s.normalizeExportRegions(record); err != nil {
+			ErrorPage(c, fmt.Sprintf("error processing export config: %v", err))
+			return
+		}
 
 		updateFn := db.AddExportConfig
 		if record.ConfigID != 0 {
@@ -96,7 +100,7 @@ func (s *Server) HandleExportsShow() func(c *gin.Context) {
 		m["export"] = record
 		m["usedSigInfos"] = usedSigInfos
 		m["siginfos"] = sigInfos
-		c.HTML(http.StatusOK, "export", m)
+		renderHTML(c, http.StatusOK, "export", m)
 	}
 }
 
@@ -134,6 +138,22 @@ type exportFormData struct {
 	ThruTime           string        `form:"thru-time"`
 	SigInfoIDs         []int64       `form:"sig-info"`
 	MaxRecordsOverride int           `form:"max-records-override"`
+	MaxBatchKeys       int           `form:"max-batch-keys"`
+	MinRecordsOverride string        `form:"min-records-override"`
+	SmallBatchPolicy   string        `form:"small-batch-policy"`
+	EncryptionKeyID    string        `form:"encryption-key-id"`
+	TestData           bool          `form:"test-data"`
+	CacheControl       string        `form:"cache-control"`
+	StorageClass       string        `form:"storage-class"`
+	ObjectMetadata     string        `form:"object-metadata"`
+
+	IncludeJurisdictions string `form:"include-jurisdictions"`
+	ExcludeJurisdictions string `form:"exclude-jurisdictions"`
+
+	ParentConfigID    int64  `form:"parent-config-id"`
+	FilterRegions     string `form:"filter-regions"`
+	FilterReportTypes string `form:"filter-report-types"`
+	ExcludeRevised    bool   `form:"exclude-revised"`
 }
 
 // splitRegions turns a string of regions (generally separated by newlines), and
@@ -150,6 +170,74 @@ func splitRegions(regions string) []string {
 	return ret
 }
 
+// splitJurisdictions is like splitRegions, but also upper cases each
+// jurisdiction to match the health authority jurisdiction.
+func splitJurisdictions(jurisdictions string) []string {
+	ret := splitRegions(jurisdictions)
+	for i, j := range ret {
+		ret[i] = strings.ToUpper(j)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// splitObjectMetadata parses key=value lines of object metadata. Blank lines
+// are skipped, and no lines is no metadata.
+func splitObjectMetadata(lines string) (map[string]string, error) {
+	return splitKeyValues(lines, "object metadata")
+}
+
+// splitKeyValues parses key=value lines, where what names the values in
+// errors. Blank lines are skipped, and no lines is a nil map.
+func splitKeyValues(lines, what string) (map[string]string, error) {
+	var ret map[string]string
+	for _, line := range strings.Split(lines, "\n") {
+		line = project.TrimSpaceAndNonPrintable(line)
+		if line == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %q, expected key=value", what, line)
+		}
+		if ret == nil {
+			ret = make(map[string]string)
+		}
+		ret[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return ret, nil
+}
+
+// normalizeExportRegions normalizes the regions of the export config, and
+// returns an error if any of them are unknown. A derived export has no output
+// region.
+func (s *Server) normalizeExportRegions(ec *model.ExportConfig) error {
+	if ec.OutputRegion != "" {
+		region, err := s.regions.Normalize(ec.OutputRegion)
+		if err != nil {
+			return fmt.Errorf("invalid output region: %w", err)
+		}
+		ec.OutputRegion = region
+	}
+
+	for _, regions := range []struct {
+		name string
+		list *[]string
+	}{
+		{"input", &ec.InputRegions},
+		{"exclude", &ec.ExcludeRegions},
+		{"filter", &ec.FilterRegions},
+	} {
+		normalized, err := s.regions.NormalizeAll(*regions.list)
+		if err != nil {
+			return fmt.Errorf("invalid %s regions: %w", regions.name, err)
+		}
+		sort.Strings(normalized)
+		*regions.list = normalized
+	}
+	return nil
+}
+
 func (f *exportFormData) PopulateExportConfig(ec *model.ExportConfig) error {
 	from, err := CombineDateAndTime(f.FromDate, f.FromTime)
 	if err != nil {
@@ -171,6 +259,8 @@ func (f *exportFormData) PopulateExportConfig(ec *model.ExportConfig) error {
 	ec.IncludeTravelers = f.IncludeTravelers
 	ec.OnlyNonTravelers = f.OnlyNonTravelers
 	ec.ExcludeRegions = splitRegions(f.ExcludeRegions)
+	ec.IncludeJurisdictions = splitJurisdictions(f.IncludeJurisdictions)
+	ec.ExcludeJurisdictions = splitJurisdictions(f.ExcludeJurisdictions)
 	ec.From = from
 	ec.Thru = thru
 	ec.SignatureInfoIDs = f.SigInfoIDs
@@ -179,6 +269,36 @@ func (f *exportFormData) PopulateExportConfig(ec *model.ExportConfig) error {
 	} else {
 		ec.MaxRecordsOverride = nil
 	}
+	// Unlike the other overrides, 0 is a valid minimum, so only a blank value
+	// means the system default.
+	if raw := project.TrimSpaceAndNonPrintable(f.MinRecordsOverride); raw != "" {
+		minRecords, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid min records override: %w", err)
+		}
+		ec.MinRecordsOverride = &minRecords
+	} else {
+		ec.MinRecordsOverride = nil
+	}
+	ec.SmallBatchPolicy = model.SmallBatchPolicy(strings.ToUpper(project.TrimSpaceAndNonPrintable(f.SmallBatchPolicy)))
+	ec.EncryptionKeyID = project.TrimSpaceAndNonPrintable(f.EncryptionKeyID)
+	if f.MaxBatchKeys > 0 {
+		ec.MaxBatchKeys = &f.MaxBatchKeys
+	} else {
+		ec.MaxBatchKeys = nil
+	}
+	ec.ParentConfigID = f.ParentConfigID
+	ec.FilterRegions = splitRegions(f.FilterRegions)
+	ec.FilterReportTypes = splitRegions(f.FilterReportTypes)
+	ec.ExcludeRevised = f.ExcludeRevised
+	ec.TestData = f.TestData
+	ec.CacheControl = project.TrimSpaceAndNonPrintable(f.CacheControl)
+	ec.StorageClass = project.TrimSpaceAndNonPrintable(f.StorageClass)
+	metadata, err := splitObjectMetadata(f.ObjectMetadata)
+	if err != nil {
+		return err
+	}
+	ec.ObjectMetadata = metadata
 
 	if limit := 10; len(ec.SignatureInfoIDs) > limit {
 		return fmt.Errorf("too many signing keys selected, there is a limit of %d", limit)
@@ -74,6 +74,7 @@ func TestPopulateExportConfig(t *testing.T) {
 
 	from := time.Unix(1609579380, 0).UTC()
 	thru := time.Unix(1641119640, 0).UTC()
+	zero := 0
 
 	cases := []struct {
 		name string
@@ -96,6 +97,12 @@ func TestPopulateExportConfig(t *testing.T) {
 				ThruDate:           "2022-01-02",
 				ThruTime:           "10:34",
 				MaxRecordsOverride: 10,
+				MaxBatchKeys:       500,
+				MinRecordsOverride: " 50 ",
+				SmallBatchPolicy:   " skip ",
+				EncryptionKeyID:    " projects/p/keys/partner ",
+
+				IncludeJurisdictions: "us\nca",
 			},
 			exp: &model.ExportConfig{
 				BucketName:         "bucket",
@@ -109,7 +116,141 @@ func TestPopulateExportConfig(t *testing.T) {
 				Thru:               thru,
 				SignatureInfoIDs:   nil,
 				MaxRecordsOverride: intPtr(10),
+				MaxBatchKeys:       intPtr(500),
+				MinRecordsOverride: intPtr(50),
+				SmallBatchPolicy:   model.SmallBatchSkip,
+				EncryptionKeyID:    "projects/p/keys/partner",
+
+				IncludeJurisdictions: []string{"CA", "US"},
+				ExcludeJurisdictions: []string{},
+
+				FilterRegions:     []string{},
+				FilterReportTypes: []string{},
+			},
+		},
+		{
+			name: "derived",
+			form: &exportFormData{
+				BucketName:        "partner-bucket",
+				FilenameRoot:      "partner",
+				FromDate:          "2021-01-02",
+				FromTime:          "09:23",
+				ParentConfigID:    3,
+				FilterRegions:     "US\nCA",
+				FilterReportTypes: "confirmed",
+				ExcludeRevised:    true,
+			},
+			exp: &model.ExportConfig{
+				BucketName:     "partner-bucket",
+				FilenameRoot:   "partner",
+				InputRegions:   []string{},
+				ExcludeRegions: []string{},
+				From:           from,
+
+				IncludeJurisdictions: []string{},
+				ExcludeJurisdictions: []string{},
+
+				ParentConfigID:    3,
+				FilterRegions:     []string{"CA", "US"},
+				FilterReportTypes: []string{"confirmed"},
+				ExcludeRevised:    true,
+			},
+		},
+		{
+			name: "test_data",
+			form: &exportFormData{
+				OutputRegion: "US",
+				BucketName:   "test-bucket",
+				FilenameRoot: "test",
+				FromDate:     "2021-01-02",
+				FromTime:     "09:23",
+				TestData:     true,
+			},
+			exp: &model.ExportConfig{
+				BucketName:     "test-bucket",
+				FilenameRoot:   "test",
+				OutputRegion:   "US",
+				InputRegions:   []string{},
+				ExcludeRegions: []string{},
+				From:           from,
+				TestData:       true,
+
+				IncludeJurisdictions: []string{},
+				ExcludeJurisdictions: []string{},
+
+				FilterRegions:     []string{},
+				FilterReportTypes: []string{},
+			},
+		},
+		{
+			name: "zero_min_records",
+			form: &exportFormData{
+				OutputRegion:       "US",
+				BucketName:         "bucket",
+				FilenameRoot:       "root",
+				FromDate:           "2021-01-02",
+				FromTime:           "09:23",
+				MinRecordsOverride: "0",
+			},
+			exp: &model.ExportConfig{
+				BucketName:         "bucket",
+				FilenameRoot:       "root",
+				OutputRegion:       "US",
+				InputRegions:       []string{},
+				ExcludeRegions:     []string{},
+				From:               from,
+				MinRecordsOverride: &zero,
+
+				IncludeJurisdictions: []string{},
+				ExcludeJurisdictions: []string{},
+
+				FilterRegions:     []string{},
+				FilterReportTypes: []string{},
+			},
+		},
+		{
+			name: "object_options",
+			form: &exportFormData{
+				OutputRegion:   "US",
+				BucketName:     "bucket",
+				FilenameRoot:   "root",
+				FromDate:       "2021-01-02",
+				FromTime:       "09:23",
+				CacheControl:   " public, max-age=3600 ",
+				StorageClass:   " NEARLINE ",
+				ObjectMetadata: "program = en\n\ntier=cdn\n",
+			},
+			exp: &model.ExportConfig{
+				BucketName:     "bucket",
+				FilenameRoot:   "root",
+				OutputRegion:   "US",
+				InputRegions:   []string{},
+				ExcludeRegions: []string{},
+				From:           from,
+				CacheControl:   "public, max-age=3600",
+				StorageClass:   "NEARLINE",
+				ObjectMetadata: map[string]string{"program": "en", "tier": "cdn"},
+
+				IncludeJurisdictions: []string{},
+				ExcludeJurisdictions: []string{},
+
+				FilterRegions:     []string{},
+				FilterReportTypes: []string{},
+			},
+		},
+		{
+			name: "bad_object_metadata",
+			form: &exportFormData{
+				ObjectMetadata: "program",
+			},
+			err: "invalid object metadata",
+		},
+		{
+			name: "bad_min_records",
+			form: &exportFormData{
+				MinRecordsOverride: "some",
 			},
+			err: "invalid min records override",
 		},
 		{
 			name: "bad_from",
@@ -0,0 +1,93 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+)
+
+// HandleExposuresShow shows the form to look up exposure keys.
+func (s *Server) HandleExposuresShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		m := TemplateMap{}
+		m.AddTitle("Look up keys")
+		renderHTML(c, http.StatusOK, "exposures", m)
+	}
+}
+
+// HandleExposuresLookup shows the stored exposures, and where they came from,
+// for the submitted exposure keys.
+func (s *Server) HandleExposuresLookup() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form exposureLookupFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+		m.AddTitle("Look up keys")
+		m["form"] = form
+
+		keys, err := parseExposureKeys(form.Keys)
+		if err != nil {
+			m.AddErrors(err.Error())
+			renderHTML(c, http.StatusOK, "exposures", m)
+			return
+		}
+
+		exposures, err := publishdb.New(s.env.Database()).LookupExposures(ctx, keys)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error looking up keys: %v", err))
+			return
+		}
+
+		m["results"] = exposureLookupResults(keys, exposures)
+		renderHTML(c, http.StatusOK, "exposures", m)
+	}
+}
+
+type exposureLookupFormData struct {
+	Keys string `form:"keys" binding:"required"`
+}
+
+// exposureLookupResult is one looked up key. Exposure and Provenance are nil
+// if the key is not stored.
+type exposureLookupResult struct {
+	Key        string
+	Exposure   *publishmodel.Exposure
+	Provenance *publishmodel.Provenance
+}
+
+// exposureLookupResults returns the results of the keys, in the order they
+// were submitted.
+func exposureLookupResults(keys []string, exposures map[string]*publishmodel.Exposure) []*exposureLookupResult {
+	results := make([]*exposureLookupResult, 0, len(keys))
+	for _, key := range keys {
+		result := &exposureLookupResult{Key: key}
+		if exp, ok := exposures[key]; ok {
+			result.Exposure = exp
+			result.Provenance = exp.Provenance()
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+)
+
+func TestExposureLookupResults(t *testing.T) {
+	t.Parallel()
+
+	importID, fileID := int64(7), int64(42)
+	exposures := map[string]*publishmodel.Exposure{
+		"AQEBAQEBAQEBAQEBAQEBAQ==": {
+			Regions:          []string{"US", "CA"},
+			ExportImportID:   &importID,
+			ImportFileID:     &fileID,
+			ProvenanceSource: publishmodel.ProvenanceExportImport,
+			OriginalRegions:  []string{"US"},
+		},
+	}
+
+	results := exposureLookupResults([]string{"AAAAAAAAAAAAAAAAAAAAAA==", "AQEBAQEBAQEBAQEBAQEBAQ=="}, exposures)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Exposure != nil || results[0].Provenance != nil {
+		t.Errorf("expected the first key to not be found, got %+v", results[0])
+	}
+	if got, want := results[1].Provenance.PartnerID, "7"; got != want {
+		t.Errorf("expected partner %q to be %q", got, want)
+	}
+
+	m := TemplateMap{}
+	m["form"] = exposureLookupFormData{Keys: "AAAAAAAAAAAAAAAAAAAAAA==\nAQEBAQEBAQEBAQEBAQEBAQ=="}
+	for _, r := range results {
+		if r.Exposure != nil {
+			r.Exposure.CreatedAt = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	m["results"] = results
+	got := testRenderTemplate(t, "exposures", m)
+	for _, want := range []string{"not found", "export-import", `href="/export-importers/7"`, "<td>42</td>", "US, CA"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected rendered page to contain %q", want)
+		}
+	}
+}
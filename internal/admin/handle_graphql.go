@@ -0,0 +1,64 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGraphQL serves the read-only GraphQL API over configuration and
+// statistics, so dashboards can compose their own queries. Requests must carry
+// one of the configured bearer tokens. The API is not available if no tokens
+// are configured.
+func (s *Server) HandleGraphQL() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if len(s.config.GraphQLTokens) == 0 {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		if !s.authenticateGraphQL(c.Request) {
+			c.Header("WWW-Authenticate", `Bearer realm="graphql"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"errors": []gin.H{{"message": "unauthorized"}}})
+			return
+		}
+
+		s.graphql.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// authenticateGraphQL returns true if the bearer token of the request is one
+// of the configured GraphQL tokens.
+func (s *Server) authenticateGraphQL(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+
+	// Compare with every token, so the time taken does not depend on which
+	// one matched.
+	match := false
+	for _, t := range s.config.GraphQLTokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			match = true
+		}
+	}
+	return match
+}
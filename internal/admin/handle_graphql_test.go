@@ -0,0 +1,164 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	verdb "github.com/google/exposure-notifications-server/internal/verification/database"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+)
+
+func TestHandleGraphQL_Auth(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(tokens ...string) *Server {
+		s := &Server{config: &Config{GraphQLTokens: tokens}}
+		schema, err := s.newGraphQLSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.graphql = schema
+		return s
+	}
+
+	cases := []struct {
+		name   string
+		server *Server
+		auth   string
+		body   string
+		want   int
+	}{
+		{"disabled", newServer(), "Bearer a", `{"query": "{ __typename }"}`, http.StatusNotFound},
+		{"missing", newServer("a", "b"), "", `{"query": "{ __typename }"}`, http.StatusUnauthorized},
+		{"wrong", newServer("a", "b"), "Bearer c", `{"query": "{ __typename }"}`, http.StatusUnauthorized},
+		{"not_bearer", newServer("a", "b"), "Basic b", `{"query": "{ __typename }"}`, http.StatusUnauthorized},
+		{"empty_token", newServer("", "b"), "Bearer ", `{"query": "{ __typename }"}`, http.StatusUnauthorized},
+		{"ok", newServer("a", "b"), "Bearer b", `{"query": "{ __typename }"}`, http.StatusOK},
+		{"mutation", newServer("a"), "Bearer a", `{"query": "mutation { x }"}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mux := gin.New()
+			mux.POST("/api/graphql", tc.server.HandleGraphQL())
+
+			r := httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", "application/json")
+			if tc.auth != "" {
+				r.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if got := w.Code; got != tc.want {
+				t.Errorf("expected status %d to be %d: %s", got, tc.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleGraphQL(t *testing.T) {
+	t.Parallel()
+	ctx := project.TestContext(t)
+
+	env, s := newTestServer(t)
+	s.config.GraphQLTokens = []string{"token"}
+	db := env.Database()
+
+	ha := &vermodel.HealthAuthority{Issuer: "iss-graphql", Audience: "aud", Name: "GraphQL HA"}
+	if err := verdb.New(db).AddHealthAuthority(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &model.AuthorizedApp{
+		AppPackageName:            "com.example.graphql",
+		AllowedRegions:            map[string]struct{}{"TEST": {}},
+		AllowedHealthAuthorityIDs: map[int64]struct{}{ha.ID: {}},
+		RequestSigningSecret:      "do-not-expose",
+	}
+	if err := database.New(db).InsertAuthorizedApp(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		query  string
+		status int
+		want   []string
+	}{
+		{
+			name:   "apps",
+			query:  `{ authorizedApps { appPackageName allowedRegions allowedHealthAuthorityIDs throttledUntil } }`,
+			status: http.StatusOK,
+			want: []string{
+				fmt.Sprintf(`{"appPackageName":"com.example.graphql","allowedRegions":["TEST"],"allowedHealthAuthorityIDs":[%d],"throttledUntil":null}`, ha.ID),
+			},
+		},
+		{
+			name:   "health_authority",
+			query:  fmt.Sprintf(`{ healthAuthority(id: %d) { name issuer keys { version } stats(hours: 24) { hour } } missing: healthAuthority(id: 0) { name } }`, ha.ID),
+			status: http.StatusOK,
+			want: []string{
+				`"healthAuthority":{"name":"GraphQL HA","issuer":"iss-graphql","keys":[],"stats":[]}`,
+				`"missing":null`,
+			},
+		},
+		{
+			name:   "no_secrets",
+			query:  `{ authorizedApps { requestSigningSecret } }`,
+			status: http.StatusBadRequest,
+			want:   []string{`unknown field \"requestSigningSecret\" on type AuthorizedApp`},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newHTTPServer(t, http.MethodPost, "/api/graphql", s.HandleGraphQL())
+			body := fmt.Sprintf(`{"query": %q}`, tc.query)
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/graphql", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer token")
+
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := resp.StatusCode, tc.status; got != want {
+				t.Errorf("expected status %d to be %d", got, want)
+			}
+			mustFindStrings(t, resp, tc.want...)
+		})
+	}
+}
@@ -17,14 +17,20 @@ package admin
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	pubmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/verification"
 	"github.com/google/exposure-notifications-server/internal/verification/database"
 	"github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 )
 
 // HandleHealthAuthoritySave handles the create/update actions for health
@@ -56,6 +62,16 @@ func (s *Server) HandleHealthAuthoritySave() func(c *gin.Context) {
 			}
 		}
 		form.PopulateHealthAuthority(healthAuthority)
+		if err := form.PopulateStage(healthAuthority); err != nil {
+			ErrorPage(c, fmt.Sprintf("Error changing stage: %v", err))
+			return
+		}
+
+		transitions, err := parseReportTypeTransitions(c.Request.PostForm)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error parsing report type transitions: %v", err))
+			return
+		}
 
 		// Decide if update or insert.
 		updateFn := haDB.AddHealthAuthority
@@ -66,6 +82,10 @@ func (s *Server) HandleHealthAuthoritySave() func(c *gin.Context) {
 			ErrorPage(c, fmt.Sprintf("Error writing health authority: %v", err))
 			return
 		}
+		if err := haDB.SetReportTypeTransitions(ctx, healthAuthority.ID, transitions); err != nil {
+			ErrorPage(c, fmt.Sprintf("Error writing report type transitions: %v", err))
+			return
+		}
 
 		m.AddSuccess(fmt.Sprintf("Updated Health Authority '%v'", healthAuthority.Issuer))
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/healthauthority/%d", healthAuthority.ID))
@@ -81,7 +101,9 @@ func (s *Server) HandleHealthAuthorityShow() func(c *gin.Context) {
 
 		healthAuthority := &model.HealthAuthority{
 			EnableStatsAPI: true, // default enabled.
+			Stage:          model.StagePending,
 		}
+		var transitions []*model.ReportTypeTransition
 		if IDParam := c.Param("id"); IDParam == "0" {
 			m["new"] = true
 		} else {
@@ -97,10 +119,56 @@ func (s *Server) HandleHealthAuthorityShow() func(c *gin.Context) {
 				ErrorPage(c, fmt.Sprintf("Unable to find requested health authority: %v. Error: %v", haID, err))
 				return
 			}
+			transitions, err = haDB.ListReportTypeTransitions(ctx, haID)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Unable to load report type transitions: %v", err))
+				return
+			}
+			embargoed, err := publishdb.New(s.env.Database()).CountEmbargoedExposures(ctx, haID, time.Now().UTC())
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Unable to count embargoed keys: %v", err))
+				return
+			}
+			m["embargoed"] = embargoed
 		}
 		m["ha"] = healthAuthority
-		m["hak"] = &model.HealthAuthorityKey{From: time.Now()} // For create form.
-		c.HTML(http.StatusOK, "healthauthority", m)
+		m["transitions"] = reportTypeTransitionRows(transitions)
+		m["hak"] = &model.HealthAuthorityKey{From: time.Now(), Stage: model.StageActive, Algorithm: model.AlgorithmES256} // For create form.
+		m["stages"] = model.Stages
+		m["algorithms"] = model.Algorithms
+		m["providers"] = verification.RegisteredProviders()
+		m["defaultProvider"] = verification.DefaultProvider
+		m["hmacSchemes"] = model.HMACSchemeNames
+		usedHMACSchemes := make(map[string]bool, len(healthAuthority.HMACSchemes))
+		for _, scheme := range healthAuthority.HMACSchemes {
+			usedHMACSchemes[scheme] = true
+		}
+		m["usedHMACSchemes"] = usedHMACSchemes
+		renderHTML(c, http.StatusOK, "healthauthority", m)
+	}
+}
+
+// HandleHealthAuthorityRelease releases the keys that are held by the health
+// authority's export delay, so they are exported early.
+func (s *Server) HandleHealthAuthorityRelease() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		haID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			ErrorPage(c, "Unable to parse `id` param")
+			return
+		}
+
+		// Released keys are exported in the batch that is currently open.
+		now := time.Now().UTC()
+		if _, err := publishdb.New(s.env.Database()).ReleaseEmbargoedExposures(ctx, haID, now.Truncate(time.Hour), now); err != nil {
+			ErrorPage(c, fmt.Sprintf("Error releasing embargoed keys: %v", err))
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/healthauthority/%d", haID))
+		c.Abort()
 	}
 }
 
@@ -140,7 +208,7 @@ func (s *Server) HandleHealthAuthorityKeys() func(c *gin.Context) {
 				ErrorPage(c, fmt.Sprintf("Error saving health authority key: %v", err))
 				return
 			}
-		} else if action == "revoke" || action == "reinstate" || action == "activate" {
+		} else if action == "revoke" || action == "reinstate" || action == "activate" || action == "promote" {
 			version := c.Param("version")
 
 			// find the key.
@@ -160,6 +228,8 @@ func (s *Server) HandleHealthAuthorityKeys() func(c *gin.Context) {
 				if hak.IsFuture() {
 					hak.From = time.Now()
 				}
+			} else if action == "promote" {
+				hak.Stage = hak.Stage.Next()
 			} else if action == "revoke" {
 				hak.Revoke()
 			} else {
@@ -187,6 +257,14 @@ type healthAuthorityFormData struct {
 	Name           string `form:"name"`
 	EnableStatsAPI bool   `form:"enable-stats-api"`
 	JwksURI        string `form:"jwks-uri"`
+	Jurisdiction   string `form:"jurisdiction"`
+
+	ExportDelayMinutes int `form:"export-delay-minutes"`
+
+	Stage string `form:"stage"`
+
+	VerificationProvider string   `form:"verification-provider"`
+	HMACSchemes          []string `form:"hmac-schemes"`
 }
 
 func (f *healthAuthorityFormData) PopulateHealthAuthority(ha *model.HealthAuthority) {
@@ -195,6 +273,127 @@ func (f *healthAuthorityFormData) PopulateHealthAuthority(ha *model.HealthAuthor
 	ha.Name = f.Name
 	ha.EnableStatsAPI = f.EnableStatsAPI
 	ha.SetJWKS(f.JwksURI)
+	ha.SetJurisdiction(f.Jurisdiction)
+	ha.ExportDelay = time.Duration(f.ExportDelayMinutes) * time.Minute
+	ha.VerificationProvider = project.TrimSpaceAndNonPrintable(f.VerificationProvider)
+	ha.HMACSchemes = nil
+	if len(f.HMACSchemes) > 0 {
+		ha.HMACSchemes = f.HMACSchemes
+	}
+}
+
+// PopulateStage moves the health authority to the stage in the form. New
+// health authorities without a stage start out pending.
+func (f *healthAuthorityFormData) PopulateStage(ha *model.HealthAuthority) error {
+	if f.Stage == "" {
+		if ha.Stage == "" {
+			ha.Stage = model.StagePending
+		}
+		return nil
+	}
+
+	stage, err := model.ParseStage(f.Stage)
+	if err != nil {
+		return err
+	}
+	return ha.SetStage(stage)
+}
+
+// reportTypes is the display order of report types in the transition matrix.
+var reportTypes = []string{
+	verifyapi.ReportTypeSelfReport,
+	verifyapi.ReportTypeClinical,
+	verifyapi.ReportTypeConfirmed,
+	verifyapi.ReportTypeNegative,
+}
+
+const (
+	transitionFormPrefix = "transition:"
+	transitionAllow      = "allow"
+	transitionDeny       = "deny"
+)
+
+// reportTypeTransitionRow is a single from/to pair in the report type
+// transition matrix form.
+type reportTypeTransitionRow struct {
+	From     string
+	To       string
+	Default  bool
+	Override string
+}
+
+// FormName is the name of the form field for this transition.
+func (r *reportTypeTransitionRow) FormName() string {
+	return transitionFormPrefix + r.From + ":" + r.To
+}
+
+// reportTypeTransitionRows builds the full transition matrix, annotated with
+// the defaults and the health authority's overrides.
+func reportTypeTransitionRows(overrides []*model.ReportTypeTransition) []*reportTypeTransitionRow {
+	rows := make([]*reportTypeTransitionRow, 0, len(reportTypes)*(len(reportTypes)-1))
+	for _, from := range reportTypes {
+		for _, to := range reportTypes {
+			if from == to {
+				continue
+			}
+			row := &reportTypeTransitionRow{
+				From:    from,
+				To:      to,
+				Default: pubmodel.ValidReportTypeTransition(from, to),
+			}
+			for _, o := range overrides {
+				if o.From == from && o.To == to {
+					row.Override = transitionDeny
+					if o.Allowed {
+						row.Override = transitionAllow
+					}
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// parseReportTypeTransitions parses the transition matrix form fields into
+// overrides. Fields that are blank use the default and are omitted.
+func parseReportTypeTransitions(form url.Values) ([]*model.ReportTypeTransition, error) {
+	var transitions []*model.ReportTypeTransition
+	for k, v := range form {
+		if !strings.HasPrefix(k, transitionFormPrefix) || len(v) == 0 || v[0] == "" {
+			continue
+		}
+
+		parts := strings.Split(strings.TrimPrefix(k, transitionFormPrefix), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid transition field %q", k)
+		}
+
+		t := &model.ReportTypeTransition{
+			From: parts[0],
+			To:   parts[1],
+		}
+		switch v[0] {
+		case transitionAllow:
+			t.Allowed = true
+		case transitionDeny:
+			t.Allowed = false
+		default:
+			return nil, fmt.Errorf("invalid value %q for transition %s to %s", v[0], t.From, t.To)
+		}
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+
+	sort.Slice(transitions, func(i, j int) bool {
+		if transitions[i].From != transitions[j].From {
+			return transitions[i].From < transitions[j].From
+		}
+		return transitions[i].To < transitions[j].To
+	})
+	return transitions, nil
 }
 
 type keyhealthAuthorityFormData struct {
@@ -204,6 +403,9 @@ type keyhealthAuthorityFormData struct {
 	FromTime string `form:"from-time"`
 	ThruDate string `form:"thru-date"`
 	ThruTime string `form:"thru-time"`
+	Stage    string `form:"stage"`
+
+	Algorithm string `form:"algorithm"`
 }
 
 func (f *keyhealthAuthorityFormData) FromTimestamp() (time.Time, error) {
@@ -228,6 +430,21 @@ func (f *keyhealthAuthorityFormData) PopulateHealthAuthorityKey(hak *model.Healt
 	hak.Thru = tTime
 	hak.PublicKeyPEM = strings.ReplaceAll(project.TrimSpaceAndNonPrintable(f.PEMBlock), "\r", "")
 
+	hak.Stage = model.StageActive
+	if f.Stage != "" {
+		if hak.Stage, err = model.ParseStage(f.Stage); err != nil {
+			return err
+		}
+	}
+
+	hak.Algorithm = model.AlgorithmES256
+	if f.Algorithm != "" {
+		hak.Algorithm = f.Algorithm
+	}
+	if _, err := hak.SigningMethod(); err != nil {
+		return err
+	}
+
 	_, err = hak.PublicKey()
 	return err
 }
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -25,6 +26,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/verification/database"
 	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
@@ -37,6 +39,7 @@ func TestRenderHealthAuthority(t *testing.T) {
 	hak := new(model.HealthAuthorityKey)
 	m["ha"] = ha
 	m["hak"] = hak
+	m["transitions"] = reportTypeTransitionRows(nil)
 
 	testRenderTemplate(t, "healthauthority", m)
 }
@@ -67,6 +70,36 @@ func TestPopulateHealthAuthority(t *testing.T) {
 				JwksURI:        stringPtr("https://foo.bar"),
 			},
 		},
+		{
+			name: "jurisdiction",
+			form: &healthAuthorityFormData{
+				Issuer:       "test-iss",
+				Audience:     "test-aud",
+				Name:         "test-ha",
+				Jurisdiction: " us ",
+			},
+			exp: &model.HealthAuthority{
+				Issuer:       "test-iss",
+				Audience:     "test-aud",
+				Name:         "test-ha",
+				Jurisdiction: "US",
+			},
+		},
+		{
+			name: "export_delay",
+			form: &healthAuthorityFormData{
+				Issuer:             "test-iss",
+				Audience:           "test-aud",
+				Name:               "test-ha",
+				ExportDelayMinutes: 120,
+			},
+			exp: &model.HealthAuthority{
+				Issuer:      "test-iss",
+				Audience:    "test-aud",
+				Name:        "test-ha",
+				ExportDelay: 2 * time.Hour,
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -86,6 +119,83 @@ func TestPopulateHealthAuthority(t *testing.T) {
 	}
 }
 
+func TestPopulateStage(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		stage string
+		from  model.Stage
+		exp   model.Stage
+		err   string
+	}{
+		{
+			name: "new_default",
+			exp:  model.StagePending,
+		},
+		{
+			name: "unchanged",
+			from: model.StageActive,
+			exp:  model.StageActive,
+		},
+		{
+			name:  "new_test",
+			stage: "test",
+			exp:   model.StageTest,
+		},
+		{
+			name:  "promote",
+			stage: "active",
+			from:  model.StageTest,
+			exp:   model.StageActive,
+		},
+		{
+			name:  "demote",
+			stage: "pending",
+			from:  model.StageActive,
+			exp:   model.StagePending,
+		},
+		{
+			name:  "skip_test",
+			stage: "active",
+			from:  model.StagePending,
+			err:   "cannot move from pending to active",
+		},
+		{
+			name:  "invalid",
+			stage: "live",
+			err:   "invalid stage",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ha := &model.HealthAuthority{Stage: tc.from}
+			form := &healthAuthorityFormData{Stage: tc.stage}
+			err := form.PopulateStage(ha)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatal(err)
+				}
+				if got, want := err.Error(), tc.err; !strings.Contains(got, want) {
+					t.Errorf("expected %q to contain %q", got, want)
+				}
+				return
+			}
+			if tc.err != "" {
+				t.Fatalf("expected error %q", tc.err)
+			}
+			if got, want := ha.Stage, tc.exp; got != want {
+				t.Errorf("expected stage %q to be %q", got, want)
+			}
+		})
+	}
+}
+
 func TestPopulateHealthAuthorityKey(t *testing.T) {
 	t.Parallel()
 
@@ -121,8 +231,44 @@ YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
 MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEml59itec9qzwVojreLXdPNRsUWzf
 YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
 -----END PUBLIC KEY-----`,
+				Stage:     model.StageActive,
+				Algorithm: model.AlgorithmES256,
 			},
 		},
+		{
+			name: "stage",
+			form: &keyhealthAuthorityFormData{
+				Version: "124",
+				PEMBlock: `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEml59itec9qzwVojreLXdPNRsUWzf
+YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
+-----END PUBLIC KEY-----`,
+				FromDate: "2021-01-02",
+				FromTime: "09:23",
+				Stage:    "test",
+			},
+			exp: &model.HealthAuthorityKey{
+				Version: "124",
+				From:    from,
+				PublicKeyPEM: `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEml59itec9qzwVojreLXdPNRsUWzf
+YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
+-----END PUBLIC KEY-----`,
+				Stage:     model.StageTest,
+				Algorithm: model.AlgorithmES256,
+			},
+		},
+		{
+			name: "bad_stage",
+			form: &keyhealthAuthorityFormData{
+				PEMBlock: `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEml59itec9qzwVojreLXdPNRsUWzf
+YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
+-----END PUBLIC KEY-----`,
+				Stage: "live",
+			},
+			err: "invalid stage",
+		},
 		{
 			name: "bad_from",
 			form: &keyhealthAuthorityFormData{
@@ -139,6 +285,17 @@ YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
 			},
 			err: "invalid thru time",
 		},
+		{
+			name: "bad_algorithm",
+			form: &keyhealthAuthorityFormData{
+				PEMBlock: `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEml59itec9qzwVojreLXdPNRsUWzf
+YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
+-----END PUBLIC KEY-----`,
+				Algorithm: "HS256",
+			},
+			err: "unsupported algorithm",
+		},
 	}
 
 	for _, tc := range cases {
@@ -168,6 +325,89 @@ YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
 	}
 }
 
+func TestReportTypeTransitionRows(t *testing.T) {
+	t.Parallel()
+
+	rows := reportTypeTransitionRows([]*model.ReportTypeTransition{
+		{From: "likely", To: "negative", Allowed: false},
+	})
+	if got, want := len(rows), 12; got != want {
+		t.Fatalf("expected %d rows to be %d", got, want)
+	}
+
+	for _, row := range rows {
+		if row.From == "likely" && row.To == "negative" {
+			if !row.Default {
+				t.Errorf("expected likely to negative to be allowed by default")
+			}
+			if got, want := row.Override, "deny"; got != want {
+				t.Errorf("expected override %q to be %q", got, want)
+			}
+			if got, want := row.FormName(), "transition:likely:negative"; got != want {
+				t.Errorf("expected form name %q to be %q", got, want)
+			}
+		} else if row.Override != "" {
+			t.Errorf("unexpected override for %s to %s", row.From, row.To)
+		}
+	}
+}
+
+func TestParseReportTypeTransitions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		form url.Values
+		want []*model.ReportTypeTransition
+		err  string
+	}{
+		{
+			name: "empty",
+			form: url.Values{"issuer": {"foo"}, "transition:likely:negative": {""}},
+		},
+		{
+			name: "overrides",
+			form: url.Values{
+				"transition:likely:negative":    {"deny"},
+				"transition:confirmed:negative": {"allow"},
+			},
+			want: []*model.ReportTypeTransition{
+				{From: "confirmed", To: "negative", Allowed: true},
+				{From: "likely", To: "negative", Allowed: false},
+			},
+		},
+		{
+			name: "bad_value",
+			form: url.Values{"transition:likely:negative": {"maybe"}},
+			err:  `invalid value "maybe"`,
+		},
+		{
+			name: "bad_report_type",
+			form: url.Values{"transition:bogus:negative": {"allow"}},
+			err:  "invalid from report type",
+		},
+		{
+			name: "bad_field",
+			form: url.Values{"transition:likely": {"allow"}},
+			err:  "invalid transition field",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseReportTypeTransitions(tc.form)
+			errcmp.MustMatch(t, err, tc.err)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestHandleHealthAuthorityShow(t *testing.T) {
 	t.Parallel()
 	ctx := project.TestContext(t)
@@ -18,11 +18,16 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	abusedb "github.com/google/exposure-notifications-server/internal/abuse/database"
 	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	clientconfigdatabase "github.com/google/exposure-notifications-server/internal/clientconfig/database"
 	exdb "github.com/google/exposure-notifications-server/internal/export/database"
 	exportimportdatabase "github.com/google/exposure-notifications-server/internal/exportimport/database"
 	mirrordatabase "github.com/google/exposure-notifications-server/internal/mirror/database"
+	quarantinedatabase "github.com/google/exposure-notifications-server/internal/quarantine/database"
+	travelruledatabase "github.com/google/exposure-notifications-server/internal/travelrule/database"
 	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	"github.com/google/exposure-notifications-server/pkg/database"
 )
 
 func (s *Server) HandleIndex() func(c *gin.Context) {
@@ -33,28 +38,50 @@ func (s *Server) HandleIndex() func(c *gin.Context) {
 		db := s.env.Database()
 
 		// Load authorized apps for index.
-		apps, err := aadb.New(db).ListAuthorizedApps(ctx)
+		// The lists are cut off, the list views have all of the entries.
+		first := &database.PageParams{Limit: indexListLimit}
+
+		apps, appsPage, err := aadb.New(db).SearchAuthorizedApps(ctx, first)
 		if err != nil {
 			ErrorPage(c, err.Error())
 			return
 		}
 		m["apps"] = apps
+		m["appsPage"] = appsPage
+
+		// Load unresolved abuse findings, so they are seen first.
+		findings, err := abusedb.New(db).ListUnresolvedFindings(ctx, "")
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+		m["abuseFindings"] = findings
+
+		// Load uploads waiting for review.
+		cohorts, err := quarantinedatabase.New(db).ListPendingCohorts(ctx)
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+		m["quarantineCohorts"] = cohorts
 
 		// Load health authorities.
-		has, err := hadb.New(db).ListAllHealthAuthoritiesWithoutKeys(ctx)
+		has, hasPage, err := hadb.New(db).SearchHealthAuthorities(ctx, first)
 		if err != nil {
 			ErrorPage(c, err.Error())
 			return
 		}
 		m["healthauthorities"] = has
+		m["healthauthoritiesPage"] = hasPage
 
 		// Load export configurations.
-		exports, err := exdb.New(db).GetAllExportConfigs(ctx)
+		exports, exportsPage, err := exdb.New(db).SearchExportConfigs(ctx, first)
 		if err != nil {
 			ErrorPage(c, err.Error())
 			return
 		}
 		m["exports"] = exports
+		m["exportsPage"] = exportsPage
 
 		// Load export importer configurations.
 		exportImporters, err := exportimportdatabase.New(db).ListConfigs(ctx)
@@ -65,12 +92,13 @@ func (s *Server) HandleIndex() func(c *gin.Context) {
 		m["exportImporters"] = exportImporters
 
 		// Load SignatureInfos
-		sigInfos, err := exdb.New(db).ListAllSignatureInfos(ctx)
+		sigInfos, sigInfosPage, err := exdb.New(db).SearchSignatureInfos(ctx, first)
 		if err != nil {
 			ErrorPage(c, err.Error())
 			return
 		}
 		m["siginfos"] = sigInfos
+		m["siginfosPage"] = sigInfosPage
 
 		// Load mirrors
 		mirrors, err := mirrordatabase.New(db).Mirrors(ctx)
@@ -80,7 +108,23 @@ func (s *Server) HandleIndex() func(c *gin.Context) {
 		}
 		m["mirrors"] = mirrors
 
+		// Load travel rules
+		travelRules, err := travelruledatabase.New(db).ListTravelRules(ctx)
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+		m["travelRules"] = travelRules
+
+		// Load client configurations
+		clientConfigs, err := clientconfigdatabase.New(db).ListClientConfigs(ctx)
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+		m["clientConfigs"] = clientConfigs
+
 		m.AddTitle("Exposure Notification Key Server - Admin Console")
-		c.HTML(http.StatusOK, "index", m)
+		renderHTML(c, http.StatusOK, "index", m)
 	}
 }
@@ -18,12 +18,15 @@ import (
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
+	abusemodel "github.com/google/exposure-notifications-server/internal/abuse/model"
 	authorizedappmodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
 	exportmodel "github.com/google/exposure-notifications-server/internal/export/model"
 	exportimportmodel "github.com/google/exposure-notifications-server/internal/exportimport/model"
 	mirrormodel "github.com/google/exposure-notifications-server/internal/mirror/model"
 	"github.com/google/exposure-notifications-server/internal/project"
+	quarantinemodel "github.com/google/exposure-notifications-server/internal/quarantine/model"
 	verificationmodel "github.com/google/exposure-notifications-server/internal/verification/model"
 )
 
@@ -37,6 +40,12 @@ func TestRenderIndex(t *testing.T) {
 	m["exportImporters"] = []*exportimportmodel.ExportImport{}
 	m["siginfos"] = []*exportmodel.SignatureInfo{}
 	m["mirrors"] = []*mirrormodel.Mirror{}
+	m["abuseFindings"] = []*abusemodel.Finding{
+		{AppPackageName: "foo.bar", DetectedAt: time.Now(), Signal: abusemodel.SignalVolume, Detail: "too many"},
+	}
+	m["quarantineCohorts"] = []*quarantinemodel.Cohort{
+		{ID: 1, AppPackageName: "foo.bar", CreatedAt: time.Now(), Score: 20, Reasons: []quarantinemodel.Reason{quarantinemodel.ReasonFutureKey}, Keys: 14},
+	}
 
 	testRenderTemplate(t, "index", m)
 }
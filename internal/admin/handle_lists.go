@@ -0,0 +1,144 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	exdb "github.com/google/exposure-notifications-server/internal/export/database"
+	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	"github.com/google/exposure-notifications-server/pkg/database"
+)
+
+// indexListLimit is the number of entries of each list on the index page.
+const indexListLimit = 10
+
+// HandleAuthorizedAppsList handles the list of authorized apps.
+func (s *Server) HandleAuthorizedAppsList() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		params := parsePageParams(c)
+		apps, page, err := aadb.New(s.env.Database()).SearchAuthorizedApps(c.Request.Context(), params)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Failed to list authorized apps: %v", err))
+			return
+		}
+
+		m := listTemplateMap(c, params, page, "name", "regions")
+		m["apps"] = apps
+		m.AddTitle("Authorized Health Authorities")
+		renderHTML(c, http.StatusOK, "apps", m)
+	}
+}
+
+// HandleHealthAuthoritiesList handles the list of health authorities.
+func (s *Server) HandleHealthAuthoritiesList() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		params := parsePageParams(c)
+		has, page, err := hadb.New(s.env.Database()).SearchHealthAuthorities(c.Request.Context(), params)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Failed to list health authorities: %v", err))
+			return
+		}
+
+		m := listTemplateMap(c, params, page, "issuer", "name", "jurisdiction")
+		m["healthauthorities"] = has
+		m.AddTitle("Verification Keys")
+		renderHTML(c, http.StatusOK, "healthauthorities", m)
+	}
+}
+
+// HandleExportsList handles the list of export configs.
+func (s *Server) HandleExportsList() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		params := parsePageParams(c)
+		exports, page, err := exdb.New(s.env.Database()).SearchExportConfigs(c.Request.Context(), params)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Failed to list export configs: %v", err))
+			return
+		}
+
+		m := listTemplateMap(c, params, page, "id", "region", "bucket")
+		m["exports"] = exports
+		m.AddTitle("Export Configurations")
+		renderHTML(c, http.StatusOK, "exports", m)
+	}
+}
+
+// HandleSignatureInfosList handles the list of signature infos.
+func (s *Server) HandleSignatureInfosList() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		params := parsePageParams(c)
+		sigInfos, page, err := exdb.New(s.env.Database()).SearchSignatureInfos(c.Request.Context(), params)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Failed to list signature infos: %v", err))
+			return
+		}
+
+		m := listTemplateMap(c, params, page, "key_id", "id", "expires")
+		m["siginfos"] = sigInfos
+		m.AddTitle("Signature Infos")
+		renderHTML(c, http.StatusOK, "siginfos", m)
+	}
+}
+
+// parsePageParams reads the search, sort and page from the query string. Sort
+// keys are checked by the database, so an unknown sort shows an error.
+func parsePageParams(c *gin.Context) *database.PageParams {
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	return &database.PageParams{
+		Search: c.Query("q"),
+		Sort:   c.Query("sort"),
+		Desc:   c.Query("order") == "desc",
+		Page:   page,
+		Limit:  limit,
+	}
+}
+
+// listTemplateMap returns the template data shared by the list views. The
+// first sort key is the default.
+func listTemplateMap(c *gin.Context, params *database.PageParams, page *database.Page, sorts ...string) TemplateMap {
+	sort := params.Sort
+	if sort == "" {
+		sort = sorts[0]
+	}
+
+	m := TemplateMap{}
+	m["search"] = params.Search
+	m["sort"] = sort
+	m["desc"] = params.Desc
+	m["sorts"] = sorts
+	m["page"] = page
+	if page.HasPrev() {
+		m["prevURL"] = pageURL(c, page.Number-1)
+	}
+	if page.HasNext() {
+		m["nextURL"] = pageURL(c, page.Number+1)
+	}
+	return m
+}
+
+// pageURL returns the URL of the request with the page number replaced.
+func pageURL(c *gin.Context, number int) string {
+	u := *c.Request.URL
+	q := u.Query()
+	q.Set("page", strconv.Itoa(number))
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,120 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	authorizedappmodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	exportmodel "github.com/google/exposure-notifications-server/internal/export/model"
+	verificationmodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderLists(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		key  string
+		data interface{}
+	}{
+		{"apps", "apps", []*authorizedappmodel.AuthorizedApp{{AppPackageName: "com.example.app", AllowedRegions: map[string]struct{}{"US": {}}}}},
+		{"healthauthorities", "healthauthorities", []*verificationmodel.HealthAuthority{{ID: 1, Issuer: "iss", Name: "Example"}}},
+		{"exports", "exports", []*exportmodel.ExportConfig{{ConfigID: 1, OutputRegion: "US", BucketName: "bucket"}}},
+		{"siginfos", "siginfos", []*exportmodel.SignatureInfo{{ID: 1, SigningKeyID: "310"}}},
+		{"empty", "apps", nil},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := TemplateMap{}
+			m["search"] = "ex"
+			m["sort"] = "name"
+			m["sorts"] = []string{"name", "regions"}
+			m["page"] = database.NewPage(&database.PageParams{Page: 2, Limit: 10}, 25)
+			m["prevURL"] = "/apps?page=1"
+			m["nextURL"] = "/apps?page=3"
+			m[tc.key] = tc.data
+
+			got := testRenderTemplate(t, tc.key, m)
+			for _, want := range []string{`value="ex"`, "Page 2 of 3, 25 total", `href="/apps?page=3"`} {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		url  string
+		want *database.PageParams
+	}{
+		{"empty", "/apps", &database.PageParams{}},
+		{"all", "/apps?q=foo&sort=regions&order=desc&page=2&limit=20", &database.PageParams{
+			Search: "foo", Sort: "regions", Desc: true, Page: 2, Limit: 20,
+		}},
+		{"invalid_numbers", "/apps?page=two&limit=", &database.PageParams{}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tc.url, nil)
+
+			if diff := cmp.Diff(tc.want, parsePageParams(c)); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListTemplateMap(t *testing.T) {
+	t.Parallel()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/exports?q=US&page=2", nil)
+
+	params := parsePageParams(c)
+	m := listTemplateMap(c, params, database.NewPage(params, 120), "id", "region")
+
+	if got, want := m["sort"], "id"; got != want {
+		t.Errorf("expected sort %q to be %q", got, want)
+	}
+	if got, want := m["prevURL"], "/exports?page=1&q=US"; got != want {
+		t.Errorf("expected prevURL %q to be %q", got, want)
+	}
+	if got, want := m["nextURL"], "/exports?page=3&q=US"; got != want {
+		t.Errorf("expected nextURL %q to be %q", got, want)
+	}
+}
@@ -113,7 +113,7 @@ func (s *Server) HandleMirrorsShow() func(c *gin.Context) {
 
 		m["mirror"] = mirror
 		m["mirrorFiles"] = mirrorFiles
-		c.HTML(http.StatusOK, "mirror", m)
+		renderHTML(c, http.StatusOK, "mirror", m)
 	}
 }
 
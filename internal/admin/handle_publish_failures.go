@@ -0,0 +1,84 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	capturedb "github.com/google/exposure-notifications-server/internal/capture/database"
+)
+
+// publishFailuresLimit is the number of captured failures that are listed.
+const publishFailuresLimit = 100
+
+// HandlePublishFailuresList lists the captured failed publish requests, newest
+// first, optionally for one app.
+func (s *Server) HandlePublishFailuresList() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		app := strings.TrimSpace(c.Query("app"))
+		captures, err := capturedb.New(s.env.Database()).ListCaptures(ctx, app, time.Now().UTC(), publishFailuresLimit)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error loading failed uploads: %v", err))
+			return
+		}
+
+		m["app"] = app
+		m["captures"] = captures
+		m.AddTitle("Failed uploads")
+		renderHTML(c, http.StatusOK, "publish-failures", m)
+	}
+}
+
+// HandlePublishFailuresShow shows a captured failed publish request.
+func (s *Server) HandlePublishFailuresShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			ErrorPage(c, "unable to parse `id` param.")
+			return
+		}
+
+		capture, err := capturedb.New(s.env.Database()).GetCapture(ctx, id, time.Now().UTC())
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error loading failed upload: %v", err))
+			return
+		}
+
+		if capture.Request != nil {
+			b, err := json.MarshalIndent(capture.Request, "", "  ")
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Error formatting failed upload: %v", err))
+				return
+			}
+			m["request"] = string(b)
+		}
+
+		m["capture"] = capture
+		m.AddTitle(fmt.Sprintf("Failed upload %d", capture.ID))
+		renderHTML(c, http.StatusOK, "publish-failure", m)
+	}
+}
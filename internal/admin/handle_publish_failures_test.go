@@ -0,0 +1,76 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/capture/model"
+)
+
+func TestRenderPublishFailures(t *testing.T) {
+	t.Parallel()
+
+	captures := []*model.Capture{
+		{
+			ID:                2,
+			CreatedAt:         time.Now(),
+			ExpiresAt:         time.Now().Add(time.Hour),
+			HealthAuthorityID: "foo.bar",
+			Platform:          "android",
+			Status:            400,
+			Code:              "bad_request",
+			ErrorMessage:      "invalid key length",
+			Request: &model.Request{
+				Keys:              []*model.Key{{Hash: model.HashKey("key"), Length: 3, IntervalNumber: 100, IntervalCount: 144}},
+				HealthAuthorityID: "foo.bar",
+			},
+		},
+		{
+			ID:           1,
+			CreatedAt:    time.Now(),
+			ExpiresAt:    time.Now().Add(time.Hour),
+			Status:       400,
+			Code:         "bad_request",
+			ErrorMessage: "error unmarshalling API call",
+		},
+	}
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		m := TemplateMap{}
+		m["app"] = ""
+		m["captures"] = captures
+		testRenderTemplate(t, "publish-failures", m)
+	})
+
+	for _, c := range captures {
+		c := c
+
+		t.Run(fmt.Sprintf("capture_%d", c.ID), func(t *testing.T) {
+			t.Parallel()
+
+			m := TemplateMap{}
+			m["capture"] = c
+			if c.Request != nil {
+				m["request"] = "{}"
+			}
+			testRenderTemplate(t, "publish-failure", m)
+		})
+	}
+}
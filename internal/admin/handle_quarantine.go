@@ -0,0 +1,100 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/quarantine/database"
+)
+
+// HandleQuarantineSave handles the release/reject actions for quarantined
+// uploads.
+func (s *Server) HandleQuarantineSave() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form quarantineFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			ErrorPage(c, "unable to parse `id` param.")
+			return
+		}
+
+		db := database.New(s.env.Database())
+		now := time.Now().UTC()
+
+		switch form.Action {
+		case "release":
+			// Released keys are exported in the batch that is currently open.
+			count, err := db.Release(ctx, id, now.Truncate(time.Hour))
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Failed to release quarantined upload: %v", err))
+				return
+			}
+			m.AddSuccess(fmt.Sprintf("Released %d keys from quarantined upload %d", count, id))
+		case "reject":
+			if err := db.Reject(ctx, id, now); err != nil {
+				ErrorPage(c, fmt.Sprintf("Failed to reject quarantined upload: %v", err))
+				return
+			}
+			m.AddSuccess(fmt.Sprintf("Rejected quarantined upload %d", id))
+		default:
+			ErrorPage(c, "Invalid form action")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/quarantine/%d", id))
+		c.Abort()
+	}
+}
+
+// HandleQuarantineShow handles the show action for quarantined uploads.
+func (s *Server) HandleQuarantineShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			ErrorPage(c, "unable to parse `id` param.")
+			return
+		}
+
+		cohort, err := database.New(s.env.Database()).GetCohort(ctx, id)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error loading quarantined upload: %v", err))
+			return
+		}
+
+		m["cohort"] = cohort
+		m.AddTitle(fmt.Sprintf("Quarantined upload %d", cohort.ID))
+		renderHTML(c, http.StatusOK, "quarantine", m)
+	}
+}
+
+type quarantineFormData struct {
+	Action string `form:"action" binding:"required"`
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/quarantine/model"
+)
+
+func TestRenderQuarantine(t *testing.T) {
+	t.Parallel()
+
+	reviewedAt := time.Now()
+	cases := []struct {
+		name   string
+		cohort *model.Cohort
+	}{
+		{
+			name: "pending",
+			cohort: &model.Cohort{
+				ID:             1,
+				AppPackageName: "foo.bar",
+				CreatedAt:      time.Now(),
+				Score:          20,
+				Reasons:        []model.Reason{model.ReasonFutureKey, model.ReasonOnsetOutlier},
+				Status:         model.StatusPending,
+				Keys:           14,
+			},
+		},
+		{
+			name: "released",
+			cohort: &model.Cohort{
+				ID:             2,
+				AppPackageName: "foo.bar",
+				CreatedAt:      time.Now(),
+				Score:          20,
+				Status:         model.StatusReleased,
+				ReviewedAt:     &reviewedAt,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := TemplateMap{}
+			m["cohort"] = tc.cohort
+			testRenderTemplate(t, "quarantine", m)
+		})
+	}
+}
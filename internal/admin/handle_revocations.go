@@ -0,0 +1,110 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+// HandleRevocationsShow shows the form to revoke exposure keys.
+func (s *Server) HandleRevocationsShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		m := TemplateMap{}
+		m.AddTitle("Revoke keys")
+		renderHTML(c, http.StatusOK, "revocations", m)
+	}
+}
+
+// HandleRevocationsSave revokes the submitted exposure keys.
+func (s *Server) HandleRevocationsSave() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form revocationFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+		m.AddTitle("Revoke keys")
+
+		keys, err := form.ParseKeys()
+		if err != nil {
+			m.AddErrors(err.Error())
+			m["form"] = form
+			renderHTML(c, http.StatusOK, "revocations", m)
+			return
+		}
+
+		// Revocations are exported in the batch that is currently open.
+		resp, err := publishdb.New(s.env.Database()).RevokeExposures(ctx, &publishdb.RevokeExposuresRequest{
+			Keys:      keys,
+			Reason:    strings.TrimSpace(form.Reason),
+			RevokedAt: time.Now().UTC().Truncate(time.Hour),
+		})
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error revoking keys: %v", err))
+			return
+		}
+
+		m.AddSuccess(fmt.Sprintf("Revoked %d keys and removed %d keys that were not yet exported", resp.Revoked, resp.Deleted))
+		renderHTML(c, http.StatusOK, "revocations", m)
+	}
+}
+
+type revocationFormData struct {
+	Keys   string `form:"keys" binding:"required"`
+	Reason string `form:"reason" binding:"required"`
+}
+
+// ParseKeys returns the base64 encoded exposure keys in the form. Keys are
+// separated by whitespace or commas.
+func (f *revocationFormData) ParseKeys() ([]string, error) {
+	return parseExposureKeys(f.Keys)
+}
+
+// parseExposureKeys returns the distinct base64 encoded exposure keys in s,
+// separated by whitespace or commas.
+func parseExposureKeys(s string) ([]string, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+
+	seen := make(map[string]struct{}, len(fields))
+	keys := make([]string, 0, len(fields))
+	for _, field := range fields {
+		b, err := base64.StdEncoding.DecodeString(field)
+		if err != nil || len(b) != verifyapi.KeyLength {
+			return nil, fmt.Errorf("invalid exposure key %q: must be %d base64 encoded bytes", field, verifyapi.KeyLength)
+		}
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		keys = append(keys, field)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no exposure keys provided")
+	}
+	return keys, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRevocationFormData_ParseKeys(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		keys string
+		exp  []string
+		err  bool
+	}{
+		{
+			name: "whitespace_and_commas",
+			keys: "AAAAAAAAAAAAAAAAAAAAAA==,\r\nAQEBAQEBAQEBAQEBAQEBAQ==  AgICAgICAgICAgICAgICAg==",
+			exp:  []string{"AAAAAAAAAAAAAAAAAAAAAA==", "AQEBAQEBAQEBAQEBAQEBAQ==", "AgICAgICAgICAgICAgICAg=="},
+		},
+		{
+			name: "duplicates",
+			keys: "AAAAAAAAAAAAAAAAAAAAAA==\nAAAAAAAAAAAAAAAAAAAAAA==",
+			exp:  []string{"AAAAAAAAAAAAAAAAAAAAAA=="},
+		},
+		{
+			name: "invalid_base64",
+			keys: "not-base64!",
+			err:  true,
+		},
+		{
+			name: "wrong_length",
+			keys: "AAAA",
+			err:  true,
+		},
+		{
+			name: "empty",
+			keys: " ,\n",
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			form := &revocationFormData{Keys: tc.keys, Reason: "lab error"}
+			got, err := form.ParseKeys()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderRevocations(t *testing.T) {
+	t.Parallel()
+
+	m := TemplateMap{}
+	m["form"] = &revocationFormData{Keys: "AAAA", Reason: "lab error"}
+	testRenderTemplate(t, "revocations", m)
+}
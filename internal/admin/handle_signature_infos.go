@@ -114,14 +114,14 @@ func (s *Server) HandleSignatureInfosShow() func(c *gin.Context) {
 			signer, err := s.env.GetSignerForKey(ctx, sigInfo.SigningKey)
 			if err != nil {
 				m.AddErrors(fmt.Sprintf("Failed to get key signer: %s", err))
-				c.HTML(http.StatusOK, "siginfo", m)
+				renderHTML(c, http.StatusOK, "siginfo", m)
 				return
 			}
 
 			x509EncodedPub, err := x509.MarshalPKIXPublicKey(signer.Public())
 			if err != nil {
 				m.AddErrors(fmt.Sprintf("Failed to marshal signer: %s", err))
-				c.HTML(http.StatusOK, "siginfo", m)
+				renderHTML(c, http.StatusOK, "siginfo", m)
 				return
 			}
 			pemEncodedPub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub})
@@ -131,13 +131,13 @@ func (s *Server) HandleSignatureInfosShow() func(c *gin.Context) {
 			sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
 			if err != nil {
 				m.AddErrors(fmt.Sprintf("Failed to digest message: %s", err))
-				c.HTML(http.StatusOK, "siginfo", m)
+				renderHTML(c, http.StatusOK, "siginfo", m)
 				return
 			}
 			m["signature"] = base64.StdEncoding.EncodeToString(sig)
 		}
 
-		c.HTML(http.StatusOK, "siginfo", m)
+		renderHTML(c, http.StatusOK, "siginfo", m)
 	}
 }
 
@@ -0,0 +1,131 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/travelrule/database"
+	"github.com/google/exposure-notifications-server/internal/travelrule/model"
+)
+
+// HandleTravelRulesSave handles the create/update/delete actions for travel
+// rules.
+func (s *Server) HandleTravelRulesSave() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form travelRuleFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		db := database.New(s.env.Database())
+		rule := &model.TravelRule{}
+		if idParam := c.Param("id"); idParam != "0" {
+			id, err := strconv.ParseInt(idParam, 10, 64)
+			if err != nil {
+				ErrorPage(c, "unable to parse `id` param.")
+				return
+			}
+			rule, err = db.GetTravelRule(ctx, id)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Error loading travel rule: %v", err))
+				return
+			}
+		}
+
+		switch form.Action {
+		case "delete":
+			if err := db.DeleteTravelRule(ctx, rule.ID); err != nil {
+				ErrorPage(c, fmt.Sprintf("Failed to delete travel rule: %v", err))
+				return
+			}
+			c.Redirect(http.StatusSeeOther, "/")
+			c.Abort()
+			return
+		case "save":
+			form.PopulateTravelRule(rule)
+
+			updateFn := db.AddTravelRule
+			if rule.ID != 0 {
+				updateFn = db.UpdateTravelRule
+			}
+			if err := updateFn(ctx, rule); err != nil {
+				ErrorPage(c, fmt.Sprintf("Error writing travel rule: %v", err))
+				return
+			}
+		default:
+			ErrorPage(c, "Invalid form action")
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/travel-rules/%d", rule.ID))
+		c.Abort()
+	}
+}
+
+// HandleTravelRulesShow handles the show action for travel rules.
+func (s *Server) HandleTravelRulesShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		rule := &model.TravelRule{
+			TravelersOnly:     true,
+			ApplyToExport:     true,
+			ApplyToFederation: true,
+		}
+		if idParam := c.Param("id"); idParam != "0" {
+			id, err := strconv.ParseInt(idParam, 10, 64)
+			if err != nil {
+				ErrorPage(c, "unable to parse `id` param.")
+				return
+			}
+			rule, err = database.New(s.env.Database()).GetTravelRule(ctx, id)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Error loading travel rule: %v", err))
+				return
+			}
+		}
+
+		m["rule"] = rule
+		renderHTML(c, http.StatusOK, "travelrule", m)
+	}
+}
+
+type travelRuleFormData struct {
+	Action string `form:"action" binding:"required"`
+
+	Name              string `form:"name"`
+	InputRegions      string `form:"input-regions"`
+	TravelersOnly     bool   `form:"travelers-only"`
+	OutputRegions     string `form:"output-regions"`
+	ApplyToExport     bool   `form:"apply-to-export"`
+	ApplyToFederation bool   `form:"apply-to-federation"`
+}
+
+func (f *travelRuleFormData) PopulateTravelRule(r *model.TravelRule) {
+	r.Name = f.Name
+	r.InputRegions = splitRegions(f.InputRegions)
+	r.TravelersOnly = f.TravelersOnly
+	r.OutputRegions = splitRegions(f.OutputRegions)
+	r.ApplyToExport = f.ApplyToExport
+	r.ApplyToFederation = f.ApplyToFederation
+}
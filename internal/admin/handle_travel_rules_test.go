@@ -0,0 +1,63 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/travelrule/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderTravelRules(t *testing.T) {
+	t.Parallel()
+
+	m := TemplateMap{}
+	m["rule"] = &model.TravelRule{
+		ID:            1,
+		Name:          "eu",
+		InputRegions:  []string{"DE", "FR"},
+		OutputRegions: []string{"DE", "FR"},
+	}
+
+	testRenderTemplate(t, "travelrule", m)
+}
+
+func TestPopulateTravelRule(t *testing.T) {
+	t.Parallel()
+
+	form := &travelRuleFormData{
+		Name:              "eu",
+		InputRegions:      "FR\nDE\n\n",
+		TravelersOnly:     true,
+		OutputRegions:     "IT\r\nDE",
+		ApplyToExport:     true,
+		ApplyToFederation: false,
+	}
+
+	var got model.TravelRule
+	form.PopulateTravelRule(&got)
+
+	want := model.TravelRule{
+		Name:          "eu",
+		InputRegions:  []string{"DE", "FR"},
+		TravelersOnly: true,
+		OutputRegions: []string{"DE", "IT"},
+		ApplyToExport: true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -18,15 +18,24 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/graphql"
+	"github.com/google/exposure-notifications-server/internal/i18n"
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/region"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 )
 
 // Server is the admin server.
 type Server struct {
-	config *Config
-	env    *serverenv.ServerEnv
+	config       *Config
+	env          *serverenv.ServerEnv
+	allowedCIDRs []netip.Prefix
+	catalog      *i18n.Catalog
+	regions      *region.Validator
+	graphql      *graphql.Schema
 }
 
 // NewServer makes a new admin console server.
@@ -35,10 +44,31 @@ func NewServer(config *Config, env *serverenv.ServerEnv) (*Server, error) {
 		return nil, fmt.Errorf("missing Database in server env")
 	}
 
-	return &Server{
-		config: config,
-		env:    env,
-	}, nil
+	allowedCIDRs, err := middleware.ParseCIDRs(config.AllowedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ALLOWED_CIDRS: %w", err)
+	}
+
+	catalog, err := i18n.Load(&config.I18n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load message catalogs: %w", err)
+	}
+
+	s := &Server{
+		config:       config,
+		env:          env,
+		allowedCIDRs: allowedCIDRs,
+		catalog:      catalog,
+		regions:      region.New(&config.Regions),
+	}
+
+	schema, err := s.newGraphQLSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build graphql schema: %w", err)
+	}
+	s.graphql = schema
+
+	return s, nil
 }
 
 func (s *Server) Routes(ctx context.Context) http.Handler {
@@ -50,22 +80,33 @@ func (s *Server) Routes(ctx context.Context) http.Handler {
 	mux := gin.Default()
 	mux.SetFuncMap(TemplateFuncMap)
 	mux.SetHTMLTemplate(tmpl)
+	mux.Use(s.requireAllowedNetwork())
+	mux.Use(s.populateLocale())
 
 	// Landing page.
 	mux.GET("/", s.HandleIndex())
 
 	// Authorized App Handling.
+	mux.GET("/apps", s.HandleAuthorizedAppsList())
 	mux.GET("/app", s.HandleAuthorizedAppsShow())
 	mux.POST("/app", s.HandleAuthorizedAppsSave())
+	mux.GET("/apps/bulk", s.HandleAuthorizedAppsBulkShow())
+	mux.POST("/apps/bulk", s.HandleAuthorizedAppsBulkSave())
+	mux.POST("/api/apps/bulk", s.HandleAuthorizedAppsBulkAPI())
+	mux.POST("/api/apps/disable", s.HandleAuthorizedAppsDisableAPI())
 
 	// HealthAuthority[Key] Handling.
+	mux.GET("/healthauthorities", s.HandleHealthAuthoritiesList())
 	mux.GET("/healthauthority/:id", s.HandleHealthAuthorityShow())
 	mux.POST("/healthauthority/:id", s.HandleHealthAuthoritySave())
+	mux.POST("/healthauthority/:id/release", s.HandleHealthAuthorityRelease())
 	mux.POST("/healthauthoritykey/:id/:action/:version", s.HandleHealthAuthorityKeys())
 
 	// Export Config Handling.
+	mux.GET("/exports", s.HandleExportsList())
 	mux.GET("/exports/:id", s.HandleExportsShow())
 	mux.POST("/exports/:id", s.HandleExportsSave())
+	mux.GET("/exports/:id/preview", s.HandleExportsPreview())
 
 	// Export importer configuration
 	mux.GET("/export-importers/:id", s.HandleExportImportersShow())
@@ -76,12 +117,73 @@ func (s *Server) Routes(ctx context.Context) http.Handler {
 	mux.GET("/mirrors/:id", s.HandleMirrorsShow())
 	mux.POST("/mirrors/:id", s.HandleMirrorsSave())
 
+	// Quarantined uploads.
+	mux.GET("/quarantine/:id", s.HandleQuarantineShow())
+	mux.POST("/quarantine/:id", s.HandleQuarantineSave())
+
+	// Failed publish requests.
+	mux.GET("/publish-failures", s.HandlePublishFailuresList())
+	mux.GET("/publish-failures/:id", s.HandlePublishFailuresShow())
+
+	// Export bucket audit.
+	mux.GET("/export-audit", s.HandleExportAuditList())
+
+	// Key lookup.
+	mux.GET("/exposures", s.HandleExposuresShow())
+	mux.POST("/exposures", s.HandleExposuresLookup())
+
+	// Key revocation.
+	mux.GET("/revocations", s.HandleRevocationsShow())
+	mux.POST("/revocations", s.HandleRevocationsSave())
+
+	// Travel rules.
+	mux.GET("/travel-rules/:id", s.HandleTravelRulesShow())
+	mux.POST("/travel-rules/:id", s.HandleTravelRulesSave())
+
+	// Client configurations.
+	mux.GET("/client-configs/:app", s.HandleClientConfigsShow())
+	mux.POST("/client-configs/:app", s.HandleClientConfigsSave())
+
 	// Signature Info.
+	mux.GET("/siginfos", s.HandleSignatureInfosList())
 	mux.GET("/siginfo/:id", s.HandleSignatureInfosShow())
 	mux.POST("/siginfo/:id", s.HandleSignatureInfosSave())
 
+	// Read-only GraphQL API.
+	mux.POST("/api/graphql", s.HandleGraphQL())
+
 	// Healthz.
 	mux.GET("/health", s.HandleHealthz())
 
 	return mux
 }
+
+// requireAllowedNetwork rejects requests from clients outside of the allowed
+// networks, if any are configured. The health check is always allowed so the
+// platform can probe the console.
+func (s *Server) requireAllowedNetwork() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(s.allowedCIDRs) == 0 || c.Request.URL.Path == "/health" {
+			c.Next()
+			return
+		}
+
+		addr, err := middleware.ClientIP(c.Request, s.config.TrustedProxyCount)
+		if err != nil || !middleware.CIDRsContain(s.allowedCIDRs, addr) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// populateLocale negotiates the locale of the request from the lang query
+// parameter or the Accept-Language header and stores it on the request context.
+func (s *Server) populateLocale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := s.catalog.Negotiate(c.Request)
+		c.Header("Content-Language", locale.Tag())
+		c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+		c.Next()
+	}
+}
@@ -17,8 +17,10 @@ package admin
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/google/exposure-notifications-server/internal/project"
 )
 
@@ -64,3 +66,49 @@ func TestServerRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_RequireAllowedNetwork(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{
+		config:       &Config{TrustedProxyCount: 1},
+		allowedCIDRs: []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")},
+	}
+
+	mux := gin.New()
+	mux.Use(s.requireAllowedNetwork())
+	mux.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	mux.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name string
+		path string
+		xff  string
+		want int
+	}{
+		{"allowed", "/", "203.0.113.7", http.StatusOK},
+		{"spoofed", "/", "203.0.113.7, 198.51.100.1", http.StatusForbidden},
+		{"denied", "/", "198.51.100.1", http.StatusForbidden},
+		{"missing", "/", "", http.StatusForbidden},
+		{"health", "/health", "198.51.100.1", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.xff != "" {
+				r.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.want; got != want {
+				t.Errorf("expected %d to be %d", got, want)
+			}
+		})
+	}
+}
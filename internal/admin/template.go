@@ -17,7 +17,10 @@ package admin
 import (
 	"fmt"
 	"html/template"
+	"strings"
 	"time"
+
+	"github.com/google/exposure-notifications-server/internal/i18n"
 )
 
 // TemplateFuncMap is the list of template functions.
@@ -26,6 +29,22 @@ var TemplateFuncMap = template.FuncMap{
 	"htmlDate":     timestampFormatter("2006-01-02"),
 	"htmlTime":     timestampFormatter("15:04"),
 	"htmlDatetime": timestampFormatter(time.UnixDate),
+	"join":         strings.Join,
+	"lang":         localeTag,
+	"t":            translate,
+}
+
+// translate returns the message for key in the locale, which is the "locale"
+// of the template data. Without a locale, the message is in English.
+func translate(locale interface{}, key string, args ...interface{}) string {
+	l, _ := locale.(*i18n.Locale)
+	return l.T(key, args...)
+}
+
+// localeTag returns the language tag of the locale.
+func localeTag(locale interface{}) string {
+	l, _ := locale.(*i18n.Locale)
+	return l.Tag()
 }
 
 // timestampFormatter returns a function that formats the given timestamp.
@@ -23,6 +23,12 @@ type Config struct {
 	// CacheDuration is the amount of time AuthorizedApp should be cached before
 	// being re-read from their provider.
 	CacheDuration time.Duration `env:"AUTHORIZED_APP_CACHE_DURATION,default=5m"`
+
+	// NotFoundCacheDuration is the amount of time an app that does not exist
+	// is remembered as not found, so that repeated requests for it don't each
+	// read the database. It is kept short so that new apps are picked up
+	// quickly.
+	NotFoundCacheDuration time.Duration `env:"AUTHORIZED_APP_NOT_FOUND_CACHE_DURATION,default=30s"`
 }
 
 // AuthorizedApp implements an interface for setup.
@@ -20,10 +20,11 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
 	"github.com/google/exposure-notifications-server/pkg/database"
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 // AuthorizedAppDB is a handle to database operations for authorized apps
@@ -51,12 +52,14 @@ func (aa *AuthorizedAppDB) InsertAuthorizedApp(ctx context.Context, m *model.Aut
 			INSERT INTO
 				AuthorizedApp
 				(app_package_name, allowed_regions,
-				allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token)
+				allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token,
+				detailed_publish_response, request_signing_secret, allowed_cidrs, same_day_key_policy, disabled, test_data)
 			VALUES
-				(LOWER($1), $2, $3, $4, $5)
+				(LOWER($1), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		`, m.AppPackageName, m.AllAllowedRegions(),
 			m.AllAllowedHealthAuthorityIDs(), m.BypassHealthAuthorityVerification,
-			m.BypassRevisionToken)
+			m.BypassRevisionToken, m.DetailedPublishResponse, m.RequestSigningSecret, allowedCIDRs(m),
+			m.SameDayKeyPolicy, m.Disabled, m.TestData)
 		if err != nil {
 			return fmt.Errorf("inserting authorizedapp: %w", err)
 		}
@@ -72,12 +75,15 @@ func (aa *AuthorizedAppDB) UpdateAuthorizedApp(ctx context.Context, priorKey str
 			SET
 				app_package_name = LOWER($1), allowed_regions = $2,
 				allowed_health_authority_ids = $3, bypass_health_authority_verification = $4,
-				bypass_revision_token = $5
+				bypass_revision_token = $5, detailed_publish_response = $6,
+				request_signing_secret = $7, allowed_cidrs = $8, same_day_key_policy = $9,
+				disabled = $10, test_data = $11
 			WHERE
-				LOWER(app_package_name) = LOWER($6)
+				LOWER(app_package_name) = LOWER($12)
 			`, m.AppPackageName, m.AllAllowedRegions(),
 			m.AllAllowedHealthAuthorityIDs(), m.BypassHealthAuthorityVerification,
-			m.BypassRevisionToken, priorKey)
+			m.BypassRevisionToken, m.DetailedPublishResponse, m.RequestSigningSecret, allowedCIDRs(m),
+			m.SameDayKeyPolicy, m.Disabled, m.TestData, priorKey)
 		if err != nil {
 			return fmt.Errorf("updating authorizedapp: %w", err)
 		}
@@ -121,7 +127,8 @@ func (aa *AuthorizedAppDB) ListAuthorizedApps(ctx context.Context) ([]*model.Aut
 		rows, err := tx.Query(ctx, `
 			SELECT
 				LOWER(app_package_name), allowed_regions,
-				allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token
+				allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token,
+				detailed_publish_response, request_signing_secret, allowed_cidrs, same_day_key_policy, disabled, test_data, throttled_until
 			FROM
 				AuthorizedApp
 			ORDER BY LOWER(app_package_name) ASC
@@ -151,16 +158,73 @@ func (aa *AuthorizedAppDB) ListAuthorizedApps(ctx context.Context) ([]*model.Aut
 	return apps, nil
 }
 
+// appListQuery searches authorized apps by name and allowed regions.
+var appListQuery = &database.ListQuery{
+	SearchColumns: []string{"app_package_name", "array_to_string(allowed_regions, ',')"},
+	SortColumns: map[string]string{
+		"name":    "LOWER(app_package_name)",
+		"regions": "array_to_string(allowed_regions, ',')",
+	},
+	DefaultSort: "name",
+	TieBreaker:  "LOWER(app_package_name)",
+}
+
+// SearchAuthorizedApps reads one page of the authorized apps, searched by app
+// package name and allowed regions. The sort keys are "name" and "regions".
+func (aa *AuthorizedAppDB) SearchAuthorizedApps(ctx context.Context, params *database.PageParams) ([]*model.AuthorizedApp, *database.Page, error) {
+	where, order, args, err := appListQuery.Build(params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search authorized apps: %w", err)
+	}
+
+	var apps []*model.AuthorizedApp
+	var total int64
+	if err := aa.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM AuthorizedApp `+where, args...).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT
+				LOWER(app_package_name), allowed_regions,
+				allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token,
+				detailed_publish_response, request_signing_secret, allowed_cidrs, same_day_key_policy, disabled, test_data, throttled_until
+			FROM
+				AuthorizedApp
+			`+where+`
+			`+order, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			app, err := scanOneAuthorizedApp(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			apps = append(apps, app)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, nil, fmt.Errorf("search authorized apps: %w", err)
+	}
+
+	return apps, database.NewPage(params, total), nil
+}
+
 // GetAuthorizedApp loads a single AuthorizedApp for the given name. If no row
 // exists, this returns nil.
 func (aa *AuthorizedAppDB) GetAuthorizedApp(ctx context.Context, name string) (*model.AuthorizedApp, error) {
 	var app *model.AuthorizedApp
 
-	if err := aa.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		row := tx.QueryRow(ctx, `
+	// This lookup is on the publish hot path, so it skips the transaction.
+	if err := aa.db.Read(ctx, func(q database.Querier) error {
+		row := q.QueryRow(ctx, `
 			SELECT
 				LOWER(app_package_name), allowed_regions,
-				allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token
+				allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token,
+				detailed_publish_response, request_signing_secret, allowed_cidrs, same_day_key_policy, disabled, test_data, throttled_until
 			FROM
 				AuthorizedApp
 			WHERE LOWER(app_package_name) = LOWER($1)
@@ -183,10 +247,12 @@ func scanOneAuthorizedApp(row pgx.Row) (*model.AuthorizedApp, error) {
 	config := model.NewAuthorizedApp()
 	var allowedRegions []string
 	var allowedHealthAuthorityIDs []int64
+	var throttledUntil *time.Time
 	if err := row.Scan(
 		&config.AppPackageName, &allowedRegions,
 		&allowedHealthAuthorityIDs, &config.BypassHealthAuthorityVerification,
-		&config.BypassRevisionToken,
+		&config.BypassRevisionToken, &config.DetailedPublishResponse,
+		&config.RequestSigningSecret, &config.AllowedCIDRs, &config.SameDayKeyPolicy, &config.Disabled, &config.TestData, &throttledUntil,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -194,6 +260,10 @@ func scanOneAuthorizedApp(row pgx.Row) (*model.AuthorizedApp, error) {
 		return nil, err
 	}
 
+	if throttledUntil != nil {
+		config.ThrottledUntil = *throttledUntil
+	}
+
 	// build the regions map
 	for _, r := range allowedRegions {
 		config.AllowedRegions[r] = struct{}{}
@@ -205,3 +275,12 @@ func scanOneAuthorizedApp(row pgx.Row) (*model.AuthorizedApp, error) {
 
 	return config, nil
 }
+
+// allowedCIDRs returns the app's allowed CIDRs, never nil, since the column
+// is not nullable.
+func allowedCIDRs(m *model.AuthorizedApp) []string {
+	if m.AllowedCIDRs == nil {
+		return []string{}
+	}
+	return m.AllowedCIDRs
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
 	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/errcmp"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -281,3 +282,64 @@ func TestListAuthorizedApps(t *testing.T) {
 		t.Errorf("mismatch (-want, +got):\n%s", diff)
 	}
 }
+
+func TestSearchAuthorizedApps(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	aadb := New(testDB)
+
+	for _, name := range []string{"com.example.apple", "com.example.banana", "org.other.cherry"} {
+		app := model.NewAuthorizedApp()
+		app.AppPackageName = name
+		app.AllowedRegions["US"] = struct{}{}
+		if name == "org.other.cherry" {
+			app.AllowedRegions = map[string]struct{}{"CA": {}}
+		}
+		if err := aadb.InsertAuthorizedApp(ctx, app); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		params    *database.PageParams
+		want      []string
+		wantTotal int64
+	}{
+		{"all", &database.PageParams{}, []string{"com.example.apple", "com.example.banana", "org.other.cherry"}, 3},
+		{"search_name", &database.PageParams{Search: "EXAMPLE"}, []string{"com.example.apple", "com.example.banana"}, 2},
+		{"search_region", &database.PageParams{Search: "ca"}, []string{"org.other.cherry"}, 1},
+		{"desc_page", &database.PageParams{Desc: true, Page: 2, Limit: 2}, []string{"com.example.apple"}, 3},
+		{"wildcard_literal", &database.PageParams{Search: "%"}, nil, 0},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			apps, page, err := aadb.SearchAuthorizedApps(ctx, tc.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, app := range apps {
+				got = append(got, app.AppPackageName)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+			if page.Total != tc.wantTotal {
+				t.Errorf("expected total %d to be %d", page.Total, tc.wantTotal)
+			}
+		})
+	}
+
+	if _, _, err := aadb.SearchAuthorizedApps(ctx, &database.PageParams{Sort: "bogus"}); err == nil {
+		t.Errorf("expected error for invalid sort")
+	}
+}
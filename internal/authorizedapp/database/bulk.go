@@ -0,0 +1,136 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// UpsertAuthorizedAppsResult is the result of UpsertAuthorizedApps.
+type UpsertAuthorizedAppsResult struct {
+	Inserted int
+	Updated  int
+}
+
+// UpsertAuthorizedApps creates or updates the given apps in a single
+// transaction. Either all apps are saved or none are. Existing apps keep their
+// request signing secret and allowed CIDRs, which are not managed in bulk.
+func (aa *AuthorizedAppDB) UpsertAuthorizedApps(ctx context.Context, apps []*model.AuthorizedApp) (*UpsertAuthorizedAppsResult, error) {
+	seen := make(map[string]struct{}, len(apps))
+	for _, m := range apps {
+		if errors := m.Validate(); len(errors) > 0 {
+			return nil, fmt.Errorf("AuthorizedApp %q invalid: %v", m.AppPackageName, strings.Join(errors, ", "))
+		}
+		name := strings.ToLower(m.AppPackageName)
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("AuthorizedApp %q is listed more than once", m.AppPackageName)
+		}
+		seen[name] = struct{}{}
+	}
+
+	var result UpsertAuthorizedAppsResult
+	if err := aa.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		for _, m := range apps {
+			updated, err := tx.Exec(ctx, `
+				UPDATE AuthorizedApp
+				SET
+					allowed_regions = $2, allowed_health_authority_ids = $3,
+					bypass_health_authority_verification = $4, bypass_revision_token = $5,
+					detailed_publish_response = $6, same_day_key_policy = $7, disabled = $8,
+					test_data = $9
+				WHERE
+					LOWER(app_package_name) = LOWER($1)
+				`, m.AppPackageName, m.AllAllowedRegions(), m.AllAllowedHealthAuthorityIDs(),
+				m.BypassHealthAuthorityVerification, m.BypassRevisionToken,
+				m.DetailedPublishResponse, m.SameDayKeyPolicy, m.Disabled, m.TestData)
+			if err != nil {
+				return fmt.Errorf("updating authorizedapp %q: %w", m.AppPackageName, err)
+			}
+			if updated.RowsAffected() > 0 {
+				result.Updated++
+				continue
+			}
+
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO
+					AuthorizedApp
+					(app_package_name, allowed_regions,
+					allowed_health_authority_ids, bypass_health_authority_verification, bypass_revision_token,
+					detailed_publish_response, request_signing_secret, allowed_cidrs, same_day_key_policy, disabled, test_data)
+				VALUES
+					(LOWER($1), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+				`, m.AppPackageName, m.AllAllowedRegions(),
+				m.AllAllowedHealthAuthorityIDs(), m.BypassHealthAuthorityVerification,
+				m.BypassRevisionToken, m.DetailedPublishResponse, m.RequestSigningSecret, allowedCIDRs(m),
+				m.SameDayKeyPolicy, m.Disabled, m.TestData); err != nil {
+				return fmt.Errorf("inserting authorizedapp %q: %w", m.AppPackageName, err)
+			}
+			result.Inserted++
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("upsert authorized apps: %w", err)
+	}
+	return &result, nil
+}
+
+// SetAuthorizedAppsDisabled disables or enables the named apps. It returns
+// the names that do not match any app, which are otherwise ignored.
+func (aa *AuthorizedAppDB) SetAuthorizedAppsDisabled(ctx context.Context, names []string, disabled bool) ([]string, error) {
+	lower := make([]string, 0, len(names))
+	for _, name := range names {
+		lower = append(lower, strings.ToLower(name))
+	}
+
+	found := make(map[string]struct{}, len(names))
+	if err := aa.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE AuthorizedApp
+			SET
+				disabled = $2
+			WHERE
+				LOWER(app_package_name) = ANY($1)
+			RETURNING LOWER(app_package_name)
+			`, lower, disabled)
+		if err != nil {
+			return fmt.Errorf("updating authorized apps: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			found[name] = struct{}{}
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("set authorized apps disabled: %w", err)
+	}
+
+	var missing []string
+	for _, name := range lower {
+		if _, ok := found[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUpsertAuthorizedApps(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	aadb := New(testDB)
+
+	existing := &model.AuthorizedApp{
+		AppPackageName:            "existing",
+		AllowedRegions:            map[string]struct{}{"US": {}},
+		AllowedHealthAuthorityIDs: map[int64]struct{}{},
+		RequestSigningSecret:      "projects/foo/secrets/bar",
+		AllowedCIDRs:              []string{"10.0.0.0/8"},
+	}
+	if err := aadb.InsertAuthorizedApp(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	apps := []*model.AuthorizedApp{
+		{
+			AppPackageName:            "Existing",
+			AllowedRegions:            map[string]struct{}{"CA": {}},
+			AllowedHealthAuthorityIDs: map[int64]struct{}{1: {}},
+			Disabled:                  true,
+			TestData:                  true,
+		},
+		{
+			AppPackageName:            "new",
+			AllowedRegions:            map[string]struct{}{"MX": {}},
+			AllowedHealthAuthorityIDs: map[int64]struct{}{},
+		},
+	}
+	result, err := aadb.UpsertAuthorizedApps(ctx, apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&UpsertAuthorizedAppsResult{Inserted: 1, Updated: 1}, result); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	got, err := aadb.GetAuthorizedApp(ctx, "existing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &model.AuthorizedApp{
+		AppPackageName:            "existing",
+		AllowedRegions:            map[string]struct{}{"CA": {}},
+		AllowedHealthAuthorityIDs: map[int64]struct{}{1: {}},
+		RequestSigningSecret:      "projects/foo/secrets/bar",
+		AllowedCIDRs:              []string{"10.0.0.0/8"},
+		Disabled:                  true,
+		TestData:                  true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Nothing is saved if any app is invalid.
+	_, err = aadb.UpsertAuthorizedApps(ctx, []*model.AuthorizedApp{
+		{AppPackageName: "other", AllowedRegions: map[string]struct{}{"US": {}}},
+		{AppPackageName: "invalid"},
+	})
+	errcmp.MustMatch(t, err, `AuthorizedApp "invalid" invalid`)
+
+	_, err = aadb.UpsertAuthorizedApps(ctx, []*model.AuthorizedApp{
+		{AppPackageName: "dup", AllowedRegions: map[string]struct{}{"US": {}}},
+		{AppPackageName: "DUP", AllowedRegions: map[string]struct{}{"US": {}}},
+	})
+	errcmp.MustMatch(t, err, "listed more than once")
+
+	if got, err := aadb.GetAuthorizedApp(ctx, "other"); err != nil || got != nil {
+		t.Errorf("expected app to not be saved, got %v, %v", got, err)
+	}
+}
+
+func TestSetAuthorizedAppsDisabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	aadb := New(testDB)
+
+	for _, name := range []string{"a", "b"} {
+		if err := aadb.InsertAuthorizedApp(ctx, &model.AuthorizedApp{
+			AppPackageName: name,
+			AllowedRegions: map[string]struct{}{"US": {}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	missing, err := aadb.SetAuthorizedAppsDisabled(ctx, []string{"A", "b", "c"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"c"}, missing); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	apps, err := aadb.ListAuthorizedApps(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, app := range apps {
+		if !app.Disabled {
+			t.Errorf("expected %q to be disabled", app.AppPackageName)
+		}
+	}
+
+	if _, err := aadb.SetAuthorizedAppsDisabled(ctx, []string{"a"}, false); err != nil {
+		t.Fatal(err)
+	}
+	app, err := aadb.GetAuthorizedApp(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Disabled {
+		t.Errorf("expected app to be enabled")
+	}
+}
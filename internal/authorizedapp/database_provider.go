@@ -25,18 +25,29 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/cache"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
+	"golang.org/x/sync/singleflight"
 )
 
 // Compile-time check to assert implementation.
 var _ Provider = (*DatabaseProvider)(nil)
 
 // DatabaseProvider is a Provider that pulls from the database and caches and
-// refreshes values on failure.
+// refreshes values on failure. Apps that are not found are cached for a
+// shorter time, so that a new app can be used soon after it is added.
 type DatabaseProvider struct {
-	database      *database.DB
-	cacheDuration time.Duration
+	database              *database.DB
+	cacheDuration         time.Duration
+	notFoundCacheDuration time.Duration
 
-	cache *cache.Cache[*model.AuthorizedApp]
+	cache    *cache.Cache[*model.AuthorizedApp]
+	notFound *cache.Cache[struct{}]
+
+	// group deduplicates concurrent database reads of the same app.
+	group singleflight.Group
+
+	// load reads an app from the database, or returns nil if it does not
+	// exist. It is replaced in tests.
+	load func(ctx context.Context, name string) (*model.AuthorizedApp, error)
 }
 
 // DatabaseProviderOption is used as input to the database provider.
@@ -44,15 +55,22 @@ type DatabaseProviderOption func(*DatabaseProvider) *DatabaseProvider
 
 // NewDatabaseProvider creates a new Provider that reads from a database.
 func NewDatabaseProvider(ctx context.Context, db *database.DB, config *Config, opts ...DatabaseProviderOption) (Provider, error) {
-	cache, err := cache.New[*model.AuthorizedApp](config.CacheDuration)
+	appCache, err := cache.New[*model.AuthorizedApp](config.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+	notFoundCache, err := cache.New[struct{}](config.NotFoundCacheDuration)
 	if err != nil {
 		return nil, fmt.Errorf("cache.New: %w", err)
 	}
 	provider := &DatabaseProvider{
-		database:      db,
-		cacheDuration: config.CacheDuration,
-		cache:         cache,
+		database:              db,
+		cacheDuration:         config.CacheDuration,
+		notFoundCacheDuration: config.NotFoundCacheDuration,
+		cache:                 appCache,
+		notFound:              notFoundCache,
 	}
+	provider.load = provider.loadAuthorizedAppFromDatabase
 
 	// Apply options.
 	for _, opt := range opts {
@@ -70,22 +88,42 @@ func (p *DatabaseProvider) AppConfig(ctx context.Context, name string) (*model.A
 	// cacher does not. To maximize cache hits, convert to lowercase.
 	name = strings.ToLower(name)
 
-	lookup := func() (*model.AuthorizedApp, error) {
-		// Load config.
-		config, err := p.loadAuthorizedAppFromDatabase(ctx, name)
+	if config, ok := p.cache.Lookup(name); ok {
+		return config, nil
+	}
+	if _, ok := p.notFound.Lookup(name); ok {
+		return nil, ErrAppNotFound
+	}
+
+	// Concurrent lookups of an app that is not cached share one database read.
+	// The read uses the context of the first caller, and errors are not
+	// cached, so a canceled read is retried by the next lookup.
+	v, err, _ := p.group.Do(name, func() (interface{}, error) {
+		config, err := p.load(ctx, name)
 		if err != nil {
 			return nil, fmt.Errorf("authorizedapp: %w", err)
 		}
+
+		if config == nil {
+			logger.Infof("authorizedapp: %v not found, caching for %s", name, p.notFoundCacheDuration)
+			if err := p.notFound.Set(name, struct{}{}); err != nil {
+				return nil, fmt.Errorf("authorizedapp: %w", err)
+			}
+			return nil, nil
+		}
+
 		logger.Infof("authorizedapp: loaded %v, caching for %s", name, p.cacheDuration)
+		if err := p.cache.Set(name, config); err != nil {
+			return nil, fmt.Errorf("authorizedapp: %w", err)
+		}
 		return config, nil
-	}
-	config, err := p.cache.WriteThruLookup(name, lookup)
-	// Indicates an error on the write thru lookup.
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Handle not found.
+	config, _ := v.(*model.AuthorizedApp)
 	if config == nil {
 		return nil, ErrAppNotFound
 	}
@@ -0,0 +1,147 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorizedapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func testDatabaseProvider(tb testing.TB, config *Config, load func(ctx context.Context, name string) (*model.AuthorizedApp, error)) *DatabaseProvider {
+	tb.Helper()
+
+	provider, err := NewDatabaseProvider(project.TestContext(tb), nil, config)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	p := provider.(*DatabaseProvider)
+	tb.Cleanup(func() {
+		p.cache.Stop()
+		p.notFound.Stop()
+	})
+	p.load = load
+	return p
+}
+
+func TestDatabaseProvider_AppConfig_Singleflight(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	var loads int32
+	release := make(chan struct{})
+	p := testDatabaseProvider(t, &Config{CacheDuration: time.Hour, NotFoundCacheDuration: time.Hour},
+		func(ctx context.Context, name string) (*model.AuthorizedApp, error) {
+			atomic.AddInt32(&loads, 1)
+			<-release
+			return nil, nil
+		})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.AppConfig(ctx, "com.example.Unknown"); !errors.Is(err, ErrAppNotFound) {
+				t.Errorf("expected %v, got %v", ErrAppNotFound, err)
+			}
+		}()
+	}
+	// Give the lookups time to join the database read before it returns.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// The app is now cached as not found.
+	if _, err := p.AppConfig(ctx, "com.example.UNKNOWN"); !errors.Is(err, ErrAppNotFound) {
+		t.Errorf("expected %v, got %v", ErrAppNotFound, err)
+	}
+	if got := atomic.LoadInt32(&loads); got != 1 {
+		t.Errorf("expected 1 database read, got %d", got)
+	}
+}
+
+func TestDatabaseProvider_AppConfig_NotFoundExpires(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	var loads int32
+	p := testDatabaseProvider(t, &Config{CacheDuration: time.Hour, NotFoundCacheDuration: time.Nanosecond},
+		func(ctx context.Context, name string) (*model.AuthorizedApp, error) {
+			// The app is added after the first lookup.
+			if atomic.AddInt32(&loads, 1) == 1 {
+				return nil, nil
+			}
+			app := model.NewAuthorizedApp()
+			app.AppPackageName = name
+			return app, nil
+		})
+
+	if _, err := p.AppConfig(ctx, "com.example.app"); !errors.Is(err, ErrAppNotFound) {
+		t.Fatalf("expected %v, got %v", ErrAppNotFound, err)
+	}
+	time.Sleep(time.Millisecond)
+
+	app, err := p.AppConfig(ctx, "com.example.app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := app.AppPackageName, "com.example.app"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Found apps are cached for the cache duration.
+	if _, err := p.AppConfig(ctx, "com.example.app"); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&loads); got != 2 {
+		t.Errorf("expected 2 database reads, got %d", got)
+	}
+}
+
+func TestDatabaseProvider_AppConfig_ErrorNotCached(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	errDatabase := errors.New("database unavailable")
+
+	var loads int32
+	p := testDatabaseProvider(t, &Config{CacheDuration: time.Hour, NotFoundCacheDuration: time.Hour},
+		func(ctx context.Context, name string) (*model.AuthorizedApp, error) {
+			if atomic.AddInt32(&loads, 1) == 1 {
+				return nil, errDatabase
+			}
+			return nil, nil
+		})
+
+	if _, err := p.AppConfig(ctx, "com.example.app"); !errors.Is(err, errDatabase) {
+		t.Errorf("expected %v, got %v", errDatabase, err)
+	}
+	if _, err := p.AppConfig(ctx, "com.example.app"); !errors.Is(err, ErrAppNotFound) {
+		t.Errorf("expected %v, got %v", ErrAppNotFound, err)
+	}
+	if got := atomic.LoadInt32(&loads); got != 2 {
+		t.Errorf("expected 2 database reads, got %d", got)
+	}
+}
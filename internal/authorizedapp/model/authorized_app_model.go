@@ -16,8 +16,11 @@
 package model
 
 import (
+	"fmt"
+	"net/netip"
 	"sort"
 	"strings"
+	"time"
 )
 
 // AuthorizedApp represents the configuration for a single exposure notification
@@ -39,6 +42,38 @@ type AuthorizedApp struct {
 	// If true - revision tokens will still be accepted and checked, but will not
 	// enforce correctness. They will still be generated as output.
 	BypassRevisionToken bool
+
+	// If true - publish responses include the number of keys that were
+	// inserted, revised, and dropped, for client telemetry.
+	DetailedPublishResponse bool
+
+	// RequestSigningSecret is the name of a secret in the secret manager. If
+	// set, publish requests must carry an HMAC signature of the body and a
+	// timestamp made with the secret's value.
+	RequestSigningSecret string
+
+	// AllowedCIDRs is the list of networks, in CIDR notation, that publish
+	// requests for this app must come from. If the list is empty, requests
+	// are accepted from any address.
+	AllowedCIDRs []string
+
+	// SameDayKeyPolicy overrides the publish server's policy for keys that are
+	// uploaded while they are still valid. If empty, the server's policy is
+	// used.
+	SameDayKeyPolicy string
+
+	// Disabled apps are rejected by the publish API as if they were not
+	// registered. The rest of the configuration is kept.
+	Disabled bool
+
+	// TestData marks every key uploaded by this app as test data, e.g. for
+	// synthetic uptake tests against the production stack. Test data is only
+	// exported by test export configs and never federated to regular partners.
+	TestData bool
+
+	// ThrottledUntil is set by the abuse detector to reject publish requests
+	// for a while. It is not edited with the rest of the app.
+	ThrottledUntil time.Time
 }
 
 // NewAuthorizedApp initializes an AuthorizedApp structure including
@@ -50,6 +85,12 @@ func NewAuthorizedApp() *AuthorizedApp {
 	}
 }
 
+// IsThrottled returns true if publish requests for the app are rejected at
+// the given time.
+func (c *AuthorizedApp) IsThrottled(now time.Time) bool {
+	return now.Before(c.ThrottledUntil)
+}
+
 // AllAllowedRegions returns a slice of all allowed region codes.
 func (c *AuthorizedApp) AllAllowedRegions() []string {
 	regions := []string{}
@@ -78,9 +119,35 @@ func (c *AuthorizedApp) Validate() []string {
 	if len(c.AllowedRegions) == 0 {
 		errors = append(errors, "Regions list cannot be empty")
 	}
+	for _, cidr := range c.AllowedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			errors = append(errors, fmt.Sprintf("Invalid CIDR %q", cidr))
+		}
+	}
 	return errors
 }
 
+// IsAllowedAddr returns true if the list of allowed CIDRs is empty or if one
+// of them contains the given address.
+func (c *AuthorizedApp) IsAllowedAddr(addr netip.Addr) bool {
+	if len(c.AllowedCIDRs) == 0 {
+		return true
+	}
+
+	for _, cidr := range c.AllowedCIDRs {
+		if p, err := netip.ParsePrefix(cidr); err == nil && p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CIDRsOnePerLine returns a string with all allowed CIDRs, one per line. This
+// is a utility method for the admin console.
+func (c *AuthorizedApp) CIDRsOnePerLine() string {
+	return strings.Join(c.AllowedCIDRs, "\n")
+}
+
 // RegionsOnePerLine returns a string with all authorized
 // regions, one per line. This is a utility method for the
 // admin console.
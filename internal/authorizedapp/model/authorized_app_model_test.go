@@ -15,8 +15,10 @@
 package model
 
 import (
+	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -39,6 +41,52 @@ func TestAuthorizedApp_IsAllowedRegion(t *testing.T) {
 	}
 }
 
+func TestAuthorizedApp_IsAllowedAddr(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewAuthorizedApp()
+	if ok := cfg.IsAllowedAddr(netip.MustParseAddr("192.0.2.1")); !ok {
+		t.Errorf("expected any address to be allowed without CIDRs")
+	}
+
+	cfg.AllowedCIDRs = []string{"192.0.2.0/24", "2001:db8::/32"}
+	for addr, want := range map[string]bool{
+		"192.0.2.1":    true,
+		"2001:db8::1":  true,
+		"198.51.100.1": false,
+		"2001:db9::1":  false,
+	} {
+		if got := cfg.IsAllowedAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("expected %s allowed to be %t", addr, want)
+		}
+	}
+
+	cfg.AllowedRegions = map[string]struct{}{"US": {}}
+	cfg.AppPackageName = "com.example"
+	cfg.AllowedCIDRs = []string{"192.0.2.0/24", "nope"}
+	if got, want := cfg.Validate(), []string{`Invalid CIDR "nope"`}; !cmp.Equal(got, want) {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func TestAuthorizedApp_IsThrottled(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	cfg := NewAuthorizedApp()
+	if cfg.IsThrottled(now) {
+		t.Errorf("expected new app not to be throttled")
+	}
+
+	cfg.ThrottledUntil = now.Add(time.Minute)
+	if !cfg.IsThrottled(now) {
+		t.Errorf("expected app to be throttled until %v", cfg.ThrottledUntil)
+	}
+	if cfg.IsThrottled(now.Add(time.Minute)) {
+		t.Errorf("expected throttle to end at %v", cfg.ThrottledUntil)
+	}
+}
+
 func TestAllAllowedRegions(t *testing.T) {
 	t.Parallel()
 
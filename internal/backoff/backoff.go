@@ -0,0 +1,130 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backoff retries outbound calls with jittered exponential backoff.
+// Each call site has its own Policy, which bounds the number of retries and
+// the time spent retrying, and is named in the retry metrics.
+package backoff
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"github.com/sethvargo/go-retry"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// jitterPercent is the jitter applied to each delay, as +/- percent.
+const jitterPercent = 10
+
+// Policy is the retry policy of a call site.
+type Policy struct {
+	// Name identifies the call site in logs and metrics, such as
+	// "blobstore_create".
+	Name string
+
+	// MaxRetries is the number of times a failed call is retried. Zero means
+	// the call is attempted once.
+	MaxRetries uint64
+
+	// Backoff is the delay before the first retry. It doubles after each
+	// retry. Zero means the call is attempted once.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between retries. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// Budget is the time after the first attempt in which retries may start.
+	// Zero means retries are only bounded by MaxRetries and the context.
+	Budget time.Duration
+
+	// Retryable reports whether an error is retried. If nil, all errors except
+	// context cancellations and deadlines are retried.
+	Retryable func(err error) bool
+}
+
+// Do calls f until it succeeds, returns an error that is not retryable, or the
+// retries or budget of the policy are spent. It returns the last error of f,
+// or the context error if the context is done while waiting to retry.
+func (p *Policy) Do(ctx context.Context, f func(ctx context.Context) error) error {
+	logger := logging.FromContext(ctx).Named("backoff")
+
+	mctx, err := tag.New(ctx, tag.Upsert(callSiteTagKey, p.Name))
+	if err != nil {
+		mctx = ctx
+	}
+
+	attempt := 0
+	err = retry.Do(ctx, p.backoff(), func(ctx context.Context) error {
+		attempt++
+		if attempt > 1 {
+			stats.Record(mctx, mRetries.M(1))
+		}
+
+		err := f(ctx)
+		result := observability.ResultOK
+		if err != nil {
+			result = observability.ResultNotOK
+		}
+		rctx, _ := tag.New(mctx, result)
+		stats.Record(rctx, mAttempts.M(1))
+
+		if err == nil || !p.retryable(err) {
+			return err
+		}
+
+		logger.Warnw("call failed", "call_site", p.Name, "attempt", attempt, "error", err)
+		return retry.RetryableError(err)
+	})
+
+	if err != nil && p.retryable(err) {
+		logger.Errorw("call failed, not retrying", "call_site", p.Name, "attempts", attempt, "error", err)
+		stats.Record(mctx, mExhausted.M(1))
+	}
+	return err
+}
+
+// backoff returns the delays of the policy. It must be built for each call,
+// since the budget starts when it is built.
+func (p *Policy) backoff() retry.Backoff {
+	if p.MaxRetries == 0 || p.Backoff <= 0 {
+		return retry.BackoffFunc(func() (time.Duration, bool) {
+			return 0, true
+		})
+	}
+
+	b := retry.WithJitterPercent(jitterPercent, retry.NewExponential(p.Backoff))
+	if p.MaxBackoff > 0 {
+		b = retry.WithCappedDuration(p.MaxBackoff, b)
+	}
+	b = retry.WithMaxRetries(p.MaxRetries, b)
+	if p.Budget > 0 {
+		b = retry.WithMaxDuration(p.Budget, b)
+	}
+	return b
+}
+
+func (p *Policy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.Retryable == nil {
+		return true
+	}
+	return p.Retryable(err)
+}
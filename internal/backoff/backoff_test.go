@@ -0,0 +1,160 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestPolicy_Do(t *testing.T) {
+	t.Parallel()
+
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+
+	cases := []struct {
+		name     string
+		policy   *Policy
+		errs     []error
+		want     error
+		attempts int
+	}{
+		{
+			name:     "success",
+			policy:   &Policy{MaxRetries: 3, Backoff: time.Millisecond},
+			attempts: 1,
+		},
+		{
+			name:     "retried_success",
+			policy:   &Policy{MaxRetries: 3, Backoff: time.Millisecond},
+			errs:     []error{errTransient, errTransient},
+			attempts: 3,
+		},
+		{
+			name:     "retries_spent",
+			policy:   &Policy{MaxRetries: 2, Backoff: time.Millisecond},
+			errs:     []error{errTransient, errTransient, errTransient, errTransient},
+			want:     errTransient,
+			attempts: 3,
+		},
+		{
+			name:     "no_retries",
+			policy:   &Policy{Backoff: time.Millisecond},
+			errs:     []error{errTransient},
+			want:     errTransient,
+			attempts: 1,
+		},
+		{
+			name:     "no_backoff",
+			policy:   &Policy{MaxRetries: 3},
+			errs:     []error{errTransient},
+			want:     errTransient,
+			attempts: 1,
+		},
+		{
+			name: "not_retryable",
+			policy: &Policy{
+				MaxRetries: 3,
+				Backoff:    time.Millisecond,
+				Retryable:  func(err error) bool { return !errors.Is(err, errPermanent) },
+			},
+			errs:     []error{errTransient, errPermanent},
+			want:     errPermanent,
+			attempts: 2,
+		},
+		{
+			name:     "context_error",
+			policy:   &Policy{MaxRetries: 3, Backoff: time.Millisecond},
+			errs:     []error{context.DeadlineExceeded},
+			want:     context.DeadlineExceeded,
+			attempts: 1,
+		},
+		{
+			name: "budget_spent",
+			// The delay is cut short to the budget, and no retry starts after it.
+			policy:   &Policy{MaxRetries: 100, Backoff: time.Hour, Budget: 100 * time.Millisecond},
+			errs:     []error{errTransient, errTransient, errTransient},
+			want:     errTransient,
+			attempts: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+
+			attempts := 0
+			err := tc.policy.Do(ctx, func(ctx context.Context) error {
+				attempts++
+				if attempts <= len(tc.errs) {
+					return tc.errs[attempts-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, err)
+			}
+			if attempts != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestPolicy_Do_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(project.TestContext(t))
+
+	policy := &Policy{MaxRetries: 3, Backoff: time.Hour}
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		cancel()
+		return errors.New("transient")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	policy := &Policy{MaxRetries: 5, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	b := policy.backoff()
+
+	wants := []time.Duration{100, 200, 300, 300, 300}
+	for i, want := range wants {
+		want *= time.Millisecond
+		got, stop := b.Next()
+		if stop {
+			t.Fatalf("retry %d: expected to continue", i+1)
+		}
+		if min, max := want*(100-jitterPercent)/100, want*(100+jitterPercent)/100; got < min || got > max {
+			t.Errorf("retry %d: expected delay in [%v, %v], got %v", i+1, min, max, got)
+		}
+	}
+	if _, stop := b.Next(); !stop {
+		t.Errorf("expected to stop after %d retries", len(wants))
+	}
+}
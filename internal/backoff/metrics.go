@@ -0,0 +1,60 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const metricPrefix = metrics.MetricRoot + "backoff"
+
+var (
+	mAttempts  = stats.Int64(metricPrefix+"/attempts", "attempts of a call", stats.UnitDimensionless)
+	mRetries   = stats.Int64(metricPrefix+"/retries", "retries of a call", stats.UnitDimensionless)
+	mExhausted = stats.Int64(metricPrefix+"/exhausted", "calls that failed after spending their retries", stats.UnitDimensionless)
+
+	callSiteTagKey = tag.MustNewKey("call_site")
+)
+
+func init() {
+	observability.CollectViews([]*view.View{
+		{
+			Name:        metricPrefix + "/attempts",
+			Description: "Number of attempts of a call, including retries",
+			Measure:     mAttempts,
+			TagKeys:     []tag.Key{callSiteTagKey, observability.ResultTagKey},
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/retries",
+			Description: "Number of retries of a call",
+			Measure:     mRetries,
+			TagKeys:     []tag.Key{callSiteTagKey},
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/exhausted",
+			Description: "Number of calls that failed after spending their retries or budget",
+			Measure:     mExhausted,
+			TagKeys:     []tag.Key{callSiteTagKey},
+			Aggregation: view.Count(),
+		},
+	}...)
+}
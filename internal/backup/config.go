@@ -17,6 +17,7 @@ package backup
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/observability"
@@ -35,6 +36,7 @@ var (
 type Config struct {
 	Database              database.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
 	SecretManager         secrets.Config
 
 	Port string `env:"PORT, default=8080"`
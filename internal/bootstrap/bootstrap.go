@@ -0,0 +1,278 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	exportmodel "github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	revisiondatabase "github.com/google/exposure-notifications-server/internal/revision/database"
+	"github.com/google/exposure-notifications-server/migrations"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+
+	// imported to register the "postgres" database driver for migrate.
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Result is the state that Bootstrap created or found.
+type Result struct {
+	// RevisionTokenKeyID is the key that wraps the revision keys, to set as
+	// REVISION_TOKEN_KEY_ID.
+	RevisionTokenKeyID string
+
+	// RevisionTokenAAD is a generated value for REVISION_TOKEN_AAD. It is only
+	// set if REVISION_TOKEN_AAD is not configured.
+	RevisionTokenAAD []byte
+
+	// RevisionKeyID is the effective revision key.
+	RevisionKeyID int64
+
+	// SignatureInfoID and ExportConfigID are the export signature info and
+	// config, if an export was configured.
+	SignatureInfoID int64
+	ExportConfigID  int64
+
+	// Created lists the state that was created, as opposed to found.
+	Created []string
+}
+
+func (r *Result) created(format string, args ...interface{}) {
+	r.Created = append(r.Created, fmt.Sprintf(format, args...))
+}
+
+// Migrate runs the up migrations that are built into the binary against the
+// database at the given connection URL.
+func Migrate(connectionURL string, lockTimeout time.Duration) error {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, connectionURL)
+	if err != nil {
+		return fmt.Errorf("failed create migrate: %w", err)
+	}
+	m.LockTimeout = lockTimeout
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed run migrate: %w", err)
+	}
+	srcErr, dbErr := m.Close()
+	if srcErr != nil {
+		return fmt.Errorf("migrate source error: %w", srcErr)
+	}
+	if dbErr != nil {
+		return fmt.Errorf("migrate database error: %w", dbErr)
+	}
+	return nil
+}
+
+// Bootstrap creates the revision keys and, if an export is configured, the
+// export signing key, signature info, and export config. The database must
+// already be migrated.
+func Bootstrap(ctx context.Context, cfg *Config, db *database.DB, km keys.KeyManager) (*Result, error) {
+	var result Result
+
+	if err := result.revisionKeys(ctx, cfg, db, km); err != nil {
+		return nil, err
+	}
+
+	if cfg.ExportRegion == "" || cfg.ExportBucket == "" {
+		logging.FromContext(ctx).Infow("skipping export config, BOOTSTRAP_EXPORT_REGION and BOOTSTRAP_EXPORT_BUCKET are not both set")
+		return &result, nil
+	}
+	if err := result.export(ctx, cfg, db, km); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// revisionKeys creates the key that wraps the revision keys, if it does not
+// have a version, and the first revision key.
+func (r *Result) revisionKeys(ctx context.Context, cfg *Config, db *database.DB, km keys.KeyManager) error {
+	keyID := cfg.RevisionToken.KeyID
+	if keyID == "" {
+		ekm, ok := km.(keys.EncryptionKeyManager)
+		if !ok {
+			return fmt.Errorf("key manager %T can't create keys, create the revision token key and set REVISION_TOKEN_KEY_ID", km)
+		}
+		id, err := ekm.CreateEncryptionKey(ctx, cfg.KeyRing, cfg.RevisionKeyName)
+		if err != nil {
+			return fmt.Errorf("failed to create revision token key: %w", err)
+		}
+		keyID = id
+	}
+	r.RevisionTokenKeyID = keyID
+
+	// A key without a version can't encrypt.
+	if _, err := km.Encrypt(ctx, keyID, []byte("bootstrap"), []byte("bootstrap")); err != nil {
+		creator, ok := km.(keys.KeyVersionCreator)
+		if !ok {
+			return fmt.Errorf("revision token key %q is not usable: %w", keyID, err)
+		}
+		if _, err := creator.CreateKeyVersion(ctx, keyID); err != nil {
+			return fmt.Errorf("failed to create revision token key version: %w", err)
+		}
+		r.created("revision token key version of %s", keyID)
+	}
+
+	if len(cfg.RevisionToken.AAD) == 0 {
+		aad := make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, aad); err != nil {
+			return fmt.Errorf("failed to generate revision token AAD: %w", err)
+		}
+		r.RevisionTokenAAD = aad
+	}
+
+	revisionDB, err := revisiondatabase.New(db, &revisiondatabase.KMSConfig{
+		WrapperKeyID: keyID,
+		KeyManager:   km,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create revision database: %w", err)
+	}
+	effectiveID, _, err := revisionDB.GetAllowedRevisionKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read revision keys: %w", err)
+	}
+	if effectiveID == 0 {
+		key, err := revisionDB.CreateRevisionKey(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create revision key: %w", err)
+		}
+		effectiveID = key.KeyID
+		r.created("revision key %d", effectiveID)
+	}
+	r.RevisionKeyID = effectiveID
+	return nil
+}
+
+// exportSigningKey creates the export signing key, if it does not have a
+// version, and returns the ID of its first version.
+func (r *Result) exportSigningKey(ctx context.Context, cfg *Config, km keys.KeyManager) (string, error) {
+	skm, ok := km.(keys.SigningKeyManager)
+	if !ok {
+		return "", fmt.Errorf("key manager %T can't create keys, create the export signing key and set BOOTSTRAP_EXPORT_SIGNING_KEY", km)
+	}
+	parent, err := skm.CreateSigningKey(ctx, cfg.KeyRing, cfg.ExportSigningKeyName)
+	if err != nil {
+		return "", fmt.Errorf("failed to create export signing key: %w", err)
+	}
+	versions, err := skm.SigningKeyVersions(ctx, parent)
+	if err != nil {
+		return "", fmt.Errorf("failed to list export signing key versions: %w", err)
+	}
+	if len(versions) == 0 {
+		if _, err := skm.CreateKeyVersion(ctx, parent); err != nil {
+			return "", fmt.Errorf("failed to create export signing key version: %w", err)
+		}
+		r.created("export signing key version of %s", parent)
+
+		if versions, err = skm.SigningKeyVersions(ctx, parent); err != nil {
+			return "", fmt.Errorf("failed to list export signing key versions: %w", err)
+		}
+		if len(versions) == 0 {
+			return "", fmt.Errorf("no versions for export signing key %q", parent)
+		}
+	}
+	return versions[0].KeyID(), nil
+}
+
+// export creates the export signing key, its signature info, and the export
+// config.
+func (r *Result) export(ctx context.Context, cfg *Config, db *database.DB, km keys.KeyManager) error {
+	outputRegion, err := region.New(&cfg.Regions).Normalize(cfg.ExportRegion)
+	if err != nil {
+		return fmt.Errorf("invalid BOOTSTRAP_EXPORT_REGION: %w", err)
+	}
+
+	signingKey := cfg.ExportSigningKey
+	if signingKey == "" {
+		if signingKey, err = r.exportSigningKey(ctx, cfg, km); err != nil {
+			return err
+		}
+	}
+
+	exportDB := exportdatabase.New(db)
+
+	infos, err := exportDB.ListAllSignatureInfos(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list signature infos: %w", err)
+	}
+	for _, si := range infos {
+		if si.SigningKey == signingKey {
+			r.SignatureInfoID = si.ID
+			break
+		}
+	}
+	if r.SignatureInfoID == 0 {
+		signingKeyID := cfg.ExportSigningKeyID
+		if signingKeyID == "" {
+			signingKeyID = outputRegion
+		}
+		si := &exportmodel.SignatureInfo{
+			SigningKey:        signingKey,
+			SigningKeyVersion: "v1",
+			SigningKeyID:      signingKeyID,
+		}
+		if err := exportDB.AddSignatureInfo(ctx, si); err != nil {
+			return fmt.Errorf("failed to add signature info: %w", err)
+		}
+		r.SignatureInfoID = si.ID
+		r.created("signature info %d", si.ID)
+	}
+
+	filenameRoot := cfg.ExportFilenameRoot
+	if filenameRoot == "" {
+		filenameRoot = strings.ToLower(outputRegion)
+	}
+
+	configs, err := exportDB.GetAllExportConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list export configs: %w", err)
+	}
+	for _, ec := range configs {
+		if strings.EqualFold(ec.BucketName, cfg.ExportBucket) && strings.EqualFold(ec.FilenameRoot, filenameRoot) {
+			r.ExportConfigID = ec.ConfigID
+			return nil
+		}
+	}
+
+	ec := &exportmodel.ExportConfig{
+		BucketName:       cfg.ExportBucket,
+		FilenameRoot:     filenameRoot,
+		Period:           cfg.ExportPeriod,
+		OutputRegion:     outputRegion,
+		From:             time.Now().UTC(),
+		SignatureInfoIDs: []int64{r.SignatureInfoID},
+	}
+	if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+		return fmt.Errorf("failed to add export config: %w", err)
+	}
+	r.ExportConfigID = ec.ConfigID
+	r.created("export config %d", ec.ConfigID)
+	return nil
+}
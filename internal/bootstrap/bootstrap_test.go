@@ -0,0 +1,105 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"testing"
+	"time"
+
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBootstrap(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, dbConfig := testDatabaseInstance.NewDatabase(t)
+
+	km, err := keys.NewFilesystem(ctx, &keys.Config{FilesystemRoot: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test database is already migrated.
+	if err := Migrate(dbConfig.ConnectionURL(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		KeyRing:              "system",
+		RevisionKeyName:      "revision-token-encrypter",
+		ExportRegion:         "us",
+		ExportBucket:         "exports",
+		ExportPeriod:         4 * time.Hour,
+		ExportSigningKeyName: "export-signer",
+	}
+
+	first, err := Bootstrap(ctx, cfg, testDB, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.RevisionTokenKeyID == "" {
+		t.Errorf("expected revision token key id")
+	}
+	if got, want := len(first.RevisionTokenAAD), 16; got != want {
+		t.Errorf("expected %d bytes of revision token aad, got %d", want, got)
+	}
+	if first.RevisionKeyID == 0 || first.SignatureInfoID == 0 || first.ExportConfigID == 0 {
+		t.Errorf("expected revision key, signature info and export config, got %#v", first)
+	}
+	if got, want := len(first.Created), 5; got != want {
+		t.Errorf("expected %d created, got %v", want, first.Created)
+	}
+
+	ec, err := exportdatabase.New(testDB).GetExportConfig(ctx, first.ExportConfigID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ec.OutputRegion, "US"; got != want {
+		t.Errorf("expected output region %q, got %q", want, got)
+	}
+	if got, want := ec.FilenameRoot, "us"; got != want {
+		t.Errorf("expected filename root %q, got %q", want, got)
+	}
+	if diff := cmp.Diff([]int64{first.SignatureInfoID}, ec.SignatureInfoIDs); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Running again finds the existing state.
+	cfg.RevisionToken.KeyID = first.RevisionTokenKeyID
+	cfg.RevisionToken.AAD = first.RevisionTokenAAD
+	second, err := Bootstrap(ctx, cfg, testDB, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Result{
+		RevisionTokenKeyID: first.RevisionTokenKeyID,
+		RevisionKeyID:      first.RevisionKeyID,
+		SignatureInfoID:    first.SignatureInfoID,
+		ExportConfigID:     first.ExportConfigID,
+	}
+	if diff := cmp.Diff(want, second); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	cfg.ExportRegion = "USA"
+	_, err = Bootstrap(ctx, cfg, testDB, km)
+	errcmp.MustMatch(t, err, "invalid BOOTSTRAP_EXPORT_REGION")
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bootstrap provisions the application state of a new deployment:
+// the schema, the revision token keys, and an export signing key and config.
+// It is for operators who can't use the provided Terraform configuration.
+// Every step is skipped if its state already exists, so it is safe to run
+// again.
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/revision"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/secrets"
+)
+
+// Compile-time check to assert this config matches requirements.
+var (
+	_ setup.DatabaseConfigProvider      = (*Config)(nil)
+	_ setup.KeyManagerConfigProvider    = (*Config)(nil)
+	_ setup.SecretManagerConfigProvider = (*Config)(nil)
+)
+
+// Config represents the configuration and associated environment variables
+// for bootstrapping a deployment.
+type Config struct {
+	Database      database.Config
+	KeyManager    keys.Config
+	SecretManager secrets.Config
+	RevisionToken revision.Config
+	Regions       region.Config
+
+	// MigrationTimeout bounds the wait for the migration lock.
+	MigrationTimeout time.Duration `env:"BOOTSTRAP_MIGRATION_TIMEOUT, default=15m"`
+
+	// KeyRing is the parent of the keys that are created, e.g. a Cloud KMS key
+	// ring, or a directory of the filesystem key manager.
+	KeyRing string `env:"BOOTSTRAP_KEY_RING, default=system"`
+
+	// RevisionKeyName is the name of the key that wraps the revision token
+	// keys. It is only created if REVISION_TOKEN_KEY_ID is not set.
+	RevisionKeyName string `env:"BOOTSTRAP_REVISION_KEY_NAME, default=revision-token-encrypter"`
+
+	// ExportRegion and ExportBucket describe the export config to create. No
+	// export config or signing key is created if either is empty.
+	ExportRegion       string        `env:"BOOTSTRAP_EXPORT_REGION"`
+	ExportBucket       string        `env:"BOOTSTRAP_EXPORT_BUCKET"`
+	ExportFilenameRoot string        `env:"BOOTSTRAP_EXPORT_FILENAME_ROOT"`
+	ExportPeriod       time.Duration `env:"BOOTSTRAP_EXPORT_PERIOD, default=24h"`
+
+	// ExportSigningKey is an existing export signing key version to use,
+	// for key managers that can't create keys. ExportSigningKeyName is the
+	// name of the export signing key that is created if it is empty.
+	// ExportSigningKeyID is the key ID that clients verify exports with, as
+	// registered with the device vendors. It defaults to the export region.
+	ExportSigningKey     string `env:"BOOTSTRAP_EXPORT_SIGNING_KEY"`
+	ExportSigningKeyName string `env:"BOOTSTRAP_EXPORT_SIGNING_KEY_NAME, default=export-signer"`
+	ExportSigningKeyID   string `env:"BOOTSTRAP_EXPORT_SIGNING_KEY_ID"`
+}
+
+func (c *Config) DatabaseConfig() *database.Config {
+	return &c.Database
+}
+
+func (c *Config) KeyManagerConfig() *keys.Config {
+	return &c.KeyManager
+}
+
+func (c *Config) SecretManagerConfig() *secrets.Config {
+	return &c.SecretManager
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	capturedb "github.com/google/exposure-notifications-server/internal/capture/database"
+	"github.com/google/exposure-notifications-server/internal/capture/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+// Capturer records failed publish requests.
+type Capturer struct {
+	config *Config
+	db     *capturedb.CaptureDB
+}
+
+// New creates a new capturer.
+func New(cfg *Config, db *database.DB) (*Capturer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if db == nil {
+		return nil, fmt.Errorf("capture requires a database")
+	}
+
+	return &Capturer{
+		config: cfg,
+		db:     capturedb.New(db),
+	}, nil
+}
+
+// ShouldCapture returns true for responses to requests that failed
+// validation.
+func ShouldCapture(status int) bool {
+	return status == http.StatusBadRequest
+}
+
+// Record captures the request if it failed validation. data is nil if the
+// request could not be parsed. Failures are logged, they never fail the
+// request.
+func (c *Capturer) Record(ctx context.Context, data *verifyapi.Publish, platform string, status int, resp *verifyapi.PublishResponse) {
+	if !ShouldCapture(status) {
+		return
+	}
+
+	now := time.Now().UTC()
+	capture := &model.Capture{
+		CreatedAt: now,
+		ExpiresAt: now.Add(c.config.TTL),
+		Platform:  platform,
+		Status:    status,
+		Request:   model.Sanitize(data),
+	}
+	if data != nil {
+		capture.HealthAuthorityID = data.HealthAuthorityID
+	}
+	if resp != nil {
+		capture.Code = resp.Code
+		capture.ErrorMessage = resp.ErrorMessage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	if err := c.db.Insert(ctx, capture, c.config.MaxEntries); err != nil {
+		logging.FromContext(ctx).Named("capture").Warnw("failed to capture publish request", "error", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package capture records sanitized failed publish requests, so that reports
+// of rejected uploads from integration partners can be diagnosed in the admin
+// console without packet captures.
+package capture
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the configuration for capturing failed publish requests.
+type Config struct {
+	// Enabled captures publish requests that fail validation.
+	Enabled bool `env:"PUBLISH_CAPTURE_ENABLED, default=false"`
+
+	// MaxEntries is the number of captures that are kept. Older captures are
+	// deleted when new ones are recorded.
+	MaxEntries int `env:"PUBLISH_CAPTURE_MAX_ENTRIES, default=500"`
+
+	// TTL is how long a capture is kept.
+	TTL time.Duration `env:"PUBLISH_CAPTURE_TTL, default=24h"`
+
+	// Timeout is how long recording a capture may take.
+	Timeout time.Duration `env:"PUBLISH_CAPTURE_TIMEOUT, default=2s"`
+}
+
+// Validate checks the configuration.
+func (c *Config) Validate() error {
+	if c.MaxEntries <= 0 {
+		return fmt.Errorf("PUBLISH_CAPTURE_MAX_ENTRIES must be positive, got %d", c.MaxEntries)
+	}
+	if c.TTL <= 0 {
+		return fmt.Errorf("PUBLISH_CAPTURE_TTL must be positive, got %s", c.TTL)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("PUBLISH_CAPTURE_TIMEOUT must be positive, got %s", c.Timeout)
+	}
+	return nil
+}
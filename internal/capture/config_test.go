@@ -0,0 +1,64 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{name: "valid", cfg: Config{MaxEntries: 10, TTL: time.Hour, Timeout: time.Second}},
+		{name: "max_entries", cfg: Config{TTL: time.Hour, Timeout: time.Second}, err: "PUBLISH_CAPTURE_MAX_ENTRIES must be positive"},
+		{name: "ttl", cfg: Config{MaxEntries: 10, Timeout: time.Second}, err: "PUBLISH_CAPTURE_TTL must be positive"},
+		{name: "timeout", cfg: Config{MaxEntries: 10, TTL: time.Hour}, err: "PUBLISH_CAPTURE_TIMEOUT must be positive"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			errcmp.MustMatch(t, tc.cfg.Validate(), tc.err)
+		})
+	}
+}
+
+func TestShouldCapture(t *testing.T) {
+	t.Parallel()
+
+	for status, want := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusBadRequest:          true,
+		http.StatusUnauthorized:        false,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+	} {
+		if got := ShouldCapture(status); got != want {
+			t.Errorf("ShouldCapture(%d): expected %t, got %t", status, want, got)
+		}
+	}
+}
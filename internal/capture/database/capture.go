@@ -0,0 +1,171 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface for captured failed publish
+// requests.
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/capture/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type CaptureDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *CaptureDB {
+	return &CaptureDB{
+		db: db,
+	}
+}
+
+const selectCaptures = `
+	SELECT
+		id, created_at, expires_at, health_authority_id, platform, status, code, error_message, request
+	FROM
+		PublishCapture
+`
+
+// Insert adds the capture, then deletes expired captures and the oldest
+// captures beyond maxEntries, so the table is a bounded ring buffer.
+func (db *CaptureDB) Insert(ctx context.Context, c *model.Capture, maxEntries int) error {
+	var request []byte
+	if c.Request != nil {
+		var err error
+		if request, err = json.Marshal(c.Request); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				PublishCapture
+				(created_at, expires_at, health_authority_id, platform, status, code, error_message, request)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, c.CreatedAt, c.ExpiresAt, c.HealthAuthorityID, c.Platform, c.Status, c.Code,
+			model.TruncateErrorMessage(c.ErrorMessage), request)
+		if err := row.Scan(&c.ID); err != nil {
+			return fmt.Errorf("inserting capture: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				PublishCapture
+			WHERE
+				expires_at <= $1
+		`, c.CreatedAt); err != nil {
+			return fmt.Errorf("deleting expired captures: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				PublishCapture
+			WHERE
+				id <= (SELECT id FROM PublishCapture ORDER BY id DESC OFFSET $1 LIMIT 1)
+		`, maxEntries); err != nil {
+			return fmt.Errorf("deleting oldest captures: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to insert capture: %w", err)
+	}
+
+	return nil
+}
+
+// ListCaptures returns up to limit captures that have not expired at now,
+// newest first. If healthAuthorityID is not empty, only the captures of the
+// app are returned.
+func (db *CaptureDB) ListCaptures(ctx context.Context, healthAuthorityID string, now time.Time, limit int) ([]*model.Capture, error) {
+	var captures []*model.Capture
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, selectCaptures+`
+			WHERE
+				expires_at > $1
+				AND ($2 = '' OR health_authority_id = $2)
+			ORDER BY id DESC
+			LIMIT $3
+		`, now, healthAuthorityID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			c, err := scanOneCapture(rows)
+			if err != nil {
+				return err
+			}
+			captures = append(captures, c)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list captures: %w", err)
+	}
+
+	return captures, nil
+}
+
+// GetCapture returns the capture with the given ID, if it has not expired at
+// now.
+func (db *CaptureDB) GetCapture(ctx context.Context, id int64, now time.Time) (*model.Capture, error) {
+	var c *model.Capture
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, selectCaptures+`
+			WHERE
+				id = $1
+				AND expires_at > $2
+		`, id, now)
+
+		var err error
+		c, err = scanOneCapture(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.ErrNotFound
+		}
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+
+	return c, nil
+}
+
+func scanOneCapture(row pgx.Row) (*model.Capture, error) {
+	var c model.Capture
+	var request []byte
+	if err := row.Scan(&c.ID, &c.CreatedAt, &c.ExpiresAt, &c.HealthAuthorityID, &c.Platform,
+		&c.Status, &c.Code, &c.ErrorMessage, &request); err != nil {
+		return nil, err
+	}
+	if len(request) > 0 {
+		c.Request = new(model.Request)
+		if err := json.Unmarshal(request, c.Request); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal request: %w", err)
+		}
+	}
+	return &c, nil
+}
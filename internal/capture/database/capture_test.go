@@ -0,0 +1,97 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/capture/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCaptures(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	captureDB := New(testDB)
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	insert := func(app string, createdAt time.Time, maxEntries int) *model.Capture {
+		t.Helper()
+
+		c := &model.Capture{
+			CreatedAt:         createdAt,
+			ExpiresAt:         createdAt.Add(time.Hour),
+			HealthAuthorityID: app,
+			Platform:          "android",
+			Status:            400,
+			Code:              "bad_request",
+			ErrorMessage:      "invalid key length",
+			Request: &model.Request{
+				Keys:              []*model.Key{{Hash: model.HashKey("key"), Length: 3, IntervalNumber: 100, IntervalCount: 144}},
+				HealthAuthorityID: app,
+			},
+		}
+		if err := captureDB.Insert(ctx, c, maxEntries); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// The expired capture is deleted by the next insert.
+	expired := insert("a", now.Add(-2*time.Hour), 10)
+	first := insert("a", now, 10)
+	second := insert("b", now.Add(time.Second), 10)
+
+	got, err := captureDB.ListCaptures(ctx, "", now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.Capture{second, first}, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	got, err = captureDB.ListCaptures(ctx, "b", now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.Capture{second}, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := captureDB.GetCapture(ctx, expired.ID, now); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected expired capture to be deleted, got %v", err)
+	}
+
+	// Inserting beyond the limit deletes the oldest captures.
+	third := insert("a", now.Add(2*time.Second), 2)
+	got, err = captureDB.ListCaptures(ctx, "", now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.Capture{third, second}, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Captures are hidden once they expire, even before they are deleted.
+	if _, err := captureDB.GetCapture(ctx, third.ID, now.Add(2*time.Hour)); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected expired capture to be hidden, got %v", err)
+	}
+}
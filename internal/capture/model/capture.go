@@ -0,0 +1,133 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction for captured failed publish requests.
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+// MaxErrorMessageLength is the longest error message that is captured.
+const MaxErrorMessageLength = 1024
+
+// keyHashBytes is the number of bytes of the key hash that are kept, enough to
+// match a key a partner has on hand but too few to recover it.
+const keyHashBytes = 8
+
+// Capture is a failed publish request.
+type Capture struct {
+	ID                int64
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	HealthAuthorityID string
+	Platform          string
+	Status            int
+	Code              string
+	ErrorMessage      string
+
+	// Request is the sanitized request, or nil if the request could not be
+	// parsed.
+	Request *Request
+}
+
+// Request is a publish request without its secrets. Keys are replaced by a
+// truncated hash, and the verification certificate, HMAC key, revision token,
+// device nonce and padding by their lengths.
+type Request struct {
+	Keys                      []*Key `json:"keys"`
+	HealthAuthorityID         string `json:"healthAuthorityID"`
+	VerificationPayloadLength int    `json:"verificationPayloadLength"`
+	HMACKeyLength             int    `json:"hmacKeyLength"`
+	SymptomOnsetInterval      int32  `json:"symptomOnsetInterval,omitempty"`
+	Traveler                  bool   `json:"traveler,omitempty"`
+	FederationConsent         *bool  `json:"federationConsent,omitempty"`
+	RevisionTokenLength       int    `json:"revisionTokenLength"`
+	DeviceNonceLength         int    `json:"deviceNonceLength,omitempty"`
+	Chunk                     int32  `json:"chunk,omitempty"`
+	Of                        int32  `json:"of,omitempty"`
+	PaddingLength             int    `json:"paddingLength"`
+}
+
+// Key is an exposure key without the key.
+type Key struct {
+	// Hash is the hex encoded start of the SHA-256 hash of the key, as sent.
+	Hash string `json:"hash"`
+
+	// Length is the length of the decoded key, or -1 if the key is not valid
+	// base64.
+	Length int `json:"length"`
+
+	IntervalNumber   int32 `json:"rollingStartNumber"`
+	IntervalCount    int32 `json:"rollingPeriod"`
+	TransmissionRisk int   `json:"transmissionRisk,omitempty"`
+}
+
+// HashKey returns the truncated hash of a base64 encoded exposure key.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:keyHashBytes])
+}
+
+// Sanitize returns the request without its secrets.
+func Sanitize(data *verifyapi.Publish) *Request {
+	if data == nil {
+		return nil
+	}
+
+	keys := make([]*Key, 0, len(data.Keys))
+	for _, k := range data.Keys {
+		length := -1
+		if b, err := base64.StdEncoding.DecodeString(k.Key); err == nil {
+			length = len(b)
+		}
+		keys = append(keys, &Key{
+			Hash:             HashKey(k.Key),
+			Length:           length,
+			IntervalNumber:   k.IntervalNumber,
+			IntervalCount:    k.IntervalCount,
+			TransmissionRisk: k.TransmissionRisk,
+		})
+	}
+
+	return &Request{
+		Keys:                      keys,
+		HealthAuthorityID:         data.HealthAuthorityID,
+		VerificationPayloadLength: len(data.VerificationPayload),
+		HMACKeyLength:             len(data.HMACKey),
+		SymptomOnsetInterval:      data.SymptomOnsetInterval,
+		Traveler:                  data.Traveler,
+		FederationConsent:         data.FederationConsent,
+		RevisionTokenLength:       len(data.RevisionToken),
+		DeviceNonceLength:         len(data.DeviceNonce),
+		Chunk:                     data.Chunk,
+		Of:                        data.Of,
+		PaddingLength:             len(data.Padding),
+	}
+}
+
+// TruncateErrorMessage truncates the message to MaxErrorMessageLength bytes,
+// dropping a rune that is cut in half.
+func TruncateErrorMessage(s string) string {
+	if len(s) <= MaxErrorMessageLength {
+		return s
+	}
+	return strings.ToValidUTF8(s[:MaxErrorMessageLength], "")
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+
+	if got := Sanitize(nil); got != nil {
+		t.Errorf("expected nil, got %#v", got)
+	}
+
+	consent := true
+	data := &verifyapi.Publish{
+		Keys: []verifyapi.ExposureKey{
+			{Key: "AAAAAAAAAAAAAAAAAAAAAA==", IntervalNumber: 100, IntervalCount: 144, TransmissionRisk: 2},
+			{Key: "not base64", IntervalNumber: 244, IntervalCount: 144},
+		},
+		HealthAuthorityID:    "com.example.app",
+		VerificationPayload:  "header.claims.signature",
+		HMACKey:              "c2VjcmV0",
+		SymptomOnsetInterval: 90,
+		FederationConsent:    &consent,
+		RevisionToken:        "dG9rZW4=",
+		Padding:              "cGFk",
+	}
+
+	want := &Request{
+		Keys: []*Key{
+			{Hash: HashKey("AAAAAAAAAAAAAAAAAAAAAA=="), Length: 16, IntervalNumber: 100, IntervalCount: 144, TransmissionRisk: 2},
+			{Hash: HashKey("not base64"), Length: -1, IntervalNumber: 244, IntervalCount: 144},
+		},
+		HealthAuthorityID:         "com.example.app",
+		VerificationPayloadLength: 23,
+		HMACKeyLength:             8,
+		SymptomOnsetInterval:      90,
+		FederationConsent:         &consent,
+		RevisionTokenLength:       8,
+		PaddingLength:             4,
+	}
+	if diff := cmp.Diff(want, Sanitize(data)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if got := len(HashKey("AAAAAAAAAAAAAAAAAAAAAA==")); got != 2*keyHashBytes {
+		t.Errorf("expected hash of %d hex characters, got %d", 2*keyHashBytes, got)
+	}
+}
+
+func TestTruncateErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	if got := TruncateErrorMessage("short"); got != "short" {
+		t.Errorf("expected message unchanged, got %q", got)
+	}
+
+	long := strings.Repeat("a", MaxErrorMessageLength-1) + "é"
+	got := TruncateErrorMessage(long)
+	if want := strings.Repeat("a", MaxErrorMessageLength-1); got != want {
+		t.Errorf("expected the cut rune to be dropped, got %d bytes", len(got))
+	}
+}
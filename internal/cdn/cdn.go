@@ -0,0 +1,88 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cdn is an interface over content delivery network cache
+// invalidation.
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Purger invalidates cached copies of objects in a CDN.
+type Purger interface {
+	// Purge invalidates the given paths. Paths are relative to the root of the
+	// CDN (e.g. "exposureKeyExport-US/index.txt") and have the configured path
+	// prefix applied by the implementation.
+	Purge(ctx context.Context, paths []string) error
+}
+
+// PurgerFunc is a func that returns a purger or error.
+type PurgerFunc func(context.Context, *Config) (Purger, error)
+
+// purgers is the list of registered purgers.
+var (
+	purgers     = make(map[string]PurgerFunc)
+	purgersLock sync.RWMutex
+)
+
+// RegisterPurger registers a new purger with the given name. If a purger is
+// already registered with the given name, it panics. Purgers are usually
+// registered via an init function.
+func RegisterPurger(name string, fn PurgerFunc) {
+	purgersLock.Lock()
+	defer purgersLock.Unlock()
+
+	if _, ok := purgers[name]; ok {
+		panic(fmt.Sprintf("purger %q is already registered", name))
+	}
+	purgers[name] = fn
+}
+
+// RegisteredPurgers returns the list of the names of the registered purgers.
+func RegisteredPurgers() []string {
+	purgersLock.RLock()
+	defer purgersLock.RUnlock()
+
+	list := make([]string, 0, len(purgers))
+	for k := range purgers {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// PurgerFor returns the purger with the given name, or an error if one does
+// not exist.
+func PurgerFor(ctx context.Context, cfg *Config) (Purger, error) {
+	purgersLock.RLock()
+	defer purgersLock.RUnlock()
+
+	name := cfg.Type
+	fn, ok := purgers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown or uncompiled cdn purger %q", name)
+	}
+	return fn(ctx, cfg)
+}
+
+// joinPath joins the configured prefix and the path, ensuring the result
+// begins with a single slash.
+func joinPath(prefix, pth string) string {
+	return "/" + strings.TrimLeft(strings.TrimRight(prefix, "/")+"/"+strings.TrimLeft(pth, "/"), "/")
+}
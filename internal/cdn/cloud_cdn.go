@@ -0,0 +1,82 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build google || all
+
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func init() {
+	RegisterPurger("GOOGLE_CLOUD_CDN", NewCloudCDN)
+}
+
+// Compile-time check to verify implements interface.
+var _ Purger = (*CloudCDN)(nil)
+
+// CloudCDN purges objects from Google Cloud CDN by invalidating the cache on
+// the load balancer URL map.
+type CloudCDN struct {
+	svc     *compute.Service
+	project string
+	urlMap  string
+	prefix  string
+	timeout time.Duration
+}
+
+// NewCloudCDN creates a new Cloud CDN purger.
+func NewCloudCDN(ctx context.Context, cfg *Config) (Purger, error) {
+	if cfg.CloudCDN.Project == "" || cfg.CloudCDN.URLMap == "" {
+		return nil, fmt.Errorf("CDN_CLOUD_CDN_PROJECT and CDN_CLOUD_CDN_URL_MAP are required")
+	}
+
+	svc, err := compute.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("compute.NewService: %w", err)
+	}
+
+	return &CloudCDN{
+		svc:     svc,
+		project: cfg.CloudCDN.Project,
+		urlMap:  cfg.CloudCDN.URLMap,
+		prefix:  cfg.PathPrefix,
+		timeout: cfg.Timeout,
+	}, nil
+}
+
+// Purge invalidates each path. Cloud CDN accepts one path per invalidation
+// request.
+func (c *CloudCDN) Purge(ctx context.Context, paths []string) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var merr *multierror.Error
+	for _, pth := range paths {
+		rule := &compute.CacheInvalidationRule{Path: joinPath(c.prefix, pth)}
+		if _, err := c.svc.UrlMaps.InvalidateCache(c.project, c.urlMap, rule).Context(ctx).Do(); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("failed to invalidate %s: %w", pth, err))
+		}
+	}
+	return merr.ErrorOrNil()
+}
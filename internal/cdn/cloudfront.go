@@ -0,0 +1,94 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build aws || all
+
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+)
+
+func init() {
+	RegisterPurger("AWS_CLOUDFRONT", NewCloudFront)
+}
+
+// Compile-time check to verify implements interface.
+var _ Purger = (*CloudFront)(nil)
+
+// CloudFront purges objects from an AWS CloudFront distribution.
+type CloudFront struct {
+	svc            *cloudfront.CloudFront
+	distributionID string
+	prefix         string
+	timeout        time.Duration
+}
+
+// NewCloudFront creates a new CloudFront purger.
+func NewCloudFront(_ context.Context, cfg *Config) (Purger, error) {
+	if cfg.CloudFront.DistributionID == "" {
+		return nil, fmt.Errorf("CDN_CLOUDFRONT_DISTRIBUTION_ID is required")
+	}
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &CloudFront{
+		svc:            cloudfront.New(sess),
+		distributionID: cfg.CloudFront.DistributionID,
+		prefix:         cfg.PathPrefix,
+		timeout:        cfg.Timeout,
+	}, nil
+}
+
+// Purge creates a single invalidation for all of the given paths.
+func (c *CloudFront) Purge(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	items := make([]*string, 0, len(paths))
+	for _, pth := range paths {
+		items = append(items, aws.String(joinPath(c.prefix, pth)))
+	}
+
+	if _, err := c.svc.CreateInvalidationWithContext(ctx, &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(c.distributionID),
+		InvalidationBatch: &cloudfront.InvalidationBatch{
+			CallerReference: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
+			Paths: &cloudfront.Paths{
+				Items:    items,
+				Quantity: aws.Int64(int64(len(items))),
+			},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to create invalidation: %w", err)
+	}
+	return nil
+}
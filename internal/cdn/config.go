@@ -0,0 +1,68 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdn
+
+import "time"
+
+// Config defines the configuration for a CDN purger.
+type Config struct {
+	// Type is the type of CDN purger. The default is NOOP, which disables
+	// purging.
+	Type string `env:"CDN_PURGER, default=NOOP"`
+
+	// PathPrefix is prepended to each purged path. Use this when the CDN serves
+	// the bucket from a subpath or when the bucket name is part of the path.
+	PathPrefix string `env:"CDN_PATH_PREFIX"`
+
+	// Timeout is the maximum amount of time to wait for a purge request.
+	Timeout time.Duration `env:"CDN_PURGE_TIMEOUT, default=30s"`
+
+	// CloudCDN is the configuration for Google Cloud CDN.
+	CloudCDN CloudCDNConfig
+
+	// CloudFront is the configuration for AWS CloudFront.
+	CloudFront CloudFrontConfig
+
+	// Fastly is the configuration for Fastly.
+	Fastly FastlyConfig
+}
+
+// CloudCDNConfig is the configuration for the Google Cloud CDN purger.
+type CloudCDNConfig struct {
+	// Project is the Google Cloud project that owns the URL map.
+	Project string `env:"CDN_CLOUD_CDN_PROJECT"`
+
+	// URLMap is the name of the load balancer URL map in front of the bucket.
+	URLMap string `env:"CDN_CLOUD_CDN_URL_MAP"`
+}
+
+// CloudFrontConfig is the configuration for the AWS CloudFront purger.
+type CloudFrontConfig struct {
+	// DistributionID is the ID of the CloudFront distribution.
+	DistributionID string `env:"CDN_CLOUDFRONT_DISTRIBUTION_ID"`
+}
+
+// FastlyConfig is the configuration for the Fastly purger.
+type FastlyConfig struct {
+	// Endpoint is the Fastly API endpoint.
+	Endpoint string `env:"CDN_FASTLY_ENDPOINT, default=https://api.fastly.com"`
+
+	// Host is the hostname clients use to fetch exports via Fastly.
+	Host string `env:"CDN_FASTLY_HOST"`
+
+	// APIKey is the Fastly API token with purge permissions. This should be
+	// a reference to a secret.
+	APIKey string `env:"CDN_FASTLY_API_KEY"`
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func init() {
+	RegisterPurger("FASTLY", NewFastly)
+}
+
+// Compile-time check to verify implements interface.
+var _ Purger = (*Fastly)(nil)
+
+// Fastly purges objects from the Fastly CDN using the purge-by-URL API.
+type Fastly struct {
+	client   *http.Client
+	endpoint string
+	host     string
+	apiKey   string
+	prefix   string
+}
+
+// NewFastly creates a new Fastly purger.
+func NewFastly(_ context.Context, cfg *Config) (Purger, error) {
+	if cfg.Fastly.Host == "" {
+		return nil, fmt.Errorf("CDN_FASTLY_HOST is required")
+	}
+	if cfg.Fastly.APIKey == "" {
+		return nil, fmt.Errorf("CDN_FASTLY_API_KEY is required")
+	}
+
+	return &Fastly{
+		client:   &http.Client{Timeout: cfg.Timeout},
+		endpoint: strings.TrimRight(cfg.Fastly.Endpoint, "/"),
+		host:     cfg.Fastly.Host,
+		apiKey:   cfg.Fastly.APIKey,
+		prefix:   cfg.PathPrefix,
+	}, nil
+}
+
+// Purge purges each path individually. Fastly does not support batch purging
+// by URL.
+func (f *Fastly) Purge(ctx context.Context, paths []string) error {
+	var merr *multierror.Error
+	for _, pth := range paths {
+		if err := f.purge(ctx, pth); err != nil {
+			merr = multierror.Append(merr, err)
+		}
+	}
+	return merr.ErrorOrNil()
+}
+
+func (f *Fastly) purge(ctx context.Context, pth string) error {
+	u := fmt.Sprintf("%s/purge/%s%s", f.endpoint, f.host, joinPath(f.prefix, pth))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Fastly-Key", f.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to purge %s: %w", pth, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to purge %s: status %d: %s", pth, resp.StatusCode, b)
+	}
+	return nil
+}
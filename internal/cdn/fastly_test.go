@@ -0,0 +1,92 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFastly_Purge(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	var lock sync.Mutex
+	var purged []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Fastly-Key"), "secret"; got != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/purge/cdn.example.com/exports/bad/index.txt" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		lock.Lock()
+		purged = append(purged, r.URL.Path)
+		lock.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	purger, err := NewFastly(ctx, &Config{
+		PathPrefix: "/exports/",
+		Fastly: FastlyConfig{
+			Endpoint: srv.URL,
+			Host:     "cdn.example.com",
+			APIKey:   "secret",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := purger.Purge(ctx, []string{"us/index.txt", "/ca/index.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := purger.Purge(ctx, []string{"bad/index.txt"}); err == nil {
+		t.Errorf("expected error")
+	}
+
+	sort.Strings(purged)
+	want := []string{
+		"/purge/cdn.example.com/exports/ca/index.txt",
+		"/purge/cdn.example.com/exports/us/index.txt",
+	}
+	if diff := cmp.Diff(want, purged); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNewFastly_Validation(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	if _, err := NewFastly(ctx, &Config{Fastly: FastlyConfig{APIKey: "k"}}); err == nil {
+		t.Errorf("expected error for missing host")
+	}
+	if _, err := NewFastly(ctx, &Config{Fastly: FastlyConfig{Host: "h"}}); err == nil {
+		t.Errorf("expected error for missing api key")
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdn
+
+import "context"
+
+func init() {
+	RegisterPurger("NOOP", NewNoop)
+}
+
+// Compile-time check to verify implements interface.
+var _ Purger = (*Noop)(nil)
+
+// Noop is a purger that does nothing.
+type Noop struct{}
+
+// NewNoop creates a new purger that does nothing.
+func NewNoop(_ context.Context, _ *Config) (Purger, error) {
+	return &Noop{}, nil
+}
+
+// Purge does nothing.
+func (n *Noop) Purge(_ context.Context, _ []string) error {
+	return nil
+}
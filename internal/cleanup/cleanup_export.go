@@ -20,7 +20,8 @@ import (
 	"net/http"
 
 	"github.com/google/exposure-notifications-server/internal/export/database"
-	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/logging"
@@ -36,6 +37,7 @@ type ExportServer struct {
 	env       *serverenv.ServerEnv
 	database  *database.ExportDB
 	blobstore storage.Blobstore
+	retention *region.Retention
 	h         *render.Renderer
 }
 
@@ -49,11 +51,17 @@ func NewExportServer(cfg *Config, env *serverenv.ServerEnv) (*ExportServer, erro
 		return nil, fmt.Errorf("missing blobstore in server environment")
 	}
 
+	retention, err := region.NewRetention(&cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGION_RETENTION: %w", err)
+	}
+
 	return &ExportServer{
 		config:    cfg,
 		env:       env,
 		database:  database.New(env.Database()),
 		blobstore: env.Blobstore(),
+		retention: retention,
 		h:         render.NewRenderer(),
 	}, nil
 }
@@ -63,13 +71,11 @@ func (s *ExportServer) Routes(ctx context.Context) *mux.Router {
 	logger := logging.FromContext(ctx).Named("mirror")
 
 	r := mux.NewRouter()
-	r.Use(middleware.Recovery())
-	r.Use(middleware.PopulateRequestID())
-	r.Use(middleware.PopulateObservability())
-	r.Use(middleware.PopulateLogger(logger))
+	s.config.Middleware.Use(r, logger)
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/", s.handleCleanup())
+	r.Handle("/audit", s.handleAudit())
 
 	return r
 }
@@ -79,11 +85,29 @@ func (s *ExportServer) handleCleanup() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		budget := s.config.Scheduler.NewBudget(r, 0)
+		ctx, cancel := scheduler.WithBudget(ctx, budget)
+		defer cancel()
+
 		logger := logging.FromContext(ctx).Named("cleanup.export")
 		logger.Debugw("starting")
 		defer logger.Debugw("finishing")
 
-		cutoff, err := cutoffDate(s.config.TTL, s.config.DebugOverrideCleanupMinDuration)
+		// In an active/active deployment, only the region that holds the lease
+		// runs the job.
+		if ok, err := s.config.Leader.Lead(ctx, s.env.Database(), "cleanup-export"); err != nil {
+			logger.Errorw("failed to acquire lease", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		} else if !ok {
+			s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("not leader"))
+			return
+		}
+
+		// The export worker only marks the files of a region for deletion
+		// once its retention has passed, so the shortest retention is the
+		// cutoff of all files.
+		cutoff, err := cutoffDate(s.retention.Shortest(s.config.TTL), s.config.DebugOverrideCleanupMinDuration)
 		if err != nil {
 			logger.Errorw("failed to calculate cutoff date", "error", err)
 			s.h.RenderJSON(w, http.StatusInternalServerError, err)
@@ -94,16 +118,20 @@ func (s *ExportServer) handleCleanup() http.Handler {
 		// attempt the other purges.
 		var merr *multierror.Error
 
-		// Files
+		// Files. Deletion stops when the budget is spent, and the files that
+		// are left are deleted on the next run.
+		var completed int
 		func() {
 			ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
 			defer cancel()
 
-			if count, err := s.database.DeleteFilesBefore(ctx, cutoff, s.blobstore); err != nil {
+			count, err := s.database.DeleteFilesBefore(ctx, cutoff, s.blobstore)
+			if err != nil {
 				merr = multierror.Append(merr, fmt.Errorf("failed to delete files: %w", err))
 			} else {
 				logger.Infow("purged files", "count", count)
 			}
+			completed = count
 		}()
 
 		if errs := merr.WrappedErrors(); len(errs) > 0 {
@@ -112,6 +140,12 @@ func (s *ExportServer) handleCleanup() http.Handler {
 			return
 		}
 
+		if budget.Spent() {
+			logger.Warnw("budget spent, files may be left to delete", "retry", budget.Retry())
+			s.h.RenderJSON(w, http.StatusAccepted, budget.Progress(completed))
+			return
+		}
+
 		stats.Record(ctx, mExportSuccess.M(1))
 		s.h.RenderJSON(w, http.StatusOK, nil)
 	})
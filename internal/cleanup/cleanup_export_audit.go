@@ -0,0 +1,354 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// auditLocation is where export configs write their files. Configs can share
+// a location, so they are audited together.
+type auditLocation struct {
+	bucket string
+	root   string
+}
+
+func (l auditLocation) String() string {
+	return l.bucket + "/" + l.root
+}
+
+// auditRun is the state of an export audit across locations.
+type auditRun struct {
+	now     time.Time
+	configs map[int64]*model.ExportConfig
+	repairs int
+}
+
+// handleAudit handles the export bucket audit, which reconciles the export
+// files in the database with the objects in the blobstore.
+func (s *ExportServer) handleAudit() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		budget := s.config.Scheduler.NewBudget(r, s.config.Audit.Timeout)
+		ctx, cancel := scheduler.WithBudget(ctx, budget)
+		defer cancel()
+
+		logger := logging.FromContext(ctx).Named("cleanup.export_audit")
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		if ok, err := s.config.Leader.Lead(ctx, s.env.Database(), "cleanup-export-audit"); err != nil {
+			logger.Errorw("failed to acquire lease", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		} else if !ok {
+			s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("not leader"))
+			return
+		}
+
+		configs, err := s.database.GetAllExportConfigs(ctx)
+		if err != nil {
+			logger.Errorw("failed to list export configs", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		run := &auditRun{
+			now:     time.Now().UTC(),
+			configs: make(map[int64]*model.ExportConfig, len(configs)),
+		}
+		locations := make(map[auditLocation][]int64)
+		for _, ec := range configs {
+			run.configs[ec.ConfigID] = ec
+			l := auditLocation{bucket: ec.BucketName, root: ec.FilenameRoot}
+			locations[l] = append(locations[l], ec.ConfigID)
+		}
+		sorted := make([]auditLocation, 0, len(locations))
+		for l := range locations {
+			sorted = append(sorted, l)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].String() < sorted[j].String()
+		})
+
+		// Locations are audited one at a time, and the locations that are
+		// left when the budget is spent are audited on the next run.
+		var completed int
+		for _, l := range sorted {
+			if budget.Spent() {
+				break
+			}
+
+			audit, err := s.auditLocation(ctx, run, l, locations[l])
+			if err != nil {
+				logger.Errorw("failed to audit location", "location", l.String(), "error", err)
+				s.h.RenderJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			audit.RunID = budget.RunID()
+			if err := s.database.SaveAudit(ctx, audit, s.config.Audit.MaxFindings); err != nil {
+				logger.Errorw("failed to save audit", "location", l.String(), "error", err)
+				s.h.RenderJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			recordAudit(ctx, l, audit)
+
+			if drift := audit.Drift(); drift > 0 {
+				logger.Warnw("export location drifted", "location", l.String(), "counts", audit.Counts)
+			}
+			completed++
+		}
+
+		if completed < len(sorted) {
+			logger.Warnw("budget spent, locations left to audit", "retry", budget.Retry(), "left", len(sorted)-completed)
+			s.h.RenderJSON(w, http.StatusAccepted, budget.Progress(completed))
+			return
+		}
+
+		// Every location was audited in this run, so older audits are of
+		// locations that no config writes to anymore.
+		if _, err := s.database.DeleteAuditsBefore(ctx, run.now); err != nil {
+			logger.Errorw("failed to delete stale audits", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		stats.Record(ctx, mAuditSuccess.M(1))
+		s.h.RenderJSON(w, http.StatusOK, nil)
+	})
+}
+
+// auditLocation reconciles the export files of the configs at a location
+// with the objects stored there, and repairs the drift if enabled.
+func (s *ExportServer) auditLocation(ctx context.Context, run *auditRun, l auditLocation, configIDs []int64) (*model.ExportAudit, error) {
+	files, err := s.database.ListAuditFiles(ctx, configIDs, l.bucket, l.root)
+	if err != nil {
+		return nil, err
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	objects, err := storage.ListObjects(listCtx, s.blobstore, l.bucket, l.root+"/")
+	if err != nil {
+		return nil, fmt.Errorf("listing objects of %s: %w", l, err)
+	}
+
+	audit := &model.ExportAudit{
+		BucketName:   l.bucket,
+		FilenameRoot: l.root,
+		ConfigIDs:    configIDs,
+		AuditedAt:    run.now,
+		Counts:       make(map[string]int, len(model.AuditKinds)),
+	}
+
+	stored := make(map[string]*storage.ObjectAttrs, len(objects))
+	for _, o := range objects {
+		if !isAuditedObject(l, o.Name) {
+			continue
+		}
+		stored[o.Name] = o
+		audit.Objects++
+	}
+
+	byName := make(map[string]*database.AuditFile, len(files))
+	var drifted []*database.AuditFile
+	for _, f := range files {
+		byName[f.Filename] = f
+
+		// Files pending deletion may or may not be deleted yet, and deleted
+		// files are checked with the objects.
+		if f.Status != model.ExportBatchComplete {
+			continue
+		}
+		audit.Files++
+
+		o, ok := stored[f.Filename]
+		switch {
+		case !ok:
+			audit.Findings = append(audit.Findings, &model.ExportAuditFinding{
+				Filename:     f.Filename,
+				Kind:         model.AuditMissing,
+				BatchID:      f.BatchID,
+				ExpectedSize: f.Size,
+			})
+		case f.Size > 0 && o.Size != f.Size:
+			audit.Findings = append(audit.Findings, &model.ExportAuditFinding{
+				Filename:     f.Filename,
+				Kind:         model.AuditSizeMismatch,
+				BatchID:      f.BatchID,
+				ExpectedSize: f.Size,
+				ActualSize:   o.Size,
+			})
+		default:
+			continue
+		}
+		drifted = append(drifted, f)
+	}
+
+	for _, o := range objects {
+		if _, ok := stored[o.Name]; !ok {
+			continue
+		}
+		if f, ok := byName[o.Name]; ok && f.Status != model.ExportBatchDeleted {
+			continue
+		}
+		// Objects are written before their files are recorded.
+		if !o.Updated.IsZero() && run.now.Sub(o.Updated) < s.config.Audit.OrphanGrace {
+			continue
+		}
+		audit.Findings = append(audit.Findings, &model.ExportAuditFinding{
+			Filename:   o.Name,
+			Kind:       model.AuditOrphan,
+			ActualSize: o.Size,
+		})
+		drifted = append(drifted, nil)
+	}
+
+	for _, f := range audit.Findings {
+		audit.Counts[f.Kind]++
+	}
+
+	if s.config.Audit.Repair {
+		s.repairAudit(ctx, run, l, audit, drifted)
+	}
+
+	sort.SliceStable(audit.Findings, func(i, j int) bool {
+		return audit.Findings[i].Filename < audit.Findings[j].Filename
+	})
+	return audit, nil
+}
+
+// isAuditedObject returns true if the object is an export file of the
+// location. Index files, provenance records and the objects of nested roots
+// are not.
+func isAuditedObject(l auditLocation, name string) bool {
+	rest := strings.TrimPrefix(name, l.root+"/")
+	if rest == name || rest == "" || strings.Contains(rest, "/") {
+		return false
+	}
+	if strings.HasPrefix(rest, "index.txt") {
+		return false
+	}
+	return !strings.HasSuffix(rest, model.ProvenanceFilenameSuffix)
+}
+
+// repairAudit repairs the findings of an audit, up to the maximum number of
+// repairs of the run. files holds the file of each finding, or nil for
+// orphans.
+func (s *ExportServer) repairAudit(ctx context.Context, run *auditRun, l auditLocation, audit *model.ExportAudit, files []*database.AuditFile) {
+	logger := logging.FromContext(ctx)
+
+	reopened := make(map[int64]string)
+	for i, finding := range audit.Findings {
+		if run.repairs >= s.config.Audit.MaxRepairs || scheduler.Spent(ctx) {
+			return
+		}
+
+		if finding.Kind == model.AuditOrphan {
+			run.repairs++
+			finding.Repair = model.AuditRepairDeleted
+			if err := s.deleteOrphan(ctx, l, finding.Filename); err != nil {
+				logger.Errorw("failed to delete orphan", "object", finding.Filename, "error", err)
+				finding.Repair = model.AuditRepairFailed
+			}
+			recordAuditRepair(ctx, finding)
+			continue
+		}
+
+		// Files are repaired by exporting their batch again, once per batch.
+		f := files[i]
+		if repair, ok := reopened[f.BatchID]; ok {
+			finding.Repair = repair
+			continue
+		}
+		if !s.canReexport(run, f) {
+			continue
+		}
+		run.repairs++
+		finding.Repair = model.AuditRepairReexport
+		if err := s.database.ReopenBatch(ctx, f.BatchID); err != nil {
+			logger.Errorw("failed to reopen batch", "batch_id", f.BatchID, "error", err)
+			finding.Repair = model.AuditRepairFailed
+		}
+		reopened[f.BatchID] = finding.Repair
+		recordAuditRepair(ctx, finding)
+	}
+}
+
+// canReexport returns true if the batch of the file can be exported again.
+// Derived batches are only exported with their parent, and the keys of
+// batches past their retention may have been deleted.
+func (s *ExportServer) canReexport(run *auditRun, f *database.AuditFile) bool {
+	ec, ok := run.configs[f.ConfigID]
+	if !ok || ec.IsDerived() {
+		return false
+	}
+	retention := s.retention.For(s.config.TTL, f.InputRegions...)
+	return f.EndTimestamp.After(run.now.Add(-retention))
+}
+
+// deleteOrphan deletes an orphaned object and its provenance record.
+func (s *ExportServer) deleteOrphan(ctx context.Context, l auditLocation, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Second)
+	defer cancel()
+
+	if err := s.blobstore.DeleteObject(ctx, l.bucket, name); err != nil {
+		return fmt.Errorf("delete object: %w", err)
+	}
+	if err := s.blobstore.DeleteObject(ctx, l.bucket, name+model.ProvenanceFilenameSuffix); err != nil {
+		return fmt.Errorf("delete provenance object: %w", err)
+	}
+	return nil
+}
+
+// recordAudit records the drift of a location.
+func recordAudit(ctx context.Context, l auditLocation, audit *model.ExportAudit) {
+	logger := logging.FromContext(ctx)
+
+	for _, kind := range model.AuditKinds {
+		if err := stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(locationTag, l.String()), tag.Upsert(kindTag, kind)},
+			mAuditFindings.M(int64(audit.Counts[kind])),
+		); err != nil {
+			logger.Errorw("failed to record audit findings", "location", l.String(), "error", err)
+		}
+	}
+}
+
+// recordAuditRepair records a repair.
+func recordAuditRepair(ctx context.Context, f *model.ExportAuditFinding) {
+	result := "success"
+	if f.Repair == model.AuditRepairFailed {
+		result = "failure"
+	}
+	if err := stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(kindTag, f.Kind), tag.Upsert(resultTag, result)},
+		mAuditRepairs.M(1),
+	); err != nil {
+		logging.FromContext(ctx).Errorw("failed to record audit repair", "object", f.Filename, "error", err)
+	}
+}
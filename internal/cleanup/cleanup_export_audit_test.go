@@ -0,0 +1,191 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleanup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestIsAuditedObject(t *testing.T) {
+	t.Parallel()
+
+	l := auditLocation{bucket: "bucket", root: "root"}
+
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{"root/1-2-00001.zip", true},
+		{"root/1-2-00001.zip.enc", true},
+		{"root/1-2-00001.zip" + model.ProvenanceFilenameSuffix, false},
+		{"root/index.txt", false},
+		{"root/index.txt.jws", false},
+		{"root/nested/1-2-00001.zip", false},
+		{"rooted/1-2-00001.zip", false},
+		{"root/", false},
+	}
+
+	for _, tc := range cases {
+		if got := isAuditedObject(l, tc.name); got != tc.want {
+			t.Errorf("isAuditedObject(%q): got %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExportAudit(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := exportdatabase.New(testDB)
+	now := time.Now().Truncate(time.Microsecond)
+
+	bs, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := bs.(*storage.Memory)
+
+	ec := &model.ExportConfig{
+		BucketName:   "bucket",
+		FilenameRoot: "root",
+		Period:       time.Hour,
+		OutputRegion: "US",
+	}
+	if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+	eb := &model.ExportBatch{
+		ConfigID:       ec.ConfigID,
+		BucketName:     ec.BucketName,
+		FilenameRoot:   ec.FilenameRoot,
+		StartTimestamp: now.Add(-2 * time.Hour),
+		EndTimestamp:   now.Add(-time.Hour),
+		OutputRegion:   ec.OutputRegion,
+		Status:         model.ExportBatchOpen,
+	}
+	if err := exportDB.AddExportBatches(ctx, []*model.ExportBatch{eb}); err != nil {
+		t.Fatal(err)
+	}
+	eb, err = exportDB.LeaseBatch(ctx, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ok.zip is stored as recorded, short.zip is smaller than recorded and
+	// gone.zip is not stored.
+	files := []string{"root/gone.zip", "root/ok.zip", "root/short.zip"}
+	sizes := map[string]int64{"root/gone.zip": 3, "root/ok.zip": 3, "root/short.zip": 3}
+	if err := exportDB.FinalizeBatch(ctx, eb, files, sizes, len(files), nil); err != nil {
+		t.Fatal(err)
+	}
+	put := func(name string, data string, age time.Duration) {
+		if err := bs.CreateObject(ctx, "bucket", name, []byte(data), false, storage.ContentTypeZip); err != nil {
+			t.Fatal(err)
+		}
+		mem.SetUpdated("bucket", name, now.Add(-age))
+	}
+	put("root/ok.zip", "abc", 2*time.Hour)
+	put("root/short.zip", "ab", 2*time.Hour)
+	put("root/index.txt", "root/ok.zip", 2*time.Hour)
+	// An old object without a file is an orphan, a new one may be being
+	// written.
+	put("root/orphan.zip", "abcd", 2*time.Hour)
+	put("root/orphan.zip"+model.ProvenanceFilenameSuffix, "{}", 2*time.Hour)
+	put("root/new.zip", "abcd", time.Minute)
+
+	cfg := &Config{
+		TTL: 336 * time.Hour,
+		Audit: AuditConfig{
+			Timeout:     time.Minute,
+			OrphanGrace: time.Hour,
+			Repair:      true,
+			MaxRepairs:  10,
+			MaxFindings: 10,
+		},
+	}
+	env := serverenv.New(ctx, serverenv.WithDatabase(testDB), serverenv.WithBlobStorage(bs))
+	server, err := NewExportServer(cfg, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/audit", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	server.Routes(ctx).ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	audits, err := exportDB.ListAudits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audits) != 1 {
+		t.Fatalf("expected 1 audit, got %d", len(audits))
+	}
+	wantCounts := map[string]int{
+		model.AuditMissing:      1,
+		model.AuditOrphan:       1,
+		model.AuditSizeMismatch: 1,
+	}
+	if diff := cmp.Diff(wantCounts, audits[0].Counts); diff != "" {
+		t.Errorf("counts mismatch (-want, +got):\n%s", diff)
+	}
+
+	findings, err := exportDB.ListAuditFindings(ctx, "bucket", "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*model.ExportAuditFinding{
+		{Filename: "root/gone.zip", Kind: model.AuditMissing, BatchID: eb.BatchID, ExpectedSize: 3, Repair: model.AuditRepairReexport},
+		{Filename: "root/orphan.zip", Kind: model.AuditOrphan, ActualSize: 4, Repair: model.AuditRepairDeleted},
+		{Filename: "root/short.zip", Kind: model.AuditSizeMismatch, BatchID: eb.BatchID, ExpectedSize: 3, ActualSize: 2, Repair: model.AuditRepairReexport},
+	}
+	if diff := cmp.Diff(want, findings, cmpopts.IgnoreFields(model.ExportAuditFinding{}, "ID")); diff != "" {
+		t.Errorf("findings mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The orphan and its provenance were deleted.
+	for _, name := range []string{"root/orphan.zip", "root/orphan.zip" + model.ProvenanceFilenameSuffix} {
+		if _, err := bs.GetObject(context.Background(), "bucket", name); err == nil {
+			t.Errorf("expected %q to be deleted", name)
+		}
+	}
+
+	// The batch was reopened to be exported again.
+	gotBatch, err := exportDB.LookupExportBatch(ctx, eb.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotBatch.Status != model.ExportBatchOpen {
+		t.Errorf("batch status: got %q, want %q", gotBatch.Status, model.ExportBatchOpen)
+	}
+}
@@ -18,9 +18,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
-	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/publish/database"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/google/exposure-notifications-server/pkg/render"
@@ -31,10 +33,11 @@ import (
 )
 
 type ExposureServer struct {
-	config   *Config
-	env      *serverenv.ServerEnv
-	database *database.PublishDB
-	h        *render.Renderer
+	config    *Config
+	env       *serverenv.ServerEnv
+	database  *database.PublishDB
+	retention *region.Retention
+	h         *render.Renderer
 }
 
 // NewExposureServer creates a http.Handler for deleting exposure keys
@@ -44,11 +47,17 @@ func NewExposureServer(cfg *Config, env *serverenv.ServerEnv) (*ExposureServer,
 		return nil, fmt.Errorf("missing database in server environment")
 	}
 
+	retention, err := region.NewRetention(&cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGION_RETENTION: %w", err)
+	}
+
 	return &ExposureServer{
-		config:   cfg,
-		env:      env,
-		database: database.New(env.Database()),
-		h:        render.NewRenderer(),
+		config:    cfg,
+		env:       env,
+		database:  database.New(env.Database()),
+		retention: retention,
+		h:         render.NewRenderer(),
 	}, nil
 }
 
@@ -57,10 +66,7 @@ func (s *ExposureServer) Routes(ctx context.Context) *mux.Router {
 	logger := logging.FromContext(ctx).Named("mirror")
 
 	r := mux.NewRouter()
-	r.Use(middleware.Recovery())
-	r.Use(middleware.PopulateRequestID())
-	r.Use(middleware.PopulateObservability())
-	r.Use(middleware.PopulateLogger(logger))
+	s.config.Middleware.Use(r, logger)
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/", s.handleCleanup())
@@ -73,10 +79,25 @@ func (s *ExposureServer) handleCleanup() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		budget := s.config.Scheduler.NewBudget(r, 0)
+		ctx, cancel := scheduler.WithBudget(ctx, budget)
+		defer cancel()
+
 		logger := logging.FromContext(ctx).Named("cleanup.exposure")
 		logger.Debugw("starting")
 		defer logger.Debugw("finishing")
 
+		// In an active/active deployment, only the region that holds the lease
+		// runs the job.
+		if ok, err := s.config.Leader.Lead(ctx, s.env.Database(), "cleanup-exposure"); err != nil {
+			logger.Errorw("failed to acquire lease", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		} else if !ok {
+			s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("not leader"))
+			return
+		}
+
 		cutoff, err := cutoffDate(s.config.TTL, s.config.DebugOverrideCleanupMinDuration)
 		if err != nil {
 			logger.Errorw("failed to calculate cutoff date", "error", err)
@@ -84,6 +105,18 @@ func (s *ExposureServer) handleCleanup() http.Handler {
 			return
 		}
 
+		// Regions with a retention override have their own cutoff.
+		regionCutoffs := make(map[string]time.Time)
+		for _, r := range s.retention.Overrides() {
+			regionCutoff, err := cutoffDate(s.retention.For(s.config.TTL, r), s.config.DebugOverrideCleanupMinDuration)
+			if err != nil {
+				logger.Errorw("failed to calculate region cutoff date", "region", r, "error", err)
+				s.h.RenderJSON(w, http.StatusInternalServerError, err)
+				return
+			}
+			regionCutoffs[r] = regionCutoff
+		}
+
 		// Construct a multi-error. If one of the purges fails, we still want to
 		// attempt the other purges.
 		var merr *multierror.Error
@@ -93,24 +126,46 @@ func (s *ExposureServer) handleCleanup() http.Handler {
 			ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
 			defer cancel()
 
-			if count, err := s.database.DeleteExposuresBefore(ctx, cutoff); err != nil {
+			if count, err := s.database.DeleteExposuresBefore(ctx, cutoff, regionCutoffs); err != nil {
 				merr = multierror.Append(merr, fmt.Errorf("failed to delete exposures: %w", err))
 			} else {
 				logger.Infow("purged exposures", "count", count)
 			}
 		}()
+		completed := 1
+
+		// The steps that are left when the budget is spent run on the next
+		// run.
+		stopped := budget.Spent()
 
 		// Stats
-		func() {
-			ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
-			defer cancel()
+		if !stopped {
+			func() {
+				ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+				defer cancel()
+
+				if count, err := s.database.DeleteStatsBefore(ctx, cutoff); err != nil {
+					merr = multierror.Append(merr, fmt.Errorf("failed to delete stats: %w", err))
+				} else {
+					logger.Infow("purged statistics", "count", count)
+				}
+			}()
+			completed++
+			stopped = budget.Spent()
+		}
 
-			if count, err := s.database.DeleteStatsBefore(ctx, cutoff); err != nil {
-				merr = multierror.Append(merr, fmt.Errorf("failed to delete stats: %w", err))
-			} else {
-				logger.Infow("purged statistics", "count", count)
-			}
-		}()
+		// Table maintenance, after the purges have left dead tuples behind.
+		if cfg := &s.config.Maintenance; cfg.Enabled && !stopped {
+			func() {
+				ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+				defer cancel()
+
+				if err := maintainTables(ctx, s.env.Database(), cfg); err != nil {
+					merr = multierror.Append(merr, fmt.Errorf("failed to maintain tables: %w", err))
+				}
+			}()
+			completed++
+		}
 
 		if errs := merr.WrappedErrors(); len(errs) > 0 {
 			logger.Errorw("failed to cleanup exposures", "errors", errs)
@@ -118,6 +173,12 @@ func (s *ExposureServer) handleCleanup() http.Handler {
 			return
 		}
 
+		if stopped {
+			logger.Warnw("budget spent, skipped the remaining steps", "retry", budget.Retry())
+			s.h.RenderJSON(w, http.StatusAccepted, budget.Progress(completed))
+			return
+		}
+
 		stats.Record(ctx, mExposureSuccess.M(1))
 		s.h.RenderJSON(w, http.StatusOK, nil)
 	})
@@ -17,6 +17,10 @@ package cleanup
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/leader"
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -39,12 +43,72 @@ type Config struct {
 	SecretManager         secrets.Config
 	Storage               storage.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
+	Leader                leader.Config
+	Scheduler             scheduler.Config
+	Regions               region.Config
 
 	Port    string        `env:"PORT, default=8080"`
 	Timeout time.Duration `env:"CLEANUP_TIMEOUT, default=10m"`
 	TTL     time.Duration `env:"CLEANUP_TTL, default=336h"`
 
 	DebugOverrideCleanupMinDuration bool `env:"DEBUG_OVERRIDE_CLEANUP_MIN_DURATION, default=false"`
+
+	// Maintenance is run by cleanup-exposure after the purge.
+	Maintenance MaintenanceConfig
+
+	// Audit is the export bucket audit, run by cleanup-export.
+	Audit AuditConfig
+}
+
+// AuditConfig is the configuration of the export bucket audit, which
+// reconciles the export files in the database with the stored objects.
+type AuditConfig struct {
+	Timeout time.Duration `env:"EXPORT_AUDIT_TIMEOUT, default=10m"`
+
+	// OrphanGrace is the age a stored object must reach before it is reported
+	// as an orphan, so that files being written by a worker are not.
+	OrphanGrace time.Duration `env:"EXPORT_AUDIT_ORPHAN_GRACE, default=1h"`
+
+	// Repair deletes orphans and reopens the batches of missing and
+	// mismatched files so that they are exported again. Derived batches and
+	// batches whose keys may have been deleted are only reported.
+	Repair bool `env:"EXPORT_AUDIT_REPAIR, default=false"`
+
+	// MaxRepairs is the maximum number of repairs in a run.
+	MaxRepairs int `env:"EXPORT_AUDIT_MAX_REPAIRS, default=100"`
+
+	// MaxFindings is the maximum number of findings saved for each location.
+	// All findings are counted.
+	MaxFindings int `env:"EXPORT_AUDIT_MAX_FINDINGS, default=1000"`
+}
+
+// MaintenanceConfig is the configuration of table maintenance. The database
+// user must own the tables, which it does if it also runs the migrations.
+type MaintenanceConfig struct {
+	Enabled bool          `env:"MAINTENANCE_ENABLED, default=true"`
+	Timeout time.Duration `env:"MAINTENANCE_TIMEOUT, default=30m"`
+
+	// Tables are the heavy churn tables to maintain. Revisions update the
+	// Exposure table in place.
+	Tables []string `env:"MAINTENANCE_TABLES, default=Exposure,ExportBatch,ExportFile,Outbox"`
+
+	// AutovacuumScaleFactor and AutovacuumAnalyzeScaleFactor are set as the
+	// autovacuum thresholds of the tables, as fractions of the table. 0 leaves
+	// the thresholds unchanged.
+	AutovacuumScaleFactor        float64 `env:"MAINTENANCE_AUTOVACUUM_SCALE_FACTOR, default=0.02"`
+	AutovacuumAnalyzeScaleFactor float64 `env:"MAINTENANCE_AUTOVACUUM_ANALYZE_SCALE_FACTOR, default=0.01"`
+
+	// Vacuum vacuums and analyzes the tables.
+	Vacuum bool `env:"MAINTENANCE_VACUUM, default=true"`
+
+	// BloatWarnRatio logs a warning for tables with at least this fraction
+	// of dead tuples. 0 disables the warning.
+	BloatWarnRatio float64 `env:"MAINTENANCE_BLOAT_WARN_RATIO, default=0.2"`
+
+	// ReindexDeadRatio rebuilds the indexes of tables with at least this
+	// fraction of dead tuples, without blocking writes. 0 disables reindexing.
+	ReindexDeadRatio float64 `env:"MAINTENANCE_REINDEX_DEAD_RATIO, default=0"`
 }
 
 func (c *Config) BlobstoreConfig() *storage.Config {
@@ -0,0 +1,116 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleanup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/hashicorp/go-multierror"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// tableMaintainer is the subset of the database used by table maintenance.
+type tableMaintainer interface {
+	TableStats(ctx context.Context, table string) (*database.TableStats, error)
+	SetAutovacuumScaleFactors(ctx context.Context, table string, vacuum, analyze float64) error
+	Vacuum(ctx context.Context, table string) error
+	ReindexConcurrently(ctx context.Context, table string) error
+}
+
+// maintainTables reports the bloat of each configured table and vacuums it,
+// after the purge has left dead tuples behind. Maintenance of the remaining
+// tables continues if one fails.
+func maintainTables(ctx context.Context, db tableMaintainer, cfg *MaintenanceConfig) error {
+	var merr *multierror.Error
+	for _, table := range cfg.Tables {
+		if err := maintainTable(ctx, db, cfg, table); err != nil {
+			merr = multierror.Append(merr, err)
+		}
+	}
+	return merr.ErrorOrNil()
+}
+
+func maintainTable(ctx context.Context, db tableMaintainer, cfg *MaintenanceConfig, table string) error {
+	logger := logging.FromContext(ctx).Named("maintainTable").With("table", table)
+
+	before, err := db.TableStats(ctx, table)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			logger.Warnw("skipping unknown table")
+			return nil
+		}
+		return err
+	}
+	recordTableStats(ctx, before)
+
+	ratio := before.DeadRatio()
+	if cfg.BloatWarnRatio > 0 && ratio >= cfg.BloatWarnRatio {
+		logger.Warnw("table is bloated",
+			"dead_ratio", ratio,
+			"dead_tuples", before.DeadTuples,
+			"table_bytes", before.TableBytes,
+			"index_bytes", before.IndexBytes,
+			"last_vacuum", before.LastVacuum)
+	}
+
+	if cfg.AutovacuumScaleFactor > 0 {
+		if err := db.SetAutovacuumScaleFactors(ctx, table, cfg.AutovacuumScaleFactor, cfg.AutovacuumAnalyzeScaleFactor); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Vacuum {
+		if err := db.Vacuum(ctx, table); err != nil {
+			return err
+		}
+	}
+
+	// Vacuum makes the space of dead tuples reusable, but does not shrink
+	// indexes, which is what slows down export queries on a bloated table.
+	if cfg.ReindexDeadRatio > 0 && ratio >= cfg.ReindexDeadRatio {
+		logger.Infow("reindexing table", "dead_ratio", ratio)
+		if err := db.ReindexConcurrently(ctx, table); err != nil {
+			return err
+		}
+	}
+
+	after, err := db.TableStats(ctx, table)
+	if err != nil {
+		return err
+	}
+	logger.Infow("maintained table",
+		"dead_tuples_before", before.DeadTuples,
+		"dead_tuples_after", after.DeadTuples,
+		"index_bytes_before", before.IndexBytes,
+		"index_bytes_after", after.IndexBytes)
+	return nil
+}
+
+// recordTableStats records the size and bloat of the table.
+func recordTableStats(ctx context.Context, s *database.TableStats) {
+	logger := logging.FromContext(ctx)
+
+	if err := stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(tableTag, s.Table)},
+		mTableBytes.M(s.TableBytes+s.IndexBytes),
+		mTableDeadTuples.M(s.DeadTuples),
+		mTableDeadRatio.M(s.DeadRatio()),
+	); err != nil {
+		logger.Errorw("failed to record table stats", "table", s.Table, "error", err)
+	}
+}
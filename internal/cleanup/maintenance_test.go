@@ -0,0 +1,140 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeMaintainer records the maintenance of tables.
+type fakeMaintainer struct {
+	stats map[string]*database.TableStats
+	fail  string
+	calls []string
+}
+
+func (f *fakeMaintainer) TableStats(ctx context.Context, table string) (*database.TableStats, error) {
+	s, ok := f.stats[table]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeMaintainer) SetAutovacuumScaleFactors(ctx context.Context, table string, vacuum, analyze float64) error {
+	f.calls = append(f.calls, fmt.Sprintf("autovacuum %s %v %v", table, vacuum, analyze))
+	return nil
+}
+
+func (f *fakeMaintainer) Vacuum(ctx context.Context, table string) error {
+	if table == f.fail {
+		return fmt.Errorf("vacuuming %s: permission denied", table)
+	}
+	f.calls = append(f.calls, "vacuum "+table)
+	return nil
+}
+
+func (f *fakeMaintainer) ReindexConcurrently(ctx context.Context, table string) error {
+	f.calls = append(f.calls, "reindex "+table)
+	return nil
+}
+
+func TestMaintainTables(t *testing.T) {
+	t.Parallel()
+
+	tableStats := map[string]*database.TableStats{
+		"Exposure": {Table: "Exposure", LiveTuples: 60, DeadTuples: 40},
+		"Outbox":   {Table: "Outbox", LiveTuples: 99, DeadTuples: 1},
+	}
+
+	cases := []struct {
+		name  string
+		cfg   *MaintenanceConfig
+		fail  string
+		calls []string
+		err   string
+	}{
+		{
+			name: "default",
+			cfg: &MaintenanceConfig{
+				Tables:                       []string{"Exposure", "Missing", "Outbox"},
+				AutovacuumScaleFactor:        0.02,
+				AutovacuumAnalyzeScaleFactor: 0.01,
+				Vacuum:                       true,
+				BloatWarnRatio:               0.2,
+			},
+			calls: []string{
+				"autovacuum Exposure 0.02 0.01",
+				"vacuum Exposure",
+				"autovacuum Outbox 0.02 0.01",
+				"vacuum Outbox",
+			},
+		},
+		{
+			name: "reindex_bloated",
+			cfg: &MaintenanceConfig{
+				Tables:           []string{"Exposure", "Outbox"},
+				Vacuum:           true,
+				ReindexDeadRatio: 0.3,
+			},
+			calls: []string{
+				"vacuum Exposure",
+				"reindex Exposure",
+				"vacuum Outbox",
+			},
+		},
+		{
+			name: "report_only",
+			cfg: &MaintenanceConfig{
+				Tables:         []string{"Exposure", "Outbox"},
+				BloatWarnRatio: 0.2,
+			},
+		},
+		{
+			name: "continues_after_failure",
+			cfg: &MaintenanceConfig{
+				Tables: []string{"Exposure", "Outbox"},
+				Vacuum: true,
+			},
+			fail:  "Exposure",
+			calls: []string{"vacuum Outbox"},
+			err:   "vacuuming Exposure: permission denied",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			db := &fakeMaintainer{stats: tableStats, fail: tc.fail}
+
+			err := maintainTables(ctx, db, tc.cfg)
+			errcmp.MustMatch(t, err, tc.err)
+			if diff := cmp.Diff(tc.calls, db.calls); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
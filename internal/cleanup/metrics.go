@@ -19,6 +19,7 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/observability"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 )
 
 const metricPrefix = metrics.MetricRoot + "cleanup"
@@ -26,6 +27,19 @@ const metricPrefix = metrics.MetricRoot + "cleanup"
 var (
 	mExportSuccess   = stats.Int64(metricPrefix+"/export_success", "successful execution", stats.UnitDimensionless)
 	mExposureSuccess = stats.Int64(metricPrefix+"/exposure_success", "successful execution", stats.UnitDimensionless)
+
+	mTableBytes      = stats.Int64(metricPrefix+"/table_bytes", "size of a table and its indexes", stats.UnitBytes)
+	mTableDeadTuples = stats.Int64(metricPrefix+"/table_dead_tuples", "dead tuples in a table", stats.UnitDimensionless)
+	mTableDeadRatio  = stats.Float64(metricPrefix+"/table_dead_ratio", "fraction of dead tuples in a table", stats.UnitDimensionless)
+
+	mAuditSuccess  = stats.Int64(metricPrefix+"/export_audit_success", "successful execution", stats.UnitDimensionless)
+	mAuditFindings = stats.Int64(metricPrefix+"/export_audit_findings", "drift found by the export audit", stats.UnitDimensionless)
+	mAuditRepairs  = stats.Int64(metricPrefix+"/export_audit_repairs", "drift repaired by the export audit", stats.UnitDimensionless)
+
+	tableTag    = tag.MustNewKey("table")
+	kindTag     = tag.MustNewKey("kind")
+	locationTag = tag.MustNewKey("location")
+	resultTag   = tag.MustNewKey("result")
 )
 
 func init() {
@@ -42,5 +56,46 @@ func init() {
 			Measure:     mExposureSuccess,
 			Aggregation: view.Count(),
 		},
+		{
+			Name:        metricPrefix + "/table/bytes",
+			Description: "Size of a maintained table and its indexes, before maintenance",
+			Measure:     mTableBytes,
+			TagKeys:     []tag.Key{tableTag},
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        metricPrefix + "/table/dead_tuples",
+			Description: "Dead tuples in a maintained table, before maintenance",
+			Measure:     mTableDeadTuples,
+			TagKeys:     []tag.Key{tableTag},
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        metricPrefix + "/table/dead_ratio",
+			Description: "Fraction of dead tuples in a maintained table, before maintenance",
+			Measure:     mTableDeadRatio,
+			TagKeys:     []tag.Key{tableTag},
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        metricPrefix + "/export_audit/success",
+			Description: "Number of export audit successes",
+			Measure:     mAuditSuccess,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/export_audit/findings",
+			Description: "Drift of an export location found by the last audit, by kind",
+			Measure:     mAuditFindings,
+			TagKeys:     []tag.Key{locationTag, kindTag},
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        metricPrefix + "/export_audit/repairs",
+			Description: "Number of export audit repairs, by kind and result",
+			Measure:     mAuditRepairs,
+			TagKeys:     []tag.Key{kindTag, resultTag},
+			Aggregation: view.Sum(),
+		},
 	}...)
 }
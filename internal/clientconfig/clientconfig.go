@@ -0,0 +1,238 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clientconfig serves the signed configuration of the mobile apps:
+// risk weights, endpoint URLs and feature toggles, managed in the admin
+// console. Apps verify the signature with a pinned public key, so the
+// configuration can be fetched from the key server with the same integrity
+// guarantees as a separately operated configuration service.
+package clientconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	clientconfigdb "github.com/google/exposure-notifications-server/internal/clientconfig/database"
+	"github.com/google/exposure-notifications-server/internal/clientconfig/model"
+	"github.com/google/exposure-notifications-server/internal/jws"
+	"github.com/google/exposure-notifications-server/pkg/cache"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/render"
+)
+
+const (
+	// Path is the path at which the signed client configuration is served. The
+	// "app" query parameter is the package name of the app.
+	Path = "/v1/client-config"
+
+	// PublicKeyPath is the path at which the public key that verifies client
+	// configurations is served.
+	PublicKeyPath = Path + "/public-key"
+
+	// maxAppLength is the longest app package name that is stored.
+	maxAppLength = 1000
+)
+
+// Document is the signed payload of a client configuration.
+type Document struct {
+	AppPackageName string `json:"appPackageName"`
+
+	// Version increases each time the configuration is changed.
+	Version int64 `json:"version"`
+
+	// UpdatedAt is the unix timestamp at which the configuration was changed.
+	UpdatedAt int64 `json:"updatedAt"`
+
+	// GeneratedAt is the unix timestamp at which the document was signed.
+	GeneratedAt int64 `json:"generatedAt"`
+
+	RiskWeights map[string]float64 `json:"riskWeights"`
+	Endpoints   map[string]string  `json:"endpoints"`
+	Features    map[string]bool    `json:"features"`
+}
+
+// PublicKey is the response of the public key endpoint.
+type PublicKey struct {
+	KeyID     string `json:"keyID"`
+	PublicKey string `json:"publicKey"`
+}
+
+// Handler serves signed client configurations.
+type Handler struct {
+	config     *Config
+	db         *clientconfigdb.ClientConfigDB
+	keyManager keys.KeyManager
+	cache      *cache.Cache[*jws.JWS]
+	notFound   *cache.Cache[struct{}]
+	h          *render.Renderer
+}
+
+// New creates a new client configuration handler.
+func New(cfg *Config, db *database.DB, km keys.KeyManager) (*Handler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if km == nil {
+		return nil, fmt.Errorf("clientconfig requires a key manager")
+	}
+
+	c, err := cache.New[*jws.JWS](cfg.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+	notFound, err := cache.New[struct{}](cfg.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+
+	return &Handler{
+		config:     cfg,
+		db:         clientconfigdb.New(db),
+		keyManager: km,
+		cache:      c,
+		notFound:   notFound,
+		h:          render.NewRenderer(),
+	}, nil
+}
+
+// ServeHTTP renders the signed configuration of the app in the "app" query
+// parameter.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx).Named("clientconfig")
+
+	app := r.URL.Query().Get("app")
+	if app == "" || len(app) > maxAppLength {
+		h.h.RenderJSON(w, http.StatusBadRequest, fmt.Errorf("invalid app %q", app))
+		return
+	}
+
+	// Unknown apps are cached too, so they don't reach the database on every
+	// request.
+	if _, ok := h.notFound.Lookup(app); ok {
+		h.h.RenderJSON(w, http.StatusNotFound, nil)
+		return
+	}
+
+	doc, err := h.cache.WriteThruLookup(app, func() (*jws.JWS, error) {
+		return h.signedDocument(ctx, app, time.Now().UTC())
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			if err := h.notFound.Set(app, struct{}{}); err != nil {
+				logger.Errorw("failed to cache unknown app", "error", err)
+			}
+			h.h.RenderJSON(w, http.StatusNotFound, nil)
+			return
+		}
+
+		logger.Errorw("failed to build client config", "app", app, "error", err)
+		h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.CacheDuration.Seconds())))
+	h.h.RenderJSON(w, http.StatusOK, doc)
+}
+
+// PublicKeyHandler returns a handler that serves the public key that verifies
+// client configurations.
+func (h *Handler) PublicKeyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("clientconfig")
+
+		pemKey, err := h.publicKeyPEM(ctx)
+		if err != nil {
+			logger.Errorw("failed to get public key", "error", err)
+			h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.CacheDuration.Seconds())))
+		h.h.RenderJSON(w, http.StatusOK, &PublicKey{
+			KeyID:     h.config.SigningKeyID,
+			PublicKey: pemKey,
+		})
+	})
+}
+
+// NewDocument builds the signed payload of a client configuration.
+func NewDocument(c *model.ClientConfig, now time.Time) *Document {
+	doc := &Document{
+		AppPackageName: c.AppPackageName,
+		Version:        c.Version,
+		UpdatedAt:      c.UpdatedAt.Unix(),
+		GeneratedAt:    now.Unix(),
+		RiskWeights:    c.RiskWeights,
+		Endpoints:      c.Endpoints,
+		Features:       c.Features,
+	}
+
+	// Apps get empty objects rather than nulls.
+	if doc.RiskWeights == nil {
+		doc.RiskWeights = map[string]float64{}
+	}
+	if doc.Endpoints == nil {
+		doc.Endpoints = map[string]string{}
+	}
+	if doc.Features == nil {
+		doc.Features = map[string]bool{}
+	}
+	return doc
+}
+
+func (h *Handler) signedDocument(ctx context.Context, app string, now time.Time) (*jws.JWS, error) {
+	c, err := h.db.GetClientConfig(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(NewDocument(c, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	signer, err := h.keyManager.NewSigner(ctx, h.config.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signer: %w", err)
+	}
+	return jws.Sign(signer, jws.Header{KeyID: h.config.SigningKeyID}, payload)
+}
+
+// publicKeyPEM returns the PEM encoded public key of the signing key.
+func (h *Handler) publicKeyPEM(ctx context.Context) (string, error) {
+	signer, err := h.keyManager.NewSigner(ctx, h.config.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get signer: %w", err)
+	}
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("unsupported public key type %T", signer.Public())
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	clientconfigdb "github.com/google/exposure-notifications-server/internal/clientconfig/database"
+	"github.com/google/exposure-notifications-server/internal/clientconfig/model"
+	"github.com/google/exposure-notifications-server/internal/jws"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var testDatabaseInstance *database.TestInstance
+
+func TestMain(m *testing.M) {
+	testDatabaseInstance = database.MustTestInstance()
+	defer testDatabaseInstance.MustClose()
+	m.Run()
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	km := keys.TestKeyManager(t)
+	signingKey := keys.TestSigningKey(t, km)
+
+	if err := clientconfigdb.New(testDB).SaveClientConfig(ctx, &model.ClientConfig{
+		AppPackageName: "com.example.app",
+		RiskWeights:    map[string]float64{"attenuationNear": 1.5},
+		Endpoints:      map[string]string{"publish": "https://publish.example.com/v1/publish"},
+		Features:       map[string]bool{"selfReport": true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := New(&Config{
+		SigningKey:    signingKey,
+		SigningKeyID:  "config-v1",
+		CacheDuration: time.Minute,
+	}, testDB, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The public key verifies the signed configuration.
+	r := httptest.NewRequest(http.MethodGet, PublicKeyPath, nil)
+	w := httptest.NewRecorder()
+	h.PublicKeyHandler().ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+	var key PublicKey
+	if err := json.Unmarshal(w.Body.Bytes(), &key); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := key.KeyID, "config-v1"; got != want {
+		t.Errorf("expected key ID %q, got %q", want, got)
+	}
+	block, _ := pem.Decode([]byte(key.PublicKey))
+	if block == nil {
+		t.Fatalf("invalid public key PEM %q", key.PublicKey)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		t.Fatalf("expected ECDSA public key, got %T", parsed)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, Path+"?app=com.example.app", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	var signed jws.JWS
+	if err := json.Unmarshal(w.Body.Bytes(), &signed); err != nil {
+		t.Fatal(err)
+	}
+	if err := signed.Verify(pub); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Document
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Document{
+		AppPackageName: "com.example.app",
+		Version:        1,
+		RiskWeights:    map[string]float64{"attenuationNear": 1.5},
+		Endpoints:      map[string]string{"publish": "https://publish.example.com/v1/publish"},
+		Features:       map[string]bool{"selfReport": true},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Document{}, "UpdatedAt", "GeneratedAt")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	km := keys.TestKeyManager(t)
+
+	h, err := New(&Config{
+		SigningKey:    keys.TestSigningKey(t, km),
+		CacheDuration: time.Minute,
+	}, testDB, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{
+			name:   "missing_app",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "long_app",
+			query:  "?app=" + strings.Repeat("a", maxAppLength+1),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown_app",
+			query:  "?app=com.example.unknown",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, Path+tc.query, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if got, want := w.Code, tc.status; got != want {
+				t.Errorf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+	got := NewDocument(&model.ClientConfig{
+		AppPackageName: "com.example.app",
+		Version:        3,
+		UpdatedAt:      now.Add(-time.Hour),
+	}, now)
+
+	want := &Document{
+		AppPackageName: "com.example.app",
+		Version:        3,
+		UpdatedAt:      now.Add(-time.Hour).Unix(),
+		GeneratedAt:    now.Unix(),
+		RiskWeights:    map[string]float64{},
+		Endpoints:      map[string]string{},
+		Features:       map[string]bool{},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(&Config{}, nil, keys.TestKeyManager(t)); err == nil {
+		t.Errorf("expected error without signing key")
+	}
+	if _, err := New(&Config{SigningKey: "key"}, nil, nil); err == nil {
+		t.Errorf("expected error without key manager")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientconfig
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the configuration for the client configuration endpoint.
+type Config struct {
+	// Enabled serves the signed client configuration at Path and its
+	// verification key at PublicKeyPath.
+	Enabled bool `env:"CLIENT_CONFIG_ENABLED, default=false"`
+
+	// SigningKey is the ID of the key in the key manager used to sign client
+	// configurations. SigningKeyID is the "kid" in the signature header.
+	SigningKey   string `env:"CLIENT_CONFIG_SIGNING_KEY"`
+	SigningKeyID string `env:"CLIENT_CONFIG_SIGNING_KEY_ID, default=v1"`
+
+	// CacheDuration is how long a signed configuration, or the absence of one,
+	// is cached. Changes made in the admin console are served after at most
+	// this long.
+	CacheDuration time.Duration `env:"CLIENT_CONFIG_CACHE_DURATION, default=5m"`
+}
+
+// Validate checks the configuration.
+func (c *Config) Validate() error {
+	if c.SigningKey == "" {
+		return fmt.Errorf("CLIENT_CONFIG_SIGNING_KEY is required")
+	}
+	return nil
+}
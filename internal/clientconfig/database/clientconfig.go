@@ -0,0 +1,160 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface for signed client configuration.
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/clientconfig/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type ClientConfigDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *ClientConfigDB {
+	return &ClientConfigDB{
+		db: db,
+	}
+}
+
+// ListClientConfigs returns all client configurations, ordered by app package
+// name.
+func (db *ClientConfigDB) ListClientConfigs(ctx context.Context) ([]*model.ClientConfig, error) {
+	var configs []*model.ClientConfig
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				app_package_name, risk_weights, endpoints, features, version, updated_at
+			FROM
+				ClientConfig
+			ORDER BY app_package_name
+		`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			c, err := scanOneClientConfig(rows)
+			if err != nil {
+				return err
+			}
+			configs = append(configs, c)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list client configs: %w", err)
+	}
+
+	return configs, nil
+}
+
+// GetClientConfig returns the client configuration of the app, or
+// database.ErrNotFound.
+func (db *ClientConfigDB) GetClientConfig(ctx context.Context, appPackageName string) (*model.ClientConfig, error) {
+	var config *model.ClientConfig
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				app_package_name, risk_weights, endpoints, features, version, updated_at
+			FROM
+				ClientConfig
+			WHERE app_package_name = $1
+		`, appPackageName)
+
+		var err error
+		config, err = scanOneClientConfig(row)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return database.ErrNotFound
+			}
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get client config: %w", err)
+	}
+
+	return config, nil
+}
+
+// SaveClientConfig creates or updates a client configuration. Each save
+// increments the version.
+func (db *ClientConfigDB) SaveClientConfig(ctx context.Context, c *model.ClientConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	riskWeights := c.RiskWeights
+	if riskWeights == nil {
+		riskWeights = map[string]float64{}
+	}
+	endpoints := c.Endpoints
+	if endpoints == nil {
+		endpoints = map[string]string{}
+	}
+	features := c.Features
+	if features == nil {
+		features = map[string]bool{}
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				ClientConfig (app_package_name, risk_weights, endpoints, features, version, updated_at)
+			VALUES
+				($1, $2, $3, $4, 1, now())
+			ON CONFLICT (tenant, app_package_name) DO UPDATE
+				SET risk_weights = EXCLUDED.risk_weights, endpoints = EXCLUDED.endpoints,
+					features = EXCLUDED.features,
+					version = ClientConfig.version + 1,
+					updated_at = EXCLUDED.updated_at
+			RETURNING version, updated_at
+		`, c.AppPackageName, riskWeights, endpoints, features)
+		if err := row.Scan(&c.Version, &c.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to save client config: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteClientConfig deletes the client configuration of the app.
+func (db *ClientConfigDB) DeleteClientConfig(ctx context.Context, appPackageName string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM ClientConfig WHERE app_package_name = $1
+		`, appPackageName); err != nil {
+			return fmt.Errorf("failed to delete client config: %w", err)
+		}
+		return nil
+	})
+}
+
+func scanOneClientConfig(row pgx.Row) (*model.ClientConfig, error) {
+	var c model.ClientConfig
+	if err := row.Scan(&c.AppPackageName, &c.RiskWeights, &c.Endpoints, &c.Features,
+		&c.Version, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/clientconfig/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestClientConfigs(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	configDB := New(testDB)
+
+	if err := configDB.SaveClientConfig(ctx, &model.ClientConfig{}); err == nil {
+		t.Errorf("expected error for invalid client config")
+	}
+
+	if _, err := configDB.GetClientConfig(ctx, "com.example.app"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	config := &model.ClientConfig{
+		AppPackageName: "com.example.app",
+		RiskWeights:    map[string]float64{"attenuationNear": 1.5},
+		Endpoints:      map[string]string{"publish": "https://publish.example.com/v1/publish"},
+	}
+	if err := configDB.SaveClientConfig(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := config.Version, int64(1); got != want {
+		t.Errorf("expected version %d, got %d", want, got)
+	}
+
+	// Saving again increments the version.
+	config.Features = map[string]bool{"selfReport": true}
+	if err := configDB.SaveClientConfig(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := config.Version, int64(2); got != want {
+		t.Errorf("expected version %d, got %d", want, got)
+	}
+
+	got, err := configDB.GetClientConfig(ctx, config.AppPackageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(config, got, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	list, err := configDB.ListClientConfigs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.ClientConfig{config}, list, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if err := configDB.DeleteClientConfig(ctx, config.AppPackageName); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := configDB.GetClientConfig(ctx, config.AppPackageName); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction for signed client configuration.
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClientConfig is the configuration served to the mobile app of an authorized
+// app. The key server signs it, so apps can fetch it from the key server
+// instead of a separately operated configuration service.
+type ClientConfig struct {
+	// AppPackageName is the authorized app the configuration is for.
+	AppPackageName string
+
+	// RiskWeights are the weights the app uses to score exposures, by name,
+	// such as "attenuationNear" or "reportTypeConfirmed".
+	RiskWeights map[string]float64
+
+	// Endpoints are the URLs of the services the app uses, by name, such as
+	// "publish" or "verification".
+	Endpoints map[string]string
+
+	// Features toggles app features by name.
+	Features map[string]bool
+
+	// Version increases each time the configuration is saved, so apps can
+	// tell newer configurations apart.
+	Version   int64
+	UpdatedAt time.Time
+}
+
+// Validate returns an error if the configuration is not valid.
+func (c *ClientConfig) Validate() error {
+	c.AppPackageName = strings.TrimSpace(c.AppPackageName)
+	if c.AppPackageName == "" {
+		return errors.New("app package name is required")
+	}
+
+	for name, weight := range c.RiskWeights {
+		if name == "" {
+			return errors.New("risk weight name is required")
+		}
+		if math.IsNaN(weight) || math.IsInf(weight, 0) || weight < 0 {
+			return fmt.Errorf("risk weight %q must be a non-negative number, got %v", name, weight)
+		}
+	}
+
+	for name, raw := range c.Endpoints {
+		if name == "" {
+			return errors.New("endpoint name is required")
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("endpoint %q: %w", name, err)
+		}
+		if u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("endpoint %q must be an https URL, got %q", name, raw)
+		}
+	}
+
+	for name := range c.Features {
+		if name == "" {
+			return errors.New("feature name is required")
+		}
+	}
+	return nil
+}
+
+// RiskWeightsOnePerLine returns the risk weights as name=weight lines, sorted
+// by name.
+func (c *ClientConfig) RiskWeightsOnePerLine() string {
+	lines := make([]string, 0, len(c.RiskWeights))
+	for name, weight := range c.RiskWeights {
+		lines = append(lines, name+"="+strconv.FormatFloat(weight, 'g', -1, 64))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// EndpointsOnePerLine returns the endpoints as name=URL lines, sorted by name.
+func (c *ClientConfig) EndpointsOnePerLine() string {
+	lines := make([]string, 0, len(c.Endpoints))
+	for name, u := range c.Endpoints {
+		lines = append(lines, name+"="+u)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// FeaturesOnePerLine returns the features as name=true or name=false lines,
+// sorted by name.
+func (c *ClientConfig) FeaturesOnePerLine() string {
+	lines := make([]string, 0, len(c.Features))
+	for name, enabled := range c.Features {
+		lines = append(lines, name+"="+strconv.FormatBool(enabled))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClientConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		config *ClientConfig
+		err    bool
+	}{
+		{
+			name: "valid",
+			config: &ClientConfig{
+				AppPackageName: " com.example.app ",
+				RiskWeights:    map[string]float64{"attenuationNear": 1.5},
+				Endpoints:      map[string]string{"publish": "https://publish.example.com/v1/publish"},
+				Features:       map[string]bool{"selfReport": true},
+			},
+		},
+		{
+			name:   "missing_app",
+			config: &ClientConfig{},
+			err:    true,
+		},
+		{
+			name: "negative_weight",
+			config: &ClientConfig{
+				AppPackageName: "com.example.app",
+				RiskWeights:    map[string]float64{"attenuationNear": -1},
+			},
+			err: true,
+		},
+		{
+			name: "nan_weight",
+			config: &ClientConfig{
+				AppPackageName: "com.example.app",
+				RiskWeights:    map[string]float64{"attenuationNear": math.NaN()},
+			},
+			err: true,
+		},
+		{
+			name: "http_endpoint",
+			config: &ClientConfig{
+				AppPackageName: "com.example.app",
+				Endpoints:      map[string]string{"publish": "http://publish.example.com"},
+			},
+			err: true,
+		},
+		{
+			name: "relative_endpoint",
+			config: &ClientConfig{
+				AppPackageName: "com.example.app",
+				Endpoints:      map[string]string{"publish": "/v1/publish"},
+			},
+			err: true,
+		},
+		{
+			name: "empty_feature",
+			config: &ClientConfig{
+				AppPackageName: "com.example.app",
+				Features:       map[string]bool{"": true},
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.config.Validate()
+			if got := err != nil; got != tc.err {
+				t.Errorf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestClientConfig_OnePerLine(t *testing.T) {
+	t.Parallel()
+
+	c := &ClientConfig{
+		RiskWeights: map[string]float64{"b": 0.5, "a": 2},
+		Endpoints:   map[string]string{"verification": "https://v.example.com", "publish": "https://p.example.com"},
+		Features:    map[string]bool{"y": false, "x": true},
+	}
+
+	if got, want := c.RiskWeightsOnePerLine(), "a=2\nb=0.5"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := c.EndpointsOnePerLine(), "publish=https://p.example.com\nverification=https://v.example.com"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := c.FeaturesOnePerLine(), "x=true\ny=false"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
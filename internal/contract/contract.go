@@ -0,0 +1,292 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contract describes the API contract with clients: the JSON types of
+// pkg/api/v1 and the protocol buffer messages of exports, federation and
+// revision tokens. The contract is compared to a golden file, so that changes
+// which would break client compatibility are caught before they ship.
+//
+// A contract is a set of entries, one per type, JSON field, constant, message,
+// message field, enum value or RPC. Entries that are removed or changed break
+// compatibility. Entries that are added do not, but must be recorded in the
+// golden file.
+package contract
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	"github.com/google/exposure-notifications-server/internal/pb/export"
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+	federationv2 "github.com/google/exposure-notifications-server/internal/pb/federation/v2"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// header is the first line of a marshaled contract.
+const header = "# API contract. Regenerate with: go run ./tools/contract-check -update"
+
+// Contract maps an element of the API to its description.
+type Contract map[string]string
+
+// Current returns the contract of the Go package in apiDir, which is
+// pkg/api/v1, and of the protocol buffers.
+func Current(fsys fs.FS, apiDir string) (Contract, error) {
+	c, err := FromGoPackage(fsys, apiDir, "api/v1")
+	if err != nil {
+		return nil, err
+	}
+	c.merge(FromProto(
+		export.File_internal_pb_export_export_proto,
+		federation.File_internal_pb_federation_federation_proto,
+		federationv2.File_internal_pb_federation_v2_federation_proto,
+		pb.File_internal_pb_revision_proto,
+	))
+	return c, nil
+}
+
+func (c Contract) merge(other Contract) {
+	for k, v := range other {
+		c[k] = v
+	}
+}
+
+// FromGoPackage returns the contract of the exported types and constants of
+// the Go package in dir. Struct fields are described by their JSON name and
+// whether they are always present: fields with omitempty and pointers are
+// optional.
+func FromGoPackage(fsys fs.FS, dir, name string) (Contract, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	c := make(Contract)
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+
+		p := dir + "/" + e.Name()
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		f, err := parser.ParseFile(fset, p, src, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						addGoType(c, fset, "go "+name+"."+spec.Name.Name, spec.Type)
+					}
+				case *ast.ValueSpec:
+					if gen.Tok != token.CONST {
+						continue
+					}
+					for i, n := range spec.Names {
+						if n.IsExported() && i < len(spec.Values) {
+							c["go "+name+"."+n.Name] = expr(fset, spec.Values[i])
+						}
+					}
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+func addGoType(c Contract, fset *token.FileSet, key string, typ ast.Expr) {
+	st, ok := typ.(*ast.StructType)
+	if !ok {
+		c[key] = expr(fset, typ)
+		return
+	}
+
+	c[key] = "struct"
+	for _, field := range st.Fields.List {
+		fieldType := expr(fset, field.Type)
+		if len(field.Names) == 0 {
+			c[key+".("+fieldType+")"] = "embedded"
+			continue
+		}
+
+		for _, n := range field.Names {
+			if !n.IsExported() {
+				continue
+			}
+			jsonName, omitempty := n.Name, false
+			if field.Tag != nil {
+				tag, err := strconv.Unquote(field.Tag.Value)
+				if err == nil {
+					if v, ok := reflect.StructTag(tag).Lookup("json"); ok {
+						parts := strings.Split(v, ",")
+						if parts[0] == "-" {
+							continue
+						}
+						if parts[0] != "" {
+							jsonName = parts[0]
+						}
+						for _, opt := range parts[1:] {
+							omitempty = omitempty || opt == "omitempty"
+						}
+					}
+				}
+			}
+
+			presence := "required"
+			if _, ptr := field.Type.(*ast.StarExpr); omitempty || ptr {
+				presence = "optional"
+			}
+			c[key+"."+jsonName] = fieldType + " " + presence
+		}
+	}
+}
+
+func expr(fset *token.FileSet, e ast.Expr) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, e); err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// FromProto returns the contract of the messages, enums and services of the
+// files, keyed by the file, since some of them have no package. Fields are
+// keyed by number, as they are on the wire.
+func FromProto(files ...protoreflect.FileDescriptor) Contract {
+	c := make(Contract)
+	for _, fd := range files {
+		prefix := "proto " + fd.Path() + " "
+		addMessages(c, prefix, fd.Messages())
+		addEnums(c, prefix, fd.Enums())
+
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			sd := services.Get(i)
+			c[prefix+string(sd.FullName())] = "service"
+
+			methods := sd.Methods()
+			for j := 0; j < methods.Len(); j++ {
+				md := methods.Get(j)
+				c[prefix+string(md.FullName())] = fmt.Sprintf("rpc %s%s -> %s%s",
+					streaming(md.IsStreamingClient()), md.Input().FullName(),
+					streaming(md.IsStreamingServer()), md.Output().FullName())
+			}
+		}
+	}
+	return c
+}
+
+func streaming(b bool) string {
+	if b {
+		return "stream "
+	}
+	return ""
+}
+
+func addMessages(c Contract, prefix string, messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		c[prefix+string(md.FullName())] = "message"
+
+		fields := md.Fields()
+		for j := 0; j < fields.Len(); j++ {
+			f := fields.Get(j)
+
+			kind := f.Kind().String()
+			switch {
+			case f.Message() != nil:
+				kind = string(f.Message().FullName())
+			case f.Enum() != nil:
+				kind = string(f.Enum().FullName())
+			}
+			c[fmt.Sprintf("%s%s.%d", prefix, md.FullName(), f.Number())] = fmt.Sprintf("%s json=%s %s %s",
+				f.Name(), f.JSONName(), f.Cardinality(), kind)
+		}
+
+		addMessages(c, prefix, md.Messages())
+		addEnums(c, prefix, md.Enums())
+	}
+}
+
+func addEnums(c Contract, prefix string, enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		ed := enums.Get(i)
+		c[prefix+string(ed.FullName())] = "enum"
+
+		values := ed.Values()
+		for j := 0; j < values.Len(); j++ {
+			v := values.Get(j)
+			c[prefix+string(ed.FullName())+"."+string(v.Name())] = strconv.Itoa(int(v.Number()))
+		}
+	}
+}
+
+// Marshal returns the contract as sorted "key = value" lines.
+func (c Contract) Marshal() []byte {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, header)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s = %s\n", k, c[k])
+	}
+	return buf.Bytes()
+}
+
+// Parse parses a marshaled contract. Blank lines and lines starting with "#"
+// are ignored.
+func Parse(b []byte) (Contract, error) {
+	c := make(Contract)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, " = ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key = value\", got %q", n, line)
+		}
+		c[k] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read contract: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contract
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestGolden fails when the API contract differs from the golden file. Run
+// "go run ./tools/contract-check" from the repository root to see whether the
+// difference breaks clients, and record intended changes with -update.
+func TestGolden(t *testing.T) {
+	t.Parallel()
+
+	current, err := Current(os.DirFS("../.."), "pkg/api/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile("testdata/contract.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := Compare(golden, current); !diff.Empty() {
+		t.Errorf("API contract changed, breaking: %t\n%s", diff.Breaking(), diff)
+	}
+}
+
+func TestFromGoPackage(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"api/types.go": {Data: []byte(`package api
+
+const (
+	ErrorBadRequest = "bad_request"
+	unexported      = "x"
+)
+
+var NotAConstant = 1
+
+type Request struct {
+	Embedded
+
+	Keys     []Key  ` + "`json:\"keys\"`" + `
+	Token    string ` + "`json:\"token,omitempty\"`" + `
+	Consent  *bool  ` + "`json:\"consent\"`" + `
+	Untagged int
+	Ignored  string ` + "`json:\"-\"`" + `
+	internal string
+}
+
+type Keys []*Key
+`)},
+		"api/types_test.go": {Data: []byte(`package api
+
+type Ignored struct{}
+`)},
+	}
+
+	got, err := FromGoPackage(fsys, "api", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Contract{
+		"go api.ErrorBadRequest":    `"bad_request"`,
+		"go api.Request":            "struct",
+		"go api.Request.(Embedded)": "embedded",
+		"go api.Request.keys":       "[]Key required",
+		"go api.Request.token":      "string optional",
+		"go api.Request.consent":    "*bool optional",
+		"go api.Request.Untagged":   "int required",
+		"go api.Keys":               "[]*Key",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMarshalParse(t *testing.T) {
+	t.Parallel()
+
+	c := Contract{
+		"go api.Request.keys": "[]Key required",
+		"proto a.proto Msg.1": "key json=key optional bytes",
+	}
+
+	got, err := Parse(c.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(c, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := Parse([]byte("no separator")); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	golden := Contract{
+		"go api.Request.keys":  "[]Key required",
+		"go api.Request.token": "string optional",
+		"go api.Request.nonce": "string optional",
+	}
+
+	cases := []struct {
+		name     string
+		current  Contract
+		breaking bool
+		empty    bool
+	}{
+		{
+			name:    "same",
+			current: golden,
+			empty:   true,
+		},
+		{
+			name: "added",
+			current: Contract{
+				"go api.Request.keys":    "[]Key required",
+				"go api.Request.token":   "string optional",
+				"go api.Request.nonce":   "string optional",
+				"go api.Request.padding": "string required",
+			},
+		},
+		{
+			name: "removed",
+			current: Contract{
+				"go api.Request.keys":  "[]Key required",
+				"go api.Request.token": "string optional",
+			},
+			breaking: true,
+		},
+		{
+			name: "changed",
+			current: Contract{
+				"go api.Request.keys":  "[]Key required",
+				"go api.Request.token": "string required",
+				"go api.Request.nonce": "string optional",
+			},
+			breaking: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			diff := Compare(golden, tc.current)
+			if got := diff.Breaking(); got != tc.breaking {
+				t.Errorf("expected breaking %t, got %t:\n%s", tc.breaking, got, diff)
+			}
+			if got := diff.Empty(); got != tc.empty {
+				t.Errorf("expected empty %t, got %t:\n%s", tc.empty, got, diff)
+			}
+		})
+	}
+}
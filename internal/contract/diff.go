@@ -0,0 +1,86 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contract
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change is a difference of an entry between two contracts. Old is empty for
+// added entries and New is empty for removed entries.
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// Diff is the difference between a golden contract and the current one.
+type Diff struct {
+	Removed []*Change
+	Changed []*Change
+	Added   []*Change
+}
+
+// Compare returns the difference from golden to current.
+func Compare(golden, current Contract) *Diff {
+	var d Diff
+	for k, old := range golden {
+		v, ok := current[k]
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, &Change{Key: k, Old: old})
+		case v != old:
+			d.Changed = append(d.Changed, &Change{Key: k, Old: old, New: v})
+		}
+	}
+	for k, v := range current {
+		if _, ok := golden[k]; !ok {
+			d.Added = append(d.Added, &Change{Key: k, New: v})
+		}
+	}
+
+	for _, changes := range [][]*Change{d.Removed, d.Changed, d.Added} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Key < changes[j].Key
+		})
+	}
+	return &d
+}
+
+// Breaking returns true if an entry was removed or changed.
+func (d *Diff) Breaking() bool {
+	return len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// Empty returns true if the contracts are the same.
+func (d *Diff) Empty() bool {
+	return !d.Breaking() && len(d.Added) == 0
+}
+
+func (d *Diff) String() string {
+	var b strings.Builder
+	for _, c := range d.Removed {
+		fmt.Fprintf(&b, "BREAKING removed %s (was %s)\n", c.Key, c.Old)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "BREAKING changed %s from %s to %s\n", c.Key, c.Old, c.New)
+	}
+	for _, c := range d.Added {
+		fmt.Fprintf(&b, "added %s = %s\n", c.Key, c.New)
+	}
+	return b.String()
+}
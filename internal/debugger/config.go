@@ -16,6 +16,7 @@ package debugger
 
 import (
 	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -39,6 +40,7 @@ type Config struct {
 	KeyManager    keys.Config
 	SecretManager secrets.Config
 	Storage       storage.Config
+	Middleware    middleware.Config
 
 	Port string `env:"PORT, default=8080"`
 }
@@ -18,7 +18,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/google/exposure-notifications-server/pkg/server"
@@ -59,10 +58,7 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 	logger := logging.FromContext(ctx).Named("debugger")
 
 	r := mux.NewRouter()
-	r.Use(middleware.Recovery())
-	r.Use(middleware.PopulateRequestID())
-	r.Use(middleware.PopulateObservability())
-	r.Use(middleware.PopulateLogger(logger))
+	s.config.Middleware.Use(r, logger)
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/", s.handleDebug())
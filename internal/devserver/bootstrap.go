@@ -0,0 +1,91 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	exportmodel "github.com/google/exposure-notifications-server/internal/export/model"
+	revisiondatabase "github.com/google/exposure-notifications-server/internal/revision/database"
+	"github.com/google/exposure-notifications-server/internal/seed"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+)
+
+// exportSigningKeyName is the name of the signing key used for exports.
+const exportSigningKeyName = "export-signer"
+
+// Bootstrap seeds the database with the standard seed data, then creates an
+// initial revision key, an export signing key, and an export config which
+// writes to the local export directory.
+func Bootstrap(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) error {
+	db := env.Database()
+	km := env.KeyManager()
+
+	if err := seed.Seed(ctx, db, km); err != nil {
+		return fmt.Errorf("failed to seed database: %w", err)
+	}
+
+	revisionDB, err := revisiondatabase.New(db, &revisiondatabase.KMSConfig{
+		WrapperKeyID: cfg.RevisionToken.KeyID,
+		KeyManager:   km,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create revision database: %w", err)
+	}
+	if _, err := revisionDB.CreateRevisionKey(ctx); err != nil {
+		return fmt.Errorf("failed to create revision key: %w", err)
+	}
+
+	version, err := seed.SigningKeyVersion(ctx, km, exportSigningKeyName)
+	if err != nil {
+		return fmt.Errorf("failed to create export signing key: %w", err)
+	}
+
+	exportDB := exportdatabase.New(db)
+	signatureInfo := &exportmodel.SignatureInfo{
+		SigningKey:        version.KeyID(),
+		SigningKeyVersion: "v1",
+		SigningKeyID:      cfg.ExportRegion,
+	}
+	if err := exportDB.AddSignatureInfo(ctx, signatureInfo); err != nil {
+		return fmt.Errorf("failed to add signature info: %w", err)
+	}
+
+	// The filesystem blobstore does not create intermediate directories.
+	filenameRoot := strings.ToLower(cfg.ExportRegion)
+	if err := os.MkdirAll(filepath.Join(cfg.ExportRoot, filenameRoot), 0o700); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	exportConfig := &exportmodel.ExportConfig{
+		BucketName:       cfg.ExportRoot,
+		FilenameRoot:     filenameRoot,
+		Period:           15 * time.Minute,
+		OutputRegion:     cfg.ExportRegion,
+		From:             time.Now().UTC().Add(-1 * time.Hour),
+		SignatureInfoIDs: []int64{signatureInfo.ID},
+	}
+	if err := exportDB.AddExportConfig(ctx, exportConfig); err != nil {
+		return fmt.Errorf("failed to add export config: %w", err)
+	}
+
+	return nil
+}
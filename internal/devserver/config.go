@@ -0,0 +1,83 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package devserver runs the entire key server locally, backed by a
+// throwaway database, for client and server development.
+package devserver
+
+import (
+	"github.com/google/exposure-notifications-server/internal/monolith"
+	"github.com/sethvargo/go-envconfig"
+)
+
+// Config is the configuration for the development server. It includes the full
+// monolith configuration plus development-specific settings.
+type Config struct {
+	monolith.Config
+
+	// StartDatabase starts a new Postgres container using Docker. If false, the
+	// database configured by the DB_* environment variables is used instead.
+	StartDatabase bool   `env:"DEVSERVER_START_DATABASE, default=true"`
+	PostgresImage string `env:"DEVSERVER_POSTGRES_IMAGE, default=postgres:13-alpine"`
+
+	// MigrationsDir is the path to the database migrations. Migrations are
+	// always run on startup.
+	MigrationsDir string `env:"DEVSERVER_MIGRATIONS_DIR, default=migrations"`
+
+	// Seed populates the database with a health authority, authorized apps,
+	// signing keys, and an export config. This should be disabled when using
+	// an existing database that has already been seeded.
+	Seed bool `env:"DEVSERVER_SEED, default=true"`
+
+	// ExportRoot is the directory to which export files are written. ExportPort
+	// is the port on which the contents of ExportRoot are served over HTTP.
+	ExportRoot string `env:"DEVSERVER_EXPORT_ROOT, default=local/exports"`
+	ExportPort string `env:"DEVSERVER_EXPORT_PORT, default=8090"`
+
+	// ExportRegion is the region of the seeded export config.
+	ExportRegion string `env:"DEVSERVER_EXPORT_REGION, default=US"`
+}
+
+// DefaultLookuper returns a lookuper that reads from the environment, falling
+// back to defaults suitable for local development. Values set in the
+// environment always take precedence.
+func DefaultLookuper(keysDir string) envconfig.Lookuper {
+	return envconfig.MultiLookuper(
+		envconfig.OsLookuper(),
+		envconfig.MapLookuper(map[string]string{
+			"OBSERVABILITY_EXPORTER":        "NOOP",
+			"KEY_MANAGER":                   "FILESYSTEM",
+			"KEY_FILESYSTEM_ROOT":           keysDir,
+			"REVISION_TOKEN_KEY_ID":         "system/revision-token-encrypter",
+			"REVISION_TOKEN_AAD":            "48W/fnGCagSiEW8j8hanTQ==",
+			"SECRET_MANAGER":                "IN_MEMORY",
+			"BLOBSTORE":                     "FILESYSTEM",
+			"AUTHORIZED_APP_CACHE_DURATION": "1s",
+			"DB_SSLMODE":                    "disable",
+
+			"ADMIN_CONSOLE_PORT": "8081",
+
+			"PUBLISH_ALLOW_PARTIAL_REVISIONS":    "true",
+			"PUBLISH_SAME_DAY_KEY_POLICY":        "RELEASE",
+			"PUBLISH_DEBUG_LOG_BAD_CERTIFICATES": "true",
+
+			"EXPORT_EXPORT_FILE_MIN_RECORDS": "1",
+			"EXPORT_TRUNCATE_WINDOW":         "1m",
+			"EXPORT_MIN_WINDOW_AGE":          "1m",
+
+			"KEY_ROTATION_NEW_KEY_PERIOD":        "1h",
+			"KEY_ROTATION_DELETE_OLD_KEY_PERIOD": "2h",
+		}),
+	)
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devserver
+
+import (
+	"context"
+	"testing"
+
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/sethvargo/go-envconfig"
+)
+
+func TestDefaultLookuper(t *testing.T) {
+	t.Setenv("BLOBSTORE", "MEMORY")
+
+	var cfg Config
+	if err := envconfig.ProcessWith(context.Background(), &cfg, DefaultLookuper("/tmp/keys")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Environment values take precedence.
+	if got, want := cfg.Storage.Type, "MEMORY"; got != want {
+		t.Errorf("expected blobstore %q to be %q", got, want)
+	}
+
+	// Development defaults are used otherwise.
+	if got, want := cfg.KeyManager.FilesystemRoot, "/tmp/keys"; got != want {
+		t.Errorf("expected key root %q to be %q", got, want)
+	}
+	if got, want := cfg.Publish.SameDayKeyPolicy(), publishmodel.SameDayKeyPolicyRelease; got != want {
+		t.Errorf("expected same day key policy %q to be %q", got, want)
+	}
+	if got, want := cfg.Export.MinRecords, 1; got != want {
+		t.Errorf("expected export min records %d to be %d", got, want)
+	}
+	if got, want := cfg.StartDatabase, true; got != want {
+		t.Errorf("expected start database %t to be %t", got, want)
+	}
+}
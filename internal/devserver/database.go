@@ -0,0 +1,171 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/ory/dockertest"
+	"github.com/ory/dockertest/docker"
+	"github.com/sethvargo/go-envconfig"
+	"github.com/sethvargo/go-retry"
+
+	// imported to register the postgres migration driver.
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	// imported to register the "file" source migration driver.
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+const (
+	// databaseName, databaseUser, and databasePassword are the credentials for
+	// the local database. These values are only used for development.
+	databaseName     = "en-server"
+	databaseUser     = "en-server"
+	databasePassword = "development"
+)
+
+// Database is a Postgres instance running in a local Docker container.
+type Database struct {
+	pool      *dockertest.Pool
+	container *dockertest.Resource
+	host      string
+	port      string
+}
+
+// StartDatabase starts a new Postgres container from the given image
+// reference (e.g. postgres:13-alpine) and waits for it to accept connections.
+// The container is removed when Close is called.
+func StartDatabase(ctx context.Context, imageRef string) (*Database, error) {
+	parts := strings.SplitN(imageRef, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid reference for database container: %q", imageRef)
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database docker pool: %w", err)
+	}
+
+	container, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: parts[0],
+		Tag:        parts[1],
+		Env: []string{
+			"LANG=C",
+			"POSTGRES_DB=" + databaseName,
+			"POSTGRES_USER=" + databaseUser,
+			"POSTGRES_PASSWORD=" + databasePassword,
+		},
+	}, func(c *docker.HostConfig) {
+		c.AutoRemove = true
+		c.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start database container: %w", err)
+	}
+
+	host, port, err := net.SplitHostPort(container.GetHostPort("5432/tcp"))
+	if err != nil {
+		_ = pool.Purge(container)
+		return nil, fmt.Errorf("failed to parse database address: %w", err)
+	}
+
+	db := &Database{
+		pool:      pool,
+		container: container,
+		host:      host,
+		port:      port,
+	}
+
+	// Wait for the database to accept connections.
+	b := retry.WithMaxRetries(30, retry.NewConstant(1*time.Second))
+	if err := retry.Do(ctx, b, func(ctx context.Context) error {
+		conn, err := pgx.Connect(ctx, db.Config().ConnectionURL())
+		if err != nil {
+			return retry.RetryableError(err)
+		}
+		defer conn.Close(ctx)
+
+		if err := conn.Ping(ctx); err != nil {
+			return retry.RetryableError(err)
+		}
+		return nil
+	}); err != nil {
+		_ = pool.Purge(container)
+		return nil, fmt.Errorf("failed waiting for database container to be ready: %w", err)
+	}
+
+	return db, nil
+}
+
+// Config returns the database configuration for connecting to the container.
+func (d *Database) Config() *database.Config {
+	return &database.Config{
+		Name:     databaseName,
+		User:     databaseUser,
+		Host:     d.host,
+		Port:     d.port,
+		SSLMode:  "disable",
+		Password: databasePassword,
+	}
+}
+
+// Lookuper returns a lookuper for the DB_* environment variables which point
+// at the container. It should take precedence over any other lookupers.
+func (d *Database) Lookuper() envconfig.Lookuper {
+	return envconfig.MapLookuper(map[string]string{
+		"DB_NAME":     databaseName,
+		"DB_USER":     databaseUser,
+		"DB_HOST":     d.host,
+		"DB_PORT":     d.port,
+		"DB_SSLMODE":  "disable",
+		"DB_PASSWORD": databasePassword,
+	})
+}
+
+// Close stops and removes the database container.
+func (d *Database) Close() error {
+	if err := d.pool.Purge(d.container); err != nil {
+		return fmt.Errorf("failed to purge database container: %w", err)
+	}
+	return nil
+}
+
+// Migrate runs all up migrations in dir against the database at the given
+// connection URL.
+func Migrate(dir, connectionURL string) error {
+	m, err := migrate.New(fmt.Sprintf("file://%s", dir), connectionURL)
+	if err != nil {
+		return fmt.Errorf("failed create migrate: %w", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed run migrate: %w", err)
+	}
+	srcErr, dbErr := m.Close()
+	if srcErr != nil {
+		return fmt.Errorf("migrate source error: %w", srcErr)
+	}
+	if dbErr != nil {
+		return fmt.Errorf("migrate database error: %w", dbErr)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the configuration for the discovery document.
+type Config struct {
+	// Enabled serves the discovery document at /.well-known/exposure-notification.
+	Enabled bool `env:"DISCOVERY_ENABLED, default=false"`
+
+	// ExportBaseURL is the URL from which export files are downloaded. The
+	// string "{bucket}" is replaced with the bucket name of each export config.
+	ExportBaseURL string `env:"DISCOVERY_EXPORT_BASE_URL, default=https://storage.googleapis.com/{bucket}"`
+
+	// FederationEndpoint is the address of the federation service, if it is
+	// offered to partners.
+	FederationEndpoint string `env:"DISCOVERY_FEDERATION_ENDPOINT"`
+
+	// SigningKey is the ID of the key in the key manager used to sign the
+	// document. SigningKeyID is the "kid" in the signature header.
+	SigningKey   string `env:"DISCOVERY_SIGNING_KEY"`
+	SigningKeyID string `env:"DISCOVERY_SIGNING_KEY_ID, default=v1"`
+
+	// PreviousKeyRetention is how long a signing key continues to be listed by
+	// the keys endpoint after its signature info expires, so clients can verify
+	// files that were signed before a rotation.
+	PreviousKeyRetention time.Duration `env:"DISCOVERY_PREVIOUS_KEY_RETENTION, default=720h"`
+
+	// CacheDuration is how long the signed document and keys are cached.
+	CacheDuration time.Duration `env:"DISCOVERY_CACHE_DURATION, default=5m"`
+
+	// VendorEndpoints serves the export file listing and the export
+	// availability probe that OS vendors use for server-to-server checks.
+	VendorEndpoints bool `env:"DISCOVERY_VENDOR_ENDPOINTS, default=false"`
+
+	// FileListingFormat is the format of the export file listing: "json", or
+	// "text" for one absolute file URL per line, like an index file.
+	FileListingFormat string `env:"DISCOVERY_FILE_LISTING_FORMAT, default=json"`
+
+	// FileListingWindow is how far back export files are listed.
+	FileListingWindow time.Duration `env:"DISCOVERY_FILE_LISTING_WINDOW, default=336h"`
+
+	// MaxExportAge is how long after the end of its period the latest batch
+	// of an export can be before the export is reported as unavailable.
+	MaxExportAge time.Duration `env:"DISCOVERY_MAX_EXPORT_AGE, default=2h"`
+}
+
+// File listing formats.
+const (
+	FileListingJSON = "json"
+	FileListingText = "text"
+)
+
+// validateVendorEndpoints checks the configuration of the vendor endpoints.
+func (c *Config) validateVendorEndpoints() error {
+	if !c.VendorEndpoints {
+		return nil
+	}
+	if f := c.FileListingFormat; f != FileListingJSON && f != FileListingText {
+		return fmt.Errorf("DISCOVERY_FILE_LISTING_FORMAT must be %q or %q, got %q", FileListingJSON, FileListingText, f)
+	}
+	if c.FileListingWindow <= 0 {
+		return fmt.Errorf("DISCOVERY_FILE_LISTING_WINDOW must be positive, got %v", c.FileListingWindow)
+	}
+	if c.MaxExportAge <= 0 {
+		return fmt.Errorf("DISCOVERY_MAX_EXPORT_AGE must be positive, got %v", c.MaxExportAge)
+	}
+	return nil
+}
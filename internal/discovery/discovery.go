@@ -0,0 +1,309 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package discovery serves a signed document that describes this deployment:
+// the export index URLs, the regions they cover, the public keys that verify
+// export signatures, and the federation endpoint. Partners and client
+// configuration can be bootstrapped from it.
+package discovery
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	exportdb "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/jws"
+	"github.com/google/exposure-notifications-server/pkg/cache"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/render"
+)
+
+// Path is the path at which the discovery document is served.
+const Path = "/.well-known/exposure-notification"
+
+// KeysPath is the path at which the export verification keys are served.
+const KeysPath = Path + "/keys"
+
+const cacheKey = "document"
+
+// Document describes the deployment.
+type Document struct {
+	// GeneratedAt is the unix timestamp at which the document was built.
+	GeneratedAt int64 `json:"generatedAt"`
+
+	// Regions are the output regions of all active exports.
+	Regions []string `json:"regions"`
+
+	// Exports are the active export configurations.
+	Exports []*Export `json:"exports"`
+
+	// SigningKeys are the public keys that verify export signatures.
+	SigningKeys []*SigningKey `json:"signingKeys"`
+
+	// FederationEndpoint is the address of the federation service, if any.
+	FederationEndpoint string `json:"federationEndpoint,omitempty"`
+}
+
+// Export describes a single export configuration.
+type Export struct {
+	Region           string   `json:"region"`
+	IndexURL         string   `json:"indexURL"`
+	PeriodSeconds    int64    `json:"periodSeconds"`
+	InputRegions     []string `json:"inputRegions,omitempty"`
+	IncludeTravelers bool     `json:"includeTravelers,omitempty"`
+	OnlyNonTravelers bool     `json:"onlyNonTravelers,omitempty"`
+	SigningKeyIDs    []string `json:"signingKeyIDs"`
+}
+
+// SigningKey is a public key that verifies export signatures.
+type SigningKey struct {
+	KeyID      string `json:"keyID"`
+	KeyVersion string `json:"keyVersion"`
+	ValidUntil int64  `json:"validUntil,omitempty"`
+	PublicKey  string `json:"publicKey"`
+}
+
+// Handler serves the signed discovery document.
+type Handler struct {
+	config      *Config
+	db          *exportdb.ExportDB
+	keyManager  keys.KeyManager
+	cache       *cache.Cache[*jws.JWS]
+	keysCache   *cache.Cache[*KeySet]
+	filesCache  *cache.Cache[*FileListing]
+	statusCache *cache.Cache[*Status]
+	h           *render.Renderer
+}
+
+// New creates a new discovery handler.
+func New(cfg *Config, db *database.DB, km keys.KeyManager) (*Handler, error) {
+	if cfg.SigningKey == "" {
+		return nil, fmt.Errorf("DISCOVERY_SIGNING_KEY is required")
+	}
+	if km == nil {
+		return nil, fmt.Errorf("discovery requires a key manager")
+	}
+	if err := cfg.validateVendorEndpoints(); err != nil {
+		return nil, err
+	}
+
+	c, err := cache.New[*jws.JWS](cfg.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+	keysCache, err := cache.New[*KeySet](cfg.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+	filesCache, err := cache.New[*FileListing](cfg.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+	statusCache, err := cache.New[*Status](cfg.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+
+	return &Handler{
+		config:      cfg,
+		db:          exportdb.New(db),
+		keyManager:  km,
+		cache:       c,
+		keysCache:   keysCache,
+		filesCache:  filesCache,
+		statusCache: statusCache,
+		h:           render.NewRenderer(),
+	}, nil
+}
+
+// ServeHTTP renders the signed document.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx).Named("discovery")
+
+	doc, err := h.cache.WriteThruLookup(cacheKey, func() (*jws.JWS, error) {
+		return h.signedDocument(ctx)
+	})
+	if err != nil {
+		logger.Errorw("failed to build discovery document", "error", err)
+		h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.CacheDuration.Seconds())))
+	h.h.RenderJSON(w, http.StatusOK, doc)
+}
+
+func (h *Handler) signedDocument(ctx context.Context) (*jws.JWS, error) {
+	doc, err := h.Document(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	signer, err := h.keyManager.NewSigner(ctx, h.config.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signer: %w", err)
+	}
+	return jws.Sign(signer, jws.Header{KeyID: h.config.SigningKeyID}, payload)
+}
+
+// Document builds the discovery document from the export configurations that
+// are active at the given time.
+func (h *Handler) Document(ctx context.Context, now time.Time) (*Document, error) {
+	configs, err := h.activeConfigs(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var sigIDs []int64
+	for _, ec := range configs {
+		sigIDs = append(sigIDs, ec.SignatureInfoIDs...)
+	}
+	sigInfos, err := h.db.LookupSignatureInfos(ctx, sigIDs, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup signature infos: %w", err)
+	}
+	signingKeys, byID, err := h.signingKeys(ctx, sigInfos)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		GeneratedAt:        now.Unix(),
+		Regions:            make([]string, 0, len(configs)),
+		Exports:            make([]*Export, 0, len(configs)),
+		SigningKeys:        signingKeys,
+		FederationEndpoint: h.config.FederationEndpoint,
+	}
+
+	seen := make(map[string]struct{}, len(configs))
+	for _, ec := range configs {
+		if _, ok := seen[ec.OutputRegion]; !ok {
+			seen[ec.OutputRegion] = struct{}{}
+			doc.Regions = append(doc.Regions, ec.OutputRegion)
+		}
+
+		export := &Export{
+			Region:           ec.OutputRegion,
+			IndexURL:         h.indexURL(ec),
+			PeriodSeconds:    int64(ec.Period.Seconds()),
+			InputRegions:     ec.InputRegions,
+			IncludeTravelers: ec.IncludeTravelers,
+			OnlyNonTravelers: ec.OnlyNonTravelers,
+			SigningKeyIDs:    make([]string, 0, len(ec.SignatureInfoIDs)),
+		}
+		for _, id := range ec.SignatureInfoIDs {
+			if key, ok := byID[id]; ok {
+				export.SigningKeyIDs = append(export.SigningKeyIDs, key.KeyID)
+			}
+		}
+		doc.Exports = append(doc.Exports, export)
+	}
+	sort.Strings(doc.Regions)
+
+	return doc, nil
+}
+
+// activeConfigs returns the export configs that are active at the given time,
+// ordered by ID.
+func (h *Handler) activeConfigs(ctx context.Context, now time.Time) ([]*model.ExportConfig, error) {
+	var configs []*model.ExportConfig
+	if err := h.db.IterateExportConfigs(ctx, now, func(ec *model.ExportConfig) error {
+		configs = append(configs, ec)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list export configs: %w", err)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].ConfigID < configs[j].ConfigID
+	})
+	return configs, nil
+}
+
+// signingKeys resolves the public key of each signature info. It returns the
+// keys and a map of signature info ID to key.
+func (h *Handler) signingKeys(ctx context.Context, sigInfos []*model.SignatureInfo) ([]*SigningKey, map[int64]*SigningKey, error) {
+	list := make([]*SigningKey, 0, len(sigInfos))
+	byID := make(map[int64]*SigningKey, len(sigInfos))
+	for _, si := range sigInfos {
+		pub, err := h.publicKey(ctx, si)
+		if err != nil {
+			return nil, nil, err
+		}
+		pemKey, err := publicKeyPEM(pub)
+		if err != nil {
+			return nil, nil, fmt.Errorf("signature info %d: %w", si.ID, err)
+		}
+
+		key := &SigningKey{
+			KeyID:      si.SigningKeyID,
+			KeyVersion: si.SigningKeyVersion,
+			PublicKey:  pemKey,
+		}
+		if !si.EndTimestamp.IsZero() {
+			key.ValidUntil = si.EndTimestamp.Unix()
+		}
+		list = append(list, key)
+		byID[si.ID] = key
+	}
+	return list, byID, nil
+}
+
+func (h *Handler) indexURL(ec *model.ExportConfig) string {
+	return h.fileURL(ec, ec.FilenameRoot+"/index.txt")
+}
+
+// fileURL returns the download URL of an object in the export config's bucket.
+func (h *Handler) fileURL(ec *model.ExportConfig, name string) string {
+	base := strings.ReplaceAll(h.config.ExportBaseURL, "{bucket}", ec.BucketName)
+	return strings.TrimSuffix(base, "/") + "/" + name
+}
+
+// publicKey returns the ECDSA public key of the signature info's signing key.
+func (h *Handler) publicKey(ctx context.Context, si *model.SignatureInfo) (*ecdsa.PublicKey, error) {
+	signer, err := h.keyManager.NewSigner(ctx, si.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signer for signature info %d: %w", si.ID, err)
+	}
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("signature info %d: unsupported public key type %T", si.ID, signer.Public())
+	}
+	return pub, nil
+}
+
+// publicKeyPEM returns the PEM encoded ECDSA public key.
+func publicKeyPEM(pub *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
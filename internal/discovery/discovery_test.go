@@ -0,0 +1,163 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	exportdb "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/jws"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var testDatabaseInstance *database.TestInstance
+
+func TestMain(m *testing.M) {
+	testDatabaseInstance = database.MustTestInstance()
+	defer testDatabaseInstance.MustClose()
+	m.Run()
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	db := exportdb.New(testDB)
+
+	km := keys.TestKeyManager(t)
+	exportKey := keys.TestSigningKey(t, km)
+	documentKey := keys.TestSigningKey(t, km)
+
+	si := &model.SignatureInfo{
+		SigningKey:        exportKey,
+		SigningKeyID:      "310",
+		SigningKeyVersion: "v1",
+	}
+	if err := db.AddSignatureInfo(ctx, si); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ec := range []*model.ExportConfig{
+		{
+			BucketName:       "exports",
+			FilenameRoot:     "exposureKeyExport-US",
+			Period:           time.Hour,
+			OutputRegion:     "US",
+			IncludeTravelers: true,
+			From:             time.Now().Add(-time.Hour),
+			SignatureInfoIDs: []int64{si.ID},
+		},
+		{
+			// Not yet active.
+			BucketName:       "exports",
+			FilenameRoot:     "exposureKeyExport-CA",
+			Period:           time.Hour,
+			OutputRegion:     "CA",
+			From:             time.Now().Add(time.Hour),
+			SignatureInfoIDs: []int64{si.ID},
+		},
+	} {
+		if err := db.AddExportConfig(ctx, ec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := New(&Config{
+		ExportBaseURL:      "https://cdn.example.com/{bucket}/",
+		FederationEndpoint: "federation.example.com:443",
+		SigningKey:         documentKey,
+		SigningKeyID:       "doc-v1",
+		CacheDuration:      time.Minute,
+	}, testDB, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, Path, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	var signed jws.JWS
+	if err := json.Unmarshal(w.Body.Bytes(), &signed); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Document
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := h.publicKey(ctx, si)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey, err := publicKeyPEM(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Document{
+		Regions: []string{"US"},
+		Exports: []*Export{
+			{
+				Region:           "US",
+				IndexURL:         "https://cdn.example.com/exports/exposureKeyExport-US/index.txt",
+				PeriodSeconds:    3600,
+				IncludeTravelers: true,
+				SigningKeyIDs:    []string{"310"},
+			},
+		},
+		SigningKeys: []*SigningKey{
+			{
+				KeyID:      "310",
+				KeyVersion: "v1",
+				PublicKey:  pemKey,
+			},
+		},
+		FederationEndpoint: "federation.example.com:443",
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Document{}, "GeneratedAt")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(&Config{}, nil, keys.TestKeyManager(t)); err == nil {
+		t.Errorf("expected error without signing key")
+	}
+	if _, err := New(&Config{SigningKey: "key"}, nil, nil); err == nil {
+		t.Errorf("expected error without key manager")
+	}
+}
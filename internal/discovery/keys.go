@@ -0,0 +1,172 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+const (
+	keysCacheKey = "keys"
+
+	// KeyStatusActive is the status of keys that currently sign exports.
+	KeyStatusActive = "active"
+
+	// KeyStatusExpired is the status of keys that no longer sign exports, but
+	// may have signed files that are still being served.
+	KeyStatusExpired = "expired"
+)
+
+// KeySet is a JSON Web Key Set of the export verification keys.
+type KeySet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// JWK is an ECDSA P-256 JSON Web Key, extended with the export signature
+// metadata.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+
+	// KeyVersion is the verification_key_version in export signatures.
+	KeyVersion string `json:"keyVersion"`
+
+	// ValidFrom is the unix timestamp at which the earliest export config that
+	// uses this key became active, if known. ValidUntil is the unix timestamp
+	// after which the key no longer signs exports, if set.
+	ValidFrom  int64 `json:"validFrom,omitempty"`
+	ValidUntil int64 `json:"validUntil,omitempty"`
+
+	Status string `json:"status"`
+	PEM    string `json:"pem"`
+}
+
+// KeysHandler returns a handler that serves the current export verification
+// keys and those that expired within the retention period.
+func (h *Handler) KeysHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("discovery")
+
+		keySet, err := h.keysCache.WriteThruLookup(keysCacheKey, func() (*KeySet, error) {
+			return h.KeySet(ctx, time.Now().UTC())
+		})
+		if err != nil {
+			logger.Errorw("failed to build key set", "error", err)
+			h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.CacheDuration.Seconds())))
+		h.h.RenderJSON(w, http.StatusOK, keySet)
+	})
+}
+
+// KeySet builds the key set at the given time.
+func (h *Handler) KeySet(ctx context.Context, now time.Time) (*KeySet, error) {
+	sigInfos, err := h.db.ListAllSignatureInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signature infos: %w", err)
+	}
+
+	configs, err := h.db.GetAllExportConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export configs: %w", err)
+	}
+	validFrom := make(map[int64]time.Time)
+	for _, ec := range configs {
+		for _, id := range ec.SignatureInfoIDs {
+			if from, ok := validFrom[id]; !ok || ec.From.Before(from) {
+				validFrom[id] = ec.From
+			}
+		}
+	}
+
+	cutoff := now.Add(-h.config.PreviousKeyRetention)
+	keySet := &KeySet{Keys: make([]*JWK, 0, len(sigInfos))}
+	for _, si := range sigInfos {
+		status := KeyStatusActive
+		if !si.EndTimestamp.IsZero() && si.EndTimestamp.Before(now) {
+			if si.EndTimestamp.Before(cutoff) {
+				continue
+			}
+			status = KeyStatusExpired
+		}
+
+		pub, err := h.publicKey(ctx, si)
+		if err != nil {
+			return nil, err
+		}
+		jwk, err := newJWK(pub)
+		if err != nil {
+			return nil, fmt.Errorf("signature info %d: %w", si.ID, err)
+		}
+
+		jwk.KeyID = si.SigningKeyID
+		jwk.KeyVersion = si.SigningKeyVersion
+		jwk.Status = status
+		if from, ok := validFrom[si.ID]; ok && !from.IsZero() {
+			jwk.ValidFrom = from.Unix()
+		}
+		if !si.EndTimestamp.IsZero() {
+			jwk.ValidUntil = si.EndTimestamp.Unix()
+		}
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+	return keySet, nil
+}
+
+// p256CoordinateSize is the size of each of the X and Y coordinates of a P-256
+// public key.
+const p256CoordinateSize = 32
+
+// newJWK builds the key portion of a JWK from an ECDSA P-256 public key.
+func newJWK(pub *ecdsa.PublicKey) (*JWK, error) {
+	if name := pub.Curve.Params().Name; name != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %s", name)
+	}
+
+	pemKey, err := publicKeyPEM(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	x := make([]byte, p256CoordinateSize)
+	y := make([]byte, p256CoordinateSize)
+	pub.X.FillBytes(x)
+	pub.Y.FillBytes(y)
+
+	return &JWK{
+		KeyType:   "EC",
+		Curve:     "P-256",
+		X:         base64.RawURLEncoding.EncodeToString(x),
+		Y:         base64.RawURLEncoding.EncodeToString(y),
+		Use:       "sig",
+		Algorithm: "ES256",
+		PEM:       pemKey,
+	}, nil
+}
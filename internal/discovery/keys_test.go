@@ -0,0 +1,193 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	exportdb "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+)
+
+func TestNewJWK(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwk, err := newJWK(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := jwk.KeyType, "EC"; got != want {
+		t.Errorf("expected kty %q to be %q", got, want)
+	}
+	if got, want := jwk.Curve, "P-256"; got != want {
+		t.Errorf("expected crv %q to be %q", got, want)
+	}
+
+	for name, tc := range map[string]struct {
+		encoded string
+		want    *big.Int
+	}{
+		"x": {jwk.X, key.PublicKey.X},
+		"y": {jwk.Y, key.PublicKey.Y},
+	} {
+		b, err := base64.RawURLEncoding.DecodeString(tc.encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(b), p256CoordinateSize; got != want {
+			t.Errorf("expected %s length %d to be %d", name, got, want)
+		}
+		if got := new(big.Int).SetBytes(b); got.Cmp(tc.want) != 0 {
+			t.Errorf("expected %s %s to be %s", name, got, tc.want)
+		}
+	}
+
+	pub, err := keys.ParseECDSAPublicKeys(jwk.PEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub) != 1 || !pub[0].Equal(&key.PublicKey) {
+		t.Errorf("expected pem to contain the public key")
+	}
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newJWK(&p384.PublicKey); err == nil {
+		t.Errorf("expected error for P-384 key")
+	}
+}
+
+func TestKeysHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	db := exportdb.New(testDB)
+
+	km := keys.TestKeyManager(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	from := now.Add(-30 * 24 * time.Hour)
+
+	current := &model.SignatureInfo{
+		SigningKey:        keys.TestSigningKey(t, km),
+		SigningKeyID:      "310",
+		SigningKeyVersion: "v2",
+	}
+	previous := &model.SignatureInfo{
+		SigningKey:        keys.TestSigningKey(t, km),
+		SigningKeyID:      "310",
+		SigningKeyVersion: "v1",
+		EndTimestamp:      now.Add(-24 * time.Hour),
+	}
+	retired := &model.SignatureInfo{
+		SigningKey:        keys.TestSigningKey(t, km),
+		SigningKeyID:      "310",
+		SigningKeyVersion: "v0",
+		EndTimestamp:      now.Add(-60 * 24 * time.Hour),
+	}
+	for _, si := range []*model.SignatureInfo{current, previous, retired} {
+		if err := db.AddSignatureInfo(ctx, si); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.AddExportConfig(ctx, &model.ExportConfig{
+		BucketName:       "exports",
+		FilenameRoot:     "exposureKeyExport-US",
+		Period:           time.Hour,
+		OutputRegion:     "US",
+		From:             from,
+		SignatureInfoIDs: []int64{current.ID, previous.ID},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := New(&Config{
+		SigningKey:           keys.TestSigningKey(t, km),
+		SigningKeyID:         "doc-v1",
+		PreviousKeyRetention: 7 * 24 * time.Hour,
+		CacheDuration:        time.Minute,
+	}, testDB, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, KeysPath, nil)
+	w := httptest.NewRecorder()
+	h.KeysHandler().ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	var keySet KeySet
+	if err := json.Unmarshal(w.Body.Bytes(), &keySet); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]*JWK, len(keySet.Keys))
+	for _, k := range keySet.Keys {
+		got[k.KeyVersion] = k
+	}
+	if _, ok := got["v0"]; ok {
+		t.Errorf("expected key beyond retention to be omitted")
+	}
+
+	cases := []struct {
+		version    string
+		status     string
+		validUntil int64
+	}{
+		{"v2", KeyStatusActive, 0},
+		{"v1", KeyStatusExpired, previous.EndTimestamp.Unix()},
+	}
+	for _, tc := range cases {
+		k, ok := got[tc.version]
+		if !ok {
+			t.Errorf("expected key %s to be listed", tc.version)
+			continue
+		}
+		if got, want := k.KeyID, "310"; got != want {
+			t.Errorf("%s: expected kid %q to be %q", tc.version, got, want)
+		}
+		if got, want := k.Status, tc.status; got != want {
+			t.Errorf("%s: expected status %q to be %q", tc.version, got, want)
+		}
+		if got, want := k.ValidFrom, from.Unix(); got != want {
+			t.Errorf("%s: expected validFrom %d to be %d", tc.version, got, want)
+		}
+		if got, want := k.ValidUntil, tc.validUntil; got != want {
+			t.Errorf("%s: expected validUntil %d to be %d", tc.version, got, want)
+		}
+	}
+}
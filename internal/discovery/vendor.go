@@ -0,0 +1,211 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+// FilesPath is the path at which the export files are listed for the OS
+// vendors' server-to-server checks.
+const FilesPath = Path + "/files"
+
+// StatusPath is the path at which the export availability probe is served.
+const StatusPath = Path + "/status"
+
+const statusCacheKey = "status"
+
+// FileListing lists the export files of the active exports.
+type FileListing struct {
+	Exports []*ExportFiles `json:"exports"`
+}
+
+// ExportFiles are the download URLs of the files of an export, oldest first.
+type ExportFiles struct {
+	Region   string   `json:"region"`
+	IndexURL string   `json:"indexURL"`
+	Files    []string `json:"files"`
+}
+
+// Status reports whether each active export is publishing files.
+type Status struct {
+	GeneratedAt int64           `json:"generatedAt"`
+	Available   bool            `json:"available"`
+	Exports     []*ExportStatus `json:"exports"`
+}
+
+// ExportStatus reports the latest batch and file of an export. An export is
+// available if its latest completed batch ended within the export period plus
+// the maximum age. Batches without keys have no files, so the latest file can
+// be older.
+type ExportStatus struct {
+	Region         string `json:"region"`
+	IndexURL       string `json:"indexURL"`
+	LatestFileURL  string `json:"latestFileURL,omitempty"`
+	LatestBatchEnd int64  `json:"latestBatchEnd,omitempty"`
+	Available      bool   `json:"available"`
+}
+
+// FilesHandler returns a handler that lists the export files of the active
+// exports, optionally only those of the output region in the "region" query
+// parameter.
+func (h *Handler) FilesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("discovery")
+
+		region := strings.ToUpper(r.URL.Query().Get("region"))
+		listing, err := h.filesCache.WriteThruLookup(region, func() (*FileListing, error) {
+			return h.FileListing(ctx, region, time.Now().UTC())
+		})
+		if err != nil {
+			logger.Errorw("failed to list export files", "error", err)
+			h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+		if region != "" && len(listing.Exports) == 0 {
+			h.h.RenderJSON(w, http.StatusNotFound, fmt.Errorf("no exports for region %q", region))
+			return
+		}
+
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.CacheDuration.Seconds())))
+		if h.config.FileListingFormat == FileListingText {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			for _, export := range listing.Exports {
+				for _, f := range export.Files {
+					fmt.Fprintln(w, f)
+				}
+			}
+			return
+		}
+		h.h.RenderJSON(w, http.StatusOK, listing)
+	})
+}
+
+// FileListing builds the file listing of the exports of the region, or of all
+// regions if region is empty, at the given time.
+func (h *Handler) FileListing(ctx context.Context, region string, now time.Time) (*FileListing, error) {
+	configs, err := h.activeConfigs(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &FileListing{Exports: make([]*ExportFiles, 0, len(configs))}
+	for _, ec := range configs {
+		if region != "" && ec.OutputRegion != region {
+			continue
+		}
+
+		names, err := h.db.LookupExportFiles(ctx, ec.ConfigID, h.config.FileListingWindow)
+		if err != nil {
+			return nil, fmt.Errorf("export config %d: %w", ec.ConfigID, err)
+		}
+		files := make([]string, 0, len(names))
+		for _, name := range names {
+			files = append(files, h.fileURL(ec, name))
+		}
+
+		listing.Exports = append(listing.Exports, &ExportFiles{
+			Region:   ec.OutputRegion,
+			IndexURL: h.indexURL(ec),
+			Files:    files,
+		})
+	}
+	return listing, nil
+}
+
+// StatusHandler returns a handler that reports whether the active exports are
+// publishing files. It responds with 503 if any export is unavailable, so it
+// can be used as an availability probe.
+func (h *Handler) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("discovery")
+
+		status, err := h.statusCache.WriteThruLookup(statusCacheKey, func() (*Status, error) {
+			return h.Status(ctx, time.Now().UTC())
+		})
+		if err != nil {
+			logger.Errorw("failed to build export status", "error", err)
+			h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+
+		code := http.StatusOK
+		if !status.Available {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.CacheDuration.Seconds())))
+		h.h.RenderJSON(w, code, status)
+	})
+}
+
+// Status builds the availability of the active exports at the given time.
+func (h *Handler) Status(ctx context.Context, now time.Time) (*Status, error) {
+	configs, err := h.activeConfigs(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		GeneratedAt: now.Unix(),
+		Available:   true,
+		Exports:     make([]*ExportStatus, 0, len(configs)),
+	}
+	for _, ec := range configs {
+		es, err := h.exportStatus(ctx, ec, now)
+		if err != nil {
+			return nil, err
+		}
+		status.Available = status.Available && es.Available
+		status.Exports = append(status.Exports, es)
+	}
+	return status, nil
+}
+
+func (h *Handler) exportStatus(ctx context.Context, ec *model.ExportConfig, now time.Time) (*ExportStatus, error) {
+	es := &ExportStatus{
+		Region:   ec.OutputRegion,
+		IndexURL: h.indexURL(ec),
+	}
+
+	end, err := h.db.LatestCompletedBatchEnd(ctx, ec.ConfigID)
+	if err != nil {
+		return nil, fmt.Errorf("export config %d: %w", ec.ConfigID, err)
+	}
+	if !end.IsZero() {
+		es.LatestBatchEnd = end.Unix()
+		es.Available = !end.Before(now.Add(-ec.Period - h.config.MaxExportAge))
+	}
+
+	name, _, err := h.db.LookupLatestExportFile(ctx, ec.ConfigID)
+	switch {
+	case err == nil:
+		es.LatestFileURL = h.fileURL(ec, name)
+	case !errors.Is(err, database.ErrNotFound):
+		return nil, fmt.Errorf("export config %d: %w", ec.ConfigID, err)
+	}
+	return es, nil
+}
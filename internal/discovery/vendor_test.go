@@ -0,0 +1,279 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	exportdb "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestVendorEndpoints(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	db := exportdb.New(testDB)
+
+	km := keys.TestKeyManager(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	us := &model.ExportConfig{
+		BucketName:   "exports",
+		FilenameRoot: "exposureKeyExport-US",
+		Period:       time.Hour,
+		OutputRegion: "US",
+		From:         now.Add(-24 * time.Hour),
+	}
+	ca := &model.ExportConfig{
+		BucketName:   "exports",
+		FilenameRoot: "exposureKeyExport-CA",
+		Period:       time.Hour,
+		OutputRegion: "CA",
+		From:         now.Add(-24 * time.Hour),
+	}
+	for _, ec := range []*model.ExportConfig{us, ca} {
+		if err := db.AddExportConfig(ctx, ec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the US export has published files.
+	if err := db.AddExportBatches(ctx, []*model.ExportBatch{
+		{
+			ConfigID:       us.ConfigID,
+			BucketName:     us.BucketName,
+			FilenameRoot:   us.FilenameRoot,
+			StartTimestamp: now.Add(-2 * time.Hour),
+			EndTimestamp:   now.Add(-time.Hour),
+			OutputRegion:   us.OutputRegion,
+			Status:         model.ExportBatchOpen,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	eb, err := db.LeaseBatch(ctx, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{
+		"exposureKeyExport-US/1-2-00001.zip",
+		"exposureKeyExport-US/1-2-00002.zip",
+	}
+	if err := db.FinalizeBatch(ctx, eb, files, nil, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	newHandler := func(tb testing.TB, format string) *Handler {
+		tb.Helper()
+
+		h, err := New(&Config{
+			ExportBaseURL:     "https://cdn.example.com/{bucket}",
+			SigningKey:        keys.TestSigningKey(tb, km),
+			CacheDuration:     time.Minute,
+			VendorEndpoints:   true,
+			FileListingFormat: format,
+			FileListingWindow: 24 * time.Hour,
+			MaxExportAge:      time.Hour,
+		}, testDB, km)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return h
+	}
+
+	t.Run("files_json", func(t *testing.T) {
+		t.Parallel()
+
+		h := newHandler(t, FileListingJSON)
+
+		r := httptest.NewRequest(http.MethodGet, FilesPath+"?region=us", nil)
+		w := httptest.NewRecorder()
+		h.FilesHandler().ServeHTTP(w, r)
+
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+		}
+
+		var got FileListing
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := FileListing{
+			Exports: []*ExportFiles{
+				{
+					Region:   "US",
+					IndexURL: "https://cdn.example.com/exports/exposureKeyExport-US/index.txt",
+					Files: []string{
+						"https://cdn.example.com/exports/exposureKeyExport-US/1-2-00001.zip",
+						"https://cdn.example.com/exports/exposureKeyExport-US/1-2-00002.zip",
+					},
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("files_text", func(t *testing.T) {
+		t.Parallel()
+
+		h := newHandler(t, FileListingText)
+
+		r := httptest.NewRequest(http.MethodGet, FilesPath, nil)
+		w := httptest.NewRecorder()
+		h.FilesHandler().ServeHTTP(w, r)
+
+		if got, want := w.Code, http.StatusOK; got != want {
+			t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+			t.Errorf("expected text content type, got %q", got)
+		}
+		want := "https://cdn.example.com/exports/exposureKeyExport-US/1-2-00001.zip\n" +
+			"https://cdn.example.com/exports/exposureKeyExport-US/1-2-00002.zip\n"
+		if got := w.Body.String(); got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+	})
+
+	t.Run("files_unknown_region", func(t *testing.T) {
+		t.Parallel()
+
+		h := newHandler(t, FileListingJSON)
+
+		r := httptest.NewRequest(http.MethodGet, FilesPath+"?region=MX", nil)
+		w := httptest.NewRecorder()
+		h.FilesHandler().ServeHTTP(w, r)
+
+		if got, want := w.Code, http.StatusNotFound; got != want {
+			t.Errorf("expected status %d to be %d: %s", got, want, w.Body.String())
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		t.Parallel()
+
+		h := newHandler(t, FileListingJSON)
+
+		r := httptest.NewRequest(http.MethodGet, StatusPath, nil)
+		w := httptest.NewRecorder()
+		h.StatusHandler().ServeHTTP(w, r)
+
+		// The CA export has not completed a batch.
+		if got, want := w.Code, http.StatusServiceUnavailable; got != want {
+			t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+		}
+
+		var got Status
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := Status{
+			Exports: []*ExportStatus{
+				{
+					Region:         "US",
+					IndexURL:       "https://cdn.example.com/exports/exposureKeyExport-US/index.txt",
+					LatestFileURL:  "https://cdn.example.com/exports/exposureKeyExport-US/1-2-00002.zip",
+					LatestBatchEnd: now.Add(-time.Hour).Unix(),
+					Available:      true,
+				},
+				{
+					Region:   "CA",
+					IndexURL: "https://cdn.example.com/exports/exposureKeyExport-CA/index.txt",
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Status{}, "GeneratedAt")); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestConfig_ValidateVendorEndpoints(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *Config {
+		return &Config{
+			VendorEndpoints:   true,
+			FileListingFormat: FileListingJSON,
+			FileListingWindow: 336 * time.Hour,
+			MaxExportAge:      2 * time.Hour,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		modify func(c *Config)
+		err    bool
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name:   "text",
+			modify: func(c *Config) { c.FileListingFormat = FileListingText },
+		},
+		{
+			name: "disabled",
+			modify: func(c *Config) {
+				c.VendorEndpoints = false
+				c.FileListingFormat = "xml"
+			},
+		},
+		{
+			name:   "unknown_format",
+			modify: func(c *Config) { c.FileListingFormat = "xml" },
+			err:    true,
+		},
+		{
+			name:   "no_window",
+			modify: func(c *Config) { c.FileListingWindow = 0 },
+			err:    true,
+		},
+		{
+			name:   "no_max_age",
+			modify: func(c *Config) { c.MaxExportAge = 0 },
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := valid()
+			tc.modify(c)
+			err := c.validateVendorEndpoints()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
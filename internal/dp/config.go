@@ -0,0 +1,81 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dp adds differential privacy noise to aggregate statistics.
+package dp
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultSensitivity is the sensitivity of a metric that is not configured.
+// It is right for counts of requests, which one request changes by at most 1.
+const DefaultSensitivity = 1.0
+
+// Config configures differential privacy for aggregate statistics.
+type Config struct {
+	// Enabled adds noise to statistics before they are returned.
+	Enabled bool `env:"STATS_DP_ENABLED, default=false"`
+
+	// Epsilon is the privacy loss of one response. It is split evenly across
+	// the metrics in the response. Smaller values add more noise.
+	Epsilon float64 `env:"STATS_DP_EPSILON, default=1"`
+
+	// Sensitivity is the most that one publish request can change a metric,
+	// keyed by metric name, e.g. "total_teks_published:30". Metrics that are
+	// not listed use DefaultSensitivity.
+	Sensitivity map[string]float64 `env:"STATS_DP_SENSITIVITY, default=total_teks_published:30"`
+
+	// Budget is the total epsilon that one consumer can spend in each
+	// BudgetPeriod. Requests that would exceed it are rejected until the next
+	// period.
+	Budget       float64       `env:"STATS_DP_BUDGET, default=10"`
+	BudgetPeriod time.Duration `env:"STATS_DP_BUDGET_PERIOD, default=24h"`
+}
+
+// Validate returns an error if the config is enabled and not valid.
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Epsilon <= 0 {
+		return fmt.Errorf("env var `STATS_DP_EPSILON` must be > 0, got: %v", c.Epsilon)
+	}
+	if c.Budget < c.Epsilon {
+		return fmt.Errorf("env var `STATS_DP_BUDGET` must be >= `STATS_DP_EPSILON`, got: %v", c.Budget)
+	}
+	if c.BudgetPeriod < time.Hour {
+		return fmt.Errorf("env var `STATS_DP_BUDGET_PERIOD` must be >= 1h, got: %v", c.BudgetPeriod)
+	}
+	for metric, s := range c.Sensitivity {
+		if s <= 0 {
+			return fmt.Errorf("sensitivity of %q must be > 0, got: %v", metric, s)
+		}
+	}
+	return nil
+}
+
+// SensitivityFor returns the sensitivity of the named metric.
+func (c *Config) SensitivityFor(metric string) float64 {
+	if s, ok := c.Sensitivity[metric]; ok {
+		return s
+	}
+	return DefaultSensitivity
+}
+
+// BudgetPeriodStart returns the start of the budget period that contains t.
+func (c *Config) BudgetPeriodStart(t time.Time) time.Time {
+	return t.UTC().Truncate(c.BudgetPeriod)
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+func TestConfig_Defaults(t *testing.T) {
+	t.Parallel()
+
+	var cfg Config
+	if err := envconfig.ProcessWith(context.Background(), &cfg, envconfig.MapLookuper(nil)); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := cfg.SensitivityFor("total_teks_published"), 30.0; got != want {
+		t.Errorf("expected sensitivity %v, got %v", want, got)
+	}
+	if got, want := cfg.SensitivityFor("publish_requests"), DefaultSensitivity; got != want {
+		t.Errorf("expected sensitivity %v, got %v", want, got)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *Config {
+		return &Config{
+			Enabled:      true,
+			Epsilon:      1,
+			Budget:       10,
+			BudgetPeriod: 24 * time.Hour,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		modify func(*Config)
+		err    bool
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name:   "disabled",
+			modify: func(c *Config) { c.Enabled = false; c.Epsilon = 0 },
+		},
+		{
+			name:   "zero_epsilon",
+			modify: func(c *Config) { c.Epsilon = 0 },
+			err:    true,
+		},
+		{
+			name:   "budget_below_epsilon",
+			modify: func(c *Config) { c.Budget = 0.5 },
+			err:    true,
+		},
+		{
+			name:   "short_period",
+			modify: func(c *Config) { c.BudgetPeriod = time.Minute },
+			err:    true,
+		},
+		{
+			name:   "negative_sensitivity",
+			modify: func(c *Config) { c.Sensitivity = map[string]float64{"x": -1} },
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := valid()
+			tc.modify(cfg)
+			err := cfg.Validate()
+			if got := err != nil; got != tc.err {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestConfig_BudgetPeriodStart(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{BudgetPeriod: 24 * time.Hour}
+	got := cfg.BudgetPeriodStart(time.Date(2021, 3, 4, 15, 4, 5, 0, time.UTC))
+	if want := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
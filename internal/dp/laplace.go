@@ -0,0 +1,68 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dp
+
+import (
+	"math"
+	mrand "math/rand"
+
+	"github.com/google/exposure-notifications-server/pkg/cryptorand"
+)
+
+// Mechanism adds Laplace noise to counts. It is safe for concurrent use.
+type Mechanism struct {
+	// uniform returns a value in [0, 1).
+	uniform func() float64
+}
+
+// NewMechanism returns a Mechanism that draws noise from a cryptographically
+// secure source, so that the noise cannot be predicted and subtracted.
+func NewMechanism() *Mechanism {
+	//nolint:gosec // cryptorand.NewSource is a secure source
+	r := mrand.New(cryptorand.NewSource())
+	return &Mechanism{uniform: r.Float64}
+}
+
+// Laplace returns noise from the Laplace distribution centered on 0 with the
+// given scale.
+func (m *Mechanism) Laplace(scale float64) float64 {
+	u := m.uniform() - 0.5
+	for u == -0.5 {
+		u = m.uniform() - 0.5
+	}
+	if u < 0 {
+		return scale * math.Log(1+2*u)
+	}
+	return -scale * math.Log(1-2*u)
+}
+
+// Count returns v with noise for the given sensitivity and epsilon, rounded
+// to the nearest count. Counts are never negative; clamping is
+// post-processing, so it does not weaken the guarantee.
+func (m *Mechanism) Count(v int64, sensitivity, epsilon float64) int64 {
+	n := math.Round(float64(v) + m.Laplace(sensitivity/epsilon))
+	if n < 0 {
+		return 0
+	}
+	return int64(n)
+}
+
+// Counts adds noise to each value of a histogram in place. One request
+// changes only one bucket, so the whole histogram has the given sensitivity.
+func (m *Mechanism) Counts(vs []int64, sensitivity, epsilon float64) {
+	for i, v := range vs {
+		vs[i] = m.Count(v, sensitivity, epsilon)
+	}
+}
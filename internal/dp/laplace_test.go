@@ -0,0 +1,97 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLaplace(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		uniform []float64
+		want    float64
+	}{
+		{name: "median", uniform: []float64{0.5}, want: 0},
+		{name: "low", uniform: []float64{0.25}, want: 2 * math.Log(0.5)},
+		{name: "high", uniform: []float64{0.75}, want: -2 * math.Log(0.5)},
+		{name: "skips_zero", uniform: []float64{0, 0.5}, want: 0},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			i := 0
+			m := &Mechanism{uniform: func() float64 {
+				v := tc.uniform[i]
+				i++
+				return v
+			}}
+			if got := m.Laplace(2); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLaplace_Distribution(t *testing.T) {
+	t.Parallel()
+
+	m := NewMechanism()
+	const n, scale = 20000, 4.0
+
+	var sum, sumAbs float64
+	for i := 0; i < n; i++ {
+		v := m.Laplace(scale)
+		sum += v
+		sumAbs += math.Abs(v)
+	}
+
+	// The mean is 0 and the mean absolute deviation is the scale.
+	if mean := sum / n; math.Abs(mean) > 0.25 {
+		t.Errorf("expected mean near 0, got %v", mean)
+	}
+	if mad := sumAbs / n; math.Abs(mad-scale) > 0.25 {
+		t.Errorf("expected mean absolute deviation near %v, got %v", scale, mad)
+	}
+}
+
+func TestCount(t *testing.T) {
+	t.Parallel()
+
+	low := &Mechanism{uniform: func() float64 { return 0.0001 }}
+	if got := low.Count(3, 1, 0.1); got != 0 {
+		t.Errorf("expected count to be clamped to 0, got %d", got)
+	}
+
+	m := NewMechanism()
+	if got := m.Count(42, 1, 1e9); got != 42 {
+		t.Errorf("expected 42 with negligible noise, got %d", got)
+	}
+
+	vs := []int64{1, 2, 3}
+	m.Counts(vs, 1, 1e9)
+	for i, v := range vs {
+		if v != int64(i+1) {
+			t.Errorf("expected bucket %d to be %d, got %d", i, i+1, v)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enctl
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) authorizedAppCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "authorized-app",
+		Aliases: []string{"app"},
+		Short:   "Manage authorized apps",
+	}
+	cmd.AddCommand(
+		a.authorizedAppListCommand(),
+		a.authorizedAppDisableCommand("disable", true),
+		a.authorizedAppDisableCommand("enable", false),
+	)
+	return cmd
+}
+
+func (a *app) authorizedAppListCommand() *cobra.Command {
+	var params coredb.PageParams
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List authorized apps",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				apps, page, err := database.New(db).SearchAuthorizedApps(ctx, &params)
+				if err != nil {
+					return fmt.Errorf("failed to list authorized apps: %w", err)
+				}
+				if err := a.print(cmd.OutOrStdout(), authorizedAppTable(apps)); err != nil {
+					return err
+				}
+				if !a.json {
+					cmd.Println(pageFooter(page))
+				}
+				return nil
+			})
+		},
+	}
+	addPageFlags(cmd, &params)
+	return cmd
+}
+
+func (a *app) authorizedAppDisableCommand(use string, disabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " APP_PACKAGE_NAME...",
+		Short: strings.ToUpper(use[:1]) + use[1:] + " authorized apps",
+		Long: fmt.Sprintf(`%s authorized apps. Disabled apps are rejected by the publish API,
+but keep their configuration. Uses the admin console API if --admin-url is set.`, strings.ToUpper(use[:1])+use[1:]),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			updated, notFound, err := a.setAuthorizedAppsDisabled(cmd.Context(), args, disabled)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Updated %d authorized apps.\n", updated)
+			if len(notFound) > 0 {
+				return fmt.Errorf("authorized apps not found: %s", strings.Join(notFound, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// setAuthorizedAppsDisabled disables or enables the apps. It returns the
+// number of apps updated and the apps that do not exist.
+func (a *app) setAuthorizedAppsDisabled(ctx context.Context, names []string, disabled bool) (int, []string, error) {
+	if a.adminURL != "" {
+		req := struct {
+			Apps     []string `json:"apps"`
+			Disabled bool     `json:"disabled"`
+		}{names, disabled}
+		var resp struct {
+			Updated  int      `json:"updated"`
+			NotFound []string `json:"notFound"`
+		}
+		if err := a.postAdmin(ctx, "/api/apps/disable", &req, &resp); err != nil {
+			return 0, nil, err
+		}
+		return resp.Updated, resp.NotFound, nil
+	}
+
+	var notFound []string
+	if err := a.withDB(ctx, func(db *coredb.DB) error {
+		var err error
+		notFound, err = database.New(db).SetAuthorizedAppsDisabled(ctx, names, disabled)
+		return err
+	}); err != nil {
+		return 0, nil, fmt.Errorf("failed to update authorized apps: %w", err)
+	}
+	return len(names) - len(notFound), notFound, nil
+}
+
+func authorizedAppTable(apps []*model.AuthorizedApp) *table {
+	t := &table{
+		header: []string{"APP PACKAGE NAME", "REGIONS", "HEALTH AUTHORITIES", "DISABLED", "TEST DATA"},
+		value:  apps,
+	}
+	for _, aa := range apps {
+		ids := aa.AllAllowedHealthAuthorityIDs()
+		has := make([]string, 0, len(ids))
+		for _, id := range ids {
+			has = append(has, strconv.FormatInt(id, 10))
+		}
+		t.rows = append(t.rows, []string{
+			aa.AppPackageName,
+			strings.Join(aa.AllAllowedRegions(), ","),
+			strings.Join(has, ","),
+			strconv.FormatBool(aa.Disabled),
+			strconv.FormatBool(aa.TestData),
+		})
+	}
+	return t
+}
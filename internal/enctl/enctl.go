@@ -0,0 +1,248 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package enctl implements the enctl command line tool, which administers the
+// configuration of a server deployment. Commands read and write the database
+// directly, using the same DB_ environment variables as the servers. Commands
+// that the admin console serves as a JSON API use it instead when --admin-url
+// is set.
+package enctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+// dateFormat is the format of days in flags.
+const dateFormat = "2006-01-02"
+
+// OpenDBFunc opens the database. The returned function closes it.
+type OpenDBFunc func(ctx context.Context) (*database.DB, func(), error)
+
+// app holds the state shared by the commands.
+type app struct {
+	openDB     OpenDBFunc
+	httpClient *http.Client
+
+	adminURL      string
+	json          bool
+	customRegions []string
+}
+
+// Option configures the command.
+type Option func(*app)
+
+// WithOpenDB sets how commands open the database. By default the database is
+// configured from the environment.
+func WithOpenDB(f OpenDBFunc) Option {
+	return func(a *app) {
+		a.openDB = f
+	}
+}
+
+// WithHTTPClient sets the client for the admin console API.
+func WithHTTPClient(c *http.Client) Option {
+	return func(a *app) {
+		a.httpClient = c
+	}
+}
+
+// NewCommand returns the root enctl command.
+func NewCommand(opts ...Option) *cobra.Command {
+	a := &app{
+		openDB:     openDBFromEnv,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	cmd := &cobra.Command{
+		Use:   "enctl",
+		Short: "Administer an exposure notifications server deployment",
+		Long: `enctl administers the configuration of an exposure notifications server
+deployment. Commands use the database configured by the DB_ environment
+variables. Commands served by the admin console JSON API use it instead when
+--admin-url is set.`,
+		SilenceUsage: true,
+	}
+	cmd.PersistentFlags().StringVar(&a.adminURL, "admin-url", "", "Base URL of the admin console, for commands that support its JSON API.")
+	cmd.PersistentFlags().BoolVar(&a.json, "json", false, "Print results as JSON instead of a table.")
+	cmd.PersistentFlags().StringSliceVar(&a.customRegions, "custom-regions", splitList(os.Getenv("CUSTOM_REGIONS")), "Regions to accept in addition to ISO 3166 codes. Defaults to CUSTOM_REGIONS.")
+
+	cmd.AddCommand(
+		a.exportConfigCommand(),
+		a.siginfoCommand(),
+		a.healthAuthorityCommand(),
+		a.authorizedAppCommand(),
+		a.federationQueryCommand(),
+		a.statsCommand(),
+	)
+	return cmd
+}
+
+// openDBFromEnv opens the database configured by the environment.
+func openDBFromEnv(ctx context.Context) (*database.DB, func(), error) {
+	var config database.Config
+	env, err := setup.Setup(ctx, &config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to setup database: %w", err)
+	}
+	return env.Database(), func() { env.Close(ctx) }, nil
+}
+
+// withDB runs f with the database.
+func (a *app) withDB(ctx context.Context, f func(db *database.DB) error) error {
+	db, closer, err := a.openDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+	return f(db)
+}
+
+// postAdmin posts the request as JSON to the admin console API at path, and
+// decodes the response into resp.
+func (a *app) postAdmin(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	u := strings.TrimRight(a.adminURL, "/") + path
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call admin console: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read admin console response: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		if err := json.Unmarshal(b, &errResp); err == nil && len(errResp.Errors) > 0 {
+			return fmt.Errorf("admin console returned %d: %s", httpResp.StatusCode, strings.Join(errResp.Errors, "; "))
+		}
+		return fmt.Errorf("admin console returned %d: %s", httpResp.StatusCode, b)
+	}
+
+	if err := json.Unmarshal(b, resp); err != nil {
+		return fmt.Errorf("failed to decode admin console response: %w", err)
+	}
+	return nil
+}
+
+// table is the result of a command, printed as a table or JSON.
+type table struct {
+	header []string
+	rows   [][]string
+
+	// value is printed instead of the rows with --json.
+	value interface{}
+}
+
+// print writes the table to w.
+func (a *app) print(w io.Writer, t *table) error {
+	if a.json {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t.value)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// parseTimestamp parses an RFC3339 flag value. An empty value is the zero
+// time.
+func parseTimestamp(flag, val string) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse --%s (use RFC3339): %w", flag, err)
+	}
+	return t, nil
+}
+
+// regions returns the validator for region flags.
+func (a *app) regions() *region.Validator {
+	return region.New(&region.Config{CustomRegions: a.customRegions})
+}
+
+// splitList splits a comma separated list, like envconfig does.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// formatTime formats t for a table, or "-" if it is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// addPageFlags adds the flags that select a page of a list.
+func addPageFlags(cmd *cobra.Command, p *database.PageParams) {
+	cmd.Flags().StringVar(&p.Search, "search", "", "Only list entries that contain the text.")
+	cmd.Flags().StringVar(&p.Sort, "sort", "", "The column to sort by.")
+	cmd.Flags().BoolVar(&p.Desc, "desc", false, "Sort in descending order.")
+	cmd.Flags().IntVar(&p.Page, "page", 1, "The page to list, starting at 1.")
+	cmd.Flags().IntVar(&p.Limit, "limit", database.DefaultPageLimit, fmt.Sprintf("The number of entries in a page, at most %d.", database.MaxPageLimit))
+}
+
+// pageFooter returns the line printed after a page of a table.
+func pageFooter(p *database.Page) string {
+	return fmt.Sprintf("page %d of %d (%d total)", p.Number, p.Pages(), p.Total)
+}
+
+// joinOrDash joins the values with commas, or returns "-" if there are none.
+func joinOrDash(vals []string) string {
+	if len(vals) == 0 {
+		return "-"
+	}
+	return strings.Join(vals, ",")
+}
@@ -0,0 +1,334 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/go-cmp/cmp"
+)
+
+// noDB fails the test if a command opens the database.
+func noDB(tb testing.TB) Option {
+	return WithOpenDB(func(ctx context.Context) (*database.DB, func(), error) {
+		tb.Errorf("unexpected database access")
+		return nil, nil, fmt.Errorf("no database")
+	})
+}
+
+// run runs the enctl command with args and returns its output.
+func run(tb testing.TB, args []string, opts ...Option) (string, error) {
+	tb.Helper()
+
+	cmd := NewCommand(opts...)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(project.TestContext(tb))
+	return out.String(), err
+}
+
+func TestNewCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewCommand()
+	for _, path := range [][]string{
+		{"export-config", "list"},
+		{"export-config", "show"},
+		{"export-config", "create"},
+		{"siginfo", "list"},
+		{"siginfo", "create"},
+		{"health-authority", "list"},
+		{"ha", "create"},
+		{"authorized-app", "list"},
+		{"app", "disable"},
+		{"authorized-app", "enable"},
+		{"federation-query", "show"},
+		{"federation-query", "create"},
+		{"stats", "show"},
+		{"stats", "recompute"},
+	} {
+		found, _, err := cmd.Find(path)
+		if err != nil || found == cmd {
+			t.Errorf("command %q not found: %v", strings.Join(path, " "), err)
+		}
+	}
+}
+
+func TestValidationBeforeDatabase(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "export config without bucket",
+			args: []string{"export-config", "create", "--region", "US", "--filename-root", "us"},
+			err:  "--bucket-name is required",
+		},
+		{
+			name: "export config bad period",
+			args: []string{"export-config", "create", "--region", "US", "--filename-root", "us", "--bucket-name", "b", "--period", "7h"},
+			err:  "period must divide equally",
+		},
+		{
+			name: "export config bad timestamp",
+			args: []string{"export-config", "create", "--region", "US", "--filename-root", "us", "--bucket-name", "b", "--from-timestamp", "today"},
+			err:  "failed to parse --from-timestamp",
+		},
+		{
+			name: "export config unknown region",
+			args: []string{"export-config", "create", "--region", "USA", "--filename-root", "us", "--bucket-name", "b"},
+			err:  `invalid --region: unknown region "USA", did you mean "US"?`,
+		},
+		{
+			name: "export config unknown input region",
+			args: []string{"export-config", "create", "--region", "TEST", "--input-regions", "TEST,TSET", "--filename-root", "us", "--bucket-name", "b", "--custom-regions", "TEST"},
+			err:  `invalid --input-regions: unknown region "TSET"`,
+		},
+		{
+			name: "siginfo without key",
+			args: []string{"siginfo", "create"},
+			err:  "--signing-key is required",
+		},
+		{
+			name: "health authority without name",
+			args: []string{"ha", "create", "--issuer", "iss", "--audience", "aud"},
+			err:  "name cannot be empty",
+		},
+		{
+			name: "federation query bad id",
+			args: []string{"federation-query", "create", "--query-id", "Bad", "--server-addr", "example.com:443"},
+			err:  "--query-id",
+		},
+		{
+			name: "federation query bad audience",
+			args: []string{"federation-query", "create", "--query-id", "other-server", "--server-addr", "example.com:443", "--audience", "http://example.com"},
+			err:  "--audience",
+		},
+		{
+			name: "federation query unknown region",
+			args: []string{"federation-query", "create", "--query-id", "other-server", "--server-addr", "example.com:443", "--exclude-regions", "CAN"},
+			err:  `invalid --exclude-regions: unknown region "CAN", did you mean "CA"?`,
+		},
+		{
+			name: "stats show without health authority",
+			args: []string{"stats", "show"},
+			err:  "--health-authority-id is required",
+		},
+		{
+			name: "stats recompute without reason",
+			args: []string{"stats", "recompute", "--from", "2021-03-01"},
+			err:  "--reason is required",
+		},
+		{
+			name: "export config show bad id",
+			args: []string{"export-config", "show", "one"},
+			err:  "failed to parse export config ID",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := run(t, tc.args, noDB(t))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestExportConfigFlags(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	f := &exportConfigFlags{
+		bucketName:       "bucket",
+		filenameRoot:     "root",
+		period:           4 * time.Hour,
+		region:           "us",
+		inputRegions:     []string{"us", " ca ", ""},
+		includeTravelers: true,
+		thruTimestamp:    "2021-04-01T00:00:00Z",
+		signatureInfoIDs: []int64{3},
+	}
+
+	ec, err := f.exportConfig(now, region.New(&region.Config{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ec.OutputRegion, "US"; got != want {
+		t.Errorf("expected output region %q, got %q", want, got)
+	}
+	if diff := cmp.Diff([]string{"US", "CA"}, ec.InputRegions); diff != "" {
+		t.Errorf("input regions mismatch (-want, +got):\n%s", diff)
+	}
+	if !ec.From.Equal(now) {
+		t.Errorf("expected from %v, got %v", now, ec.From)
+	}
+	if want := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC); !ec.Thru.Equal(want) {
+		t.Errorf("expected thru %v, got %v", want, ec.Thru)
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		from, to string
+		wantFrom string
+		wantTo   string
+		err      bool
+	}{
+		{from: "2021-03-01", wantFrom: "2021-03-01", wantTo: "2021-03-02"},
+		{from: "2021-03-01", to: "2021-03-07", wantFrom: "2021-03-01", wantTo: "2021-03-08"},
+		{from: "", err: true},
+		{from: "2021-03-07", to: "2021-03-01", err: true},
+		{from: "03/01/2021", err: true},
+	}
+
+	for _, tc := range cases {
+		from, to, err := parseDays(tc.from, tc.to)
+		if (err != nil) != tc.err {
+			t.Errorf("parseDays(%q, %q): expected error %t, got %v", tc.from, tc.to, tc.err, err)
+			continue
+		}
+		if tc.err {
+			continue
+		}
+		if got := from.Format(dateFormat); got != tc.wantFrom {
+			t.Errorf("parseDays(%q, %q): expected from %s, got %s", tc.from, tc.to, tc.wantFrom, got)
+		}
+		if got := to.Format(dateFormat); got != tc.wantTo {
+			t.Errorf("parseDays(%q, %q): expected to %s, got %s", tc.from, tc.to, tc.wantTo, got)
+		}
+	}
+}
+
+func TestSumStatsDays(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	hourly := []*model.HealthAuthorityStats{
+		{Hour: day, PublishCount: []int64{1, 2, 3}, TEKCount: 10, RevisionCount: 1},
+		{Hour: day.Add(5 * time.Hour), PublishCount: []int64{1, 0, 0}, TEKCount: 5, MissingOnset: 1},
+		{Hour: day.Add(25 * time.Hour), PublishCount: []int64{0, 1, 0}, TEKCount: 3},
+	}
+
+	want := []*statsDay{
+		{Day: "2021-03-01", Publishes: 7, TEKs: 15, Revisions: 1, MissingOnset: 1},
+		{Day: "2021-03-02", Publishes: 1, TEKs: 3},
+	}
+	if diff := cmp.Diff(want, sumStatsDays(hourly)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestAuthorizedAppDisable_AdminAPI(t *testing.T) {
+	t.Parallel()
+
+	var got struct {
+		Apps     []string `json:"apps"`
+		Disabled bool     `json:"disabled"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/apps/disable" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"updated":1,"notFound":["com.missing"]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	out, err := run(t, []string{"app", "disable", "--admin-url", srv.URL + "/", "com.example", "com.missing"},
+		noDB(t), WithHTTPClient(srv.Client()))
+	if err == nil || !strings.Contains(err.Error(), "com.missing") {
+		t.Errorf("expected error for missing app, got %v", err)
+	}
+	if !strings.Contains(out, "Updated 1 authorized apps.") {
+		t.Errorf("expected update count in output, got %q", out)
+	}
+	if diff := cmp.Diff([]string{"com.example", "com.missing"}, got.Apps); diff != "" {
+		t.Errorf("apps mismatch (-want, +got):\n%s", diff)
+	}
+	if !got.Disabled {
+		t.Errorf("expected apps to be disabled")
+	}
+}
+
+func TestAuthorizedAppEnable_AdminAPIError(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"errors":["no authorized apps provided"]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := run(t, []string{"app", "enable", "--admin-url", srv.URL, "com.example"},
+		noDB(t), WithHTTPClient(srv.Client()))
+	if err == nil || !strings.Contains(err.Error(), "400: no authorized apps provided") {
+		t.Errorf("expected admin console error, got %v", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	t.Parallel()
+
+	tbl := &table{
+		header: []string{"ID", "NAME"},
+		rows:   [][]string{{"1", "one"}, {"22", "two"}},
+		value:  []map[string]string{{"name": "one"}},
+	}
+
+	var b bytes.Buffer
+	if err := (&app{}).print(&b, tbl); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "ID  NAME\n1   one\n22  two\n"; got != want {
+		t.Errorf("expected table %q, got %q", want, got)
+	}
+
+	b.Reset()
+	if err := (&app{json: true}).print(&b, tbl); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "[\n  {\n    \"name\": \"one\"\n  }\n]\n"; got != want {
+		t.Errorf("expected JSON %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enctl
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) exportConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-config",
+		Short: "Manage export configs",
+	}
+	cmd.AddCommand(a.exportConfigListCommand(), a.exportConfigShowCommand(), a.exportConfigCreateCommand())
+	return cmd
+}
+
+func (a *app) exportConfigListCommand() *cobra.Command {
+	var params coredb.PageParams
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List export configs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				configs, page, err := database.New(db).SearchExportConfigs(ctx, &params)
+				if err != nil {
+					return fmt.Errorf("failed to list export configs: %w", err)
+				}
+				if err := a.print(cmd.OutOrStdout(), exportConfigTable(configs)); err != nil {
+					return err
+				}
+				if !a.json {
+					cmd.Println(pageFooter(page))
+				}
+				return nil
+			})
+		},
+	}
+	addPageFlags(cmd, &params)
+	return cmd
+}
+
+func (a *app) exportConfigShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show ID",
+		Short: "Show an export config",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse export config ID %q: %w", args[0], err)
+			}
+
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				ec, err := database.New(db).GetExportConfig(ctx, id)
+				if err != nil {
+					return fmt.Errorf("failed to get export config %d: %w", id, err)
+				}
+				return a.print(cmd.OutOrStdout(), exportConfigTable([]*model.ExportConfig{ec}))
+			})
+		},
+	}
+}
+
+// exportConfigFlags are the flags of export-config create.
+type exportConfigFlags struct {
+	bucketName        string
+	filenameRoot      string
+	period            time.Duration
+	region            string
+	inputRegions      []string
+	excludeRegions    []string
+	includeTravelers  bool
+	fromTimestamp     string
+	thruTimestamp     string
+	signatureInfoIDs  []int64
+	signingKey        string
+	signingKeyID      string
+	signingKeyVersion string
+}
+
+// exportConfig returns the export config for the flags. The signature infos
+// are set by the caller.
+func (f *exportConfigFlags) exportConfig(now time.Time, regions *region.Validator) (*model.ExportConfig, error) {
+	if f.bucketName == "" {
+		return nil, fmt.Errorf("--bucket-name is required")
+	}
+	if f.filenameRoot == "" {
+		return nil, fmt.Errorf("--filename-root is required")
+	}
+	if f.region == "" {
+		return nil, fmt.Errorf("--region is required")
+	}
+	outputRegion, err := regions.Normalize(f.region)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --region: %w", err)
+	}
+	inputRegions, err := regions.NormalizeAll(f.inputRegions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --input-regions: %w", err)
+	}
+	excludeRegions, err := regions.NormalizeAll(f.excludeRegions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --exclude-regions: %w", err)
+	}
+
+	from, err := parseTimestamp("from-timestamp", f.fromTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	if from.IsZero() {
+		from = now
+	}
+	thru, err := parseTimestamp("thru-timestamp", f.thruTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	ec := &model.ExportConfig{
+		BucketName:       f.bucketName,
+		FilenameRoot:     f.filenameRoot,
+		Period:           f.period,
+		OutputRegion:     outputRegion,
+		InputRegions:     inputRegions,
+		ExcludeRegions:   excludeRegions,
+		IncludeTravelers: f.includeTravelers,
+		From:             from,
+		Thru:             thru,
+		SignatureInfoIDs: f.signatureInfoIDs,
+	}
+	if err := ec.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid export config: %w", err)
+	}
+	return ec, nil
+}
+
+func (a *app) exportConfigCreateCommand() *cobra.Command {
+	var f exportConfigFlags
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an export config",
+		Long: `Create an export config. Use --signature-info-id to sign with existing
+signature infos, or --signing-key to create a new signature info for it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ec, err := f.exportConfig(time.Now(), a.regions())
+			if err != nil {
+				return err
+			}
+			if f.signingKey == "" && len(ec.SignatureInfoIDs) == 0 {
+				log.Printf("WARNING - you are creating an export config without a signing key!!")
+			}
+
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				exportDB := database.New(db)
+				if f.signingKey != "" {
+					si := &model.SignatureInfo{
+						SigningKey:        f.signingKey,
+						SigningKeyVersion: f.signingKeyVersion,
+						SigningKeyID:      f.signingKeyID,
+					}
+					if err := exportDB.AddSignatureInfo(ctx, si); err != nil {
+						return fmt.Errorf("failed to add signature info: %w", err)
+					}
+					ec.SignatureInfoIDs = append(ec.SignatureInfoIDs, si.ID)
+				}
+
+				if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+					return fmt.Errorf("failed to add export config: %w", err)
+				}
+				cmd.Printf("Created export config %d.\n", ec.ConfigID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&f.bucketName, "bucket-name", "", "(Required) The bucket name to store the export files.")
+	cmd.Flags().StringVar(&f.filenameRoot, "filename-root", "", "(Required) The root filename for the export files.")
+	cmd.Flags().DurationVar(&f.period, "period", 24*time.Hour, "The frequency with which to create export files.")
+	cmd.Flags().StringVar(&f.region, "region", "", "(Required) The output region for the export batches and files.")
+	cmd.Flags().StringSliceVar(&f.inputRegions, "input-regions", nil, "The regions to export keys from. Defaults to the output region.")
+	cmd.Flags().StringSliceVar(&f.excludeRegions, "exclude-regions", nil, "The regions to exclude keys from.")
+	cmd.Flags().BoolVar(&f.includeTravelers, "include-travelers", true, "Include travelers from any region.")
+	cmd.Flags().StringVar(&f.fromTimestamp, "from-timestamp", "", "The timestamp (RFC3339) when this config becomes active. Defaults to now.")
+	cmd.Flags().StringVar(&f.thruTimestamp, "thru-timestamp", "", "The timestamp (RFC3339) when this config ends.")
+	cmd.Flags().Int64SliceVar(&f.signatureInfoIDs, "signature-info-id", nil, "The IDs of existing signature infos to sign with.")
+	cmd.Flags().StringVar(&f.signingKey, "signing-key", "", "The key manager resource ID to create a signature info for.")
+	cmd.Flags().StringVar(&f.signingKeyID, "signing-key-id", "", "The ID of the new signing key (for clients).")
+	cmd.Flags().StringVar(&f.signingKeyVersion, "signing-key-version", "", "The version of the new signing key (for clients).")
+	return cmd
+}
+
+func exportConfigTable(configs []*model.ExportConfig) *table {
+	t := &table{
+		header: []string{"ID", "OUTPUT REGION", "INPUT REGIONS", "BUCKET", "FILENAME ROOT", "PERIOD", "FROM", "THRU", "SIGNATURE INFOS"},
+		value:  configs,
+	}
+	for _, ec := range configs {
+		ids := make([]string, 0, len(ec.SignatureInfoIDs))
+		for _, id := range ec.SignatureInfoIDs {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		t.rows = append(t.rows, []string{
+			strconv.FormatInt(ec.ConfigID, 10),
+			ec.OutputRegion,
+			strings.Join(ec.EffectiveInputRegions(), ","),
+			ec.BucketName,
+			ec.FilenameRoot,
+			ec.Period.String(),
+			formatTime(ec.From),
+			formatTime(ec.Thru),
+			strings.Join(ids, ","),
+		})
+	}
+	return t
+}
@@ -0,0 +1,161 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enctl
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/exposure-notifications-server/internal/federationin"
+	"github.com/google/exposure-notifications-server/internal/federationin/database"
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validQueryIDStr    = `\A[a-z][a-z0-9-_]*[a-z0-9]\z`
+	validQueryIDRegexp = regexp.MustCompile(validQueryIDStr)
+
+	validServerAddrStr    = `\A[a-z0-9.-]+(:\d+)?\z`
+	validServerAddrRegexp = regexp.MustCompile(validServerAddrStr)
+)
+
+func (a *app) federationQueryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "federation-query",
+		Short: "Manage the queries for federating keys in from other servers",
+	}
+	cmd.AddCommand(a.federationQueryShowCommand(), a.federationQueryCreateCommand())
+	return cmd
+}
+
+func (a *app) federationQueryShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show QUERY_ID",
+		Short: "Show a federation query",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				query, err := database.New(db).GetFederationInQuery(ctx, args[0])
+				if err != nil {
+					return fmt.Errorf("failed to get federation query %s: %w", args[0], err)
+				}
+				return a.print(cmd.OutOrStdout(), federationQueryTable(query))
+			})
+		},
+	}
+}
+
+// federationQueryFlags are the flags of federation-query create.
+type federationQueryFlags struct {
+	queryID        string
+	serverAddr     string
+	audience       string
+	regions        []string
+	excludeRegions []string
+	lastTimestamp  string
+}
+
+// query returns the federation query for the flags.
+func (f *federationQueryFlags) query(regions *region.Validator) (*model.FederationInQuery, error) {
+	if f.queryID == "" {
+		return nil, fmt.Errorf("--query-id is required")
+	}
+	if !validQueryIDRegexp.MatchString(f.queryID) {
+		return nil, fmt.Errorf("--query-id %q must match %s", f.queryID, validQueryIDStr)
+	}
+	if f.serverAddr == "" {
+		return nil, fmt.Errorf("--server-addr is required")
+	}
+	if !validServerAddrRegexp.MatchString(f.serverAddr) {
+		return nil, fmt.Errorf("--server-addr %q must match %s", f.serverAddr, validServerAddrStr)
+	}
+	if f.audience == "" {
+		return nil, fmt.Errorf("--audience is required")
+	}
+	if !federationin.ValidAudienceRegexp.MatchString(f.audience) {
+		return nil, fmt.Errorf("--audience %q must match %s", f.audience, federationin.ValidAudienceStr)
+	}
+	includeRegions, err := regions.NormalizeAll(f.regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --regions: %w", err)
+	}
+	excludeRegions, err := regions.NormalizeAll(f.excludeRegions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --exclude-regions: %w", err)
+	}
+	lastTime, err := parseTimestamp("last-timestamp", f.lastTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.FederationInQuery{
+		QueryID:        f.queryID,
+		ServerAddr:     f.serverAddr,
+		Audience:       f.audience,
+		IncludeRegions: includeRegions,
+		ExcludeRegions: excludeRegions,
+		LastTimestamp:  lastTime,
+	}, nil
+}
+
+func (a *app) federationQueryCreateCommand() *cobra.Command {
+	var f federationQueryFlags
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create or replace a federation query",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query, err := f.query(a.regions())
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				if err := database.New(db).AddFederationInQuery(ctx, query); err != nil {
+					return fmt.Errorf("failed to add federation query %s: %w", query.QueryID, err)
+				}
+				cmd.Printf("Added federation query %s.\n", query.QueryID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&f.queryID, "query-id", "", "(Required) The ID of the federation query to set.")
+	cmd.Flags().StringVar(&f.serverAddr, "server-addr", "", "(Required) The address of the remote server, in the form some-server:some-port.")
+	cmd.Flags().StringVar(&f.audience, "audience", federationin.DefaultAudience, "The OIDC audience to use when creating client tokens.")
+	cmd.Flags().StringSliceVar(&f.regions, "regions", nil, "The regions to query. Leave blank for all regions.")
+	cmd.Flags().StringSliceVar(&f.excludeRegions, "exclude-regions", nil, "The regions to exclude from the query.")
+	cmd.Flags().StringVar(&f.lastTimestamp, "last-timestamp", "", "The last timestamp (RFC3339) to set; queries start from this point and go forward.")
+	return cmd
+}
+
+func federationQueryTable(q *model.FederationInQuery) *table {
+	return &table{
+		header: []string{"QUERY ID", "SERVER", "AUDIENCE", "REGIONS", "EXCLUDE REGIONS", "LAST TIMESTAMP"},
+		rows: [][]string{{
+			q.QueryID,
+			q.ServerAddr,
+			q.Audience,
+			joinOrDash(q.IncludeRegions),
+			joinOrDash(q.ExcludeRegions),
+			formatTime(q.LastTimestamp),
+		}},
+		value: q,
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enctl
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/verification/database"
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) healthAuthorityCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "health-authority",
+		Aliases: []string{"ha"},
+		Short:   "Manage health authorities",
+	}
+	cmd.AddCommand(a.healthAuthorityListCommand(), a.healthAuthorityCreateCommand())
+	return cmd
+}
+
+func (a *app) healthAuthorityListCommand() *cobra.Command {
+	var params coredb.PageParams
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List health authorities",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				has, page, err := database.New(db).SearchHealthAuthorities(ctx, &params)
+				if err != nil {
+					return fmt.Errorf("failed to list health authorities: %w", err)
+				}
+				if err := a.print(cmd.OutOrStdout(), healthAuthorityTable(has)); err != nil {
+					return err
+				}
+				if !a.json {
+					cmd.Println(pageFooter(page))
+				}
+				return nil
+			})
+		},
+	}
+	addPageFlags(cmd, &params)
+	return cmd
+}
+
+// healthAuthorityFlags are the flags of health-authority create.
+type healthAuthorityFlags struct {
+	issuer       string
+	audience     string
+	name         string
+	jwksURI      string
+	jurisdiction string
+	enableStats  bool
+	exportDelay  time.Duration
+	stage        string
+}
+
+// healthAuthority returns the health authority for the flags.
+func (f *healthAuthorityFlags) healthAuthority() (*model.HealthAuthority, error) {
+	ha := &model.HealthAuthority{
+		Issuer:         f.issuer,
+		Audience:       f.audience,
+		Name:           f.name,
+		EnableStatsAPI: f.enableStats,
+		ExportDelay:    f.exportDelay,
+		Stage:          model.Stage(f.stage),
+	}
+	ha.SetJWKS(f.jwksURI)
+	ha.SetJurisdiction(f.jurisdiction)
+	if err := ha.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid health authority: %w", err)
+	}
+	return ha, nil
+}
+
+func (a *app) healthAuthorityCreateCommand() *cobra.Command {
+	var f healthAuthorityFlags
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a health authority",
+		Long: `Create a health authority. Add its verification keys in the admin console,
+or use --jwks-uri to discover them.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ha, err := f.healthAuthority()
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				if err := database.New(db).AddHealthAuthority(ctx, ha); err != nil {
+					return fmt.Errorf("failed to add health authority: %w", err)
+				}
+				cmd.Printf("Created health authority %d.\n", ha.ID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&f.issuer, "issuer", "", "(Required) The issuer of the verification certificates.")
+	cmd.Flags().StringVar(&f.audience, "audience", "", "(Required) The audience of the verification certificates.")
+	cmd.Flags().StringVar(&f.name, "name", "", "(Required) The display name of the health authority.")
+	cmd.Flags().StringVar(&f.jwksURI, "jwks-uri", "", "The URI to discover verification keys from.")
+	cmd.Flags().StringVar(&f.jurisdiction, "jurisdiction", "", "The data residency jurisdiction, e.g. a country code.")
+	cmd.Flags().BoolVar(&f.enableStats, "enable-stats", false, "Enable the stats API for the health authority.")
+	cmd.Flags().DurationVar(&f.exportDelay, "export-delay", 0, "How long verified keys are held out of exports.")
+	cmd.Flags().StringVar(&f.stage, "stage", "", "The onboarding stage. Defaults to active.")
+	return cmd
+}
+
+func healthAuthorityTable(has []*model.HealthAuthority) *table {
+	t := &table{
+		header: []string{"ID", "ISSUER", "AUDIENCE", "NAME", "JURISDICTION", "STAGE", "STATS"},
+		value:  has,
+	}
+	for _, ha := range has {
+		t.rows = append(t.rows, []string{
+			strconv.FormatInt(ha.ID, 10),
+			ha.Issuer,
+			ha.Audience,
+			ha.Name,
+			ha.Jurisdiction,
+			string(ha.Stage),
+			strconv.FormatBool(ha.EnableStatsAPI),
+		})
+	}
+	return t
+}
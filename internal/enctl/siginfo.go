@@ -0,0 +1,112 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enctl
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) siginfoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "siginfo",
+		Short: "Manage export signature infos",
+	}
+	cmd.AddCommand(a.siginfoListCommand(), a.siginfoCreateCommand())
+	return cmd
+}
+
+func (a *app) siginfoListCommand() *cobra.Command {
+	var params coredb.PageParams
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List signature infos",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				infos, page, err := database.New(db).SearchSignatureInfos(ctx, &params)
+				if err != nil {
+					return fmt.Errorf("failed to list signature infos: %w", err)
+				}
+				if err := a.print(cmd.OutOrStdout(), siginfoTable(infos)); err != nil {
+					return err
+				}
+				if !a.json {
+					cmd.Println(pageFooter(page))
+				}
+				return nil
+			})
+		},
+	}
+	addPageFlags(cmd, &params)
+	return cmd
+}
+
+func (a *app) siginfoCreateCommand() *cobra.Command {
+	var si model.SignatureInfo
+	var endTimestamp string
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a signature info",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if si.SigningKey == "" {
+				return fmt.Errorf("--signing-key is required")
+			}
+			end, err := parseTimestamp("end-timestamp", endTimestamp)
+			if err != nil {
+				return err
+			}
+			si.EndTimestamp = end
+
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				if err := database.New(db).AddSignatureInfo(ctx, &si); err != nil {
+					return fmt.Errorf("failed to add signature info: %w", err)
+				}
+				cmd.Printf("Created signature info %d.\n", si.ID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&si.SigningKey, "signing-key", "", "(Required) The key manager resource ID of the signing key.")
+	cmd.Flags().StringVar(&si.SigningKeyID, "signing-key-id", "", "The ID of the signing key (for clients).")
+	cmd.Flags().StringVar(&si.SigningKeyVersion, "signing-key-version", "", "The version of the signing key (for clients).")
+	cmd.Flags().StringVar(&endTimestamp, "end-timestamp", "", "The timestamp (RFC3339) when this key stops being used.")
+	return cmd
+}
+
+func siginfoTable(infos []*model.SignatureInfo) *table {
+	t := &table{
+		header: []string{"ID", "SIGNING KEY ID", "VERSION", "SIGNING KEY", "END"},
+		value:  infos,
+	}
+	for _, si := range infos {
+		t.rows = append(t.rows, []string{
+			strconv.FormatInt(si.ID, 10),
+			si.SigningKeyID,
+			si.SigningKeyVersion,
+			si.SigningKey,
+			formatTime(si.EndTimestamp),
+		})
+	}
+	return t
+}
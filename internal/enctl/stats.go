@@ -0,0 +1,166 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enctl
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/publish/database"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+func (a *app) statsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Inspect and recompute health authority stats",
+	}
+	cmd.AddCommand(a.statsShowCommand(), a.statsRecomputeCommand())
+	return cmd
+}
+
+// statsDay is the raw stats of a health authority for a UTC day.
+type statsDay struct {
+	Day          string `json:"day"`
+	Publishes    int64  `json:"publishes"`
+	TEKs         int64  `json:"teks"`
+	Revisions    int64  `json:"revisions"`
+	MissingOnset int64  `json:"missingOnset"`
+}
+
+// sumStatsDays adds up the hourly stats by UTC day, in order.
+func sumStatsDays(hourly []*model.HealthAuthorityStats) []*statsDay {
+	days := make([]*statsDay, 0, len(hourly)/24+1)
+	for _, h := range hourly {
+		day := h.Hour.UTC().Format(dateFormat)
+		if len(days) == 0 || days[len(days)-1].Day != day {
+			days = append(days, &statsDay{Day: day})
+		}
+		d := days[len(days)-1]
+		for _, n := range h.PublishCount {
+			d.Publishes += n
+		}
+		d.TEKs += h.TEKCount
+		d.Revisions += h.RevisionCount
+		d.MissingOnset += h.MissingOnset
+	}
+	return days
+}
+
+func (a *app) statsShowCommand() *cobra.Command {
+	var healthAuthorityID int64
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the daily stats of a health authority",
+		Long: `Show the daily stats of a health authority. These are the stored counts,
+without the thresholds and noise that the stats API applies.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if healthAuthorityID <= 0 {
+				return fmt.Errorf("--health-authority-id is required")
+			}
+
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				hourly, err := database.New(db).ReadStats(ctx, healthAuthorityID)
+				if err != nil {
+					return fmt.Errorf("failed to read stats: %w", err)
+				}
+
+				days := sumStatsDays(hourly)
+				t := &table{
+					header: []string{"DAY", "PUBLISHES", "TEKS", "REVISIONS", "MISSING ONSET"},
+					value:  days,
+				}
+				for _, d := range days {
+					t.rows = append(t.rows, []string{
+						d.Day,
+						strconv.FormatInt(d.Publishes, 10),
+						strconv.FormatInt(d.TEKs, 10),
+						strconv.FormatInt(d.Revisions, 10),
+						strconv.FormatInt(d.MissingOnset, 10),
+					})
+				}
+				return a.print(cmd.OutOrStdout(), t)
+			})
+		},
+	}
+	cmd.Flags().Int64Var(&healthAuthorityID, "health-authority-id", 0, "(Required) The health authority to show.")
+	return cmd
+}
+
+// parseDays parses the --from and --to days of stats recompute. It returns the
+// start of the first day and the end of the last day.
+func parseDays(fromDate, toDate string) (time.Time, time.Time, error) {
+	if fromDate == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("--from is required")
+	}
+	if toDate == "" {
+		toDate = fromDate
+	}
+
+	from, err := time.Parse(dateFormat, fromDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse --from (use YYYY-MM-DD): %w", err)
+	}
+	to, err := time.Parse(dateFormat, toDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse --to (use YYYY-MM-DD): %w", err)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--to must not be before --from")
+	}
+	return from, to.Add(24 * time.Hour), nil
+}
+
+func (a *app) statsRecomputeCommand() *cobra.Command {
+	var fromDate, toDate, reason string
+	var healthAuthorityID int64
+	cmd := &cobra.Command{
+		Use:   "recompute",
+		Short: "Recompute health authority stats from the stored exposures",
+		Long: `Recompute health authority stats from the stored exposures for a range of
+days, for example after fixing a counting bug or importing late federation
+data.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, to, err := parseDays(fromDate, toDate)
+			if err != nil {
+				return err
+			}
+			if reason == "" {
+				return fmt.Errorf("--reason is required")
+			}
+
+			ctx := cmd.Context()
+			return a.withDB(ctx, func(db *coredb.DB) error {
+				count, err := database.New(db).RecomputeStats(ctx, from, to, healthAuthorityID, reason)
+				if err != nil {
+					return fmt.Errorf("failed to recompute stats: %w", err)
+				}
+				cmd.Printf("Recomputed %d hours of stats from %s to %s.\n", count, from.Format(dateFormat), to.Format(dateFormat))
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&fromDate, "from", "", "(Required) The first UTC day (YYYY-MM-DD) to recompute.")
+	cmd.Flags().StringVar(&toDate, "to", "", "The last UTC day (YYYY-MM-DD) to recompute, inclusive. Defaults to --from.")
+	cmd.Flags().Int64Var(&healthAuthorityID, "health-authority-id", 0, "The health authority to recompute, or 0 for all.")
+	cmd.Flags().StringVar(&reason, "reason", "", "(Required) Why the stats are recomputed. Stored with each recomputed hour.")
+	return cmd
+}
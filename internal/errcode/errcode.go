@@ -0,0 +1,343 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errcode defines the stable error codes returned by the server's
+// APIs. Each code maps to an HTTP status, a gRPC status code, and a short
+// localized message.
+//
+// Codes are part of the public contract: clients are expected to switch on
+// the code instead of matching on error messages, which may change at any
+// time. Existing codes must never be renamed or removed.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain is the domain reported in the ErrorInfo detail of gRPC errors.
+const Domain = "exposure-notifications-server"
+
+// DefaultLanguage is the language used when a message is not available in the
+// requested language.
+const DefaultLanguage = "en"
+
+// Code is a stable, machine-readable error code.
+type Code string
+
+// Publish and stats API codes. The values are defined in pkg/api/v1.
+const (
+	UnknownHealthAuthorityID                  Code = verifyapi.ErrorUnknownHealthAuthorityID
+	UnableToLoadHealthAuthority               Code = verifyapi.ErrorUnableToLoadHealthAuthority
+	HealthAuthorityMissingRegionConfiguration Code = verifyapi.ErrorHealthAuthorityMissingRegionConfiguration
+	VerificationCertificateInvalid            Code = verifyapi.ErrorVerificationCertificateInvalid
+	BadRequest                                Code = verifyapi.ErrorBadRequest
+	InternalError                             Code = verifyapi.ErrorInternalError
+	MissingRevisionToken                      Code = verifyapi.ErrorMissingRevisionToken
+	InvalidRevisionToken                      Code = verifyapi.ErrorInvalidRevisionToken
+	KeyAlreadyRevised                         Code = verifyapi.ErrorKeyAlreadyRevised
+	InvalidReportTypeTransition               Code = verifyapi.ErrorInvalidReportTypeTransition
+	InvalidChunk                              Code = verifyapi.ErrorInvalidChunk
+	PartialFailure                            Code = verifyapi.ErrorPartialFailure
+	InvalidRequestSignature                   Code = verifyapi.ErrorInvalidRequestSignature
+	AddressNotAllowed                         Code = verifyapi.ErrorAddressNotAllowed
+	TemporarilyThrottled                      Code = verifyapi.ErrorTemporarilyThrottled
+	Unauthorized                              Code = verifyapi.ErrorUnauthorized
+	PrivacyBudgetExhausted                    Code = verifyapi.ErrorPrivacyBudgetExhausted
+)
+
+// Federation codes.
+const (
+	MissingAuthorization       Code = "missing_authorization"
+	InvalidToken               Code = "invalid_token"
+	InvalidAudience            Code = "invalid_audience"
+	UnknownFederationClient    Code = "unknown_federation_client"
+	UnsupportedProtocolVersion Code = "unsupported_protocol_version"
+	NoCommonProtocolVersion    Code = "no_common_protocol_version"
+	UnsupportedCompression     Code = "unsupported_compression"
+	InvalidCursor              Code = "invalid_cursor"
+)
+
+// Export importer codes.
+const (
+	ImportFailed Code = "import_failed"
+	TooEarly     Code = "too_early"
+)
+
+type definition struct {
+	httpStatus int
+	grpcCode   codes.Code
+	messages   map[string]string
+}
+
+var registry = map[Code]*definition{
+	UnknownHealthAuthorityID: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The health authority is not registered with this server.",
+		"es": "La autoridad sanitaria no está registrada en este servidor.",
+	}},
+	UnableToLoadHealthAuthority: {http.StatusInternalServerError, codes.Unavailable, map[string]string{
+		"en": "The health authority configuration could not be loaded. Try again later.",
+		"es": "No se pudo cargar la configuración de la autoridad sanitaria. Inténtelo más tarde.",
+	}},
+	HealthAuthorityMissingRegionConfiguration: {http.StatusInternalServerError, codes.FailedPrecondition, map[string]string{
+		"en": "The health authority has no regions configured.",
+		"es": "La autoridad sanitaria no tiene regiones configuradas.",
+	}},
+	VerificationCertificateInvalid: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The verification certificate is invalid.",
+		"es": "El certificado de verificación no es válido.",
+	}},
+	BadRequest: {http.StatusBadRequest, codes.InvalidArgument, map[string]string{
+		"en": "The request is malformed or contains invalid data.",
+		"es": "La solicitud tiene un formato incorrecto o contiene datos no válidos.",
+	}},
+	InternalError: {http.StatusInternalServerError, codes.Internal, map[string]string{
+		"en": "An internal error occurred.",
+		"es": "Se produjo un error interno.",
+	}},
+	MissingRevisionToken: {http.StatusBadRequest, codes.InvalidArgument, map[string]string{
+		"en": "A revision token is required to revise existing keys.",
+		"es": "Se requiere un token de revisión para revisar claves existentes.",
+	}},
+	InvalidRevisionToken: {http.StatusBadRequest, codes.InvalidArgument, map[string]string{
+		"en": "The revision token is invalid.",
+		"es": "El token de revisión no es válido.",
+	}},
+	KeyAlreadyRevised: {http.StatusBadRequest, codes.FailedPrecondition, map[string]string{
+		"en": "A key in the request has already been revised.",
+		"es": "Una clave de la solicitud ya ha sido revisada.",
+	}},
+	InvalidReportTypeTransition: {http.StatusBadRequest, codes.FailedPrecondition, map[string]string{
+		"en": "A key in the request cannot transition to the requested report type.",
+		"es": "Una clave de la solicitud no puede cambiar al tipo de informe solicitado.",
+	}},
+	InvalidChunk: {http.StatusBadRequest, codes.InvalidArgument, map[string]string{
+		"en": "The chunk does not continue the upload.",
+		"es": "El fragmento no continúa la carga.",
+	}},
+	PartialFailure: {http.StatusOK, codes.OK, map[string]string{
+		"en": "Some keys were invalid and were not saved.",
+		"es": "Algunas claves no eran válidas y no se guardaron.",
+	}},
+	InvalidRequestSignature: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The request signature is missing, invalid, or expired.",
+		"es": "La firma de la solicitud falta, no es válida o ha caducado.",
+	}},
+	AddressNotAllowed: {http.StatusForbidden, codes.PermissionDenied, map[string]string{
+		"en": "Requests are not accepted from this network.",
+		"es": "No se aceptan solicitudes desde esta red.",
+	}},
+	TemporarilyThrottled: {http.StatusTooManyRequests, codes.ResourceExhausted, map[string]string{
+		"en": "Uploads are temporarily paused for this app. Try again later.",
+		"es": "Las cargas están pausadas temporalmente para esta aplicación. Inténtelo de nuevo más tarde.",
+	}},
+	Unauthorized: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The request is not authorized.",
+		"es": "La solicitud no está autorizada.",
+	}},
+	PrivacyBudgetExhausted: {http.StatusTooManyRequests, codes.ResourceExhausted, map[string]string{
+		"en": "The privacy budget for statistics has been spent. Try again in the next period.",
+		"es": "Se ha agotado el presupuesto de privacidad para las estadísticas. Inténtelo de nuevo en el próximo período.",
+	}},
+
+	MissingAuthorization: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The request is missing authorization.",
+		"es": "Falta la autorización en la solicitud.",
+	}},
+	InvalidToken: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The authorization token is invalid.",
+		"es": "El token de autorización no es válido.",
+	}},
+	InvalidAudience: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The authorization token has an invalid audience.",
+		"es": "El token de autorización tiene una audiencia no válida.",
+	}},
+	UnknownFederationClient: {http.StatusUnauthorized, codes.Unauthenticated, map[string]string{
+		"en": "The federation client is not authorized.",
+		"es": "El cliente de federación no está autorizado.",
+	}},
+	UnsupportedProtocolVersion: {http.StatusBadRequest, codes.InvalidArgument, map[string]string{
+		"en": "The protocol version is not supported.",
+		"es": "La versión del protocolo no es compatible.",
+	}},
+	NoCommonProtocolVersion: {http.StatusBadRequest, codes.FailedPrecondition, map[string]string{
+		"en": "The client and server share no protocol version.",
+		"es": "El cliente y el servidor no comparten ninguna versión del protocolo.",
+	}},
+	UnsupportedCompression: {http.StatusBadRequest, codes.InvalidArgument, map[string]string{
+		"en": "The compression is not supported.",
+		"es": "La compresión no es compatible.",
+	}},
+	InvalidCursor: {http.StatusBadRequest, codes.InvalidArgument, map[string]string{
+		"en": "The cursor is invalid or does not match the request.",
+		"es": "El cursor no es válido o no coincide con la solicitud.",
+	}},
+
+	ImportFailed: {http.StatusInternalServerError, codes.Internal, map[string]string{
+		"en": "One or more imports failed.",
+		"es": "Una o más importaciones fallaron.",
+	}},
+	TooEarly: {http.StatusOK, codes.OK, map[string]string{
+		"en": "The operation ran too recently and was skipped.",
+		"es": "La operación se ejecutó recientemente y se omitió.",
+	}},
+}
+
+// Codes returns all registered codes, sorted.
+func Codes() []Code {
+	list := make([]Code, 0, len(registry))
+	for c := range registry {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// Known returns true if the code is registered.
+func (c Code) Known() bool {
+	_, ok := registry[c]
+	return ok
+}
+
+// HTTPStatus returns the HTTP status for the code. Unknown codes map to 500.
+func (c Code) HTTPStatus() int {
+	if def, ok := registry[c]; ok {
+		return def.httpStatus
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the gRPC status code for the code. Unknown codes map to
+// Internal.
+func (c Code) GRPCCode() codes.Code {
+	if def, ok := registry[c]; ok {
+		return def.grpcCode
+	}
+	return codes.Internal
+}
+
+// Message returns the message for the code in the given language, which is a
+// BCP 47 tag like "es" or "es-MX". If the exact tag is not available, the base
+// language is tried, then DefaultLanguage.
+func (c Code) Message(lang string) string {
+	def, ok := registry[c]
+	if !ok {
+		def = registry[InternalError]
+	}
+
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if msg, ok := def.messages[lang]; ok {
+		return msg
+	}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		if msg, ok := def.messages[lang[:i]]; ok {
+			return msg
+		}
+	}
+	return def.messages[DefaultLanguage]
+}
+
+// Error is an error with a stable code. Message is safe to return to clients;
+// the wrapped error is not.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+// New creates an error with the given code and a message built from format
+// and args. If format is empty, the code's default message is used.
+func New(code Code, format string, args ...interface{}) *Error {
+	msg := code.Message(DefaultLanguage)
+	if format != "" {
+		msg = fmt.Sprintf(format, args...)
+	}
+	return &Error{Code: code, Message: msg}
+}
+
+// Wrap creates an error with the given code and the code's default message
+// which wraps err.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: code.Message(DefaultLanguage), Err: err}
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap returns the wrapped error, if any.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code as a string. It allows packages that cannot
+// import errcode to discover the code.
+func (e *Error) ErrorCode() string {
+	return string(e.Code)
+}
+
+// GRPCStatus returns the gRPC status for the error. The status carries the
+// message (but not the wrapped error) and an ErrorInfo detail whose reason is
+// the code. The gRPC server uses this method when the error is returned from a
+// handler or interceptor.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Code.GRPCCode(), e.Message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: string(e.Code),
+		Domain: Domain,
+	})
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// Of returns the code of the first Error in err's chain. It returns the empty
+// code if err is nil and InternalError if err has no code.
+func Of(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return InternalError
+}
+
+// FromStatus returns the code carried in the ErrorInfo detail of a gRPC
+// status, or the empty code if there is none.
+func FromStatus(st *status.Status) Code {
+	if st == nil {
+		return ""
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+			return Code(info.Reason)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range Codes() {
+		def := registry[c]
+		if def.httpStatus == 0 {
+			t.Errorf("%s: missing http status", c)
+		}
+		if _, ok := def.messages[DefaultLanguage]; !ok {
+			t.Errorf("%s: missing %s message", c, DefaultLanguage)
+		}
+	}
+}
+
+func TestCode_Message(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		lang string
+		want string
+	}{
+		{"exact", "es", registry[BadRequest].messages["es"]},
+		{"region", "es-MX", registry[BadRequest].messages["es"]},
+		{"case", "ES_mx", registry[BadRequest].messages["es"]},
+		{"fallback", "fr", registry[BadRequest].messages["en"]},
+		{"empty", "", registry[BadRequest].messages["en"]},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := BadRequest.Message(tc.lang); got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+
+	if got, want := Code("nope").Message("en"), InternalError.Message("en"); got != want {
+		t.Errorf("expected unknown code message %q to be %q", got, want)
+	}
+}
+
+func TestCode_Statuses(t *testing.T) {
+	t.Parallel()
+
+	if got, want := InvalidRevisionToken.HTTPStatus(), http.StatusBadRequest; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := InvalidToken.GRPCCode(), codes.Unauthenticated; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+	if got, want := Code("nope").HTTPStatus(), http.StatusInternalServerError; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := Code("nope").GRPCCode(), codes.Internal; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+}
+
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	cause := fmt.Errorf("connection refused")
+	err := fmt.Errorf("outer: %w", Wrap(UnableToLoadHealthAuthority, cause))
+
+	if !errors.Is(err, cause) {
+		t.Errorf("expected %v to wrap %v", err, cause)
+	}
+	if got, want := Of(err), UnableToLoadHealthAuthority; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := Of(cause), InternalError; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got := Of(nil); got != "" {
+		t.Errorf("expected empty code, got %q", got)
+	}
+
+	if got, want := New(InvalidCursor, "").Error(), InvalidCursor.Message(DefaultLanguage); got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := New(InvalidCursor, "bad %s", "mac").Error(), "bad mac"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func TestError_GRPCStatus(t *testing.T) {
+	t.Parallel()
+
+	err := Wrap(InvalidToken, fmt.Errorf("signature mismatch"))
+
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("expected %v to be a status", err)
+	}
+	if got, want := st.Code(), codes.Unauthenticated; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+	if got, want := st.Message(), InvalidToken.Message(DefaultLanguage); got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := FromStatus(st), InvalidToken; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got := FromStatus(status.New(codes.Internal, "x")); got != "" {
+		t.Errorf("expected empty code, got %q", got)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
 	"github.com/google/exposure-notifications-server/internal/export/model"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/hashicorp/go-multierror"
 	"go.opencensus.io/stats"
@@ -41,12 +43,24 @@ func (s *Server) handleCreateBatches() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		budget := s.config.Scheduler.NewBudget(r, s.config.CreateTimeout)
+		ctx, cancel := scheduler.WithBudget(ctx, budget)
+		defer cancel()
+
 		logger := logging.FromContext(ctx).Named("handleCreateBatches")
 		logger.Debugw("starting")
 		defer logger.Debugw("finishing")
 
-		ctx, cancel := context.WithTimeout(ctx, s.config.CreateTimeout)
-		defer cancel()
+		// In an active/active deployment, only the region that holds the lease
+		// runs the job.
+		if ok, err := s.config.Leader.Lead(ctx, db, "export-create-batches"); err != nil {
+			logger.Errorw("failed to acquire lease", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		} else if !ok {
+			s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("not leader"))
+			return
+		}
 
 		// Obtain lock to make sure there are no other processes working to create batches.
 		unlockFn, err := db.Lock(ctx, createBatchesLock, s.config.CreateTimeout)
@@ -76,8 +90,21 @@ func (s *Server) handleCreateBatches() http.Handler {
 
 		var merr *multierror.Error
 
+		// stopped is true if configs were left for the next run to stay within
+		// the budget.
+		stopped := false
+
 		effectiveTime := time.Now().Add(-1 * s.config.MinWindowAge)
 		if err := exportdatabase.New(db).IterateExportConfigs(ctx, effectiveTime, func(ec *model.ExportConfig) error {
+			// Derived configs are batched along with their parent config.
+			if ec.IsDerived() || stopped {
+				return nil
+			}
+			if budget.Spent() {
+				logger.Warnw("budget spent, but there are still configs to batch", "retry", budget.Retry())
+				stopped = true
+				return nil
+			}
 			totalConfigs++
 			batchesCreated, err := s.maybeCreateBatches(ctx, ec, effectiveTime)
 			if err != nil {
@@ -105,6 +132,11 @@ func (s *Server) handleCreateBatches() http.Handler {
 			return
 		}
 
+		if stopped {
+			s.h.RenderJSON(w, http.StatusAccepted, budget.Progress(totalConfigs))
+			return
+		}
+
 		stats.Record(ctx, mBatcherSuccess.M(1))
 		s.h.RenderJSON(w, http.StatusOK, nil)
 	})
@@ -127,25 +159,19 @@ func (s *Server) maybeCreateBatches(ctx context.Context, ec *model.ExportConfig,
 		return 0, nil
 	}
 
+	// Keys are matched by exact region, so an unknown region, e.g. "USA"
+	// instead of "US", results in empty exports instead of an error.
+	regions := append([]string{ec.OutputRegion}, ec.InputRegions...)
+	regions = append(append(regions, ec.ExcludeRegions...), ec.FilterRegions...)
+	if unknown := region.New(&s.config.Regions).Unknown(regions...); len(unknown) > 0 {
+		logger.Warnw("export config has unknown regions", "regions", unknown)
+	}
+
 	batches := make([]*model.ExportBatch, 0, len(ranges))
 	for _, br := range ranges {
-		infoIds := make([]int64, len(ec.SignatureInfoIDs))
-		copy(infoIds, ec.SignatureInfoIDs)
-		batches = append(batches, &model.ExportBatch{
-			ConfigID:           ec.ConfigID,
-			BucketName:         ec.BucketName,
-			FilenameRoot:       ec.FilenameRoot,
-			StartTimestamp:     br.start,
-			EndTimestamp:       br.end,
-			OutputRegion:       ec.OutputRegion,
-			InputRegions:       ec.InputRegions,
-			IncludeTravelers:   ec.IncludeTravelers,
-			OnlyNonTravelers:   ec.OnlyNonTravelers,
-			ExcludeRegions:     ec.ExcludeRegions,
-			Status:             model.ExportBatchOpen,
-			SignatureInfoIDs:   infoIds,
-			MaxRecordsOverride: ec.MaxRecordsOverride,
-		})
+		eb := newExportBatch(ec, br)
+		eb.RunID = scheduler.RunID(ctx)
+		batches = append(batches, eb)
 	}
 
 	if err := exportDB.AddExportBatches(ctx, batches); err != nil {
@@ -161,6 +187,35 @@ type batchRange struct {
 	start, end time.Time
 }
 
+// newExportBatch returns an open batch of the export config for the range.
+func newExportBatch(ec *model.ExportConfig, br batchRange) *model.ExportBatch {
+	infoIds := make([]int64, len(ec.SignatureInfoIDs))
+	copy(infoIds, ec.SignatureInfoIDs)
+	return &model.ExportBatch{
+		ConfigID:           ec.ConfigID,
+		BucketName:         ec.BucketName,
+		FilenameRoot:       ec.FilenameRoot,
+		StartTimestamp:     br.start,
+		EndTimestamp:       br.end,
+		OutputRegion:       ec.OutputRegion,
+		InputRegions:       ec.InputRegions,
+		IncludeTravelers:   ec.IncludeTravelers,
+		OnlyNonTravelers:   ec.OnlyNonTravelers,
+		ExcludeRegions:     ec.ExcludeRegions,
+		Status:             model.ExportBatchOpen,
+		SignatureInfoIDs:   infoIds,
+		MaxRecordsOverride: ec.MaxRecordsOverride,
+		MaxBatchKeys:       ec.MaxBatchKeys,
+		MinRecordsOverride: ec.MinRecordsOverride,
+		SmallBatchPolicy:   ec.SmallBatchPolicy,
+		EncryptionKeyID:    ec.EncryptionKeyID,
+
+		IncludeJurisdictions: ec.IncludeJurisdictions,
+		ExcludeJurisdictions: ec.ExcludeJurisdictions,
+		TestData:             ec.TestData,
+	}
+}
+
 var sanityDate = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
 
 func makeBatchRanges(period time.Duration, latestEnd, now time.Time, truncateWindow time.Duration) []batchRange {
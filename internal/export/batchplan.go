@@ -0,0 +1,387 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/featureflag"
+	publishdatabase "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"go.opencensus.io/stats"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+)
+
+// errStopIterating stops an iteration early once it has read what it needs.
+var errStopIterating = errors.New("stop iterating")
+
+// exposureKeyID is an exposure key. Batches are planned from the keys alone,
+// so that the exposures of a batch are never all held in memory at once.
+type exposureKeyID [verifyapi.KeyLength]byte
+
+// keyIDOf returns the key of an exposure. The key must be
+// verifyapi.KeyLength bytes long.
+func keyIDOf(key []byte) exposureKeyID {
+	var id exposureKeyID
+	copy(id[:], key)
+	return id
+}
+
+// sortKeyIDs sorts the keys in byte order, the order of sortExposures.
+func sortKeyIDs(ids []exposureKeyID) {
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+}
+
+// keyRange is the exposure keys from from, inclusive, until until, exclusive,
+// in byte order. A nil bound is open.
+type keyRange struct {
+	from, until []byte
+}
+
+func (r *keyRange) contains(key []byte) bool {
+	return (r.from == nil || bytes.Compare(key, r.from) >= 0) &&
+		(r.until == nil || bytes.Compare(key, r.until) < 0)
+}
+
+// filePlan is one export file of a batch. It holds the primary keys in
+// primary and the revised keys in revised, either of which may be nil.
+type filePlan struct {
+	primary *keyRange
+	revised *keyRange
+	length  int
+}
+
+// planFiles splits the sorted primary and revised keys into files of at most
+// maxRecords keys, in order, like makeGroups. The ranges of the files cover
+// all keys, so a key that is revised between planning a file and reading it
+// is still exported once.
+func planFiles(primaryKeys, revisedKeys []exposureKeyID, maxRecords int) []*filePlan {
+	var files []*filePlan
+	cur := &filePlan{}
+
+	var primaryRanges, revisedRanges []*keyRange
+	var primaryStarts, revisedStarts []int
+	for i := range primaryKeys {
+		if cur.primary == nil {
+			cur.primary = &keyRange{}
+			primaryRanges = append(primaryRanges, cur.primary)
+			primaryStarts = append(primaryStarts, i)
+		}
+		cur.length++
+		if cur.length >= maxRecords {
+			files = append(files, cur)
+			cur = &filePlan{}
+		}
+	}
+	for i := range revisedKeys {
+		if cur.revised == nil {
+			cur.revised = &keyRange{}
+			revisedRanges = append(revisedRanges, cur.revised)
+			revisedStarts = append(revisedStarts, i)
+		}
+		cur.length++
+		if cur.length >= maxRecords {
+			files = append(files, cur)
+			cur = &filePlan{}
+		}
+	}
+	if cur.length > 0 {
+		files = append(files, cur)
+	}
+
+	setKeyRanges(primaryRanges, primaryStarts, primaryKeys)
+	setKeyRanges(revisedRanges, revisedStarts, revisedKeys)
+	return files
+}
+
+// setKeyRanges bounds each range by the first key of the next one. The first
+// range is open below and the last is open above.
+func setKeyRanges(ranges []*keyRange, starts []int, keys []exposureKeyID) {
+	for i, r := range ranges {
+		if i > 0 {
+			r.from = keys[starts[i]][:]
+		}
+		if i < len(ranges)-1 {
+			r.until = keys[starts[i+1]][:]
+		}
+	}
+}
+
+// filesLength returns the total number of keys in the files.
+func filesLength(files []*filePlan) int {
+	n := 0
+	for _, f := range files {
+		n += f.length
+	}
+	return n
+}
+
+// batchPlan is the files of an export batch.
+type batchPlan struct {
+	criteria publishdatabase.IterateExposuresCriteria
+	files    []*filePlan
+
+	// generated are the noise and padding keys created for this run of the
+	// batch. They are exported from memory, since they do not necessarily
+	// match the criteria.
+	generated []*publishmodel.Exposure
+
+	maxRecords   int
+	primaryKeys  int
+	padTo        int
+	outputRegion string
+	maxCreatedAt time.Time
+}
+
+// exposureFilter returns true if the exposure belongs in a file. revised is
+// true for revised keys.
+type exposureFilter func(exp *publishmodel.Exposure, revised bool) bool
+
+// planBatch reads the keys of the exposures that match criteria and splits
+// them into files of at most maxRecords. If there are fewer than padTo primary
+// keys, the last file is padded with generated keys when it is read; a padTo
+// of 0 disables padding.
+func (s *Server) planBatch(ctx context.Context, iterate iterateExposuresFunc, criteria publishdatabase.IterateExposuresCriteria, maxRecords, padTo int, outputRegion string) (*batchPlan, error) {
+	logger := logging.FromContext(ctx)
+
+	criteria.OnlyRevisedKeys = false
+	plan := &batchPlan{
+		criteria:     criteria,
+		maxRecords:   maxRecords,
+		padTo:        padTo,
+		outputRegion: outputRegion,
+	}
+
+	primaryKeys := make([]exposureKeyID, 0, padTo)
+	revisedKeys := make([]exposureKeyID, 0, padTo)
+	generatedKeys := 0
+	droppedKeys := 0
+
+	if _, err := iterate(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength {
+			droppedKeys++
+			return nil
+		}
+		// see if assigned time for generated data should be moved up.
+		if exp.CreatedAt.After(plan.maxCreatedAt) {
+			plan.maxCreatedAt = exp.CreatedAt
+		}
+		if exp.AppPackageName == exportAppPackageName {
+			generatedKeys++
+		}
+		primaryKeys = append(primaryKeys, keyIDOf(exp.ExposureKey))
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating exposures: %w", err)
+	}
+
+	// go get the revised keys.
+	revisedCriteria := criteria
+	revisedCriteria.OnlyRevisedKeys = true
+	if _, err := iterate(ctx, revisedCriteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength {
+			droppedKeys++
+			return nil
+		}
+		revisedKeys = append(revisedKeys, keyIDOf(exp.ExposureKey))
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating revised exposures: %w", err)
+	}
+
+	if droppedKeys > 0 {
+		logger.Errorw("export found keys of invalid length", "dropped_keys", droppedKeys)
+		stats.Record(ctx, mWorkerBadKeyLength.M(int64(droppedKeys)))
+	}
+
+	// Mix noise into small exports so that the number of keys doesn't reveal
+	// the number of cases. Noise is added before sorting so that it can't be
+	// told apart by its position in the file.
+	if s.config.NoisePercent > 0 && s.env.FeatureFlags().Enabled(ctx, featureflag.ExportNoiseKeys, outputRegion, true) {
+		noise, err := s.planNoise(ctx, iterate, plan, len(primaryKeys)-generatedKeys, generatedKeys)
+		if err != nil {
+			return nil, fmt.Errorf("generating noise: %w", err)
+		}
+		for _, exp := range noise {
+			primaryKeys = append(primaryKeys, keyIDOf(exp.ExposureKey))
+		}
+	}
+
+	// Sort all the keys that we got so that if the batch is re-run, the contents are stable IFF
+	// the sizing information is the same.
+	sortKeyIDs(primaryKeys)
+	sortKeyIDs(revisedKeys)
+
+	plan.primaryKeys = len(primaryKeys)
+	plan.files = planFiles(primaryKeys, revisedKeys, maxRecords)
+	if len(plan.files) == 0 {
+		logger.Infof("No records for export batch")
+	}
+	return plan, nil
+}
+
+// planNoise generates and persists the noise keys for a batch with the given
+// number of real and generated primary keys, and adds them to the plan. The
+// noise keys look like the first real keys of the batch.
+func (s *Server) planNoise(ctx context.Context, iterate iterateExposuresFunc, plan *batchPlan, realKeys, generatedKeys int) ([]*publishmodel.Exposure, error) {
+	n, err := s.noiseKeys(realKeys, generatedKeys)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+
+	// Only the keys that the noise is modeled on are read.
+	templates := make([]*publishmodel.Exposure, 0, n)
+	if _, err := iterate(ctx, plan.criteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength || exp.AppPackageName == exportAppPackageName {
+			return nil
+		}
+		templates = append(templates, exp)
+		if len(templates) >= n {
+			return errStopIterating
+		}
+		return nil
+	}); err != nil && !errors.Is(err, errStopIterating) {
+		return nil, fmt.Errorf("iterating exposures: %w", err)
+	}
+
+	noise, err := generateNoise(templates, n, plan.maxCreatedAt)
+	if err != nil || len(noise) == 0 {
+		return nil, err
+	}
+	if err := s.insertGenerated(ctx, publishdatabase.New(s.env.Database()), noise); err != nil {
+		return nil, err
+	}
+	plan.generated = append(plan.generated, noise...)
+	stats.Record(ctx, mWorkerNoiseKeys.M(int64(len(noise))))
+	return noise, nil
+}
+
+// readFile reads the exposures of one file of the plan that pass include. A
+// nil include passes all exposures.
+func (s *Server) readFile(ctx context.Context, iterate iterateExposuresFunc, plan *batchPlan, f *filePlan, include exposureFilter) (*group, error) {
+	g := &group{}
+
+	read := func(r *keyRange, revised bool) ([]*publishmodel.Exposure, error) {
+		criteria := plan.criteria
+		criteria.OnlyRevisedKeys = revised
+		criteria.KeysFrom = r.from
+		criteria.KeysUntil = r.until
+
+		seen := make(map[exposureKeyID]struct{})
+		var exposures []*publishmodel.Exposure
+		if _, err := iterate(ctx, criteria, func(exp *publishmodel.Exposure) error {
+			if len(exp.ExposureKey) != verifyapi.KeyLength {
+				return nil
+			}
+			seen[keyIDOf(exp.ExposureKey)] = struct{}{}
+			if include == nil || include(exp, revised) {
+				exposures = append(exposures, exp)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		// Generated keys are only ever primary keys.
+		if revised {
+			return exposures, nil
+		}
+		for _, exp := range plan.generated {
+			if _, ok := seen[keyIDOf(exp.ExposureKey)]; ok || !r.contains(exp.ExposureKey) {
+				continue
+			}
+			if include == nil || include(exp, false) {
+				exposures = append(exposures, exp)
+			}
+		}
+		return exposures, nil
+	}
+
+	if f.primary != nil {
+		exposures, err := read(f.primary, false)
+		if err != nil {
+			return nil, fmt.Errorf("iterating exposures: %w", err)
+		}
+		sortExposures(exposures)
+		g.exposures = exposures
+	}
+	if f.revised != nil {
+		revised, err := read(f.revised, true)
+		if err != nil {
+			return nil, fmt.Errorf("iterating revised exposures: %w", err)
+		}
+		sortExposures(revised)
+		g.revised = revised
+	}
+	return g, nil
+}
+
+// streamFiles reads the files one at a time and calls write with each, so
+// that only one file's exposures are held in memory.
+func (s *Server) streamFiles(ctx context.Context, iterate iterateExposuresFunc, plan *batchPlan, files []*filePlan, include exposureFilter, write func(i int, g *group) error) error {
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		g, err := s.readFile(ctx, iterate, plan, f, include)
+		if err != nil {
+			return fmt.Errorf("reading export file %d: %w", i+1, err)
+		}
+		if err := write(i, g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// padFile pads the primary keys of the last file of the batch with generated
+// keys if the batch has fewer than padTo primary keys. The generated keys are
+// persisted and added to the plan.
+func (s *Server) padFile(ctx context.Context, plan *batchPlan, g *group) error {
+	// only drop into the padding code if the overall sum of keys is less than requested. Otherwise the pre-sorting
+	// will give away the generated data.
+	if plan.primaryKeys >= plan.padTo {
+		return nil
+	}
+
+	var generated []*publishmodel.Exposure
+	var err error
+	g.exposures, generated, err = ensureMinNumExposures(g.exposures, plan.outputRegion, plan.padTo, s.config.PaddingRange, plan.maxRecords, plan.maxCreatedAt)
+	if err != nil {
+		return fmt.Errorf("ensureMinNumExposures: %w", err)
+	}
+	// padding revised keys doesn't provide any useful protection as one can work backwords and figure out which
+	// keys appeared as primary keys in a previous export.
+	if len(generated) == 0 {
+		return nil
+	}
+
+	// we generated some data in order to pad out this export. This data needs to be persisted.
+	if err := s.insertGenerated(ctx, publishdatabase.New(s.env.Database()), generated); err != nil {
+		return err
+	}
+	plan.generated = append(plan.generated, generated...)
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+// testKeyID returns a key that sorts in the order of i.
+func testKeyID(i int) exposureKeyID {
+	var id exposureKeyID
+	id[0], id[1] = byte(i>>8), byte(i)
+	return id
+}
+
+func testKeyIDs(from, to int) []exposureKeyID {
+	ids := make([]exposureKeyID, 0, to-from)
+	for i := from; i < to; i++ {
+		ids = append(ids, testKeyID(i))
+	}
+	return ids
+}
+
+func TestPlanFiles(t *testing.T) {
+	t.Parallel()
+
+	// fileKeys returns the keys of each file, with revised keys prefixed by r.
+	fileKeys := func(files []*filePlan, primaryKeys, revisedKeys []exposureKeyID) [][]int {
+		var out [][]int
+		for _, f := range files {
+			var keys []int
+			if f.primary != nil {
+				for i, id := range primaryKeys {
+					if f.primary.contains(id[:]) {
+						keys = append(keys, i)
+					}
+				}
+			}
+			if f.revised != nil {
+				for i, id := range revisedKeys {
+					if f.revised.contains(id[:]) {
+						keys = append(keys, -(i + 1))
+					}
+				}
+			}
+			if got, want := len(keys), f.length; got != want {
+				t.Errorf("expected file length %d to be %d", want, got)
+			}
+			out = append(out, keys)
+		}
+		return out
+	}
+
+	cases := []struct {
+		name       string
+		primary    int
+		revised    int
+		maxRecords int
+		want       [][]int
+	}{
+		{
+			name:       "empty",
+			maxRecords: 2,
+		},
+		{
+			name:       "one_file",
+			primary:    2,
+			revised:    1,
+			maxRecords: 10,
+			want:       [][]int{{0, 1, -1}},
+		},
+		{
+			name:       "split",
+			primary:    3,
+			revised:    2,
+			maxRecords: 2,
+			want:       [][]int{{0, 1}, {2, -1}, {-2}},
+		},
+		{
+			name:       "only_revised",
+			revised:    3,
+			maxRecords: 2,
+			want:       [][]int{{-1, -2}, {-3}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			primaryKeys := testKeyIDs(0, tc.primary)
+			revisedKeys := testKeyIDs(0, tc.revised)
+			files := planFiles(primaryKeys, revisedKeys, tc.maxRecords)
+			if diff := cmp.Diff(tc.want, fileKeys(files, primaryKeys, revisedKeys)); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStreamFiles_PeakResidency(t *testing.T) {
+	// Not parallel, since it measures the heap.
+
+	ctx := project.TestContext(t)
+
+	const (
+		numKeys    = 256
+		numRevised = 32
+		maxRecords = 16
+		ballast    = 64 << 10
+	)
+
+	// Every exposure read carries a large ballast, so the heap is dominated by
+	// the exposures that are held.
+	iterate := func(ctx context.Context, criteria publishdb.IterateExposuresCriteria, f publishdb.IteratorFunction) (string, error) {
+		n := numKeys
+		if criteria.OnlyRevisedKeys {
+			n = numRevised
+		}
+		for i := 0; i < n; i++ {
+			id := testKeyID(i)
+			key := id[:]
+			if criteria.KeysFrom != nil && bytes.Compare(key, criteria.KeysFrom) < 0 {
+				continue
+			}
+			if criteria.KeysUntil != nil && bytes.Compare(key, criteria.KeysUntil) >= 0 {
+				continue
+			}
+			if err := f(&publishmodel.Exposure{
+				ExposureKey: key,
+				KeyMetadata: make([]byte, ballast),
+			}); err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+	}
+
+	heapAlloc := func() uint64 {
+		runtime.GC()
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc
+	}
+
+	server := &Server{config: &Config{MaxRecords: maxRecords}}
+	baseline := heapAlloc()
+
+	plan, err := server.planBatch(ctx, iterate, publishdb.IterateExposuresCriteria{}, maxRecords, 0, "US")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var peak uint64
+	seen := make(map[exposureKeyID]int)
+	if err := server.streamFiles(ctx, iterate, plan, plan.files, nil, func(i int, g *group) error {
+		if got := g.Length(); got > maxRecords {
+			t.Errorf("file %d: expected at most %d keys, got %d", i+1, maxRecords, got)
+		}
+		for _, exp := range g.exposures {
+			seen[keyIDOf(exp.ExposureKey)]++
+		}
+		for _, exp := range g.revised {
+			seen[keyIDOf(exp.ExposureKey)] += 100
+		}
+
+		if h := heapAlloc(); h > peak {
+			peak = h
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(plan.files), (numKeys+numRevised+maxRecords-1)/maxRecords; got != want {
+		t.Errorf("expected %d files, got %d", want, got)
+	}
+	for i := 0; i < numKeys; i++ {
+		want := 1
+		if i < numRevised {
+			want = 101
+		}
+		if got := seen[testKeyID(i)]; got != want {
+			t.Errorf("key %d: expected to be seen %d, got %d", i, want, got)
+		}
+	}
+
+	// Reading the whole batch would hold numKeys+numRevised ballasts. Reading
+	// a file at a time holds maxRecords of them, plus the keys of the plan.
+	var resident uint64
+	if peak > baseline {
+		resident = peak - baseline
+	}
+	if limit := uint64(4 * maxRecords * ballast); resident > limit {
+		t.Errorf("expected peak residency %d to be at most %d (whole batch is %d)", resident, limit, (numKeys+numRevised)*ballast)
+	}
+}
@@ -18,6 +18,13 @@ package export
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/cdn"
+	"github.com/google/exposure-notifications-server/internal/featureflag"
+	"github.com/google/exposure-notifications-server/internal/leader"
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/outbox"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -29,10 +36,12 @@ import (
 // Compile-time check to assert this config matches requirements.
 var (
 	_ setup.BlobstoreConfigProvider             = (*Config)(nil)
+	_ setup.CDNPurgerConfigProvider             = (*Config)(nil)
 	_ setup.DatabaseConfigProvider              = (*Config)(nil)
 	_ setup.KeyManagerConfigProvider            = (*Config)(nil)
 	_ setup.SecretManagerConfigProvider         = (*Config)(nil)
 	_ setup.ObservabilityExporterConfigProvider = (*Config)(nil)
+	_ setup.FeatureFlagConfigProvider           = (*Config)(nil)
 )
 
 // Config represents the configuration and associated environment variables for
@@ -42,7 +51,14 @@ type Config struct {
 	KeyManager            keys.Config
 	SecretManager         secrets.Config
 	Storage               storage.Config
+	CDN                   cdn.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
+	Leader                leader.Config
+	FeatureFlag           featureflag.Config
+	Outbox                outbox.Config
+	Regions               region.Config
+	Scheduler             scheduler.Config
 
 	Port               string        `env:"PORT, default=8080"`
 	CreateTimeout      time.Duration `env:"CREATE_BATCHES_TIMEOUT, default=5m"`
@@ -54,6 +70,52 @@ type Config struct {
 	TruncateWindow     time.Duration `env:"TRUNCATE_WINDOW, default=1h"`
 	MinWindowAge       time.Duration `env:"MIN_WINDOW_AGE, default=2h"`
 	TTL                time.Duration `env:"CLEANUP_TTL, default=336h"`
+
+	// FileMemoryBudget is the memory, in bytes, that writing one export file
+	// may use. Batches whose files would be estimated to exceed it are split
+	// into more, smaller files. 0 disables the budget.
+	FileMemoryBudget int64 `env:"EXPORT_FILE_MEMORY_BUDGET, default=0"`
+
+	// FileConcurrency is the number of export files of a batch that are signed
+	// and uploaded at once. Each file being written holds its exposures in
+	// memory, so raising it also raises the memory FileMemoryBudget plans for.
+	FileConcurrency int `env:"EXPORT_FILE_CONCURRENCY, default=1"`
+
+	// NoisePercent is the number of generated keys to mix into an export, as
+	// a percentage of its real keys. Noise is only added to exports with fewer
+	// than NoiseMaxKeys real keys, or to every export if NoiseMaxKeys is 0.
+	// Noise keys are well-formed but random, and are flagged internally so they
+	// are not counted as real keys when a batch is re-run.
+	NoisePercent int `env:"EXPORT_NOISE_PERCENT, default=0"`
+	NoiseMaxKeys int `env:"EXPORT_NOISE_MAX_KEYS, default=0"`
+
+	// RequireTravelerConsent, if true, only includes traveler keys from other
+	// regions if the user explicitly consented to sharing them. Traveler keys
+	// where the user declined are never included.
+	RequireTravelerConsent bool `env:"REQUIRE_TRAVELER_CONSENT"`
+
+	// SignIndex, if true, writes a detached JWS of each index file next to it,
+	// signed with the batch's export signing keys.
+	SignIndex bool `env:"EXPORT_INDEX_SIGNATURE"`
+
+	// KeyMetadata, if true, writes the per-key fields of later export format
+	// revisions, such as the variant of concern, that were captured from
+	// imported files. Otherwise they are left out of the export files.
+	KeyMetadata bool `env:"EXPORT_KEY_METADATA, default=false"`
+
+	// Provenance, if true, writes a record of the export importers that each
+	// export file's keys were imported by next to the file. Mirrors, which
+	// re-sign keys imported from their neighbors, enable it.
+	Provenance bool `env:"EXPORT_PROVENANCE, default=false"`
+
+	// SigningMaxRetries is the number of times a failed signing request to the
+	// key manager is retried. SigningRetryBackoff is the delay before the first
+	// retry, which doubles with each retry, and SigningRetryBudget bounds the
+	// time spent retrying one signature.
+	SigningMaxRetries   uint64        `env:"SIGNING_MAX_RETRIES, default=3"`
+	SigningRetryBackoff time.Duration `env:"SIGNING_RETRY_BACKOFF, default=250ms"`
+	SigningRetryBudget  time.Duration `env:"SIGNING_RETRY_BUDGET, default=10s"`
+
 	// ReprocessCount needs to be incremented by one every time you go back and
 	// regenerate previously exported files.
 	ReprocessCount uint `env:"REPROCESS_COUNT, default=0"`
@@ -67,6 +129,10 @@ func (c *Config) BlobstoreConfig() *storage.Config {
 	return &c.Storage
 }
 
+func (c *Config) CDNPurgerConfig() *cdn.Config {
+	return &c.CDN
+}
+
 func (c *Config) DatabaseConfig() *database.Config {
 	return &c.Database
 }
@@ -82,3 +148,7 @@ func (c *Config) SecretManagerConfig() *secrets.Config {
 func (c *Config) ObservabilityExporterConfig() *observability.Config {
 	return &c.ObservabilityExporter
 }
+
+func (c *Config) FeatureFlagConfig() *featureflag.Config {
+	return &c.FeatureFlag
+}
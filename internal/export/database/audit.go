@@ -0,0 +1,300 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// AuditFile is an export file as seen by the export audit.
+type AuditFile struct {
+	Filename     string
+	BatchID      int64
+	ConfigID     int64
+	EndTimestamp time.Time
+	InputRegions []string
+
+	// Status is the status of the file.
+	Status string
+
+	// Size is the recorded size of the file, or 0 if it is unknown.
+	Size int64
+}
+
+// ListAuditFiles returns the export files of the configs that were written
+// to the bucket under the filename root.
+func (db *ExportDB) ListAuditFiles(ctx context.Context, configIDs []int64, bucketName, filenameRoot string) ([]*AuditFile, error) {
+	var files []*AuditFile
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				ef.filename, ef.batch_id, eb.config_id, eb.end_timestamp, ef.input_regions, ef.status, ef.size_bytes
+			FROM
+				ExportFile ef
+			INNER JOIN
+				ExportBatch eb ON (eb.batch_id = ef.batch_id)
+			WHERE
+				eb.config_id = ANY($1)
+				AND ef.bucket_name = $2
+				AND starts_with(ef.filename, $3)
+			ORDER BY
+				ef.filename
+		`, configIDs, bucketName, filenameRoot+"/")
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				f    AuditFile
+				size *int64
+			)
+			if err := rows.Scan(&f.Filename, &f.BatchID, &f.ConfigID, &f.EndTimestamp, &f.InputRegions, &f.Status, &size); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			if size != nil {
+				f.Size = *size
+			}
+			files = append(files, &f)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("list audit files: %w", err)
+	}
+
+	return files, nil
+}
+
+// ErrBatchNotComplete is returned when reopening a batch that is not
+// complete.
+var ErrBatchNotComplete = errors.New("batch is not complete")
+
+// ReopenBatch reopens a complete batch so that a worker exports it again,
+// and deletes its export file records, which are written again when the
+// batch is finalized. Derived batches are exported with their parent, so
+// they must not be reopened.
+func (db *ExportDB) ReopenBatch(ctx context.Context, batchID int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE
+				ExportBatch
+			SET
+				status = $1, lease_expires = NULL
+			WHERE
+				batch_id = $2 AND status = $3
+		`, model.ExportBatchOpen, batchID, model.ExportBatchComplete)
+		if err != nil {
+			return fmt.Errorf("reopening batch %d: %w", batchID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("reopening batch %d: %w", batchID, ErrBatchNotComplete)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				ExportFile
+			WHERE
+				batch_id = $1
+		`, batchID); err != nil {
+			return fmt.Errorf("deleting files of batch %d: %w", batchID, err)
+		}
+		return nil
+	})
+}
+
+// SaveAudit replaces the audit of a location and its findings. At most
+// maxFindings findings are saved, 0 saves them all.
+func (db *ExportDB) SaveAudit(ctx context.Context, a *model.ExportAudit, maxFindings int) error {
+	findings := a.Findings
+	if maxFindings > 0 && len(findings) > maxFindings {
+		findings = findings[:maxFindings]
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO
+				ExportAudit
+				(bucket_name, filename_root, config_ids, run_id, audited_at, files, objects, missing, orphans, size_mismatches)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (tenant, bucket_name, filename_root) DO UPDATE
+			SET
+				config_ids = EXCLUDED.config_ids, run_id = EXCLUDED.run_id, audited_at = EXCLUDED.audited_at,
+				files = EXCLUDED.files, objects = EXCLUDED.objects, missing = EXCLUDED.missing,
+				orphans = EXCLUDED.orphans, size_mismatches = EXCLUDED.size_mismatches
+		`, a.BucketName, a.FilenameRoot, a.ConfigIDs, a.RunID, a.AuditedAt, a.Files, a.Objects,
+			a.Counts[model.AuditMissing], a.Counts[model.AuditOrphan], a.Counts[model.AuditSizeMismatch]); err != nil {
+			return fmt.Errorf("saving audit: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				ExportAuditFinding
+			WHERE
+				bucket_name = $1 AND filename_root = $2
+		`, a.BucketName, a.FilenameRoot); err != nil {
+			return fmt.Errorf("deleting findings: %w", err)
+		}
+
+		for _, f := range findings {
+			var batchID *int64
+			if f.BatchID != 0 {
+				batchID = &f.BatchID
+			}
+			if err := tx.QueryRow(ctx, `
+				INSERT INTO
+					ExportAuditFinding
+					(bucket_name, filename_root, filename, kind, batch_id, expected_size, actual_size, repair)
+				VALUES
+					($1, $2, $3, $4, $5, $6, $7, $8)
+				RETURNING id
+			`, a.BucketName, a.FilenameRoot, f.Filename, f.Kind, batchID,
+				nullableSize(f.ExpectedSize), nullableSize(f.ActualSize), f.Repair).Scan(&f.ID); err != nil {
+				return fmt.Errorf("saving finding: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ListAudits returns the latest audit of each location, without findings,
+// ordered by bucket and filename root.
+func (db *ExportDB) ListAudits(ctx context.Context) ([]*model.ExportAudit, error) {
+	var audits []*model.ExportAudit
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				bucket_name, filename_root, config_ids, run_id, audited_at, files, objects, missing, orphans, size_mismatches
+			FROM
+				ExportAudit
+			ORDER BY
+				bucket_name, filename_root
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				a                        model.ExportAudit
+				missing, orphans, sizeMM int
+			)
+			if err := rows.Scan(&a.BucketName, &a.FilenameRoot, &a.ConfigIDs, &a.RunID, &a.AuditedAt,
+				&a.Files, &a.Objects, &missing, &orphans, &sizeMM); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			a.Counts = map[string]int{
+				model.AuditMissing:      missing,
+				model.AuditOrphan:       orphans,
+				model.AuditSizeMismatch: sizeMM,
+			}
+			audits = append(audits, &a)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("list audits: %w", err)
+	}
+
+	return audits, nil
+}
+
+// ListAuditFindings returns the saved findings of the latest audit of a
+// location, ordered by filename.
+func (db *ExportDB) ListAuditFindings(ctx context.Context, bucketName, filenameRoot string) ([]*model.ExportAuditFinding, error) {
+	var findings []*model.ExportAuditFinding
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				id, filename, kind, batch_id, expected_size, actual_size, repair
+			FROM
+				ExportAuditFinding
+			WHERE
+				bucket_name = $1 AND filename_root = $2
+			ORDER BY
+				filename, id
+		`, bucketName, filenameRoot)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				f                         model.ExportAuditFinding
+				batchID, expected, actual *int64
+			)
+			if err := rows.Scan(&f.ID, &f.Filename, &f.Kind, &batchID, &expected, &actual, &f.Repair); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			if batchID != nil {
+				f.BatchID = *batchID
+			}
+			if expected != nil {
+				f.ExpectedSize = *expected
+			}
+			if actual != nil {
+				f.ActualSize = *actual
+			}
+			findings = append(findings, &f)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("list audit findings: %w", err)
+	}
+
+	return findings, nil
+}
+
+// DeleteAuditsBefore deletes the audits, and their findings, of locations
+// that were last audited before the given time, which are locations that no
+// export config writes to anymore.
+func (db *ExportDB) DeleteAuditsBefore(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	return count, db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				ExportAuditFinding f
+			USING
+				ExportAudit a
+			WHERE
+				a.tenant = f.tenant AND a.bucket_name = f.bucket_name AND a.filename_root = f.filename_root AND a.audited_at < $1
+		`, before); err != nil {
+			return fmt.Errorf("deleting findings: %w", err)
+		}
+
+		tag, err := tx.Exec(ctx, `
+			DELETE FROM
+				ExportAudit
+			WHERE
+				audited_at < $1
+		`, before)
+		if err != nil {
+			return fmt.Errorf("deleting audits: %w", err)
+		}
+		count = int(tag.RowsAffected())
+		return nil
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestAuditFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := New(testDB)
+	now := time.Now().Truncate(time.Microsecond)
+
+	ec := &model.ExportConfig{
+		BucketName:   "some-bucket",
+		FilenameRoot: "root",
+		Period:       time.Minute,
+		OutputRegion: "US",
+	}
+	if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+	eb := &model.ExportBatch{
+		ConfigID:       ec.ConfigID,
+		BucketName:     ec.BucketName,
+		FilenameRoot:   ec.FilenameRoot,
+		StartTimestamp: now.Add(-2 * time.Hour),
+		EndTimestamp:   now.Add(-time.Hour),
+		OutputRegion:   ec.OutputRegion,
+		Status:         model.ExportBatchOpen,
+	}
+	if err := exportDB.AddExportBatches(ctx, []*model.ExportBatch{eb}); err != nil {
+		t.Fatal(err)
+	}
+	eb, err := exportDB.LeaseBatch(ctx, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{"root/1.zip", "root/2.zip"}
+	sizes := map[string]int64{"root/1.zip": 100}
+	if err := exportDB.FinalizeBatch(ctx, eb, files, sizes, len(files), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := exportDB.ListAuditFiles(ctx, []int64{ec.ConfigID}, ec.BucketName, ec.FilenameRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*AuditFile{
+		{Filename: "root/1.zip", BatchID: eb.BatchID, ConfigID: ec.ConfigID, Status: model.ExportBatchComplete, Size: 100},
+		{Filename: "root/2.zip", BatchID: eb.BatchID, ConfigID: ec.ConfigID, Status: model.ExportBatchComplete},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(AuditFile{}, "EndTimestamp", "InputRegions")); diff != "" {
+		t.Errorf("ListAuditFiles mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Other roots are not listed.
+	if got, err := exportDB.ListAuditFiles(ctx, []int64{ec.ConfigID}, ec.BucketName, "ro"); err != nil || len(got) != 0 {
+		t.Errorf("ListAuditFiles(ro): got %v, %v, want none", got, err)
+	}
+
+	// Reopening the batch deletes its files.
+	if err := exportDB.ReopenBatch(ctx, eb.BatchID); err != nil {
+		t.Fatal(err)
+	}
+	gotBatch, err := exportDB.LookupExportBatch(ctx, eb.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotBatch.Status != model.ExportBatchOpen {
+		t.Errorf("Status: got %q, want %q", gotBatch.Status, model.ExportBatchOpen)
+	}
+	if got, err := exportDB.ListAuditFiles(ctx, []int64{ec.ConfigID}, ec.BucketName, ec.FilenameRoot); err != nil || len(got) != 0 {
+		t.Errorf("ListAuditFiles after reopen: got %v, %v, want none", got, err)
+	}
+
+	// A batch that is not complete can't be reopened.
+	if err := exportDB.ReopenBatch(ctx, eb.BatchID); !errors.Is(err, ErrBatchNotComplete) {
+		t.Errorf("ReopenBatch: got %v, want %v", err, ErrBatchNotComplete)
+	}
+}
+
+func TestSaveAudit(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := New(testDB)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	audit := &model.ExportAudit{
+		BucketName:   "bucket",
+		FilenameRoot: "root",
+		ConfigIDs:    []int64{1, 2},
+		RunID:        "run",
+		AuditedAt:    now,
+		Files:        3,
+		Objects:      4,
+		Counts: map[string]int{
+			model.AuditMissing:      1,
+			model.AuditOrphan:       2,
+			model.AuditSizeMismatch: 0,
+		},
+		Findings: []*model.ExportAuditFinding{
+			{Filename: "root/a.zip", Kind: model.AuditMissing, BatchID: 7, ExpectedSize: 10},
+			{Filename: "root/b.zip", Kind: model.AuditOrphan, ActualSize: 20, Repair: model.AuditRepairDeleted},
+			{Filename: "root/c.zip", Kind: model.AuditOrphan, ActualSize: 30},
+		},
+	}
+	if err := exportDB.SaveAudit(ctx, audit, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	audits, err := exportDB.ListAudits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAudit := *audit
+	wantAudit.Findings = nil
+	if diff := cmp.Diff([]*model.ExportAudit{&wantAudit}, audits, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Errorf("ListAudits mismatch (-want, +got):\n%s", diff)
+	}
+
+	findings, err := exportDB.ListAuditFindings(ctx, "bucket", "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(audit.Findings[:2], findings); diff != "" {
+		t.Errorf("ListAuditFindings mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Saving again replaces the findings.
+	audit.Findings = nil
+	audit.Counts = map[string]int{}
+	if err := exportDB.SaveAudit(ctx, audit, 0); err != nil {
+		t.Fatal(err)
+	}
+	if findings, err := exportDB.ListAuditFindings(ctx, "bucket", "root"); err != nil || len(findings) != 0 {
+		t.Errorf("ListAuditFindings: got %v, %v, want none", findings, err)
+	}
+
+	// Stale audits are deleted.
+	if n, err := exportDB.DeleteAuditsBefore(ctx, now); err != nil || n != 0 {
+		t.Errorf("DeleteAuditsBefore(now): got %d, %v, want 0", n, err)
+	}
+	if n, err := exportDB.DeleteAuditsBefore(ctx, now.Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("DeleteAuditsBefore(now+1s): got %d, %v, want 1", n, err)
+	}
+}
@@ -24,12 +24,17 @@ import (
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/export/model"
+	keylogdb "github.com/google/exposure-notifications-server/internal/keylog/database"
+	keylogmodel "github.com/google/exposure-notifications-server/internal/keylog/model"
+	outboxdb "github.com/google/exposure-notifications-server/internal/outbox/database"
+	outboxmodel "github.com/google/exposure-notifications-server/internal/outbox/model"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/cryptorand"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 type ExportDB struct {
@@ -50,18 +55,30 @@ func (db *ExportDB) AddExportConfig(ctx context.Context, ec *model.ExportConfig)
 
 	thru := database.NullableTime(ec.Thru)
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := checkParentConfig(ctx, tx, ec); err != nil {
+			return err
+		}
+
 		row := tx.QueryRow(ctx, `
 			INSERT INTO
 				ExportConfig
 				(bucket_name, filename_root, period_seconds, output_region, from_timestamp, thru_timestamp,
 				 signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers,
-				 max_records_override)
+				 max_records_override, include_jurisdictions, exclude_jurisdictions, max_batch_keys,
+				 min_records_override, small_batch_policy, encryption_key_id,
+				 parent_config_id, filter_regions, filter_report_types, exclude_revised, test_data,
+				 cache_control, storage_class, object_metadata)
 			VALUES
-				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+				 $19, $20, $21, $22, $23, $24, $25, $26)
 			RETURNING config_id
 		`, ec.BucketName, ec.FilenameRoot, int(ec.Period.Seconds()), ec.OutputRegion,
 			ec.From, thru, ec.SignatureInfoIDs, ec.InputRegions, ec.IncludeTravelers,
-			ec.ExcludeRegions, ec.OnlyNonTravelers, ec.MaxRecordsOverride)
+			ec.ExcludeRegions, ec.OnlyNonTravelers, ec.MaxRecordsOverride,
+			ec.IncludeJurisdictions, ec.ExcludeJurisdictions, ec.MaxBatchKeys,
+			ec.MinRecordsOverride, ec.SmallBatchPolicy, ec.EncryptionKeyID,
+			nullableConfigID(ec.ParentConfigID), ec.FilterRegions, ec.FilterReportTypes, ec.ExcludeRevised, ec.TestData,
+			ec.CacheControl, ec.StorageClass, objectMetadata(ec))
 
 		if err := row.Scan(&ec.ConfigID); err != nil {
 			return fmt.Errorf("fetching config_id: %w", err)
@@ -79,18 +96,29 @@ func (db *ExportDB) UpdateExportConfig(ctx context.Context, ec *model.ExportConf
 
 	thru := database.NullableTime(ec.Thru)
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := checkParentConfig(ctx, tx, ec); err != nil {
+			return err
+		}
+
 		result, err := tx.Exec(ctx, `
 			UPDATE
 				ExportConfig
 			SET
 				bucket_name = $1, filename_root = $2, period_seconds = $3, output_region = $4, from_timestamp = $5,
 				thru_timestamp = $6, signature_info_ids = $7, input_regions = $8, include_travelers = $9,
-				exclude_regions = $10, only_non_travelers = $11, max_records_override = $12
-			WHERE config_id = $13
+				exclude_regions = $10, only_non_travelers = $11, max_records_override = $12,
+				include_jurisdictions = $13, exclude_jurisdictions = $14, max_batch_keys = $15,
+				min_records_override = $16, small_batch_policy = $17, encryption_key_id = $18,
+				parent_config_id = $19, filter_regions = $20, filter_report_types = $21, exclude_revised = $22,
+				test_data = $23, cache_control = $24, storage_class = $25, object_metadata = $26
+			WHERE config_id = $27
 		`, ec.BucketName, ec.FilenameRoot, int(ec.Period.Seconds()), ec.OutputRegion,
 			ec.From, thru, ec.SignatureInfoIDs, ec.InputRegions, ec.IncludeTravelers,
 			ec.ExcludeRegions, ec.OnlyNonTravelers, ec.MaxRecordsOverride,
-			ec.ConfigID)
+			ec.IncludeJurisdictions, ec.ExcludeJurisdictions, ec.MaxBatchKeys,
+			ec.MinRecordsOverride, ec.SmallBatchPolicy, ec.EncryptionKeyID,
+			nullableConfigID(ec.ParentConfigID), ec.FilterRegions, ec.FilterReportTypes, ec.ExcludeRevised,
+			ec.TestData, ec.CacheControl, ec.StorageClass, objectMetadata(ec), ec.ConfigID)
 		if err != nil {
 			return fmt.Errorf("updating signatureinfo: %w", err)
 		}
@@ -108,7 +136,11 @@ func (db *ExportDB) GetExportConfig(ctx context.Context, id int64) (*model.Expor
 			SELECT
 				config_id, bucket_name, filename_root, period_seconds, output_region,
 				from_timestamp, thru_timestamp, signature_info_ids, input_regions,
-				include_travelers, exclude_regions, only_non_travelers, max_records_override
+				include_travelers, exclude_regions, only_non_travelers, max_records_override,
+				include_jurisdictions, exclude_jurisdictions, max_batch_keys,
+				min_records_override, small_batch_policy, encryption_key_id,
+				parent_config_id, filter_regions, filter_report_types, exclude_revised, test_data,
+				cache_control, storage_class, object_metadata
 			FROM
 				ExportConfig
 			WHERE
@@ -136,7 +168,11 @@ func (db *ExportDB) GetAllExportConfigs(ctx context.Context) ([]*model.ExportCon
 			SELECT
 				config_id, bucket_name, filename_root, period_seconds, output_region,
 				from_timestamp, thru_timestamp, signature_info_ids, input_regions, include_travelers,
-				exclude_regions, only_non_travelers, max_records_override
+				exclude_regions, only_non_travelers, max_records_override,
+				include_jurisdictions, exclude_jurisdictions, max_batch_keys,
+				min_records_override, small_batch_policy, encryption_key_id,
+				parent_config_id, filter_regions, filter_report_types, exclude_revised, test_data,
+				cache_control, storage_class, object_metadata
 			FROM
 				ExportConfig
 			ORDER BY config_id
@@ -166,6 +202,71 @@ func (db *ExportDB) GetAllExportConfigs(ctx context.Context) ([]*model.ExportCon
 	return configs, nil
 }
 
+// exportConfigListQuery searches export configs by regions, bucket and
+// filename root.
+var exportConfigListQuery = &database.ListQuery{
+	SearchColumns: []string{
+		"CAST(config_id AS TEXT)", "output_region", "array_to_string(input_regions, ',')",
+		"bucket_name", "filename_root",
+	},
+	SortColumns: map[string]string{
+		"id":     "config_id",
+		"region": "output_region",
+		"bucket": "bucket_name",
+	},
+	DefaultSort: "id",
+	TieBreaker:  "config_id",
+}
+
+// SearchExportConfigs reads one page of the export configs, searched by ID,
+// regions, bucket and filename root. The sort keys are "id", "region" and
+// "bucket".
+func (db *ExportDB) SearchExportConfigs(ctx context.Context, params *database.PageParams) ([]*model.ExportConfig, *database.Page, error) {
+	where, order, args, err := exportConfigListQuery.Build(params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search export configs: %w", err)
+	}
+
+	var configs []*model.ExportConfig
+	var total int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM ExportConfig `+where, args...).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT
+				config_id, bucket_name, filename_root, period_seconds, output_region,
+				from_timestamp, thru_timestamp, signature_info_ids, input_regions, include_travelers,
+				exclude_regions, only_non_travelers, max_records_override,
+				include_jurisdictions, exclude_jurisdictions, max_batch_keys,
+				min_records_override, small_batch_policy, encryption_key_id,
+				parent_config_id, filter_regions, filter_report_types, exclude_revised, test_data,
+				cache_control, storage_class, object_metadata
+			FROM
+				ExportConfig
+			`+where+`
+			`+order, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			config, err := scanOneExportConfig(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			configs = append(configs, config)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, nil, fmt.Errorf("search export configs: %w", err)
+	}
+
+	return configs, database.NewPage(params, total), nil
+}
+
 // IterateExportConfigs applies f to each ExportConfig whose FromTimestamp is
 // before the given time. If f returns a non-nil error, the iteration stops, and
 // the returned error will match f's error with errors.Is.
@@ -175,7 +276,11 @@ func (db *ExportDB) IterateExportConfigs(ctx context.Context, t time.Time, f fun
 			SELECT
 				config_id, bucket_name, filename_root, period_seconds, output_region,
 				from_timestamp, thru_timestamp, signature_info_ids, input_regions, include_travelers,
-				exclude_regions, only_non_travelers, max_records_override
+				exclude_regions, only_non_travelers, max_records_override,
+				include_jurisdictions, exclude_jurisdictions, max_batch_keys,
+				min_records_override, small_batch_policy, encryption_key_id,
+				parent_config_id, filter_regions, filter_report_types, exclude_revised, test_data,
+				cache_control, storage_class, object_metadata
 			FROM
 				ExportConfig
 			WHERE
@@ -210,17 +315,137 @@ func (db *ExportDB) IterateExportConfigs(ctx context.Context, t time.Time, f fun
 	return nil
 }
 
+// ListDerivedExportConfigs returns the configs derived from the given parent
+// config that are active at the given time.
+func (db *ExportDB) ListDerivedExportConfigs(ctx context.Context, parentID int64, t time.Time) ([]*model.ExportConfig, error) {
+	var configs []*model.ExportConfig
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				config_id, bucket_name, filename_root, period_seconds, output_region,
+				from_timestamp, thru_timestamp, signature_info_ids, input_regions, include_travelers,
+				exclude_regions, only_non_travelers, max_records_override,
+				include_jurisdictions, exclude_jurisdictions, max_batch_keys,
+				min_records_override, small_batch_policy, encryption_key_id,
+				parent_config_id, filter_regions, filter_report_types, exclude_revised, test_data,
+				cache_control, storage_class, object_metadata
+			FROM
+				ExportConfig
+			WHERE
+				parent_config_id = $1
+			AND
+				from_timestamp < $2
+			AND
+				(thru_timestamp IS NULL OR thru_timestamp > $2)
+			ORDER BY config_id
+		`, parentID, t)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			config, err := scanOneExportConfig(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			configs = append(configs, config)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list derived export configs: %w", err)
+	}
+
+	return configs, nil
+}
+
+// checkParentConfig ensures that a derived config's parent exists and is not
+// derived itself, and that a config with derived configs is not made derived.
+func checkParentConfig(ctx context.Context, tx pgx.Tx, ec *model.ExportConfig) error {
+	if !ec.IsDerived() {
+		return nil
+	}
+
+	var (
+		grandparentID *int64
+		bucketName    string
+		filenameRoot  string
+	)
+	row := tx.QueryRow(ctx, `
+		SELECT parent_config_id, bucket_name, filename_root FROM ExportConfig WHERE config_id = $1
+	`, ec.ParentConfigID)
+	if err := row.Scan(&grandparentID, &bucketName, &filenameRoot); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("parent export config %d does not exist", ec.ParentConfigID)
+		}
+		return fmt.Errorf("looking up parent export config: %w", err)
+	}
+	if grandparentID != nil {
+		return fmt.Errorf("parent export config %d is derived, exports cannot be derived from derived exports", ec.ParentConfigID)
+	}
+	if bucketName == ec.BucketName && filenameRoot == ec.FilenameRoot {
+		return fmt.Errorf("a derived export must use a different bucket or filename root than its parent")
+	}
+
+	if ec.ConfigID != 0 {
+		var children int
+		row := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM ExportConfig WHERE parent_config_id = $1
+		`, ec.ConfigID)
+		if err := row.Scan(&children); err != nil {
+			return fmt.Errorf("counting derived export configs: %w", err)
+		}
+		if children > 0 {
+			return fmt.Errorf("export config %d has derived exports and cannot be derived itself", ec.ConfigID)
+		}
+	}
+	return nil
+}
+
+func nullableConfigID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// objectMetadata returns the object metadata of the config to store, which is
+// never null.
+func objectMetadata(ec *model.ExportConfig) map[string]string {
+	if ec.ObjectMetadata == nil {
+		return map[string]string{}
+	}
+	return ec.ObjectMetadata
+}
+
 func scanOneExportConfig(row pgx.Row) (*model.ExportConfig, error) {
 	var (
 		m             model.ExportConfig
 		outputRegion  sql.NullString
 		periodSeconds int
 		thru          *time.Time
+		parentID      *int64
+		metadata      map[string]string
 	)
 	if err := row.Scan(&m.ConfigID, &m.BucketName, &m.FilenameRoot, &periodSeconds, &outputRegion, &m.From, &thru,
-		&m.SignatureInfoIDs, &m.InputRegions, &m.IncludeTravelers, &m.ExcludeRegions, &m.OnlyNonTravelers, &m.MaxRecordsOverride); err != nil {
+		&m.SignatureInfoIDs, &m.InputRegions, &m.IncludeTravelers, &m.ExcludeRegions, &m.OnlyNonTravelers, &m.MaxRecordsOverride,
+		&m.IncludeJurisdictions, &m.ExcludeJurisdictions, &m.MaxBatchKeys, &m.MinRecordsOverride, &m.SmallBatchPolicy,
+		&m.EncryptionKeyID, &parentID, &m.FilterRegions, &m.FilterReportTypes, &m.ExcludeRevised, &m.TestData,
+		&m.CacheControl, &m.StorageClass, &metadata); err != nil {
 		return nil, err
 	}
+	if len(metadata) > 0 {
+		m.ObjectMetadata = metadata
+	}
+	if parentID != nil {
+		m.ParentConfigID = *parentID
+	}
 
 	m.Period = time.Duration(periodSeconds) * time.Second
 	if thru != nil {
@@ -256,7 +481,7 @@ func (db *ExportDB) AddSignatureInfo(ctx context.Context, si *model.SignatureInf
 		if err := row.Scan(&si.ID); err != nil {
 			return fmt.Errorf("fetching id: %w", err)
 		}
-		return nil
+		return logSignatureInfo(ctx, tx, si)
 	})
 }
 
@@ -282,10 +507,27 @@ func (db *ExportDB) UpdateSignatureInfo(ctx context.Context, si *model.Signature
 		if result.RowsAffected() != 1 {
 			return fmt.Errorf("no rows updated")
 		}
-		return nil
+		return logSignatureInfo(ctx, tx, si)
 	})
 }
 
+// logSignatureInfo appends the signing key to the key transparency log.
+func logSignatureInfo(ctx context.Context, tx pgx.Tx, si *model.SignatureInfo) error {
+	data := &keylogmodel.ExportSigningKey{
+		SignatureInfoID: si.ID,
+		SigningKey:      si.SigningKey,
+		KeyVersion:      si.SigningKeyVersion,
+		KeyID:           si.SigningKeyID,
+	}
+	if !si.EndTimestamp.IsZero() {
+		data.ValidUntil = si.EndTimestamp.Unix()
+	}
+	if _, err := keylogdb.Append(ctx, tx, keylogmodel.KindExportSigningKey, data, time.Now()); err != nil {
+		return fmt.Errorf("logging signing key: %w", err)
+	}
+	return nil
+}
+
 func (db *ExportDB) ListAllSignatureInfos(ctx context.Context) ([]*model.SignatureInfo, error) {
 	var sigs []*model.SignatureInfo
 
@@ -322,6 +564,61 @@ func (db *ExportDB) ListAllSignatureInfos(ctx context.Context) ([]*model.Signatu
 	return sigs, nil
 }
 
+// signatureInfoListQuery searches signature infos by key ID, key and version.
+var signatureInfoListQuery = &database.ListQuery{
+	SearchColumns: []string{"signing_key_id", "signing_key", "signing_key_version"},
+	SortColumns: map[string]string{
+		"id":      "id",
+		"key_id":  "signing_key_id",
+		"expires": "thru_timestamp",
+	},
+	DefaultSort: "key_id",
+	TieBreaker:  "id",
+}
+
+// SearchSignatureInfos reads one page of the signature infos, searched by
+// signing key ID, key and version. The sort keys are "id", "key_id" and
+// "expires".
+func (db *ExportDB) SearchSignatureInfos(ctx context.Context, params *database.PageParams) ([]*model.SignatureInfo, *database.Page, error) {
+	where, order, args, err := signatureInfoListQuery.Build(params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search signature infos: %w", err)
+	}
+
+	var sigs []*model.SignatureInfo
+	var total int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM SignatureInfo `+where, args...).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT
+				id, signing_key, signing_key_version, signing_key_id, thru_timestamp
+			FROM
+				SignatureInfo
+			`+where+`
+			`+order, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			sig, err := scanOneSignatureInfo(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			sigs = append(sigs, sig)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, nil, fmt.Errorf("search signature infos: %w", err)
+	}
+
+	return sigs, database.NewPage(params, total), nil
+}
+
 func (db *ExportDB) LookupSignatureInfos(ctx context.Context, ids []int64, validUntil time.Time) ([]*model.SignatureInfo, error) {
 	var sigs []*model.SignatureInfo
 
@@ -438,6 +735,37 @@ func (db *ExportDB) LatestExportBatchEnd(ctx context.Context, ec *model.ExportCo
 	return t, nil
 }
 
+// LatestCompletedBatchEnd returns the end time of the most recent completed
+// ExportBatch of the config. It returns the zero time if no batch has been
+// completed.
+func (db *ExportDB) LatestCompletedBatchEnd(ctx context.Context, configID int64) (time.Time, error) {
+	var t time.Time
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				MAX(end_timestamp)
+			FROM
+				ExportBatch
+			WHERE
+				config_id = $1 AND status = $2
+		`, configID, model.ExportBatchComplete)
+
+		var latestEnd sql.NullTime
+		if err := row.Scan(&latestEnd); err != nil {
+			return fmt.Errorf("failed to scan result: %w", err)
+		}
+		if latestEnd.Valid {
+			t = latestEnd.Time
+		}
+		return nil
+	}); err != nil {
+		return t, fmt.Errorf("latest completed batch end: %w", err)
+	}
+
+	return t, nil
+}
+
 // ListLatestExportBatchEnds returns a map of export config IDs to their latest
 // batch end times.
 func (db *ExportDB) ListLatestExportBatchEnds(ctx context.Context) (map[int64]*time.Time, error) {
@@ -484,9 +812,9 @@ func (db *ExportDB) AddExportBatches(ctx context.Context, batches []*model.Expor
 		_, err := tx.Prepare(ctx, stmtName, `
 			INSERT INTO
 				ExportBatch
-				(config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override)
+				(config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override, include_jurisdictions, exclude_jurisdictions, max_batch_keys, min_records_override, small_batch_policy, encryption_key_id, test_data, run_id)
 			VALUES
-				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, NULLIF($21, ''))
 		`)
 		if err != nil {
 			return err
@@ -495,7 +823,8 @@ func (db *ExportDB) AddExportBatches(ctx context.Context, batches []*model.Expor
 		for _, eb := range batches {
 			if _, err := tx.Exec(ctx, stmtName,
 				eb.ConfigID, eb.BucketName, eb.FilenameRoot, eb.StartTimestamp, eb.EndTimestamp, eb.OutputRegion, eb.Status, eb.SignatureInfoIDs,
-				eb.InputRegions, eb.IncludeTravelers, eb.ExcludeRegions, eb.OnlyNonTravelers, eb.MaxRecordsOverride); err != nil {
+				eb.InputRegions, eb.IncludeTravelers, eb.ExcludeRegions, eb.OnlyNonTravelers, eb.MaxRecordsOverride,
+				eb.IncludeJurisdictions, eb.ExcludeJurisdictions, eb.MaxBatchKeys, eb.MinRecordsOverride, eb.SmallBatchPolicy, eb.EncryptionKeyID, eb.TestData, eb.RunID); err != nil {
 				return err
 			}
 		}
@@ -503,15 +832,9 @@ func (db *ExportDB) AddExportBatches(ctx context.Context, batches []*model.Expor
 	})
 }
 
-// LeaseBatch returns a leased ExportBatch for the worker to process. If no work to do, nil will be returned.
-func (db *ExportDB) LeaseBatch(ctx context.Context, ttl time.Duration, batchMaxCloseTime time.Time) (*model.ExportBatch, error) {
-	var openBatchIDs []int64
-
-	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		// batches are ordered by end time. We try to fill the "oldest"
-		// batches first so that padding written will be covered by
-		// "newer" export batches.
-		rows, err := tx.Query(ctx, `
+// leaseBatchesSQL lists the batches that are ready to be leased. It is served
+// by the exportbatch_status and exportbatch_end_timestamp indexes.
+const leaseBatchesSQL = `
 			SELECT
 				batch_id
 			FROM
@@ -527,7 +850,17 @@ func (db *ExportDB) LeaseBatch(ctx context.Context, ttl time.Duration, batchMaxC
 			ORDER BY
 				end_timestamp ASC
 			LIMIT 100
-		`, model.ExportBatchOpen, model.ExportBatchPending, batchMaxCloseTime)
+		`
+
+// LeaseBatch returns a leased ExportBatch for the worker to process. If no work to do, nil will be returned.
+func (db *ExportDB) LeaseBatch(ctx context.Context, ttl time.Duration, batchMaxCloseTime time.Time) (*model.ExportBatch, error) {
+	var openBatchIDs []int64
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		// batches are ordered by end time. We try to fill the "oldest"
+		// batches first so that padding written will be covered by
+		// "newer" export batches.
+		rows, err := tx.Query(ctx, leaseBatchesSQL, model.ExportBatchOpen, model.ExportBatchPending, batchMaxCloseTime)
 		if err != nil {
 			return fmt.Errorf("failed to list: %w", err)
 		}
@@ -631,7 +964,7 @@ type queryRowFn func(ctx context.Context, query string, args ...interface{}) pgx
 func lookupExportBatch(ctx context.Context, batchID int64, queryRow queryRowFn) (*model.ExportBatch, error) {
 	row := queryRow(ctx, `
 		SELECT
-			batch_id, config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, lease_expires, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override
+			batch_id, config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, lease_expires, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override, include_jurisdictions, exclude_jurisdictions, max_batch_keys, min_records_override, small_batch_policy, encryption_key_id, test_data, COALESCE(run_id, '')
 		FROM
 			ExportBatch
 		WHERE
@@ -641,7 +974,7 @@ func lookupExportBatch(ctx context.Context, batchID int64, queryRow queryRowFn)
 
 	var expires *time.Time
 	eb := model.ExportBatch{}
-	if err := row.Scan(&eb.BatchID, &eb.ConfigID, &eb.BucketName, &eb.FilenameRoot, &eb.StartTimestamp, &eb.EndTimestamp, &eb.OutputRegion, &eb.Status, &expires, &eb.SignatureInfoIDs, &eb.InputRegions, &eb.IncludeTravelers, &eb.ExcludeRegions, &eb.OnlyNonTravelers, &eb.MaxRecordsOverride); err != nil {
+	if err := row.Scan(&eb.BatchID, &eb.ConfigID, &eb.BucketName, &eb.FilenameRoot, &eb.StartTimestamp, &eb.EndTimestamp, &eb.OutputRegion, &eb.Status, &expires, &eb.SignatureInfoIDs, &eb.InputRegions, &eb.IncludeTravelers, &eb.ExcludeRegions, &eb.OnlyNonTravelers, &eb.MaxRecordsOverride, &eb.IncludeJurisdictions, &eb.ExcludeJurisdictions, &eb.MaxBatchKeys, &eb.MinRecordsOverride, &eb.SmallBatchPolicy, &eb.EncryptionKeyID, &eb.TestData, &eb.RunID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, database.ErrNotFound
 		}
@@ -653,8 +986,138 @@ func lookupExportBatch(ctx context.Context, batchID int64, queryRow queryRowFn)
 	return &eb, nil
 }
 
-// FinalizeBatch writes the ExportFile records and marks the ExportBatch as complete.
-func (db *ExportDB) FinalizeBatch(ctx context.Context, eb *model.ExportBatch, files []string, batchSize int) error {
+// SplitBatch closes the given batch early at the given time and adds a new,
+// open batch that covers the rest of its time window. The given batch is
+// updated in place and the new batch is returned.
+func (db *ExportDB) SplitBatch(ctx context.Context, eb *model.ExportBatch, at time.Time) (*model.ExportBatch, error) {
+	if !at.After(eb.StartTimestamp) || !at.Before(eb.EndTimestamp) {
+		return nil, fmt.Errorf("split time %v is outside of batch window [%v, %v)", at, eb.StartTimestamp, eb.EndTimestamp)
+	}
+
+	rest := *eb
+	rest.BatchID = 0
+	rest.StartTimestamp = at
+	rest.Status = model.ExportBatchOpen
+	rest.LeaseExpires = time.Time{}
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE
+				ExportBatch
+			SET
+				end_timestamp = $1
+			WHERE
+				batch_id = $2 AND end_timestamp = $3
+		`, at, eb.BatchID, eb.EndTimestamp)
+		if err != nil {
+			return fmt.Errorf("updating batch end: %w", err)
+		}
+		if result.RowsAffected() != 1 {
+			return fmt.Errorf("batch %d was modified or does not exist", eb.BatchID)
+		}
+
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				ExportBatch
+				(config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override, include_jurisdictions, exclude_jurisdictions, max_batch_keys, min_records_override, small_batch_policy, encryption_key_id, test_data, run_id)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, NULLIF($21, ''))
+			RETURNING batch_id
+		`, rest.ConfigID, rest.BucketName, rest.FilenameRoot, rest.StartTimestamp, rest.EndTimestamp, rest.OutputRegion, rest.Status, rest.SignatureInfoIDs,
+			rest.InputRegions, rest.IncludeTravelers, rest.ExcludeRegions, rest.OnlyNonTravelers, rest.MaxRecordsOverride,
+			rest.IncludeJurisdictions, rest.ExcludeJurisdictions, rest.MaxBatchKeys, rest.MinRecordsOverride, rest.SmallBatchPolicy, rest.EncryptionKeyID, rest.TestData, rest.RunID)
+		if err := row.Scan(&rest.BatchID); err != nil {
+			return fmt.Errorf("inserting remainder batch: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("split export batch: %w", err)
+	}
+
+	eb.EndTimestamp = at
+	return &rest, nil
+}
+
+// AddDerivedBatch returns the batch of a derived config that covers the same
+// time window as the given parent batch, creating it if needed. The batch is
+// created as pending without a lease so that it is never leased by a worker;
+// it is completed with FinalizeBatch by the worker processing the parent.
+func (db *ExportDB) AddDerivedBatch(ctx context.Context, parent *model.ExportBatch, ec *model.ExportConfig) (*model.ExportBatch, error) {
+	eb := &model.ExportBatch{
+		ConfigID:           ec.ConfigID,
+		BucketName:         ec.BucketName,
+		FilenameRoot:       ec.FilenameRoot,
+		StartTimestamp:     parent.StartTimestamp,
+		EndTimestamp:       parent.EndTimestamp,
+		OutputRegion:       parent.OutputRegion,
+		InputRegions:       parent.InputRegions,
+		IncludeTravelers:   parent.IncludeTravelers,
+		OnlyNonTravelers:   parent.OnlyNonTravelers,
+		ExcludeRegions:     parent.ExcludeRegions,
+		Status:             model.ExportBatchPending,
+		SignatureInfoIDs:   append([]int64(nil), ec.SignatureInfoIDs...),
+		MaxRecordsOverride: ec.MaxRecordsOverride,
+		MinRecordsOverride: ec.MinRecordsOverride,
+		SmallBatchPolicy:   ec.SmallBatchPolicy,
+		EncryptionKeyID:    ec.EncryptionKeyID,
+
+		IncludeJurisdictions: parent.IncludeJurisdictions,
+		ExcludeJurisdictions: parent.ExcludeJurisdictions,
+		TestData:             parent.TestData,
+		RunID:                parent.RunID,
+	}
+	if ec.OutputRegion != "" {
+		eb.OutputRegion = ec.OutputRegion
+	}
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				batch_id
+			FROM
+				ExportBatch
+			WHERE
+				config_id = $1 AND start_timestamp = $2 AND end_timestamp = $3
+			LIMIT 1
+		`, eb.ConfigID, eb.StartTimestamp, eb.EndTimestamp)
+		var batchID int64
+		if err := row.Scan(&batchID); err == nil {
+			existing, err := lookupExportBatch(ctx, batchID, tx.QueryRow)
+			if err != nil {
+				return fmt.Errorf("looking up existing batch: %w", err)
+			}
+			eb = existing
+			return nil
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("looking up existing batch: %w", err)
+		}
+
+		row = tx.QueryRow(ctx, `
+			INSERT INTO
+				ExportBatch
+				(config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override, include_jurisdictions, exclude_jurisdictions, max_batch_keys, min_records_override, small_batch_policy, encryption_key_id, test_data, run_id)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, NULLIF($21, ''))
+			RETURNING batch_id
+		`, eb.ConfigID, eb.BucketName, eb.FilenameRoot, eb.StartTimestamp, eb.EndTimestamp, eb.OutputRegion, eb.Status, eb.SignatureInfoIDs,
+			eb.InputRegions, eb.IncludeTravelers, eb.ExcludeRegions, eb.OnlyNonTravelers, eb.MaxRecordsOverride,
+			eb.IncludeJurisdictions, eb.ExcludeJurisdictions, eb.MaxBatchKeys, eb.MinRecordsOverride, eb.SmallBatchPolicy, eb.EncryptionKeyID, eb.TestData, eb.RunID)
+		if err := row.Scan(&eb.BatchID); err != nil {
+			return fmt.Errorf("inserting derived batch: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("add derived export batch: %w", err)
+	}
+
+	return eb, nil
+}
+
+// FinalizeBatch writes the ExportFile records and marks the ExportBatch as
+// complete. sizes holds the stored size of each file in bytes, files without
+// a size are recorded with an unknown size. If event is not nil, it is
+// written to the outbox in the same transaction.
+func (db *ExportDB) FinalizeBatch(ctx context.Context, eb *model.ExportBatch, files []string, sizes map[string]int64, batchSize int, event *outboxmodel.Event) error {
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		// Update ExportFile for the files created.
 		for i, file := range files {
@@ -670,6 +1133,7 @@ func (db *ExportDB) FinalizeBatch(ctx context.Context, eb *model.ExportBatch, fi
 				BatchNum:         i + 1,
 				BatchSize:        batchSize,
 				Status:           model.ExportBatchComplete,
+				Size:             sizes[file],
 			}
 			if err := addExportFile(ctx, tx, &ef); err != nil {
 				if errors.Is(err, database.ErrKeyConflict) {
@@ -684,6 +1148,12 @@ func (db *ExportDB) FinalizeBatch(ctx context.Context, eb *model.ExportBatch, fi
 		if err := completeBatch(ctx, tx, eb.BatchID); err != nil {
 			return fmt.Errorf("marking batch %v complete: %w", eb.BatchID, err)
 		}
+
+		if event != nil {
+			if err := outboxdb.InsertEvent(ctx, tx, event); err != nil {
+				return fmt.Errorf("recording batch %v event: %w", eb.BatchID, err)
+			}
+		}
 		return nil
 	})
 }
@@ -772,6 +1242,46 @@ func (db *ExportDB) LookupExportFiles(ctx context.Context, configID int64, ttl t
 	return files, nil
 }
 
+// LookupLatestExportFile returns the name of the newest completed export file
+// of the config and the end of its batch. It returns database.ErrNotFound if
+// the config has no files.
+func (db *ExportDB) LookupLatestExportFile(ctx context.Context, configID int64) (string, time.Time, error) {
+	var filename string
+	var end time.Time
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				ef.filename, eb.end_timestamp
+			FROM
+				ExportFile ef
+			INNER JOIN
+				ExportBatch eb ON (eb.batch_id = ef.batch_id)
+			WHERE
+				eb.config_id = $1
+			AND
+				eb.status = $2
+			AND
+				ef.status = $2
+			ORDER BY
+				eb.end_timestamp DESC, ef.filename DESC
+			LIMIT 1
+		`, configID, model.ExportBatchComplete)
+
+		if err := row.Scan(&filename, &end); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return database.ErrNotFound
+			}
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", time.Time{}, fmt.Errorf("lookup latest export file: %w", err)
+	}
+
+	return filename, end, nil
+}
+
 type joinedExportBatchFile struct {
 	bucketName  string
 	filename    string
@@ -810,12 +1320,9 @@ func (db *ExportDB) LookupExportFile(ctx context.Context, filename string) (*mod
 	return &file, nil
 }
 
-// DeleteFilesBefore deletes the export batch files for batches ending before the time passed in.
-func (db *ExportDB) DeleteFilesBefore(ctx context.Context, before time.Time, blobstore storage.Blobstore) (int, error) {
-	var files []joinedExportBatchFile
-
-	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		rows, err := tx.Query(ctx, `
+// deleteFilesBeforeSQL lists the expired files to delete. It is served by the
+// exportbatch_end_timestamp and exportfile_batch_id indexes.
+const deleteFilesBeforeSQL = `
 			SELECT
 				eb.batch_id,
 				eb.status,
@@ -831,7 +1338,14 @@ func (db *ExportDB) DeleteFilesBefore(ctx context.Context, before time.Time, blo
 				eb.end_timestamp < $1
 				AND eb.status != $2
 				AND ef.status = $3
-		`, before, model.ExportBatchDeleted, model.ExportBatchDeletePending)
+		`
+
+// DeleteFilesBefore deletes the export batch files for batches ending before the time passed in.
+func (db *ExportDB) DeleteFilesBefore(ctx context.Context, before time.Time, blobstore storage.Blobstore) (int, error) {
+	var files []joinedExportBatchFile
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, deleteFilesBeforeSQL, before, model.ExportBatchDeleted, model.ExportBatchDeletePending)
 		if err != nil {
 			return fmt.Errorf("failed to list: %w", err)
 		}
@@ -860,6 +1374,12 @@ func (db *ExportDB) DeleteFilesBefore(ctx context.Context, before time.Time, blo
 	for _, f := range files {
 		f := f
 
+		// Leave the rest of the files for the next run once the budget of
+		// the job is spent.
+		if scheduler.Spent(ctx) {
+			break
+		}
+
 		// If file is already deleted, skip to the next.
 		if f.fileStatus == model.ExportBatchDeleted {
 			batchFileDeleteCounter[f.batchID]++
@@ -872,6 +1392,11 @@ func (db *ExportDB) DeleteFilesBefore(ctx context.Context, before time.Time, blo
 		if err := blobstore.DeleteObject(gcsCtx, f.bucketName, f.filename); err != nil {
 			return 0, fmt.Errorf("delete object: %w", err)
 		}
+		// Provenance records are only written when enabled, but deleting a
+		// missing object is not an error.
+		if err := blobstore.DeleteObject(gcsCtx, f.bucketName, f.filename+model.ProvenanceFilenameSuffix); err != nil {
+			return 0, fmt.Errorf("delete provenance object: %w", err)
+		}
 
 		err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 			// Update Status in ExportFile.
@@ -903,11 +1428,11 @@ func addExportFile(ctx context.Context, tx pgx.Tx, ef *model.ExportFile) error {
 	tag, err := tx.Exec(ctx, `
 		INSERT INTO
 			ExportFile
-			(bucket_name, filename, batch_id, output_region, batch_num, batch_size, status, input_regions, include_travelers, exclude_regions, only_non_travelers)
+			(bucket_name, filename, batch_id, output_region, batch_num, batch_size, status, input_regions, include_travelers, exclude_regions, only_non_travelers, size_bytes)
 		VALUES
-			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		ON CONFLICT (filename) DO NOTHING
-		`, ef.BucketName, ef.Filename, ef.BatchID, ef.OutputRegion, ef.BatchNum, ef.BatchSize, ef.Status, ef.InputRegions, ef.IncludeTravelers, ef.ExcludeRegions, ef.OnlyNonTravelers)
+		`, ef.BucketName, ef.Filename, ef.BatchID, ef.OutputRegion, ef.BatchNum, ef.BatchSize, ef.Status, ef.InputRegions, ef.IncludeTravelers, ef.ExcludeRegions, ef.OnlyNonTravelers, nullableSize(ef.Size))
 	if err != nil {
 		return fmt.Errorf("inserting to ExportFile: %w", err)
 	}
@@ -917,6 +1442,14 @@ func addExportFile(ctx context.Context, tx pgx.Tx, ef *model.ExportFile) error {
 	return nil
 }
 
+// nullableSize stores unknown (zero) sizes as NULL.
+func nullableSize(size int64) *int64 {
+	if size <= 0 {
+		return nil
+	}
+	return &size
+}
+
 func updateExportFileStatus(ctx context.Context, tx pgx.Tx, filename, status string) error {
 	_, err := tx.Exec(ctx, `
 		UPDATE
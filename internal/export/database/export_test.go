@@ -22,13 +22,15 @@ import (
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/export/model"
+	outboxdb "github.com/google/exposure-notifications-server/internal/outbox/database"
+	outboxmodel "github.com/google/exposure-notifications-server/internal/outbox/model"
 	"github.com/google/exposure-notifications-server/internal/project"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/exposure-notifications-server/pkg/database"
 
 	"github.com/google/go-cmp/cmp"
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 func TestAddRetrieveUpdateSignatureInfo(t *testing.T) {
@@ -164,6 +166,11 @@ func TestAddGetUpdateExportConfig(t *testing.T) {
 	want.Thru = time.Time{}
 	want.SignatureInfoIDs = []int64{1, 2, 3, 4, 5}
 	want.InputRegions = []string{"US", "CA"}
+	want.IncludeJurisdictions = []string{"US"}
+	want.ExcludeJurisdictions = []string{"CA"}
+	want.CacheControl = "public, max-age=3600"
+	want.StorageClass = "NEARLINE"
+	want.ObjectMetadata = map[string]string{"program": "en"}
 
 	if err := exportDB.UpdateExportConfig(ctx, want); err != nil {
 		t.Fatal(err)
@@ -421,12 +428,32 @@ func TestFinalizeBatch(t *testing.T) {
 		t.Errorf("pre gotBatch.Status=%q, want=%q", gotBatch.Status, model.ExportBatchPending)
 	}
 
+	if _, _, err := exportDB.LookupLatestExportFile(ctx, eb.ConfigID); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("LookupLatestExportFile: expected not found before finalize, got %v", err)
+	}
+	if end, err := exportDB.LatestCompletedBatchEnd(ctx, eb.ConfigID); err != nil || !end.IsZero() {
+		t.Errorf("LatestCompletedBatchEnd: expected zero time before finalize, got %v, %v", end, err)
+	}
+
 	// Finalize the batch.
 	files := []string{"file1.txt", "file2.txt"}
 	batchSize := 10
-	if err := exportDB.FinalizeBatch(ctx, eb, files, batchSize); err != nil {
+	event, err := outboxmodel.NewEvent(outboxmodel.KindExportPublished, &outboxmodel.ExportPublished{
+		ConfigID: eb.ConfigID,
+		BatchID:  eb.BatchID,
+		Files:    files,
+	}, now)
+	if err != nil {
 		t.Fatal(err)
 	}
+	if err := exportDB.FinalizeBatch(ctx, eb, files, nil, batchSize, event); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check that the event was recorded with the batch.
+	if got, err := outboxdb.New(testDB).CountUndelivered(ctx); err != nil || got != 1 {
+		t.Errorf("CountUndelivered: got %d, %v, want 1", got, err)
+	}
 
 	// Check that the batch is COMPLETED.
 	gotBatch, err = exportDB.LookupExportBatch(ctx, eb.BatchID)
@@ -447,6 +474,20 @@ func TestFinalizeBatch(t *testing.T) {
 		t.Errorf("mismatch (-want, +got):\n%s", diff)
 	}
 
+	gotLatest, gotEnd, err := exportDB.LookupLatestExportFile(ctx, eb.ConfigID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := files[len(files)-1]; gotLatest != want {
+		t.Errorf("LookupLatestExportFile: got %q, want %q", gotLatest, want)
+	}
+	if !gotEnd.Equal(eb.EndTimestamp) {
+		t.Errorf("LookupLatestExportFile: got end %v, want %v", gotEnd, eb.EndTimestamp)
+	}
+	if end, err := exportDB.LatestCompletedBatchEnd(ctx, eb.ConfigID); err != nil || !end.Equal(eb.EndTimestamp) {
+		t.Errorf("LatestCompletedBatchEnd: got %v, %v, want %v", end, err, eb.EndTimestamp)
+	}
+
 	for i, filename := range gotFiles {
 		got, err := exportDB.LookupExportFile(ctx, filename)
 		if err != nil {
@@ -477,6 +518,181 @@ func TestFinalizeBatch(t *testing.T) {
 }
 
 // TestTravelerKeys ensures traveler keys are pulled in when necessary.
+func TestSplitBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := New(testDB)
+	now := time.Now().Truncate(time.Hour)
+
+	ec := &model.ExportConfig{
+		BucketName:   "some-bucket",
+		FilenameRoot: "filename-root",
+		Period:       4 * time.Hour,
+		OutputRegion: "US",
+	}
+	if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+
+	maxKeys := 100
+	eb := &model.ExportBatch{
+		ConfigID:       ec.ConfigID,
+		BucketName:     ec.BucketName,
+		FilenameRoot:   ec.FilenameRoot,
+		StartTimestamp: now.Add(-8 * time.Hour),
+		EndTimestamp:   now.Add(-4 * time.Hour),
+		OutputRegion:   ec.OutputRegion,
+		Status:         model.ExportBatchOpen,
+		MaxBatchKeys:   &maxKeys,
+	}
+	if err := exportDB.AddExportBatches(ctx, []*model.ExportBatch{eb}); err != nil {
+		t.Fatal(err)
+	}
+	eb, err := exportDB.LeaseBatch(ctx, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := exportDB.SplitBatch(ctx, eb, eb.EndTimestamp); err == nil {
+		t.Errorf("expected error splitting at the end of the batch")
+	}
+
+	at := now.Add(-6 * time.Hour)
+	rest, err := exportDB.SplitBatch(ctx, eb, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := exportDB.LookupExportBatch(ctx, eb.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.EndTimestamp.Equal(at) {
+		t.Errorf("expected batch end %v to be %v", got.EndTimestamp, at)
+	}
+	if got.Status != model.ExportBatchPending {
+		t.Errorf("expected batch status %q to be %q", got.Status, model.ExportBatchPending)
+	}
+
+	got, err = exportDB.LookupExportBatch(ctx, rest.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.StartTimestamp.Equal(at) || !got.EndTimestamp.Equal(now.Add(-4*time.Hour)) {
+		t.Errorf("expected remainder [%v, %v) to be [%v, %v)", got.StartTimestamp, got.EndTimestamp, at, now.Add(-4*time.Hour))
+	}
+	if got.Status != model.ExportBatchOpen {
+		t.Errorf("expected remainder status %q to be %q", got.Status, model.ExportBatchOpen)
+	}
+	if got.EffectiveMaxBatchKeys() != maxKeys {
+		t.Errorf("expected remainder max keys %d to be %d", got.EffectiveMaxBatchKeys(), maxKeys)
+	}
+}
+
+func TestDerivedExportConfigs(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := New(testDB)
+	now := time.Now().Truncate(time.Hour)
+
+	parent := &model.ExportConfig{
+		BucketName:   "some-bucket",
+		FilenameRoot: "filename-root",
+		Period:       4 * time.Hour,
+		OutputRegion: "US",
+		From:         now.Add(-24 * time.Hour),
+	}
+	if err := exportDB.AddExportConfig(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+
+	same := &model.ExportConfig{
+		BucketName:     parent.BucketName,
+		FilenameRoot:   parent.FilenameRoot,
+		ParentConfigID: parent.ConfigID,
+	}
+	if err := exportDB.AddExportConfig(ctx, same); err == nil {
+		t.Errorf("expected error for derived export with the parent's filename root")
+	}
+
+	derived := &model.ExportConfig{
+		BucketName:        "partner-bucket",
+		FilenameRoot:      "partner-root",
+		From:              now.Add(-24 * time.Hour),
+		SignatureInfoIDs:  []int64{7},
+		ParentConfigID:    parent.ConfigID,
+		FilterRegions:     []string{"CA"},
+		FilterReportTypes: []string{"confirmed"},
+		ExcludeRevised:    true,
+	}
+	if err := exportDB.AddExportConfig(ctx, derived); err != nil {
+		t.Fatal(err)
+	}
+
+	nested := &model.ExportConfig{
+		BucketName:     "partner-bucket",
+		FilenameRoot:   "nested-root",
+		ParentConfigID: derived.ConfigID,
+	}
+	if err := exportDB.AddExportConfig(ctx, nested); err == nil {
+		t.Errorf("expected error for export derived from a derived export")
+	}
+
+	parent.ParentConfigID = derived.ConfigID
+	if err := exportDB.UpdateExportConfig(ctx, parent); err == nil {
+		t.Errorf("expected error for deriving an export that has derived exports")
+	}
+	parent.ParentConfigID = 0
+
+	got, err := exportDB.ListDerivedExportConfigs(ctx, parent.ConfigID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.ExportConfig{derived}, got, database.ApproxTime); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	pb := &model.ExportBatch{
+		BatchID:        42,
+		ConfigID:       parent.ConfigID,
+		StartTimestamp: now.Add(-8 * time.Hour),
+		EndTimestamp:   now.Add(-4 * time.Hour),
+		OutputRegion:   parent.OutputRegion,
+	}
+	eb, err := exportDB.AddDerivedBatch(ctx, pb, derived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eb.ConfigID != derived.ConfigID || eb.FilenameRoot != derived.FilenameRoot {
+		t.Errorf("expected derived batch for config %d, got %d", derived.ConfigID, eb.ConfigID)
+	}
+	if eb.Status != model.ExportBatchPending {
+		t.Errorf("expected derived batch status %q to be %q", eb.Status, model.ExportBatchPending)
+	}
+
+	// Derived batches are never leased.
+	leased, err := exportDB.LeaseBatch(ctx, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leased != nil {
+		t.Errorf("expected no batch to lease, got %d", leased.BatchID)
+	}
+
+	// Adding it again returns the same batch.
+	again, err := exportDB.AddDerivedBatch(ctx, pb, derived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.BatchID != eb.BatchID {
+		t.Errorf("expected batch %d to be reused, got %d", eb.BatchID, again.BatchID)
+	}
+}
+
 func TestTravelerKeys(t *testing.T) {
 	t.Parallel()
 
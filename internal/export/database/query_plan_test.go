@@ -0,0 +1,48 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+)
+
+// TestQueryPlans guards the batch leasing and file cleanup queries against
+// sequential scans, which a migration could introduce by dropping or changing
+// an index.
+func TestQueryPlans(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	now := time.Now().UTC()
+
+	t.Run("lease_batch", func(t *testing.T) {
+		t.Parallel()
+
+		database.RequireIndexed(t, testDB, []string{"exportbatch"}, leaseBatchesSQL,
+			model.ExportBatchOpen, model.ExportBatchPending, now)
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		t.Parallel()
+
+		database.RequireIndexed(t, testDB, []string{"exportbatch", "exportfile"}, deleteFilesBeforeSQL,
+			now.Add(-14*24*time.Hour), model.ExportBatchDeleted, model.ExportBatchDeletePending)
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/pkg/keys"
+)
+
+const (
+	// EncryptedFilenameSuffix is appended to the name of encrypted export files.
+	EncryptedFilenameSuffix = ".enc"
+
+	// ContentTypeEncrypted is the content type of encrypted export files.
+	ContentTypeEncrypted = "application/octet-stream"
+
+	dataKeyLength = 32
+)
+
+// encryptedHeader identifies the format of an encrypted export file. It is also
+// the additional authenticated data for the file contents.
+var encryptedHeader = []byte("ENEXPORT1")
+
+// EncryptExportFile encrypts an export file for a private partner channel. The
+// contents are encrypted with a random AES-256-GCM data key, and that data key
+// is encrypted with keyID in the key manager. Partners need decrypt access to
+// keyID to read the file; see DecryptExportFile.
+//
+// The result is the header, the length of the encrypted data key as a 16-bit
+// big-endian integer, the encrypted data key, the nonce, and the ciphertext.
+func EncryptExportFile(ctx context.Context, km keys.KeyManager, keyID string, data []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := km.Encrypt(ctx, keyID, dataKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+	if len(wrappedKey) > 0xffff {
+		return nil, fmt.Errorf("encrypted data key is too long (%d bytes)", len(wrappedKey))
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	var b bytes.Buffer
+	b.Grow(len(encryptedHeader) + 2 + len(wrappedKey) + len(nonce) + len(data) + aead.Overhead())
+	b.Write(encryptedHeader)
+	var keyLen [2]byte
+	binary.BigEndian.PutUint16(keyLen[:], uint16(len(wrappedKey)))
+	b.Write(keyLen[:])
+	b.Write(wrappedKey)
+	b.Write(nonce)
+	b.Write(aead.Seal(nil, nonce, data, encryptedHeader))
+	return b.Bytes(), nil
+}
+
+// DecryptExportFile decrypts a file that was encrypted with EncryptExportFile.
+func DecryptExportFile(ctx context.Context, km keys.KeyManager, keyID string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedHeader) {
+		return nil, fmt.Errorf("not an encrypted export file")
+	}
+	rest := data[len(encryptedHeader):]
+
+	if len(rest) < 2 {
+		return nil, fmt.Errorf("encrypted export file is truncated")
+	}
+	keyLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < keyLen {
+		return nil, fmt.Errorf("encrypted export file is truncated")
+	}
+	wrappedKey, rest := rest[:keyLen], rest[keyLen:]
+
+	dataKey, err := km.Decrypt(ctx, keyID, wrappedKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted export file is truncated")
+	}
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, encryptedHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt export file: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+	return aead, nil
+}
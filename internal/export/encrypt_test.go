@@ -0,0 +1,82 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+)
+
+func TestEncryptExportFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	km := keys.TestKeyManager(t)
+	keyID := keys.TestEncryptionKey(t, km)
+
+	plaintext := []byte("export contents")
+
+	encrypted, err := EncryptExportFile(ctx, km, keyID, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, plaintext) {
+		t.Fatalf("expected encrypted file to not contain plaintext")
+	}
+
+	t.Run("round_trip", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := DecryptExportFile(ctx, km, keyID, encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("expected %q to be %q", got, plaintext)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		t.Parallel()
+
+		tampered := append([]byte(nil), encrypted...)
+		tampered[len(tampered)-1] ^= 0xff
+		if _, err := DecryptExportFile(ctx, km, keyID, tampered); err == nil {
+			t.Errorf("expected error decrypting tampered file")
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		for _, n := range []int{0, len(encryptedHeader), len(encryptedHeader) + 3} {
+			if _, err := DecryptExportFile(ctx, km, keyID, encrypted[:n]); err == nil {
+				t.Errorf("expected error decrypting %d bytes", n)
+			}
+		}
+	})
+
+	t.Run("wrong_key", func(t *testing.T) {
+		t.Parallel()
+
+		otherKeyID := keys.TestEncryptionKey(t, km)
+		if _, err := DecryptExportFile(ctx, km, otherKeyID, encrypted); err == nil {
+			t.Errorf("expected error decrypting with a different key")
+		}
+	})
+}
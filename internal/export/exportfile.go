@@ -20,8 +20,10 @@ import (
 	"crypto"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"path"
 	"sort"
 
 	"github.com/google/exposure-notifications-server/internal/export/model"
@@ -39,8 +41,113 @@ const (
 	defaultIntervalCount = 144
 	// http://oid-info.com/get/1.2.840.10045.4.3.2
 	algorithm = "1.2.840.10045.4.3.2"
+
+	// Export files are read from third parties, so unmarshaling bounds the
+	// memory a malformed or malicious archive can make it allocate. The export
+	// binary of the largest export, 500000 keys, is about 20MB.
+	maxArchiveFiles        = 8
+	maxExportBinarySize    = 64 << 20
+	maxExportSignatureSize = 1 << 20
+	maxCompressionRatio    = 100
+
+	// Small files can compress well without being a risk, so the compression
+	// ratio is only checked for files larger than this.
+	minRatioCheckSize = 64 << 10
+)
+
+// Reasons an export archive is rejected by its ArchiveLimits.
+const (
+	ArchiveReasonFileCount        = "file_count"
+	ArchiveReasonUncompressedSize = "uncompressed_size"
+	ArchiveReasonCompressionRatio = "compression_ratio"
+	ArchiveReasonNestedArchive    = "nested_archive"
 )
 
+var (
+	// ErrArchiveTooLarge is returned when an export archive, or a file in it,
+	// is over the limits of unmarshaling.
+	ErrArchiveTooLarge = errors.New("export archive too large")
+
+	// ErrNestedArchive is returned when an export archive contains another
+	// archive.
+	ErrNestedArchive = errors.New("export archive contains an archive")
+)
+
+// nestedArchiveExts are the extensions of files in an export archive that are
+// treated as archives themselves.
+var nestedArchiveExts = map[string]struct{}{
+	".zip": {},
+	".gz":  {},
+	".tgz": {},
+	".tar": {},
+	".bz2": {},
+	".xz":  {},
+	".7z":  {},
+}
+
+// nestedArchiveMagic are the leading bytes of zip and gzip content.
+var nestedArchiveMagic = [][]byte{
+	[]byte("PK\x03\x04"),
+	{0x1f, 0x8b},
+}
+
+// ArchiveLimits bounds the export archives that are unmarshaled. A zero limit
+// is the default limit.
+type ArchiveLimits struct {
+	// MaxFiles is the number of files an archive may have.
+	MaxFiles int
+	// MaxUncompressedSize is the total uncompressed size of the files in an
+	// archive, in bytes.
+	MaxUncompressedSize int64
+	// MaxCompressionRatio is the ratio of the uncompressed to the compressed
+	// size a file in an archive may have.
+	MaxCompressionRatio float64
+}
+
+// DefaultArchiveLimits are the limits of UnmarshalExportFile and
+// UnmarshalSignatureFile.
+var DefaultArchiveLimits = ArchiveLimits{
+	MaxFiles:            maxArchiveFiles,
+	MaxUncompressedSize: maxExportBinarySize + maxExportSignatureSize,
+	MaxCompressionRatio: maxCompressionRatio,
+}
+
+func (l ArchiveLimits) withDefaults() ArchiveLimits {
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = DefaultArchiveLimits.MaxFiles
+	}
+	if l.MaxUncompressedSize <= 0 {
+		l.MaxUncompressedSize = DefaultArchiveLimits.MaxUncompressedSize
+	}
+	if l.MaxCompressionRatio <= 0 {
+		l.MaxCompressionRatio = DefaultArchiveLimits.MaxCompressionRatio
+	}
+	return l
+}
+
+// ArchiveLimitError is returned when an export archive is rejected by its
+// ArchiveLimits. It wraps ErrArchiveTooLarge or ErrNestedArchive.
+type ArchiveLimitError struct {
+	// Reason is one of the ArchiveReason constants.
+	Reason string
+	Err    error
+}
+
+func (e *ArchiveLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ArchiveLimitError) Unwrap() error {
+	return e.Err
+}
+
+func archiveLimitError(reason string, err error, format string, args ...interface{}) error {
+	return &ArchiveLimitError{
+		Reason: reason,
+		Err:    fmt.Errorf("%w: "+format, append([]interface{}{err}, args...)...),
+	}
+}
+
 var (
 	fixedHeader      = []byte("EK Export v1    ")
 	fixedHeaderWidth = 16
@@ -97,34 +204,38 @@ func MarshalExportFile(eb *model.ExportBatch, exposures, revisedExposures []*pub
 // The digest is useful in validating the signature as it returns the deigest of the content that
 // was signed when the archive was created.
 func UnmarshalExportFile(zippedProtoPayload []byte) (*export.TemporaryExposureKeyExport, []byte, error) {
-	zp, err := zip.NewReader(bytes.NewReader(zippedProtoPayload), int64(len(zippedProtoPayload)))
+	return UnmarshalExportFileWithLimits(zippedProtoPayload, DefaultArchiveLimits)
+}
+
+// UnmarshalExportFileWithLimits is UnmarshalExportFile with the limits the
+// archive is checked against.
+func UnmarshalExportFileWithLimits(zippedProtoPayload []byte, limits ArchiveLimits) (*export.TemporaryExposureKeyExport, []byte, error) {
+	limits = limits.withDefaults()
+	zp, err := openArchive(zippedProtoPayload, limits)
 	if err != nil {
-		return nil, nil, fmt.Errorf("can't read payload: %w", err)
+		return nil, nil, err
 	}
 
 	for _, file := range zp.File {
 		if file.Name == exportBinaryName {
-			return unmarshalContent(file)
+			return unmarshalContent(file, limits)
 		}
 	}
 
 	return nil, nil, fmt.Errorf("payload is invalid: no %v file was found", exportBinaryName)
 }
 
-func unmarshalContent(file *zip.File) (*export.TemporaryExposureKeyExport, []byte, error) {
-	f, err := file.Open()
-	if err != nil {
-		return nil, nil, err
-	}
-	defer f.Close()
-
-	content, err := io.ReadAll(f)
+func unmarshalContent(file *zip.File, limits ArchiveLimits) (*export.TemporaryExposureKeyExport, []byte, error) {
+	content, err := readArchiveFile(file, maxExportBinarySize, limits)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	digest := sha256.Sum256(content)
 
+	if len(content) < fixedHeaderWidth {
+		return nil, nil, fmt.Errorf("content is too short: %d bytes", len(content))
+	}
 	prefix := content[:fixedHeaderWidth]
 	if !bytes.Equal(prefix, fixedHeader) {
 		return nil, nil, fmt.Errorf("unknown prefix: %v", string(prefix))
@@ -156,6 +267,9 @@ func makeTEK(exp *publishmodel.Exposure) *export.TemporaryExposureKey {
 	if exp.IntervalCount != defaultIntervalCount {
 		pbek.RollingPeriod = proto.Int32(exp.IntervalCount)
 	}
+	// Key metadata was validated when it was captured. If it can't be read
+	// anyway, the key is still exported without it.
+	_ = publishmodel.ApplyKeyMetadata(&pbek, exp.KeyMetadata)
 	return &pbek
 }
 
@@ -251,39 +365,119 @@ func createSignatureInfo(si *model.SignatureInfo) *export.SignatureInfo {
 
 // UnmarshalSignatureFile extracts the protobuf encode dsignatures.
 func UnmarshalSignatureFile(zippedProtoPayload []byte) (*export.TEKSignatureList, error) {
-	zp, err := zip.NewReader(bytes.NewReader(zippedProtoPayload), int64(len(zippedProtoPayload)))
+	return UnmarshalSignatureFileWithLimits(zippedProtoPayload, DefaultArchiveLimits)
+}
+
+// UnmarshalSignatureFileWithLimits is UnmarshalSignatureFile with the limits
+// the archive is checked against.
+func UnmarshalSignatureFileWithLimits(zippedProtoPayload []byte, limits ArchiveLimits) (*export.TEKSignatureList, error) {
+	limits = limits.withDefaults()
+	zp, err := openArchive(zippedProtoPayload, limits)
 	if err != nil {
-		return nil, fmt.Errorf("can't read payload: %w", err)
+		return nil, err
 	}
 
 	for _, file := range zp.File {
 		if file.Name == exportSignatureName {
-			return unmarshalSignatureContent(file)
+			return unmarshalSignatureContent(file, limits)
 		}
 	}
 
-	return nil, fmt.Errorf("payload is invalid: no %v file was found", exportBinaryName)
+	return nil, fmt.Errorf("payload is invalid: no %v file was found", exportSignatureName)
 }
 
-func unmarshalSignatureContent(file *zip.File) (*export.TEKSignatureList, error) {
-	f, err := file.Open()
+func unmarshalSignatureContent(file *zip.File, limits ArchiveLimits) (*export.TEKSignatureList, error) {
+	content, err := readArchiveFile(file, maxExportSignatureSize, limits)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
-	content, err := io.ReadAll(f)
+	message := new(export.TEKSignatureList)
+	err = proto.Unmarshal(content, message)
 	if err != nil {
 		return nil, err
 	}
 
-	message := new(export.TEKSignatureList)
-	err = proto.Unmarshal(content, message)
+	return message, nil
+}
+
+// openArchive opens the zip archive of an export file and checks the sizes
+// its directory claims against the limits. Export archives have two files, so
+// archives with many more are rejected.
+func openArchive(payload []byte, limits ArchiveLimits) (*zip.Reader, error) {
+	zp, err := zip.NewReader(bytes.NewReader(payload), int64(len(payload)))
+	if err != nil {
+		return nil, fmt.Errorf("can't read payload: %w", err)
+	}
+	if n := len(zp.File); n > limits.MaxFiles {
+		return nil, archiveLimitError(ArchiveReasonFileCount, ErrArchiveTooLarge,
+			"%d files, the limit is %d", n, limits.MaxFiles)
+	}
+
+	var total uint64
+	for _, file := range zp.File {
+		if _, ok := nestedArchiveExts[path.Ext(file.Name)]; ok {
+			return nil, archiveLimitError(ArchiveReasonNestedArchive, ErrNestedArchive, "%s", file.Name)
+		}
+		if err := checkCompressionRatio(file, file.UncompressedSize64, limits); err != nil {
+			return nil, err
+		}
+		total += file.UncompressedSize64
+		if total > uint64(limits.MaxUncompressedSize) {
+			return nil, archiveLimitError(ArchiveReasonUncompressedSize, ErrArchiveTooLarge,
+				"files are over %d bytes", limits.MaxUncompressedSize)
+		}
+	}
+	return zp, nil
+}
+
+// checkCompressionRatio returns an error if a file in an export archive that
+// is size bytes uncompressed is over the compression ratio limit.
+func checkCompressionRatio(file *zip.File, size uint64, limits ArchiveLimits) error {
+	if size <= minRatioCheckSize {
+		return nil
+	}
+	if file.CompressedSize64 == 0 || float64(size)/float64(file.CompressedSize64) > limits.MaxCompressionRatio {
+		return archiveLimitError(ArchiveReasonCompressionRatio, ErrArchiveTooLarge,
+			"%s is %d bytes compressed to %d, the ratio limit is %v", file.Name, size, file.CompressedSize64, limits.MaxCompressionRatio)
+	}
+	return nil
+}
+
+// readArchiveFile returns the uncompressed contents of a file in an export
+// archive, if they are at most limit bytes. The size in the archive is
+// checked before the file is read, and the read is bounded in case the size
+// is wrong. The compression ratio and nesting are checked again on the
+// contents that were read.
+func readArchiveFile(file *zip.File, limit int64, limits ArchiveLimits) ([]byte, error) {
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, archiveLimitError(ArchiveReasonUncompressedSize, ErrArchiveTooLarge,
+			"%s is %d bytes, the limit is %d", file.Name, file.UncompressedSize64, limit)
+	}
+
+	f, err := file.Open()
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	return message, nil
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, archiveLimitError(ArchiveReasonUncompressedSize, ErrArchiveTooLarge,
+			"%s is over %d bytes", file.Name, limit)
+	}
+	if err := checkCompressionRatio(file, uint64(len(content)), limits); err != nil {
+		return nil, err
+	}
+	for _, magic := range nestedArchiveMagic {
+		if bytes.HasPrefix(content, magic) {
+			return nil, archiveLimitError(ArchiveReasonNestedArchive, ErrNestedArchive, "%s", file.Name)
+		}
+	}
+	return content, nil
 }
 
 func marshalSignature(exportContents []byte, signers []*Signer) ([]byte, error) {
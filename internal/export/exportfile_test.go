@@ -15,9 +15,17 @@
 package export
 
 import (
+	"archive/zip"
+	"bytes"
 	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,6 +36,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -197,3 +206,374 @@ func (s *customTestSigner) Public() crypto.PublicKey { return s.pub }
 func (s *customTestSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
 	return s.sig, nil
 }
+
+func TestMakeTEK_KeyMetadata(t *testing.T) {
+	t.Parallel()
+
+	// A key from a file written with a later revision of the proto, which
+	// has a field that this server does not know about.
+	in := &export.TemporaryExposureKey{
+		KeyData:          []byte("ABC"),
+		VariantOfConcern: export.TemporaryExposureKey_VARIANT_TYPE_3.Enum(),
+	}
+	unknown := protowire.AppendVarint(protowire.AppendTag(nil, 9, protowire.VarintType), 2)
+	in.ProtoReflect().SetUnknown(unknown)
+
+	metadata, err := publishmodel.KeyMetadataFromExportKey(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		metadata    []byte
+		wantVariant export.TemporaryExposureKey_VariantOfConcern
+		wantUnknown []byte
+	}{
+		{
+			name:        "metadata",
+			metadata:    metadata,
+			wantVariant: export.TemporaryExposureKey_VARIANT_TYPE_3,
+			wantUnknown: unknown,
+		},
+		{
+			name: "none",
+		},
+		{
+			name:     "invalid",
+			metadata: []byte{0xff},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := proto.Marshal(makeTEK(&publishmodel.Exposure{
+				ExposureKey:    []byte("ABC"),
+				IntervalNumber: 18,
+				IntervalCount:  144,
+				KeyMetadata:    tc.metadata,
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got export.TemporaryExposureKey
+			if err := proto.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.GetVariantOfConcern() != tc.wantVariant {
+				t.Errorf("expected variant of concern %v, got %v", tc.wantVariant, got.GetVariantOfConcern())
+			}
+			if unknown := got.ProtoReflect().GetUnknown(); !bytes.Equal(unknown, tc.wantUnknown) {
+				t.Errorf("expected unknown fields %x, got %x", tc.wantUnknown, unknown)
+			}
+			if !bytes.Equal(got.KeyData, []byte("ABC")) {
+				t.Errorf("expected key data to be kept, got %x", got.KeyData)
+			}
+		})
+	}
+}
+
+// testExportFile returns a small, valid export file.
+func testExportFile(tb testing.TB) []byte {
+	tb.Helper()
+
+	now := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	batch := &model.ExportBatch{
+		BatchID:        1,
+		ConfigID:       1,
+		StartTimestamp: now,
+		EndTimestamp:   now.Add(time.Hour),
+		OutputRegion:   "US",
+	}
+	exposures := []*publishmodel.Exposure{
+		{
+			ExposureKey:      []byte("ABCDEFGHIJKLMNOP"),
+			IntervalNumber:   18,
+			IntervalCount:    144,
+			TransmissionRisk: 2,
+			ReportType:       verifyapi.ReportTypeConfirmed,
+		},
+	}
+	signers := []*Signer{
+		{
+			SignatureInfo: &model.SignatureInfo{SigningKeyID: "310", SigningKeyVersion: "1"},
+			Signer:        &customTestSigner{sig: []byte("signature")},
+		},
+	}
+
+	blob, err := MarshalExportFile(batch, exposures, nil, 1, false, signers)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return blob
+}
+
+// zipFiles returns a zip archive of the files, by name.
+func zipFiles(tb testing.TB, files map[string][]byte) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUnmarshalExportFile_Malformed(t *testing.T) {
+	t.Parallel()
+
+	manyFiles := make(map[string][]byte)
+	for i := 0; i <= maxArchiveFiles; i++ {
+		manyFiles[fmt.Sprintf("file%d", i)] = nil
+	}
+
+	cases := []struct {
+		name    string
+		payload []byte
+		err     string
+	}{
+		{
+			name:    "not_zip",
+			payload: []byte("not a zip"),
+			err:     "can't read payload",
+		},
+		{
+			name:    "short_content",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: []byte("EK")}),
+			err:     "content is too short",
+		},
+		{
+			name:    "too_many_files",
+			payload: zipFiles(t, manyFiles),
+			err:     ErrArchiveTooLarge.Error(),
+		},
+		{
+			name:    "too_large",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: make([]byte, maxExportBinarySize+1)}),
+			err:     ErrArchiveTooLarge.Error(),
+		},
+		{
+			name:    "missing_binary",
+			payload: zipFiles(t, map[string][]byte{exportSignatureName: nil}),
+			err:     "no export.bin file was found",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := UnmarshalExportFile(tc.payload)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSignatureFile_Malformed(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		payload []byte
+		err     string
+	}{
+		{
+			name:    "too_large",
+			payload: zipFiles(t, map[string][]byte{exportSignatureName: make([]byte, maxExportSignatureSize+1)}),
+			err:     ErrArchiveTooLarge.Error(),
+		},
+		{
+			name:    "missing_signature",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: nil}),
+			err:     "no export.sig file was found",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := UnmarshalSignatureFile(tc.payload)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestReadArchiveFile_WrongSize(t *testing.T) {
+	t.Parallel()
+
+	// The archive claims the file is smaller than it is.
+	data := bytes.Repeat([]byte("a"), 100)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               exportBinaryName,
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zp, err := openArchive(buf.Bytes(), DefaultArchiveLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readArchiveFile(zp.File[0], 50, DefaultArchiveLimits); err == nil {
+		t.Errorf("expected error reading file larger than its size")
+	}
+}
+
+func TestUnmarshalExportFileWithLimits(t *testing.T) {
+	t.Parallel()
+
+	valid := testExportFile(t)
+
+	// Random data doesn't compress, so it stays under the ratio limit.
+	random := make([]byte, 2*minRatioCheckSize)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		payload []byte
+		limits  ArchiveLimits
+		reason  string
+		err     error
+	}{
+		{
+			name:    "valid",
+			payload: valid,
+		},
+		{
+			name:    "file_count",
+			payload: valid,
+			limits:  ArchiveLimits{MaxFiles: 1},
+			reason:  ArchiveReasonFileCount,
+			err:     ErrArchiveTooLarge,
+		},
+		{
+			name:    "uncompressed_size",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: random}),
+			limits:  ArchiveLimits{MaxUncompressedSize: minRatioCheckSize},
+			reason:  ArchiveReasonUncompressedSize,
+			err:     ErrArchiveTooLarge,
+		},
+		{
+			name:    "compression_ratio",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: make([]byte, 2*minRatioCheckSize)}),
+			reason:  ArchiveReasonCompressionRatio,
+			err:     ErrArchiveTooLarge,
+		},
+		{
+			name:    "compression_ratio_small_file",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: append([]byte("EK Export v1    "), make([]byte, 1024)...)}),
+		},
+		{
+			name:    "nested_name",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: nil, "inner.zip": nil}),
+			reason:  ArchiveReasonNestedArchive,
+			err:     ErrNestedArchive,
+		},
+		{
+			name:    "nested_content",
+			payload: zipFiles(t, map[string][]byte{exportBinaryName: valid}),
+			reason:  ArchiveReasonNestedArchive,
+			err:     ErrNestedArchive,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := UnmarshalExportFileWithLimits(tc.payload, tc.limits)
+			if tc.err == nil {
+				// Only the limits are under test, so a file that isn't an
+				// export is fine.
+				var lerr *ArchiveLimitError
+				if errors.As(err, &lerr) {
+					t.Fatalf("unexpected limit error: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			var lerr *ArchiveLimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("expected *ArchiveLimitError, got %T", err)
+			}
+			if got, want := lerr.Reason, tc.reason; got != want {
+				t.Errorf("expected reason %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func FuzzUnmarshalExportFile(f *testing.F) {
+	f.Add(testExportFile(f))
+	f.Add(zipFiles(f, map[string][]byte{exportBinaryName: []byte("EK Export v1    ")}))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		got, digest, err := UnmarshalExportFile(payload)
+		if err != nil {
+			return
+		}
+		if got == nil || len(digest) != sha256.Size {
+			t.Errorf("expected export and digest, got %v, %x", got, digest)
+		}
+	})
+}
+
+func FuzzUnmarshalSignatureFile(f *testing.F) {
+	f.Add(testExportFile(f))
+	f.Add(zipFiles(f, map[string][]byte{exportSignatureName: nil}))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		got, err := UnmarshalSignatureFile(payload)
+		if err != nil {
+			return
+		}
+		if got == nil {
+			t.Errorf("expected signature list")
+		}
+	})
+}
@@ -39,6 +39,12 @@ var (
 	mBatcherCreated        = stats.Int64(metricPrefix+"/batches_created", "Number of export batchers created", stats.UnitDimensionless)
 	mWorkerBadKeyLength    = stats.Int64(metricPrefix+"/worker_bad_key_length", "Number of dropped keys caused by bad key length", stats.UnitDimensionless)
 	mExportBatchCompletion = stats.Int64(metricPrefix+"/batch_completion", "Number of batches complete by output region", stats.UnitDimensionless)
+	mWorkerBatchSplit      = stats.Int64(metricPrefix+"/worker_batch_split", "Number of batches closed early because of the key cap", stats.UnitDimensionless)
+
+	mWorkerMemorySplit       = stats.Int64(metricPrefix+"/worker_memory_split", "Number of batches written to more files because of the memory budget", stats.UnitDimensionless)
+	mWorkerNoiseKeys         = stats.Int64(metricPrefix+"/worker_noise_keys", "Number of noise keys added to exports", stats.UnitDimensionless)
+	mWorkerSmallBatchSkipped = stats.Int64(metricPrefix+"/worker_small_batch_skipped", "Number of batches exported without files because they were below the minimum", stats.UnitDimensionless)
+	mWorkerDerivedBatches    = stats.Int64(metricPrefix+"/worker_derived_batches", "Number of derived batches written from a parent batch", stats.UnitDimensionless)
 )
 
 func init() {
@@ -80,5 +86,35 @@ func init() {
 			Aggregation: view.Sum(),
 			TagKeys:     []tag.Key{ExportConfigIDTagKey, ExportRegionTagKey, ExportTravelersTagKey},
 		},
+		{
+			Name:        metricPrefix + "/worker_batch_split",
+			Description: "Number of batches closed early because of the key cap",
+			Measure:     mWorkerBatchSplit,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/worker_memory_split",
+			Description: "Number of batches written to more files because of the memory budget",
+			Measure:     mWorkerMemorySplit,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/worker_noise_keys",
+			Description: "Total number of noise keys added to exports",
+			Measure:     mWorkerNoiseKeys,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/worker_small_batch_skipped",
+			Description: "Number of batches exported without files because they were below the minimum",
+			Measure:     mWorkerSmallBatchSkipped,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/worker_derived_batches",
+			Description: "Number of derived batches written from a parent batch",
+			Measure:     mWorkerDerivedBatches,
+			Aggregation: view.Count(),
+		},
 	}...)
 }
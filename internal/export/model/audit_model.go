@@ -0,0 +1,98 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+// Kinds of drift found by an export audit.
+const (
+	// AuditMissing is a file that should be stored but is not.
+	AuditMissing = "missing"
+
+	// AuditOrphan is a stored file that is not a current export file.
+	AuditOrphan = "orphan"
+
+	// AuditSizeMismatch is a stored file whose size is not the size it was
+	// written with.
+	AuditSizeMismatch = "size_mismatch"
+)
+
+// AuditKinds are the kinds of drift, in the order they are reported.
+var AuditKinds = []string{AuditMissing, AuditOrphan, AuditSizeMismatch}
+
+// Repairs of findings.
+const (
+	// AuditRepairDeleted is an orphan that was deleted.
+	AuditRepairDeleted = "deleted"
+
+	// AuditRepairReexport is a file whose batch was reopened to be exported
+	// again.
+	AuditRepairReexport = "reexport"
+
+	// AuditRepairFailed is a finding whose repair failed.
+	AuditRepairFailed = "failed"
+)
+
+// ExportAudit is the result of reconciling the export files of a location,
+// a bucket and filename root, with what is stored there.
+type ExportAudit struct {
+	BucketName   string
+	FilenameRoot string
+
+	// ConfigIDs are the export configs that write to the location.
+	ConfigIDs []int64
+
+	RunID     string
+	AuditedAt time.Time
+
+	// Files is the number of files that should be stored, and Objects the
+	// number of objects that were found.
+	Files   int
+	Objects int
+
+	// Counts are the number of findings of each kind. Findings may be capped
+	// when stored, the counts are not.
+	Counts map[string]int
+
+	Findings []*ExportAuditFinding
+}
+
+// Drift returns the total number of findings.
+func (a *ExportAudit) Drift() int {
+	n := 0
+	for _, c := range a.Counts {
+		n += c
+	}
+	return n
+}
+
+// ExportAuditFinding is a difference between the export files in the
+// database and the stored objects.
+type ExportAuditFinding struct {
+	ID       int64
+	Filename string
+	Kind     string
+
+	// BatchID is the batch of the file, or 0 for orphans that have no file.
+	BatchID int64
+
+	// ExpectedSize and ActualSize are the recorded and stored sizes, or 0 if
+	// unknown.
+	ExpectedSize int64
+	ActualSize   int64
+
+	// Repair is how the finding was repaired, if it was.
+	Repair string
+}
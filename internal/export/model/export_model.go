@@ -17,8 +17,12 @@ package model
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 )
 
 var (
@@ -33,6 +37,39 @@ const (
 	oneDay = 24 * time.Hour
 )
 
+// ProvenanceFilenameSuffix is appended to the name of an export file to name
+// the provenance record that is written next to it when export provenance is
+// enabled.
+const ProvenanceFilenameSuffix = ".provenance.json"
+
+// SmallBatchPolicy controls what is exported for a batch with fewer keys than
+// the minimum number of records.
+type SmallBatchPolicy string
+
+const (
+	// SmallBatchPad pads the batch with generated keys up to the minimum. This
+	// is the default.
+	SmallBatchPad SmallBatchPolicy = "PAD"
+
+	// SmallBatchSkip completes the batch without creating any files. The keys in
+	// the batch are not exported by this config.
+	SmallBatchSkip SmallBatchPolicy = "SKIP"
+
+	// SmallBatchNormal exports the batch as-is, without padding.
+	SmallBatchNormal SmallBatchPolicy = "NORMAL"
+)
+
+// Validate returns an error if the policy is not a known policy. The empty
+// policy is valid and means SmallBatchPad.
+func (p SmallBatchPolicy) Validate() error {
+	switch p {
+	case "", SmallBatchPad, SmallBatchSkip, SmallBatchNormal:
+		return nil
+	default:
+		return fmt.Errorf("unknown small batch policy %q", p)
+	}
+}
+
 // ExportConfig describes what goes into an export, and how frequently.
 // These are used to periodically generate an ExportBatch.
 type ExportConfig struct {
@@ -49,6 +86,146 @@ type ExportConfig struct {
 	Thru               time.Time
 	SignatureInfoIDs   []int64
 	MaxRecordsOverride *int
+
+	// MaxBatchKeys, if set, caps the number of new keys in a single batch. A
+	// batch that would exceed it is closed early, and the rest of its time
+	// window is exported as a new batch.
+	MaxBatchKeys *int
+
+	// MinRecordsOverride, if set, overrides the minimum number of keys in a
+	// batch. SmallBatchPolicy controls what happens to batches below it.
+	MinRecordsOverride *int
+	SmallBatchPolicy   SmallBatchPolicy
+
+	// EncryptionKeyID, if set, is the key manager key used to encrypt the
+	// export files. It is meant for exports that are only for a partner and not
+	// for public clients.
+	EncryptionKeyID string
+
+	// IncludeJurisdictions, if set, limits the export to keys verified by
+	// health authorities in these jurisdictions. ExcludeJurisdictions removes
+	// keys verified by health authorities in these jurisdictions.
+	IncludeJurisdictions []string
+	ExcludeJurisdictions []string
+
+	// ParentConfigID, if set, makes this a derived export. A derived export
+	// does not have batches of its own: each batch of the parent config is
+	// filtered and written again to this config's bucket and filename root,
+	// reusing the keys the parent already read. The parent's period and input
+	// settings apply.
+	ParentConfigID int64
+
+	// FilterRegions, if set, limits a derived export to keys with at least one
+	// of these regions. FilterReportTypes, if set, limits it to keys with one
+	// of these report types. ExcludeRevised drops revised keys.
+	FilterRegions     []string
+	FilterReportTypes []string
+	ExcludeRevised    bool
+
+	// TestData makes this a test export. A test export only includes keys
+	// verified by health authorities (or keys) that are still onboarding, and
+	// those keys are never in any other export.
+	TestData bool
+
+	// CacheControl, if set, is the Cache-Control of the export files in place
+	// of the blobstore's default. Index files are never cached.
+	CacheControl string
+
+	// StorageClass, if set, is the storage class of the objects of the export,
+	// in the terms of the blobstore.
+	StorageClass string
+
+	// ObjectMetadata is custom metadata set on the objects of the export, in
+	// addition to the metadata that describes each file.
+	ObjectMetadata map[string]string
+}
+
+// Metadata keys that the exporter sets on the objects it writes, which can't
+// be set in ObjectMetadata.
+const (
+	MetadataBatchID         = "batch_id"
+	MetadataBatchRunID      = "batch_run_id"
+	MetadataRunID           = "run_id"
+	MetadataKeyCount        = "key_count"
+	MetadataSignatureKeyIDs = "signature_key_ids"
+)
+
+var reservedMetadataKeys = []string{
+	MetadataBatchID, MetadataBatchRunID, MetadataRunID, MetadataKeyCount, MetadataSignatureKeyIDs,
+}
+
+const (
+	maxCacheControlLength  = 200
+	maxStorageClassLength  = 50
+	maxObjectMetadataKeys  = 16
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 256
+)
+
+// validateObjectOptions validates the options of the objects of the export.
+func (ec *ExportConfig) validateObjectOptions() error {
+	if len(ec.CacheControl) > maxCacheControlLength {
+		return fmt.Errorf("cache control cannot be longer than %d characters", maxCacheControlLength)
+	}
+	if !isPrintableASCII(ec.CacheControl) {
+		return errors.New("cache control can only contain printable ASCII characters")
+	}
+	if len(ec.StorageClass) > maxStorageClassLength {
+		return fmt.Errorf("storage class cannot be longer than %d characters", maxStorageClassLength)
+	}
+	for _, r := range ec.StorageClass {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return fmt.Errorf("invalid storage class %q", ec.StorageClass)
+		}
+	}
+	if len(ec.ObjectMetadata) > maxObjectMetadataKeys {
+		return fmt.Errorf("at most %d object metadata keys can be set", maxObjectMetadataKeys)
+	}
+	for k, v := range ec.ObjectMetadata {
+		if !isMetadataKey(k) {
+			return fmt.Errorf("invalid object metadata key %q, keys are lowercase letters, digits and '_'", k)
+		}
+		for _, reserved := range reservedMetadataKeys {
+			if k == reserved {
+				return fmt.Errorf("object metadata key %q is set by the exporter", k)
+			}
+		}
+		if len(v) > maxMetadataValueLength || !isPrintableASCII(v) {
+			return fmt.Errorf("invalid value of object metadata key %q", k)
+		}
+	}
+	return nil
+}
+
+// isMetadataKey returns true if k can be a custom metadata key in all
+// blobstores. Azure only allows identifiers.
+func isMetadataKey(k string) bool {
+	if k == "" || len(k) > maxMetadataKeyLength {
+		return false
+	}
+	for i, r := range k {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case (r >= '0' && r <= '9' || r == '_') && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isPrintableASCII(s string) bool {
+	for _, r := range s {
+		if r < ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDerived returns true if the config is derived from a parent config.
+func (ec *ExportConfig) IsDerived() bool {
+	return ec.ParentConfigID != 0
 }
 
 // EffectiveInputRegions either returns `InputRegions` or if that array is
@@ -65,7 +242,43 @@ func (ec *ExportConfig) ExcludeRegionsOnePerLine() string {
 	return strings.Join(ec.ExcludeRegions, "\n")
 }
 
+func (ec *ExportConfig) IncludeJurisdictionsOnePerLine() string {
+	return strings.Join(ec.IncludeJurisdictions, "\n")
+}
+
+func (ec *ExportConfig) ExcludeJurisdictionsOnePerLine() string {
+	return strings.Join(ec.ExcludeJurisdictions, "\n")
+}
+
+func (ec *ExportConfig) FilterRegionsOnePerLine() string {
+	return strings.Join(ec.FilterRegions, "\n")
+}
+
+func (ec *ExportConfig) FilterReportTypesOnePerLine() string {
+	return strings.Join(ec.FilterReportTypes, "\n")
+}
+
+// ObjectMetadataOnePerLine returns the object metadata as key=value lines,
+// sorted by key.
+func (ec *ExportConfig) ObjectMetadataOnePerLine() string {
+	lines := make([]string, 0, len(ec.ObjectMetadata))
+	for k, v := range ec.ObjectMetadata {
+		lines = append(lines, k+"="+v)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
 func (ec *ExportConfig) Validate() error {
+	if err := ec.validateObjectOptions(); err != nil {
+		return err
+	}
+	if ec.IsDerived() {
+		return ec.validateDerived()
+	}
+	if len(ec.FilterRegions) > 0 || len(ec.FilterReportTypes) > 0 || ec.ExcludeRevised {
+		return errors.New("filters can only be set on a derived export")
+	}
 	if ec.Period > oneDay {
 		return errors.New("maximum period is 24h")
 	}
@@ -75,6 +288,50 @@ func (ec *ExportConfig) Validate() error {
 	if int64(oneDay.Seconds())%int64(ec.Period.Seconds()) != 0 {
 		return errors.New("period must divide equally into 24 hours (e.g., 2h, 4h, 12h, 15m, 30m)")
 	}
+	if err := ec.SmallBatchPolicy.Validate(); err != nil {
+		return err
+	}
+	if ec.MinRecordsOverride != nil && *ec.MinRecordsOverride < 0 {
+		return errors.New("min records cannot be negative")
+	}
+	if ec.MaxBatchKeys != nil && *ec.MaxBatchKeys < 0 {
+		return errors.New("max batch keys cannot be negative")
+	}
+	for _, in := range ec.IncludeJurisdictions {
+		for _, ex := range ec.ExcludeJurisdictions {
+			if in == ex {
+				return fmt.Errorf("jurisdiction %q cannot be both included and excluded", in)
+			}
+		}
+	}
+	return nil
+}
+
+// validateDerived validates a derived export. The period, travelers, regions
+// and jurisdictions come from the parent, so they are not checked here.
+func (ec *ExportConfig) validateDerived() error {
+	if ec.ParentConfigID == ec.ConfigID {
+		return errors.New("an export cannot be derived from itself")
+	}
+	if ec.MaxBatchKeys != nil {
+		return errors.New("max batch keys cannot be set on a derived export")
+	}
+	if err := ec.SmallBatchPolicy.Validate(); err != nil {
+		return err
+	}
+	if ec.SmallBatchPolicy == SmallBatchPad {
+		return errors.New("derived exports cannot be padded, use the SKIP or NORMAL small batch policy")
+	}
+	if ec.MinRecordsOverride != nil && *ec.MinRecordsOverride < 0 {
+		return errors.New("min records cannot be negative")
+	}
+	for _, rt := range ec.FilterReportTypes {
+		switch rt {
+		case verifyapi.ReportTypeConfirmed, verifyapi.ReportTypeClinical, verifyapi.ReportTypeNegative, verifyapi.ReportTypeSelfReport:
+		default:
+			return fmt.Errorf("unknown report type %q", rt)
+		}
+	}
 	return nil
 }
 
@@ -95,6 +352,19 @@ type ExportBatch struct {
 	LeaseExpires       time.Time
 	SignatureInfoIDs   []int64
 	MaxRecordsOverride *int
+	MaxBatchKeys       *int
+	MinRecordsOverride *int
+	SmallBatchPolicy   SmallBatchPolicy
+	EncryptionKeyID    string
+
+	IncludeJurisdictions []string
+	ExcludeJurisdictions []string
+
+	TestData bool
+
+	// RunID is the run ID of the batcher run that created the batch, or empty
+	// for batches created before run IDs were recorded.
+	RunID string
 }
 
 // EffectiveMaxRecords returns either the provided value or the override
@@ -108,6 +378,34 @@ func (eb *ExportBatch) EffectiveMaxRecords(systemDefault int) int {
 	return systemDefault
 }
 
+// EffectiveMinRecords returns either the provided value or the override
+// present in this batch. Unlike the max records override, an override of 0
+// is honored and turns off the small batch handling.
+func (eb *ExportBatch) EffectiveMinRecords(systemDefault int) int {
+	if eb.MinRecordsOverride != nil {
+		return *eb.MinRecordsOverride
+	}
+	return systemDefault
+}
+
+// EffectiveSmallBatchPolicy returns the policy for this batch, defaulting to
+// SmallBatchPad.
+func (eb *ExportBatch) EffectiveSmallBatchPolicy() SmallBatchPolicy {
+	if eb.SmallBatchPolicy == "" {
+		return SmallBatchPad
+	}
+	return eb.SmallBatchPolicy
+}
+
+// EffectiveMaxBatchKeys returns the maximum number of new keys in this batch,
+// or 0 if the batch is only bounded by its time window.
+func (eb *ExportBatch) EffectiveMaxBatchKeys() int {
+	if eb.MaxBatchKeys != nil && *eb.MaxBatchKeys > 0 {
+		return *eb.MaxBatchKeys
+	}
+	return 0
+}
+
 // EffectiveInputRegions either returns `InputRegions` or if that array is
 // empty, the output region (`Region`) is returned (in an array).
 func (eb *ExportBatch) EffectiveInputRegions() []string {
@@ -126,6 +424,10 @@ type ExportFile struct {
 	BatchNum         int
 	BatchSize        int
 	Status           string
+
+	// Size is the size of the stored file in bytes, or 0 if it is unknown, as
+	// it is for files written before sizes were recorded.
+	Size int64
 }
 
 // EffectiveInputRegions either returns `InputRegions` or if that array is
@@ -16,6 +16,7 @@ package model
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -78,3 +79,250 @@ func TestEffectiveMaxRecords(t *testing.T) {
 		t.Fatalf("mismatch want: %v got: %v", want, got)
 	}
 }
+
+func TestEffectiveMaxBatchKeys(t *testing.T) {
+	t.Parallel()
+
+	eb := &ExportBatch{}
+	if got, want := eb.EffectiveMaxBatchKeys(), 0; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+
+	zero := 0
+	eb.MaxBatchKeys = &zero
+	if got, want := eb.EffectiveMaxBatchKeys(), 0; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+
+	maxKeys := 500000
+	eb.MaxBatchKeys = &maxKeys
+	if got, want := eb.EffectiveMaxBatchKeys(), maxKeys; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+}
+
+func TestEffectiveMinRecords(t *testing.T) {
+	t.Parallel()
+
+	eb := &ExportBatch{}
+	if got, want := eb.EffectiveMinRecords(1000), 1000; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+
+	minRecords := 50
+	eb.MinRecordsOverride = &minRecords
+	if got, want := eb.EffectiveMinRecords(1000), minRecords; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+
+	// An explicit 0 is not the default.
+	zero := 0
+	eb.MinRecordsOverride = &zero
+	if got, want := eb.EffectiveMinRecords(1000), 0; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+}
+
+func TestEffectiveSmallBatchPolicy(t *testing.T) {
+	t.Parallel()
+
+	eb := &ExportBatch{}
+	if got, want := eb.EffectiveSmallBatchPolicy(), SmallBatchPad; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+
+	eb.SmallBatchPolicy = SmallBatchSkip
+	if got, want := eb.EffectiveSmallBatchPolicy(), SmallBatchSkip; got != want {
+		t.Fatalf("mismatch want: %v got: %v", want, got)
+	}
+}
+
+func TestExportConfigValidate_SmallBatches(t *testing.T) {
+	t.Parallel()
+
+	ec := &ExportConfig{
+		Period:           time.Hour,
+		SmallBatchPolicy: SmallBatchNormal,
+	}
+	if err := ec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ec.SmallBatchPolicy = "BANANA"
+	if err := ec.Validate(); err == nil {
+		t.Errorf("expected error for unknown small batch policy")
+	}
+
+	negative := -1
+	ec.SmallBatchPolicy = ""
+	ec.MinRecordsOverride = &negative
+	if err := ec.Validate(); err == nil {
+		t.Errorf("expected error for negative min records")
+	}
+}
+
+func TestExportConfigValidate_MaxBatchKeys(t *testing.T) {
+	t.Parallel()
+
+	negative := -1
+	ec := &ExportConfig{
+		Period:       time.Hour,
+		MaxBatchKeys: &negative,
+	}
+	if err := ec.Validate(); err == nil {
+		t.Errorf("expected error for negative max batch keys")
+	}
+}
+
+func TestExportConfigValidate_Jurisdictions(t *testing.T) {
+	t.Parallel()
+
+	ec := &ExportConfig{
+		Period:               time.Hour,
+		IncludeJurisdictions: []string{"US", "CA"},
+		ExcludeJurisdictions: []string{"MX"},
+	}
+	if err := ec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ec.ExcludeJurisdictions = append(ec.ExcludeJurisdictions, "CA")
+	if err := ec.Validate(); err == nil {
+		t.Errorf("expected error for jurisdiction that is both included and excluded")
+	}
+}
+
+func TestExportConfigValidate_ObjectOptions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		ec   *ExportConfig
+		err  bool
+	}{
+		{
+			name: "valid",
+			ec: &ExportConfig{
+				CacheControl:   "public, max-age=3600",
+				StorageClass:   "NEARLINE",
+				ObjectMetadata: map[string]string{"program": "en", "tier_2": "cdn"},
+			},
+		},
+		{
+			name: "derived",
+			ec: &ExportConfig{
+				ParentConfigID: 1,
+				StorageClass:   "STANDARD_IA",
+			},
+		},
+		{
+			name: "cache_control_newline",
+			ec:   &ExportConfig{CacheControl: "public\r\nX-Injected: 1"},
+			err:  true,
+		},
+		{
+			name: "storage_class_space",
+			ec:   &ExportConfig{StorageClass: "NEAR LINE"},
+			err:  true,
+		},
+		{
+			name: "metadata_key_uppercase",
+			ec:   &ExportConfig{ObjectMetadata: map[string]string{"Program": "en"}},
+			err:  true,
+		},
+		{
+			name: "metadata_key_dash",
+			ec:   &ExportConfig{ObjectMetadata: map[string]string{"my-key": "en"}},
+			err:  true,
+		},
+		{
+			name: "metadata_key_reserved",
+			ec:   &ExportConfig{ObjectMetadata: map[string]string{MetadataBatchID: "1"}},
+			err:  true,
+		},
+		{
+			name: "metadata_value_control",
+			ec:   &ExportConfig{ObjectMetadata: map[string]string{"program": "a\tb"}},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if !tc.ec.IsDerived() {
+				tc.ec.Period = time.Hour
+			}
+			if err := tc.ec.Validate(); (err != nil) != tc.err {
+				t.Errorf("got error %v, want error %t", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestExportConfigValidate_Derived(t *testing.T) {
+	t.Parallel()
+
+	maxKeys := 10
+
+	cases := []struct {
+		name string
+		ec   *ExportConfig
+		err  bool
+	}{
+		{
+			name: "derived",
+			ec: &ExportConfig{
+				ConfigID:          2,
+				ParentConfigID:    1,
+				FilterRegions:     []string{"US"},
+				FilterReportTypes: []string{"confirmed", "likely"},
+				ExcludeRevised:    true,
+			},
+		},
+		{
+			name: "filters_without_parent",
+			ec: &ExportConfig{
+				Period:        time.Hour,
+				FilterRegions: []string{"US"},
+			},
+			err: true,
+		},
+		{
+			name: "self",
+			ec:   &ExportConfig{ConfigID: 1, ParentConfigID: 1},
+			err:  true,
+		},
+		{
+			name: "padded",
+			ec:   &ExportConfig{ParentConfigID: 1, SmallBatchPolicy: SmallBatchPad},
+			err:  true,
+		},
+		{
+			name: "max_batch_keys",
+			ec:   &ExportConfig{ParentConfigID: 1, MaxBatchKeys: &maxKeys},
+			err:  true,
+		},
+		{
+			name: "unknown_report_type",
+			ec:   &ExportConfig{ParentConfigID: 1, FilterReportTypes: []string{"maybe"}},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.ec.Validate()
+			if got := err != nil; got != tc.err {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
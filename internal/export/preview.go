@@ -0,0 +1,246 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	publishdatabase "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/travelrule"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/database"
+)
+
+// maxPreviewBatches bounds the number of batches in a preview, so a short
+// period over a long window doesn't read the whole table one batch at a time.
+const maxPreviewBatches = 500
+
+// PreviewConfig holds the export service settings that shape the batches of a
+// preview. The defaults match the export service.
+type PreviewConfig struct {
+	MaxRecords             int           `env:"EXPORT_FILE_MAX_RECORDS, default=500000"`
+	MinRecords             int           `env:"EXPORT_FILE_MIN_RECORDS, default=1000"`
+	TruncateWindow         time.Duration `env:"TRUNCATE_WINDOW, default=1h"`
+	RequireTravelerConsent bool          `env:"REQUIRE_TRAVELER_CONSENT"`
+}
+
+// Preview is what an export config would have exported over a window of
+// time.
+type Preview struct {
+	Start   time.Time
+	End     time.Time
+	Batches []*PreviewBatch
+
+	// Regions are the regions of the keys found, and MissingRegions are the
+	// input regions of the config that no key was found for.
+	Regions        []string
+	MissingRegions []string
+}
+
+// PreviewBatch is what a single batch would have exported.
+type PreviewBatch struct {
+	Start       time.Time
+	End         time.Time
+	Keys        int
+	RevisedKeys int
+
+	// Files is the number of keys in each file, before padding. PaddedTo is
+	// the number of keys the last file is padded to, if the batch is small.
+	// Skipped is true if the small batch policy drops the files.
+	Files    []int
+	PaddedTo int
+	Skipped  bool
+
+	// Capped is true if the batch has more new keys than the config's max
+	// batch keys, and would be closed early.
+	Capped bool
+
+	Regions []string
+}
+
+// Empty returns true if the batch has no keys.
+func (b *PreviewBatch) Empty() bool {
+	return b.Keys+b.RevisedKeys == 0
+}
+
+// EmptyBatches returns the number of batches without keys.
+func (p *Preview) EmptyBatches() int {
+	n := 0
+	for _, b := range p.Batches {
+		if b.Empty() {
+			n++
+		}
+	}
+	return n
+}
+
+// TotalFiles returns the number of files over all batches.
+func (p *Preview) TotalFiles() int {
+	n := 0
+	for _, b := range p.Batches {
+		n += len(b.Files)
+	}
+	return n
+}
+
+// TotalKeys returns the number of new and revised keys over all batches.
+func (p *Preview) TotalKeys() int {
+	n := 0
+	for _, b := range p.Batches {
+		n += b.Keys + b.RevisedKeys
+	}
+	return n
+}
+
+// PreviewExportConfig runs the batching queries of the export config for the
+// batches that would end in the window before now. It only reads from the
+// database: no batches, files, or padding keys are written. Noise keys and
+// splits for the file memory budget are not included.
+func PreviewExportConfig(ctx context.Context, db *database.DB, cfg *PreviewConfig, ec *model.ExportConfig, window time.Duration, now time.Time) (*Preview, error) {
+	if ec.IsDerived() {
+		return nil, fmt.Errorf("export config %d is derived from export config %d and has no batches of its own", ec.ConfigID, ec.ParentConfigID)
+	}
+	if ec.Period <= 0 {
+		return nil, fmt.Errorf("export config %d has no period", ec.ConfigID)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+
+	rules, err := travelrule.Load(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("loading travel rules: %w", err)
+	}
+	publishDB := publishdatabase.New(db)
+
+	ranges := previewRanges(ec.Period, window, now, cfg.TruncateWindow)
+	preview := &Preview{
+		Start:   ranges[0].start,
+		End:     ranges[len(ranges)-1].end,
+		Batches: make([]*PreviewBatch, 0, len(ranges)),
+	}
+	allRegions := make(map[string]struct{})
+	for _, br := range ranges {
+		eb := newExportBatch(ec, br)
+		criteria := batchCriteria(eb, rules, cfg.RequireTravelerConsent)
+
+		batch := &PreviewBatch{Start: br.start, End: br.end}
+		regions := make(map[string]struct{})
+		count := func(n *int) publishdatabase.IteratorFunction {
+			return func(exp *publishmodel.Exposure) error {
+				if len(exp.ExposureKey) != verifyapi.KeyLength {
+					return nil
+				}
+				*n++
+				for _, r := range exp.Regions {
+					regions[r] = struct{}{}
+					allRegions[r] = struct{}{}
+				}
+				return nil
+			}
+		}
+		if _, err := publishDB.IterateExposures(ctx, criteria, count(&batch.Keys)); err != nil {
+			return nil, fmt.Errorf("iterating exposures: %w", err)
+		}
+		criteria.OnlyRevisedKeys = true
+		if _, err := publishDB.IterateExposures(ctx, criteria, count(&batch.RevisedKeys)); err != nil {
+			return nil, fmt.Errorf("iterating revised exposures: %w", err)
+		}
+
+		if maxKeys := eb.EffectiveMaxBatchKeys(); maxKeys > 0 && batch.Keys > maxKeys {
+			batch.Capped = true
+		}
+		batch.Files, batch.PaddedTo, batch.Skipped = previewFiles(batch.Keys, batch.RevisedKeys,
+			eb.EffectiveMaxRecords(cfg.MaxRecords), eb.EffectiveMinRecords(cfg.MinRecords), eb.EffectiveSmallBatchPolicy())
+		batch.Regions = sortedKeys(regions)
+		preview.Batches = append(preview.Batches, batch)
+	}
+
+	preview.Regions = sortedKeys(allRegions)
+	for _, r := range ec.EffectiveInputRegions() {
+		if _, ok := allRegions[r]; !ok {
+			preview.MissingRegions = append(preview.MissingRegions, r)
+		}
+	}
+	return preview, nil
+}
+
+// previewRanges returns the batch ranges that end in the window before now, in
+// order. Like the batcher, the last range ends before the current publish
+// window. There is always at least one range.
+func previewRanges(period, window time.Duration, now time.Time, truncateWindow time.Duration) []batchRange {
+	end := publishmodel.TruncateWindow(now, truncateWindow).Truncate(period)
+	since := now.Add(-window)
+
+	ranges := make([]batchRange, 0, 1)
+	for {
+		ranges = append(ranges, batchRange{start: end.Add(-period), end: end})
+		end = end.Add(-period)
+		if !end.After(since) || len(ranges) >= maxPreviewBatches {
+			break
+		}
+	}
+
+	for i, j := 0, len(ranges)-1; i < j; i, j = i+1, j-1 {
+		ranges[i], ranges[j] = ranges[j], ranges[i]
+	}
+	return ranges
+}
+
+// previewFiles returns the number of keys in each file of a batch, as
+// makeGroups would split them, and what the small batch policy does to it.
+func previewFiles(keys, revised, maxRecords, minRecords int, policy model.SmallBatchPolicy) ([]int, int, bool) {
+	total := keys + revised
+	if total == 0 {
+		return nil, 0, false
+	}
+	if policy == model.SmallBatchSkip && total < minRecords {
+		return nil, 0, true
+	}
+
+	files := make([]int, 0, (total+maxRecords-1)/maxRecords)
+	for remaining := total; remaining > 0; remaining -= maxRecords {
+		if remaining < maxRecords {
+			files = append(files, remaining)
+			break
+		}
+		files = append(files, maxRecords)
+	}
+
+	// Only the new keys in the last file are padded, so there is nothing to
+	// pad from if it only has revised keys.
+	paddedTo := 0
+	if policy == model.SmallBatchPad && keys < minRecords && keys > (len(files)-1)*maxRecords {
+		paddedTo = minRecords
+		if paddedTo > maxRecords {
+			paddedTo = maxRecords
+		}
+	}
+	return files, paddedTo, false
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,227 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPreviewRanges(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 12, 10, 10, 11, 0, 0, time.UTC)
+	at := func(hour int) time.Time {
+		return time.Date(2021, 12, 10, hour, 0, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		name   string
+		period time.Duration
+		window time.Duration
+		want   []batchRange
+	}{
+		{
+			name:   "window shorter than period",
+			period: time.Hour,
+			window: time.Minute,
+			want:   []batchRange{{at(9), at(10)}},
+		},
+		{
+			name:   "hourly",
+			period: time.Hour,
+			window: 3 * time.Hour,
+			want:   []batchRange{{at(7), at(8)}, {at(8), at(9)}, {at(9), at(10)}},
+		},
+		{
+			name:   "two hourly",
+			period: 2 * time.Hour,
+			window: 4 * time.Hour,
+			want:   []batchRange{{at(6), at(8)}, {at(8), at(10)}},
+		},
+		{
+			name:   "capped",
+			period: time.Minute,
+			window: 24 * time.Hour,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := previewRanges(tc.period, tc.window, now, time.Hour)
+			if tc.want == nil {
+				if len(got) != maxPreviewBatches {
+					t.Errorf("expected %d ranges, got %d", maxPreviewBatches, len(got))
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(batchRange{})); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPreviewFiles(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		keys         int
+		revised      int
+		policy       model.SmallBatchPolicy
+		wantFiles    []int
+		wantPaddedTo int
+		wantSkipped  bool
+	}{
+		{
+			name:   "empty",
+			policy: model.SmallBatchPad,
+		},
+		{
+			name:         "padded",
+			keys:         3,
+			policy:       model.SmallBatchPad,
+			wantFiles:    []int{3},
+			wantPaddedTo: 5,
+		},
+		{
+			name:      "only revised keys are not padded",
+			revised:   3,
+			policy:    model.SmallBatchPad,
+			wantFiles: []int{3},
+		},
+		{
+			name:        "skipped",
+			keys:        3,
+			policy:      model.SmallBatchSkip,
+			wantSkipped: true,
+		},
+		{
+			name:      "normal",
+			keys:      3,
+			policy:    model.SmallBatchNormal,
+			wantFiles: []int{3},
+		},
+		{
+			name:      "split",
+			keys:      12,
+			revised:   13,
+			policy:    model.SmallBatchPad,
+			wantFiles: []int{10, 10, 5},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			files, paddedTo, skipped := previewFiles(tc.keys, tc.revised, 10, 5, tc.policy)
+			if diff := cmp.Diff(tc.wantFiles, files); diff != "" {
+				t.Errorf("files mismatch (-want, +got):\n%s", diff)
+			}
+			if paddedTo != tc.wantPaddedTo {
+				t.Errorf("expected padded to %d, got %d", tc.wantPaddedTo, paddedTo)
+			}
+			if skipped != tc.wantSkipped {
+				t.Errorf("expected skipped %t, got %t", tc.wantSkipped, skipped)
+			}
+		})
+	}
+}
+
+func TestPreviewExportConfig(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	now := time.Now().UTC()
+	createdAt := publishmodel.TruncateWindow(now, time.Hour).Add(-30 * time.Minute)
+	exposures := make([]*publishmodel.Exposure, 0, 3)
+	for _, region := range []string{"US", "US", "CA"} {
+		exposures = append(exposures, &publishmodel.Exposure{
+			ExposureKey:     randomTEK(t),
+			Regions:         []string{region},
+			IntervalNumber:  100,
+			IntervalCount:   144,
+			CreatedAt:       createdAt,
+			LocalProvenance: true,
+			ReportType:      verifyapi.ReportTypeConfirmed,
+		})
+	}
+	if _, err := publishdb.New(testDB).InsertAndReviseExposures(ctx, &publishdb.InsertAndReviseExposuresRequest{
+		Incoming: exposures,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &PreviewConfig{
+		MaxRecords:     2,
+		MinRecords:     1,
+		TruncateWindow: time.Hour,
+	}
+	ec := &model.ExportConfig{
+		ConfigID:     1,
+		Period:       time.Hour,
+		OutputRegion: "US",
+		InputRegions: []string{"US", "MX"},
+	}
+
+	preview, err := PreviewExportConfig(ctx, testDB, cfg, ec, 3*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(preview.Batches), 3; got != want {
+		t.Fatalf("expected %d batches, got %d", want, got)
+	}
+	if got, want := preview.EmptyBatches(), 2; got != want {
+		t.Errorf("expected %d empty batches, got %d", want, got)
+	}
+	if got, want := preview.TotalKeys(), 2; got != want {
+		t.Errorf("expected %d keys, got %d", want, got)
+	}
+	if diff := cmp.Diff([]string{"US"}, preview.Regions); diff != "" {
+		t.Errorf("regions mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"MX"}, preview.MissingRegions); diff != "" {
+		t.Errorf("missing regions mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The keys should be in the last batch.
+	last := preview.Batches[len(preview.Batches)-1]
+	if diff := cmp.Diff([]int{2}, last.Files); diff != "" {
+		t.Errorf("files mismatch (-want, +got):\n%s", diff)
+	}
+
+	ec.ParentConfigID = 2
+	if _, err := PreviewExportConfig(ctx, testDB, cfg, ec, 3*time.Hour, now); err == nil {
+		t.Errorf("expected error for derived config")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	eidatabase "github.com/google/exposure-notifications-server/internal/exportimport/database"
+	eimodel "github.com/google/exposure-notifications-server/internal/exportimport/model"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/storage"
+)
+
+// Provenance records where the keys of one export file came from. Export files
+// have no room for it, so it is written next to the file, at the file's name
+// with model.ProvenanceFilenameSuffix appended. It is not listed in the index.
+//
+// A mirror re-signs the keys it imports with its own signing keys, and the
+// provenance lets its consumers trace them back to the server that first
+// published them.
+type Provenance struct {
+	File   string `json:"file"`
+	Region string `json:"region"`
+
+	// Sources are the export importers that keys in the file were imported by,
+	// ordered by ID.
+	Sources []*ProvenanceSource `json:"sources"`
+
+	// LocalKeys and LocalRevisedKeys count the keys that were not imported,
+	// including any generated padding.
+	LocalKeys        int `json:"localKeys"`
+	LocalRevisedKeys int `json:"localRevisedKeys"`
+}
+
+// ProvenanceSource is the number of keys in an export file that were imported
+// by one export importer.
+type ProvenanceSource struct {
+	ExportImportID int64  `json:"exportImportID"`
+	IndexFile      string `json:"indexFile"`
+	ExportRoot     string `json:"exportRoot"`
+	Region         string `json:"region"`
+	Keys           int    `json:"keys"`
+	RevisedKeys    int    `json:"revisedKeys"`
+}
+
+// importIDs returns the distinct export importer IDs of the given exposures.
+func importIDs(exposures, revised []*publishmodel.Exposure) []int64 {
+	seen := make(map[int64]struct{})
+	ids := make([]int64, 0)
+	for _, list := range [][]*publishmodel.Exposure{exposures, revised} {
+		for _, exp := range list {
+			if exp.ExportImportID == nil {
+				continue
+			}
+			if _, ok := seen[*exp.ExportImportID]; !ok {
+				seen[*exp.ExportImportID] = struct{}{}
+				ids = append(ids, *exp.ExportImportID)
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// buildProvenance counts the keys of an export file by the export importer
+// that imported them. importers must hold every ID returned by importIDs.
+func buildProvenance(objectName, region string, exposures, revised []*publishmodel.Exposure, importers map[int64]*eimodel.ExportImport) (*Provenance, error) {
+	p := &Provenance{
+		File:    objectName,
+		Region:  region,
+		Sources: make([]*ProvenanceSource, 0),
+	}
+
+	sources := make(map[int64]*ProvenanceSource)
+	for _, id := range importIDs(exposures, revised) {
+		ei, ok := importers[id]
+		if !ok {
+			return nil, fmt.Errorf("missing export importer %d", id)
+		}
+		src := &ProvenanceSource{
+			ExportImportID: ei.ID,
+			IndexFile:      ei.IndexFile,
+			ExportRoot:     ei.ExportRoot,
+			Region:         ei.Region,
+		}
+		sources[id] = src
+		p.Sources = append(p.Sources, src)
+	}
+
+	for _, exp := range exposures {
+		if exp.ExportImportID == nil {
+			p.LocalKeys++
+			continue
+		}
+		sources[*exp.ExportImportID].Keys++
+	}
+	for _, exp := range revised {
+		if exp.ExportImportID == nil {
+			p.LocalRevisedKeys++
+			continue
+		}
+		sources[*exp.ExportImportID].RevisedKeys++
+	}
+	return p, nil
+}
+
+// writeProvenance writes the provenance record of an export file. Encrypted
+// exports are for private partner channels, so their provenance is not
+// published next to them.
+func (s *Server) writeProvenance(ctx context.Context, cfi *createFileInfo, objectName string) error {
+	if !s.config.Provenance || cfi.exportBatch.EncryptionKeyID != "" {
+		return nil
+	}
+
+	eiDB := eidatabase.New(s.env.Database())
+	ids := importIDs(cfi.exposures, cfi.revisedExposures)
+	importers := make(map[int64]*eimodel.ExportImport, len(ids))
+	for _, id := range ids {
+		ei, err := eiDB.GetConfig(ctx, id)
+		if err != nil {
+			return fmt.Errorf("loading export importer %d: %w", id, err)
+		}
+		importers[id] = ei
+	}
+
+	p, err := buildProvenance(objectName, cfi.exportBatch.OutputRegion, cfi.exposures, cfi.revisedExposures, importers)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshaling provenance: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, blobOperationTimeout)
+	defer cancel()
+	provenanceName := objectName + model.ProvenanceFilenameSuffix
+	if err := s.env.Blobstore().CreateObject(ctx, cfi.exportBatch.BucketName, provenanceName, data, true, storage.ContentTypeJSON); err != nil {
+		return fmt.Errorf("creating file %s in bucket %s: %w", provenanceName, cfi.exportBatch.BucketName, err)
+	}
+	return nil
+}
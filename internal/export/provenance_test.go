@@ -0,0 +1,132 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	eidatabase "github.com/google/exposure-notifications-server/internal/exportimport/database"
+	eimodel "github.com/google/exposure-notifications-server/internal/exportimport/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildProvenance(t *testing.T) {
+	t.Parallel()
+
+	one, two := int64(1), int64(2)
+	importers := map[int64]*eimodel.ExportImport{
+		1: {ID: 1, IndexFile: "https://a.example/index.txt", ExportRoot: "https://a.example/", Region: "AA"},
+		2: {ID: 2, IndexFile: "https://b.example/index.txt", ExportRoot: "https://b.example/", Region: "BB"},
+	}
+
+	exposures := []*publishmodel.Exposure{
+		{ExportImportID: &two},
+		{ExportImportID: &one},
+		{ExportImportID: &two},
+		{},
+	}
+	revised := []*publishmodel.Exposure{
+		{ExportImportID: &one},
+		{},
+	}
+
+	got, err := buildProvenance("mirror/1-2-00001.zip", "CC", exposures, revised, importers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Provenance{
+		File:   "mirror/1-2-00001.zip",
+		Region: "CC",
+		Sources: []*ProvenanceSource{
+			{ExportImportID: 1, IndexFile: "https://a.example/index.txt", ExportRoot: "https://a.example/", Region: "AA", Keys: 1, RevisedKeys: 1},
+			{ExportImportID: 2, IndexFile: "https://b.example/index.txt", ExportRoot: "https://b.example/", Region: "BB", Keys: 2},
+		},
+		LocalKeys:        1,
+		LocalRevisedKeys: 1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	delete(importers, 2)
+	if _, err := buildProvenance("mirror/1-2-00001.zip", "CC", exposures, revised, importers); err == nil {
+		t.Errorf("expected error for a missing export importer")
+	}
+}
+
+func TestWriteProvenance(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	ei := &eimodel.ExportImport{
+		IndexFile:  "https://a.example/index.txt",
+		ExportRoot: "https://a.example/",
+		Region:     "AA",
+		From:       time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+	}
+	if err := eidatabase.New(testDB).AddConfig(ctx, ei); err != nil {
+		t.Fatal(err)
+	}
+
+	blobstore, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		config: &Config{Provenance: true},
+		env:    serverenv.New(ctx, serverenv.WithDatabase(testDB), serverenv.WithBlobStorage(blobstore)),
+	}
+
+	cfi := &createFileInfo{
+		exposures:   []*publishmodel.Exposure{{ExportImportID: &ei.ID}, {}},
+		exportBatch: &model.ExportBatch{BucketName: "bucket", OutputRegion: "CC"},
+	}
+	if err := server.writeProvenance(ctx, cfi, "mirror/1-2-00001.zip"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := blobstore.GetObject(ctx, "bucket", "mirror/1-2-00001.zip"+model.ProvenanceFilenameSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Provenance
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Sources) != 1 || got.Sources[0].ExportImportID != ei.ID || got.Sources[0].Keys != 1 {
+		t.Errorf("expected 1 key from export importer %d, got %+v", ei.ID, got.Sources)
+	}
+	if got, want := got.LocalKeys, 1; got != want {
+		t.Errorf("expected %d local keys, got %d", want, got)
+	}
+
+	// Encrypted exports do not get a provenance record.
+	cfi.exportBatch.EncryptionKeyID = "partner-key"
+	if err := server.writeProvenance(ctx, cfi, "partner/1-2-00001.zip"+EncryptedFilenameSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blobstore.GetObject(ctx, "bucket", "partner/1-2-00001.zip"+EncryptedFilenameSuffix+model.ProvenanceFilenameSuffix); err == nil {
+		t.Errorf("expected no provenance for an encrypted export")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+// handleRelayOutbox delivers the events in the outbox, such as the CDN purges
+// of completed batches.
+func (s *Server) handleRelayOutbox() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		logger := logging.FromContext(ctx).Named("handleRelayOutbox")
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		ctx, cancel := context.WithTimeout(ctx, s.config.WorkerTimeout)
+		defer cancel()
+
+		if err := s.relay.Deliver(ctx); err != nil {
+			logger.Errorw("failed to relay outbox", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.h.RenderJSON(w, http.StatusOK, nil)
+	})
+}
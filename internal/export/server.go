@@ -18,7 +18,8 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/outbox"
+	"github.com/google/exposure-notifications-server/internal/region"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/google/exposure-notifications-server/pkg/render"
@@ -30,7 +31,11 @@ import (
 type Server struct {
 	config *Config
 	env    *serverenv.ServerEnv
+	relay  *outbox.Relay
 	h      *render.Renderer
+
+	// retention overrides the TTL of the files of output regions.
+	retention *region.Retention
 }
 
 // NewServer makes a Server.
@@ -48,10 +53,22 @@ func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
 		return nil, fmt.Errorf("MIN_WINDOW_AGE must be a duration of >= 0")
 	}
 
+	retention, err := region.NewRetention(&cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGION_RETENTION: %w", err)
+	}
+
+	relay, err := outbox.NewRelay(&cfg.Outbox, env.Database(), env.CDNPurger())
+	if err != nil {
+		return nil, fmt.Errorf("outbox.NewRelay: %w", err)
+	}
+
 	return &Server{
-		config: cfg,
-		env:    env,
-		h:      render.NewRenderer(),
+		config:    cfg,
+		env:       env,
+		relay:     relay,
+		h:         render.NewRenderer(),
+		retention: retention,
 	}, nil
 }
 
@@ -60,14 +77,12 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 	logger := logging.FromContext(ctx).Named("export")
 
 	r := mux.NewRouter()
-	r.Use(middleware.Recovery())
-	r.Use(middleware.PopulateRequestID())
-	r.Use(middleware.PopulateObservability())
-	r.Use(middleware.PopulateLogger(logger))
+	s.config.Middleware.Use(r, logger)
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/create-batches", s.handleCreateBatches())
 	r.Handle("/do-work", s.handleDoWork())
+	r.Handle("/relay-outbox", s.handleRelayOutbox())
 
 	return r
 }
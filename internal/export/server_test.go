@@ -23,6 +23,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/sethvargo/go-envconfig"
 )
 
 // TestNewServer tests NewServer().
@@ -70,13 +71,18 @@ func TestNewServer(t *testing.T) {
 		},
 	}
 
+	var cfg Config
+	if err := envconfig.ProcessWith(ctx, &cfg, envconfig.MapLookuper(nil)); err != nil {
+		t.Fatal(err)
+	}
+
 	for _, tc := range testCases {
 		tc := tc
 
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := NewServer(&Config{}, tc.env)
+			got, err := NewServer(&cfg, tc.env)
 			if tc.err != nil {
 				if err.Error() != tc.err.Error() {
 					t.Fatalf("got %+v: want %v", err, tc.err)
@@ -0,0 +1,77 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"crypto"
+	"io"
+
+	"github.com/google/exposure-notifications-server/internal/backoff"
+)
+
+// signingPolicy returns the retry policy of requests to the key manager.
+func (s *Server) signingPolicy(name string) *backoff.Policy {
+	return &backoff.Policy{
+		Name:       name,
+		MaxRetries: s.config.SigningMaxRetries,
+		Backoff:    s.config.SigningRetryBackoff,
+		Budget:     s.config.SigningRetryBudget,
+	}
+}
+
+// signerForKey returns the signer of the signing key. Looking up the key and
+// each signature are retried with the signing policy, since they are remote
+// calls to the key manager.
+func (s *Server) signerForKey(ctx context.Context, keyID string) (crypto.Signer, error) {
+	var signer crypto.Signer
+	if err := s.signingPolicy("kms_get_signer").Do(ctx, func(ctx context.Context) error {
+		var err error
+		signer, err = s.env.GetSignerForKey(ctx, keyID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &retryingSigner{
+		ctx:    ctx,
+		signer: signer,
+		policy: s.signingPolicy("kms_sign"),
+	}, nil
+}
+
+// retryingSigner is a crypto.Signer that retries failed signatures. Signers
+// have no context, so it uses the context it was created for.
+type retryingSigner struct {
+	ctx    context.Context
+	signer crypto.Signer
+	policy *backoff.Policy
+}
+
+// Public returns the public key of the signer.
+func (r *retryingSigner) Public() crypto.PublicKey {
+	return r.signer.Public()
+}
+
+// Sign signs the digest, retrying on failure.
+func (r *retryingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var sig []byte
+	err := r.policy.Do(r.ctx, func(ctx context.Context) error {
+		var err error
+		sig, err = r.signer.Sign(rand, digest, opts)
+		return err
+	})
+	return sig, err
+}
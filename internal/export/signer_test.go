@@ -0,0 +1,106 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+// flakySigner fails the first failures signatures.
+type flakySigner struct {
+	crypto.Signer
+	failures int
+	calls    int
+}
+
+func (f *flakySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, errors.New("kms unavailable")
+	}
+	return f.Signer.Sign(rand, digest, opts)
+}
+
+func TestRetryingSigner(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("export"))
+
+	cases := []struct {
+		name     string
+		failures int
+		wantErr  bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:     "retried",
+			failures: 2,
+		},
+		{
+			name:     "retries_spent",
+			failures: 3,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := &Server{config: &Config{
+				SigningMaxRetries:   2,
+				SigningRetryBackoff: time.Millisecond,
+			}}
+			flaky := &flakySigner{Signer: key, failures: tc.failures}
+			signer := &retryingSigner{
+				ctx:    project.TestContext(t),
+				signer: flaky,
+				policy: server.signingPolicy("kms_sign"),
+			}
+
+			sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], sig) {
+				t.Error("invalid signature")
+			}
+		})
+	}
+}
@@ -19,24 +19,33 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/backoff"
 	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/jws"
 	publishdatabase "github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/exposure-notifications-server/internal/travelrule"
+	"github.com/google/exposure-notifications-server/internal/worker"
 	coredb "github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/hashicorp/go-multierror"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/tag"
 
 	"github.com/google/exposure-notifications-server/internal/export/model"
+	outboxmodel "github.com/google/exposure-notifications-server/internal/outbox/model"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 
 	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/observability"
 
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 )
@@ -46,7 +55,7 @@ const (
 	blobOperationTimeout = 50 * time.Second
 
 	travelerLockID       = "TRAVELERS"
-	exportAppPackageName = "export-generated"
+	exportAppPackageName = publishmodel.GeneratedAppPackageName
 )
 
 // handleDoWork is a handler to iterate the rows of ExportBatch, and creates
@@ -57,21 +66,25 @@ func (s *Server) handleDoWork() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		budget := s.config.Scheduler.NewBudget(r, s.config.WorkerTimeout)
+		ctx, cancel := scheduler.WithBudget(ctx, budget)
+		defer cancel()
+
 		logger := logging.FromContext(ctx).Named("handleDoWork")
 		logger.Debugw("starting")
 		defer logger.Debugw("finishing")
 
-		ctx, cancel := context.WithTimeout(ctx, s.config.WorkerTimeout)
-		defer cancel()
-
 		var merr *multierror.Error
 
 		indexesWritten := make(map[int64]struct{})
 
+		// Batches are not started once the budget is spent, so they are not
+		// cut off mid-transaction. The next run picks up the rest.
+		completed, stopped := 0, false
 		for {
-			if ctx.Err() != nil {
-				logger.Warnw("deadline passed, still work to do")
-				merr = multierror.Append(merr, fmt.Errorf("deadline exceeded"))
+			if budget.Spent() {
+				logger.Warnw("budget spent, still work to do", "retry", budget.Retry())
+				stopped = true
 				break
 			}
 
@@ -92,6 +105,7 @@ func (s *Server) handleDoWork() http.Handler {
 				continue
 			}
 
+			completed++
 			logger.Debugw("completed batch", "batch_id", batch.BatchID, "config_id", batch.ConfigID)
 		}
 
@@ -101,6 +115,11 @@ func (s *Server) handleDoWork() http.Handler {
 			return
 		}
 
+		if stopped {
+			s.h.RenderJSON(w, http.StatusAccepted, budget.Progress(completed))
+			return
+		}
+
 		stats.Record(ctx, mWorkerSuccess.M(1))
 		s.h.RenderJSON(w, http.StatusOK, nil)
 	})
@@ -173,132 +192,87 @@ func (g *group) Length() int {
 	return len(g.exposures) + len(g.revised)
 }
 
-func (s *Server) batchExposures(ctx context.Context, criteria publishdatabase.IterateExposuresCriteria, maxRecords int, outputRegion string) ([]*group, error) {
+// insertGenerated persists generated keys in batches. If this fails, the
+// export batch will be retried.
+func (s *Server) insertGenerated(ctx context.Context, publishDB *publishdatabase.PublishDB, generated []*publishmodel.Exposure) error {
 	logger := logging.FromContext(ctx)
-	db := s.env.Database()
-
-	// Build up groups of exposures in memory. We need to use memory so we can
-	// determine the total number of groups (which is embedded in each export
-	// file). This technique avoids SELECT COUNT which would lock the database
-	// slowing new uploads.
-	primaryKeys := make([]*publishmodel.Exposure, 0, s.config.MinRecords)
-	revisedKeys := make([]*publishmodel.Exposure, 0, s.config.MinRecords)
-	totalNewKeys, totalRevisedKeys := 0, 0
-	droppedKeys := 0
 
-	publishDB := publishdatabase.New(db)
-
-	maxCreatedAt := time.Time{}
-	_, err := publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
-		if len(exp.ExposureKey) != verifyapi.KeyLength {
-			droppedKeys++
-			return nil
+	insertRequest := &publishdatabase.InsertAndReviseExposuresRequest{
+		RequireToken:  false,
+		SkipRevisions: true,
+	}
+	length := len(generated)
+	for i := 0; i < length; i = i + s.config.MaxInsertBatchSize {
+		upper := i + s.config.MaxInsertBatchSize
+		if upper > length {
+			upper = length
 		}
-		// see if assigned time for generated data should be moved up.
-		if exp.CreatedAt.After(maxCreatedAt) {
-			maxCreatedAt = exp.CreatedAt
+		insertRequest.Incoming = generated[i:upper]
+		insertResponse, err := publishDB.InsertAndReviseExposures(ctx, insertRequest)
+		if err != nil {
+			return fmt.Errorf("writing generated data, publishDB.InsertAndReviseExposures: %w", err)
 		}
-		primaryKeys = append(primaryKeys, exp)
-		totalNewKeys++
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("iterating exposures: %w", err)
+		logger.Debugw("persisting generated keys", "num", insertResponse.Inserted)
 	}
+	return nil
+}
 
-	// go get the revised keys.
-	criteria.OnlyRevisedKeys = true
-	_, err = publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
-		if len(exp.ExposureKey) != verifyapi.KeyLength {
-			droppedKeys++
-			return nil
-		}
-		revisedKeys = append(revisedKeys, exp)
-		totalRevisedKeys++
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("iterating revised exposures: %w", err)
+// noiseKeys returns the number of noise keys to mix into an export with the
+// given number of real and generated primary keys. Keys that were generated
+// by a previous run of the batch are counted as noise, so re-running a batch
+// does not keep adding to it.
+func (s *Server) noiseKeys(realKeys, generatedKeys int) (int, error) {
+	if realKeys == 0 {
+		return 0, nil
 	}
-
-	if droppedKeys > 0 {
-		logger.Errorw("export found keys of invalid length", "dropped_keys", droppedKeys)
-		stats.Record(ctx, mWorkerBadKeyLength.M(int64(droppedKeys)))
+	if limit := s.config.NoiseMaxKeys; limit > 0 && realKeys >= limit {
+		return 0, nil
 	}
 
-	// Sort all the keys that we got so that if the batch is re-run, the contents are stable IFF
-	// the sizing information is the same.
-	sortExposures(primaryKeys)
-	sortExposures(revisedKeys)
-
-	// Break these into groups according to the max records per file.
-	groups := make([]*group, 0, 1)
-	nextGroup := &group{}
-	for _, exp := range primaryKeys {
-		nextGroup.exposures = append(nextGroup.exposures, exp)
-		if nextGroup.Length() >= maxRecords {
-			groups = append(groups, nextGroup)
-			nextGroup = &group{}
-		}
+	jitter, err := randomInt(0, s.config.PaddingRange)
+	if err != nil {
+		return 0, err
 	}
-	for _, exp := range revisedKeys {
-		nextGroup.revised = append(nextGroup.revised, exp)
-		if nextGroup.Length() >= maxRecords {
-			groups = append(groups, nextGroup)
-			nextGroup = &group{}
-		}
+	n := noiseCount(realKeys, s.config.NoisePercent) + jitter - generatedKeys
+	if n <= 0 {
+		return 0, nil
 	}
+	return n, nil
+}
 
-	// If the last group has anything, add it to the list.
-	if nextGroup.Length() > 0 {
-		groups = append(groups, nextGroup)
+// generateNoise returns n noise keys that look like the template keys.
+func generateNoise(templates []*publishmodel.Exposure, n int, createdAt time.Time) ([]*publishmodel.Exposure, error) {
+	if len(templates) == 0 {
+		return nil, nil
 	}
 
-	if len(groups) == 0 {
-		logger.Infof("No records for export batch")
-	} else if len(primaryKeys) < s.config.MinRecords {
-		// only drop into the padding code if the overall sum of groups is less than requested. Otherwise the pre-sorting
-		// will give away the generated data.
-		lastGroup := groups[len(groups)-1]
-		var generated []*publishmodel.Exposure
-		lastGroup.exposures, generated, err = ensureMinNumExposures(lastGroup.exposures, outputRegion, s.config.MinRecords, s.config.PaddingRange, maxRecords, maxCreatedAt)
+	noise := make([]*publishmodel.Exposure, 0, n)
+	for i := 0; i < n; i++ {
+		exp, err := generateExposure(templates[i%len(templates)], createdAt)
 		if err != nil {
-			return nil, fmt.Errorf("ensureMinNumExposures: %w", err)
-		}
-		// padding revised keys doesn't provide any useful protection as one can work backwords and figure out which
-		// keys appeared as primary keys in a previous export.
-
-		if length := len(generated); length > 0 {
-			// we generated some data in order to pad out this export. This data needs to be persisted.
-			insertRequest := &publishdatabase.InsertAndReviseExposuresRequest{
-				RequireToken:  false,
-				SkipRevisions: true,
-			}
-			// Insert the generated data in batches.
-			for i := 0; i < length; i = i + s.config.MaxInsertBatchSize {
-				upper := i + s.config.MaxInsertBatchSize
-				if upper > length {
-					upper = length
-				}
-				insertRequest.Incoming = generated[i:upper]
-				insertResponse, err := publishDB.InsertAndReviseExposures(ctx, insertRequest)
-				if err != nil {
-					// If this fails, the batch will be retried.
-					return nil, fmt.Errorf("writing generated data, publishDB.InsertAndReviseExposures: %w", err)
-				}
-				logger.Debugw("persisting generated keys", "num", insertResponse.Inserted)
-				i = i + upper
-			}
+			return nil, err
 		}
+		noise = append(noise, exp)
 	}
+	return noise, nil
+}
 
-	return groups, nil
+// noiseCount returns the given percentage of n, rounded up.
+func noiseCount(n, percent int) int {
+	return (n*percent + 99) / 100
 }
 
 func (s *Server) exportBatch(ctx context.Context, eb *model.ExportBatch, emitIndexForEmptyBatch bool) error {
+	ctx = withBatchMetadata(ctx, eb)
 	logger := logging.FromContext(ctx)
 	db := s.env.Database()
 
+	ec, err := exportdatabase.New(db).GetExportConfig(ctx, eb.ConfigID)
+	if err != nil {
+		return fmt.Errorf("loading export config %d: %w", eb.ConfigID, err)
+	}
+	ctx = withConfigObjectOptions(ctx, ec)
+
 	maxRecords := eb.EffectiveMaxRecords(s.config.MaxRecords)
 	if maxRecords != s.config.MaxRecords {
 		logger.Debugw("max records is being overridden", "batchID", eb.BatchID)
@@ -306,23 +280,51 @@ func (s *Server) exportBatch(ctx context.Context, eb *model.ExportBatch, emitInd
 
 	logger.Infof("Processing export batch %d (root: %q, region: %s), max records per file %d", eb.BatchID, eb.FilenameRoot, eb.OutputRegion, maxRecords)
 
-	// Criteria starts w/ non-revised keys.
-	// Will be changed later to grab the revised keys.
-	criteria := publishdatabase.IterateExposuresCriteria{
-		SinceTimestamp:      eb.StartTimestamp,
-		UntilTimestamp:      eb.EndTimestamp,
-		IncludeRegions:      eb.EffectiveInputRegions(),
-		IncludeTravelers:    eb.IncludeTravelers, // Travelers are included from "any" region.
-		OnlyNonTravelers:    eb.OnlyNonTravelers,
-		ExcludeRegions:      eb.ExcludeRegions,
-		OnlyLocalProvenance: false, // include federated ids
-		OnlyRevisedKeys:     false,
+	rules, err := travelrule.Load(ctx, db)
+	if err != nil {
+		return fmt.Errorf("loading travel rules: %w", err)
 	}
+	criteria := batchCriteria(eb, rules, s.config.RequireTravelerConsent)
+	criteria.IncludeKeyMetadata = s.config.KeyMetadata
 
-	groups, err := s.batchExposures(ctx, criteria, maxRecords, eb.OutputRegion)
+	if maxKeys := eb.EffectiveMaxBatchKeys(); maxKeys > 0 {
+		if err := s.capBatch(ctx, eb, criteria, maxKeys); err != nil {
+			return fmt.Errorf("capping batch: %w", err)
+		}
+		criteria.UntilTimestamp = eb.EndTimestamp
+	}
+
+	minRecords := eb.EffectiveMinRecords(s.config.MinRecords)
+	policy := eb.EffectiveSmallBatchPolicy()
+	padTo := 0
+	if policy == model.SmallBatchPad {
+		padTo = minRecords
+	}
+
+	publishDB := publishdatabase.New(db)
+	budgetRecords := memoryBudgetRecords(maxRecords, s.config.FileMemoryBudget)
+	plan, err := s.planBatch(ctx, publishDB.IterateExposures, criteria, budgetRecords, padTo, eb.OutputRegion)
 	if err != nil {
 		return fmt.Errorf("reading exposures for batch: %w", err)
 	}
+	files := plan.files
+	if n := filesLength(files); len(files) > (n+maxRecords-1)/maxRecords {
+		logger.Infow("splitting batch to stay within the memory budget",
+			"batch_id", eb.BatchID,
+			"keys", n,
+			"files", len(files),
+			"max_records", maxRecords,
+			"budget_records", budgetRecords)
+		stats.Record(ctx, mWorkerMemorySplit.M(1))
+	}
+
+	if policy == model.SmallBatchSkip {
+		if n := filesLength(files); n > 0 && n < minRecords {
+			logger.Infow("skipping files for small batch", "batch_id", eb.BatchID, "keys", n, "min_records", minRecords)
+			stats.Record(ctx, mWorkerSmallBatchSkipped.M(1))
+			files = nil
+		}
+	}
 
 	exportDB := exportdatabase.New(db)
 	// Load the non-expired signature infos associated with this export batch.
@@ -331,51 +333,100 @@ func (s *Server) exportBatch(ctx context.Context, eb *model.ExportBatch, emitInd
 		return fmt.Errorf("error loading signature info for batch %d, %w", eb.BatchID, err)
 	}
 
-	// Create the export files.
-	batchSize := len(groups)
+	// The keys of the derived configs are collected while the files are
+	// written, so the exposures are only read once for the parent.
+	derivedConfigs, err := exportDB.ListDerivedExportConfigs(ctx, eb.ConfigID, eb.EndTimestamp)
+	if err != nil {
+		return err
+	}
+	derived := make([]*derivedKeys, 0, len(derivedConfigs))
+	for _, ec := range derivedConfigs {
+		derived = append(derived, &derivedKeys{ec: ec})
+	}
+
+	// Create the export files. Files are read from the database one at a
+	// time while at most FileConcurrency files are written, so only one more
+	// file's exposures than that are in memory.
+	batchSize := len(files)
 	splitBatch := batchSize > 1
-	objectNames := make([]string, 0, len(groups))
-	for i, group := range groups {
+	objectNames := make([]string, len(files))
+	fileSizes := make([]int64, len(files))
+	pool := worker.New("export_files", s.config.FileConcurrency)
+	err = s.streamFiles(ctx, publishDB.IterateExposures, plan, files, nil, func(i int, group *group) error {
+		if i == len(files)-1 {
+			if err := s.padFile(ctx, plan, group); err != nil {
+				return err
+			}
+		}
+
+		// The keys of the derived configs are collected before the file is
+		// written, which sorts its exposures.
+		for _, d := range derived {
+			d.add(group)
+		}
+
+		return pool.Go(ctx, func(ctx context.Context) error {
+			// 20201120 - Batch num/size changed to always be 1/1.
+			// The batch numbering being deemed unnecessary.
+			// However timing adjustments are put in place for variable batch sizes.
+			objectName, size, err := s.createFile(ctx,
+				&createFileInfo{
+					exposures:        group.exposures,
+					revisedExposures: group.revised,
+					exportBatch:      eb,
+					signatureInfos:   sigInfos,
+					fileNum:          int32(i + 1), // the batchNum and batchSize are flattened to 1 and 1 when
+					splitBatch:       splitBatch,
+				})
+			if err != nil {
+				return fmt.Errorf("creating export file %d for batch %d: %w", i+1, eb.BatchID, err)
+			}
+			logger.Infof("Wrote export file %q for batch %d", objectName, eb.BatchID)
+			objectNames[i], fileSizes[i] = objectName, size
+			return nil
+		})
+	})
+	if werr := pool.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
 		if ctx.Err() != nil {
 			logger.Infof("Timed out writing export files for batch %s, the entire batch will be retried once the batch lease expires on %v", eb.BatchID, eb.LeaseExpires)
 			return nil
 		}
-
-		// 20201120 - Batch num/size changed to always be 1/1.
-		// The batch numbering being deemed unnecessary.
-		// However timing adjustments are put in place for variable batch sizes.
-		objectName, err := s.createFile(ctx,
-			&createFileInfo{
-				exposures:        group.exposures,
-				revisedExposures: group.revised,
-				exportBatch:      eb,
-				signatureInfos:   sigInfos,
-				fileNum:          int32(i + 1), // the batchNum and batchSize are flattened to 1 and 1 when
-				splitBatch:       splitBatch,
-			})
-		if err != nil {
-			return fmt.Errorf("creating export file %d for batch %d: %w", i+1, eb.BatchID, err)
-		}
-		logger.Infof("Wrote export file %q for batch %d", objectName, eb.BatchID)
-		objectNames = append(objectNames, objectName)
+		return err
 	}
 
+	sizes := sizesByName(objectNames, fileSizes)
+
 	// Emit the index file if needed.
-	if batchSize > 0 || emitIndexForEmptyBatch {
+	indexWritten := batchSize > 0 || emitIndexForEmptyBatch
+	if indexWritten {
 		if err := s.retryingCreateIndex(ctx, eb, objectNames); err != nil {
 			return err
 		}
 	}
 
-	// Write the files records in database and complete the batch.
-	if err := exportDB.FinalizeBatch(ctx, eb, objectNames, batchSize); err != nil {
+	// Derived exports are written before the batch is completed, so that a
+	// failure retries them along with the batch.
+	if err := s.exportDerived(ctx, eb, plan, derived); err != nil {
+		return fmt.Errorf("exporting derived configs: %w", err)
+	}
+
+	// Write the files records in database and complete the batch, along with
+	// the event that announces it.
+	event, err := s.publishedEvent(ctx, eb, objectNames, indexWritten)
+	if err != nil {
+		return err
+	}
+	if err := exportDB.FinalizeBatch(ctx, eb, objectNames, sizes, batchSize, event); err != nil {
 		return fmt.Errorf("completing batch: %w", err)
 	}
 	logger.Infof("Batch %d completed", eb.BatchID)
 
 	tags := []tag.Mutator{
 		tag.Upsert(ExportConfigIDTagKey, fmt.Sprintf("%d", eb.ConfigID)),
-		tag.Upsert(ExportRegionTagKey, eb.OutputRegion),
+		observability.Upsert(ExportRegionTagKey, eb.OutputRegion),
 		tag.Upsert(ExportTravelersTagKey, fmt.Sprintf("%v", eb.IncludeTravelers)),
 	}
 	if err := stats.RecordWithTags(ctx, tags, mExportBatchCompletion.M(1)); err != nil {
@@ -385,6 +436,272 @@ func (s *Server) exportBatch(ctx context.Context, eb *model.ExportBatch, emitInd
 	return nil
 }
 
+const (
+	// estimatedKeyBytes is the approximate size of one key in a serialized
+	// export file, including protobuf framing.
+	estimatedKeyBytes = 32
+
+	// serializationCopies is the number of copies of an export file's contents
+	// held in memory while it is marshaled, compressed, and signed.
+	serializationCopies = 3
+)
+
+// memoryBudgetRecords returns the number of records per file, at most
+// maxRecords, whose estimated serialization fits in budget bytes. A budget of
+// 0 or less returns maxRecords.
+func memoryBudgetRecords(maxRecords int, budget int64) int {
+	if budget <= 0 {
+		return maxRecords
+	}
+
+	records := budget / (estimatedKeyBytes * serializationCopies)
+	if records < 1 {
+		return 1
+	}
+	if records < int64(maxRecords) {
+		return int(records)
+	}
+	return maxRecords
+}
+
+// capBatch closes the batch early if its window has more than maxKeys new
+// keys, so that surges produce more batches instead of larger files. The rest
+// of the window is added as a new open batch.
+func (s *Server) capBatch(ctx context.Context, eb *model.ExportBatch, criteria publishdatabase.IterateExposuresCriteria, maxKeys int) error {
+	logger := logging.FromContext(ctx).Named("capBatch").
+		With("batch_id", eb.BatchID)
+	db := s.env.Database()
+
+	splitAt, err := batchSplitTime(ctx, publishdatabase.New(db).IterateExposures, criteria, maxKeys)
+	if err != nil {
+		return err
+	}
+	if splitAt.IsZero() {
+		return nil
+	}
+
+	rest, err := exportdatabase.New(db).SplitBatch(ctx, eb, splitAt)
+	if err != nil {
+		return err
+	}
+	logger.Infow("closed batch early at key cap",
+		"max_keys", maxKeys,
+		"end", eb.EndTimestamp,
+		"new_batch_id", rest.BatchID)
+	stats.Record(ctx, mWorkerBatchSplit.M(1))
+	return nil
+}
+
+type iterateExposuresFunc func(context.Context, publishdatabase.IterateExposuresCriteria, publishdatabase.IteratorFunction) (string, error)
+
+// batchSplitTime returns the time at which the window described by criteria
+// must end so that it contains no more than maxKeys new keys. It returns the
+// zero time if the window is under the cap or cannot be split.
+//
+// Keys are only split on their created_at timestamps, which are truncated on
+// publish. If more than maxKeys keys share the first timestamp in the window,
+// the split is after that timestamp and the batch exceeds the cap.
+func batchSplitTime(ctx context.Context, iterate iterateExposuresFunc, criteria publishdatabase.IterateExposuresCriteria, maxKeys int) (time.Time, error) {
+	criteria.OnlyRevisedKeys = false
+	criteria.LastCursor = ""
+	criteria.Limit = uint32(maxKeys) + 1
+
+	count := 0
+	var last time.Time
+	if _, err := iterate(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		count++
+		last = exp.CreatedAt
+		return nil
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("counting exposures: %w", err)
+	}
+	if count <= maxKeys {
+		return time.Time{}, nil
+	}
+
+	// Exposures are ordered by created_at, so every key before the first one
+	// over the cap was created before it.
+	if last.After(criteria.SinceTimestamp) {
+		return last, nil
+	}
+
+	// The cap was reached within the first timestamp of the window, so find
+	// the next one. Timestamps are stored with microsecond precision.
+	criteria.SinceTimestamp = last.Add(time.Microsecond)
+	criteria.Limit = 1
+
+	var next time.Time
+	if _, err := iterate(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		next = exp.CreatedAt
+		return nil
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("finding next timestamp: %w", err)
+	}
+	logging.FromContext(ctx).Warnw("batch exceeds key cap within a single timestamp",
+		"max_keys", maxKeys,
+		"created_at", last)
+	return next, nil
+}
+
+// derivedKeys are the keys of the parent batch that a derived config
+// includes.
+type derivedKeys struct {
+	ec          *model.ExportConfig
+	primaryKeys []exposureKeyID
+	revisedKeys []exposureKeyID
+}
+
+// add adds the keys of a file of the parent batch that the derived config
+// includes.
+func (d *derivedKeys) add(g *group) {
+	for _, exp := range g.exposures {
+		if d.includes(exp, false) {
+			d.primaryKeys = append(d.primaryKeys, keyIDOf(exp.ExposureKey))
+		}
+	}
+	for _, exp := range g.revised {
+		if d.includes(exp, true) {
+			d.revisedKeys = append(d.revisedKeys, keyIDOf(exp.ExposureKey))
+		}
+	}
+}
+
+// includes returns true if the derived config includes the key. Revised keys
+// are filtered on their revised report type.
+func (d *derivedKeys) includes(exp *publishmodel.Exposure, revised bool) bool {
+	reportType := exp.ReportType
+	if revised {
+		if d.ec.ExcludeRevised {
+			return false
+		}
+		if exp.RevisedReportType != nil {
+			reportType = *exp.RevisedReportType
+		}
+	}
+	return derivedIncludes(d.ec, exp, reportType)
+}
+
+// exportDerived writes the batches of the configs derived from the batch's
+// config. Derived batches only ever contain keys that the parent exported.
+// Their files are read like the parent's, one at a time.
+func (s *Server) exportDerived(ctx context.Context, parent *model.ExportBatch, plan *batchPlan, derived []*derivedKeys) error {
+	logger := logging.FromContext(ctx)
+	exportDB := exportdatabase.New(s.env.Database())
+	publishDB := publishdatabase.New(s.env.Database())
+
+	for _, d := range derived {
+		eb, err := exportDB.AddDerivedBatch(ctx, parent, d.ec)
+		if err != nil {
+			return err
+		}
+		ctx := withConfigObjectOptions(withBatchMetadata(ctx, eb), d.ec)
+		if eb.Status == model.ExportBatchComplete {
+			continue
+		}
+
+		sortKeyIDs(d.primaryKeys)
+		sortKeyIDs(d.revisedKeys)
+		files := planFiles(d.primaryKeys, d.revisedKeys, eb.EffectiveMaxRecords(s.config.MaxRecords))
+		if d.ec.SmallBatchPolicy == model.SmallBatchSkip {
+			minRecords := eb.EffectiveMinRecords(s.config.MinRecords)
+			if n := filesLength(files); n > 0 && n < minRecords {
+				logger.Infow("skipping files for small derived batch", "batch_id", eb.BatchID, "keys", n, "min_records", minRecords)
+				stats.Record(ctx, mWorkerSmallBatchSkipped.M(1))
+				files = nil
+			}
+		}
+
+		sigInfos, err := exportDB.LookupSignatureInfos(ctx, eb.SignatureInfoIDs, time.Now())
+		if err != nil {
+			return fmt.Errorf("error loading signature info for batch %d, %w", eb.BatchID, err)
+		}
+
+		objectNames := make([]string, len(files))
+		fileSizes := make([]int64, len(files))
+		pool := worker.New("export_files", s.config.FileConcurrency)
+		err = s.streamFiles(ctx, publishDB.IterateExposures, plan, files, d.includes, func(i int, group *group) error {
+			return pool.Go(ctx, func(ctx context.Context) error {
+				objectName, size, err := s.createFile(ctx,
+					&createFileInfo{
+						exposures:        group.exposures,
+						revisedExposures: group.revised,
+						exportBatch:      eb,
+						signatureInfos:   sigInfos,
+						fileNum:          int32(i + 1),
+						splitBatch:       len(files) > 1,
+					})
+				if err != nil {
+					return fmt.Errorf("creating export file %d for derived batch %d: %w", i+1, eb.BatchID, err)
+				}
+				logger.Infof("Wrote export file %q for derived batch %d", objectName, eb.BatchID)
+				objectNames[i], fileSizes[i] = objectName, size
+				return nil
+			})
+		})
+		if werr := pool.Wait(); err == nil {
+			err = werr
+		}
+		if err != nil {
+			return fmt.Errorf("writing derived batch %d: %w", eb.BatchID, err)
+		}
+		sizes := sizesByName(objectNames, fileSizes)
+
+		indexWritten := len(objectNames) > 0
+		if indexWritten {
+			if err := s.retryingCreateIndex(ctx, eb, objectNames); err != nil {
+				return err
+			}
+		}
+
+		event, err := s.publishedEvent(ctx, eb, objectNames, indexWritten)
+		if err != nil {
+			return err
+		}
+		if err := exportDB.FinalizeBatch(ctx, eb, objectNames, sizes, len(files), event); err != nil {
+			return fmt.Errorf("completing derived batch: %w", err)
+		}
+		logger.Infow("derived batch completed", "batch_id", eb.BatchID, "config_id", eb.ConfigID, "parent_batch_id", parent.BatchID)
+		stats.Record(ctx, mWorkerDerivedBatches.M(1))
+	}
+	return nil
+}
+
+// derivedIncludes returns true if the key passes the region and report type
+// filters of the derived config ec.
+func derivedIncludes(ec *model.ExportConfig, exp *publishmodel.Exposure, reportType string) bool {
+	if len(ec.FilterReportTypes) > 0 && !containsString(ec.FilterReportTypes, reportType) {
+		return false
+	}
+	if len(ec.FilterRegions) == 0 {
+		return true
+	}
+	for _, r := range exp.Regions {
+		if containsString(ec.FilterRegions, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sizesByName returns the sizes of the files written for a batch, keyed by the
+// file name.
+func sizesByName(objectNames []string, fileSizes []int64) map[string]int64 {
+	sizes := make(map[string]int64, len(objectNames))
+	for i, name := range objectNames {
+		sizes[name] = fileSizes[i]
+	}
+	return sizes
+}
+
 type createFileInfo struct {
 	exposures        []*publishmodel.Exposure
 	revisedExposures []*publishmodel.Exposure
@@ -394,14 +711,15 @@ type createFileInfo struct {
 	splitBatch       bool  // Did this batch contain more than 1 file due to too many keys?
 }
 
-func (s *Server) createFile(ctx context.Context, cfi *createFileInfo) (string, error) {
+// createFile writes an export file and returns its name and size in bytes.
+func (s *Server) createFile(ctx context.Context, cfi *createFileInfo) (string, int64, error) {
 	logger := logging.FromContext(ctx)
 
 	signers := make([]*Signer, 0, len(cfi.signatureInfos))
 	for _, si := range cfi.signatureInfos {
-		signer, err := s.env.GetSignerForKey(ctx, si.SigningKey)
+		signer, err := s.signerForKey(ctx, si.SigningKey)
 		if err != nil {
-			return "", fmt.Errorf("unable to get signer for key %v: %w", si.SigningKey, err)
+			return "", 0, fmt.Errorf("unable to get signer for key %v: %w", si.SigningKey, err)
 		}
 		signers = append(signers, &Signer{SignatureInfo: si, Signer: signer})
 	}
@@ -409,17 +727,88 @@ func (s *Server) createFile(ctx context.Context, cfi *createFileInfo) (string, e
 	// Generate exposure key export file.
 	data, err := MarshalExportFile(cfi.exportBatch, cfi.exposures, cfi.revisedExposures, cfi.fileNum, cfi.splitBatch, signers)
 	if err != nil {
-		return "", fmt.Errorf("marshaling export file: %w", err)
+		return "", 0, fmt.Errorf("marshaling export file: %w", err)
 	}
 
 	objectName := exportFilename(cfi.exportBatch, cfi.fileNum, s.config.RepressGeneration())
+	contentType := storage.ContentTypeZip
+
+	// Exports for private partner channels are encrypted.
+	if keyID := cfi.exportBatch.EncryptionKeyID; keyID != "" {
+		data, err = EncryptExportFile(ctx, s.env.KeyManager(), keyID, data)
+		if err != nil {
+			return "", 0, fmt.Errorf("encrypting export file: %w", err)
+		}
+		objectName += EncryptedFilenameSuffix
+		contentType = ContentTypeEncrypted
+	}
+
 	logger.Infof("Created file %v, signed with %v keys", objectName, len(signers))
 	ctx, cancel := context.WithTimeout(ctx, blobOperationTimeout)
 	defer cancel()
-	if err := s.env.Blobstore().CreateObject(ctx, cfi.exportBatch.BucketName, objectName, data, true, storage.ContentTypeZip); err != nil {
-		return "", fmt.Errorf("creating file %s in bucket %s: %w", objectName, cfi.exportBatch.BucketName, err)
+	if err := s.env.Blobstore().CreateObject(withFileMetadata(ctx, cfi), cfi.exportBatch.BucketName, objectName, data, true, contentType); err != nil {
+		return "", 0, fmt.Errorf("creating file %s in bucket %s: %w", objectName, cfi.exportBatch.BucketName, err)
+	}
+	if err := s.writeProvenance(ctx, cfi, objectName); err != nil {
+		return "", 0, fmt.Errorf("writing provenance of %s: %w", objectName, err)
 	}
-	return objectName, nil
+	return objectName, int64(len(data)), nil
+}
+
+// applyTravelRules expands the criteria with the keys that the travel rules
+// distribute to the batch's output region.
+func applyTravelRules(criteria *publishdatabase.IterateExposuresCriteria, rules *travelrule.Engine, eb *model.ExportBatch) {
+	sources := rules.SourcesFor(travelrule.TargetExport, eb.OutputRegion)
+
+	criteria.IncludeRegions = unionRegions(criteria.IncludeRegions, sources.Regions)
+	if eb.OnlyNonTravelers {
+		return
+	}
+	if sources.AllTravelers {
+		criteria.IncludeTravelers = true
+	}
+	criteria.IncludeTravelersFrom = unionRegions(criteria.IncludeTravelersFrom, sources.TravelerRegions)
+}
+
+// batchCriteria returns the criteria for reading the new keys of an export
+// batch. Revised keys are read by setting OnlyRevisedKeys on the result.
+func batchCriteria(eb *model.ExportBatch, rules *travelrule.Engine, requireTravelerConsent bool) publishdatabase.IterateExposuresCriteria {
+	criteria := publishdatabase.IterateExposuresCriteria{
+		SinceTimestamp:      eb.StartTimestamp,
+		UntilTimestamp:      eb.EndTimestamp,
+		IncludeRegions:      eb.EffectiveInputRegions(),
+		IncludeTravelers:    eb.IncludeTravelers, // Travelers are included from "any" region.
+		OnlyNonTravelers:    eb.OnlyNonTravelers,
+		ExcludeRegions:      eb.ExcludeRegions,
+		OnlyLocalProvenance: false, // include federated ids
+		OnlyRevisedKeys:     false,
+		ExcludeRevoked:      true, // revoked keys are only published as revisions
+		ExcludeTestData:     !eb.TestData,
+		OnlyTestData:        eb.TestData,
+
+		IncludeJurisdictions: eb.IncludeJurisdictions,
+		ExcludeJurisdictions: eb.ExcludeJurisdictions,
+		TravelerConsent:      publishdatabase.ConsentFilterFor(requireTravelerConsent),
+	}
+	applyTravelRules(&criteria, rules, eb)
+	return criteria
+}
+
+// unionRegions returns the regions in a followed by the regions in b that are
+// not in a.
+func unionRegions(a, b []string) []string {
+	a = append([]string(nil), a...)
+	seen := make(map[string]struct{}, len(a))
+	for _, r := range a {
+		seen[r] = struct{}{}
+	}
+	for _, r := range b {
+		if _, ok := seen[r]; !ok {
+			seen[r] = struct{}{}
+			a = append(a, r)
+		}
+	}
+	return a
 }
 
 // retryingCreateIndex create the index file. The index file includes _all_
@@ -432,51 +821,119 @@ func (s *Server) retryingCreateIndex(ctx context.Context, eb *model.ExportBatch,
 	// Lock at the export config level, if there are multiple batches in parallel for the same
 	// config, they should serially update the index.
 	lockID := fmt.Sprintf("export-config-%d", eb.ConfigID)
-	sleep := 10 * time.Second
-	for {
+
+	// Wait for the lock until the context is done.
+	policy := &backoff.Policy{
+		Name:       "export_index_lock",
+		MaxRetries: math.MaxUint64,
+		Backoff:    time.Second,
+		MaxBackoff: 10 * time.Second,
+		Retryable: func(err error) bool {
+			return errors.Is(err, coredb.ErrAlreadyLocked)
+		},
+	}
+
+	var unlock coredb.UnlockFn
+	if err := policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		unlock, err = db.Lock(ctx, lockID, time.Minute)
+		return err
+	}); err != nil {
 		if ctx.Err() != nil {
 			logger.Infof("Timed out acquiring index file lock for config %s, the entire batch will be retried once the batch lease expires on %v", eb.BatchID, eb.LeaseExpires)
 			return nil
 		}
+		return err
+	}
 
-		unlock, err := db.Lock(ctx, lockID, time.Minute)
-		if err != nil {
-			if errors.Is(err, coredb.ErrAlreadyLocked) {
-				logger.Debugf("Lock %s is locked; sleeping %v and will try again", lockID, sleep)
-				time.Sleep(sleep)
-				continue
-			}
-			return err
-		}
+	// Mark files that we've previously cared about as expired.
+	if err := s.markExpiredFiles(ctx, eb); err != nil {
+		return fmt.Errorf("marking expired: %w", err)
+	}
 
-		// Mark files that we've previously cared about as expired.
-		if err := s.markExpiredFiles(ctx, eb); err != nil {
-			return fmt.Errorf("marking expired: %w", err)
+	indexName, entries, err := s.createIndex(ctx, eb, objectNames)
+	if err != nil {
+		if err1 := unlock(); err1 != nil {
+			return fmt.Errorf("releasing lock: %v (original error: %w)", err1, err)
 		}
+		return fmt.Errorf("creating index file for batch %d: %w", eb.BatchID, err)
+	}
 
-		indexName, entries, err := s.createIndex(ctx, eb, objectNames)
-		if err != nil {
-			if err1 := unlock(); err1 != nil {
-				return fmt.Errorf("releasing lock: %v (original error: %w)", err1, err)
-			}
-			return fmt.Errorf("creating index file for batch %d: %w", eb.BatchID, err)
+	logger.Infof("Wrote index file %q with %d entries (triggered by batch %d)", indexName, entries, eb.BatchID)
+	if err := unlock(); err != nil {
+		return fmt.Errorf("releasing lock: %w", err)
+	}
+
+	return nil
+}
+
+// withBatchMetadata returns a context whose objects are created with the IDs
+// of the batch and of the runs that created and exported it, so an object can
+// be traced back to its batch and runs.
+func withBatchMetadata(ctx context.Context, eb *model.ExportBatch) context.Context {
+	return storage.WithMetadata(ctx, map[string]string{
+		model.MetadataBatchID:    strconv.FormatInt(eb.BatchID, 10),
+		model.MetadataBatchRunID: eb.RunID,
+		model.MetadataRunID:      scheduler.RunID(ctx),
+	})
+}
+
+// withConfigObjectOptions returns a context whose objects are created with
+// the object options and custom metadata of the export config.
+func withConfigObjectOptions(ctx context.Context, ec *model.ExportConfig) context.Context {
+	ctx = storage.WithObjectOptions(ctx, storage.ObjectOptions{
+		CacheControl: ec.CacheControl,
+		StorageClass: ec.StorageClass,
+	})
+	return storage.WithMetadata(ctx, ec.ObjectMetadata)
+}
+
+// withFileMetadata returns a context whose objects are created with the
+// number of keys in the export file and the IDs of the keys that sign it, so
+// downstream automation does not have to parse the file.
+func withFileMetadata(ctx context.Context, cfi *createFileInfo) context.Context {
+	keyIDs := make([]string, 0, len(cfi.signatureInfos))
+	for _, si := range cfi.signatureInfos {
+		if si.SigningKeyID != "" {
+			keyIDs = append(keyIDs, si.SigningKeyID)
 		}
+	}
+	return storage.WithMetadata(ctx, map[string]string{
+		model.MetadataKeyCount:        strconv.Itoa(len(cfi.exposures) + len(cfi.revisedExposures)),
+		model.MetadataSignatureKeyIDs: strings.Join(keyIDs, ","),
+	})
+}
 
-		logger.Infof("Wrote index file %q with %d entries (triggered by batch %d)", indexName, entries, eb.BatchID)
-		if err := unlock(); err != nil {
-			return fmt.Errorf("releasing lock: %w", err)
+// publishedEvent returns the outbox event recorded when a batch is finalized.
+// If the index was written, the relay purges the CDN cache of the index so
+// clients see new exports without waiting for the cached index to expire.
+func (s *Server) publishedEvent(ctx context.Context, eb *model.ExportBatch, objectNames []string, indexWritten bool) (*outboxmodel.Event, error) {
+	payload := &outboxmodel.ExportPublished{
+		ConfigID:     eb.ConfigID,
+		BatchID:      eb.BatchID,
+		BucketName:   eb.BucketName,
+		OutputRegion: eb.OutputRegion,
+		Files:        objectNames,
+		RunID:        scheduler.RunID(ctx),
+		BatchRunID:   eb.RunID,
+	}
+	if indexWritten {
+		indexName := exportIndexFilename(eb)
+		payload.Paths = []string{indexName}
+		if s.config.SignIndex {
+			payload.Paths = append(payload.Paths, indexName+IndexSignatureSuffix)
 		}
-		break
 	}
-	return nil
+	return outboxmodel.NewEvent(outboxmodel.KindExportPublished, payload, time.Now())
 }
 
 // markExpiredFiles marks previously created files for deletion where the TTL has expired.
 // These get cleaned up in the cleanup task.
+// The TTL of the output region overrides CLEANUP_TTL.
 func (s *Server) markExpiredFiles(ctx context.Context, eb *model.ExportBatch) error {
 	db := s.env.Database()
 	logger := logging.FromContext(ctx)
-	num, err := exportdatabase.New(db).MarkExpiredFiles(ctx, eb.ConfigID, s.config.TTL)
+	num, err := exportdatabase.New(db).MarkExpiredFiles(ctx, eb.ConfigID, s.ttl(eb))
 	if err != nil {
 		return err
 	}
@@ -484,10 +941,16 @@ func (s *Server) markExpiredFiles(ctx context.Context, eb *model.ExportBatch) er
 	return nil
 }
 
+// ttl returns how long the files of the batch are kept and listed in the
+// index.
+func (s *Server) ttl(eb *model.ExportBatch) time.Duration {
+	return s.retention.For(s.config.TTL, eb.OutputRegion)
+}
+
 func (s *Server) createIndex(ctx context.Context, eb *model.ExportBatch, newObjectNames []string) (string, int, error) {
 	db := s.env.Database()
 
-	objects, err := exportdatabase.New(db).LookupExportFiles(ctx, eb.ConfigID, s.config.TTL)
+	objects, err := exportdatabase.New(db).LookupExportFiles(ctx, eb.ConfigID, s.ttl(eb))
 	if err != nil {
 		return "", 0, fmt.Errorf("lookup available export files: %w", err)
 	}
@@ -508,15 +971,57 @@ func (s *Server) createIndex(ctx context.Context, eb *model.ExportBatch, newObje
 
 	data := []byte(strings.Join(objects, "\n"))
 
+	// Sign before writing, so a signing failure leaves the previous index and
+	// signature in place.
+	var signature []byte
+	if s.config.SignIndex {
+		if signature, err = s.signIndex(ctx, eb, data); err != nil {
+			return "", 0, fmt.Errorf("signing index: %w", err)
+		}
+	}
+
 	indexObjectName := exportIndexFilename(eb)
 	ctx, cancel := context.WithTimeout(ctx, blobOperationTimeout)
 	defer cancel()
 	if err := s.env.Blobstore().CreateObject(ctx, eb.BucketName, indexObjectName, data, false, storage.ContentTypeTextPlain); err != nil {
 		return "", 0, fmt.Errorf("creating index file %s in bucket %s: %w", indexObjectName, eb.BucketName, err)
 	}
+	if signature != nil {
+		signatureObjectName := indexObjectName + IndexSignatureSuffix
+		if err := s.env.Blobstore().CreateObject(ctx, eb.BucketName, signatureObjectName, signature, false, ContentTypeJOSE); err != nil {
+			return "", 0, fmt.Errorf("creating index signature %s in bucket %s: %w", signatureObjectName, eb.BucketName, err)
+		}
+	}
 	return indexObjectName, len(objects), nil
 }
 
+// signIndex returns a detached JWS of the index for each of the batch's
+// current signing keys, one per line. Clients verify the line whose key ID and
+// version they trust, so keys can be rotated without breaking verification.
+func (s *Server) signIndex(ctx context.Context, eb *model.ExportBatch, data []byte) ([]byte, error) {
+	sigInfos, err := exportdatabase.New(s.env.Database()).LookupSignatureInfos(ctx, eb.SignatureInfoIDs, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("loading signature infos: %w", err)
+	}
+	if len(sigInfos) == 0 {
+		return nil, fmt.Errorf("export config %d has no current signing keys", eb.ConfigID)
+	}
+
+	lines := make([]string, 0, len(sigInfos))
+	for _, si := range sigInfos {
+		signer, err := s.signerForKey(ctx, si.SigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get signer for key %v: %w", si.SigningKey, err)
+		}
+		signed, err := jws.Sign(signer, jws.Header{KeyID: si.SigningKeyID, KeyVersion: si.SigningKeyVersion}, data)
+		if err != nil {
+			return nil, fmt.Errorf("signing with key %v: %w", si.SigningKey, err)
+		}
+		lines = append(lines, signed.Detached())
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
 // The batchNum is still needed in the filename to preserve a stable filename sort
 // order when generating the index file.
 func exportFilename(eb *model.ExportBatch, fileNum int32, regenCount int64) string {
@@ -525,6 +1030,15 @@ func exportFilename(eb *model.ExportBatch, fileNum int32, regenCount int64) stri
 	return fmt.Sprintf("%s/%d-%d-%05d%s", eb.FilenameRoot, sTime, eTime, fileNum, filenameSuffix)
 }
 
+const (
+	// IndexSignatureSuffix is appended to the name of an index file for its
+	// detached signature.
+	IndexSignatureSuffix = ".jws"
+
+	// ContentTypeJOSE is the content type of index signatures.
+	ContentTypeJOSE = "application/jose"
+)
+
 func exportIndexFilename(eb *model.ExportBatch) string {
 	return fmt.Sprintf("%s/index.txt", eb.FilenameRoot)
 }
@@ -552,29 +1066,9 @@ func ensureMinNumExposures(exposures []*publishmodel.Exposure, region string, mi
 	for len(exposures) < target {
 		// loop through the source data
 		for fromIdx := 0; fromIdx < sourceLen; fromIdx++ {
-			// Pieces needed are
-			// (1) exposure key, (2) interval number, (3) transmission risk
-			// Exposure key is 16 random bytes.
-			eKey := make([]byte, verifyapi.KeyLength)
-			_, err := rand.Read(eKey)
+			ek, err := generateExposure(exposures[fromIdx], createdAt)
 			if err != nil {
-				return nil, nil, fmt.Errorf("rand.Read: %w", err)
-			}
-
-			ek := &publishmodel.Exposure{
-				ExposureKey:           eKey,
-				TransmissionRisk:      exposures[fromIdx].TransmissionRisk,
-				AppPackageName:        exportAppPackageName,
-				Regions:               exposures[fromIdx].Regions,
-				Traveler:              exposures[fromIdx].Traveler,
-				IntervalNumber:        exposures[fromIdx].IntervalNumber,
-				IntervalCount:         exposures[fromIdx].IntervalCount,
-				CreatedAt:             createdAt,
-				LocalProvenance:       true,
-				ReportType:            exposures[fromIdx].ReportType,
-				DaysSinceSymptomOnset: exposures[fromIdx].DaysSinceSymptomOnset,
-				// key revision fields are not used here - generated data only covers primary keys.
-				// The rest of the publishmodel.Exposure fields are not used in the export file.
+				return nil, nil, err
 			}
 			generated = append(generated, ek)
 			exposures = append(exposures, ek)
@@ -587,3 +1081,33 @@ func ensureMinNumExposures(exposures []*publishmodel.Exposure, region string, mi
 
 	return exposures, generated, nil
 }
+
+// generateExposure returns a random key that looks like the given exposure.
+// Generated keys are flagged with exportAppPackageName.
+func generateExposure(from *publishmodel.Exposure, createdAt time.Time) (*publishmodel.Exposure, error) {
+	// Pieces needed are
+	// (1) exposure key, (2) interval number, (3) transmission risk
+	// Exposure key is 16 random bytes.
+	eKey := make([]byte, verifyapi.KeyLength)
+	if _, err := rand.Read(eKey); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+
+	return &publishmodel.Exposure{
+		ExposureKey:           eKey,
+		TransmissionRisk:      from.TransmissionRisk,
+		AppPackageName:        exportAppPackageName,
+		Regions:               from.Regions,
+		Traveler:              from.Traveler,
+		IntervalNumber:        from.IntervalNumber,
+		IntervalCount:         from.IntervalCount,
+		CreatedAt:             createdAt,
+		LocalProvenance:       true,
+		ProvenanceSource:      publishmodel.ProvenanceGenerated,
+		ReportType:            from.ReportType,
+		DaysSinceSymptomOnset: from.DaysSinceSymptomOnset,
+		KeyMetadata:           from.KeyMetadata,
+		// key revision fields are not used here - generated data only covers primary keys.
+		// The rest of the publishmodel.Exposure fields are not used in the export file.
+	}, nil
+}
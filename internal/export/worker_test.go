@@ -15,15 +15,28 @@
 package export
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
 	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/jws"
+	outboxmodel "github.com/google/exposure-notifications-server/internal/outbox/model"
 	"github.com/google/exposure-notifications-server/internal/project"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/exposure-notifications-server/internal/travelrule"
+	travelrulemodel "github.com/google/exposure-notifications-server/internal/travelrule/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	"github.com/google/exposure-notifications-server/pkg/keys"
 	"github.com/google/exposure-notifications-server/pkg/util"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/proto"
@@ -255,7 +268,7 @@ func TestBatchExposures(t *testing.T) {
 					OnlyLocalProvenance: true,
 				}
 
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "US")
+				groups, err := readBatch(ctx, &server, criteria, config.MaxRecords, config.MinRecords, "US")
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -286,7 +299,7 @@ func TestBatchExposures(t *testing.T) {
 					OnlyLocalProvenance: false,
 				}
 
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "REMOTE")
+				groups, err := readBatch(ctx, &server, criteria, config.MaxRecords, config.MinRecords, "REMOTE")
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -314,7 +327,7 @@ func TestBatchExposures(t *testing.T) {
 					IncludeTravelers:    true,
 					OnlyLocalProvenance: true,
 				}
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "US")
+				groups, err := readBatch(ctx, &server, criteria, config.MaxRecords, config.MinRecords, "US")
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -347,7 +360,7 @@ func TestBatchExposures(t *testing.T) {
 					OnlyNonTravelers:    true,
 					OnlyLocalProvenance: false,
 				}
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "REMOTE")
+				groups, err := readBatch(ctx, &server, criteria, config.MaxRecords, config.MinRecords, "REMOTE")
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -429,7 +442,7 @@ func TestVariableBatchMaxSize(t *testing.T) {
 			OnlyLocalProvenance: true,
 		}
 
-		groups, err := server.batchExposures(ctx, criteria, batchSize, "REMOTE")
+		groups, err := readBatch(ctx, &server, criteria, batchSize, config.MinRecords, "REMOTE")
 		if err != nil {
 			t.Fatalf("failed to read exposures: %v", err)
 		}
@@ -454,6 +467,29 @@ func TestVariableBatchMaxSize(t *testing.T) {
 	}
 }
 
+// readBatch reads all the files of a batch into memory.
+func readBatch(ctx context.Context, s *Server, criteria publishdb.IterateExposuresCriteria, maxRecords, padTo int, outputRegion string) ([]*group, error) {
+	iterate := publishdb.New(s.env.Database()).IterateExposures
+	plan, err := s.planBatch(ctx, iterate, criteria, maxRecords, padTo, outputRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*group
+	if err := s.streamFiles(ctx, iterate, plan, plan.files, nil, func(i int, g *group) error {
+		if i == len(plan.files)-1 {
+			if err := s.padFile(ctx, plan, g); err != nil {
+				return err
+			}
+		}
+		groups = append(groups, g)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
 // randomTEK is like util.RandomTEK, but handles the error from tb.
 func randomTEK(tb testing.TB) []byte {
 	tb.Helper()
@@ -522,3 +558,541 @@ func TestExportFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyTravelRules(t *testing.T) {
+	t.Parallel()
+
+	rules := travelrule.NewEngine([]*travelrulemodel.TravelRule{
+		{
+			Name:          "eu-travelers",
+			InputRegions:  []string{"DE", "FR"},
+			TravelersOnly: true,
+			OutputRegions: []string{"DE", "FR"},
+			ApplyToExport: true,
+		},
+		{
+			Name:          "at-into-de",
+			InputRegions:  []string{"AT"},
+			OutputRegions: []string{"DE"},
+			ApplyToExport: true,
+		},
+	})
+
+	cases := []struct {
+		name string
+		eb   *model.ExportBatch
+		exp  publishdb.IterateExposuresCriteria
+	}{
+		{
+			name: "de",
+			eb:   &model.ExportBatch{OutputRegion: "DE"},
+			exp: publishdb.IterateExposuresCriteria{
+				IncludeRegions:       []string{"DE", "AT"},
+				IncludeTravelersFrom: []string{"DE", "FR"},
+			},
+		},
+		{
+			name: "de_only_non_travelers",
+			eb:   &model.ExportBatch{OutputRegion: "DE", OnlyNonTravelers: true},
+			exp: publishdb.IterateExposuresCriteria{
+				IncludeRegions: []string{"DE", "AT"},
+			},
+		},
+		{
+			name: "us",
+			eb:   &model.ExportBatch{OutputRegion: "US"},
+			exp: publishdb.IterateExposuresCriteria{
+				IncludeRegions: []string{"US"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			criteria := publishdb.IterateExposuresCriteria{
+				IncludeRegions: tc.eb.EffectiveInputRegions(),
+			}
+			applyTravelRules(&criteria, rules, tc.eb)
+			if diff := cmp.Diff(tc.exp, criteria); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBatchSplitTime(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+	hour := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+
+	// fakeIterate returns exposures created at the given times, in order,
+	// applying the time window and limit of the criteria.
+	fakeIterate := func(times ...time.Time) iterateExposuresFunc {
+		return func(ctx context.Context, c publishdb.IterateExposuresCriteria, f publishdb.IteratorFunction) (string, error) {
+			n := 0
+			for _, ts := range times {
+				if ts.Before(c.SinceTimestamp) || !ts.Before(c.UntilTimestamp) {
+					continue
+				}
+				if c.Limit > 0 && n >= int(c.Limit) {
+					break
+				}
+				n++
+				if err := f(&publishmodel.Exposure{CreatedAt: ts}); err != nil {
+					return "", err
+				}
+			}
+			return "", nil
+		}
+	}
+
+	cases := []struct {
+		name    string
+		times   []time.Time
+		maxKeys int
+		want    time.Time
+	}{
+		{
+			name:    "empty",
+			maxKeys: 2,
+		},
+		{
+			name:    "under_cap",
+			times:   []time.Time{hour(0), hour(1)},
+			maxKeys: 2,
+		},
+		{
+			name:    "over_cap",
+			times:   []time.Time{hour(0), hour(1), hour(2)},
+			maxKeys: 2,
+			want:    hour(2),
+		},
+		{
+			name:    "over_cap_shared_timestamp",
+			times:   []time.Time{hour(0), hour(1), hour(1), hour(3)},
+			maxKeys: 2,
+			want:    hour(1),
+		},
+		{
+			name:    "first_timestamp_over_cap",
+			times:   []time.Time{hour(0), hour(0), hour(0), hour(2)},
+			maxKeys: 2,
+			want:    hour(2),
+		},
+		{
+			name:    "single_timestamp_over_cap",
+			times:   []time.Time{hour(0), hour(0), hour(0)},
+			maxKeys: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			criteria := publishdb.IterateExposuresCriteria{
+				SinceTimestamp: start,
+				UntilTimestamp: end,
+			}
+
+			got, err := batchSplitTime(ctx, fakeIterate(tc.times...), criteria, tc.maxKeys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("expected %v to be %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNoiseCount(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		n, percent, want int
+	}{
+		{0, 10, 0},
+		{1, 10, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{200, 25, 50},
+	}
+
+	for _, tc := range cases {
+		if got := noiseCount(tc.n, tc.percent); got != tc.want {
+			t.Errorf("noiseCount(%d, %d): expected %d to be %d", tc.n, tc.percent, got, tc.want)
+		}
+	}
+}
+
+func TestMemoryBudgetRecords(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		maxRecords int
+		budget     int64
+		want       int
+	}{
+		{"disabled", 500, 0, 500},
+		{"negative", 500, -1, 500},
+		{"within_budget", 500, 1 << 20, 500},
+		{"over_budget", 500, 96 * 100, 100},
+		{"tiny_budget", 500, 1, 1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := memoryBudgetRecords(tc.maxRecords, tc.budget); got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNoiseKeys(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	makeKeys := func(n int, appPackageName string) []*publishmodel.Exposure {
+		keys := make([]*publishmodel.Exposure, 0, n)
+		for i := 0; i < n; i++ {
+			keys = append(keys, &publishmodel.Exposure{
+				ExposureKey:      []byte(fmt.Sprintf("%016d", i)),
+				AppPackageName:   appPackageName,
+				Regions:          []string{"US"},
+				IntervalNumber:   int32(100 + i),
+				IntervalCount:    144,
+				TransmissionRisk: 2,
+			})
+		}
+		return keys
+	}
+
+	cases := []struct {
+		name          string
+		realKeys      int
+		generatedKeys int
+		maxKeys       int
+		want          int
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "adds_noise",
+			realKeys: 20,
+			want:     10,
+		},
+		{
+			name:     "over_max",
+			realKeys: 20,
+			maxKeys:  20,
+		},
+		{
+			name:          "counts_previous_noise",
+			realKeys:      20,
+			generatedKeys: 4,
+			want:          6,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := &Server{
+				config: &Config{
+					NoisePercent: 50,
+					NoiseMaxKeys: tc.maxKeys,
+				},
+			}
+
+			n, err := server.noiseKeys(tc.realKeys, tc.generatedKeys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tc.want {
+				t.Fatalf("expected %d noise keys to be %d", n, tc.want)
+			}
+
+			noise, err := generateNoise(makeKeys(tc.realKeys, "app"), n, createdAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(noise); got != tc.want {
+				t.Fatalf("expected %d noise keys to be %d", got, tc.want)
+			}
+			for _, exp := range noise {
+				if got, want := len(exp.ExposureKey), verifyapi.KeyLength; got != want {
+					t.Errorf("expected key length %d to be %d", got, want)
+				}
+				if got, want := exp.AppPackageName, exportAppPackageName; got != want {
+					t.Errorf("expected app package %q to be %q", got, want)
+				}
+				if !exp.CreatedAt.Equal(createdAt) {
+					t.Errorf("expected created at %v to be %v", exp.CreatedAt, createdAt)
+				}
+			}
+		})
+	}
+}
+
+func TestSignIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	db := exportdatabase.New(testDB)
+	km := keys.TestKeyManager(t)
+
+	current := &model.SignatureInfo{
+		SigningKey:        keys.TestSigningKey(t, km),
+		SigningKeyID:      "310",
+		SigningKeyVersion: "v2",
+	}
+	expired := &model.SignatureInfo{
+		SigningKey:        keys.TestSigningKey(t, km),
+		SigningKeyID:      "310",
+		SigningKeyVersion: "v1",
+		EndTimestamp:      time.Now().Add(-time.Hour),
+	}
+	for _, si := range []*model.SignatureInfo{current, expired} {
+		if err := db.AddSignatureInfo(ctx, si); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := &Server{
+		config: &Config{SignIndex: true},
+		env:    serverenv.New(ctx, serverenv.WithDatabase(testDB), serverenv.WithKeyManager(km)),
+	}
+
+	index := []byte("exposureKeyExport-US/1-2-00001.zip\nexposureKeyExport-US/2-3-00001.zip")
+	eb := &model.ExportBatch{ConfigID: 1, SignatureInfoIDs: []int64{current.ID, expired.ID}}
+	signature, err := server.signIndex(ctx, eb, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the current key signs.
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 signature, got %d: %q", len(lines), signature)
+	}
+	signed, header, err := jws.ParseDetached(lines[0], index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := header.KeyVersion, current.SigningKeyVersion; got != want {
+		t.Errorf("expected key version %q to be %q", got, want)
+	}
+
+	signer, err := km.NewSigner(ctx, current.SigningKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signed.Verify(signer.Public().(*ecdsa.PublicKey)); err != nil {
+		t.Errorf("expected signature to verify: %v", err)
+	}
+
+	// Without a current key the index cannot be signed.
+	eb.SignatureInfoIDs = []int64{expired.ID}
+	if _, err := server.signIndex(ctx, eb, index); err == nil {
+		t.Errorf("expected error without a current signing key")
+	}
+}
+
+func TestDerivedKeys(t *testing.T) {
+	t.Parallel()
+
+	exp := func(key, region, reportType string) *publishmodel.Exposure {
+		return &publishmodel.Exposure{
+			ExposureKey: []byte(key),
+			Regions:     []string{region},
+			ReportType:  reportType,
+		}
+	}
+	revised := exp("r1", "US", "likely")
+	revisedTo := "confirmed"
+	revised.RevisedReportType = &revisedTo
+
+	groups := []*group{
+		{
+			exposures: []*publishmodel.Exposure{
+				exp("k1", "US", "confirmed"),
+				exp("k2", "CA", "confirmed"),
+				exp("k3", "US", "likely"),
+			},
+		},
+		{
+			exposures: []*publishmodel.Exposure{exp("k4", "US", "confirmed")},
+			revised:   []*publishmodel.Exposure{revised},
+		},
+	}
+
+	name := func(id exposureKeyID) string {
+		return strings.TrimRight(string(id[:]), "\x00")
+	}
+
+	cases := []struct {
+		name string
+		ec   *model.ExportConfig
+		want []string
+	}{
+		{
+			name: "no_filters",
+			ec:   &model.ExportConfig{},
+			want: []string{"k1", "k2", "k3", "k4", "revised:r1"},
+		},
+		{
+			name: "regions",
+			ec:   &model.ExportConfig{FilterRegions: []string{"CA"}},
+			want: []string{"k2"},
+		},
+		{
+			name: "report_types_use_revised_type",
+			ec:   &model.ExportConfig{FilterReportTypes: []string{"confirmed"}},
+			want: []string{"k1", "k2", "k4", "revised:r1"},
+		},
+		{
+			name: "exclude_revised",
+			ec:   &model.ExportConfig{FilterRegions: []string{"US"}, ExcludeRevised: true},
+			want: []string{"k1", "k3", "k4"},
+		},
+		{
+			name: "nothing",
+			ec:   &model.ExportConfig{FilterRegions: []string{"MX"}},
+			want: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := &derivedKeys{ec: tc.ec}
+			for _, g := range groups {
+				d.add(g)
+			}
+
+			var got []string
+			for _, id := range d.primaryKeys {
+				got = append(got, name(id))
+			}
+			for _, id := range d.revisedKeys {
+				got = append(got, "revised:"+name(id))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPublishedEvent_RunIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := scheduler.WithRunID(project.TestContext(t), "worker-run")
+	server := &Server{config: &Config{}}
+
+	eb := &model.ExportBatch{BatchID: 7, ConfigID: 3, FilenameRoot: "root", OutputRegion: "US", RunID: "batcher-run"}
+	event, err := server.publishedEvent(ctx, eb, []string{"root/1-2-00001.zip"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload outboxmodel.ExportPublished
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := payload.RunID, "worker-run"; got != want {
+		t.Errorf("expected run ID %q to be %q", got, want)
+	}
+	if got, want := payload.BatchRunID, "batcher-run"; got != want {
+		t.Errorf("expected batch run ID %q to be %q", got, want)
+	}
+
+	blobstore, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blobstore.CreateObject(withBatchMetadata(ctx, eb), "bucket", "object", nil, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"batch_id": "7", "batch_run_id": "batcher-run", "run_id": "worker-run"}
+	if diff := cmp.Diff(want, blobstore.(*storage.Memory).Metadata("bucket", "object")); diff != "" {
+		t.Errorf("metadata mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestObjectOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	eb := &model.ExportBatch{BatchID: 7, ConfigID: 3, FilenameRoot: "root", OutputRegion: "US"}
+	ec := &model.ExportConfig{
+		ConfigID:       3,
+		CacheControl:   "public, max-age=3600",
+		StorageClass:   "NEARLINE",
+		ObjectMetadata: map[string]string{"program": "en"},
+	}
+	cfi := &createFileInfo{
+		exposures:        make([]*publishmodel.Exposure, 3),
+		revisedExposures: make([]*publishmodel.Exposure, 1),
+		exportBatch:      eb,
+		signatureInfos:   []*model.SignatureInfo{{SigningKeyID: "310"}, {SigningKeyID: ""}, {SigningKeyID: "311"}},
+	}
+
+	blobstore, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := blobstore.(*storage.Memory)
+
+	ctx = withConfigObjectOptions(withBatchMetadata(ctx, eb), ec)
+	if err := blobstore.CreateObject(withFileMetadata(ctx, cfi), "bucket", "file", nil, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := blobstore.CreateObject(ctx, "bucket", "index", nil, false, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	wantMetadata := map[string]string{"batch_id": "7", "program": "en", "key_count": "4", "signature_key_ids": "310,311"}
+	if diff := cmp.Diff(wantMetadata, mem.Metadata("bucket", "file")); diff != "" {
+		t.Errorf("file metadata mismatch (-want, +got):\n%s", diff)
+	}
+	wantMetadata = map[string]string{"batch_id": "7", "program": "en"}
+	if diff := cmp.Diff(wantMetadata, mem.Metadata("bucket", "index")); diff != "" {
+		t.Errorf("index metadata mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The index is never cached.
+	want := storage.ObjectOptions{CacheControl: "public, max-age=3600", StorageClass: "NEARLINE"}
+	if diff := cmp.Diff(want, mem.Options("bucket", "file")); diff != "" {
+		t.Errorf("file options mismatch (-want, +got):\n%s", diff)
+	}
+	want = storage.ObjectOptions{CacheControl: "no-cache, max-age=0", StorageClass: "NEARLINE"}
+	if diff := cmp.Diff(want, mem.Options("bucket", "index")); diff != "" {
+		t.Errorf("index options mismatch (-want, +got):\n%s", diff)
+	}
+}
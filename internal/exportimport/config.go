@@ -17,6 +17,9 @@ package exportimport
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/observability"
@@ -32,18 +35,48 @@ var (
 type Config struct {
 	Database              database.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
 	SecretManager         secrets.Config
+	Scheduler             scheduler.Config
 
 	Port string `env:"PORT, default=8080"`
 
 	IndexFileDownloadTimeout  time.Duration `env:"INDEX_FILE_DOWNLOAD_TIMEOUT, default=30s"`
 	ExportFileDownloadTimeout time.Duration `env:"EXPORT_FILE_DOWNLOAD_TIMEOUT, default=2m"`
 
+	// DownloadMaxRetries is the number of times a failed download of an index
+	// or export file is retried. DownloadRetryBackoff is the delay before the
+	// first retry, which doubles with each retry, and DownloadRetryBudget
+	// bounds the time spent retrying one download. Missing files are not
+	// retried.
+	DownloadMaxRetries   uint64        `env:"DOWNLOAD_MAX_RETRIES, default=3"`
+	DownloadRetryBackoff time.Duration `env:"DOWNLOAD_RETRY_BACKOFF, default=1s"`
+	DownloadRetryBudget  time.Duration `env:"DOWNLOAD_RETRY_BUDGET, default=1m"`
+
+	// FileRoots are the local directories, such as mounted volumes, that
+	// file:// index files and export roots may be read from. file:// URLs are
+	// rejected if they are not under one of them.
+	FileRoots []string `env:"IMPORT_FILE_ROOTS"`
+
+	// SFTPPrivateKey is the PEM encoded private key the importer authenticates
+	// with to sftp:// index files and export roots, as the user of the URL. It
+	// may come from secret://.
+	SFTPPrivateKey string `env:"SFTP_PRIVATE_KEY"`
+
+	// SFTPKnownHosts pins the host keys of the SFTP servers, in the
+	// known_hosts format. Servers without a pinned key are rejected.
+	SFTPKnownHosts string `env:"SFTP_KNOWN_HOSTS"`
+
 	// For importing files that may have missed setting v1.5+ fields.
 	BackfillReportType          string `env:"BACKFILL_REPORT_TYPE, default=confirmed"`
 	BackfillDaysSinceOnset      bool   `env:"BACKFILL_DAYS_SINCE_ONSET, default=true"`
 	BackfillDaysSinceOnsetValue int    `env:"BACKFILL_DAYS_SINCE_ONSET_VALUE, default=10"`
 
+	// KeyMetadata, if true, keeps the per-key fields of later export format
+	// revisions, such as the variant of concern, so they can be exported
+	// again. Otherwise they are dropped on import.
+	KeyMetadata bool `env:"IMPORT_KEY_METADATA, default=false"`
+
 	MaxInsertBatchSize           int           `env:"MAX_INSERT_BATCH_SIZE, default=100"`
 	MaxIntervalAge               time.Duration `env:"MAX_INTERVAL_AGE_ON_PUBLISH, default=360h"`
 	MaxMagnitudeSymptomOnsetDays uint          `env:"MAX_SYMPTOM_ONSET_DAYS, default=14"`
@@ -61,6 +94,32 @@ type Config struct {
 	// ImportRetryRate is the rate at which files that encounter an error while
 	// importing are retried.
 	ImportRetryRate time.Duration `env:"IMPORT_RETRY_RATE, default=6h"`
+	// ImportBatchMaxRetries is the number of times a batch that is missing
+	// files is retried in batch strictness before it is rejected.
+	ImportBatchMaxRetries uint `env:"IMPORT_BATCH_MAX_RETRIES, default=4"`
+	// ImportFileConcurrency is the number of files of an import config that
+	// are downloaded and imported at once. Files in batch strictness are
+	// always imported one at a time, in the order of the index.
+	ImportFileConcurrency int `env:"IMPORT_FILE_CONCURRENCY, default=1"`
+
+	// The limits on the export archives the importer opens, which protect it
+	// from zip bombs and corrupt archives: the number of files in an archive,
+	// their total uncompressed size in bytes and the ratio of the uncompressed
+	// to the compressed size of a file. Archives that are over a limit, or
+	// that contain another archive, are rejected and quarantined. Files
+	// downloaded over SFTP are also limited to the uncompressed size.
+	MaxArchiveFiles            int     `env:"IMPORT_MAX_ARCHIVE_FILES, default=8"`
+	MaxArchiveUncompressedSize int64   `env:"IMPORT_MAX_ARCHIVE_UNCOMPRESSED_SIZE, default=68157440"`
+	MaxArchiveCompressionRatio float64 `env:"IMPORT_MAX_ARCHIVE_COMPRESSION_RATIO, default=100"`
+}
+
+// ArchiveLimits returns the limits on the export archives the importer opens.
+func (c *Config) ArchiveLimits() export.ArchiveLimits {
+	return export.ArchiveLimits{
+		MaxFiles:            c.MaxArchiveFiles,
+		MaxUncompressedSize: c.MaxArchiveUncompressedSize,
+		MaxCompressionRatio: c.MaxArchiveCompressionRatio,
+	}
 }
 
 func (c *Config) DatabaseConfig() *database.Config {
@@ -23,7 +23,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/exportimport/model"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v5"
 )
 
 // ExportImportDB contains database methods for managing with export-import configs.
@@ -44,7 +44,8 @@ func (db *ExportImportDB) GetConfig(ctx context.Context, id int64) (*model.Expor
 	if err := db.db.InTx(ctx, pgx.RepeatableRead, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			SELECT
-				id, index_file, export_root, region, traveler, from_timestamp, thru_timestamp
+				id, index_file, export_root, region, traveler, from_timestamp, thru_timestamp, strictness,
+				last_batch_start, last_batch_end
 			FROM
 				exportimport
 			WHERE
@@ -71,7 +72,8 @@ func (db *ExportImportDB) ListConfigs(ctx context.Context) ([]*model.ExportImpor
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				id, index_file, export_root, region, traveler, from_timestamp, thru_timestamp
+				id, index_file, export_root, region, traveler, from_timestamp, thru_timestamp, strictness,
+				last_batch_start, last_batch_end
 			FROM
 				exportimport
 			ORDER BY id ASC
@@ -107,7 +109,8 @@ func (db *ExportImportDB) ActiveConfigs(ctx context.Context) ([]*model.ExportImp
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				id, index_file, export_root, region, traveler, from_timestamp, thru_timestamp
+				id, index_file, export_root, region, traveler, from_timestamp, thru_timestamp, strictness,
+				last_batch_start, last_batch_end
 			FROM
 				exportimport
 			WHERE
@@ -146,7 +149,8 @@ func scanOneConfig(row pgx.Row) (*model.ExportImport, error) {
 		thru *time.Time
 	)
 
-	if err := row.Scan(&m.ID, &m.IndexFile, &m.ExportRoot, &m.Region, &m.Traveler, &m.From, &thru); err != nil {
+	if err := row.Scan(&m.ID, &m.IndexFile, &m.ExportRoot, &m.Region, &m.Traveler, &m.From, &thru, &m.Strictness,
+		&m.LastBatchStart, &m.LastBatchEnd); err != nil {
 		return nil, err
 	}
 	if thru != nil {
@@ -166,11 +170,11 @@ func (db *ExportImportDB) AddConfig(ctx context.Context, ei *model.ExportImport)
 		row := tx.QueryRow(ctx, `
 			INSERT INTO
 			ExportImport
-				(index_file, export_root, region, traveler, from_timestamp, thru_timestamp)
+				(index_file, export_root, region, traveler, from_timestamp, thru_timestamp, strictness)
 			VALUES
-				($1, $2, $3, $4, $5, $6)
+				($1, $2, $3, $4, $5, $6, $7)
 			RETURNING id
-		`, ei.IndexFile, ei.ExportRoot, ei.Region, ei.Traveler, ei.From, ei.Thru)
+		`, ei.IndexFile, ei.ExportRoot, ei.Region, ei.Traveler, ei.From, ei.Thru, ei.StrictnessLevel())
 
 		if err := row.Scan(&ei.ID); err != nil {
 			return fmt.Errorf("fetching exportimport.ID: %w", err)
@@ -191,9 +195,10 @@ func (db *ExportImportDB) UpdateConfig(ctx context.Context, c *model.ExportImpor
 			UPDATE
 				ExportImport
 			SET
-				index_file = $1, export_root = $2, region = $3, traveler = $4, from_timestamp = $5, thru_timestamp = $6
-			WHERE id = $7
-		`, c.IndexFile, c.ExportRoot, c.Region, c.Traveler, from, c.Thru, c.ID)
+				index_file = $1, export_root = $2, region = $3, traveler = $4, from_timestamp = $5, thru_timestamp = $6,
+				strictness = $7
+			WHERE id = $8
+		`, c.IndexFile, c.ExportRoot, c.Region, c.Traveler, from, c.Thru, c.StrictnessLevel(), c.ID)
 		if err != nil {
 			return fmt.Errorf("failed to update export importer config: %w", err)
 		}
@@ -209,6 +214,30 @@ func (db *ExportImportDB) UpdateConfig(ctx context.Context, c *model.ExportImpor
 	})
 }
 
+// SaveLastBatch records the timestamps of the last batch that was imported
+// with batch strictness.
+func (db *ExportImportDB) SaveLastBatch(ctx context.Context, ei *model.ExportImport, start, end time.Time) error {
+	start, end = start.UTC(), end.UTC()
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE
+				ExportImport
+			SET
+				last_batch_start = $1, last_batch_end = $2
+			WHERE id = $3
+		`, start, end, ei.ID)
+		if err != nil {
+			return fmt.Errorf("failed to save last batch: %w", err)
+		}
+		if result.RowsAffected() != 1 {
+			return fmt.Errorf("no rows updated")
+		}
+		ei.LastBatchStart = &start
+		ei.LastBatchEnd = &end
+		return nil
+	})
+}
+
 func (db *ExportImportDB) ExpireImportFilePublicKey(ctx context.Context, ifpk *model.ImportFilePublicKey) error {
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		now := time.Now().UTC()
@@ -495,10 +524,10 @@ func (db *ExportImportDB) CompleteImportFile(ctx context.Context, ef *model.Impo
 			UPDATE
 				ImportFile
 			SET
-				status=$1, processed_at=$2, retries=$3
+				status=$1, processed_at=$2, retries=$3, run_id=NULLIF($4, '')
 			WHERE
-				id=$4
-			`, ef.Status, ef.ProcessedAt, ef.Retries, ef.ID)
+				id=$5
+			`, ef.Status, ef.ProcessedAt, ef.Retries, ef.RunID, ef.ID)
 		if err != nil {
 			return fmt.Errorf("unable to mark complete: %w", err)
 		}
@@ -550,10 +579,10 @@ func (db *ExportImportDB) LeaseImportFile(ctx context.Context, lockDuration time
 			UPDATE
 				ImportFile
 			SET
-				status=$1, processed_at=$2
+				status=$1, processed_at=$2, run_id=NULLIF($3, '')
 			WHERE
-				id=$3
-			`, ef.Status, ef.ProcessedAt, ef.ID)
+				id=$4
+			`, ef.Status, ef.ProcessedAt, ef.RunID, ef.ID)
 		if err != nil {
 			return fmt.Errorf("unable to lock file: %w", err)
 		}
@@ -571,7 +600,7 @@ func (db *ExportImportDB) GetOpenImportFiles(ctx context.Context, lockDuration,
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				id, zip_filename, discovered_at, processed_at, status, retries
+				id, zip_filename, discovered_at, processed_at, status, retries, COALESCE(run_id, '')
 			FROM
 				ImportFile
 			WHERE
@@ -592,7 +621,7 @@ func (db *ExportImportDB) GetOpenImportFiles(ctx context.Context, lockDuration,
 			file := model.ImportFile{
 				ExportImportID: ei.ID,
 			}
-			if err := rows.Scan(&file.ID, &file.ZipFilename, &file.DiscoveredAt, &file.ProcessedAt, &file.Status, &file.Retries); err != nil {
+			if err := rows.Scan(&file.ID, &file.ZipFilename, &file.DiscoveredAt, &file.ProcessedAt, &file.Status, &file.Retries, &file.RunID); err != nil {
 				return fmt.Errorf("failed to scan rows: %w", err)
 			}
 
@@ -611,6 +640,50 @@ func (db *ExportImportDB) GetOpenImportFiles(ctx context.Context, lockDuration,
 	return importFiles, nil
 }
 
+// GetUnfinishedImportFiles returns the open and pending files for a config,
+// including the ones that are leased or waiting to be retried, in the order
+// they were scheduled.
+func (db *ExportImportDB) GetUnfinishedImportFiles(ctx context.Context, ei *model.ExportImport) ([]*model.ImportFile, error) {
+	var importFiles []*model.ImportFile
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				id, zip_filename, discovered_at, processed_at, status, retries, COALESCE(run_id, '')
+			FROM
+				ImportFile
+			WHERE
+				export_import_id = $1 AND status IN ($2, $3)
+			ORDER BY
+				id ASC
+		`, ei.ID, model.ImportFileOpen, model.ImportFilePending)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			file := model.ImportFile{
+				ExportImportID: ei.ID,
+			}
+			if err := rows.Scan(&file.ID, &file.ZipFilename, &file.DiscoveredAt, &file.ProcessedAt, &file.Status, &file.Retries, &file.RunID); err != nil {
+				return fmt.Errorf("failed to scan rows: %w", err)
+			}
+
+			importFiles = append(importFiles, &file)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to read unfinished import files: %w", err)
+	}
+
+	return importFiles, nil
+}
+
 // GetAllImportFiles returns all input files for a config, regardless of their state.
 // This function is used for testing.
 func (db *ExportImportDB) GetAllImportFiles(ctx context.Context, lockDuration time.Duration, ei *model.ExportImport) ([]*model.ImportFile, error) {
@@ -619,7 +692,7 @@ func (db *ExportImportDB) GetAllImportFiles(ctx context.Context, lockDuration ti
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				id, zip_filename, discovered_at, status, retries
+				id, zip_filename, discovered_at, status, retries, COALESCE(run_id, '')
 			FROM
 				ImportFile
 			WHERE
@@ -640,7 +713,7 @@ func (db *ExportImportDB) GetAllImportFiles(ctx context.Context, lockDuration ti
 			file := model.ImportFile{
 				ExportImportID: ei.ID,
 			}
-			if err := rows.Scan(&file.ID, &file.ZipFilename, &file.DiscoveredAt, &file.Status, &file.Retries); err != nil {
+			if err := rows.Scan(&file.ID, &file.ZipFilename, &file.DiscoveredAt, &file.Status, &file.Retries, &file.RunID); err != nil {
 				return fmt.Errorf("failed to scan rows: %w", err)
 			}
 
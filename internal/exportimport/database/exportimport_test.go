@@ -45,6 +45,7 @@ func TestAddGetUpdateExportConfig(t *testing.T) {
 			Region:     "US",
 			From:       fromTime,
 			Thru:       nil,
+			Strictness: model.StrictnessOff,
 		},
 		{
 			IndexFile:  "https://myserver2/exports/index.txt",
@@ -53,6 +54,7 @@ func TestAddGetUpdateExportConfig(t *testing.T) {
 			Traveler:   true,
 			From:       fromTime.Add(time.Hour),
 			Thru:       nil,
+			Strictness: model.StrictnessBatch,
 		},
 	}
 	for _, w := range want {
@@ -115,6 +117,23 @@ func TestAddGetUpdateExportConfig(t *testing.T) {
 			t.Errorf("mismatch (-want, +got):\n%s", diff)
 		}
 	}
+
+	// Save the last batch, which is kept when the config is updated.
+	if err := exportImportDB.SaveLastBatch(ctx, want[1], fromTime, fromTime.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to save last batch: %v", err)
+	}
+	if err := exportImportDB.UpdateConfig(ctx, want[1]); err != nil {
+		t.Fatalf("failed to update config: %v", err)
+	}
+	{
+		got, err := exportImportDB.GetConfig(ctx, want[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want[1], got, database.ApproxTime); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+	}
 }
 
 func TestAddImportFiles(t *testing.T) {
@@ -0,0 +1,84 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/exportimport/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// QuarantineImportFile records that an import file was rejected by the
+// archive limits of the importer. A file that is quarantined again replaces
+// its record.
+func (db *ExportImportDB) QuarantineImportFile(ctx context.Context, q *model.ImportFileQuarantine) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			INSERT INTO
+				ImportFileQuarantine
+				(import_file_id, export_import_id, zip_filename, reason, detail, quarantined_at)
+			VALUES
+				($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (import_file_id) DO UPDATE
+			SET
+				reason = EXCLUDED.reason, detail = EXCLUDED.detail, quarantined_at = EXCLUDED.quarantined_at
+		`, q.ImportFileID, q.ExportImportID, q.ZipFilename, q.Reason, q.Detail, q.QuarantinedAt)
+		if err != nil {
+			return fmt.Errorf("inserting import file quarantine: %w", err)
+		}
+		if result.RowsAffected() != 1 {
+			return fmt.Errorf("no rows inserted")
+		}
+		return nil
+	})
+}
+
+// ListQuarantinedImportFiles returns the most recently quarantined files of
+// the export import config, up to limit.
+func (db *ExportImportDB) ListQuarantinedImportFiles(ctx context.Context, ei *model.ExportImport, limit int) ([]*model.ImportFileQuarantine, error) {
+	var quarantined []*model.ImportFileQuarantine
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				import_file_id, export_import_id, zip_filename, reason, detail, quarantined_at
+			FROM
+				ImportFileQuarantine
+			WHERE
+				export_import_id = $1
+			ORDER BY
+				quarantined_at DESC
+			LIMIT $2
+		`, ei.ID, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var q model.ImportFileQuarantine
+			if err := rows.Scan(&q.ImportFileID, &q.ExportImportID, &q.ZipFilename, &q.Reason, &q.Detail, &q.QuarantinedAt); err != nil {
+				return fmt.Errorf("failed to scan: %w", err)
+			}
+			quarantined = append(quarantined, &q)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+	return quarantined, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/exportimport/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuarantineImportFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportImportDB := New(testDB)
+
+	config := model.ExportImport{
+		IndexFile:  "https://mysever/exports/index.txt",
+		ExportRoot: "https://myserver/",
+		Region:     "US",
+		From:       time.Now().UTC(),
+	}
+	if err := exportImportDB.AddConfig(ctx, &config); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := exportImportDB.CreateNewFilesAndFailOld(ctx, &config, []string{"a.zip"}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := exportImportDB.GetAllImportFiles(ctx, time.Minute, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+
+	q := &model.ImportFileQuarantine{
+		ImportFileID:   files[0].ID,
+		ExportImportID: config.ID,
+		ZipFilename:    files[0].ZipFilename,
+		Reason:         "file_count",
+		Detail:         "too many files",
+		QuarantinedAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := exportImportDB.QuarantineImportFile(ctx, q); err != nil {
+		t.Fatal(err)
+	}
+
+	// Quarantining the file again replaces the record.
+	q.Reason = "compression_ratio"
+	if err := exportImportDB.QuarantineImportFile(ctx, q); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := exportImportDB.ListQuarantinedImportFiles(ctx, &config, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*model.ImportFileQuarantine{q}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
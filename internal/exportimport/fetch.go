@@ -0,0 +1,168 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/backoff"
+)
+
+// fileScheme is the URL scheme of index files and export roots that are on a
+// local filesystem or mounted volume, e.g. file:///mnt/exports/index.txt.
+const fileScheme = "file"
+
+// permanentError is a fetch error that does not change on retry, such as a
+// misconfigured import.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// fetch returns the contents of the index or export file at rawURL. http,
+// https and sftp URLs are downloaded with the given timeout, and file URLs are
+// read from disk if they are under one of the configured file roots. Failed
+// downloads are retried with the download policy. It returns
+// ErrArchiveNotFound if the file does not exist.
+func (s *Server) fetch(ctx context.Context, rawURL string, timeout time.Duration) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	policy := &backoff.Policy{
+		Name:       "import_download",
+		MaxRetries: s.config.DownloadMaxRetries,
+		Backoff:    s.config.DownloadRetryBackoff,
+		Budget:     s.config.DownloadRetryBudget,
+		Retryable: func(err error) bool {
+			var perr *permanentError
+			return !errors.Is(err, ErrArchiveNotFound) && !errors.As(err, &perr)
+		},
+	}
+
+	var b []byte
+	if err := policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		b, err = s.fetchOnce(ctx, u, timeout)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// fetchOnce makes one attempt to read the file at u.
+func (s *Server) fetchOnce(ctx context.Context, u *url.URL, timeout time.Duration) ([]byte, error) {
+	switch u.Scheme {
+	case fileScheme:
+		return s.readLocalFile(u)
+	case sftpScheme:
+		return s.readSFTPFile(ctx, u, timeout)
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, &permanentError{fmt.Errorf("failed to create request: %w", err)}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrArchiveNotFound
+		}
+		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		// Other client errors will not change on retry, except for timeouts
+		// and rate limits.
+		if resp.StatusCode < http.StatusInternalServerError &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, &permanentError{err}
+		}
+		return nil, err
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return b, nil
+}
+
+// readLocalFile reads the file of a file URL. The file must be under one of
+// the configured file roots, so that import configs can't be used to read
+// arbitrary files from the importer.
+func (s *Server) readLocalFile(u *url.URL) ([]byte, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, &permanentError{fmt.Errorf("file URL must not have a remote host, got %q", u.Host)}
+	}
+
+	name := filepath.Clean(filepath.FromSlash(u.Path))
+	if !s.config.allowsFile(name) {
+		return nil, &permanentError{fmt.Errorf("file %q is not under an allowed file root", name)}
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrArchiveNotFound
+		}
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return b, nil
+}
+
+// allowsFile returns true if the cleaned, absolute file name is under one of
+// the configured file roots.
+func (c *Config) allowsFile(name string) bool {
+	if !filepath.IsAbs(name) {
+		return false
+	}
+	for _, root := range c.FileRoots {
+		root = filepath.Clean(root)
+		if name == root {
+			continue
+		}
+		if strings.HasPrefix(name, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
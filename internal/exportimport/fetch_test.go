@@ -0,0 +1,179 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportimport
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	var flaky, forbidden atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.txt":
+			w.Write([]byte("a.zip"))
+		case "/error.txt":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/flaky.txt":
+			// Fails on the first request only.
+			if flaky.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("d.zip"))
+		case "/forbidden.txt":
+			forbidden.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "index.txt"), []byte("b.zip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "index.txt"), []byte("c.zip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fileURL := func(name string) string {
+		return "file://" + filepath.ToSlash(name)
+	}
+
+	cases := []struct {
+		name     string
+		url      string
+		want     string
+		notFound bool
+		err      string
+	}{
+		{
+			name: "http",
+			url:  ts.URL + "/index.txt",
+			want: "a.zip",
+		},
+		{
+			name:     "http_not_found",
+			url:      ts.URL + "/missing.txt",
+			notFound: true,
+		},
+		{
+			name: "http_error",
+			url:  ts.URL + "/error.txt",
+			err:  "unexpected status code: 500",
+		},
+		{
+			name: "http_retried",
+			url:  ts.URL + "/flaky.txt",
+			want: "d.zip",
+		},
+		{
+			name: "http_client_error",
+			url:  ts.URL + "/forbidden.txt",
+			err:  "unexpected status code: 403",
+		},
+		{
+			name: "file",
+			url:  fileURL(filepath.Join(root, "index.txt")),
+			want: "b.zip",
+		},
+		{
+			name: "file_localhost",
+			url:  "file://localhost" + filepath.ToSlash(filepath.Join(root, "index.txt")),
+			want: "b.zip",
+		},
+		{
+			name:     "file_not_found",
+			url:      fileURL(filepath.Join(root, "missing.txt")),
+			notFound: true,
+		},
+		{
+			name: "file_outside_roots",
+			url:  fileURL(filepath.Join(outside, "index.txt")),
+			err:  "is not under an allowed file root",
+		},
+		{
+			name: "file_escapes_root",
+			url:  fileURL(root + "/../" + filepath.Base(outside) + "/index.txt"),
+			err:  "is not under an allowed file root",
+		},
+		{
+			name: "file_root_itself",
+			url:  fileURL(root),
+			err:  "is not under an allowed file root",
+		},
+		{
+			name: "file_remote_host",
+			url:  "file://example.com" + filepath.ToSlash(filepath.Join(root, "index.txt")),
+			err:  "must not have a remote host",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			s := &Server{config: &Config{
+				FileRoots:            []string{root},
+				DownloadMaxRetries:   2,
+				DownloadRetryBackoff: time.Millisecond,
+			}}
+
+			got, err := s.fetch(ctx, tc.url, time.Minute)
+			if tc.notFound {
+				if !errors.Is(err, ErrArchiveNotFound) {
+					t.Fatalf("expected %v, got %v", ErrArchiveNotFound, err)
+				}
+				return
+			}
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		// Client errors other than timeouts and rate limits are not retried.
+		if got := forbidden.Load(); got != 1 {
+			t.Errorf("expected 1 request for a forbidden file, got %d", got)
+		}
+	})
+}
@@ -19,14 +19,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	"github.com/google/exposure-notifications-server/internal/export"
 	"github.com/google/exposure-notifications-server/internal/exportimport/model"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
+	"github.com/google/exposure-notifications-server/internal/worker"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/hashicorp/go-multierror"
 
 	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 )
 
 const lockPrefix = "import-lock-"
@@ -35,26 +41,28 @@ func (s *Server) handleImport() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		budget := s.config.Scheduler.NewBudget(r, s.config.MaxRuntime)
+		ctx, cancel := scheduler.WithBudget(ctx, budget)
+		defer cancel()
+
 		logger := logging.FromContext(ctx).Named("handleImport")
 		logger.Debugw("starting")
 		defer logger.Debugw("finishing")
 
-		ctx, cancel := context.WithDeadline(ctx, time.Now().Add(s.config.MaxRuntime))
-		defer cancel()
-
 		configs, err := s.exportImportDB.ActiveConfigs(ctx)
 		if err != nil {
 			logger.Errorw("failed to read active configs", "error", err)
-			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, errcode.Wrap(errcode.InternalError, err))
 			return
 		}
 
 		var merr *multierror.Error
 
+		completed := 0
 		for _, cfg := range configs {
 			// Check how we're doing on max runtime.
-			if deadlinePassed(ctx) {
-				logger.Warnw("deadline passed, but there is still work to do")
+			if budget.Spent() {
+				logger.Warnw("budget spent, but there is still work to do", "retry", budget.Retry())
 				break
 			}
 
@@ -62,11 +70,19 @@ func (s *Server) handleImport() http.Handler {
 				merr = multierror.Append(merr, fmt.Errorf("failed to import %d: %w", cfg.ID, err))
 				continue
 			}
+			completed++
 		}
 
 		if errs := merr.WrappedErrors(); len(errs) > 0 {
 			logger.Errorw("failed to sync", "errors", errs)
-			s.h.RenderJSON(w, http.StatusInternalServerError, errs)
+			s.h.RenderJSON(w, errcode.ImportFailed.HTTPStatus(), errcode.Wrap(errcode.ImportFailed, merr))
+			return
+		}
+
+		// Files that were left when the budget was spent are imported on the
+		// next run.
+		if budget.Spent() {
+			s.h.RenderJSON(w, http.StatusAccepted, budget.Progress(completed))
 			return
 		}
 
@@ -98,10 +114,21 @@ func (s *Server) runImport(ctx context.Context, cfg *model.ExportImport) error {
 		}
 	}()
 
-	// Get the list of files that needs to be processed.
-	openFiles, err := s.exportImportDB.GetOpenImportFiles(ctx, s.config.ImportLockTime, s.config.ImportRetryRate, cfg)
-	if err != nil {
-		return fmt.Errorf("failed to read open import files: %w", err)
+	// Get the list of files that needs to be processed. In batch strictness,
+	// batches are imported in the order of the index.
+	strictBatch := cfg.StrictnessLevel() == model.StrictnessBatch
+	var openFiles []*model.ImportFile
+	if strictBatch {
+		files, err := s.exportImportDB.GetUnfinishedImportFiles(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to read unfinished import files: %w", err)
+		}
+		openFiles = leadingImportFiles(files, s.config.ImportLockTime, s.config.ImportRetryRate)
+	} else {
+		openFiles, err = s.exportImportDB.GetOpenImportFiles(ctx, s.config.ImportLockTime, s.config.ImportRetryRate, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to read open import files: %w", err)
+		}
 	}
 	if len(openFiles) == 0 {
 		return nil
@@ -116,27 +143,15 @@ func (s *Server) runImport(ctx context.Context, cfg *model.ExportImport) error {
 
 	var merr *multierror.Error
 
+	// finish is called by the files imported on the pool, so it holds the
+	// lock while it updates the results of the run.
+	var finishLock sync.Mutex
 	var completedFiles, failedFiles int64
-	for _, file := range openFiles {
-		// Check how we're doing on max runtime.
-		if deadlinePassed(ctx) {
-			logger.Warnw("deadline passed, but there is still work to do")
-			break
-		}
+	finish := func(file *model.ImportFile, result *ImportResponse, err error) {
+		finishLock.Lock()
+		defer finishLock.Unlock()
 
-		if err := s.exportImportDB.LeaseImportFile(ctx, s.config.ImportLockTime, file); err != nil {
-			logger.Warnw("unexpected race condition, file already locked", "file", file, "error", err)
-			return nil
-		}
-
-		// import the file.
 		status := model.ImportFileComplete
-		result, err := s.ImportExportFile(ctx, &ImportRequest{
-			config:       s.config,
-			exportImport: cfg,
-			keys:         keys,
-			file:         file,
-		})
 		if err != nil {
 			merr = multierror.Append(merr, err)
 
@@ -145,9 +160,21 @@ func (s *Server) runImport(ctx context.Context, cfg *model.ExportImport) error {
 			if errors.Is(err, ErrArchiveNotFound) {
 				str += ", file not found"
 			}
+			if errors.Is(err, ErrStrictValidation) {
+				str += ", rejected"
+				status = model.ImportFileFailed
+			}
+			// Archives over the limits are not retried, they would be
+			// rejected again.
+			var lerr *export.ArchiveLimitError
+			if errors.As(err, &lerr) {
+				str += ", quarantined"
+				status = model.ImportFileFailed
+				s.quarantineImportFile(ctx, file, lerr)
+			}
 
 			// Check the retries.
-			logger.Errorw(str, "exportImportID", cfg.ID, "filename", file.ZipFilename)
+			logger.Errorw(str, "exportImportID", cfg.ID, "filename", file.ZipFilename, "error", err)
 			failedFiles++
 		}
 
@@ -162,18 +189,186 @@ func (s *Server) runImport(ctx context.Context, cfg *model.ExportImport) error {
 		}
 	}
 
+	// reopen releases a leased file so it is tried again by a later run.
+	// Callers increment the retries of the file to have it wait for the
+	// retry rate.
+	reopen := func(file *model.ImportFile) {
+		if err := s.exportImportDB.CompleteImportFile(ctx, file, model.ImportFileOpen); err != nil {
+			logger.Errorw("failed to reopen file", "file", file, "error", err)
+		}
+	}
+
+	// In batch strictness, the files of a batch are downloaded and validated
+	// before any of them are imported. A batch that can't be imported yet
+	// holds back all the batches after it.
+	var (
+		grouper batchGrouper
+		last    = lastBatchKey(cfg)
+	)
+	importBatch := func(b *pendingBatch) bool {
+		err := checkBatch(b, last, s.config.ImportBatchMaxRetries)
+		if errors.Is(err, ErrBatchIncomplete) {
+			logger.Warnw("holding back incomplete batch", "batch", b.key.String(), "files", len(b.requests), "error", err)
+			for _, ir := range b.requests {
+				ir.file.Retries++
+				reopen(ir.file)
+			}
+			return false
+		}
+		if err != nil {
+			logger.Warnw("rejecting batch", "batch", b.key.String(), "files", len(b.requests), "error", err)
+			stats.Record(ctx, mBatchesRejected.M(1))
+			for _, ir := range b.requests {
+				finish(ir.file, nil, err)
+			}
+			return true
+		}
+
+		if err := s.exportImportDB.SaveLastBatch(ctx, cfg, b.key.startTime(), b.key.endTime()); err != nil {
+			merr = multierror.Append(merr, err)
+			logger.Errorw("failed to save last batch", "batch", b.key.String(), "error", err)
+		}
+		key := b.key
+		last = &key
+		for i, ir := range b.requests {
+			result, err := s.importKeys(ctx, ir, b.exports[i].export)
+			finish(ir.file, result, err)
+		}
+		return true
+	}
+
+	// Files that aren't in batch strictness are imported independently of
+	// each other, on a pool.
+	pool := worker.New("import_files", s.config.ImportFileConcurrency)
+	waitPool := func() {
+		if err := pool.Wait(); err != nil {
+			finishLock.Lock()
+			merr = multierror.Append(merr, err)
+			finishLock.Unlock()
+		}
+	}
+
+	// stopped is true if a batch was held back before all files were
+	// imported, and backoff is true if the files of the batch being downloaded
+	// should wait for the retry rate.
+	deadlineExceeded, stopped, backoff := false, false, false
+	for _, file := range openFiles {
+		// Check how we're doing on max runtime.
+		if scheduler.Spent(ctx) {
+			logger.Warnw("budget spent, but there is still work to do")
+			deadlineExceeded = true
+			break
+		}
+
+		file.RunID = scheduler.RunID(ctx)
+		if err := s.exportImportDB.LeaseImportFile(ctx, s.config.ImportLockTime, file); err != nil {
+			logger.Warnw("unexpected race condition, file already locked", "file", file, "error", err)
+			waitPool()
+			return nil
+		}
+
+		ir := &ImportRequest{
+			config:       s.config,
+			exportImport: cfg,
+			keys:         keys,
+			file:         file,
+		}
+
+		// import the file.
+		if !strictBatch {
+			file := file
+			if err := pool.Go(ctx, func(ctx context.Context) error {
+				result, err := s.ImportExportFile(ctx, ir)
+				finish(file, result, err)
+				return nil
+			}); err != nil {
+				// The file stays leased, and is retried once the lease expires.
+				logger.Warnw("stopped before importing all files", "error", err)
+				deadlineExceeded = true
+				break
+			}
+			continue
+		}
+		if ir.isExportRoot() {
+			result, err := s.ImportExportFile(ctx, ir)
+			finish(file, result, err)
+			continue
+		}
+
+		ef, err := s.fetchExportFile(ctx, ir)
+		var lerr *export.ArchiveLimitError
+		if errors.As(err, &lerr) {
+			// A quarantined file is never imported, the rest of its batch is
+			// incomplete until it is rejected.
+			finish(file, nil, err)
+			continue
+		}
+		if err != nil {
+			// The batch of a file that can't be downloaded is retried with it.
+			merr = multierror.Append(merr, err)
+			logger.Errorw("import file error, holding back batch", "exportImportID", cfg.ID, "filename", file.ZipFilename, "error", err)
+			failedFiles++
+			file.Retries++
+			reopen(file)
+			stopped, backoff = true, true
+			break
+		}
+		if err := validateExportFile(ef); err != nil {
+			grouper.reject(batchKeyOf(ef.export))
+			finish(file, nil, err)
+			continue
+		}
+
+		if b := grouper.add(ir, ef); b != nil && !importBatch(b) {
+			stopped = true
+			break
+		}
+	}
+
+	waitPool()
+
+	if b := grouper.flush(); b != nil {
+		switch {
+		case deadlineExceeded:
+			// Files of a batch that was cut short by the deadline stay leased,
+			// and hold back the batches after them until the lease expires.
+		case stopped:
+			// The rest of the batch wasn't downloaded, try all of it again.
+			for _, ir := range b.requests {
+				if backoff {
+					ir.file.Retries++
+				}
+				reopen(ir.file)
+			}
+		default:
+			importBatch(b)
+		}
+	}
+
 	stats.Record(ctx, mFilesImported.M(completedFiles))
 	stats.Record(ctx, mFilesFailed.M(failedFiles))
 	return merr.ErrorOrNil()
 }
 
-func deadlinePassed(ctx context.Context) bool {
-	deadline, ok := ctx.Deadline()
-	if !ok {
-		return false
+// quarantineImportFile records that the file was rejected by the archive
+// limits, so it can be reviewed. Failing to record it is logged, the file is
+// still marked failed.
+func (s *Server) quarantineImportFile(ctx context.Context, file *model.ImportFile, lerr *export.ArchiveLimitError) {
+	logger := logging.FromContext(ctx).Named("quarantineImportFile")
+
+	q := &model.ImportFileQuarantine{
+		ImportFileID:   file.ID,
+		ExportImportID: file.ExportImportID,
+		ZipFilename:    file.ZipFilename,
+		Reason:         lerr.Reason,
+		Detail:         lerr.Error(),
+		QuarantinedAt:  time.Now().UTC(),
 	}
-	if time.Now().After(deadline) {
-		return true
+	if err := s.exportImportDB.QuarantineImportFile(ctx, q); err != nil {
+		logger.Errorw("failed to quarantine file", "file", file, "reason", lerr.Reason, "error", err)
+	}
+
+	if err := stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(quarantineReasonTagKey, lerr.Reason)}, mFilesQuarantined.M(1)); err != nil {
+		logger.Errorw("failed to record quarantined file", "error", err)
 	}
-	return false
 }
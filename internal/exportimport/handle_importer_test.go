@@ -15,15 +15,30 @@
 package exportimport
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/export"
 	exportimportdb "github.com/google/exposure-notifications-server/internal/exportimport/database"
 	"github.com/google/exposure-notifications-server/internal/exportimport/model"
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestImportingRetries(t *testing.T) {
@@ -76,3 +91,315 @@ func TestImportingRetries(t *testing.T) {
 		}
 	}
 }
+
+func TestRunImport_Quarantine(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportImportDB := exportimportdb.New(testDB)
+
+	// The archive has more files than the limit.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < 3; i++ {
+		if _, err := zw.Create(fmt.Sprintf("file%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	cfg := &model.ExportImport{
+		IndexFile:  ts.URL + "/index.txt",
+		ExportRoot: ts.URL,
+		Region:     "US",
+		From:       time.Now().UTC(),
+	}
+	if err := exportImportDB.AddConfig(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := exportImportDB.CreateNewFilesAndFailOld(ctx, cfg, []string{ts.URL + "/a.zip"}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{MaxArchiveFiles: 2}
+	env := serverenv.New(ctx, serverenv.WithDatabase(testDB))
+	s, err := NewServer(config, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.runImport(ctx, cfg); err == nil {
+		t.Fatal("expected error")
+	}
+
+	files, err := exportImportDB.GetAllImportFiles(ctx, time.Minute, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := files[0].Status, model.ImportFileFailed; got != want {
+		t.Errorf("expected status %q, got %q", want, got)
+	}
+
+	quarantined, err := exportImportDB.ListQuarantinedImportFiles(ctx, cfg, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quarantined) != 1 {
+		t.Fatalf("expected 1 quarantined file, got %d", len(quarantined))
+	}
+	if got, want := quarantined[0].Reason, export.ArchiveReasonFileCount; got != want {
+		t.Errorf("expected reason %q, got %q", want, got)
+	}
+}
+
+// deadlineContext is a context with a deadline that passes when passed is
+// set, without canceling the context.
+type deadlineContext struct {
+	context.Context
+	passed *atomic.Bool
+}
+
+func (c *deadlineContext) Deadline() (time.Time, bool) {
+	if c.passed.Load() {
+		return time.Now().Add(-time.Second), true
+	}
+	return time.Now().Add(time.Hour), true
+}
+
+// marshalTestExport returns the zipped export file of ef, signed with key.
+func marshalTestExport(t *testing.T, key *ecdsa.PrivateKey, ef *exportFile) []byte {
+	t.Helper()
+
+	b, err := proto.Marshal(ef.export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := append([]byte("EK Export v1    "), b...)
+
+	digest := sha256.Sum256(content)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range ef.signatures.Signatures {
+		s.Signature = sig
+	}
+	sigContent, err := proto.Marshal(ef.signatures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{"export.bin": content, "export.sig": sigContent} {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRunImport_StrictBatch(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	// Two batches of two files each.
+	exports := []*exportFile{
+		testExportFile(1000, 2000, 1, 2, "a"),
+		testExportFile(1000, 2000, 2, 2, "a"),
+		testExportFile(2000, 3000, 1, 2, "a"),
+		testExportFile(2000, 3000, 2, 2, "a"),
+	}
+	zips := make([][]byte, 0, len(exports))
+	for _, ef := range exports {
+		zips = append(zips, marshalTestExport(t, key, ef))
+	}
+
+	writeFile := func(w http.ResponseWriter, i int) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(zips[i])))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(zips[i])
+	}
+
+	cases := []struct {
+		name string
+		// files are the indexes of the exports that are scheduled.
+		files []int
+		// serve serves the export with the index i.
+		serve func(w http.ResponseWriter, i int)
+		// runs is the number of times the import is run.
+		runs       int
+		maxRetries uint
+		// deadline, if true, passes the deadline once the first file is served.
+		deadline bool
+		status   []string
+		retries  []uint
+	}{
+		{
+			name:    "complete",
+			files:   []int{0, 1, 2, 3},
+			serve:   writeFile,
+			runs:    1,
+			status:  []string{model.ImportFileComplete, model.ImportFileComplete, model.ImportFileComplete, model.ImportFileComplete},
+			retries: []uint{0, 0, 0, 0},
+		},
+		{
+			name:  "download_failure",
+			files: []int{0, 1, 2, 3},
+			serve: func(w http.ResponseWriter, i int) {
+				if i == 1 {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				writeFile(w, i)
+			},
+			runs:       1,
+			maxRetries: 1,
+			// The batch is retried, and holds back the batch after it.
+			status:  []string{model.ImportFileOpen, model.ImportFileOpen, model.ImportFileOpen, model.ImportFileOpen},
+			retries: []uint{1, 1, 0, 0},
+		},
+		{
+			name:       "incomplete",
+			files:      []int{0, 2, 3},
+			serve:      writeFile,
+			runs:       1,
+			maxRetries: 1,
+			status:     []string{model.ImportFileOpen, model.ImportFileOpen, model.ImportFileOpen},
+			retries:    []uint{1, 0, 0},
+		},
+		{
+			name:       "incomplete_out_of_retries",
+			files:      []int{0, 2, 3},
+			serve:      writeFile,
+			runs:       2,
+			maxRetries: 1,
+			status:     []string{model.ImportFileFailed, model.ImportFileComplete, model.ImportFileComplete},
+			retries:    []uint{2, 0, 0},
+		},
+		{
+			name:     "deadline",
+			files:    []int{0, 1, 2, 3},
+			serve:    writeFile,
+			runs:     1,
+			deadline: true,
+			// The first file of the cut short batch stays leased.
+			status:  []string{model.ImportFilePending, model.ImportFileOpen, model.ImportFileOpen, model.ImportFileOpen},
+			retries: []uint{0, 0, 0, 0},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			testDB, _ := testDatabaseInstance.NewDatabase(t)
+			exportImportDB := exportimportdb.New(testDB)
+
+			var passed atomic.Bool
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".zip"))
+				if err != nil || i >= len(zips) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if tc.deadline {
+					passed.Store(true)
+				}
+				tc.serve(w, i)
+			}))
+			t.Cleanup(ts.Close)
+
+			eiConfig := model.ExportImport{
+				IndexFile:  ts.URL + "/index.txt",
+				ExportRoot: ts.URL,
+				Region:     "US",
+				From:       time.Now().UTC().Add(-time.Minute),
+				Strictness: model.StrictnessBatch,
+			}
+			if err := exportImportDB.AddConfig(ctx, &eiConfig); err != nil {
+				t.Fatal(err)
+			}
+			if err := exportImportDB.AddImportFilePublicKey(ctx, &model.ImportFilePublicKey{
+				ExportImportID: eiConfig.ID,
+				KeyID:          "a",
+				KeyVersion:     "v1",
+				PublicKeyPEM:   publicKeyPEM,
+				From:           time.Now().UTC().Add(-time.Minute),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			filenames := make([]string, 0, len(tc.files))
+			for _, i := range tc.files {
+				filenames = append(filenames, fmt.Sprintf("%s/%d.zip", ts.URL, i))
+			}
+			if _, _, err := exportImportDB.CreateNewFilesAndFailOld(ctx, &eiConfig, filenames); err != nil {
+				t.Fatal(err)
+			}
+
+			config := &Config{
+				ImportLockTime:        time.Hour,
+				ImportRetryRate:       time.Nanosecond,
+				ImportBatchMaxRetries: tc.maxRetries,
+				ImportAPKName:         "exportimport",
+			}
+			env := serverenv.New(ctx, serverenv.WithDatabase(testDB))
+			s, err := NewServer(config, env)
+			if err != nil {
+				t.Fatalf("error creating server: %v", err)
+			}
+
+			for i := 0; i < tc.runs; i++ {
+				runCtx := context.Context(&deadlineContext{Context: ctx, passed: &passed})
+				cfg, err := exportImportDB.GetConfig(ctx, eiConfig.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Errors are expected for the files that failed.
+				_ = s.runImport(runCtx, cfg)
+			}
+
+			files, err := exportImportDB.GetAllImportFiles(ctx, time.Hour, &eiConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(files), len(tc.status); got != want {
+				t.Fatalf("expected %d files, got %d", want, got)
+			}
+			for i, f := range files {
+				if got, want := f.Status, tc.status[i]; got != want {
+					t.Errorf("file %d: expected status %s, got %s", i, want, got)
+				}
+				if got, want := f.Retries, tc.retries[i]; got != want {
+					t.Errorf("file %d: expected %d retries, got %d", i, want, got)
+				}
+			}
+		})
+	}
+}
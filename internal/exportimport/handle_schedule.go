@@ -18,16 +18,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/errcode"
 	exportimportdb "github.com/google/exposure-notifications-server/internal/exportimport/database"
 	"github.com/google/exposure-notifications-server/internal/exportimport/model"
 	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/scheduler"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/hashicorp/go-multierror"
@@ -38,7 +39,7 @@ const schedulerLockID = "import-scheduler-lock"
 
 func (s *Server) handleSchedule() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+		ctx := scheduler.WithRunID(r.Context(), scheduler.NewRunID(time.Now()))
 
 		logger := logging.FromContext(ctx).Named("handleSchedule").
 			With("lock", schedulerLockID)
@@ -49,11 +50,11 @@ func (s *Server) handleSchedule() http.Handler {
 		if err != nil {
 			if errors.Is(err, database.ErrAlreadyLocked) {
 				logger.Debugw("skipping (already locked)")
-				s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("too early"))
+				s.h.RenderJSON(w, http.StatusOK, errcode.New(errcode.TooEarly, "too early"))
 				return
 			}
 			logger.Errorw("failed to obtain lock", "error", err)
-			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, errcode.Wrap(errcode.InternalError, err))
 			return
 		}
 		defer func() {
@@ -68,7 +69,7 @@ func (s *Server) handleSchedule() http.Handler {
 		configs, err := s.exportImportDB.ActiveConfigs(ctx)
 		if err != nil {
 			logger.Errorw("failed to read active configs", "error", err)
-			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, errcode.Wrap(errcode.InternalError, err))
 			return
 		}
 
@@ -83,7 +84,7 @@ func (s *Server) handleSchedule() http.Handler {
 
 		if errs := merr.WrappedErrors(); len(errs) > 0 {
 			logger.Errorw("failed to sync", "errors", errs)
-			s.h.RenderJSON(w, http.StatusInternalServerError, errs)
+			s.h.RenderJSON(w, errcode.ImportFailed.HTTPStatus(), errcode.Wrap(errcode.ImportFailed, merr))
 			return
 		}
 
@@ -101,25 +102,10 @@ func (s *Server) syncOne(ctx context.Context, cfg *model.ExportImport) error {
 	logger.Debugw("syncing index file")
 	defer logger.Debugw("finished syncing index file")
 
-	client := &http.Client{
-		Timeout: s.config.IndexFileDownloadTimeout,
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.IndexFile, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request to download index file: %w", err)
-	}
-
-	resp, err := client.Do(req)
+	bytes, err := s.fetch(ctx, cfg.IndexFile, s.config.IndexFileDownloadTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to download index file: %w", err)
 	}
-	defer resp.Body.Close()
-
-	bytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read index file: %w", err)
-	}
 
 	numNew, numFailed, err := syncFilesFromIndex(ctx, s.exportImportDB, cfg, string(bytes))
 	if err != nil {
@@ -103,6 +103,23 @@ func TestSyncFilenameShapes(t *testing.T) {
 				"https://cdn.example.com/export-b.zip",
 			},
 		},
+		{
+			name: "file_root",
+			config: &model.ExportImport{
+				IndexFile:  "file:///mnt/exports/index.txt",
+				ExportRoot: "file:///mnt/exports",
+				Region:     "US",
+				From:       fromTime,
+			},
+			index: []string{
+				"export-a.zip",
+				"/region-us/export-b.zip",
+			},
+			want: []string{
+				"file:///mnt/exports/export-a.zip",
+				"file:///mnt/exports/region-us/export-b.zip",
+			},
+		},
 		{
 			name: "nested_paths",
 			config: &model.ExportImport{
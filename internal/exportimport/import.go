@@ -20,8 +20,6 @@ import (
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/export"
@@ -53,10 +51,22 @@ type SignatureAndKey struct {
 	publicKey *ecdsa.PublicKey
 }
 
+// exportFile is a downloaded export file with a verified signature.
+type exportFile struct {
+	export     *exportproto.TemporaryExposureKeyExport
+	signatures *exportproto.TEKSignatureList
+}
+
+// isExportRoot returns true if the file of the request is the export root.
+// Previous versions may have inserted the filename root as a file.
+func (ir *ImportRequest) isExportRoot() bool {
+	return ir.exportImport.ExportRoot == ir.file.ZipFilename
+}
+
 func (s *Server) ImportExportFile(ctx context.Context, ir *ImportRequest) (*ImportResponse, error) {
-	// Special case - previous versions may have inserted the filename root as a file.
-	// If we find that, skip attempted processing and just mark as successful.
-	if ir.exportImport.ExportRoot == ir.file.ZipFilename {
+	// Special case - if we find the export root, skip attempted processing and
+	// just mark as successful.
+	if ir.isExportRoot() {
 		return &ImportResponse{
 			insertedKeys: 0,
 			revisedKeys:  0,
@@ -64,42 +74,39 @@ func (s *Server) ImportExportFile(ctx context.Context, ir *ImportRequest) (*Impo
 		}, nil
 	}
 
-	logger := logging.FromContext(ctx)
-
-	// Download zip file.
-	httpClient := &http.Client{
-		Timeout: s.config.ExportFileDownloadTimeout,
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ir.file.ZipFilename, nil)
+	ef, err := s.fetchExportFile(ctx, ir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request to download export file: %w", err)
+		return nil, err
 	}
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error downloading export file: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, ErrArchiveNotFound
+	if ir.exportImport.StrictnessLevel() != model.StrictnessOff {
+		if err := validateExportFile(ef); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("unable to download file, code: %d", resp.StatusCode)
 	}
+	return s.importKeys(ctx, ir, ef.export)
+}
+
+// fetchExportFile downloads the file of the import request and verifies its
+// signature against the allowed public keys.
+func (s *Server) fetchExportFile(ctx context.Context, ir *ImportRequest) (*exportFile, error) {
+	logger := logging.FromContext(ctx)
 
-	defer resp.Body.Close()
-	bytes, err := io.ReadAll(resp.Body)
+	// Download zip file.
+	bytes, err := s.fetch(ctx, ir.file.ZipFilename, s.config.ExportFileDownloadTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+		if errors.Is(err, ErrArchiveNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error downloading export file: %w", err)
 	}
 
 	// Get bin and sig files.
-	tekExport, digest, err := export.UnmarshalExportFile(bytes)
+	limits := s.config.ArchiveLimits()
+	tekExport, digest, err := export.UnmarshalExportFileWithLimits(bytes, limits)
 	if err != nil {
 		return nil, fmt.Errorf("bin data error: %w", err)
 	}
-	tekSignatures, err := export.UnmarshalSignatureFile(bytes)
+	tekSignatures, err := export.UnmarshalSignatureFileWithLimits(bytes, limits)
 	if err != nil {
 		return nil, fmt.Errorf("signature data missing: %w", err)
 	}
@@ -142,6 +149,17 @@ func (s *Server) ImportExportFile(ctx context.Context, ir *ImportRequest) (*Impo
 		return nil, fmt.Errorf("no valid signature found")
 	}
 
+	return &exportFile{
+		export:     tekExport,
+		signatures: tekSignatures,
+	}, nil
+}
+
+// importKeys inserts the primary keys and revises the revised keys of an
+// export file.
+func (s *Server) importKeys(ctx context.Context, ir *ImportRequest, tekExport *exportproto.TemporaryExposureKeyExport) (*ImportResponse, error) {
+	logger := logging.FromContext(ctx)
+
 	// Common transform settings for primary + revised keys.
 	exKeyTransform := transformer{
 		appPackageName: s.config.ImportAPKName,
@@ -151,6 +169,7 @@ func (s *Server) ImportExportFile(ctx context.Context, ir *ImportRequest) (*Impo
 		truncateWindow: s.config.CreatedAtTruncateWindow,
 		exportImportID: ir.exportImport.ID,
 		importFileID:   ir.file.ID,
+		keyMetadata:    s.config.KeyMetadata,
 		exportImportConfig: &pubmodel.ExportImportConfig{
 			DefaultReportType:         s.config.BackfillReportType,
 			BackfillSymptomOnset:      s.config.BackfillDaysSinceOnset,
@@ -231,6 +250,7 @@ type transformer struct {
 	truncateWindow     time.Duration
 	exportImportID     int64
 	importFileID       int64
+	keyMetadata        bool
 	exportImportConfig *pubmodel.ExportImportConfig
 	logger             *zap.SugaredLogger
 }
@@ -251,9 +271,18 @@ func (t *transformer) transform(keys []*exportproto.TemporaryExposureKey) ([]*pu
 		exp.Traveler = t.traveler
 		exp.CreatedAt = t.batchTime
 		exp.LocalProvenance = false
+		exp.ProvenanceSource = pubmodel.ProvenanceExportImport
 		exp.ExportImportID = &t.exportImportID
 		exp.ImportFileID = &t.importFileID
 
+		// Key metadata that can't be kept doesn't make the key invalid.
+		if t.keyMetadata {
+			exp.KeyMetadata, err = pubmodel.KeyMetadataFromExportKey(k)
+			if err != nil {
+				t.logger.Warnw("dropping key metadata", "error", err)
+			}
+		}
+
 		// Adjust created at time, if this key is not yet expired.
 		if expTime := pubmodel.TimeForIntervalNumber(exp.IntervalNumber + exp.IntervalCount); exp.CreatedAt.Before(expTime) {
 			exp.CreatedAt = expTime.UTC().Add(t.truncateWindow).Truncate(t.truncateWindow)
@@ -15,6 +15,7 @@
 package exportimport
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -24,6 +25,7 @@ import (
 	pubmodel "github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
@@ -137,6 +139,7 @@ func TestTransform(t *testing.T) {
 					CreatedAt:             batchTime,
 					ExportImportID:        &settings.exportImportID,
 					ImportFileID:          &settings.importFileID,
+					ProvenanceSource:      pubmodel.ProvenanceExportImport,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: &settings.exportImportConfig.BackfillSymptomOnsetValue,
 				},
@@ -151,6 +154,7 @@ func TestTransform(t *testing.T) {
 					CreatedAt:             timeutils.UTCMidnight(batchTime.Add(24 * time.Hour)).Add(settings.truncateWindow),
 					ExportImportID:        &settings.exportImportID,
 					ImportFileID:          &settings.importFileID,
+					ProvenanceSource:      pubmodel.ProvenanceExportImport,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: &settings.exportImportConfig.BackfillSymptomOnsetValue,
 				},
@@ -162,3 +166,62 @@ func TestTransform(t *testing.T) {
 		})
 	}
 }
+
+func TestTransform_KeyMetadata(t *testing.T) {
+	t.Parallel()
+
+	gen := &keyGenerator{}
+	day := timeutils.UTCMidnight(time.Now().UTC()).Add(-48 * time.Hour)
+	newKey := func() *exportproto.TemporaryExposureKey {
+		return &exportproto.TemporaryExposureKey{
+			KeyData:                    gen.fakeExposureKey(t),
+			RollingStartIntervalNumber: proto.Int32(pubmodel.IntervalNumber(day)),
+			RollingPeriod:              proto.Int32(144),
+			ReportType:                 exportproto.TemporaryExposureKey_CONFIRMED_TEST.Enum(),
+			VariantOfConcern:           exportproto.TemporaryExposureKey_VARIANT_TYPE_1.Enum(),
+		}
+	}
+	tooLarge := newKey()
+	tooLarge.ProtoReflect().SetUnknown(protowire.AppendBytes(
+		protowire.AppendTag(nil, 9, protowire.BytesType), make([]byte, pubmodel.MaxKeyMetadataBytes)))
+	input := []*exportproto.TemporaryExposureKey{newKey(), tooLarge}
+
+	for _, keyMetadata := range []bool{false, true} {
+		keyMetadata := keyMetadata
+
+		t.Run(fmt.Sprintf("enabled_%t", keyMetadata), func(t *testing.T) {
+			t.Parallel()
+
+			settings := &transformer{
+				importRegions:      []string{"US"},
+				batchTime:          time.Now().UTC(),
+				truncateWindow:     time.Hour,
+				keyMetadata:        keyMetadata,
+				exportImportConfig: &pubmodel.ExportImportConfig{},
+				logger:             logging.FromContext(project.TestContext(t)),
+			}
+
+			got, dropped := settings.transform(input)
+			if dropped != 0 || len(got) != 2 {
+				t.Fatalf("expected 2 keys and none dropped, got %d keys and %d dropped", len(got), dropped)
+			}
+			if got[1].KeyMetadata != nil {
+				t.Errorf("expected too large key metadata to be dropped, got %x", got[1].KeyMetadata)
+			}
+			if !keyMetadata {
+				if got[0].KeyMetadata != nil {
+					t.Errorf("expected no key metadata when disabled, got %x", got[0].KeyMetadata)
+				}
+				return
+			}
+
+			var out exportproto.TemporaryExposureKey
+			if err := pubmodel.ApplyKeyMetadata(&out, got[0].KeyMetadata); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := out.GetVariantOfConcern(), exportproto.TemporaryExposureKey_VARIANT_TYPE_1; got != want {
+				t.Errorf("expected variant of concern %v, got %v", want, got)
+			}
+		})
+	}
+}
@@ -29,7 +29,10 @@ import (
 
 const metricPrefix = metrics.MetricRoot + "export-importer"
 
-var exportimportConfigIDTagKey = tag.MustNewKey("export_importer_config_id")
+var (
+	exportimportConfigIDTagKey = tag.MustNewKey("export_importer_config_id")
+	quarantineReasonTagKey     = tag.MustNewKey("reason")
+)
 
 var (
 	// mImportSuccess is the overall success of the import job.
@@ -42,6 +45,12 @@ var (
 	mFilesScheduled = stats.Int64(metricPrefix+"/files_scheduled", "Number of import files scheduled by ID", stats.UnitDimensionless)
 	mFilesImported  = stats.Int64(metricPrefix+"/files_imported", "Number of import files completed by ID", stats.UnitDimensionless)
 	mFilesFailed    = stats.Int64(metricPrefix+"/files_failed", "Number of import files failed by ID", stats.UnitDimensionless)
+
+	// mBatchesRejected is the number of batches rejected by strict validation.
+	mBatchesRejected = stats.Int64(metricPrefix+"/batches_rejected", "Number of batches rejected by ID", stats.UnitDimensionless)
+
+	// mFilesQuarantined is the number of files rejected by the archive limits.
+	mFilesQuarantined = stats.Int64(metricPrefix+"/files_quarantined", "Number of import files quarantined by ID", stats.UnitDimensionless)
 )
 
 func init() {
@@ -79,6 +88,20 @@ func init() {
 			Aggregation: view.Sum(),
 			TagKeys:     metricsTagKeys(),
 		},
+		{
+			Name:        metricPrefix + "/batches_rejected",
+			Description: "Total count of batches rejected by strict validation, by configuration",
+			Measure:     mBatchesRejected,
+			Aggregation: view.Sum(),
+			TagKeys:     metricsTagKeys(),
+		},
+		{
+			Name:        metricPrefix + "/files_quarantined",
+			Description: "Total count of files rejected by the archive limits, by configuration and reason",
+			Measure:     mFilesQuarantined,
+			Aggregation: view.Sum(),
+			TagKeys:     append(metricsTagKeys(), quarantineReasonTagKey),
+		},
 	}...)
 }
 
@@ -20,6 +20,18 @@ import (
 	"time"
 )
 
+// Strictness levels of the validation of imported export files.
+const (
+	// StrictnessOff only verifies the signatures of each file.
+	StrictnessOff = "OFF"
+	// StrictnessFile also validates the batch metadata and signature infos of
+	// each file, and rejects files that are inconsistent.
+	StrictnessFile = "FILE"
+	// StrictnessBatch also validates that the files of a batch agree with each
+	// other, and rejects the whole batch if they don't.
+	StrictnessBatch = "BATCH"
+)
+
 // ExportImport represents the configuration of a set of export files
 // to be imported into this server, by pointing at the index file
 // and remote root directory.
@@ -31,6 +43,22 @@ type ExportImport struct {
 	Traveler   bool
 	From       time.Time
 	Thru       *time.Time
+	Strictness string
+
+	// LastBatchStart and LastBatchEnd are the timestamps of the last batch
+	// imported with batch strictness. Batches that start or end before it are
+	// rejected as out of order.
+	LastBatchStart *time.Time
+	LastBatchEnd   *time.Time
+}
+
+// StrictnessLevel returns the strictness of the import, defaulting to
+// StrictnessOff.
+func (ei *ExportImport) StrictnessLevel() string {
+	if ei.Strictness == "" {
+		return StrictnessOff
+	}
+	return ei.Strictness
 }
 
 // Validate checks the contents of an ExportImport file. This is a utility
@@ -48,6 +76,11 @@ func (ei *ExportImport) Validate() error {
 	if ei.ExportRoot == "" {
 		return fmt.Errorf("ExportRoot cannot be blank")
 	}
+	switch s := ei.StrictnessLevel(); s {
+	case StrictnessOff, StrictnessFile, StrictnessBatch:
+	default:
+		return fmt.Errorf("strictness must be one of %s, %s, or %s, got %q", StrictnessOff, StrictnessFile, StrictnessBatch, s)
+	}
 
 	return nil
 }
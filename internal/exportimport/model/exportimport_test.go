@@ -79,6 +79,26 @@ func TestValidate(t *testing.T) {
 			},
 			want: "",
 		},
+		{
+			name: "strict",
+			ei: &ExportImport{
+				Region:     "US",
+				IndexFile:  "a/index.txt",
+				ExportRoot: "a",
+				Strictness: StrictnessBatch,
+			},
+			want: "",
+		},
+		{
+			name: "invalid_strictness",
+			ei: &ExportImport{
+				Region:     "US",
+				IndexFile:  "a/index.txt",
+				ExportRoot: "a",
+				Strictness: "strict",
+			},
+			want: `strictness must be one of OFF, FILE, or BATCH, got "strict"`,
+		},
 	}
 
 	for _, tc := range cases {
@@ -35,6 +35,10 @@ type ImportFile struct {
 	ProcessedAt    *time.Time
 	Status         string
 	Retries        uint
+
+	// RunID is the run ID of the import run that last leased or completed the
+	// file.
+	RunID string
 }
 
 // ShouldTry performs some introspection on an import file from the DB, and
@@ -0,0 +1,33 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"time"
+)
+
+// ImportFileQuarantine records an import file that was rejected because its
+// archive was over the limits of the importer, such as a zip bomb or an
+// archive nested in the export archive. The file itself is marked failed.
+type ImportFileQuarantine struct {
+	ImportFileID   int64
+	ExportImportID int64
+	ZipFilename    string
+	// Reason is the limit the archive was over, one of the
+	// export.ArchiveReason constants.
+	Reason        string
+	Detail        string
+	QuarantinedAt time.Time
+}
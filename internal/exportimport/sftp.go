@@ -0,0 +1,177 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportimport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpScheme is the URL scheme of index files and export roots that are on an
+// SFTP server, e.g. sftp://user@sftp.example.com/exports/index.txt.
+const sftpScheme = "sftp"
+
+// readSFTPFile downloads the file of an sftp URL. The host key of the server
+// must be pinned in the known hosts, and the importer authenticates as the
+// user of the URL with the configured private key.
+func (s *Server) readSFTPFile(ctx context.Context, u *url.URL, timeout time.Duration) ([]byte, error) {
+	user := u.User.Username()
+	if user == "" {
+		return nil, &permanentError{fmt.Errorf("sftp URL must include a user")}
+	}
+
+	clientConfig, err := s.config.sftpClientConfig(user, timeout)
+	if err != nil {
+		return nil, &permanentError{err}
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "22")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	// The deadline bounds the handshake and the transfer, like the timeout of
+	// an HTTP download.
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	defer sshClient.Close()
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// A valid archive is no larger than its uncompressed contents, so the
+	// archive size limit also bounds the download.
+	maxSize := s.config.MaxArchiveUncompressedSize
+	if maxSize <= 0 {
+		maxSize = export.DefaultArchiveLimits.MaxUncompressedSize
+	}
+
+	b, err := client.ReadFile(u.Path, maxSize)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrArchiveNotFound
+		}
+		if errors.Is(err, sftp.ErrFileTooLarge) {
+			return nil, &permanentError{&export.ArchiveLimitError{
+				Reason: export.ArchiveReasonUncompressedSize,
+				Err:    fmt.Errorf("%w: %v", export.ErrArchiveTooLarge, err),
+			}}
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// sftpClientConfig returns the SSH configuration for connecting to SFTP
+// servers as the user.
+func (c *Config) sftpClientConfig(user string, timeout time.Duration) (*ssh.ClientConfig, error) {
+	if c.SFTPPrivateKey == "" {
+		return nil, fmt.Errorf("SFTP_PRIVATE_KEY is required for sftp URLs")
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(c.SFTPPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SFTP_PRIVATE_KEY: %w", err)
+	}
+
+	hostKeyCallback, err := knownHostsCallback(c.SFTPKnownHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, nil
+}
+
+// knownHostsCallback returns a host key callback that only accepts the keys
+// pinned for a host in knownHosts, which is in the known_hosts format.
+// Hashed host names, wildcards and markers are not supported, so each pin is
+// explicit.
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	pins := make(map[string][]ssh.PublicKey)
+
+	rest := []byte(knownHosts)
+	for len(bytes.TrimSpace(rest)) > 0 {
+		var (
+			marker string
+			hosts  []string
+			key    ssh.PublicKey
+			err    error
+		)
+		marker, hosts, key, _, rest, err = ssh.ParseKnownHosts(rest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SFTP_KNOWN_HOSTS: %w", err)
+		}
+		if marker != "" {
+			return nil, fmt.Errorf("SFTP_KNOWN_HOSTS: unsupported marker @%s", marker)
+		}
+		for _, host := range hosts {
+			pins[knownhosts.Normalize(host)] = append(pins[knownhosts.Normalize(host)], key)
+		}
+	}
+
+	if len(pins) == 0 {
+		return nil, fmt.Errorf("SFTP_KNOWN_HOSTS is required for sftp URLs")
+	}
+
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		keys, ok := pins[knownhosts.Normalize(hostname)]
+		if !ok {
+			return fmt.Errorf("no host key is pinned for %s", hostname)
+		}
+		for _, k := range keys {
+			if bytes.Equal(k.Marshal(), key.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key of %s does not match the pinned keys", hostname)
+	}, nil
+}
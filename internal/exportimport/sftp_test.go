@@ -0,0 +1,192 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportimport
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func testSSHPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKnownHostsCallback(t *testing.T) {
+	t.Parallel()
+
+	pinned := testSSHPublicKey(t)
+	rotated := testSSHPublicKey(t)
+	other := testSSHPublicKey(t)
+
+	knownHosts := strings.Join([]string{
+		knownhosts.Line([]string{"sftp.example.com"}, pinned),
+		knownhosts.Line([]string{"sftp.example.com"}, rotated),
+		knownhosts.Line([]string{"[sftp.example.org]:2222"}, pinned),
+	}, "\n")
+
+	callback, err := knownHostsCallback(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		hostname string
+		key      ssh.PublicKey
+		err      string
+	}{
+		{
+			name:     "pinned",
+			hostname: "sftp.example.com:22",
+			key:      pinned,
+		},
+		{
+			name:     "second_pin",
+			hostname: "sftp.example.com:22",
+			key:      rotated,
+		},
+		{
+			name:     "pinned_port",
+			hostname: "sftp.example.org:2222",
+			key:      pinned,
+		},
+		{
+			name:     "wrong_key",
+			hostname: "sftp.example.com:22",
+			key:      other,
+			err:      "does not match the pinned keys",
+		},
+		{
+			name:     "wrong_port",
+			hostname: "sftp.example.org:22",
+			key:      pinned,
+			err:      "no host key is pinned",
+		},
+		{
+			name:     "unknown_host",
+			hostname: "evil.example.com:22",
+			key:      pinned,
+			err:      "no host key is pinned",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := callback(tc.hostname, nil, tc.key)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestSFTPClientConfig(t *testing.T) {
+	t.Parallel()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	knownHosts := knownhosts.Line([]string{"sftp.example.com"}, testSSHPublicKey(t))
+
+	cases := []struct {
+		name   string
+		config *Config
+		err    string
+	}{
+		{
+			name:   "valid",
+			config: &Config{SFTPPrivateKey: privateKey, SFTPKnownHosts: knownHosts},
+		},
+		{
+			name:   "missing_private_key",
+			config: &Config{SFTPKnownHosts: knownHosts},
+			err:    "SFTP_PRIVATE_KEY is required",
+		},
+		{
+			name:   "invalid_private_key",
+			config: &Config{SFTPPrivateKey: "nope", SFTPKnownHosts: knownHosts},
+			err:    "failed to parse SFTP_PRIVATE_KEY",
+		},
+		{
+			name:   "missing_known_hosts",
+			config: &Config{SFTPPrivateKey: privateKey},
+			err:    "SFTP_KNOWN_HOSTS is required",
+		},
+		{
+			name: "marker",
+			config: &Config{
+				SFTPPrivateKey: privateKey,
+				SFTPKnownHosts: "@cert-authority " + knownHosts,
+			},
+			err: "unsupported marker",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := tc.config.sftpClientConfig("importer", time.Minute)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := cfg.User, "importer"; got != want {
+				t.Errorf("expected user %q, got %q", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportimport
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/exportimport/model"
+	exportproto "github.com/google/exposure-notifications-server/internal/pb/export"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrStrictValidation is returned when an export file, or the batch it belongs
+// to, fails the strict validation of its export import config. Files that
+// fail are not retried.
+var ErrStrictValidation = errors.New("strict validation failed")
+
+// ErrBatchIncomplete is returned when a batch is missing files that may still
+// be imported by a later run. The files of the batch are retried.
+var ErrBatchIncomplete = errors.New("batch incomplete")
+
+// batchKey identifies the batch an export file belongs to.
+type batchKey struct {
+	start uint64
+	end   uint64
+}
+
+func (k batchKey) String() string {
+	return fmt.Sprintf("%d-%d", k.start, k.end)
+}
+
+func (k batchKey) startTime() time.Time {
+	return time.Unix(int64(k.start), 0).UTC()
+}
+
+func (k batchKey) endTime() time.Time {
+	return time.Unix(int64(k.end), 0).UTC()
+}
+
+// lastBatchKey returns the last batch imported for the config, or nil if
+// there is none.
+func lastBatchKey(ei *model.ExportImport) *batchKey {
+	if ei.LastBatchStart == nil || ei.LastBatchEnd == nil {
+		return nil
+	}
+	return &batchKey{
+		start: uint64(ei.LastBatchStart.Unix()),
+		end:   uint64(ei.LastBatchEnd.Unix()),
+	}
+}
+
+func batchKeyOf(e *exportproto.TemporaryExposureKeyExport) batchKey {
+	return batchKey{
+		start: e.GetStartTimestamp(),
+		end:   e.GetEndTimestamp(),
+	}
+}
+
+// validateExportFile validates the batch metadata of a single export file and
+// that its signature file signs exactly the signature infos it lists.
+func validateExportFile(ef *exportFile) error {
+	e := ef.export
+
+	num, size := e.GetBatchNum(), e.GetBatchSize()
+	if size < 1 {
+		return fmt.Errorf("%w: batch_size must be positive, got %d", ErrStrictValidation, size)
+	}
+	if num < 1 || num > size {
+		return fmt.Errorf("%w: batch_num %d is not within batch_size %d", ErrStrictValidation, num, size)
+	}
+
+	if e.StartTimestamp == nil || e.EndTimestamp == nil {
+		return fmt.Errorf("%w: start_timestamp and end_timestamp are required", ErrStrictValidation)
+	}
+	if start, end := e.GetStartTimestamp(), e.GetEndTimestamp(); start >= end {
+		return fmt.Errorf("%w: start_timestamp %d is not before end_timestamp %d", ErrStrictValidation, start, end)
+	}
+
+	infos := e.GetSignatureInfos()
+	if len(infos) == 0 {
+		return fmt.Errorf("%w: no signature_infos", ErrStrictValidation)
+	}
+	unsigned := make(map[string]*exportproto.SignatureInfo, len(infos))
+	for _, info := range infos {
+		unsigned[signatureInfoID(info)] = info
+	}
+
+	for _, sig := range ef.signatures.GetSignatures() {
+		if sig.GetBatchNum() != num || sig.GetBatchSize() != size {
+			return fmt.Errorf("%w: signature is for batch %d of %d, export is batch %d of %d",
+				ErrStrictValidation, sig.GetBatchNum(), sig.GetBatchSize(), num, size)
+		}
+
+		id := signatureInfoID(sig.GetSignatureInfo())
+		if _, ok := unsigned[id]; !ok {
+			return fmt.Errorf("%w: signature for %s is not listed in signature_infos, or listed twice",
+				ErrStrictValidation, keyIDAndVersion(sig.GetSignatureInfo()))
+		}
+		delete(unsigned, id)
+	}
+
+	for _, info := range infos {
+		if _, ok := unsigned[signatureInfoID(info)]; !ok {
+			continue
+		}
+		return fmt.Errorf("%w: signature_info %s has no signature", ErrStrictValidation, keyIDAndVersion(info))
+	}
+	return nil
+}
+
+// validateBatch validates that the export files of a batch, which have each
+// passed validateExportFile, agree with each other. If they do but some are
+// missing, it returns an error wrapping ErrBatchIncomplete.
+func validateBatch(files []*exportFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	first := files[0].export
+	key := batchKeyOf(first)
+	size := first.GetBatchSize()
+	infos := signatureInfoIDs(first)
+
+	seen := make(map[int32]struct{}, len(files))
+	for _, ef := range files {
+		e := ef.export
+
+		if got := batchKeyOf(e); got != key {
+			return fmt.Errorf("%w: batch %s contains a file for %s", ErrStrictValidation, key, got)
+		}
+		if got := e.GetBatchSize(); got != size {
+			return fmt.Errorf("%w: batch %s has files with batch_size %d and %d", ErrStrictValidation, key, size, got)
+		}
+		if got := e.GetRegion(); got != first.GetRegion() {
+			return fmt.Errorf("%w: batch %s has files for region %q and %q", ErrStrictValidation, key, first.GetRegion(), got)
+		}
+		if got := signatureInfoIDs(e); got != infos {
+			return fmt.Errorf("%w: batch %s has files with different signature_infos", ErrStrictValidation, key)
+		}
+
+		num := e.GetBatchNum()
+		if _, ok := seen[num]; ok {
+			return fmt.Errorf("%w: batch %s has more than one file with batch_num %d", ErrStrictValidation, key, num)
+		}
+		seen[num] = struct{}{}
+	}
+
+	if got := len(seen); got != int(size) {
+		return fmt.Errorf("%w: batch %s has %d of %d files", ErrBatchIncomplete, key, got, size)
+	}
+	return nil
+}
+
+// checkBatch decides whether a downloaded batch can be imported. It returns
+// nil if it can, an error wrapping ErrBatchIncomplete if its files should be
+// retried, and an error wrapping ErrStrictValidation if it must be rejected.
+// An incomplete batch is rejected once its files have been retried
+// maxRetries times.
+func checkBatch(b *pendingBatch, last *batchKey, maxRetries uint) error {
+	if b.rejected {
+		return fmt.Errorf("%w: batch %s has a rejected file", ErrStrictValidation, b.key)
+	}
+	if err := validateBatch(b.exports); err != nil {
+		if retries := b.retries(); errors.Is(err, ErrBatchIncomplete) && retries >= maxRetries {
+			return fmt.Errorf("%w: giving up after %d retries: %v", ErrStrictValidation, retries, err)
+		}
+		return err
+	}
+	if last != nil {
+		return validateBatchOrder(*last, b.key)
+	}
+	return nil
+}
+
+// validateBatchOrder validates that a batch doesn't start or end before the
+// batch that was imported before it.
+func validateBatchOrder(prev, next batchKey) error {
+	if next.start < prev.start || next.end < prev.end {
+		return fmt.Errorf("%w: batch %s is out of order after batch %s", ErrStrictValidation, next, prev)
+	}
+	return nil
+}
+
+// signatureInfoID returns a comparable identity of the entire signature info.
+func signatureInfoID(info *exportproto.SignatureInfo) string {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(info)
+	if err != nil {
+		// Marshaling a message that was just parsed doesn't fail, fall back to
+		// the key that identifies it.
+		return keyIDAndVersion(info)
+	}
+	return string(b)
+}
+
+// signatureInfoIDs returns the identities of all the signature infos of an
+// export, independent of their order.
+func signatureInfoIDs(e *exportproto.TemporaryExposureKeyExport) string {
+	ids := make([]string, 0, len(e.GetSignatureInfos()))
+	for _, info := range e.GetSignatureInfos() {
+		ids = append(ids, signatureInfoID(info))
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, "\x00")
+}
+
+func keyIDAndVersion(info *exportproto.SignatureInfo) string {
+	return fmt.Sprintf("%s.%s", info.GetVerificationKeyId(), info.GetVerificationKeyVersion())
+}
+
+// pendingBatch is a batch of downloaded export files that have not been
+// imported yet.
+type pendingBatch struct {
+	key      batchKey
+	requests []*ImportRequest
+	exports  []*exportFile
+
+	// rejected is true if a file of the batch failed validation.
+	rejected bool
+}
+
+// retries returns the most times any file of the batch has been retried.
+func (b *pendingBatch) retries() uint {
+	var retries uint
+	for _, ir := range b.requests {
+		if ir.file.Retries > retries {
+			retries = ir.file.Retries
+		}
+	}
+	return retries
+}
+
+// batchGrouper groups downloaded export files into batches. The files of a
+// batch are expected to be next to each other in the index.
+type batchGrouper struct {
+	current  *pendingBatch
+	rejected map[batchKey]struct{}
+}
+
+// add adds a downloaded file to its batch. If the file starts a new batch, the
+// previous batch is returned.
+func (g *batchGrouper) add(ir *ImportRequest, ef *exportFile) *pendingBatch {
+	var done *pendingBatch
+	if key := batchKeyOf(ef.export); g.current == nil || g.current.key != key {
+		_, rejected := g.rejected[key]
+		done, g.current = g.current, &pendingBatch{key: key, rejected: rejected}
+	}
+	g.current.requests = append(g.current.requests, ir)
+	g.current.exports = append(g.current.exports, ef)
+	return done
+}
+
+// reject marks the batch of a file that failed validation as rejected.
+func (g *batchGrouper) reject(key batchKey) {
+	if g.rejected == nil {
+		g.rejected = make(map[batchKey]struct{})
+	}
+	g.rejected[key] = struct{}{}
+	if g.current != nil && g.current.key == key {
+		g.current.rejected = true
+	}
+}
+
+// flush returns the batch that is being grouped, if any.
+func (g *batchGrouper) flush() *pendingBatch {
+	b := g.current
+	g.current = nil
+	return b
+}
+
+// leadingImportFiles returns the files from the start of files that can be
+// imported now. Batches are imported in the order of the index, so a file that
+// is leased by another run or waiting to be retried holds back all the files
+// after it.
+func leadingImportFiles(files []*model.ImportFile, lockDuration, retryRate time.Duration) []*model.ImportFile {
+	lockOverrideTime := time.Now().UTC().Add(-lockDuration)
+	for i, file := range files {
+		switch file.Status {
+		case model.ImportFileOpen:
+			if file.ShouldTry(retryRate) {
+				continue
+			}
+		case model.ImportFilePending:
+			if file.ProcessedAt == nil || file.ProcessedAt.Before(lockOverrideTime) {
+				continue
+			}
+		}
+		return files[:i]
+	}
+	return files
+}
@@ -0,0 +1,530 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportimport
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/exportimport/model"
+	exportproto "github.com/google/exposure-notifications-server/internal/pb/export"
+	"google.golang.org/protobuf/proto"
+)
+
+func testSignatureInfo(keyID string) *exportproto.SignatureInfo {
+	return &exportproto.SignatureInfo{
+		VerificationKeyId:      proto.String(keyID),
+		VerificationKeyVersion: proto.String("v1"),
+		SignatureAlgorithm:     proto.String("1.2.840.10045.4.3.2"),
+	}
+}
+
+// testExportFile returns an export file of the batch from start to end that
+// is signed by each of keyIDs.
+func testExportFile(start, end uint64, num, size int32, keyIDs ...string) *exportFile {
+	e := &exportproto.TemporaryExposureKeyExport{
+		StartTimestamp: proto.Uint64(start),
+		EndTimestamp:   proto.Uint64(end),
+		Region:         proto.String("US"),
+		BatchNum:       proto.Int32(num),
+		BatchSize:      proto.Int32(size),
+	}
+	sigs := &exportproto.TEKSignatureList{}
+	for _, id := range keyIDs {
+		e.SignatureInfos = append(e.SignatureInfos, testSignatureInfo(id))
+		sigs.Signatures = append(sigs.Signatures, &exportproto.TEKSignature{
+			SignatureInfo: testSignatureInfo(id),
+			BatchNum:      proto.Int32(num),
+			BatchSize:     proto.Int32(size),
+			Signature:     []byte("signature"),
+		})
+	}
+	return &exportFile{
+		export:     e,
+		signatures: sigs,
+	}
+}
+
+func TestValidateExportFile(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		mutate func(ef *exportFile)
+		err    bool
+	}{
+		{
+			name:   "valid",
+			mutate: func(ef *exportFile) {},
+		},
+		{
+			name: "zero_batch_size",
+			mutate: func(ef *exportFile) {
+				ef.export.BatchSize = proto.Int32(0)
+			},
+			err: true,
+		},
+		{
+			name: "zero_batch_num",
+			mutate: func(ef *exportFile) {
+				ef.export.BatchNum = proto.Int32(0)
+			},
+			err: true,
+		},
+		{
+			name: "batch_num_above_size",
+			mutate: func(ef *exportFile) {
+				ef.export.BatchNum = proto.Int32(3)
+			},
+			err: true,
+		},
+		{
+			name: "missing_start",
+			mutate: func(ef *exportFile) {
+				ef.export.StartTimestamp = nil
+			},
+			err: true,
+		},
+		{
+			name: "missing_end",
+			mutate: func(ef *exportFile) {
+				ef.export.EndTimestamp = nil
+			},
+			err: true,
+		},
+		{
+			name: "start_equals_end",
+			mutate: func(ef *exportFile) {
+				ef.export.EndTimestamp = ef.export.StartTimestamp
+			},
+			err: true,
+		},
+		{
+			name: "start_after_end",
+			mutate: func(ef *exportFile) {
+				ef.export.StartTimestamp = proto.Uint64(3000)
+			},
+			err: true,
+		},
+		{
+			name: "no_signature_infos",
+			mutate: func(ef *exportFile) {
+				ef.export.SignatureInfos = nil
+			},
+			err: true,
+		},
+		{
+			name: "signature_for_other_batch",
+			mutate: func(ef *exportFile) {
+				ef.signatures.Signatures[0].BatchNum = proto.Int32(2)
+			},
+			err: true,
+		},
+		{
+			name: "signature_not_listed",
+			mutate: func(ef *exportFile) {
+				ef.signatures.Signatures[1].SignatureInfo = testSignatureInfo("other")
+			},
+			err: true,
+		},
+		{
+			name: "duplicate_signature",
+			mutate: func(ef *exportFile) {
+				ef.signatures.Signatures = append(ef.signatures.Signatures, ef.signatures.Signatures[0])
+			},
+			err: true,
+		},
+		{
+			name: "unsigned_info",
+			mutate: func(ef *exportFile) {
+				ef.signatures.Signatures = ef.signatures.Signatures[:1]
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ef := testExportFile(1000, 2000, 1, 2, "a", "b")
+			tc.mutate(ef)
+
+			err := validateExportFile(ef)
+			if got := err != nil; got != tc.err {
+				t.Fatalf("expected error: %t, got %v", tc.err, err)
+			}
+			if err != nil && !errors.Is(err, ErrStrictValidation) {
+				t.Errorf("expected %v, got %v", ErrStrictValidation, err)
+			}
+		})
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		files func() []*exportFile
+		want  error
+	}{
+		{
+			name: "empty",
+			files: func() []*exportFile {
+				return nil
+			},
+		},
+		{
+			name: "complete",
+			files: func() []*exportFile {
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 2, "a"),
+					testExportFile(1000, 2000, 2, 2, "a"),
+				}
+			},
+		},
+		{
+			name: "mixed_timestamps",
+			files: func() []*exportFile {
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 2, "a"),
+					testExportFile(1000, 2001, 2, 2, "a"),
+				}
+			},
+			want: ErrStrictValidation,
+		},
+		{
+			name: "mixed_batch_size",
+			files: func() []*exportFile {
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 2, "a"),
+					testExportFile(1000, 2000, 2, 3, "a"),
+				}
+			},
+			want: ErrStrictValidation,
+		},
+		{
+			name: "mixed_region",
+			files: func() []*exportFile {
+				other := testExportFile(1000, 2000, 2, 2, "a")
+				other.export.Region = proto.String("CA")
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 2, "a"),
+					other,
+				}
+			},
+			want: ErrStrictValidation,
+		},
+		{
+			name: "mixed_signature_infos",
+			files: func() []*exportFile {
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 2, "a"),
+					testExportFile(1000, 2000, 2, 2, "a", "b"),
+				}
+			},
+			want: ErrStrictValidation,
+		},
+		{
+			name: "signature_infos_in_any_order",
+			files: func() []*exportFile {
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 2, "a", "b"),
+					testExportFile(1000, 2000, 2, 2, "b", "a"),
+				}
+			},
+		},
+		{
+			name: "duplicate_batch_num",
+			files: func() []*exportFile {
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 2, "a"),
+					testExportFile(1000, 2000, 1, 2, "a"),
+				}
+			},
+			want: ErrStrictValidation,
+		},
+		{
+			name: "missing_file",
+			files: func() []*exportFile {
+				return []*exportFile{
+					testExportFile(1000, 2000, 1, 3, "a"),
+					testExportFile(1000, 2000, 3, 3, "a"),
+				}
+			},
+			want: ErrBatchIncomplete,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateBatch(tc.files())
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateBatchOrder(t *testing.T) {
+	t.Parallel()
+
+	prev := batchKey{start: 1000, end: 2000}
+
+	cases := []struct {
+		name string
+		next batchKey
+		err  bool
+	}{
+		{
+			name: "same",
+			next: prev,
+		},
+		{
+			name: "after",
+			next: batchKey{start: 2000, end: 3000},
+		},
+		{
+			name: "overlapping",
+			next: batchKey{start: 1500, end: 2500},
+		},
+		{
+			name: "split_batch",
+			next: batchKey{start: 1000, end: 2001},
+		},
+		{
+			name: "starts_before",
+			next: batchKey{start: 999, end: 3000},
+			err:  true,
+		},
+		{
+			name: "ends_before",
+			next: batchKey{start: 1000, end: 1999},
+			err:  true,
+		},
+		{
+			name: "before",
+			next: batchKey{start: 0, end: 1000},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateBatchOrder(prev, tc.next)
+			if got := err != nil; got != tc.err {
+				t.Fatalf("expected error: %t, got %v", tc.err, err)
+			}
+			if err != nil && !errors.Is(err, ErrStrictValidation) {
+				t.Errorf("expected %v, got %v", ErrStrictValidation, err)
+			}
+		})
+	}
+}
+
+func TestCheckBatch(t *testing.T) {
+	t.Parallel()
+
+	newBatch := func(retries uint, files ...*exportFile) *pendingBatch {
+		var g batchGrouper
+		for _, ef := range files {
+			g.add(&ImportRequest{file: &model.ImportFile{Retries: retries}}, ef)
+		}
+		return g.flush()
+	}
+
+	cases := []struct {
+		name  string
+		batch *pendingBatch
+		last  *batchKey
+		want  error
+	}{
+		{
+			name:  "complete",
+			batch: newBatch(0, testExportFile(1000, 2000, 1, 1, "a")),
+		},
+		{
+			name:  "in_order",
+			batch: newBatch(0, testExportFile(1000, 2000, 1, 1, "a")),
+			last:  &batchKey{start: 0, end: 1000},
+		},
+		{
+			name:  "out_of_order",
+			batch: newBatch(0, testExportFile(1000, 2000, 1, 1, "a")),
+			last:  &batchKey{start: 2000, end: 3000},
+			want:  ErrStrictValidation,
+		},
+		{
+			name: "rejected_file",
+			batch: func() *pendingBatch {
+				b := newBatch(0, testExportFile(1000, 2000, 1, 2, "a"))
+				b.rejected = true
+				return b
+			}(),
+			want: ErrStrictValidation,
+		},
+		{
+			name:  "incomplete",
+			batch: newBatch(1, testExportFile(1000, 2000, 1, 2, "a")),
+			want:  ErrBatchIncomplete,
+		},
+		{
+			name:  "incomplete_out_of_retries",
+			batch: newBatch(2, testExportFile(1000, 2000, 1, 2, "a")),
+			want:  ErrStrictValidation,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkBatch(tc.batch, tc.last, 2)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestBatchGrouper(t *testing.T) {
+	t.Parallel()
+
+	files := []*exportFile{
+		testExportFile(1000, 2000, 1, 2, "a"),
+		testExportFile(1000, 2000, 2, 2, "a"),
+		testExportFile(2000, 3000, 1, 2, "a"),
+	}
+
+	var g batchGrouper
+	if b := g.add(&ImportRequest{}, files[0]); b != nil {
+		t.Fatalf("expected no batch, got %v", b.key)
+	}
+	if b := g.add(&ImportRequest{}, files[1]); b != nil {
+		t.Fatalf("expected no batch, got %v", b.key)
+	}
+
+	// The second file of the next batch was rejected.
+	next := batchKeyOf(files[2].export)
+	g.reject(next)
+
+	b := g.add(&ImportRequest{}, files[2])
+	if b == nil {
+		t.Fatal("expected first batch to be done")
+	}
+	if got, want := b.key, batchKeyOf(files[0].export); got != want {
+		t.Errorf("expected batch %v, got %v", want, got)
+	}
+	if got, want := len(b.exports), 2; got != want {
+		t.Errorf("expected %d files, got %d", want, got)
+	}
+	if b.rejected {
+		t.Errorf("expected first batch not to be rejected")
+	}
+
+	b = g.flush()
+	if b == nil {
+		t.Fatal("expected second batch")
+	}
+	if got := b.key; got != next {
+		t.Errorf("expected batch %v, got %v", next, got)
+	}
+	if !b.rejected {
+		t.Errorf("expected second batch to be rejected")
+	}
+	if b := g.flush(); b != nil {
+		t.Errorf("expected no batch after flush, got %v", b.key)
+	}
+}
+
+func TestLeadingImportFiles(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	recent := now.Add(-time.Minute)
+	expired := now.Add(-time.Hour)
+	discovered := now.Add(-2 * time.Hour)
+
+	open := &model.ImportFile{ID: 1, Status: model.ImportFileOpen, DiscoveredAt: discovered}
+	expiredLease := &model.ImportFile{ID: 2, Status: model.ImportFilePending, DiscoveredAt: discovered, ProcessedAt: &expired}
+	backoff := &model.ImportFile{ID: 3, Status: model.ImportFileOpen, DiscoveredAt: discovered, Retries: 1}
+	leased := &model.ImportFile{ID: 4, Status: model.ImportFilePending, DiscoveredAt: discovered, ProcessedAt: &recent}
+
+	cases := []struct {
+		name  string
+		files []*model.ImportFile
+		want  []int64
+	}{
+		{
+			name:  "all",
+			files: []*model.ImportFile{open, expiredLease},
+			want:  []int64{1, 2},
+		},
+		{
+			name:  "held_back_by_retry",
+			files: []*model.ImportFile{open, backoff, expiredLease},
+			want:  []int64{1},
+		},
+		{
+			name:  "held_back_by_lease",
+			files: []*model.ImportFile{leased, open},
+			want:  []int64{},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := leadingImportFiles(tc.files, 30*time.Minute, 6*time.Hour)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %d files, got %d", len(tc.want), len(got))
+			}
+			for i, f := range got {
+				if f.ID != tc.want[i] {
+					t.Errorf("expected file %d at %d, got %d", tc.want[i], i, f.ID)
+				}
+			}
+		})
+	}
+}
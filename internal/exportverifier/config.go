@@ -0,0 +1,97 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportverifier
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"github.com/google/exposure-notifications-server/pkg/secrets"
+)
+
+// Compile-time check to assert this config matches requirements.
+var (
+	_ setup.DatabaseConfigProvider              = (*Config)(nil)
+	_ setup.SecretManagerConfigProvider         = (*Config)(nil)
+	_ setup.ObservabilityExporterConfigProvider = (*Config)(nil)
+	_ setup.KeyManagerConfigProvider            = (*Config)(nil)
+)
+
+// Config represents the configuration and associated environment variables for
+// the export verifier.
+type Config struct {
+	Database              database.Config
+	SecretManager         secrets.Config
+	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
+	KeyManager            keys.Config
+
+	Port string `env:"PORT, default=8080"`
+
+	// ExportBaseURL is the URL from which export files are downloaded, as
+	// devices would download them. The string "{bucket}" is replaced with the
+	// bucket name of each export config.
+	ExportBaseURL   string        `env:"EXPORT_BASE_URL, default=https://storage.googleapis.com/{bucket}"`
+	DownloadTimeout time.Duration `env:"DOWNLOAD_TIMEOUT, default=30s"`
+
+	// MaxStaleness is how far the published exports may fall behind before
+	// they are reported as stale. It should cover the export worker schedule
+	// and the CDN cache lifetime of the index file.
+	MaxStaleness time.Duration `env:"MAX_STALENESS, default=2h"`
+
+	// MinKeyRatio is the share of the keys in the database for a batch that
+	// must be in the published files of the batch. Only keys that every export
+	// of the batch would include are counted, so the ratio is a lower bound.
+	// A ratio of 0 disables the check.
+	MinKeyRatio float64 `env:"MIN_KEY_RATIO, default=0.9"`
+}
+
+func (c *Config) DatabaseConfig() *database.Config {
+	return &c.Database
+}
+
+func (c *Config) SecretManagerConfig() *secrets.Config {
+	return &c.SecretManager
+}
+
+func (c *Config) ObservabilityExporterConfig() *observability.Config {
+	return &c.ObservabilityExporter
+}
+
+func (c *Config) KeyManagerConfig() *keys.Config {
+	return &c.KeyManager
+}
+
+// Validate checks the verifier configuration.
+func (c *Config) Validate() error {
+	if c.ExportBaseURL == "" {
+		return fmt.Errorf("EXPORT_BASE_URL is required")
+	}
+	if c.DownloadTimeout <= 0 {
+		return fmt.Errorf("DOWNLOAD_TIMEOUT must be positive, got %v", c.DownloadTimeout)
+	}
+	if c.MaxStaleness <= 0 {
+		return fmt.Errorf("MAX_STALENESS must be positive, got %v", c.MaxStaleness)
+	}
+	if c.MinKeyRatio < 0 || c.MinKeyRatio > 1 {
+		return fmt.Errorf("MIN_KEY_RATIO must be between 0 and 1, got %v", c.MinKeyRatio)
+	}
+	return nil
+}
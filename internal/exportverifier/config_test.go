@@ -0,0 +1,83 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportverifier
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *Config {
+		return &Config{
+			ExportBaseURL:   "https://storage.googleapis.com/{bucket}",
+			DownloadTimeout: 30 * time.Second,
+			MaxStaleness:    2 * time.Hour,
+			MinKeyRatio:     0.9,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		modify func(c *Config)
+		err    bool
+	}{
+		{
+			name:   "default",
+			modify: func(c *Config) {},
+		},
+		{
+			name:   "key_count_disabled",
+			modify: func(c *Config) { c.MinKeyRatio = 0 },
+		},
+		{
+			name:   "missing_base_url",
+			modify: func(c *Config) { c.ExportBaseURL = "" },
+			err:    true,
+		},
+		{
+			name:   "no_download_timeout",
+			modify: func(c *Config) { c.DownloadTimeout = 0 },
+			err:    true,
+		},
+		{
+			name:   "no_staleness",
+			modify: func(c *Config) { c.MaxStaleness = 0 },
+			err:    true,
+		},
+		{
+			name:   "ratio_too_large",
+			modify: func(c *Config) { c.MinKeyRatio = 1.5 },
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := valid()
+			tc.modify(c)
+			err := c.Validate()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportverifier
+
+import (
+	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const metricPrefix = metrics.MetricRoot + "export-verifier"
+
+var (
+	mSuccess  = stats.Int64(metricPrefix+"/success", "successful execution", stats.UnitDimensionless)
+	mFindings = stats.Int64(metricPrefix+"/findings", "problems with published exports", stats.UnitDimensionless)
+
+	checkTag = tag.MustNewKey("check")
+)
+
+func init() {
+	observability.CollectViews([]*view.View{
+		{
+			Name:        metricPrefix + "/success",
+			Description: "Number of successes",
+			Measure:     mSuccess,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/findings",
+			Description: "Number of problems found with published exports",
+			Measure:     mFindings,
+			TagKeys:     []tag.Key{checkTag},
+			Aggregation: view.Sum(),
+		},
+	}...)
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exportverifier implements the API handlers for verifying the export
+// files that are published to the CDN.
+package exportverifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	exportdb "github.com/google/exposure-notifications-server/internal/export/database"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/render"
+	"github.com/google/exposure-notifications-server/pkg/server"
+	"github.com/gorilla/mux"
+)
+
+// Server hosts end points to verify published exports.
+type Server struct {
+	config     *Config
+	env        *serverenv.ServerEnv
+	db         *database.DB
+	exportDB   *exportdb.ExportDB
+	publishDB  *publishdb.PublishDB
+	keyManager keys.KeyManager
+	client     *http.Client
+	h          *render.Renderer
+}
+
+// NewServer creates a Server that downloads the latest published export files
+// of each export config and checks them against the database.
+func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
+	if env.Database() == nil {
+		return nil, fmt.Errorf("missing database in server environment")
+	}
+	if env.GetKeyManager() == nil {
+		return nil, fmt.Errorf("missing key manager in server environment")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation: %w", err)
+	}
+
+	db := env.Database()
+	return &Server{
+		config:     cfg,
+		env:        env,
+		db:         db,
+		exportDB:   exportdb.New(db),
+		publishDB:  publishdb.New(db),
+		keyManager: env.GetKeyManager(),
+		client:     &http.Client{Timeout: cfg.DownloadTimeout},
+		h:          render.NewRenderer(),
+	}, nil
+}
+
+// Routes defines and returns the routes for this server.
+func (s *Server) Routes(ctx context.Context) *mux.Router {
+	logger := logging.FromContext(ctx).Named("exportverifier")
+
+	r := mux.NewRouter()
+	s.config.Middleware.Use(r, logger)
+
+	r.Handle("/health", server.HandleHealthz(s.env.Database()))
+	r.Handle("/verify-exports", s.handleVerify())
+
+	return r
+}
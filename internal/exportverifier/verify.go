@@ -0,0 +1,451 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportverifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/hashicorp/go-multierror"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// Global lock id for export verification.
+const lockID = "export-verifier-lock"
+
+// Check is the name of a verification that published exports can fail.
+type Check string
+
+const (
+	// CheckIndex fails when the index file cannot be downloaded or parsed.
+	CheckIndex Check = "index"
+	// CheckStale fails when batches or files are not published in time.
+	CheckStale Check = "stale"
+	// CheckFile fails when an export file cannot be downloaded or parsed.
+	CheckFile Check = "file"
+	// CheckSignature fails when an export file is not signed by each of the
+	// signature infos of its export config.
+	CheckSignature Check = "signature"
+	// CheckKeyCount fails when an export batch has fewer keys than expected.
+	CheckKeyCount Check = "key_count"
+)
+
+// Finding is a problem with the published exports of an export config.
+type Finding struct {
+	ConfigID int64  `json:"configID"`
+	Check    Check  `json:"check"`
+	Filename string `json:"filename,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+var errNotFound = errors.New("not found")
+
+func (s *Server) handleVerify() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		logger := logging.FromContext(ctx).Named("handleVerify").
+			With("lock", lockID)
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		unlock, err := s.db.Lock(ctx, lockID, 5*time.Minute)
+		if err != nil {
+			if errors.Is(err, database.ErrAlreadyLocked) {
+				logger.Debugw("skipping (already locked)")
+				s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("too early"))
+				return
+			}
+			logger.Errorw("failed to obtain lock", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				logger.Errorw("failed to unlock", "error", err)
+			}
+		}()
+
+		findings, err := s.verify(ctx, time.Now().UTC())
+		if err != nil {
+			logger.Errorw("failed to verify exports", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		stats.Record(ctx, mSuccess.M(1))
+		s.h.RenderJSON(w, http.StatusOK, findings)
+	})
+}
+
+// verify checks the published exports of each active export config. Every
+// finding is logged and counted.
+func (s *Server) verify(ctx context.Context, now time.Time) ([]*Finding, error) {
+	logger := logging.FromContext(ctx).Named("verify")
+
+	var configs []*model.ExportConfig
+	if err := s.exportDB.IterateExportConfigs(ctx, now, func(ec *model.ExportConfig) error {
+		configs = append(configs, ec)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("listing export configs: %w", err)
+	}
+
+	var findings []*Finding
+	var result *multierror.Error
+	for _, ec := range configs {
+		found, err := s.verifyConfig(ctx, ec, now)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("export config %d: %w", ec.ConfigID, err))
+		}
+
+		for _, f := range found {
+			f.ConfigID = ec.ConfigID
+
+			logger.Warnw("problem with published exports",
+				"config_id", f.ConfigID,
+				"check", f.Check,
+				"filename", f.Filename,
+				"detail", f.Detail)
+			if err := stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(checkTag, string(f.Check))}, mFindings.M(1)); err != nil {
+				logger.Errorw("failed to record stats", "error", err)
+			}
+		}
+		findings = append(findings, found...)
+	}
+
+	return findings, result.ErrorOrNil()
+}
+
+// verifyConfig downloads the index and the files of the latest published batch
+// of the export config and compares them to the database.
+func (s *Server) verifyConfig(ctx context.Context, ec *model.ExportConfig, now time.Time) ([]*Finding, error) {
+	var findings []*Finding
+
+	// The batcher creates batches whether or not there are keys, so the
+	// batches of an active config always reach close to now.
+	latestEnd, err := s.exportDB.LatestExportBatchEnd(ctx, ec)
+	if err != nil {
+		return nil, err
+	}
+	if limit := now.Add(-ec.Period - s.config.MaxStaleness); latestEnd.Before(limit) && ec.From.Before(limit) {
+		findings = append(findings, &Finding{
+			Check:  CheckStale,
+			Detail: fmt.Sprintf("no export batch ends after %s", limit.Format(time.RFC3339)),
+		})
+	}
+
+	written, err := s.exportDB.LookupExportFiles(ctx, ec.ConfigID, 2*(ec.Period+s.config.MaxStaleness))
+	if err != nil {
+		return nil, err
+	}
+
+	indexName := ec.FilenameRoot + "/index.txt"
+	index, err := s.download(ctx, s.fileURL(ec, indexName))
+	if err != nil {
+		if errors.Is(err, errNotFound) && len(written) == 0 {
+			// Nothing has been published yet.
+			return findings, nil
+		}
+		return append(findings, &Finding{Check: CheckIndex, Filename: indexName, Detail: err.Error()}), nil
+	}
+	published := parseIndex(index)
+
+	// The newest file in the database must reach the CDN in time.
+	if n := len(written); n > 0 {
+		latest := written[n-1]
+		_, end, err := batchWindow(latest)
+		if err != nil {
+			return nil, err
+		}
+		if !contains(published, latest) && end.Before(now.Add(-s.config.MaxStaleness)) {
+			findings = append(findings, &Finding{
+				Check:    CheckStale,
+				Filename: latest,
+				Detail:   "file is not in the published index",
+			})
+		}
+	}
+
+	files, err := latestBatch(published)
+	if err != nil {
+		return append(findings, &Finding{Check: CheckIndex, Filename: indexName, Detail: err.Error()}), nil
+	}
+	if len(files) == 0 || ec.EncryptionKeyID != "" {
+		// Encrypted files cannot be checked with public keys.
+		return findings, nil
+	}
+
+	sigInfos, err := s.exportDB.LookupSignatureInfos(ctx, ec.SignatureInfoIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	verifiers, err := s.verifiers(ctx, sigInfos)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := 0
+	complete := true
+	for _, name := range files {
+		data, err := s.download(ctx, s.fileURL(ec, name))
+		if err != nil {
+			findings = append(findings, &Finding{Check: CheckFile, Filename: name, Detail: err.Error()})
+			complete = false
+			continue
+		}
+
+		keys, problems, err := checkFile(data, verifiers)
+		if err != nil {
+			findings = append(findings, &Finding{Check: CheckFile, Filename: name, Detail: err.Error()})
+			complete = false
+			continue
+		}
+		for _, p := range problems {
+			findings = append(findings, &Finding{Check: CheckSignature, Filename: name, Detail: p})
+		}
+		exported += keys
+	}
+
+	// Derived configs filter the keys of their parent, so only the keys of
+	// regular configs are counted.
+	if complete && !ec.IsDerived() && s.config.MinKeyRatio > 0 {
+		f, err := s.checkKeyCount(ctx, files[0], exported)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			findings = append(findings, f)
+		}
+	}
+
+	return findings, nil
+}
+
+// checkKeyCount compares the number of keys exported in the batch of the file
+// with the number of keys in the database for the batch.
+func (s *Server) checkKeyCount(ctx context.Context, filename string, exported int) (*Finding, error) {
+	file, err := s.exportDB.LookupExportFile(ctx, filename)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			// The batch has been cleaned up.
+			return nil, nil
+		}
+		return nil, err
+	}
+	eb, err := s.exportDB.LookupExportBatch(ctx, file.BatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.publishDB.CountExposures(ctx, keyCountCriteria(eb))
+	if err != nil {
+		return nil, err
+	}
+	if float64(exported) >= float64(count)*s.config.MinKeyRatio {
+		return nil, nil
+	}
+	return &Finding{
+		Check:    CheckKeyCount,
+		Filename: filename,
+		Detail:   fmt.Sprintf("batch %d exported %d keys, the database has %d", eb.BatchID, exported, count),
+	}, nil
+}
+
+// keyCountCriteria returns the criteria for the keys that any export of the
+// batch includes. Travelers depend on travel rules and consent, and are not
+// counted.
+func keyCountCriteria(eb *model.ExportBatch) publishdb.IterateExposuresCriteria {
+	return publishdb.IterateExposuresCriteria{
+		SinceTimestamp:       eb.StartTimestamp,
+		UntilTimestamp:       eb.EndTimestamp,
+		IncludeRegions:       eb.EffectiveInputRegions(),
+		OnlyNonTravelers:     true,
+		ExcludeRegions:       eb.ExcludeRegions,
+		ExcludeRevoked:       true,
+		ExcludeTestData:      !eb.TestData,
+		OnlyTestData:         eb.TestData,
+		IncludeJurisdictions: eb.IncludeJurisdictions,
+		ExcludeJurisdictions: eb.ExcludeJurisdictions,
+	}
+}
+
+// verifier is the public key of a signature info.
+type verifier struct {
+	signatureInfo *model.SignatureInfo
+	publicKey     *ecdsa.PublicKey
+}
+
+func (s *Server) verifiers(ctx context.Context, sigInfos []*model.SignatureInfo) ([]*verifier, error) {
+	verifiers := make([]*verifier, 0, len(sigInfos))
+	for _, si := range sigInfos {
+		signer, err := s.keyManager.NewSigner(ctx, si.SigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signer for signature info %d: %w", si.ID, err)
+		}
+		pub, ok := signer.Public().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("signature info %d: unsupported public key type %T", si.ID, signer.Public())
+		}
+		verifiers = append(verifiers, &verifier{signatureInfo: si, publicKey: pub})
+	}
+	return verifiers, nil
+}
+
+// checkFile parses the export file and verifies its signature for each of the
+// verifiers. It returns the number of primary keys in the file and a
+// description of each signature that is missing or invalid.
+func checkFile(data []byte, verifiers []*verifier) (int, []string, error) {
+	keyExport, digest, err := export.UnmarshalExportFile(data)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse export: %w", err)
+	}
+	sigList, err := export.UnmarshalSignatureFile(data)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse signatures: %w", err)
+	}
+
+	var problems []string
+	for _, v := range verifiers {
+		si := v.signatureInfo
+
+		var sig []byte
+		for _, s := range sigList.GetSignatures() {
+			info := s.GetSignatureInfo()
+			if info.GetVerificationKeyId() == si.SigningKeyID && info.GetVerificationKeyVersion() == si.SigningKeyVersion {
+				sig = s.GetSignature()
+				break
+			}
+		}
+
+		switch {
+		case sig == nil:
+			problems = append(problems, fmt.Sprintf("no signature for key %q version %q", si.SigningKeyID, si.SigningKeyVersion))
+		case !ecdsa.VerifyASN1(v.publicKey, digest, sig):
+			problems = append(problems, fmt.Sprintf("invalid signature for key %q version %q", si.SigningKeyID, si.SigningKeyVersion))
+		}
+	}
+
+	return len(keyExport.GetKeys()), problems, nil
+}
+
+// fileURL returns the CDN URL of the object in the bucket of the export config.
+func (s *Server) fileURL(ec *model.ExportConfig, name string) string {
+	base := strings.ReplaceAll(s.config.ExportBaseURL, "{bucket}", ec.BucketName)
+	return strings.TrimSuffix(base, "/") + "/" + name
+}
+
+func (s *Server) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("failed to download %s: %w", url, errNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return data, nil
+}
+
+// parseIndex returns the filenames listed in an index file.
+func parseIndex(data []byte) []string {
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// batchWindow returns the start and end of the batch of an export file, as
+// encoded in its name. The times can be a few seconds late for files that were
+// regenerated.
+func batchWindow(filename string) (time.Time, time.Time, error) {
+	base := filename[strings.LastIndex(filename, "/")+1:]
+	parts := strings.Split(base, "-")
+	if len(parts) != 3 {
+		return time.Time{}, time.Time{}, fmt.Errorf("unexpected export filename %q", filename)
+	}
+
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unexpected export filename %q: %w", filename, err)
+	}
+	end, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unexpected export filename %q: %w", filename, err)
+	}
+	return time.Unix(start, 0).UTC(), time.Unix(end, 0).UTC(), nil
+}
+
+// latestBatch returns the files of the batch that ends last, in order.
+func latestBatch(files []string) ([]string, error) {
+	var latest []string
+	var latestStart, latestEnd time.Time
+	for _, f := range files {
+		start, end, err := batchWindow(f)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case end.After(latestEnd):
+			latest = []string{f}
+			latestStart, latestEnd = start, end
+		case end.Equal(latestEnd) && start.Equal(latestStart):
+			latest = append(latest, f)
+		}
+	}
+	sort.Strings(latest)
+	return latest, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportverifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBatchWindow(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		filename  string
+		wantStart int64
+		wantEnd   int64
+		err       bool
+	}{
+		{
+			name:      "valid",
+			filename:  "us/1600000000-1600003600-00001.zip",
+			wantStart: 1600000000,
+			wantEnd:   1600003600,
+		},
+		{
+			name:      "nested_root",
+			filename:  "us/ca/1600000000-1600003600-00002.zip",
+			wantStart: 1600000000,
+			wantEnd:   1600003600,
+		},
+		{
+			name:     "not_an_export",
+			filename: "us/index.txt",
+			err:      true,
+		},
+		{
+			name:     "bad_end",
+			filename: "us/1600000000-soon-00001.zip",
+			err:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			start, end, err := batchWindow(tc.filename)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := start.Unix(); got != tc.wantStart {
+				t.Errorf("start: got %d, want %d", got, tc.wantStart)
+			}
+			if got := end.Unix(); got != tc.wantEnd {
+				t.Errorf("end: got %d, want %d", got, tc.wantEnd)
+			}
+		})
+	}
+}
+
+func TestLatestBatch(t *testing.T) {
+	t.Parallel()
+
+	index := []byte(`
+us/1600000000-1600003600-00001.zip
+us/1600003600-1600007200-00002.zip
+us/1600003600-1600007200-00001.zip
+
+us/1600001800-1600007200-00001.zip
+`)
+
+	got, err := latestBatch(parseIndex(index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"us/1600003600-1600007200-00001.zip",
+		"us/1600003600-1600007200-00002.zip",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := latestBatch([]string{"us/index.txt"}); err == nil {
+		t.Errorf("expected error for malformed index")
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	t.Parallel()
+
+	signingKey := testKey(t)
+	otherKey := testKey(t)
+
+	signed := &model.SignatureInfo{SigningKeyID: "310", SigningKeyVersion: "v1"}
+	unsigned := &model.SignatureInfo{SigningKeyID: "310", SigningKeyVersion: "v2"}
+
+	eb := &model.ExportBatch{
+		OutputRegion:   "US",
+		StartTimestamp: time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC),
+		EndTimestamp:   time.Date(2020, 9, 1, 1, 0, 0, 0, time.UTC),
+	}
+	exposures := []*publishmodel.Exposure{
+		{ExposureKey: []byte("ABCDEFGHIJKLMNOP"), IntervalNumber: 100, IntervalCount: 144},
+		{ExposureKey: []byte("QRSTUVWXYZABCDEF"), IntervalNumber: 100, IntervalCount: 144},
+	}
+	data, err := export.MarshalExportFile(eb, exposures, nil, 1, false, []*export.Signer{
+		{SignatureInfo: signed, Signer: signingKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		data      []byte
+		verifiers []*verifier
+		problems  []string
+		err       bool
+	}{
+		{
+			name:      "valid",
+			data:      data,
+			verifiers: []*verifier{{signatureInfo: signed, publicKey: &signingKey.PublicKey}},
+		},
+		{
+			name:      "wrong_key",
+			data:      data,
+			verifiers: []*verifier{{signatureInfo: signed, publicKey: &otherKey.PublicKey}},
+			problems:  []string{`invalid signature for key "310" version "v1"`},
+		},
+		{
+			name: "missing_signature",
+			data: data,
+			verifiers: []*verifier{
+				{signatureInfo: signed, publicKey: &signingKey.PublicKey},
+				{signatureInfo: unsigned, publicKey: &signingKey.PublicKey},
+			},
+			problems: []string{`no signature for key "310" version "v2"`},
+		},
+		{
+			name: "not_an_export",
+			data: []byte("not a zip file"),
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys, problems, err := checkFile(tc.data, tc.verifiers)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if keys != len(exposures) {
+				t.Errorf("got %d keys, want %d", keys, len(exposures))
+			}
+			if diff := cmp.Diff(tc.problems, problems); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bucket/us/index.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("us/1600000000-1600003600-00001.zip\n"))
+	})
+	mux.HandleFunc("/bucket/us/broken.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	s := &Server{
+		config: &Config{ExportBaseURL: srv.URL + "/{bucket}/"},
+		client: srv.Client(),
+	}
+	ec := &model.ExportConfig{BucketName: "bucket", FilenameRoot: "us"}
+	ctx := context.Background()
+
+	got, err := s.download(ctx, s.fileURL(ec, "us/index.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "us/1600000000-1600003600-00001.zip\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := s.download(ctx, s.fileURL(ec, "us/missing.zip")); !errors.Is(err, errNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := s.download(ctx, s.fileURL(ec, "us/broken.zip")); err == nil || errors.Is(err, errNotFound) {
+		t.Errorf("expected download error, got %v", err)
+	}
+}
+
+func TestKeyCountCriteria(t *testing.T) {
+	t.Parallel()
+
+	eb := &model.ExportBatch{
+		StartTimestamp:   time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC),
+		EndTimestamp:     time.Date(2020, 9, 1, 1, 0, 0, 0, time.UTC),
+		OutputRegion:     "US",
+		IncludeTravelers: true,
+		TestData:         true,
+	}
+
+	got := keyCountCriteria(eb)
+	if !got.OnlyNonTravelers || got.IncludeTravelers {
+		t.Errorf("expected only non-travelers to be counted, got %+v", got)
+	}
+	if !got.ExcludeRevoked {
+		t.Errorf("expected revoked keys to be excluded")
+	}
+	if !got.OnlyTestData || got.ExcludeTestData {
+		t.Errorf("expected only test data for a test data batch, got %+v", got)
+	}
+	if diff := cmp.Diff([]string{"US"}, got.IncludeRegions); diff != "" {
+		t.Errorf("regions mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func testKey(tb testing.TB) *ecdsa.PrivateKey {
+	tb.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return key
+}
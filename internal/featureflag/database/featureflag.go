@@ -0,0 +1,149 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface for feature flags.
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/featureflag/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type FeatureFlagDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *FeatureFlagDB {
+	return &FeatureFlagDB{
+		db: db,
+	}
+}
+
+// ListFlags returns all feature flags, ordered by name.
+func (db *FeatureFlagDB) ListFlags(ctx context.Context) ([]*model.Flag, error) {
+	var flags []*model.Flag
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				name, description, enabled, rollout_percent, subjects, updated_at
+			FROM
+				FeatureFlag
+			ORDER BY name
+		`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			f, err := scanOneFlag(rows)
+			if err != nil {
+				return err
+			}
+			flags = append(flags, f)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+// GetFlag returns the named feature flag, or database.ErrNotFound.
+func (db *FeatureFlagDB) GetFlag(ctx context.Context, name string) (*model.Flag, error) {
+	var flag *model.Flag
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				name, description, enabled, rollout_percent, subjects, updated_at
+			FROM
+				FeatureFlag
+			WHERE name = $1
+		`, name)
+
+		var err error
+		flag, err = scanOneFlag(row)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return database.ErrNotFound
+			}
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+
+	return flag, nil
+}
+
+// SaveFlag creates or updates a feature flag.
+func (db *FeatureFlagDB) SaveFlag(ctx context.Context, f *model.Flag) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	subjects := f.Subjects
+	if subjects == nil {
+		subjects = []string{}
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				FeatureFlag (name, description, enabled, rollout_percent, subjects, updated_at)
+			VALUES
+				($1, $2, $3, $4, $5, now())
+			ON CONFLICT (tenant, name) DO UPDATE
+				SET description = EXCLUDED.description, enabled = EXCLUDED.enabled,
+					rollout_percent = EXCLUDED.rollout_percent,
+					subjects = EXCLUDED.subjects,
+					updated_at = EXCLUDED.updated_at
+			RETURNING updated_at
+		`, f.Name, f.Description, f.Enabled, f.RolloutPercent, subjects)
+		if err := row.Scan(&f.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to save feature flag: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteFlag deletes a feature flag, so callers use their default again.
+func (db *FeatureFlagDB) DeleteFlag(ctx context.Context, name string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM FeatureFlag WHERE name = $1
+		`, name); err != nil {
+			return fmt.Errorf("failed to delete feature flag: %w", err)
+		}
+		return nil
+	})
+}
+
+func scanOneFlag(row pgx.Row) (*model.Flag, error) {
+	var f model.Flag
+	if err := row.Scan(&f.Name, &f.Description, &f.Enabled, &f.RolloutPercent,
+		&f.Subjects, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/featureflag/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	flagDB := New(testDB)
+
+	if err := flagDB.SaveFlag(ctx, &model.Flag{Name: "bad", RolloutPercent: 101}); err == nil {
+		t.Errorf("expected error for invalid flag")
+	}
+
+	if _, err := flagDB.GetFlag(ctx, "v2-api"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	want := &model.Flag{
+		Name:           "v2-api",
+		Description:    "Serve the v2 publish API.",
+		Enabled:        true,
+		RolloutPercent: 25,
+		Subjects:       []string{"com.example.app"},
+	}
+	for _, f := range []*model.Flag{
+		{Name: "v2-api"},
+		want,
+		{Name: "zip64"},
+	} {
+		if err := flagDB.SaveFlag(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := flagDB.GetFlag(ctx, "v2-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(model.Flag{}, "UpdatedAt")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if err := flagDB.DeleteFlag(ctx, "zip64"); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err := flagDB.ListFlags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flags) != 1 || flags[0].Name != "v2-api" || flags[0].UpdatedAt.IsZero() {
+		t.Errorf("unexpected flags: %#v", flags)
+	}
+}
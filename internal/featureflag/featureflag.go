@@ -0,0 +1,120 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package featureflag rolls out new behaviors gradually. Flags are stored in
+// the database and can be turned on for specific authorized apps or export
+// regions, or for a percentage of them.
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/featureflag/database"
+	"github.com/google/exposure-notifications-server/internal/featureflag/model"
+	"github.com/google/exposure-notifications-server/pkg/cache"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+// Flags that gate behaviors in this server. A flag that is not in the database
+// keeps the behavior the service is configured with.
+const (
+	// PublishQuarantine scores uploads for quarantine. The subject is the
+	// authorized app package name.
+	PublishQuarantine = "publish-quarantine"
+
+	// ExportNoiseKeys mixes noise keys into small exports. The subject is the
+	// export region.
+	ExportNoiseKeys = "export-noise-keys"
+)
+
+// Config is the configuration for evaluating feature flags.
+type Config struct {
+	// CacheDuration is how long flags are cached before they are read again.
+	CacheDuration time.Duration `env:"FEATURE_FLAG_CACHE_DURATION, default=1m"`
+}
+
+// cacheKey is the cache key of the full set of flags.
+const cacheKey = "flags"
+
+// Evaluator evaluates feature flags.
+type Evaluator struct {
+	load  func(context.Context) ([]*model.Flag, error)
+	cache *cache.Cache[map[string]*model.Flag]
+}
+
+// NewEvaluator creates an evaluator that reads flags from the database.
+func NewEvaluator(db *database.FeatureFlagDB, config *Config) (*Evaluator, error) {
+	c, err := cache.New[map[string]*model.Flag](config.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+
+	return &Evaluator{
+		load:  db.ListFlags,
+		cache: c,
+	}, nil
+}
+
+// NewStaticEvaluator creates an evaluator with a fixed set of flags. It is
+// intended for tests.
+func NewStaticEvaluator(flags ...*model.Flag) *Evaluator {
+	c, _ := cache.New[map[string]*model.Flag](0)
+
+	return &Evaluator{
+		load: func(context.Context) ([]*model.Flag, error) {
+			return flags, nil
+		},
+		cache: c,
+	}
+}
+
+// Flag returns the named flag, or nil if it is not defined.
+func (e *Evaluator) Flag(ctx context.Context, name string) (*model.Flag, error) {
+	flags, err := e.cache.WriteThruLookup(cacheKey, func() (map[string]*model.Flag, error) {
+		list, err := e.load(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		flags := make(map[string]*model.Flag, len(list))
+		for _, f := range list {
+			flags[f.Name] = f
+		}
+		return flags, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feature flags: %w", err)
+	}
+	return flags[name], nil
+}
+
+// Enabled returns true if the named flag is on for the subject. If the flag is
+// not defined or cannot be read, or e is nil, it returns fallback.
+func (e *Evaluator) Enabled(ctx context.Context, name, subject string, fallback bool) bool {
+	if e == nil {
+		return fallback
+	}
+
+	flag, err := e.Flag(ctx, name)
+	if err != nil {
+		logging.FromContext(ctx).Errorw("failed to evaluate feature flag", "flag", name, "error", err)
+		return fallback
+	}
+	if flag == nil {
+		return fallback
+	}
+	return flag.EnabledFor(subject)
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featureflag
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/featureflag/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestEvaluator_Enabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	e := NewStaticEvaluator(
+		&model.Flag{Name: "on", Enabled: true, RolloutPercent: 100},
+		&model.Flag{Name: "off", Enabled: false, RolloutPercent: 100},
+		&model.Flag{Name: "listed", Enabled: true, Subjects: []string{"com.example.app"}},
+	)
+
+	cases := []struct {
+		name      string
+		evaluator *Evaluator
+		flag      string
+		subject   string
+		fallback  bool
+		want      bool
+	}{
+		{name: "on", evaluator: e, flag: "on", want: true},
+		{name: "off", evaluator: e, flag: "off", fallback: true, want: false},
+		{name: "listed", evaluator: e, flag: "listed", subject: "com.example.app", want: true},
+		{name: "not_listed", evaluator: e, flag: "listed", subject: "com.example.other", fallback: true, want: false},
+		{name: "undefined", evaluator: e, flag: "undefined", fallback: true, want: true},
+		{name: "nil_evaluator", flag: "on", fallback: false, want: false},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.evaluator.Enabled(ctx, tc.flag, tc.subject, tc.fallback); got != tc.want {
+				t.Errorf("expected %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction for feature flags.
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Flag gates a behavior that is being rolled out gradually.
+type Flag struct {
+	Name        string
+	Description string
+
+	// Enabled turns the flag on. A disabled flag is off for every subject.
+	Enabled bool
+
+	// RolloutPercent is the percentage of subjects, from 0 to 100, the flag is
+	// on for. A subject is always in or out of the same rollout, and a subject
+	// in the rollout stays in it when the percentage grows.
+	RolloutPercent int
+
+	// Subjects lists the subjects the flag is always on for: authorized app
+	// package names for publish flags and export regions for export flags.
+	Subjects []string
+
+	UpdatedAt time.Time
+}
+
+// Validate returns an error if the flag is not valid.
+func (f *Flag) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("flag name is required")
+	}
+	if f.RolloutPercent < 0 || f.RolloutPercent > 100 {
+		return fmt.Errorf("rollout percent must be between 0 and 100, got %d", f.RolloutPercent)
+	}
+	return nil
+}
+
+// EnabledFor returns true if the flag is on for the subject.
+func (f *Flag) EnabledFor(subject string) bool {
+	if f == nil || !f.Enabled {
+		return false
+	}
+
+	for _, name := range f.Subjects {
+		if strings.EqualFold(name, subject) {
+			return true
+		}
+	}
+
+	return Bucket(f.Name, subject) < f.RolloutPercent
+}
+
+// Bucket returns the rollout bucket, from 0 to 99, of the subject for the
+// named flag. Hashing the flag name with the subject means different flags
+// roll out to different subjects first.
+func Bucket(name, subject string) int {
+	sum := sha256.Sum256([]byte(name + "\x00" + strings.ToLower(subject)))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFlag_EnabledFor(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		flag    *Flag
+		subject string
+		want    bool
+	}{
+		{
+			name:    "nil",
+			subject: "com.example.app",
+			want:    false,
+		},
+		{
+			name:    "disabled",
+			flag:    &Flag{Name: "f", RolloutPercent: 100, Subjects: []string{"com.example.app"}},
+			subject: "com.example.app",
+			want:    false,
+		},
+		{
+			name:    "listed",
+			flag:    &Flag{Name: "f", Enabled: true, Subjects: []string{"com.example.app"}},
+			subject: "COM.EXAMPLE.APP",
+			want:    true,
+		},
+		{
+			name:    "not_listed",
+			flag:    &Flag{Name: "f", Enabled: true, Subjects: []string{"com.example.app"}},
+			subject: "com.example.other",
+			want:    false,
+		},
+		{
+			name:    "full_rollout",
+			flag:    &Flag{Name: "f", Enabled: true, RolloutPercent: 100},
+			subject: "com.example.other",
+			want:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.flag.EnabledFor(tc.subject); got != tc.want {
+				t.Errorf("expected %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFlag_Rollout(t *testing.T) {
+	t.Parallel()
+
+	flag := &Flag{Name: "f", Enabled: true}
+	const subjects = 1000
+
+	prev := make(map[string]bool)
+	for _, percent := range []int{10, 50, 90} {
+		flag.RolloutPercent = percent
+
+		count := 0
+		for i := 0; i < subjects; i++ {
+			subject := fmt.Sprintf("com.example.app%d", i)
+			on := flag.EnabledFor(subject)
+			if prev[subject] && !on {
+				t.Fatalf("%s left the rollout when it grew to %d%%", subject, percent)
+			}
+			if on {
+				prev[subject] = true
+				count++
+			}
+		}
+
+		if want := subjects * percent / 100; count < want-50 || count > want+50 {
+			t.Errorf("expected about %d subjects at %d%%, got %d", want, percent, count)
+		}
+	}
+}
+
+func TestFlag_Validate(t *testing.T) {
+	t.Parallel()
+
+	for _, f := range []*Flag{
+		{},
+		{Name: "f", RolloutPercent: -1},
+		{Name: "f", RolloutPercent: 101},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("expected error for %#v", f)
+		}
+	}
+
+	if err := (&Flag{Name: "f", RolloutPercent: 100}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -18,6 +18,9 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/region"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/observability"
@@ -48,6 +51,8 @@ type Config struct {
 	Database              database.Config
 	SecretManager         secrets.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
+	Regions               region.Config
 
 	Port                         string        `env:"PORT, default=8080"`
 	Timeout                      time.Duration `env:"RPC_TIMEOUT, default=10m"`
@@ -55,9 +60,14 @@ type Config struct {
 	MaxIntervalAge               time.Duration `env:"MAX_INTERVAL_AGE_ON_PUBLISH, default=360h"`
 	MaxMagnitudeSymptomOnsetDays uint          `env:"MAX_SYMPTOM_ONSET_DAYS, default=14"`
 
+	// SameDayPolicy decides what happens to keys that are still valid when they
+	// are pulled, see the publish service's SAME_DAY_KEY_POLICY.
+	SameDayPolicy       publishmodel.SameDayKeyPolicy `env:"SAME_DAY_KEY_POLICY, default=EMBARGO"`
+	SameDayReleaseDelay time.Duration                 `env:"SAME_DAY_KEY_RELEASE_DELAY, default=0"`
+
 	// Flags for local development and testing. This will cause still valid keys
 	// to not be embargoed.
-	// Normally "still valid" keys can be accepted, but are embargoed.
+	// Deprecated: ReleaseSameDayKeys is the same as SAME_DAY_KEY_POLICY=RELEASE.
 	ReleaseSameDayKeys bool `env:"DEBUG_RELEASE_SAME_DAY_KEYS"`
 
 	// TLSSkipVerify, if set to true, causes the server certificate to not be
@@ -74,6 +84,14 @@ type Config struct {
 	// empty.
 	CredentialsFile string `env:"CREDENTIALS_FILE"`
 
+	// FetchMaxRetries is the number of times a fetch from the remote server is
+	// retried when the server can't be reached or is overloaded.
+	// FetchRetryBackoff is the delay before the first retry, which doubles with
+	// each retry, and FetchRetryBudget bounds the time spent retrying one fetch.
+	FetchMaxRetries   uint64        `env:"FETCH_MAX_RETRIES, default=3"`
+	FetchRetryBackoff time.Duration `env:"FETCH_RETRY_BACKOFF, default=1s"`
+	FetchRetryBudget  time.Duration `env:"FETCH_RETRY_BUDGET, default=1m"`
+
 	// Sync configuration.
 	// If accepted, both self report and recursive will be sent as clinical,
 	// otherwise they will be dropped.
@@ -92,3 +110,12 @@ func (c *Config) SecretManagerConfig() *secrets.Config {
 func (c *Config) ObservabilityExporterConfig() *observability.Config {
 	return &c.ObservabilityExporter
 }
+
+// SameDayKeyPolicy returns the policy for still valid keys, honoring the
+// deprecated DEBUG_RELEASE_SAME_DAY_KEYS flag.
+func (c *Config) SameDayKeyPolicy() publishmodel.SameDayKeyPolicy {
+	if c.ReleaseSameDayKeys {
+		return publishmodel.SameDayKeyPolicyRelease
+	}
+	return c.SameDayPolicy
+}
@@ -27,20 +27,25 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/backoff"
 	"github.com/google/exposure-notifications-server/internal/federationin/database"
 	"github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/internal/pb/federation"
 	"github.com/google/exposure-notifications-server/internal/project"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/worker"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 	coredb "github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 
 	"google.golang.org/api/idtoken"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
+	"google.golang.org/grpc/status"
 
 	"go.opencensus.io/plugin/ocgrpc"
 	"go.opencensus.io/stats"
@@ -163,6 +168,13 @@ func (s *Server) handleSync() http.Handler {
 			TokenSource: ts,
 		}))
 
+		// Unknown regions match no keys on the remote server, so the query
+		// silently pulls fewer keys than intended.
+		regions := region.New(&s.config.Regions)
+		if unknown := append(regions.Unknown(query.IncludeRegions...), regions.Unknown(query.ExcludeRegions...)...); len(unknown) > 0 {
+			logger.Warnw("federation query has unknown regions", "query", queryID, "regions", unknown)
+		}
+
 		logger.Infof("Dialing %s", query.ServerAddr)
 		conn, err := grpc.Dial(query.ServerAddr, dialOpts...)
 		if err != nil {
@@ -186,7 +198,15 @@ func (s *Server) handleSync() http.Handler {
 			truncateWindow:               s.config.TruncateWindow,
 			maxIntervalStartAge:          s.config.MaxIntervalAge,
 			maxMagnitudeSymptomOnsetDays: s.config.MaxMagnitudeSymptomOnsetDays,
-			debugReleaseSameDay:          s.config.ReleaseSameDayKeys,
+			sameDayKeyPolicy:             s.config.SameDayKeyPolicy(),
+			sameDayKeyReleaseDelay:       s.config.SameDayReleaseDelay,
+			fetchPolicy: &backoff.Policy{
+				Name:       "federation_fetch",
+				MaxRetries: s.config.FetchMaxRetries,
+				Backoff:    s.config.FetchRetryBackoff,
+				Budget:     s.config.FetchRetryBudget,
+				Retryable:  retryableFetchError,
+			},
 		}
 		if err := pull(timeoutContext, &opts); err != nil {
 			internalErrorf(ctx, w, "Federation query %q failed: %v", queryID, err)
@@ -206,7 +226,9 @@ type pullOptions struct {
 	truncateWindow               time.Duration
 	maxIntervalStartAge          time.Duration
 	maxMagnitudeSymptomOnsetDays uint
-	debugReleaseSameDay          bool
+	sameDayKeyPolicy             publishmodel.SameDayKeyPolicy
+	sameDayKeyReleaseDelay       time.Duration
+	fetchPolicy                  *backoff.Policy
 	config                       *Config
 }
 
@@ -248,6 +270,7 @@ func buildExposure(e *federation.ExposureKey, config *Config) (*publishmodel.Exp
 		IntervalNumber:   e.IntervalNumber,
 		IntervalCount:    e.IntervalCount,
 		LocalProvenance:  false,
+		ProvenanceSource: publishmodel.ProvenanceFederationIn,
 	}
 	switch e.ReportType {
 	case federation.ExposureKey_CONFIRMED_TEST:
@@ -283,6 +306,17 @@ func buildExposure(e *federation.ExposureKey, config *Config) (*publishmodel.Exp
 	return &exposure, nil
 }
 
+// retryableFetchError reports whether a failed fetch may succeed on retry,
+// because the remote server could not be reached or was overloaded.
+func retryableFetchError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
 func pull(ctx context.Context, opts *pullOptions) (err error) {
 	ctx, span := trace.StartSpan(ctx, "federationin.pull")
 	defer func() {
@@ -323,28 +357,22 @@ func pull(ctx context.Context, opts *pullOptions) (err error) {
 		// A key must have been issued on the device in the current interval or earlier.
 		MaxStartInterval: publishmodel.IntervalNumber(opts.batchStart),
 		// And the max valid interval is the maxStartInterval + 144
-		MaxEndInteral:         publishmodel.IntervalNumber(opts.batchStart) + verifyapi.MaxIntervalCount,
-		CreatedAt:             createdAt,
-		ReleaseStillValidKeys: opts.debugReleaseSameDay,
-		BatchWindow:           opts.truncateWindow,
+		MaxEndInteral:          publishmodel.IntervalNumber(opts.batchStart) + verifyapi.MaxIntervalCount,
+		CreatedAt:              createdAt,
+		BatchWindow:            opts.truncateWindow,
+		SameDayKeyPolicy:       opts.sameDayKeyPolicy,
+		SameDayKeyReleaseDelay: opts.sameDayKeyReleaseDelay,
 	}
 
-	partial := true
-	nPartials := int64(0)
-	for partial {
-		nPartials++
-		span.AddAttributes(trace.Int64Attribute("n_partial", nPartials))
-
-		// TODO(mikehelmick): react to the context timeout and complete a chunk of work so next invocation can pick up where left off.
-
-		response, err := opts.deps.fetch(ctx, request)
-		if err != nil {
-			return fmt.Errorf("fetching query %s: %w", opts.query.QueryID, err)
-		}
-
-		// Advance timestamps based on cursors.
-		maxTimestamp, maxRevisedTimestamp = updateTimestamps(maxTimestamp, maxRevisedTimestamp, response.NextFetchState)
+	// The connection is dialed lazily, so failing to reach the remote server
+	// surfaces as a failed fetch.
+	fetchPolicy := opts.fetchPolicy
+	if fetchPolicy == nil {
+		fetchPolicy = &backoff.Policy{Name: "federation_fetch"}
+	}
 
+	// insertPage inserts the keys and revised keys of a fetched page.
+	insertPage := func(ctx context.Context, response *federation.FederationFetchResponse) error {
 		if len(response.Keys) > 0 {
 			// Build state for new inserts.
 			newExposures := make([]*publishmodel.Exposure, 0, len(response.Keys))
@@ -419,11 +447,61 @@ func pull(ctx context.Context, opts *pullOptions) (err error) {
 		} else {
 			logger.Info("no revised keys in response")
 		}
+		return nil
+	}
+
+	// Pages are inserted on a pool while the next page is fetched. The pool
+	// inserts one page at a time, so pages are inserted in order, and a failed
+	// insert cancels the fetching of the pages after it.
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pages := worker.New("federation_pages", 1)
+
+	var fetchErr error
+	partial := true
+	nPartials := int64(0)
+	for partial {
+		nPartials++
+		span.AddAttributes(trace.Int64Attribute("n_partial", nPartials))
+
+		// TODO(mikehelmick): react to the context timeout and complete a chunk of work so next invocation can pick up where left off.
+
+		var response *federation.FederationFetchResponse
+		if err := fetchPolicy.Do(fetchCtx, func(ctx context.Context) error {
+			var err error
+			response, err = opts.deps.fetch(ctx, request)
+			return err
+		}); err != nil {
+			fetchErr = fmt.Errorf("fetching query %s: %w", opts.query.QueryID, err)
+			break
+		}
+
+		// Advance timestamps based on cursors.
+		maxTimestamp, maxRevisedTimestamp = updateTimestamps(maxTimestamp, maxRevisedTimestamp, response.NextFetchState)
+
+		if err := pages.Go(fetchCtx, func(ctx context.Context) error {
+			if err := insertPage(ctx, response); err != nil {
+				cancel()
+				return err
+			}
+			return nil
+		}); err != nil {
+			fetchErr = err
+			break
+		}
 
 		partial = response.PartialResponse
 		request.State = response.NextFetchState
 	}
 
+	// A failed insert is reported over the fetch it canceled.
+	if err := pages.Wait(); err != nil {
+		return err
+	}
+	if fetchErr != nil {
+		return fetchErr
+	}
+
 	if err := finalizeFn(request.State, opts.query, total); err != nil {
 		// TODO(mikehelmick): how do we clean up here? Just leave the records in and have the exporter eliminate them? Other?
 		return fmt.Errorf("finalizing federation sync for query %s: %w", opts.query.QueryID, err)
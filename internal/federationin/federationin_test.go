@@ -16,9 +16,12 @@ package federationin
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/backoff"
 	"github.com/google/exposure-notifications-server/internal/federationin/database"
 	"github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/internal/project"
@@ -31,6 +34,8 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -474,10 +479,139 @@ func TestFederationPull(t *testing.T) {
 	}
 }
 
+func TestFederationPull_Retry(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		errs     []error
+		wantErr  bool
+		attempts int
+	}{
+		{
+			name:     "unavailable",
+			errs:     []error{status.Error(codes.Unavailable, "connection refused")},
+			attempts: 2,
+		},
+		{
+			name:     "resource_exhausted",
+			errs:     []error{status.Error(codes.ResourceExhausted, "slow down"), status.Error(codes.ResourceExhausted, "slow down")},
+			attempts: 3,
+		},
+		{
+			name:     "retries_spent",
+			errs:     []error{status.Error(codes.Unavailable, "a"), status.Error(codes.Unavailable, "b"), status.Error(codes.Unavailable, "c"), status.Error(codes.Unavailable, "d")},
+			wantErr:  true,
+			attempts: 4,
+		},
+		{
+			name:     "permission_denied",
+			errs:     []error{status.Error(codes.PermissionDenied, "denied")},
+			wantErr:  true,
+			attempts: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+
+			attempts := 0
+			fetch := func(ctx context.Context, req *federation.FederationFetchRequest, opts ...grpc.CallOption) (*federation.FederationFetchResponse, error) {
+				attempts++
+				if attempts <= len(tc.errs) {
+					return nil, tc.errs[attempts-1]
+				}
+				return &federation.FederationFetchResponse{NextFetchState: &federation.FetchState{}}, nil
+			}
+
+			sdb := syncDB{}
+			opts := pullOptions{
+				deps: pullDependencies{
+					fetch:               fetch,
+					insertExposures:     (&publishDB{}).insertExposures,
+					startFederationSync: sdb.startFederationSync,
+				},
+				query:                        &model.FederationInQuery{QueryID: queryID},
+				batchStart:                   time.Now(),
+				truncateWindow:               time.Hour,
+				maxIntervalStartAge:          14 * 24 * time.Hour,
+				maxMagnitudeSymptomOnsetDays: 14,
+				fetchPolicy: &backoff.Policy{
+					Name:       "federation_fetch",
+					MaxRetries: 3,
+					Backoff:    time.Millisecond,
+					Retryable:  retryableFetchError,
+				},
+			}
+
+			err := pull(ctx, &opts)
+			if got := err != nil; got != tc.wantErr {
+				t.Errorf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if attempts != tc.attempts {
+				t.Errorf("expected %d fetch attempts, got %d", tc.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestFederationPull_InsertError(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	errInsert := errors.New("insert failed")
+
+	var fetches int32
+	fetch := func(ctx context.Context, req *federation.FederationFetchRequest, opts ...grpc.CallOption) (*federation.FederationFetchResponse, error) {
+		n := atomic.AddInt32(&fetches, 1)
+		return &federation.FederationFetchResponse{
+			Keys:            []*federation.ExposureKey{{ExposureKey: []byte("eeeeeeeeeeeeeeee"), IntervalCount: 144}},
+			PartialResponse: n < 100,
+			NextFetchState:  &federation.FetchState{},
+		}, nil
+	}
+	insertExposures := func(ctx context.Context, req *publishdb.InsertAndReviseExposuresRequest) (*publishdb.InsertAndReviseExposuresResponse, error) {
+		return nil, errInsert
+	}
+
+	sdb := syncDB{}
+	opts := pullOptions{
+		deps: pullDependencies{
+			fetch:               fetch,
+			insertExposures:     insertExposures,
+			startFederationSync: sdb.startFederationSync,
+		},
+		query:                        &model.FederationInQuery{QueryID: queryID},
+		batchStart:                   time.Now(),
+		truncateWindow:               time.Hour,
+		maxIntervalStartAge:          14 * 24 * time.Hour,
+		maxMagnitudeSymptomOnsetDays: 14,
+	}
+
+	if err := pull(ctx, &opts); !errors.Is(err, errInsert) {
+		t.Errorf("expected %v, got %v", errInsert, err)
+	}
+	if sdb.syncCompleted {
+		t.Errorf("expected failed sync not to be completed")
+	}
+	// The failed insert stops the pull after at most the pages fetched while
+	// it was running.
+	if got := atomic.LoadInt32(&fetches); got > 3 {
+		t.Errorf("expected at most 3 fetches, got %d", got)
+	}
+}
+
 // makeRemoteExposure returns a mock publishmodel.Exposure with LocalProvenance=false.
 func makeRemoteExposure(diagKey *federation.ExposureKey, reportType string, regions []string, traveler bool, createdAt time.Time) *publishmodel.Exposure {
 	inf := makeExposure(diagKey, reportType, regions, traveler)
 	inf.LocalProvenance = false
+	inf.ProvenanceSource = publishmodel.ProvenanceFederationIn
 	inf.FederationSyncID = syncID
 	inf.FederationQueryID = queryID
 	inf.CreatedAt = time.Now()
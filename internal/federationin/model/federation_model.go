@@ -91,4 +91,19 @@ type FederationOutAuthorization struct {
 	Note           string
 	IncludeRegions []string
 	ExcludeRegions []string
+
+	// JWKSURI, if set, is where the issuer's signing keys are fetched from.
+	// Otherwise the keys are located through OIDC discovery on the issuer.
+	JWKSURI string
+
+	// IncludeJurisdictions, if set, limits the client to keys verified by
+	// health authorities in these jurisdictions. ExcludeJurisdictions prevents
+	// the client from reading keys verified by health authorities in these
+	// jurisdictions.
+	IncludeJurisdictions []string
+	ExcludeJurisdictions []string
+
+	// TestData, if set, serves the client only test data, e.g. for integration
+	// testing with a partner. Otherwise the client never receives test data.
+	TestData bool
 }
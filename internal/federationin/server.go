@@ -16,9 +16,9 @@ package federationin
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/exposure-notifications-server/internal/federationin/database"
-	"github.com/google/exposure-notifications-server/internal/middleware"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/pkg/logging"
@@ -34,6 +34,10 @@ type Server struct {
 }
 
 func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
+	if err := cfg.SameDayKeyPolicy().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid SAME_DAY_KEY_POLICY: %w", err)
+	}
+
 	return &Server{
 		env:       env,
 		db:        database.New(env.Database()),
@@ -46,10 +50,7 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 	logger := logging.FromContext(ctx).Named("federationin")
 
 	r := mux.NewRouter()
-	r.Use(middleware.Recovery())
-	r.Use(middleware.PopulateRequestID())
-	r.Use(middleware.PopulateObservability())
-	r.Use(middleware.PopulateLogger(logger))
+	s.config.Middleware.Use(r, logger)
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/", s.handleSync())
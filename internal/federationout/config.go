@@ -17,6 +17,8 @@ package federationout
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/revision"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/observability"
@@ -35,22 +37,80 @@ type Config struct {
 	Database              database.Config
 	SecretManager         secrets.Config
 	ObservabilityExporter observability.Config
+	Regions               region.Config
 
 	Port           string        `env:"PORT, default=8080"`
 	MaxRecords     uint32        `env:"MAX_RECORDS, default=500"`
 	Timeout        time.Duration `env:"RPC_TIMEOUT, default=5m"`
 	TruncateWindow time.Duration `env:"TRUNCATE_WINDOW, default=1h"`
 
+	// MaxChunkKeys is the maximum number of keys in each chunk of a version 2
+	// Fetch stream.
+	MaxChunkKeys uint32 `env:"MAX_CHUNK_KEYS, default=100"`
+
+	// CursorKey is the HMAC key protecting the integrity of version 2 cursors.
+	// It must be at least 32 bytes and the same on all instances. Version 2 of
+	// the protocol is only served if it is set. It must be base64 encoded and
+	// may come from secret://.
+	CursorKey revision.Base64Bytes `env:"CURSOR_KEY"`
+
+	// RequireConsent, if true, only serves keys where the user explicitly
+	// consented to federation. Keys where the user declined are never served.
+	RequireConsent bool `env:"REQUIRE_FEDERATION_CONSENT"`
+
 	// AllowAnyClient, if true, removes authentication requirements on the
 	// federation endpoint. In practice, this is only useful in local testing.
 	AllowAnyClient bool `env:"ALLOW_ANY_CLIENT"`
 
+	// OIDCCacheDuration is how long an issuer's discovered signing keys are
+	// cached. Keys are refetched early when a token names an unknown key ID.
+	// OIDCRequestTimeout bounds each discovery and JWKS request, and
+	// OIDCClockSkew is the leeway allowed on token timestamps.
+	OIDCCacheDuration  time.Duration `env:"OIDC_CACHE_DURATION, default=1h"`
+	OIDCRequestTimeout time.Duration `env:"OIDC_REQUEST_TIMEOUT, default=10s"`
+	OIDCClockSkew      time.Duration `env:"OIDC_CLOCK_SKEW, default=1m"`
+
 	// TLSCertFile is the certificate file to use if TLS encryption is enabled on
 	// the server. If present, TLSKeyFile must also be present. These settings
 	// should be left blank on Managed Cloud Run where the TLS termination is
 	// handled by the environment.
 	TLSCertFile string `env:"TLS_CERT_FILE"`
 	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+
+	// EnableReflection registers the gRPC reflection service, so tools like
+	// grpcurl can list and call the federation services. Reflection requires
+	// the same authentication as the federation services. The gRPC health
+	// service is always registered and does not require authentication.
+	EnableReflection bool `env:"ENABLE_GRPC_REFLECTION, default=true"`
+
+	// Keepalive configures the server's HTTP/2 pings, which keep long fetches
+	// from being dropped by load balancers.
+	Keepalive KeepaliveConfig
+
+	// MaxRecvMsgSize and MaxSendMsgSize are the maximum sizes, in bytes, of a
+	// single received and sent gRPC message.
+	MaxRecvMsgSize int `env:"GRPC_MAX_RECV_MSG_SIZE, default=4194304"`
+	MaxSendMsgSize int `env:"GRPC_MAX_SEND_MSG_SIZE, default=16777216"`
+}
+
+// KeepaliveConfig is the gRPC keepalive configuration for the federation
+// server.
+type KeepaliveConfig struct {
+	// Time is how long a connection may be idle before the server pings the
+	// client. Timeout is how long the server waits for the ping to be
+	// acknowledged before closing the connection.
+	Time    time.Duration `env:"GRPC_KEEPALIVE_TIME, default=1m"`
+	Timeout time.Duration `env:"GRPC_KEEPALIVE_TIMEOUT, default=20s"`
+
+	// MaxConnectionIdle closes connections without active RPCs after this
+	// duration. A value of 0 keeps idle connections open.
+	MaxConnectionIdle time.Duration `env:"GRPC_MAX_CONNECTION_IDLE, default=0"`
+
+	// MinTime is the minimum interval clients may send pings at. Clients that
+	// ping more often are disconnected. PermitWithoutStream allows client pings
+	// when there are no active RPCs.
+	MinTime             time.Duration `env:"GRPC_KEEPALIVE_MIN_TIME, default=30s"`
+	PermitWithoutStream bool          `env:"GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM, default=true"`
 }
 
 func (c *Config) DatabaseConfig() *database.Config {
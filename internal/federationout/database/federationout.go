@@ -22,7 +22,7 @@ import (
 
 	"github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/pkg/database"
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 type FederationOutDB struct {
@@ -41,15 +41,19 @@ func (db *FederationOutDB) AddFederationOutAuthorization(ctx context.Context, au
 		q := `
 			INSERT INTO
 				FederationOutAuthorization
-				(oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions)
+				(oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions,
+				 include_jurisdictions, exclude_jurisdictions, oidc_jwks_uri, test_data)
 			VALUES
-				($1, $2, $3, $4, $5, $6)
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 			ON CONFLICT ON CONSTRAINT
 				federation_authorization_pk
 			DO UPDATE
-				SET oidc_audience = $3, note = $4, include_regions = $5, exclude_regions = $6
+				SET oidc_audience = $3, note = $4, include_regions = $5, exclude_regions = $6,
+					include_jurisdictions = $7, exclude_jurisdictions = $8, oidc_jwks_uri = $9,
+					test_data = $10
 		`
-		_, err := tx.Exec(ctx, q, auth.Issuer, auth.Subject, auth.Audience, auth.Note, auth.IncludeRegions, auth.ExcludeRegions)
+		_, err := tx.Exec(ctx, q, auth.Issuer, auth.Subject, auth.Audience, auth.Note, auth.IncludeRegions, auth.ExcludeRegions,
+			auth.IncludeJurisdictions, auth.ExcludeJurisdictions, auth.JWKSURI, auth.TestData)
 		if err != nil {
 			return fmt.Errorf("upserting federation authorization: %w", err)
 		}
@@ -64,7 +68,8 @@ func (db *FederationOutDB) GetFederationOutAuthorization(ctx context.Context, is
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			SELECT
-				oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions
+				oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions,
+				include_jurisdictions, exclude_jurisdictions, oidc_jwks_uri, test_data
 			FROM
 				FederationOutAuthorization
 			WHERE
@@ -74,7 +79,8 @@ func (db *FederationOutDB) GetFederationOutAuthorization(ctx context.Context, is
 			LIMIT 1
 		`, issuer, subject)
 
-		if err := row.Scan(&auth.Issuer, &auth.Subject, &auth.Audience, &auth.Note, &auth.IncludeRegions, &auth.ExcludeRegions); err != nil {
+		if err := row.Scan(&auth.Issuer, &auth.Subject, &auth.Audience, &auth.Note, &auth.IncludeRegions, &auth.ExcludeRegions,
+			&auth.IncludeJurisdictions, &auth.ExcludeJurisdictions, &auth.JWKSURI, &auth.TestData); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return database.ErrNotFound
 			}
@@ -59,6 +59,7 @@ func TestFederationOutAuthorization(t *testing.T) {
 
 	// AddFederationOutAuthorization should overwrite.
 	want.Note = "a different note"
+	want.JWKSURI = "https://idp.example.com/keys"
 	if err := New(testDB).AddFederationOutAuthorization(ctx, want); err != nil {
 		t.Fatal(err)
 	}
@@ -23,23 +23,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/errcode"
 	"github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/internal/federationout/database"
 	"github.com/google/exposure-notifications-server/internal/pb/federation"
 	"github.com/google/exposure-notifications-server/internal/project"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/region"
+	"github.com/google/exposure-notifications-server/internal/travelrule"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	coredb "github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"go.opencensus.io/stats"
 
 	"github.com/google/exposure-notifications-server/internal/serverenv"
-	"google.golang.org/api/idtoken"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
 const (
@@ -68,6 +69,7 @@ type Server struct {
 	env       *serverenv.ServerEnv
 	db        *database.FederationOutDB
 	publishdb *publishdb.PublishDB
+	verifier  *oidcVerifier
 	config    *Config
 }
 
@@ -80,7 +82,7 @@ func (s Server) Fetch(ctx context.Context, req *federation.FederationFetchReques
 	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
 	defer cancel()
 
-	response, err := s.fetch(ctx, req, s.publishdb.IterateExposures, publishmodel.TruncateWindow(time.Now(), s.config.TruncateWindow)) // Don't fetch the current window, which isn't complete yet.
+	response, err := s.fetchCurrent(ctx, req)
 	if err != nil {
 		stats.Record(ctx, mFetchFailed.M(1))
 		logger.Errorw("failed to fetch", "error", err)
@@ -89,7 +91,17 @@ func (s Server) Fetch(ctx context.Context, req *federation.FederationFetchReques
 	return response, nil
 }
 
-func (s Server) fetch(ctx context.Context, req *federation.FederationFetchRequest, itFunc iterateExposuresFunc, fetchUntil time.Time) (*federation.FederationFetchResponse, error) {
+// fetchCurrent runs the request against the publish database with the current
+// travel rules. The current window isn't complete yet, so it is not fetched.
+func (s Server) fetchCurrent(ctx context.Context, req *federation.FederationFetchRequest) (*federation.FederationFetchResponse, error) {
+	rules, err := travelrule.Load(ctx, s.env.Database())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load travel rules: %w", err)
+	}
+	return s.fetch(ctx, req, s.publishdb.IterateExposures, rules, publishmodel.TruncateWindow(time.Now(), s.config.TruncateWindow))
+}
+
+func (s Server) fetch(ctx context.Context, req *federation.FederationFetchRequest, itFunc iterateExposuresFunc, rules *travelrule.Engine, fetchUntil time.Time) (*federation.FederationFetchResponse, error) {
 	logger := logging.FromContext(ctx).Named("federationout.fetch")
 
 	if in := intersect(req.IncludeRegions, req.ExcludeRegions); len(in) > 0 {
@@ -103,6 +115,10 @@ func (s Server) fetch(ctx context.Context, req *federation.FederationFetchReques
 	for i, exRegion := range req.ExcludeRegions {
 		req.ExcludeRegions[i] = strings.ToUpper(exRegion)
 	}
+	regions := region.New(&s.config.Regions)
+	if unknown := append(regions.Unknown(req.IncludeRegions...), regions.Unknown(req.ExcludeRegions...)...); len(unknown) > 0 {
+		logger.Warnw("request has unknown regions", "regions", unknown)
+	}
 	stats.Record(ctx, mFetchRegionsRequested.M(int64(len(req.IncludeRegions))))
 	stats.Record(ctx, mFetchRegionsExcluded.M(int64(len(req.ExcludeRegions))))
 
@@ -115,11 +131,17 @@ func (s Server) fetch(ctx context.Context, req *federation.FederationFetchReques
 	logger.Infof("Processing client request %#v", req)
 
 	// If there is a FederationAuthorization on the context, set the query to operate within its limits.
+	var includeJurisdictions, excludeJurisdictions []string
+	var testData bool
 	if auth, ok := ctx.Value(authKey{}).(*model.FederationOutAuthorization); ok {
 		// For included regions, we INTERSECT the requested included regions with the configured included regions.
 		req.IncludeRegions = intersect(req.IncludeRegions, auth.IncludeRegions)
 		// For excluded regions, we UNION the the requested excluded regions with the configured excluded regions.
 		req.ExcludeRegions = union(req.ExcludeRegions, auth.ExcludeRegions)
+		// Jurisdictions are only configured on the authorization.
+		includeJurisdictions = auth.IncludeJurisdictions
+		excludeJurisdictions = auth.ExcludeJurisdictions
+		testData = auth.TestData
 	}
 
 	state := req.GetState()
@@ -130,9 +152,17 @@ func (s Server) fetch(ctx context.Context, req *federation.FederationFetchReques
 		}
 	}
 
+	// Travel rules may distribute keys from other regions into the requested
+	// regions. Travelers are always read, so only the non-traveler sources need
+	// to be added to the query.
+	queryRegions := req.IncludeRegions
+	if len(queryRegions) > 0 {
+		queryRegions = union(queryRegions, rules.SourcesFor(travelrule.TargetFederation, req.IncludeRegions...).Regions)
+	}
+
 	// Primary (non-revised) keys are read first.
 	criteria := publishdb.IterateExposuresCriteria{
-		IncludeRegions:      req.IncludeRegions,
+		IncludeRegions:      queryRegions,
 		ExcludeRegions:      req.ExcludeRegions,
 		SinceTimestamp:      time.Unix(state.KeyCursor.Timestamp, 0),
 		UntilTimestamp:      fetchUntil,
@@ -140,7 +170,15 @@ func (s Server) fetch(ctx context.Context, req *federation.FederationFetchReques
 		IncludeTravelers:    true,
 		OnlyTravelers:       req.OnlyTravelers,
 		OnlyLocalProvenance: req.OnlyLocalProvenance, // Include re-federation?
+		ExcludeGenerated:    true,
+		ExcludeRevoked:      true,
+		ExcludeTestData:     !testData,
+		OnlyTestData:        testData,
 		Limit:               maxRecords,
+
+		IncludeJurisdictions: includeJurisdictions,
+		ExcludeJurisdictions: excludeJurisdictions,
+		FederationConsent:    publishdb.ConsentFilterFor(s.config.RequireConsent),
 	}
 	// The next token wil be set during the read if the read is incomplete.
 	state.KeyCursor.NextToken = ""
@@ -171,6 +209,7 @@ func (s Server) fetch(ctx context.Context, req *federation.FederationFetchReques
 		count:          &count,
 		includeRegions: includedRegions,
 		excludeRegions: excludedRegions,
+		rules:          rules,
 	}))
 	keepGoing := true
 	if err != nil {
@@ -257,6 +296,7 @@ type BuildIteratorRequest struct {
 	count          *int
 	includeRegions map[string]struct{}
 	excludeRegions map[string]struct{}
+	rules          *travelrule.Engine
 }
 
 func reportType(reportType string) federation.ExposureKey_ReportType {
@@ -290,6 +330,19 @@ func buildIteratorFunction(request *BuildIteratorRequest) publishdb.IteratorFunc
 			}
 		}
 
+		// Add any requested regions the key is distributed to by travel rules.
+		for _, region := range request.rules.OutputRegions(travelrule.TargetFederation, exp.Regions, exp.Traveler) {
+			if _, ok := request.excludeRegions[region]; ok {
+				continue
+			}
+			if _, ok := request.includeRegions[region]; !ok {
+				continue
+			}
+			if !contains(reportRegions, region) {
+				reportRegions = append(reportRegions, region)
+			}
+		}
+
 		key := federation.ExposureKey{
 			ExposureKey:    exp.ExposureKey,
 			IntervalNumber: exp.IntervalNumber,
@@ -337,7 +390,51 @@ func buildIteratorFunction(request *BuildIteratorRequest) publishdb.IteratorFunc
 
 // AuthInterceptor validates incoming OIDC bearer token and adds corresponding FederationAuthorization record to the context.
 func (s Server) AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	logger := logging.FromContext(ctx).Named("federationout.AuthInterceptor")
+	if isPublicMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamAuthInterceptor is the streaming equivalent of AuthInterceptor.
+func (s Server) StreamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if isPublicMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	ctx, err := s.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// isPublicMethod returns true for methods that don't require authentication.
+// Only the health service is public, so load balancers can check it.
+func isPublicMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// authenticatedStream is a grpc.ServerStream with the authorization on its
+// context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authenticatedStream) Context() context.Context {
+	return a.ctx
+}
+
+// authenticate validates the OIDC bearer token on the incoming context and
+// returns a context carrying the caller's FederationAuthorization.
+func (s Server) authenticate(ctx context.Context) (context.Context, error) {
+	logger := logging.FromContext(ctx).Named("federationout.authenticate")
 
 	raw, err := rawToken(ctx)
 	if err != nil {
@@ -345,55 +442,62 @@ func (s Server) AuthInterceptor(ctx context.Context, req interface{}, info *grpc
 		return nil, err
 	}
 
-	token, err := idtoken.Validate(ctx, raw, "")
+	// The token is verified against the authorization for its issuer and
+	// subject, so the authorization is found first. This also ensures keys are
+	// only ever fetched from issuers that have been authorized.
+	claims, err := unverifiedClaims(raw)
 	if err != nil {
 		logger.Infof("Invalid token: %v", err)
 		stats.Record(ctx, mFetchInvalidAuthToken.M(1))
-		return nil, status.Errorf(codes.Unauthenticated, "Invalid token")
+		return nil, errcode.New(errcode.InvalidToken, "Invalid token")
 	}
 
-	auth, err := s.db.GetFederationOutAuthorization(ctx, token.Issuer, token.Subject)
+	auth, err := s.db.GetFederationOutAuthorization(ctx, claims.Issuer, claims.Subject)
 	if err != nil {
 		if errors.Is(err, coredb.ErrNotFound) {
 			stats.Record(ctx, mFetchUnauthorized.M(1))
-			logger.Infof("Authorization not found (issuer %q, subject %s)", token.Issuer, token.Subject)
-			return nil, status.Errorf(codes.Unauthenticated, "Invalid issuer/subject")
+			logger.Infof("Authorization not found (issuer %q, subject %s)", claims.Issuer, claims.Subject)
+			return nil, errcode.New(errcode.UnknownFederationClient, "Invalid issuer/subject")
 		}
-		logger.Errorw("failed to fetch authorization", "issuer", token.Issuer, "subject", token.Subject, "error", err)
+		logger.Errorw("failed to fetch authorization", "issuer", claims.Issuer, "subject", claims.Subject, "error", err)
 		stats.Record(ctx, mFetchInternalError.M(1))
-		return nil, status.Errorf(codes.Internal, "Internal error")
+		return nil, errcode.New(errcode.InternalError, "Internal error")
 	}
 
-	if auth.Audience != "" && auth.Audience != token.Audience {
-		stats.Record(ctx, mFetchInvalidAudience.M(1))
-		logger.Infof("Invalid audience, got %q, want %q", token.Audience, auth.Audience)
-		return nil, status.Errorf(codes.Unauthenticated, "Invalid audience")
+	if err := s.verifier.verify(ctx, raw, auth); err != nil {
+		if errors.Is(err, errInvalidAudience) {
+			stats.Record(ctx, mFetchInvalidAudience.M(1))
+			logger.Infof("Invalid audience: %v", err)
+			return nil, errcode.New(errcode.InvalidAudience, "Invalid audience")
+		}
+		logger.Infof("Invalid token: %v", err)
+		stats.Record(ctx, mFetchInvalidAuthToken.M(1))
+		return nil, errcode.New(errcode.InvalidToken, "Invalid token")
 	}
 
 	// Store the FederationAuthorization on the context.
 	logger.Infof("Caller: issuer %q subject %q", auth.Issuer, auth.Subject)
-	ctx = context.WithValue(ctx, authKey{}, auth)
-	return handler(ctx, req)
+	return context.WithValue(ctx, authKey{}, auth), nil
 }
 
 func rawToken(ctx context.Context) (string, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return "", status.Errorf(codes.Unauthenticated, "Missing metadata")
+		return "", errcode.New(errcode.MissingAuthorization, "Missing metadata")
 	}
 	if _, ok := md[authHeader]; !ok {
-		return "", status.Errorf(codes.Unauthenticated, "Missing authorization header [1]")
+		return "", errcode.New(errcode.MissingAuthorization, "Missing authorization header [1]")
 	}
 	if len(md[authHeader]) == 0 {
-		return "", status.Errorf(codes.Unauthenticated, "Missing authorization header [2]")
+		return "", errcode.New(errcode.MissingAuthorization, "Missing authorization header [2]")
 	}
 	if len(md[authHeader]) > 1 {
-		return "", status.Errorf(codes.Unauthenticated, "Multiple authorization headers")
+		return "", errcode.New(errcode.InvalidToken, "Multiple authorization headers")
 	}
 
 	authHeader := md[authHeader][0]
 	if !strings.HasPrefix(authHeader, bearer) {
-		return "", status.Errorf(codes.Unauthenticated, "Invalid authorization header")
+		return "", errcode.New(errcode.InvalidToken, "Invalid authorization header")
 	}
 	rawToken := project.TrimSpaceAndNonPrintable(strings.TrimPrefix(authHeader, bearer))
 	return rawToken, nil
@@ -436,3 +540,12 @@ func union(aa, bb []string) []string {
 	sort.Strings(result)
 	return result
 }
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
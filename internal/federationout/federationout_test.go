@@ -20,11 +20,14 @@ import (
 	"testing"
 	"time"
 
+	fedmodel "github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/internal/pb/federation"
 	"github.com/google/exposure-notifications-server/internal/project"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/travelrule"
+	travelrulemodel "github.com/google/exposure-notifications-server/internal/travelrule/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 
 	"github.com/google/go-cmp/cmp"
@@ -535,7 +538,7 @@ func TestFetch(t *testing.T) {
 				revised: tc.revisedIterations,
 			}
 
-			got, err := server.fetch(project.TestContext(t), req, fakeDB.provideInput, time.Now())
+			got, err := server.fetch(project.TestContext(t), req, fakeDB.provideInput, nil, time.Now())
 			if err != nil {
 				t.Fatalf("fetch() returned err=%v, want err=nil", err)
 			}
@@ -546,6 +549,130 @@ func TestFetch(t *testing.T) {
 	}
 }
 
+func TestFetch_Criteria(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	ctx = context.WithValue(ctx, authKey{}, &fedmodel.FederationOutAuthorization{
+		IncludeRegions:       []string{"US"},
+		IncludeJurisdictions: []string{"US"},
+		ExcludeJurisdictions: []string{"CA"},
+	})
+
+	server := Server{
+		env:    serverenv.New(ctx),
+		config: &Config{MaxRecords: 100, RequireConsent: true},
+	}
+	req := &federation.FederationFetchRequest{
+		IncludeRegions: []string{"US"},
+	}
+
+	var got []publishdb.IterateExposuresCriteria
+	itFunc := func(_ context.Context, c publishdb.IterateExposuresCriteria, _ publishdb.IteratorFunction) (string, error) {
+		got = append(got, c)
+		return "", nil
+	}
+
+	if _, err := server.fetch(ctx, req, itFunc, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 {
+		t.Fatal("expected exposures to be iterated")
+	}
+	for _, c := range got {
+		if diff := cmp.Diff([]string{"US"}, c.IncludeJurisdictions); diff != "" {
+			t.Errorf("include jurisdictions mismatch (-want, +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"CA"}, c.ExcludeJurisdictions); diff != "" {
+			t.Errorf("exclude jurisdictions mismatch (-want, +got):\n%s", diff)
+		}
+		if got, want := c.FederationConsent, publishdb.ConsentGiven; got != want {
+			t.Errorf("expected federation consent %v to be %v", got, want)
+		}
+		if !c.ExcludeTestData || c.OnlyTestData {
+			t.Errorf("expected test data to be excluded, got exclude=%t only=%t", c.ExcludeTestData, c.OnlyTestData)
+		}
+		if !c.ExcludeGenerated {
+			t.Errorf("expected generated keys to be excluded")
+		}
+	}
+}
+
+func TestFetch_TestDataCriteria(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	ctx = context.WithValue(ctx, authKey{}, &fedmodel.FederationOutAuthorization{
+		TestData: true,
+	})
+
+	server := Server{
+		env:    serverenv.New(ctx),
+		config: &Config{MaxRecords: 100},
+	}
+	req := &federation.FederationFetchRequest{}
+
+	var got []publishdb.IterateExposuresCriteria
+	itFunc := func(_ context.Context, c publishdb.IterateExposuresCriteria, _ publishdb.IteratorFunction) (string, error) {
+		got = append(got, c)
+		return "", nil
+	}
+
+	if _, err := server.fetch(ctx, req, itFunc, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 {
+		t.Fatal("expected exposures to be iterated")
+	}
+	for _, c := range got {
+		if c.ExcludeTestData || !c.OnlyTestData {
+			t.Errorf("expected only test data, got exclude=%t only=%t", c.ExcludeTestData, c.OnlyTestData)
+		}
+	}
+}
+
+func TestBuildIteratorFunction_TravelRules(t *testing.T) {
+	t.Parallel()
+
+	rules := travelrule.NewEngine([]*travelrulemodel.TravelRule{
+		{
+			Name:              "eu-travelers",
+			InputRegions:      []string{"DE", "FR", "IT"},
+			TravelersOnly:     true,
+			OutputRegions:     []string{"DE", "FR", "IT"},
+			ApplyToFederation: true,
+		},
+	})
+
+	var keys []*federation.ExposureKey
+	count := 0
+	f := buildIteratorFunction(&BuildIteratorRequest{
+		destination:    &keys,
+		state:          &federation.FetchState{KeyCursor: &federation.Cursor{}, RevisedKeyCursor: &federation.Cursor{}},
+		count:          &count,
+		includeRegions: map[string]struct{}{"DE": {}, "FR": {}},
+		excludeRegions: map[string]struct{}{"FR": {}},
+		rules:          rules,
+	})
+
+	if err := f(makeExposure(aaa, verifyapi.ReportTypeConfirmed, "IT", true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f(makeExposure(bbb, verifyapi.ReportTypeConfirmed, "IT", false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(keys), 2; got != want {
+		t.Fatalf("expected %d keys, got %d", want, got)
+	}
+	if diff := cmp.Diff([]string{"DE"}, keys[0].Regions); diff != "" {
+		t.Errorf("traveler regions mismatch (-want, +got):\n%s", diff)
+	}
+	if got := keys[1].Regions; len(got) != 0 {
+		t.Errorf("expected non-traveler to have no regions, got %v", got)
+	}
+}
+
 // TestRawToken tests rawToken().
 func TestRawToken(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,99 @@
+// Copyright 2020 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+	federationv2 "github.com/google/exposure-notifications-server/internal/pb/federation/v2"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"go.opencensus.io/plugin/ocgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewGRPCServer builds a gRPC server with the federation service registered.
+// Version 2 of the federation service is registered alongside version 1 if a
+// cursor key is configured. TLS and client authentication are configured
+// according to the provided config. The caller is responsible for serving and
+// stopping the returned server.
+func NewGRPCServer(env *serverenv.ServerEnv, config *Config) (*grpc.Server, error) {
+	federationServer := NewServer(env, config)
+	server := federationServer.(*Server)
+
+	var serverV2 *ServerV2
+	if len(config.CursorKey) > 0 {
+		var err error
+		serverV2, err = NewServerV2(server, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create federation v2 server: %w", err)
+		}
+	}
+
+	var sopts []grpc.ServerOption
+	if config.TLSCertFile != "" && config.TLSKeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create credentials: %w", err)
+		}
+		sopts = append(sopts, grpc.Creds(creds))
+	}
+
+	if !config.AllowAnyClient {
+		verifier, err := newOIDCVerifier(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create oidc verifier: %w", err)
+		}
+		server.verifier = verifier
+		sopts = append(sopts,
+			grpc.UnaryInterceptor(server.AuthInterceptor),
+			grpc.StreamInterceptor(server.StreamAuthInterceptor))
+	}
+
+	sopts = append(sopts,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:              config.Keepalive.Time,
+			Timeout:           config.Keepalive.Timeout,
+			MaxConnectionIdle: config.Keepalive.MaxConnectionIdle,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             config.Keepalive.MinTime,
+			PermitWithoutStream: config.Keepalive.PermitWithoutStream,
+		}),
+		grpc.MaxRecvMsgSize(config.MaxRecvMsgSize),
+		grpc.MaxSendMsgSize(config.MaxSendMsgSize),
+		grpc.StatsHandler(&ocgrpc.ServerHandler{}))
+	grpcServer := grpc.NewServer(sopts...)
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	federation.RegisterFederationServer(grpcServer, federationServer)
+	healthServer.SetServingStatus(federation.Federation_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	if serverV2 != nil {
+		federationv2.RegisterFederationServer(grpcServer, serverV2)
+		healthServer.SetServingStatus(federationv2.Federation_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	if config.EnableReflection {
+		reflection.Register(grpcServer)
+	}
+	return grpcServer, nil
+}
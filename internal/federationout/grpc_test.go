@@ -0,0 +1,123 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	federationv2 "github.com/google/exposure-notifications-server/internal/pb/federation/v2"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestNewGRPCServer(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	grpcServer, err := NewGRPCServer(serverenv.New(ctx), &Config{
+		Timeout:            time.Minute,
+		MaxChunkKeys:       100,
+		CursorKey:          bytes.Repeat([]byte{1}, minCursorKeyLength),
+		OIDCCacheDuration:  time.Hour,
+		OIDCRequestTimeout: time.Second,
+		EnableReflection:   true,
+		Keepalive: KeepaliveConfig{
+			Time:    time.Minute,
+			Timeout: 20 * time.Second,
+			MinTime: 30 * time.Second,
+		},
+		MaxRecvMsgSize: 1 << 20,
+		MaxSendMsgSize: 1 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	t.Run("health_is_public", func(t *testing.T) {
+		t.Parallel()
+
+		client := healthpb.NewHealthClient(conn)
+		for _, service := range []string{"", "Federation", federationv2.Federation_ServiceDesc.ServiceName} {
+			resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatalf("%q: %v", service, err)
+			}
+			if got, want := resp.Status, healthpb.HealthCheckResponse_SERVING; got != want {
+				t.Errorf("%q: expected status %v to be %v", service, got, want)
+			}
+		}
+	})
+
+	t.Run("reflection_requires_auth", func(t *testing.T) {
+		t.Parallel()
+
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		_, err = stream.Recv()
+		if got, want := status.Code(err), codes.Unauthenticated; got != want {
+			t.Errorf("expected code %v to be %v", got, want)
+		}
+	})
+
+	t.Run("federation_requires_auth", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := federationv2.NewFederationClient(conn).Handshake(ctx, &federationv2.HandshakeRequest{
+			SupportedVersions: []uint32{protocolVersion2},
+		})
+		if got, want := status.Code(err), codes.Unauthenticated; got != want {
+			t.Errorf("expected code %v to be %v", got, want)
+		}
+		if got, want := errcode.FromStatus(status.Convert(err)), errcode.MissingAuthorization; got != want {
+			t.Errorf("expected error code %q to be %q", got, want)
+		}
+	})
+}
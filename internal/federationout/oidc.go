@@ -0,0 +1,302 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/pkg/cache"
+	"github.com/rakutentech/jwk-go/jwk"
+)
+
+const (
+	// discoveryPath is appended to an issuer to locate its OIDC discovery
+	// document.
+	discoveryPath = "/.well-known/openid-configuration"
+
+	// maxOIDCResponseBytes limits the size of discovery and JWKS responses.
+	maxOIDCResponseBytes = 1 << 20
+
+	// minKeyRefreshInterval is the minimum time between refetching an issuer's
+	// keys because a token named a key ID that was not in the cached set.
+	minKeyRefreshInterval = time.Minute
+)
+
+var (
+	errInvalidAudience = errors.New("invalid audience")
+	errUnknownKey      = errors.New("unknown signing key")
+
+	// oidcSigningMethods are the asymmetric algorithms accepted on ID tokens.
+	oidcSigningMethods = []string{
+		"RS256", "RS384", "RS512",
+		"PS256", "PS384", "PS512",
+		"ES256", "ES384", "ES512",
+	}
+)
+
+// oidcClaims are the identifying claims of an ID token.
+type oidcClaims struct {
+	Issuer  string
+	Subject string
+}
+
+// oidcVerifier validates OIDC ID tokens from any issuer. The signing keys for
+// a FederationOutAuthorization are read from its JWKS URI if set, or located
+// through OIDC discovery on its issuer. Keys are cached per source.
+type oidcVerifier struct {
+	client *http.Client
+	keys   *cache.Cache[*jwk.KeySpecSet]
+	skew   time.Duration
+
+	refreshedLock sync.Mutex
+	refreshed     map[string]time.Time
+}
+
+func newOIDCVerifier(config *Config) (*oidcVerifier, error) {
+	keys, err := cache.New[*jwk.KeySpecSet](config.OIDCCacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key cache: %w", err)
+	}
+
+	return &oidcVerifier{
+		client: &http.Client{
+			Timeout: config.OIDCRequestTimeout,
+		},
+		keys:      keys,
+		skew:      config.OIDCClockSkew,
+		refreshed: make(map[string]time.Time),
+	}, nil
+}
+
+// unverifiedClaims returns the issuer and subject of the token WITHOUT
+// validating it. The result must only be used to find the authorization the
+// token is then verified against.
+func unverifiedClaims(raw string) (*oidcClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(raw, claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	iss, _ := claims["iss"].(string)
+	sub, _ := claims["sub"].(string)
+	if iss == "" || sub == "" {
+		return nil, fmt.Errorf("token is missing issuer or subject")
+	}
+	return &oidcClaims{Issuer: iss, Subject: sub}, nil
+}
+
+// verify validates the token's signature against the keys for the
+// authorization, and its claims against the authorization. If the audience
+// does not match, the returned error wraps errInvalidAudience.
+func (v *oidcVerifier) verify(ctx context.Context, raw string, auth *model.FederationOutAuthorization) error {
+	parser := &jwt.Parser{
+		ValidMethods:         oidcSigningMethods,
+		SkipClaimsValidation: true,
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.signingKey(ctx, auth, kid)
+	}); err != nil {
+		// Errors from the key func are wrapped in a way that doesn't unwrap.
+		var verr *jwt.ValidationError
+		if errors.As(err, &verr) && verr.Inner != nil {
+			err = verr.Inner
+		}
+		return fmt.Errorf("failed to verify token: %w", err)
+	}
+
+	now := time.Now().Unix()
+	skew := int64(v.skew.Seconds())
+
+	if !claims.VerifyIssuer(auth.Issuer, true) {
+		return fmt.Errorf("invalid issuer")
+	}
+	if sub, _ := claims["sub"].(string); sub != auth.Subject {
+		return fmt.Errorf("invalid subject")
+	}
+	if !claims.VerifyExpiresAt(now-skew, true) {
+		return fmt.Errorf("token is expired")
+	}
+	if !claims.VerifyIssuedAt(now+skew, false) {
+		return fmt.Errorf("token used before issued")
+	}
+	if !claims.VerifyNotBefore(now+skew, false) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if auth.Audience != "" && !claims.VerifyAudience(auth.Audience, true) {
+		return fmt.Errorf("%w, want %q", errInvalidAudience, auth.Audience)
+	}
+	return nil
+}
+
+// signingKey returns the public key with the given ID for the authorization.
+// If the key is not in the cached set, the set is refetched in case the issuer
+// rotated its keys.
+func (v *oidcVerifier) signingKey(ctx context.Context, auth *model.FederationOutAuthorization, kid string) (interface{}, error) {
+	source := keySource(auth)
+
+	set, err := v.keys.WriteThruLookup(source, func() (*jwk.KeySpecSet, error) {
+		return v.fetchKeys(ctx, auth)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if key := findKey(set, kid); key != nil {
+		return key, nil
+	}
+
+	if !v.allowRefresh(source) {
+		return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+	}
+	set, err = v.fetchKeys(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.keys.Set(source, set); err != nil {
+		return nil, fmt.Errorf("failed to cache keys: %w", err)
+	}
+	if key := findKey(set, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+}
+
+// allowRefresh reports whether the keys for source may be refetched early.
+// This prevents tokens with made up key IDs from causing a fetch per request.
+func (v *oidcVerifier) allowRefresh(source string) bool {
+	v.refreshedLock.Lock()
+	defer v.refreshedLock.Unlock()
+
+	now := time.Now()
+	if last, ok := v.refreshed[source]; ok && now.Sub(last) < minKeyRefreshInterval {
+		return false
+	}
+	v.refreshed[source] = now
+	return true
+}
+
+// fetchKeys reads the key set for the authorization.
+func (v *oidcVerifier) fetchKeys(ctx context.Context, auth *model.FederationOutAuthorization) (*jwk.KeySpecSet, error) {
+	jwksURI := auth.JWKSURI
+	if jwksURI == "" {
+		var err error
+		jwksURI, err = v.discover(ctx, auth.Issuer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var set jwk.KeySpecSet
+	if err := v.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to read jwks: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("jwks %q contains no keys", jwksURI)
+	}
+	return &set, nil
+}
+
+// discover returns the JWKS URI from the issuer's discovery document.
+func (v *oidcVerifier) discover(ctx context.Context, issuer string) (string, error) {
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, strings.TrimSuffix(issuer, "/")+discoveryPath, &doc); err != nil {
+		return "", fmt.Errorf("failed to discover issuer %q: %w", issuer, err)
+	}
+
+	// The discovery document must be for the issuer that was requested.
+	if doc.Issuer != issuer {
+		return "", fmt.Errorf("discovery document issuer %q does not match %q", doc.Issuer, issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", fmt.Errorf("discovery document for %q has no jwks_uri", issuer)
+	}
+	return doc.JWKSURI, nil
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, uri string, out interface{}) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("url %q must use https", uri)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response code %d from %q", resp.StatusCode, uri)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOIDCResponseBytes)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// keySource is the cache key for an authorization's signing keys.
+func keySource(auth *model.FederationOutAuthorization) string {
+	if auth.JWKSURI != "" {
+		return auth.JWKSURI
+	}
+	return auth.Issuer
+}
+
+// findKey returns the signing key with the given ID. Tokens without a key ID
+// are only accepted if the set has exactly one key.
+func findKey(set *jwk.KeySpecSet, kid string) interface{} {
+	if kid == "" {
+		if len(set.Keys) == 1 {
+			return set.Keys[0].Key
+		}
+		return nil
+	}
+
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if key.KeyID == kid {
+			return key.Key
+		}
+	}
+	return nil
+}
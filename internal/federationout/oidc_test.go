@@ -0,0 +1,371 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/rakutentech/jwk-go/jwk"
+)
+
+// testIssuer serves an OIDC discovery document and JWKS over TLS.
+type testIssuer struct {
+	server *httptest.Server
+	keys   atomic.Value // *jwk.KeySpecSet
+	hits   int32
+	issuer string
+}
+
+func newTestIssuer(t *testing.T, keys ...jwk.KeySpec) *testIssuer {
+	t.Helper()
+
+	ti := &testIssuer{}
+	ti.setKeys(keys...)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		issuer := ti.issuer
+		if issuer == "" {
+			issuer = ti.server.URL
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": ti.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ti.hits, 1)
+		b, err := ti.keys.Load().(*jwk.KeySpecSet).MarshalPublicJSON()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+
+	ti.server = httptest.NewTLSServer(mux)
+	t.Cleanup(ti.server.Close)
+	return ti
+}
+
+func (ti *testIssuer) setKeys(keys ...jwk.KeySpec) {
+	ti.keys.Store(&jwk.KeySpecSet{Keys: keys})
+}
+
+func (ti *testIssuer) verifier(t *testing.T) *oidcVerifier {
+	t.Helper()
+
+	v, err := newOIDCVerifier(&Config{
+		OIDCCacheDuration:  time.Hour,
+		OIDCRequestTimeout: 5 * time.Second,
+		OIDCClockSkew:      time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(v.keys.Stop)
+	v.client = ti.server.Client()
+	return v
+}
+
+func testSigningKey(t *testing.T, kid string) (*ecdsa.PrivateKey, jwk.KeySpec) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, jwk.KeySpec{Key: &key.PublicKey, KeyID: kid, Algorithm: "ES256", Use: "sig"}
+}
+
+func signTestToken(t *testing.T, key *ecdsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestUnverifiedClaims(t *testing.T) {
+	t.Parallel()
+
+	key, _ := testSigningKey(t, "k1")
+
+	raw := signTestToken(t, key, "k1", jwt.MapClaims{"iss": "https://idp.example.com", "sub": "partner"})
+	claims, err := unverifiedClaims(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := claims.Issuer, "https://idp.example.com"; got != want {
+		t.Errorf("expected issuer %q to be %q", got, want)
+	}
+	if got, want := claims.Subject, "partner"; got != want {
+		t.Errorf("expected subject %q to be %q", got, want)
+	}
+
+	if _, err := unverifiedClaims(signTestToken(t, key, "k1", jwt.MapClaims{"sub": "partner"})); err == nil {
+		t.Errorf("expected error for missing issuer")
+	}
+	if _, err := unverifiedClaims("not-a-token"); err == nil {
+		t.Errorf("expected error for malformed token")
+	}
+}
+
+func TestOIDCVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	key, spec := testSigningKey(t, "k1")
+	otherKey, _ := testSigningKey(t, "k1")
+
+	now := time.Now()
+	validClaims := func(issuer string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": issuer,
+			"sub": "partner",
+			"aud": []string{"federation", "other"},
+			"iat": now.Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		}
+	}
+
+	cases := []struct {
+		name    string
+		modify  func(c jwt.MapClaims)
+		signer  *ecdsa.PrivateKey
+		kid     string
+		auth    func(a *model.FederationOutAuthorization)
+		wantErr error
+		errStr  string
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name:   "no_audience_configured",
+			modify: func(c jwt.MapClaims) { delete(c, "aud") },
+			auth:   func(a *model.FederationOutAuthorization) { a.Audience = "" },
+		},
+		{
+			name:    "wrong_audience",
+			modify:  func(c jwt.MapClaims) { c["aud"] = "someone-else" },
+			wantErr: errInvalidAudience,
+		},
+		{
+			name:   "expired",
+			modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-2 * time.Minute).Unix() },
+			errStr: "expired",
+		},
+		{
+			name:   "expired_within_skew",
+			modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-30 * time.Second).Unix() },
+		},
+		{
+			name:   "missing_expiry",
+			modify: func(c jwt.MapClaims) { delete(c, "exp") },
+			errStr: "expired",
+		},
+		{
+			name:   "not_yet_valid",
+			modify: func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Hour).Unix() },
+			errStr: "not valid yet",
+		},
+		{
+			name:   "wrong_subject",
+			modify: func(c jwt.MapClaims) { c["sub"] = "intruder" },
+			errStr: "invalid subject",
+		},
+		{
+			name:   "bad_signature",
+			signer: otherKey,
+			errStr: "failed to verify",
+		},
+		{
+			name:    "unknown_kid",
+			kid:     "k2",
+			wantErr: errUnknownKey,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			ti := newTestIssuer(t, spec)
+			v := ti.verifier(t)
+
+			auth := &model.FederationOutAuthorization{
+				Issuer:   ti.server.URL,
+				Subject:  "partner",
+				Audience: "federation",
+			}
+			if tc.auth != nil {
+				tc.auth(auth)
+			}
+
+			claims := validClaims(ti.server.URL)
+			if tc.modify != nil {
+				tc.modify(claims)
+			}
+			signer := key
+			if tc.signer != nil {
+				signer = tc.signer
+			}
+			kid := "k1"
+			if tc.kid != "" {
+				kid = tc.kid
+			}
+
+			err := v.verify(ctx, signTestToken(t, signer, kid, claims), auth)
+			switch {
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("expected %v to be %v", err, tc.wantErr)
+				}
+			case tc.errStr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.errStr) {
+					t.Errorf("expected %v to contain %q", err, tc.errStr)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifier_KeyCaching(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	key1, spec1 := testSigningKey(t, "k1")
+	key2, spec2 := testSigningKey(t, "k2")
+
+	ti := newTestIssuer(t, spec1)
+	v := ti.verifier(t)
+	auth := &model.FederationOutAuthorization{Issuer: ti.server.URL, Subject: "partner"}
+
+	claims := jwt.MapClaims{"iss": ti.server.URL, "sub": "partner", "exp": time.Now().Add(time.Hour).Unix()}
+	for i := 0; i < 3; i++ {
+		if err := v.verify(ctx, signTestToken(t, key1, "k1", claims), auth); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := atomic.LoadInt32(&ti.hits), int32(1); got != want {
+		t.Errorf("expected %d jwks fetches to be %d", got, want)
+	}
+
+	// The issuer rotates keys, which is picked up on the first unknown key ID.
+	ti.setKeys(spec1, spec2)
+	if err := v.verify(ctx, signTestToken(t, key2, "k2", claims), auth); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt32(&ti.hits), int32(2); got != want {
+		t.Errorf("expected %d jwks fetches to be %d", got, want)
+	}
+
+	// Further unknown key IDs don't refetch until the refresh interval passes.
+	if err := v.verify(ctx, signTestToken(t, key2, "k3", claims), auth); !errors.Is(err, errUnknownKey) {
+		t.Errorf("expected %v to be %v", err, errUnknownKey)
+	}
+	if got, want := atomic.LoadInt32(&ti.hits), int32(2); got != want {
+		t.Errorf("expected %d jwks fetches to be %d", got, want)
+	}
+}
+
+func TestOIDCVerifier_FetchKeys(t *testing.T) {
+	t.Parallel()
+
+	_, spec := testSigningKey(t, "k1")
+
+	t.Run("discovery", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		ti := newTestIssuer(t, spec)
+
+		set, err := ti.verifier(t).fetchKeys(ctx, &model.FederationOutAuthorization{Issuer: ti.server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(set.Keys), 1; got != want {
+			t.Errorf("expected %d keys to be %d", got, want)
+		}
+	})
+
+	t.Run("jwks_uri_override", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		ti := newTestIssuer(t, spec)
+		// Discovery would fail for this issuer.
+		ti.issuer = "https://mismatch.example.com"
+
+		auth := &model.FederationOutAuthorization{
+			Issuer:  ti.server.URL,
+			JWKSURI: ti.server.URL + "/jwks",
+		}
+		if _, err := ti.verifier(t).fetchKeys(ctx, auth); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("issuer_mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		ti := newTestIssuer(t, spec)
+		ti.issuer = "https://mismatch.example.com"
+
+		_, err := ti.verifier(t).fetchKeys(ctx, &model.FederationOutAuthorization{Issuer: ti.server.URL})
+		if err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Errorf("expected %v to be an issuer mismatch", err)
+		}
+	})
+
+	t.Run("requires_https", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		ti := newTestIssuer(t, spec)
+
+		insecure := strings.Replace(ti.server.URL, "https://", "http://", 1)
+		_, err := ti.verifier(t).fetchKeys(ctx, &model.FederationOutAuthorization{Issuer: insecure})
+		if err == nil || !strings.Contains(err.Error(), "must use https") {
+			t.Errorf("expected %v to require https", err)
+		}
+	})
+}
@@ -0,0 +1,406 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+	federationv2 "github.com/google/exposure-notifications-server/internal/pb/federation/v2"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"go.opencensus.io/stats"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// protocolVersion2 is the version of the streaming federation protocol.
+	protocolVersion2 = 2
+
+	// minCursorKeyLength is the minimum length of the cursor HMAC key.
+	minCursorKeyLength = 32
+
+	// cursorMACContext is prepended to cursor state before computing the HMAC,
+	// so the key can't be used to forge other values.
+	cursorMACContext = "federation-cursor-v2:"
+)
+
+var (
+	// Compile time assert that this server implements the required grpc interface.
+	_ federationv2.FederationServer = (*ServerV2)(nil)
+
+	// supportedVersions are the protocol versions served by ServerV2.
+	supportedVersions = []uint32{protocolVersion2}
+
+	// supportedCompressions are the batch compressions supported, in order of
+	// preference.
+	supportedCompressions = []federationv2.Compression{
+		federationv2.Compression_COMPRESSION_GZIP,
+		federationv2.Compression_COMPRESSION_NONE,
+	}
+
+	errInvalidCursor = errors.New("invalid cursor")
+)
+
+// fetchFunc runs a version 1 fetch request.
+type fetchFunc func(context.Context, *federation.FederationFetchRequest) (*federation.FederationFetchResponse, error)
+
+// ServerV2 serves version 2 of the federation protocol. Queries are run the
+// same way as version 1, so both versions return the same keys.
+type ServerV2 struct {
+	federationv2.UnimplementedFederationServer
+
+	server    *Server
+	cursorKey []byte
+}
+
+// NewServerV2 builds a new version 2 FederationServer on top of the version 1
+// server.
+func NewServerV2(server *Server, config *Config) (*ServerV2, error) {
+	if len(config.CursorKey) < minCursorKeyLength {
+		return nil, fmt.Errorf("cursor key must be at least %d bytes, got %d", minCursorKeyLength, len(config.CursorKey))
+	}
+
+	return &ServerV2{
+		server:    server,
+		cursorKey: config.CursorKey,
+	}, nil
+}
+
+// Handshake implements the FederationServer Handshake endpoint. It selects the
+// highest protocol version supported by both peers and the capabilities they
+// have in common.
+func (s *ServerV2) Handshake(ctx context.Context, req *federationv2.HandshakeRequest) (*federationv2.HandshakeResponse, error) {
+	version, ok := negotiateVersion(req.SupportedVersions)
+	if !ok {
+		return nil, errcode.New(errcode.NoCommonProtocolVersion, "no supported protocol version in %v, server supports %v",
+			req.SupportedVersions, supportedVersions)
+	}
+
+	return &federationv2.HandshakeResponse{
+		Version:      version,
+		Capabilities: negotiateCapabilities(s.capabilities(), req.Capabilities),
+	}, nil
+}
+
+// Fetch implements the FederationServer Fetch endpoint. Keys are streamed in
+// chunks, and the final chunk carries the cursor to continue from.
+func (s *ServerV2) Fetch(req *federationv2.FetchRequest, stream federationv2.Federation_FetchServer) error {
+	ctx, cancel := context.WithTimeout(stream.Context(), s.server.config.Timeout)
+	defer cancel()
+
+	return s.fetch(ctx, req, stream.Send, s.server.fetchCurrent)
+}
+
+func (s *ServerV2) fetch(ctx context.Context, req *federationv2.FetchRequest, send func(*federationv2.FetchChunk) error, fetch fetchFunc) error {
+	logger := logging.FromContext(ctx).Named("federationout.FetchV2")
+
+	if req.Version != protocolVersion2 {
+		return errcode.New(errcode.UnsupportedProtocolVersion, "unsupported protocol version %d", req.Version)
+	}
+	if !containsCompression(supportedCompressions, req.Compression) {
+		return errcode.New(errcode.UnsupportedCompression, "unsupported compression %v", req.Compression)
+	}
+
+	binding := cursorBinding(ctx, req)
+	state, err := s.decodeCursor(req.Cursor, binding)
+	if err != nil {
+		logger.Infow("rejected cursor", "error", err)
+		return errcode.New(errcode.InvalidCursor, "invalid cursor")
+	}
+
+	response, err := fetch(ctx, &federation.FederationFetchRequest{
+		IncludeRegions:      req.IncludeRegions,
+		ExcludeRegions:      req.ExcludeRegions,
+		OnlyTravelers:       req.OnlyTravelers,
+		OnlyLocalProvenance: req.OnlyLocalProvenance,
+		MaxExposureKeys:     req.MaxExposureKeys,
+		State:               state,
+	})
+	if err != nil {
+		stats.Record(ctx, mFetchFailed.M(1))
+		logger.Errorw("failed to fetch", "error", err)
+		return errcode.New(errcode.InternalError, "internal error")
+	}
+
+	cursor, err := s.encodeCursor(response.NextFetchState, binding)
+	if err != nil {
+		stats.Record(ctx, mFetchFailed.M(1))
+		logger.Errorw("failed to encode cursor", "error", err)
+		return errcode.New(errcode.InternalError, "internal error")
+	}
+
+	chunks, err := chunkResponse(response, s.chunkSize(req.MaxChunkKeys), req.Compression)
+	if err != nil {
+		stats.Record(ctx, mFetchFailed.M(1))
+		logger.Errorw("failed to build chunks", "error", err)
+		return errcode.New(errcode.InternalError, "internal error")
+	}
+
+	last := chunks[len(chunks)-1]
+	last.Final = true
+	last.PartialResponse = response.PartialResponse
+	last.NextCursor = cursor
+
+	for _, chunk := range chunks {
+		if err := send(chunk); err != nil {
+			return fmt.Errorf("failed to send chunk %d: %w", chunk.Sequence, err)
+		}
+	}
+	return nil
+}
+
+// capabilities returns the capabilities of this server.
+func (s *ServerV2) capabilities() *federationv2.Capabilities {
+	return &federationv2.Capabilities{
+		Compressions:    supportedCompressions,
+		MaxChunkKeys:    s.server.config.MaxChunkKeys,
+		MaxExposureKeys: s.server.config.MaxRecords,
+	}
+}
+
+// chunkSize returns the number of keys per chunk for a request.
+func (s *ServerV2) chunkSize(requested uint32) int {
+	size := s.server.config.MaxChunkKeys
+	if requested > 0 && (size == 0 || requested < size) {
+		size = requested
+	}
+	if size == 0 {
+		size = 1
+	}
+	return int(size)
+}
+
+// encodeCursor signs the fetch state so it can be handed to the client.
+func (s *ServerV2) encodeCursor(state *federation.FetchState, binding []byte) (*federationv2.Cursor, error) {
+	cs := &federationv2.CursorState{
+		KeyTimestamp:        state.GetKeyCursor().GetTimestamp(),
+		KeyToken:            state.GetKeyCursor().GetNextToken(),
+		RevisedKeyTimestamp: state.GetRevisedKeyCursor().GetTimestamp(),
+		RevisedKeyToken:     state.GetRevisedKeyCursor().GetNextToken(),
+		Binding:             binding,
+	}
+
+	b, err := proto.Marshal(cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+	return &federationv2.Cursor{
+		State: b,
+		Mac:   s.cursorMAC(b),
+	}, nil
+}
+
+// decodeCursor verifies the cursor's integrity and that it was issued for the
+// same query and caller. An empty cursor starts from the beginning, which is
+// represented by a nil state.
+func (s *ServerV2) decodeCursor(c *federationv2.Cursor, binding []byte) (*federation.FetchState, error) {
+	if len(c.GetState()) == 0 && len(c.GetMac()) == 0 {
+		return nil, nil
+	}
+
+	if !hmac.Equal(s.cursorMAC(c.State), c.Mac) {
+		return nil, fmt.Errorf("%w: integrity check failed", errInvalidCursor)
+	}
+
+	var cs federationv2.CursorState
+	if err := proto.Unmarshal(c.State, &cs); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCursor, err)
+	}
+	if !hmac.Equal(cs.Binding, binding) {
+		return nil, fmt.Errorf("%w: issued for a different query", errInvalidCursor)
+	}
+
+	return &federation.FetchState{
+		KeyCursor: &federation.Cursor{
+			Timestamp: cs.KeyTimestamp,
+			NextToken: cs.KeyToken,
+		},
+		RevisedKeyCursor: &federation.Cursor{
+			Timestamp: cs.RevisedKeyTimestamp,
+			NextToken: cs.RevisedKeyToken,
+		},
+	}, nil
+}
+
+func (s *ServerV2) cursorMAC(state []byte) []byte {
+	mac := hmac.New(sha256.New, s.cursorKey)
+	mac.Write([]byte(cursorMACContext))
+	mac.Write(state)
+	return mac.Sum(nil)
+}
+
+// cursorBinding hashes the parts of the request that must stay the same
+// between pages, along with the caller's identity if known.
+func cursorBinding(ctx context.Context, req *federationv2.FetchRequest) []byte {
+	normalize := func(regions []string) string {
+		upper := make([]string, len(regions))
+		for i, r := range regions {
+			upper[i] = strings.ToUpper(r)
+		}
+		sort.Strings(upper)
+		return strings.Join(upper, ",")
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "include=%s\n", normalize(req.IncludeRegions))
+	fmt.Fprintf(h, "exclude=%s\n", normalize(req.ExcludeRegions))
+	fmt.Fprintf(h, "travelers=%t\n", req.OnlyTravelers)
+	fmt.Fprintf(h, "local=%t\n", req.OnlyLocalProvenance)
+	if auth, ok := ctx.Value(authKey{}).(*model.FederationOutAuthorization); ok {
+		fmt.Fprintf(h, "issuer=%s\nsubject=%s\n", auth.Issuer, auth.Subject)
+	}
+	return h.Sum(nil)
+}
+
+// chunkResponse splits the keys of a version 1 response into chunks of at
+// most size keys. Primary keys are sent before revised keys. At least one
+// chunk is always returned.
+func chunkResponse(response *federation.FederationFetchResponse, size int, compression federationv2.Compression) ([]*federationv2.FetchChunk, error) {
+	primary, revised := response.Keys, response.RevisedKeys
+
+	var chunks []*federationv2.FetchChunk
+	for len(chunks) == 0 || len(primary)+len(revised) > 0 {
+		batch := &federationv2.KeyBatch{}
+
+		n := size
+		if n > len(primary) {
+			n = len(primary)
+		}
+		batch.Keys, primary = convertKeys(primary[:n]), primary[n:]
+
+		n = size - n
+		if n > len(revised) {
+			n = len(revised)
+		}
+		batch.RevisedKeys, revised = convertKeys(revised[:n]), revised[n:]
+
+		chunk := &federationv2.FetchChunk{
+			Sequence:    uint32(len(chunks)),
+			Compression: compression,
+		}
+		switch compression {
+		case federationv2.Compression_COMPRESSION_GZIP:
+			b, err := compressBatch(batch)
+			if err != nil {
+				return nil, err
+			}
+			chunk.CompressedBatch = b
+		default:
+			chunk.Batch = batch
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func compressBatch(batch *federationv2.KeyBatch) ([]byte, error) {
+	b, err := proto.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(b); err != nil {
+		return nil, fmt.Errorf("failed to compress batch: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress batch: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func convertKeys(keys []*federation.ExposureKey) []*federationv2.ExposureKey {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	out := make([]*federationv2.ExposureKey, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, &federationv2.ExposureKey{
+			ExposureKey:              k.ExposureKey,
+			TransmissionRisk:         k.TransmissionRisk,
+			IntervalNumber:           k.IntervalNumber,
+			IntervalCount:            k.IntervalCount,
+			ReportType:               federationv2.ExposureKey_ReportType(k.ReportType),
+			DaysSinceOnsetOfSymptoms: k.DaysSinceOnsetOfSymptoms,
+			HasSymptomOnset:          k.HasSymptomOnset,
+			Traveler:                 k.Traveler,
+			Regions:                  k.Regions,
+		})
+	}
+	return out
+}
+
+// negotiateVersion returns the highest version supported by both peers.
+func negotiateVersion(clientVersions []uint32) (uint32, bool) {
+	var best uint32
+	for _, v := range clientVersions {
+		for _, sv := range supportedVersions {
+			if v == sv && v > best {
+				best = v
+			}
+		}
+	}
+	return best, best != 0
+}
+
+// negotiateCapabilities returns the capabilities supported by both the server
+// and the client. Compressions keep the client's order of preference, and
+// uncompressed batches are always supported.
+func negotiateCapabilities(server, client *federationv2.Capabilities) *federationv2.Capabilities {
+	var compressions []federationv2.Compression
+	for _, c := range client.GetCompressions() {
+		if containsCompression(server.Compressions, c) && !containsCompression(compressions, c) {
+			compressions = append(compressions, c)
+		}
+	}
+	if !containsCompression(compressions, federationv2.Compression_COMPRESSION_NONE) {
+		compressions = append(compressions, federationv2.Compression_COMPRESSION_NONE)
+	}
+
+	return &federationv2.Capabilities{
+		Compressions:    compressions,
+		MaxChunkKeys:    minNonZero(server.MaxChunkKeys, client.GetMaxChunkKeys()),
+		MaxExposureKeys: minNonZero(server.MaxExposureKeys, client.GetMaxExposureKeys()),
+	}
+}
+
+func containsCompression(list []federationv2.Compression, c federationv2.Compression) bool {
+	for _, v := range list {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// minNonZero returns the smaller of a and b, ignoring zero values.
+func minNonZero(a, b uint32) uint32 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
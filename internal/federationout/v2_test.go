@@ -0,0 +1,339 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	fedmodel "github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+	federationv2 "github.com/google/exposure-notifications-server/internal/pb/federation/v2"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func testServerV2(t *testing.T) *ServerV2 {
+	t.Helper()
+
+	s, err := NewServerV2(&Server{
+		config: &Config{MaxRecords: 500, MaxChunkKeys: 3},
+	}, &Config{CursorKey: bytes.Repeat([]byte{1}, minCursorKeyLength)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewServerV2_ShortKey(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewServerV2(&Server{}, &Config{CursorKey: []byte("short")}); err == nil {
+		t.Errorf("expected error for short cursor key")
+	}
+}
+
+func TestServerV2_Handshake(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	s := testServerV2(t)
+
+	resp, err := s.Handshake(ctx, &federationv2.HandshakeRequest{
+		SupportedVersions: []uint32{1, 2, 3},
+		Capabilities: &federationv2.Capabilities{
+			Compressions: []federationv2.Compression{federationv2.Compression_COMPRESSION_GZIP},
+			MaxChunkKeys: 10,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &federationv2.HandshakeResponse{
+		Version: protocolVersion2,
+		Capabilities: &federationv2.Capabilities{
+			Compressions: []federationv2.Compression{
+				federationv2.Compression_COMPRESSION_GZIP,
+				federationv2.Compression_COMPRESSION_NONE,
+			},
+			MaxChunkKeys:    3,
+			MaxExposureKeys: 500,
+		},
+	}
+	if diff := cmp.Diff(want, resp, protocmp.Transform()); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	_, err = s.Handshake(ctx, &federationv2.HandshakeRequest{SupportedVersions: []uint32{1}})
+	if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+		t.Errorf("expected code %v to be %v", got, want)
+	}
+}
+
+func TestServerV2_Cursor(t *testing.T) {
+	t.Parallel()
+
+	s := testServerV2(t)
+	binding := []byte("binding")
+	state := &federation.FetchState{
+		KeyCursor:        &federation.Cursor{Timestamp: 100, NextToken: "abc"},
+		RevisedKeyCursor: &federation.Cursor{Timestamp: 50},
+	}
+
+	cursor, err := s.encodeCursor(state, binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.decodeCursor(cursor, binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(state, got, protocmp.Transform()); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// An empty cursor starts from the beginning.
+	if got, err := s.decodeCursor(nil, binding); err != nil || got != nil {
+		t.Errorf("expected nil state, got %v, %v", got, err)
+	}
+
+	// Cursors for a different query are rejected.
+	if _, err := s.decodeCursor(cursor, []byte("other")); !errors.Is(err, errInvalidCursor) {
+		t.Errorf("expected %v to be %v", err, errInvalidCursor)
+	}
+
+	// Modified cursors are rejected.
+	tampered := proto.Clone(cursor).(*federationv2.Cursor)
+	tampered.State[len(tampered.State)-1] ^= 0xff
+	if _, err := s.decodeCursor(tampered, binding); !errors.Is(err, errInvalidCursor) {
+		t.Errorf("expected %v to be %v", err, errInvalidCursor)
+	}
+}
+
+func TestCursorBinding(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	a := cursorBinding(ctx, &federationv2.FetchRequest{IncludeRegions: []string{"us", "CA"}})
+	b := cursorBinding(ctx, &federationv2.FetchRequest{IncludeRegions: []string{"CA", "US"}})
+	if !bytes.Equal(a, b) {
+		t.Errorf("expected region order and case to be ignored")
+	}
+
+	c := cursorBinding(ctx, &federationv2.FetchRequest{IncludeRegions: []string{"CA", "US"}, OnlyTravelers: true})
+	if bytes.Equal(a, c) {
+		t.Errorf("expected filters to change the binding")
+	}
+
+	authCtx := context.WithValue(ctx, authKey{}, &fedmodel.FederationOutAuthorization{Issuer: "iss", Subject: "sub"})
+	d := cursorBinding(authCtx, &federationv2.FetchRequest{IncludeRegions: []string{"CA", "US"}})
+	if bytes.Equal(a, d) {
+		t.Errorf("expected caller to change the binding")
+	}
+}
+
+func TestServerV2_Fetch(t *testing.T) {
+	t.Parallel()
+
+	keys := func(n int) []*federation.ExposureKey {
+		out := make([]*federation.ExposureKey, 0, n)
+		for i := 0; i < n; i++ {
+			out = append(out, &federation.ExposureKey{
+				ExposureKey:    []byte{byte(i)},
+				IntervalNumber: int32(i),
+				IntervalCount:  144,
+				ReportType:     federation.ExposureKey_CONFIRMED_TEST,
+				Regions:        []string{"US"},
+			})
+		}
+		return out
+	}
+
+	nextState := &federation.FetchState{
+		KeyCursor:        &federation.Cursor{Timestamp: 200, NextToken: "next"},
+		RevisedKeyCursor: &federation.Cursor{Timestamp: 100},
+	}
+
+	for _, compression := range []federationv2.Compression{
+		federationv2.Compression_COMPRESSION_NONE,
+		federationv2.Compression_COMPRESSION_GZIP,
+	} {
+		compression := compression
+
+		t.Run(compression.String(), func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			s := testServerV2(t)
+
+			var gotStates []*federation.FetchState
+			fetch := func(_ context.Context, req *federation.FederationFetchRequest) (*federation.FederationFetchResponse, error) {
+				gotStates = append(gotStates, req.State)
+				return &federation.FederationFetchResponse{
+					Keys:            keys(5),
+					RevisedKeys:     keys(2),
+					PartialResponse: true,
+					NextFetchState:  nextState,
+				}, nil
+			}
+
+			var chunks []*federationv2.FetchChunk
+			send := func(c *federationv2.FetchChunk) error {
+				chunks = append(chunks, c)
+				return nil
+			}
+
+			req := &federationv2.FetchRequest{
+				Version:        protocolVersion2,
+				IncludeRegions: []string{"US"},
+				MaxChunkKeys:   10, // capped to the server's 3
+				Compression:    compression,
+			}
+			if err := s.fetch(ctx, req, send, fetch); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := len(chunks), 3; got != want {
+				t.Fatalf("expected %d chunks to be %d", got, want)
+			}
+			var primary, revised int
+			for i, chunk := range chunks {
+				if got, want := chunk.Sequence, uint32(i); got != want {
+					t.Errorf("expected sequence %d to be %d", got, want)
+				}
+				if got, want := chunk.Final, i == len(chunks)-1; got != want {
+					t.Errorf("chunk %d: expected final %t to be %t", i, got, want)
+				}
+
+				batch := chunk.Batch
+				if compression == federationv2.Compression_COMPRESSION_GZIP {
+					batch = decompressTestBatch(t, chunk.CompressedBatch)
+				}
+				if n := len(batch.Keys) + len(batch.RevisedKeys); n > 3 {
+					t.Errorf("chunk %d: expected at most 3 keys, got %d", i, n)
+				}
+				primary += len(batch.Keys)
+				revised += len(batch.RevisedKeys)
+			}
+			if primary != 5 || revised != 2 {
+				t.Errorf("expected 5 primary and 2 revised keys, got %d and %d", primary, revised)
+			}
+
+			last := chunks[len(chunks)-1]
+			if !last.PartialResponse {
+				t.Errorf("expected partial response on final chunk")
+			}
+
+			// The cursor continues from the returned state.
+			req.Cursor = last.NextCursor
+			chunks = nil
+			if err := s.fetch(ctx, req, send, fetch); err != nil {
+				t.Fatal(err)
+			}
+			if gotStates[0] != nil {
+				t.Errorf("expected first fetch to start from the beginning, got %v", gotStates[0])
+			}
+			if diff := cmp.Diff(nextState, gotStates[1], protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+
+			// The cursor can't be reused for a different query.
+			req.IncludeRegions = []string{"CA"}
+			err := s.fetch(ctx, req, send, fetch)
+			if got, want := status.Code(err), codes.InvalidArgument; got != want {
+				t.Errorf("expected code %v to be %v", got, want)
+			}
+		})
+	}
+}
+
+func TestServerV2_Fetch_Empty(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	s := testServerV2(t)
+
+	fetch := func(_ context.Context, _ *federation.FederationFetchRequest) (*federation.FederationFetchResponse, error) {
+		return &federation.FederationFetchResponse{NextFetchState: &federation.FetchState{}}, nil
+	}
+
+	var chunks []*federationv2.FetchChunk
+	send := func(c *federationv2.FetchChunk) error {
+		chunks = append(chunks, c)
+		return nil
+	}
+
+	if err := s.fetch(ctx, &federationv2.FetchRequest{Version: protocolVersion2}, send, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(chunks), 1; got != want {
+		t.Fatalf("expected %d chunks to be %d", got, want)
+	}
+	if !chunks[0].Final || chunks[0].NextCursor == nil {
+		t.Errorf("expected a final chunk with a cursor, got %v", chunks[0])
+	}
+}
+
+func TestServerV2_Fetch_Version(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	s := testServerV2(t)
+
+	fetch := func(_ context.Context, _ *federation.FederationFetchRequest) (*federation.FederationFetchResponse, error) {
+		t.Fatal("fetch should not be called")
+		return nil, nil
+	}
+	send := func(_ *federationv2.FetchChunk) error { return nil }
+
+	err := s.fetch(ctx, &federationv2.FetchRequest{Version: 1}, send, fetch)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("expected code %v to be %v", got, want)
+	}
+	if got, want := errcode.Of(err), errcode.UnsupportedProtocolVersion; got != want {
+		t.Errorf("expected error code %q to be %q", got, want)
+	}
+}
+
+func decompressTestBatch(t *testing.T, b []byte) *federationv2.KeyBatch {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batch federationv2.KeyBatch
+	if err := proto.Unmarshal(raw, &batch); err != nil {
+		t.Fatal(err)
+	}
+	return &batch
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Callers are the clients allowed to call the generator.
+type Callers []*Caller
+
+// Caller is a client of the generator.
+type Caller struct {
+	// Name identifies the caller in logs and metrics.
+	Name string `json:"name"`
+
+	// Token is the bearer token of the caller.
+	Token string `json:"token"`
+
+	// Regions are the regions the caller may generate data for. If empty, any
+	// region is allowed.
+	Regions []string `json:"regions,omitempty"`
+
+	// RequestsPerHour is the number of requests the caller may make per hour on
+	// each instance. If 0, requests are not limited.
+	RequestsPerHour float64 `json:"requestsPerHour,omitempty"`
+
+	limiter *rate.Limiter
+}
+
+// EnvDecode implements envconfig.Decoder to decode the callers from a JSON
+// array. The value may come from secret://.
+func (c *Callers) EnvDecode(val string) error {
+	var callers Callers
+	if err := json.Unmarshal([]byte(val), &callers); err != nil {
+		return fmt.Errorf("failed to parse callers: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(callers))
+	for i, caller := range callers {
+		if caller.Name == "" {
+			return fmt.Errorf("caller %d: name is required", i)
+		}
+		if caller.Token == "" {
+			return fmt.Errorf("caller %q: token is required", caller.Name)
+		}
+		if _, ok := seen[caller.Name]; ok {
+			return fmt.Errorf("caller %q is listed more than once", caller.Name)
+		}
+		seen[caller.Name] = struct{}{}
+		if caller.RequestsPerHour < 0 {
+			return fmt.Errorf("caller %q: requestsPerHour cannot be negative", caller.Name)
+		}
+	}
+
+	*c = callers
+	return nil
+}
+
+// newLimiters creates the per-caller quota limiters.
+func (c Callers) newLimiters() {
+	for _, caller := range c {
+		caller.limiter = rate.NewLimiter(rate.Inf, 0)
+		if caller.RequestsPerHour > 0 {
+			burst := int(caller.RequestsPerHour)
+			if burst < 1 {
+				burst = 1
+			}
+			caller.limiter = rate.NewLimiter(rate.Limit(caller.RequestsPerHour/time.Hour.Seconds()), burst)
+		}
+	}
+}
+
+// authenticate returns the caller whose token is the bearer token of the
+// request, or nil if there is none.
+func (c Callers) authenticate(r *http.Request) *Caller {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+
+	// Compare with every token, so the time taken does not depend on which
+	// one matched.
+	var match *Caller
+	for _, caller := range c {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(caller.Token)) == 1 {
+			match = caller
+		}
+	}
+	return match
+}
+
+// disallowedRegion returns the first of the regions the caller may not generate
+// data for, or "" if all are allowed.
+func (c *Caller) disallowedRegion(regions []string) string {
+	if len(c.Regions) == 0 {
+		return ""
+	}
+	for _, r := range regions {
+		allowed := false
+		for _, a := range c.Regions {
+			if strings.EqualFold(r, a) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return r
+		}
+	}
+	return ""
+}
+
+// allow reports whether the request is within the caller's quota.
+func (c *Caller) allow() bool {
+	return c.limiter.Allow()
+}
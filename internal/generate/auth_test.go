@@ -0,0 +1,223 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/pkg/render"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCallers_EnvDecode(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		val  string
+		want Callers
+		err  bool
+	}{
+		{
+			name: "valid",
+			val:  `[{"name":"ios","token":"t1","regions":["US"],"requestsPerHour":10},{"name":"android","token":"t2"}]`,
+			want: Callers{
+				{Name: "ios", Token: "t1", Regions: []string{"US"}, RequestsPerHour: 10},
+				{Name: "android", Token: "t2"},
+			},
+		},
+		{
+			name: "not_json",
+			val:  `ios:t1`,
+			err:  true,
+		},
+		{
+			name: "missing_name",
+			val:  `[{"token":"t1"}]`,
+			err:  true,
+		},
+		{
+			name: "missing_token",
+			val:  `[{"name":"ios"}]`,
+			err:  true,
+		},
+		{
+			name: "duplicate",
+			val:  `[{"name":"ios","token":"t1"},{"name":"ios","token":"t2"}]`,
+			err:  true,
+		},
+		{
+			name: "negative_quota",
+			val:  `[{"name":"ios","token":"t1","requestsPerHour":-1}]`,
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got Callers
+			err := got.EnvDecode(tc.val)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error: %v, got %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(Caller{})); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCallers_authenticate(t *testing.T) {
+	t.Parallel()
+
+	callers := Callers{
+		{Name: "ios", Token: "t1"},
+		{Name: "android", Token: "t2"},
+	}
+
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{header: "Bearer t1", want: "ios"},
+		{header: "Bearer t2", want: "android"},
+		{header: "Bearer t3"},
+		{header: "t1"},
+		{header: ""},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+
+		var got string
+		if caller := callers.authenticate(r); caller != nil {
+			got = caller.Name
+		}
+		if got != tc.want {
+			t.Errorf("authenticate(%q): expected %q, got %q", tc.header, tc.want, got)
+		}
+	}
+}
+
+func TestCaller_disallowedRegion(t *testing.T) {
+	t.Parallel()
+
+	open := &Caller{Name: "open"}
+	if got := open.disallowedRegion([]string{"US", "CA"}); got != "" {
+		t.Errorf("expected all regions to be allowed, got %q", got)
+	}
+
+	limited := &Caller{Name: "limited", Regions: []string{"US"}}
+	if got := limited.disallowedRegion([]string{"us"}); got != "" {
+		t.Errorf("expected region to be allowed, got %q", got)
+	}
+	if got, want := limited.disallowedRegion([]string{"US", "CA"}), "CA"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCaller_allow(t *testing.T) {
+	t.Parallel()
+
+	callers := Callers{
+		{Name: "unlimited"},
+		{Name: "limited", RequestsPerHour: 2},
+	}
+	callers.newLimiters()
+
+	for i := 0; i < 10; i++ {
+		if !callers[0].allow() {
+			t.Fatalf("expected unlimited caller to be allowed")
+		}
+	}
+
+	if !callers[1].allow() || !callers[1].allow() {
+		t.Fatalf("expected the first requests to be allowed")
+	}
+	if callers[1].allow() {
+		t.Errorf("expected the quota to be exceeded")
+	}
+}
+
+func TestServer_handleGenerateDenied(t *testing.T) {
+	t.Parallel()
+
+	callers := Callers{
+		{Name: "ios", Token: "t1", Regions: []string{"US"}},
+	}
+	callers.newLimiters()
+
+	srv := &Server{
+		config: &Config{
+			Callers:       callers,
+			DefaultRegion: "US",
+		},
+		h: render.NewRenderer(),
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		code   int
+	}{
+		{
+			name: "no_token",
+			path: "/",
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong_token",
+			path:   "/",
+			header: "Bearer t2",
+			code:   http.StatusUnauthorized,
+		},
+		{
+			name:   "region",
+			path:   "/?region=US,CA",
+			header: "Bearer t1",
+			code:   http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			srv.handleGenerate().ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.code; got != want {
+				t.Errorf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+		})
+	}
+}
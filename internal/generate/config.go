@@ -18,6 +18,7 @@ package generate
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -39,6 +40,7 @@ type Config struct {
 	Database              database.Config
 	SecretManager         secrets.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
 
 	Port                         string        `env:"PORT, default=8080"`
 	NumExposures                 int           `env:"NUM_EXPOSURES_GENERATED, default=10"`
@@ -56,6 +58,58 @@ type Config struct {
 	KeyRevisionDelay             time.Duration `env:"KEY_REVISION_DELAY, default=2h"`     // key revision will be forward dates this amount.
 	SymptomOnsetDaysAgo          uint          `env:"DEFAULT_SYMPTOM_ONSET_DAYS_AGO, default=4"`
 	ForceConfirmed               bool          `env:"FORCE_CONFIRMED, default=false"` // force report type to be confirmed for all exposures
+
+	// MaxScenarioUploads is the maximum number of uploads in a scenario.
+	MaxScenarioUploads int `env:"MAX_SCENARIO_UPLOADS, default=10000"`
+
+	// Callers are the clients allowed to call the generator, as a JSON array of
+	// objects with a name, a bearer token, and optionally the regions they may
+	// generate data for and their requestsPerHour. If empty, the generator
+	// accepts all requests.
+	Callers Callers `env:"GENERATE_CALLERS"`
+
+	// EpidemicCurve configures simulation of case counts over time. When
+	// disabled, NumExposures are generated on every invocation.
+	EpidemicCurve EpidemicCurveConfig
+}
+
+// EpidemicCurveConfig describes a simulated epidemic. Daily case counts follow
+// a (optionally repeating) Gaussian wave on top of a baseline, with fewer
+// reports on weekends. Each invocation generates the share of the day's cases
+// that corresponds to InvocationInterval.
+type EpidemicCurveConfig struct {
+	Enabled bool `env:"EPIDEMIC_CURVE_ENABLED, default=false"`
+
+	// StartDate is the first day of the epidemic, in YYYY-MM-DD format.
+	StartDate string `env:"EPIDEMIC_CURVE_START_DATE, default=2020-03-01"`
+
+	// BaselineCases is the number of daily cases outside of a wave and
+	// PeakCases is the number of additional daily cases at the peak of a wave.
+	BaselineCases float64 `env:"EPIDEMIC_CURVE_BASELINE_CASES, default=20"`
+	PeakCases     float64 `env:"EPIDEMIC_CURVE_PEAK_CASES, default=500"`
+
+	// PeakAfter is the time from the start of a wave to its peak and Width is
+	// the standard deviation of the wave. If Period is positive, the wave
+	// repeats with that period.
+	PeakAfter time.Duration `env:"EPIDEMIC_CURVE_PEAK_AFTER, default=720h"`
+	Width     time.Duration `env:"EPIDEMIC_CURVE_WIDTH, default=240h"`
+	Period    time.Duration `env:"EPIDEMIC_CURVE_PERIOD, default=0"`
+
+	// WeekendFactor scales case counts on Saturdays and Sundays to simulate
+	// reduced testing.
+	WeekendFactor float64 `env:"EPIDEMIC_CURVE_WEEKEND_FACTOR, default=0.6"`
+
+	// InvocationInterval is how often the generator is invoked, usually by a
+	// scheduler. It determines the share of the daily cases generated by each
+	// invocation.
+	InvocationInterval time.Duration `env:"EPIDEMIC_CURVE_INVOCATION_INTERVAL, default=1h"`
+
+	// OnsetMeanDays and OnsetStddevDays describe the normal distribution of the
+	// days from symptom onset to upload. ChanceOfAsymptomatic is the chance,
+	// 0-100, that an upload has no symptom onset.
+	OnsetMeanDays        float64 `env:"EPIDEMIC_CURVE_ONSET_MEAN_DAYS, default=4"`
+	OnsetStddevDays      float64 `env:"EPIDEMIC_CURVE_ONSET_STDDEV_DAYS, default=2"`
+	ChanceOfAsymptomatic int     `env:"EPIDEMIC_CURVE_CHANCE_OF_ASYMPTOMATIC, default=30"`
 }
 
 func (c *Config) MaxExposureKeys() uint {
@@ -86,8 +140,12 @@ func (c *Config) DefaultSymptomOnsetDaysAgo() uint {
 	return c.SymptomOnsetDaysAgo
 }
 
-func (c *Config) DebugReleaseSameDayKeys() bool {
-	return false
+func (c *Config) SameDayKeyPolicy() model.SameDayKeyPolicy {
+	return model.SameDayKeyPolicyEmbargo
+}
+
+func (c *Config) SameDayKeyReleaseDelay() time.Duration {
+	return 0
 }
 
 func (c *Config) DatabaseConfig() *database.Config {
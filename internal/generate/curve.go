@@ -0,0 +1,144 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/cryptorand"
+)
+
+// epidemicCurve samples case counts and symptom onsets from a simulated
+// epidemic.
+type epidemicCurve struct {
+	config *EpidemicCurveConfig
+	start  time.Time
+
+	// r is not safe for concurrent use.
+	r     *rand.Rand
+	rLock sync.Mutex
+}
+
+func newEpidemicCurve(cfg *EpidemicCurveConfig) (*epidemicCurve, error) {
+	start, err := time.Parse("2006-01-02", cfg.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse EPIDEMIC_CURVE_START_DATE: %w", err)
+	}
+	if cfg.BaselineCases < 0 || cfg.PeakCases < 0 {
+		return nil, fmt.Errorf("epidemic curve case counts must be >= 0")
+	}
+	if cfg.Width <= 0 {
+		return nil, fmt.Errorf("EPIDEMIC_CURVE_WIDTH must be > 0, got %s", cfg.Width)
+	}
+	if cfg.Period < 0 {
+		return nil, fmt.Errorf("EPIDEMIC_CURVE_PERIOD must be >= 0, got %s", cfg.Period)
+	}
+	if cfg.WeekendFactor < 0 {
+		return nil, fmt.Errorf("EPIDEMIC_CURVE_WEEKEND_FACTOR must be >= 0, got %v", cfg.WeekendFactor)
+	}
+	if cfg.InvocationInterval <= 0 {
+		return nil, fmt.Errorf("EPIDEMIC_CURVE_INVOCATION_INTERVAL must be > 0, got %s", cfg.InvocationInterval)
+	}
+	if cfg.OnsetMeanDays < 0 || cfg.OnsetStddevDays < 0 {
+		return nil, fmt.Errorf("epidemic curve onset distribution must be >= 0")
+	}
+	if c := cfg.ChanceOfAsymptomatic; c < 0 || c > 100 {
+		return nil, fmt.Errorf("EPIDEMIC_CURVE_CHANCE_OF_ASYMPTOMATIC must be between 0 and 100, got %d", c)
+	}
+
+	//nolint:gosec // cryptorand.NewSource is a random source
+	r := rand.New(cryptorand.NewSource())
+
+	return &epidemicCurve{
+		config: cfg,
+		start:  start,
+		r:      r,
+	}, nil
+}
+
+// dailyCases returns the expected number of cases on the day of t.
+func (c *epidemicCurve) dailyCases(t time.Time) float64 {
+	elapsed := t.Sub(c.start)
+	if elapsed < 0 {
+		return 0
+	}
+	if c.config.Period > 0 {
+		elapsed %= c.config.Period
+	}
+
+	z := float64(elapsed-c.config.PeakAfter) / float64(c.config.Width)
+	cases := c.config.BaselineCases + c.config.PeakCases*math.Exp(-z*z/2)
+
+	if wd := t.UTC().Weekday(); wd == time.Saturday || wd == time.Sunday {
+		cases *= c.config.WeekendFactor
+	}
+	return cases
+}
+
+// exposures returns the number of exposures to generate in an invocation at
+// time t. The count is Poisson distributed around the invocation's share of
+// the day's cases.
+func (c *epidemicCurve) exposures(t time.Time) int {
+	mean := c.dailyCases(t) * float64(c.config.InvocationInterval) / float64(24*time.Hour)
+	return c.poisson(mean)
+}
+
+// onsetDaysAgo returns the number of days between symptom onset and upload,
+// or false if the upload is asymptomatic.
+func (c *epidemicCurve) onsetDaysAgo() (int, bool) {
+	c.rLock.Lock()
+	defer c.rLock.Unlock()
+
+	if c.r.Intn(100) < c.config.ChanceOfAsymptomatic {
+		return 0, false
+	}
+
+	days := math.Round(c.config.OnsetMeanDays + c.config.OnsetStddevDays*c.r.NormFloat64())
+	if days < 0 {
+		days = 0
+	}
+	return int(days), true
+}
+
+func (c *epidemicCurve) poisson(mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+
+	c.rLock.Lock()
+	defer c.rLock.Unlock()
+
+	// For large means, the normal approximation is accurate and avoids a long
+	// loop.
+	if mean > 30 {
+		n := math.Round(mean + math.Sqrt(mean)*c.r.NormFloat64())
+		if n < 0 {
+			return 0
+		}
+		return int(n)
+	}
+
+	// Knuth's algorithm.
+	l := math.Exp(-mean)
+	k := 0
+	for p := c.r.Float64(); p > l; p *= c.r.Float64() {
+		k++
+	}
+	return k
+}
@@ -0,0 +1,217 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCurveConfig() *EpidemicCurveConfig {
+	return &EpidemicCurveConfig{
+		Enabled:              true,
+		StartDate:            "2020-03-02", // a Monday
+		BaselineCases:        10,
+		PeakCases:            100,
+		PeakAfter:            14 * 24 * time.Hour,
+		Width:                3 * 24 * time.Hour,
+		WeekendFactor:        0.5,
+		InvocationInterval:   24 * time.Hour,
+		OnsetMeanDays:        4,
+		OnsetStddevDays:      2,
+		ChanceOfAsymptomatic: 0,
+	}
+}
+
+func TestNewEpidemicCurve(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		fn   func(c *EpidemicCurveConfig)
+		err  string
+	}{
+		{
+			name: "valid",
+			fn:   func(c *EpidemicCurveConfig) {},
+		},
+		{
+			name: "bad_start_date",
+			fn:   func(c *EpidemicCurveConfig) { c.StartDate = "yesterday" },
+			err:  "EPIDEMIC_CURVE_START_DATE",
+		},
+		{
+			name: "negative_cases",
+			fn:   func(c *EpidemicCurveConfig) { c.PeakCases = -1 },
+			err:  "case counts",
+		},
+		{
+			name: "zero_width",
+			fn:   func(c *EpidemicCurveConfig) { c.Width = 0 },
+			err:  "EPIDEMIC_CURVE_WIDTH",
+		},
+		{
+			name: "zero_invocation_interval",
+			fn:   func(c *EpidemicCurveConfig) { c.InvocationInterval = 0 },
+			err:  "EPIDEMIC_CURVE_INVOCATION_INTERVAL",
+		},
+		{
+			name: "bad_chance",
+			fn:   func(c *EpidemicCurveConfig) { c.ChanceOfAsymptomatic = 101 },
+			err:  "EPIDEMIC_CURVE_CHANCE_OF_ASYMPTOMATIC",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testCurveConfig()
+			tc.fn(cfg)
+
+			_, err := newEpidemicCurve(cfg)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestEpidemicCurve_dailyCases(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2020, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		period time.Duration
+		t      time.Time
+		want   float64
+	}{
+		{
+			name: "before_start",
+			t:    start.Add(-48 * time.Hour),
+			want: 0,
+		},
+		{
+			name: "peak",
+			t:    start.Add(14 * 24 * time.Hour).Add(-12 * time.Hour),
+			want: 110,
+		},
+		{
+			name: "long_after_peak",
+			t:    start.Add(70 * 24 * time.Hour),
+			want: 10,
+		},
+		{
+			name: "weekend",
+			t:    start.Add(75 * 24 * time.Hour), // a Saturday
+			want: 5,
+		},
+		{
+			name:   "repeating_peak",
+			period: 28 * 24 * time.Hour,
+			t:      start.Add(42 * 24 * time.Hour).Add(-12 * time.Hour),
+			want:   110,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testCurveConfig()
+			cfg.Period = tc.period
+			curve, err := newEpidemicCurve(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := curve.dailyCases(tc.t); math.Abs(got-tc.want) > 0.01 {
+				t.Errorf("expected %v to be %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEpidemicCurve_exposures(t *testing.T) {
+	t.Parallel()
+
+	cfg := testCurveConfig()
+	cfg.PeakCases = 0
+	curve, err := newEpidemicCurve(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A Monday, long after the start, so the expected count is the baseline.
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	const samples = 2000
+	total := 0
+	for i := 0; i < samples; i++ {
+		total += curve.exposures(now)
+	}
+	if mean := float64(total) / samples; math.Abs(mean-cfg.BaselineCases) > 1 {
+		t.Errorf("expected mean exposures %v to be close to %v", mean, cfg.BaselineCases)
+	}
+
+	// The normal approximation is used for large means.
+	cfg.BaselineCases = 1000
+	total = 0
+	for i := 0; i < samples; i++ {
+		total += curve.exposures(now)
+	}
+	if mean := float64(total) / samples; math.Abs(mean-cfg.BaselineCases) > 5 {
+		t.Errorf("expected mean exposures %v to be close to %v", mean, cfg.BaselineCases)
+	}
+}
+
+func TestEpidemicCurve_onsetDaysAgo(t *testing.T) {
+	t.Parallel()
+
+	cfg := testCurveConfig()
+	curve, err := newEpidemicCurve(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		days, ok := curve.onsetDaysAgo()
+		if !ok {
+			t.Fatalf("expected symptomatic upload")
+		}
+		if days < 0 {
+			t.Fatalf("expected onset days %d to be >= 0", days)
+		}
+	}
+
+	cfg.ChanceOfAsymptomatic = 100
+	if _, ok := curve.onsetDaysAgo(); ok {
+		t.Errorf("expected asymptomatic upload")
+	}
+}
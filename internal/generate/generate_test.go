@@ -130,6 +130,7 @@ func TestServer_handleGenerate(t *testing.T) {
 		regions        []string
 		sameDayRelease bool
 		reviseKeys     bool
+		epidemicCurve  bool
 	}{
 		{
 			name: "not_enough_keys",
@@ -143,6 +144,11 @@ func TestServer_handleGenerate(t *testing.T) {
 			name:       "revised_keys",
 			reviseKeys: true,
 		},
+		{
+			name:          "epidemic_curve",
+			regions:       []string{"US"},
+			epidemicCurve: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -159,6 +165,15 @@ func TestServer_handleGenerate(t *testing.T) {
 			if tc.reviseKeys {
 				srv.config.ChanceOfKeyRevision = 100
 			}
+			if tc.epidemicCurve {
+				cfg := testCurveConfig()
+				cfg.InvocationInterval = time.Hour
+				curve, err := newEpidemicCurve(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				srv.curve = curve
+			}
 
 			if err := srv.generate(ctx, tc.regions); err != nil {
 				if tc.err == "" {
@@ -31,6 +31,7 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/timeutils"
 	"github.com/google/exposure-notifications-server/pkg/util"
 	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 )
 
 func (s *Server) handleGenerate() http.Handler {
@@ -47,6 +48,10 @@ func (s *Server) handleGenerate() http.Handler {
 		}
 		regions := strings.Split(regionStr, ",")
 
+		if !s.authorize(ctx, w, r, regions) {
+			return
+		}
+
 		if err := s.generate(ctx, regions); err != nil {
 			logger.Errorw("generate", "error", err, "regions", regions)
 			s.h.RenderJSON(w, http.StatusInternalServerError, err)
@@ -58,6 +63,43 @@ func (s *Server) handleGenerate() http.Handler {
 	})
 }
 
+// authorize checks the caller of the request if callers are configured, and
+// responds with an error if it may not generate data for the regions.
+func (s *Server) authorize(ctx context.Context, w http.ResponseWriter, r *http.Request, regions []string) bool {
+	if len(s.config.Callers) == 0 {
+		return true
+	}
+	logger := logging.FromContext(ctx).Named("authorize")
+
+	caller := s.config.Callers.authenticate(r)
+	if caller == nil {
+		s.deny(ctx, w, http.StatusUnauthorized, "unauthenticated", fmt.Errorf("missing or invalid bearer token"))
+		return false
+	}
+	logger = logger.With("caller", caller.Name)
+
+	if region := caller.disallowedRegion(regions); region != "" {
+		logger.Warnw("region not allowed", "region", region)
+		s.deny(ctx, w, http.StatusForbidden, "region", fmt.Errorf("caller %q may not generate data for region %q", caller.Name, region))
+		return false
+	}
+
+	if !caller.allow() {
+		logger.Warnw("quota exceeded")
+		s.deny(ctx, w, http.StatusTooManyRequests, "quota", fmt.Errorf("caller %q exceeded its quota", caller.Name))
+		return false
+	}
+	return true
+}
+
+// deny records a request denied for the given reason and responds with the
+// error.
+func (s *Server) deny(ctx context.Context, w http.ResponseWriter, code int, reason string, err error) {
+	ctx, _ = tag.New(ctx, tag.Upsert(reasonTag, reason))
+	stats.Record(ctx, mDenied.M(1))
+	s.h.RenderJSON(w, code, err)
+}
+
 func (s *Server) generate(ctx context.Context, regions []string) error {
 	for _, r := range regions {
 		if err := s.generateKeysInRegion(ctx, r); err != nil {
@@ -80,13 +122,6 @@ func (s *Server) generateKeysInRegion(ctx context.Context, region string) error
 	// API calls treat region as a list, for legacy regions.
 	regions := []string{region}
 
-	traveler := false
-	if val, err := util.RandomInt(100); err != nil {
-		return fmt.Errorf("failed to determien traveler status: %w", err)
-	} else if val < s.config.ChanceOfTraveler {
-		traveler = true
-	}
-
 	now := time.Now().UTC()
 	// Find the valid intervals - starting with today and working backwards
 	minInterval := publishmodel.IntervalNumber(timeutils.UTCMidnight(now.Add(-1 * s.config.MaxIntervalAge).Add(24 * time.Hour)))
@@ -97,9 +132,22 @@ func (s *Server) generateKeysInRegion(ctx context.Context, region string) error
 		curInterval -= verifyapi.MaxIntervalCount
 	}
 
+	numExposures := s.config.NumExposures
+	if s.curve != nil {
+		numExposures = s.curve.exposures(now)
+		logger.Debugw("sampled epidemic curve", "daily_cases", s.curve.dailyCases(now), "exposures", numExposures)
+	}
+
 	batchTime := now
-	for i := 0; i < s.config.NumExposures; i++ {
-		logger.Debugf("generating exposure %d of %d", i+1, s.config.NumExposures)
+	for i := 0; i < numExposures; i++ {
+		logger.Debugf("generating exposure %d of %d", i+1, numExposures)
+
+		traveler := false
+		if val, err := util.RandomInt(100); err != nil {
+			return fmt.Errorf("failed to determien traveler status: %w", err)
+		} else if val < s.config.ChanceOfTraveler {
+			traveler = true
+		}
 
 		exposures, err := util.GenerateExposuresForIntervals(intervals)
 		if err != nil {
@@ -154,6 +202,16 @@ func (s *Server) generateKeysInRegion(ctx context.Context, region string) error
 			SymptomOnsetInterval: uint32(publish.Keys[intervalIdx].IntervalNumber),
 		}
 
+		// With an epidemic curve, the symptom onset follows the configured
+		// distribution instead. Asymptomatic uploads have no onset.
+		if s.curve != nil {
+			claims.SymptomOnsetInterval = 0
+			if days, ok := s.curve.onsetDaysAgo(); ok {
+				onset := timeutils.UTCMidnight(now).Add(time.Duration(-days) * 24 * time.Hour)
+				claims.SymptomOnsetInterval = uint32(publishmodel.IntervalNumber(onset))
+			}
+		}
+
 		result, err := s.transformer.TransformPublish(ctx, publish, regions, &claims, batchTime)
 		if err != nil {
 			return fmt.Errorf("failed to transform generated exposures: %w", err)
@@ -19,11 +19,19 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/observability"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 )
 
 const metricPrefix = metrics.MetricRoot + "generate"
 
-var mSuccess = stats.Int64(metricPrefix+"/success", "successful execution", stats.UnitDimensionless)
+var (
+	mSuccess = stats.Int64(metricPrefix+"/success", "successful execution", stats.UnitDimensionless)
+	mDenied  = stats.Int64(metricPrefix+"/denied", "requests denied by caller authentication", stats.UnitDimensionless)
+
+	mScenarioUploads = stats.Int64(metricPrefix+"/scenario_uploads", "uploads written by scenarios", stats.UnitDimensionless)
+
+	reasonTag = tag.MustNewKey("reason")
+)
 
 func init() {
 	observability.CollectViews([]*view.View{
@@ -33,5 +41,18 @@ func init() {
 			Measure:     mSuccess,
 			Aggregation: view.Count(),
 		},
+		{
+			Name:        metricPrefix + "/denied",
+			Description: "Number of requests denied by caller authentication",
+			Measure:     mDenied,
+			TagKeys:     []tag.Key{reasonTag},
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/scenario_uploads",
+			Description: "Number of uploads written by scenarios",
+			Measure:     mScenarioUploads,
+			Aggregation: view.Sum(),
+		},
 	}...)
 }
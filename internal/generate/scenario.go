@@ -0,0 +1,386 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	"github.com/google/exposure-notifications-server/internal/pb"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/verification"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+	"go.opencensus.io/stats"
+)
+
+// Scenario is a scripted series of uploads over simulated days. The same
+// scenario and seed always generate the same keys, report types, and revisions,
+// so clients can test risk scoring against a known ground truth.
+type Scenario struct {
+	// Seed seeds all random choices of the scenario.
+	Seed int64 `json:"seed"`
+
+	// StartDate is the first simulated day, in YYYY-MM-DD format. By default
+	// the scenario, including its revisions, ends yesterday. All uploads and
+	// revisions must be in the past.
+	StartDate string `json:"startDate,omitempty"`
+
+	// Regions are the regions of all uploads. By default, DEFAULT_REGION.
+	Regions []string `json:"regions,omitempty"`
+
+	// KeysPerUpload is the number of daily keys in each upload. By default,
+	// KEYS_PER_EXPOSURE.
+	KeysPerUpload int `json:"keysPerUpload,omitempty"`
+
+	// Days are the simulated days, starting at StartDate.
+	Days []*ScenarioDay `json:"days"`
+}
+
+// ScenarioDay describes the uploads of a simulated day. They are spread evenly
+// over the day.
+type ScenarioDay struct {
+	// Uploads is the number of uploads on the day.
+	Uploads int `json:"uploads"`
+
+	// ReportTypes weighs the report types of the uploads, e.g.
+	// {"confirmed": 3, "likely": 1}. By default all uploads are confirmed.
+	ReportTypes map[string]float64 `json:"reportTypes,omitempty"`
+
+	// TravelerShare is the share, from 0 to 1, of uploads from travelers.
+	TravelerShare float64 `json:"travelerShare,omitempty"`
+
+	// SymptomOnsetDaysAgo is the number of days from symptom onset to upload.
+	// If not set, the uploads have no symptom onset.
+	SymptomOnsetDaysAgo *int `json:"symptomOnsetDaysAgo,omitempty"`
+
+	// Revision revises a share of the day's likely uploads.
+	Revision *ScenarioRevision `json:"revision,omitempty"`
+}
+
+// ScenarioRevision schedules revisions of likely uploads.
+type ScenarioRevision struct {
+	// Share is the share, from 0 to 1, of likely uploads that are revised.
+	Share float64 `json:"share"`
+
+	// AfterDays is the number of days from upload to revision.
+	AfterDays int `json:"afterDays"`
+
+	// ReportType is the revised report type, confirmed or negative. By
+	// default, confirmed.
+	ReportType string `json:"reportType,omitempty"`
+}
+
+// ScenarioResult is the ground truth of a scenario: every upload as it was
+// written, in the order in which it was uploaded.
+type ScenarioResult struct {
+	Uploads []*ScenarioUpload `json:"uploads"`
+}
+
+// ScenarioUpload is an upload of a scenario.
+type ScenarioUpload struct {
+	Day                  int            `json:"day"`
+	UploadedAt           time.Time      `json:"uploadedAt"`
+	Regions              []string       `json:"regions"`
+	Traveler             bool           `json:"traveler,omitempty"`
+	ReportType           string         `json:"reportType"`
+	SymptomOnsetInterval int32          `json:"symptomOnsetInterval,omitempty"`
+	Keys                 []*ScenarioKey `json:"keys"`
+
+	RevisedAt         *time.Time `json:"revisedAt,omitempty"`
+	RevisedReportType string     `json:"revisedReportType,omitempty"`
+}
+
+// ScenarioKey is a key of an upload, with the values that were stored.
+type ScenarioKey struct {
+	Key                   string `json:"key"`
+	IntervalNumber        int32  `json:"intervalNumber"`
+	IntervalCount         int32  `json:"intervalCount"`
+	TransmissionRisk      int    `json:"transmissionRisk"`
+	DaysSinceSymptomOnset *int32 `json:"daysSinceSymptomOnset,omitempty"`
+}
+
+// scenarioEvent is an upload or revision of a planned scenario.
+type scenarioEvent struct {
+	at       time.Time
+	upload   *ScenarioUpload
+	publish  *verifyapi.Publish
+	revision bool
+}
+
+func (s *Server) handleScenario() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		logger := logging.FromContext(ctx).Named("handleScenario")
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		var scenario Scenario
+		if code, err := jsonutil.Unmarshal(w, r, &scenario); err != nil {
+			s.h.RenderJSON(w, code, err)
+			return
+		}
+		if len(scenario.Regions) == 0 {
+			scenario.Regions = []string{s.config.DefaultRegion}
+		}
+
+		if !s.authorize(ctx, w, r, scenario.Regions) {
+			return
+		}
+
+		events, err := s.planScenario(&scenario, time.Now().UTC())
+		if err != nil {
+			s.h.RenderJSON(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := s.runScenario(ctx, events)
+		if err != nil {
+			logger.Errorw("failed to run scenario", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		stats.Record(ctx, mScenarioUploads.M(int64(len(result.Uploads))))
+		s.h.RenderJSON(w, http.StatusOK, result)
+	})
+}
+
+// planScenario validates the scenario and returns its uploads and revisions in
+// the order in which they happen. All random choices are made here, from the
+// scenario's seed.
+func (s *Server) planScenario(sc *Scenario, now time.Time) ([]*scenarioEvent, error) {
+	if len(sc.Days) == 0 {
+		return nil, fmt.Errorf("scenario must have at least one day")
+	}
+
+	keysPerUpload := sc.KeysPerUpload
+	if keysPerUpload == 0 {
+		keysPerUpload = s.config.KeysPerExposure
+	}
+	if keysPerUpload < 1 || keysPerUpload > int(s.config.MaxKeysOnPublish) {
+		return nil, fmt.Errorf("keysPerUpload must be between 1 and %d, got %d", s.config.MaxKeysOnPublish, keysPerUpload)
+	}
+
+	// By default, the last revision is yesterday.
+	lastDay := len(sc.Days)
+	for i, day := range sc.Days {
+		if day.Revision != nil && i+1+day.Revision.AfterDays > lastDay {
+			lastDay = i + 1 + day.Revision.AfterDays
+		}
+	}
+	start := timeutils.UTCMidnight(now).Add(time.Duration(-lastDay) * 24 * time.Hour)
+	if sc.StartDate != "" {
+		var err error
+		start, err = time.Parse("2006-01-02", sc.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse startDate: %w", err)
+		}
+	}
+
+	total := 0
+	for i, day := range sc.Days {
+		if err := day.validate(); err != nil {
+			return nil, fmt.Errorf("day %d: %w", i, err)
+		}
+		total += day.Uploads
+	}
+	if total > s.config.MaxScenarioUploads {
+		return nil, fmt.Errorf("scenario has %d uploads, at most %d are allowed", total, s.config.MaxScenarioUploads)
+	}
+
+	//nolint:gosec // the scenario must be reproducible from its seed
+	r := rand.New(rand.NewSource(sc.Seed))
+
+	var events []*scenarioEvent
+	for i, day := range sc.Days {
+		dayStart := start.Add(time.Duration(i) * 24 * time.Hour)
+		for j := 0; j < day.Uploads; j++ {
+			at := dayStart.Add(time.Duration(2*j+1) * 12 * time.Hour / time.Duration(day.Uploads))
+			upload := &ScenarioUpload{
+				Day:        i,
+				UploadedAt: at,
+				Regions:    sc.Regions,
+				Traveler:   r.Float64() < day.TravelerShare,
+				ReportType: day.reportType(r),
+			}
+			if day.SymptomOnsetDaysAgo != nil {
+				onset := timeutils.UTCMidnight(at).Add(time.Duration(-*day.SymptomOnsetDaysAgo) * 24 * time.Hour)
+				upload.SymptomOnsetInterval = publishmodel.IntervalNumber(onset)
+			}
+
+			publish := &verifyapi.Publish{
+				HealthAuthorityID: "generated.data",
+				Traveler:          upload.Traveler,
+			}
+			interval := publishmodel.IntervalNumber(timeutils.UTCMidnight(at))
+			for k := 0; k < keysPerUpload; k++ {
+				tek := make([]byte, verifyapi.KeyLength)
+				r.Read(tek)
+				publish.Keys = append(publish.Keys, verifyapi.ExposureKey{
+					Key:            base64.StdEncoding.EncodeToString(tek),
+					IntervalNumber: interval,
+					IntervalCount:  verifyapi.MaxIntervalCount,
+				})
+				interval -= verifyapi.MaxIntervalCount
+			}
+
+			events = append(events, &scenarioEvent{at: at, upload: upload, publish: publish})
+
+			if rev := day.Revision; rev != nil && upload.ReportType == verifyapi.ReportTypeClinical && r.Float64() < rev.Share {
+				revisedAt := at.Add(time.Duration(rev.AfterDays) * 24 * time.Hour)
+				upload.RevisedAt = &revisedAt
+				upload.RevisedReportType = verifyapi.ReportTypeConfirmed
+				if rev.ReportType != "" {
+					upload.RevisedReportType = rev.ReportType
+				}
+				events = append(events, &scenarioEvent{at: revisedAt, upload: upload, publish: publish, revision: true})
+			}
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, fmt.Errorf("scenario has no uploads")
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+	if last := events[len(events)-1].at; last.After(now) {
+		return nil, fmt.Errorf("scenario runs until %s, which is in the future", last.Format(time.RFC3339))
+	}
+	return events, nil
+}
+
+// runScenario writes the planned uploads and revisions at their simulated
+// times, and returns the uploads as they were written.
+func (s *Server) runScenario(ctx context.Context, events []*scenarioEvent) (*ScenarioResult, error) {
+	var result ScenarioResult
+	for _, e := range events {
+		claims := verification.VerifiedClaims{
+			ReportType:           e.upload.ReportType,
+			SymptomOnsetInterval: uint32(e.upload.SymptomOnsetInterval),
+		}
+		if e.revision {
+			claims.ReportType = e.upload.RevisedReportType
+		}
+
+		transformed, err := s.transformer.TransformPublish(ctx, e.publish, e.upload.Regions, &claims, e.at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform upload of day %d: %w", e.upload.Day, err)
+		}
+
+		req := &publishdb.InsertAndReviseExposuresRequest{
+			Incoming:     transformed.Exposures,
+			RequireToken: true,
+		}
+		if e.revision {
+			// Bypass revision token enforcement on generated data.
+			var token pb.RevisionTokenData
+			for _, exp := range transformed.Exposures {
+				token.RevisableKeys = append(token.RevisableKeys, &pb.RevisableKey{
+					TemporaryExposureKey: exp.ExposureKey,
+					IntervalNumber:       exp.IntervalNumber,
+					IntervalCount:        exp.IntervalCount,
+				})
+			}
+			req.Token = &token
+		}
+		if _, err := s.database.InsertAndReviseExposures(ctx, req); err != nil {
+			return nil, fmt.Errorf("failed to write upload of day %d: %w", e.upload.Day, err)
+		}
+
+		if e.revision {
+			continue
+		}
+		for _, exp := range transformed.Exposures {
+			e.upload.Keys = append(e.upload.Keys, &ScenarioKey{
+				Key:                   base64.StdEncoding.EncodeToString(exp.ExposureKey),
+				IntervalNumber:        exp.IntervalNumber,
+				IntervalCount:         exp.IntervalCount,
+				TransmissionRisk:      exp.TransmissionRisk,
+				DaysSinceSymptomOnset: exp.DaysSinceSymptomOnset,
+			})
+		}
+		result.Uploads = append(result.Uploads, e.upload)
+	}
+	return &result, nil
+}
+
+// validate checks the day's settings.
+func (d *ScenarioDay) validate() error {
+	if d.Uploads < 0 {
+		return fmt.Errorf("uploads cannot be negative")
+	}
+	for rt, w := range d.ReportTypes {
+		if rt != verifyapi.ReportTypeConfirmed && rt != verifyapi.ReportTypeClinical && rt != verifyapi.ReportTypeNegative {
+			return fmt.Errorf("unsupported report type %q", rt)
+		}
+		if w < 0 {
+			return fmt.Errorf("weight of report type %q cannot be negative", rt)
+		}
+	}
+	if d.TravelerShare < 0 || d.TravelerShare > 1 {
+		return fmt.Errorf("travelerShare must be between 0 and 1")
+	}
+	if d.SymptomOnsetDaysAgo != nil && *d.SymptomOnsetDaysAgo < 0 {
+		return fmt.Errorf("symptomOnsetDaysAgo cannot be negative")
+	}
+	if rev := d.Revision; rev != nil {
+		if rev.Share < 0 || rev.Share > 1 {
+			return fmt.Errorf("revision share must be between 0 and 1")
+		}
+		if rev.AfterDays < 1 {
+			return fmt.Errorf("revision afterDays must be at least 1")
+		}
+		if rt := rev.ReportType; rt != "" && rt != verifyapi.ReportTypeConfirmed && rt != verifyapi.ReportTypeNegative {
+			return fmt.Errorf("unsupported revised report type %q", rt)
+		}
+	}
+	return nil
+}
+
+// reportType picks a report type by weight. The report types are considered
+// in sorted order, so the choice only depends on r.
+func (d *ScenarioDay) reportType(r *rand.Rand) string {
+	types := make([]string, 0, len(d.ReportTypes))
+	total := 0.0
+	for rt, w := range d.ReportTypes {
+		types = append(types, rt)
+		total += w
+	}
+	if total == 0 {
+		return verifyapi.ReportTypeConfirmed
+	}
+	sort.Strings(types)
+
+	pick := r.Float64() * total
+	for _, rt := range types {
+		pick -= d.ReportTypes[rt]
+		if pick < 0 {
+			return rt
+		}
+	}
+	return types[len(types)-1]
+}
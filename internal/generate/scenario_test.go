@@ -0,0 +1,261 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func testScenario() *Scenario {
+	return &Scenario{
+		Seed:    7,
+		Regions: []string{"US"},
+		Days: []*ScenarioDay{
+			{
+				Uploads:             3,
+				SymptomOnsetDaysAgo: intPtr(2),
+			},
+			{
+				Uploads:       4,
+				ReportTypes:   map[string]float64{"confirmed": 1, "likely": 1},
+				TravelerShare: 0.5,
+				Revision: &ScenarioRevision{
+					Share:     1,
+					AfterDays: 1,
+				},
+			},
+		},
+	}
+}
+
+func TestPlanScenario(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{
+		config: &Config{
+			KeysPerExposure:    3,
+			MaxKeysOnPublish:   14,
+			MaxScenarioUploads: 100,
+		},
+	}
+	now := time.Date(2021, 6, 10, 15, 0, 0, 0, time.UTC)
+
+	plan := func(sc *Scenario) []*scenarioEvent {
+		t.Helper()
+		events, err := srv.planScenario(sc, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	first := plan(testScenario())
+	second := plan(testScenario())
+	if diff := cmp.Diff(first, second, cmp.AllowUnexported(scenarioEvent{})); diff != "" {
+		t.Errorf("same seed should give the same plan (-first, +second):\n%s", diff)
+	}
+
+	other := testScenario()
+	other.Seed = 8
+	if first[0].publish.Keys[0].Key == plan(other)[0].publish.Keys[0].Key {
+		t.Errorf("different seeds should give different keys")
+	}
+
+	// The revisions end yesterday by default, and events are in order.
+	if got, want := first[0].at, time.Date(2021, 6, 7, 4, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected first upload at %v, got %v", want, got)
+	}
+	uploads, revisions := 0, 0
+	for i, e := range first {
+		if i > 0 && e.at.Before(first[i-1].at) {
+			t.Errorf("event %d is before the previous event", i)
+		}
+		if e.revision {
+			revisions++
+			if e.upload.ReportType != verifyapi.ReportTypeClinical {
+				t.Errorf("expected only likely uploads to be revised, got %q", e.upload.ReportType)
+			}
+			continue
+		}
+		uploads++
+		if got, want := len(e.publish.Keys), 3; got != want {
+			t.Errorf("expected %d keys, got %d", want, got)
+		}
+	}
+	if uploads != 7 {
+		t.Errorf("expected 7 uploads, got %d", uploads)
+	}
+	if revisions == 0 {
+		t.Errorf("expected likely uploads to be revised")
+	}
+}
+
+func TestPlanScenario_errors(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{
+		config: &Config{
+			KeysPerExposure:    3,
+			MaxKeysOnPublish:   14,
+			MaxScenarioUploads: 5,
+		},
+	}
+	now := time.Date(2021, 6, 10, 15, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		scenario *Scenario
+	}{
+		{
+			name:     "no_days",
+			scenario: &Scenario{},
+		},
+		{
+			name:     "no_uploads",
+			scenario: &Scenario{Days: []*ScenarioDay{{}}},
+		},
+		{
+			name:     "too_many_uploads",
+			scenario: &Scenario{Days: []*ScenarioDay{{Uploads: 3}, {Uploads: 3}}},
+		},
+		{
+			name:     "too_many_keys",
+			scenario: &Scenario{KeysPerUpload: 15, Days: []*ScenarioDay{{Uploads: 1}}},
+		},
+		{
+			name:     "bad_start_date",
+			scenario: &Scenario{StartDate: "June 1", Days: []*ScenarioDay{{Uploads: 1}}},
+		},
+		{
+			name:     "future",
+			scenario: &Scenario{StartDate: "2021-06-11", Days: []*ScenarioDay{{Uploads: 1}}},
+		},
+		{
+			name: "future_revision",
+			scenario: &Scenario{StartDate: "2021-06-09", Days: []*ScenarioDay{{
+				Uploads:     1,
+				ReportTypes: map[string]float64{"likely": 1},
+				Revision:    &ScenarioRevision{Share: 1, AfterDays: 2},
+			}}},
+		},
+		{
+			name: "zero_after_days",
+			scenario: &Scenario{Days: []*ScenarioDay{{
+				Uploads:  1,
+				Revision: &ScenarioRevision{Share: 1, AfterDays: 0},
+			}}},
+		},
+		{
+			name:     "bad_report_type",
+			scenario: &Scenario{Days: []*ScenarioDay{{Uploads: 1, ReportTypes: map[string]float64{"revoked": 1}}}},
+		},
+		{
+			name:     "bad_traveler_share",
+			scenario: &Scenario{Days: []*ScenarioDay{{Uploads: 1, TravelerShare: 2}}},
+		},
+		{
+			name: "bad_revised_report_type",
+			scenario: &Scenario{Days: []*ScenarioDay{{
+				Uploads:  1,
+				Revision: &ScenarioRevision{Share: 1, AfterDays: 1, ReportType: "likely"},
+			}}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := srv.planScenario(tc.scenario, now); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestServer_handleScenario(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	srv := testServer(t)
+	srv.config.MaxScenarioUploads = 100
+	mux := srv.Routes(ctx)
+
+	b, err := json.Marshal(testScenario())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/scenario", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	var result ScenarioResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(result.Uploads), 7; got != want {
+		t.Fatalf("expected %d uploads, got %d", want, got)
+	}
+
+	wantRevised := make(map[string]string)
+	wantKeys := 0
+	for _, u := range result.Uploads {
+		wantKeys += len(u.Keys)
+		for _, k := range u.Keys {
+			wantRevised[k.Key] = u.RevisedReportType
+		}
+	}
+
+	gotKeys := 0
+	if _, err := srv.database.IterateExposures(ctx, publishdb.IterateExposuresCriteria{}, func(e *publishmodel.Exposure) error {
+		gotKeys++
+		var revised string
+		if e.RevisedReportType != nil {
+			revised = *e.RevisedReportType
+		}
+		if want := wantRevised[e.ExposureKeyBase64()]; revised != want {
+			t.Errorf("key %s: expected revised report type %q, got %q", e.ExposureKeyBase64(), want, revised)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if gotKeys != wantKeys {
+		t.Errorf("expected %d keys, got %d", wantKeys, gotKeys)
+	}
+}
@@ -17,8 +17,8 @@ package generate
 import (
 	"context"
 	"fmt"
+	"net/http"
 
-	"github.com/google/exposure-notifications-server/internal/middleware"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
@@ -34,6 +34,9 @@ type Server struct {
 	transformer *publishmodel.Transformer
 	database    *publishdb.PublishDB
 	h           *render.Renderer
+
+	// curve is the simulated epidemic, or nil if disabled.
+	curve *epidemicCurve
 }
 
 func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
@@ -46,12 +49,23 @@ func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
 		return nil, fmt.Errorf("model.NewTransformer: %w", err)
 	}
 
+	var curve *epidemicCurve
+	if cfg.EpidemicCurve.Enabled {
+		curve, err = newEpidemicCurve(&cfg.EpidemicCurve)
+		if err != nil {
+			return nil, fmt.Errorf("invalid epidemic curve: %w", err)
+		}
+	}
+
+	cfg.Callers.newLimiters()
+
 	return &Server{
 		env:         env,
 		transformer: transformer,
 		config:      cfg,
 		database:    publishdb.New(env.Database()),
 		h:           render.NewRenderer(),
+		curve:       curve,
 	}, nil
 }
 
@@ -60,12 +74,10 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 	logger := logging.FromContext(ctx).Named("generate")
 
 	r := mux.NewRouter()
-	r.Use(middleware.Recovery())
-	r.Use(middleware.PopulateRequestID())
-	r.Use(middleware.PopulateObservability())
-	r.Use(middleware.PopulateLogger(logger))
+	s.config.Middleware.Use(r, logger)
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
+	r.Handle("/scenario", s.handleScenario()).Methods(http.MethodPost)
 	r.Handle("/", s.handleGenerate())
 
 	return r
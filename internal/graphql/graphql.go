@@ -0,0 +1,583 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graphql implements a small, read-only subset of GraphQL. It supports
+// queries with aliases, arguments and variables over a schema of object types
+// whose fields are resolved by Go functions. Mutations, fragments, directives
+// and introspection beyond __typename are not supported.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultMaxDepth is the default limit on the nesting of selection sets.
+const DefaultMaxDepth = 10
+
+// Scalar type names. Time values are time.Time and zero times are null, since
+// that is how unset times are usually stored.
+const (
+	String  = "String"
+	Int     = "Int"
+	Float   = "Float"
+	Boolean = "Boolean"
+	ID      = "ID"
+	Time    = "Time"
+)
+
+var scalars = map[string]struct{}{
+	String:  {},
+	Int:     {},
+	Float:   {},
+	Boolean: {},
+	ID:      {},
+	Time:    {},
+}
+
+// ResolveFunc resolves the value of a field from its parent value and
+// arguments.
+type ResolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// Field is a field of an object type.
+type Field struct {
+	// Type is the name of a scalar or object type, or a list of it in square
+	// brackets, like "[HealthAuthority]".
+	Type string
+
+	// Args maps argument names to their scalar types. Types ending in "!" are
+	// required.
+	Args map[string]string
+
+	// Resolve resolves the field. If nil, the exported struct field or map key
+	// matching the field name, ignoring case, is used.
+	Resolve ResolveFunc
+}
+
+// Object is an object type.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Schema is a set of object types with a query root.
+type Schema struct {
+	query    *Object
+	types    map[string]*Object
+	maxDepth int
+}
+
+// NewSchema creates a schema with the given query root and object types. It
+// returns an error if any field refers to an unknown type.
+func NewSchema(query *Object, types ...*Object) (*Schema, error) {
+	s := &Schema{
+		query:    query,
+		types:    make(map[string]*Object, len(types)+1),
+		maxDepth: DefaultMaxDepth,
+	}
+	for _, t := range append([]*Object{query}, types...) {
+		if _, ok := s.types[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate type %q", t.Name)
+		}
+		s.types[t.Name] = t
+	}
+
+	for _, t := range s.types {
+		for name, f := range t.Fields {
+			typ, _ := unwrap(f.Type)
+			if _, ok := scalars[typ]; ok {
+				continue
+			}
+			if _, ok := s.types[typ]; !ok {
+				return nil, fmt.Errorf("%s.%s: unknown type %q", t.Name, name, f.Type)
+			}
+		}
+	}
+	return s, nil
+}
+
+// SetMaxDepth sets the limit on the nesting of selection sets.
+func (s *Schema) SetMaxDepth(depth int) {
+	s.maxDepth = depth
+}
+
+// Request is a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response. Data is nil if the request could not be
+// executed.
+type Response struct {
+	Data   *OrderedMap `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is an error in a response. Path is set for errors resolving a field.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// OrderedMap is a JSON object that keeps the order of its keys, since
+// responses follow the order of the selections in the query.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// Get returns the value of key.
+func (m *OrderedMap) Get(key string) interface{} {
+	return m.values[key]
+}
+
+// Keys returns the keys in order.
+func (m *OrderedMap) Keys() []string {
+	return m.keys
+}
+
+func (m *OrderedMap) set(key string, v interface{}) {
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = v
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Execute parses, validates and executes the request. Errors in the request
+// itself are returned in a response without data. Errors resolving fields set
+// the field to null and are added to the response errors.
+func (s *Schema) Execute(ctx context.Context, req *Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return requestError(err)
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return requestError(err)
+	}
+
+	vars, err := coerceVariables(op.Variables, req.Variables)
+	if err != nil {
+		return requestError(err)
+	}
+
+	if err := s.validate(s.query, op.Selections, vars, 1); err != nil {
+		return requestError(err)
+	}
+
+	e := &executor{schema: s}
+	data := e.selections(ctx, s.query, nil, op.Selections, vars, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func requestError(err error) *Response {
+	return &Response{Errors: []*Error{{Message: err.Error()}}}
+}
+
+func (d *Document) operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required for documents with more than one operation")
+		}
+		return d.Operations[0], nil
+	}
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func coerceVariables(defs []*VariableDefinition, values map[string]interface{}) (map[string]interface{}, error) {
+	vars := make(map[string]interface{}, len(defs))
+	for _, def := range defs {
+		if _, ok := vars[def.Name]; ok {
+			return nil, fmt.Errorf("variable $%s is defined more than once", def.Name)
+		}
+
+		v, ok := values[def.Name]
+		if !ok && def.HasDefault {
+			v, ok = def.Default, true
+		}
+		if !ok || v == nil {
+			if def.Required() {
+				return nil, fmt.Errorf("variable $%s of type %s is required", def.Name, def.Type)
+			}
+			vars[def.Name] = nil
+			continue
+		}
+
+		c, err := coerce(def.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %w", def.Name, err)
+		}
+		vars[def.Name] = c
+	}
+	return vars, nil
+}
+
+// validate checks the selections against the schema before anything is
+// resolved, so an invalid query does not return partial data.
+func (s *Schema) validate(obj *Object, selections []*Selection, vars map[string]interface{}, depth int) error {
+	if depth > s.maxDepth {
+		return fmt.Errorf("query exceeds the maximum depth of %d", s.maxDepth)
+	}
+
+	for _, sel := range selections {
+		if sel.Name == "__typename" {
+			if len(sel.Selections) > 0 || len(sel.Args) > 0 {
+				return fmt.Errorf("__typename does not take arguments or selections")
+			}
+			continue
+		}
+
+		f, ok := obj.Fields[sel.Name]
+		if !ok {
+			return fmt.Errorf("unknown field %q on type %s", sel.Name, obj.Name)
+		}
+
+		if _, err := f.args(sel.Args, vars); err != nil {
+			return fmt.Errorf("%s.%s: %w", obj.Name, sel.Name, err)
+		}
+
+		typ, _ := unwrap(f.Type)
+		child, isObject := s.types[typ]
+		switch {
+		case isObject && len(sel.Selections) == 0:
+			return fmt.Errorf("field %q of type %s must have a selection of subfields", sel.Name, f.Type)
+		case !isObject && len(sel.Selections) > 0:
+			return fmt.Errorf("field %q of type %s cannot have a selection of subfields", sel.Name, f.Type)
+		case isObject:
+			if err := s.validate(child, sel.Selections, vars, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// args resolves variables in the given arguments and coerces them to the
+// argument types.
+func (f *Field) args(given map[string]interface{}, vars map[string]interface{}) (map[string]interface{}, error) {
+	for name := range given {
+		if _, ok := f.Args[name]; !ok {
+			return nil, fmt.Errorf("unknown argument %q", name)
+		}
+	}
+
+	args := make(map[string]interface{}, len(f.Args))
+	for name, typ := range f.Args {
+		v, err := substitute(given[name], vars)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", name, err)
+		}
+		if v == nil {
+			if strings.HasSuffix(typ, "!") {
+				return nil, fmt.Errorf("argument %q of type %s is required", name, typ)
+			}
+			continue
+		}
+
+		c, err := coerce(typ, v)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", name, err)
+		}
+		args[name] = c
+	}
+	return args, nil
+}
+
+func substitute(v interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case variable:
+		val, ok := vars[string(t)]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable $%s", t)
+		}
+		return val, nil
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, item := range t {
+			val, err := substitute(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = val
+		}
+		return list, nil
+	}
+	return v, nil
+}
+
+// coerce converts an input value to the given scalar type. Integers are
+// int64, floats are float64, and strings, IDs and times are strings.
+func coerce(typ string, v interface{}) (interface{}, error) {
+	name, list := unwrap(typ)
+	if list {
+		items, ok := v.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one.
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			c, err := coerce(name, item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, c)
+		}
+		return out, nil
+	}
+
+	switch name {
+	case Int:
+		switch t := v.(type) {
+		case int64:
+			return t, nil
+		case float64:
+			// JSON variables are decoded as floats.
+			if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+				return int64(t), nil
+			}
+		}
+	case Float:
+		switch t := v.(type) {
+		case int64:
+			return float64(t), nil
+		case float64:
+			return t, nil
+		}
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case String, Time:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case ID:
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case int64:
+			return fmt.Sprintf("%d", t), nil
+		case float64:
+			if t == math.Trunc(t) {
+				return fmt.Sprintf("%.0f", t), nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported input type %s", typ)
+	}
+	return nil, fmt.Errorf("cannot use %v as %s", v, typ)
+}
+
+// unwrap returns the named type of a type reference and whether it is a list.
+func unwrap(typ string) (string, bool) {
+	typ = strings.TrimSuffix(typ, "!")
+	if strings.HasPrefix(typ, "[") && strings.HasSuffix(typ, "]") {
+		return strings.TrimSuffix(typ[1:len(typ)-1], "!"), true
+	}
+	return typ, false
+}
+
+type executor struct {
+	schema *Schema
+	errors []*Error
+}
+
+func (e *executor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, &Error{
+		Message: err.Error(),
+		Path:    append([]interface{}(nil), path...),
+	})
+}
+
+func (e *executor) selections(ctx context.Context, obj *Object, source interface{}, selections []*Selection, vars map[string]interface{}, path []interface{}) *OrderedMap {
+	out := &OrderedMap{}
+	for _, sel := range selections {
+		key := sel.ResponseKey()
+		if sel.Name == "__typename" {
+			out.set(key, obj.Name)
+			continue
+		}
+
+		fieldPath := append(path[:len(path):len(path)], key)
+		out.set(key, e.field(ctx, obj.Fields[sel.Name], source, sel, vars, fieldPath))
+	}
+	return out
+}
+
+func (e *executor) field(ctx context.Context, f *Field, source interface{}, sel *Selection, vars map[string]interface{}, path []interface{}) interface{} {
+	if err := ctx.Err(); err != nil {
+		e.fail(path, err)
+		return nil
+	}
+
+	args, err := f.args(sel.Args, vars)
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+
+	var v interface{}
+	if f.Resolve != nil {
+		v, err = f.Resolve(ctx, source, args)
+	} else {
+		v, err = property(source, sel.Name)
+	}
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+	return e.complete(ctx, f.Type, v, sel, vars, path)
+}
+
+// complete converts a resolved value to its response value, resolving the
+// selections of object types.
+func (e *executor) complete(ctx context.Context, typ string, v interface{}, sel *Selection, vars map[string]interface{}, path []interface{}) interface{} {
+	name, list := unwrap(typ)
+	if list {
+		// A nil slice is an empty list, which is what resolvers returning
+		// database rows mean.
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return []interface{}{}
+		}
+	}
+	if isNil(v) {
+		return nil
+	}
+
+	if list {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fail(path, fmt.Errorf("expected a list for type %s, got %T", typ, v))
+			return nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			itemPath := append(path[:len(path):len(path)], i)
+			out[i] = e.complete(ctx, name, rv.Index(i).Interface(), sel, vars, itemPath)
+		}
+		return out
+	}
+
+	if obj, ok := e.schema.types[name]; ok {
+		return e.selections(ctx, obj, v, sel.Selections, vars, path)
+	}
+	if t, ok := v.(time.Time); ok && name == Time && t.IsZero() {
+		return nil
+	}
+	if s, ok := v.(fmt.Stringer); ok && (name == String || name == ID) {
+		return s.String()
+	}
+	return v
+}
+
+// property returns the exported struct field or map value named like the
+// GraphQL field, ignoring case, so "tekCount" resolves TEKCount.
+func property(source interface{}, name string) (interface{}, error) {
+	rv := reflect.ValueOf(source)
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		f := rv.FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+		if !f.IsValid() || !f.CanInterface() {
+			return nil, fmt.Errorf("no property %q on %s", name, rv.Type())
+		}
+		return f.Interface(), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())); v.IsValid() {
+			return v.Interface(), nil
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			if strings.EqualFold(iter.Key().String(), name) {
+				return iter.Value().Interface(), nil
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cannot resolve %q on %T", name, source)
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+	}
+	return false
+}
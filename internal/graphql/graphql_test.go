@@ -0,0 +1,392 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/go-cmp/cmp"
+)
+
+type testAuthority struct {
+	ID        int64
+	Name      string
+	JwksURI   *string
+	Delay     time.Duration
+	Created   time.Time
+	secret    string //nolint:unused
+	TEKCounts []int64
+}
+
+func testSchema(t testing.TB) *Schema {
+	t.Helper()
+
+	authorities := []*testAuthority{
+		{ID: 1, Name: "Alpha", Delay: time.Hour, Created: time.Date(2021, 1, 2, 3, 0, 0, 0, time.UTC), TEKCounts: []int64{1, 2}},
+		{ID: 2, Name: "Beta", TEKCounts: []int64{}},
+	}
+
+	authority := &Object{
+		Name: "Authority",
+		Fields: map[string]*Field{
+			"id":        {Type: ID},
+			"name":      {Type: String},
+			"jwksURI":   {Type: String},
+			"delay":     {Type: String},
+			"created":   {Type: Time},
+			"tekCounts": {Type: "[Int]"},
+			"secret":    {Type: String},
+			"broken": {
+				Type: String,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					return nil, fmt.Errorf("broken %d", source.(*testAuthority).ID)
+				},
+			},
+		},
+	}
+
+	query := &Object{
+		Name: "Query",
+		Fields: map[string]*Field{
+			"authorities": {
+				Type: "[Authority]",
+				Args: map[string]string{"limit": Int},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					if limit, ok := args["limit"].(int64); ok && int(limit) < len(authorities) {
+						return authorities[:limit], nil
+					}
+					return authorities, nil
+				},
+			},
+			"authority": {
+				Type: "Authority",
+				Args: map[string]string{"id": "ID!"},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					for _, a := range authorities {
+						if fmt.Sprintf("%d", a.ID) == args["id"] {
+							return a, nil
+						}
+					}
+					return nil, nil
+				},
+			},
+			"echo": {
+				Type: "[String]",
+				Args: map[string]string{"values": "[String]", "n": Float, "flag": Boolean},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					return []string{fmt.Sprint(args["values"]), fmt.Sprint(args["n"]), fmt.Sprint(args["flag"])}, nil
+				},
+			},
+			"settings": {Type: "Settings"},
+		},
+	}
+
+	settings := &Object{
+		Name: "Settings",
+		Fields: map[string]*Field{
+			"mode": {Type: String},
+		},
+	}
+
+	s, err := NewSchema(query, authority, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func execute(t testing.TB, s *Schema, query string, vars map[string]interface{}) (string, []*Error) {
+	t.Helper()
+
+	resp := s.Execute(context.Background(), &Request{Query: query, Variables: vars})
+	if resp.Data == nil {
+		return "", resp.Errors
+	}
+	b, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), resp.Errors
+}
+
+func TestNewSchema(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSchema(&Object{Name: "Query", Fields: map[string]*Field{"a": {Type: "[Missing]"}}})
+	errcmp.MustMatch(t, err, `Query.a: unknown type "[Missing]"`)
+
+	_, err = NewSchema(&Object{Name: "Query"}, &Object{Name: "Query"})
+	errcmp.MustMatch(t, err, `duplicate type "Query"`)
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	s := testSchema(t)
+
+	cases := []struct {
+		name   string
+		query  string
+		vars   map[string]interface{}
+		want   string
+		errors []*Error
+	}{
+		{
+			name:  "order_and_aliases",
+			query: `{ authorities { name, key: id, __typename } first: authorities(limit: 1) { name } }`,
+			want:  `{"authorities":[{"name":"Alpha","key":1,"__typename":"Authority"},{"name":"Beta","key":2,"__typename":"Authority"}],"first":[{"name":"Alpha"}]}`,
+		},
+		{
+			name:  "scalars",
+			query: `{ authorities { jwksURI delay created tekCounts } }`,
+			want:  `{"authorities":[{"jwksURI":null,"delay":"1h0m0s","created":"2021-01-02T03:00:00Z","tekCounts":[1,2]},{"jwksURI":null,"delay":"0s","created":null,"tekCounts":[]}]}`,
+		},
+		{
+			name:  "variables",
+			query: `query ($id: ID!, $limit: Int = 5) { authority(id: $id) { name } authorities(limit: $limit) { id } }`,
+			vars:  map[string]interface{}{"id": 2.0},
+			want:  `{"authority":{"name":"Beta"},"authorities":[{"id":1},{"id":2}]}`,
+		},
+		{
+			name:  "json_int_variable",
+			query: `query ($limit: Int) { authorities(limit: $limit) { id } }`,
+			vars:  map[string]interface{}{"limit": 1.0},
+			want:  `{"authorities":[{"id":1}]}`,
+		},
+		{
+			name:  "null_object",
+			query: `{ authority(id: "3") { name } settings { mode } }`,
+			want:  `{"authority":null,"settings":null}`,
+		},
+		{
+			name:  "coercion",
+			query: `{ echo(values: "one", n: 2, flag: false) }`,
+			want:  `{"echo":["[one]","2","false"]}`,
+		},
+		{
+			name:  "field_errors",
+			query: `{ authorities { name broken } }`,
+			want:  `{"authorities":[{"name":"Alpha","broken":null},{"name":"Beta","broken":null}]}`,
+			errors: []*Error{
+				{Message: "broken 1", Path: []interface{}{"authorities", 0, "broken"}},
+				{Message: "broken 2", Path: []interface{}{"authorities", 1, "broken"}},
+			},
+		},
+		{
+			name:  "unexported",
+			query: `{ authority(id: 1) { secret } }`,
+			want:  `{"authority":{"secret":null}}`,
+			errors: []*Error{
+				{Message: `no property "secret" on graphql.testAuthority`, Path: []interface{}{"authority", "secret"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, errs := execute(t, s, tc.query, tc.vars)
+			if got != tc.want {
+				t.Errorf("expected\n%s\ngot\n%s", tc.want, got)
+			}
+			if diff := cmp.Diff(tc.errors, errs); diff != "" {
+				t.Errorf("errors mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExecute_RequestErrors(t *testing.T) {
+	t.Parallel()
+
+	s := testSchema(t)
+
+	cases := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		err   string
+	}{
+		{name: "parse", query: `{`, err: "end of document"},
+		{name: "unknown_field", query: `{ nope }`, err: `unknown field "nope" on type Query`},
+		{name: "unknown_nested_field", query: `{ authorities { nope } }`, err: `unknown field "nope" on type Authority`},
+		{name: "missing_selection", query: `{ authorities }`, err: `field "authorities" of type [Authority] must have a selection`},
+		{name: "scalar_selection", query: `{ authorities { name { x } } }`, err: `field "name" of type String cannot have a selection`},
+		{name: "unknown_argument", query: `{ authorities(nope: 1) { id } }`, err: `unknown argument "nope"`},
+		{name: "missing_argument", query: `{ authority { id } }`, err: `argument "id" of type ID! is required`},
+		{name: "bad_argument", query: `{ authorities(limit: "x") { id } }`, err: `cannot use x as Int`},
+		{name: "fractional_int", query: `query ($l: Int) { authorities(limit: $l) { id } }`, vars: map[string]interface{}{"l": 1.5}, err: `cannot use 1.5 as Int`},
+		{name: "undefined_variable", query: `{ authorities(limit: $l) { id } }`, err: `undefined variable $l`},
+		{name: "missing_variable", query: `query ($id: ID!) { authority(id: $id) { id } }`, err: `variable $id of type ID! is required`},
+		{name: "duplicate_variable", query: `query ($a: Int, $a: Int) { authorities { id } }`, err: `variable $a is defined more than once`},
+		{name: "typename_selection", query: `{ __typename { x } }`, err: `__typename does not take arguments or selections`},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, errs := execute(t, s, tc.query, tc.vars)
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 error, got %d", len(errs))
+			}
+			errcmp.MustMatch(t, errs[0], tc.err)
+		})
+	}
+
+}
+
+func TestExecute_MaxDepth(t *testing.T) {
+	t.Parallel()
+
+	s := testSchema(t)
+	s.SetMaxDepth(1)
+
+	if _, errs := execute(t, s, `{ __typename }`, nil); len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+
+	_, errs := execute(t, s, `{ authorities { id } }`, nil)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(errs))
+	}
+	errcmp.MustMatch(t, errs[0], "query exceeds the maximum depth of 1")
+}
+
+func TestExecute_OperationName(t *testing.T) {
+	t.Parallel()
+
+	s := testSchema(t)
+	query := `query A { settings { mode } } query B { authorities(limit: 1) { id } }`
+
+	resp := s.Execute(context.Background(), &Request{Query: query})
+	errcmp.MustMatch(t, resp.Errors[0], "operationName is required")
+
+	resp = s.Execute(context.Background(), &Request{Query: query, OperationName: "C"})
+	errcmp.MustMatch(t, resp.Errors[0], `unknown operation "C"`)
+
+	resp = s.Execute(context.Background(), &Request{Query: query, OperationName: "B"})
+	if len(resp.Errors) > 0 {
+		t.Fatal(resp.Errors[0])
+	}
+	if got, want := resp.Data.Keys(), []string{"authorities"}; !cmp.Equal(got, want) {
+		t.Errorf("expected keys %v, got %v", want, got)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	s := testSchema(t)
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		want        string
+	}{
+		{
+			name:   "ok",
+			method: http.MethodPost,
+			body:   `{"query": "query ($n: Int) { authorities(limit: $n) { name } }", "variables": {"n": 1}}`,
+			status: http.StatusOK,
+			want:   `{"data":{"authorities":[{"name":"Alpha"}]}}`,
+		},
+		{
+			name:   "field_errors",
+			method: http.MethodPost,
+			body:   `{"query": "{ authority(id: 1) { broken } }"}`,
+			status: http.StatusOK,
+			want:   `{"data":{"authority":{"broken":null}},"errors":[{"message":"broken 1","path":["authority","broken"]}]}`,
+		},
+		{
+			name:   "invalid_query",
+			method: http.MethodPost,
+			body:   `{"query": "{ nope }"}`,
+			status: http.StatusBadRequest,
+			want:   `{"errors":[{"message":"unknown field \"nope\" on type Query"}]}`,
+		},
+		{
+			name:   "invalid_json",
+			method: http.MethodPost,
+			body:   `{"query": `,
+			status: http.StatusBadRequest,
+			want:   `{"errors":[{"message":"invalid request: request body is empty, truncated or larger than 65536 bytes"}]}`,
+		},
+		{
+			name:   "too_large",
+			method: http.MethodPost,
+			body:   `{"query": "` + strings.Repeat(" ", maxRequestBytes) + `{ settings { mode } }"}`,
+			status: http.StatusBadRequest,
+			want:   `{"errors":[{"message":"invalid request: request body is empty, truncated or larger than 65536 bytes"}]}`,
+		},
+		{
+			name:        "content_type",
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        `{"query": "{ settings { mode } }"}`,
+			status:      http.StatusUnsupportedMediaType,
+			want:        `{"errors":[{"message":"content type must be application/json"}]}`,
+		},
+		{
+			name:   "method",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			want:   `{"errors":[{"message":"method GET is not allowed"}]}`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(tc.method, "/", bytes.NewBufferString(tc.body))
+			r.Header.Set("Content-Type", "application/json; charset=utf-8")
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			if got := w.Code; got != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, got)
+			}
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("expected\n%s\ngot\n%s", tc.want, got)
+			}
+			if got, want := w.Header().Get("Content-Type"), "application/json"; got != want {
+				t.Errorf("expected content type %q, got %q", want, got)
+			}
+		})
+	}
+}
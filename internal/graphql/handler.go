@@ -0,0 +1,74 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxRequestBytes limits the size of request bodies.
+const maxRequestBytes = 64 * 1024
+
+// ServeHTTP executes a JSON encoded GraphQL request given as the body of a
+// POST. Requests that cannot be executed are answered with 400; errors
+// resolving fields are returned with 200 and partial data.
+func (s *Schema) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, requestError(fmt.Errorf("method %s is not allowed", r.Method)))
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, err := mime.ParseMediaType(ct); err != nil || mt != "application/json" {
+			writeJSON(w, http.StatusUnsupportedMediaType, requestError(fmt.Errorf("content type must be application/json")))
+			return
+		}
+	}
+
+	var req Request
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes))
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			err = fmt.Errorf("request body is empty, truncated or larger than %d bytes", maxRequestBytes)
+		}
+		writeJSON(w, http.StatusBadRequest, requestError(fmt.Errorf("invalid request: %w", err)))
+		return
+	}
+
+	resp := s.Execute(r.Context(), &req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp *Response) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(requestError(fmt.Errorf("failed to encode response: %w", err)))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(b) //nolint:errcheck
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	doc, err := Parse(`
+		# Stats for a health authority.
+		query Stats($id: ID!, $hours: Int = 24, $regions: [String!]) {
+			ha: healthAuthority(id: $id) {
+				name, stats(hours: $hours, regions: ["US", "CA"], ratio: -1.5e2, active: true, policy: DROP, nothing: null) {
+					hour
+				}
+			}
+			__typename
+		}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Document{
+		Operations: []*Operation{
+			{
+				Name: "Stats",
+				Variables: []*VariableDefinition{
+					{Name: "id", Type: "ID!"},
+					{Name: "hours", Type: "Int", Default: int64(24), HasDefault: true},
+					{Name: "regions", Type: "[String!]"},
+				},
+				Selections: []*Selection{
+					{
+						Alias: "ha",
+						Name:  "healthAuthority",
+						Args:  map[string]interface{}{"id": variable("id")},
+						Selections: []*Selection{
+							{Name: "name"},
+							{
+								Name: "stats",
+								Args: map[string]interface{}{
+									"hours":   variable("hours"),
+									"regions": []interface{}{"US", "CA"},
+									"ratio":   -150.0,
+									"active":  true,
+									"policy":  "DROP",
+									"nothing": nil,
+								},
+								Selections: []*Selection{{Name: "hour"}},
+							},
+						},
+					},
+					{Name: "__typename"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, doc); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{name: "empty", query: " # nothing", err: "document has no operations"},
+		{name: "mutation", query: `mutation { x }`, err: "mutation operations are not supported"},
+		{name: "subscription", query: `subscription { x }`, err: "subscription operations are not supported"},
+		{name: "fragment_definition", query: `fragment F on T { x }`, err: "fragments are not supported"},
+		{name: "fragment_spread", query: `{ a { ...F } }`, err: "fragments are not supported"},
+		{name: "directive", query: `{ a @skip(if: true) }`, err: "directives are not supported"},
+		{name: "input_object", query: `{ a(b: {c: 1}) }`, err: "input objects are not supported"},
+		{name: "empty_selection", query: `{ }`, err: "empty selection set"},
+		{name: "unterminated", query: `{ a(b: "x) }`, err: "unterminated string"},
+		{name: "block_string", query: `{ a(b: """x""") }`, err: "block strings are not supported"},
+		{name: "bad_character", query: `{ a% }`, err: `unexpected character '%'`},
+		{name: "unclosed", query: `{ a`, err: "end of document"},
+		{name: "duplicate_argument", query: `{ a(b: 1, b: 2) }`, err: `argument "b" is given more than once`},
+		{name: "variable_in_default", query: `query ($a: Int = $b) { a }`, err: "variables are not allowed in constant values"},
+		{name: "bad_number", query: `{ a(b: 1.2.3) }`, err: `invalid number "1.2.3"`},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(tc.query)
+			errcmp.MustMatch(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+// Config configures the message catalogs.
+type Config struct {
+	// LocalesPath is an optional directory of message catalogs that are added
+	// to the built-in ones. Each catalog is a JSON object of message keys to
+	// messages, in a file named after its BCP 47 language tag, like
+	// "fr-CA.json". Messages replace the built-in message with the same key.
+	LocalesPath string `env:"LOCALES_PATH"`
+
+	// DefaultLocale is used when no catalog matches the request, and for
+	// messages that are missing from the negotiated catalog. If empty, English
+	// is used.
+	DefaultLocale string `env:"DEFAULT_LOCALE, default=en"`
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if _, err := c.defaultTag(); err != nil {
+		return fmt.Errorf("DEFAULT_LOCALE is not a valid language tag: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) defaultTag() (language.Tag, error) {
+	if c.DefaultLocale == "" {
+		return language.English, nil
+	}
+	return language.Parse(c.DefaultLocale)
+}
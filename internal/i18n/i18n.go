@@ -0,0 +1,260 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package i18n provides message catalogs for the admin console and the
+// client-facing API error messages, and negotiates the locale of each request.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	"github.com/gorilla/mux"
+	"golang.org/x/text/language"
+)
+
+// QueryParam is the query parameter that overrides the Accept-Language header.
+const QueryParam = "lang"
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// defaultLocale is used when there is no locale on the context.
+var defaultLocale = func() *Locale {
+	catalog, err := Load(&Config{})
+	if err != nil {
+		panic(fmt.Errorf("failed to load built-in catalogs: %w", err))
+	}
+	return catalog.Default()
+}()
+
+// Catalog holds the messages of every supported locale.
+type Catalog struct {
+	// tags are the supported locales, with the default first.
+	tags     []language.Tag
+	matcher  language.Matcher
+	messages map[language.Tag]map[string]string
+}
+
+// Load reads the built-in catalogs and the catalogs in cfg.LocalesPath, if
+// set.
+func Load(cfg *Config) (*Catalog, error) {
+	def, err := cfg.defaultTag()
+	if err != nil {
+		return nil, fmt.Errorf("invalid default locale: %w", err)
+	}
+
+	messages := make(map[language.Tag]map[string]string)
+	if err := readCatalogs(localesFS, "locales", messages); err != nil {
+		return nil, err
+	}
+	if cfg.LocalesPath != "" {
+		if err := readCatalogs(os.DirFS(cfg.LocalesPath), ".", messages); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := messages[def]; !ok {
+		return nil, fmt.Errorf("no catalog for default locale %q", def)
+	}
+
+	tags := make([]language.Tag, 0, len(messages))
+	for tag := range messages {
+		if tag != def {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].String() < tags[j].String() })
+	tags = append([]language.Tag{def}, tags...)
+
+	return &Catalog{
+		tags:     tags,
+		matcher:  language.NewMatcher(tags),
+		messages: messages,
+	}, nil
+}
+
+// readCatalogs merges the JSON catalogs in dir into messages.
+func readCatalogs(fsys fs.FS, dir string, messages map[language.Tag]map[string]string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to list catalogs: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || path.Ext(name) != ".json" {
+			continue
+		}
+
+		tag, err := language.Parse(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return fmt.Errorf("catalog %s is not named after a language tag: %w", name, err)
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read catalog %s: %w", name, err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(b, &catalog); err != nil {
+			return fmt.Errorf("failed to parse catalog %s: %w", name, err)
+		}
+
+		if messages[tag] == nil {
+			messages[tag] = make(map[string]string, len(catalog))
+		}
+		for k, v := range catalog {
+			messages[tag][k] = v
+		}
+	}
+	return nil
+}
+
+// Tags returns the supported locales, with the default first.
+func (c *Catalog) Tags() []string {
+	tags := make([]string, 0, len(c.tags))
+	for _, tag := range c.tags {
+		tags = append(tags, tag.String())
+	}
+	return tags
+}
+
+// Default returns the default locale.
+func (c *Catalog) Default() *Locale {
+	return c.locale(c.tags[0])
+}
+
+// Match returns the best supported locale for the given preferences, each an
+// Accept-Language header value or a language tag, in order of preference. It
+// returns the default locale if nothing matches.
+func (c *Catalog) Match(preferences ...string) *Locale {
+	var prefs []language.Tag
+	for _, p := range preferences {
+		tags, _, err := language.ParseAcceptLanguage(p)
+		if err != nil {
+			continue
+		}
+		prefs = append(prefs, tags...)
+	}
+
+	_, i, confidence := c.matcher.Match(prefs...)
+	if confidence == language.No {
+		return c.Default()
+	}
+	return c.locale(c.tags[i])
+}
+
+// Negotiate returns the locale for the request, from the lang query parameter
+// or the Accept-Language header.
+func (c *Catalog) Negotiate(r *http.Request) *Locale {
+	return c.Match(r.URL.Query().Get(QueryParam), r.Header.Get("Accept-Language"))
+}
+
+func (c *Catalog) locale(tag language.Tag) *Locale {
+	return &Locale{
+		tag:      tag,
+		messages: c.messages[tag],
+		fallback: c.messages[c.tags[0]],
+	}
+}
+
+// Middleware negotiates the locale of each request and stores it on the
+// request context.
+func (c *Catalog) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := c.Negotiate(r)
+			w.Header().Set("Content-Language", locale.Tag())
+			next.ServeHTTP(w, r.Clone(WithLocale(r.Context(), locale)))
+		})
+	}
+}
+
+// Locale translates messages into one language. A nil Locale uses the
+// built-in English messages.
+type Locale struct {
+	tag      language.Tag
+	messages map[string]string
+	fallback map[string]string
+}
+
+// Tag returns the BCP 47 tag of the locale.
+func (l *Locale) Tag() string {
+	if l == nil {
+		l = defaultLocale
+	}
+	return l.tag.String()
+}
+
+// T returns the message for key, formatted with args. Messages missing from
+// the locale are taken from the default locale. If the key is not in either,
+// the key itself is returned so the missing message is easy to spot.
+func (l *Locale) T(key string, args ...interface{}) string {
+	if l == nil {
+		l = defaultLocale
+	}
+
+	msg, ok := l.messages[key]
+	if !ok {
+		if msg, ok = l.fallback[key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// ErrorMessage returns the client-facing message for an error code. A catalog
+// can set the message of a code with the key "error.<code>", otherwise the
+// message built into errcode is used.
+func (l *Locale) ErrorMessage(code errcode.Code) string {
+	if l == nil {
+		l = defaultLocale
+	}
+
+	key := "error." + string(code)
+	if msg, ok := l.messages[key]; ok {
+		return msg
+	}
+	return code.Message(l.Tag())
+}
+
+type contextKey string
+
+const localeKey = contextKey("locale")
+
+// WithLocale returns a new context with the given locale.
+func WithLocale(ctx context.Context, l *Locale) context.Context {
+	return context.WithValue(ctx, localeKey, l)
+}
+
+// FromContext returns the locale stored on the context, or the built-in
+// English locale if there is none.
+func FromContext(ctx context.Context) *Locale {
+	if l, ok := ctx.Value(localeKey).(*Locale); ok && l != nil {
+		return l
+	}
+	return defaultLocale
+}
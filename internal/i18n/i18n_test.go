@@ -0,0 +1,248 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testCatalog(tb testing.TB) *Catalog {
+	tb.Helper()
+
+	catalog, err := Load(&Config{LocalesPath: "testdata", DefaultLocale: "en"})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return catalog
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	if diff := cmp.Diff([]string{"en", "es", "es-MX", "fr"}, testCatalog(t).Tags()); diff != "" {
+		t.Errorf("tags mismatch (-want, +got):\n%s", diff)
+	}
+
+	cases := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"invalid_default", &Config{DefaultLocale: "not a tag"}},
+		{"missing_default", &Config{DefaultLocale: "de"}},
+		{"missing_path", &Config{DefaultLocale: "en", LocalesPath: "does-not-exist"}},
+		{"invalid_name", &Config{DefaultLocale: "en", LocalesPath: writeCatalog(t, "not a tag.json", "{}")}},
+		{"invalid_json", &Config{DefaultLocale: "en", LocalesPath: writeCatalog(t, "de.json", "[]")}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := Load(tc.cfg); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func writeCatalog(tb testing.TB, name, contents string) string {
+	tb.Helper()
+
+	dir := tb.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+	return dir
+}
+
+func TestCatalog_Match(t *testing.T) {
+	t.Parallel()
+
+	catalog := testCatalog(t)
+
+	cases := []struct {
+		name  string
+		prefs []string
+		want  string
+	}{
+		{"none", nil, "en"},
+		{"empty", []string{""}, "en"},
+		{"exact", []string{"fr"}, "fr"},
+		{"region", []string{"fr-CA"}, "fr"},
+		{"region_catalog", []string{"es-MX"}, "es-MX"},
+		{"base_language", []string{"es-ES"}, "es"},
+		{"quality", []string{"de;q=0.9, fr;q=0.5, es;q=0.7"}, "es"},
+		{"unsupported", []string{"de"}, "en"},
+		{"first_preference", []string{"fr", "es"}, "fr"},
+		{"invalid_skipped", []string{"!!", "fr"}, "fr"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := catalog.Match(tc.prefs...).Tag(); got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLocale_T(t *testing.T) {
+	t.Parallel()
+
+	catalog := testCatalog(t)
+	fr := catalog.Match("fr")
+
+	if got, want := fr.T("nav.home"), "Accueil"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := fr.T("nav.tenant", "prod"), "Locataire : prod"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	// Missing from the deployment catalog, so it comes from the default.
+	if got, want := fr.T("nav.brand"), "Admin console"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	// Deployment catalogs are merged with the built-in catalog.
+	if got, want := catalog.Match("es").T("nav.home"), "Inicio"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := fr.T("does.not.exist"), "does.not.exist"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	var nilLocale *Locale
+	if got, want := nilLocale.T("nav.home"), "Home"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func TestLocale_ErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	catalog := testCatalog(t)
+
+	cases := []struct {
+		name   string
+		locale *Locale
+		code   errcode.Code
+		want   string
+	}{
+		{"catalog", catalog.Match("fr"), errcode.BadRequest, "La requête est invalide."},
+		{"errcode_fallback", catalog.Match("fr"), errcode.InternalError, errcode.InternalError.Message("en")},
+		{"errcode_language", catalog.Match("es"), errcode.BadRequest, errcode.BadRequest.Message("es")},
+		{"nil", nil, errcode.BadRequest, errcode.BadRequest.Message("en")},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.locale.ErrorMessage(tc.code); got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_Middleware(t *testing.T) {
+	t.Parallel()
+
+	catalog := testCatalog(t)
+
+	cases := []struct {
+		name   string
+		url    string
+		header string
+		want   string
+	}{
+		{"default", "/", "", "en"},
+		{"header", "/", "fr-FR,fr;q=0.9", "fr"},
+		{"query", "/?lang=es", "fr", "es"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			handler := catalog.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = FromContext(r.Context()).Tag()
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.header != "" {
+				r.Header.Set("Accept-Language", tc.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got != tc.want {
+				t.Errorf("expected locale %q to be %q", got, tc.want)
+			}
+			if got := w.Header().Get("Content-Language"); got != tc.want {
+				t.Errorf("expected Content-Language %q to be %q", got, tc.want)
+			}
+		})
+	}
+
+	if got, want := FromContext(context.Background()).Tag(), "en"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		locale  string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"valid", "fr-CA", false},
+		{"invalid", "not a tag", true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &Config{DefaultLocale: tc.locale}
+			if err := cfg.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("expected error: %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -25,10 +25,11 @@ import (
 )
 
 const (
-	// Max request size of 64KB. None of the current API requests for this
-	// server are near that limit. Prevents us from unnecessarily parsing JSON
-	// payloads that are much large than we anticipate.
-	maxBodyBytes = 64_000
+	// MaxBodyBytes is the max request size of 64KB. None of the current API
+	// requests for this server are near that limit. Prevents us from
+	// unnecessarily parsing JSON payloads that are much large than we
+	// anticipate.
+	MaxBodyBytes = 64_000
 )
 
 // Unmarshal provides a common implementation of JSON unmarshalling with well defined error handling.
@@ -38,7 +39,7 @@ func Unmarshal(w http.ResponseWriter, r *http.Request, data interface{}) (int, e
 	}
 
 	defer r.Body.Close()
-	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
 
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
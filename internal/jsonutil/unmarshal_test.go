@@ -34,7 +34,7 @@ func TestBodyTooLarge(t *testing.T) {
 	t.Parallel()
 
 	input := make(map[string]string, 1)
-	input["padding"] = strings.Repeat("0", maxBodyBytes+10)
+	input["padding"] = strings.Repeat("0", MaxBodyBytes+10)
 
 	largeJSON, err := json.Marshal(input)
 	if err != nil {
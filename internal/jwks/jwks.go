@@ -27,18 +27,17 @@ import (
 	"net/http"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/project"
 	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
 	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/internal/worker"
 	"github.com/google/exposure-notifications-server/pkg/cryptorand"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/hashicorp/go-multierror"
 	"github.com/rakutentech/jwk-go/jwk"
-	"golang.org/x/sync/semaphore"
 )
 
 // Manager handles updating all HealthAuthorities if they've specified a JWKS
@@ -272,32 +271,25 @@ func (mgr *Manager) UpdateAll(ctx context.Context) error {
 	})
 
 	var merr *multierror.Error
-	var merrLock sync.Mutex
-	sem := semaphore.NewWeighted(int64(mgr.maxWorkers))
-	var wg sync.WaitGroup
+	pool := worker.New("jwks_update", int(mgr.maxWorkers))
 	for _, ha := range healthAuthorities {
-		// Rate limit the number of concurrent workers with a semaphore.
-		if err := sem.Acquire(ctx, 1); err != nil {
-			logger.Errorw("failed to acquire semaphore", "error", err)
-			merrLock.Lock()
+		ha := ha
+
+		// Rate limit the number of concurrent workers with a pool.
+		if err := pool.Go(ctx, func(ctx context.Context) error {
+			if err := mgr.updateHA(ctx, ha); err != nil {
+				return fmt.Errorf("failed to processes %v: %w", ha.Name, err)
+			}
+			return nil
+		}); err != nil {
+			logger.Errorw("failed to start worker", "error", err)
 			merr = multierror.Append(merr, fmt.Errorf("failed to processes %v: %w", ha.Name, err))
-			merrLock.Unlock()
 			break
 		}
-
-		wg.Add(1)
-		go func(ha *model.HealthAuthority) {
-			defer sem.Release(1)
-			defer wg.Done()
-			err := mgr.updateHA(ctx, ha)
-			if err != nil {
-				merrLock.Lock()
-				merr = multierror.Append(merr, fmt.Errorf("failed to processes %v: %w", ha.Name, err))
-				merrLock.Unlock()
-			}
-		}(ha)
 	}
-	wg.Wait()
+	if err := pool.Wait(); err != nil {
+		merr = multierror.Append(merr, err)
+	}
 
 	if err := merr.ErrorOrNil(); err != nil {
 		return fmt.Errorf("failed to update all: %w", err)
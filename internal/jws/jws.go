@@ -0,0 +1,134 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jws signs and verifies JSON Web Signatures (RFC 7515) with ES256,
+// the algorithm of the export signing keys.
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// es256SignatureSize is the size of each of the R and S values in an ES256
+// signature.
+const es256SignatureSize = 32
+
+// JWS is a JSON Web Signature in the flattened JSON serialization (RFC 7515,
+// section 7.2.2).
+type JWS struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// Header is the protected header of a JWS.
+type Header struct {
+	Algorithm  string `json:"alg"`
+	KeyID      string `json:"kid,omitempty"`
+	KeyVersion string `json:"keyVersion,omitempty"`
+}
+
+// Sign signs the payload with an ECDSA P-256 signer. The algorithm of the
+// header is set to ES256.
+func Sign(signer crypto.Signer, h Header, payload []byte) (*JWS, error) {
+	h.Algorithm = "ES256"
+	header, err := json.Marshal(&h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal header: %w", err)
+	}
+
+	jws := &JWS{
+		Protected: base64.RawURLEncoding.EncodeToString(header),
+		Payload:   base64.RawURLEncoding.EncodeToString(payload),
+	}
+
+	digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	// Signers return ASN.1 encoded signatures, but JWS uses the concatenation
+	// of the fixed-width R and S values.
+	var rs struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &rs); err != nil {
+		return nil, fmt.Errorf("failed to parse signature: %w", err)
+	}
+	sig := make([]byte, 2*es256SignatureSize)
+	rs.R.FillBytes(sig[:es256SignatureSize])
+	rs.S.FillBytes(sig[es256SignatureSize:])
+	jws.Signature = base64.RawURLEncoding.EncodeToString(sig)
+
+	return jws, nil
+}
+
+// Detached returns the compact serialization with the payload left out (RFC
+// 7515, appendix F), for a signature stored next to the content it signs.
+func (j *JWS) Detached() string {
+	return j.Protected + ".." + j.Signature
+}
+
+// ParseDetached parses a detached compact serialization and returns its header.
+// The payload is attached, so the result can be verified.
+func ParseDetached(detached string, payload []byte) (*JWS, *Header, error) {
+	parts := strings.Split(strings.TrimSpace(detached), ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return nil, nil, fmt.Errorf("not a detached JWS")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode header: %w", err)
+	}
+	var h Header
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse header: %w", err)
+	}
+
+	return &JWS{
+		Protected: parts[0],
+		Payload:   base64.RawURLEncoding.EncodeToString(payload),
+		Signature: parts[2],
+	}, &h, nil
+}
+
+// Verify checks the ES256 signature with the public key.
+func (j *JWS) Verify(pub *ecdsa.PublicKey) error {
+	sig, err := base64.RawURLEncoding.DecodeString(j.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if len(sig) != 2*es256SignatureSize {
+		return fmt.Errorf("invalid signature length %d", len(sig))
+	}
+
+	digest := sha256.Sum256([]byte(j.Protected + "." + j.Payload))
+	r := new(big.Int).SetBytes(sig[:es256SignatureSize])
+	s := new(big.Int).SetBytes(sig[es256SignatureSize:])
+	if !ecdsa.Verify(pub, digest[:], r, s) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"regions":["US"]}`)
+	jws, err := Sign(key, Header{KeyID: "v1"}, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h Header
+	if err := json.Unmarshal(header, &h); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.Algorithm, "ES256"; got != want {
+		t.Errorf("expected alg %q to be %q", got, want)
+	}
+	if got, want := h.KeyID, "v1"; got != want {
+		t.Errorf("expected kid %q to be %q", got, want)
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("expected payload %q to be %q", got, payload)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(sig), 2*es256SignatureSize; got != want {
+		t.Fatalf("expected signature length %d to be %d", got, want)
+	}
+
+	digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	r := new(big.Int).SetBytes(sig[:es256SignatureSize])
+	s := new(big.Int).SetBytes(sig[es256SignatureSize:])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Errorf("signature did not verify")
+	}
+}
+
+func TestDetached(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("US/1-2-00001.zip\nUS/2-3-00001.zip")
+	signed, err := Sign(key, Header{KeyID: "310", KeyVersion: "v1"}, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detached := signed.Detached()
+	if strings.Count(detached, ".") != 2 || !strings.Contains(detached, "..") {
+		t.Fatalf("expected detached compact serialization, got %q", detached)
+	}
+
+	cases := []struct {
+		name    string
+		payload []byte
+		key     *ecdsa.PublicKey
+		err     bool
+	}{
+		{name: "valid", payload: payload, key: &key.PublicKey},
+		{name: "truncated", payload: payload[:16], key: &key.PublicKey, err: true},
+		{name: "tampered", payload: append([]byte("DE/1-2-00001.zip\n"), payload...), key: &key.PublicKey, err: true},
+		{name: "wrong_key", payload: payload, key: &other.PublicKey, err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			parsed, h, err := ParseDetached(detached, tc.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := *h, (Header{Algorithm: "ES256", KeyID: "310", KeyVersion: "v1"}); got != want {
+				t.Errorf("expected header %#v to be %#v", got, want)
+			}
+			if err := parsed.Verify(tc.key); (err != nil) != tc.err {
+				t.Errorf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+
+	if _, _, err := ParseDetached(signed.Protected+"."+signed.Payload+"."+signed.Signature, payload); err == nil {
+		t.Errorf("expected error for attached payload")
+	}
+}
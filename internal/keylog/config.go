@@ -0,0 +1,49 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keylog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the configuration for the key transparency log endpoints.
+type Config struct {
+	// Enabled serves the signed tree head at TreeHeadPath and the entries at
+	// EntriesPath.
+	Enabled bool `env:"KEYLOG_ENABLED, default=false"`
+
+	// SigningKey is the ID of the key in the key manager used to sign tree
+	// heads. SigningKeyID is the "kid" in the signature header.
+	SigningKey   string `env:"KEYLOG_SIGNING_KEY"`
+	SigningKeyID string `env:"KEYLOG_SIGNING_KEY_ID, default=v1"`
+
+	// MaxEntries is the maximum number of entries returned by one request.
+	MaxEntries int `env:"KEYLOG_MAX_ENTRIES, default=1000"`
+
+	// CacheDuration is how long the signed tree head is cached.
+	CacheDuration time.Duration `env:"KEYLOG_CACHE_DURATION, default=1m"`
+}
+
+// Validate checks the configuration.
+func (c *Config) Validate() error {
+	if c.SigningKey == "" {
+		return fmt.Errorf("KEYLOG_SIGNING_KEY is required")
+	}
+	if c.MaxEntries <= 0 {
+		return fmt.Errorf("KEYLOG_MAX_ENTRIES must be positive, got %d", c.MaxEntries)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface to the key transparency log.
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/keylog/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type KeyLogDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *KeyLogDB {
+	return &KeyLogDB{
+		db: db,
+	}
+}
+
+// Append adds an entry to the log in the given transaction, so the key change
+// it records and the entry are committed together. The table is locked until
+// the transaction ends, which keeps the chain linear.
+func Append(ctx context.Context, tx pgx.Tx, kind string, data interface{}, now time.Time) (*model.Entry, error) {
+	if _, err := tx.Exec(ctx, `LOCK TABLE KeyLog IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, fmt.Errorf("locking key log: %w", err)
+	}
+
+	prev, err := lastEntry(ctx, tx)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, err
+	}
+
+	e, err := model.NewEntry(prev, kind, data, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO
+			KeyLog
+			(idx, leaf, leaf_hash, chain_hash, created_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+	`, e.Index, e.Leaf, e.LeafHash, e.ChainHash, e.CreatedAt); err != nil {
+		return nil, fmt.Errorf("inserting key log entry: %w", err)
+	}
+	return e, nil
+}
+
+func lastEntry(ctx context.Context, tx pgx.Tx) (*model.Entry, error) {
+	row := tx.QueryRow(ctx, `
+		SELECT
+			idx, leaf, leaf_hash, chain_hash, created_at
+		FROM
+			KeyLog
+		ORDER BY idx DESC
+		LIMIT 1
+	`)
+	e, err := scanOneEntry(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("reading last key log entry: %w", err)
+	}
+	return e, nil
+}
+
+// Head returns the last entry of the log, or database.ErrNotFound if the log
+// is empty.
+func (db *KeyLogDB) Head(ctx context.Context) (*model.Entry, error) {
+	var e *model.Entry
+	if err := db.db.InTx(ctx, pgx.RepeatableRead, func(tx pgx.Tx) error {
+		var err error
+		e, err = lastEntry(ctx, tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListEntries returns up to limit entries, starting at index start.
+func (db *KeyLogDB) ListEntries(ctx context.Context, start int64, limit int) ([]*model.Entry, error) {
+	var entries []*model.Entry
+	if err := db.db.InTx(ctx, pgx.RepeatableRead, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				idx, leaf, leaf_hash, chain_hash, created_at
+			FROM
+				KeyLog
+			WHERE
+				idx >= $1
+			ORDER BY idx ASC
+			LIMIT $2
+		`, start, limit)
+		if err != nil {
+			return fmt.Errorf("listing key log entries: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			e, err := scanOneEntry(rows)
+			if err != nil {
+				return fmt.Errorf("scanning key log entry: %w", err)
+			}
+			entries = append(entries, e)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func scanOneEntry(row pgx.Row) (*model.Entry, error) {
+	var e model.Entry
+	if err := row.Scan(&e.Index, &e.Leaf, &e.LeafHash, &e.ChainHash, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/keylog/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+func TestAppend(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	keyLogDB := New(testDB)
+
+	for i := 0; i < 3; i++ {
+		if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+			_, err := Append(ctx, tx, model.KindExportSigningKey, &model.ExportSigningKey{SignatureInfoID: int64(i)}, time.Now())
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := keyLogDB.ListEntries(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(entries), 3; got != want {
+		t.Fatalf("expected %d entries, got %d", want, got)
+	}
+	if err := model.VerifyChain(nil, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := keyLogDB.Head(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Index != 2 {
+		t.Errorf("expected head at index 2, got %d", head.Index)
+	}
+
+	rest, err := keyLogDB.ListEntries(ctx, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.VerifyChain(entries[0], rest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppendOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := Append(ctx, tx, model.KindExportSigningKey, &model.ExportSigningKey{}, time.Now())
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sql := range []string{
+		`UPDATE KeyLog SET leaf = 'x'`,
+		`DELETE FROM KeyLog`,
+	} {
+		if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, sql)
+			return err
+		}); err == nil {
+			t.Errorf("expected %q to fail", sql)
+		}
+	}
+}
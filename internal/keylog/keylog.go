@@ -0,0 +1,190 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keylog serves the key transparency log: an append-only, hash-chained
+// log of every health authority key and export signing key that was
+// configured. Auditors fetch the signed tree head, replay the entries to
+// recompute its root hash, and compare tree heads over time to detect keys
+// that were added without their knowledge.
+package keylog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/jws"
+	keylogdb "github.com/google/exposure-notifications-server/internal/keylog/database"
+	"github.com/google/exposure-notifications-server/internal/keylog/model"
+	"github.com/google/exposure-notifications-server/pkg/cache"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/render"
+)
+
+const (
+	// TreeHeadPath is the path at which the signed tree head is served.
+	TreeHeadPath = "/v1/keylog/tree-head"
+
+	// EntriesPath is the path at which the log entries are served. The "start"
+	// query parameter is the index of the first entry to return.
+	EntriesPath = "/v1/keylog/entries"
+
+	treeHeadCacheKey = "tree-head"
+)
+
+// Entries is the response of the entries endpoint.
+type Entries struct {
+	Entries []*Entry `json:"entries"`
+}
+
+// Entry is a log entry. The byte fields are base64 encoded.
+type Entry struct {
+	Index     int64  `json:"index"`
+	Leaf      []byte `json:"leaf"`
+	LeafHash  []byte `json:"leafHash"`
+	ChainHash []byte `json:"chainHash"`
+}
+
+// Handler serves the key transparency log.
+type Handler struct {
+	config     *Config
+	db         *keylogdb.KeyLogDB
+	keyManager keys.KeyManager
+	cache      *cache.Cache[*jws.JWS]
+	h          *render.Renderer
+}
+
+// New creates a new key transparency log handler.
+func New(cfg *Config, db *database.DB, km keys.KeyManager) (*Handler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if km == nil {
+		return nil, fmt.Errorf("keylog requires a key manager")
+	}
+
+	c, err := cache.New[*jws.JWS](cfg.CacheDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %w", err)
+	}
+
+	return &Handler{
+		config:     cfg,
+		db:         keylogdb.New(db),
+		keyManager: km,
+		cache:      c,
+		h:          render.NewRenderer(),
+	}, nil
+}
+
+// TreeHeadHandler returns a handler that serves the signed tree head.
+func (h *Handler) TreeHeadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("keylog")
+
+		sth, err := h.cache.WriteThruLookup(treeHeadCacheKey, func() (*jws.JWS, error) {
+			return h.signedTreeHead(ctx, time.Now().UTC())
+		})
+		if err != nil {
+			logger.Errorw("failed to build tree head", "error", err)
+			h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.CacheDuration.Seconds())))
+		h.h.RenderJSON(w, http.StatusOK, sth)
+	})
+}
+
+// EntriesHandler returns a handler that serves up to MaxEntries entries of the
+// log, starting at the "start" query parameter.
+func (h *Handler) EntriesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("keylog")
+
+		var start int64
+		if v := r.URL.Query().Get("start"); v != "" {
+			var err error
+			start, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || start < 0 {
+				h.h.RenderJSON(w, http.StatusBadRequest, fmt.Errorf("invalid start %q", v))
+				return
+			}
+		}
+
+		entries, err := h.db.ListEntries(ctx, start, h.config.MaxEntries)
+		if err != nil {
+			logger.Errorw("failed to list entries", "error", err)
+			h.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+
+		resp := &Entries{
+			Entries: make([]*Entry, 0, len(entries)),
+		}
+		for _, e := range entries {
+			resp.Entries = append(resp.Entries, &Entry{
+				Index:     e.Index,
+				Leaf:      e.Leaf,
+				LeafHash:  e.LeafHash,
+				ChainHash: e.ChainHash,
+			})
+		}
+		h.h.RenderJSON(w, http.StatusOK, resp)
+	})
+}
+
+// TreeHead returns the tree head of the log at the given time.
+func (h *Handler) TreeHead(ctx context.Context, now time.Time) (*model.TreeHead, error) {
+	head := &model.TreeHead{
+		Timestamp: now.Unix(),
+	}
+
+	last, err := h.db.Head(ctx)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return head, nil
+		}
+		return nil, fmt.Errorf("failed to read log head: %w", err)
+	}
+	head.TreeSize = last.Index + 1
+	head.RootHash = last.ChainHash
+	return head, nil
+}
+
+func (h *Handler) signedTreeHead(ctx context.Context, now time.Time) (*jws.JWS, error) {
+	head, err := h.TreeHead(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tree head: %w", err)
+	}
+
+	signer, err := h.keyManager.NewSigner(ctx, h.config.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signer: %w", err)
+	}
+	return jws.Sign(signer, jws.Header{KeyID: h.config.SigningKeyID}, payload)
+}
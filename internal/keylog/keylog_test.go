@@ -0,0 +1,160 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keylog
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	exportdb "github.com/google/exposure-notifications-server/internal/export/database"
+	exportmodel "github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/jws"
+	"github.com/google/exposure-notifications-server/internal/keylog/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+)
+
+var testDatabaseInstance *database.TestInstance
+
+func TestMain(m *testing.M) {
+	testDatabaseInstance = database.MustTestInstance()
+	defer testDatabaseInstance.MustClose()
+	m.Run()
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	db := exportdb.New(testDB)
+
+	km := keys.TestKeyManager(t)
+	exportKey := keys.TestSigningKey(t, km)
+	logKey := keys.TestSigningKey(t, km)
+
+	// Adding and rotating a signing key are both logged.
+	si := &exportmodel.SignatureInfo{
+		SigningKey:        exportKey,
+		SigningKeyID:      "310",
+		SigningKeyVersion: "v1",
+	}
+	if err := db.AddSignatureInfo(ctx, si); err != nil {
+		t.Fatal(err)
+	}
+	si.EndTimestamp = time.Now().Add(time.Hour)
+	if err := db.UpdateSignatureInfo(ctx, si); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := New(&Config{
+		SigningKey:    logKey,
+		SigningKeyID:  "log-v1",
+		MaxEntries:    10,
+		CacheDuration: time.Minute,
+	}, testDB, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetch and verify the signed tree head.
+	w := httptest.NewRecorder()
+	h.TreeHeadHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, TreeHeadPath, nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	var signed jws.JWS
+	if err := json.Unmarshal(w.Body.Bytes(), &signed); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := km.NewSigner(ctx, logKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signed.Verify(signer.Public().(*ecdsa.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var head model.TreeHead
+	if err := json.Unmarshal(payload, &head); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := head.TreeSize, int64(2); got != want {
+		t.Fatalf("expected tree size %d, got %d", want, got)
+	}
+
+	// Replay the entries and compare with the tree head.
+	w = httptest.NewRecorder()
+	h.EntriesHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, EntriesPath+"?start=0", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	var resp Entries
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	entries := make([]*model.Entry, 0, len(resp.Entries))
+	for _, e := range resp.Entries {
+		entries = append(entries, &model.Entry{
+			Index:     e.Index,
+			Leaf:      e.Leaf,
+			LeafHash:  e.LeafHash,
+			ChainHash: e.ChainHash,
+		})
+	}
+	if err := model.VerifyChain(nil, entries); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := int64(len(entries)), head.TreeSize; got != want {
+		t.Fatalf("expected %d entries, got %d", want, got)
+	}
+	if !bytes.Equal(entries[len(entries)-1].ChainHash, head.RootHash) {
+		t.Errorf("root hash does not match the last entry")
+	}
+
+	// Invalid start.
+	w = httptest.NewRecorder()
+	h.EntriesHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, EntriesPath+"?start=-1", nil))
+	if got, want := w.Code, http.StatusBadRequest; got != want {
+		t.Errorf("expected status %d to be %d", got, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(&Config{MaxEntries: 10}, nil, keys.TestKeyManager(t)); err == nil {
+		t.Errorf("expected error without signing key")
+	}
+	if _, err := New(&Config{SigningKey: "key", MaxEntries: 10}, nil, nil); err == nil {
+		t.Errorf("expected error without key manager")
+	}
+	if _, err := New(&Config{SigningKey: "key"}, nil, keys.TestKeyManager(t)); err == nil {
+		t.Errorf("expected error without max entries")
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction of the key transparency log.
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// KindHealthAuthorityKey is logged when a health authority key is added or
+	// changed. The data is a HealthAuthorityKey.
+	KindHealthAuthorityKey = "health_authority_key"
+
+	// KindExportSigningKey is logged when an export signing key is added or
+	// changed. The data is an ExportSigningKey.
+	KindExportSigningKey = "export_signing_key"
+)
+
+// Entry is an entry of the log. Leaf holds the exact bytes that were hashed,
+// so auditors can recompute the chain without knowing how it was encoded.
+type Entry struct {
+	Index     int64
+	Leaf      []byte
+	LeafHash  []byte
+	ChainHash []byte
+	CreatedAt time.Time
+}
+
+// Leaf is the content of an entry.
+type Leaf struct {
+	Index     int64           `json:"index"`
+	Kind      string          `json:"kind"`
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// HealthAuthorityKey records a health authority key.
+type HealthAuthorityKey struct {
+	HealthAuthorityID int64  `json:"healthAuthorityID"`
+	Version           string `json:"version"`
+	From              int64  `json:"from"`
+	Thru              int64  `json:"thru,omitempty"`
+	PublicKey         string `json:"publicKey"`
+	Stage             string `json:"stage"`
+	Algorithm         string `json:"algorithm,omitempty"`
+}
+
+// ExportSigningKey records an export signing key. The key itself is held by the
+// key manager, so its name and version are logged.
+type ExportSigningKey struct {
+	SignatureInfoID int64  `json:"signatureInfoID"`
+	SigningKey      string `json:"signingKey"`
+	KeyVersion      string `json:"keyVersion"`
+	KeyID           string `json:"keyID"`
+	ValidUntil      int64  `json:"validUntil,omitempty"`
+}
+
+// TreeHead describes the log at a point in time. RootHash is the chain hash of
+// the last entry, or empty if the log is empty.
+type TreeHead struct {
+	TreeSize  int64  `json:"treeSize"`
+	RootHash  []byte `json:"rootHash"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// NewEntry creates the entry that follows prev, which is nil for the first
+// entry of the log.
+func NewEntry(prev *Entry, kind string, data interface{}, now time.Time) (*Entry, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s data: %w", kind, err)
+	}
+
+	var index int64
+	var prevHash []byte
+	if prev != nil {
+		index = prev.Index + 1
+		prevHash = prev.ChainHash
+	}
+
+	now = now.UTC().Truncate(time.Microsecond)
+	leaf, err := json.Marshal(&Leaf{
+		Index:     index,
+		Kind:      kind,
+		Timestamp: now.Unix(),
+		Data:      b,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal leaf: %w", err)
+	}
+
+	leafHash := LeafHash(leaf)
+	return &Entry{
+		Index:     index,
+		Leaf:      leaf,
+		LeafHash:  leafHash,
+		ChainHash: ChainHash(prevHash, leafHash),
+		CreatedAt: now,
+	}, nil
+}
+
+// LeafHash returns SHA-256(leaf).
+func LeafHash(leaf []byte) []byte {
+	h := sha256.Sum256(leaf)
+	return h[:]
+}
+
+// ChainHash returns SHA-256(prev || leafHash). The prev hash of the first
+// entry is 32 zero bytes.
+func ChainHash(prev, leafHash []byte) []byte {
+	if len(prev) == 0 {
+		prev = make([]byte, sha256.Size)
+	}
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(leafHash)
+	return h.Sum(nil)
+}
+
+// VerifyChain checks that entries are consecutive and that each one is hashed
+// correctly and chained to the one before it. prev is the entry before the
+// first one, or nil if entries start at the beginning of the log.
+func VerifyChain(prev *Entry, entries []*Entry) error {
+	var index int64
+	var prevHash []byte
+	if prev != nil {
+		index = prev.Index + 1
+		prevHash = prev.ChainHash
+	}
+
+	for _, e := range entries {
+		if e.Index != index {
+			return fmt.Errorf("expected entry %d, got %d", index, e.Index)
+		}
+		if !bytes.Equal(LeafHash(e.Leaf), e.LeafHash) {
+			return fmt.Errorf("entry %d: leaf hash does not match", e.Index)
+		}
+		if !bytes.Equal(ChainHash(prevHash, e.LeafHash), e.ChainHash) {
+			return fmt.Errorf("entry %d: chain hash does not match", e.Index)
+		}
+		index++
+		prevHash = e.ChainHash
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testChain(t *testing.T, n int) []*Entry {
+	t.Helper()
+
+	var entries []*Entry
+	var prev *Entry
+	for i := 0; i < n; i++ {
+		e, err := NewEntry(prev, KindExportSigningKey, &ExportSigningKey{
+			SignatureInfoID: int64(i),
+			SigningKey:      "projects/p/keys/k",
+			KeyVersion:      "v1",
+			KeyID:           "310",
+		}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+		prev = e
+	}
+	return entries
+}
+
+func TestNewEntry(t *testing.T) {
+	t.Parallel()
+
+	entries := testChain(t, 3)
+	for i, e := range entries {
+		if got, want := e.Index, int64(i); got != want {
+			t.Errorf("entry %d: expected index %d, got %d", i, want, got)
+		}
+
+		var leaf Leaf
+		if err := json.Unmarshal(e.Leaf, &leaf); err != nil {
+			t.Fatal(err)
+		}
+		if leaf.Index != e.Index || leaf.Kind != KindExportSigningKey {
+			t.Errorf("entry %d: unexpected leaf %s", i, e.Leaf)
+		}
+	}
+
+	if err := VerifyChain(nil, entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChain(entries[0], entries[1:]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		tamper func(entries []*Entry) []*Entry
+	}{
+		{
+			name: "changed_leaf",
+			tamper: func(entries []*Entry) []*Entry {
+				entries[1].Leaf = bytes.Replace(entries[1].Leaf, []byte("310"), []byte("311"), 1)
+				return entries
+			},
+		},
+		{
+			name: "rehashed_leaf",
+			tamper: func(entries []*Entry) []*Entry {
+				entries[1].Leaf = bytes.Replace(entries[1].Leaf, []byte("310"), []byte("311"), 1)
+				entries[1].LeafHash = LeafHash(entries[1].Leaf)
+				return entries
+			},
+		},
+		{
+			name: "removed_entry",
+			tamper: func(entries []*Entry) []*Entry {
+				return append(entries[:1], entries[2:]...)
+			},
+		},
+		{
+			name: "reordered",
+			tamper: func(entries []*Entry) []*Entry {
+				entries[1], entries[2] = entries[2], entries[1]
+				return entries
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			entries := tc.tamper(testChain(t, 3))
+			if err := VerifyChain(nil, entries); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
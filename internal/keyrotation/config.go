@@ -17,6 +17,8 @@ package keyrotation
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/leader"
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/revision"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -39,6 +41,8 @@ type Config struct {
 	Database              database.Config
 	SecretManager         secrets.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
+	Leader                leader.Config
 	RevisionToken         revision.Config
 	KeyManager            keys.Config
 
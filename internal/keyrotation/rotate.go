@@ -41,6 +41,17 @@ func (s *Server) handleRotateKeys() http.Handler {
 		logger.Debugw("starting")
 		defer logger.Debugw("finishing")
 
+		// In an active/active deployment, only the region that holds the lease
+		// runs the job.
+		if ok, err := s.config.Leader.Lead(ctx, s.db, "key-rotation"); err != nil {
+			logger.Errorw("failed to acquire lease", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		} else if !ok {
+			s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("not leader"))
+			return
+		}
+
 		unlock, err := s.db.Lock(ctx, lockID, time.Minute)
 		if err != nil {
 			if errors.Is(err, database.ErrAlreadyLocked) {
@@ -0,0 +1,65 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leader runs recurring jobs in one place when the same service is
+// deployed in more than one region. Each job has a lease in the database. The
+// region that holds it runs the job and renews the lease; the other regions
+// skip the job until the lease expires.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+// Config is the configuration for leader election.
+type Config struct {
+	// Holder identifies this deployment, usually by its region. Leases are not
+	// used if it is empty, so every deployment runs every job.
+	Holder string `env:"LEADER_HOLDER"`
+
+	// LeaseDuration is how long a lease is held after the job last ran. It
+	// must be longer than the job's schedule interval, or the lease will move
+	// between regions, and is how long a job stops running after the holder
+	// fails.
+	LeaseDuration time.Duration `env:"LEADER_LEASE_DURATION, default=15m"`
+}
+
+// Enabled returns true if jobs are coordinated with leases.
+func (c *Config) Enabled() bool {
+	return c.Holder != ""
+}
+
+// Lead returns true if this deployment should run the named job, renewing its
+// lease. It returns false if another holder has the lease.
+func (c *Config) Lead(ctx context.Context, db *database.DB, job string) (bool, error) {
+	if !c.Enabled() {
+		return true, nil
+	}
+
+	holder, err := db.AcquireLease(ctx, job, c.Holder, c.LeaseDuration)
+	if err != nil {
+		if errors.Is(err, database.ErrLeaseHeld) {
+			logging.FromContext(ctx).Debugw("skipping, another holder leads", "job", job, "holder", holder)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire lease for %s: %w", job, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestConfig_Lead_Disabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	// Without a holder, every deployment leads and the database is not used.
+	var cfg Config
+	ok, err := cfg.Lead(ctx, nil, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Errorf("expected to lead")
+	}
+}
@@ -2,6 +2,7 @@
 package metricsregistrar
 
 import (
+	_ "github.com/google/exposure-notifications-server/internal/abuse"
 	_ "github.com/google/exposure-notifications-server/internal/backup"
 	_ "github.com/google/exposure-notifications-server/internal/cleanup"
 	_ "github.com/google/exposure-notifications-server/internal/export"
@@ -14,4 +15,5 @@ import (
 	_ "github.com/google/exposure-notifications-server/internal/mirror"
 	_ "github.com/google/exposure-notifications-server/internal/publish"
 	_ "github.com/google/exposure-notifications-server/internal/storage"
+	_ "github.com/google/exposure-notifications-server/pkg/database"
 )
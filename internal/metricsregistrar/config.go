@@ -15,6 +15,7 @@
 package metricsregistrar
 
 import (
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/observability"
 )
@@ -23,6 +24,7 @@ var _ setup.ObservabilityExporterConfigProvider = (*Config)(nil)
 
 type Config struct {
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
 
 	Port string `env:"PORT, default=8080"`
 }
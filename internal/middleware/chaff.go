@@ -24,9 +24,23 @@ import (
 // chaffHeader is the chaff header key.
 const chaffHeader = "X-Chaff"
 
-// ProcessChaff injects the chaff processing middleware.
-func ProcessChaff(t *chaff.Tracker) mux.MiddlewareFunc {
+// ProcessChaff injects the chaff processing middleware. Requests with the chaff
+// header, or for one of the given chaff paths, get a chaff response and are not
+// used to profile real responses.
+func ProcessChaff(t *chaff.Tracker, chaffPaths ...string) mux.MiddlewareFunc {
+	detector := chaff.DetectorFunc(func(r *http.Request) bool {
+		if r.Header.Get(chaffHeader) != "" {
+			return true
+		}
+		for _, p := range chaffPaths {
+			if r.URL.Path == p {
+				return true
+			}
+		}
+		return false
+	})
+
 	return func(next http.Handler) http.Handler {
-		return t.HandleTrack(chaff.HeaderDetector(chaffHeader), next)
+		return t.HandleTrack(detector, next)
 	}
 }
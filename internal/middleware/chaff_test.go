@@ -0,0 +1,83 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mikehelmick/go-chaff"
+)
+
+func TestProcessChaff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		chaff  bool
+	}{
+		{
+			name:  "real",
+			path:  "/v1/publish",
+			chaff: false,
+		},
+		{
+			name:   "header",
+			path:   "/v1/publish",
+			header: "1",
+			chaff:  true,
+		},
+		{
+			name:  "path",
+			path:  "/v1/chaff",
+			chaff: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tracker := chaff.New(chaff.WithMaxLatency(1))
+			t.Cleanup(tracker.Close)
+
+			r := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			if tc.header != "" {
+				r.Header.Set(chaffHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			called := false
+			ProcessChaff(tracker, "/v1/chaff")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusAccepted)
+			})).ServeHTTP(w, r)
+
+			if got, want := called, !tc.chaff; got != want {
+				t.Errorf("expected handler called to be %t", want)
+			}
+			if tc.chaff {
+				if got, want := w.Code, http.StatusOK; got != want {
+					t.Errorf("expected %d to be %d", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// contextKeyClientIP is the unique key in the context where the client IP is
+// stored.
+const contextKeyClientIP = contextKey("client_ip")
+
+// PopulateClientIP populates the request context with the client's IP address
+// as determined by ClientIP. If the address cannot be determined, none is
+// stored; it is up to handlers to decide whether that is an error.
+func PopulateClientIP(trustedProxies int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, err := ClientIP(r, trustedProxies); err == nil {
+				r = r.Clone(withClientIP(r.Context(), ip))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the IP address of the client that made the request. If
+// trustedProxies is zero, the address is the remote address of the connection.
+// Otherwise the request is expected to have passed through that many proxies,
+// each appending the address it received the request from to the
+// X-Forwarded-For header, and the client is the entry that many positions from
+// the right. Entries further left are set by the client and are ignored.
+func ClientIP(r *http.Request, trustedProxies int) (netip.Addr, error) {
+	if trustedProxies <= 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return parseAddr(host)
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) < trustedProxies {
+		return netip.Addr{}, fmt.Errorf("expected at least %d X-Forwarded-For entries, got %d", trustedProxies, len(hops))
+	}
+	return parseAddr(hops[len(hops)-trustedProxies])
+}
+
+func parseAddr(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid client address %q: %w", s, err)
+	}
+	return addr.Unmap(), nil
+}
+
+// ClientIPFromContext pulls the client IP from the context, if one was set.
+func ClientIPFromContext(ctx context.Context) (netip.Addr, bool) {
+	v := ctx.Value(contextKeyClientIP)
+	if v == nil {
+		return netip.Addr{}, false
+	}
+
+	t, ok := v.(netip.Addr)
+	return t, ok
+}
+
+// withClientIP sets the client IP on the provided context, returning a new
+// context.
+func withClientIP(ctx context.Context, ip netip.Addr) context.Context {
+	return context.WithValue(ctx, contextKeyClientIP, ip)
+}
+
+// ParseCIDRs parses a list of CIDR blocks like "10.0.0.0/8" or "2001:db8::/32".
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		p, err := netip.ParsePrefix(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// CIDRsContain returns true if any of the prefixes contains the address.
+func CIDRsContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
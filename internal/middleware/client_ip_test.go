@@ -0,0 +1,147 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		remote  string
+		xff     []string
+		proxies int
+		want    string
+		err     bool
+	}{
+		{
+			name:   "remote_addr",
+			remote: "192.0.2.1:1234",
+			xff:    []string{"198.51.100.1"},
+			want:   "192.0.2.1",
+		},
+		{
+			name:   "remote_addr_v6",
+			remote: "[2001:db8::1]:1234",
+			want:   "2001:db8::1",
+		},
+		{
+			name:    "one_proxy",
+			remote:  "10.0.0.1:1234",
+			xff:     []string{"198.51.100.1, 203.0.113.9"},
+			proxies: 1,
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "two_proxies_multiple_headers",
+			remote:  "10.0.0.1:1234",
+			xff:     []string{"198.51.100.1, 203.0.113.9", "10.1.1.1"},
+			proxies: 2,
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "mapped_v4",
+			remote:  "10.0.0.1:1234",
+			xff:     []string{"::ffff:203.0.113.9"},
+			proxies: 1,
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "too_few_hops",
+			remote:  "10.0.0.1:1234",
+			xff:     []string{"203.0.113.9"},
+			proxies: 2,
+			err:     true,
+		},
+		{
+			name:    "garbage",
+			remote:  "10.0.0.1:1234",
+			xff:     []string{"not-an-ip"},
+			proxies: 1,
+			err:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remote
+			for _, v := range tc.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			got, err := ClientIP(r, tc.proxies)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if tc.err {
+				return
+			}
+			if want := netip.MustParseAddr(tc.want); got != want {
+				t.Errorf("expected %v to be %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPopulateClientIP(t *testing.T) {
+	t.Parallel()
+
+	var got netip.Addr
+	var ok bool
+	h := PopulateClientIP(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok = ClientIPFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if !ok {
+		t.Fatal("expected client ip in context")
+	}
+	if want := netip.MustParseAddr("192.0.2.1"); got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+}
+
+func TestCIDRs(t *testing.T) {
+	t.Parallel()
+
+	prefixes, err := ParseCIDRs([]string{"203.0.113.7/24", " 2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CIDRsContain(prefixes, netip.MustParseAddr("203.0.113.200")) {
+		t.Errorf("expected address to be contained")
+	}
+	if CIDRsContain(prefixes, netip.MustParseAddr("198.51.100.1")) {
+		t.Errorf("expected address to not be contained")
+	}
+
+	if _, err := ParseCIDRs([]string{"203.0.113.7"}); err == nil {
+		t.Errorf("expected error for address without prefix length")
+	}
+}
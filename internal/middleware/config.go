@@ -0,0 +1,101 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// healthPath is the health check path of every service. It is not rate
+// limited, so an overloaded instance is not restarted for failing checks.
+const healthPath = "/health"
+
+// Config is the configuration for the middleware that is shared by all HTTP
+// services.
+type Config struct {
+	// Recovery returns a 500 instead of crashing when a handler panics.
+	Recovery bool `env:"MIDDLEWARE_RECOVERY, default=true"`
+
+	// RequestLogging logs the method, path, status, and duration of every
+	// request.
+	RequestLogging bool `env:"MIDDLEWARE_REQUEST_LOGGING, default=false"`
+
+	// RateLimit is the number of requests per second each instance accepts.
+	// Requests over the limit get a 429. Zero disables rate limiting.
+	RateLimit      float64 `env:"MIDDLEWARE_RATE_LIMIT, default=0"`
+	RateLimitBurst int     `env:"MIDDLEWARE_RATE_LIMIT_BURST, default=10"`
+
+	// MaxBodyBytes is the largest request body that is read. Zero allows any
+	// size.
+	MaxBodyBytes int64 `env:"MIDDLEWARE_MAX_BODY_BYTES, default=0"`
+
+	chaff   mux.MiddlewareFunc
+	limiter *rate.Limiter
+}
+
+// WithChaff returns a copy of the configuration that answers chaff requests
+// with mw. Chaff requests are answered directly after recovery, so they are
+// not logged, observed, or rate limited differently from real requests.
+func (c *Config) WithChaff(mw mux.MiddlewareFunc) *Config {
+	cp := *c
+	cp.chaff = mw
+	return &cp
+}
+
+// WithRateLimiter returns a copy of the configuration that rate limits with l
+// instead of a limiter built from RateLimit, for services that change the
+// limit while running.
+func (c *Config) WithRateLimiter(l *rate.Limiter) *Config {
+	cp := *c
+	cp.limiter = l
+	return &cp
+}
+
+// Chain returns the middleware for a service in the order they should be
+// applied. The service's own middleware runs last, after the shared
+// middleware has populated the request context.
+func (c *Config) Chain(logger *zap.SugaredLogger, service ...mux.MiddlewareFunc) []mux.MiddlewareFunc {
+	chain := make([]mux.MiddlewareFunc, 0, 8+len(service))
+	if c.Recovery {
+		chain = append(chain, Recovery())
+	}
+	if c.chaff != nil {
+		chain = append(chain, c.chaff)
+	}
+	chain = append(chain,
+		PopulateRequestID(),
+		PopulateObservability(),
+		PopulateLogger(logger))
+	if c.RequestLogging {
+		chain = append(chain, LogRequests())
+	}
+	switch {
+	case c.limiter != nil:
+		chain = append(chain, RateLimit(c.limiter, healthPath))
+	case c.RateLimit > 0:
+		chain = append(chain, RateLimit(NewRateLimiter(c.RateLimit, c.RateLimitBurst), healthPath))
+	}
+	if c.MaxBodyBytes > 0 {
+		chain = append(chain, LimitBody(c.MaxBodyBytes))
+	}
+	return append(chain, service...)
+}
+
+// Use applies the middleware of Chain to the router.
+func (c *Config) Use(r *mux.Router, logger *zap.SugaredLogger, service ...mux.MiddlewareFunc) {
+	r.Use(c.Chain(logger, service...)...)
+}
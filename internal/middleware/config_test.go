@@ -0,0 +1,193 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/gorilla/mux"
+	"github.com/mikehelmick/go-chaff"
+)
+
+func TestConfig_Chain(t *testing.T) {
+	t.Parallel()
+
+	logger := logging.NewLoggerFromEnv()
+
+	cases := []struct {
+		name string
+		cfg  *Config
+		want int
+	}{
+		{
+			name: "required_only",
+			cfg:  &Config{},
+			want: 3,
+		},
+		{
+			name: "all",
+			cfg: &Config{
+				Recovery:       true,
+				RequestLogging: true,
+				RateLimit:      10,
+				RateLimitBurst: 1,
+				MaxBodyBytes:   100,
+			},
+			want: 7,
+		},
+		{
+			name: "chaff_and_limiter",
+			cfg: (&Config{}).
+				WithChaff(Recovery()).
+				WithRateLimiter(NewRateLimiter(10, 1)),
+			want: 5,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			chain := tc.cfg.Chain(logger, PopulateClientIP(0))
+			if got, want := len(chain), tc.want+1; got != want {
+				t.Errorf("expected %d middleware, got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestConfig_Use(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Recovery:       true,
+		RateLimit:      0.001,
+		RateLimitBurst: 1,
+		MaxBodyBytes:   4,
+	}
+
+	r := mux.NewRouter()
+	cfg.Use(r, logging.NewLoggerFromEnv())
+	r.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if RequestIDFromContext(r.Context()) == "" {
+			t.Errorf("expected request ID to be set")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return w
+	}
+
+	if got, want := serve("body").Code, http.StatusAccepted; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	w := serve("body")
+	if got, want := w.Code, http.StatusTooManyRequests; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := w.Header().Get("Retry-After"), "1000"; got != want {
+		t.Errorf("expected Retry-After %q to be %q", got, want)
+	}
+}
+
+func TestConfig_Use_Order(t *testing.T) {
+	t.Parallel()
+
+	tracker := chaff.New(chaff.WithMaxLatency(1))
+	t.Cleanup(tracker.Close)
+
+	cfg := (&Config{
+		Recovery:       true,
+		RateLimit:      0.001,
+		RateLimitBurst: 1,
+	}).WithChaff(ProcessChaff(tracker))
+
+	r := mux.NewRouter()
+	cfg.Use(r, logging.NewLoggerFromEnv())
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	r.Handle("/", handler)
+	r.Handle(healthPath, handler)
+
+	serve := func(path string, isChaff bool) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if isChaff {
+			req.Header.Set(chaffHeader, "1")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Use up the rate limit.
+	if got, want := serve("/", false), http.StatusAccepted; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := serve("/", false), http.StatusTooManyRequests; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	// Chaff is answered before the rate limit applies.
+	if got, want := serve("/", true), http.StatusOK; got != want {
+		t.Errorf("chaff: expected %d to be %d", got, want)
+	}
+
+	// Health checks are not rate limited.
+	if got, want := serve(healthPath, false), http.StatusAccepted; got != want {
+		t.Errorf("health: expected %d to be %d", got, want)
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	t.Parallel()
+
+	handler := LimitBody(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "fits", body: "1234", want: http.StatusAccepted},
+		{name: "too_large", body: "12345", want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+			if got := w.Code; got != tc.want {
+				t.Errorf("expected %d to be %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+)
+
+// NewRateLimiter creates a limiter for RateLimit. See SetRateLimit.
+func NewRateLimiter(limit float64, burst int) *rate.Limiter {
+	l := rate.NewLimiter(rate.Inf, burst)
+	SetRateLimit(l, limit, burst)
+	return l
+}
+
+// SetRateLimit changes the limiter to allow limit requests per second, with
+// bursts of up to burst requests. A limit of zero or less allows all requests.
+func SetRateLimit(l *rate.Limiter, limit float64, burst int) {
+	if limit <= 0 {
+		l.SetLimit(rate.Inf)
+	} else {
+		l.SetLimit(rate.Limit(limit))
+	}
+	l.SetBurst(burst)
+}
+
+// RateLimit limits the requests handled to those allowed by the limiter.
+// Requests over the limit get a 429 with a Retry-After header. The limit
+// applies to the instance, not to each client. Requests for one of the given
+// exempt paths are not limited.
+func RateLimit(l *rate.Limiter, exemptPaths ...string) mux.MiddlewareFunc {
+	exempt := make(map[string]struct{}, len(exemptPaths))
+	for _, p := range exemptPaths {
+		exempt[p] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := exempt[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !l.Allow() {
+				retryAfter := int(math.Ceil(1 / float64(l.Limit())))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
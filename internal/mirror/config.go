@@ -17,6 +17,7 @@ package mirror
 import (
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -34,6 +35,7 @@ var (
 type Config struct {
 	Database              database.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
 	SecretManager         secrets.Config
 	Storage               storage.Config
 
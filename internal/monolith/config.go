@@ -0,0 +1,249 @@
+// Copyright 2020 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package monolith runs all of the key server services in a single process.
+// This is intended for small deployments that do not want to operate each
+// service independently.
+package monolith
+
+import (
+	"github.com/google/exposure-notifications-server/internal/abuse"
+	"github.com/google/exposure-notifications-server/internal/admin"
+	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	"github.com/google/exposure-notifications-server/internal/cdn"
+	"github.com/google/exposure-notifications-server/internal/cleanup"
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/exportimport"
+	"github.com/google/exposure-notifications-server/internal/exportverifier"
+	"github.com/google/exposure-notifications-server/internal/featureflag"
+	"github.com/google/exposure-notifications-server/internal/federationin"
+	"github.com/google/exposure-notifications-server/internal/federationout"
+	"github.com/google/exposure-notifications-server/internal/jwks"
+	"github.com/google/exposure-notifications-server/internal/keyrotation"
+	"github.com/google/exposure-notifications-server/internal/mirror"
+	"github.com/google/exposure-notifications-server/internal/publish"
+	"github.com/google/exposure-notifications-server/internal/publishconsumer"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/internal/revision"
+	"github.com/google/exposure-notifications-server/internal/settings"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"github.com/google/exposure-notifications-server/pkg/secrets"
+)
+
+// Compile-time check to assert this config matches requirements.
+var (
+	_ setup.AuthorizedAppConfigProvider         = (*Config)(nil)
+	_ setup.BlobstoreConfigProvider             = (*Config)(nil)
+	_ setup.CDNPurgerConfigProvider             = (*Config)(nil)
+	_ setup.DatabaseConfigProvider              = (*Config)(nil)
+	_ setup.KeyManagerConfigProvider            = (*Config)(nil)
+	_ setup.SecretManagerConfigProvider         = (*Config)(nil)
+	_ setup.ObservabilityExporterConfigProvider = (*Config)(nil)
+	_ setup.SettingsConfigProvider              = (*Config)(nil)
+	_ setup.FeatureFlagConfigProvider           = (*Config)(nil)
+	_ setup.QueueConfigProvider                 = (*Config)(nil)
+)
+
+// Config is the configuration for the monolith. Shared resources (database,
+// key manager, secrets, storage, and observability) are configured once at the
+// top level. Each service has its own configuration section, read from
+// environment variables with a service-specific prefix (e.g.
+// PUBLISH_MAX_KEYS_ON_PUBLISH).
+type Config struct {
+	AuthorizedApp         authorizedapp.Config
+	Database              database.Config
+	KeyManager            keys.Config
+	SecretManager         secrets.Config
+	Storage               storage.Config
+	CDN                   cdn.Config
+	ObservabilityExporter observability.Config
+	RevisionToken         revision.Config
+	Settings              settings.Config
+	FeatureFlag           featureflag.Config
+
+	// Queue enables queue mode for publish. The publish consumer is only
+	// started if a queue is configured.
+	Queue queue.Config
+
+	// Port is the port on which all HTTP services are served. Each service is
+	// mounted at its own subpath, except for publish which is mounted at the
+	// root.
+	Port string `env:"PORT, default=8080"`
+
+	// AdminPort is the port on which the admin console is served. The admin
+	// console is not started if this is empty.
+	AdminPort string `env:"ADMIN_CONSOLE_PORT"`
+
+	// FederationPort is the port on which the federationout gRPC service is
+	// served. The federation service is not started if this is empty.
+	FederationPort string `env:"FEDERATION_PORT"`
+
+	// MirrorMode runs the deployment as a mirror of its neighbors' keys. Only
+	// export-importer, export, export-verifier, cleanup, mirror, and the admin
+	// console are started; publish, federation, JWKS, key rotation, and the
+	// abuse detector are not. Imported keys are re-signed into this
+	// deployment's own exports, with export provenance enabled.
+	MirrorMode bool `env:"MIRROR_MODE, default=false"`
+
+	Publish         publish.Config         `env:",prefix=PUBLISH_"`
+	PublishConsumer publishconsumer.Config `env:",prefix=PUBLISH_CONSUMER_"`
+	AbuseDetector   abuse.Config           `env:",prefix=ABUSE_DETECTOR_"`
+	ExportVerifier  exportverifier.Config  `env:",prefix=EXPORT_VERIFIER_"`
+	Export          export.Config          `env:",prefix=EXPORT_"`
+	CleanupExport   cleanup.Config         `env:",prefix=CLEANUP_EXPORT_"`
+	CleanupExposure cleanup.Config         `env:",prefix=CLEANUP_EXPOSURE_"`
+	ExportImport    exportimport.Config    `env:",prefix=EXPORT_IMPORT_"`
+	FederationIn    federationin.Config    `env:",prefix=FEDERATION_IN_"`
+	FederationOut   federationout.Config   `env:",prefix=FEDERATION_OUT_"`
+	JWKS            jwks.Config            `env:",prefix=JWKS_"`
+	KeyRotation     keyrotation.Config     `env:",prefix=KEY_ROTATION_"`
+	Mirror          mirror.Config          `env:",prefix=MIRROR_"`
+	Admin           admin.Config           `env:",prefix=ADMIN_"`
+}
+
+// shareResources copies the top-level shared resource configuration into each
+// of the service configurations so they behave as if they had been configured
+// independently.
+func (c *Config) shareResources() {
+	c.Publish.AuthorizedApp = c.AuthorizedApp
+	c.Publish.Database = c.Database
+	c.Publish.KeyManager = c.KeyManager
+	c.Publish.SecretManager = c.SecretManager
+	c.Publish.ObservabilityExporter = c.ObservabilityExporter
+	c.Publish.RevisionToken = c.RevisionToken
+	c.Publish.Settings = c.Settings
+	c.Publish.FeatureFlag = c.FeatureFlag
+	c.Publish.Queue = c.Queue
+	c.Publish.Port = c.Port
+
+	c.PublishConsumer.Database = c.Database
+	c.PublishConsumer.SecretManager = c.SecretManager
+	c.PublishConsumer.ObservabilityExporter = c.ObservabilityExporter
+	c.PublishConsumer.Queue = c.Queue
+	c.PublishConsumer.Port = c.Port
+
+	c.AbuseDetector.Database = c.Database
+	c.AbuseDetector.SecretManager = c.SecretManager
+	c.AbuseDetector.ObservabilityExporter = c.ObservabilityExporter
+	c.AbuseDetector.Port = c.Port
+
+	c.ExportVerifier.Database = c.Database
+	c.ExportVerifier.KeyManager = c.KeyManager
+	c.ExportVerifier.SecretManager = c.SecretManager
+	c.ExportVerifier.ObservabilityExporter = c.ObservabilityExporter
+	c.ExportVerifier.Port = c.Port
+
+	c.Export.Database = c.Database
+	c.Export.KeyManager = c.KeyManager
+	c.Export.SecretManager = c.SecretManager
+	c.Export.Storage = c.Storage
+	c.Export.CDN = c.CDN
+	c.Export.FeatureFlag = c.FeatureFlag
+	c.Export.ObservabilityExporter = c.ObservabilityExporter
+	c.Export.Port = c.Port
+	if c.MirrorMode {
+		c.Export.Provenance = true
+	}
+
+	for _, cc := range []*cleanup.Config{&c.CleanupExport, &c.CleanupExposure} {
+		cc.Database = c.Database
+		cc.SecretManager = c.SecretManager
+		cc.Storage = c.Storage
+		cc.ObservabilityExporter = c.ObservabilityExporter
+		cc.Port = c.Port
+	}
+
+	c.ExportImport.Database = c.Database
+	c.ExportImport.SecretManager = c.SecretManager
+	c.ExportImport.ObservabilityExporter = c.ObservabilityExporter
+	c.ExportImport.Port = c.Port
+
+	c.FederationIn.Database = c.Database
+	c.FederationIn.SecretManager = c.SecretManager
+	c.FederationIn.ObservabilityExporter = c.ObservabilityExporter
+	c.FederationIn.Port = c.Port
+
+	c.FederationOut.Database = c.Database
+	c.FederationOut.SecretManager = c.SecretManager
+	c.FederationOut.ObservabilityExporter = c.ObservabilityExporter
+	c.FederationOut.Port = c.FederationPort
+
+	c.JWKS.Database = c.Database
+	c.JWKS.SecretManager = c.SecretManager
+	c.JWKS.ObservabilityExporter = c.ObservabilityExporter
+	c.JWKS.Port = c.Port
+
+	c.KeyRotation.Database = c.Database
+	c.KeyRotation.KeyManager = c.KeyManager
+	c.KeyRotation.SecretManager = c.SecretManager
+	c.KeyRotation.ObservabilityExporter = c.ObservabilityExporter
+	c.KeyRotation.RevisionToken = c.RevisionToken
+	c.KeyRotation.Port = c.Port
+
+	c.Mirror.Database = c.Database
+	c.Mirror.SecretManager = c.SecretManager
+	c.Mirror.Storage = c.Storage
+	c.Mirror.ObservabilityExporter = c.ObservabilityExporter
+	c.Mirror.Port = c.Port
+
+	c.Admin.Database = c.Database
+	c.Admin.KeyManager = c.KeyManager
+	c.Admin.SecretManager = c.SecretManager
+	c.Admin.Storage = c.Storage
+	c.Admin.Port = c.AdminPort
+}
+
+func (c *Config) AuthorizedAppConfig() *authorizedapp.Config {
+	return &c.AuthorizedApp
+}
+
+func (c *Config) BlobstoreConfig() *storage.Config {
+	return &c.Storage
+}
+
+func (c *Config) CDNPurgerConfig() *cdn.Config {
+	return &c.CDN
+}
+
+func (c *Config) QueueConfig() *queue.Config {
+	return &c.Queue
+}
+
+func (c *Config) DatabaseConfig() *database.Config {
+	return &c.Database
+}
+
+func (c *Config) KeyManagerConfig() *keys.Config {
+	return &c.KeyManager
+}
+
+func (c *Config) SecretManagerConfig() *secrets.Config {
+	return &c.SecretManager
+}
+
+func (c *Config) ObservabilityExporterConfig() *observability.Config {
+	return &c.ObservabilityExporter
+}
+
+func (c *Config) SettingsConfig() *settings.Config {
+	return &c.Settings
+}
+
+func (c *Config) FeatureFlagConfig() *featureflag.Config {
+	return &c.FeatureFlag
+}
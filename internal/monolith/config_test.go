@@ -0,0 +1,110 @@
+// Copyright 2020 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monolith
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+func TestConfig_Process(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	lookuper := envconfig.MapLookuper(map[string]string{
+		"PORT":                         "9000",
+		"ADMIN_CONSOLE_PORT":           "9001",
+		"FEDERATION_PORT":              "9002",
+		"DB_NAME":                      "monolith",
+		"BLOBSTORE":                    "FILESYSTEM",
+		"QUEUE":                        "MEMORY",
+		"REVISION_TOKEN_KEY_ID":        "revision-key",
+		"PUBLISH_MAX_KEYS_ON_PUBLISH":  "15",
+		"EXPORT_WORKER_TIMEOUT":        "1m",
+		"CLEANUP_EXPORT_CLEANUP_TTL":   "1h",
+		"CLEANUP_EXPOSURE_CLEANUP_TTL": "2h",
+	})
+
+	var cfg Config
+	if err := envconfig.ProcessWith(ctx, &cfg, lookuper); err != nil {
+		t.Fatal(err)
+	}
+	cfg.shareResources()
+
+	if got, want := cfg.Publish.MaxKeysOnPublish, uint(15); got != want {
+		t.Errorf("expected publish max keys %d to be %d", got, want)
+	}
+	if got, want := cfg.Export.WorkerTimeout, time.Minute; got != want {
+		t.Errorf("expected export worker timeout %s to be %s", got, want)
+	}
+	if got, want := cfg.CleanupExport.TTL, time.Hour; got != want {
+		t.Errorf("expected cleanup export ttl %s to be %s", got, want)
+	}
+	if got, want := cfg.CleanupExposure.TTL, 2*time.Hour; got != want {
+		t.Errorf("expected cleanup exposure ttl %s to be %s", got, want)
+	}
+
+	// Shared resources are copied into each service.
+	if got, want := cfg.Export.Database.Name, "monolith"; got != want {
+		t.Errorf("expected export database name %q to be %q", got, want)
+	}
+	if got, want := cfg.Mirror.Storage.Type, "FILESYSTEM"; got != want {
+		t.Errorf("expected mirror blobstore %q to be %q", got, want)
+	}
+	if got, want := cfg.KeyRotation.RevisionToken.KeyID, "revision-key"; got != want {
+		t.Errorf("expected key rotation revision key %q to be %q", got, want)
+	}
+	if got, want := cfg.Publish.RevisionToken.KeyID, "revision-key"; got != want {
+		t.Errorf("expected publish revision key %q to be %q", got, want)
+	}
+	if got, want := cfg.PublishConsumer.Queue.Type, "MEMORY"; got != want {
+		t.Errorf("expected publish consumer queue %q to be %q", got, want)
+	}
+
+	// Ports are assigned from the top-level config.
+	if got, want := cfg.Publish.Port, "9000"; got != want {
+		t.Errorf("expected publish port %q to be %q", got, want)
+	}
+	if got, want := cfg.Admin.Port, "9001"; got != want {
+		t.Errorf("expected admin port %q to be %q", got, want)
+	}
+	if got, want := cfg.FederationOut.Port, "9002"; got != want {
+		t.Errorf("expected federation port %q to be %q", got, want)
+	}
+}
+
+func TestConfig_MirrorMode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	lookuper := envconfig.MapLookuper(map[string]string{
+		"MIRROR_MODE": "true",
+	})
+
+	var cfg Config
+	if err := envconfig.ProcessWith(ctx, &cfg, lookuper); err != nil {
+		t.Fatal(err)
+	}
+	cfg.shareResources()
+
+	if !cfg.Export.Provenance {
+		t.Errorf("expected mirror mode to enable export provenance")
+	}
+}
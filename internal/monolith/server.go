@@ -0,0 +1,189 @@
+// Copyright 2020 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monolith
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/exposure-notifications-server/internal/abuse"
+
+	"github.com/google/exposure-notifications-server/internal/admin"
+	"github.com/google/exposure-notifications-server/internal/cleanup"
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/internal/exportimport"
+	"github.com/google/exposure-notifications-server/internal/exportverifier"
+	"github.com/google/exposure-notifications-server/internal/federationin"
+	"github.com/google/exposure-notifications-server/internal/federationout"
+	"github.com/google/exposure-notifications-server/internal/jwks"
+	"github.com/google/exposure-notifications-server/internal/keyrotation"
+	"github.com/google/exposure-notifications-server/internal/mirror"
+	"github.com/google/exposure-notifications-server/internal/publish"
+	"github.com/google/exposure-notifications-server/internal/publishconsumer"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+)
+
+// routable is a service which exposes HTTP routes.
+type routable interface {
+	Routes(ctx context.Context) *mux.Router
+}
+
+// Server hosts all services in a single process.
+type Server struct {
+	config *Config
+	env    *serverenv.ServerEnv
+
+	publish  *publish.Server
+	admin    *admin.Server
+	prefixed map[string]routable
+}
+
+// NewServer creates all of the services that make up the key server. The
+// provided config must have been processed by setup.
+func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
+	cfg.shareResources()
+
+	exportServer, err := export.NewServer(&cfg.Export, env)
+	if err != nil {
+		return nil, fmt.Errorf("export.NewServer: %w", err)
+	}
+
+	cleanupExportServer, err := cleanup.NewExportServer(&cfg.CleanupExport, env)
+	if err != nil {
+		return nil, fmt.Errorf("cleanup.NewExportServer: %w", err)
+	}
+
+	cleanupExposureServer, err := cleanup.NewExposureServer(&cfg.CleanupExposure, env)
+	if err != nil {
+		return nil, fmt.Errorf("cleanup.NewExposureServer: %w", err)
+	}
+
+	exportImportServer, err := exportimport.NewServer(&cfg.ExportImport, env)
+	if err != nil {
+		return nil, fmt.Errorf("exportimport.NewServer: %w", err)
+	}
+
+	exportVerifierServer, err := exportverifier.NewServer(&cfg.ExportVerifier, env)
+	if err != nil {
+		return nil, fmt.Errorf("exportverifier.NewServer: %w", err)
+	}
+
+	mirrorServer, err := mirror.NewServer(&cfg.Mirror, env)
+	if err != nil {
+		return nil, fmt.Errorf("mirror.NewServer: %w", err)
+	}
+
+	prefixed := map[string]routable{
+		"/cleanup-export":   cleanupExportServer,
+		"/cleanup-exposure": cleanupExposureServer,
+		"/export":           exportServer,
+		"/export-verifier":  exportVerifierServer,
+		"/export-importer":  exportImportServer,
+		"/mirror":           mirrorServer,
+	}
+
+	// A mirror only re-exports imported keys, so the services that accept,
+	// federate, or protect locally published keys are not started.
+	var publishServer *publish.Server
+	if !cfg.MirrorMode {
+		publishServer, err = publish.NewServer(ctx, &cfg.Publish, env)
+		if err != nil {
+			return nil, fmt.Errorf("publish.NewServer: %w", err)
+		}
+
+		federationInServer, err := federationin.NewServer(&cfg.FederationIn, env)
+		if err != nil {
+			return nil, fmt.Errorf("federationin.NewServer: %w", err)
+		}
+		prefixed["/federation-in"] = federationInServer
+
+		jwksServer, err := jwks.NewServer(&cfg.JWKS, env)
+		if err != nil {
+			return nil, fmt.Errorf("jwks.NewServer: %w", err)
+		}
+		prefixed["/jwks"] = jwksServer
+
+		abuseDetectorServer, err := abuse.NewServer(&cfg.AbuseDetector, env)
+		if err != nil {
+			return nil, fmt.Errorf("abuse.NewServer: %w", err)
+		}
+		prefixed["/abuse-detector"] = abuseDetectorServer
+
+		keyRotationServer, err := keyrotation.NewServer(&cfg.KeyRotation, env)
+		if err != nil {
+			return nil, fmt.Errorf("keyrotation.NewServer: %w", err)
+		}
+		prefixed["/key-rotation"] = keyRotationServer
+
+		if cfg.Queue.Enabled() {
+			publishConsumerServer, err := publishconsumer.NewServer(&cfg.PublishConsumer, env)
+			if err != nil {
+				return nil, fmt.Errorf("publishconsumer.NewServer: %w", err)
+			}
+			prefixed["/publish-consumer"] = publishConsumerServer
+		}
+	}
+
+	var adminServer *admin.Server
+	if cfg.AdminPort != "" {
+		adminServer, err = admin.NewServer(&cfg.Admin, env)
+		if err != nil {
+			return nil, fmt.Errorf("admin.NewServer: %w", err)
+		}
+	}
+
+	return &Server{
+		config:   cfg,
+		env:      env,
+		publish:  publishServer,
+		admin:    adminServer,
+		prefixed: prefixed,
+	}, nil
+}
+
+// Routes returns the HTTP handler for all HTTP services except for the admin
+// console. Each service is mounted at its own subpath (e.g. /export/do-work)
+// and publish, unless in mirror mode, is mounted at the root.
+func (s *Server) Routes(ctx context.Context) http.Handler {
+	r := mux.NewRouter()
+	for prefix, srv := range s.prefixed {
+		r.PathPrefix(prefix + "/").Handler(http.StripPrefix(prefix, srv.Routes(ctx)))
+	}
+	if s.publish != nil {
+		r.PathPrefix("/").Handler(s.publish.Routes(ctx))
+	}
+	return r
+}
+
+// AdminRoutes returns the HTTP handler for the admin console, or nil if the
+// admin console is not enabled.
+func (s *Server) AdminRoutes(ctx context.Context) http.Handler {
+	if s.admin == nil {
+		return nil
+	}
+	return s.admin.Routes(ctx)
+}
+
+// FederationServer returns the federationout gRPC server, or nil if
+// federation is not enabled. Mirrors do not serve federation.
+func (s *Server) FederationServer() (*grpc.Server, error) {
+	if s.config.FederationPort == "" || s.config.MirrorMode {
+		return nil, nil
+	}
+	return federationout.NewGRPCServer(s.env, &s.config.FederationOut)
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the configuration for the outbox relay.
+type Config struct {
+	// BatchSize is the number of events claimed at once.
+	BatchSize int `env:"OUTBOX_BATCH_SIZE, default=100"`
+
+	// Lease is how long a claimed event is reserved for the relay that claimed
+	// it. It should be longer than delivering a batch takes.
+	Lease time.Duration `env:"OUTBOX_LEASE, default=5m"`
+
+	// MaxAttempts is the number of times delivery of an event is attempted.
+	// Events that run out of attempts are kept, but not delivered.
+	MaxAttempts int `env:"OUTBOX_MAX_ATTEMPTS, default=10"`
+
+	// RetryBackoff is the delay before the first retry of an event. It doubles
+	// with each attempt, up to MaxRetryBackoff.
+	RetryBackoff    time.Duration `env:"OUTBOX_RETRY_BACKOFF, default=30s"`
+	MaxRetryBackoff time.Duration `env:"OUTBOX_MAX_RETRY_BACKOFF, default=1h"`
+
+	// Retention is how long delivered events are kept.
+	Retention time.Duration `env:"OUTBOX_RETENTION, default=72h"`
+
+	// WebhookURLs receive each event as a JSON POST request. If WebhookSecret is
+	// set, requests are signed with it.
+	WebhookURLs    []string      `env:"OUTBOX_WEBHOOK_URLS"`
+	WebhookSecret  string        `env:"OUTBOX_WEBHOOK_SECRET"`
+	WebhookTimeout time.Duration `env:"OUTBOX_WEBHOOK_TIMEOUT, default=10s"`
+}
+
+// Validate checks the relay configuration.
+func (c *Config) Validate() error {
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("OUTBOX_BATCH_SIZE must be positive, got %d", c.BatchSize)
+	}
+	if c.Lease <= 0 {
+		return fmt.Errorf("OUTBOX_LEASE must be positive, got %v", c.Lease)
+	}
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("OUTBOX_MAX_ATTEMPTS must be positive, got %d", c.MaxAttempts)
+	}
+	if c.RetryBackoff <= 0 || c.MaxRetryBackoff < c.RetryBackoff {
+		return fmt.Errorf("OUTBOX_RETRY_BACKOFF must be positive and at most OUTBOX_MAX_RETRY_BACKOFF")
+	}
+	return nil
+}
+
+// retryAfter returns the delay before the next attempt of an event that
+// failed for the given number of attempts.
+func (c *Config) retryAfter(attempts int) time.Duration {
+	d := c.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= c.MaxRetryBackoff {
+			return c.MaxRetryBackoff
+		}
+	}
+	return d
+}
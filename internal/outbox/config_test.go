@@ -0,0 +1,88 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_retryAfter(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		RetryBackoff:    30 * time.Second,
+		MaxRetryBackoff: 5 * time.Minute,
+	}
+
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 3, want: 2 * time.Minute},
+		{attempts: 4, want: 4 * time.Minute},
+		{attempts: 5, want: 5 * time.Minute},
+		{attempts: 50, want: 5 * time.Minute},
+	}
+
+	for _, tc := range cases {
+		if got := cfg.retryAfter(tc.attempts); got != tc.want {
+			t.Errorf("retryAfter(%d): expected %v, got %v", tc.attempts, tc.want, got)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *Config {
+		return &Config{
+			BatchSize:       100,
+			Lease:           time.Minute,
+			MaxAttempts:     10,
+			RetryBackoff:    time.Second,
+			MaxRetryBackoff: time.Minute,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(c *Config)
+		err    bool
+	}{
+		{name: "valid", mutate: func(c *Config) {}},
+		{name: "batch_size", mutate: func(c *Config) { c.BatchSize = 0 }, err: true},
+		{name: "lease", mutate: func(c *Config) { c.Lease = 0 }, err: true},
+		{name: "max_attempts", mutate: func(c *Config) { c.MaxAttempts = 0 }, err: true},
+		{name: "retry_backoff", mutate: func(c *Config) { c.RetryBackoff = 0 }, err: true},
+		{name: "max_retry_backoff", mutate: func(c *Config) { c.MaxRetryBackoff = time.Millisecond }, err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := valid()
+			tc.mutate(cfg)
+			if err := cfg.Validate(); (err != nil) != tc.err {
+				t.Errorf("expected error: %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface to the outbox.
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/outbox/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type OutboxDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *OutboxDB {
+	return &OutboxDB{
+		db: db,
+	}
+}
+
+// InsertEvent writes the event in the given transaction, so it is only
+// delivered if the transaction commits.
+func InsertEvent(ctx context.Context, tx pgx.Tx, e *model.Event) error {
+	row := tx.QueryRow(ctx, `
+		INSERT INTO
+			Outbox
+			(kind, payload, created_at, next_attempt_at)
+		VALUES
+			($1, $2, $3, $4)
+		RETURNING event_id
+	`, e.Kind, e.Payload, e.CreatedAt, e.NextAttemptAt)
+	if err := row.Scan(&e.ID); err != nil {
+		return fmt.Errorf("inserting outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimEvents returns up to limit events that are due for delivery, oldest
+// first. Each event counts an attempt and is not returned again until lease
+// has passed, so concurrent relays don't deliver the same event. Events that
+// reached maxAttempts are not returned.
+func (db *OutboxDB) ClaimEvents(ctx context.Context, limit int, lease time.Duration, maxAttempts int) ([]*model.Event, error) {
+	var events []*model.Event
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE
+				Outbox
+			SET
+				attempts = attempts + 1,
+				next_attempt_at = $1
+			WHERE
+				event_id IN (
+					SELECT
+						event_id
+					FROM
+						Outbox
+					WHERE
+						delivered_at IS NULL AND next_attempt_at <= $2 AND attempts < $3
+					ORDER BY
+						event_id
+					LIMIT $4
+					FOR UPDATE SKIP LOCKED
+				)
+			RETURNING
+				event_id, kind, payload, created_at, attempts, next_attempt_at, last_error, delivered_at
+		`, time.Now().Add(lease), time.Now(), maxAttempts, limit)
+		if err != nil {
+			return fmt.Errorf("claiming outbox events: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e model.Event
+			var lastError *string
+			if err := rows.Scan(&e.ID, &e.Kind, &e.Payload, &e.CreatedAt, &e.Attempts,
+				&e.NextAttemptAt, &lastError, &e.DeliveredAt); err != nil {
+				return fmt.Errorf("scanning outbox event: %w", err)
+			}
+			if lastError != nil {
+				e.LastError = *lastError
+			}
+			events = append(events, &e)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	// UPDATE ... RETURNING does not keep the order of the subquery.
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+// MarkDelivered records that the event was delivered.
+func (db *OutboxDB) MarkDelivered(ctx context.Context, id int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE
+				Outbox
+			SET
+				delivered_at = $1, last_error = NULL
+			WHERE
+				event_id = $2
+		`, time.Now(), id)
+		if err != nil {
+			return fmt.Errorf("marking outbox event delivered: %w", err)
+		}
+		if result.RowsAffected() != 1 {
+			return fmt.Errorf("no outbox event %d", id)
+		}
+		return nil
+	})
+}
+
+// MarkFailed records that delivering the event failed, and when it should be
+// attempted again.
+func (db *OutboxDB) MarkFailed(ctx context.Context, id int64, cause error, next time.Time) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE
+				Outbox
+			SET
+				last_error = $1, next_attempt_at = $2
+			WHERE
+				event_id = $3
+		`, cause.Error(), next, id)
+		if err != nil {
+			return fmt.Errorf("marking outbox event failed: %w", err)
+		}
+		if result.RowsAffected() != 1 {
+			return fmt.Errorf("no outbox event %d", id)
+		}
+		return nil
+	})
+}
+
+// DeleteDeliveredBefore deletes events that were delivered before the given
+// time. Returns the number of events deleted.
+func (db *OutboxDB) DeleteDeliveredBefore(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM
+				Outbox
+			WHERE
+				delivered_at < $1
+		`, before)
+		if err != nil {
+			return fmt.Errorf("deleting outbox events: %w", err)
+		}
+		count = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountUndelivered returns the number of events that were not delivered yet,
+// including the ones that ran out of attempts.
+func (db *OutboxDB) CountUndelivered(ctx context.Context) (int64, error) {
+	var count int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				COUNT(*)
+			FROM
+				Outbox
+			WHERE
+				delivered_at IS NULL
+		`)
+		return row.Scan(&count)
+	}); err != nil {
+		return 0, fmt.Errorf("counting outbox events: %w", err)
+	}
+	return count, nil
+}
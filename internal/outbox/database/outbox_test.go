@@ -0,0 +1,194 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/outbox/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+func insertEvents(ctx context.Context, tb testing.TB, db *database.DB, n int, now time.Time) []*model.Event {
+	tb.Helper()
+
+	events := make([]*model.Event, 0, n)
+	if err := db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		for i := 0; i < n; i++ {
+			e, err := model.NewEvent(model.KindKeysPublished, &model.KeysPublished{Inserted: uint32(i)}, now)
+			if err != nil {
+				return err
+			}
+			if err := InsertEvent(ctx, tx, e); err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return nil
+	}); err != nil {
+		tb.Fatal(err)
+	}
+	return events
+}
+
+func TestInsertEvent_Rollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	errAbort := errors.New("abort")
+	err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		e, err := model.NewEvent(model.KindKeysPublished, &model.KeysPublished{}, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := InsertEvent(ctx, tx, e); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected %v, got %v", errAbort, err)
+	}
+
+	count, err := New(testDB).CountUndelivered(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected no events after rollback, got %d", count)
+	}
+}
+
+func TestClaimEvents(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	outboxDB := New(testDB)
+
+	inserted := insertEvents(ctx, t, testDB, 3, time.Now().Add(-time.Minute))
+
+	// Claim the first two events.
+	got, err := outboxDB.ClaimEvents(ctx, 2, time.Hour, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(got))
+	}
+	for i, e := range got {
+		if e.ID != inserted[i].ID {
+			t.Errorf("event %d: expected id %d, got %d", i, inserted[i].ID, e.ID)
+		}
+		if e.Attempts != 1 {
+			t.Errorf("event %d: expected 1 attempt, got %d", i, e.Attempts)
+		}
+	}
+
+	// Claimed events are leased, so only the third is returned.
+	got, err = outboxDB.ClaimEvents(ctx, 10, time.Hour, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != inserted[2].ID {
+		t.Fatalf("expected only event %d, got %v", inserted[2].ID, got)
+	}
+
+	// Deliver one, fail another so it is due again.
+	if err := outboxDB.MarkDelivered(ctx, inserted[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := outboxDB.MarkFailed(ctx, inserted[1].ID, errors.New("webhook down"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = outboxDB.ClaimEvents(ctx, 10, time.Hour, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != inserted[1].ID {
+		t.Fatalf("expected only event %d, got %v", inserted[1].ID, got)
+	}
+	if got[0].Attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", got[0].Attempts)
+	}
+	if got, want := got[0].LastError, "webhook down"; got != want {
+		t.Errorf("expected last error %q, got %q", want, got)
+	}
+
+	// Events that ran out of attempts are not claimed.
+	if err := outboxDB.MarkFailed(ctx, inserted[1].ID, errors.New("webhook down"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	got, err = outboxDB.ClaimEvents(ctx, 10, time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no events, got %v", got)
+	}
+
+	count, err := outboxDB.CountUndelivered(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 undelivered events, got %d", count)
+	}
+}
+
+func TestDeleteDeliveredBefore(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	outboxDB := New(testDB)
+
+	inserted := insertEvents(ctx, t, testDB, 2, time.Now())
+	if err := outboxDB.MarkDelivered(ctx, inserted[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := outboxDB.DeleteDeliveredBefore(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected recent events to be kept, deleted %d", count)
+	}
+
+	count, err = outboxDB.DeleteDeliveredBefore(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 deleted event, got %d", count)
+	}
+
+	undelivered, err := outboxDB.CountUndelivered(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undelivered != 1 {
+		t.Errorf("expected undelivered event to be kept, got %d", undelivered)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const metricPrefix = metrics.MetricRoot + "outbox"
+
+var (
+	mSuccess     = stats.Int64(metricPrefix+"/success", "successful execution", stats.UnitDimensionless)
+	mDelivered   = stats.Int64(metricPrefix+"/delivered", "events delivered", stats.UnitDimensionless)
+	mFailed      = stats.Int64(metricPrefix+"/failed", "event delivery attempts that failed", stats.UnitDimensionless)
+	mExhausted   = stats.Int64(metricPrefix+"/exhausted", "events that ran out of delivery attempts", stats.UnitDimensionless)
+	mUndelivered = stats.Int64(metricPrefix+"/undelivered", "events not delivered yet", stats.UnitDimensionless)
+
+	kindTag = tag.MustNewKey("kind")
+)
+
+func init() {
+	observability.CollectViews([]*view.View{
+		{
+			Name:        metricPrefix + "/success",
+			Description: "Number of successes",
+			Measure:     mSuccess,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/delivered",
+			Description: "Number of events delivered",
+			Measure:     mDelivered,
+			TagKeys:     []tag.Key{kindTag},
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/failed",
+			Description: "Number of event delivery attempts that failed",
+			Measure:     mFailed,
+			TagKeys:     []tag.Key{kindTag},
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/exhausted",
+			Description: "Number of events that ran out of delivery attempts",
+			Measure:     mExhausted,
+			TagKeys:     []tag.Key{kindTag},
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/undelivered",
+			Description: "Number of events not delivered yet, including the ones that ran out of attempts",
+			Measure:     mUndelivered,
+			Aggregation: view.LastValue(),
+		},
+	}...)
+}
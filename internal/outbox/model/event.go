@@ -0,0 +1,89 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction of outbox events.
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// KindKeysPublished is recorded when publish writes the keys of an upload.
+	// The payload is a KeysPublished.
+	KindKeysPublished = "keys_published"
+
+	// KindExportPublished is recorded when an export batch is complete. The
+	// payload is an ExportPublished.
+	KindExportPublished = "export_published"
+)
+
+// Event is a side effect of a database write that is delivered after the
+// write is committed.
+type Event struct {
+	ID            int64
+	Kind          string
+	Payload       json.RawMessage
+	CreatedAt     time.Time
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	DeliveredAt   *time.Time
+}
+
+// NewEvent creates an event of the given kind with the payload encoded as
+// JSON.
+func NewEvent(kind string, payload interface{}, now time.Time) (*Event, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", kind, err)
+	}
+	now = now.UTC().Truncate(time.Microsecond)
+	return &Event{
+		Kind:          kind,
+		Payload:       b,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// KeysPublished describes the keys written by a single upload.
+type KeysPublished struct {
+	AppPackageName    string `json:"appPackageName"`
+	HealthAuthorityID *int64 `json:"healthAuthorityID,omitempty"`
+	Inserted          uint32 `json:"inserted"`
+	Revised           uint32 `json:"revised"`
+	Quarantined       uint32 `json:"quarantined,omitempty"`
+	Extended          uint32 `json:"extended,omitempty"`
+}
+
+// ExportPublished describes a completed export batch.
+type ExportPublished struct {
+	ConfigID     int64    `json:"configID"`
+	BatchID      int64    `json:"batchID"`
+	BucketName   string   `json:"bucketName"`
+	OutputRegion string   `json:"outputRegion"`
+	Files        []string `json:"files"`
+
+	// Paths are the objects whose cached copies are stale, such as the index
+	// file and its signature.
+	Paths []string `json:"paths"`
+
+	// RunID is the run ID of the worker run that exported the batch, and
+	// BatchRunID the run ID of the batcher run that created it.
+	RunID      string `json:"runID,omitempty"`
+	BatchRunID string `json:"batchRunID,omitempty"`
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package outbox delivers side effects that were written to the database in
+// the same transaction as the change they describe. Events are delivered at
+// least once, and retried with backoff until they succeed or run out of
+// attempts.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/cdn"
+	outboxdb "github.com/google/exposure-notifications-server/internal/outbox/database"
+	"github.com/google/exposure-notifications-server/internal/outbox/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// Handler delivers an event. If it returns an error, the event is attempted
+// again later.
+type Handler func(ctx context.Context, e *model.Event) error
+
+// Relay delivers the events in the outbox.
+type Relay struct {
+	config   *Config
+	db       *outboxdb.OutboxDB
+	handlers map[string]Handler
+}
+
+// NewRelay creates a relay. Published keys are sent to the webhooks. Published
+// exports purge the CDN cache of the changed paths and are then sent to the
+// webhooks.
+func NewRelay(cfg *Config, db *database.DB, purger cdn.Purger) (*Relay, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	hooks := newWebhooks(cfg)
+	return &Relay{
+		config: cfg,
+		db:     outboxdb.New(db),
+		handlers: map[string]Handler{
+			model.KindKeysPublished: hooks.send,
+			model.KindExportPublished: func(ctx context.Context, e *model.Event) error {
+				var payload model.ExportPublished
+				if err := json.Unmarshal(e.Payload, &payload); err != nil {
+					return fmt.Errorf("failed to unmarshal payload: %w", err)
+				}
+				if len(payload.Paths) > 0 {
+					if err := purger.Purge(ctx, payload.Paths); err != nil {
+						return fmt.Errorf("failed to purge cdn cache: %w", err)
+					}
+				}
+				return hooks.send(ctx, e)
+			},
+		},
+	}, nil
+}
+
+// Deliver delivers the events that are due, in batches, until none are left
+// or the context is done. Delivered events older than the retention are
+// deleted afterwards.
+func (r *Relay) Deliver(ctx context.Context) error {
+	logger := logging.FromContext(ctx).Named("outbox.Deliver")
+
+	for ctx.Err() == nil {
+		events, err := r.db.ClaimEvents(ctx, r.config.BatchSize, r.config.Lease, r.config.MaxAttempts)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if err := r.deliver(ctx, e); err != nil {
+				return err
+			}
+		}
+
+		if len(events) < r.config.BatchSize {
+			break
+		}
+	}
+
+	count, err := r.db.DeleteDeliveredBefore(ctx, time.Now().Add(-r.config.Retention))
+	if err != nil {
+		return err
+	}
+	logger.Debugw("deleted delivered events", "count", count)
+
+	undelivered, err := r.db.CountUndelivered(ctx)
+	if err != nil {
+		return err
+	}
+	stats.Record(ctx, mUndelivered.M(undelivered), mSuccess.M(1))
+
+	return nil
+}
+
+// deliver calls the handler for the event and records the outcome. It only
+// returns an error if the outcome could not be recorded.
+func (r *Relay) deliver(ctx context.Context, e *model.Event) error {
+	logger := logging.FromContext(ctx).Named("outbox.deliver").
+		With("event", e.ID, "kind", e.Kind, "attempt", e.Attempts)
+	ctx, _ = tag.New(ctx, tag.Upsert(kindTag, e.Kind))
+
+	err := fmt.Errorf("no handler for event kind %q", e.Kind)
+	if handler, ok := r.handlers[e.Kind]; ok {
+		err = handler(ctx, e)
+	}
+
+	if err == nil {
+		if err := r.db.MarkDelivered(ctx, e.ID); err != nil {
+			return err
+		}
+		logger.Debugw("delivered event")
+		stats.Record(ctx, mDelivered.M(1))
+		return nil
+	}
+
+	stats.Record(ctx, mFailed.M(1))
+	if e.Attempts >= r.config.MaxAttempts {
+		logger.Errorw("giving up on event", "error", err)
+		stats.Record(ctx, mExhausted.M(1))
+	} else {
+		logger.Warnw("failed to deliver event", "error", err)
+	}
+	return r.db.MarkFailed(ctx, e.ID, err, time.Now().Add(r.config.retryAfter(e.Attempts)))
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/outbox/model"
+)
+
+const (
+	// HeaderEventID carries the ID of the event. Receivers should use it to
+	// ignore events they already processed, since an event is delivered again
+	// if any of the webhooks fails.
+	HeaderEventID = "X-Event-ID"
+
+	// HeaderSignature and HeaderSignatureTimestamp carry the signature of a
+	// webhook request: base64(HMAC-SHA256(secret, timestamp + "." + body)).
+	HeaderSignature          = "X-Signature"
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+)
+
+// webhookBody is the body of a webhook request.
+type webhookBody struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// webhooks posts events to the configured URLs.
+type webhooks struct {
+	client *http.Client
+	urls   []string
+	secret []byte
+}
+
+func newWebhooks(cfg *Config) *webhooks {
+	return &webhooks{
+		client: &http.Client{Timeout: cfg.WebhookTimeout},
+		urls:   cfg.WebhookURLs,
+		secret: []byte(cfg.WebhookSecret),
+	}
+}
+
+// send posts the event to each URL. It fails if any of the requests fail.
+func (w *webhooks) send(ctx context.Context, e *model.Event) error {
+	if len(w.urls) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(&webhookBody{
+		ID:        e.ID,
+		Kind:      e.Kind,
+		CreatedAt: e.CreatedAt,
+		Payload:   e.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook body: %w", err)
+	}
+
+	for _, u := range w.urls {
+		if err := w.post(ctx, u, e.ID, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *webhooks) post(ctx context.Context, u string, id int64, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, strconv.FormatInt(id, 10))
+	if len(w.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderSignatureTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(webhookSignature(w.secret, timestamp, body)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to post webhook to %s: status %d: %s", req.URL.Host, resp.StatusCode, b)
+	}
+	return nil
+}
+
+// webhookSignature computes HMAC-SHA256(secret, timestamp + "." + body).
+func webhookSignature(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/outbox/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWebhooks_send(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	event, err := model.NewEvent(model.KindKeysPublished, &model.KeysPublished{
+		AppPackageName: "com.example.app",
+		Inserted:       14,
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.ID = 42
+
+	secret := "hunter2"
+	var got webhookBody
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if got, want := r.Header.Get(HeaderEventID), "42"; got != want {
+			t.Errorf("expected %s %q, got %q", HeaderEventID, want, got)
+		}
+		sig, err := base64.StdEncoding.DecodeString(r.Header.Get(HeaderSignature))
+		if err != nil {
+			t.Error(err)
+		}
+		want := webhookSignature([]byte(secret), r.Header.Get(HeaderSignatureTimestamp), body)
+		if !hmac.Equal(sig, want) {
+			t.Errorf("signature does not match")
+		}
+
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	hooks := newWebhooks(&Config{
+		WebhookURLs:    []string{srv.URL},
+		WebhookSecret:  secret,
+		WebhookTimeout: 5 * time.Second,
+	})
+	if err := hooks.send(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	want := webhookBody{
+		ID:        event.ID,
+		Kind:      event.Kind,
+		CreatedAt: event.CreatedAt,
+		Payload:   event.Payload,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWebhooks_sendError(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	hooks := newWebhooks(&Config{
+		WebhookURLs:    []string{srv.URL},
+		WebhookTimeout: 5 * time.Second,
+	})
+	if err := hooks.send(ctx, &model.Event{ID: 1, Kind: model.KindKeysPublished}); err == nil {
+		t.Fatal("expected error")
+	}
+}
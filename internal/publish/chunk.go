@@ -0,0 +1,100 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	"github.com/google/exposure-notifications-server/internal/verification"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkChunk validates the chunk fields of a publish request against the
+// chunked upload state carried in the revision token. It returns the chunked
+// upload state for this request, or nil if the request is not part of a
+// chunked upload.
+func (s *Server) checkChunk(data *verifyapi.Publish, token *pb.RevisionTokenData, appPackageName string, now time.Time) (*pb.ChunkedUpload, error) {
+	if data.Chunk == 0 && data.Of == 0 {
+		return nil, nil
+	}
+
+	if data.Chunk < 1 || data.Of < 1 || data.Chunk > data.Of {
+		return nil, fmt.Errorf("chunk %d of %d is invalid", data.Chunk, data.Of)
+	}
+	if max := s.startupConfig.MaxUploadChunks; uint(data.Of) > max {
+		return nil, fmt.Errorf("upload cannot be split into more than %d chunks", max)
+	}
+
+	if data.Chunk == 1 {
+		return &pb.ChunkedUpload{
+			Chunk:          1,
+			Of:             data.Of,
+			AppPackageName: appPackageName,
+			ExpiresAt:      now.Add(s.startupConfig.ChunkedUploadTimeout).Unix(),
+		}, nil
+	}
+
+	previous := token.GetChunkedUpload()
+	if previous == nil {
+		return nil, fmt.Errorf("chunk %d of %d requires the revision token from the previous chunk", data.Chunk, data.Of)
+	}
+	if previous.Of != data.Of || previous.Chunk+1 != data.Chunk {
+		return nil, fmt.Errorf("expected chunk %d of %d, got chunk %d of %d",
+			previous.Chunk+1, previous.Of, data.Chunk, data.Of)
+	}
+	if previous.AppPackageName != appPackageName {
+		return nil, fmt.Errorf("chunked upload was started by a different health authority")
+	}
+	if now.Unix() > previous.ExpiresAt {
+		return nil, fmt.Errorf("chunked upload expired")
+	}
+
+	chunk := proto.Clone(previous).(*pb.ChunkedUpload)
+	chunk.Chunk = data.Chunk
+	return chunk, nil
+}
+
+// isContinuation returns true if the chunk continues a previous chunk of the
+// same upload.
+func isContinuation(chunk *pb.ChunkedUpload) bool {
+	return chunk != nil && chunk.Chunk > 1
+}
+
+// chunkClaims returns the claims that were verified on the first chunk of an
+// upload.
+func chunkClaims(chunk *pb.ChunkedUpload) *verification.VerifiedClaims {
+	if chunk.HealthAuthorityID == 0 && chunk.ReportType == "" && chunk.SymptomOnsetInterval == 0 {
+		return nil
+	}
+	return &verification.VerifiedClaims{
+		HealthAuthorityID:    chunk.HealthAuthorityID,
+		ReportType:           chunk.ReportType,
+		SymptomOnsetInterval: chunk.SymptomOnsetInterval,
+	}
+}
+
+// setChunkClaims records the claims verified on the first chunk of an upload
+// so they can be applied to subsequent chunks.
+func setChunkClaims(chunk *pb.ChunkedUpload, claims *verification.VerifiedClaims) {
+	if chunk == nil || claims == nil {
+		return
+	}
+	chunk.HealthAuthorityID = claims.HealthAuthorityID
+	chunk.ReportType = claims.ReportType
+	chunk.SymptomOnsetInterval = claims.SymptomOnsetInterval
+}
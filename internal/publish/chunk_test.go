@@ -0,0 +1,180 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	"github.com/google/exposure-notifications-server/internal/verification"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestCheckChunk(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1600000000, 0)
+	s := &Server{
+		startupConfig: &Config{
+			MaxUploadChunks:      3,
+			ChunkedUploadTimeout: time.Hour,
+		},
+	}
+
+	inProgress := &pb.RevisionTokenData{
+		ChunkedUpload: &pb.ChunkedUpload{
+			Chunk:             1,
+			Of:                3,
+			AppPackageName:    "com.example.app",
+			HealthAuthorityID: 12,
+			ReportType:        verifyapi.ReportTypeConfirmed,
+			ExpiresAt:         now.Add(time.Hour).Unix(),
+		},
+	}
+
+	cases := []struct {
+		name  string
+		chunk int32
+		of    int32
+		token *pb.RevisionTokenData
+		app   string
+		want  *pb.ChunkedUpload
+		err   string
+	}{
+		{
+			name: "not_chunked",
+		},
+		{
+			name:  "first",
+			chunk: 1,
+			of:    3,
+			want: &pb.ChunkedUpload{
+				Chunk:          1,
+				Of:             3,
+				AppPackageName: "com.example.app",
+				ExpiresAt:      now.Add(time.Hour).Unix(),
+			},
+		},
+		{
+			name:  "continued",
+			chunk: 2,
+			of:    3,
+			token: inProgress,
+			want: &pb.ChunkedUpload{
+				Chunk:             2,
+				Of:                3,
+				AppPackageName:    "com.example.app",
+				HealthAuthorityID: 12,
+				ReportType:        verifyapi.ReportTypeConfirmed,
+				ExpiresAt:         now.Add(time.Hour).Unix(),
+			},
+		},
+		{
+			name:  "chunk_too_large",
+			chunk: 4,
+			of:    3,
+			err:   "chunk 4 of 3 is invalid",
+		},
+		{
+			name:  "missing_of",
+			chunk: 1,
+			err:   "chunk 1 of 0 is invalid",
+		},
+		{
+			name:  "too_many_chunks",
+			chunk: 1,
+			of:    4,
+			err:   "more than 3 chunks",
+		},
+		{
+			name:  "missing_token",
+			chunk: 2,
+			of:    3,
+			err:   "requires the revision token",
+		},
+		{
+			name:  "out_of_order",
+			chunk: 3,
+			of:    3,
+			token: inProgress,
+			err:   "expected chunk 2 of 3",
+		},
+		{
+			name:  "different_app",
+			chunk: 2,
+			of:    3,
+			token: inProgress,
+			app:   "com.example.other",
+			err:   "different health authority",
+		},
+		{
+			name:  "expired",
+			chunk: 2,
+			of:    3,
+			token: &pb.RevisionTokenData{
+				ChunkedUpload: &pb.ChunkedUpload{
+					Chunk:          1,
+					Of:             3,
+					AppPackageName: "com.example.app",
+					ExpiresAt:      now.Add(-time.Second).Unix(),
+				},
+			},
+			err: "expired",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := tc.app
+			if app == "" {
+				app = "com.example.app"
+			}
+
+			data := &verifyapi.Publish{Chunk: tc.chunk, Of: tc.of}
+			got, err := s.checkChunk(data, tc.token, app, now)
+			errcmp.MustMatch(t, err, tc.err)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChunkClaims(t *testing.T) {
+	t.Parallel()
+
+	chunk := &pb.ChunkedUpload{Chunk: 1, Of: 2}
+	if got := chunkClaims(chunk); got != nil {
+		t.Errorf("expected no claims, got %#v", got)
+	}
+
+	want := &verification.VerifiedClaims{
+		HealthAuthorityID:    7,
+		ReportType:           verifyapi.ReportTypeClinical,
+		SymptomOnsetInterval: 2650000,
+	}
+	setChunkClaims(chunk, want)
+	if diff := cmp.Diff(want, chunkClaims(chunk)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -16,13 +16,25 @@
 package publish
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	"github.com/google/exposure-notifications-server/internal/capture"
+	"github.com/google/exposure-notifications-server/internal/clientconfig"
+	"github.com/google/exposure-notifications-server/internal/discovery"
+	"github.com/google/exposure-notifications-server/internal/dp"
+	"github.com/google/exposure-notifications-server/internal/featureflag"
+	"github.com/google/exposure-notifications-server/internal/i18n"
+	"github.com/google/exposure-notifications-server/internal/keylog"
 	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/quarantine"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/internal/region"
 	"github.com/google/exposure-notifications-server/internal/revision"
+	"github.com/google/exposure-notifications-server/internal/settings"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/internal/verification"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -41,6 +53,9 @@ var (
 	_ model.TransformerConfig                   = (*Config)(nil)
 	_ setup.KeyManagerConfigProvider            = (*Config)(nil)
 	_ middleware.Maintainable                   = (*Config)(nil)
+	_ setup.SettingsConfigProvider              = (*Config)(nil)
+	_ setup.FeatureFlagConfigProvider           = (*Config)(nil)
+	_ setup.QueueConfigProvider                 = (*Config)(nil)
 )
 
 // Config represents the configuration and associated environment variables for
@@ -52,7 +67,21 @@ type Config struct {
 	KeyManager            keys.Config
 	Verification          verification.Config
 	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
 	RevisionToken         revision.Config
+	Discovery             discovery.Config
+	ClientConfig          clientconfig.Config
+	KeyLog                keylog.Config
+	I18n                  i18n.Config
+	Regions               region.Config
+	Quarantine            quarantine.Config
+	Capture               capture.Config
+	Settings              settings.Config
+	FeatureFlag           featureflag.Config
+
+	// Queue enables queue mode. When set, accepted uploads are sent to the
+	// queue and written to the database by the publish consumer.
+	Queue queue.Config
 
 	Port        string `env:"PORT, default=8080"`
 	Maintenance bool   `env:"MAINTENANCE_MODE, default=false"`
@@ -78,6 +107,12 @@ type Config struct {
 	ResponsePaddingMinBytes int64 `env:"RESPONSE_PADDING_MIN_BYTES, default=1024"`
 	ResponsePaddingRange    int64 `env:"RESPONSE_PADDING_RANGE, default=1024"`
 
+	// RejectMinDuration is the least time a rejected publish request takes.
+	// Rejections are delayed until then, so that the reason for a rejection,
+	// e.g. an unknown app or an invalid certificate, can not be told apart by
+	// timing. 0 disables the delay.
+	RejectMinDuration time.Duration `env:"REJECT_MIN_DURATION, default=250ms"`
+
 	RevisionKeyCacheDuration time.Duration `env:"REVISION_KEY_CACHE_DURATION, default=1m"`
 
 	// AllowPartialRevisions permits uploading multiple exposure keys with a
@@ -86,6 +121,47 @@ type Config struct {
 	// uploaded and the remainder are discarded.
 	AllowPartialRevisions bool `env:"ALLOW_PARTIAL_REVISIONS, default=false"`
 
+	// SameDayPolicy decides what happens to keys that are uploaded while they
+	// are still valid, one of EMBARGO, EMBARGO_DAY_END, RELEASE or REJECT.
+	// Authorized apps may override it. SameDayReleaseDelay is added to any
+	// embargo.
+	SameDayPolicy       model.SameDayKeyPolicy `env:"SAME_DAY_KEY_POLICY, default=EMBARGO"`
+	SameDayReleaseDelay time.Duration          `env:"SAME_DAY_KEY_RELEASE_DELAY, default=0"`
+
+	// ReleaseExtendedKeys allows a key that was uploaded with a partial rolling
+	// period to be uploaded again with a longer one. The stored key is extended
+	// and released again, subject to the same day key policy. If revision
+	// tokens are required, the key must be in the token.
+	ReleaseExtendedKeys bool `env:"RELEASE_EXTENDED_KEYS, default=false"`
+
+	// MaxUploadChunks is the maximum number of requests a client may split a
+	// single upload across, using the `chunk` and `of` fields. Subsequent chunks
+	// are authorized by the revision token returned from the previous chunk and
+	// must arrive within ChunkedUploadTimeout of the first chunk. A value of 1
+	// disables chunked uploads.
+	MaxUploadChunks      uint          `env:"MAX_UPLOAD_CHUNKS, default=4"`
+	ChunkedUploadTimeout time.Duration `env:"CHUNKED_UPLOAD_TIMEOUT, default=1h"`
+
+	// RequestSignatureMaxSkew is how far the timestamp of a signed publish
+	// request may be from the server's clock. Only applies to authorized apps
+	// with a request signing secret.
+	RequestSignatureMaxSkew time.Duration `env:"REQUEST_SIGNATURE_MAX_SKEW, default=5m"`
+
+	// TrustedProxyCount is the number of proxies in front of the server that
+	// append to the X-Forwarded-For header. It is used to find the client's
+	// address for health authorities with allowed networks. If 0, the address
+	// of the connection is used.
+	TrustedProxyCount int `env:"TRUSTED_PROXY_COUNT, default=0"`
+
+	// RecordAbuseStats records the metadata of each publish request for the
+	// abuse detector. The keys themselves are not recorded.
+	RecordAbuseStats bool `env:"RECORD_ABUSE_STATS, default=true"`
+
+	// RecordPublishEvents writes an event to the outbox for each upload, in the
+	// same transaction as its keys. The outbox relay sends the events to the
+	// configured webhooks.
+	RecordPublishEvents bool `env:"RECORD_PUBLISH_EVENTS, default=false"`
+
 	// API Versions.
 	EnableV1Alpha1API bool `env:"ENABLE_V1ALPHA1_API, default=false"`
 
@@ -93,6 +169,7 @@ type Config struct {
 	// has no regions configured, then this default will be assumed.
 	// This is present for an upgrade edgecase where empty region list used to mean "all regions"
 	// Should only be set if a server is being operated in a single region.
+	// It must be an ISO 3166 code or one of CUSTOM_REGIONS.
 	DefaultRegion string `env:"DEFAULT_REGION"`
 
 	// LogJSONParseErrors will log errors from parsoning incoming requests if enabled.
@@ -101,7 +178,7 @@ type Config struct {
 
 	// Flags for local development and testing. This will cause still valid keys
 	// to not be embargoed.
-	// Normally "still valid" keys can be accepted, but are embargoed.
+	// Deprecated: ReleaseSameDayKeys is the same as SAME_DAY_KEY_POLICY=RELEASE.
 	ReleaseSameDayKeys      bool `env:"DEBUG_RELEASE_SAME_DAY_KEYS"`
 	DebugLogBadCertificates bool `env:"DEBUG_LOG_BAD_CERTIFICATES"`
 
@@ -117,6 +194,15 @@ type Config struct {
 	StatsResponsePaddingMinBytes int64         `env:"RESPONSE_PADDING_MIN_BYTES, default=2048"`
 	StatsResponsePaddingRange    int64         `env:"RESPONSE_PADDING_RANGE, default=1024"`
 
+	// UploadMetadataStats adds the keys per upload and the per report type
+	// onset to upload distributions to the stats. Uploads are only counted in
+	// the hourly stats, nothing is stored per upload.
+	UploadMetadataStats bool `env:"UPLOAD_METADATA_STATS, default=false"`
+
+	// StatsPrivacy optionally adds differential privacy noise to the stats API,
+	// for health authorities that publish their stats.
+	StatsPrivacy dp.Config
+
 	// ChaffRequestMaxLatencyMS prevents chaff request from consistently increasing latency
 	// if the server is under abnormal load.
 	ChaffRequestMaxLatencyMS uint64 `env:"CHAFF_REQUEST_MAX_LATENCY_MS, default=1000"`
@@ -126,9 +212,84 @@ func (c *Config) MaintenanceMode() bool {
 	return c.Maintenance
 }
 
+// settingsService is the service name of publish settings.
+const settingsService = "publish"
+
+// liveConfig is the part of Config that can be changed through settings while
+// the server is running.
+type liveConfig struct {
+	Maintenance bool `env:"MAINTENANCE_MODE, overwrite"`
+
+	MaxKeysOnPublish             uint          `env:"MAX_KEYS_ON_PUBLISH, overwrite"`
+	MaxSameStartIntervalKeys     uint          `env:"MAX_SAME_START_INTERVAL_KEYS, overwrite"`
+	MaxIntervalAge               time.Duration `env:"MAX_INTERVAL_AGE_ON_PUBLISH, overwrite"`
+	MaxMagnitudeSymptomOnsetDays uint          `env:"MAX_SYMPTOM_ONSET_DAYS, overwrite"`
+	MaxSymptomOnsetReportDays    uint          `env:"MAX_VALID_SYMPTOM_ONSET_REPORT_DAYS, overwrite"`
+	SymptomOnsetDaysAgo          uint          `env:"DEFAULT_SYMPTOM_ONSET_DAYS_AGO, overwrite"`
+
+	AllowPartialRevisions   bool                   `env:"ALLOW_PARTIAL_REVISIONS, overwrite"`
+	SameDayPolicy           model.SameDayKeyPolicy `env:"SAME_DAY_KEY_POLICY, overwrite"`
+	RecordAbuseStats        bool                   `env:"RECORD_ABUSE_STATS, overwrite"`
+	LogJSONParseErrors      bool                   `env:"LOG_JSON_PARSE_ERRORS, overwrite"`
+	DebugLogBadCertificates bool                   `env:"DEBUG_LOG_BAD_CERTIFICATES, overwrite"`
+
+	RateLimit      float64 `env:"MIDDLEWARE_RATE_LIMIT, overwrite"`
+	RateLimitBurst int     `env:"MIDDLEWARE_RATE_LIMIT_BURST, overwrite"`
+}
+
+// withSettings returns a copy of the config with the current settings
+// applied. Settings that are not set keep the value of c.
+func (c *Config) withSettings(ctx context.Context, w *settings.Watcher) (*Config, error) {
+	live := liveConfig{
+		Maintenance:                  c.Maintenance,
+		MaxKeysOnPublish:             c.MaxKeysOnPublish,
+		MaxSameStartIntervalKeys:     c.MaxSameStartIntervalKeys,
+		MaxIntervalAge:               c.MaxIntervalAge,
+		MaxMagnitudeSymptomOnsetDays: c.MaxMagnitudeSymptomOnsetDays,
+		MaxSymptomOnsetReportDays:    c.MaxSymptomOnsetReportDays,
+		SymptomOnsetDaysAgo:          c.SymptomOnsetDaysAgo,
+		AllowPartialRevisions:        c.AllowPartialRevisions,
+		SameDayPolicy:                c.SameDayPolicy,
+		RecordAbuseStats:             c.RecordAbuseStats,
+		LogJSONParseErrors:           c.LogJSONParseErrors,
+		DebugLogBadCertificates:      c.DebugLogBadCertificates,
+		RateLimit:                    c.Middleware.RateLimit,
+		RateLimitBurst:               c.Middleware.RateLimitBurst,
+	}
+	if err := w.Overlay(ctx, settingsService, &live); err != nil {
+		return nil, err
+	}
+
+	cfg := *c
+	cfg.Maintenance = live.Maintenance
+	cfg.MaxKeysOnPublish = live.MaxKeysOnPublish
+	cfg.MaxSameStartIntervalKeys = live.MaxSameStartIntervalKeys
+	cfg.MaxIntervalAge = live.MaxIntervalAge
+	cfg.MaxMagnitudeSymptomOnsetDays = live.MaxMagnitudeSymptomOnsetDays
+	cfg.MaxSymptomOnsetReportDays = live.MaxSymptomOnsetReportDays
+	cfg.SymptomOnsetDaysAgo = live.SymptomOnsetDaysAgo
+	cfg.AllowPartialRevisions = live.AllowPartialRevisions
+	cfg.SameDayPolicy = live.SameDayPolicy
+	cfg.RecordAbuseStats = live.RecordAbuseStats
+	cfg.LogJSONParseErrors = live.LogJSONParseErrors
+	cfg.DebugLogBadCertificates = live.DebugLogBadCertificates
+	cfg.Middleware.RateLimit = live.RateLimit
+	cfg.Middleware.RateLimitBurst = live.RateLimitBurst
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	return &cfg, nil
+}
+
 func (c *Config) Validate() error {
 	var result *multierror.Error
 
+	if c.MaxUploadChunks == 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `MAX_UPLOAD_CHUNKS` must be > 0, got: %v", c.MaxUploadChunks))
+	}
+
 	if c.MaxMagnitudeSymptomOnsetDays == 0 {
 		result = multierror.Append(result,
 			fmt.Errorf("env var `MAX_SYMPTOM_ONSET_DAYS` must be > 0, got: %v", c.MaxMagnitudeSymptomOnsetDays))
@@ -148,6 +309,23 @@ func (c *Config) Validate() error {
 			fmt.Errorf("env var `STATS_EMBARGO_PERIOD` must be >= 48h or <= 0 to disable release of stats days below the threshold"))
 	}
 
+	if err := c.SameDayPolicy.Validate(); err != nil {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `SAME_DAY_KEY_POLICY` is invalid: %w", err))
+	}
+	if c.SameDayReleaseDelay < 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `SAME_DAY_KEY_RELEASE_DELAY` must be >= 0, got: %v", c.SameDayReleaseDelay))
+	}
+
+	if err := c.I18n.Validate(); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	if err := c.StatsPrivacy.Validate(); err != nil {
+		result = multierror.Append(result, err)
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -179,8 +357,15 @@ func (c *Config) DefaultSymptomOnsetDaysAgo() uint {
 	return c.SymptomOnsetDaysAgo
 }
 
-func (c *Config) DebugReleaseSameDayKeys() bool {
-	return c.ReleaseSameDayKeys
+func (c *Config) SameDayKeyPolicy() model.SameDayKeyPolicy {
+	if c.ReleaseSameDayKeys {
+		return model.SameDayKeyPolicyRelease
+	}
+	return c.SameDayPolicy
+}
+
+func (c *Config) SameDayKeyReleaseDelay() time.Duration {
+	return c.SameDayReleaseDelay
 }
 
 func (c *Config) AuthorizedAppConfig() *authorizedapp.Config {
@@ -202,3 +387,15 @@ func (c *Config) ObservabilityExporterConfig() *observability.Config {
 func (c *Config) KeyManagerConfig() *keys.Config {
 	return &c.KeyManager
 }
+
+func (c *Config) SettingsConfig() *settings.Config {
+	return &c.Settings
+}
+
+func (c *Config) FeatureFlagConfig() *featureflag.Config {
+	return &c.FeatureFlag
+}
+
+func (c *Config) QueueConfig() *queue.Config {
+	return &c.Queue
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/settings"
+)
+
+func TestConfig_WithSettings(t *testing.T) {
+	t.Parallel()
+
+	base := &Config{
+		MaxKeysOnPublish:             30,
+		MaxUploadChunks:              1,
+		MaxMagnitudeSymptomOnsetDays: 14,
+		MaxSymptomOnsetReportDays:    28,
+		StatsUploadMinimum:           10,
+	}
+
+	cases := []struct {
+		name    string
+		values  map[string]string
+		want    uint
+		wantErr bool
+	}{
+		{
+			name: "unset",
+			want: 30,
+		},
+		{
+			name:   "overlay",
+			values: map[string]string{"MAX_KEYS_ON_PUBLISH": "40"},
+			want:   40,
+		},
+		{
+			name:    "unparsable",
+			values:  map[string]string{"MAX_KEYS_ON_PUBLISH": "lots"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			values:  map[string]string{"MAX_SYMPTOM_ONSET_DAYS": "0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			w := settings.NewWatcher(nil)
+			w.Replace(ctx, map[string]map[string]string{settingsService: tc.values})
+
+			cfg, err := base.withSettings(ctx, w)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.MaxKeysOnPublish; got != tc.want {
+				t.Errorf("expected MaxKeysOnPublish %d, got %d", tc.want, got)
+			}
+			if base.MaxKeysOnPublish != 30 {
+				t.Errorf("base config was modified")
+			}
+		})
+	}
+}
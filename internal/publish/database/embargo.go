@@ -0,0 +1,71 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// CountEmbargoedExposures returns the number of exposures of the health
+// authority that are held out of exports by its export delay as of now. Keys
+// that are still valid are not counted, they cannot be released early.
+func (db *PublishDB) CountEmbargoedExposures(ctx context.Context, healthAuthorityID int64, now time.Time) (int64, error) {
+	var count int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				COUNT(*)
+			FROM
+				Exposure
+			WHERE
+				health_authority_id = $1 AND created_at > $2 AND interval_number + interval_count <= $3
+			`, healthAuthorityID, now, model.IntervalNumber(now))
+		return row.Scan(&count)
+	}); err != nil {
+		return 0, fmt.Errorf("counting embargoed exposures: %w", err)
+	}
+	return count, nil
+}
+
+// ReleaseEmbargoedExposures releases the exposures of the health authority
+// that are held by its export delay, so they are exported in the batch that
+// contains exportAt. Keys that are still valid as of now stay embargoed.
+// Returns the number of keys released.
+func (db *PublishDB) ReleaseEmbargoedExposures(ctx context.Context, healthAuthorityID int64, exportAt, now time.Time) (int64, error) {
+	var count int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE
+				Exposure
+			SET
+				created_at = $2
+			WHERE
+				health_authority_id = $1 AND created_at > $2 AND interval_number + interval_count <= $3
+			`, healthAuthorityID, exportAt, model.IntervalNumber(now))
+		if err != nil {
+			return err
+		}
+		count = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("releasing embargoed exposures: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	hamodel "github.com/google/exposure-notifications-server/internal/verification/model"
+)
+
+func TestReleaseEmbargoedExposures(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	healthAuthority := &hamodel.HealthAuthority{
+		Issuer:      "a",
+		Audience:    "b",
+		Name:        "c",
+		ExportDelay: 2 * time.Hour,
+	}
+	if err := hadb.New(testDB).AddHealthAuthority(ctx, healthAuthority); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Hour)
+
+	// Held by the export delay.
+	delayed := testExposure(t)
+	delayed.CreatedAt = now.Add(2 * time.Hour)
+	delayed.SetHealthAuthorityID(healthAuthority.ID)
+
+	// Still valid, so it stays embargoed.
+	stillValid := testExposure(t)
+	stillValid.IntervalNumber = model.IntervalNumber(now)
+	stillValid.CreatedAt = now.Add(25 * time.Hour)
+	stillValid.SetHealthAuthorityID(healthAuthority.ID)
+
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{delayed, stillValid},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := pubDB.CountEmbargoedExposures(ctx, healthAuthority.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := count, int64(1); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	released, err := pubDB.ReleaseEmbargoedExposures(ctx, healthAuthority.ID, now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := released, int64(1); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	count, err = pubDB.CountEmbargoedExposures(ctx, healthAuthority.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := count, int64(0); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+}
@@ -21,18 +21,22 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	outboxdb "github.com/google/exposure-notifications-server/internal/outbox/database"
+	outboxmodel "github.com/google/exposure-notifications-server/internal/outbox/model"
 	"github.com/google/exposure-notifications-server/internal/pb"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	quarantinemodel "github.com/google/exposure-notifications-server/internal/quarantine/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 	"github.com/google/exposure-notifications-server/pkg/base64util"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 const (
@@ -69,6 +73,45 @@ func New(db *database.DB) *PublishDB {
 	}
 }
 
+// ConsentFilter selects exposures based on the consent of the user to share
+// them outside of the home region. The zero value, ConsentAny, does not
+// filter; ConsentNotRefused and ConsentGiven are progressively stricter.
+type ConsentFilter int
+
+const (
+	// ConsentAny does not filter on consent.
+	ConsentAny ConsentFilter = iota
+	// ConsentNotRefused excludes exposures where the user explicitly declined
+	// to share. Exposures published without a consent value are included.
+	ConsentNotRefused
+	// ConsentGiven only includes exposures where the user explicitly consented
+	// to share.
+	ConsentGiven
+)
+
+// ConsentFilterFor returns the filter for sharing keys outside of the home
+// region. Keys where the user declined are never shared. If requireExplicit is
+// true, keys published without a consent value are not shared either.
+func ConsentFilterFor(requireExplicit bool) ConsentFilter {
+	if requireExplicit {
+		return ConsentGiven
+	}
+	return ConsentNotRefused
+}
+
+// clause returns the SQL condition for the filter, or the empty string if
+// there is no condition.
+func (c ConsentFilter) clause() string {
+	switch c {
+	case ConsentNotRefused:
+		return "federation_consent IS DISTINCT FROM false"
+	case ConsentGiven:
+		return "federation_consent = true"
+	default:
+		return ""
+	}
+}
+
 // IterateExposuresCriteria is criteria to iterate exposures.
 type IterateExposuresCriteria struct {
 	IncludeRegions   []string
@@ -81,9 +124,64 @@ type IterateExposuresCriteria struct {
 	LastCursor       string
 	OnlyRevisedKeys  bool // If true, only revised keys that match will be selected.
 
-	// OnlyLocalProvenance indicates that only exposures with LocalProvenance=true will be returned.
+	// ExcludeRevoked leaves out keys that have been revised to the negative
+	// report type. Revoked keys are still selected with OnlyRevisedKeys, so
+	// the revocation is published, but they are not published as primary keys
+	// after they have been revoked.
+	ExcludeRevoked bool
+
+	// IncludeTravelersFrom additionally includes traveler records from these
+	// regions when IncludeRegions is set. It is ignored if IncludeTravelers is
+	// set.
+	IncludeTravelersFrom []string
+
+	// IncludeJurisdictions, if set, limits results to exposures from health
+	// authorities in these jurisdictions. Exposures without a jurisdiction, such
+	// as federated keys, are not included.
+	IncludeJurisdictions []string
+
+	// ExcludeJurisdictions removes exposures from health authorities in these
+	// jurisdictions. Exposures without a jurisdiction are kept.
+	ExcludeJurisdictions []string
+
+	// FederationConsent filters all exposures on their sharing consent.
+	// TravelerConsent only filters the traveler exposures that are included
+	// because of IncludeTravelers or IncludeTravelersFrom.
+	FederationConsent ConsentFilter
+	TravelerConsent   ConsentFilter
+
+	// ExcludeTestData leaves out test data, OnlyTestData selects only test
+	// data. Production exports and federation exclude test data.
+	ExcludeTestData bool
+	OnlyTestData    bool
+
+	// OnlyLocalProvenance indicates that only exposures that were published to
+	// this server are returned. Exposures without a recorded provenance source
+	// fall back to LocalProvenance.
 	OnlyLocalProvenance bool
 
+	// IncludeProvenanceSources, if set, limits results to exposures from these
+	// provenance sources. Exposures without a recorded source are left out.
+	IncludeProvenanceSources []model.ProvenanceSource
+
+	// ExcludeGenerated leaves out the padding and noise keys generated by the
+	// export worker.
+	ExcludeGenerated bool
+
+	// IncludeKeyMetadata selects the key metadata of the exposures. Otherwise
+	// it is left nil.
+	IncludeKeyMetadata bool
+
+	// IncludeProvenance selects the provenance source and original regions of
+	// the exposures. Otherwise they are left empty.
+	IncludeProvenance bool
+
+	// KeysFrom and KeysUntil, if set, limit results to the exposure keys from
+	// KeysFrom, inclusive, until KeysUntil, exclusive, compared as bytes. The
+	// export worker uses them to read a batch one file at a time.
+	KeysFrom  []byte
+	KeysUntil []byte
+
 	// If limit is > 0, a limit query will be set on the database query.
 	Limit uint32
 }
@@ -100,18 +198,6 @@ type IteratorFunction func(*model.Exposure) error
 // the iteration at the failed row. If IterateExposures returns a nil error,
 // the first return value will be the empty string.
 func (db *PublishDB) IterateExposures(ctx context.Context, criteria IterateExposuresCriteria, f IteratorFunction) (cur string, err error) {
-	offset := 0
-	if criteria.LastCursor != "" {
-		offsetStr, err := decodeCursor(criteria.LastCursor)
-		if err != nil {
-			return "", fmt.Errorf("decoding cursor: %w", err)
-		}
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			return "", fmt.Errorf("decoding cursor %w", err)
-		}
-	}
-
 	query, args, err := generateExposureQuery(criteria)
 	if err != nil {
 		return "", fmt.Errorf("generating where: %w", err)
@@ -120,13 +206,23 @@ func (db *PublishDB) IterateExposures(ctx context.Context, criteria IterateExpos
 	logger := logging.FromContext(ctx).Named("IterateExposures")
 	logger.Debugw("iterator query", "query", query, "args", args)
 
-	// This is a pretty weak cursor solution, but not too bad since we'll
-	// typically have queries ahead of the cleanup and before the current
-	// ingestion window, and those should be stable.
-	cursor := func() string { return encodeCursor(strconv.Itoa(offset)) }
+	// The cursor is the (time, key) position of the last row that was
+	// successfully processed. Results are ordered by that pair, so resuming
+	// from it is stable even while new rows are being inserted. Cursors issued
+	// before keyset pagination are offsets, which are still accepted.
+	var last *database.Keyset
+	cursor := func() string {
+		if last != nil {
+			return last.Encode()
+		}
+		if criteria.LastCursor != "" {
+			return criteria.LastCursor
+		}
+		return encodeCursor("0")
+	}
 
-	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		rows, err := tx.Query(ctx, query, args...)
+	if err := db.db.Read(ctx, func(q database.Querier) error {
+		rows, err := q.Query(ctx, query, args...)
 		if err != nil {
 			return fmt.Errorf("failed to list: %w", err)
 		}
@@ -145,11 +241,16 @@ func (db *PublishDB) IterateExposures(ctx context.Context, criteria IterateExpos
 			var encodedKey string
 			var syncID *int64
 			var queryID *string
+			var source *string
 			if err := rows.Scan(&encodedKey, &m.TransmissionRisk, &m.AppPackageName, &m.Regions, &m.Traveler,
-				&m.IntervalNumber, &m.IntervalCount, &m.CreatedAt, &m.LocalProvenance, &syncID, &queryID, &m.HealthAuthorityID,
-				&m.ReportType, &m.DaysSinceSymptomOnset, &m.RevisedReportType, &m.RevisedAt, &m.RevisedDaysSinceSymptomOnset); err != nil {
+				&m.IntervalNumber, &m.IntervalCount, &m.CreatedAt, &m.LocalProvenance, &syncID, &queryID, &m.ExportImportID, &source, &m.OriginalRegions, &m.HealthAuthorityID,
+				&m.ReportType, &m.DaysSinceSymptomOnset, &m.RevisedReportType, &m.RevisedAt, &m.RevisedDaysSinceSymptomOnset,
+				&m.Jurisdiction, &m.FederationConsent, &m.TestData, &m.KeyMetadata); err != nil {
 				return fmt.Errorf("failed to parse: %w", err)
 			}
+			if source != nil {
+				m.ProvenanceSource = model.ProvenanceSource(*source)
+			}
 
 			var err error
 			m.ExposureKey, err = decodeExposureKey(encodedKey)
@@ -165,10 +266,15 @@ func (db *PublishDB) IterateExposures(ctx context.Context, criteria IterateExpos
 			if err := f(&m); err != nil {
 				return err
 			}
-			offset++
+
+			position := m.CreatedAt
+			if criteria.OnlyRevisedKeys && m.RevisedAt != nil {
+				position = *m.RevisedAt
+			}
+			last = &database.Keyset{Time: position, Key: encodedKey}
 		}
 
-		return nil
+		return rows.Err()
 	}); err != nil {
 		return cursor(), fmt.Errorf("iterate exposures: %w", err)
 	}
@@ -176,27 +282,66 @@ func (db *PublishDB) IterateExposures(ctx context.Context, criteria IterateExpos
 	return "", nil
 }
 
+// CountExposures returns the number of exposures that match the criteria.
+func (db *PublishDB) CountExposures(ctx context.Context, criteria IterateExposuresCriteria) (int64, error) {
+	query, args, err := generateExposureQuery(criteria)
+	if err != nil {
+		return 0, fmt.Errorf("generating where: %w", err)
+	}
+
+	var count int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, "SELECT COUNT(*) FROM ("+query+") AS e", args...)
+		return row.Scan(&count)
+	}); err != nil {
+		return 0, fmt.Errorf("counting exposures: %w", err)
+	}
+	return count, nil
+}
+
 func generateExposureQuery(criteria IterateExposuresCriteria) (string, []interface{}, error) {
+	keyMetadata := "NULL::BYTEA"
+	if criteria.IncludeKeyMetadata {
+		keyMetadata = "key_metadata"
+	}
+	provenance := "NULL::VARCHAR, NULL::VARCHAR[]"
+	if criteria.IncludeProvenance {
+		provenance = "provenance_source, original_regions"
+	}
+
 	var args []interface{}
 	q := `
 		SELECT
 			exposure_key, transmission_risk, LOWER(app_package_name), regions, traveler,
 			interval_number, interval_count,
-			created_at, local_provenance, sync_id, sync_query_id, health_authority_id, report_type,
-			days_since_symptom_onset, revised_report_type, revised_at, revised_days_since_symptom_onset
+			created_at, local_provenance, sync_id, sync_query_id, export_import_id, ` + provenance + `,
+			health_authority_id, report_type,
+			days_since_symptom_onset, revised_report_type, revised_at, revised_days_since_symptom_onset,
+			jurisdiction, federation_consent, test_data, ` + keyMetadata + `
 		FROM
 			Exposure
 		WHERE 1=1
 	`
 
-	if len(criteria.IncludeRegions) == 1 {
-		if criteria.IncludeTravelers {
+	travelerConsent := ""
+	if c := criteria.TravelerConsent.clause(); c != "" {
+		travelerConsent = " AND " + c
+	}
+
+	if len(criteria.IncludeRegions) > 0 {
+		switch {
+		case criteria.IncludeTravelers:
 			// If the query has include ragions and include travelers set - we want the union of the specified regions and
 			// all "traveler" keys that this server knows about.
 			args = append(args, criteria.IncludeRegions)
 			args = append(args, true)
-			q += fmt.Sprintf(" AND ((regions && $%d) OR traveler = $%d)", len(args)-1, len(args)) // Operation "&&" means "array overlaps / intersects"
-		} else {
+			q += fmt.Sprintf(" AND ((regions && $%d) OR (traveler = $%d%s))", len(args)-1, len(args), travelerConsent) // Operation "&&" means "array overlaps / intersects"
+		case len(criteria.IncludeTravelersFrom) > 0:
+			// Union of the specified regions and travelers from the traveler regions.
+			args = append(args, criteria.IncludeRegions)
+			args = append(args, criteria.IncludeTravelersFrom)
+			q += fmt.Sprintf(" AND ((regions && $%d) OR (traveler = true AND regions && $%d%s))", len(args)-1, len(args), travelerConsent)
+		default:
 			args = append(args, criteria.IncludeRegions)
 			q += fmt.Sprintf(" AND (regions && $%d)", len(args)) // Operation "&&" means "array overlaps / intersects"
 		}
@@ -207,10 +352,27 @@ func generateExposureQuery(criteria IterateExposuresCriteria) (string, []interfa
 		q += fmt.Sprintf(" AND NOT (regions && $%d)", len(args)) // Operation "&&" means "array overlaps / intersects"
 	}
 
+	if len(criteria.IncludeJurisdictions) > 0 {
+		args = append(args, criteria.IncludeJurisdictions)
+		q += fmt.Sprintf(" AND jurisdiction = ANY($%d)", len(args))
+	}
+
+	if len(criteria.ExcludeJurisdictions) > 0 {
+		args = append(args, criteria.ExcludeJurisdictions)
+		q += fmt.Sprintf(" AND (jurisdiction IS NULL OR NOT (jurisdiction = ANY($%d)))", len(args))
+	}
+
+	if c := criteria.FederationConsent.clause(); c != "" {
+		q += " AND " + c
+	}
+
 	timeField := "created_at"
 	if criteria.OnlyRevisedKeys {
 		q += " AND revised_at IS NOT NULL"
 		timeField = "revised_at"
+	} else if criteria.ExcludeRevoked {
+		args = append(args, verifyapi.ReportTypeNegative)
+		q += fmt.Sprintf(" AND revised_report_type IS DISTINCT FROM $%d", len(args))
 	}
 
 	// It is important for StartTimestamp to be inclusive (as opposed to exclusive). When the exposure keys are
@@ -229,8 +391,42 @@ func generateExposureQuery(criteria IterateExposuresCriteria) (string, []interfa
 	}
 
 	if criteria.OnlyLocalProvenance {
+		args = append(args, string(model.ProvenancePublish))
+		q += fmt.Sprintf(" AND COALESCE(provenance_source = $%d, local_provenance)", len(args))
+	}
+
+	if len(criteria.IncludeProvenanceSources) > 0 {
+		sources := make([]string, 0, len(criteria.IncludeProvenanceSources))
+		for _, src := range criteria.IncludeProvenanceSources {
+			if err := src.Validate(); err != nil {
+				return "", nil, err
+			}
+			sources = append(sources, string(src))
+		}
+		args = append(args, sources)
+		q += fmt.Sprintf(" AND provenance_source = ANY($%d)", len(args))
+	}
+
+	if criteria.ExcludeGenerated {
+		args = append(args, model.GeneratedAppPackageName)
+		q += fmt.Sprintf(" AND app_package_name != $%d", len(args))
+	}
+
+	if criteria.KeysFrom != nil {
+		args = append(args, criteria.KeysFrom)
+		q += fmt.Sprintf(" AND decode(exposure_key, 'base64') >= $%d", len(args))
+	}
+	if criteria.KeysUntil != nil {
+		args = append(args, criteria.KeysUntil)
+		q += fmt.Sprintf(" AND decode(exposure_key, 'base64') < $%d", len(args))
+	}
+
+	if criteria.ExcludeTestData {
+		args = append(args, false)
+		q += fmt.Sprintf(" AND test_data = $%d", len(args))
+	} else if criteria.OnlyTestData {
 		args = append(args, true)
-		q += fmt.Sprintf(" AND local_provenance = $%d", len(args))
+		q += fmt.Sprintf(" AND test_data = $%d", len(args))
 	}
 
 	if criteria.OnlyTravelers {
@@ -243,23 +439,42 @@ func generateExposureQuery(criteria IterateExposuresCriteria) (string, []interfa
 		q += fmt.Sprintf(" AND traveler = $%d", len(args))
 	}
 
-	if criteria.OnlyRevisedKeys {
-		q += " ORDER BY revised_at"
-	} else {
-		q += " ORDER BY created_at"
-	}
-
+	// Legacy cursors are offsets and are applied after ordering.
+	var offset string
 	if criteria.LastCursor != "" {
-		decoded, err := decodeCursor(criteria.LastCursor)
-		if err != nil {
+		keyset, err := database.DecodeKeyset(criteria.LastCursor)
+		switch {
+		case err == nil:
+			clause, keysetArgs := keyset.After(timeField, "exposure_key", len(args)+1)
+			args = append(args, keysetArgs...)
+			q += " AND " + clause
+		case errors.Is(err, database.ErrNotKeyset):
+			offset, err = decodeCursor(criteria.LastCursor)
+			if err != nil {
+				return "", nil, err
+			}
+			if _, err := strconv.Atoi(offset); err != nil {
+				return "", nil, fmt.Errorf("decoding cursor: %w", err)
+			}
+		default:
 			return "", nil, err
 		}
-		args = append(args, decoded)
+	}
+
+	// The exposure key breaks ties between rows with the same timestamp, which
+	// is common since timestamps are truncated on publish.
+	q += fmt.Sprintf(" ORDER BY %s, exposure_key", timeField)
+
+	if offset != "" {
+		args = append(args, offset)
 		q += fmt.Sprintf(" OFFSET $%d", len(args))
 	}
 
+	// The limit is passed as an argument so that the statement text, and
+	// therefore the cached prepared statement, is the same for every page.
 	if criteria.Limit > 0 {
-		q += fmt.Sprintf(" LIMIT %d", criteria.Limit)
+		args = append(args, criteria.Limit)
+		q += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
 
 	q = strings.ReplaceAll(q, "\n", " ")
@@ -267,6 +482,35 @@ func generateExposureQuery(criteria IterateExposuresCriteria) (string, []interfa
 	return q, args, nil
 }
 
+// readExposuresSQL looks up exposures by key. It is served by the
+// exposure_key_hash index.
+const readExposuresSQL = `
+		SELECT
+			exposure_key, transmission_risk, app_package_name, regions, traveler,
+			interval_number, interval_count, created_at, local_provenance, sync_id,
+			health_authority_id, report_type, days_since_symptom_onset,
+			revised_report_type, revised_at, revised_days_since_symptom_onset,
+			revised_transmission_risk, export_import_id, jurisdiction, federation_consent, test_data
+		FROM
+			Exposure
+		WHERE exposure_key = ANY($1)
+		FOR UPDATE
+	`
+
+// lookupExposuresSQL looks up exposures by key, along with their provenance.
+const lookupExposuresSQL = `
+		SELECT
+			exposure_key, transmission_risk, app_package_name, regions, traveler,
+			interval_number, interval_count, created_at, local_provenance, sync_id,
+			sync_query_id, health_authority_id, report_type, days_since_symptom_onset,
+			revised_report_type, revised_at, revised_days_since_symptom_onset,
+			revised_transmission_risk, export_import_id, import_file_id, jurisdiction,
+			federation_consent, test_data, provenance_source, original_regions
+		FROM
+			Exposure
+		WHERE exposure_key = ANY($1)
+	`
+
 // ReadExposures will read an existing set of exposures from the database.
 // This is necessary in case a key needs to be revised.
 // In the return map, the key is the base64 of the ExposureKey.
@@ -274,18 +518,7 @@ func generateExposureQuery(criteria IterateExposuresCriteria) (string, []interfa
 func (db *PublishDB) ReadExposures(ctx context.Context, tx pgx.Tx, b64keys []string) (map[string]*model.Exposure, error) {
 	exposures := make(map[string]*model.Exposure)
 
-	rows, err := tx.Query(ctx, `
-			SELECT
-				exposure_key, transmission_risk, app_package_name, regions, traveler,
-				interval_number, interval_count, created_at, local_provenance, sync_id,
-				health_authority_id, report_type, days_since_symptom_onset,
-				revised_report_type, revised_at, revised_days_since_symptom_onset,
-				revised_transmission_risk, export_import_id
-			FROM
-				Exposure
-			WHERE exposure_key = ANY($1)
-			FOR UPDATE
-		`, b64keys)
+	rows, err := tx.Query(ctx, readExposuresSQL, b64keys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list: %w", err)
 	}
@@ -306,7 +539,8 @@ func (db *PublishDB) ReadExposures(ctx context.Context, tx pgx.Tx, b64keys []str
 			&exposure.CreatedAt, &exposure.LocalProvenance, &syncID,
 			&exposure.HealthAuthorityID, &exposure.ReportType, &exposure.DaysSinceSymptomOnset,
 			&exposure.RevisedReportType, &exposure.RevisedAt, &exposure.RevisedDaysSinceSymptomOnset,
-			&exposure.RevisedTransmissionRisk, &exposure.ExportImportID,
+			&exposure.RevisedTransmissionRisk, &exposure.ExportImportID, &exposure.Jurisdiction,
+			&exposure.FederationConsent, &exposure.TestData,
 		); err != nil {
 			return nil, fmt.Errorf("failed to parse: %w", err)
 		}
@@ -327,22 +561,136 @@ func (db *PublishDB) ReadExposures(ctx context.Context, tx pgx.Tx, b64keys []str
 	return exposures, nil
 }
 
-func prepareInsertExposure(ctx context.Context, tx pgx.Tx) (string, error) {
-	const stmtName = "insert exposures"
-	_, err := tx.Prepare(ctx, stmtName, `
+// LookupExposures reads the exposures with the given base64 encoded keys and
+// their provenance, without locking them. In the return map, the key is the
+// base64 of the ExposureKey. Keys that are not found are left out.
+func (db *PublishDB) LookupExposures(ctx context.Context, b64keys []string) (map[string]*model.Exposure, error) {
+	exposures := make(map[string]*model.Exposure)
+
+	if err := db.db.Read(ctx, func(q database.Querier) error {
+		rows, err := q.Query(ctx, lookupExposuresSQL, b64keys)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			var encodedKey string
+			var syncID sql.NullInt64
+			var queryID, source sql.NullString
+
+			var exposure model.Exposure
+			if err := rows.Scan(
+				&encodedKey, &exposure.TransmissionRisk, &exposure.AppPackageName,
+				&exposure.Regions, &exposure.Traveler, &exposure.IntervalNumber, &exposure.IntervalCount,
+				&exposure.CreatedAt, &exposure.LocalProvenance, &syncID,
+				&queryID, &exposure.HealthAuthorityID, &exposure.ReportType, &exposure.DaysSinceSymptomOnset,
+				&exposure.RevisedReportType, &exposure.RevisedAt, &exposure.RevisedDaysSinceSymptomOnset,
+				&exposure.RevisedTransmissionRisk, &exposure.ExportImportID, &exposure.ImportFileID,
+				&exposure.Jurisdiction, &exposure.FederationConsent, &exposure.TestData,
+				&source, &exposure.OriginalRegions,
+			); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+
+			// Base64 decode the exposure key
+			var err error
+			exposure.ExposureKey, err = decodeExposureKey(encodedKey)
+			if err != nil {
+				return fmt.Errorf("failed to decode key: %w", err)
+			}
+			// Optionally set all of the nullable columns.
+			if syncID.Valid {
+				exposure.FederationSyncID = syncID.Int64
+			}
+			if queryID.Valid {
+				exposure.FederationQueryID = queryID.String
+			}
+			if source.Valid {
+				exposure.ProvenanceSource = model.ProvenanceSource(source.String)
+			}
+
+			exposures[exposure.ExposureKeyBase64()] = &exposure
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+	return exposures, nil
+}
+
+// readReportTypeTransitions reads the report type transition overrides of the
+// health authority that is revising the incoming keys. It returns nil if the
+// keys have no health authority or the health authority has no overrides.
+func readReportTypeTransitions(ctx context.Context, tx pgx.Tx, incoming []*model.Exposure) (model.ReportTypeTransitions, error) {
+	var healthAuthorityID *int64
+	for _, exp := range incoming {
+		if exp.HasHealthAuthorityID() {
+			healthAuthorityID = exp.HealthAuthorityID
+			break
+		}
+	}
+	if healthAuthorityID == nil {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			from_report_type, to_report_type, allowed
+		FROM
+			ReportTypeTransition
+		WHERE
+			health_authority_id = $1
+		`, *healthAuthorityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list: %w", err)
+	}
+	defer rows.Close()
+
+	var transitions model.ReportTypeTransitions
+	for rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to iterate: %w", err)
+		}
+
+		var from, to string
+		var allowed bool
+		if err := rows.Scan(&from, &to, &allowed); err != nil {
+			return nil, fmt.Errorf("failed to parse: %w", err)
+		}
+		if transitions == nil {
+			transitions = make(model.ReportTypeTransitions)
+		}
+		transitions.Set(from, to, allowed)
+	}
+	return transitions, nil
+}
+
+// The exposure writes are queued in a batch. pgx prepares the statements the
+// first time a connection sends them and caches them, so later batches are a
+// single round trip.
+const insertExposureSQL = `
 		INSERT INTO
 			Exposure
 				(exposure_key, transmission_risk, app_package_name, regions, traveler, interval_number, interval_count,
 				 created_at, local_provenance, sync_id, sync_query_id, health_authority_id, report_type, days_since_symptom_onset,
-				 export_import_id, import_file_id)
+				 export_import_id, import_file_id, jurisdiction, federation_consent, test_data, key_metadata,
+				 provenance_source, original_regions)
 		VALUES
-			($1, $2, LOWER($3), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
-		ON CONFLICT (exposure_key) DO NOTHING
-	`)
-	return stmtName, err
+			($1, $2, LOWER($3), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+		ON CONFLICT (tenant, exposure_key) DO NOTHING
+	`
+
+func queueInsertExposure(b *exposureBatch, exp *model.Exposure) {
+	b.queue(writeInsert, insertExposureSQL, insertExposureArgs(exp)...)
 }
 
-func executeInsertExposure(ctx context.Context, tx pgx.Tx, stmtName string, exp *model.Exposure) error {
+// insertExposureArgs returns the arguments of the insert exposures statement.
+func insertExposureArgs(exp *model.Exposure) []interface{} {
 	var syncID *int64
 	var queryID *string
 	if exp.FederationSyncID != 0 {
@@ -351,44 +699,167 @@ func executeInsertExposure(ctx context.Context, tx pgx.Tx, stmtName string, exp
 	if exp.FederationQueryID != "" {
 		queryID = &exp.FederationQueryID
 	}
+	// New keys always record their provenance, and are first stored in the
+	// regions they arrived with.
+	originalRegions := exp.OriginalRegions
+	if originalRegions == nil {
+		originalRegions = exp.Regions
+	}
 
-	_, err := tx.Exec(ctx, stmtName, encodeExposureKey(exp.ExposureKey), exp.TransmissionRisk,
+	return []interface{}{
+		encodeExposureKey(exp.ExposureKey), exp.TransmissionRisk,
 		exp.AppPackageName, exp.Regions, exp.Traveler, exp.IntervalNumber, exp.IntervalCount,
 		exp.CreatedAt, exp.LocalProvenance, syncID, queryID,
 		exp.HealthAuthorityID, exp.ReportType, exp.DaysSinceSymptomOnset,
-		exp.ExportImportID, exp.ImportFileID)
-	if err != nil {
-		return fmt.Errorf("inserting exposure: %w", err)
+		exp.ExportImportID, exp.ImportFileID, exp.Jurisdiction, exp.FederationConsent,
+		exp.TestData, exp.KeyMetadata,
+		string(exp.Provenance().Source), originalRegions,
+	}
+}
+
+// insertQuarantineCohort inserts the cohort and sets its ID.
+func insertQuarantineCohort(ctx context.Context, tx pgx.Tx, cohort *quarantinemodel.Cohort) error {
+	reasons := make([]string, 0, len(cohort.Reasons))
+	for _, r := range cohort.Reasons {
+		reasons = append(reasons, string(r))
+	}
+
+	row := tx.QueryRow(ctx, `
+		INSERT INTO
+			QuarantineCohort
+				(app_package_name, health_authority_id, created_at, score, reasons, status)
+		VALUES
+			(LOWER($1), $2, $3, $4, $5, $6)
+		RETURNING id
+	`, cohort.AppPackageName, cohort.HealthAuthorityID, cohort.CreatedAt, cohort.Score, reasons, string(cohort.Status))
+	if err := row.Scan(&cohort.ID); err != nil {
+		return fmt.Errorf("inserting quarantine cohort: %w", err)
 	}
 	return nil
 }
 
-func prepareReviseExposure(ctx context.Context, tx pgx.Tx) (string, error) {
-	const stmtName = "update exposures"
-	_, err := tx.Prepare(ctx, stmtName, `
+const quarantineExposureSQL = `
+		INSERT INTO
+			QuarantinedExposure
+				(exposure_key, transmission_risk, app_package_name, regions, traveler, interval_number, interval_count,
+				 created_at, local_provenance, sync_id, sync_query_id, health_authority_id, report_type, days_since_symptom_onset,
+				 export_import_id, import_file_id, jurisdiction, federation_consent, test_data, key_metadata,
+				 provenance_source, original_regions, cohort_id)
+		VALUES
+			($1, $2, LOWER($3), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		ON CONFLICT (tenant, exposure_key) DO NOTHING
+	`
+
+func queueQuarantineExposure(b *exposureBatch, cohortID int64, exp *model.Exposure) {
+	b.queue(writeQuarantine, quarantineExposureSQL, append(insertExposureArgs(exp), cohortID)...)
+}
+
+const reviseExposureSQL = `
 		UPDATE
 			Exposure
 		SET
 			health_authority_id = $1, revised_report_type = $2, revised_at = $3,
 			revised_days_since_symptom_onset = $4, revised_transmission_risk = $5,
-			revised_import_file_id = $6
+			revised_import_file_id = $6, federation_consent = $7
 		WHERE
-			exposure_key = $7 AND revised_at IS NULL
-		`)
-	return stmtName, err
-}
+			exposure_key = $8 AND (revised_at IS NULL OR $2 = 'negative')
+		`
 
-func executeReviseExposure(ctx context.Context, tx pgx.Tx, stmtName string, exp *model.Exposure) error {
-	result, err := tx.Exec(ctx, stmtName,
+func queueReviseExposure(b *exposureBatch, exp *model.Exposure) {
+	b.queue(writeRevise, reviseExposureSQL,
 		exp.HealthAuthorityID, exp.RevisedReportType, exp.RevisedAt,
 		exp.RevisedDaysSinceSymptomOnset, exp.RevisedTransmissionRisk,
-		exp.RevisedImportFileID,
+		exp.RevisedImportFileID, exp.FederationConsent,
 		encodeExposureKey(exp.ExposureKey))
-	if err != nil {
-		return fmt.Errorf("revising exposure: %w", err)
+}
+
+const extendExposureSQL = `
+		UPDATE
+			Exposure
+		SET
+			interval_count = $1, created_at = GREATEST(created_at, $2)
+		WHERE
+			exposure_key = $3 AND interval_number = $4 AND interval_count < $1
+		`
+
+func queueExtendExposure(b *exposureBatch, exp *model.Exposure) {
+	b.queue(writeExtend, extendExposureSQL,
+		exp.IntervalCount, exp.CreatedAt, encodeExposureKey(exp.ExposureKey), exp.IntervalNumber)
+}
+
+// extendsExposure returns true if in is the same key as ex with the same
+// interval number and a longer interval count. This happens when a same day
+// key is uploaded again after more of its rolling period has passed.
+func extendsExposure(ex, in *model.Exposure) bool {
+	return ex.IntervalNumber == in.IntervalNumber && ex.IntervalCount < in.IntervalCount
+}
+
+type exposureWrite int
+
+const (
+	writeInsert exposureWrite = iota
+	writeQuarantine
+	writeRevise
+	writeExtend
+	writeStats
+)
+
+// exposureBatch queues the exposure writes of a publish so they are sent to
+// the database in one round trip instead of one per key.
+type exposureBatch struct {
+	batch  pgx.Batch
+	writes []exposureWrite
+}
+
+func (b *exposureBatch) queue(w exposureWrite, sql string, args ...interface{}) {
+	b.batch.Queue(sql, args...)
+	b.writes = append(b.writes, w)
+}
+
+// send executes the queued writes. Every revision and extension must update
+// exactly one key, otherwise the key was changed concurrently.
+func (b *exposureBatch) send(ctx context.Context, tx pgx.Tx) (retErr error) {
+	if len(b.writes) == 0 {
+		return nil
 	}
-	if result.RowsAffected() != 1 {
-		return fmt.Errorf("invalid key revision request")
+
+	results := tx.SendBatch(ctx, &b.batch)
+	defer func() {
+		if err := results.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("closing batch: %w", err)
+		}
+	}()
+
+	for _, w := range b.writes {
+		result, err := results.Exec()
+		switch w {
+		case writeInsert:
+			if err != nil {
+				return fmt.Errorf("inserting exposure: %w", err)
+			}
+		case writeQuarantine:
+			if err != nil {
+				return fmt.Errorf("quarantining exposure: %w", err)
+			}
+		case writeRevise:
+			if err != nil {
+				return fmt.Errorf("revising exposure: %w", err)
+			}
+			if result.RowsAffected() != 1 {
+				return fmt.Errorf("invalid key revision request")
+			}
+		case writeExtend:
+			if err != nil {
+				return fmt.Errorf("extending exposure: %w", err)
+			}
+			if result.RowsAffected() != 1 {
+				return fmt.Errorf("invalid key extension request")
+			}
+		case writeStats:
+			if err != nil {
+				return fmt.Errorf("update stats: %w", err)
+			}
+		}
 	}
 	return nil
 }
@@ -410,6 +881,12 @@ type InsertAndReviseExposuresRequest struct {
 	// to support roaming scenarios. This is only used if RequireToken is true.
 	AllowPartialRevisions bool
 
+	// ExtendSameDayKeys allows an existing key that was uploaded with a partial
+	// rolling period to be uploaded again with the same interval number and a
+	// longer interval count. The stored key is extended and its created at time
+	// is moved to the incoming one, so the key is released again.
+	ExtendSameDayKeys bool
+
 	// The following operations are for federation.
 
 	// If true, if a key is determined to be a revsion, it is skipped.
@@ -420,6 +897,15 @@ type InsertAndReviseExposuresRequest struct {
 	RequireQueryID bool
 	// When revising, require matching export-import-ID. For export file based import federation.
 	RequireExportImportID bool
+
+	// RecordEvent writes a keys published event to the outbox in the same
+	// transaction as the keys, for delivery to webhooks.
+	RecordEvent bool
+
+	// Optional: if provided, new keys are held in this quarantine cohort
+	// instead of being exported. Revisions of existing keys are applied as
+	// usual.
+	Quarantine *quarantinemodel.Cohort
 }
 
 // InsertAndReviseExposuresResponse is the response from an
@@ -437,6 +923,14 @@ type InsertAndReviseExposuresResponse struct {
 	// could be because they weren't present in the revision token, etc.
 	Dropped uint32
 
+	// Quarantined is the number of inserted exposures that were held in the
+	// requested quarantine cohort. They are included in Inserted.
+	Quarantined uint32
+
+	// Extended is the number of existing exposures whose interval count was
+	// extended, see ExtendSameDayKeys.
+	Extended uint32
+
 	// Exposures is the actual exposures that were inserted or updated in this
 	// call.
 	Exposures []*model.Exposure
@@ -547,7 +1041,7 @@ func (db *PublishDB) InsertAndReviseExposures(ctx context.Context, req *InsertAn
 				}
 
 				// Check the incoming values first.
-				if in, ok := incomingMap[k]; ok {
+				if in, ok := incomingMap[k]; ok && !(req.ExtendSameDayKeys && extendsExposure(ex, in)) {
 					if ex.IntervalNumber != in.IntervalNumber || ex.IntervalCount != in.IntervalCount {
 						logger.Errorw("incoming metadata mismatch",
 							"existing_count", ex.IntervalCount,
@@ -619,42 +1113,81 @@ func (db *PublishDB) InsertAndReviseExposures(ctx context.Context, req *InsertAn
 			incoming = append(incoming, v)
 		}
 
+		// Same day keys that are uploaded again with a longer rolling period are
+		// extended and released again.
+		var extended []*model.Exposure
+		if req.ExtendSameDayKeys {
+			for _, in := range incoming {
+				if ex, ok := existingMap[in.ExposureKeyBase64()]; ok && extendsExposure(ex, in) {
+					extended = append(extended, in)
+				}
+			}
+		}
+
+		// Health authorities may override which report type transitions are
+		// permitted on revision.
+		transitions, err := readReportTypeTransitions(ctx, tx, incoming)
+		if err != nil {
+			return fmt.Errorf("unable to read report type transitions: %w", err)
+		}
+
 		// Run through the merge logic.
-		exposures, err := model.ReviseKeys(ctx, existing, incoming)
+		exposures, err := model.ReviseKeys(ctx, existing, incoming, transitions)
 		if err != nil {
 			return fmt.Errorf("unable to revise keys: %w", err)
 		}
+
+		// A key can be both revised and extended, report it once.
 		resp.Exposures = exposures
+		revisedKeys := make(map[string]struct{}, len(exposures))
+		for _, exp := range exposures {
+			revisedKeys[exp.ExposureKeyBase64()] = struct{}{}
+		}
+		var extendedOnly uint32
+		for _, exp := range extended {
+			if _, ok := revisedKeys[exp.ExposureKeyBase64()]; !ok {
+				resp.Exposures = append(resp.Exposures, exp)
+				extendedOnly++
+			}
+		}
 
-		// Prepare the insert and update statements.
-		insertStmt, err := prepareInsertExposure(ctx, tx)
-		if err != nil {
-			return fmt.Errorf("preparing insert statement: %w", err)
+		if req.Quarantine != nil {
+			if err := insertQuarantineCohort(ctx, tx, req.Quarantine); err != nil {
+				return err
+			}
 		}
-		updateStmt, err := prepareReviseExposure(ctx, tx)
-		if err != nil {
-			return fmt.Errorf("preparing update statement: %w", err)
+
+		var batch exposureBatch
+		for _, exp := range extended {
+			queueExtendExposure(&batch, exp)
+			resp.Extended++
 		}
 
 		// only possible if all passed in keys are already existing and not revisions.
-		if len(exposures) == 0 {
+		if len(exposures) == 0 && len(extended) == 0 {
 			return nil
 		}
 
-		healthAuthorityID := exposures[0].HealthAuthorityID
+		// An upload of only extended keys is still published.
+		published := exposures
+		if len(published) == 0 {
+			published = extended
+		}
+		healthAuthorityID := published[0].HealthAuthorityID
 		for _, exp := range exposures {
 			if exp.RevisedAt == nil {
 				if exp.ReportType == verifyapi.ReportTypeNegative {
 					continue
 				}
-				if err := executeInsertExposure(ctx, tx, insertStmt, exp); err != nil {
-					return err
+				if req.Quarantine != nil {
+					queueQuarantineExposure(&batch, req.Quarantine.ID, exp)
+					resp.Quarantined++
+				} else {
+					queueInsertExposure(&batch, exp)
 				}
 				resp.Inserted++
 			} else {
-				if err := executeReviseExposure(ctx, tx, updateStmt, exp); err != nil {
-					return err
-				}
+				queueReviseExposure(&batch, exp)
 				resp.Revised++
 			}
 
@@ -664,18 +1197,35 @@ func (db *PublishDB) InsertAndReviseExposures(ctx context.Context, req *InsertAn
 			}
 		}
 
-		// If requested, update the publish stats for the associated health authority.
-		if stats != nil && healthAuthorityID != nil {
+		// If requested, update the publish stats for the associated health
+		// authority in the same round trip as the keys.
+		if stats != nil && healthAuthorityID != nil && *healthAuthorityID > 0 {
 			// For all practical purposes - this can be no more than a couple hundred TEKs in a single transaction.
-			stats.NumTEKs = int32(resp.Inserted) + int32(resp.Revised)
+			stats.NumTEKs = int32(resp.Inserted) + int32(resp.Revised) + int32(extendedOnly)
 			stats.Revision = resp.Revised > 0
-			go func() {
-				if err := db.UpdateStats(context.Background(), stats.CreatedAt, *healthAuthorityID, stats); err != nil {
-					logger.Errorw("failed to update statistics", "error", err)
-				}
-			}()
+			batch.queue(writeStats, addStatsSQL, addStatsArgs(stats.CreatedAt, *healthAuthorityID, stats)...)
 		}
 
+		if err := batch.send(ctx, tx); err != nil {
+			return err
+		}
+
+		if req.RecordEvent {
+			event, err := outboxmodel.NewEvent(outboxmodel.KindKeysPublished, &outboxmodel.KeysPublished{
+				AppPackageName:    published[0].AppPackageName,
+				HealthAuthorityID: healthAuthorityID,
+				Inserted:          resp.Inserted,
+				Revised:           resp.Revised,
+				Quarantined:       resp.Quarantined,
+				Extended:          resp.Extended,
+			}, time.Now())
+			if err != nil {
+				return err
+			}
+			if err := outboxdb.InsertEvent(ctx, tx, event); err != nil {
+				return err
+			}
+		}
 		return nil
 	}); err != nil {
 		return nil, err
@@ -684,21 +1234,79 @@ func (db *PublishDB) InsertAndReviseExposures(ctx context.Context, req *InsertAn
 	return &resp, nil
 }
 
-// DeleteExposuresBefore deletes exposures created before "before" date. Returns the number of records deleted.
-func (db *PublishDB) DeleteExposuresBefore(ctx context.Context, before time.Time) (int64, error) {
-	var count int64
-	// ReadCommitted is sufficient here because we are dealing with historical, immutable rows.
-	err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		result, err := tx.Exec(ctx, `
+// deleteExposuresBeforeSQL deletes expired exposures, except those whose
+// regions are all kept longer. It is served by the exposure_created_at index.
+const deleteExposuresBeforeSQL = `
+			DELETE FROM
+				Exposure
+			WHERE
+				created_at < $1
+				AND NOT (COALESCE(cardinality(regions), 0) > 0 AND regions <@ $2::VARCHAR[])
+			`
+
+// deleteRegionExposuresBeforeSQL deletes the expired exposures of a region
+// with a retention override. It is served by the exposure_created_at index.
+const deleteRegionExposuresBeforeSQL = `
 			DELETE FROM
 				Exposure
 			WHERE
 				created_at < $1
-			`, before)
+				AND $2 = ANY(regions)
+			`
+
+// DeleteExposuresBefore deletes exposures created before "before" date.
+// regionBefore overrides the date for the regions that keep exposures for a
+// shorter or longer time. An exposure in several regions is deleted at the
+// latest of the dates, so it is kept for the shortest retention of its
+// regions. Returns the number of records deleted.
+func (db *PublishDB) DeleteExposuresBefore(ctx context.Context, before time.Time, regionBefore map[string]time.Time) (int64, error) {
+	// Regions that are kept longer are skipped by the default pass.
+	longer := make([]string, 0, len(regionBefore))
+	for r, t := range regionBefore {
+		if t.Before(before) {
+			longer = append(longer, r)
+		}
+	}
+	sort.Strings(longer)
+
+	var count int64
+	// ReadCommitted is sufficient here because we are dealing with historical, immutable rows.
+	err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, deleteExposuresBeforeSQL, before, longer)
 		if err != nil {
 			return fmt.Errorf("deleting exposures: %w", err)
 		}
 		count = result.RowsAffected()
+
+		// Quarantined keys that were never released are past their export window
+		// at the same time.
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				QuarantinedExposure
+			WHERE
+				created_at < $1
+				AND NOT (COALESCE(cardinality(regions), 0) > 0 AND regions <@ $2::VARCHAR[])
+			`, before, longer); err != nil {
+			return fmt.Errorf("deleting quarantined exposures: %w", err)
+		}
+
+		for r, t := range regionBefore {
+			result, err := tx.Exec(ctx, deleteRegionExposuresBeforeSQL, t, r)
+			if err != nil {
+				return fmt.Errorf("deleting exposures of region %s: %w", r, err)
+			}
+			count += result.RowsAffected()
+
+			if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				QuarantinedExposure
+			WHERE
+				created_at < $1
+				AND $2 = ANY(regions)
+			`, t, r); err != nil {
+				return fmt.Errorf("deleting quarantined exposures of region %s: %w", r, err)
+			}
+		}
 		return nil
 	})
 	if err != nil {
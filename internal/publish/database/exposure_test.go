@@ -18,6 +18,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -26,13 +27,17 @@ import (
 	"testing"
 	"time"
 
+	outboxdb "github.com/google/exposure-notifications-server/internal/outbox/database"
+	outboxmodel "github.com/google/exposure-notifications-server/internal/outbox/model"
 	"github.com/google/exposure-notifications-server/internal/pb"
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	hamodel "github.com/google/exposure-notifications-server/internal/verification/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/errcmp"
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -153,21 +158,25 @@ func TestExposures(t *testing.T) {
 	batchTime := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC).Truncate(time.Microsecond)
 	exposures := []*model.Exposure{
 		{
-			ExposureKey:     []byte("ABC"),
-			Regions:         []string{"US", "CA", "MX"},
-			IntervalNumber:  18,
-			IntervalCount:   0,
-			CreatedAt:       batchTime,
-			LocalProvenance: true,
+			ExposureKey:       []byte("ABC"),
+			Regions:           []string{"US", "CA", "MX"},
+			IntervalNumber:    18,
+			IntervalCount:     0,
+			CreatedAt:         batchTime,
+			LocalProvenance:   true,
+			Jurisdiction:      stringPtr("US"),
+			FederationConsent: boolPtr(true),
 		},
 		{
-			ExposureKey:     []byte("DEF"),
-			Regions:         []string{"CA"},
-			Traveler:        true,
-			IntervalNumber:  118,
-			IntervalCount:   1,
-			CreatedAt:       batchTime.Add(1 * time.Hour),
-			LocalProvenance: true,
+			ExposureKey:       []byte("DEF"),
+			Regions:           []string{"CA"},
+			Traveler:          true,
+			IntervalNumber:    118,
+			IntervalCount:     1,
+			CreatedAt:         batchTime.Add(1 * time.Hour),
+			LocalProvenance:   true,
+			Jurisdiction:      stringPtr("CA"),
+			FederationConsent: boolPtr(false),
 		},
 		{
 			ExposureKey:     []byte("123"),
@@ -243,10 +252,50 @@ func TestExposures(t *testing.T) {
 			IterateExposuresCriteria{IncludeRegions: []string{"CA"}, IncludeTravelers: true},
 			[]int{0, 1, 2, 3},
 		},
+		{
+			IterateExposuresCriteria{IncludeRegions: []string{"MX"}, IncludeTravelersFrom: []string{"US"}},
+			[]int{0, 2, 3},
+		},
+		{
+			IterateExposuresCriteria{IncludeRegions: []string{"US", "MX"}},
+			[]int{0, 2, 3},
+		},
 		{
 			IterateExposuresCriteria{OnlyLocalProvenance: true, OnlyTravelers: true},
 			[]int{1},
 		},
+		{
+			IterateExposuresCriteria{IncludeJurisdictions: []string{"US"}},
+			[]int{0},
+		},
+		{
+			IterateExposuresCriteria{ExcludeJurisdictions: []string{"US"}},
+			[]int{1, 2, 3},
+		},
+		{
+			IterateExposuresCriteria{IncludeRegions: []string{"CA"}, ExcludeJurisdictions: []string{"CA"}},
+			[]int{0, 2},
+		},
+		{
+			IterateExposuresCriteria{FederationConsent: ConsentNotRefused},
+			[]int{0, 2, 3},
+		},
+		{
+			IterateExposuresCriteria{FederationConsent: ConsentGiven},
+			[]int{0},
+		},
+		{
+			IterateExposuresCriteria{IncludeRegions: []string{"MX"}, IncludeTravelers: true, TravelerConsent: ConsentNotRefused},
+			[]int{0, 2, 3},
+		},
+		{
+			IterateExposuresCriteria{IncludeRegions: []string{"MX"}, IncludeTravelers: true, TravelerConsent: ConsentGiven},
+			[]int{0, 2},
+		},
+		{
+			IterateExposuresCriteria{IncludeRegions: []string{"MX"}, IncludeTravelersFrom: []string{"CA"}, TravelerConsent: ConsentNotRefused},
+			[]int{0, 2},
+		},
 	} {
 		got, err := listExposures(ctx, testPublishDB, test.criteria)
 		if err != nil {
@@ -259,10 +308,18 @@ func TestExposures(t *testing.T) {
 		if diff := cmp.Diff(want, got, ignoreUnexportedExposure); diff != "" {
 			t.Errorf("%+v: mismatch (-want, +got):\n%s", test.criteria, diff)
 		}
+
+		count, err := testPublishDB.CountExposures(ctx, test.criteria)
+		if err != nil {
+			t.Fatalf("%+v: %v", test.criteria, err)
+		}
+		if count != int64(len(test.want)) {
+			t.Errorf("%+v: CountExposures got %d, want %d", test.criteria, count, len(test.want))
+		}
 	}
 
 	// Delete some exposures.
-	gotN, err := testPublishDB.DeleteExposuresBefore(ctx, exposures[2].CreatedAt)
+	gotN, err := testPublishDB.DeleteExposuresBefore(ctx, exposures[2].CreatedAt, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -290,6 +347,14 @@ func listExposures(ctx context.Context, db *PublishDB, c IterateExposuresCriteri
 	return exps, nil
 }
 
+func stringPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 func testRandomTEK(tb testing.TB) []byte {
 	tb.Helper()
 
@@ -322,6 +387,57 @@ func testExposure(tb testing.TB) *model.Exposure {
 	return exposure
 }
 
+func TestDeleteExposuresBefore_Regions(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	testPublishDB := New(testDB)
+
+	// All exposures are 12 days old. The default retention has passed, US-OR
+	// keeps exposures longer and US-WA has a shorter retention.
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	createdAt := now.Add(-12 * 24 * time.Hour)
+	exposures := []*model.Exposure{
+		{ExposureKey: []byte("default"), Regions: []string{"US-CA"}},
+		{ExposureKey: []byte("longer"), Regions: []string{"US-OR"}},
+		{ExposureKey: []byte("shorter"), Regions: []string{"US-WA"}},
+		{ExposureKey: []byte("mixed"), Regions: []string{"US-OR", "US-CA"}},
+		{ExposureKey: []byte("none")},
+	}
+	for i, e := range exposures {
+		e.IntervalNumber = int32(100 + i)
+		e.IntervalCount = 144
+		e.CreatedAt = createdAt
+		e.LocalProvenance = true
+	}
+	if _, err := testPublishDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming:     exposures,
+		RequireToken: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	gotN, err := testPublishDB.DeleteExposuresBefore(ctx, now.Add(-10*24*time.Hour), map[string]time.Time{
+		"US-OR": now.Add(-21 * 24 * time.Hour),
+		"US-WA": now.Add(-7 * 24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gotN, int64(4); got != want {
+		t.Errorf("expected %d deleted, got %d", want, got)
+	}
+
+	got, err := listExposures(ctx, testPublishDB, IterateExposuresCriteria{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || string(got[0].ExposureKey) != "longer" {
+		t.Errorf("expected only the longer retention exposure to be kept, got %v", got)
+	}
+}
+
 func TestInsertAndReviseExposures_MissingRequest(t *testing.T) {
 	t.Parallel()
 
@@ -349,6 +465,60 @@ func TestInsertAndReviseExposures_ConfigParadox(t *testing.T) {
 	errcmp.MustMatch(t, err, "configuration paradox")
 }
 
+func TestInsertAndReviseExposures_Tenants(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, config := testDatabaseInstance.NewDatabase(t)
+
+	createdAt := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC).Truncate(time.Microsecond)
+	exposure := &model.Exposure{
+		ExposureKey:     []byte("ABC123"),
+		Regions:         []string{"US"},
+		IntervalNumber:  100,
+		IntervalCount:   144,
+		CreatedAt:       createdAt,
+		LocalProvenance: true,
+	}
+	key := exposure.ExposureKeyBase64()
+
+	// Both tenants can upload the same key.
+	for _, tenant := range []string{"a", "b"} {
+		pubDB := New(database.NewTenantDatabase(t, testDB, config, tenant))
+
+		resp, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+			Incoming:     []*model.Exposure{exposure},
+			RequireToken: true,
+		})
+		if err != nil {
+			t.Fatalf("tenant %q: %v", tenant, err)
+		}
+		if got, want := resp.Inserted, uint32(1); got != want {
+			t.Errorf("tenant %q: expected %d inserted, got %d", tenant, want, got)
+		}
+
+		var got map[string]*model.Exposure
+		if err := pubDB.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+			var err error
+			got, err = pubDB.ReadExposures(ctx, tx, []string{key})
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 {
+			t.Errorf("tenant %q: expected 1 exposure, got %d", tenant, len(got))
+		}
+	}
+
+	var count int
+	if err := testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM Exposure WHERE exposure_key = $1`, key).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 exposures across tenants, got %d", count)
+	}
+}
+
 func TestReviseExposures(t *testing.T) {
 	t.Parallel()
 
@@ -746,6 +916,280 @@ func TestReviseExposures(t *testing.T) {
 	})
 }
 
+func TestReviseExposures_ReportTypeTransitions(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	haDB := hadb.New(testDB)
+	healthAuthority := &hamodel.HealthAuthority{
+		Issuer:   "a",
+		Audience: "b",
+		Name:     "c",
+	}
+	if err := haDB.AddHealthAuthority(ctx, healthAuthority); err != nil {
+		t.Fatal(err)
+	}
+	if err := haDB.SetReportTypeTransitions(ctx, healthAuthority.ID, []*hamodel.ReportTypeTransition{
+		{From: verifyapi.ReportTypeClinical, To: verifyapi.ReportTypeNegative, Allowed: false},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exposure := testExposure(t)
+	exposure.SetHealthAuthorityID(healthAuthority.ID)
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exposure},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Allowed by default, but denied by the health authority.
+	exposure.ReportType = verifyapi.ReportTypeNegative
+	_, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exposure},
+	})
+	var transitionErr *model.ErrorKeyInvalidReportTypeTransition
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("expected invalid transition error, got %v", err)
+	}
+
+	// Transitions without an override use the defaults.
+	exposure.ReportType = verifyapi.ReportTypeConfirmed
+	resp, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exposure},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := int(resp.Revised), 1; got != want {
+		t.Fatalf("expected %d to be %d", got, want)
+	}
+}
+
+func TestInsertAndReviseExposures_ExtendSameDayKeys(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	exposure := testExposure(t)
+	exposure.IntervalCount = 60
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exposure},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the option, the longer key is a mismatch.
+	longer := *exposure
+	longer.IntervalCount = 144
+	longer.CreatedAt = exposure.CreatedAt.Add(24 * time.Hour)
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming:     []*model.Exposure{&longer},
+		RequireToken: true,
+		Token: &pb.RevisionTokenData{
+			RevisableKeys: []*pb.RevisableKey{
+				{TemporaryExposureKey: exposure.ExposureKey, IntervalNumber: 100, IntervalCount: 60},
+			},
+		},
+	}); !errors.Is(err, ErrIncomingMetadataMismatch) {
+		t.Fatalf("expected %v, got %v", ErrIncomingMetadataMismatch, err)
+	}
+
+	resp, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming:          []*model.Exposure{&longer},
+		ExtendSameDayKeys: true,
+		RequireToken:      true,
+		Token: &pb.RevisionTokenData{
+			RevisableKeys: []*pb.RevisableKey{
+				{TemporaryExposureKey: exposure.ExposureKey, IntervalNumber: 100, IntervalCount: 60},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := int(resp.Extended), 1; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := int(resp.Inserted+resp.Revised), 0; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	var got map[string]*model.Exposure
+	if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var err error
+		got, err = pubDB.ReadExposures(ctx, tx, []string{exposure.ExposureKeyBase64()})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stored := got[exposure.ExposureKeyBase64()]
+	if stored == nil {
+		t.Fatal("missing exposure")
+	}
+	if got, want := stored.IntervalCount, int32(144); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := stored.CreatedAt, longer.CreatedAt; !got.Equal(want) {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+}
+
+func TestInsertAndReviseExposures_ExtendSameDayKeysEvent(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	exposure := testExposure(t)
+	exposure.IntervalCount = 60
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exposure},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An upload of only extended keys records an event.
+	longer := *exposure
+	longer.IntervalCount = 144
+	resp, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming:          []*model.Exposure{&longer},
+		ExtendSameDayKeys: true,
+		RecordEvent:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := int(resp.Extended), 1; got != want {
+		t.Fatalf("expected %d to be %d", got, want)
+	}
+
+	events, err := outboxdb.New(testDB).ClaimEvents(ctx, 10, time.Minute, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(events), 1; got != want {
+		t.Fatalf("expected %d events, got %d", want, got)
+	}
+	if got, want := events[0].Kind, outboxmodel.KindKeysPublished; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	var got outboxmodel.KeysPublished
+	if err := json.Unmarshal(events[0].Payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := outboxmodel.KeysPublished{
+		AppPackageName: exposure.AppPackageName,
+		Extended:       1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestInsertAndReviseExposures_ExtendSameDayKeysStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	healthAuthority := &hamodel.HealthAuthority{
+		Issuer:   "a",
+		Audience: "b",
+		Name:     "c",
+	}
+	if err := hadb.New(testDB).AddHealthAuthority(ctx, healthAuthority); err != nil {
+		t.Fatal(err)
+	}
+
+	exposure := testExposure(t)
+	exposure.IntervalCount = 60
+	exposure.HealthAuthorityID = &healthAuthority.ID
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exposure},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An upload of only extended keys records its stats.
+	hour := time.Now().UTC().Truncate(time.Hour)
+	longer := *exposure
+	longer.IntervalCount = 144
+	info := &model.PublishInfo{
+		CreatedAt: hour,
+		Platform:  model.PlatformAndroid,
+	}
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming:          []*model.Exposure{&longer},
+		PublishInfo:       info,
+		ExtendSameDayKeys: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := info.NumTEKs, int32(1); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	want := model.InitHour(healthAuthority.ID, hour)
+	want.AddPublish(info)
+
+	stats, err := pubDB.ReadStats(ctx, healthAuthority.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 hour of stats, got: %v", len(stats))
+	}
+	if diff := cmp.Diff(want, stats[0]); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestInsertAndReviseExposures_ExtendAndRevise(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	exposure := testExposure(t)
+	exposure.IntervalCount = 60
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exposure},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is both revised and extended, it is reported once.
+	longer := *exposure
+	longer.IntervalCount = 144
+	longer.ReportType = verifyapi.ReportTypeConfirmed
+	resp, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming:          []*model.Exposure{&longer},
+		ExtendSameDayKeys: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := int(resp.Revised), 1; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := int(resp.Extended), 1; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := len(resp.Exposures), 1; got != want {
+		t.Errorf("expected %d exposures, got %d", want, got)
+	}
+}
+
 func TestIterateExposuresCursor(t *testing.T) {
 	t.Parallel()
 
@@ -796,8 +1240,15 @@ func TestIterateExposuresCursor(t *testing.T) {
 	if want := 2; len(seen) != want {
 		t.Fatalf("cursor: got %d, want %d", len(seen), want)
 	}
-	if want := encodeCursor("2"); cursor != want {
-		t.Fatalf("cursor: got %q, want %q", cursor, want)
+	keyset, err := database.DecodeKeyset(cursor)
+	if err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+	if got, want := keyset.Key, encodeExposureKey(seen[1].ExposureKey); got != want {
+		t.Fatalf("cursor key: got %q, want %q", got, want)
+	}
+	if got, want := keyset.Time, seen[1].CreatedAt; !got.Equal(want) {
+		t.Fatalf("cursor time: got %s, want %s", got, want)
 	}
 	// Resume from the cursor.
 	ctx = project.TestContext(t)
@@ -813,3 +1264,356 @@ func TestIterateExposuresCursor(t *testing.T) {
 		t.Fatalf("cursor: got %q, want empty", cursor)
 	}
 }
+
+func TestIterateExposures_ExcludeGenerated(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	testPublishDB := New(testDB)
+
+	exposures := []*model.Exposure{
+		{
+			ExposureKey:     []byte("ABC"),
+			AppPackageName:  "com.example.app",
+			Regions:         []string{"US"},
+			IntervalNumber:  18,
+			LocalProvenance: true,
+		},
+		{
+			ExposureKey:     []byte("DEF"),
+			AppPackageName:  model.GeneratedAppPackageName,
+			Regions:         []string{"US"},
+			IntervalNumber:  18,
+			LocalProvenance: true,
+		},
+	}
+	if _, err := testPublishDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming:     exposures,
+		RequireToken: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exclude := range []bool{false, true} {
+		var seen [][]byte
+		if _, err := testPublishDB.IterateExposures(ctx, IterateExposuresCriteria{ExcludeGenerated: exclude}, func(e *model.Exposure) error {
+			seen = append(seen, e.ExposureKey)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		want := [][]byte{[]byte("ABC"), []byte("DEF")}
+		if exclude {
+			want = want[:1]
+		}
+		if diff := cmp.Diff(want, seen, cmpopts.SortSlices(func(a, b []byte) bool { return string(a) < string(b) })); diff != "" {
+			t.Errorf("exclude=%t: keys mismatch (-want, +got):\n%s", exclude, diff)
+		}
+	}
+}
+
+func TestIterateExposures_KeyRange(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	testPublishDB := New(testDB)
+
+	// Base64 does not sort like the bytes it encodes. These keys are in byte
+	// order, but "/w==" sorts before "AA==" as text.
+	keys := [][]byte{{0x00}, {0x7f}, {0xff}}
+	exposures := make([]*model.Exposure, 0, len(keys))
+	for _, k := range keys {
+		exposures = append(exposures, &model.Exposure{
+			ExposureKey:     k,
+			Regions:         []string{"US"},
+			IntervalNumber:  18,
+			LocalProvenance: true,
+		})
+	}
+	if _, err := testPublishDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: exposures,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		criteria IterateExposuresCriteria
+		want     [][]byte
+	}{
+		{
+			name:     "all",
+			criteria: IterateExposuresCriteria{},
+			want:     keys,
+		},
+		{
+			name:     "from",
+			criteria: IterateExposuresCriteria{KeysFrom: []byte{0x7f}},
+			want:     keys[1:],
+		},
+		{
+			name:     "until",
+			criteria: IterateExposuresCriteria{KeysUntil: []byte{0x7f}},
+			want:     keys[:1],
+		},
+		{
+			name:     "from_until",
+			criteria: IterateExposuresCriteria{KeysFrom: []byte{0x01}, KeysUntil: []byte{0xff}},
+			want:     keys[1:2],
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var seen [][]byte
+			if _, err := testPublishDB.IterateExposures(ctx, tc.criteria, func(e *model.Exposure) error {
+				seen = append(seen, e.ExposureKey)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, seen, cmpopts.SortSlices(func(a, b []byte) bool { return string(a) < string(b) })); diff != "" {
+				t.Errorf("keys mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateExposureQuery_Pagination(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2021, 1, 2, 3, 0, 0, 0, time.UTC)
+	keyset := &database.Keyset{Time: createdAt, Key: "a2V5"}
+
+	cases := []struct {
+		name     string
+		criteria IterateExposuresCriteria
+		contains []string
+		args     []interface{}
+		err      bool
+	}{
+		{
+			name:     "first_page",
+			criteria: IterateExposuresCriteria{Limit: 10},
+			contains: []string{"ORDER BY created_at, exposure_key", "LIMIT $1"},
+			args:     []interface{}{uint32(10)},
+		},
+		{
+			name:     "keyset",
+			criteria: IterateExposuresCriteria{LastCursor: keyset.Encode(), Limit: 10},
+			contains: []string{"(created_at, exposure_key) > ($1, $2) ORDER BY created_at, exposure_key", "LIMIT $3"},
+			args:     []interface{}{createdAt, "a2V5", uint32(10)},
+		},
+		{
+			name:     "keyset_revised",
+			criteria: IterateExposuresCriteria{LastCursor: keyset.Encode(), OnlyRevisedKeys: true},
+			contains: []string{"(revised_at, exposure_key) > ($1, $2) ORDER BY revised_at, exposure_key"},
+			args:     []interface{}{createdAt, "a2V5"},
+		},
+		{
+			name:     "exclude_revoked",
+			criteria: IterateExposuresCriteria{ExcludeRevoked: true},
+			contains: []string{"revised_report_type IS DISTINCT FROM $1", "ORDER BY created_at, exposure_key"},
+			args:     []interface{}{verifyapi.ReportTypeNegative},
+		},
+		{
+			name:     "exclude_revoked_ignored_for_revised",
+			criteria: IterateExposuresCriteria{ExcludeRevoked: true, OnlyRevisedKeys: true},
+			contains: []string{"revised_at IS NOT NULL", "ORDER BY revised_at, exposure_key"},
+		},
+		{
+			name:     "exclude_test_data",
+			criteria: IterateExposuresCriteria{ExcludeTestData: true},
+			contains: []string{"test_data = $1"},
+			args:     []interface{}{false},
+		},
+		{
+			name:     "exclude_generated",
+			criteria: IterateExposuresCriteria{ExcludeGenerated: true},
+			contains: []string{"app_package_name != $1"},
+			args:     []interface{}{model.GeneratedAppPackageName},
+		},
+		{
+			name:     "key_range",
+			criteria: IterateExposuresCriteria{KeysFrom: []byte{1}, KeysUntil: []byte{2}},
+			contains: []string{"decode(exposure_key, 'base64') >= $1 AND decode(exposure_key, 'base64') < $2"},
+			args:     []interface{}{[]byte{1}, []byte{2}},
+		},
+		{
+			name:     "only_test_data",
+			criteria: IterateExposuresCriteria{OnlyTestData: true},
+			contains: []string{"test_data = $1"},
+			args:     []interface{}{true},
+		},
+		{
+			name:     "no_key_metadata",
+			criteria: IterateExposuresCriteria{},
+			contains: []string{"test_data, NULL::BYTEA "},
+		},
+		{
+			name:     "key_metadata",
+			criteria: IterateExposuresCriteria{IncludeKeyMetadata: true},
+			contains: []string{"test_data, key_metadata "},
+		},
+		{
+			name:     "provenance",
+			criteria: IterateExposuresCriteria{IncludeProvenance: true},
+			contains: []string{"export_import_id, provenance_source, original_regions,"},
+		},
+		{
+			name:     "only_local_provenance",
+			criteria: IterateExposuresCriteria{OnlyLocalProvenance: true},
+			contains: []string{"COALESCE(provenance_source = $1, local_provenance)"},
+			args:     []interface{}{string(model.ProvenancePublish)},
+		},
+		{
+			name:     "provenance_sources",
+			criteria: IterateExposuresCriteria{IncludeProvenanceSources: []model.ProvenanceSource{model.ProvenanceFederationIn, model.ProvenanceExportImport}},
+			contains: []string{"provenance_source = ANY($1)"},
+			args:     []interface{}{[]string{"federation-in", "export-import"}},
+		},
+		{
+			name:     "invalid_provenance_source",
+			criteria: IterateExposuresCriteria{IncludeProvenanceSources: []model.ProvenanceSource{"mystery"}},
+			err:      true,
+		},
+		{
+			name:     "legacy_offset",
+			criteria: IterateExposuresCriteria{LastCursor: encodeCursor("20"), Limit: 10},
+			contains: []string{"ORDER BY created_at, exposure_key OFFSET $1 LIMIT $2"},
+			args:     []interface{}{"20", uint32(10)},
+		},
+		{
+			name:     "invalid_cursor",
+			criteria: IterateExposuresCriteria{LastCursor: encodeCursor("nope")},
+			err:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			q, args, err := generateExposureQuery(tc.criteria)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			for _, want := range tc.contains {
+				if !strings.Contains(q, want) {
+					t.Errorf("expected %q to contain %q", q, want)
+				}
+			}
+			if diff := cmp.Diff(tc.args, args); diff != "" {
+				t.Errorf("args mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadExposures_QueryPlan(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	testPublishDB := New(testDB)
+
+	exposures := make([]*model.Exposure, 0, 5000)
+	for i := 0; i < cap(exposures); i++ {
+		exposures = append(exposures, testExposure(t))
+	}
+	if _, err := testPublishDB.BulkInsertExposures(ctx, exposures); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.Pool.Exec(ctx, `ANALYZE Exposure`); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 0, 30)
+	for _, exp := range exposures[:30] {
+		keys = append(keys, exp.ExposureKeyBase64())
+	}
+
+	// The test user bypasses row level security, so the plan looks up keys by
+	// exposure_key alone, which the (tenant, exposure_key) primary key cannot
+	// serve without reading the whole index.
+	var plan []string
+	if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, "EXPLAIN "+readExposuresSQL, keys)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			plan = append(plan, line)
+		}
+		return rows.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(plan, "\n"); !strings.Contains(got, "exposure_key_hash") {
+		t.Errorf("expected plan to use exposure_key_hash:\n%s", got)
+	}
+}
+
+// BenchmarkInsertAndReviseExposures measures a publish of the maximum 30 keys
+// against a table that already holds many keys.
+func BenchmarkInsertAndReviseExposures(b *testing.B) {
+	ctx := project.TestContext(b)
+	testDB, _ := testDatabaseInstance.NewDatabase(b)
+	testPublishDB := New(testDB)
+
+	createdAt := time.Now().UTC().Truncate(time.Hour)
+	randomExposures := func(n int) []*model.Exposure {
+		exposures := make([]*model.Exposure, 0, n)
+		for i := 0; i < n; i++ {
+			key := make([]byte, verifyapi.KeyLength)
+			if _, err := rand.Read(key); err != nil {
+				b.Fatal(err)
+			}
+			exposures = append(exposures, &model.Exposure{
+				ExposureKey:     key,
+				Regions:         []string{"US"},
+				IntervalNumber:  int32(2650000 + i),
+				IntervalCount:   144,
+				CreatedAt:       createdAt,
+				LocalProvenance: true,
+			})
+		}
+		return exposures
+	}
+
+	if _, err := testPublishDB.BulkInsertExposures(ctx, randomExposures(50000)); err != nil {
+		b.Fatal(err)
+	}
+
+	requests := make([]*InsertAndReviseExposuresRequest, b.N)
+	for i := range requests {
+		requests[i] = &InsertAndReviseExposuresRequest{Incoming: randomExposures(30)}
+	}
+
+	b.ResetTimer()
+	for _, req := range requests {
+		resp, err := testPublishDB.InsertAndReviseExposures(ctx, req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if resp.Inserted != 30 {
+			b.Fatalf("expected 30 inserted keys, got %d", resp.Inserted)
+		}
+	}
+}
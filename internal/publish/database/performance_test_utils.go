@@ -22,7 +22,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 func executeBulkInsertExposure(ctx context.Context, tx pgx.Tx, expos []*model.Exposure) (int64, error) {
@@ -0,0 +1,133 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+)
+
+// TestQueryPlans guards the export, federation and cleanup queries against
+// sequential scans of the Exposure table, which a migration could introduce
+// by dropping or changing an index.
+func TestQueryPlans(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	// Seed two weeks of keys so the statistics look like a production table.
+	now := time.Now().UTC().Truncate(time.Hour)
+	exposures := make([]*model.Exposure, 0, 10000)
+	for i := 0; i < cap(exposures); i++ {
+		exposure := testExposure(t)
+		exposure.CreatedAt = now.Add(-time.Duration(i%(14*24)) * time.Hour)
+		exposure.Traveler = i%10 == 0
+		exposure.LocalProvenance = i%4 != 0
+		exposures = append(exposures, exposure)
+	}
+	if _, err := pubDB.BulkInsertExposures(ctx, exposures); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.Pool.Exec(ctx, `ANALYZE Exposure`); err != nil {
+		t.Fatal(err)
+	}
+
+	since := now.Add(-24 * time.Hour)
+	cases := []struct {
+		name     string
+		criteria IterateExposuresCriteria
+	}{
+		{
+			name: "export",
+			criteria: IterateExposuresCriteria{
+				SinceTimestamp:   since,
+				UntilTimestamp:   now,
+				IncludeRegions:   []string{"US"},
+				IncludeTravelers: true,
+				ExcludeRegions:   []string{"MX"},
+				ExcludeRevoked:   true,
+				ExcludeTestData:  true,
+			},
+		},
+		{
+			name: "export_revised",
+			criteria: IterateExposuresCriteria{
+				SinceTimestamp:  since,
+				UntilTimestamp:  now,
+				IncludeRegions:  []string{"US"},
+				OnlyRevisedKeys: true,
+				ExcludeTestData: true,
+			},
+		},
+		{
+			name: "federation",
+			criteria: IterateExposuresCriteria{
+				SinceTimestamp:      since,
+				UntilTimestamp:      now,
+				IncludeRegions:      []string{"US", "CA"},
+				IncludeTravelers:    true,
+				OnlyLocalProvenance: true,
+				ExcludeGenerated:    true,
+				ExcludeRevoked:      true,
+				ExcludeTestData:     true,
+				Limit:               1000,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sql, args, err := generateExposureQuery(tc.criteria)
+			if err != nil {
+				t.Fatal(err)
+			}
+			database.RequireIndexed(t, testDB, []string{"exposure"}, sql, args...)
+		})
+	}
+
+	t.Run("revision_lookup", func(t *testing.T) {
+		t.Parallel()
+
+		keys := []string{
+			encodeExposureKey(exposures[0].ExposureKey),
+			encodeExposureKey(exposures[1].ExposureKey),
+		}
+		database.RequireIndexed(t, testDB, []string{"exposure"}, readExposuresSQL, keys)
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		t.Parallel()
+
+		before := now.Add(-14 * 24 * time.Hour)
+		database.RequireIndexed(t, testDB, []string{"exposure"}, deleteExposuresBeforeSQL, before, []string{"US-WA"})
+	})
+
+	t.Run("cleanup_region", func(t *testing.T) {
+		t.Parallel()
+
+		before := now.Add(-10 * 24 * time.Hour)
+		database.RequireIndexed(t, testDB, []string{"exposure"}, deleteRegionExposuresBeforeSQL, before, "US-WA")
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	quarantinemodel "github.com/google/exposure-notifications-server/internal/quarantine/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// queuedRequest is an InsertAndReviseExposuresRequest as it is carried on the
+// publish queue. Only the fields used by publish are carried, federation
+// writes are never queued.
+type queuedRequest struct {
+	Incoming    []*model.QueuedExposure
+	Token       []byte `json:",omitempty"`
+	PublishInfo *model.PublishInfo
+
+	RequireToken          bool
+	AllowPartialRevisions bool
+	ExtendSameDayKeys     bool
+	RecordEvent           bool
+
+	Quarantine *quarantinemodel.Cohort
+}
+
+// MarshalQueuedRequest encodes the request for the publish queue.
+func MarshalQueuedRequest(req *InsertAndReviseExposuresRequest) ([]byte, error) {
+	q := &queuedRequest{
+		Incoming:              model.NewQueuedExposures(req.Incoming),
+		PublishInfo:           req.PublishInfo,
+		RequireToken:          req.RequireToken,
+		AllowPartialRevisions: req.AllowPartialRevisions,
+		ExtendSameDayKeys:     req.ExtendSameDayKeys,
+		RecordEvent:           req.RecordEvent,
+		Quarantine:            req.Quarantine,
+	}
+	if req.Token != nil {
+		b, err := proto.Marshal(req.Token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal revision token: %w", err)
+		}
+		q.Token = b
+	}
+
+	b, err := json.Marshal(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal queued request: %w", err)
+	}
+	return b, nil
+}
+
+// UnmarshalQueuedRequest decodes a request from the publish queue.
+func UnmarshalQueuedRequest(b []byte) (*InsertAndReviseExposuresRequest, error) {
+	var q queuedRequest
+	if err := json.Unmarshal(b, &q); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queued request: %w", err)
+	}
+
+	req := &InsertAndReviseExposuresRequest{
+		Incoming:              model.UnwrapQueuedExposures(q.Incoming),
+		PublishInfo:           q.PublishInfo,
+		RequireToken:          q.RequireToken,
+		AllowPartialRevisions: q.AllowPartialRevisions,
+		ExtendSameDayKeys:     q.ExtendSameDayKeys,
+		RecordEvent:           q.RecordEvent,
+		Quarantine:            q.Quarantine,
+	}
+	if len(q.Token) > 0 {
+		var token pb.RevisionTokenData
+		if err := proto.Unmarshal(q.Token, &token); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal revision token: %w", err)
+		}
+		req.Token = &token
+	}
+	return req, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	quarantinemodel "github.com/google/exposure-notifications-server/internal/quarantine/model"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestQueuedRequest(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	onset := int32(2)
+
+	cases := []struct {
+		name string
+		req  *InsertAndReviseExposuresRequest
+	}{
+		{
+			name: "minimal",
+			req: &InsertAndReviseExposuresRequest{
+				Incoming: []*model.Exposure{
+					{ExposureKey: []byte("ABCDEFGHIJKLMNOP"), IntervalNumber: 100, IntervalCount: 144, CreatedAt: createdAt},
+				},
+			},
+		},
+		{
+			name: "full",
+			req: &InsertAndReviseExposuresRequest{
+				// The symptom onset is unexported, it can only be set through the
+				// queued form.
+				Incoming: model.UnwrapQueuedExposures([]*model.QueuedExposure{
+					{
+						Exposure: &model.Exposure{
+							ExposureKey:           []byte("ABCDEFGHIJKLMNOP"),
+							AppPackageName:        "com.example.app",
+							Regions:               []string{"US"},
+							IntervalNumber:        100,
+							IntervalCount:         144,
+							CreatedAt:             createdAt,
+							LocalProvenance:       true,
+							ReportType:            "confirmed",
+							DaysSinceSymptomOnset: &onset,
+							TestData:              true,
+						},
+						SymptomOnsetInterval: 388,
+					},
+				}),
+				Token: &pb.RevisionTokenData{
+					RevisableKeys: []*pb.RevisableKey{
+						{TemporaryExposureKey: []byte("ABCDEFGHIJKLMNOP"), IntervalNumber: 100, IntervalCount: 144},
+					},
+				},
+				PublishInfo: &model.PublishInfo{
+					CreatedAt: createdAt,
+					Platform:  "android",
+					NumTEKs:   1,
+				},
+				RequireToken:          true,
+				AllowPartialRevisions: true,
+				ExtendSameDayKeys:     true,
+				RecordEvent:           true,
+				Quarantine: &quarantinemodel.Cohort{
+					AppPackageName: "com.example.app",
+					CreatedAt:      createdAt,
+					Score:          10,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := MarshalQueuedRequest(tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := UnmarshalQueuedRequest(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.req, got, cmp.AllowUnexported(model.Exposure{}), protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
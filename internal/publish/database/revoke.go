@@ -0,0 +1,89 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// RevokeExposuresRequest is the input to RevokeExposures.
+type RevokeExposuresRequest struct {
+	// Keys are the base64 encoded exposure keys to revoke.
+	Keys []string
+	// Reason is recorded with the revoked keys, e.g. "lab error".
+	Reason string
+	// RevokedAt is the revision time of the revoked keys. It determines the
+	// export batch in which the revocation is published.
+	RevokedAt time.Time
+}
+
+// RevokeExposuresResponse is the result of RevokeExposures.
+type RevokeExposuresResponse struct {
+	// Revoked is the number of keys that were revised to revoked.
+	Revoked int64
+	// Deleted is the number of keys that were removed because they were not
+	// yet eligible for export or federation.
+	Deleted int64
+}
+
+// RevokeExposures revokes previously published exposure keys. Keys that may
+// have already been exported or federated are revised to the negative report
+// type, so the revocation is published in the revised keys of the next export
+// batch and federation response. Keys that are not yet eligible for export,
+// such as keys held by a health authority's export delay, are deleted instead.
+// Keys that are already revoked are left unchanged.
+func (db *PublishDB) RevokeExposures(ctx context.Context, req *RevokeExposuresRequest) (*RevokeExposuresResponse, error) {
+	if req == nil || len(req.Keys) == 0 {
+		return &RevokeExposuresResponse{}, nil
+	}
+
+	var resp RevokeExposuresResponse
+	if err := db.db.InTx(ctx, pgx.Serializable, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM
+				Exposure
+			WHERE
+				exposure_key = ANY($1) AND created_at > $2
+			`, req.Keys, req.RevokedAt)
+		if err != nil {
+			return fmt.Errorf("deleting unexported exposures: %w", err)
+		}
+		resp.Deleted = result.RowsAffected()
+
+		result, err = tx.Exec(ctx, `
+			UPDATE
+				Exposure
+			SET
+				revised_report_type = $2, revised_at = $3, revised_transmission_risk = $4,
+				revised_days_since_symptom_onset = COALESCE(revised_days_since_symptom_onset, days_since_symptom_onset),
+				revocation_reason = $5
+			WHERE
+				exposure_key = ANY($1) AND revised_report_type IS DISTINCT FROM $2
+			`, req.Keys, verifyapi.ReportTypeNegative, req.RevokedAt, verifyapi.TransmissionRiskNegative, req.Reason)
+		if err != nil {
+			return fmt.Errorf("revising revoked exposures: %w", err)
+		}
+		resp.Revoked = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("revoking exposures: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+func TestRevokeExposures(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	now := time.Now().UTC().Truncate(time.Hour)
+
+	// Already eligible for export, so it is revised.
+	exported := testExposure(t)
+
+	// Held by an export delay, so it is deleted.
+	delayed := testExposure(t)
+	delayed.CreatedAt = now.Add(2 * time.Hour)
+
+	// Not revoked.
+	other := testExposure(t)
+
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{exported, delayed, other},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &RevokeExposuresRequest{
+		Keys:      []string{exported.ExposureKeyBase64(), delayed.ExposureKeyBase64()},
+		Reason:    "lab error",
+		RevokedAt: now,
+	}
+	resp, err := pubDB.RevokeExposures(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.Revoked, int64(1); got != want {
+		t.Errorf("expected revoked %d to be %d", got, want)
+	}
+	if got, want := resp.Deleted, int64(1); got != want {
+		t.Errorf("expected deleted %d to be %d", got, want)
+	}
+
+	// Revoking again is a no-op.
+	resp, err = pubDB.RevokeExposures(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Revoked != 0 || resp.Deleted != 0 {
+		t.Errorf("expected no changes, got %#v", resp)
+	}
+
+	var got map[string]*model.Exposure
+	if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var err error
+		got, err = pubDB.ReadExposures(ctx, tx, []string{exported.ExposureKeyBase64(), delayed.ExposureKeyBase64(), other.ExposureKeyBase64()})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got[delayed.ExposureKeyBase64()]; ok {
+		t.Errorf("expected delayed key to be deleted")
+	}
+	revoked := got[exported.ExposureKeyBase64()]
+	if revoked == nil || revoked.RevisedReportType == nil || *revoked.RevisedReportType != verifyapi.ReportTypeNegative {
+		t.Fatalf("expected key to be revoked, got %#v", revoked)
+	}
+	if revoked.RevisedAt == nil || !revoked.RevisedAt.Equal(now) {
+		t.Errorf("expected revised at %v, got %v", now, revoked.RevisedAt)
+	}
+	if got[other.ExposureKeyBase64()].RevisedAt != nil {
+		t.Errorf("expected other key to not be revised")
+	}
+
+	// Revoked keys are only returned as revised keys.
+	for _, tc := range []struct {
+		criteria IterateExposuresCriteria
+		want     int
+	}{
+		{criteria: IterateExposuresCriteria{ExcludeRevoked: true}, want: 1},
+		{criteria: IterateExposuresCriteria{ExcludeRevoked: true, OnlyRevisedKeys: true}, want: 1},
+	} {
+		var count int
+		if _, err := pubDB.IterateExposures(ctx, tc.criteria, func(*model.Exposure) error {
+			count++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if count != tc.want {
+			t.Errorf("%#v: expected %d keys, got %d", tc.criteria, tc.want, count)
+		}
+	}
+}
@@ -16,12 +16,13 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/exposure-notifications-server/pkg/timeutils"
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 // DeleteStatsBefore deletes exposure publish stats created before "before" date. Returns the number of records deleted.
@@ -40,6 +41,127 @@ func (db *PublishDB) DeleteStatsBefore(ctx context.Context, before time.Time) (i
 			return fmt.Errorf("deleting exposures: %w", err)
 		}
 		count = result.RowsAffected()
+
+		// Privacy budgets are kept as long as the stats they were spent on.
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				StatsPrivacyBudget
+			WHERE
+				period_start < $1
+			`, before); err != nil {
+			return fmt.Errorf("deleting privacy budgets: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ErrPrivacyBudgetExhausted is returned when spending would exceed a privacy
+// budget.
+var ErrPrivacyBudgetExhausted = errors.New("privacy budget exhausted")
+
+// SpendStatsPrivacyBudget records that the health authority spent epsilon of
+// its stats privacy budget for the period that starts at periodStart. It
+// returns the total spent in the period, or ErrPrivacyBudgetExhausted without
+// spending anything if the total would exceed budget.
+func (db *PublishDB) SpendStatsPrivacyBudget(ctx context.Context, healthAuthorityID int64, periodStart time.Time, epsilon, budget float64) (float64, error) {
+	if healthAuthorityID <= 0 {
+		return 0, fmt.Errorf("missing healthAuthorityID")
+	}
+	if epsilon > budget {
+		return 0, ErrPrivacyBudgetExhausted
+	}
+
+	var spent float64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				StatsPrivacyBudget (health_authority_id, period_start, epsilon_spent)
+			VALUES
+				($1, $2, $3)
+			ON CONFLICT (tenant, health_authority_id, period_start) DO UPDATE
+				SET epsilon_spent = StatsPrivacyBudget.epsilon_spent + EXCLUDED.epsilon_spent
+				WHERE StatsPrivacyBudget.epsilon_spent + EXCLUDED.epsilon_spent <= $4
+			RETURNING epsilon_spent
+			`, healthAuthorityID, periodStart.UTC(), epsilon, budget)
+		if err := row.Scan(&spent); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrPrivacyBudgetExhausted
+			}
+			return fmt.Errorf("spending privacy budget: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return spent, nil
+}
+
+// RecomputeStats recounts the TEKs of each health authority and hour in
+// [from, to) from the exposures that are still stored, and overwrites the
+// stats of those hours. Keys are counted in the hour they were published and
+// again in the hour they were revised, as they are when published. A
+// healthAuthorityID of 0 recomputes every health authority.
+//
+// Publish requests, revisions, and the age distributions are per request and
+// cannot be derived from the keys, so they are left as recorded. Hours without
+// stored keys, for example after cleanup, are not changed. Each recomputed hour
+// is marked with the time and reason. It returns the number of hours
+// recomputed.
+func (db *PublishDB) RecomputeStats(ctx context.Context, from, to time.Time, healthAuthorityID int64, reason string) (int64, error) {
+	if !from.Before(to) {
+		return 0, fmt.Errorf("from must be before to")
+	}
+	if healthAuthorityID < 0 {
+		return 0, fmt.Errorf("invalid healthAuthorityID")
+	}
+	if reason == "" {
+		return 0, fmt.Errorf("missing reason")
+	}
+
+	empty := model.InitHour(healthAuthorityID, from)
+
+	var count int64
+	err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			WITH keys AS (
+				SELECT
+					health_authority_id, date_trunc('hour', created_at AT TIME ZONE 'UTC') AS hour
+				FROM
+					Exposure
+				WHERE
+					created_at >= $1 AND created_at < $2 AND
+					health_authority_id IS NOT NULL AND ($3 = 0 OR health_authority_id = $3)
+				UNION ALL
+				SELECT
+					health_authority_id, date_trunc('hour', revised_at AT TIME ZONE 'UTC') AS hour
+				FROM
+					Exposure
+				WHERE
+					revised_at >= $1 AND revised_at < $2 AND
+					health_authority_id IS NOT NULL AND ($3 = 0 OR health_authority_id = $3)
+			)
+			INSERT INTO
+				HealthAuthorityStats
+				(health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset,
+				 recomputed_at, recompute_reason)
+			SELECT
+				health_authority_id, hour AT TIME ZONE 'UTC', $4, COUNT(*), 0, $5, $6, 0, now(), $7
+			FROM
+				keys
+			GROUP BY health_authority_id, hour
+			ON CONFLICT (health_authority_id, hour) DO
+				UPDATE
+				SET teks = EXCLUDED.teks, recomputed_at = EXCLUDED.recomputed_at, recompute_reason = EXCLUDED.recompute_reason
+			`, from.UTC(), to.UTC(), healthAuthorityID,
+			empty.PublishCount, empty.OldestTekDays, empty.OnsetAgeDays, reason)
+		if err != nil {
+			return fmt.Errorf("recomputing stats: %w", err)
+		}
+		count = result.RowsAffected()
 		return nil
 	})
 	if err != nil {
@@ -62,7 +184,8 @@ func (db *PublishDB) ReadStats(ctx context.Context, healthAuthorityID int64) ([]
 	err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 		SELECT
-			health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset
+			health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset,
+			keys_per_upload, confirmed_onset_age_days, likely_onset_age_days
 		FROM
 			HealthAuthorityStats
 		WHERE
@@ -93,7 +216,8 @@ func (db *PublishDB) ReadStats(ctx context.Context, healthAuthorityID int64) ([]
 func scanOneHealthAuthorityStats(rows pgx.Row, stats *model.HealthAuthorityStats) error {
 	return rows.Scan(
 		&stats.HealthAuthorityID, &stats.Hour, &stats.PublishCount, &stats.TEKCount,
-		&stats.RevisionCount, &stats.OldestTekDays, &stats.OnsetAgeDays, &stats.MissingOnset)
+		&stats.RevisionCount, &stats.OldestTekDays, &stats.OnsetAgeDays, &stats.MissingOnset,
+		&stats.KeysPerUpload, &stats.ConfirmedOnsetAgeDays, &stats.LikelyOnsetAgeDays)
 }
 
 // UpdateStats performance a read-modify-write to update the requested stats.
@@ -109,50 +233,54 @@ func (db *PublishDB) UpdateStatsInTx(ctx context.Context, tx pgx.Tx, hour time.T
 		return nil
 	}
 
-	hour = hour.UTC().Truncate(time.Hour)
-
-	rows, err := tx.Query(ctx, `
-		SELECT
-			health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset
-		FROM
-			HealthAuthorityStats
-		WHERE
-			health_authority_id=$1 AND hour=$2
-		FOR UPDATE
-		`, healthAuthorityID, hour)
-	if err != nil {
-		return fmt.Errorf("read stats: %w", err)
-	}
-
-	stats := model.InitHour(healthAuthorityID, hour)
-	if rows.Next() {
-		// This hour already exists, so load it.
-		if err := scanOneHealthAuthorityStats(rows, stats); err != nil {
-			rows.Close()
-			return fmt.Errorf("failed to scan stats: %w", err)
-		}
+	if _, err := tx.Exec(ctx, addStatsSQL, addStatsArgs(hour, healthAuthorityID, info)...); err != nil {
+		return fmt.Errorf("update stats: %w", err)
 	}
-	// Thw rows.close here isn't deferred because it needs to be closed before the exec below.
-	rows.Close()
+	return nil
+}
 
-	stats.AddPublish(info)
+// sumStatsArray returns the element by element sum of the stored and the
+// excluded distribution in col. Distributions of different lengths are summed
+// as if the shorter one was padded with zeros, and two missing distributions
+// stay missing.
+func sumStatsArray(col string) string {
+	return fmt.Sprintf(`(
+				SELECT array_agg(COALESCE(a, 0) + COALESCE(b, 0) ORDER BY i)
+				FROM unnest(HealthAuthorityStats.%[1]s, EXCLUDED.%[1]s) WITH ORDINALITY AS t(a, b, i)
+			)`, col)
+}
 
-	// Insert/Update the stats for this hour.
-	_, err = tx.Exec(ctx, `
+// addStatsSQL adds the stats of one publish request to the hour. The stats
+// are added in the database instead of read and written back, so the update
+// is a single statement that can be queued with the exposure writes.
+var addStatsSQL = `
 		INSERT INTO
 			HealthAuthorityStats
-			(health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset)
+			(health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset,
+			 keys_per_upload, confirmed_onset_age_days, likely_onset_age_days)
 		VALUES
-			($1, $2, $3, $4, $5, $6, $7, $8)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		ON CONFLICT (health_authority_id, hour) DO
 			UPDATE
-			SET publish=$3, teks=$4, revisions=$5, oldest_tek_days=$6, onset_age_days=$7, missing_onset=$8
-		`,
+			SET publish = ` + sumStatsArray("publish") + `,
+			    teks = HealthAuthorityStats.teks + EXCLUDED.teks,
+			    revisions = HealthAuthorityStats.revisions + EXCLUDED.revisions,
+			    oldest_tek_days = ` + sumStatsArray("oldest_tek_days") + `,
+			    onset_age_days = ` + sumStatsArray("onset_age_days") + `,
+			    missing_onset = COALESCE(HealthAuthorityStats.missing_onset, 0) + EXCLUDED.missing_onset,
+			    keys_per_upload = ` + sumStatsArray("keys_per_upload") + `,
+			    confirmed_onset_age_days = ` + sumStatsArray("confirmed_onset_age_days") + `,
+			    likely_onset_age_days = ` + sumStatsArray("likely_onset_age_days") + `
+		`
+
+// addStatsArgs returns the arguments of addStatsSQL for one publish request.
+func addStatsArgs(hour time.Time, healthAuthorityID int64, info *model.PublishInfo) []interface{} {
+	stats := model.InitHour(healthAuthorityID, hour)
+	stats.AddPublish(info)
+
+	return []interface{}{
 		stats.HealthAuthorityID, stats.Hour, stats.PublishCount, stats.TEKCount, stats.RevisionCount,
-		stats.OldestTekDays, stats.OnsetAgeDays, stats.MissingOnset)
-	if err != nil {
-		return fmt.Errorf("update stats: %w", err)
+		stats.OldestTekDays, stats.OnsetAgeDays, stats.MissingOnset,
+		stats.KeysPerUpload, stats.ConfirmedOnsetAgeDays, stats.LikelyOnsetAgeDays,
 	}
-
-	return nil
 }
@@ -15,6 +15,8 @@
 package database
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -22,6 +24,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
 	hamodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestDeleteStatsBefore(t *testing.T) {
@@ -88,6 +91,77 @@ func TestUpdateStats(t *testing.T) {
 	if err := testPublishDB.UpdateStats(ctx, hour, healthAuthority.ID, info); err != nil {
 		t.Fatalf("updating stats: %v", err)
 	}
+
+	// The stats of both requests are added in the database.
+	want := model.InitHour(healthAuthority.ID, hour)
+	want.AddPublish(info)
+	want.AddPublish(info)
+
+	stats, err := testPublishDB.ReadStats(ctx, healthAuthority.ID)
+	if err != nil {
+		t.Fatalf("unexpected error reading stats: %v", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 hour of stats, got: %v", len(stats))
+	}
+	if diff := cmp.Diff(want, stats[0]); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSpendStatsPrivacyBudget(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	testPublishDB := New(testDB)
+
+	testHADB := hadb.New(testDB)
+	healthAuthority := hamodel.HealthAuthority{
+		Issuer:   "a",
+		Audience: "b",
+		Name:     "c",
+	}
+	if err := testHADB.AddHealthAuthority(ctx, &healthAuthority); err != nil {
+		t.Fatalf("unable to cerate health authority: %v", err)
+	}
+
+	period := time.Now().UTC().Truncate(24 * time.Hour)
+
+	for i, want := range []float64{1, 2, 3} {
+		spent, err := testPublishDB.SpendStatsPrivacyBudget(ctx, healthAuthority.ID, period, 1, 3)
+		if err != nil {
+			t.Fatalf("spend %d: %v", i, err)
+		}
+		if spent != want {
+			t.Errorf("spend %d: expected %v spent, got %v", i, want, spent)
+		}
+	}
+
+	if _, err := testPublishDB.SpendStatsPrivacyBudget(ctx, healthAuthority.ID, period, 1, 3); !errors.Is(err, ErrPrivacyBudgetExhausted) {
+		t.Errorf("expected %v, got %v", ErrPrivacyBudgetExhausted, err)
+	}
+
+	// The next period has a new budget.
+	spent, err := testPublishDB.SpendStatsPrivacyBudget(ctx, healthAuthority.ID, period.Add(24*time.Hour), 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent != 1 {
+		t.Errorf("expected 1 spent in the next period, got %v", spent)
+	}
+
+	// Budgets are deleted with the stats.
+	if _, err := testPublishDB.DeleteStatsBefore(ctx, period.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	spent, err = testPublishDB.SpendStatsPrivacyBudget(ctx, healthAuthority.ID, period, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent != 1 {
+		t.Errorf("expected 1 spent after cleanup, got %v", spent)
+	}
 }
 
 func TestReadStats(t *testing.T) {
@@ -134,3 +208,84 @@ func TestReadStats(t *testing.T) {
 		t.Fatalf("added 11 hours of stats, got: %v", len(stats))
 	}
 }
+
+func TestRecomputeStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	testPublishDB := New(testDB)
+
+	testHADB := hadb.New(testDB)
+	healthAuthority := hamodel.HealthAuthority{
+		Issuer:   "a",
+		Audience: "b",
+		Name:     "c",
+	}
+	if err := testHADB.AddHealthAuthority(ctx, &healthAuthority); err != nil {
+		t.Fatalf("unable to cerate health authority: %v", err)
+	}
+
+	hour := time.Now().UTC().Add(-5 * time.Hour).Truncate(time.Hour)
+
+	// The recorded stats undercount the keys of the first hour.
+	info := &model.PublishInfo{
+		Platform:     model.PlatformIOS,
+		NumTEKs:      1,
+		OldestDays:   2,
+		OnsetDaysAgo: 1,
+	}
+	if err := testPublishDB.UpdateStats(ctx, hour, healthAuthority.ID, info); err != nil {
+		t.Fatalf("updating stats: %v", err)
+	}
+
+	var exposures []*model.Exposure
+	for i := 0; i < 3; i++ {
+		exposures = append(exposures, &model.Exposure{
+			ExposureKey:       []byte(fmt.Sprintf("key%d", i)),
+			Regions:           []string{"US"},
+			IntervalNumber:    int32(100 + i),
+			IntervalCount:     144,
+			CreatedAt:         hour.Add(time.Duration(i) * time.Hour / 2),
+			LocalProvenance:   true,
+			HealthAuthorityID: &healthAuthority.ID,
+		})
+	}
+	if _, err := testPublishDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: exposures,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testPublishDB.RecomputeStats(ctx, hour, hour, 0, "test"); err == nil {
+		t.Errorf("expected error for empty range")
+	}
+	if _, err := testPublishDB.RecomputeStats(ctx, hour, hour.Add(time.Hour), 0, ""); err == nil {
+		t.Errorf("expected error for missing reason")
+	}
+
+	count, err := testPublishDB.RecomputeStats(ctx, hour, hour.Add(24*time.Hour), healthAuthority.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 hours recomputed, got %d", count)
+	}
+
+	stats, err := testPublishDB.ReadStats(ctx, healthAuthority.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 hours, got %d", len(stats))
+	}
+	if got := stats[0].TEKCount; got != 2 {
+		t.Errorf("expected 2 TEKs in the first hour, got %d", got)
+	}
+	if got := stats[0].PublishCount[2]; got != 1 { // iOS
+		t.Errorf("expected the recorded publish to be kept, got %d", got)
+	}
+	if got := stats[1].TEKCount; got != 1 {
+		t.Errorf("expected 1 TEK in the second hour, got %d", got)
+	}
+}
@@ -47,6 +47,21 @@ var (
 	mPaddingFailed = stats.Int64(publishMetricsPrefix+"padding_failed",
 		"Instances of response padding failures", stats.UnitDimensionless)
 
+	mQuarantinedUploads = stats.Int64(publishMetricsPrefix+"quarantined_uploads",
+		"uploads with keys held out of exports for review", stats.UnitDimensionless)
+
+	mTestDataUploads = stats.Int64(publishMetricsPrefix+"test_data_uploads",
+		"uploads stored as test data", stats.UnitDimensionless)
+
+	mQueuedUploads = stats.Int64(publishMetricsPrefix+"queued_uploads",
+		"uploads sent to the publish queue", stats.UnitDimensionless)
+
+	mRevisionTokenVersion = stats.Int64(publishMetricsPrefix+"revision_token_version",
+		"version of the revision tokens presented", stats.UnitDimensionless)
+
+	mStatsPrivacyBudgetExhausted = stats.Int64(publishMetricsPrefix+"stats_privacy_budget_exhausted",
+		"stats requests rejected because the privacy budget was spent", stats.UnitDimensionless)
+
 	exposureTypeTag = tag.MustNewKey("type")
 
 	requestTagKeys = []tag.Key{
@@ -134,6 +149,36 @@ func init() {
 			Measure:     mPaddingFailed,
 			Aggregation: view.Sum(),
 		},
+		{
+			Name:        metrics.MetricRoot + "quarantined_uploads",
+			Description: "Total count of uploads with keys held out of exports for review",
+			Measure:     mQuarantinedUploads,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metrics.MetricRoot + "test_data_uploads",
+			Description: "Total count of uploads stored as test data",
+			Measure:     mTestDataUploads,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metrics.MetricRoot + "queued_uploads",
+			Description: "Total count of uploads sent to the publish queue",
+			Measure:     mQueuedUploads,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metrics.MetricRoot + "revision_token_version",
+			Description: "Distribution of the versions of the revision tokens presented",
+			Measure:     mRevisionTokenVersion,
+			Aggregation: view.Distribution(1, 2, 3),
+		},
+		{
+			Name:        metrics.MetricRoot + "stats_privacy_budget_exhausted",
+			Description: "Total count of stats requests rejected because the privacy budget was spent",
+			Measure:     mStatsPrivacyBudgetExhausted,
+			Aggregation: view.Count(),
+		},
 		{
 			Name:        metrics.MetricRoot + "no_public_key",
 			Description: "Publish request with no public key",
@@ -45,6 +45,11 @@ var (
 	ErrorKeyAlreadyRevised = fmt.Errorf("key has already been revised and cannot be revised again")
 )
 
+// GeneratedAppPackageName is the app package name of the keys that the export
+// worker generates to pad or add noise to an export. They are only ever
+// served in the exports of this server, never through federation.
+const GeneratedAppPackageName = "export-generated"
+
 var _ error = (*ErrorKeyInvalidReportTypeTransition)(nil)
 
 // ErrorKeyInvalidReportTypeTransition is an error returned when the TEK tried
@@ -77,12 +82,39 @@ type Exposure struct {
 	ImportFileID        *int64
 	RevisedImportFileID *int64
 
+	// ProvenanceSource is how the key came to this server, and
+	// OriginalRegions the regions it was first stored with. Both are empty for
+	// keys stored before they were recorded; see Provenance.
+	ProvenanceSource ProvenanceSource
+	OriginalRegions  []string
+
 	// These fields are nullable to maintain backwards compatibility with
 	// older versions that predate their existence.
 	HealthAuthorityID     *int64
 	ReportType            string
 	DaysSinceSymptomOnset *int32
 
+	// Jurisdiction is the data residency jurisdiction of the health authority
+	// that originally verified this key, if any. It is not changed by
+	// revisions.
+	Jurisdiction *string
+
+	// FederationConsent records whether the user consented to this key being
+	// shared outside of the home region. Nil means the client did not say.
+	FederationConsent *bool
+
+	// TestData marks keys that were verified by a health authority, or a
+	// health authority key, that is still being onboarded. Test data is only
+	// published by test export configs, never by production exports or
+	// federation.
+	TestData bool
+
+	// KeyMetadata holds the per-key fields of later export format revisions,
+	// such as the variant of concern, as protocol buffer wire data of the
+	// export TemporaryExposureKey. It is only captured from imported export
+	// files, and only written to export files, when enabled. Nil means none.
+	KeyMetadata []byte
+
 	// Fields to support key revision.
 	RevisedReportType            *string
 	RevisedAt                    *time.Time
@@ -91,6 +123,13 @@ type Exposure struct {
 
 	// b64 key
 	base64Key string
+
+	// symptomOnsetInterval is the symptom onset interval provided on publish,
+	// if any. It is used to recalculate days since symptom onset when this
+	// upload revises an existing key. symptomOnsetDefaulted is true if the
+	// days since symptom onset was estimated because no onset was provided.
+	symptomOnsetInterval  int32
+	symptomOnsetDefaulted bool
 }
 
 // ExportImportConfig represents the configuration for
@@ -211,8 +250,38 @@ func ValidReportTypeTransition(from, to string) bool {
 	return false
 }
 
-// Revise updates the Revised fields of a key.
+// ReportTypeTransitions holds a health authority's overrides of the default
+// report type transition rules, keyed by the from and then the to report type.
+// Transitions without an override fall back to ValidReportTypeTransition. A nil
+// value applies the defaults.
+type ReportTypeTransitions map[string]map[string]bool
+
+// Set overrides whether a transition between the two report types is allowed.
+func (t ReportTypeTransitions) Set(from, to string, allowed bool) {
+	if t[from] == nil {
+		t[from] = make(map[string]bool)
+	}
+	t[from][to] = allowed
+}
+
+// Allowed returns true if a TEK is allowed to transition from the `from` type
+// to the `to` type.
+func (t ReportTypeTransitions) Allowed(from, to string) bool {
+	if allowed, ok := t[from][to]; ok {
+		return allowed
+	}
+	return ValidReportTypeTransition(from, to)
+}
+
+// Revise updates the Revised fields of a key using the default report type
+// transition rules.
 func (e *Exposure) Revise(in *Exposure) (bool, error) {
+	return e.ReviseWithTransitions(in, nil)
+}
+
+// ReviseWithTransitions updates the Revised fields of a key. The provided
+// transitions determine which report type changes are permitted.
+func (e *Exposure) ReviseWithTransitions(in *Exposure, transitions ReportTypeTransitions) (bool, error) {
 	if e.ExposureKeyBase64() != in.ExposureKeyBase64() {
 		return false, ErrorExposureKeyMismatch
 	}
@@ -242,8 +311,13 @@ func (e *Exposure) Revise(in *Exposure) (bool, error) {
 			return false, ErrorNonLocalProvenance
 		}
 	}
+	// A revocation of a federated or imported key was already authorized by
+	// its source, so it is accepted even if the key has been revised before
+	// or the transition would not be allowed for a local key.
+	revocation := !e.LocalProvenance && in.ReportType == verifyapi.ReportTypeNegative
+
 	// make sure key hasn't been revised already.
-	if e.RevisedAt != nil {
+	if e.RevisedAt != nil && !revocation {
 		return false, ErrorKeyAlreadyRevised
 	}
 
@@ -252,7 +326,7 @@ func (e *Exposure) Revise(in *Exposure) (bool, error) {
 	if eReportType == "" {
 		eReportType = verifyapi.ReportTypeClinical
 	}
-	if !ValidReportTypeTransition(eReportType, in.ReportType) {
+	if !revocation && !transitions.Allowed(eReportType, in.ReportType) {
 		return false, &ErrorKeyInvalidReportTypeTransition{
 			from: e.ReportType,
 			to:   in.ReportType,
@@ -267,16 +341,42 @@ func (e *Exposure) Revise(in *Exposure) (bool, error) {
 	e.AddMissingRegions(in.Regions)
 	e.RevisedReportType = &in.ReportType
 	e.RevisedAt = &in.CreatedAt
-	e.RevisedDaysSinceSymptomOnset = in.DaysSinceSymptomOnset
+	e.RevisedDaysSinceSymptomOnset = e.revisedDaysSinceSymptomOnset(in)
 	tr := ReportTypeTransmissionRisk(in.ReportType, in.TransmissionRisk)
 	e.RevisedTransmissionRisk = &tr
 
 	e.HealthAuthorityID = in.HealthAuthorityID
 	e.RevisedImportFileID = in.ImportFileID
+	// A revision may change the sharing consent, but only if one was given.
+	if in.FederationConsent != nil {
+		e.FederationConsent = in.FederationConsent
+	}
 
 	return true, nil
 }
 
+// revisedDaysSinceSymptomOnset calculates days since symptom onset for a
+// revision of this key by the incoming key. If the revision provided a symptom
+// onset, days since onset is recalculated relative to this key's interval.
+// Otherwise, the incoming value is used unless it was only an estimate, in
+// which case the original value is kept.
+func (e *Exposure) revisedDaysSinceSymptomOnset(in *Exposure) *int32 {
+	var d int32
+	switch {
+	case in.symptomOnsetInterval > 0:
+		d = DaysBetweenIntervals(in.symptomOnsetInterval, e.IntervalNumber)
+	case in.DaysSinceSymptomOnset != nil && !in.symptomOnsetDefaulted:
+		d = *in.DaysSinceSymptomOnset
+	case e.DaysSinceSymptomOnset != nil:
+		d = *e.DaysSinceSymptomOnset
+	case in.DaysSinceSymptomOnset != nil:
+		d = *in.DaysSinceSymptomOnset
+	default:
+		return nil
+	}
+	return &d
+}
+
 // AddMissingRegions will merge the input regions into the regions already on the exposure.
 // Set union operation.
 func (e *Exposure) AddMissingRegions(regions []string) {
@@ -314,6 +414,14 @@ func (e *Exposure) SetHealthAuthorityID(haID int64) {
 	e.HealthAuthorityID = &haID
 }
 
+// SetJurisdiction assigns a jurisdiction. Blank jurisdictions are ignored.
+func (e *Exposure) SetJurisdiction(jurisdiction string) {
+	if jurisdiction == "" {
+		return
+	}
+	e.Jurisdiction = &jurisdiction
+}
+
 // HasBeenRevised returns true if this key has been revised. This is indicated
 // by the RevisedAt time not being nil.
 func (e *Exposure) HasBeenRevised() bool {
@@ -374,11 +482,12 @@ func (e *Exposure) AdjustAndValidate(settings *KeyTransform) error {
 
 	// If the key is valid beyond the current interval number. Adjust the createdAt time for the key.
 	if e.IntervalNumber+e.IntervalCount > settings.MaxStartInterval {
-		// key is still valid. The created At for this key needs to be adjusted unless debugging is enabled.
-		if !settings.ReleaseStillValidKeys {
-			// The add of the batch window is to ensure that the created at time is after the expiry.
-			e.CreatedAt = TimeForIntervalNumber(e.IntervalNumber + e.IntervalCount).Add(settings.BatchWindow).Truncate(settings.BatchWindow)
+		// key is still valid. The created At for this key is adjusted according to the same day key policy.
+		releaseAt, ok := settings.SameDayKeyPolicy.releaseTime(e, settings.SameDayKeyReleaseDelay, settings.BatchWindow)
+		if !ok {
+			return fmt.Errorf("key is still valid until interval %v, same day keys are not accepted", e.IntervalNumber+e.IntervalCount)
 		}
+		e.CreatedAt = releaseAt
 	}
 
 	if tr := e.TransmissionRisk; tr < verifyapi.MinTransmissionRisk || tr > verifyapi.MaxTransmissionRisk {
@@ -426,7 +535,8 @@ type TransformerConfig interface {
 	MaxSymptomOnsetDays() uint
 	MaxValidSymptomOnsetReportDays() uint
 	DefaultSymptomOnsetDaysAgo() uint
-	DebugReleaseSameDayKeys() bool
+	SameDayKeyPolicy() SameDayKeyPolicy
+	SameDayKeyReleaseDelay() time.Duration
 }
 
 // Transformer represents a configured Publish -> Exposure[] transformer.
@@ -438,7 +548,8 @@ type Transformer struct {
 	maxSymptomOnsetDays            float64 // to avoid casting in comparisons
 	maxValidSymptomOnsetReportDays uint
 	defaultSymptomOnsetDaysAgo     uint
-	debugReleaseSameDay            bool // If true, still valid keys are not embargoed.
+	sameDayKeyPolicy               SameDayKeyPolicy // What to do with still valid keys.
+	sameDayKeyReleaseDelay         time.Duration    // Extra embargo for still valid keys.
 }
 
 // NewTransformer creates a transformer for turning publish API requests into
@@ -451,6 +562,13 @@ func NewTransformer(config TransformerConfig) (*Transformer, error) {
 	if config.MaxSameDayKeys() < 1 {
 		return nil, fmt.Errorf("maxSameDayKeys must be >= 1, got %v", config.MaxSameDayKeys())
 	}
+	sameDayKeyPolicy, err := ParseSameDayKeyPolicy(string(config.SameDayKeyPolicy()))
+	if err != nil {
+		return nil, err
+	}
+	if config.SameDayKeyReleaseDelay() < 0 {
+		return nil, fmt.Errorf("sameDayKeyReleaseDelay must be >= 0, got %v", config.SameDayKeyReleaseDelay())
+	}
 	return &Transformer{
 		maxExposureKeys:                int(config.MaxExposureKeys()),
 		maxSameDayKeys:                 int(config.MaxSameDayKeys()),
@@ -459,18 +577,57 @@ func NewTransformer(config TransformerConfig) (*Transformer, error) {
 		maxSymptomOnsetDays:            float64(config.MaxSymptomOnsetDays()),
 		maxValidSymptomOnsetReportDays: config.MaxValidSymptomOnsetReportDays(),
 		defaultSymptomOnsetDaysAgo:     config.DefaultSymptomOnsetDaysAgo(),
-		debugReleaseSameDay:            config.DebugReleaseSameDayKeys(),
+		sameDayKeyPolicy:               sameDayKeyPolicy,
+		sameDayKeyReleaseDelay:         config.SameDayKeyReleaseDelay(),
 	}, nil
 }
 
+// WithSameDayKeyPolicy returns a copy of the transformer that applies the
+// given policy to still valid keys, for apps that override the server's
+// policy. If the policy is empty, the transformer is returned as is.
+func (t *Transformer) WithSameDayKeyPolicy(p SameDayKeyPolicy) (*Transformer, error) {
+	if p == "" {
+		return t, nil
+	}
+	policy, err := ParseSameDayKeyPolicy(string(p))
+	if err != nil {
+		return nil, err
+	}
+
+	cpy := *t
+	cpy.sameDayKeyPolicy = policy
+	return &cpy, nil
+}
+
+// MaxIntervalStartAge returns how old the keys that the transformer accepts
+// can be.
+func (t *Transformer) MaxIntervalStartAge() time.Duration {
+	return t.maxIntervalStartAge
+}
+
+// WithMaxIntervalStartAge returns a copy of the transformer that accepts keys
+// up to the given age, for regions that override the retention of keys.
+func (t *Transformer) WithMaxIntervalStartAge(d time.Duration) *Transformer {
+	if d == t.maxIntervalStartAge {
+		return t
+	}
+	cpy := *t
+	cpy.maxIntervalStartAge = d
+	return &cpy
+}
+
 // KeyTransform represents the settings to apply when transforming an individual key on a publish request.
 type KeyTransform struct {
-	MinStartInterval      int32
-	MaxStartInterval      int32
-	MaxEndInteral         int32
-	CreatedAt             time.Time
-	ReleaseStillValidKeys bool
-	BatchWindow           time.Duration
+	MinStartInterval int32
+	MaxStartInterval int32
+	MaxEndInteral    int32
+	CreatedAt        time.Time
+	BatchWindow      time.Duration
+
+	// SameDayKeyPolicy and SameDayKeyReleaseDelay decide when keys that are
+	// still valid are released, see SameDayKeyPolicy.
+	SameDayKeyPolicy       SameDayKeyPolicy
+	SameDayKeyReleaseDelay time.Duration
 }
 
 // TransformExposureKey converts individual key data to an exposure entity.
@@ -494,6 +651,7 @@ func TransformExposureKey(exposureKey verifyapi.ExposureKey, appPackageName stri
 		IntervalCount:    exposureKey.IntervalCount,
 		CreatedAt:        settings.CreatedAt,
 		LocalProvenance:  true,
+		ProvenanceSource: ProvenancePublish,
 	}
 
 	if err := e.AdjustAndValidate(settings); err != nil {
@@ -504,8 +662,9 @@ func TransformExposureKey(exposureKey verifyapi.ExposureKey, appPackageName stri
 
 // ReviseKeys takes a set of existing keys, and a list of keys currently being uploaded.
 // Only keys that need to be revised or are being created for the first time
-// are returned in the output set.
-func ReviseKeys(ctx context.Context, existing map[string]*Exposure, incoming []*Exposure) ([]*Exposure, error) {
+// are returned in the output set. Report type changes are checked against the
+// provided transitions, which may be nil to apply the defaults.
+func ReviseKeys(ctx context.Context, existing map[string]*Exposure, incoming []*Exposure, transitions ReportTypeTransitions) ([]*Exposure, error) {
 	output := make([]*Exposure, 0, len(incoming))
 
 	// Iterate over incoming keys.
@@ -521,7 +680,7 @@ func ReviseKeys(ctx context.Context, existing map[string]*Exposure, incoming []*
 		}
 
 		// Attempt to revise this key.
-		keyRevised, err := prevExposure.Revise(inExposure)
+		keyRevised, err := prevExposure.ReviseWithTransitions(inExposure, transitions)
 		if err != nil {
 			return nil, err
 		}
@@ -578,7 +737,7 @@ type TransformPublishResult struct {
 func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Publish, regions []string, claims *verification.VerifiedClaims, batchTime time.Time) (*TransformPublishResult, error) {
 	logger := logging.FromContext(ctx).Named("TransformPublish")
 
-	if t.debugReleaseSameDay {
+	if t.sameDayKeyPolicy == SameDayKeyPolicyRelease {
 		logger.Warnw("DEBUG SERVER - CURRENT DAYS KEYS ARE NOT EMBARGOED!")
 	}
 
@@ -610,10 +769,11 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 		// A key must have been issued on the device in the current interval or earlier.
 		MaxStartInterval: IntervalNumber(batchTime),
 		// And the max valid interval is the maxStartInterval + 144
-		MaxEndInteral:         IntervalNumber(batchTime) + verifyapi.MaxIntervalCount,
-		CreatedAt:             defaultCreatedAt,
-		ReleaseStillValidKeys: t.debugReleaseSameDay,
-		BatchWindow:           t.truncateWindow,
+		MaxEndInteral:          IntervalNumber(batchTime) + verifyapi.MaxIntervalCount,
+		CreatedAt:              defaultCreatedAt,
+		BatchWindow:            t.truncateWindow,
+		SameDayKeyPolicy:       t.sameDayKeyPolicy,
+		SameDayKeyReleaseDelay: t.sameDayKeyReleaseDelay,
 	}
 
 	// For validating key timing information, can't be newer than now.
@@ -678,6 +838,15 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 			if claims.HealthAuthorityID > 0 {
 				exposure.SetHealthAuthorityID(claims.HealthAuthorityID)
 			}
+			exposure.SetJurisdiction(claims.Jurisdiction)
+			exposure.TestData = claims.TestData
+			// The health authority may hold its keys out of exports for a while.
+			// Still valid keys may already be embargoed for longer.
+			if claims.ExportDelay > 0 {
+				if eligibleAt := TruncateWindow(batchTime.Add(claims.ExportDelay), t.truncateWindow); eligibleAt.After(exposure.CreatedAt) {
+					exposure.CreatedAt = eligibleAt
+				}
+			}
 		}
 		// Set days since onset, either from the API or from the verified claims (see above).
 		if onsetInterval > 0 {
@@ -694,6 +863,11 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 
 			// The value is within acceptable range, save it.
 			exposure.SetDaysSinceSymptomOnset(daysSince)
+			if stats.MissingOnset {
+				exposure.symptomOnsetDefaulted = true
+			} else {
+				exposure.symptomOnsetInterval = onsetInterval
+			}
 		}
 
 		// Check and see many days old the key is.
@@ -702,6 +876,7 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 		}
 
 		exposure.Traveler = inData.Traveler
+		exposure.FederationConsent = inData.FederationConsent
 		entities = append(entities, exposure)
 	}
 
@@ -50,7 +50,7 @@ type testConfig struct {
 	maxSymptomOnsetDays            uint
 	maxValidSymptomOnsetReportDays uint
 	defaultSymptomOnsetDays        uint
-	debugReleaseSameDay            bool
+	sameDayKeyPolicy               SameDayKeyPolicy
 }
 
 func (c *testConfig) MaxExposureKeys() uint {
@@ -81,8 +81,12 @@ func (c *testConfig) DefaultSymptomOnsetDaysAgo() uint {
 	return c.defaultSymptomOnsetDays
 }
 
-func (c *testConfig) DebugReleaseSameDayKeys() bool {
-	return c.debugReleaseSameDay
+func (c *testConfig) SameDayKeyPolicy() SameDayKeyPolicy {
+	return c.sameDayKeyPolicy
+}
+
+func (c *testConfig) SameDayKeyReleaseDelay() time.Duration {
+	return 0
 }
 
 func TestIntervalNumber(t *testing.T) {
@@ -197,7 +201,7 @@ func TestPublishValidation(t *testing.T) {
 		name    string
 		p       *verifyapi.Publish
 		m       string
-		sameDay bool
+		sameDay SameDayKeyPolicy
 	}{
 		{
 			name: "no keys",
@@ -331,7 +335,7 @@ func TestPublishValidation(t *testing.T) {
 					},
 				},
 			},
-			sameDay: true,
+			sameDay: SameDayKeyPolicyRelease,
 		},
 	}
 
@@ -348,7 +352,7 @@ func TestPublishValidation(t *testing.T) {
 				maxIntervalStartAge: maxAge,
 				truncateWindow:      time.Hour,
 				maxSymptomOnsetDays: maxSymptomOnsetDays,
-				debugReleaseSameDay: c.sameDay,
+				sameDayKeyPolicy:    c.sameDay,
 			})
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -382,10 +386,10 @@ func TestStillValidKey(t *testing.T) {
 	intervalNumber := IntervalNumber(now) - 1
 
 	cases := []struct {
-		name               string
-		source             verifyapi.Publish
-		createdAt          time.Time
-		releaseSameDayKeys bool
+		name      string
+		source    verifyapi.Publish
+		createdAt time.Time
+		policy    SameDayKeyPolicy
 	}{
 		{
 			name: "release same day keys",
@@ -399,8 +403,8 @@ func TestStillValidKey(t *testing.T) {
 					},
 				},
 			},
-			createdAt:          batchWindow,
-			releaseSameDayKeys: true,
+			createdAt: batchWindow,
+			policy:    SameDayKeyPolicyRelease,
 		},
 		{
 			name: "proper embargo",
@@ -414,8 +418,23 @@ func TestStillValidKey(t *testing.T) {
 					},
 				},
 			},
-			createdAt:          TruncateWindow(TimeForIntervalNumber(intervalNumber+verifyapi.MaxIntervalCount).Add(time.Minute), time.Minute),
-			releaseSameDayKeys: false,
+			createdAt: TruncateWindow(TimeForIntervalNumber(intervalNumber+verifyapi.MaxIntervalCount).Add(time.Minute), time.Minute),
+			policy:    SameDayKeyPolicyEmbargo,
+		},
+		{
+			name: "embargo until day end",
+			source: verifyapi.Publish{
+				Keys: []verifyapi.ExposureKey{
+					{
+						Key:              encodeKey(generateKey(t)),
+						IntervalNumber:   IntervalNumber(now),
+						IntervalCount:    36,
+						TransmissionRisk: 1,
+					},
+				},
+			},
+			createdAt: now.AddDate(0, 0, 1).Add(time.Minute),
+			policy:    SameDayKeyPolicyEmbargoDayEnd,
 		},
 	}
 
@@ -432,7 +451,7 @@ func TestStillValidKey(t *testing.T) {
 				maxIntervalStartAge: allowedAge,
 				truncateWindow:      time.Minute,
 				maxSymptomOnsetDays: maxSymptomOnsetDays,
-				debugReleaseSameDay: tc.releaseSameDayKeys,
+				sameDayKeyPolicy:    tc.policy,
 			})
 			if err != nil {
 				t.Fatal(err)
@@ -493,6 +512,7 @@ func int32Ptr(v int32) *int32        { return &v }
 func int64Ptr(v int64) *int64        { return &v }
 func timePtr(t time.Time) *time.Time { return &t }
 func stringPtr(s string) *string     { return &s }
+func boolPtr(b bool) *bool           { return &b }
 
 func TestTransform(t *testing.T) {
 	t.Parallel()
@@ -564,6 +584,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					DaysSinceSymptomOnset: int32Ptr(-3),
 				},
 				{
@@ -575,6 +596,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					DaysSinceSymptomOnset: int32Ptr(-2),
 				},
 				{
@@ -586,6 +608,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					DaysSinceSymptomOnset: int32Ptr(-1),
 				},
 				{
@@ -597,6 +620,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					DaysSinceSymptomOnset: int32Ptr(0),
 				},
 			},
@@ -639,6 +663,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: int32Ptr(-3),
 				},
@@ -651,6 +676,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: int32Ptr(-2),
 				},
@@ -701,6 +727,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: int32Ptr(-1),
 				},
@@ -713,6 +740,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: int32Ptr(0),
 				},
@@ -725,6 +753,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: int32Ptr(1),
 				},
@@ -773,6 +802,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(-2),
 					HealthAuthorityID:     int64Ptr(27),
@@ -786,6 +816,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(-1),
 					HealthAuthorityID:     int64Ptr(27),
@@ -799,6 +830,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(0),
 					HealthAuthorityID:     int64Ptr(27),
@@ -849,6 +881,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(-1),
 					HealthAuthorityID:     int64Ptr(27),
@@ -862,6 +895,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(0),
 					HealthAuthorityID:     int64Ptr(27),
@@ -875,6 +909,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(1),
 					HealthAuthorityID:     int64Ptr(27),
@@ -908,10 +943,12 @@ func TestTransform(t *testing.T) {
 				},
 				HealthAuthorityID:    appPackage,
 				SymptomOnsetInterval: 1,
+				FederationConsent:    boolPtr(false),
 			},
 			Regions: wantRegions,
 			Claims: &verification.VerifiedClaims{
 				HealthAuthorityID: 27,
+				Jurisdiction:      "US",
 				ReportType:        verifyapi.ReportTypeClinical,
 			},
 			Want: []*Exposure{
@@ -924,9 +961,12 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(-1),
 					HealthAuthorityID:     int64Ptr(27),
+					Jurisdiction:          stringPtr("US"),
+					FederationConsent:     boolPtr(false),
 				},
 				{
 					ExposureKey:           testKeys[4],
@@ -937,9 +977,12 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(0),
 					HealthAuthorityID:     int64Ptr(27),
+					Jurisdiction:          stringPtr("US"),
+					FederationConsent:     boolPtr(false),
 				},
 				{
 					ExposureKey:           testKeys[5],
@@ -950,9 +993,12 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(1),
 					HealthAuthorityID:     int64Ptr(27),
+					Jurisdiction:          stringPtr("US"),
+					FederationConsent:     boolPtr(false),
 				},
 			},
 			WantStats: &PublishInfo{
@@ -994,6 +1040,7 @@ func TestTransform(t *testing.T) {
 					Regions:               wantRegions,
 					CreatedAt:             batchTimeRounded,
 					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
 					ReportType:            verifyapi.ReportTypeClinical,
 					DaysSinceSymptomOnset: int32Ptr(14),
 					HealthAuthorityID:     int64Ptr(27),
@@ -1006,6 +1053,89 @@ func TestTransform(t *testing.T) {
 			},
 			Warnings: []string{"key 1 symptom onset is too large, 15 > 14 - saving without this key"},
 		},
+		{
+			Name: "health_authority_export_delay",
+			Publish: &verifyapi.Publish{
+				Keys: []verifyapi.ExposureKey{
+					{
+						Key:            encodeKey(testKeys[6]),
+						IntervalNumber: intervalNumber,
+						IntervalCount:  verifyapi.MaxIntervalCount,
+					},
+				},
+				HealthAuthorityID: appPackage,
+			},
+			Regions: wantRegions,
+			Claims: &verification.VerifiedClaims{
+				HealthAuthorityID:    27,
+				ExportDelay:          2 * time.Hour,
+				ReportType:           verifyapi.ReportTypeClinical,
+				SymptomOnsetInterval: uint32(intervalNumber - 14*verifyapi.MaxIntervalCount),
+			},
+			Want: []*Exposure{
+				{
+					ExposureKey:           testKeys[6],
+					IntervalNumber:        intervalNumber,
+					IntervalCount:         verifyapi.MaxIntervalCount,
+					TransmissionRisk:      verifyapi.TransmissionRiskClinical,
+					AppPackageName:        appPackage,
+					Regions:               wantRegions,
+					CreatedAt:             batchTimeRounded.Add(2 * time.Hour),
+					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
+					ReportType:            verifyapi.ReportTypeClinical,
+					DaysSinceSymptomOnset: int32Ptr(14),
+					HealthAuthorityID:     int64Ptr(27),
+				},
+			},
+			WantStats: &PublishInfo{
+				CreatedAt:    batchTimeRounded,
+				OldestDays:   7,
+				OnsetDaysAgo: 21,
+			},
+		},
+		{
+			Name: "health_authority_test_data",
+			Publish: &verifyapi.Publish{
+				Keys: []verifyapi.ExposureKey{
+					{
+						Key:            encodeKey(testKeys[7]),
+						IntervalNumber: intervalNumber,
+						IntervalCount:  verifyapi.MaxIntervalCount,
+					},
+				},
+				HealthAuthorityID: appPackage,
+			},
+			Regions: wantRegions,
+			Claims: &verification.VerifiedClaims{
+				HealthAuthorityID:    27,
+				TestData:             true,
+				ReportType:           verifyapi.ReportTypeClinical,
+				SymptomOnsetInterval: uint32(intervalNumber - 14*verifyapi.MaxIntervalCount),
+			},
+			Want: []*Exposure{
+				{
+					ExposureKey:           testKeys[7],
+					IntervalNumber:        intervalNumber,
+					IntervalCount:         verifyapi.MaxIntervalCount,
+					TransmissionRisk:      verifyapi.TransmissionRiskClinical,
+					AppPackageName:        appPackage,
+					Regions:               wantRegions,
+					CreatedAt:             batchTimeRounded,
+					LocalProvenance:       true,
+					ProvenanceSource:      ProvenancePublish,
+					ReportType:            verifyapi.ReportTypeClinical,
+					DaysSinceSymptomOnset: int32Ptr(14),
+					HealthAuthorityID:     int64Ptr(27),
+					TestData:              true,
+				},
+			},
+			WantStats: &PublishInfo{
+				CreatedAt:    batchTimeRounded,
+				OldestDays:   7,
+				OnsetDaysAgo: 21,
+			},
+		},
 	}
 
 	allowedAge := 14 * 24 * time.Hour
@@ -1119,7 +1249,6 @@ func TestDefaultSymptomOnset(t *testing.T) {
 				maxIntervalStartAge:     allowedAge,
 				truncateWindow:          time.Minute,
 				maxSymptomOnsetDays:     maxSymptomOnsetDays,
-				debugReleaseSameDay:     false,
 				defaultSymptomOnsetDays: onsetDaysAgo,
 			})
 			if err != nil {
@@ -1513,6 +1642,51 @@ func TestReviseKeys_FromFederation(t *testing.T) {
 				FederationQueryID: "foo",
 			},
 		},
+		{
+			name: "federation_revoke_confirmed",
+			existing: &Exposure{
+				ExposureKey:       []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+				LocalProvenance:   false,
+				ReportType:        "confirmed",
+				FederationQueryID: "foo",
+			},
+			incoming: &Exposure{
+				ExposureKey:       []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+				ReportType:        "negative",
+				FederationQueryID: "foo",
+			},
+		},
+		{
+			name: "export_import_revoke_revised",
+			existing: &Exposure{
+				ExposureKey:       []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+				LocalProvenance:   false,
+				ReportType:        "likely",
+				RevisedReportType: proto.String("confirmed"),
+				RevisedAt:         &time.Time{},
+				ExportImportID:    proto.Int64(2),
+			},
+			incoming: &Exposure{
+				ExposureKey:    []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+				ReportType:     "negative",
+				ExportImportID: proto.Int64(2),
+			},
+		},
+		{
+			name: "federation_revoke_mismatch",
+			existing: &Exposure{
+				ExposureKey:       []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+				LocalProvenance:   false,
+				ReportType:        "confirmed",
+				FederationQueryID: "foo",
+			},
+			incoming: &Exposure{
+				ExposureKey:       []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+				ReportType:        "negative",
+				FederationQueryID: "bar",
+			},
+			err: ErrorNotSameFederationSource,
+		},
 	}
 
 	for _, tc := range cases {
@@ -1528,7 +1702,7 @@ func TestReviseKeys_FromFederation(t *testing.T) {
 			incoming := make([]*Exposure, 1)
 			incoming[0] = tc.incoming
 
-			_, err := ReviseKeys(ctx, existing, incoming)
+			_, err := ReviseKeys(ctx, existing, incoming, nil)
 			if err == nil && tc.err != nil {
 				t.Errorf("missing expected error: %v", tc.err)
 			} else if err != nil && tc.err == nil {
@@ -1611,7 +1785,7 @@ func TestReviseKeys(t *testing.T) {
 	incoming[1] = allExposures[3]
 	incoming[2] = allExposures[4]
 
-	got, err := ReviseKeys(ctx, existing, incoming)
+	got, err := ReviseKeys(ctx, existing, incoming, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1649,6 +1823,70 @@ func TestReviseKeys(t *testing.T) {
 	}
 }
 
+func TestExposure_RevisedDaysSinceSymptomOnset(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		previous *Exposure
+		incoming *Exposure
+		want     *int32
+	}{
+		{
+			name:     "recalculated_from_onset",
+			previous: &Exposure{IntervalNumber: 144 * 10, DaysSinceSymptomOnset: int32Ptr(4)},
+			incoming: &Exposure{IntervalNumber: 144 * 10, DaysSinceSymptomOnset: int32Ptr(4), symptomOnsetInterval: 144 * 8},
+			want:     int32Ptr(2),
+		},
+		{
+			name:     "incoming_value",
+			previous: &Exposure{DaysSinceSymptomOnset: int32Ptr(4)},
+			incoming: &Exposure{DaysSinceSymptomOnset: int32Ptr(1)},
+			want:     int32Ptr(1),
+		},
+		{
+			name:     "incoming_defaulted_keeps_original",
+			previous: &Exposure{DaysSinceSymptomOnset: int32Ptr(4)},
+			incoming: &Exposure{DaysSinceSymptomOnset: int32Ptr(1), symptomOnsetDefaulted: true},
+			want:     int32Ptr(4),
+		},
+		{
+			name:     "incoming_missing_keeps_original",
+			previous: &Exposure{DaysSinceSymptomOnset: int32Ptr(4)},
+			incoming: &Exposure{},
+			want:     int32Ptr(4),
+		},
+		{
+			name:     "incoming_defaulted_no_original",
+			previous: &Exposure{},
+			incoming: &Exposure{DaysSinceSymptomOnset: int32Ptr(1), symptomOnsetDefaulted: true},
+			want:     int32Ptr(1),
+		},
+		{
+			name:     "none",
+			previous: &Exposure{},
+			incoming: &Exposure{},
+			want:     nil,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.previous.revisedDaysSinceSymptomOnset(tc.incoming)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+			if got != nil && (got == tc.incoming.DaysSinceSymptomOnset || got == tc.previous.DaysSinceSymptomOnset) {
+				t.Errorf("expected revised value to not alias an existing field")
+			}
+		})
+	}
+}
+
 func TestExposureReview(t *testing.T) {
 	t.Parallel()
 
@@ -1787,6 +2025,53 @@ func TestExposureReview(t *testing.T) {
 			needsRevision: true,
 			err:           "",
 		},
+		{
+			name: "revise_key_updates_consent",
+			previous: &Exposure{
+				ReportType:        verifyapi.ReportTypeClinical,
+				LocalProvenance:   true,
+				CreatedAt:         createdAt,
+				FederationConsent: boolPtr(true),
+			},
+			incoming: &Exposure{
+				ReportType:        verifyapi.ReportTypeConfirmed,
+				CreatedAt:         revisedAt,
+				FederationConsent: boolPtr(false),
+			},
+			want: &Exposure{
+				ReportType:              verifyapi.ReportTypeClinical,
+				LocalProvenance:         true,
+				CreatedAt:               createdAt,
+				FederationConsent:       boolPtr(false),
+				RevisedReportType:       stringPtr(verifyapi.ReportTypeConfirmed),
+				RevisedAt:               &revisedAt,
+				RevisedTransmissionRisk: intPtr(verifyapi.TransmissionRiskConfirmedStandard),
+			},
+			needsRevision: true,
+		},
+		{
+			name: "revise_key_keeps_consent",
+			previous: &Exposure{
+				ReportType:        verifyapi.ReportTypeClinical,
+				LocalProvenance:   true,
+				CreatedAt:         createdAt,
+				FederationConsent: boolPtr(false),
+			},
+			incoming: &Exposure{
+				ReportType: verifyapi.ReportTypeConfirmed,
+				CreatedAt:  revisedAt,
+			},
+			want: &Exposure{
+				ReportType:              verifyapi.ReportTypeClinical,
+				LocalProvenance:         true,
+				CreatedAt:               createdAt,
+				FederationConsent:       boolPtr(false),
+				RevisedReportType:       stringPtr(verifyapi.ReportTypeConfirmed),
+				RevisedAt:               &revisedAt,
+				RevisedTransmissionRisk: intPtr(verifyapi.TransmissionRiskConfirmedStandard),
+			},
+			needsRevision: true,
+		},
 		{
 			name: "double_revise",
 			previous: &Exposure{
@@ -2159,3 +2444,57 @@ func TestExposureFromExportFile(t *testing.T) {
 		})
 	}
 }
+
+func TestReportTypeTransitions_Allowed(t *testing.T) {
+	t.Parallel()
+
+	overrides := make(ReportTypeTransitions)
+	overrides.Set(verifyapi.ReportTypeClinical, verifyapi.ReportTypeNegative, false)
+	overrides.Set(verifyapi.ReportTypeConfirmed, verifyapi.ReportTypeNegative, true)
+
+	cases := []struct {
+		name        string
+		transitions ReportTypeTransitions
+		from        string
+		to          string
+		want        bool
+	}{
+		{"default_allowed", nil, verifyapi.ReportTypeClinical, verifyapi.ReportTypeNegative, true},
+		{"default_denied", nil, verifyapi.ReportTypeConfirmed, verifyapi.ReportTypeNegative, false},
+		{"override_denied", overrides, verifyapi.ReportTypeClinical, verifyapi.ReportTypeNegative, false},
+		{"override_allowed", overrides, verifyapi.ReportTypeConfirmed, verifyapi.ReportTypeNegative, true},
+		{"fallback_default", overrides, verifyapi.ReportTypeClinical, verifyapi.ReportTypeConfirmed, true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.transitions.Allowed(tc.from, tc.to); got != tc.want {
+				t.Errorf("Allowed(%q, %q) = %t, want %t", tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("revise", func(t *testing.T) {
+		t.Parallel()
+
+		existing := &Exposure{
+			ExposureKey:     []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			LocalProvenance: true,
+			ReportType:      verifyapi.ReportTypeClinical,
+		}
+		incoming := &Exposure{
+			ExposureKey: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			ReportType:  verifyapi.ReportTypeNegative,
+		}
+
+		_, err := existing.ReviseWithTransitions(incoming, overrides)
+		var transitionErr *ErrorKeyInvalidReportTypeTransition
+		if !errors.As(err, &transitionErr) {
+			t.Fatalf("expected invalid transition error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/pb/export"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxKeyMetadataBytes is the largest key metadata that is kept for a key.
+// Partners only send a few small fields; anything larger is more likely junk
+// than a new format revision.
+const MaxKeyMetadataBytes = 64
+
+// ErrKeyMetadataTooLarge is returned if the key metadata of an export key is
+// larger than MaxKeyMetadataBytes.
+var ErrKeyMetadataTooLarge = errors.New("key metadata too large")
+
+// KeyMetadataFromExportKey returns the per-key fields of the export key that
+// are not otherwise stored: the variant of concern, and any fields of later
+// format revisions that this server does not know about. It returns nil if
+// the key has none.
+func KeyMetadataFromExportKey(key *export.TemporaryExposureKey) ([]byte, error) {
+	meta := &export.TemporaryExposureKey{
+		VariantOfConcern: key.VariantOfConcern,
+	}
+	meta.ProtoReflect().SetUnknown(key.ProtoReflect().GetUnknown())
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key metadata: %w", err)
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+	if len(b) > MaxKeyMetadataBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrKeyMetadataTooLarge, len(b))
+	}
+	return b, nil
+}
+
+// ApplyKeyMetadata sets the per-key fields in the key metadata on the export
+// key. Fields that the key already has are replaced.
+func ApplyKeyMetadata(key *export.TemporaryExposureKey, metadata []byte) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	var meta export.TemporaryExposureKey
+	if err := proto.Unmarshal(metadata, &meta); err != nil {
+		return fmt.Errorf("failed to unmarshal key metadata: %w", err)
+	}
+	key.VariantOfConcern = meta.VariantOfConcern
+	key.ProtoReflect().SetUnknown(meta.ProtoReflect().GetUnknown())
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/pb/export"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// unknownField returns a length delimited field with the number, which the
+// export proto does not define.
+func unknownField(num protowire.Number, val []byte) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, val)
+}
+
+func TestKeyMetadata(t *testing.T) {
+	t.Parallel()
+
+	voc := export.TemporaryExposureKey_VARIANT_TYPE_2.Enum()
+	cases := []struct {
+		name    string
+		key     *export.TemporaryExposureKey
+		unknown []byte
+		wantNil bool
+		err     error
+	}{
+		{
+			name:    "none",
+			key:     &export.TemporaryExposureKey{KeyData: []byte("key"), ReportType: export.TemporaryExposureKey_CONFIRMED_TEST.Enum()},
+			wantNil: true,
+		},
+		{
+			name: "variant of concern",
+			key:  &export.TemporaryExposureKey{KeyData: []byte("key"), VariantOfConcern: voc},
+		},
+		{
+			name:    "later revision field",
+			key:     &export.TemporaryExposureKey{KeyData: []byte("key")},
+			unknown: unknownField(9, []byte("extension")),
+		},
+		{
+			name:    "both",
+			key:     &export.TemporaryExposureKey{KeyData: []byte("key"), VariantOfConcern: voc},
+			unknown: unknownField(10, []byte{1}),
+		},
+		{
+			name:    "too large",
+			key:     &export.TemporaryExposureKey{KeyData: []byte("key")},
+			unknown: unknownField(9, make([]byte, MaxKeyMetadataBytes)),
+			err:     ErrKeyMetadataTooLarge,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Round trip the key through the wire format, as if it was read
+			// from a file written with a later revision of the proto.
+			b, err := proto.Marshal(tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b = append(b, tc.unknown...)
+			var in export.TemporaryExposureKey
+			if err := proto.Unmarshal(b, &in); err != nil {
+				t.Fatal(err)
+			}
+
+			meta, err := KeyMetadataFromExportKey(&in)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if tc.err != nil {
+				return
+			}
+			if (meta == nil) != tc.wantNil {
+				t.Fatalf("expected nil metadata %t, got %x", tc.wantNil, meta)
+			}
+
+			// The metadata must not carry the key data.
+			if bytes.Contains(meta, []byte("key")) {
+				t.Errorf("metadata %x contains the key data", meta)
+			}
+
+			out := &export.TemporaryExposureKey{KeyData: []byte("key")}
+			if err := ApplyKeyMetadata(out, meta); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := out.GetVariantOfConcern(), tc.key.GetVariantOfConcern(); got != want {
+				t.Errorf("expected variant of concern %v, got %v", want, got)
+			}
+			if got := out.ProtoReflect().GetUnknown(); !bytes.Equal(got, tc.unknown) {
+				t.Errorf("expected unknown fields %x, got %x", tc.unknown, got)
+			}
+		})
+	}
+}
+
+func TestApplyKeyMetadata_Invalid(t *testing.T) {
+	t.Parallel()
+
+	if err := ApplyKeyMetadata(&export.TemporaryExposureKey{}, []byte{0xff}); err == nil {
+		t.Errorf("expected error for invalid metadata")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ProvenanceSource is how an exposure came to be stored on this server.
+type ProvenanceSource string
+
+const (
+	// ProvenancePublish keys were uploaded to this server's publish API.
+	ProvenancePublish ProvenanceSource = "publish"
+
+	// ProvenanceFederationIn keys were pulled from another server by a
+	// federation-in query.
+	ProvenanceFederationIn ProvenanceSource = "federation-in"
+
+	// ProvenanceExportImport keys were read from another server's export files
+	// by an export importer.
+	ProvenanceExportImport ProvenanceSource = "export-import"
+
+	// ProvenanceGenerated keys were generated by the export worker to pad or
+	// add noise to an export.
+	ProvenanceGenerated ProvenanceSource = "generated"
+)
+
+// ProvenanceSources are the known provenance sources.
+var ProvenanceSources = []ProvenanceSource{
+	ProvenancePublish,
+	ProvenanceFederationIn,
+	ProvenanceExportImport,
+	ProvenanceGenerated,
+}
+
+// Validate returns an error if the source is not a known source.
+func (s ProvenanceSource) Validate() error {
+	for _, known := range ProvenanceSources {
+		if s == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown provenance source %q", s)
+}
+
+// Provenance describes where an exposure came from.
+type Provenance struct {
+	Source ProvenanceSource
+
+	// PartnerID identifies the server the key came from: the query ID of
+	// federated keys, or the export importer ID of imported keys. It is empty
+	// for local keys.
+	PartnerID string
+
+	// ImportFileID is the import file that imported keys were read from.
+	ImportFileID *int64
+
+	// OriginalRegions are the regions the key was first stored with. Revisions
+	// can add regions to the key, but do not change these. Nil if the key was
+	// stored before original regions were recorded.
+	OriginalRegions []string
+}
+
+// Provenance returns where the exposure came from. Keys stored before the
+// source was recorded have it inferred from the other provenance fields.
+func (e *Exposure) Provenance() *Provenance {
+	p := &Provenance{
+		Source:          e.ProvenanceSource,
+		ImportFileID:    e.ImportFileID,
+		OriginalRegions: e.OriginalRegions,
+	}
+	if p.Source == "" {
+		p.Source = e.inferProvenanceSource()
+	}
+
+	switch p.Source {
+	case ProvenanceFederationIn:
+		p.PartnerID = e.FederationQueryID
+	case ProvenanceExportImport:
+		if e.ExportImportID != nil {
+			p.PartnerID = strconv.FormatInt(*e.ExportImportID, 10)
+		}
+	}
+	return p
+}
+
+// inferProvenanceSource returns the source of an exposure that was stored
+// without one.
+func (e *Exposure) inferProvenanceSource() ProvenanceSource {
+	switch {
+	case e.AppPackageName == GeneratedAppPackageName:
+		return ProvenanceGenerated
+	case e.ExportImportID != nil:
+		return ProvenanceExportImport
+	case !e.LocalProvenance:
+		return ProvenanceFederationIn
+	default:
+		return ProvenancePublish
+	}
+}
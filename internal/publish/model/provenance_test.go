@@ -0,0 +1,94 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExposure_Provenance(t *testing.T) {
+	t.Parallel()
+
+	importID, fileID := int64(7), int64(42)
+
+	cases := []struct {
+		name     string
+		exposure *Exposure
+		want     *Provenance
+	}{
+		{
+			name:     "publish",
+			exposure: &Exposure{LocalProvenance: true, ProvenanceSource: ProvenancePublish, OriginalRegions: []string{"US"}},
+			want:     &Provenance{Source: ProvenancePublish, OriginalRegions: []string{"US"}},
+		},
+		{
+			name:     "federation_in",
+			exposure: &Exposure{ProvenanceSource: ProvenanceFederationIn, FederationQueryID: "neighbor"},
+			want:     &Provenance{Source: ProvenanceFederationIn, PartnerID: "neighbor"},
+		},
+		{
+			name:     "export_import",
+			exposure: &Exposure{ProvenanceSource: ProvenanceExportImport, ExportImportID: &importID, ImportFileID: &fileID},
+			want:     &Provenance{Source: ProvenanceExportImport, PartnerID: "7", ImportFileID: &fileID},
+		},
+		{
+			name:     "legacy_local",
+			exposure: &Exposure{LocalProvenance: true},
+			want:     &Provenance{Source: ProvenancePublish},
+		},
+		{
+			name:     "legacy_generated",
+			exposure: &Exposure{LocalProvenance: true, AppPackageName: GeneratedAppPackageName},
+			want:     &Provenance{Source: ProvenanceGenerated},
+		},
+		{
+			name:     "legacy_federated",
+			exposure: &Exposure{FederationQueryID: "neighbor"},
+			want:     &Provenance{Source: ProvenanceFederationIn, PartnerID: "neighbor"},
+		},
+		{
+			name:     "legacy_imported",
+			exposure: &Exposure{ExportImportID: &importID},
+			want:     &Provenance{Source: ProvenanceExportImport, PartnerID: "7"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.want, tc.exposure.Provenance()); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProvenanceSource_Validate(t *testing.T) {
+	t.Parallel()
+
+	for _, src := range ProvenanceSources {
+		if err := src.Validate(); err != nil {
+			t.Errorf("expected %q to be valid: %v", src, err)
+		}
+	}
+	if err := ProvenanceSource("mystery").Validate(); err == nil {
+		t.Errorf("expected unknown source to be invalid")
+	}
+}
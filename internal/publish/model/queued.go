@@ -0,0 +1,52 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// QueuedExposure is an Exposure as it is carried on the publish queue. Along
+// with the exported fields, it carries the symptom onset provided on publish,
+// which is needed when the upload revises an existing key.
+type QueuedExposure struct {
+	*Exposure
+
+	SymptomOnsetInterval  int32 `json:",omitempty"`
+	SymptomOnsetDefaulted bool  `json:",omitempty"`
+}
+
+// NewQueuedExposures wraps the exposures for the publish queue.
+func NewQueuedExposures(exposures []*Exposure) []*QueuedExposure {
+	queued := make([]*QueuedExposure, 0, len(exposures))
+	for _, e := range exposures {
+		queued = append(queued, &QueuedExposure{
+			Exposure:              e,
+			SymptomOnsetInterval:  e.symptomOnsetInterval,
+			SymptomOnsetDefaulted: e.symptomOnsetDefaulted,
+		})
+	}
+	return queued
+}
+
+// UnwrapQueuedExposures returns the exposures carried on the publish queue.
+func UnwrapQueuedExposures(queued []*QueuedExposure) []*Exposure {
+	exposures := make([]*Exposure, 0, len(queued))
+	for _, q := range queued {
+		if q.Exposure == nil {
+			continue
+		}
+		q.Exposure.symptomOnsetInterval = q.SymptomOnsetInterval
+		q.Exposure.symptomOnsetDefaulted = q.SymptomOnsetDefaulted
+		exposures = append(exposures, q.Exposure)
+	}
+	return exposures
+}
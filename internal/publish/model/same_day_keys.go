@@ -0,0 +1,101 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+)
+
+// SameDayKeyPolicy governs what happens to keys that are uploaded while they
+// are still valid, typically the current day's key with a partial rolling
+// period.
+type SameDayKeyPolicy string
+
+const (
+	// SameDayKeyPolicyEmbargo accepts still valid keys, but holds them until
+	// after they expire. This is the default.
+	SameDayKeyPolicyEmbargo SameDayKeyPolicy = "EMBARGO"
+
+	// SameDayKeyPolicyEmbargoDayEnd accepts still valid keys, but holds them
+	// until they expire and the UTC day they started in is over, whichever is
+	// later.
+	SameDayKeyPolicyEmbargoDayEnd SameDayKeyPolicy = "EMBARGO_DAY_END"
+
+	// SameDayKeyPolicyRelease accepts still valid keys and releases them in the
+	// next export. This is only meant for local development and testing.
+	SameDayKeyPolicyRelease SameDayKeyPolicy = "RELEASE"
+
+	// SameDayKeyPolicyReject drops still valid keys from the upload.
+	SameDayKeyPolicyReject SameDayKeyPolicy = "REJECT"
+)
+
+// SameDayKeyPolicies is the list of valid policies.
+var SameDayKeyPolicies = []SameDayKeyPolicy{
+	SameDayKeyPolicyEmbargo,
+	SameDayKeyPolicyEmbargoDayEnd,
+	SameDayKeyPolicyRelease,
+	SameDayKeyPolicyReject,
+}
+
+// ParseSameDayKeyPolicy parses a policy name, ignoring case. The empty string
+// is parsed as the default policy.
+func ParseSameDayKeyPolicy(s string) (SameDayKeyPolicy, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return SameDayKeyPolicyEmbargo, nil
+	}
+	for _, p := range SameDayKeyPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid same day key policy %q", s)
+}
+
+// Validate returns an error if the policy is not one of the known policies.
+func (p SameDayKeyPolicy) Validate() error {
+	_, err := ParseSameDayKeyPolicy(string(p))
+	return err
+}
+
+// releaseTime returns when a still valid exposure may be released under this
+// policy, or false if the key must be rejected. The time is always at the end
+// of a batch window, so the key lands in the first batch after the embargo.
+func (p SameDayKeyPolicy) releaseTime(e *Exposure, delay, batchWindow time.Duration) (time.Time, bool) {
+	expiry := TimeForIntervalNumber(e.IntervalNumber + e.IntervalCount)
+
+	var releaseAt time.Time
+	switch p {
+	case SameDayKeyPolicyRelease:
+		return e.CreatedAt, true
+	case SameDayKeyPolicyReject:
+		return time.Time{}, false
+	case SameDayKeyPolicyEmbargoDayEnd:
+		releaseAt = expiry
+		if dayEnd := timeutils.UTCMidnight(TimeForIntervalNumber(e.IntervalNumber)).AddDate(0, 0, 1); dayEnd.After(releaseAt) {
+			releaseAt = dayEnd
+		}
+	default:
+		releaseAt = expiry
+	}
+
+	// The add of the batch window is to ensure that the created at time is after
+	// the embargo.
+	return releaseAt.Add(delay).Add(batchWindow).Truncate(batchWindow), true
+}
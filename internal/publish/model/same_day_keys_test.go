@@ -0,0 +1,156 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+func TestParseSameDayKeyPolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in      string
+		want    SameDayKeyPolicy
+		wantErr bool
+	}{
+		{in: "", want: SameDayKeyPolicyEmbargo},
+		{in: "embargo", want: SameDayKeyPolicyEmbargo},
+		{in: " EMBARGO_DAY_END ", want: SameDayKeyPolicyEmbargoDayEnd},
+		{in: "release", want: SameDayKeyPolicyRelease},
+		{in: "REJECT", want: SameDayKeyPolicyReject},
+		{in: "sometimes", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseSameDayKeyPolicy(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: expected error to be %t, got %v", tc.in, tc.wantErr, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: expected %q to be %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSameDayKeyPolicy_ReleaseTime(t *testing.T) {
+	t.Parallel()
+
+	midnight := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	createdAt := midnight.Add(4 * time.Hour)
+	exposure := &Exposure{
+		IntervalNumber: IntervalNumber(midnight),
+		IntervalCount:  36,
+		CreatedAt:      createdAt,
+	}
+
+	cases := []struct {
+		name   string
+		policy SameDayKeyPolicy
+		delay  time.Duration
+		want   time.Time
+		ok     bool
+	}{
+		{"default", "", 0, midnight.Add(7 * time.Hour), true},
+		{"embargo", SameDayKeyPolicyEmbargo, 0, midnight.Add(7 * time.Hour), true},
+		{"embargo_delay", SameDayKeyPolicyEmbargo, 2 * time.Hour, midnight.Add(9 * time.Hour), true},
+		{"day_end", SameDayKeyPolicyEmbargoDayEnd, 0, midnight.Add(25 * time.Hour), true},
+		{"release", SameDayKeyPolicyRelease, 2 * time.Hour, createdAt, true},
+		{"reject", SameDayKeyPolicyReject, 0, time.Time{}, false},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := tc.policy.releaseTime(exposure, tc.delay, time.Hour)
+			if ok != tc.ok {
+				t.Fatalf("expected ok to be %t", tc.ok)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("expected %v to be %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTransformer_WithSameDayKeyPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	now := timeutils.UTCMidnight(time.Now()).Add(time.Hour)
+
+	transformer, err := NewTransformer(&testConfig{
+		maxExposureKeys:     10,
+		maxSameDayKeys:      1,
+		maxIntervalStartAge: 24 * time.Hour,
+		truncateWindow:      time.Minute,
+		maxSymptomOnsetDays: maxSymptomOnsetDays,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := transformer.WithSameDayKeyPolicy("sometimes"); err == nil {
+		t.Errorf("expected error for invalid policy")
+	}
+
+	rejecting, err := transformer.WithSameDayKeyPolicy(SameDayKeyPolicyReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish := &verifyapi.Publish{
+		Keys: []verifyapi.ExposureKey{
+			{
+				Key:              encodeKey(generateKey(t)),
+				IntervalNumber:   IntervalNumber(timeutils.UTCMidnight(now).Add(-24 * time.Hour)),
+				IntervalCount:    verifyapi.MaxIntervalCount,
+				TransmissionRisk: 1,
+			},
+			{
+				Key:              encodeKey(generateKey(t)),
+				IntervalNumber:   IntervalNumber(timeutils.UTCMidnight(now)),
+				IntervalCount:    verifyapi.MaxIntervalCount,
+				TransmissionRisk: 1,
+			},
+		},
+	}
+
+	result, err := rejecting.TransformPublish(ctx, publish, []string{}, nil, now)
+	if err == nil {
+		t.Errorf("expected error for rejected key")
+	}
+	if got, want := len(result.Exposures), 1; got != want {
+		t.Errorf("expected %d exposures to be %d", got, want)
+	}
+
+	// The original transformer still embargoes the key.
+	result, err = transformer.TransformPublish(ctx, publish, []string{}, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(result.Exposures), 2; got != want {
+		t.Errorf("expected %d exposures to be %d", got, want)
+	}
+}
@@ -30,6 +30,9 @@ const (
 	// StatsMaxOnsetDays represents the oldest symptom onset age that will be reflected in stats.
 	// Anything >= will count in the largest bucket.
 	StatsMaxOnsetDays = 29
+	// StatsMaxKeysPerUpload represents the largest number of keys in an upload
+	// that will be reflected in stats. Anything >= will count in the largest bucket.
+	StatsMaxKeysPerUpload = 15
 
 	PlatformAndroid = "android"
 	PlatformIOS     = "ios"
@@ -59,6 +62,12 @@ type HealthAuthorityStats struct {
 	OldestTekDays     []int64
 	OnsetAgeDays      []int64
 	MissingOnset      int64
+
+	// Upload metadata, only captured if enabled. These are nil for hours
+	// without any captured uploads.
+	KeysPerUpload         []int64
+	ConfirmedOnsetAgeDays []int64
+	LikelyOnsetAgeDays    []int64
 }
 
 // ReduceStats takes hourly breakdowns and rolls them up to daily. The onlyBefore
@@ -100,6 +109,10 @@ func ReduceStats(hourly []*HealthAuthorityStats, onlyBefore time.Time, dayThresh
 		for i := 0; i <= StatsMaxOnsetDays && i < len(hour.OnsetAgeDays); i++ {
 			metricsDay.OnsetToUploadDistribution[i] += hour.OnsetAgeDays[i]
 		}
+
+		metricsDay.KeysPerUploadDistribution = addBuckets(metricsDay.KeysPerUploadDistribution, hour.KeysPerUpload, StatsMaxKeysPerUpload+1)
+		metricsDay.ConfirmedOnsetToUploadDistribution = addBuckets(metricsDay.ConfirmedOnsetToUploadDistribution, hour.ConfirmedOnsetAgeDays, StatsMaxOnsetDays+1)
+		metricsDay.LikelyOnsetToUploadDistribution = addBuckets(metricsDay.LikelyOnsetToUploadDistribution, hour.LikelyOnsetAgeDays, StatsMaxOnsetDays+1)
 	}
 
 	// Bring the map back to an array
@@ -121,6 +134,41 @@ func ReduceStats(hourly []*HealthAuthorityStats, onlyBefore time.Time, dayThresh
 	return result
 }
 
+// addBuckets adds the src distribution to dst, allocating dst with the given
+// size if needed. dst is left unchanged if src is empty, so days without
+// captured metadata don't show the distribution.
+func addBuckets(dst, src []int64, size int) []int64 {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make([]int64, size)
+	}
+	for i := 0; i < size && i < len(src); i++ {
+		dst[i] += src[i]
+	}
+	return dst
+}
+
+// incrementBucket counts one in bucket i of a distribution with the given
+// size, allocating the distribution if needed. Anything >= size counts in the
+// last bucket.
+func incrementBucket(buckets []int64, i, size int) []int64 {
+	if i < 0 {
+		return buckets
+	}
+	if len(buckets) < size {
+		resized := make([]int64, size)
+		copy(resized, buckets)
+		buckets = resized
+	}
+	if i >= size {
+		i = size - 1
+	}
+	buckets[i]++
+	return buckets
+}
+
 // InitHour creates a HealthAuthorityStats record for specified hour.
 func InitHour(healthAuthorityID int64, hour time.Time) *HealthAuthorityStats {
 	return &HealthAuthorityStats{
@@ -144,6 +192,16 @@ type PublishInfo struct {
 	OldestDays   int
 	OnsetDaysAgo int
 	MissingOnset bool
+
+	// ContinuedChunk is true if this publish continues a chunked upload. The
+	// chunks are counted as a single publish, so only the TEKs are added.
+	ContinuedChunk bool
+
+	// CaptureMetadata adds the upload to the keys per upload and the per report
+	// type onset to upload distributions. ReportType is the report type of the
+	// verification certificate.
+	CaptureMetadata bool
+	ReportType      string
 }
 
 // AddPublish increments the stats for a given hour. This should be called
@@ -156,9 +214,12 @@ type PublishInfo struct {
 // This method does not enforce that it is called in a transaction, it only
 // applyes the in-memory logic.
 func (has *HealthAuthorityStats) AddPublish(info *PublishInfo) {
-	has.PublishCount[platformToInt(info.Platform)]++
-
 	has.TEKCount += int64(info.NumTEKs)
+	if info.ContinuedChunk {
+		return
+	}
+
+	has.PublishCount[platformToInt(info.Platform)]++
 	if info.Revision {
 		has.RevisionCount++
 		return
@@ -179,4 +240,23 @@ func (has *HealthAuthorityStats) AddPublish(info *PublishInfo) {
 			has.OnsetAgeDays[length-1]++
 		}
 	}
+
+	if info.CaptureMetadata {
+		has.addMetadata(info)
+	}
+}
+
+// addMetadata adds a new upload to the upload metadata distributions. Only the
+// distributions are kept, nothing is recorded per upload.
+func (has *HealthAuthorityStats) addMetadata(info *PublishInfo) {
+	has.KeysPerUpload = incrementBucket(has.KeysPerUpload, int(info.NumTEKs), StatsMaxKeysPerUpload+1)
+	if info.MissingOnset {
+		return
+	}
+	switch info.ReportType {
+	case verifyapi.ReportTypeConfirmed:
+		has.ConfirmedOnsetAgeDays = incrementBucket(has.ConfirmedOnsetAgeDays, info.OnsetDaysAgo, StatsMaxOnsetDays+1)
+	case verifyapi.ReportTypeClinical:
+		has.LikelyOnsetAgeDays = incrementBucket(has.LikelyOnsetAgeDays, info.OnsetDaysAgo, StatsMaxOnsetDays+1)
+	}
 }
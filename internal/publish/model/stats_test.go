@@ -131,6 +131,31 @@ func TestCheckAddPublish(t *testing.T) {
 		}
 		compare(want, record, t)
 	}
+
+	{
+		info := PublishInfo{
+			Platform:       PlatformAndroid,
+			NumTEKs:        6,
+			OldestDays:     13,
+			MissingOnset:   true,
+			ContinuedChunk: true,
+		}
+
+		record.AddPublish(&info)
+
+		// Only the TEKs of a continued chunk are counted.
+		want = &HealthAuthorityStats{
+			HealthAuthorityID: want.HealthAuthorityID,
+			Hour:              want.Hour,
+			PublishCount:      []int64{1, 2, 1},
+			TEKCount:          55,
+			RevisionCount:     1,
+			OldestTekDays:     []int64{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1},
+			OnsetAgeDays:      []int64{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			MissingOnset:      1,
+		}
+		compare(want, record, t)
+	}
 }
 
 func compare(want, got *HealthAuthorityStats, t *testing.T) {
@@ -270,3 +295,110 @@ func TestReduce(t *testing.T) {
 		t.Fatalf("mismatch (-want, +got):\n%s", diff)
 	}
 }
+
+func TestAddPublishMetadata(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		info      PublishInfo
+		keys      []int64
+		confirmed []int64
+		likely    []int64
+	}{
+		{
+			name: "not_captured",
+			info: PublishInfo{NumTEKs: 14, OnsetDaysAgo: 4, ReportType: verifyapi.ReportTypeConfirmed},
+		},
+		{
+			name:      "confirmed",
+			info:      PublishInfo{CaptureMetadata: true, NumTEKs: 14, OnsetDaysAgo: 4, ReportType: verifyapi.ReportTypeConfirmed},
+			keys:      minPadSlice(append(make([]int64, 14), 1), StatsMaxKeysPerUpload+1),
+			confirmed: minPadSlice([]int64{0, 0, 0, 0, 1}, StatsMaxOnsetDays+1),
+		},
+		{
+			name:   "likely_max_buckets",
+			info:   PublishInfo{CaptureMetadata: true, NumTEKs: 30, OnsetDaysAgo: 40, ReportType: verifyapi.ReportTypeClinical},
+			keys:   append(make([]int64, StatsMaxKeysPerUpload), 1),
+			likely: append(make([]int64, StatsMaxOnsetDays), 1),
+		},
+		{
+			name: "self_report",
+			info: PublishInfo{CaptureMetadata: true, NumTEKs: 2, OnsetDaysAgo: 1, ReportType: verifyapi.ReportTypeSelfReport},
+			keys: minPadSlice([]int64{0, 0, 1}, StatsMaxKeysPerUpload+1),
+		},
+		{
+			name: "missing_onset",
+			info: PublishInfo{CaptureMetadata: true, NumTEKs: 3, MissingOnset: true, ReportType: verifyapi.ReportTypeConfirmed},
+			keys: minPadSlice([]int64{0, 0, 0, 1}, StatsMaxKeysPerUpload+1),
+		},
+		{
+			name: "revision",
+			info: PublishInfo{CaptureMetadata: true, NumTEKs: 3, Revision: true, ReportType: verifyapi.ReportTypeConfirmed},
+		},
+		{
+			name: "continued_chunk",
+			info: PublishInfo{CaptureMetadata: true, NumTEKs: 3, ContinuedChunk: true, ReportType: verifyapi.ReportTypeConfirmed},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			record := InitHour(1, time.Now())
+			record.AddPublish(&tc.info)
+
+			if diff := cmp.Diff(tc.keys, record.KeysPerUpload); diff != "" {
+				t.Errorf("keys per upload mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.confirmed, record.ConfirmedOnsetAgeDays); diff != "" {
+				t.Errorf("confirmed onset mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.likely, record.LikelyOnsetAgeDays); diff != "" {
+				t.Errorf("likely onset mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReduceMetadata(t *testing.T) {
+	t.Parallel()
+
+	day := timeutils.UTCMidnight(time.Now().UTC()).Add(-72 * time.Hour)
+
+	input := []*HealthAuthorityStats{
+		InitHour(42, day),
+		InitHour(42, day.Add(time.Hour)),
+		InitHour(42, day.Add(24*time.Hour)),
+	}
+	for _, hour := range input {
+		hour.PublishCount = []int64{10, 0, 0}
+	}
+	// Stored distributions may be shorter than the current bucket count.
+	input[0].KeysPerUpload = []int64{0, 2, 3}
+	input[1].KeysPerUpload = minPadSlice([]int64{0, 1}, StatsMaxKeysPerUpload+1)
+	input[1].ConfirmedOnsetAgeDays = minPadSlice([]int64{1, 1}, StatsMaxOnsetDays+1)
+
+	got := ReduceStats(input, day.Add(48*time.Hour), 10, 0)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 days, got %d", len(got))
+	}
+
+	if diff := cmp.Diff(minPadSlice([]int64{0, 3, 3}, StatsMaxKeysPerUpload+1), got[0].KeysPerUploadDistribution); diff != "" {
+		t.Errorf("keys per upload mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(minPadSlice([]int64{1, 1}, StatsMaxOnsetDays+1), got[0].ConfirmedOnsetToUploadDistribution); diff != "" {
+		t.Errorf("confirmed onset mismatch (-want, +got):\n%s", diff)
+	}
+	if got[0].LikelyOnsetToUploadDistribution != nil {
+		t.Errorf("expected no likely onset distribution, got %v", got[0].LikelyOnsetToUploadDistribution)
+	}
+
+	// A day without any captured metadata has none of the distributions.
+	if d := got[1]; d.KeysPerUploadDistribution != nil || d.ConfirmedOnsetToUploadDistribution != nil || d.LikelyOnsetToUploadDistribution != nil {
+		t.Errorf("expected no metadata distributions, got %#v", d)
+	}
+}
@@ -18,22 +18,41 @@ package publish
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	abusedb "github.com/google/exposure-notifications-server/internal/abuse/database"
+	abusemodel "github.com/google/exposure-notifications-server/internal/abuse/model"
+	"github.com/google/exposure-notifications-server/internal/featureflag"
+	quarantinemodel "github.com/google/exposure-notifications-server/internal/quarantine/model"
+	"golang.org/x/time/rate"
+
 	"go.opencensus.io/stats"
 	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
 
 	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	"github.com/google/exposure-notifications-server/internal/capture"
+	"github.com/google/exposure-notifications-server/internal/clientconfig"
+	"github.com/google/exposure-notifications-server/internal/discovery"
+	"github.com/google/exposure-notifications-server/internal/dp"
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	"github.com/google/exposure-notifications-server/internal/i18n"
+	"github.com/google/exposure-notifications-server/internal/keylog"
 	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/pb"
 	"github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/internal/region"
 	"github.com/google/exposure-notifications-server/internal/revision"
 	revisiondb "github.com/google/exposure-notifications-server/internal/revision/database"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
@@ -53,15 +72,52 @@ const (
 )
 
 type Server struct {
-	config                *Config
 	env                   *serverenv.ServerEnv
-	transformer           *model.Transformer
 	database              *database.PublishDB
+	abuseDB               *abusedb.AbuseDB
 	tokenManager          *revision.TokenManager
 	tracker               *chaff.Tracker
 	tokenAAD              []byte
 	authorizedAppProvider authorizedapp.Provider
 	verifier              *verification.Verifier
+	featureFlags          *featureflag.Evaluator
+
+	// constantWork is done by requests that are rejected before their
+	// certificate is verified, see timing.go.
+	constantWork *constantWork
+
+	// queue receives the keys of accepted uploads instead of the database, or
+	// nil if queue mode is disabled.
+	queue queue.Queue
+
+	// discovery serves the discovery document, or nil if disabled.
+	discovery *discovery.Handler
+
+	// clientConfig serves the signed client configurations, or nil if
+	// disabled.
+	clientConfig *clientconfig.Handler
+
+	// keyLog serves the key transparency log, or nil if disabled.
+	keyLog *keylog.Handler
+
+	// capture records failed publish requests, if enabled.
+	capture *capture.Capturer
+
+	// statsNoise adds differential privacy noise to stats, if enabled.
+	statsNoise *dp.Mechanism
+
+	// catalog translates the client-facing error messages.
+	catalog *i18n.Catalog
+
+	// retention overrides the maximum key age of regions.
+	retention *region.Retention
+
+	// startupConfig is the configuration the server was started with. config
+	// and transformer are replaced when settings change, see applySettings.
+	startupConfig *Config
+	config        atomic.Pointer[Config]
+	transformer   atomic.Pointer[model.Transformer]
+	limiter       *rate.Limiter
 }
 
 func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
@@ -72,7 +128,7 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 		"max_same_start_interval_keys", cfg.MaxSameStartIntervalKeys,
 		"max_interval_age", cfg.MaxIntervalAge,
 		"truncate_window", cfg.TruncateWindow)
-	if cfg.DebugReleaseSameDayKeys() {
+	if cfg.SameDayKeyPolicy() == model.SameDayKeyPolicyRelease {
 		logger.Warnw("SERVER IS IN DEBUG MODE - KEYS MAY BE RELEASED EARLY!")
 	}
 
@@ -83,6 +139,21 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 		return nil, fmt.Errorf("missing AuthorizedApp provider in server environment")
 	}
 
+	if cfg.DefaultRegion != "" {
+		defaultRegion, err := region.New(&cfg.Regions).Normalize(cfg.DefaultRegion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEFAULT_REGION: %w", err)
+		}
+		if defaultRegion != cfg.DefaultRegion {
+			return nil, fmt.Errorf("invalid DEFAULT_REGION: %q must be written as %q", cfg.DefaultRegion, defaultRegion)
+		}
+	}
+
+	retention, err := region.NewRetention(&cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGION_RETENTION: %w", err)
+	}
+
 	transformer, err := model.NewTransformer(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("model.NewTransformer: %w", err)
@@ -92,6 +163,10 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 	if err != nil {
 		return nil, fmt.Errorf("verification.New: %w", err)
 	}
+	work, err := newConstantWork()
+	if err != nil {
+		return nil, fmt.Errorf("newConstantWork: %w", err)
+	}
 
 	aadBytes := cfg.RevisionToken.AAD
 	if len(aadBytes) == 0 {
@@ -105,7 +180,9 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 	if err != nil {
 		return nil, fmt.Errorf("revisiondb.New: %w", err)
 	}
-	tm, err := revision.New(ctx, revisionDB, cfg.RevisionKeyCacheDuration, cfg.RevisionToken.MinLength)
+	tm, err := revision.New(ctx, revisionDB, cfg.RevisionKeyCacheDuration, cfg.RevisionToken.MinLength,
+		revision.WithVersion(cfg.RevisionToken.Version),
+		revision.WithAcceptV1(!cfg.RevisionToken.RejectV1))
 	if err != nil {
 		return nil, fmt.Errorf("revision.New: %w", err)
 	}
@@ -122,29 +199,127 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 		return nil, fmt.Errorf("error making chaffer: %w", err)
 	}
 
-	return &Server{
+	catalog, err := i18n.Load(&cfg.I18n)
+	if err != nil {
+		return nil, fmt.Errorf("i18n.Load: %w", err)
+	}
+
+	var discoveryHandler *discovery.Handler
+	if cfg.Discovery.Enabled {
+		discoveryHandler, err = discovery.New(&cfg.Discovery, env.Database(), env.KeyManager())
+		if err != nil {
+			return nil, fmt.Errorf("discovery.New: %w", err)
+		}
+	}
+
+	var clientConfigHandler *clientconfig.Handler
+	if cfg.ClientConfig.Enabled {
+		clientConfigHandler, err = clientconfig.New(&cfg.ClientConfig, env.Database(), env.KeyManager())
+		if err != nil {
+			return nil, fmt.Errorf("clientconfig.New: %w", err)
+		}
+	}
+
+	var capturer *capture.Capturer
+	if cfg.Capture.Enabled {
+		capturer, err = capture.New(&cfg.Capture, env.Database())
+		if err != nil {
+			return nil, fmt.Errorf("capture.New: %w", err)
+		}
+	}
+
+	var keyLogHandler *keylog.Handler
+	if cfg.KeyLog.Enabled {
+		keyLogHandler, err = keylog.New(&cfg.KeyLog, env.Database(), env.KeyManager())
+		if err != nil {
+			return nil, fmt.Errorf("keylog.New: %w", err)
+		}
+	}
+
+	s := &Server{
 		env:                   env,
-		transformer:           transformer,
-		config:                cfg,
 		database:              database.New(env.Database()),
+		abuseDB:               abusedb.New(env.Database()),
 		tracker:               chaffer,
 		tokenManager:          tm,
 		tokenAAD:              aadBytes,
 		authorizedAppProvider: env.AuthorizedAppProvider(),
 		verifier:              verifier,
-	}, nil
+		constantWork:          work,
+		featureFlags:          env.FeatureFlags(),
+		queue:                 env.Queue(),
+		discovery:             discoveryHandler,
+		clientConfig:          clientConfigHandler,
+		keyLog:                keyLogHandler,
+		capture:               capturer,
+		statsNoise:            dp.NewMechanism(),
+		catalog:               catalog,
+		retention:             retention,
+		startupConfig:         cfg,
+		limiter:               middleware.NewRateLimiter(cfg.Middleware.RateLimit, cfg.Middleware.RateLimitBurst),
+	}
+	s.config.Store(cfg)
+	s.transformer.Store(transformer)
+
+	if w := env.Settings(); w != nil {
+		s.applySettings(ctx)
+		w.OnChange(s.applySettings)
+	}
+
+	return s, nil
 }
 
+// applySettings replaces the configuration with the startup configuration
+// overlaid with the current settings. Requests that are in flight finish with
+// the configuration they started with. Invalid settings are logged and
+// ignored.
+func (s *Server) applySettings(ctx context.Context) {
+	logger := logging.FromContext(ctx).Named("publish.applySettings")
+
+	cfg, err := s.startupConfig.withSettings(ctx, s.env.Settings())
+	if err != nil {
+		logger.Errorw("failed to apply settings, keeping previous configuration", "error", err)
+		return
+	}
+	transformer, err := model.NewTransformer(cfg)
+	if err != nil {
+		logger.Errorw("failed to apply settings, keeping previous configuration", "error", err)
+		return
+	}
+
+	s.config.Store(cfg)
+	s.transformer.Store(transformer)
+	middleware.SetRateLimit(s.limiter, cfg.Middleware.RateLimit, cfg.Middleware.RateLimitBurst)
+	logger.Infow("applied settings",
+		"maintenance_mode", cfg.Maintenance,
+		"max_keys_on_publish", cfg.MaxKeysOnPublish,
+		"max_interval_age", cfg.MaxIntervalAge,
+		"rate_limit", cfg.Middleware.RateLimit)
+}
+
+// MaintenanceMode returns true if the current configuration is in maintenance
+// mode.
+func (s *Server) MaintenanceMode() bool {
+	return s.config.Load().MaintenanceMode()
+}
+
+// chaffPath is the path that always gets a chaff response, for clients that
+// send cover traffic without the chaff header.
+const chaffPath = "/v1/chaff"
+
 func (s *Server) Routes(ctx context.Context) *mux.Router {
 	logger := logging.FromContext(ctx).Named("publish")
 
 	r := mux.NewRouter()
-	r.Use(middleware.Recovery())
-	r.Use(middleware.ProcessChaff(s.tracker))
-	r.Use(middleware.PopulateRequestID())
-	r.Use(middleware.PopulateObservability())
-	r.Use(middleware.PopulateLogger(logger))
-	r.Use(middleware.ProcessMaintenance(s.config))
+	// The rate limit can change with settings, so the shared middleware uses the
+	// server's limiter.
+	mw := s.startupConfig.Middleware.
+		WithRateLimiter(s.limiter).
+		WithChaff(middleware.ProcessChaff(s.tracker, chaffPath))
+	mw.Use(r, logger,
+		middleware.PopulateClientIP(s.startupConfig.TrustedProxyCount),
+		middleware.ProcessMaintenance(s),
+		s.catalog.Middleware())
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 
@@ -153,12 +328,39 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 	r.Handle("/v1/publish", s.handlePublishV1())
 	r.Handle("/v1/publish/", http.NotFoundHandler())
 
+	// Cover traffic for clients. The chaff middleware answers these requests.
+	r.Handle(chaffPath, s.tracker.HandleChaff())
+
 	// Handle stats retrieval API
 	r.Handle("/v1/stats", s.handleStats())
 	r.Handle("/v1/stats/", http.NotFoundHandler())
 
+	// Handle the discovery document and verification keys, if enabled.
+	if s.discovery != nil {
+		r.Handle(discovery.Path, s.discovery).Methods(http.MethodGet)
+		r.Handle(discovery.KeysPath, s.discovery.KeysHandler()).Methods(http.MethodGet)
+
+		// Compatibility endpoints for the OS vendors' server-to-server checks.
+		if s.startupConfig.Discovery.VendorEndpoints {
+			r.Handle(discovery.FilesPath, s.discovery.FilesHandler()).Methods(http.MethodGet)
+			r.Handle(discovery.StatusPath, s.discovery.StatusHandler()).Methods(http.MethodGet)
+		}
+	}
+
+	// Handle the signed client configurations, if enabled.
+	if s.clientConfig != nil {
+		r.Handle(clientconfig.Path, s.clientConfig).Methods(http.MethodGet)
+		r.Handle(clientconfig.PublicKeyPath, s.clientConfig.PublicKeyHandler()).Methods(http.MethodGet)
+	}
+
+	// Handle the key transparency log, if enabled.
+	if s.keyLog != nil {
+		r.Handle(keylog.TreeHeadPath, s.keyLog.TreeHeadHandler()).Methods(http.MethodGet)
+		r.Handle(keylog.EntriesPath, s.keyLog.EntriesHandler()).Methods(http.MethodGet)
+	}
+
 	// Serving of v1alpha1 is on by default, but can be disabled through env var.
-	if s.config.EnableV1Alpha1API {
+	if s.startupConfig.EnableV1Alpha1API {
 		r.Handle("/", s.handlePublishV1Alpha1())
 	}
 
@@ -168,9 +370,40 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 type response struct {
 	status      int
 	pubResponse *verifyapi.PublishResponse
+
+	// retryAfter is sent in the Retry-After header, if set.
+	retryAfter time.Duration
+}
+
+// writeHeaders sets the response headers that are not part of the body.
+func (r *response) writeHeaders(w http.ResponseWriter) {
+	if r.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(r.retryAfter.Seconds()))))
+	}
+}
+
+// errorResponse builds an error response for the given code. The HTTP status
+// is derived from the code.
+func errorResponse(code errcode.Code, message string) *response {
+	return &response{
+		status: code.HTTPStatus(),
+		pubResponse: &verifyapi.PublishResponse{
+			ErrorMessage: message,
+			Code:         string(code),
+		},
+	}
 }
 
 func generatePadding(minPadding, paddingRange int64) (string, error) {
+	n, err := paddingLength(minPadding, paddingRange)
+	if err != nil {
+		return "", err
+	}
+	return randomPadding(n)
+}
+
+// paddingLength returns a random number of padding bytes in the range.
+func paddingLength(minPadding, paddingRange int64) (int, error) {
 	minBytes := minPadding
 	if minBytes <= 0 {
 		minBytes = 1024
@@ -182,10 +415,13 @@ func generatePadding(minPadding, paddingRange int64) (string, error) {
 
 	bi, err := rand.Int(rand.Reader, big.NewInt(padRange))
 	if err != nil {
-		return "", fmt.Errorf("padding: failed to generate random number: %w", err)
+		return 0, fmt.Errorf("padding: failed to generate random number: %w", err)
 	}
-	i := int(bi.Int64() + minBytes)
+	return int(bi.Int64() + minBytes), nil
+}
 
+// randomPadding returns i random bytes, base64 encoded.
+func randomPadding(i int) (string, error) {
 	b := make([]byte, i)
 	n, err := rand.Read(b)
 	if err != nil {
@@ -213,10 +449,19 @@ func newVersionBridge(regions []string) *versionBridge {
 
 // process runs the publish business logic over a "v1" version of the publish request
 // and knows how to join in data from previous versions (the provided versionBridge).
-func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform string, bridge *versionBridge) *response {
+func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform string, bridge *versionBridge, signed *signedRequest) (processed *response) {
 	ctx, span := trace.StartSpan(ctx, "(*publish.PublishHandler).process")
 	defer span.End()
 
+	// Requests that are rejected without a successfully verified certificate
+	// do the work of verifying one anyway.
+	verified := false
+	defer func() {
+		if !verified && processed != nil && isRejected(processed.status) {
+			s.constantWork.do(data)
+		}
+	}()
+
 	blame := obs.BlameNone
 	obsResult := obs.ResultOK
 	defer obs.RecordLatency(ctx, time.Now(), mLatencyMs, &blame, &obsResult)
@@ -227,6 +472,9 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 
 	logger.Info("publish API request")
 
+	// Use the same configuration for the whole request, even if settings change.
+	cfg := s.config.Load()
+
 	appConfig, err := s.authorizedAppProvider.AppConfig(ctx, data.HealthAuthorityID)
 	if err != nil {
 		// Config loaded, but app with that name isn't registered. This can also
@@ -237,13 +485,7 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
 			blame = obs.BlameClient
 			obsResult = obs.ResultError("ERROR_UNAUTHORIZED_HEALTH_AUTHORITY")
-			return &response{
-				status: http.StatusUnauthorized,
-				pubResponse: &verifyapi.PublishResponse{
-					ErrorMessage: message,
-					Code:         verifyapi.ErrorUnknownHealthAuthorityID,
-				},
-			}
+			return errorResponse(errcode.UnknownHealthAuthorityID, message)
 		}
 
 		// A higher-level configuration error occurred, likely while trying to read
@@ -255,15 +497,63 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
 		blame = obs.BlameServer
 		obsResult = obs.ResultError("ERROR_LOADING_HEALTH_AUTHORITY")
-		return &response{
-			status: http.StatusInternalServerError,
-			pubResponse: &verifyapi.PublishResponse{
-				ErrorMessage: message,
-				Code:         verifyapi.ErrorUnableToLoadHealthAuthority,
-			},
+		return errorResponse(errcode.UnableToLoadHealthAuthority, message)
+	}
+
+	if appConfig.Disabled {
+		message := fmt.Sprintf("health authority is disabled: %v", data.HealthAuthorityID)
+		span.SetStatus(trace.Status{Code: trace.StatusCodePermissionDenied, Message: message})
+		blame = obs.BlameClient
+		obsResult = obs.ResultError("ERROR_HEALTH_AUTHORITY_DISABLED")
+		return errorResponse(errcode.UnknownHealthAuthorityID, message)
+	}
+
+	// The abuse detector may pause uploads for an authorized app.
+	if now := time.Now(); appConfig.IsThrottled(now) {
+		message := fmt.Sprintf("uploads for authorized app %v are paused until %v", appConfig.AppPackageName, appConfig.ThrottledUntil.UTC().Format(time.RFC3339))
+		logger.Warnw("rejecting throttled publish request",
+			"app_package_name", appConfig.AppPackageName,
+			"throttled_until", appConfig.ThrottledUntil)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeResourceExhausted, Message: message})
+		blame = obs.BlameClient
+		obsResult = obs.ResultError("TEMPORARILY_THROTTLED")
+		resp := errorResponse(errcode.TemporarilyThrottled, message)
+		resp.retryAfter = appConfig.ThrottledUntil.Sub(now)
+		return resp
+	}
+
+	// Health authorities that upload server-to-server may restrict the
+	// networks requests come from.
+	if len(appConfig.AllowedCIDRs) > 0 {
+		addr, ok := middleware.ClientIPFromContext(ctx)
+		if !ok || !appConfig.IsAllowedAddr(addr) {
+			message := fmt.Sprintf("address %v is not allowed for health authority", addr)
+			span.SetStatus(trace.Status{Code: trace.StatusCodePermissionDenied, Message: message})
+			blame = obs.BlameClient
+			obsResult = obs.ResultError("ADDRESS_NOT_ALLOWED")
+			return errorResponse(errcode.AddressNotAllowed, message)
 		}
 	}
 
+	// Authorized apps whose backends upload server-to-server may require
+	// signed requests.
+	if err := s.verifySignature(ctx, appConfig, signed, time.Now()); err != nil {
+		if errors.Is(err, errMissingSignature) || errors.Is(err, errInvalidSignature) || errors.Is(err, errSignatureExpired) {
+			message := fmt.Sprintf("verifying request signature: %v", err)
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnauthenticated, Message: message})
+			blame = obs.BlameClient
+			obsResult = obs.ResultError("INVALID_REQUEST_SIGNATURE")
+			return errorResponse(errcode.InvalidRequestSignature, message)
+		}
+
+		message := fmt.Sprintf("error verifying request signature: %v", err)
+		logger.Errorw("failed to verify request signature", "error", err)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
+		blame = obs.BlameServer
+		obsResult = obs.ResultError("ERROR_VERIFYING_REQUEST_SIGNATURE")
+		return errorResponse(errcode.InternalError, http.StatusText(http.StatusInternalServerError))
+	}
+
 	// In the v1 API - regions aren't passed. They may be passed from v1alpha1
 	var regions []string
 	if bridge != nil && len(bridge.AdditionalRegions) > 0 {
@@ -272,6 +562,11 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		// v1alpha1 version of the API.
 		for _, r := range regions {
 			if !appConfig.IsAllowedRegion(r) {
+				observation := abusemodel.NewObservation(appConfig.AppPackageName, data, time.Now())
+				observation.RegionMismatch = true
+				observation.Fingerprint = ""
+				s.recordAbuseStats(ctx, observation)
+
 				err := fmt.Errorf("app %v tried to write to unauthorized region %v", appConfig.AppPackageName, r)
 				message := fmt.Sprintf("verifying allowed regions: %v", err)
 				span.SetStatus(trace.Status{Code: trace.StatusCodePermissionDenied, Message: message})
@@ -292,8 +587,8 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		regions = appConfig.AllAllowedRegions()
 	}
 	// And - worse case, still no regions and server default set.
-	if len(regions) == 0 && s.config.DefaultRegion != "" {
-		regions = append(regions, s.config.DefaultRegion)
+	if len(regions) == 0 && s.startupConfig.DefaultRegion != "" {
+		regions = append(regions, s.startupConfig.DefaultRegion)
 	}
 
 	// Verify that there is at least one region set by API call or by one of the
@@ -306,17 +601,74 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		span.SetStatus(trace.Status{Code: trace.StatusCodePermissionDenied, Message: message})
 		blame = obs.BlameClient
 		obsResult = obs.ResultError("ERROR_REGION_NOT_SPECIFIED")
-		return &response{
-			status: http.StatusInternalServerError,
-			pubResponse: &verifyapi.PublishResponse{
-				ErrorMessage: message,
-				Code:         verifyapi.ErrorHealthAuthorityMissingRegionConfiguration,
-			},
+		return errorResponse(errcode.HealthAuthorityMissingRegionConfiguration, message)
+	}
+
+	// The device nonce binds new revision tokens to the device.
+	var deviceNonce []byte
+	if data.DeviceNonce != "" {
+		var err error
+		deviceNonce, err = base64util.DecodeString(data.DeviceNonce)
+		if err == nil && len(deviceNonce) > revision.MaxDeviceNonceLength {
+			err = fmt.Errorf("must be at most %d bytes", revision.MaxDeviceNonceLength)
+		}
+		if err != nil {
+			message := fmt.Sprintf("invalid device nonce: %v", err)
+			logger.Warnw(message)
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
+			blame = obs.BlameClient
+			obsResult = obs.ResultError("INVALID_DEVICE_NONCE")
+			return errorResponse(errcode.BadRequest, message)
+		}
+	}
+
+	// Examine the revision token. It is expected that it is missing in most cases.
+	var token *pb.RevisionTokenData
+	decryptFail := false
+	if len(data.RevisionToken) != 0 {
+		encryptedToken, err := base64util.DecodeString(data.RevisionToken)
+		if err != nil {
+			logger.Warnw("failed to decode revision token, proceeding without", "error", err)
+		} else {
+			parsed, err := s.tokenManager.ParseRevisionToken(ctx, encryptedToken, s.tokenAAD)
+			switch {
+			case err != nil:
+				logger.Errorw("failed to unmarshal revision token, treating as if none was provided", "error", err)
+				decryptFail = true
+			case len(parsed.DeviceNonce) > 0 && subtle.ConstantTimeCompare(parsed.DeviceNonce, deviceNonce) != 1:
+				logger.Warnw("revision token is bound to another device, treating as if none was provided")
+				decryptFail = true
+			default:
+				token = parsed.Data
+				stats.Record(ctx, mRevisionTokenVersion.M(int64(parsed.Version)))
+			}
 		}
 	}
 
-	// Perform health authority certificate verification.
-	verifiedClaims, err := s.verifier.VerifyDiagnosisCertificate(ctx, appConfig, data)
+	batchTime := time.Now()
+
+	// Uploads may be split across multiple requests. Only the first chunk is
+	// verified, later chunks are authorized by the revision token from the
+	// previous chunk.
+	chunk, err := s.checkChunk(data, token, appConfig.AppPackageName, batchTime)
+	if err != nil {
+		message := fmt.Sprintf("invalid chunked upload: %v", err)
+		logger.Warnw(message)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
+		blame = obs.BlameClient
+		obsResult = obs.ResultError("INVALID_CHUNK")
+		return errorResponse(errcode.InvalidChunk, message)
+	}
+
+	var verifiedClaims *verification.VerifiedClaims
+	if isContinuation(chunk) {
+		// The claims were verified on the first chunk.
+		verifiedClaims = chunkClaims(chunk)
+	} else {
+		// Perform health authority certificate verification.
+		verifiedClaims, err = s.verifier.VerifyDiagnosisCertificate(ctx, appConfig, data)
+	}
+	verified = err == nil
 	if err != nil {
 		if appConfig.BypassHealthAuthorityVerification {
 			logger.Warnf("bypassing health authority certificate verification health authority: %v", appConfig.AppPackageName)
@@ -325,7 +677,7 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 			if errors.Is(err, verification.ErrNoPublicKeys) {
 				// This only happens if the health authority ID exists in the database.
 				logger.Warnw("received publish request for health authority with no public keys", "healthAuthorityID", data.HealthAuthorityID)
-				tags := []tag.Mutator{tag.Upsert(healthAuthorityIDTag, data.HealthAuthorityID)}
+				tags := []tag.Mutator{obs.Upsert(healthAuthorityIDTag, data.HealthAuthorityID)}
 				if err := stats.RecordWithTags(ctx, tags, mNoPublicKey.M(1)); err != nil {
 					logger.Errorw("failed to record stats for missing public key", "error", err, "healthAuthorityID", data.HealthAuthorityID)
 				}
@@ -333,14 +685,14 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 
 			if errors.Is(err, verification.ErrNotValidYet) {
 				logger.Warnw("received future dated verification certificate", "healthAuthorityID", data.HealthAuthorityID)
-				tags := []tag.Mutator{tag.Upsert(healthAuthorityIDTag, data.HealthAuthorityID)}
+				tags := []tag.Mutator{obs.Upsert(healthAuthorityIDTag, data.HealthAuthorityID)}
 				if err := stats.RecordWithTags(ctx, tags, mJWTNotYetValid.M(1)); err != nil {
 					logger.Errorw("failed to record stats for missing public key", "error", err, "healthAuthorityID", data.HealthAuthorityID)
 				}
 			}
 
 			message := fmt.Sprintf("unable to validate diagnosis verification: %v", err)
-			if s.config.DebugLogBadCertificates {
+			if cfg.DebugLogBadCertificates {
 				logger.Errorw(message, "error", err, "jwt", data.VerificationPayload)
 			} else {
 				logger.Errorw(message, "error", err)
@@ -348,37 +700,32 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 			span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
 			blame = obs.BlameClient
 			obsResult = obs.ResultError("BAD_VERIFICATION")
-			return &response{
-				status: http.StatusUnauthorized,
-				pubResponse: &verifyapi.PublishResponse{
-					ErrorMessage: message,
-					Code:         verifyapi.ErrorVerificationCertificateInvalid,
-				},
-			}
+			return errorResponse(errcode.VerificationCertificateInvalid, message)
 		}
 	}
+	setChunkClaims(chunk, verifiedClaims)
 
-	// Examine the revision token. It is expected that it is missing in most cases.
-	var token *pb.RevisionTokenData
-	decryptFail := false
-	if len(data.RevisionToken) != 0 {
-		encryptedToken, err := base64util.DecodeString(data.RevisionToken)
-		if err != nil {
-			logger.Warnw("failed to decode revision token, proceeding without", "error", err)
-		} else {
-			token, err = s.tokenManager.UnmarshalRevisionToken(ctx, encryptedToken, s.tokenAAD)
-			if err != nil {
-				logger.Errorw("failed to unmarshal revision token, treating as if none was provided", "error", err)
-				token = nil // just in case.
-				decryptFail = true
-			}
-		}
+	// Apps may override the server's same day key policy.
+	transformer, err := s.transformer.Load().WithSameDayKeyPolicy(model.SameDayKeyPolicy(appConfig.SameDayKeyPolicy))
+	if err != nil {
+		logger.Warnw("ignoring invalid same day key policy", "app", appConfig.AppPackageName, "error", err)
+		transformer = s.transformer.Load()
 	}
 
-	batchTime := time.Now()
-	result, transformError := s.transformer.TransformPublish(ctx, data, regions, verifiedClaims, batchTime)
+	// Regions may keep keys for a shorter or longer time than the server.
+	maxIntervalAge := s.retention.For(transformer.MaxIntervalStartAge(), regions...)
+	transformer = transformer.WithMaxIntervalStartAge(maxIntervalAge)
+
+	result, transformError := transformer.TransformPublish(ctx, data, regions, verifiedClaims, batchTime)
 	// Break apart the result object for easier usage below.
 	exposures := result.Exposures
+
+	// Later chunks of an upload are part of the first chunk's request.
+	if !isContinuation(chunk) {
+		observation := abusemodel.NewObservation(appConfig.AppPackageName, data, batchTime)
+		observation.RejectedKeys = len(data.Keys) - len(exposures)
+		s.recordAbuseStats(ctx, observation)
+	}
 	publishInfo := result.PublishInfo
 	transformWarnings := result.Warnings
 	// Check for non-recoverable error. It is possible that individual keys are dropped, but if there
@@ -390,83 +737,117 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
 		blame = obs.BlameClient
 		obsResult = obs.ResultError("TRANSFORM_FAILED")
-		return &response{
-			status: http.StatusBadRequest,
-			pubResponse: &verifyapi.PublishResponse{
-				ErrorMessage: message,
-				Code:         verifyapi.ErrorBadRequest,
-				Warnings:     transformWarnings,
-			},
+		resp := errorResponse(errcode.BadRequest, message)
+		resp.pubResponse.Warnings = transformWarnings
+		return resp
+	}
+
+	// Keys from test apps, or verified by a health authority that is still
+	// onboarding, are test data. Test uploads are left out of the stats API.
+	if appConfig.TestData || (verifiedClaims != nil && verifiedClaims.TestData) {
+		for _, exposure := range exposures {
+			exposure.TestData = true
 		}
+		publishInfo = nil
+		stats.Record(ctx, mTestDataUploads.M(1))
 	}
 
 	// Add in the platform
 	if publishInfo != nil {
 		publishInfo.Platform = platform
+		publishInfo.ContinuedChunk = isContinuation(chunk)
+		if s.startupConfig.UploadMetadataStats && verifiedClaims != nil {
+			publishInfo.CaptureMetadata = true
+			publishInfo.ReportType = verifiedClaims.ReportType
+		}
 	}
 
-	resp, err := s.database.InsertAndReviseExposures(ctx, &database.InsertAndReviseExposuresRequest{
+	// Suspicious uploads are accepted as usual, but their keys are held out of
+	// exports until they are reviewed.
+	var cohort *quarantinemodel.Cohort
+	if s.featureFlags.Enabled(ctx, featureflag.PublishQuarantine, appConfig.AppPackageName, true) {
+		cohort = s.startupConfig.Quarantine.Evaluate(exposures, token, batchTime)
+	}
+	if cohort != nil {
+		logger.Warnw("quarantining upload", "score", cohort.Score, "reasons", cohort.Reasons)
+		stats.Record(ctx, mQuarantinedUploads.M(1))
+	}
+
+	insertRequest := &database.InsertAndReviseExposuresRequest{
 		Incoming:    exposures,
 		Token:       token,
 		PublishInfo: publishInfo,
 
 		RequireToken:          !appConfig.BypassRevisionToken,
-		AllowPartialRevisions: s.config.AllowPartialRevisions,
-	})
+		AllowPartialRevisions: cfg.AllowPartialRevisions,
+		ExtendSameDayKeys:     cfg.ReleaseExtendedKeys,
+		RecordEvent:           cfg.RecordPublishEvents,
+		Quarantine:            cohort,
+	}
+
+	// In queue mode the keys are written by the publish consumer.
+	var resp *database.InsertAndReviseExposuresResponse
+	if s.queue != nil {
+		resp, err = s.enqueueExposures(ctx, insertRequest)
+	} else {
+		resp, err = s.database.InsertAndReviseExposures(ctx, insertRequest)
+	}
 	if err != nil {
-		status := http.StatusBadRequest
-		var logMessage, errorMessage, errorCode string
+		var logMessage, errorMessage string
+		var errorCode errcode.Code
 		var errInvalidReportTypeTransition *model.ErrorKeyInvalidReportTypeTransition
 		switch {
 		case decryptFail || errors.Is(err, database.ErrExistingKeyNotInToken) || errors.Is(err, database.ErrRevisionTokenMetadataMismatch):
 			logMessage = fmt.Sprintf("revision token present, but invalid: %v", err)
 			errorMessage = "revision token is invalid"
-			errorCode = verifyapi.ErrorInvalidRevisionToken
+			errorCode = errcode.InvalidRevisionToken
 			blame = obs.BlameClient
 			obsResult = obs.ResultError("INVALID_REVISION_TOKEN")
 		case errors.Is(err, database.ErrNoRevisionToken):
 			logMessage = "no revision token"
 			errorMessage = "no revision token, but sent existing keys"
-			errorCode = verifyapi.ErrorMissingRevisionToken
+			errorCode = errcode.MissingRevisionToken
 			blame = obs.BlameClient
 			obsResult = obs.ResultError("MISSING_REVISION_TOKEN")
 		case errors.Is(err, model.ErrorKeyAlreadyRevised):
 			logMessage = "key already revised"
 			errorMessage = "key was already revised"
-			errorCode = verifyapi.ErrorKeyAlreadyRevised
+			errorCode = errcode.KeyAlreadyRevised
 			blame = obs.BlameClient
 			obsResult = obs.ResultError("KEY_ALREADY_REVISED")
 		case errors.As(err, &errInvalidReportTypeTransition):
 			logMessage = errInvalidReportTypeTransition.Error()
 			errorMessage = errInvalidReportTypeTransition.Error()
-			errorCode = verifyapi.ErrorInvalidReportTypeTransition
+			errorCode = errcode.InvalidReportTypeTransition
 			blame = obs.BlameClient
 			obsResult = obs.ResultError("INVALID_REPORT_TYPE_TRANSITION")
+		case errors.Is(err, errEnqueueFailed):
+			logMessage = err.Error()
+			errorMessage = http.StatusText(http.StatusInternalServerError)
+			errorCode = errcode.InternalError
+			logger.Errorw("publish error", "error", logMessage)
+			blame = obs.BlameServer
+			obsResult = obs.ResultError("ERROR_ENQUEUE")
 		default:
 			logMessage = fmt.Sprintf("error writing exposure record: %v", err)
 			errorMessage = http.StatusText(http.StatusInternalServerError)
-			errorCode = verifyapi.ErrorInternalError
+			errorCode = errcode.InternalError
 			logger.Errorw("publish error", "error", logMessage)
 			blame = obs.BlameServer
 			obsResult = obs.ResultError("ERROR_DB_WRITE")
 		}
 		logger.Debugw("publish error", "error", logMessage)
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: logMessage})
-		return &response{
-			status: status,
-			pubResponse: &verifyapi.PublishResponse{
-				ErrorMessage: errorMessage,
-				Code:         errorCode,
-				Warnings:     transformWarnings,
-			},
-		}
+		resp := errorResponse(errorCode, errorMessage)
+		resp.pubResponse.Warnings = transformWarnings
+		return resp
 	}
 
 	// Build the new revision token. Union of existing token take + new exposures.
 	var keep pb.RevisionTokenData
 	if token != nil {
 		// put existing tokens that aren't too old back in the token.
-		retainInterval := model.IntervalNumber(batchTime.Add(-1 * s.config.MaxIntervalAge))
+		retainInterval := model.IntervalNumber(batchTime.Add(-1 * maxIntervalAge))
 		for _, rk := range token.RevisableKeys {
 			if rk.IntervalNumber+rk.IntervalCount >= retainInterval {
 				keep.RevisableKeys = append(keep.RevisableKeys, rk)
@@ -474,10 +855,15 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		}
 	}
 
+	// Carry the chunked upload state forward until the final chunk.
+	if chunk != nil && chunk.Chunk < chunk.Of {
+		keep.ChunkedUpload = chunk
+	}
+
 	newToken := make([]byte, 0)
 	if len(keep.RevisableKeys) != 0 || len(resp.Exposures) != 0 {
 		var err error
-		newToken, err = s.tokenManager.MakeRevisionToken(ctx, &keep, resp.Exposures, s.tokenAAD)
+		newToken, err = s.tokenManager.MakeRevisionToken(ctx, &keep, resp.Exposures, s.tokenAAD, deviceNonce)
 		if err != nil {
 			// Something failed with the revision token generation or encryption.
 			logger.Errorw("failed to make updated revision token", "error", err)
@@ -490,16 +876,30 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 	logger.Infow("published exposures",
 		"inserted", resp.Inserted,
 		"updated", resp.Revised,
-		"dropped", resp.Dropped)
+		"dropped", resp.Dropped,
+		"quarantined", resp.Quarantined)
 
 	publishResponse := verifyapi.PublishResponse{
 		RevisionToken:     base64.StdEncoding.EncodeToString(newToken),
 		InsertedExposures: int(resp.Inserted),
 		Warnings:          transformWarnings,
 	}
+	if appConfig.DetailedPublishResponse {
+		// Keys rejected during transform never reach the database, so they are
+		// counted as dropped along with the ones the database dropped.
+		rejected := len(data.Keys) - len(exposures)
+		if rejected < 0 {
+			rejected = 0
+		}
+		publishResponse.Outcome = &verifyapi.PublishOutcome{
+			Inserted: int(resp.Inserted),
+			Revised:  int(resp.Revised),
+			Dropped:  int(resp.Dropped) + rejected,
+		}
+	}
 	// If there was a partial failure on transform, add that information back into the success response.
 	if transformError != nil {
-		publishResponse.Code = verifyapi.ErrorPartialFailure
+		publishResponse.Code = string(errcode.PartialFailure)
 		publishResponse.ErrorMessage = transformError.Error()
 	}
 
@@ -516,8 +916,8 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 	// Backwards compat from v1alpha1 API, normally there is one region.
 	for _, region := range regions {
 		tags := []tag.Mutator{
-			tag.Upsert(healthAuthorityIDTag, data.HealthAuthorityID),
-			tag.Upsert(regionTag, region),
+			obs.Upsert(healthAuthorityIDTag, data.HealthAuthorityID),
+			obs.Upsert(regionTag, region),
 		}
 		if err := stats.RecordWithTags(ctx, tags, mPublishRequest.M(int64(1))); err != nil {
 			logger.Errorw("failed to record publish request stats", "error", err)
@@ -530,6 +930,43 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 	}
 }
 
+// recordAbuseStats records the publish metadata for the abuse detector. A
+// failure is logged, but does not fail the request.
+func (s *Server) recordAbuseStats(ctx context.Context, o *abusemodel.Observation) {
+	if !s.config.Load().RecordAbuseStats {
+		return
+	}
+	if err := s.abuseDB.RecordPublish(ctx, o); err != nil {
+		logger := logging.FromContext(ctx).Named("recordAbuseStats")
+		logger.Errorw("failed to record abuse stats", "error", err)
+	}
+}
+
+// errEnqueueFailed is returned when an upload could not be sent to the publish
+// queue.
+var errEnqueueFailed = errors.New("failed to enqueue exposures")
+
+// enqueueExposures sends the insert request to the publish queue. The keys are
+// checked against the database and written by the publish consumer, so
+// revisions that are not allowed are dropped there instead of being rejected
+// here. The response reports all incoming keys as inserted, and includes them
+// in the revision token.
+func (s *Server) enqueueExposures(ctx context.Context, req *database.InsertAndReviseExposuresRequest) (*database.InsertAndReviseExposuresResponse, error) {
+	b, err := database.MarshalQueuedRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errEnqueueFailed, err)
+	}
+	if err := s.queue.Publish(ctx, b); err != nil {
+		return nil, fmt.Errorf("%w: %v", errEnqueueFailed, err)
+	}
+	stats.Record(ctx, mQueuedUploads.M(1))
+
+	return &database.InsertAndReviseExposuresResponse{
+		Inserted:  uint32(len(req.Incoming)),
+		Exposures: req.Incoming,
+	}, nil
+}
+
 // chaffPushResponse takes a chaffing string, and builds a chaff response.
 func chaffPublishResponse(s string) interface{} {
 	return verifyapi.PublishResponse{Padding: s}
@@ -29,6 +29,8 @@ import (
 	"testing"
 	"time"
 
+	abusedb "github.com/google/exposure-notifications-server/internal/abuse/database"
+
 	"github.com/google/exposure-notifications-server/internal/authorizedapp"
 	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
 	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
@@ -36,6 +38,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/project"
 	pubdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/queue"
 	"github.com/google/exposure-notifications-server/internal/revision"
 	revisiondb "github.com/google/exposure-notifications-server/internal/revision/database"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
@@ -45,7 +48,7 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/keys"
 	"github.com/google/exposure-notifications-server/pkg/timeutils"
 	"github.com/google/exposure-notifications-server/pkg/util"
-	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v5"
 	"github.com/sethvargo/go-envconfig"
 
 	testutil "github.com/google/exposure-notifications-server/internal/utils"
@@ -123,6 +126,7 @@ func TestPublishWithBypass(t *testing.T) {
 		ErrorCode          string
 		SkipVersions       map[version]bool
 		SkipKeys           map[int]bool // which keys should be skipped (partial success)
+		WantOutcome        *verifyapi.PublishOutcome
 	}{
 		{
 			Name:       "successful_insert_bypass_ha_verification",
@@ -141,6 +145,24 @@ func TestPublishWithBypass(t *testing.T) {
 			Regions: []string{regions.current()},
 			Code:    http.StatusOK,
 		},
+		{
+			Name:       "test_data_app",
+			TestRegion: regions.next(),
+			AuthorizedApp: func() *aamodel.AuthorizedApp {
+				authApp := aamodel.NewAuthorizedApp()
+				authApp.AppPackageName = names.next()
+				authApp.BypassHealthAuthorityVerification = true
+				authApp.AllowedRegions[regions.current()] = struct{}{}
+				authApp.TestData = true
+				return authApp
+			}(),
+			Publish: verifyapi.Publish{
+				Keys:              util.GenerateExposureKeys(2, 5, false),
+				HealthAuthorityID: names.current(),
+			},
+			Regions: []string{regions.current()},
+			Code:    http.StatusOK,
+		},
 		{
 			Name:       "partial_success",
 			TestRegion: regions.next(),
@@ -169,6 +191,32 @@ func TestPublishWithBypass(t *testing.T) {
 			Error:     "4 errors occurred:",
 			ErrorCode: verifyapi.ErrorPartialFailure,
 		},
+		{
+			Name:       "detailed_response",
+			TestRegion: regions.next(),
+			AuthorizedApp: func() *aamodel.AuthorizedApp {
+				authApp := aamodel.NewAuthorizedApp()
+				authApp.AppPackageName = names.next()
+				authApp.BypassHealthAuthorityVerification = true
+				authApp.DetailedPublishResponse = true
+				authApp.AllowedRegions[regions.current()] = struct{}{}
+				return authApp
+			}(),
+			Publish: verifyapi.Publish{
+				Keys: func() []verifyapi.ExposureKey {
+					keys := util.GenerateExposureKeys(3, 0, false)
+					keys[2].IntervalCount = 200 // key 2 has invalid interval count
+					return keys
+				}(),
+				HealthAuthorityID: names.current(),
+			},
+			Regions:     []string{regions.current()},
+			Code:        http.StatusOK,
+			SkipKeys:    map[int]bool{2: true},
+			Error:       "1 error occurred:",
+			ErrorCode:   verifyapi.ErrorPartialFailure,
+			WantOutcome: &verifyapi.PublishOutcome{Inserted: 2, Revised: 0, Dropped: 1},
+		},
 		{
 			Name:        "invalid_content_type",
 			ContentType: "application/pdf",
@@ -211,6 +259,46 @@ func TestPublishWithBypass(t *testing.T) {
 			Error:     "unauthorized health authority",
 			ErrorCode: "unknown_health_authority_id",
 		},
+		{
+			Name:       "disabled",
+			TestRegion: regions.next(),
+			AuthorizedApp: func() *aamodel.AuthorizedApp {
+				authApp := aamodel.NewAuthorizedApp()
+				authApp.AppPackageName = names.next()
+				authApp.BypassHealthAuthorityVerification = true
+				authApp.AllowedRegions[regions.current()] = struct{}{}
+				authApp.Disabled = true
+				return authApp
+			}(),
+			Publish: verifyapi.Publish{
+				Keys:              util.GenerateExposureKeys(2, 5, false),
+				HealthAuthorityID: names.current(),
+			},
+			Regions:   []string{regions.current()},
+			Code:      http.StatusUnauthorized,
+			Error:     "health authority is disabled",
+			ErrorCode: "unknown_health_authority_id",
+		},
+		{
+			Name:       "throttled",
+			TestRegion: regions.next(),
+			AuthorizedApp: func() *aamodel.AuthorizedApp {
+				authApp := aamodel.NewAuthorizedApp()
+				authApp.AppPackageName = names.next()
+				authApp.BypassHealthAuthorityVerification = true
+				authApp.AllowedRegions[regions.current()] = struct{}{}
+				authApp.ThrottledUntil = time.Now().Add(time.Hour)
+				return authApp
+			}(),
+			Publish: verifyapi.Publish{
+				Keys:              util.GenerateExposureKeys(2, 5, false),
+				HealthAuthorityID: names.current(),
+			},
+			Regions:   []string{regions.current()},
+			Code:      http.StatusTooManyRequests,
+			Error:     "are paused until",
+			ErrorCode: verifyapi.ErrorTemporarilyThrottled,
+		},
 		{
 			Name:       "write_to_unauthorized_region",
 			TestRegion: regions.next(),
@@ -553,6 +641,11 @@ func TestPublishWithBypass(t *testing.T) {
 					if err := appDB.InsertAuthorizedApp(ctx, tc.AuthorizedApp); err != nil {
 						t.Fatal(err)
 					}
+					if until := tc.AuthorizedApp.ThrottledUntil; !until.IsZero() {
+						if err := abusedb.New(env.Database()).ThrottleApp(ctx, tc.AuthorizedApp.AppPackageName, until); err != nil {
+							t.Fatal(err)
+						}
+					}
 				}
 				pubDB := pubdb.New(env.Database())
 
@@ -643,6 +736,17 @@ func TestPublishWithBypass(t *testing.T) {
 							}
 						}
 
+						// Detailed outcomes are only returned when enabled on the app.
+						var wantOutcome *verifyapi.PublishOutcome
+						if tc.WantOutcome != nil {
+							outcome := *tc.WantOutcome
+							outcome.PaddingBytes = len(response.Padding)
+							wantOutcome = &outcome
+						}
+						if diff := cmp.Diff(wantOutcome, response.Outcome); diff != "" {
+							t.Errorf("outcome mismatch (-want, +got):\n%s", diff)
+						}
+
 						got := make([]*model.Exposure, 0, len(tc.Publish.Keys))
 						_, err = pubDB.IterateExposures(ctx, criteria, func(ex *model.Exposure) error {
 							got = append(got, ex)
@@ -678,6 +782,7 @@ func TestPublishWithBypass(t *testing.T) {
 									Traveler:         tc.Publish.Traveler,
 									LocalProvenance:  true,
 									FederationSyncID: 0,
+									TestData:         tc.AuthorizedApp.TestData,
 								}
 								if tc.ReportType != "" {
 									next.ReportType = tc.ReportType
@@ -1015,7 +1120,7 @@ func TestKeyRevision(t *testing.T) {
 					},
 				}
 				revToken.RevisableKeys = revToken.RevisableKeys[0:1]
-				tokenBytes, err = tm.MakeRevisionToken(ctx, revToken, newKeys, aad)
+				tokenBytes, err = tm.MakeRevisionToken(ctx, revToken, newKeys, aad, nil)
 				if err != nil {
 					return "", err
 				}
@@ -1266,3 +1371,51 @@ func TestKeyRevision(t *testing.T) {
 		})
 	}
 }
+
+func TestEnqueueExposures(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	q, err := queue.NewMemory(ctx, &queue.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{queue: q}
+
+	req := &pubdb.InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{
+			{ExposureKey: []byte("ABCDEFGHIJKLMNOP"), IntervalNumber: 100, IntervalCount: 144},
+			{ExposureKey: []byte("BCDEFGHIJKLMNOPQ"), IntervalNumber: 244, IntervalCount: 144},
+		},
+		RequireToken: true,
+	}
+	resp, err := s.enqueueExposures(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// All keys are reported as inserted, so they are part of the revision
+	// token.
+	if got, want := resp.Inserted, uint32(2); got != want {
+		t.Errorf("expected %d inserted, got %d", want, got)
+	}
+	if diff := cmp.Diff(req.Incoming, resp.Exposures, cmpopts.IgnoreUnexported(model.Exposure{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	msgs, err := q.Receive(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(msgs), 1; got != want {
+		t.Fatalf("expected %d queued uploads, got %d", want, got)
+	}
+	got, err := pubdb.UnmarshalQueuedRequest(msgs[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(req, got, cmpopts.IgnoreUnexported(model.Exposure{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
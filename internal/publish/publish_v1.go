@@ -23,22 +23,40 @@ import (
 	"go.opencensus.io/stats"
 	"go.opencensus.io/trace"
 
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	"github.com/google/exposure-notifications-server/internal/i18n"
 	"github.com/google/exposure-notifications-server/internal/jsonutil"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	obs "github.com/google/exposure-notifications-server/pkg/observability"
 )
 
-func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) *response {
+func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) (resp *response) {
 	ctx, span := trace.StartSpan(r.Context(), "(*publish.PublishHandler).handleRequest")
 	defer span.End()
 
 	w.Header().Set(HeaderAPIVersion, "v1")
 
+	signed := newSignedRequest(w, r)
+	clientPlatform := platform(r.UserAgent())
+
 	var data verifyapi.Publish
+	parsed := false
+
+	// Requests that fail validation are captured for debugging, if enabled.
+	if s.capture != nil {
+		defer func() {
+			var req *verifyapi.Publish
+			if parsed {
+				req = &data
+			}
+			s.capture.Record(ctx, req, clientPlatform, resp.status, resp.pubResponse)
+		}()
+	}
+
 	code, err := jsonutil.Unmarshal(w, r, &data)
 	if err != nil {
-		if s.config.LogJSONParseErrors {
+		if s.config.Load().LogJSONParseErrors {
 			logger := logging.FromContext(ctx).Named("handlePublishV1.handleRequest")
 			logger.Warnw("v1 unmarshal failure", "error", err)
 		}
@@ -48,21 +66,21 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) *response
 		defer obs.RecordLatency(ctx, time.Now(), mLatencyMs, &blame, &obsResult)
 		message := fmt.Sprintf("error unmarshalling API call, code: %v: %v", code, err)
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
-		errorCode := verifyapi.ErrorBadRequest
+		errorCode := errcode.BadRequest
 		if code == http.StatusInternalServerError {
-			errorCode = verifyapi.ErrorInternalError
+			errorCode = errcode.InternalError
 		}
 		return &response{
 			status: code,
 			pubResponse: &verifyapi.PublishResponse{
 				ErrorMessage: message,
-				Code:         errorCode,
+				Code:         string(errorCode),
 			},
 		}
 	}
 
-	clientPlatform := platform(r.UserAgent())
-	return s.process(ctx, &data, clientPlatform, newVersionBridge([]string{}))
+	parsed = true
+	return s.process(ctx, &data, clientPlatform, newVersionBridge([]string{}), signed)
 }
 
 // handlePublishV1 returns an http.Handler that can process V1 publish requests.
@@ -72,15 +90,25 @@ func (s *Server) handlePublishV1() http.Handler {
 
 		logger := logging.FromContext(ctx).Named("handlePublishV1")
 
+		start := time.Now()
 		response := s.handleRequest(w, r)
+		delayRejection(ctx, start, response.status, s.config.Load().RejectMinDuration)
+
+		if code := response.pubResponse.Code; code != "" {
+			response.pubResponse.Message = i18n.FromContext(ctx).ErrorMessage(errcode.Code(code))
+		}
 
-		if padding, err := generatePadding(s.config.ResponsePaddingMinBytes, s.config.ResponsePaddingRange); err != nil {
+		if padding, err := uniformPadding(response.pubResponse, s.startupConfig.ResponsePaddingMinBytes, s.startupConfig.ResponsePaddingRange); err != nil {
 			stats.Record(ctx, mPaddingFailed.M(1))
 			logger.Errorw("failed to pad response", "error", err)
 		} else {
 			response.pubResponse.Padding = padding
+			if response.pubResponse.Outcome != nil {
+				response.pubResponse.Outcome.PaddingBytes = len(padding)
+			}
 		}
 
+		response.writeHeaders(w)
 		jsonutil.MarshalResponse(w, response.status, response.pubResponse)
 	})
 }
@@ -36,10 +36,12 @@ func (s *Server) handleV1Apha1Request(w http.ResponseWriter, r *http.Request) *r
 
 	w.Header().Set(HeaderAPIVersion, "v1alpha")
 
+	signed := newSignedRequest(w, r)
+
 	var data v1alpha1.Publish
 	code, err := jsonutil.Unmarshal(w, r, &data)
 	if err != nil {
-		if s.config.LogJSONParseErrors {
+		if s.config.Load().LogJSONParseErrors {
 			logger := logging.FromContext(ctx).Named("handleV1Apha1Request")
 			logger.Warnw("v1alpha1 unmarshal failure", "error", err)
 		}
@@ -80,7 +82,7 @@ func (s *Server) handleV1Apha1Request(w http.ResponseWriter, r *http.Request) *r
 	bridge := newVersionBridge(data.Regions)
 
 	clientPlatform := platform(r.UserAgent())
-	return s.process(ctx, &publish, clientPlatform, bridge)
+	return s.process(ctx, &publish, clientPlatform, bridge, signed)
 }
 
 func (s *Server) handlePublishV1Alpha1() http.Handler {
@@ -89,9 +91,11 @@ func (s *Server) handlePublishV1Alpha1() http.Handler {
 
 		logger := logging.FromContext(ctx).Named("handlePublishV1Alpha1")
 
+		start := time.Now()
 		response := s.handleV1Apha1Request(w, r)
+		delayRejection(ctx, start, response.status, s.config.Load().RejectMinDuration)
 
-		if padding, err := generatePadding(s.config.ResponsePaddingMinBytes, s.config.ResponsePaddingRange); err != nil {
+		if padding, err := uniformPadding(response.pubResponse, s.startupConfig.ResponsePaddingMinBytes, s.startupConfig.ResponsePaddingRange); err != nil {
 			stats.Record(ctx, mPaddingFailed.M(1))
 			logger.Errorw("failed to pad response", "error", err)
 		} else {
@@ -107,6 +111,7 @@ func (s *Server) handlePublishV1Alpha1() http.Handler {
 			Warnings:          response.pubResponse.Warnings,
 		}
 
+		response.writeHeaders(w)
 		jsonutil.MarshalResponse(w, response.status, alpha1Response)
 	})
 }
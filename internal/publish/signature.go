@@ -0,0 +1,124 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+var (
+	errMissingSignature = errors.New("missing request signature")
+	errInvalidSignature = errors.New("invalid request signature")
+	errSignatureExpired = errors.New("request signature timestamp outside allowed skew")
+)
+
+// signedRequest is the part of a publish request that is covered by the
+// optional request signature.
+type signedRequest struct {
+	body      []byte
+	signature string
+	timestamp string
+}
+
+// newSignedRequest reads the request body so it can be both decoded and
+// verified, and replaces the body with a reader over the same bytes. Read
+// errors are deferred to the reader so that the JSON decoder reports them as
+// it would have without signing.
+func newSignedRequest(w http.ResponseWriter, r *http.Request) *signedRequest {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, jsonutil.MaxBodyBytes))
+	r.Body.Close()
+
+	var reader io.Reader = bytes.NewReader(body)
+	if err != nil {
+		reader = io.MultiReader(reader, &errReader{err})
+	}
+	r.Body = io.NopCloser(reader)
+
+	return &signedRequest{
+		body:      body,
+		signature: r.Header.Get(verifyapi.HeaderSignature),
+		timestamp: r.Header.Get(verifyapi.HeaderSignatureTimestamp),
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(_ []byte) (int, error) {
+	return 0, r.err
+}
+
+// verifySignature checks the request signature if the authorized app requires
+// signed requests. The signing secret is read from the secret manager, which
+// caches it.
+func (s *Server) verifySignature(ctx context.Context, app *model.AuthorizedApp, req *signedRequest, now time.Time) error {
+	if app.RequestSigningSecret == "" {
+		return nil
+	}
+	if req == nil || req.signature == "" || req.timestamp == "" {
+		return errMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(req.timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", errInvalidSignature)
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > s.startupConfig.RequestSignatureMaxSkew || skew < -s.startupConfig.RequestSignatureMaxSkew {
+		return errSignatureExpired
+	}
+
+	got, err := base64.StdEncoding.DecodeString(req.signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", errInvalidSignature)
+	}
+
+	sm := s.env.SecretManager()
+	if sm == nil {
+		return fmt.Errorf("no secret manager configured")
+	}
+	secret, err := sm.GetSecretValue(ctx, app.RequestSigningSecret)
+	if err != nil {
+		return fmt.Errorf("failed to read request signing secret: %w", err)
+	}
+
+	if !hmac.Equal(got, requestSignature([]byte(secret), req.timestamp, req.body)) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// requestSignature computes HMAC-SHA256(secret, timestamp + "." + body).
+func requestSignature(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
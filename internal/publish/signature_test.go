@@ -0,0 +1,177 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/secrets"
+)
+
+func TestNewSignedRequest(t *testing.T) {
+	t.Parallel()
+
+	body := `{"hak":"com.example"}`
+	r := httptest.NewRequest("POST", "/v1/publish", strings.NewReader(body))
+	r.Header.Set(verifyapi.HeaderSignature, "sig")
+	r.Header.Set(verifyapi.HeaderSignatureTimestamp, "123")
+
+	signed := newSignedRequest(httptest.NewRecorder(), r)
+	if got, want := string(signed.body), body; got != want {
+		t.Errorf("expected body %q to be %q", got, want)
+	}
+	if got, want := signed.signature, "sig"; got != want {
+		t.Errorf("expected signature %q to be %q", got, want)
+	}
+	if got, want := signed.timestamp, "123"; got != want {
+		t.Errorf("expected timestamp %q to be %q", got, want)
+	}
+
+	// The body can still be read for decoding.
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), body; got != want {
+		t.Errorf("expected re-read body %q to be %q", got, want)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	sm, err := secrets.NewInMemoryFromMap(ctx, map[string]string{
+		"signing-secret": "super-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		env:           serverenv.New(ctx, serverenv.WithSecretManager(sm)),
+		startupConfig: &Config{RequestSignatureMaxSkew: 5 * time.Minute},
+	}
+
+	now := time.Now()
+	body := []byte(`{"temporaryExposureKeys":[]}`)
+	sign := func(secret string, ts time.Time, body []byte) *signedRequest {
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		return &signedRequest{
+			body:      body,
+			timestamp: timestamp,
+			signature: base64.StdEncoding.EncodeToString(requestSignature([]byte(secret), timestamp, body)),
+		}
+	}
+
+	signingApp := &model.AuthorizedApp{RequestSigningSecret: "signing-secret"}
+
+	cases := []struct {
+		name string
+		app  *model.AuthorizedApp
+		req  *signedRequest
+		err  error
+	}{
+		{
+			name: "not_required",
+			app:  &model.AuthorizedApp{},
+			req:  &signedRequest{body: body},
+		},
+		{
+			name: "valid",
+			app:  signingApp,
+			req:  sign("super-secret", now, body),
+		},
+		{
+			name: "valid_within_skew",
+			app:  signingApp,
+			req:  sign("super-secret", now.Add(-4*time.Minute), body),
+		},
+		{
+			name: "missing",
+			app:  signingApp,
+			req:  &signedRequest{body: body},
+			err:  errMissingSignature,
+		},
+		{
+			name: "wrong_secret",
+			app:  signingApp,
+			req:  sign("other-secret", now, body),
+			err:  errInvalidSignature,
+		},
+		{
+			name: "tampered_body",
+			app:  signingApp,
+			req: func() *signedRequest {
+				req := sign("super-secret", now, body)
+				req.body = []byte(`{"temporaryExposureKeys":[{}]}`)
+				return req
+			}(),
+			err: errInvalidSignature,
+		},
+		{
+			name: "malformed_timestamp",
+			app:  signingApp,
+			req: func() *signedRequest {
+				req := sign("super-secret", now, body)
+				req.timestamp = "yesterday"
+				return req
+			}(),
+			err: errInvalidSignature,
+		},
+		{
+			name: "expired",
+			app:  signingApp,
+			req:  sign("super-secret", now.Add(-10*time.Minute), body),
+			err:  errSignatureExpired,
+		},
+		{
+			name: "future",
+			app:  signingApp,
+			req:  sign("super-secret", now.Add(10*time.Minute), body),
+			err:  errSignatureExpired,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := s.verifySignature(ctx, tc.app, tc.req, now)
+			if tc.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v to be %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -16,15 +16,20 @@ package publish
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/google/exposure-notifications-server/internal/errcode"
+	"github.com/google/exposure-notifications-server/internal/i18n"
 	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	"github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	"github.com/google/exposure-notifications-server/pkg/logging"
+	"go.opencensus.io/stats"
 	"go.opencensus.io/trace"
 )
 
@@ -39,19 +44,23 @@ func (s *Server) handleStats() http.Handler {
 		if err != nil {
 			message := fmt.Sprintf("error unmarshalling API call, code: %v: %v", code, err)
 			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
-			errorCode := verifyapi.ErrorBadRequest
+			errorCode := errcode.BadRequest
 			if code == http.StatusInternalServerError {
-				errorCode = verifyapi.ErrorInternalError
+				errorCode = errcode.InternalError
 			}
 			s.addMetricsPadding(ctx, response)
 			jsonutil.MarshalResponse(w, http.StatusBadRequest, &verifyapi.StatsResponse{
 				ErrorMessage: message,
-				ErrorCode:    errorCode,
+				ErrorCode:    string(errorCode),
+				Message:      i18n.FromContext(ctx).ErrorMessage(errorCode),
 			})
 			return
 		}
 
 		response, status := s.handleMetricsRequest(ctx, r.Header.Get("Authorization"), &request)
+		if response.ErrorCode != "" {
+			response.Message = i18n.FromContext(ctx).ErrorMessage(errcode.Code(response.ErrorCode))
+		}
 		s.addMetricsPadding(ctx, response)
 
 		jsonutil.MarshalResponse(w, status, response)
@@ -61,7 +70,7 @@ func (s *Server) handleStats() http.Handler {
 func (s *Server) addMetricsPadding(ctx context.Context, response *verifyapi.StatsResponse) {
 	logger := logging.FromContext(ctx).Named("addMetricsPadding")
 
-	if padding, err := generatePadding(s.config.StatsResponsePaddingMinBytes, s.config.StatsResponsePaddingRange); err != nil {
+	if padding, err := generatePadding(s.startupConfig.StatsResponsePaddingMinBytes, s.startupConfig.StatsResponsePaddingRange); err != nil {
 		logger.Errorw("failed to pad response", "error", err)
 	} else {
 		response.Padding = padding
@@ -75,8 +84,8 @@ func (s *Server) handleMetricsRequest(ctx context.Context, bearerToken string, r
 
 	if !strings.HasPrefix(bearerToken, "Bearer ") {
 		response.ErrorMessage = "Authorization header is not in `Bearer <token>` format"
-		response.ErrorCode = verifyapi.ErrorUnauthorized
-		return response, http.StatusUnauthorized
+		response.ErrorCode = string(errcode.Unauthorized)
+		return response, errcode.Unauthorized.HTTPStatus()
 	}
 	// Remove 'Bearer ' from the token.
 	bearerToken = bearerToken[7:]
@@ -86,25 +95,69 @@ func (s *Server) handleMetricsRequest(ctx context.Context, bearerToken string, r
 	if err != nil {
 		logger.Infow("stats authorization failure", "error", err)
 		response.ErrorMessage = err.Error()
-		response.ErrorCode = verifyapi.ErrorUnauthorized
-		return response, http.StatusUnauthorized
+		response.ErrorCode = string(errcode.Unauthorized)
+		return response, errcode.Unauthorized.HTTPStatus()
 	}
 
 	// retrieve stats
-	stats, err := s.database.ReadStats(ctx, healthAuthorityID)
+	haStats, err := s.database.ReadStats(ctx, healthAuthorityID)
 	if err != nil {
 		logger.Errorw("error reading stats", "error", err)
 		response.ErrorMessage = "error reading stats"
-		response.ErrorCode = verifyapi.ErrorInternalError
-		return response, http.StatusInternalServerError
+		response.ErrorCode = string(errcode.InternalError)
+		return response, errcode.InternalError.HTTPStatus()
 	}
 
 	// Nothing from the current hour can be shown.
 	onlyBefore := time.Now().UTC().Truncate(time.Hour)
 
 	// Combine days - this also filters things that are "too new" and days that don't meet the threshold.
-	response.Days = model.ReduceStats(stats, onlyBefore, s.config.StatsUploadMinimum, s.config.StatsEmbargoPeriod)
+	response.Days = model.ReduceStats(haStats, onlyBefore, s.startupConfig.StatsUploadMinimum, s.startupConfig.StatsEmbargoPeriod)
+
+	// Metadata captured before it was turned off is not released, it would
+	// not be covered by the privacy noise. If it's on, every day has all of the
+	// distributions, so an empty one doesn't stand out from the noise.
+	for _, day := range response.Days {
+		if s.startupConfig.UploadMetadataStats {
+			day.KeysPerUploadDistribution = padStats(day.KeysPerUploadDistribution, model.StatsMaxKeysPerUpload+1)
+			day.ConfirmedOnsetToUploadDistribution = padStats(day.ConfirmedOnsetToUploadDistribution, model.StatsMaxOnsetDays+1)
+			day.LikelyOnsetToUploadDistribution = padStats(day.LikelyOnsetToUploadDistribution, model.StatsMaxOnsetDays+1)
+		} else {
+			day.KeysPerUploadDistribution = nil
+			day.ConfirmedOnsetToUploadDistribution = nil
+			day.LikelyOnsetToUploadDistribution = nil
+		}
+	}
+
+	// Add noise if differential privacy is enabled. Nothing is released, and no
+	// budget is spent, if there are no days.
+	if s.startupConfig.StatsPrivacy.Enabled && len(response.Days) > 0 {
+		privacy, err := s.addStatsPrivacy(ctx, healthAuthorityID, response.Days)
+		if err != nil {
+			response.Days = nil
+			if errors.Is(err, database.ErrPrivacyBudgetExhausted) {
+				logger.Infow("stats privacy budget exhausted", "health_authority_id", healthAuthorityID)
+				stats.Record(ctx, mStatsPrivacyBudgetExhausted.M(1))
+				response.ErrorMessage = "privacy budget exhausted for this period"
+				response.ErrorCode = string(errcode.PrivacyBudgetExhausted)
+				return response, errcode.PrivacyBudgetExhausted.HTTPStatus()
+			}
+			logger.Errorw("error applying stats privacy", "error", err)
+			response.ErrorMessage = "error reading stats"
+			response.ErrorCode = string(errcode.InternalError)
+			return response, errcode.InternalError.HTTPStatus()
+		}
+		response.Privacy = privacy
+	}
 
 	// return
 	return response, http.StatusOK
 }
+
+// padStats allocates an empty distribution of the given size if d is nil.
+func padStats(d []int64, size int) []int64 {
+	if d == nil {
+		return make([]int64, size)
+	}
+	return d
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/dp"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+// Names of the metrics in a stats response, used to configure their
+// sensitivity. They match the JSON field names.
+const (
+	statsMetricPublishRequests  = "publish_requests"
+	statsMetricTEKsPublished    = "total_teks_published"
+	statsMetricRevisionRequests = "requests_with_revisions"
+	statsMetricMissingOnset     = "requests_missing_onset_date"
+	statsMetricTEKAge           = "tek_age_distribution"
+	statsMetricOnsetToUpload    = "onset_to_upload_distribution"
+
+	statsMetricKeysPerUpload          = "keys_per_upload_distribution"
+	statsMetricConfirmedOnsetToUpload = "confirmed_onset_to_upload_distribution"
+	statsMetricLikelyOnsetToUpload    = "likely_onset_to_upload_distribution"
+)
+
+// statsMetrics are the metrics that the epsilon of a response is split across.
+var statsMetrics = []string{
+	statsMetricPublishRequests,
+	statsMetricTEKsPublished,
+	statsMetricRevisionRequests,
+	statsMetricMissingOnset,
+	statsMetricTEKAge,
+	statsMetricOnsetToUpload,
+}
+
+// statsMetadataMetrics are also split across if upload metadata is captured.
+var statsMetadataMetrics = []string{
+	statsMetricKeysPerUpload,
+	statsMetricConfirmedOnsetToUpload,
+	statsMetricLikelyOnsetToUpload,
+}
+
+// addStatsPrivacy spends the health authority's privacy budget for one
+// response and adds noise to days. It returns database.ErrPrivacyBudgetExhausted
+// if the budget for the current period is spent.
+func (s *Server) addStatsPrivacy(ctx context.Context, healthAuthorityID int64, days verifyapi.StatsDays) (*verifyapi.StatsPrivacy, error) {
+	cfg := &s.startupConfig.StatsPrivacy
+
+	periodStart := cfg.BudgetPeriodStart(time.Now())
+	spent, err := s.database.SpendStatsPrivacyBudget(ctx, healthAuthorityID, periodStart, cfg.Epsilon, cfg.Budget)
+	if err != nil {
+		return nil, err
+	}
+
+	addStatsNoise(s.statsNoise, cfg, days, s.startupConfig.UploadMetadataStats)
+
+	return &verifyapi.StatsPrivacy{
+		Epsilon:         cfg.Epsilon,
+		BudgetRemaining: math.Max(0, cfg.Budget-spent),
+		BudgetResetsAt:  periodStart.Add(cfg.BudgetPeriod),
+	}, nil
+}
+
+// addStatsNoise adds noise to each metric of each day in place. The epsilon of
+// a response is split evenly across the metrics. A publish request is counted
+// in only one day, so the days do not add to the privacy loss. Within a
+// distribution, a request is counted in only one bucket. If metadata is set,
+// the upload metadata distributions share the epsilon.
+func addStatsNoise(m *dp.Mechanism, cfg *dp.Config, days verifyapi.StatsDays, metadata bool) {
+	metrics := len(statsMetrics)
+	if metadata {
+		metrics += len(statsMetadataMetrics)
+	}
+	epsilon := cfg.Epsilon / float64(metrics)
+
+	for _, day := range days {
+		sensitivity := cfg.SensitivityFor(statsMetricPublishRequests)
+		day.PublishRequests.UnknownPlatform = m.Count(day.PublishRequests.UnknownPlatform, sensitivity, epsilon)
+		day.PublishRequests.Android = m.Count(day.PublishRequests.Android, sensitivity, epsilon)
+		day.PublishRequests.IOS = m.Count(day.PublishRequests.IOS, sensitivity, epsilon)
+
+		day.TotalTEKsPublished = m.Count(day.TotalTEKsPublished, cfg.SensitivityFor(statsMetricTEKsPublished), epsilon)
+		day.RevisionRequests = m.Count(day.RevisionRequests, cfg.SensitivityFor(statsMetricRevisionRequests), epsilon)
+		day.RequestsMissingOnsetDate = m.Count(day.RequestsMissingOnsetDate, cfg.SensitivityFor(statsMetricMissingOnset), epsilon)
+
+		m.Counts(day.TEKAgeDistribution, cfg.SensitivityFor(statsMetricTEKAge), epsilon)
+		m.Counts(day.OnsetToUploadDistribution, cfg.SensitivityFor(statsMetricOnsetToUpload), epsilon)
+
+		if metadata {
+			m.Counts(day.KeysPerUploadDistribution, cfg.SensitivityFor(statsMetricKeysPerUpload), epsilon)
+			m.Counts(day.ConfirmedOnsetToUploadDistribution, cfg.SensitivityFor(statsMetricConfirmedOnsetToUpload), epsilon)
+			m.Counts(day.LikelyOnsetToUploadDistribution, cfg.SensitivityFor(statsMetricLikelyOnsetToUpload), epsilon)
+		}
+	}
+}
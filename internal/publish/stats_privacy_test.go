@@ -0,0 +1,89 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/dp"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAddStatsNoise(t *testing.T) {
+	t.Parallel()
+
+	day := func() *verifyapi.StatsDay {
+		return &verifyapi.StatsDay{
+			Day:                       time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+			PublishRequests:           verifyapi.PublishRequests{UnknownPlatform: 1, Android: 20, IOS: 30},
+			TotalTEKsPublished:        400,
+			RevisionRequests:          5,
+			TEKAgeDistribution:        []int64{1, 2, 3},
+			OnsetToUploadDistribution: []int64{4, 5, 6},
+			RequestsMissingOnsetDate:  7,
+
+			KeysPerUploadDistribution:          []int64{8, 9},
+			ConfirmedOnsetToUploadDistribution: []int64{10, 11},
+			LikelyOnsetToUploadDistribution:    []int64{12, 13},
+		}
+	}
+
+	t.Run("negligible_noise", func(t *testing.T) {
+		t.Parallel()
+
+		days := verifyapi.StatsDays{day()}
+		addStatsNoise(dp.NewMechanism(), &dp.Config{Epsilon: 1e12}, days, false)
+		if diff := cmp.Diff(verifyapi.StatsDays{day()}, days); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("negligible_noise_metadata", func(t *testing.T) {
+		t.Parallel()
+
+		days := verifyapi.StatsDays{day()}
+		addStatsNoise(dp.NewMechanism(), &dp.Config{Epsilon: 1e12}, days, true)
+		if diff := cmp.Diff(verifyapi.StatsDays{day()}, days); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("never_negative", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 0; i < 100; i++ {
+			days := verifyapi.StatsDays{day()}
+			addStatsNoise(dp.NewMechanism(), &dp.Config{Epsilon: 0.01}, days, true)
+
+			d := days[0]
+			values := []int64{
+				d.PublishRequests.UnknownPlatform, d.PublishRequests.Android, d.PublishRequests.IOS,
+				d.TotalTEKsPublished, d.RevisionRequests, d.RequestsMissingOnsetDate,
+			}
+			values = append(values, d.TEKAgeDistribution...)
+			values = append(values, d.OnsetToUploadDistribution...)
+			values = append(values, d.KeysPerUploadDistribution...)
+			values = append(values, d.ConfirmedOnsetToUploadDistribution...)
+			values = append(values, d.LikelyOnsetToUploadDistribution...)
+			for _, v := range values {
+				if v < 0 {
+					t.Fatalf("expected no negative counts, got %v", values)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/verification"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Rejected publish requests must not reveal why they were rejected through
+// their timing or size, e.g. whether the app is unknown or the certificate is
+// invalid. Requests rejected before their certificate is verified do the work
+// of verifying one anyway, rejected requests take at least
+// RejectMinDuration, and the padding of error responses makes up for the
+// length of the error message.
+
+// constantWork does the expensive work of verifying a certificate, the ECDSA
+// signature and the HMAC of the keys, against a throwaway key.
+type constantWork struct {
+	publicKey *ecdsa.PublicKey
+	digest    []byte
+	signature []byte
+	secret    []byte
+}
+
+func newConstantWork() (*constantWork, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	digest := sha256.Sum256([]byte("constant work"))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return &constantWork{
+		publicKey: &key.PublicKey,
+		digest:    digest[:],
+		signature: sig,
+		secret:    secret,
+	}, nil
+}
+
+// do verifies the throwaway signature and calculates the HMAC of the keys in
+// the request. The results are discarded.
+func (w *constantWork) do(data *verifyapi.Publish) {
+	if w == nil {
+		return
+	}
+
+	var claims verifyapi.VerificationClaims
+	_, _, _ = new(jwt.Parser).ParseUnverified(data.VerificationPayload, &claims)
+	_ = ecdsa.VerifyASN1(w.publicKey, w.digest, w.signature)
+	if len(data.Keys) > 0 {
+		_, _ = verification.CalculateAllAllowedExposureKeyHMACWithOptions(data.Keys, w.secret, verification.HMACOptions{})
+	}
+}
+
+// isRejected returns true if the response rejects the request because of the
+// request, rather than a server error.
+func isRejected(status int) bool {
+	return status >= 400 && status < 500 && status != http.StatusTooManyRequests
+}
+
+// delayRejection waits until minDuration after start if the response rejects
+// the request.
+func delayRejection(ctx context.Context, start time.Time, status int, minDuration time.Duration) {
+	if !isRejected(status) || minDuration <= 0 {
+		return
+	}
+
+	d := time.Until(start.Add(minDuration))
+	if d <= 0 {
+		return
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// uniformPadding is like generatePadding, but for error responses, it
+// shortens the padding by the length of the error, so that the size of error
+// responses does not depend on the error.
+func uniformPadding(resp *verifyapi.PublishResponse, minPadding, paddingRange int64) (string, error) {
+	n, err := paddingLength(minPadding, paddingRange)
+	if err != nil {
+		return "", err
+	}
+
+	if variable := len(resp.ErrorMessage) + len(resp.Message) + len(resp.Code); variable > 0 {
+		want := base64.StdEncoding.EncodedLen(n) - variable
+		if want < 0 {
+			want = 0
+		}
+		n = base64.StdEncoding.DecodedLen(want)
+	}
+	return randomPadding(n)
+}
@@ -0,0 +1,216 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/verification"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/util"
+)
+
+func TestUniformPadding(t *testing.T) {
+	t.Parallel()
+
+	// A range of 1 always pads with the minimum.
+	const minPadding = 1000
+	want := base64.StdEncoding.EncodedLen(minPadding)
+
+	for _, resp := range []*verifyapi.PublishResponse{
+		{Code: "unknown_health_authority_id", ErrorMessage: "unauthorized health authority: a"},
+		{Code: "health_authority_verification_certificate_invalid", ErrorMessage: strings.Repeat("x", 300)},
+		{ErrorMessage: "no code"},
+	} {
+		padding, err := uniformPadding(resp, minPadding, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := len(padding) + len(resp.ErrorMessage) + len(resp.Message) + len(resp.Code)
+		if diff := got - want; diff < -4 || diff > 4 {
+			t.Errorf("%q: expected size %d to be within 4 of %d", resp.ErrorMessage, got, want)
+		}
+	}
+
+	// Successful responses are padded as usual.
+	padding, err := uniformPadding(&verifyapi.PublishResponse{}, minPadding, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(padding); got != want {
+		t.Errorf("expected padding %d to be %d", got, want)
+	}
+}
+
+func TestDelayRejection(t *testing.T) {
+	t.Parallel()
+
+	const minDuration = 50 * time.Millisecond
+
+	cases := []struct {
+		name   string
+		status int
+		delay  bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "unauthorized", status: http.StatusUnauthorized, delay: true},
+		{name: "bad_request", status: http.StatusBadRequest, delay: true},
+		{name: "too_many_requests", status: http.StatusTooManyRequests},
+		{name: "internal_error", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Now()
+			delayRejection(context.Background(), start, tc.status, minDuration)
+			elapsed := time.Since(start)
+
+			if tc.delay && elapsed < minDuration {
+				t.Errorf("expected %v to be at least %v", elapsed, minDuration)
+			}
+			if !tc.delay && elapsed >= minDuration {
+				t.Errorf("expected %v to be less than %v", elapsed, minDuration)
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		delayRejection(ctx, start, http.StatusUnauthorized, time.Hour)
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			t.Errorf("expected canceled delay to return, took %v", elapsed)
+		}
+	})
+}
+
+// TestHandlePublishV1_uniformRejections measures that an unknown app and an
+// invalid certificate can not be told apart by the timing or the size of the
+// response.
+func TestHandlePublishV1_uniformRejections(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	const minDuration = 50 * time.Millisecond
+	const samples = 5
+
+	provider, err := authorizedapp.NewMemoryProvider(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := aamodel.NewAuthorizedApp()
+	app.AppPackageName = "com.example.app"
+	app.AllowedRegions["US"] = struct{}{}
+	if err := provider.(*authorizedapp.MemoryProvider).Add(ctx, app); err != nil {
+		t.Fatal(err)
+	}
+
+	// The verifier does not reach the database for payloads that are not
+	// certificates.
+	verifier, err := verification.New(nil, &verification.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, err := newConstantWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		ResponsePaddingMinBytes: 1024,
+		ResponsePaddingRange:    1,
+		RejectMinDuration:       minDuration,
+		MaxUploadChunks:         1,
+	}
+	s := &Server{
+		authorizedAppProvider: provider,
+		verifier:              verifier,
+		constantWork:          work,
+		startupConfig:         cfg,
+	}
+	s.config.Store(cfg)
+	handler := s.handlePublishV1()
+
+	publish := func(healthAuthorityID string) (time.Duration, int) {
+		body, err := json.Marshal(&verifyapi.Publish{
+			Keys:                util.GenerateExposureKeys(14, -1, false),
+			HealthAuthorityID:   healthAuthorityID,
+			VerificationPayload: "not.a.certificate",
+			HMACKey:             base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/publish", bytes.NewReader(body)).WithContext(ctx)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		start := time.Now()
+		handler.ServeHTTP(w, r)
+		elapsed := time.Since(start)
+
+		if w.Code == http.StatusOK {
+			t.Fatalf("expected %s to be rejected", healthAuthorityID)
+		}
+		return elapsed, w.Body.Len()
+	}
+
+	var unknown, invalid time.Duration
+	for i := 0; i < samples; i++ {
+		for _, id := range []string{"com.example.unknown", app.AppPackageName} {
+			elapsed, size := publish(id)
+			if elapsed < minDuration {
+				t.Errorf("%s: expected %v to be at least %v", id, elapsed, minDuration)
+			}
+			if diff := size - 1024*4/3; diff < -8 || diff > 64 {
+				t.Errorf("%s: expected response size %d to be close to the padded size", id, size)
+			}
+
+			if id == app.AppPackageName {
+				invalid += elapsed
+			} else {
+				unknown += elapsed
+			}
+		}
+	}
+
+	unknown /= samples
+	invalid /= samples
+	if diff := unknown - invalid; diff < -minDuration/2 || diff > minDuration/2 {
+		t.Errorf("expected mean unknown app time %v to be close to mean invalid certificate time %v", unknown, invalid)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publishconsumer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"github.com/google/exposure-notifications-server/pkg/secrets"
+)
+
+// Compile-time check to assert this config matches requirements.
+var (
+	_ setup.DatabaseConfigProvider              = (*Config)(nil)
+	_ setup.SecretManagerConfigProvider         = (*Config)(nil)
+	_ setup.ObservabilityExporterConfigProvider = (*Config)(nil)
+	_ setup.QueueConfigProvider                 = (*Config)(nil)
+)
+
+// Config represents the configuration and associated environment variables for
+// the publish consumer.
+type Config struct {
+	Database              database.Config
+	SecretManager         secrets.Config
+	ObservabilityExporter observability.Config
+	Middleware            middleware.Config
+	Queue                 queue.Config
+
+	Port string `env:"PORT, default=8080"`
+
+	// MaxRuntime is how long a run keeps receiving uploads from the queue. It
+	// should be shorter than the interval the consumer is invoked at.
+	MaxRuntime time.Duration `env:"MAX_RUNTIME, default=4m"`
+
+	// BatchSize is the number of uploads received from the queue at once.
+	BatchSize int `env:"BATCH_SIZE, default=100"`
+}
+
+func (c *Config) DatabaseConfig() *database.Config {
+	return &c.Database
+}
+
+func (c *Config) SecretManagerConfig() *secrets.Config {
+	return &c.SecretManager
+}
+
+func (c *Config) ObservabilityExporterConfig() *observability.Config {
+	return &c.ObservabilityExporter
+}
+
+func (c *Config) QueueConfig() *queue.Config {
+	return &c.Queue
+}
+
+// Validate checks the consumer configuration.
+func (c *Config) Validate() error {
+	if !c.Queue.Enabled() {
+		return fmt.Errorf("QUEUE is required")
+	}
+	if c.MaxRuntime <= 0 {
+		return fmt.Errorf("MAX_RUNTIME must be positive, got %v", c.MaxRuntime)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("BATCH_SIZE must be positive, got %d", c.BatchSize)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publishconsumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/queue"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		config *Config
+		err    bool
+	}{
+		{
+			name:   "default",
+			config: &Config{Queue: queue.Config{Type: "MEMORY"}, MaxRuntime: time.Minute, BatchSize: 10},
+		},
+		{
+			name:   "no_queue",
+			config: &Config{MaxRuntime: time.Minute, BatchSize: 10},
+			err:    true,
+		},
+		{
+			name:   "no_runtime",
+			config: &Config{Queue: queue.Config{Type: "MEMORY"}, BatchSize: 10},
+			err:    true,
+		},
+		{
+			name:   "no_batch_size",
+			config: &Config{Queue: queue.Config{Type: "MEMORY"}, MaxRuntime: time.Minute},
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.config.Validate()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
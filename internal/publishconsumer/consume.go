@@ -0,0 +1,131 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publishconsumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"go.opencensus.io/stats"
+)
+
+func (s *Server) handleConsume() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		logger := logging.FromContext(ctx).Named("handleConsume")
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		if err := s.consume(ctx, time.Now().Add(s.config.MaxRuntime)); err != nil {
+			logger.Errorw("failed to consume publish queue", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		stats.Record(ctx, mSuccess.M(1))
+		s.h.RenderJSON(w, http.StatusOK, nil)
+	})
+}
+
+// consume writes batches of uploads from the queue to the database until the
+// queue is empty or the deadline passes. Uploads are acknowledged once they
+// are written, or when they can never be written. If an upload fails to be
+// written for another reason, the run stops and the upload is delivered again
+// on a later run.
+func (s *Server) consume(ctx context.Context, deadline time.Time) error {
+	for time.Now().Before(deadline) {
+		msgs, err := s.queue.Receive(ctx, s.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to receive: %w", err)
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+
+		var processErr error
+		done := make([]*queue.Message, 0, len(msgs))
+		for _, msg := range msgs {
+			if err := s.process(ctx, msg); err != nil {
+				processErr = fmt.Errorf("message %s: %w", msg.ID, err)
+				break
+			}
+			done = append(done, msg)
+		}
+
+		if err := s.queue.Ack(ctx, done); err != nil {
+			return fmt.Errorf("failed to acknowledge: %w", err)
+		}
+		if processErr != nil {
+			return processErr
+		}
+	}
+	return nil
+}
+
+// process writes a single upload to the database. It returns an error only if
+// the upload should be delivered again.
+func (s *Server) process(ctx context.Context, msg *queue.Message) error {
+	logger := logging.FromContext(ctx).Named("process").
+		With("message", msg.ID)
+
+	req, err := publishdb.UnmarshalQueuedRequest(msg.Data)
+	if err != nil {
+		logger.Errorw("dropping malformed message", "error", err)
+		stats.Record(ctx, mMalformed.M(1))
+		return nil
+	}
+
+	resp, err := s.publishDB.InsertAndReviseExposures(ctx, req)
+	if err != nil {
+		if rejected(err) {
+			// The upload was accepted by publish before the keys were checked
+			// against the database, so the client can't be told anymore.
+			logger.Warnw("dropping rejected upload", "error", err)
+			stats.Record(ctx, mRejected.M(1))
+			return nil
+		}
+		return err
+	}
+
+	logger.Debugw("wrote upload",
+		"inserted", resp.Inserted,
+		"revised", resp.Revised,
+		"dropped", resp.Dropped)
+	stats.Record(ctx,
+		mUploads.M(1),
+		mInserted.M(int64(resp.Inserted)),
+		mRevised.M(int64(resp.Revised)))
+	return nil
+}
+
+// rejected returns true if the error means the upload can never be written,
+// because it is not a valid revision of the keys in the database.
+func rejected(err error) bool {
+	var errInvalidReportTypeTransition *model.ErrorKeyInvalidReportTypeTransition
+	return errors.Is(err, publishdb.ErrExistingKeyNotInToken) ||
+		errors.Is(err, publishdb.ErrNoRevisionToken) ||
+		errors.Is(err, publishdb.ErrRevisionTokenMetadataMismatch) ||
+		errors.Is(err, publishdb.ErrIncomingMetadataMismatch) ||
+		errors.Is(err, model.ErrorKeyAlreadyRevised) ||
+		errors.As(err, &errInvalidReportTypeTransition)
+}
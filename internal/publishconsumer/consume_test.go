@@ -0,0 +1,138 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publishconsumer
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/go-cmp/cmp"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+func TestConsume(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	q, err := queue.NewMemory(ctx, &queue.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := serverenv.New(ctx,
+		serverenv.WithDatabase(testDB),
+		serverenv.WithQueue(q))
+
+	s, err := NewServer(&Config{
+		Queue:      queue.Config{Type: "MEMORY"},
+		MaxRuntime: time.Minute,
+		BatchSize:  2,
+	}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createdAt := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	exposure := func(key string) *model.Exposure {
+		return &model.Exposure{
+			ExposureKey:     []byte(key),
+			Regions:         []string{"US"},
+			IntervalNumber:  100,
+			IntervalCount:   144,
+			CreatedAt:       createdAt,
+			LocalProvenance: true,
+			ReportType:      verifyapi.ReportTypeClinical,
+		}
+	}
+	enqueue := func(req *publishdb.InsertAndReviseExposuresRequest) {
+		t.Helper()
+		b, err := publishdb.MarshalQueuedRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Publish(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enqueue(&publishdb.InsertAndReviseExposuresRequest{
+		Incoming:     []*model.Exposure{exposure("AAAAAAAAAAAAAAAA"), exposure("BBBBBBBBBBBBBBBB")},
+		RequireToken: true,
+	})
+	// A message that can't be decoded is dropped.
+	if err := q.Publish(ctx, []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+	// Revising a key without a revision token is rejected and dropped.
+	revised := exposure("AAAAAAAAAAAAAAAA")
+	revised.ReportType = verifyapi.ReportTypeConfirmed
+	enqueue(&publishdb.InsertAndReviseExposuresRequest{
+		Incoming:     []*model.Exposure{revised},
+		RequireToken: true,
+	})
+	enqueue(&publishdb.InsertAndReviseExposuresRequest{
+		Incoming:     []*model.Exposure{exposure("CCCCCCCCCCCCCCCC")},
+		RequireToken: true,
+	})
+
+	if err := s.consume(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything was acknowledged.
+	msgs, err := q.Receive(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("expected queue to be empty, got %d messages", len(msgs))
+	}
+
+	keys := []string{
+		exposure("AAAAAAAAAAAAAAAA").ExposureKeyBase64(),
+		exposure("BBBBBBBBBBBBBBBB").ExposureKeyBase64(),
+		exposure("CCCCCCCCCCCCCCCC").ExposureKeyBase64(),
+	}
+	var stored map[string]*model.Exposure
+	if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var err error
+		stored, err = publishdb.New(testDB).ReadExposures(ctx, tx, keys)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0, len(stored))
+	for k, e := range stored {
+		got = append(got, k+"="+e.ReportType)
+	}
+	sort.Strings(got)
+	want := []string{
+		keys[0] + "=" + verifyapi.ReportTypeClinical,
+		keys[1] + "=" + verifyapi.ReportTypeClinical,
+		keys[2] + "=" + verifyapi.ReportTypeClinical,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
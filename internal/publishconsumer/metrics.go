@@ -0,0 +1,74 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publishconsumer
+
+import (
+	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+const metricPrefix = metrics.MetricRoot + "publish-consumer"
+
+var (
+	mSuccess   = stats.Int64(metricPrefix+"/success", "successful execution", stats.UnitDimensionless)
+	mUploads   = stats.Int64(metricPrefix+"/uploads", "uploads written to the database", stats.UnitDimensionless)
+	mInserted  = stats.Int64(metricPrefix+"/inserted", "exposures inserted", stats.UnitDimensionless)
+	mRevised   = stats.Int64(metricPrefix+"/revised", "exposures revised", stats.UnitDimensionless)
+	mRejected  = stats.Int64(metricPrefix+"/rejected", "uploads rejected by the database", stats.UnitDimensionless)
+	mMalformed = stats.Int64(metricPrefix+"/malformed", "messages that could not be decoded", stats.UnitDimensionless)
+)
+
+func init() {
+	observability.CollectViews([]*view.View{
+		{
+			Name:        metricPrefix + "/success",
+			Description: "Number of successes",
+			Measure:     mSuccess,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/uploads",
+			Description: "Number of uploads written to the database",
+			Measure:     mUploads,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/inserted",
+			Description: "Number of exposures inserted",
+			Measure:     mInserted,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/revised",
+			Description: "Number of exposures revised",
+			Measure:     mRevised,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/rejected",
+			Description: "Number of uploads rejected by the database, such as revisions without a valid revision token",
+			Measure:     mRejected,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/malformed",
+			Description: "Number of messages that could not be decoded",
+			Measure:     mMalformed,
+			Aggregation: view.Sum(),
+		},
+	}...)
+}
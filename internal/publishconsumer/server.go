@@ -0,0 +1,73 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package publishconsumer implements the service that writes uploads from the
+// publish queue to the database.
+package publishconsumer
+
+import (
+	"context"
+	"fmt"
+
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/render"
+	"github.com/google/exposure-notifications-server/pkg/server"
+	"github.com/gorilla/mux"
+)
+
+// Server hosts end points to consume the publish queue.
+type Server struct {
+	config    *Config
+	env       *serverenv.ServerEnv
+	queue     queue.Queue
+	publishDB *publishdb.PublishDB
+	h         *render.Renderer
+}
+
+// NewServer creates a Server that writes queued uploads to the database.
+func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
+	if env.Database() == nil {
+		return nil, fmt.Errorf("missing database in server environment")
+	}
+	if env.Queue() == nil {
+		return nil, fmt.Errorf("missing queue in server environment")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation: %w", err)
+	}
+
+	return &Server{
+		config:    cfg,
+		env:       env,
+		queue:     env.Queue(),
+		publishDB: publishdb.New(env.Database()),
+		h:         render.NewRenderer(),
+	}, nil
+}
+
+// Routes defines and returns the routes for this server.
+func (s *Server) Routes(ctx context.Context) *mux.Router {
+	logger := logging.FromContext(ctx).Named("publishconsumer")
+
+	r := mux.NewRouter()
+	s.config.Middleware.Use(r, logger)
+
+	r.Handle("/health", server.HandleHealthz(s.env.Database()))
+	r.Handle("/consume", s.handleConsume())
+
+	return r
+}
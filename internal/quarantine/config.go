@@ -0,0 +1,79 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quarantine scores published keys and holds suspicious uploads out of
+// exports until they are reviewed.
+package quarantine
+
+import (
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/quarantine/model"
+)
+
+// Config is the configuration for scoring uploads.
+type Config struct {
+	// ScoreThreshold is the score at which an upload is quarantined. Zero
+	// disables quarantine.
+	ScoreThreshold int `env:"QUARANTINE_SCORE_THRESHOLD, default=0"`
+
+	FutureKeyWeight      int `env:"QUARANTINE_FUTURE_KEY_WEIGHT, default=10"`
+	OverlappingKeyWeight int `env:"QUARANTINE_OVERLAPPING_KEY_WEIGHT, default=10"`
+	OnsetOutlierWeight   int `env:"QUARANTINE_ONSET_OUTLIER_WEIGHT, default=5"`
+	OnsetOutlierDays     int `env:"QUARANTINE_ONSET_OUTLIER_DAYS, default=7"`
+}
+
+// Enabled returns true if uploads may be quarantined.
+func (c *Config) Enabled() bool {
+	return c.ScoreThreshold > 0
+}
+
+// Weights returns the scoring weights.
+func (c *Config) Weights() *model.Weights {
+	return &model.Weights{
+		FutureKey:        c.FutureKeyWeight,
+		OverlappingKey:   c.OverlappingKeyWeight,
+		OnsetOutlier:     c.OnsetOutlierWeight,
+		OnsetOutlierDays: c.OnsetOutlierDays,
+	}
+}
+
+// Evaluate scores the keys of an upload. It returns the cohort to quarantine
+// the keys in, or nil if the upload should be exported as usual.
+func (c *Config) Evaluate(exposures []*publishmodel.Exposure, token *pb.RevisionTokenData, now time.Time) *model.Cohort {
+	if !c.Enabled() || len(exposures) == 0 {
+		return nil
+	}
+
+	var previous []*pb.RevisableKey
+	if token != nil {
+		previous = token.RevisableKeys
+	}
+
+	score, reasons := model.Score(exposures, previous, now, c.Weights())
+	if score < c.ScoreThreshold {
+		return nil
+	}
+
+	return &model.Cohort{
+		AppPackageName:    exposures[0].AppPackageName,
+		HealthAuthorityID: exposures[0].HealthAuthorityID,
+		CreatedAt:         now,
+		Score:             score,
+		Reasons:           reasons,
+		Status:            model.StatusPending,
+	}
+}
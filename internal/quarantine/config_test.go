@@ -0,0 +1,68 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quarantine
+
+import (
+	"testing"
+	"time"
+
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/quarantine/model"
+)
+
+func TestConfig_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 6, 10, 15, 0, 0, 0, time.UTC)
+	today := publishmodel.IntervalNumber(time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC))
+	future := []*publishmodel.Exposure{
+		{AppPackageName: "com.example.app", ExposureKey: []byte("a"), IntervalNumber: today + 90, IntervalCount: 144},
+	}
+
+	cases := []struct {
+		name      string
+		threshold int
+		want      bool
+	}{
+		{name: "disabled", threshold: 0, want: false},
+		{name: "below", threshold: 16, want: false},
+		{name: "at", threshold: 15, want: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &Config{
+				ScoreThreshold:     tc.threshold,
+				FutureKeyWeight:    10,
+				OnsetOutlierWeight: 5,
+				OnsetOutlierDays:   7,
+			}
+			cohort := cfg.Evaluate(future, nil, now)
+			if got := cohort != nil; got != tc.want {
+				t.Fatalf("expected quarantine %t, got %t", tc.want, got)
+			}
+			if cohort == nil {
+				return
+			}
+			if cohort.AppPackageName != "com.example.app" || cohort.Score != 15 || cohort.Status != model.StatusPending {
+				t.Errorf("unexpected cohort %#v", cohort)
+			}
+		})
+	}
+}
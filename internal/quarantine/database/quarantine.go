@@ -0,0 +1,196 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface for quarantined publish requests.
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/quarantine/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// ErrNotPending is returned when reviewing a cohort that was already
+// reviewed.
+var ErrNotPending = errors.New("quarantine cohort is not pending review")
+
+type QuarantineDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *QuarantineDB {
+	return &QuarantineDB{
+		db: db,
+	}
+}
+
+const selectCohorts = `
+	SELECT
+		c.id, c.app_package_name, c.health_authority_id, c.created_at, c.score, c.reasons, c.status, c.reviewed_at,
+		(SELECT COUNT(*) FROM QuarantinedExposure e WHERE e.cohort_id = c.id)
+	FROM
+		QuarantineCohort c
+`
+
+// ListPendingCohorts returns the cohorts that have not been reviewed, oldest
+// first.
+func (db *QuarantineDB) ListPendingCohorts(ctx context.Context) ([]*model.Cohort, error) {
+	var cohorts []*model.Cohort
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, selectCohorts+`
+			WHERE
+				c.status = $1
+			ORDER BY c.created_at, c.id
+		`, string(model.StatusPending))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			cohort, err := scanOneCohort(rows)
+			if err != nil {
+				return err
+			}
+			cohorts = append(cohorts, cohort)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list quarantine cohorts: %w", err)
+	}
+
+	return cohorts, nil
+}
+
+// GetCohort returns the cohort with the given ID.
+func (db *QuarantineDB) GetCohort(ctx context.Context, id int64) (*model.Cohort, error) {
+	var cohort *model.Cohort
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, selectCohorts+`
+			WHERE
+				c.id = $1
+		`, id)
+
+		var err error
+		cohort, err = scanOneCohort(row)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read quarantine cohort: %w", err)
+	}
+
+	return cohort, nil
+}
+
+func scanOneCohort(row pgx.Row) (*model.Cohort, error) {
+	var c model.Cohort
+	var reasons []string
+	var status string
+	if err := row.Scan(&c.ID, &c.AppPackageName, &c.HealthAuthorityID, &c.CreatedAt,
+		&c.Score, &reasons, &status, &c.ReviewedAt, &c.Keys); err != nil {
+		return nil, err
+	}
+	c.Status = model.Status(status)
+	for _, r := range reasons {
+		c.Reasons = append(c.Reasons, model.Reason(r))
+	}
+	return &c, nil
+}
+
+// Release moves the keys of a pending cohort into the exposures table so they
+// are exported. Keys are given a created_at of no earlier than exportAt, so
+// that they are included in a batch that has not been exported yet. Keys that
+// were published again while quarantined are not overwritten. Returns the
+// number of keys released.
+func (db *QuarantineDB) Release(ctx context.Context, id int64, exportAt time.Time) (int64, error) {
+	var count int64
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := reviewCohort(ctx, tx, id, model.StatusReleased, exportAt); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(ctx, `
+			INSERT INTO
+				Exposure
+					(exposure_key, transmission_risk, app_package_name, regions, traveler, interval_number, interval_count,
+					 created_at, local_provenance, sync_id, sync_query_id, health_authority_id, report_type, days_since_symptom_onset,
+					 export_import_id, import_file_id, jurisdiction, federation_consent, test_data, key_metadata,
+					 provenance_source, original_regions)
+			SELECT
+				exposure_key, transmission_risk, app_package_name, regions, traveler, interval_number, interval_count,
+				GREATEST(created_at, $2), local_provenance, sync_id, sync_query_id, health_authority_id, report_type, days_since_symptom_onset,
+				export_import_id, import_file_id, jurisdiction, federation_consent, test_data, key_metadata,
+				provenance_source, original_regions
+			FROM
+				QuarantinedExposure
+			WHERE
+				cohort_id = $1
+			ON CONFLICT (tenant, exposure_key) DO NOTHING
+		`, id, exportAt)
+		if err != nil {
+			return fmt.Errorf("failed to release quarantined exposures: %w", err)
+		}
+		count = result.RowsAffected()
+
+		if _, err := tx.Exec(ctx, `DELETE FROM QuarantinedExposure WHERE cohort_id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete quarantined exposures: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Reject deletes the keys of a pending cohort. The cohort is kept as a record
+// of the review.
+func (db *QuarantineDB) Reject(ctx context.Context, id int64, now time.Time) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := reviewCohort(ctx, tx, id, model.StatusRejected, now); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM QuarantinedExposure WHERE cohort_id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete quarantined exposures: %w", err)
+		}
+		return nil
+	})
+}
+
+// reviewCohort sets the status of a pending cohort.
+func reviewCohort(ctx context.Context, tx pgx.Tx, id int64, status model.Status, now time.Time) error {
+	result, err := tx.Exec(ctx, `
+		UPDATE
+			QuarantineCohort
+		SET
+			status = $2, reviewed_at = $3
+		WHERE
+			id = $1 AND status = $4
+	`, id, string(status), now, string(model.StatusPending))
+	if err != nil {
+		return fmt.Errorf("failed to update quarantine cohort: %w", err)
+	}
+	if result.RowsAffected() != 1 {
+		return ErrNotPending
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/quarantine/model"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+func TestReleaseAndReject(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	publishDB := publishdb.New(testDB)
+	quarantineDB := New(testDB)
+
+	createdAt := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	quarantine := func(keys ...string) int64 {
+		t.Helper()
+
+		exposures := make([]*publishmodel.Exposure, 0, len(keys))
+		for i, k := range keys {
+			exposures = append(exposures, &publishmodel.Exposure{
+				ExposureKey:     []byte(k),
+				AppPackageName:  "app",
+				Regions:         []string{"US"},
+				IntervalNumber:  int32(100 + 144*i),
+				IntervalCount:   144,
+				CreatedAt:       createdAt,
+				LocalProvenance: true,
+			})
+		}
+		cohort := &model.Cohort{
+			AppPackageName: "app",
+			CreatedAt:      createdAt,
+			Score:          20,
+			Reasons:        []model.Reason{model.ReasonFutureKey},
+			Status:         model.StatusPending,
+		}
+		resp, err := publishDB.InsertAndReviseExposures(ctx, &publishdb.InsertAndReviseExposuresRequest{
+			Incoming:   exposures,
+			Quarantine: cohort,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.Quarantined, uint32(len(keys)); got != want {
+			t.Fatalf("expected %d quarantined keys, got %d", want, got)
+		}
+		return cohort.ID
+	}
+
+	readExposures := func(keys ...string) map[string]*publishmodel.Exposure {
+		t.Helper()
+
+		var exposures map[string]*publishmodel.Exposure
+		if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+			var err error
+			exposures, err = publishDB.ReadExposures(ctx, tx, keys)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return exposures
+	}
+
+	released := quarantine("aaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb")
+	rejected := quarantine("cccccccccccccccc")
+
+	// Quarantined keys are not visible to exports.
+	if existing := readExposures("YWFhYWFhYWFhYWFhYWFhYQ=="); len(existing) != 0 {
+		t.Fatalf("expected quarantined key to not be in exposures, got %v", existing)
+	}
+
+	cohorts, err := quarantineDB.ListPendingCohorts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cohorts) != 2 || cohorts[0].ID != released || cohorts[0].Keys != 2 || cohorts[1].Keys != 1 {
+		t.Fatalf("unexpected pending cohorts: %#v", cohorts)
+	}
+	if got := cohorts[0].Reasons; len(got) != 1 || got[0] != model.ReasonFutureKey {
+		t.Errorf("unexpected reasons: %v", got)
+	}
+
+	exportAt := createdAt.Add(3 * time.Hour)
+	count, err := quarantineDB.Release(ctx, released, exportAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 released keys, got %d", count)
+	}
+	if _, err := quarantineDB.Release(ctx, released, exportAt); !errors.Is(err, ErrNotPending) {
+		t.Errorf("expected %v releasing twice, got %v", ErrNotPending, err)
+	}
+
+	if err := quarantineDB.Reject(ctx, rejected, exportAt); err != nil {
+		t.Fatal(err)
+	}
+
+	cohort, err := quarantineDB.GetCohort(ctx, rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cohort.Status != model.StatusRejected || cohort.Keys != 0 || cohort.ReviewedAt == nil {
+		t.Errorf("unexpected rejected cohort: %#v", cohort)
+	}
+
+	existing := readExposures("YWFhYWFhYWFhYWFhYWFhYQ==", "Y2NjY2NjY2NjY2NjY2NjYw==")
+	if len(existing) != 1 {
+		t.Fatalf("expected only the released key in exposures, got %v", existing)
+	}
+	for _, e := range existing {
+		if !e.CreatedAt.Equal(exportAt) {
+			t.Errorf("expected released key created at %v, got %v", exportAt, e.CreatedAt)
+		}
+	}
+}
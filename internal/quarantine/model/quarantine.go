@@ -0,0 +1,149 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction for quarantined publish requests.
+package model
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+)
+
+// Reason names a property of an upload that makes it suspicious.
+type Reason string
+
+const (
+	// ReasonFutureKey is a key that is valid past the end of the current UTC
+	// day. Devices roll keys at UTC midnight, so these keys were not produced by
+	// a device with a correct clock.
+	ReasonFutureKey Reason = "future_key"
+	// ReasonOverlappingKeys is a key whose interval overlaps, but does not start
+	// with, a key that the same device uploaded earlier according to its
+	// revision token.
+	ReasonOverlappingKeys Reason = "overlapping_keys"
+	// ReasonOnsetOutlier is an upload where none of the keys are near the
+	// symptom onset.
+	ReasonOnsetOutlier Reason = "onset_outlier"
+)
+
+// Status is the review state of a cohort.
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusReleased Status = "released"
+	StatusRejected Status = "rejected"
+)
+
+// Weights are the score added for each suspicious property of an upload.
+type Weights struct {
+	FutureKey      int
+	OverlappingKey int
+	OnsetOutlier   int
+
+	// OnsetOutlierDays is how far from symptom onset the closest key may be
+	// before the upload is an outlier. Zero disables the check.
+	OnsetOutlierDays int
+}
+
+// Cohort is the set of keys from a single publish request that were held out
+// of exports.
+type Cohort struct {
+	ID                int64
+	AppPackageName    string
+	HealthAuthorityID *int64
+	CreatedAt         time.Time
+	Score             int
+	Reasons           []Reason
+	Status            Status
+	ReviewedAt        *time.Time
+
+	// Keys is the number of keys that are still quarantined. It is only set
+	// when reading cohorts.
+	Keys int
+}
+
+// Pending returns true if the cohort has not been reviewed.
+func (c *Cohort) Pending() bool {
+	return c.Status == StatusPending
+}
+
+// Score scores the keys of a single upload. Previous are the keys that the
+// device uploaded before, from its revision token, and may be empty. It
+// returns the total score and the distinct reasons that contributed to it.
+func Score(exposures []*publishmodel.Exposure, previous []*pb.RevisableKey, now time.Time, w *Weights) (int, []Reason) {
+	nextMidnight := publishmodel.IntervalNumber(timeutils.UTCMidnight(now).Add(24 * time.Hour))
+
+	score := 0
+	found := make(map[Reason]struct{})
+	nearOnset := false
+	for _, e := range exposures {
+		if e.IntervalNumber+e.IntervalCount > nextMidnight {
+			score += w.FutureKey
+			found[ReasonFutureKey] = struct{}{}
+		}
+
+		if overlapsPrevious(e, previous) {
+			score += w.OverlappingKey
+			found[ReasonOverlappingKeys] = struct{}{}
+		}
+
+		if d := e.DaysSinceSymptomOnset; d != nil && abs(int(*d)) <= w.OnsetOutlierDays {
+			nearOnset = true
+		}
+	}
+
+	if w.OnsetOutlierDays > 0 && len(exposures) > 0 && !nearOnset {
+		score += w.OnsetOutlier
+		found[ReasonOnsetOutlier] = struct{}{}
+	}
+
+	reasons := make([]Reason, 0, len(found))
+	for r := range found {
+		reasons = append(reasons, r)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		return reasons[i] < reasons[j]
+	})
+	return score, reasons
+}
+
+// overlapsPrevious returns true if e is a different key than one of the
+// previous keys, and is valid during part of that key's validity without
+// starting at the same interval. Keys starting at the same interval are
+// allowed since devices replace their key when the user resets exposure
+// notifications.
+func overlapsPrevious(e *publishmodel.Exposure, previous []*pb.RevisableKey) bool {
+	for _, p := range previous {
+		if bytes.Equal(p.TemporaryExposureKey, e.ExposureKey) || p.IntervalNumber == e.IntervalNumber {
+			continue
+		}
+		if e.IntervalNumber < p.IntervalNumber+p.IntervalCount && p.IntervalNumber < e.IntervalNumber+e.IntervalCount {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
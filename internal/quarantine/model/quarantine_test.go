@@ -0,0 +1,136 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+)
+
+func TestScore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 6, 10, 15, 0, 0, 0, time.UTC)
+	today := publishmodel.IntervalNumber(time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC))
+	onset := func(d int32) *int32 {
+		return &d
+	}
+	weights := &Weights{
+		FutureKey:        10,
+		OverlappingKey:   7,
+		OnsetOutlier:     5,
+		OnsetOutlierDays: 3,
+	}
+
+	cases := []struct {
+		name      string
+		exposures []*publishmodel.Exposure
+		previous  []*pb.RevisableKey
+		score     int
+		reasons   []Reason
+	}{
+		{
+			name: "clean",
+			exposures: []*publishmodel.Exposure{
+				{ExposureKey: []byte("a"), IntervalNumber: today - 144, IntervalCount: 144, DaysSinceSymptomOnset: onset(-1)},
+				{ExposureKey: []byte("b"), IntervalNumber: today, IntervalCount: 144, DaysSinceSymptomOnset: onset(0)},
+			},
+			score:   0,
+			reasons: []Reason{},
+		},
+		{
+			name: "future_key",
+			exposures: []*publishmodel.Exposure{
+				{ExposureKey: []byte("a"), IntervalNumber: today + 90, IntervalCount: 144, DaysSinceSymptomOnset: onset(0)},
+			},
+			score:   10,
+			reasons: []Reason{ReasonFutureKey},
+		},
+		{
+			name: "overlapping_previous",
+			exposures: []*publishmodel.Exposure{
+				{ExposureKey: []byte("a"), IntervalNumber: today - 72, IntervalCount: 144, DaysSinceSymptomOnset: onset(0)},
+			},
+			previous: []*pb.RevisableKey{
+				{TemporaryExposureKey: []byte("z"), IntervalNumber: today - 144, IntervalCount: 144},
+			},
+			score:   7,
+			reasons: []Reason{ReasonOverlappingKeys},
+		},
+		{
+			name: "same_start_previous",
+			exposures: []*publishmodel.Exposure{
+				{ExposureKey: []byte("a"), IntervalNumber: today - 144, IntervalCount: 144, DaysSinceSymptomOnset: onset(0)},
+			},
+			previous: []*pb.RevisableKey{
+				{TemporaryExposureKey: []byte("z"), IntervalNumber: today - 144, IntervalCount: 100},
+			},
+			score:   0,
+			reasons: []Reason{},
+		},
+		{
+			name: "revised_previous",
+			exposures: []*publishmodel.Exposure{
+				{ExposureKey: []byte("z"), IntervalNumber: today - 72, IntervalCount: 144, DaysSinceSymptomOnset: onset(0)},
+			},
+			previous: []*pb.RevisableKey{
+				{TemporaryExposureKey: []byte("z"), IntervalNumber: today - 144, IntervalCount: 144},
+			},
+			score:   0,
+			reasons: []Reason{},
+		},
+		{
+			name: "onset_outlier",
+			exposures: []*publishmodel.Exposure{
+				{ExposureKey: []byte("a"), IntervalNumber: today - 144, IntervalCount: 144, DaysSinceSymptomOnset: onset(-9)},
+				{ExposureKey: []byte("b"), IntervalNumber: today, IntervalCount: 144},
+			},
+			score:   5,
+			reasons: []Reason{ReasonOnsetOutlier},
+		},
+		{
+			name: "everything",
+			exposures: []*publishmodel.Exposure{
+				{ExposureKey: []byte("a"), IntervalNumber: today - 72, IntervalCount: 144},
+				{ExposureKey: []byte("b"), IntervalNumber: today + 72, IntervalCount: 144},
+			},
+			previous: []*pb.RevisableKey{
+				{TemporaryExposureKey: []byte("z"), IntervalNumber: today - 144, IntervalCount: 144},
+			},
+			score:   22,
+			reasons: []Reason{ReasonFutureKey, ReasonOnsetOutlier, ReasonOverlappingKeys},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			score, reasons := Score(tc.exposures, tc.previous, now, weights)
+			if score != tc.score {
+				t.Errorf("expected score %d, got %d", tc.score, score)
+			}
+			if !reflect.DeepEqual(reasons, tc.reasons) {
+				t.Errorf("expected reasons %v, got %v", tc.reasons, reasons)
+			}
+		})
+	}
+}
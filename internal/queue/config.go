@@ -0,0 +1,67 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import "time"
+
+// Config defines the configuration for a queue.
+type Config struct {
+	// Type is the type of queue. The default is empty, which disables
+	// queueing.
+	Type string `env:"QUEUE"`
+
+	// Timeout is the maximum amount of time to wait for a queue request.
+	Timeout time.Duration `env:"QUEUE_TIMEOUT, default=30s"`
+
+	// PubSub is the configuration for Google Cloud Pub/Sub.
+	PubSub PubSubConfig
+
+	// KafkaREST is the configuration for Kafka via a REST proxy.
+	KafkaREST KafkaRESTConfig
+}
+
+// Enabled returns true if a queue is configured.
+func (c *Config) Enabled() bool {
+	return c.Type != ""
+}
+
+// PubSubConfig is the configuration for the Google Cloud Pub/Sub queue.
+type PubSubConfig struct {
+	// Topic is the full name of the topic messages are published to, in the
+	// form "projects/<project>/topics/<topic>".
+	Topic string `env:"QUEUE_PUBSUB_TOPIC"`
+
+	// Subscription is the full name of the subscription messages are received
+	// from, in the form "projects/<project>/subscriptions/<subscription>".
+	Subscription string `env:"QUEUE_PUBSUB_SUBSCRIPTION"`
+}
+
+// KafkaRESTConfig is the configuration for the Kafka queue. Kafka is reached
+// through a Confluent compatible REST proxy (API v2).
+type KafkaRESTConfig struct {
+	// Endpoint is the URL of the REST proxy.
+	Endpoint string `env:"QUEUE_KAFKA_REST_ENDPOINT"`
+
+	// Username and Password are used for basic authentication against the REST
+	// proxy, if set.
+	Username string `env:"QUEUE_KAFKA_REST_USERNAME"`
+	Password string `env:"QUEUE_KAFKA_REST_PASSWORD"`
+
+	// Topic is the Kafka topic.
+	Topic string `env:"QUEUE_KAFKA_TOPIC"`
+
+	// ConsumerGroup is the consumer group used to receive messages.
+	ConsumerGroup string `env:"QUEUE_KAFKA_CONSUMER_GROUP, default=publish-consumer"`
+}
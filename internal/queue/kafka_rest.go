@@ -0,0 +1,326 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	kafkaContentType       = "application/vnd.kafka.v2+json"
+	kafkaBinaryContentType = "application/vnd.kafka.binary.v2+json"
+)
+
+func init() {
+	RegisterQueue("KAFKA_REST", NewKafkaREST)
+}
+
+// Compile-time check to verify implements interface.
+var _ Queue = (*KafkaREST)(nil)
+
+// KafkaREST is a queue backed by a Kafka topic, reached through a Confluent
+// compatible REST proxy. Messages are received through a consumer instance
+// that is created on first use and deleted on Close. Offsets are committed
+// when messages are acknowledged.
+type KafkaREST struct {
+	client   *http.Client
+	endpoint string
+	username string
+	password string
+	topic    string
+	group    string
+
+	lock sync.Mutex
+	// instance is the base URI of the consumer instance, if one was created.
+	instance string
+	// unacked are the offsets of the records that were received but not
+	// acknowledged, by partition, in the order they were received.
+	unacked map[int32][]int64
+}
+
+// kafkaRecord identifies a record within the topic.
+type kafkaRecord struct {
+	partition int32
+	offset    int64
+}
+
+// NewKafkaREST creates a new Kafka queue.
+func NewKafkaREST(_ context.Context, cfg *Config) (Queue, error) {
+	if cfg.KafkaREST.Endpoint == "" {
+		return nil, fmt.Errorf("QUEUE_KAFKA_REST_ENDPOINT is required")
+	}
+	if cfg.KafkaREST.Topic == "" {
+		return nil, fmt.Errorf("QUEUE_KAFKA_TOPIC is required")
+	}
+
+	return &KafkaREST{
+		client:   &http.Client{Timeout: cfg.Timeout},
+		endpoint: strings.TrimRight(cfg.KafkaREST.Endpoint, "/"),
+		username: cfg.KafkaREST.Username,
+		password: cfg.KafkaREST.Password,
+		topic:    cfg.KafkaREST.Topic,
+		group:    cfg.KafkaREST.ConsumerGroup,
+		unacked:  make(map[int32][]int64),
+	}, nil
+}
+
+// Publish produces the message to the topic.
+func (k *KafkaREST) Publish(ctx context.Context, data []byte) error {
+	body := map[string]any{
+		"records": []map[string]any{
+			{"value": data},
+		},
+	}
+
+	var resp struct {
+		Offsets []struct {
+			Partition int32  `json:"partition"`
+			Offset    int64  `json:"offset"`
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	u := fmt.Sprintf("%s/topics/%s", k.endpoint, url.PathEscape(k.topic))
+	if err := k.do(ctx, http.MethodPost, u, kafkaBinaryContentType, body, &resp); err != nil {
+		return fmt.Errorf("failed to produce to %s: %w", k.topic, err)
+	}
+	for _, o := range resp.Offsets {
+		if o.ErrorCode != nil || o.Error != "" {
+			return fmt.Errorf("failed to produce to %s: %s", k.topic, o.Error)
+		}
+	}
+	return nil
+}
+
+// Receive fetches records for the consumer instance. If records from a
+// previous call were not acknowledged, the consumer is first moved back to
+// them so they are delivered again.
+func (k *KafkaREST) Receive(ctx context.Context, max int) ([]*Message, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if err := k.ensureInstance(ctx); err != nil {
+		return nil, err
+	}
+	if err := k.rewind(ctx); err != nil {
+		return nil, err
+	}
+
+	var records []struct {
+		Topic     string `json:"topic"`
+		Value     []byte `json:"value"`
+		Partition int32  `json:"partition"`
+		Offset    int64  `json:"offset"`
+	}
+	if err := k.do(ctx, http.MethodGet, k.instance+"/records", "", nil, &records); err != nil {
+		return nil, fmt.Errorf("failed to fetch records from %s: %w", k.topic, err)
+	}
+
+	msgs := make([]*Message, 0, len(records))
+	for _, r := range records {
+		// Records past the requested maximum are still tracked, so the consumer
+		// is moved back to them on the next call.
+		k.unacked[r.Partition] = append(k.unacked[r.Partition], r.Offset)
+		if len(msgs) == max {
+			continue
+		}
+		msgs = append(msgs, &Message{
+			ID:   fmt.Sprintf("%s-%d-%d", r.Topic, r.Partition, r.Offset),
+			Data: r.Value,
+			ack:  kafkaRecord{partition: r.Partition, offset: r.Offset},
+		})
+	}
+	return msgs, nil
+}
+
+// Ack commits the offsets of the messages.
+func (k *KafkaREST) Ack(ctx context.Context, msgs []*Message) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.instance == "" || len(msgs) == 0 {
+		return nil
+	}
+
+	// The REST proxy commits the position after the given offset, so only the
+	// last acknowledged record of each partition is committed.
+	last := make(map[int32]int64)
+	for _, msg := range msgs {
+		r, ok := msg.ack.(kafkaRecord)
+		if !ok {
+			continue
+		}
+		if off, ok := last[r.partition]; !ok || r.offset > off {
+			last[r.partition] = r.offset
+		}
+	}
+
+	offsets := make([]map[string]any, 0, len(last))
+	for partition, offset := range last {
+		offsets = append(offsets, map[string]any{
+			"topic":     k.topic,
+			"partition": partition,
+			"offset":    offset,
+		})
+	}
+	body := map[string]any{"offsets": offsets}
+	if err := k.do(ctx, http.MethodPost, k.instance+"/offsets", kafkaContentType, body, nil); err != nil {
+		return fmt.Errorf("failed to commit offsets on %s: %w", k.topic, err)
+	}
+
+	for partition, offset := range last {
+		remaining := k.unacked[partition][:0]
+		for _, off := range k.unacked[partition] {
+			if off > offset {
+				remaining = append(remaining, off)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(k.unacked, partition)
+			continue
+		}
+		k.unacked[partition] = remaining
+	}
+	return nil
+}
+
+// Close deletes the consumer instance, if one was created. Records that were
+// not acknowledged are delivered to the next consumer in the group.
+func (k *KafkaREST) Close(ctx context.Context) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.instance == "" {
+		return nil
+	}
+	instance := k.instance
+	k.instance = ""
+	k.unacked = make(map[int32][]int64)
+
+	if err := k.do(ctx, http.MethodDelete, instance, kafkaContentType, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete consumer instance: %w", err)
+	}
+	return nil
+}
+
+// ensureInstance creates the consumer instance and subscribes it to the topic.
+// The caller must hold the lock.
+func (k *KafkaREST) ensureInstance(ctx context.Context) error {
+	if k.instance != "" {
+		return nil
+	}
+
+	body := map[string]any{
+		"format":             "binary",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}
+	var resp struct {
+		InstanceID string `json:"instance_id"`
+		BaseURI    string `json:"base_uri"`
+	}
+	u := fmt.Sprintf("%s/consumers/%s", k.endpoint, url.PathEscape(k.group))
+	if err := k.do(ctx, http.MethodPost, u, kafkaContentType, body, &resp); err != nil {
+		return fmt.Errorf("failed to create consumer in group %s: %w", k.group, err)
+	}
+
+	sub := map[string]any{"topics": []string{k.topic}}
+	if err := k.do(ctx, http.MethodPost, resp.BaseURI+"/subscription", kafkaContentType, sub, nil); err != nil {
+		// Don't leak the instance on the proxy.
+		_ = k.do(ctx, http.MethodDelete, resp.BaseURI, kafkaContentType, nil, nil)
+		return fmt.Errorf("failed to subscribe to %s: %w", k.topic, err)
+	}
+
+	k.instance = resp.BaseURI
+	return nil
+}
+
+// rewind moves the consumer back to the first unacknowledged record of each
+// partition. The caller must hold the lock.
+func (k *KafkaREST) rewind(ctx context.Context) error {
+	if len(k.unacked) == 0 {
+		return nil
+	}
+
+	offsets := make([]map[string]any, 0, len(k.unacked))
+	for partition, unacked := range k.unacked {
+		offsets = append(offsets, map[string]any{
+			"topic":     k.topic,
+			"partition": partition,
+			"offset":    unacked[0],
+		})
+	}
+	body := map[string]any{"offsets": offsets}
+	if err := k.do(ctx, http.MethodPost, k.instance+"/positions", kafkaContentType, body, nil); err != nil {
+		return fmt.Errorf("failed to seek on %s: %w", k.topic, err)
+	}
+	k.unacked = make(map[int32][]int64)
+	return nil
+}
+
+// do sends a request to the REST proxy and decodes the response into out, if
+// given.
+func (k *KafkaREST) do(ctx context.Context, method, u, contentType string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Accept", kafkaBinaryContentType)
+	} else {
+		req.Header.Set("Accept", kafkaContentType)
+	}
+	if k.username != "" {
+		req.SetBasicAuth(k.username, k.password)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, b)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeKafkaProxy is a REST proxy for a single partition topic.
+type fakeKafkaProxy struct {
+	lock      sync.Mutex
+	records   [][]byte
+	position  int64
+	committed int64
+	deleted   bool
+}
+
+func (f *fakeKafkaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if user, pass, _ := r.BasicAuth(); user != "user" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Records []struct {
+			Value []byte `json:"value"`
+		} `json:"records"`
+		Offsets []struct {
+			Offset int64 `json:"offset"`
+		} `json:"offsets"`
+	}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	const instance = "/consumers/group/instances/1"
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/topics/keys":
+		for _, rec := range body.Records {
+			f.records = append(f.records, rec.Value)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"offsets": []map[string]any{{"partition": 0, "offset": len(f.records) - 1}},
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/consumers/group":
+		f.position = f.committed
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"instance_id": "1",
+			"base_uri":    "http://" + r.Host + instance,
+		})
+	case r.Method == http.MethodPost && r.URL.Path == instance+"/subscription":
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == instance+"/positions":
+		f.position = body.Offsets[0].Offset
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == instance+"/offsets":
+		f.committed = body.Offsets[0].Offset + 1
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && r.URL.Path == instance+"/records":
+		out := make([]map[string]any, 0)
+		for ; f.position < int64(len(f.records)); f.position++ {
+			out = append(out, map[string]any{
+				"topic":     "keys",
+				"partition": 0,
+				"offset":    f.position,
+				"value":     f.records[f.position],
+			})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	case r.Method == http.MethodDelete && r.URL.Path == instance:
+		f.deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestKafkaREST(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	proxy := &fakeKafkaProxy{}
+	srv := httptest.NewServer(proxy)
+	t.Cleanup(srv.Close)
+
+	q, err := NewKafkaREST(ctx, &Config{
+		KafkaREST: KafkaRESTConfig{
+			Endpoint:      srv.URL,
+			Username:      "user",
+			Password:      "secret",
+			Topic:         "keys",
+			ConsumerGroup: "group",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"a", "b", "c"} {
+		if err := q.Publish(ctx, []byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receive := func(max int) []*Message {
+		t.Helper()
+		msgs, err := q.Receive(ctx, max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msgs
+	}
+	data := func(msgs []*Message) []string {
+		var got []string
+		for _, msg := range msgs {
+			got = append(got, string(msg.Data))
+		}
+		return got
+	}
+
+	msgs := receive(2)
+	if diff := cmp.Diff([]string{"a", "b"}, data(msgs)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Only the first message is acknowledged, so the consumer is moved back to
+	// the second.
+	if err := q.Ack(ctx, msgs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := proxy.committed, int64(1); got != want {
+		t.Errorf("expected committed offset %d, got %d", want, got)
+	}
+	msgs = receive(10)
+	if diff := cmp.Diff([]string{"b", "c"}, data(msgs)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Records that were not acknowledged go to the next consumer.
+	if err := q.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !proxy.deleted {
+		t.Errorf("expected consumer instance to be deleted")
+	}
+	msgs = receive(10)
+	if diff := cmp.Diff([]string{"b", "c"}, data(msgs)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if err := q.Ack(ctx, msgs); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := proxy.committed, int64(3); got != want {
+		t.Errorf("expected committed offset %d, got %d", want, got)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+func init() {
+	RegisterQueue("MEMORY", NewMemory)
+}
+
+// Compile-time check to verify implements interface.
+var _ Queue = (*Memory)(nil)
+
+// Memory is an in-process queue. It is only useful when the publisher and the
+// consumer run in the same process, such as in the monolith or in tests.
+type Memory struct {
+	lock     sync.Mutex
+	nextID   int
+	pending  []*Message
+	inflight []*Message
+}
+
+// NewMemory creates a new in-memory queue.
+func NewMemory(_ context.Context, _ *Config) (Queue, error) {
+	return &Memory{}, nil
+}
+
+// Publish adds a message to the end of the queue.
+func (m *Memory) Publish(_ context.Context, data []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.nextID++
+	m.pending = append(m.pending, &Message{
+		ID:   strconv.Itoa(m.nextID),
+		Data: append([]byte(nil), data...),
+	})
+	return nil
+}
+
+// Receive returns up to max messages. Messages from a previous call that were
+// not acknowledged are returned first.
+func (m *Memory) Receive(_ context.Context, max int) ([]*Message, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.pending = append(m.inflight, m.pending...)
+	m.inflight = nil
+
+	if max > len(m.pending) {
+		max = len(m.pending)
+	}
+	msgs := make([]*Message, max)
+	copy(msgs, m.pending)
+	m.pending = m.pending[max:]
+	m.inflight = append(m.inflight, msgs...)
+	return msgs, nil
+}
+
+// Ack removes the messages from the queue.
+func (m *Memory) Ack(_ context.Context, msgs []*Message) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	acked := make(map[string]struct{}, len(msgs))
+	for _, msg := range msgs {
+		acked[msg.ID] = struct{}{}
+	}
+
+	inflight := m.inflight[:0]
+	for _, msg := range m.inflight {
+		if _, ok := acked[msg.ID]; !ok {
+			inflight = append(inflight, msg)
+		}
+	}
+	m.inflight = inflight
+	return nil
+}
+
+// Close does nothing.
+func (m *Memory) Close(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	q, err := NewMemory(ctx, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"a", "b", "c"} {
+		if err := q.Publish(ctx, []byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receive := func(max int) []*Message {
+		t.Helper()
+		msgs, err := q.Receive(ctx, max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msgs
+	}
+	data := func(msgs []*Message) []string {
+		var got []string
+		for _, msg := range msgs {
+			got = append(got, string(msg.Data))
+		}
+		return got
+	}
+
+	msgs := receive(2)
+	if diff := cmp.Diff([]string{"a", "b"}, data(msgs)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Only the first message is acknowledged, so the second is delivered
+	// again.
+	if err := q.Ack(ctx, msgs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	msgs = receive(10)
+	if diff := cmp.Diff([]string{"b", "c"}, data(msgs)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if err := q.Ack(ctx, msgs); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := receive(10); len(msgs) != 0 {
+		t.Errorf("expected no messages, got %v", data(msgs))
+	}
+}
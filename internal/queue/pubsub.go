@@ -0,0 +1,151 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build google || all
+
+package queue
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+func init() {
+	RegisterQueue("GOOGLE_PUBSUB", NewPubSub)
+}
+
+// Compile-time check to verify implements interface.
+var _ Queue = (*PubSub)(nil)
+
+// PubSub is a queue backed by a Google Cloud Pub/Sub topic and subscription.
+type PubSub struct {
+	svc          *pubsub.Service
+	topic        string
+	subscription string
+	timeout      time.Duration
+}
+
+// NewPubSub creates a new Pub/Sub queue. A topic is required to publish and a
+// subscription is required to receive, so services that only do one of them
+// may leave the other unset.
+func NewPubSub(ctx context.Context, cfg *Config) (Queue, error) {
+	if cfg.PubSub.Topic == "" && cfg.PubSub.Subscription == "" {
+		return nil, fmt.Errorf("QUEUE_PUBSUB_TOPIC or QUEUE_PUBSUB_SUBSCRIPTION is required")
+	}
+
+	svc, err := pubsub.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub.NewService: %w", err)
+	}
+
+	return &PubSub{
+		svc:          svc,
+		topic:        cfg.PubSub.Topic,
+		subscription: cfg.PubSub.Subscription,
+		timeout:      cfg.Timeout,
+	}, nil
+}
+
+// Publish publishes the message to the topic.
+func (p *PubSub) Publish(ctx context.Context, data []byte) error {
+	if p.topic == "" {
+		return fmt.Errorf("no pubsub topic configured")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	req := &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{
+			{Data: base64.StdEncoding.EncodeToString(data)},
+		},
+	}
+	if _, err := p.svc.Projects.Topics.Publish(p.topic, req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+// Receive pulls up to max messages from the subscription.
+func (p *PubSub) Receive(ctx context.Context, max int) ([]*Message, error) {
+	if p.subscription == "" {
+		return nil, fmt.Errorf("no pubsub subscription configured")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	req := &pubsub.PullRequest{MaxMessages: int64(max)}
+	resp, err := p.svc.Projects.Subscriptions.Pull(p.subscription, req).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull from %s: %w", p.subscription, err)
+	}
+
+	msgs := make([]*Message, 0, len(resp.ReceivedMessages))
+	for _, rm := range resp.ReceivedMessages {
+		if rm.Message == nil {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(rm.Message.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode message %s: %w", rm.Message.MessageId, err)
+		}
+		msgs = append(msgs, &Message{
+			ID:   rm.Message.MessageId,
+			Data: data,
+			ack:  rm.AckId,
+		})
+	}
+	return msgs, nil
+}
+
+// Ack acknowledges the messages on the subscription.
+func (p *PubSub) Ack(ctx context.Context, msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		if id, ok := msg.ack.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	req := &pubsub.AcknowledgeRequest{AckIds: ids}
+	if _, err := p.svc.Projects.Subscriptions.Acknowledge(p.subscription, req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to acknowledge on %s: %w", p.subscription, err)
+	}
+	return nil
+}
+
+// Close does nothing. Unacknowledged messages are delivered again after the
+// subscription's acknowledgement deadline.
+func (p *PubSub) Close(_ context.Context) error {
+	return nil
+}
+
+func (p *PubSub) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout > 0 {
+		return context.WithTimeout(ctx, p.timeout)
+	}
+	return context.WithCancel(ctx)
+}
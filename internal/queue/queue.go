@@ -0,0 +1,107 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queue is an interface over message queues that carry work between
+// services.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Message is a message received from a queue.
+type Message struct {
+	// ID identifies the message within the queue.
+	ID string
+
+	// Data is the message payload.
+	Data []byte
+
+	// ack is the implementation specific handle used to acknowledge the
+	// message.
+	ack any
+}
+
+// Queue publishes messages and receives them for processing. Delivery is at
+// least once: a message is delivered again until it is acknowledged.
+type Queue interface {
+	// Publish adds a message to the queue.
+	Publish(ctx context.Context, data []byte) error
+
+	// Receive returns up to max messages, waiting briefly for messages to
+	// arrive. It returns an empty list if none are available.
+	Receive(ctx context.Context, max int) ([]*Message, error)
+
+	// Ack acknowledges messages that were processed, so they are not delivered
+	// again. Messages that are received but never acknowledged are delivered
+	// again later. Callers must acknowledge messages in the order they were
+	// received, and stop at the first message that could not be processed,
+	// because some queues only track the position in the queue.
+	Ack(ctx context.Context, msgs []*Message) error
+
+	// Close releases any resources held by the queue.
+	Close(ctx context.Context) error
+}
+
+// QueueFunc is a func that returns a queue or error.
+type QueueFunc func(context.Context, *Config) (Queue, error)
+
+// queues is the list of registered queues.
+var (
+	queues     = make(map[string]QueueFunc)
+	queuesLock sync.RWMutex
+)
+
+// RegisterQueue registers a new queue with the given name. If a queue is
+// already registered with the given name, it panics. Queues are usually
+// registered via an init function.
+func RegisterQueue(name string, fn QueueFunc) {
+	queuesLock.Lock()
+	defer queuesLock.Unlock()
+
+	if _, ok := queues[name]; ok {
+		panic(fmt.Sprintf("queue %q is already registered", name))
+	}
+	queues[name] = fn
+}
+
+// RegisteredQueues returns the list of the names of the registered queues.
+func RegisteredQueues() []string {
+	queuesLock.RLock()
+	defer queuesLock.RUnlock()
+
+	list := make([]string, 0, len(queues))
+	for k := range queues {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// QueueFor returns the queue with the given name, or an error if one does not
+// exist.
+func QueueFor(ctx context.Context, cfg *Config) (Queue, error) {
+	queuesLock.RLock()
+	defer queuesLock.RUnlock()
+
+	name := cfg.Type
+	fn, ok := queues[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown or uncompiled queue %q", name)
+	}
+	return fn(ctx, cfg)
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package region validates and normalizes the regions of authorized apps,
+// export configs and federation queries.
+//
+// Regions are ISO 3166-1 alpha-2 country codes, e.g. "US", or ISO 3166-2
+// subdivision codes, e.g. "US-WA". Keys are matched to exports by exact
+// region, so a region like "USA" results in an empty export rather than an
+// error. Deployments that use other regions list them in CUSTOM_REGIONS.
+package region
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+// subdivisionRegexp matches ISO 3166-2 subdivision codes, a country code and
+// up to three letters or digits.
+var subdivisionRegexp = regexp.MustCompile(`\A([A-Z]{2})-[A-Z0-9]{1,3}\z`)
+
+// aliases are codes that are commonly used in place of the ISO 3166-1 code.
+var aliases = map[string]string{
+	"UK": "GB",
+	"EL": "GR",
+}
+
+// Config is the configuration for region validation.
+type Config struct {
+	// CustomRegions are regions that are accepted in addition to ISO 3166
+	// codes, e.g. "TEST".
+	CustomRegions []string `env:"CUSTOM_REGIONS"`
+
+	// Retention overrides how long the keys of a region are kept, e.g.
+	// "US-WA:240h". The override sets the maximum key age on publish, the
+	// export index window and the cleanup TTL of the region together. Regions
+	// without an override use the defaults of each component.
+	Retention map[string]time.Duration `env:"REGION_RETENTION"`
+}
+
+// Validator validates and normalizes regions.
+type Validator struct {
+	custom map[string]struct{}
+}
+
+// New creates a validator that accepts ISO 3166 codes and the custom regions
+// of the config.
+func New(config *Config) *Validator {
+	custom := make(map[string]struct{}, len(config.CustomRegions))
+	for _, r := range config.CustomRegions {
+		if r = normalize(r); r != "" {
+			custom[r] = struct{}{}
+		}
+	}
+	return &Validator{custom: custom}
+}
+
+// normalize trims and upper cases the region.
+func normalize(r string) string {
+	return strings.ToUpper(project.TrimSpaceAndNonPrintable(r))
+}
+
+// IsKnown returns true if the normalized region is an ISO 3166-1 alpha-2 or
+// ISO 3166-2 code, or a custom region.
+func (v *Validator) IsKnown(r string) bool {
+	if _, ok := v.custom[r]; ok {
+		return true
+	}
+	if _, ok := countries[r]; ok {
+		return true
+	}
+	if m := subdivisionRegexp.FindStringSubmatch(r); m != nil {
+		_, ok := countries[m[1]]
+		return ok
+	}
+	return false
+}
+
+// Normalize trims and upper cases the region, and returns an error if it is
+// not known. The error suggests the ISO 3166-1 alpha-2 code when the region
+// is an alpha-3 code or a common alias.
+func (v *Validator) Normalize(r string) (string, error) {
+	r = normalize(r)
+	if r == "" {
+		return "", fmt.Errorf("region cannot be empty")
+	}
+	if !v.IsKnown(r) {
+		return "", unknownError(r)
+	}
+	return r, nil
+}
+
+// NormalizeAll normalizes the regions, dropping blank ones. The error lists
+// every unknown region.
+func (v *Validator) NormalizeAll(regions []string) ([]string, error) {
+	ret := make([]string, 0, len(regions))
+	var errs []string
+	for _, r := range regions {
+		if r = normalize(r); r == "" {
+			continue
+		}
+		if !v.IsKnown(r) {
+			errs = append(errs, unknownError(r).Error())
+			continue
+		}
+		ret = append(ret, r)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return ret, nil
+}
+
+// Unknown returns the regions that are not known, without normalizing them.
+// Blank regions are ignored. It is used to warn about regions that were saved
+// before validation, or that come from other servers.
+func (v *Validator) Unknown(regions ...string) []string {
+	var unknown []string
+	for _, r := range regions {
+		if r != "" && !v.IsKnown(r) {
+			unknown = append(unknown, r)
+		}
+	}
+	return unknown
+}
+
+// Suggest returns the ISO 3166-1 alpha-2 code that was likely meant by the
+// normalized region, or "" if there is none.
+func Suggest(r string) string {
+	if a2, ok := alpha3[r]; ok {
+		return a2
+	}
+	return aliases[r]
+}
+
+func unknownError(r string) error {
+	if s := Suggest(r); s != "" {
+		return fmt.Errorf("unknown region %q, did you mean %q?", r, s)
+	}
+	return fmt.Errorf("unknown region %q, must be an ISO 3166-1 alpha-2 or ISO 3166-2 code, or listed in CUSTOM_REGIONS", r)
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package region
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCountries(t *testing.T) {
+	t.Parallel()
+
+	if got, want := len(countries), 249; got != want {
+		t.Errorf("expected %d countries, got %d", want, got)
+	}
+	if got, want := len(alpha3), len(countries); got != want {
+		t.Errorf("expected %d alpha-3 codes, got %d", want, got)
+	}
+}
+
+func TestValidator_Normalize(t *testing.T) {
+	t.Parallel()
+
+	v := New(&Config{CustomRegions: []string{" test ", "eu"}})
+
+	cases := []struct {
+		region string
+		want   string
+		err    string
+	}{
+		{region: "US", want: "US"},
+		{region: " ca\n", want: "CA"},
+		{region: "us-wa", want: "US-WA"},
+		{region: "FR-75C", want: "FR-75C"},
+		{region: "TEST", want: "TEST"},
+		{region: "Eu", want: "EU"},
+		{region: "", err: "region cannot be empty"},
+		{region: "USA", err: `unknown region "USA", did you mean "US"?`},
+		{region: "uk", err: `unknown region "UK", did you mean "GB"?`},
+		{region: "XX", err: `unknown region "XX", must be`},
+		{region: "XX-WA", err: `unknown region "XX-WA"`},
+		{region: "US-WASH", err: `unknown region "US-WASH"`},
+		{region: "NOT-TEST", err: `unknown region "NOT-TEST"`},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.region, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := v.Normalize(tc.region)
+			errcmp.MustMatch(t, err, tc.err)
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidator_NormalizeAll(t *testing.T) {
+	t.Parallel()
+
+	v := New(&Config{})
+
+	got, err := v.NormalizeAll([]string{"us", "", " CA ", "mx-cmx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"US", "CA", "MX-CMX"}, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	_, err = v.NormalizeAll([]string{"USA", "US", "CAN"})
+	errcmp.MustMatch(t, err, `unknown region "USA", did you mean "US"?, unknown region "CAN", did you mean "CA"?`)
+}
+
+func TestValidator_Unknown(t *testing.T) {
+	t.Parallel()
+
+	v := New(&Config{CustomRegions: []string{"TEST"}})
+
+	got := v.Unknown("US", "USA", "", "TEST", "us")
+	if diff := cmp.Diff([]string{"USA", "us"}, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
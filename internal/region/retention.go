@@ -0,0 +1,90 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package region
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Retention is the per-region retention of keys.
+type Retention struct {
+	overrides map[string]time.Duration
+}
+
+// NewRetention creates the retention of the config. It returns an error if an
+// override is not positive.
+func NewRetention(config *Config) (*Retention, error) {
+	overrides := make(map[string]time.Duration, len(config.Retention))
+	for r, d := range config.Retention {
+		r = normalize(r)
+		if r == "" {
+			continue
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("retention of region %q must be positive, got %s", r, d)
+		}
+		overrides[r] = d
+	}
+	return &Retention{overrides: overrides}, nil
+}
+
+// For returns the retention of keys in the regions, which is the shortest
+// override of the regions. Regions without an override use def.
+func (r *Retention) For(def time.Duration, regions ...string) time.Duration {
+	if r == nil || len(regions) == 0 {
+		return def
+	}
+
+	ret := time.Duration(0)
+	for _, region := range regions {
+		d, ok := r.overrides[normalize(region)]
+		if !ok {
+			d = def
+		}
+		if ret == 0 || d < ret {
+			ret = d
+		}
+	}
+	return ret
+}
+
+// Shortest returns the shortest of def and the overrides.
+func (r *Retention) Shortest(def time.Duration) time.Duration {
+	ret := def
+	if r == nil {
+		return ret
+	}
+	for _, d := range r.overrides {
+		if d < ret {
+			ret = d
+		}
+	}
+	return ret
+}
+
+// Overrides returns the regions with an override, sorted.
+func (r *Retention) Overrides() []string {
+	if r == nil {
+		return nil
+	}
+	ret := make([]string, 0, len(r.overrides))
+	for region := range r.overrides {
+		ret = append(ret, region)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package region
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewRetention(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRetention(&Config{Retention: map[string]time.Duration{"US-WA": 0}})
+	errcmp.MustMatch(t, err, `retention of region "US-WA" must be positive`)
+
+	r, err := NewRetention(&Config{Retention: map[string]time.Duration{" us-wa ": 240 * time.Hour, "": time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"US-WA"}, r.Overrides()); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRetention_For(t *testing.T) {
+	t.Parallel()
+
+	const def = 14 * 24 * time.Hour
+
+	r, err := NewRetention(&Config{Retention: map[string]time.Duration{
+		"US-WA": 10 * 24 * time.Hour,
+		"US-OR": 21 * 24 * time.Hour,
+		"US-ID": 28 * 24 * time.Hour,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		regions []string
+		want    time.Duration
+	}{
+		{name: "no_regions", want: def},
+		{name: "default", regions: []string{"US-CA"}, want: def},
+		{name: "shorter", regions: []string{"us-wa"}, want: 10 * 24 * time.Hour},
+		{name: "longer", regions: []string{"US-OR"}, want: 21 * 24 * time.Hour},
+		{name: "shortest_override", regions: []string{"US-ID", "US-OR"}, want: 21 * 24 * time.Hour},
+		{name: "shortest_default", regions: []string{"US-OR", "US-CA"}, want: def},
+		{name: "shortest_of_all", regions: []string{"US-OR", "US-CA", "US-WA"}, want: 10 * 24 * time.Hour},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := r.For(def, tc.regions...); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	if got, want := r.Shortest(def), 10*24*time.Hour; got != want {
+		t.Errorf("Shortest: expected %s, got %s", want, got)
+	}
+
+	var none *Retention
+	if got := none.For(def, "US-WA"); got != def {
+		t.Errorf("nil For: expected %s, got %s", def, got)
+	}
+	if got := none.Shortest(def); got != def {
+		t.Errorf("nil Shortest: expected %s, got %s", def, got)
+	}
+}
@@ -25,6 +25,17 @@ type Config struct {
 	KeyID     string      `env:"REVISION_TOKEN_KEY_ID"`
 	AAD       Base64Bytes `env:"REVISION_TOKEN_AAD"` // must be base64 encoded, may come from secret://
 	MinLength uint        `env:"REVISION_TOKEN_MIN_LENGTH, default=28"`
+
+	// Version is the version of the revision tokens that are issued. Version
+	// 2 tokens are smaller, and carry their issue time and an optional device
+	// nonce. Tokens of both versions are accepted, so all instances must be
+	// able to read version 2 tokens before they are issued. 0 is version 1.
+	Version int `env:"REVISION_TOKEN_VERSION, default=1"`
+
+	// RejectV1 rejects version 1 tokens. Once version 2 tokens have been
+	// issued for longer than keys are retained in tokens, version 1 tokens
+	// are no longer needed.
+	RejectV1 bool `env:"REVISION_TOKEN_REJECT_V1, default=false"`
 }
 
 // Base64Bytes is a type that parses a base64-encoded string into a []byte.
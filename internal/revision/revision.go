@@ -66,11 +66,39 @@ type TokenManager struct {
 	// the keys are unwrapped.
 	cacheDuration     time.Duration
 	cacheRefreshAfter time.Time
+
+	// The version of the tokens that are issued, and whether version 1 tokens
+	// are accepted.
+	version  int
+	acceptV1 bool
+}
+
+// Option configures a TokenManager.
+type Option func(*TokenManager)
+
+// WithVersion sets the version of the tokens that are issued. The default, and
+// version 0, is version 1.
+func WithVersion(version int) Option {
+	return func(tm *TokenManager) {
+		if version == 0 {
+			version = TokenV1
+		}
+		tm.version = version
+	}
+}
+
+// WithAcceptV1 sets whether version 1 tokens are accepted. They are accepted
+// by default, so that tokens issued before a migration to version 2 can still
+// be used.
+func WithAcceptV1(accept bool) Option {
+	return func(tm *TokenManager) {
+		tm.acceptV1 = accept
+	}
 }
 
 // New creates a new TokenManager that uses a database handle to manage a cache
 // of allowed revision keys.
-func New(ctx context.Context, db *database.RevisionDB, cacheDuration time.Duration, minTokenSize uint) (*TokenManager, error) {
+func New(ctx context.Context, db *database.RevisionDB, cacheDuration time.Duration, minTokenSize uint, opts ...Option) (*TokenManager, error) {
 	if cacheDuration > 60*time.Minute {
 		return nil, fmt.Errorf("cache duration must be <= 60 minutes, got: %v", cacheDuration)
 	}
@@ -81,6 +109,17 @@ func New(ctx context.Context, db *database.RevisionDB, cacheDuration time.Durati
 		minTokenSize:      int(minTokenSize),
 		cacheDuration:     cacheDuration,
 		cacheRefreshAfter: now.Add(-2 * cacheDuration),
+		version:           TokenV1,
+		acceptV1:          true,
+	}
+	for _, opt := range opts {
+		opt(tm)
+	}
+	if tm.version != TokenV1 && tm.version != TokenV2 {
+		return nil, fmt.Errorf("unsupported revision token version %d", tm.version)
+	}
+	if tm.version == TokenV1 && !tm.acceptV1 {
+		return nil, fmt.Errorf("version 1 tokens must be accepted while they are issued")
 	}
 	if err := tm.maybeRefreshCache(ctx); err != nil {
 		return nil, err
@@ -183,25 +222,34 @@ func buildTokenBufer(previous *pb.RevisionTokenData, eKeys []*model.Exposure) *p
 	tokenData := pb.RevisionTokenData{
 		RevisableKeys: make([]*pb.RevisableKey, 0, len(eKeys)),
 	}
-	got := make(map[string]struct{})
+	got := make(map[string]*pb.RevisableKey)
 
 	// Add in previous keys from the revision token. This needs to come first so
 	// the revision token is valid for all keys, not just the ones uploaded now.
 	if previous != nil {
+		tokenData.ChunkedUpload = previous.ChunkedUpload
 		for _, rk := range previous.RevisableKeys {
-			got[base64.StdEncoding.EncodeToString(rk.TemporaryExposureKey)] = struct{}{}
+			got[base64.StdEncoding.EncodeToString(rk.TemporaryExposureKey)] = rk
 			tokenData.RevisableKeys = append(tokenData.RevisableKeys, rk)
 		}
 	}
 
 	// Now add new keys and their metadata, iff they aren't already in the list.
+	// Same day keys that were extended keep their new interval count.
 	for _, k := range eKeys {
-		if _, ok := got[k.ExposureKeyBase64()]; !ok {
-			tokenData.RevisableKeys = append(tokenData.RevisableKeys, &pb.RevisableKey{
+		rk, ok := got[k.ExposureKeyBase64()]
+		if !ok {
+			rk = &pb.RevisableKey{
 				TemporaryExposureKey: append([]byte{}, k.ExposureKey...), // deep copy
 				IntervalNumber:       k.IntervalNumber,
 				IntervalCount:        k.IntervalCount,
-			})
+			}
+			got[k.ExposureKeyBase64()] = rk
+			tokenData.RevisableKeys = append(tokenData.RevisableKeys, rk)
+			continue
+		}
+		if rk.IntervalNumber == k.IntervalNumber && rk.IntervalCount < k.IntervalCount {
+			rk.IntervalCount = k.IntervalCount
 		}
 	}
 
@@ -209,9 +257,12 @@ func buildTokenBufer(previous *pb.RevisionTokenData, eKeys []*model.Exposure) *p
 }
 
 // MakeRevisionToken turns the TEK data from a given publish request
-// into an encrypted protocol buffer revision token.
+// into an encrypted revision token, in the configured version.
 // This is using envelope encryption, based on the currently active revision key.
-func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.RevisionTokenData, eKeys []*model.Exposure, aad []byte) ([]byte, error) {
+//
+// If deviceNonce is not empty, version 2 tokens are bound to it. It is ignored
+// for version 1 tokens.
+func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.RevisionTokenData, eKeys []*model.Exposure, aad, deviceNonce []byte) ([]byte, error) {
 	if len(eKeys) == 0 && (previous == nil || len(previous.RevisableKeys) == 0) {
 		return nil, fmt.Errorf("no keys or previous keys for which to build revision token")
 	}
@@ -221,15 +272,23 @@ func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.Revi
 	}
 	// Capture DEK and KID in read lock, but don't do encryption with the lock
 	var dek []byte
-	var kid string
+	var kid int64
 	{
 		tm.mu.RLock()
 		dek = tm.effective.DEK
-		kid = tm.effective.KeyIDString()
+		kid = tm.effective.KeyID
 		tm.mu.RUnlock()
 	}
 
 	tokenData := buildTokenBufer(previous, eKeys)
+	if tm.version == TokenV2 {
+		return tm.makeTokenV2(dek, kid, tokenData, aad, deviceNonce)
+	}
+	return tm.makeTokenV1(dek, kid, tokenData, aad)
+}
+
+// makeTokenV1 encrypts the token data into a version 1 token.
+func (tm *TokenManager) makeTokenV1(dek []byte, kid int64, tokenData *pb.RevisionTokenData, aad []byte) ([]byte, error) {
 	// Padd the revisable keys out w/ the zero key.
 	for len(tokenData.RevisableKeys) < tm.minTokenSize {
 		tokenData.RevisableKeys = append(tokenData.RevisableKeys, &zeroTEK)
@@ -240,7 +299,48 @@ func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.Revi
 		return nil, fmt.Errorf("failed to marshal token data: %w", err)
 	}
 
-	// encrypt the serialized proto.
+	ciphertext, err := seal(dek, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the revision token.
+	token := pb.RevisionToken{
+		Kid:  strconv.FormatInt(kid, 10),
+		Data: ciphertext,
+	}
+	tokenBytes, err := proto.Marshal(&token)
+	if err != nil {
+		return nil, fmt.Errorf("faield to marshal token: %w", err)
+	}
+
+	return tokenBytes, nil
+}
+
+// makeTokenV2 encrypts the token data into a version 2 token.
+func (tm *TokenManager) makeTokenV2(dek []byte, kid int64, tokenData *pb.RevisionTokenData, aad, deviceNonce []byte) ([]byte, error) {
+	plaintext, err := marshalTokenV2Data(tokenData, deviceNonce, tm.minTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token data: %w", err)
+	}
+
+	header := marshalTokenV2Header(kid, time.Now())
+	ciphertext, err := seal(dek, plaintext, tokenV2AAD(aad, header))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ciphertext...), nil
+}
+
+// tokenV2AAD returns the additional authenticated data of a version 2 token,
+// which covers its header.
+func tokenV2AAD(aad, header []byte) []byte {
+	return append(append(make([]byte, 0, len(aad)+len(header)), aad...), header...)
+}
+
+// seal encrypts the plaintext with the DEK and returns the nonce followed by
+// the ciphertext.
+func seal(dek, plaintext, aad []byte) ([]byte, error) {
 	block, err := aes.NewCipher(dek)
 	if err != nil {
 		return nil, fmt.Errorf("bad cipher block: %w", err)
@@ -253,19 +353,32 @@ func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.Revi
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
-	ciphertext := aesgcm.Seal(nonce, nonce, plaintext, aad)
+	return aesgcm.Seal(nonce, nonce, plaintext, aad), nil
+}
 
-	// Build the revision token.
-	token := pb.RevisionToken{
-		Kid:  kid,
-		Data: ciphertext,
+// open decrypts data produced by seal.
+func open(dek, data, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher from dek: %w", err)
 	}
-	tokenBytes, err := proto.Marshal(&token)
+
+	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return nil, fmt.Errorf("faield to marshal token: %w", err)
+		return nil, fmt.Errorf("failed to create gcm from dek: %w", err)
 	}
 
-	return tokenBytes, nil
+	size := aesgcm.NonceSize()
+	if len(data) < size {
+		return nil, fmt.Errorf("malformed ciphertext")
+	}
+	nonce, ciphertext := data[:size], data[size:]
+
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt ciphertext with dek: %w", err)
+	}
+	return plaintext, nil
 }
 
 // UnmarshalRevisionToken unmarshals a revision token, decrypts the payload,
@@ -273,67 +386,117 @@ func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.Revi
 //
 // The incoming key ID is used to determine if this token can still be unlocked.
 func (tm *TokenManager) UnmarshalRevisionToken(ctx context.Context, tokenBytes []byte, aad []byte) (*pb.RevisionTokenData, error) {
+	token, err := tm.ParseRevisionToken(ctx, tokenBytes, aad)
+	if err != nil {
+		return nil, err
+	}
+	return token.Data, nil
+}
+
+// ParseRevisionToken unmarshals and decrypts a revision token of either
+// version, and returns it if valid. Version 1 tokens are rejected if they are
+// no longer accepted.
+func (tm *TokenManager) ParseRevisionToken(ctx context.Context, tokenBytes []byte, aad []byte) (*Token, error) {
 	if err := tm.maybeRefreshCache(ctx); err != nil {
 		return nil, err
 	}
 
+	if isTokenV2(tokenBytes) {
+		return tm.parseTokenV2(tokenBytes, aad)
+	}
+	if !tm.acceptV1 {
+		return nil, fmt.Errorf("version 1 tokens are no longer accepted")
+	}
+	return tm.parseTokenV1(tokenBytes, aad)
+}
+
+// parseTokenV1 decrypts a version 1 token.
+func (tm *TokenManager) parseTokenV1(tokenBytes []byte, aad []byte) (*Token, error) {
 	var revisionToken pb.RevisionToken
 	if err := proto.Unmarshal(tokenBytes, &revisionToken); err != nil {
 		return nil, fmt.Errorf("unable to unmarshal proto envelope: %w", err)
 	}
-	data := revisionToken.Data
 	kid, err := strconv.ParseInt(revisionToken.Kid, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid key id: %w", err)
 	}
 
-	var dek []byte
-	// Capture the DEK under read lock, but don't hold lock for decryption.
-	{
-		tm.mu.RLock()
-		defer tm.mu.RUnlock()
-		rk, ok := tm.allowed[kid]
-		if !ok {
-			return nil, fmt.Errorf("token has invalid key id: %v", revisionToken.Kid)
-		}
-		dek = rk.DEK
+	dek, err := tm.allowedDEK(kid)
+	if err != nil {
+		return nil, err
 	}
 
-	// Decrypt the data block.
-	block, err := aes.NewCipher(dek)
+	plaintext, err := open(dek, revisionToken.Data, aad)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher from dek: %w", err)
+		return nil, err
 	}
 
-	aesgcm, err := cipher.NewGCM(block)
+	// The plaintext is a pb.RevisionTokenData
+	var paddedTokenData pb.RevisionTokenData
+	if err := proto.Unmarshal(plaintext, &paddedTokenData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token data: %w", err)
+	}
+
+	return &Token{
+		Version: TokenV1,
+		KeyID:   kid,
+		Data:    withoutPadding(&paddedTokenData),
+	}, nil
+}
+
+// parseTokenV2 decrypts a version 2 token.
+func (tm *TokenManager) parseTokenV2(tokenBytes []byte, aad []byte) (*Token, error) {
+	kid, issuedAt, header, data, err := parseTokenV2Header(tokenBytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gcm from dek: %w", err)
+		return nil, err
 	}
 
-	size := aesgcm.NonceSize()
-	if len(data) < size {
-		return nil, fmt.Errorf("malformed ciphertext")
+	dek, err := tm.allowedDEK(kid)
+	if err != nil {
+		return nil, err
 	}
-	nonce, ciphertext := data[:size], data[size:]
 
-	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, aad)
+	plaintext, err := open(dek, data, tokenV2AAD(aad, header))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt ciphertext with dek: %w", err)
+		return nil, err
 	}
 
-	// The plaintext is a pb.RevisionTokenData
-	var paddedTokenData pb.RevisionTokenData
-	if err := proto.Unmarshal(plaintext, &paddedTokenData); err != nil {
+	tokenData, deviceNonce, err := unmarshalTokenV2Data(plaintext)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal token data: %w", err)
 	}
 
-	var tokenData pb.RevisionTokenData
-	for _, rk := range paddedTokenData.RevisableKeys {
+	return &Token{
+		Version:     TokenV2,
+		KeyID:       kid,
+		IssuedAt:    issuedAt,
+		DeviceNonce: deviceNonce,
+		Data:        withoutPadding(tokenData),
+	}, nil
+}
+
+// allowedDEK returns the DEK of the allowed revision key with the given ID.
+func (tm *TokenManager) allowedDEK(kid int64) ([]byte, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	rk, ok := tm.allowed[kid]
+	if !ok {
+		return nil, fmt.Errorf("token has invalid key id: %v", kid)
+	}
+	return rk.DEK, nil
+}
+
+// withoutPadding returns the token data without the zero keys that pad it.
+func withoutPadding(padded *pb.RevisionTokenData) *pb.RevisionTokenData {
+	tokenData := pb.RevisionTokenData{
+		ChunkedUpload: padded.ChunkedUpload,
+	}
+	for _, rk := range padded.RevisableKeys {
 		if rk.IntervalNumber == 0 && rk.IntervalCount == 0 {
 			continue
 		}
 		tokenData.RevisableKeys = append(tokenData.RevisableKeys, rk)
 	}
-
-	return &tokenData, nil
+	return &tokenData
 }
@@ -142,6 +142,57 @@ func TestBuildTokenBuffer(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "merge_extends_same_day_key",
+			publish: []*model.Exposure{
+				{
+					ExposureKey:    []byte{4, 3, 2, 1},
+					IntervalNumber: 200144,
+					IntervalCount:  144,
+				},
+			},
+			previous: &pb.RevisionTokenData{
+				RevisableKeys: []*pb.RevisableKey{
+					{
+						TemporaryExposureKey: []byte{4, 3, 2, 1},
+						IntervalNumber:       200144,
+						IntervalCount:        60,
+					},
+				},
+			},
+			want: &pb.RevisionTokenData{
+				RevisableKeys: []*pb.RevisableKey{
+					{
+						TemporaryExposureKey: []byte{4, 3, 2, 1},
+						IntervalNumber:       200144,
+						IntervalCount:        144,
+					},
+				},
+			},
+		},
+		{
+			name: "keeps_chunked_upload",
+			publish: []*model.Exposure{
+				{
+					ExposureKey:    []byte{1, 2, 3, 4},
+					IntervalNumber: 200000,
+					IntervalCount:  144,
+				},
+			},
+			previous: &pb.RevisionTokenData{
+				ChunkedUpload: &pb.ChunkedUpload{Chunk: 1, Of: 2},
+			},
+			want: &pb.RevisionTokenData{
+				RevisableKeys: []*pb.RevisableKey{
+					{
+						TemporaryExposureKey: []byte{1, 2, 3, 4},
+						IntervalNumber:       200000,
+						IntervalCount:        144,
+					},
+				},
+				ChunkedUpload: &pb.ChunkedUpload{Chunk: 1, Of: 2},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -151,7 +202,7 @@ func TestBuildTokenBuffer(t *testing.T) {
 			t.Parallel()
 
 			got := buildTokenBufer(tc.previous, tc.publish)
-			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(pb.RevisionTokenData{}), cmpopts.IgnoreUnexported(pb.RevisableKey{})); diff != "" {
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(pb.RevisionTokenData{}), cmpopts.IgnoreUnexported(pb.RevisableKey{}), cmpopts.IgnoreUnexported(pb.ChunkedUpload{})); diff != "" {
 				t.Fatalf("mismatch (-want, +got):\n%s", diff)
 			}
 		})
@@ -217,7 +268,7 @@ func TestEncryptDecrypt(t *testing.T) {
 		},
 	}
 
-	encrypted, err := tm.MakeRevisionToken(ctx, nil, source, aad)
+	encrypted, err := tm.MakeRevisionToken(ctx, nil, source, aad, nil)
 	if err != nil {
 		t.Fatalf("error encrypting and serializing data: %v", err)
 	}
@@ -301,7 +352,7 @@ func TestExpandRevisionToken(t *testing.T) {
 		},
 	}
 
-	encrypted, err := tm.MakeRevisionToken(ctx, previousToken, source, aad)
+	encrypted, err := tm.MakeRevisionToken(ctx, previousToken, source, aad, nil)
 	if err != nil {
 		t.Fatalf("error encrypting and serializing data: %v", err)
 	}
@@ -338,7 +389,7 @@ func TestExpandRevisionToken(t *testing.T) {
 		},
 	}
 
-	encrypted, err = tm.MakeRevisionToken(ctx, previousToken, source, aad)
+	encrypted, err = tm.MakeRevisionToken(ctx, previousToken, source, aad, nil)
 	if err != nil {
 		t.Fatalf("error encrypting and serializing data: %v", err)
 	}
@@ -352,3 +403,124 @@ func TestExpandRevisionToken(t *testing.T) {
 		t.Fatalf("mismatch (-want, +got):\n%s", diff)
 	}
 }
+
+func TestTokenVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	kms := keys.TestKeyManager(t)
+	keyID := keys.TestEncryptionKey(t, kms)
+
+	revDB, err := revisiondb.New(testDB, &revisiondb.KMSConfig{
+		WrapperKeyID: keyID,
+		KeyManager:   kms,
+	})
+	if err != nil {
+		t.Fatalf("unable to provision revision DB: %v", err)
+	}
+	if _, err := revDB.CreateRevisionKey(ctx); err != nil {
+		t.Fatalf("unable to create a revision key: %v", err)
+	}
+
+	v1, err := New(ctx, revDB, time.Second, 28)
+	if err != nil {
+		t.Fatalf("unable to build token manager: %v", err)
+	}
+	v2, err := New(ctx, revDB, time.Second, 28, WithVersion(TokenV2))
+	if err != nil {
+		t.Fatalf("unable to build token manager: %v", err)
+	}
+	v2Only, err := New(ctx, revDB, time.Second, 28, WithVersion(TokenV2), WithAcceptV1(false))
+	if err != nil {
+		t.Fatalf("unable to build token manager: %v", err)
+	}
+
+	source := []*model.Exposure{
+		{
+			ExposureKey:    []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			IntervalNumber: 2654208,
+			IntervalCount:  144,
+		},
+	}
+	previous := &pb.RevisionTokenData{ChunkedUpload: &pb.ChunkedUpload{Chunk: 1, Of: 2}}
+	want := &pb.RevisionTokenData{
+		RevisableKeys: []*pb.RevisableKey{
+			{
+				TemporaryExposureKey: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				IntervalNumber:       2654208,
+				IntervalCount:        144,
+			},
+		},
+		ChunkedUpload: &pb.ChunkedUpload{Chunk: 1, Of: 2},
+	}
+	aad := []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	nonce := []byte("device-nonce")
+	opts := []cmp.Option{
+		cmpopts.IgnoreUnexported(pb.RevisionTokenData{}, pb.RevisableKey{}, pb.ChunkedUpload{}),
+	}
+
+	v1Token, err := v1.MakeRevisionToken(ctx, previous, source, aad, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2Token, err := v2.MakeRevisionToken(ctx, previous, source, aad, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v2Token) >= len(v1Token) {
+		t.Errorf("expected version 2 token (%d bytes) to be smaller than version 1 token (%d bytes)", len(v2Token), len(v1Token))
+	}
+
+	// Both versions are accepted during the migration.
+	for _, tm := range []*TokenManager{v1, v2} {
+		got, err := tm.ParseRevisionToken(ctx, v1Token, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != TokenV1 || got.DeviceNonce != nil || !got.IssuedAt.IsZero() {
+			t.Errorf("unexpected version 1 token metadata: %#v", got)
+		}
+		if diff := cmp.Diff(want, got.Data, opts...); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+
+		got, err = tm.ParseRevisionToken(ctx, v2Token, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != TokenV2 {
+			t.Errorf("expected version %d to be %d", got.Version, TokenV2)
+		}
+		if got, want := string(got.DeviceNonce), string(nonce); got != want {
+			t.Errorf("expected device nonce %q to be %q", got, want)
+		}
+		if since := time.Since(got.IssuedAt); since < 0 || since > time.Minute {
+			t.Errorf("expected token to be issued now, got %v", got.IssuedAt)
+		}
+		if diff := cmp.Diff(want, got.Data, opts...); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+	}
+
+	// After the migration, version 1 tokens can be rejected.
+	if _, err := v2Only.ParseRevisionToken(ctx, v1Token, aad); err == nil {
+		t.Errorf("expected version 1 token to be rejected")
+	}
+
+	// The header of a version 2 token is authenticated.
+	_, _, header, _, err := parseTokenV2Header(v2Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte(nil), v2Token...)
+	tampered[len(header)-1] ^= 1
+	if _, err := v2.ParseRevisionToken(ctx, tampered, aad); err == nil {
+		t.Errorf("expected tampered token to be rejected")
+	}
+
+	if _, err := New(ctx, revDB, time.Second, 28, WithAcceptV1(false)); err == nil {
+		t.Errorf("expected error when rejecting the issued version")
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Revision token versions. Version 1 tokens are a RevisionToken protocol
+// buffer. Version 2 tokens use a compact binary encoding:
+//
+//	header:  0x02 | uvarint revision key ID | uvarint issue time (unix seconds)
+//	nonce:   the AES-GCM nonce
+//	payload: the AES-GCM ciphertext of the token data
+//
+// The header is authenticated along with the configured AAD, so the key
+// version hint and the issue time can be read before decrypting the token, but
+// not changed. The token data is:
+//
+//	flags | [uvarint length | device nonce] | [uvarint length | ChunkedUpload]
+//	uvarint key count | uvarint key length | uvarint base interval number
+//	for each key: key | varint interval number - base | varint 144 - interval count
+//
+// followed by zero padding up to the minimum token size.
+const (
+	TokenV1 = 1
+	TokenV2 = 2
+
+	// tokenV2Prefix is the first byte of a version 2 token. It can't start a
+	// version 1 token, which is a protocol buffer and begins with a field tag.
+	tokenV2Prefix byte = 0x02
+
+	// MaxDeviceNonceLength is the maximum length of the device nonce that a
+	// token is bound to.
+	MaxDeviceNonceLength = 32
+
+	// v2PaddedKeySize is the size of a key in the token data, used to pad the
+	// token data to the minimum number of keys.
+	v2PaddedKeySize = 19
+
+	// maxIntervalCount is the interval count of a key that spans a whole day.
+	maxIntervalCount = 144
+
+	flagDeviceNonce   byte = 1 << 0
+	flagChunkedUpload byte = 1 << 1
+)
+
+// Token is a decoded revision token.
+type Token struct {
+	// Version is the version of the token encoding.
+	Version int
+
+	// KeyID is the ID of the revision key that encrypted the token.
+	KeyID int64
+
+	// IssuedAt is the time the token was issued, to the second. It is zero for
+	// version 1 tokens.
+	IssuedAt time.Time
+
+	// DeviceNonce is the nonce of the device the token is bound to, if any.
+	// Only version 2 tokens are bound to devices.
+	DeviceNonce []byte
+
+	// Data is the decrypted token data.
+	Data *pb.RevisionTokenData
+}
+
+// isTokenV2 returns true if the token bytes are a version 2 token.
+func isTokenV2(b []byte) bool {
+	return len(b) > 0 && b[0] == tokenV2Prefix
+}
+
+// marshalTokenV2Header returns the header of a version 2 token.
+func marshalTokenV2Header(kid int64, issuedAt time.Time) []byte {
+	b := make([]byte, 0, 1+2*binary.MaxVarintLen64)
+	b = append(b, tokenV2Prefix)
+	b = binary.AppendUvarint(b, uint64(kid))
+	b = binary.AppendUvarint(b, uint64(issuedAt.Unix()))
+	return b
+}
+
+// parseTokenV2Header parses the header of a version 2 token. It returns the
+// header and the remainder of the token.
+func parseTokenV2Header(b []byte) (kid int64, issuedAt time.Time, header, rest []byte, err error) {
+	if !isTokenV2(b) {
+		return 0, time.Time{}, nil, nil, fmt.Errorf("not a version 2 token")
+	}
+	r := &byteReader{b: b[1:]}
+	rawKID := r.uvarint()
+	rawIssuedAt := r.uvarint()
+	if r.err != nil {
+		return 0, time.Time{}, nil, nil, fmt.Errorf("malformed token header: %w", r.err)
+	}
+	n := len(b) - len(r.b)
+	return int64(rawKID), time.Unix(int64(rawIssuedAt), 0).UTC(), b[:n], b[n:], nil
+}
+
+// marshalTokenV2Data encodes the token data and the device nonce, padded to
+// at least minKeys keys.
+func marshalTokenV2Data(data *pb.RevisionTokenData, deviceNonce []byte, minKeys int) ([]byte, error) {
+	if len(deviceNonce) > MaxDeviceNonceLength {
+		return nil, fmt.Errorf("device nonce is %d bytes, must be at most %d", len(deviceNonce), MaxDeviceNonceLength)
+	}
+
+	var flags byte
+	if len(deviceNonce) > 0 {
+		flags |= flagDeviceNonce
+	}
+	var chunk []byte
+	if data.ChunkedUpload != nil {
+		var err error
+		if chunk, err = proto.Marshal(data.ChunkedUpload); err != nil {
+			return nil, fmt.Errorf("failed to marshal chunked upload: %w", err)
+		}
+		flags |= flagChunkedUpload
+	}
+
+	keys := data.RevisableKeys
+	b := make([]byte, 0, 64+len(keys)*v2PaddedKeySize)
+	b = append(b, flags)
+	if flags&flagDeviceNonce != 0 {
+		b = binary.AppendUvarint(b, uint64(len(deviceNonce)))
+		b = append(b, deviceNonce...)
+	}
+	if flags&flagChunkedUpload != 0 {
+		b = binary.AppendUvarint(b, uint64(len(chunk)))
+		b = append(b, chunk...)
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(keys)))
+	if len(keys) > 0 {
+		keyLength := len(keys[0].TemporaryExposureKey)
+		base := keys[0].IntervalNumber
+		b = binary.AppendUvarint(b, uint64(keyLength))
+		b = binary.AppendUvarint(b, uint64(base))
+		for _, rk := range keys {
+			if len(rk.TemporaryExposureKey) != keyLength {
+				return nil, fmt.Errorf("keys have different lengths: %d and %d", keyLength, len(rk.TemporaryExposureKey))
+			}
+			b = append(b, rk.TemporaryExposureKey...)
+			b = binary.AppendVarint(b, int64(rk.IntervalNumber)-int64(base))
+			b = binary.AppendVarint(b, maxIntervalCount-int64(rk.IntervalCount))
+		}
+	}
+
+	// Pad the data so that the size of the token can't be used to determine
+	// how many keys are held within.
+	if padded := minKeys * v2PaddedKeySize; len(b) < padded {
+		b = append(b, make([]byte, padded-len(b))...)
+	}
+	return b, nil
+}
+
+// unmarshalTokenV2Data decodes the token data and the device nonce. Padding
+// is ignored.
+func unmarshalTokenV2Data(b []byte) (*pb.RevisionTokenData, []byte, error) {
+	r := &byteReader{b: b}
+	flags := r.byte()
+
+	var deviceNonce []byte
+	if flags&flagDeviceNonce != 0 {
+		deviceNonce = r.bytes(int(r.uvarint()))
+		if len(deviceNonce) > MaxDeviceNonceLength {
+			return nil, nil, fmt.Errorf("device nonce is %d bytes, must be at most %d", len(deviceNonce), MaxDeviceNonceLength)
+		}
+	}
+
+	var data pb.RevisionTokenData
+	if flags&flagChunkedUpload != 0 {
+		chunk := r.bytes(int(r.uvarint()))
+		if r.err == nil {
+			var cu pb.ChunkedUpload
+			if err := proto.Unmarshal(chunk, &cu); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal chunked upload: %w", err)
+			}
+			data.ChunkedUpload = &cu
+		}
+	}
+
+	count := int(r.uvarint())
+	if count > 0 {
+		keyLength := int(r.uvarint())
+		base := int64(r.uvarint())
+		for i := 0; i < count && r.err == nil; i++ {
+			tek := r.bytes(keyLength)
+			intervalNumber := base + r.varint()
+			intervalCount := maxIntervalCount - r.varint()
+			if r.err != nil {
+				break
+			}
+			data.RevisableKeys = append(data.RevisableKeys, &pb.RevisableKey{
+				TemporaryExposureKey: append([]byte(nil), tek...),
+				IntervalNumber:       int32(intervalNumber),
+				IntervalCount:        int32(intervalCount),
+			})
+		}
+	}
+	if r.err != nil {
+		return nil, nil, fmt.Errorf("malformed token data: %w", r.err)
+	}
+	return &data, append([]byte(nil), deviceNonce...), nil
+}
+
+var errTruncated = errors.New("truncated")
+
+// byteReader reads varints and bytes, and records the first error.
+type byteReader struct {
+	b   []byte
+	err error
+}
+
+func (r *byteReader) byte() byte {
+	if b := r.bytes(1); len(b) == 1 {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *byteReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = errTruncated
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *byteReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *byteReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/pb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTokenV2Header(t *testing.T) {
+	t.Parallel()
+
+	issuedAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	b := append(marshalTokenV2Header(310, issuedAt), 1, 2, 3)
+
+	kid, gotIssuedAt, header, rest, err := parseTokenV2Header(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid != 310 {
+		t.Errorf("expected key ID %d to be 310", kid)
+	}
+	if !gotIssuedAt.Equal(issuedAt) {
+		t.Errorf("expected issue time %v to be %v", gotIssuedAt, issuedAt)
+	}
+	if got, want := len(header), len(b)-3; got != want {
+		t.Errorf("expected header length %d to be %d", got, want)
+	}
+	if diff := cmp.Diff([]byte{1, 2, 3}, rest); diff != "" {
+		t.Errorf("rest mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, _, _, _, err := parseTokenV2Header([]byte{tokenV2Prefix, 0x80}); err == nil {
+		t.Errorf("expected error for truncated header")
+	}
+
+	// Version 1 tokens are protocol buffers, which never start with the version
+	// 2 prefix.
+	v1, err := proto.Marshal(&pb.RevisionToken{Kid: "310", Data: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isTokenV2(v1) {
+		t.Errorf("expected version 1 token not to be detected as version 2")
+	}
+}
+
+func TestTokenV2Data(t *testing.T) {
+	t.Parallel()
+
+	tek := func(b byte) []byte {
+		k := make([]byte, 16)
+		k[0] = b
+		return k
+	}
+
+	cases := []struct {
+		name  string
+		data  *pb.RevisionTokenData
+		nonce []byte
+		err   bool
+	}{
+		{
+			name: "empty",
+			data: &pb.RevisionTokenData{},
+		},
+		{
+			name: "keys",
+			data: &pb.RevisionTokenData{
+				RevisableKeys: []*pb.RevisableKey{
+					{TemporaryExposureKey: tek(1), IntervalNumber: 2654208, IntervalCount: 144},
+					{TemporaryExposureKey: tek(2), IntervalNumber: 2654064, IntervalCount: 144},
+					{TemporaryExposureKey: tek(3), IntervalNumber: 2654352, IntervalCount: 61},
+				},
+			},
+		},
+		{
+			name: "nonce_and_chunk",
+			data: &pb.RevisionTokenData{
+				RevisableKeys: []*pb.RevisableKey{
+					{TemporaryExposureKey: tek(1), IntervalNumber: 2654208, IntervalCount: 144},
+				},
+				ChunkedUpload: &pb.ChunkedUpload{Chunk: 1, Of: 3, AppPackageName: "com.example.app", ExpiresAt: 1614834367},
+			},
+			nonce: []byte("0123456789abcdef"),
+		},
+		{
+			name: "mixed_key_lengths",
+			data: &pb.RevisionTokenData{
+				RevisableKeys: []*pb.RevisableKey{
+					{TemporaryExposureKey: tek(1), IntervalNumber: 2654208, IntervalCount: 144},
+					{TemporaryExposureKey: []byte{1, 2, 3, 4}, IntervalNumber: 2654208, IntervalCount: 144},
+				},
+			},
+			err: true,
+		},
+		{
+			name:  "nonce_too_long",
+			data:  &pb.RevisionTokenData{},
+			nonce: make([]byte, MaxDeviceNonceLength+1),
+			err:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := marshalTokenV2Data(tc.data, tc.nonce, 28)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := len(b), 28*v2PaddedKeySize; got < want {
+				t.Errorf("expected data of %d bytes to be padded to %d", got, want)
+			}
+
+			got, nonce, err := unmarshalTokenV2Data(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := cmpopts.IgnoreUnexported(pb.RevisionTokenData{}, pb.RevisableKey{}, pb.ChunkedUpload{})
+			if diff := cmp.Diff(tc.data, got, opts, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("data mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.nonce, nonce, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("nonce mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTokenV2Data_Size(t *testing.T) {
+	t.Parallel()
+
+	// A version 2 token holds a day aligned 16 byte key in at most
+	// v2PaddedKeySize bytes, a third less than a version 1 token.
+	data := &pb.RevisionTokenData{}
+	for i := 0; i < 30; i++ {
+		data.RevisableKeys = append(data.RevisableKeys, &pb.RevisableKey{
+			TemporaryExposureKey: make([]byte, 16),
+			IntervalNumber:       2654208 - int32(i)*144,
+			IntervalCount:        144,
+		})
+	}
+
+	v2, err := marshalTokenV2Data(data, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, err := proto.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, limit := len(v2), 30*v2PaddedKeySize+16; got > limit {
+		t.Errorf("expected version 2 data of %d bytes to be at most %d", got, limit)
+	}
+	if got, limit := len(v2), len(v1)*3/4; got > limit {
+		t.Errorf("expected version 2 data of %d bytes to be at most 3/4 of version 1 data of %d bytes", got, len(v1))
+	}
+}
+
+func TestUnmarshalTokenV2Data_Truncated(t *testing.T) {
+	t.Parallel()
+
+	data := &pb.RevisionTokenData{
+		RevisableKeys: []*pb.RevisableKey{
+			{TemporaryExposureKey: make([]byte, 16), IntervalNumber: 2654208, IntervalCount: 144},
+		},
+	}
+	b, err := marshalTokenV2Data(data, []byte("nonce"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(b); i++ {
+		if _, _, err := unmarshalTokenV2Data(b[:i]); err == nil {
+			t.Errorf("expected error for data truncated to %d bytes", i)
+		}
+	}
+}
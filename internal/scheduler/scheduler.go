@@ -0,0 +1,228 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scheduler budgets the work of jobs that are invoked by Cloud
+// Scheduler, so they stop before the scheduler's attempt deadline instead of
+// being cut off mid-transaction.
+//
+// A job runs until its deadline, the earliest of its own maximum runtime, the
+// attempt deadline of the scheduler job, and the deadline in the configured
+// request header. It does not start new units of work, such as a batch or a
+// file, within the stop margin of the deadline. A job that stops early
+// responds with 202 Accepted and its progress, and the next run continues
+// where it left off. Cloud Scheduler only retries failed attempts, so a job
+// that runs out of time is not retried on top of its next run.
+//
+// Every invocation of a job gets a run ID, which is added to its logs and
+// recorded with the batches, objects, and events it creates, so that one run
+// can be traced across logs, storage, and the database.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+const (
+	// HeaderScheduleTime is the time the Cloud Scheduler job was scheduled to
+	// run, in RFC 3339 format. It is the same for all attempts of a run.
+	HeaderScheduleTime = "X-CloudScheduler-ScheduleTime"
+
+	// HeaderJobName is the name of the Cloud Scheduler job.
+	HeaderJobName = "X-CloudScheduler-JobName"
+)
+
+// Config is the configuration for budgeting scheduled jobs.
+type Config struct {
+	// AttemptDeadline is the attempt deadline of the Cloud Scheduler job. The
+	// scheduler abandons and retries the attempt after it, so jobs stop before
+	// it. 0 leaves jobs to their own maximum runtime.
+	AttemptDeadline time.Duration `env:"SCHEDULER_ATTEMPT_DEADLINE, default=0"`
+
+	// DeadlineHeader is a request header with the deadline of the request,
+	// either a RFC 3339 time or a number of seconds from when the request is
+	// received. Empty disables it.
+	DeadlineHeader string `env:"SCHEDULER_DEADLINE_HEADER"`
+
+	// StopMargin is the time before the deadline in which jobs do not start
+	// new units of work. It is at most a quarter of the job's runtime.
+	StopMargin time.Duration `env:"SCHEDULER_STOP_MARGIN, default=30s"`
+}
+
+// Budget is the time a job has to run.
+type Budget struct {
+	runID    string
+	deadline time.Time
+	stop     time.Time
+	retry    bool
+}
+
+// Progress is the response of a job that stopped early to stay within its
+// budget.
+type Progress struct {
+	// Completed is the number of units of work that were completed.
+	Completed int `json:"completed"`
+
+	// Deadline is the deadline the job stopped for.
+	Deadline time.Time `json:"deadline"`
+}
+
+// NewBudget returns the budget of a job invoked by the request, which runs
+// for at most maxRuntime. A maxRuntime of 0 does not bound the job.
+func (c *Config) NewBudget(r *http.Request, maxRuntime time.Duration) *Budget {
+	return c.newBudget(r, maxRuntime, time.Now())
+}
+
+func (c *Config) newBudget(r *http.Request, maxRuntime time.Duration, now time.Time) *Budget {
+	b := Budget{runID: NewRunID(now)}
+
+	earliest := func(t time.Time) {
+		if b.deadline.IsZero() || t.Before(b.deadline) {
+			b.deadline = t
+		}
+	}
+	if maxRuntime > 0 {
+		earliest(now.Add(maxRuntime))
+	}
+	if c.AttemptDeadline > 0 {
+		earliest(now.Add(c.AttemptDeadline))
+	}
+	if c.DeadlineHeader != "" {
+		if t, ok := parseDeadline(r.Header.Get(c.DeadlineHeader), now); ok {
+			earliest(t)
+		}
+	}
+
+	if !b.deadline.IsZero() {
+		margin := c.StopMargin
+		if limit := b.deadline.Sub(now) / 4; margin > limit {
+			margin = limit
+		}
+		b.stop = b.deadline.Add(-margin)
+	}
+
+	// Attempts after the first keep the schedule time of the run, so an
+	// attempt that starts after the first attempt's deadline is a retry.
+	if scheduled, err := time.Parse(time.RFC3339, r.Header.Get(HeaderScheduleTime)); err == nil {
+		window := c.AttemptDeadline
+		if window <= 0 {
+			window = maxRuntime
+		}
+		b.retry = window > 0 && now.Sub(scheduled) > window
+	}
+	return &b
+}
+
+// parseDeadline parses a RFC 3339 time, or a number of seconds after now.
+func parseDeadline(v string, now time.Time) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return now.Add(time.Duration(secs * float64(time.Second))), true
+	}
+	return time.Time{}, false
+}
+
+// NewRunID returns a new run ID for a job invoked at the given time. Run IDs
+// sort by the time of the run.
+func NewRunID(now time.Time) string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// The time alone still identifies the run in practice.
+		return now.UTC().Format("20060102T150405.000000Z")
+	}
+	return now.UTC().Format("20060102T150405.000000Z") + "-" + hex.EncodeToString(b[:])
+}
+
+// RunID returns the run ID of the job.
+func (b *Budget) RunID() string {
+	return b.runID
+}
+
+// Deadline returns the deadline of the job, and false if the job has none.
+func (b *Budget) Deadline() (time.Time, bool) {
+	return b.deadline, !b.deadline.IsZero()
+}
+
+// Spent returns true if the job should not start new units of work.
+func (b *Budget) Spent() bool {
+	return !b.stop.IsZero() && !time.Now().Before(b.stop)
+}
+
+// Retry returns true if the job was invoked by a retry of a Cloud Scheduler
+// run.
+func (b *Budget) Retry() bool {
+	return b.retry
+}
+
+// Progress returns the response of a job that stopped early after completing
+// the given units of work.
+func (b *Budget) Progress(completed int) *Progress {
+	return &Progress{
+		Completed: completed,
+		Deadline:  b.deadline,
+	}
+}
+
+type contextKey struct{}
+
+type runIDKey struct{}
+
+// WithBudget returns a context that is canceled at the deadline of the budget
+// and carries the budget, for Spent, and its run ID, for RunID. The run ID is
+// also added to the logger of the context.
+func WithBudget(ctx context.Context, b *Budget) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(ctx, contextKey{}, b)
+	if b.runID != "" {
+		ctx = WithRunID(ctx, b.runID)
+	}
+	if deadline, ok := b.Deadline(); ok {
+		return context.WithDeadline(ctx, deadline)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Spent returns true if the budget in the context is spent. Without a budget,
+// it returns true if the deadline of the context passed.
+func Spent(ctx context.Context) bool {
+	if b, ok := ctx.Value(contextKey{}).(*Budget); ok {
+		return b.Spent()
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
+// WithRunID returns a context that carries the given run ID, and whose logger
+// logs it as "run_id".
+func WithRunID(ctx context.Context, runID string) context.Context {
+	ctx = context.WithValue(ctx, runIDKey{}, runID)
+	return logging.WithLogger(ctx, logging.FromContext(ctx).With("run_id", runID))
+}
+
+// RunID returns the run ID in the context, or the empty string if there is
+// none.
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
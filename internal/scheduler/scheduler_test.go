@@ -0,0 +1,224 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewBudget(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 3, 4, 5, 6, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		config     Config
+		headers    map[string]string
+		maxRuntime time.Duration
+		deadline   time.Time
+		stop       time.Time
+		retry      bool
+	}{
+		{
+			name: "unbounded",
+		},
+		{
+			name:       "max_runtime",
+			config:     Config{StopMargin: 30 * time.Second},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(5 * time.Minute),
+			stop:       now.Add(4*time.Minute + 30*time.Second),
+		},
+		{
+			name:       "attempt_deadline",
+			config:     Config{AttemptDeadline: 3 * time.Minute, StopMargin: 30 * time.Second},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(3 * time.Minute),
+			stop:       now.Add(2*time.Minute + 30*time.Second),
+		},
+		{
+			name:     "margin_capped",
+			config:   Config{AttemptDeadline: time.Minute, StopMargin: time.Minute},
+			deadline: now.Add(time.Minute),
+			stop:     now.Add(45 * time.Second),
+		},
+		{
+			name:       "header_time",
+			config:     Config{DeadlineHeader: "X-Deadline"},
+			headers:    map[string]string{"X-Deadline": now.Add(2 * time.Minute).Format(time.RFC3339)},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(2 * time.Minute),
+			stop:       now.Add(2 * time.Minute),
+		},
+		{
+			name:       "header_seconds",
+			config:     Config{DeadlineHeader: "X-Deadline", StopMargin: 10 * time.Second},
+			headers:    map[string]string{"X-Deadline": "90"},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(90 * time.Second),
+			stop:       now.Add(80 * time.Second),
+		},
+		{
+			name:       "header_later",
+			config:     Config{DeadlineHeader: "X-Deadline"},
+			headers:    map[string]string{"X-Deadline": "900"},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(5 * time.Minute),
+			stop:       now.Add(5 * time.Minute),
+		},
+		{
+			name:       "header_invalid",
+			config:     Config{DeadlineHeader: "X-Deadline"},
+			headers:    map[string]string{"X-Deadline": "soon"},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(5 * time.Minute),
+			stop:       now.Add(5 * time.Minute),
+		},
+		{
+			name:       "first_attempt",
+			config:     Config{AttemptDeadline: 3 * time.Minute},
+			headers:    map[string]string{HeaderScheduleTime: now.Add(-time.Second).Format(time.RFC3339)},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(3 * time.Minute),
+			stop:       now.Add(3 * time.Minute),
+		},
+		{
+			name:       "retry",
+			config:     Config{AttemptDeadline: 3 * time.Minute},
+			headers:    map[string]string{HeaderScheduleTime: now.Add(-4 * time.Minute).Format(time.RFC3339)},
+			maxRuntime: 5 * time.Minute,
+			deadline:   now.Add(3 * time.Minute),
+			stop:       now.Add(3 * time.Minute),
+			retry:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+
+			b := tc.config.newBudget(r, tc.maxRuntime, now)
+			if got, want := b.deadline, tc.deadline; !got.Equal(want) {
+				t.Errorf("expected deadline %v to be %v", got, want)
+			}
+			if got, want := b.stop, tc.stop; !got.Equal(want) {
+				t.Errorf("expected stop %v to be %v", got, want)
+			}
+			if got, want := b.Retry(), tc.retry; got != want {
+				t.Errorf("expected retry %t to be %t", got, want)
+			}
+		})
+	}
+}
+
+func TestSpent(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	t.Run("budget", func(t *testing.T) {
+		t.Parallel()
+
+		config := &Config{StopMargin: time.Hour}
+		ctx, cancel := WithBudget(context.Background(), config.NewBudget(r, time.Hour))
+		defer cancel()
+
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("expected context to have a deadline")
+		}
+		if Spent(ctx) {
+			t.Errorf("expected budget not to be spent")
+		}
+
+		spent := &Budget{deadline: time.Now().Add(time.Hour), stop: time.Now().Add(-time.Second)}
+		ctx, cancel = WithBudget(context.Background(), spent)
+		defer cancel()
+
+		if !Spent(ctx) {
+			t.Errorf("expected budget to be spent")
+		}
+		if ctx.Err() != nil {
+			t.Errorf("expected context not to be canceled before the deadline")
+		}
+	})
+
+	t.Run("unbounded", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := WithBudget(context.Background(), new(Config).NewBudget(r, 0))
+		defer cancel()
+
+		if _, ok := ctx.Deadline(); ok {
+			t.Errorf("expected context to have no deadline")
+		}
+		if Spent(ctx) {
+			t.Errorf("expected budget not to be spent")
+		}
+	})
+
+	t.Run("context_deadline", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		if !Spent(ctx) {
+			t.Errorf("expected passed deadline to be spent")
+		}
+	})
+}
+
+func TestRunID(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	config := new(Config)
+
+	first, second := config.NewBudget(r, 0), config.NewBudget(r, 0)
+	if first.RunID() == "" {
+		t.Fatalf("expected budget to have a run ID")
+	}
+	if first.RunID() == second.RunID() {
+		t.Errorf("expected run IDs of different runs to differ, both are %q", first.RunID())
+	}
+
+	if got := RunID(context.Background()); got != "" {
+		t.Errorf("expected no run ID, got %q", got)
+	}
+
+	ctx, cancel := WithBudget(context.Background(), first)
+	defer cancel()
+	if got, want := RunID(ctx), first.RunID(); got != want {
+		t.Errorf("expected run ID %q to be %q", got, want)
+	}
+
+	earlier := NewRunID(time.Date(2021, 3, 4, 5, 6, 0, 0, time.UTC))
+	later := NewRunID(time.Date(2021, 3, 4, 5, 7, 0, 0, time.UTC))
+	if !(earlier < later) {
+		t.Errorf("expected run ID %q to sort before %q", earlier, later)
+	}
+}
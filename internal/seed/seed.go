@@ -0,0 +1,185 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package seed populates a database with default data suitable for local
+// development.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	authorizedappdatabase "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	authorizedappmodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	verificationdatabase "github.com/google/exposure-notifications-server/internal/verification/database"
+	verificationmodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+)
+
+const (
+	// RevisionTokenKeyName is the name of the encryption key used to wrap
+	// revision tokens. It is created in the "system" key ring.
+	RevisionTokenKeyName = "revision-token-encrypter"
+
+	// HealthAuthorityKeyName is the name of the signing key for the seeded
+	// health authority. It is created in the "system" key ring.
+	HealthAuthorityKeyName = "health-authority-1"
+)
+
+// Seed creates the revision token encryption key, a health authority with a
+// signing key, and authorized apps for iOS and Android.
+func Seed(ctx context.Context, db *database.DB, km keys.KeyManager) error {
+	aadb := authorizedappdatabase.New(db)
+	verifydb := verificationdatabase.New(db)
+
+	// Create the revision token encrypter.
+	if err := CreateEncryptionKey(ctx, km, RevisionTokenKeyName); err != nil {
+		return err
+	}
+
+	// Create a health authority.
+	var ha verificationmodel.HealthAuthority
+	ha.ID = 1
+	ha.Issuer = "iss-test"
+	ha.Audience = "aud-test"
+	ha.Name = "Health Systems, Inc."
+	if err := verifydb.AddHealthAuthority(ctx, &ha); err != nil {
+		return fmt.Errorf("failed to create health authority: %w", err)
+	}
+
+	// Create a health authority key.
+	publicKeyPEM, err := CreateSigningKey(ctx, km, HealthAuthorityKeyName)
+	if err != nil {
+		return err
+	}
+
+	var hak verificationmodel.HealthAuthorityKey
+	hak.AuthorityID = 1
+	hak.Version = "1"
+	hak.From = time.Now()
+	hak.Thru = time.Now().Add(365 * 24 * time.Hour)
+	hak.PublicKeyPEM = publicKeyPEM
+	if err := verifydb.AddHealthAuthorityKey(ctx, &ha, &hak); err != nil {
+		return fmt.Errorf("failed to add health authority key: %w", err)
+	}
+
+	// Create some authorized apps.
+	iosApp := authorizedappmodel.NewAuthorizedApp()
+	iosApp.AppPackageName = "com.example.ios.app"
+	iosApp.AllowedRegions = map[string]struct{}{"US": {}}
+	iosApp.AllowedHealthAuthorityIDs = map[int64]struct{}{ha.ID: {}}
+	if err := aadb.InsertAuthorizedApp(ctx, iosApp); err != nil {
+		return fmt.Errorf("failed to create ios app: %w", err)
+	}
+
+	androidApp := authorizedappmodel.NewAuthorizedApp()
+	androidApp.AppPackageName = "com.example.android.app"
+	androidApp.AllowedRegions = map[string]struct{}{"US": {}}
+	androidApp.AllowedHealthAuthorityIDs = map[int64]struct{}{ha.ID: {}}
+	if err := aadb.InsertAuthorizedApp(ctx, androidApp); err != nil {
+		return fmt.Errorf("failed to create android app: %w", err)
+	}
+
+	return nil
+}
+
+// CreateEncryptionKey creates an encryption key with the given name in the
+// "system" key ring, and creates an initial version.
+func CreateEncryptionKey(ctx context.Context, km keys.KeyManager, name string) error {
+	kmst, ok := km.(keys.EncryptionKeyManager)
+	if !ok {
+		return fmt.Errorf("not EncryptionKeyManager, %T", km)
+	}
+
+	parent, err := kmst.CreateEncryptionKey(ctx, "system", name)
+	if err != nil {
+		return err
+	}
+	if _, err := kmst.CreateKeyVersion(ctx, parent); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateSigningKey creates a signing key with the given name in the "system"
+// key ring, if one does not already exist, and returns the PEM-encoded public
+// key of the first version.
+func CreateSigningKey(ctx context.Context, km keys.KeyManager, name string) (string, error) {
+	version, err := SigningKeyVersion(ctx, km, name)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := version.Signer(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	publicKey := signer.Public()
+	pemBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := pem.Encode(&b, &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pemBytes,
+	}); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// SigningKeyVersion returns the first version of the signing key with the
+// given name in the "system" key ring. The key and version are created if they
+// do not already exist.
+func SigningKeyVersion(ctx context.Context, km keys.KeyManager, name string) (keys.SigningKeyVersion, error) {
+	kmst, ok := km.(keys.SigningKeyManager)
+	if !ok {
+		return nil, fmt.Errorf("not SigningKeyManager, %T", km)
+	}
+
+	parent, err := kmst.CreateSigningKey(ctx, "system", name)
+	if err != nil {
+		return nil, err
+	}
+	list, err := kmst.SigningKeyVersions(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		if _, err := kmst.CreateKeyVersion(ctx, parent); err != nil {
+			return nil, err
+		}
+
+		list, err = kmst.SigningKeyVersions(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no versions for signing key %q", parent)
+	}
+	return list[0], nil
+}
@@ -21,7 +21,11 @@ import (
 	"fmt"
 
 	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	"github.com/google/exposure-notifications-server/internal/cdn"
+	"github.com/google/exposure-notifications-server/internal/featureflag"
 	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/internal/queue"
+	"github.com/google/exposure-notifications-server/internal/settings"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/keys"
@@ -36,11 +40,15 @@ type ExporterFunc func(context.Context) metrics.Exporter
 type ServerEnv struct {
 	authorizedAppProvider authorizedapp.Provider
 	blobstore             storage.Blobstore
+	cdnPurger             cdn.Purger
 	database              *database.DB
 	exporter              metrics.ExporterFromContext
 	keyManager            keys.KeyManager
 	secretManager         secrets.SecretManager
 	observabilityExporter observability.Exporter
+	queue                 queue.Queue
+	settings              *settings.Watcher
+	featureFlags          *featureflag.Evaluator
 }
 
 // Option defines function types to modify the ServerEnv on creation.
@@ -110,6 +118,22 @@ func WithBlobStorage(sto storage.Blobstore) Option {
 	}
 }
 
+// WithCDNPurger attaches a CDN cache purger to the environment.
+func WithCDNPurger(p cdn.Purger) Option {
+	return func(s *ServerEnv) *ServerEnv {
+		s.cdnPurger = p
+		return s
+	}
+}
+
+// WithQueue attaches a message queue to the environment.
+func WithQueue(q queue.Queue) Option {
+	return func(s *ServerEnv) *ServerEnv {
+		s.queue = q
+		return s
+	}
+}
+
 // WithObservabilityExporter creates an Option to install a specific observability exporter system.
 func WithObservabilityExporter(oe observability.Exporter) Option {
 	return func(s *ServerEnv) *ServerEnv {
@@ -118,6 +142,22 @@ func WithObservabilityExporter(oe observability.Exporter) Option {
 	}
 }
 
+// WithSettings installs the runtime settings watcher.
+func WithSettings(w *settings.Watcher) Option {
+	return func(s *ServerEnv) *ServerEnv {
+		s.settings = w
+		return s
+	}
+}
+
+// WithFeatureFlags installs the feature flag evaluator.
+func WithFeatureFlags(e *featureflag.Evaluator) Option {
+	return func(s *ServerEnv) *ServerEnv {
+		s.featureFlags = e
+		return s
+	}
+}
+
 func (s *ServerEnv) SecretManager() secrets.SecretManager {
 	return s.secretManager
 }
@@ -130,6 +170,20 @@ func (s *ServerEnv) Blobstore() storage.Blobstore {
 	return s.blobstore
 }
 
+// CDNPurger returns the CDN purger. If none was configured, it returns a purger
+// that does nothing.
+func (s *ServerEnv) CDNPurger() cdn.Purger {
+	if s.cdnPurger == nil {
+		return &cdn.Noop{}
+	}
+	return s.cdnPurger
+}
+
+// Queue returns the message queue, or nil if none was configured.
+func (s *ServerEnv) Queue() queue.Queue {
+	return s.queue
+}
+
 func (s *ServerEnv) AuthorizedAppProvider() authorizedapp.Provider {
 	return s.authorizedAppProvider
 }
@@ -142,6 +196,19 @@ func (s *ServerEnv) ObservabilityExporter() observability.Exporter {
 	return s.observabilityExporter
 }
 
+// Settings returns the runtime settings watcher, or nil if the service does
+// not watch settings.
+func (s *ServerEnv) Settings() *settings.Watcher {
+	return s.settings
+}
+
+// FeatureFlags returns the feature flag evaluator, or nil if the service does
+// not evaluate feature flags. A nil evaluator returns the fallback of every
+// flag.
+func (s *ServerEnv) FeatureFlags() *featureflag.Evaluator {
+	return s.featureFlags
+}
+
 func (s *ServerEnv) GetKeyManager() keys.KeyManager {
 	return s.keyManager
 }
@@ -177,6 +244,12 @@ func (s *ServerEnv) Close(ctx context.Context) error {
 		s.database.Close(ctx)
 	}
 
+	if s.queue != nil {
+		if err := s.queue.Close(ctx); err != nil {
+			return err
+		}
+	}
+
 	if s.observabilityExporter != nil {
 		if err := s.observabilityExporter.Close(); err != nil {
 			return nil
@@ -0,0 +1,103 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface for runtime settings.
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/settings/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v5"
+)
+
+type SettingsDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *SettingsDB {
+	return &SettingsDB{
+		db: db,
+	}
+}
+
+// ListSettings returns all settings, ordered by service and name.
+func (db *SettingsDB) ListSettings(ctx context.Context) ([]*model.Setting, error) {
+	var settings []*model.Setting
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				service, name, value, updated_at
+			FROM
+				Setting
+			ORDER BY service, name
+		`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s model.Setting
+			if err := rows.Scan(&s.Service, &s.Name, &s.Value, &s.UpdatedAt); err != nil {
+				return err
+			}
+			settings = append(settings, &s)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// SetSetting creates or updates a setting.
+func (db *SettingsDB) SetSetting(ctx context.Context, s *model.Setting) error {
+	if s.Service == "" || s.Name == "" {
+		return fmt.Errorf("setting service and name are required")
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				Setting (service, name, value, updated_at)
+			VALUES
+				($1, $2, $3, now())
+			ON CONFLICT (tenant, service, name) DO UPDATE
+				SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+			RETURNING updated_at
+		`, s.Service, s.Name, s.Value)
+		if err := row.Scan(&s.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to set setting: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteSetting deletes a setting, so the service uses its configured value
+// again.
+func (db *SettingsDB) DeleteSetting(ctx context.Context, service, name string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM Setting WHERE service = $1 AND name = $2
+		`, service, name); err != nil {
+			return fmt.Errorf("failed to delete setting: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/settings/model"
+)
+
+func TestSettings(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	settingsDB := New(testDB)
+
+	if err := settingsDB.SetSetting(ctx, &model.Setting{Name: "MAINTENANCE_MODE"}); err == nil {
+		t.Errorf("expected error for setting without service")
+	}
+
+	for _, s := range []*model.Setting{
+		{Service: "publish", Name: "MAINTENANCE_MODE", Value: "true"},
+		{Service: "publish", Name: "MAX_KEYS_ON_PUBLISH", Value: "20"},
+		{Service: "publish", Name: "MAX_KEYS_ON_PUBLISH", Value: "25"},
+	} {
+		if err := settingsDB.SetSetting(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := settingsDB.DeleteSetting(ctx, "publish", "MAINTENANCE_MODE"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := settingsDB.ListSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "MAX_KEYS_ON_PUBLISH" || got[0].Value != "25" || got[0].UpdatedAt.IsZero() {
+		t.Errorf("unexpected settings: %#v", got)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction for runtime settings.
+package model
+
+import "time"
+
+// Setting overrides a configuration value of a service while it is running.
+type Setting struct {
+	// Service is the service the setting applies to, for example "publish".
+	Service string
+	// Name is the environment variable name without any monolith prefix.
+	Name      string
+	Value     string
+	UpdatedAt time.Time
+}
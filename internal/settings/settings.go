@@ -0,0 +1,156 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package settings applies configuration changes to running services. Settings
+// are read from the database periodically and when the process receives
+// SIGHUP.
+package settings
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/settings/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/sethvargo/go-envconfig"
+)
+
+// Config is the configuration for watching settings.
+type Config struct {
+	// RefreshInterval is how often settings are read. Zero only reads them on
+	// SIGHUP.
+	RefreshInterval time.Duration `env:"SETTINGS_REFRESH_INTERVAL, default=1m"`
+}
+
+// Watcher holds the current settings and notifies subscribers when they
+// change.
+type Watcher struct {
+	db *database.SettingsDB
+
+	mu          sync.RWMutex
+	values      map[string]map[string]string
+	subscribers []func(context.Context)
+}
+
+// NewWatcher creates a watcher that reads settings from the database. Call
+// Refresh or Run to read them.
+func NewWatcher(db *database.SettingsDB) *Watcher {
+	return &Watcher{
+		db:     db,
+		values: make(map[string]map[string]string),
+	}
+}
+
+// OnChange registers fn to be called after the settings change.
+func (w *Watcher) OnChange(fn func(context.Context)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Refresh reads the settings and notifies the subscribers if they changed.
+func (w *Watcher) Refresh(ctx context.Context) error {
+	settings, err := w.db.ListSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]map[string]string)
+	for _, s := range settings {
+		if values[s.Service] == nil {
+			values[s.Service] = make(map[string]string)
+		}
+		values[s.Service][s.Name] = s.Value
+	}
+	w.Replace(ctx, values)
+	return nil
+}
+
+// Replace replaces the settings, keyed by service and name, and notifies the
+// subscribers if they changed.
+func (w *Watcher) Replace(ctx context.Context, values map[string]map[string]string) {
+	w.mu.Lock()
+	if reflect.DeepEqual(w.values, values) {
+		w.mu.Unlock()
+		return
+	}
+	w.values = values
+	subscribers := append([]func(context.Context){}, w.subscribers...)
+	w.mu.Unlock()
+
+	logging.FromContext(ctx).Named("settings").Infow("settings changed", "settings", values)
+	for _, fn := range subscribers {
+		fn(ctx)
+	}
+}
+
+// Run refreshes the settings every interval and on SIGHUP until the context
+// is done. Errors are logged and the previous settings are kept.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx).Named("settings")
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Infow("received SIGHUP, reading settings")
+		case <-tick:
+		}
+
+		if err := w.Refresh(ctx); err != nil {
+			logger.Errorw("failed to refresh settings", "error", err)
+		}
+	}
+}
+
+// Lookuper returns the current settings of the service as an envconfig
+// lookuper.
+func (w *Watcher) Lookuper(service string) envconfig.Lookuper {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	values := make(map[string]string, len(w.values[service]))
+	for k, v := range w.values[service] {
+		values[k] = v
+	}
+	return envconfig.MapLookuper(values)
+}
+
+// Overlay sets the fields of dst that have a setting for the service. Fields
+// must be tagged with the overwrite option, fields without a setting are left
+// unchanged.
+func (w *Watcher) Overlay(ctx context.Context, service string, dst interface{}) error {
+	if err := envconfig.ProcessWith(ctx, dst, w.Lookuper(service)); err != nil {
+		return fmt.Errorf("failed to apply %s settings: %w", service, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Maintenance bool          `env:"MAINTENANCE_MODE, overwrite"`
+	MaxKeys     uint          `env:"MAX_KEYS_ON_PUBLISH, overwrite"`
+	MaxAge      time.Duration `env:"MAX_INTERVAL_AGE_ON_PUBLISH, overwrite"`
+}
+
+func TestWatcher_Overlay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	w := NewWatcher(nil)
+
+	changes := 0
+	w.OnChange(func(context.Context) {
+		changes++
+	})
+
+	values := map[string]map[string]string{
+		"publish": {
+			"MAINTENANCE_MODE":    "true",
+			"MAX_KEYS_ON_PUBLISH": "10",
+		},
+		"export": {
+			"MAX_INTERVAL_AGE_ON_PUBLISH": "1h",
+		},
+	}
+	w.Replace(ctx, values)
+	w.Replace(ctx, values)
+	if changes != 1 {
+		t.Errorf("expected 1 change notification, got %d", changes)
+	}
+
+	cfg := &testConfig{MaxKeys: 30, MaxAge: 14 * 24 * time.Hour}
+	if err := w.Overlay(ctx, "publish", cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := testConfig{Maintenance: true, MaxKeys: 10, MaxAge: 14 * 24 * time.Hour}
+	if *cfg != want {
+		t.Errorf("expected %#v to be %#v", *cfg, want)
+	}
+
+	w.Replace(ctx, map[string]map[string]string{
+		"publish": {"MAX_KEYS_ON_PUBLISH": "ten"},
+	})
+	if err := w.Overlay(ctx, "publish", &testConfig{}); err == nil {
+		t.Errorf("expected error for invalid setting")
+	}
+}
@@ -20,9 +20,16 @@ import (
 	"fmt"
 
 	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	"github.com/google/exposure-notifications-server/internal/cdn"
+	"github.com/google/exposure-notifications-server/internal/featureflag"
+	featureflagdb "github.com/google/exposure-notifications-server/internal/featureflag/database"
 	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/internal/queue"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/settings"
+	settingsdb "github.com/google/exposure-notifications-server/internal/settings/database"
 	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/exposure-notifications-server/migrations"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/keys"
 	"github.com/google/exposure-notifications-server/pkg/logging"
@@ -43,12 +50,30 @@ type BlobstoreConfigProvider interface {
 	BlobstoreConfig() *storage.Config
 }
 
+// CDNPurgerConfigProvider signals that the config knows how to configure a
+// CDN cache purger.
+type CDNPurgerConfigProvider interface {
+	CDNPurgerConfig() *cdn.Config
+}
+
+// QueueConfigProvider signals that the config knows how to configure a message
+// queue.
+type QueueConfigProvider interface {
+	QueueConfig() *queue.Config
+}
+
 // DatabaseConfigProvider ensures that the environment config can provide a DB config.
 // All binaries in this application connect to the database via the same method.
 type DatabaseConfigProvider interface {
 	DatabaseConfig() *database.Config
 }
 
+// FeatureFlagConfigProvider signals that the config knows how to configure
+// feature flags. It requires a database.
+type FeatureFlagConfigProvider interface {
+	FeatureFlagConfig() *featureflag.Config
+}
+
 // KeyManagerConfigProvider is a marker interface indicating the key manager
 // should be installed.
 type KeyManagerConfigProvider interface {
@@ -67,6 +92,12 @@ type SecretManagerConfigProvider interface {
 	SecretManagerConfig() *secrets.Config
 }
 
+// SettingsConfigProvider signals that the config knows how to configure a
+// runtime settings watcher. It requires a database.
+type SettingsConfigProvider interface {
+	SettingsConfig() *settings.Config
+}
+
 // Setup runs common initialization code for all servers. See SetupWith.
 func Setup(ctx context.Context, config interface{}) (*serverenv.ServerEnv, error) {
 	return SetupWith(ctx, config, envconfig.OsLookuper())
@@ -204,6 +235,39 @@ func SetupWith(ctx context.Context, config interface{}, l envconfig.Lookuper) (*
 		logger.Infow("blobstore", "config", bsConfig)
 	}
 
+	// Configure CDN purging.
+	if provider, ok := config.(CDNPurgerConfigProvider); ok {
+		logger.Info("configuring cdn purger")
+
+		cdnConfig := provider.CDNPurgerConfig()
+		purger, err := cdn.PurgerFor(ctx, cdnConfig)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create cdn purger: %w", err)
+		}
+
+		// Update serverEnv setup.
+		serverEnvOpts = append(serverEnvOpts, serverenv.WithCDNPurger(purger))
+
+		logger.Infow("cdn purger", "config", cdnConfig)
+	}
+
+	// Configure the message queue, if one is enabled.
+	if provider, ok := config.(QueueConfigProvider); ok {
+		if queueConfig := provider.QueueConfig(); queueConfig.Enabled() {
+			logger.Info("configuring queue")
+
+			q, err := queue.QueueFor(ctx, queueConfig)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create queue: %w", err)
+			}
+
+			// Update serverEnv setup.
+			serverEnvOpts = append(serverEnvOpts, serverenv.WithQueue(q))
+
+			logger.Infow("queue", "type", queueConfig.Type)
+		}
+	}
+
 	// Setup the database connection.
 	if provider, ok := config.(DatabaseConfigProvider); ok {
 		logger.Info("configuring database")
@@ -219,6 +283,12 @@ func SetupWith(ctx context.Context, config interface{}, l envconfig.Lookuper) (*
 
 		logger.Infow("database", "config", dbConfig)
 
+		if err := checkSchema(ctx, db, dbConfig.SchemaCheck); err != nil {
+			// Ensure the database is closed on an error.
+			defer db.Close(ctx)
+			return nil, err
+		}
+
 		// AuthorizedApp must come after database setup due to the dependency.
 		if provider, ok := config.(AuthorizedAppConfigProvider); ok {
 			logger.Info("configuring authorizedapp")
@@ -236,7 +306,72 @@ func SetupWith(ctx context.Context, config interface{}, l envconfig.Lookuper) (*
 
 			logger.Infow("authorizedapp", "config", aaConfig)
 		}
+
+		// Settings must come after database setup due to the dependency.
+		if provider, ok := config.(SettingsConfigProvider); ok {
+			logger.Info("configuring settings")
+
+			sConfig := provider.SettingsConfig()
+			watcher := settings.NewWatcher(settingsdb.New(db))
+			if err := watcher.Refresh(ctx); err != nil {
+				// Ensure the database is closed on an error.
+				defer db.Close(ctx)
+				return nil, fmt.Errorf("unable to read settings: %w", err)
+			}
+			go watcher.Run(ctx, sConfig.RefreshInterval)
+
+			// Update serverEnv setup.
+			serverEnvOpts = append(serverEnvOpts, serverenv.WithSettings(watcher))
+
+			logger.Infow("settings", "config", sConfig)
+		}
+
+		// Feature flags must come after database setup due to the dependency.
+		if provider, ok := config.(FeatureFlagConfigProvider); ok {
+			logger.Info("configuring feature flags")
+
+			ffConfig := provider.FeatureFlagConfig()
+			evaluator, err := featureflag.NewEvaluator(featureflagdb.New(db), ffConfig)
+			if err != nil {
+				// Ensure the database is closed on an error.
+				defer db.Close(ctx)
+				return nil, fmt.Errorf("unable to create feature flag evaluator: %w", err)
+			}
+
+			// Update serverEnv setup.
+			serverEnvOpts = append(serverEnvOpts, serverenv.WithFeatureFlags(evaluator))
+
+			logger.Infow("feature flags", "config", ffConfig)
+		}
 	}
 
 	return serverenv.New(ctx, serverEnvOpts...), nil
 }
+
+// checkSchema compares the live schema with the embedded migrations according
+// to mode, one of the database.SchemaCheck* modes.
+func checkSchema(ctx context.Context, db *database.DB, mode string) error {
+	logger := logging.FromContext(ctx)
+
+	switch mode {
+	case database.SchemaCheckOff:
+		return nil
+	case database.SchemaCheckWarn, database.SchemaCheckFail:
+	default:
+		return fmt.Errorf("invalid DB_SCHEMA_CHECK %q, must be one of %q, %q, or %q",
+			mode, database.SchemaCheckOff, database.SchemaCheckWarn, database.SchemaCheckFail)
+	}
+
+	drift, err := db.CheckSchema(ctx, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("checking database schema: %w", err)
+	}
+	if drift.Empty() {
+		return nil
+	}
+	if drift.Failed() && mode == database.SchemaCheckFail {
+		return fmt.Errorf("database schema does not match the migrations, run the migrations or set DB_SCHEMA_CHECK=warn:\n%s", drift)
+	}
+	logger.Warnw("database schema differs from the migrations", "drift", drift.String())
+	return nil
+}
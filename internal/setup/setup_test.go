@@ -171,8 +171,12 @@ func TestSetupWith(t *testing.T) {
 			t.Errorf("expected blobstore to exist")
 		}
 
-		if _, ok := bs.(*storage.Memory); !ok {
-			t.Errorf("expected %T to be Noop", bs)
+		instrumented, ok := bs.(*storage.Instrumented)
+		if !ok {
+			t.Fatalf("expected %T to be Instrumented", bs)
+		}
+		if _, ok := instrumented.Unwrap().(*storage.Memory); !ok {
+			t.Errorf("expected %T to be Memory", instrumented.Unwrap())
 		}
 	})
 
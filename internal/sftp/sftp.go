@@ -0,0 +1,372 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sftp is a minimal, read-only SFTP client. It speaks version 3 of the
+// protocol, which all common servers support, and only implements what is
+// needed to download whole files.
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Packet types of version 3 of the protocol.
+const (
+	fxpInit    = 1
+	fxpVersion = 2
+	fxpOpen    = 3
+	fxpClose   = 4
+	fxpRead    = 5
+	fxpStatus  = 101
+	fxpHandle  = 102
+	fxpData    = 103
+)
+
+// Status codes of fxpStatus packets.
+const (
+	fxOK               = 0
+	fxEOF              = 1
+	fxNoSuchFile       = 2
+	fxPermissionDenied = 3
+)
+
+const (
+	protocolVersion = 3
+
+	// openRead is the flag to open a file for reading.
+	openRead = 0x00000001
+
+	// readSize is the length of each read request. Servers must support at
+	// least 32KiB.
+	readSize = 32 << 10
+
+	// maxPacket bounds the packets accepted from the server.
+	maxPacket = 256 << 10
+)
+
+// ErrFileTooLarge is returned by ReadFile when the file is larger than the
+// maximum size.
+var ErrFileTooLarge = errors.New("sftp: file too large")
+
+// StatusError is a status other than OK returned by the server.
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sftp: status %d: %s", e.Code, e.Message)
+}
+
+// Is makes missing files and denied permissions match fs.ErrNotExist and
+// fs.ErrPermission.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e.Code == fxNoSuchFile
+	case fs.ErrPermission:
+		return e.Code == fxPermissionDenied
+	}
+	return false
+}
+
+// Client is an SFTP session. It is not safe for concurrent use.
+type Client struct {
+	session *ssh.Session
+	r       io.Reader
+	w       io.WriteCloser
+	nextID  uint32
+}
+
+// NewClient starts the sftp subsystem on the SSH connection.
+func NewClient(conn *ssh.Client) (*Client, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("sftp: failed to open session: %w", err)
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("sftp: failed to open stdin: %w", err)
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("sftp: failed to open stdout: %w", err)
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("sftp: failed to start subsystem: %w", err)
+	}
+
+	c, err := newClient(r, w)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	c.session = session
+	return c, nil
+}
+
+// newClient negotiates the protocol version over r and w.
+func newClient(r io.Reader, w io.WriteCloser) (*Client, error) {
+	c := &Client{r: r, w: w}
+
+	var b packetBuilder
+	b.uint32(protocolVersion)
+	if err := c.send(fxpInit, b.bytes()); err != nil {
+		return nil, err
+	}
+
+	typ, data, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	if typ != fxpVersion {
+		return nil, fmt.Errorf("sftp: expected version packet, got type %d", typ)
+	}
+	p := packetReader(data)
+	version, err := p.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if version < protocolVersion {
+		return nil, fmt.Errorf("sftp: unsupported protocol version %d", version)
+	}
+	return c, nil
+}
+
+// Close ends the session.
+func (c *Client) Close() error {
+	err := c.w.Close()
+	if c.session != nil {
+		if cerr := c.session.Close(); cerr != nil && !errors.Is(cerr, io.EOF) && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// ReadFile returns the contents of the file at the path on the server. The
+// error matches fs.ErrNotExist if the file does not exist, and
+// ErrFileTooLarge once more than maxSize bytes are read.
+func (c *Client) ReadFile(name string, maxSize int64) ([]byte, error) {
+	handle, err := c.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	for {
+		chunk, err := c.read(handle, uint64(len(out)))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			c.close(handle)
+			return nil, err
+		}
+		if int64(len(out)+len(chunk)) > maxSize {
+			c.close(handle)
+			return nil, fmt.Errorf("%w: %s is over %d bytes", ErrFileTooLarge, name, maxSize)
+		}
+		out = append(out, chunk...)
+	}
+
+	if err := c.close(handle); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) open(name string) (string, error) {
+	id := c.id()
+	var b packetBuilder
+	b.uint32(id)
+	b.string(name)
+	b.uint32(openRead)
+	b.uint32(0) // no attributes
+	if err := c.send(fxpOpen, b.bytes()); err != nil {
+		return "", err
+	}
+
+	p, err := c.reply(id, fxpHandle)
+	if err != nil {
+		return "", fmt.Errorf("sftp: open %s: %w", name, err)
+	}
+	return p.string()
+}
+
+// read returns the next chunk of the file at offset, or io.EOF at the end of
+// the file.
+func (c *Client) read(handle string, offset uint64) ([]byte, error) {
+	id := c.id()
+	var b packetBuilder
+	b.uint32(id)
+	b.string(handle)
+	b.uint64(offset)
+	b.uint32(readSize)
+	if err := c.send(fxpRead, b.bytes()); err != nil {
+		return nil, err
+	}
+
+	p, err := c.reply(id, fxpData)
+	if err != nil {
+		var serr *StatusError
+		if errors.As(err, &serr) && serr.Code == fxEOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("sftp: read: %w", err)
+	}
+	data, err := p.string()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+func (c *Client) close(handle string) error {
+	id := c.id()
+	var b packetBuilder
+	b.uint32(id)
+	b.string(handle)
+	if err := c.send(fxpClose, b.bytes()); err != nil {
+		return err
+	}
+
+	if _, err := c.reply(id, fxpStatus); err != nil {
+		return fmt.Errorf("sftp: close: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) id() uint32 {
+	c.nextID++
+	return c.nextID
+}
+
+// reply reads the response to the request with the id. It returns the rest of
+// the packet if it has the wanted type, and a *StatusError if the server
+// returned an error status instead.
+func (c *Client) reply(id uint32, want byte) (*packetReader, error) {
+	typ, data, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	p := packetReader(data)
+	got, err := p.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if got != id {
+		return nil, fmt.Errorf("sftp: expected response to request %d, got %d", id, got)
+	}
+
+	if typ == fxpStatus {
+		code, err := p.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if code == fxOK && want == fxpStatus {
+			return &p, nil
+		}
+		msg, _ := p.string()
+		return nil, &StatusError{Code: code, Message: msg}
+	}
+	if typ != want {
+		return nil, fmt.Errorf("sftp: expected packet type %d, got %d", want, typ)
+	}
+	return &p, nil
+}
+
+func (c *Client) send(typ byte, payload []byte) error {
+	buf := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(1+len(payload)))
+	buf[4] = typ
+	if _, err := c.w.Write(append(buf, payload...)); err != nil {
+		return fmt.Errorf("sftp: failed to send: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("sftp: failed to receive: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxPacket {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return 0, nil, fmt.Errorf("sftp: failed to receive: %w", err)
+	}
+	return header[4], data, nil
+}
+
+// packetBuilder encodes the fields of a packet.
+type packetBuilder struct {
+	buf []byte
+}
+
+func (b *packetBuilder) uint32(v uint32) {
+	b.buf = binary.BigEndian.AppendUint32(b.buf, v)
+}
+
+func (b *packetBuilder) uint64(v uint64) {
+	b.buf = binary.BigEndian.AppendUint64(b.buf, v)
+}
+
+func (b *packetBuilder) string(s string) {
+	b.uint32(uint32(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+func (b *packetBuilder) bytes() []byte {
+	return b.buf
+}
+
+// packetReader decodes the fields of a packet.
+type packetReader []byte
+
+var errShortPacket = errors.New("sftp: packet too short")
+
+func (p *packetReader) uint32() (uint32, error) {
+	if len(*p) < 4 {
+		return 0, errShortPacket
+	}
+	v := binary.BigEndian.Uint32(*p)
+	*p = (*p)[4:]
+	return v, nil
+}
+
+func (p *packetReader) string() (string, error) {
+	n, err := p.uint32()
+	if err != nil {
+		return "", err
+	}
+	if uint32(len(*p)) < n {
+		return "", errShortPacket
+	}
+	s := string((*p)[:n])
+	*p = (*p)[n:]
+	return s, nil
+}
@@ -0,0 +1,266 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sftp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeServer serves files over the protocol, in chunks of at most chunk bytes.
+type fakeServer struct {
+	files map[string][]byte
+	chunk int
+}
+
+func (s *fakeServer) serve(t *testing.T, r io.Reader, w io.Writer) {
+	c := &Client{r: r}
+	reply := func(typ byte, fields func(b *packetBuilder)) {
+		var b packetBuilder
+		fields(&b)
+		buf := make([]byte, 5)
+		binary.BigEndian.PutUint32(buf, uint32(1+len(b.buf)))
+		buf[4] = typ
+		if _, err := w.Write(append(buf, b.buf...)); err != nil {
+			t.Errorf("server failed to write: %v", err)
+		}
+	}
+	status := func(id, code uint32) {
+		reply(fxpStatus, func(b *packetBuilder) {
+			b.uint32(id)
+			b.uint32(code)
+			b.string("status")
+			b.string("")
+		})
+	}
+
+	for {
+		typ, data, err := c.recv()
+		if err != nil {
+			return
+		}
+		p := packetReader(data)
+		id, _ := p.uint32()
+
+		switch typ {
+		case fxpInit:
+			reply(fxpVersion, func(b *packetBuilder) { b.uint32(protocolVersion) })
+		case fxpOpen:
+			name, _ := p.string()
+			if _, ok := s.files[name]; !ok {
+				status(id, fxNoSuchFile)
+				continue
+			}
+			reply(fxpHandle, func(b *packetBuilder) {
+				b.uint32(id)
+				b.string(name)
+			})
+		case fxpRead:
+			name, _ := p.string()
+			offset := binary.BigEndian.Uint64(p)
+			contents := s.files[name]
+			if offset >= uint64(len(contents)) {
+				status(id, fxEOF)
+				continue
+			}
+			end := offset + uint64(s.chunk)
+			if end > uint64(len(contents)) {
+				end = uint64(len(contents))
+			}
+			reply(fxpData, func(b *packetBuilder) {
+				b.uint32(id)
+				b.string(string(contents[offset:end]))
+			})
+		case fxpClose:
+			status(id, fxOK)
+		default:
+			t.Errorf("unexpected packet type %d", typ)
+			return
+		}
+	}
+}
+
+func TestClient_ReadFile(t *testing.T) {
+	t.Parallel()
+
+	large := bytes.Repeat([]byte("0123456789"), 10000)
+	server := &fakeServer{
+		files: map[string][]byte{
+			"/exports/index.txt": []byte("a.zip\nb.zip\n"),
+			"/exports/empty.txt": {},
+			"/exports/a.zip":     large,
+		},
+		chunk: 4096,
+	}
+
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	go server.serve(t, serverR, serverW)
+
+	client, err := newClient(clientR, clientW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		serverW.Close()
+	})
+
+	cases := []struct {
+		name    string
+		maxSize int64
+		want    []byte
+		err     error
+	}{
+		{
+			name: "/exports/index.txt",
+			want: []byte("a.zip\nb.zip\n"),
+		},
+		{
+			name: "/exports/empty.txt",
+		},
+		{
+			name: "/exports/a.zip",
+			want: large,
+		},
+		{
+			name: "/exports/missing.zip",
+			err:  fs.ErrNotExist,
+		},
+		{
+			name:    "/exports/a.zip",
+			maxSize: int64(len(large)),
+			want:    large,
+		},
+		{
+			name:    "/exports/a.zip",
+			maxSize: int64(len(large)) - 1,
+			err:     ErrFileTooLarge,
+		},
+	}
+
+	// The client is not safe for concurrent use, so the cases run in order.
+	for _, tc := range cases {
+		maxSize := tc.maxSize
+		if maxSize == 0 {
+			maxSize = 1 << 20
+		}
+		got, err := client.ReadFile(tc.name, maxSize)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: expected %d bytes, got %d", tc.name, len(tc.want), len(got))
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(hostSigner)
+
+	server := &fakeServer{
+		files: map[string][]byte{"/index.txt": []byte("a.zip")},
+		chunk: 2,
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	// Serve the sftp subsystem on the first session.
+	go func() {
+		serverConn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer serverConn.Close()
+
+		_, chans, reqs, err := ssh.NewServerConn(serverConn, serverConfig)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newCh := range chans {
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				for req := range reqs {
+					ok := req.Type == "subsystem" && bytes.Equal(req.Payload[4:], []byte("sftp"))
+					req.Reply(ok, nil)
+					if ok {
+						go func() {
+							server.serve(t, ch, ch)
+							ch.Close()
+						}()
+					}
+				}
+			}()
+		}
+	}()
+
+	sshClient, err := ssh.Dial("tcp", ln.Addr().String(), &ssh.ClientConfig{
+		HostKeyCallback: ssh.FixedHostKey(hostSigner.PublicKey()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sshClient.Close() })
+
+	client, err := NewClient(sshClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	got, err := client.ReadFile("/index.txt", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.zip"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
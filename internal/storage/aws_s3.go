@@ -34,7 +34,10 @@ func init() {
 }
 
 // Compile-time check to verify implements interface.
-var _ Blobstore = (*AWSS3)(nil)
+var (
+	_ Blobstore = (*AWSS3)(nil)
+	_ Lister    = (*AWSS3)(nil)
+)
 
 // AWSS3 implements the Blob interface and provides the ability
 // write files to AWS S3.
@@ -59,20 +62,22 @@ func NewAWSS3(ctx context.Context, _ *Config) (Blobstore, error) {
 
 // CreateObject creates a new S3 object or overwrites an existing one.
 func (s *AWSS3) CreateObject(ctx context.Context, bucket, key string, contents []byte, cacheable bool, contentType string) error {
-	cacheControl := "public, max-age=86400"
-	if !cacheable {
-		cacheControl = "no-cache, max-age=0"
-	}
 
 	putInput := s3.PutObjectInput{
 		Bucket:       aws.String(bucket),
 		Key:          aws.String(key),
-		CacheControl: aws.String(cacheControl),
+		CacheControl: aws.String(cacheControl(ctx, cacheable)),
 		Body:         bytes.NewReader(contents),
 	}
 	if contentType != "" {
 		putInput.ContentType = aws.String(contentType)
 	}
+	if md := MetadataFromContext(ctx); len(md) > 0 {
+		putInput.Metadata = aws.StringMap(md)
+	}
+	if class := ObjectOptionsFromContext(ctx).StorageClass; class != "" {
+		putInput.StorageClass = aws.String(class)
+	}
 	if _, err := s.svc.PutObjectWithContext(ctx, &putInput); err != nil {
 		return fmt.Errorf("storage.CreateObject: %w", err)
 	}
@@ -114,3 +119,24 @@ func (s *AWSS3) GetObject(ctx context.Context, bucket, key string) ([]byte, erro
 
 	return b, nil
 }
+
+// ListObjects returns the objects in the bucket whose keys start with prefix.
+func (s *AWSS3) ListObjects(ctx context.Context, bucket, prefix string) ([]*ObjectAttrs, error) {
+	objects := make([]*ObjectAttrs, 0)
+	if err := s.svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, o := range page.Contents {
+			objects = append(objects, &ObjectAttrs{
+				Name:    aws.StringValue(o.Key),
+				Size:    aws.Int64Value(o.Size),
+				Updated: aws.TimeValue(o.LastModified),
+			})
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	return objects, nil
+}
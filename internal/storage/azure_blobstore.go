@@ -38,7 +38,10 @@ func init() {
 }
 
 // Compile-time check to verify implements interface.
-var _ Blobstore = (*AzureBlobstore)(nil)
+var (
+	_ Blobstore = (*AzureBlobstore)(nil)
+	_ Lister    = (*AzureBlobstore)(nil)
+)
 
 // AzureBlobstore implements the Blob interface and provides the ability
 // write files to Azure Blob Storage.
@@ -145,20 +148,18 @@ func NewAzureBlobstore(ctx context.Context, _ *Config) (Blobstore, error) {
 
 // CreateObject creates a new blobstore object or overwrites an existing one.
 func (s *AzureBlobstore) CreateObject(ctx context.Context, container, name string, contents []byte, cacheable bool, contentType string) error {
-	cacheControl := "public, max-age=86400"
-	if !cacheable {
-		cacheControl = "no-cache, max-age=0"
-	}
 
 	blobURL := s.serviceURL.NewContainerURL(container).NewBlockBlobURL(name)
 	headers := azblob.BlobHTTPHeaders{
-		CacheControl: cacheControl,
+		CacheControl: cacheControl(ctx, cacheable),
 	}
 	if contentType != "" {
 		headers.ContentType = contentType
 	}
 	if _, err := azblob.UploadBufferToBlockBlob(ctx, contents, blobURL, azblob.UploadToBlockBlobOptions{
 		BlobHTTPHeaders: headers,
+		Metadata:        azblob.Metadata(MetadataFromContext(ctx)),
+		BlobAccessTier:  azblob.AccessTierType(ObjectOptionsFromContext(ctx).StorageClass),
 	}); err != nil {
 		return fmt.Errorf("storage.CreateObject: %w", err)
 	}
@@ -199,3 +200,29 @@ func (s *AzureBlobstore) GetObject(ctx context.Context, container, name string)
 
 	return b.Bytes(), nil
 }
+
+// ListObjects returns the blobs in the container whose names start with prefix.
+func (s *AzureBlobstore) ListObjects(ctx context.Context, container, prefix string) ([]*ObjectAttrs, error) {
+	containerURL := s.serviceURL.NewContainerURL(container)
+
+	objects := make([]*ObjectAttrs, 0)
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: prefix})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blobs: %w", err)
+		}
+		for _, item := range resp.Segment.BlobItems {
+			var size int64
+			if item.Properties.ContentLength != nil {
+				size = *item.Properties.ContentLength
+			}
+			objects = append(objects, &ObjectAttrs{
+				Name:    item.Name,
+				Size:    size,
+				Updated: item.Properties.LastModified,
+			})
+		}
+		marker = resp.NextMarker
+	}
+	return objects, nil
+}
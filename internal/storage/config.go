@@ -14,8 +14,41 @@
 
 package storage
 
+import "time"
+
 // Config defines the configuration for a blobstore.
 type Config struct {
 	// Type is the type of blobstore.
 	Type string `env:"BLOBSTORE, default=MEMORY"`
+
+	// Timeout is the maximum amount of time for a single blobstore operation,
+	// including all retries. A value of 0 means no timeout is applied beyond
+	// the caller's context.
+	Timeout time.Duration `env:"BLOBSTORE_TIMEOUT, default=5m"`
+
+	// MaxRetries is the maximum number of times a failed operation is retried.
+	// RetryBackoff is the initial delay between retries, which grows
+	// exponentially with jitter.
+	MaxRetries   uint64        `env:"BLOBSTORE_MAX_RETRIES, default=3"`
+	RetryBackoff time.Duration `env:"BLOBSTORE_RETRY_BACKOFF, default=500ms"`
+
+	// GoogleCloudStorage holds configuration specific to Google Cloud Storage.
+	GoogleCloudStorage GoogleCloudStorageConfig
+}
+
+// GoogleCloudStorageConfig is the configuration for the Google Cloud Storage
+// blobstore.
+type GoogleCloudStorageConfig struct {
+	// ChunkSize is the size of each chunk of a resumable upload. Objects smaller
+	// than this are uploaded in a single request. A value of 0 disables
+	// resumable uploads.
+	ChunkSize int `env:"GCS_CHUNK_SIZE, default=16777216"`
+
+	// CompositeThreshold is the object size at or above which objects are
+	// uploaded as multiple parts in parallel and then composed into the final
+	// object. A value of 0 disables composite uploads.
+	CompositeThreshold int `env:"GCS_COMPOSITE_UPLOAD_THRESHOLD, default=0"`
+
+	// CompositePartSize is the size of each part of a composite upload.
+	CompositePartSize int `env:"GCS_COMPOSITE_UPLOAD_PART_SIZE, default=8388608"`
 }
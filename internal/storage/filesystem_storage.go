@@ -17,9 +17,13 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 func init() {
@@ -27,7 +31,10 @@ func init() {
 }
 
 // Compile-time check to verify implements interface.
-var _ Blobstore = (*FilesystemStorage)(nil)
+var (
+	_ Blobstore = (*FilesystemStorage)(nil)
+	_ Lister    = (*FilesystemStorage)(nil)
+)
 
 // FilesystemStorage implements Blobstore and provides the ability
 // write files to the filesystem.
@@ -41,7 +48,8 @@ func NewFilesystemStorage(ctx context.Context, _ *Config) (Blobstore, error) {
 
 // CreateObject creates a new object on the filesystem or overwrites an existing
 // one.
-// contentType is ignored for this storage implementation.
+// contentType, custom metadata and object options are ignored for this storage
+// implementation.
 func (s *FilesystemStorage) CreateObject(ctx context.Context, folder, filename string, contents []byte, cacheable bool, contentType string) error {
 	pth := filepath.Join(folder, filename)
 	if err := os.WriteFile(pth, contents, 0o600); err != nil {
@@ -73,3 +81,46 @@ func (s *FilesystemStorage) GetObject(ctx context.Context, folder, filename stri
 	}
 	return b, nil
 }
+
+// ListObjects returns the files under the folder whose paths, relative to the
+// folder and with forward slashes, start with prefix.
+func (s *FilesystemStorage) ListObjects(ctx context.Context, folder, prefix string) ([]*ObjectAttrs, error) {
+	objects := make([]*ObjectAttrs, 0)
+	err := filepath.WalkDir(folder, func(pth string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(folder, pth)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, &ObjectAttrs{
+			Name:    name,
+			Size:    info.Size(),
+			Updated: info.ModTime().UTC(),
+		})
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/exposure-notifications-server/internal/project"
@@ -187,3 +188,51 @@ func TestFilesystemStorage_GetObject(t *testing.T) {
 		})
 	}
 }
+
+func TestFilesystemStorage_ListObjects(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	tmp := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmp, "root", "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := NewFilesystemStorage(ctx, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"root/a.zip", "root/sub/b.zip", "other.zip"} {
+		if err := storage.CreateObject(ctx, tmp, name, []byte("contents"), false, ContentTypeZip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objects, err := ListObjects(ctx, storage, tmp, "root/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(objects))
+	for _, o := range objects {
+		names = append(names, o.Name)
+		if got, want := o.Size, int64(8); got != want {
+			t.Errorf("%s: expected size %d to be %d", o.Name, got, want)
+		}
+		if o.Updated.IsZero() {
+			t.Errorf("%s: expected updated time", o.Name)
+		}
+	}
+	if got, want := strings.Join(names, ","), "root/a.zip,root/sub/b.zip"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	missing, err := ListObjects(ctx, storage, filepath.Join(tmp, "missing"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("expected no objects, got %d", len(missing))
+	}
+}
@@ -24,40 +24,58 @@ import (
 	"io"
 
 	"cloud.google.com/go/storage"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
 )
 
+// maxComposeSources is the maximum number of source objects that can be
+// composed in a single request.
+const maxComposeSources = 32
+
 func init() {
 	RegisterBlobstore("GOOGLE_CLOUD_STORAGE", NewGoogleCloudStorage)
 }
 
 // Compile-time check to verify implements interface.
-var _ Blobstore = (*GoogleCloudStorage)(nil)
+var (
+	_ Blobstore = (*GoogleCloudStorage)(nil)
+	_ Lister    = (*GoogleCloudStorage)(nil)
+)
 
 // GoogleCloudStorage implements the Blob interface and provides the ability
 // write files to Google Cloud Storage.
 type GoogleCloudStorage struct {
 	client *storage.Client
+	config *GoogleCloudStorageConfig
 }
 
 // NewGoogleCloudStorage creates a Google Cloud Storage Client, suitable
 // for use with serverenv.ServerEnv.
-func NewGoogleCloudStorage(ctx context.Context, _ *Config) (Blobstore, error) {
+func NewGoogleCloudStorage(ctx context.Context, cfg *Config) (Blobstore, error) {
 	client, err := storage.NewClient(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("storage.NewClient: %w", err)
 	}
-	return &GoogleCloudStorage{client}, nil
+	return &GoogleCloudStorage{
+		client: client,
+		config: &cfg.GoogleCloudStorage,
+	}, nil
 }
 
 // CreateObject creates a new cloud storage object or overwrites an existing one.
 func (s *GoogleCloudStorage) CreateObject(ctx context.Context, bucket, objectName string, contents []byte, cacheable bool, contentType string) error {
-	cacheControl := "public, max-age=86400"
-	if !cacheable {
-		cacheControl = "no-cache, max-age=0"
+	cacheControl := cacheControl(ctx, cacheable)
+	storageClass := ObjectOptionsFromContext(ctx).StorageClass
+
+	if t := s.config.CompositeThreshold; t > 0 && len(contents) >= t && s.config.CompositePartSize > 0 {
+		return s.createComposite(ctx, bucket, objectName, contents, cacheControl, storageClass, contentType)
 	}
 
 	wc := s.client.Bucket(bucket).Object(objectName).NewWriter(ctx)
+	wc.ChunkSize = s.config.ChunkSize
 	wc.CacheControl = cacheControl
+	wc.StorageClass = storageClass
+	wc.Metadata = MetadataFromContext(ctx)
 	if contentType != "" {
 		wc.ContentType = contentType
 	}
@@ -71,6 +89,70 @@ func (s *GoogleCloudStorage) CreateObject(ctx context.Context, bucket, objectNam
 	return nil
 }
 
+// createComposite uploads contents as multiple temporary parts in parallel,
+// composes them into the final object, and then deletes the parts.
+func (s *GoogleCloudStorage) createComposite(ctx context.Context, bucket, objectName string, contents []byte, cacheControl, storageClass, contentType string) error {
+	bkt := s.client.Bucket(bucket)
+
+	partSize := s.config.CompositePartSize
+	numParts := (len(contents) + partSize - 1) / partSize
+	if numParts > maxComposeSources {
+		// Grow the parts so the upload fits in a single compose request.
+		numParts = maxComposeSources
+		partSize = (len(contents) + numParts - 1) / numParts
+	}
+
+	parts := make([]*storage.ObjectHandle, 0, numParts)
+	for i := 0; i < numParts; i++ {
+		parts = append(parts, bkt.Object(fmt.Sprintf("%s.part-%04d", objectName, i)))
+	}
+
+	// Always attempt to clean up the temporary parts, even on failure.
+	defer func() {
+		for _, part := range parts {
+			_ = part.Delete(context.Background())
+		}
+	}()
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, part := range parts {
+		start := i * partSize
+		end := start + partSize
+		if end > len(contents) {
+			end = len(contents)
+		}
+		chunk := contents[start:end]
+		part := part
+
+		g.Go(func() error {
+			wc := part.NewWriter(gctx)
+			wc.ChunkSize = s.config.ChunkSize
+			if _, err := wc.Write(chunk); err != nil {
+				return fmt.Errorf("storage.Writer.Write (part %s): %w", part.ObjectName(), err)
+			}
+			if err := wc.Close(); err != nil {
+				return fmt.Errorf("storage.Writer.Close (part %s): %w", part.ObjectName(), err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	composer := bkt.Object(objectName).ComposerFrom(parts...)
+	composer.CacheControl = cacheControl
+	composer.StorageClass = storageClass
+	composer.Metadata = MetadataFromContext(ctx)
+	if contentType != "" {
+		composer.ContentType = contentType
+	}
+	if _, err := composer.Run(ctx); err != nil {
+		return fmt.Errorf("storage.Composer.Run: %w", err)
+	}
+	return nil
+}
+
 // DeleteObject deletes a cloud storage object, returns nil if the object was
 // successfully deleted, or of the object doesn't exist.
 func (s *GoogleCloudStorage) DeleteObject(ctx context.Context, bucket, objectName string) error {
@@ -92,6 +174,7 @@ func (s *GoogleCloudStorage) GetObject(ctx context.Context, bucket, object strin
 		if errors.Is(err, storage.ErrObjectNotExist) {
 			return nil, ErrNotFound
 		}
+		return nil, fmt.Errorf("storage.NewReader: %w", err)
 	}
 	defer r.Close()
 
@@ -102,3 +185,25 @@ func (s *GoogleCloudStorage) GetObject(ctx context.Context, bucket, object strin
 
 	return b.Bytes(), nil
 }
+
+// ListObjects returns the objects in the bucket whose names start with prefix.
+func (s *GoogleCloudStorage) ListObjects(ctx context.Context, bucket, prefix string) ([]*ObjectAttrs, error) {
+	it := s.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+
+	objects := make([]*ObjectAttrs, 0)
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("storage.ObjectIterator.Next: %w", err)
+		}
+		objects = append(objects, &ObjectAttrs{
+			Name:    attrs.Name,
+			Size:    attrs.Size,
+			Updated: attrs.Updated,
+		})
+	}
+	return objects, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/backoff"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// Compile-time check to verify implements interface.
+var (
+	_ Blobstore = (*Instrumented)(nil)
+	_ Lister    = (*Instrumented)(nil)
+)
+
+// Instrumented is a Blobstore that wraps another Blobstore, applying a deadline
+// to each operation, retrying failed operations with exponential backoff, and
+// recording per-operation latency and retry metrics.
+type Instrumented struct {
+	store        Blobstore
+	storeType    string
+	timeout      time.Duration
+	maxRetries   uint64
+	retryBackoff time.Duration
+}
+
+// WrapInstrumented wraps the given blobstore using the timeout and retry
+// settings in cfg. If store is already instrumented, it is returned unchanged.
+func WrapInstrumented(store Blobstore, cfg *Config) Blobstore {
+	if _, ok := store.(*Instrumented); ok {
+		return store
+	}
+
+	return &Instrumented{
+		store:        store,
+		storeType:    cfg.Type,
+		timeout:      cfg.Timeout,
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+	}
+}
+
+// Unwrap returns the underlying blobstore.
+func (s *Instrumented) Unwrap() Blobstore {
+	return s.store
+}
+
+// CreateObject creates or overwrites an object, retrying on failure.
+func (s *Instrumented) CreateObject(ctx context.Context, parent, name string, contents []byte, cacheable bool, contentType string) error {
+	return s.do(ctx, "create", func(ctx context.Context) error {
+		return s.store.CreateObject(ctx, parent, name, contents, cacheable, contentType)
+	})
+}
+
+// DeleteObject deletes an object, retrying on failure.
+func (s *Instrumented) DeleteObject(ctx context.Context, parent, name string) error {
+	return s.do(ctx, "delete", func(ctx context.Context) error {
+		return s.store.DeleteObject(ctx, parent, name)
+	})
+}
+
+// GetObject fetches an object, retrying on failure. ErrNotFound is not
+// retried.
+func (s *Instrumented) GetObject(ctx context.Context, parent, name string) ([]byte, error) {
+	var b []byte
+	err := s.do(ctx, "get", func(ctx context.Context) error {
+		var err error
+		b, err = s.store.GetObject(ctx, parent, name)
+		return err
+	})
+	return b, err
+}
+
+// ListObjects lists objects, retrying on failure. It returns
+// ErrListNotSupported if the wrapped blobstore cannot list.
+func (s *Instrumented) ListObjects(ctx context.Context, parent, prefix string) ([]*ObjectAttrs, error) {
+	var objects []*ObjectAttrs
+	err := s.do(ctx, "list", func(ctx context.Context) error {
+		var err error
+		objects, err = ListObjects(ctx, s.store, parent, prefix)
+		return err
+	})
+	return objects, err
+}
+
+// do runs f with the configured deadline and retries, recording metrics for
+// the operation.
+func (s *Instrumented) do(ctx context.Context, operation string, f func(ctx context.Context) error) (retErr error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := observability.ResultOK
+	defer func() {
+		if retErr != nil {
+			result = observability.ResultNotOK
+			if errors.Is(retErr, ErrNotFound) {
+				result = observability.ResultError("NOT_FOUND")
+			}
+		}
+		tags := []tag.Mutator{
+			tag.Upsert(operationTagKey, operation),
+			tag.Upsert(blobstoreTagKey, s.storeType),
+		}
+		ctx, _ := tag.New(ctx, tags...)
+		observability.RecordLatency(ctx, start, mOperationLatencyMs, &result)
+	}()
+
+	policy := &backoff.Policy{
+		Name:       "blobstore_" + operation,
+		MaxRetries: s.maxRetries,
+		Backoff:    s.retryBackoff,
+		// Do not retry errors that will not change on retry.
+		Retryable: func(err error) bool {
+			return !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrListNotSupported)
+		},
+	}
+
+	attempt := 0
+	return policy.Do(ctx, func(ctx context.Context) error {
+		attempt++
+		if attempt > 1 {
+			ctx, _ := tag.New(ctx, tag.Upsert(operationTagKey, operation), tag.Upsert(blobstoreTagKey, s.storeType))
+			stats.Record(ctx, mOperationRetries.M(1))
+		}
+		return f(ctx)
+	})
+}
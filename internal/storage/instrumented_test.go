@@ -0,0 +1,150 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+// flakyBlobstore counts calls and fails the first n CreateObject calls.
+type flakyBlobstore struct {
+	*Memory
+	failures int
+	calls    int
+}
+
+func (f *flakyBlobstore) CreateObject(ctx context.Context, parent, name string, contents []byte, cacheable bool, contentType string) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return fmt.Errorf("transient failure %d", f.calls)
+	}
+	return f.Memory.CreateObject(ctx, parent, name, contents, cacheable, contentType)
+}
+
+func (f *flakyBlobstore) GetObject(ctx context.Context, parent, name string) ([]byte, error) {
+	f.calls++
+	return f.Memory.GetObject(ctx, parent, name)
+}
+
+func TestInstrumented(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Type:         "MEMORY",
+		Timeout:      5 * time.Second,
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	}
+
+	t.Run("retries_transient", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		mem, _ := NewMemory(ctx, cfg)
+		flaky := &flakyBlobstore{Memory: mem.(*Memory), failures: 2}
+		store := WrapInstrumented(flaky, cfg)
+
+		if err := store.CreateObject(ctx, "bucket", "obj", []byte("hi"), false, ""); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := flaky.calls, 3; got != want {
+			t.Errorf("expected %d calls, got %d", want, got)
+		}
+	})
+
+	t.Run("exhausts_retries", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		mem, _ := NewMemory(ctx, cfg)
+		flaky := &flakyBlobstore{Memory: mem.(*Memory), failures: 10}
+		store := WrapInstrumented(flaky, cfg)
+
+		if err := store.CreateObject(ctx, "bucket", "obj", []byte("hi"), false, ""); err == nil {
+			t.Fatal("expected error")
+		}
+		if got, want := flaky.calls, 3; got != want {
+			t.Errorf("expected %d calls, got %d", want, got)
+		}
+	})
+
+	t.Run("not_found_not_retried", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		mem, _ := NewMemory(ctx, cfg)
+		flaky := &flakyBlobstore{Memory: mem.(*Memory)}
+		store := WrapInstrumented(flaky, cfg)
+
+		if _, err := store.GetObject(ctx, "bucket", "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected %v to be %v", err, ErrNotFound)
+		}
+		if got, want := flaky.calls, 1; got != want {
+			t.Errorf("expected %d calls, got %d", want, got)
+		}
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		mem, _ := NewMemory(ctx, cfg)
+		store := WrapInstrumented(mem, cfg)
+		if err := store.CreateObject(ctx, "bucket", "root/a.zip", []byte("hi"), false, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		objects, err := ListObjects(ctx, store, "bucket", "root/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(objects), 1; got != want {
+			t.Fatalf("expected %d objects, got %d", want, got)
+		}
+	})
+
+	t.Run("list_not_supported", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		mem, _ := NewMemory(ctx, cfg)
+		// Embedding the interface hides the ListObjects method of Memory.
+		store := WrapInstrumented(struct{ Blobstore }{mem}, cfg)
+
+		if _, err := ListObjects(ctx, store, "bucket", ""); !errors.Is(err, ErrListNotSupported) {
+			t.Fatalf("expected %v to be %v", err, ErrListNotSupported)
+		}
+	})
+
+	t.Run("wraps_once", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := project.TestContext(t)
+		mem, _ := NewMemory(ctx, cfg)
+		store := WrapInstrumented(mem, cfg)
+		if got := WrapInstrumented(store, cfg); got != store {
+			t.Errorf("expected instrumented store to not be wrapped again")
+		}
+		if got := store.(*Instrumented).Unwrap(); got != mem {
+			t.Errorf("expected %v to be %v", got, mem)
+		}
+	})
+}
@@ -17,7 +17,10 @@ package storage
 import (
 	"context"
 	"path"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 func init() {
@@ -25,33 +28,65 @@ func init() {
 }
 
 // Compile-time check to verify implements interface.
-var _ Blobstore = (*Memory)(nil)
+var (
+	_ Blobstore = (*Memory)(nil)
+	_ Lister    = (*Memory)(nil)
+)
 
 // Memory implements Blobstore and provides the ability write files to
 // memory.
 type Memory struct {
-	lock sync.Mutex
-	data map[string][]byte
+	lock     sync.Mutex
+	data     map[string][]byte
+	metadata map[string]map[string]string
+	options  map[string]ObjectOptions
+	updated  map[string]time.Time
 }
 
 // NewMemory creates a Blobstore that writes data in memory.
 func NewMemory(_ context.Context, _ *Config) (Blobstore, error) {
 	return &Memory{
-		data: make(map[string][]byte),
+		data:     make(map[string][]byte),
+		metadata: make(map[string]map[string]string),
+		options:  make(map[string]ObjectOptions),
+		updated:  make(map[string]time.Time),
 	}, nil
 }
 
 // CreateObject creates a new object.
 // contentType is ignored in this implementation.
-func (s *Memory) CreateObject(_ context.Context, folder, filename string, contents []byte, cacheable bool, contentType string) error {
+func (s *Memory) CreateObject(ctx context.Context, folder, filename string, contents []byte, cacheable bool, contentType string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
 	pth := path.Join(folder, filename)
 	s.data[pth] = contents
+	s.metadata[pth] = MetadataFromContext(ctx)
+	s.options[pth] = ObjectOptions{
+		CacheControl: cacheControl(ctx, cacheable),
+		StorageClass: ObjectOptionsFromContext(ctx).StorageClass,
+	}
+	s.updated[pth] = time.Now().UTC()
 	return nil
 }
 
+// Metadata returns the custom metadata the given object was created with.
+func (s *Memory) Metadata(folder, filename string) map[string]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.metadata[path.Join(folder, filename)]
+}
+
+// Options returns the options the given object was created with, with the
+// Cache-Control it would be served with.
+func (s *Memory) Options(folder, filename string) ObjectOptions {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.options[path.Join(folder, filename)]
+}
+
 // DeleteObject deletes an object. It returns nil if the object was deleted or
 // if the object no longer exists.
 func (s *Memory) DeleteObject(_ context.Context, folder, filename string) error {
@@ -60,6 +95,9 @@ func (s *Memory) DeleteObject(_ context.Context, folder, filename string) error
 
 	pth := path.Join(folder, filename)
 	delete(s.data, pth)
+	delete(s.metadata, pth)
+	delete(s.options, pth)
+	delete(s.updated, pth)
 	return nil
 }
 
@@ -76,3 +114,37 @@ func (s *Memory) GetObject(_ context.Context, folder, filename string) ([]byte,
 	}
 	return v, nil
 }
+
+// SetUpdated sets the time the given object was last written, to test code
+// that depends on the age of objects.
+func (s *Memory) SetUpdated(folder, filename string, t time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pth := path.Join(folder, filename)
+	if _, ok := s.data[pth]; ok {
+		s.updated[pth] = t
+	}
+}
+
+// ListObjects returns the objects in the folder whose names start with prefix.
+func (s *Memory) ListObjects(_ context.Context, folder, prefix string) ([]*ObjectAttrs, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	base := path.Clean(folder) + "/"
+	objects := make([]*ObjectAttrs, 0)
+	for pth, v := range s.data {
+		name := strings.TrimPrefix(pth, base)
+		if name == pth || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		objects = append(objects, &ObjectAttrs{
+			Name:    name,
+			Size:    int64(len(v)),
+			Updated: s.updated[pth],
+		})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemory_ListObjects(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	store, err := NewMemory(ctx, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := store.(*Memory)
+
+	for _, name := range []string{"root/b.zip", "root/a.zip", "other/c.zip", "rootless.zip"} {
+		if err := mem.CreateObject(ctx, "bucket", name, []byte(name), true, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mem.CreateObject(ctx, "bucket-2", "root/d.zip", []byte("d"), true, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	mem.SetUpdated("bucket", "root/a.zip", updated)
+
+	objects, err := mem.ListObjects(ctx, "bucket", "root/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(objects), 2; got != want {
+		t.Fatalf("expected %d objects, got %d", want, got)
+	}
+	if diff := cmp.Diff(&ObjectAttrs{Name: "root/a.zip", Size: 10, Updated: updated}, objects[0]); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if got, want := objects[1].Name, "root/b.zip"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	all, err := mem.ListObjects(ctx, "bucket", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(all), 4; got != want {
+		t.Errorf("expected %d objects, got %d", want, got)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import "context"
+
+type metadataKey struct{}
+
+// WithMetadata returns a context whose objects are created with the given
+// custom metadata, in addition to any metadata already in the context.
+// Blobstores that support custom metadata attach it to the objects created by
+// CreateObject with the returned context.
+func WithMetadata(ctx context.Context, md map[string]string) context.Context {
+	merged := make(map[string]string, len(md))
+	for k, v := range MetadataFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range md {
+		if v == "" {
+			continue
+		}
+		merged[k] = v
+	}
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// MetadataFromContext returns the custom object metadata in the context. The
+// returned map must not be modified.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return md
+}
+
+type objectOptionsKey struct{}
+
+// ObjectOptions control how objects are stored and served, beyond their
+// contents and custom metadata.
+type ObjectOptions struct {
+	// CacheControl, if set, is the Cache-Control of cacheable objects in place
+	// of the default. Objects that are not cacheable are always served with
+	// headers that prevent caching.
+	CacheControl string
+
+	// StorageClass, if set, is the storage class of objects in the terms of
+	// the blobstore: a storage class for Google Cloud Storage and S3, or an
+	// access tier for Azure.
+	StorageClass string
+}
+
+// WithObjectOptions returns a context whose objects are created with the
+// given options. Options that are not set keep their value in the context.
+func WithObjectOptions(ctx context.Context, opts ObjectOptions) context.Context {
+	merged := ObjectOptionsFromContext(ctx)
+	if opts.CacheControl != "" {
+		merged.CacheControl = opts.CacheControl
+	}
+	if opts.StorageClass != "" {
+		merged.StorageClass = opts.StorageClass
+	}
+	return context.WithValue(ctx, objectOptionsKey{}, merged)
+}
+
+// ObjectOptionsFromContext returns the object options in the context.
+func ObjectOptionsFromContext(ctx context.Context) ObjectOptions {
+	opts, _ := ctx.Value(objectOptionsKey{}).(ObjectOptions)
+	return opts
+}
+
+// cacheControl returns the Cache-Control of an object created with the
+// context.
+func cacheControl(ctx context.Context, cacheable bool) string {
+	if !cacheable {
+		return "no-cache, max-age=0"
+	}
+	if cc := ObjectOptionsFromContext(ctx).CacheControl; cc != "" {
+		return cc
+	}
+	return "public, max-age=86400"
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	if md := MetadataFromContext(ctx); md != nil {
+		t.Errorf("expected no metadata, got %v", md)
+	}
+
+	parent := WithMetadata(ctx, map[string]string{"run_id": "run", "batch_id": "1"})
+	child := WithMetadata(parent, map[string]string{"batch_id": "2", "batch_run_id": ""})
+
+	if diff := cmp.Diff(map[string]string{"run_id": "run", "batch_id": "1"}, MetadataFromContext(parent)); diff != "" {
+		t.Errorf("parent mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"run_id": "run", "batch_id": "2"}, MetadataFromContext(child)); diff != "" {
+		t.Errorf("child mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWithObjectOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	if got := cacheControl(ctx, true); got != "public, max-age=86400" {
+		t.Errorf("expected default cache control, got %q", got)
+	}
+
+	parent := WithObjectOptions(ctx, ObjectOptions{CacheControl: "public, max-age=3600"})
+	child := WithObjectOptions(parent, ObjectOptions{StorageClass: "NEARLINE"})
+
+	want := ObjectOptions{CacheControl: "public, max-age=3600", StorageClass: "NEARLINE"}
+	if diff := cmp.Diff(want, ObjectOptionsFromContext(child)); diff != "" {
+		t.Errorf("options mismatch (-want, +got):\n%s", diff)
+	}
+	if got, want := cacheControl(child, true), "public, max-age=3600"; got != want {
+		t.Errorf("cacheable: got %q, want %q", got, want)
+	}
+	if got, want := cacheControl(child, false), "no-cache, max-age=0"; got != want {
+		t.Errorf("not cacheable: got %q, want %q", got, want)
+	}
+}
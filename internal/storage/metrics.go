@@ -18,8 +18,10 @@ import (
 	"github.com/google/exposure-notifications-server/internal/metrics"
 	"github.com/google/exposure-notifications-server/pkg/observability"
 
+	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 )
 
 const metricPrefix = metrics.MetricRoot + "storage"
@@ -27,6 +29,12 @@ const metricPrefix = metrics.MetricRoot + "storage"
 var (
 	mAzureRefreshFailed  = stats.Int64(metricPrefix+"/azure/refresh_failed", "refresh token failed", stats.UnitDimensionless)
 	mAzureRefreshExpired = stats.Int64(metricPrefix+"/azure/refresh_expired", "refresh token expired", stats.UnitDimensionless)
+
+	mOperationLatencyMs = stats.Float64(metricPrefix+"/operation/latency", "blobstore operation latency", stats.UnitMilliseconds)
+	mOperationRetries   = stats.Int64(metricPrefix+"/operation/retries", "blobstore operation retries", stats.UnitDimensionless)
+
+	operationTagKey = tag.MustNewKey("operation")
+	blobstoreTagKey = tag.MustNewKey("blobstore")
 )
 
 func init() {
@@ -43,5 +51,26 @@ func init() {
 			Measure:     mAzureRefreshExpired,
 			Aggregation: view.Count(),
 		},
+		{
+			Name:        metricPrefix + "/operation/count",
+			Description: "Number of blobstore operations",
+			Measure:     mOperationLatencyMs,
+			TagKeys:     []tag.Key{operationTagKey, blobstoreTagKey, observability.ResultTagKey},
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/operation/latency",
+			Description: "Distribution of blobstore operation latency in milliseconds",
+			Measure:     mOperationLatencyMs,
+			TagKeys:     []tag.Key{operationTagKey, blobstoreTagKey, observability.ResultTagKey},
+			Aggregation: ochttp.DefaultLatencyDistribution,
+		},
+		{
+			Name:        metricPrefix + "/operation/retries",
+			Description: "Number of blobstore operation retries",
+			Measure:     mOperationRetries,
+			TagKeys:     []tag.Key{operationTagKey, blobstoreTagKey},
+			Aggregation: view.Count(),
+		},
 	}...)
 }
@@ -20,28 +20,75 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 )
 
 var ErrNotFound = fmt.Errorf("storage object not found")
 
+// ErrListNotSupported is returned when listing objects of a blobstore that
+// does not implement Lister.
+var ErrListNotSupported = fmt.Errorf("storage does not support listing objects")
+
 const (
 	ContentTypeTextPlain = "text/plain"
 	ContentTypeZip       = "application/zip"
+	ContentTypeJSON      = "application/json"
 )
 
 // Blobstore defines the minimum interface for a blob storage system.
+//
+// Objects are addressed by a parent (a bucket, container, or directory
+// depending on the implementation) and a name, which may contain slashes. All
+// methods must respect cancellation and deadlines on the provided context.
+// Implementations should not retry internally; blobstores returned by
+// BlobstoreFor are wrapped with retries, deadlines, and metrics (see
+// WrapInstrumented).
 type Blobstore interface {
-	// CreateObject creates or overwrites an object in the storage system.
-	// If contentType is blank, the default for the chosen storage implementation is used.
+	// CreateObject creates or overwrites an object in the storage system. If
+	// cacheable is false, the object should be served with headers that
+	// prevent caching. If contentType is blank, the default for the chosen
+	// storage implementation is used.
 	CreateObject(ctx context.Context, parent, name string, contents []byte, cacheable bool, contentType string) error
 
-	// DeleteObject deletes an object or does nothing if the object doesn't exist.
-	DeleteObject(ctx context.Context, parent, bame string) error
+	// DeleteObject deletes an object or does nothing if the object doesn't
+	// exist.
+	DeleteObject(ctx context.Context, parent, name string) error
 
-	// GetObject fetches the object's contents.
+	// GetObject fetches the object's contents. If the object does not exist, it
+	// returns ErrNotFound.
 	GetObject(ctx context.Context, parent, name string) ([]byte, error)
 }
 
+// ObjectAttrs are the attributes of a stored object.
+type ObjectAttrs struct {
+	// Name is the name of the object in its parent.
+	Name string
+
+	// Size is the size of the object in bytes.
+	Size int64
+
+	// Updated is the time the object was last written.
+	Updated time.Time
+}
+
+// Lister is implemented by blobstores that can list their objects. It is
+// optional, and only needed to audit what is stored.
+type Lister interface {
+	// ListObjects returns the objects in parent whose names start with prefix,
+	// sorted by name.
+	ListObjects(ctx context.Context, parent, prefix string) ([]*ObjectAttrs, error)
+}
+
+// ListObjects lists the objects in parent whose names start with prefix. It
+// returns ErrListNotSupported if the blobstore does not implement Lister.
+func ListObjects(ctx context.Context, store Blobstore, parent, prefix string) ([]*ObjectAttrs, error) {
+	lister, ok := store.(Lister)
+	if !ok {
+		return nil, ErrListNotSupported
+	}
+	return lister.ListObjects(ctx, parent, prefix)
+}
+
 // BlobstoreFunc is a func that returns a blobstore or error.
 type BlobstoreFunc func(context.Context, *Config) (Blobstore, error)
 
@@ -79,7 +126,8 @@ func RegisteredBlobstores() []string {
 }
 
 // BlobstoreFor returns the blobstore with the given name, or an error if one
-// does not exist.
+// does not exist. The returned blobstore is wrapped with per-operation
+// deadlines, retries, and metrics.
 func BlobstoreFor(ctx context.Context, cfg *Config) (Blobstore, error) {
 	blobstoresLock.RLock()
 	defer blobstoresLock.RUnlock()
@@ -89,5 +137,10 @@ func BlobstoreFor(ctx context.Context, cfg *Config) (Blobstore, error) {
 	if !ok {
 		return nil, fmt.Errorf("unknown or uncompiled blobstore %q", name)
 	}
-	return fn(ctx, cfg)
+
+	store, err := fn(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return WrapInstrumented(store, cfg), nil
 }
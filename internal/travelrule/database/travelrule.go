@@ -0,0 +1,171 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database is a database interface for travel rules.
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/travelrule/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/jackc/pgx/v5"
+)
+
+type TravelRuleDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) *TravelRuleDB {
+	return &TravelRuleDB{
+		db: db,
+	}
+}
+
+// AddTravelRule inserts the rule and sets its ID.
+func (db *TravelRuleDB) AddTravelRule(ctx context.Context, r *model.TravelRule) error {
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("invalid travel rule: %w", err)
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				TravelRule (name, input_regions, travelers_only, output_regions, apply_to_export, apply_to_federation)
+			VALUES
+				($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, r.Name, r.InputRegions, r.TravelersOnly, r.OutputRegions, r.ApplyToExport, r.ApplyToFederation)
+
+		if err := row.Scan(&r.ID); err != nil {
+			return fmt.Errorf("fetching travelrule.ID: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateTravelRule updates the given rule in the database. It must already
+// exist in the database, keyed off of ID.
+func (db *TravelRuleDB) UpdateTravelRule(ctx context.Context, r *model.TravelRule) error {
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("invalid travel rule: %w", err)
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE
+				TravelRule
+			SET
+				name = $2,
+				input_regions = $3,
+				travelers_only = $4,
+				output_regions = $5,
+				apply_to_export = $6,
+				apply_to_federation = $7
+			WHERE id = $1
+		`, r.ID, r.Name, r.InputRegions, r.TravelersOnly, r.OutputRegions, r.ApplyToExport, r.ApplyToFederation)
+		if err != nil {
+			return fmt.Errorf("failed to update travel rule: %w", err)
+		}
+
+		switch v := result.RowsAffected(); v {
+		case 0:
+			return fmt.Errorf("no rows were updated (does the record exist?)")
+		case 1:
+			return nil
+		default:
+			return fmt.Errorf("only 1 row should have been updated, got %d", v)
+		}
+	})
+}
+
+// DeleteTravelRule deletes the rule with the given ID.
+func (db *TravelRuleDB) DeleteTravelRule(ctx context.Context, id int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM TravelRule WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete travel rule: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetTravelRule returns the rule with the given ID.
+func (db *TravelRuleDB) GetTravelRule(ctx context.Context, id int64) (*model.TravelRule, error) {
+	var rule *model.TravelRule
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				id, name, input_regions, travelers_only, output_regions, apply_to_export, apply_to_federation
+			FROM
+				TravelRule
+			WHERE
+				id = $1
+		`, id)
+
+		var err error
+		rule, err = scanOneTravelRule(row)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get travel rule %d: %w", id, err)
+	}
+
+	return rule, nil
+}
+
+// ListTravelRules returns all travel rules, ordered by id.
+func (db *TravelRuleDB) ListTravelRules(ctx context.Context) ([]*model.TravelRule, error) {
+	var rules []*model.TravelRule
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				id, name, input_regions, travelers_only, output_regions, apply_to_export, apply_to_federation
+			FROM
+				TravelRule
+			ORDER BY id
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			r, err := scanOneTravelRule(rows)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, r)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("listing travel rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// scanOneTravelRule scans a single pgx row into a travel rule model.
+func scanOneTravelRule(row pgx.Row) (*model.TravelRule, error) {
+	var r model.TravelRule
+	if err := row.Scan(&r.ID, &r.Name, &r.InputRegions, &r.TravelersOnly, &r.OutputRegions, &r.ApplyToExport, &r.ApplyToFederation); err != nil {
+		return nil, fmt.Errorf("failed to scan travel rule row: %w", err)
+	}
+	return &r, nil
+}
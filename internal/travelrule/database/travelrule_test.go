@@ -0,0 +1,91 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/travelrule/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTravelRule_Lifecycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	ruleDB := New(testDB)
+
+	want := []*model.TravelRule{
+		{
+			Name:              "eu-travelers",
+			InputRegions:      []string{"DE", "FR"},
+			TravelersOnly:     true,
+			OutputRegions:     []string{"DE", "FR"},
+			ApplyToExport:     true,
+			ApplyToFederation: true,
+		},
+		{
+			Name:          "all-travelers",
+			InputRegions:  []string{},
+			TravelersOnly: true,
+			OutputRegions: []string{"US"},
+			ApplyToExport: true,
+		},
+	}
+	for _, w := range want {
+		if err := ruleDB.AddTravelRule(ctx, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules, err := ruleDB.ListTravelRules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, rules); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	rule, err := ruleDB.GetTravelRule(ctx, rules[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule.OutputRegions = []string{"de", "fr", "it"}
+	if err := ruleDB.UpdateTravelRule(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ruleDB.GetTravelRule(ctx, rule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"DE", "FR", "IT"}, got.OutputRegions); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if err := ruleDB.DeleteTravelRule(ctx, rule.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ruleDB.GetTravelRule(ctx, rule.ID); err == nil {
+		t.Errorf("expected error getting deleted rule")
+	}
+
+	// Invalid rules are rejected.
+	if err := ruleDB.AddTravelRule(ctx, &model.TravelRule{Name: "bad"}); err == nil {
+		t.Errorf("expected error adding invalid rule")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model is a model abstraction of travel rules.
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TravelRule maps keys uploaded for a set of input regions to additional
+// output regions. For example, a rule with the EU member states as both input
+// and output regions and TravelersOnly set causes travelers from any EU region
+// to appear in every EU export.
+type TravelRule struct {
+	ID   int64
+	Name string
+
+	// InputRegions are the regions a key must be associated with for the rule
+	// to apply. If empty, the rule applies to keys from any region; this is
+	// only permitted for traveler-only rules.
+	InputRegions []string
+
+	// TravelersOnly restricts the rule to keys marked as travelers.
+	TravelersOnly bool
+
+	// OutputRegions are the regions in which matching keys are distributed.
+	OutputRegions []string
+
+	// ApplyToExport and ApplyToFederation control where the rule is used.
+	ApplyToExport     bool
+	ApplyToFederation bool
+}
+
+// Validate normalizes the regions on the rule and checks that the rule is
+// well-formed.
+func (r *TravelRule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.InputRegions = normalizeRegions(r.InputRegions)
+	r.OutputRegions = normalizeRegions(r.OutputRegions)
+
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(r.OutputRegions) == 0 {
+		return errors.New("at least one output region is required")
+	}
+	if len(r.InputRegions) == 0 && !r.TravelersOnly {
+		return errors.New("input regions are required unless the rule only applies to travelers")
+	}
+	if !r.ApplyToExport && !r.ApplyToFederation {
+		return fmt.Errorf("rule %q must apply to export, federation, or both", r.Name)
+	}
+	return nil
+}
+
+// MatchesInput returns true if a key with the given regions and traveler
+// status is matched by this rule.
+func (r *TravelRule) MatchesInput(regions []string, traveler bool) bool {
+	if r.TravelersOnly && !traveler {
+		return false
+	}
+	if len(r.InputRegions) == 0 {
+		return true
+	}
+	for _, in := range r.InputRegions {
+		for _, region := range regions {
+			if strings.EqualFold(in, region) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasOutput returns true if the rule distributes keys to the given region.
+func (r *TravelRule) HasOutput(region string) bool {
+	for _, out := range r.OutputRegions {
+		if strings.EqualFold(out, region) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TravelRule) InputRegionsOnePerLine() string {
+	return strings.Join(r.InputRegions, "\n")
+}
+
+func (r *TravelRule) OutputRegionsOnePerLine() string {
+	return strings.Join(r.OutputRegions, "\n")
+}
+
+// normalizeRegions upper-cases, trims, de-duplicates, and sorts the regions.
+func normalizeRegions(in []string) []string {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]string, 0, len(in))
+	for _, region := range in {
+		region = strings.ToUpper(strings.TrimSpace(region))
+		if region == "" {
+			continue
+		}
+		if _, ok := seen[region]; ok {
+			continue
+		}
+		seen[region] = struct{}{}
+		out = append(out, region)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTravelRule_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		rule *TravelRule
+		want *TravelRule
+		err  bool
+	}{
+		{
+			name: "normalizes",
+			rule: &TravelRule{
+				Name:          " eu ",
+				InputRegions:  []string{"fr", "DE", "de", ""},
+				TravelersOnly: true,
+				OutputRegions: []string{" it"},
+				ApplyToExport: true,
+			},
+			want: &TravelRule{
+				Name:          "eu",
+				InputRegions:  []string{"DE", "FR"},
+				TravelersOnly: true,
+				OutputRegions: []string{"IT"},
+				ApplyToExport: true,
+			},
+		},
+		{
+			name: "missing_name",
+			rule: &TravelRule{OutputRegions: []string{"US"}, TravelersOnly: true, ApplyToExport: true},
+			err:  true,
+		},
+		{
+			name: "missing_outputs",
+			rule: &TravelRule{Name: "a", TravelersOnly: true, ApplyToExport: true},
+			err:  true,
+		},
+		{
+			name: "any_region_non_traveler",
+			rule: &TravelRule{Name: "a", OutputRegions: []string{"US"}, ApplyToExport: true},
+			err:  true,
+		},
+		{
+			name: "no_target",
+			rule: &TravelRule{Name: "a", OutputRegions: []string{"US"}, TravelersOnly: true},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.rule.Validate()
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if tc.want != nil {
+				if diff := cmp.Diff(tc.want, tc.rule); diff != "" {
+					t.Errorf("mismatch (-want, +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package travelrule maps the regions and traveler status of uploaded keys to
+// the regions in which they are distributed, for both exports and federation.
+package travelrule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/travelrule/database"
+	"github.com/google/exposure-notifications-server/internal/travelrule/model"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+)
+
+// Target is the destination to which rules are applied.
+type Target int
+
+const (
+	TargetExport Target = iota
+	TargetFederation
+)
+
+// Sources describes which keys feed a set of output regions.
+type Sources struct {
+	// Regions are input regions from which all keys are included.
+	Regions []string
+
+	// TravelerRegions are input regions from which only traveler keys are
+	// included.
+	TravelerRegions []string
+
+	// AllTravelers indicates that traveler keys from any region are included.
+	AllTravelers bool
+}
+
+// Engine evaluates travel rules. The zero value and a nil *Engine have no
+// rules.
+type Engine struct {
+	rules []*model.TravelRule
+}
+
+// NewEngine creates an engine with the given rules.
+func NewEngine(rules []*model.TravelRule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Load creates an engine with the rules currently in the database.
+func Load(ctx context.Context, db *coredb.DB) (*Engine, error) {
+	rules, err := database.New(db).ListTravelRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load travel rules: %w", err)
+	}
+	return NewEngine(rules), nil
+}
+
+// rulesFor returns the rules that apply to the given target.
+func (e *Engine) rulesFor(target Target) []*model.TravelRule {
+	if e == nil {
+		return nil
+	}
+
+	rules := make([]*model.TravelRule, 0, len(e.rules))
+	for _, r := range e.rules {
+		if (target == TargetExport && r.ApplyToExport) || (target == TargetFederation && r.ApplyToFederation) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// SourcesFor returns the input regions whose keys are distributed to any of
+// the given output regions by the rules for target.
+func (e *Engine) SourcesFor(target Target, outputRegions ...string) *Sources {
+	regions := make(map[string]struct{})
+	travelerRegions := make(map[string]struct{})
+	var allTravelers bool
+
+	for _, r := range e.rulesFor(target) {
+		matched := false
+		for _, out := range outputRegions {
+			if r.HasOutput(out) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		switch {
+		case len(r.InputRegions) == 0:
+			allTravelers = true
+		case r.TravelersOnly:
+			for _, in := range r.InputRegions {
+				travelerRegions[in] = struct{}{}
+			}
+		default:
+			for _, in := range r.InputRegions {
+				regions[in] = struct{}{}
+			}
+		}
+	}
+
+	return &Sources{
+		Regions:         sortedKeys(regions),
+		TravelerRegions: sortedKeys(travelerRegions),
+		AllTravelers:    allTravelers,
+	}
+}
+
+// OutputRegions returns the additional regions in which a key with the given
+// regions and traveler status is distributed by the rules for target.
+func (e *Engine) OutputRegions(target Target, regions []string, traveler bool) []string {
+	out := make(map[string]struct{})
+	for _, r := range e.rulesFor(target) {
+		if !r.MatchesInput(regions, traveler) {
+			continue
+		}
+		for _, region := range r.OutputRegions {
+			out[strings.ToUpper(region)] = struct{}{}
+		}
+	}
+	return sortedKeys(out)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,173 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package travelrule
+
+import (
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/travelrule/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testEngine() *Engine {
+	return NewEngine([]*model.TravelRule{
+		{
+			Name:              "eu-travelers",
+			InputRegions:      []string{"DE", "FR", "IT"},
+			TravelersOnly:     true,
+			OutputRegions:     []string{"DE", "FR", "IT"},
+			ApplyToExport:     true,
+			ApplyToFederation: true,
+		},
+		{
+			Name:          "ca-into-us",
+			InputRegions:  []string{"CA"},
+			OutputRegions: []string{"US"},
+			ApplyToExport: true,
+		},
+		{
+			Name:              "all-travelers-mx",
+			TravelersOnly:     true,
+			OutputRegions:     []string{"MX"},
+			ApplyToFederation: true,
+		},
+	})
+}
+
+func TestEngine_SourcesFor(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		target  Target
+		outputs []string
+		want    *Sources
+	}{
+		{
+			name:    "eu_export",
+			target:  TargetExport,
+			outputs: []string{"FR"},
+			want:    &Sources{TravelerRegions: []string{"DE", "FR", "IT"}},
+		},
+		{
+			name:    "us_export",
+			target:  TargetExport,
+			outputs: []string{"US"},
+			want:    &Sources{Regions: []string{"CA"}},
+		},
+		{
+			name:    "us_federation",
+			target:  TargetFederation,
+			outputs: []string{"US"},
+			want:    &Sources{},
+		},
+		{
+			name:    "mx_federation",
+			target:  TargetFederation,
+			outputs: []string{"MX", "DE"},
+			want:    &Sources{TravelerRegions: []string{"DE", "FR", "IT"}, AllTravelers: true},
+		},
+		{
+			name:    "no_match",
+			target:  TargetExport,
+			outputs: []string{"JP"},
+			want:    &Sources{},
+		},
+	}
+
+	engine := testEngine()
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := engine.SourcesFor(tc.target, tc.outputs...)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEngine_OutputRegions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		target   Target
+		regions  []string
+		traveler bool
+		want     []string
+	}{
+		{
+			name:     "eu_traveler",
+			target:   TargetExport,
+			regions:  []string{"DE"},
+			traveler: true,
+			want:     []string{"DE", "FR", "IT"},
+		},
+		{
+			name:     "eu_non_traveler",
+			target:   TargetExport,
+			regions:  []string{"DE"},
+			traveler: false,
+		},
+		{
+			name:    "ca_export",
+			target:  TargetExport,
+			regions: []string{"CA"},
+			want:    []string{"US"},
+		},
+		{
+			name:    "ca_federation",
+			target:  TargetFederation,
+			regions: []string{"CA"},
+		},
+		{
+			name:     "any_traveler_federation",
+			target:   TargetFederation,
+			regions:  []string{"JP"},
+			traveler: true,
+			want:     []string{"MX"},
+		},
+	}
+
+	engine := testEngine()
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := engine.OutputRegions(tc.target, tc.regions, tc.traveler)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEngine_Nil(t *testing.T) {
+	t.Parallel()
+
+	var engine *Engine
+	if diff := cmp.Diff(&Sources{}, engine.SourcesFor(TargetExport, "US")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if got := engine.OutputRegions(TargetExport, []string{"US"}, true); got != nil {
+		t.Errorf("expected no output regions, got %v", got)
+	}
+}
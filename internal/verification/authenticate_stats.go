@@ -30,11 +30,8 @@ func (v *Verifier) AuthenticateStatsToken(ctx context.Context, rawToken string)
 	var healthAuthorityID int64
 	var claims *jwt.StandardClaims
 
-	token, err := jwt.ParseWithClaims(rawToken, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if method, ok := token.Method.(*jwt.SigningMethodECDSA); !ok || method.Name != jwt.SigningMethodES256.Name {
-			return nil, fmt.Errorf("unsupported signing method, must be %v", jwt.SigningMethodES256.Name)
-		}
-
+	parser := &jwt.Parser{ValidMethods: model.Algorithms}
+	token, err := parser.ParseWithClaims(rawToken, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
 		kidHeader, ok := token.Header["kid"]
 		if !ok {
 			err := errors.New("missing 'kid' header in token")
@@ -82,6 +79,9 @@ func (v *Verifier) AuthenticateStatsToken(ctx context.Context, rawToken string)
 		// Look for the matching 'kid'
 		for _, key := range healthAuthority.Keys {
 			if key.Version == kid && key.IsValid() {
+				if err := checkSigningMethod(token, key); err != nil {
+					return nil, err
+				}
 				healthAuthorityID = healthAuthority.ID
 				return key.PublicKey()
 			}
@@ -220,7 +220,7 @@ func TestAuthenticateStatsToken(t *testing.T) {
 			}
 			jwtString = tc.ModifyJWT(jwtString)
 
-			verifier, err := New(haDB, &Config{time.Nanosecond, statsAudience})
+			verifier, err := New(haDB, &Config{CacheDuration: time.Nanosecond, StatsAudience: statsAudience})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -21,6 +21,11 @@ import "time"
 type Config struct {
 	CacheDuration time.Duration `env:"VERIFICATION_CACHE_DURATION, default=5m"`
 
+	// Providers are the names of the enabled verification providers. Each
+	// health authority selects one of them, blank selects the standard PHA JWT
+	// certificates.
+	Providers []string `env:"VERIFICATION_PROVIDERS, default=pha-jwt"`
+
 	// StatsAudience is the expected JWT 'aud' value when calling the /v1/stats API.
 	StatsAudience string `env:"STATS_AUDIENCE, default=keyserver"`
 }
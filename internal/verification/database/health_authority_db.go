@@ -21,10 +21,12 @@ import (
 	"fmt"
 	"time"
 
+	keylogdb "github.com/google/exposure-notifications-server/internal/keylog/database"
+	keylogmodel "github.com/google/exposure-notifications-server/internal/keylog/model"
 	"github.com/google/exposure-notifications-server/internal/verification/model"
 	"github.com/google/exposure-notifications-server/pkg/database"
 
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 var ErrHealthAuthorityNotFound = errors.New("health authority not found")
@@ -51,16 +53,21 @@ func (db *HealthAuthorityDB) AddHealthAuthority(ctx context.Context, ha *model.H
 	if err := ha.Validate(); err != nil {
 		return err
 	}
+	if ha.Stage == "" {
+		ha.Stage = model.StageActive
+	}
 
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			INSERT INTO
 				HealthAuthority
-				(iss, aud, name, jwks_uri, enable_stats)
+				(iss, aud, name, jwks_uri, enable_stats, jurisdiction, export_delay_seconds, stage,
+				verification_provider, hmac_schemes)
 			VALUES
-				($1, $2, $3, $4, $5)
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 			RETURNING id
-			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI)
+			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI, ha.Jurisdiction,
+			int64(ha.ExportDelay.Seconds()), ha.Stage, ha.VerificationProvider, hmacSchemes(ha))
 		if err := row.Scan(&ha.ID); err != nil {
 			return fmt.Errorf("inserting healthauthority: %w", err)
 		}
@@ -76,15 +83,20 @@ func (db *HealthAuthorityDB) UpdateHealthAuthority(ctx context.Context, ha *mode
 	if err := ha.Validate(); err != nil {
 		return err
 	}
+	if ha.Stage == "" {
+		ha.Stage = model.StageActive
+	}
 
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		result, err := tx.Exec(ctx, `
 			UPDATE HealthAuthority
 			SET
-				iss = $1, aud = $2, name = $3, jwks_uri = $4, enable_stats = $5
+				iss = $1, aud = $2, name = $3, jwks_uri = $4, enable_stats = $5, jurisdiction = $6,
+				export_delay_seconds = $7, stage = $8, verification_provider = $9, hmac_schemes = $10
 			WHERE
-				id = $6
-			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI, ha.ID)
+				id = $11
+			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI, ha.Jurisdiction,
+			int64(ha.ExportDelay.Seconds()), ha.Stage, ha.VerificationProvider, hmacSchemes(ha), ha.ID)
 		if err != nil {
 			return fmt.Errorf("updating health authority: %w", err)
 		}
@@ -101,7 +113,8 @@ func (db *HealthAuthorityDB) GetHealthAuthorityByID(ctx context.Context, id int6
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			SELECT
-				id, iss, aud, name, jwks_uri, enable_stats
+				id, iss, aud, name, jwks_uri, enable_stats, jurisdiction, export_delay_seconds, stage,
+				verification_provider, hmac_schemes
 			FROM
 				HealthAuthority
 			WHERE
@@ -134,7 +147,8 @@ func (db *HealthAuthorityDB) GetHealthAuthority(ctx context.Context, issuer stri
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			SELECT
-				id, iss, aud, name, jwks_uri, enable_stats
+				id, iss, aud, name, jwks_uri, enable_stats, jurisdiction, export_delay_seconds, stage,
+				verification_provider, hmac_schemes
 			FROM
 				HealthAuthority
 			WHERE
@@ -171,7 +185,8 @@ func (db *HealthAuthorityDB) ListAllHealthAuthoritiesWithoutKeys(ctx context.Con
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				id, iss, aud, name, jwks_uri, enable_stats
+				id, iss, aud, name, jwks_uri, enable_stats, jurisdiction, export_delay_seconds, stage,
+				verification_provider, hmac_schemes
 			FROM
 				HealthAuthority
 			ORDER BY iss ASC
@@ -200,11 +215,84 @@ func (db *HealthAuthorityDB) ListAllHealthAuthoritiesWithoutKeys(ctx context.Con
 	return has, nil
 }
 
+// healthAuthorityListQuery searches health authorities by issuer, audience,
+// name and jurisdiction.
+var healthAuthorityListQuery = &database.ListQuery{
+	SearchColumns: []string{"iss", "aud", "name", "jurisdiction"},
+	SortColumns: map[string]string{
+		"issuer":       "iss",
+		"name":         "LOWER(name)",
+		"jurisdiction": "jurisdiction",
+	},
+	DefaultSort: "issuer",
+	TieBreaker:  "id",
+}
+
+// SearchHealthAuthorities reads one page of the health authorities, without
+// their keys. The sort keys are "issuer", "name" and "jurisdiction". Support
+// function for admin console.
+func (db *HealthAuthorityDB) SearchHealthAuthorities(ctx context.Context, params *database.PageParams) ([]*model.HealthAuthority, *database.Page, error) {
+	where, order, args, err := healthAuthorityListQuery.Build(params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search health authorities: %w", err)
+	}
+
+	var has []*model.HealthAuthority
+	var total int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM HealthAuthority `+where, args...).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT
+				id, iss, aud, name, jwks_uri, enable_stats, jurisdiction, export_delay_seconds, stage,
+				verification_provider, hmac_schemes
+			FROM
+				HealthAuthority
+			`+where+`
+			`+order, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			ha, err := scanOneHealthAuthority(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			has = append(has, ha)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, nil, fmt.Errorf("search health authorities: %w", err)
+	}
+
+	return has, database.NewPage(params, total), nil
+}
+
+// hmacSchemes returns the HMAC schemes of the health authority, as a non-nil
+// slice for the NOT NULL column.
+func hmacSchemes(ha *model.HealthAuthority) []string {
+	if ha.HMACSchemes == nil {
+		return []string{}
+	}
+	return ha.HMACSchemes
+}
+
 func scanOneHealthAuthority(row pgx.Row) (*model.HealthAuthority, error) {
 	var ha model.HealthAuthority
-	if err := row.Scan(&ha.ID, &ha.Issuer, &ha.Audience, &ha.Name, &ha.JwksURI, &ha.EnableStatsAPI); err != nil {
+	var exportDelaySeconds int64
+	var schemes []string
+	if err := row.Scan(&ha.ID, &ha.Issuer, &ha.Audience, &ha.Name, &ha.JwksURI, &ha.EnableStatsAPI, &ha.Jurisdiction,
+		&exportDelaySeconds, &ha.Stage, &ha.VerificationProvider, &schemes); err != nil {
 		return nil, err
 	}
+	ha.ExportDelay = time.Duration(exportDelaySeconds) * time.Second
+	if len(schemes) > 0 {
+		ha.HMACSchemes = schemes
+	}
 	return &ha, nil
 }
 
@@ -224,22 +312,28 @@ func (db *HealthAuthorityDB) AddHealthAuthorityKey(ctx context.Context, ha *mode
 	}
 
 	hak.AuthorityID = ha.ID
+	if hak.Stage == "" {
+		hak.Stage = model.StageActive
+	}
+	if hak.Algorithm == "" {
+		hak.Algorithm = model.AlgorithmES256
+	}
 	thru := database.NullableTime(hak.Thru)
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		result, err := tx.Exec(ctx, `
 			INSERT INTO
 				HealthAuthorityKey
-				(health_authority_id, version, from_timestamp, thru_timestamp, public_key)
+				(health_authority_id, version, from_timestamp, thru_timestamp, public_key, stage, algorithm)
 			VALUES
-				($1, $2, $3, $4, $5)
-			`, hak.AuthorityID, hak.Version, hak.From, thru, hak.PublicKeyPEM)
+				($1, $2, $3, $4, $5, $6, $7)
+			`, hak.AuthorityID, hak.Version, hak.From, thru, hak.PublicKeyPEM, hak.Stage, hak.Algorithm)
 		if err != nil {
 			return fmt.Errorf("inserting healthauthoritykey: %w", err)
 		}
 		if result.RowsAffected() != 1 {
 			return fmt.Errorf("no rows inserted")
 		}
-		return nil
+		return logHealthAuthorityKey(ctx, tx, hak)
 	})
 }
 
@@ -264,36 +358,61 @@ func (db *HealthAuthorityDB) PurgeHealthAuthorityKeys(ctx context.Context, ha *m
 }
 
 func (db *HealthAuthorityDB) UpdateHealthAuthorityKey(ctx context.Context, hak *model.HealthAuthorityKey) error {
-	if _, err := hak.PublicKey(); err != nil {
+	if err := hak.Validate(); err != nil {
 		return err
 	}
+	if hak.Stage == "" {
+		hak.Stage = model.StageActive
+	}
+	if hak.Algorithm == "" {
+		hak.Algorithm = model.AlgorithmES256
+	}
 
 	thru := database.NullableTime(hak.Thru)
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		result, err := tx.Exec(ctx, `
 			UPDATE HealthAuthorityKey
 			SET
-				from_timestamp = $1, thru_timestamp = $2, public_key = $3
+				from_timestamp = $1, thru_timestamp = $2, public_key = $3, stage = $4, algorithm = $5
 			WHERE
-				health_authority_id = $4 AND version = $5
-			`, hak.From, thru, hak.PublicKeyPEM, hak.AuthorityID, hak.Version)
+				health_authority_id = $6 AND version = $7
+			`, hak.From, thru, hak.PublicKeyPEM, hak.Stage, hak.Algorithm, hak.AuthorityID, hak.Version)
 		if err != nil {
 			return fmt.Errorf("updating health authority key: %w", err)
 		}
 		if result.RowsAffected() != 1 {
 			return fmt.Errorf("no rows updated")
 		}
-		return nil
+		return logHealthAuthorityKey(ctx, tx, hak)
 	})
 }
 
+// logHealthAuthorityKey appends the key to the key transparency log.
+func logHealthAuthorityKey(ctx context.Context, tx pgx.Tx, hak *model.HealthAuthorityKey) error {
+	data := &keylogmodel.HealthAuthorityKey{
+		HealthAuthorityID: hak.AuthorityID,
+		Version:           hak.Version,
+		From:              hak.From.Unix(),
+		PublicKey:         hak.PublicKeyPEM,
+		Stage:             string(hak.Stage),
+		Algorithm:         hak.Algorithm,
+	}
+	if !hak.Thru.IsZero() {
+		data.Thru = hak.Thru.Unix()
+	}
+	if _, err := keylogdb.Append(ctx, tx, keylogmodel.KindHealthAuthorityKey, data, time.Now()); err != nil {
+		return fmt.Errorf("logging health authority key: %w", err)
+	}
+	return nil
+}
+
 func (db *HealthAuthorityDB) GetHealthAuthorityKeys(ctx context.Context, ha *model.HealthAuthority) ([]*model.HealthAuthorityKey, error) {
 	var keys []*model.HealthAuthorityKey
 
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				health_authority_id, version, from_timestamp, thru_timestamp, public_key
+				health_authority_id, version, from_timestamp, thru_timestamp, public_key, stage, algorithm
 			FROM
 				HealthAuthorityKey
 			WHERE
@@ -313,7 +432,7 @@ func (db *HealthAuthorityDB) GetHealthAuthorityKeys(ctx context.Context, ha *mod
 
 			var key model.HealthAuthorityKey
 			var thru *time.Time
-			if err := rows.Scan(&key.AuthorityID, &key.Version, &key.From, &thru, &key.PublicKeyPEM); err != nil {
+			if err := rows.Scan(&key.AuthorityID, &key.Version, &key.From, &thru, &key.PublicKeyPEM, &key.Stage, &key.Algorithm); err != nil {
 				return fmt.Errorf("failed to parse: %w", err)
 			}
 			if thru != nil {
@@ -329,3 +448,80 @@ func (db *HealthAuthorityDB) GetHealthAuthorityKeys(ctx context.Context, ha *mod
 
 	return keys, nil
 }
+
+// ListReportTypeTransitions returns the report type transition overrides for
+// the given health authority.
+func (db *HealthAuthorityDB) ListReportTypeTransitions(ctx context.Context, healthAuthorityID int64) ([]*model.ReportTypeTransition, error) {
+	var transitions []*model.ReportTypeTransition
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				health_authority_id, from_report_type, to_report_type, allowed
+			FROM
+				ReportTypeTransition
+			WHERE
+				health_authority_id = $1
+			ORDER BY
+				from_report_type, to_report_type
+		`, healthAuthorityID)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			var t model.ReportTypeTransition
+			if err := rows.Scan(&t.AuthorityID, &t.From, &t.To, &t.Allowed); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			transitions = append(transitions, &t)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list report type transitions: %w", err)
+	}
+
+	return transitions, nil
+}
+
+// SetReportTypeTransitions replaces all of the report type transition
+// overrides for the given health authority.
+func (db *HealthAuthorityDB) SetReportTypeTransitions(ctx context.Context, healthAuthorityID int64, transitions []*model.ReportTypeTransition) error {
+	if healthAuthorityID == 0 {
+		return errors.New("invalid health authority ID, must be non zero")
+	}
+	for _, t := range transitions {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM ReportTypeTransition
+			WHERE
+				health_authority_id = $1
+			`, healthAuthorityID); err != nil {
+			return fmt.Errorf("deleting report type transitions: %w", err)
+		}
+
+		for _, t := range transitions {
+			t.AuthorityID = healthAuthorityID
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO
+					ReportTypeTransition
+					(health_authority_id, from_report_type, to_report_type, allowed)
+				VALUES
+					($1, $2, $3, $4)
+				`, t.AuthorityID, t.From, t.To, t.Allowed); err != nil {
+				return fmt.Errorf("inserting report type transition: %w", err)
+			}
+		}
+		return nil
+	})
+}
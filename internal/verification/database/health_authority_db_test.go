@@ -19,6 +19,8 @@ import (
 	"testing"
 	"time"
 
+	keylogdb "github.com/google/exposure-notifications-server/internal/keylog/database"
+	keylogmodel "github.com/google/exposure-notifications-server/internal/keylog/model"
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/verification/model"
 	"github.com/google/exposure-notifications-server/pkg/errcmp"
@@ -105,10 +107,12 @@ func TestAddRetrieveHealthAuthority(t *testing.T) {
 	testDB, _ := testDatabaseInstance.NewDatabase(t)
 
 	want := &model.HealthAuthority{
-		Issuer:   "doh.mystate.gov",
-		Audience: "ens.usacovid.org",
-		Name:     "My State Department of Healthiness",
-		JwksURI:  nil,
+		Issuer:       "doh.mystate.gov",
+		Audience:     "ens.usacovid.org",
+		Name:         "My State Department of Healthiness",
+		JwksURI:      nil,
+		Jurisdiction: "US",
+		HMACSchemes:  []string{model.HMACSchemeUploadOrder},
 	}
 
 	haDB := New(testDB)
@@ -207,6 +211,19 @@ func TestAddRetrieveHealthAuthorityKeys(t *testing.T) {
 	if count != 1 {
 		t.Fatalf("wrong number of keys purged, want: %v got: %v", 1, count)
 	}
+
+	// The key transparency log keeps the addition and the revocation of the
+	// purged key.
+	entries, err := keylogdb.New(testDB).ListEntries(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(entries), 2; got != want {
+		t.Fatalf("expected %d key log entries, got %d", want, got)
+	}
+	if err := keylogmodel.VerifyChain(nil, entries); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestListAllHealthAuthoritiesWithoutKeys(t *testing.T) {
@@ -247,3 +264,63 @@ func TestListAllHealthAuthoritiesWithoutKeys(t *testing.T) {
 		t.Fatalf("mismatch (-want, +got):\n%s", diff)
 	}
 }
+
+func TestSetListReportTypeTransitions(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	ha := &model.HealthAuthority{
+		Issuer:   "doh.mystate.gov",
+		Audience: "ens.usacovid.org",
+		Name:     "My State Department of Healthiness",
+	}
+
+	haDB := New(testDB)
+	if err := haDB.AddHealthAuthority(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := haDB.ListReportTypeTransitions(ctx, ha.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected no transitions, got %d", len(got))
+	}
+
+	want := []*model.ReportTypeTransition{
+		{From: "likely", To: "negative", Allowed: false},
+		{From: "confirmed", To: "negative", Allowed: true},
+	}
+	if err := haDB.SetReportTypeTransitions(ctx, ha.ID, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = haDB.ListReportTypeTransitions(ctx, ha.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorter := cmpopts.SortSlices(func(a, b *model.ReportTypeTransition) bool {
+		return a.From < b.From
+	})
+	if diff := cmp.Diff(want, got, sorter); diff != "" {
+		t.Fatalf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Setting replaces the existing transitions.
+	if err := haDB.SetReportTypeTransitions(ctx, ha.ID, want[1:]); err != nil {
+		t.Fatal(err)
+	}
+	got, err = haDB.ListReportTypeTransitions(ctx, ha.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want[1:], got); diff != "" {
+		t.Fatalf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	invalid := []*model.ReportTypeTransition{{From: "bogus", To: "negative"}}
+	errcmp.MustMatch(t, haDB.SetReportTypeTransitions(ctx, ha.ID, invalid), "invalid from report type")
+}
@@ -16,13 +16,20 @@
 package model
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/project"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/verification"
+
+	"github.com/golang-jwt/jwt"
 )
 
 // HealthAuthority represents a public health authority that is authorized to
@@ -35,6 +42,52 @@ type HealthAuthority struct {
 	Keys           []*HealthAuthorityKey
 	JwksURI        *string
 	EnableStatsAPI bool
+
+	// Jurisdiction is the data residency jurisdiction of the health authority,
+	// e.g. a country code. It is copied to the exposures the health authority
+	// verifies so that exports and federation can be scoped by jurisdiction.
+	// Blank means no jurisdiction.
+	Jurisdiction string
+
+	// ExportDelay holds the keys this health authority verifies out of exports
+	// and federation for a while after they are uploaded, e.g. to allow for lab
+	// re-checks. Keys are exported no earlier than the upload time plus the
+	// delay.
+	ExportDelay time.Duration
+
+	// Stage is the onboarding stage of the health authority. Keys verified
+	// while it is not active are test data. New health authorities start out
+	// pending.
+	Stage Stage
+
+	// VerificationProvider is the name of the verification provider that
+	// checks the verification payloads issued by this health authority. Blank
+	// means the standard PHA JWT certificates.
+	VerificationProvider string
+
+	// HMACSchemes are the names of the alternate canonicalizations of the keys
+	// that are accepted for the HMAC of the certificates of this health
+	// authority, in addition to the standard one. See HMACSchemes.
+	HMACSchemes []string
+}
+
+// The alternate HMAC schemes a health authority may accept for interoperating
+// with clients that do not canonicalize the keys as the verification protocol
+// requires.
+const (
+	HMACSchemeUploadOrder         = "upload-order"
+	HMACSchemeUnpadded            = "unpadded"
+	HMACSchemeUploadOrderUnpadded = "upload-order-unpadded"
+)
+
+// HMACSchemeNames is the list of alternate HMAC schemes, in display order.
+var HMACSchemeNames = []string{HMACSchemeUploadOrder, HMACSchemeUnpadded, HMACSchemeUploadOrderUnpadded}
+
+// HMACSchemes maps the alternate HMAC schemes to their canonicalization.
+var HMACSchemes = map[string]verification.HMACOptions{
+	HMACSchemeUploadOrder:         {UploadOrder: true},
+	HMACSchemeUnpadded:            {Unpadded: true},
+	HMACSchemeUploadOrderUnpadded: {UploadOrder: true, Unpadded: true},
 }
 
 // JWKSEnabled returns true if JWKS discovery is enabled for this health authority.
@@ -52,6 +105,14 @@ func (ha *HealthAuthority) SetJWKS(uri string) {
 	ha.JwksURI = &uri
 }
 
+// SetJurisdiction sets the normalized jurisdiction of the HealthAuthority.
+func (ha *HealthAuthority) SetJurisdiction(jurisdiction string) {
+	ha.Jurisdiction = strings.ToUpper(project.TrimSpaceAndNonPrintable(jurisdiction))
+}
+
+// MaxExportDelay is the longest export delay a health authority may have.
+const MaxExportDelay = 72 * time.Hour
+
 // Validate returns an error if the HealthAuthority struct is not valid.
 func (ha *HealthAuthority) Validate() error {
 	if ha.Issuer == "" {
@@ -63,6 +124,33 @@ func (ha *HealthAuthority) Validate() error {
 	if ha.Name == "" {
 		return errors.New("name cannot be empty")
 	}
+	if ha.ExportDelay < 0 || ha.ExportDelay > MaxExportDelay {
+		return fmt.Errorf("export delay must be between 0 and %v", MaxExportDelay)
+	}
+	if ha.Stage != "" {
+		if err := ha.Stage.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, scheme := range ha.HMACSchemes {
+		if _, ok := HMACSchemes[scheme]; !ok {
+			return fmt.Errorf("invalid hmac scheme %q", scheme)
+		}
+	}
+	return nil
+}
+
+// SetStage moves the health authority to the given stage, if the onboarding
+// workflow allows it.
+func (ha *HealthAuthority) SetStage(stage Stage) error {
+	from := ha.Stage
+	if from == "" {
+		from = StagePending
+	}
+	if !from.CanTransitionTo(stage) {
+		return fmt.Errorf("health authority cannot move from %s to %s", from, stage)
+	}
+	ha.Stage = stage
 	return nil
 }
 
@@ -73,16 +161,51 @@ type HealthAuthorityKey struct {
 	From         time.Time
 	Thru         time.Time
 	PublicKeyPEM string
+
+	// Stage is the onboarding stage of the key, e.g. while rotating to a new
+	// signing key. Keys verified with a key that is not active are test data.
+	Stage Stage
+
+	// Algorithm is the JWS algorithm the key is pinned to. Certificates signed
+	// with any other algorithm are rejected. Blank means ES256.
+	Algorithm string
 }
 
+// The JWS algorithms that health authority keys may be pinned to.
+const (
+	AlgorithmES256 = "ES256"
+	AlgorithmES384 = "ES384"
+	AlgorithmPS256 = "PS256"
+)
+
+// Algorithms is the list of supported JWS algorithms, in display order.
+var Algorithms = []string{AlgorithmES256, AlgorithmES384, AlgorithmPS256}
+
+// minRSAKeyBits is the smallest RSA key accepted for PS256.
+const minRSAKeyBits = 2048
+
 // Validate returns an error if the HealthAuthorityKey is not valid.
 func (k *HealthAuthorityKey) Validate() error {
+	if _, err := k.SigningMethod(); err != nil {
+		return err
+	}
 	if _, err := k.PublicKey(); err != nil {
 		return fmt.Errorf("invalid public key PEM block: %w", err)
 	}
+	if k.Stage != "" {
+		if err := k.Stage.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// IsTest returns true if keys verified by this key, issued by the given
+// health authority, are test data.
+func (k *HealthAuthorityKey) IsTest(ha *HealthAuthority) bool {
+	return k.Stage.IsTest() || ha.Stage.IsTest()
+}
+
 func (k *HealthAuthorityKey) IsFuture() bool {
 	return k.From.After(time.Now())
 }
@@ -106,8 +229,75 @@ func (k *HealthAuthorityKey) Revoke() {
 	}
 }
 
-// PublicKey decodes the PublicKeyPEM text and returns the `*ecdsa.PublicKey`
-// This system only supports verifying ECDSA JWTs, `alg: ES256`.
-func (k *HealthAuthorityKey) PublicKey() (*ecdsa.PublicKey, error) {
-	return keys.ParseECDSAPublicKey(k.PublicKeyPEM)
+// SigningMethod returns the JWT signing method of the key's algorithm.
+func (k *HealthAuthorityKey) SigningMethod() (jwt.SigningMethod, error) {
+	switch k.Algorithm {
+	case "", AlgorithmES256:
+		return jwt.SigningMethodES256, nil
+	case AlgorithmES384:
+		return jwt.SigningMethodES384, nil
+	case AlgorithmPS256:
+		return jwt.SigningMethodPS256, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", k.Algorithm)
+	}
+}
+
+// PublicKey decodes the PublicKeyPEM text and returns the public key. The key
+// type must match the algorithm of the key: a P-256 `*ecdsa.PublicKey` for
+// ES256, a P-384 `*ecdsa.PublicKey` for ES384 and an `*rsa.PublicKey` for
+// PS256.
+func (k *HealthAuthorityKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.Algorithm {
+	case "", AlgorithmES256:
+		return parseECDSAPublicKey(k.PublicKeyPEM, elliptic.P256())
+	case AlgorithmES384:
+		return parseECDSAPublicKey(k.PublicKeyPEM, elliptic.P384())
+	case AlgorithmPS256:
+		pub, err := keys.ParseRSAPublicKey(k.PublicKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		if bits := pub.N.BitLen(); bits < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits, got %d", minRSAKeyBits, bits)
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", k.Algorithm)
+	}
+}
+
+func parseECDSAPublicKey(pemBlock string, curve elliptic.Curve) (*ecdsa.PublicKey, error) {
+	pub, err := keys.ParseECDSAPublicKey(pemBlock)
+	if err != nil {
+		return nil, err
+	}
+	if got, want := pub.Curve.Params().Name, curve.Params().Name; got != want {
+		return nil, fmt.Errorf("key is on curve %s, algorithm requires %s", got, want)
+	}
+	return pub, nil
+}
+
+// ReportTypeTransition overrides whether a key published by a health authority
+// may be revised from one report type to another. Transitions without an
+// override use the server defaults.
+type ReportTypeTransition struct {
+	AuthorityID int64
+	From        string
+	To          string
+	Allowed     bool
+}
+
+// Validate returns an error if the ReportTypeTransition is not valid.
+func (t *ReportTypeTransition) Validate() error {
+	if !verifyapi.ValidReportTypes[t.From] {
+		return fmt.Errorf("invalid from report type %q", t.From)
+	}
+	if !verifyapi.ValidReportTypes[t.To] {
+		return fmt.Errorf("invalid to report type %q", t.To)
+	}
+	if t.From == t.To {
+		return fmt.Errorf("cannot transition from %q to itself", t.From)
+	}
+	return nil
 }
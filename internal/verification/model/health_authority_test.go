@@ -152,9 +152,10 @@ func TestPublicKeyParse(t *testing.T) {
 	t.Parallel()
 
 	cases := []struct {
-		name     string
-		pemBlock string
-		msg      string
+		name      string
+		pemBlock  string
+		algorithm string
+		msg       string
 	}{
 		{
 			name: "valid PEM",
@@ -183,6 +184,65 @@ NTG3+oqI0Q6a3kPOuAAAupr373j7O1YXrM2KAix966EPwTNlK7YCcJa0m6PKz9DT
 -----END PUBLIC KEY-----`,
 			msg: "unsupported public key type: *rsa.PublicKey",
 		},
+		{
+			name: "ES384",
+			pemBlock: `-----BEGIN PUBLIC KEY-----
+MHYwEAYHKoZIzj0CAQYFK4EEACIDYgAE3WavUg8IBKgNlkBbU37JAQJ2uIsDXSp3
+otLc5HsGQeb13nR667oTEHmXXXQCCXlqIIsAIOu6IZheuM5XxqeZcsYGmVF2Tb9h
+j8CJ5lxYdoNIEzAHjwbyI7Fz/j4PLBqL
+-----END PUBLIC KEY-----`,
+			algorithm: AlgorithmES384,
+		},
+		{
+			name: "ES256 with P-384 key",
+			pemBlock: `-----BEGIN PUBLIC KEY-----
+MHYwEAYHKoZIzj0CAQYFK4EEACIDYgAE3WavUg8IBKgNlkBbU37JAQJ2uIsDXSp3
+otLc5HsGQeb13nR667oTEHmXXXQCCXlqIIsAIOu6IZheuM5XxqeZcsYGmVF2Tb9h
+j8CJ5lxYdoNIEzAHjwbyI7Fz/j4PLBqL
+-----END PUBLIC KEY-----`,
+			algorithm: AlgorithmES256,
+			msg:       "key is on curve P-384, algorithm requires P-256",
+		},
+		{
+			name: "ES384 with P-256 key",
+			pemBlock: `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEA+k9YktDK3UpOhBIy+O17biuwd/g
+IBSEEHOdgpAynz0yrHpkWL6vxjNHxRdWcImZxPgL0NVHMdY4TlsL7qaxBQ==
+-----END PUBLIC KEY-----`,
+			algorithm: AlgorithmES384,
+			msg:       "key is on curve P-256, algorithm requires P-384",
+		},
+		{
+			name: "PS256",
+			pemBlock: `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAvs3MAjWBFJecFLwT4lhd
+HxXbn7EaVbx3/JgiXG3Q3PCCxEYQq6SRYp/4qJpZJ2nAW+BoMCxZjTBq8bmby3WT
+js5A/G62dLgq5qKRsny6kw2ix3tFXb0I9TsPSUieVmxPgioFF1ytvIU7wKQ07vAZ
+HW05DlJJM3E9WhB/ZVKl9NmVp01CcojfhmENPNu65XaAWEMp4txyyX7rU8iPPSsK
+QCmoWZQ6r1E1r5+/RumIobbwdYxax3esvC4B3W2jyLFqMJGVBrhWf7tDki/3mCub
+NTG3+oqI0Q6a3kPOuAAAupr373j7O1YXrM2KAix966EPwTNlK7YCcJa0m6PKz9DT
+6wIDAQAB
+-----END PUBLIC KEY-----`,
+			algorithm: AlgorithmPS256,
+		},
+		{
+			name: "PS256 with ECDSA key",
+			pemBlock: `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEA+k9YktDK3UpOhBIy+O17biuwd/g
+IBSEEHOdgpAynz0yrHpkWL6vxjNHxRdWcImZxPgL0NVHMdY4TlsL7qaxBQ==
+-----END PUBLIC KEY-----`,
+			algorithm: AlgorithmPS256,
+			msg:       "unsupported public key type: *ecdsa.PublicKey",
+		},
+		{
+			name: "unknown algorithm",
+			pemBlock: `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEA+k9YktDK3UpOhBIy+O17biuwd/g
+IBSEEHOdgpAynz0yrHpkWL6vxjNHxRdWcImZxPgL0NVHMdY4TlsL7qaxBQ==
+-----END PUBLIC KEY-----`,
+			algorithm: "HS256",
+			msg:       `unsupported algorithm "HS256"`,
+		},
 	}
 
 	for _, tc := range cases {
@@ -193,13 +253,54 @@ NTG3+oqI0Q6a3kPOuAAAupr373j7O1YXrM2KAix966EPwTNlK7YCcJa0m6PKz9DT
 
 			hak := HealthAuthorityKey{
 				PublicKeyPEM: tc.pemBlock,
+				Algorithm:    tc.algorithm,
 			}
 
 			k, err := hak.PublicKey()
 			errcmp.MustMatch(t, err, tc.msg)
 			if err == nil && k == nil {
-				t.Errorf("ECDSA public key is unexpectedly nil")
+				t.Errorf("public key is unexpectedly nil")
 			}
 		})
 	}
 }
+
+func TestReportTypeTransition_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		transition *ReportTypeTransition
+		err        string
+	}{
+		{
+			name:       "valid",
+			transition: &ReportTypeTransition{From: "likely", To: "negative"},
+		},
+		{
+			name:       "invalid_from",
+			transition: &ReportTypeTransition{From: "bogus", To: "negative"},
+			err:        `invalid from report type "bogus"`,
+		},
+		{
+			name:       "invalid_to",
+			transition: &ReportTypeTransition{From: "likely", To: ""},
+			err:        `invalid to report type ""`,
+		},
+		{
+			name:       "same",
+			transition: &ReportTypeTransition{From: "likely", To: "likely"},
+			err:        "to itself",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			errcmp.MustMatch(t, tc.transition.Validate(), tc.err)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stage is the onboarding stage of a health authority or one of its keys.
+// Health authorities and keys move from pending to test to active. Uploads
+// verified while either is not active are accepted, but marked as test data
+// and kept out of production exports and federation.
+type Stage string
+
+const (
+	// StagePending is a newly added health authority or key that has not been
+	// reviewed yet.
+	StagePending Stage = "pending"
+	// StageTest is a health authority or key in integration testing.
+	StageTest Stage = "test"
+	// StageActive is a health authority or key in production.
+	StageActive Stage = "active"
+)
+
+// Stages lists all stages in onboarding order.
+var Stages = []Stage{StagePending, StageTest, StageActive}
+
+// ParseStage parses a stage, ignoring case.
+func ParseStage(s string) (Stage, error) {
+	stage := Stage(strings.ToLower(strings.TrimSpace(s)))
+	if err := stage.Validate(); err != nil {
+		return "", err
+	}
+	return stage, nil
+}
+
+// Validate returns an error if the stage is not known.
+func (s Stage) Validate() error {
+	for _, stage := range Stages {
+		if s == stage {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid stage %q", s)
+}
+
+// IsTest returns true if uploads verified in this stage are test data. An
+// empty stage is active.
+func (s Stage) IsTest() bool {
+	return s == StagePending || s == StageTest
+}
+
+// CanTransitionTo returns true if the onboarding workflow allows moving from
+// this stage to the given stage. Stages advance one at a time, but can go back
+// to any earlier stage, e.g. to test again before a change.
+func (s Stage) CanTransitionTo(to Stage) bool {
+	from, next := s.index(), to.index()
+	if from < 0 || next < 0 {
+		return false
+	}
+	return next <= from+1
+}
+
+// Next returns the stage after this one. Active is the last stage.
+func (s Stage) Next() Stage {
+	i := s.index()
+	if i < 0 || i+1 >= len(Stages) {
+		return s
+	}
+	return Stages[i+1]
+}
+
+func (s Stage) index() int {
+	for i, stage := range Stages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
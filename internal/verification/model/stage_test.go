@@ -0,0 +1,118 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestParseStage(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		input string
+		want  Stage
+		err   bool
+	}{
+		{name: "pending", input: "pending", want: StagePending},
+		{name: "mixed_case", input: " Test ", want: StageTest},
+		{name: "active", input: "ACTIVE", want: StageActive},
+		{name: "empty", input: "", err: true},
+		{name: "unknown", input: "live", err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseStage(tc.input)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStage_CanTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		from, to Stage
+		want     bool
+	}{
+		{from: StagePending, to: StagePending, want: true},
+		{from: StagePending, to: StageTest, want: true},
+		{from: StagePending, to: StageActive, want: false},
+		{from: StageTest, to: StageActive, want: true},
+		{from: StageTest, to: StagePending, want: true},
+		{from: StageActive, to: StagePending, want: true},
+		{from: StageActive, to: "", want: false},
+		{from: "", to: StageTest, want: false},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(string(tc.from)+"_"+string(tc.to), func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.from.CanTransitionTo(tc.to); got != tc.want {
+				t.Errorf("expected %q to %q to be %t, got %t", tc.from, tc.to, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStage_Next(t *testing.T) {
+	t.Parallel()
+
+	cases := map[Stage]Stage{
+		StagePending: StageTest,
+		StageTest:    StageActive,
+		StageActive:  StageActive,
+	}
+	for from, want := range cases {
+		if got := from.Next(); got != want {
+			t.Errorf("expected %q.Next() to be %q, got %q", from, want, got)
+		}
+	}
+}
+
+func TestHealthAuthority_SetStage(t *testing.T) {
+	t.Parallel()
+
+	ha := &HealthAuthority{}
+	if err := ha.SetStage(StageActive); err == nil {
+		t.Fatal("expected error skipping the test stage")
+	}
+	if err := ha.SetStage(StageTest); err != nil {
+		t.Fatal(err)
+	}
+	if err := ha.SetStage(StageActive); err != nil {
+		t.Fatal(err)
+	}
+	if !(&HealthAuthorityKey{Stage: StageActive}).IsTest(&HealthAuthority{Stage: StageTest}) {
+		t.Error("expected key of a health authority in test to be test data")
+	}
+	if (&HealthAuthorityKey{}).IsTest(ha) {
+		t.Error("expected key of an active health authority to not be test data")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func init() {
+	RegisterProvider(DefaultProvider, func(*Config) (Provider, error) {
+		return &phaJWTProvider{}, nil
+	})
+}
+
+// phaJWTProvider verifies the standard public health authority diagnosis
+// verification certificates, ES256 signed JWTs.
+type phaJWTProvider struct{}
+
+// Issuer returns the unverified issuer of the JWT.
+func (p *phaJWTProvider) Issuer(payload string) (string, error) {
+	var claims verifyapi.VerificationClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(payload, &claims); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedPayload, err)
+	}
+	return claims.Issuer, nil
+}
+
+// Verify fully verifies the JWT and its signature against the keys of the
+// health authority.
+func (p *phaJWTProvider) Verify(ctx context.Context, ha *model.HealthAuthority, payload string) (*ProviderClaims, error) {
+	// This gets assigned during the ParseWithClaims closure.
+	var testData bool
+
+	// ParseWithClaims also calls .Valid() on the parsed token.
+	parser := &jwt.Parser{ValidMethods: model.Algorithms}
+	token, err := parser.ParseWithClaims(payload, &verifyapi.VerificationClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header[verifyapi.KeyIDHeader]
+		if !ok {
+			return nil, fmt.Errorf("missing required header field, 'kid' indicating key id")
+		}
+
+		claims, ok := token.Claims.(*verifyapi.VerificationClaims)
+		if !ok {
+			return nil, fmt.Errorf("does not contain expected claim set")
+		}
+
+		// Advisory check the aud.
+		if claims.Audience != ha.Audience {
+			return nil, fmt.Errorf("audience mismatch for issuer: %v (+%s, -%s)", ha.Issuer, claims.Audience, ha.Audience)
+		}
+
+		// Find a key version.
+		for _, hak := range ha.Keys {
+			// Key version matches and the key is valid based on the current time.
+			if hak.Version == kid && hak.IsValid() {
+				if err := checkSigningMethod(token, hak); err != nil {
+					return nil, err
+				}
+				testData = hak.IsTest(ha)
+				// Extract the public key from the PEM block.
+				return hak.PublicKey()
+			}
+		}
+		return nil, ErrNoPublicKeys
+	})
+	if err != nil {
+		// Check for specific errors in the bitmask that may exist and
+		// convert them to application local errors.
+		validationError := new(jwt.ValidationError)
+		if errors.As(err, &validationError) {
+			if mask := validationError.Errors; mask&jwt.ValidationErrorIssuedAt != 0 || mask&jwt.ValidationErrorNotValidYet != 0 {
+				return nil, ErrNotValidYet
+			}
+		}
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid verificationPayload")
+	}
+
+	claims, ok := token.Claims.(*verifyapi.VerificationClaims)
+	if !ok {
+		return nil, fmt.Errorf("does not contain expected claim set")
+	}
+
+	// Verify our cutom claim types
+	if err := claims.CustomClaimsValid(); err != nil {
+		return nil, err
+	}
+
+	return &ProviderClaims{
+		SignedMAC:            claims.SignedMAC,
+		TestData:             testData,
+		ReportType:           claims.ReportType,
+		SymptomOnsetInterval: claims.SymptomOnsetInterval,
+	}, nil
+}
+
+// checkSigningMethod returns an error if the token is not signed with the
+// algorithm the key is pinned to.
+func checkSigningMethod(token *jwt.Token, hak *model.HealthAuthorityKey) error {
+	method, err := hak.SigningMethod()
+	if err != nil {
+		return err
+	}
+	if got, want := token.Method.Alg(), method.Alg(); got != want {
+		return fmt.Errorf("unsupported signing method %v, key %v requires %v", got, hak.Version, want)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verification
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+func TestPHAJWTProvider_Verify(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		algorithm string
+		key       crypto.Signer
+		method    jwt.SigningMethod
+		err       string
+	}{
+		{
+			name:   "default",
+			key:    p256,
+			method: jwt.SigningMethodES256,
+		},
+		{
+			name:      "ES384",
+			algorithm: model.AlgorithmES384,
+			key:       p384,
+			method:    jwt.SigningMethodES384,
+		},
+		{
+			name:      "PS256",
+			algorithm: model.AlgorithmPS256,
+			key:       rsaKey,
+			method:    jwt.SigningMethodPS256,
+		},
+		{
+			name:      "pinned",
+			algorithm: model.AlgorithmPS256,
+			key:       rsaKey,
+			method:    jwt.SigningMethodRS256,
+			err:       "signing method RS256 is invalid",
+		},
+		{
+			name:      "pinned_supported",
+			algorithm: model.AlgorithmES256,
+			key:       p384,
+			method:    jwt.SigningMethodES384,
+			err:       "unsupported signing method ES384, key v1 requires ES256",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			der, err := x509.MarshalPKIXPublicKey(tc.key.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ha := &model.HealthAuthority{
+				Issuer:   "issuer",
+				Audience: "audience",
+				Keys: []*model.HealthAuthorityKey{
+					{
+						Version:      "v1",
+						From:         time.Now().Add(-time.Hour),
+						PublicKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+						Algorithm:    tc.algorithm,
+					},
+				},
+			}
+
+			claims := verifyapi.NewVerificationClaims()
+			claims.Audience = ha.Audience
+			claims.Issuer = ha.Issuer
+			claims.IssuedAt = time.Now().Unix()
+			claims.ExpiresAt = time.Now().Add(5 * time.Minute).Unix()
+			claims.ReportType = verifyapi.ReportTypeConfirmed
+			claims.SignedMAC = "mac"
+
+			token := jwt.NewWithClaims(tc.method, claims)
+			token.Header[verifyapi.KeyIDHeader] = "v1"
+			payload, err := token.SignedString(tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := (&phaJWTProvider{}).Verify(ctx, ha, payload)
+			errcmp.MustMatch(t, err, tc.err)
+			if err != nil {
+				return
+			}
+			if got.SignedMAC != "mac" {
+				t.Errorf("expected signed mac %q to be %q", got.SignedMAC, "mac")
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/hmac"
 	"errors"
 	"fmt"
+	"time"
 
 	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
 	"github.com/google/exposure-notifications-server/internal/verification/database"
@@ -31,8 +32,6 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/cache"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	utils "github.com/google/exposure-notifications-server/pkg/verification"
-
-	"github.com/golang-jwt/jwt"
 )
 
 var (
@@ -43,148 +42,187 @@ var (
 
 // Verifier can be used to verify public health authority diagnosis verification certificates.
 type Verifier struct {
-	db      *database.HealthAuthorityDB
-	config  *Config
-	haCache *cache.Cache[*model.HealthAuthority]
+	db        *database.HealthAuthorityDB
+	config    *Config
+	haCache   *cache.Cache[*model.HealthAuthority]
+	providers []*namedProvider
 }
 
-// New creates a new verifier, based on this DB handle.
+// New creates a new verifier, based on this DB handle. The verifier accepts
+// the payloads of the providers enabled in the config, or only the standard
+// PHA JWT certificates if none are.
 func New(db *database.HealthAuthorityDB, config *Config) (*Verifier, error) {
 	cache, err := cache.New[*model.HealthAuthority](config.CacheDuration)
 	if err != nil {
 		return nil, err
 	}
-	return &Verifier{db, config, cache}, nil
+
+	names := config.Providers
+	if len(names) == 0 {
+		names = []string{DefaultProvider}
+	}
+	providers := make([]*namedProvider, 0, len(names))
+	for _, name := range names {
+		p, err := ProviderFor(name, config)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, &namedProvider{name: name, Provider: p})
+	}
+
+	return &Verifier{db, config, cache, providers}, nil
 }
 
 // VerifiedClaims represents the relevant claims extracted from a verified
 // certificate that may need to be applied.
 type VerifiedClaims struct {
 	HealthAuthorityID    int64
-	ReportType           string // blank indicates no report type was present.
-	SymptomOnsetInterval uint32 // 0 indicates no symptom onset interval present. This should be checked for "reasonable" value before application.
+	Jurisdiction         string        // blank indicates the health authority has no jurisdiction.
+	ExportDelay          time.Duration // 0 indicates keys may be exported right away.
+	TestData             bool          // true if the health authority or its key is not active yet.
+	ReportType           string        // blank indicates no report type was present.
+	SymptomOnsetInterval uint32        // 0 indicates no symptom onset interval present. This should be checked for "reasonable" value before application.
 }
 
 // VerifyDiagnosisCertificate accepts a publish request (from which is extracts the JWT),
 // fully verifies the JWT and signture against what the passed in authorrized app is allowed
 // to use. Returns any transmission risk overrides if they are present.
+//
+// The payload is verified by the provider selected by the health authority
+// that issued it.
 func (v *Verifier) VerifyDiagnosisCertificate(ctx context.Context, authApp *aamodel.AuthorizedApp, publish *verifyapi.Publish) (*VerifiedClaims, error) {
-	logger := logging.FromContext(ctx)
-	// These get assigned during the ParseWithClaims closure.
-	var healthAuthorityID int64
-	var claims *verifyapi.VerificationClaims
-
-	// Unpack JWT so we can determine issuer and key version.
-	// ParseWithClaims also calls .Valid() on the parsed token.
-	token, err := jwt.ParseWithClaims(publish.VerificationPayload, &verifyapi.VerificationClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if method, ok := token.Method.(*jwt.SigningMethodECDSA); !ok || method.Name != jwt.SigningMethodES256.Name {
-			return nil, fmt.Errorf("unsupported signing method, must be %v", jwt.SigningMethodES256.Name)
-		}
+	provider, issuer, err := v.issuer(publish.VerificationPayload)
+	if err != nil {
+		return nil, err
+	}
 
-		var ok bool
-		kid, ok := token.Header[verifyapi.KeyIDHeader]
-		if !ok {
-			return nil, fmt.Errorf("missing required header field, 'kid' indicating key id")
-		}
+	ha, err := v.healthAuthority(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
 
-		claims, ok = token.Claims.(*verifyapi.VerificationClaims)
-		if !ok {
-			return nil, fmt.Errorf("does not contain expected claim set")
-		}
+	if name := ha.VerificationProvider; name != provider.name && !(name == "" && provider.name == DefaultProvider) {
+		return nil, fmt.Errorf("issuer %v does not accept %s verification payloads", ha.Issuer, provider.name)
+	}
+
+	claims, err := provider.Verify(ctx, ha, publish.VerificationPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Payload is valid and signature is valid.
+	// This is chacked after the signature verification to prevent timing attacks.
+	if _, ok := authApp.AllowedHealthAuthorityIDs[ha.ID]; !ok {
+		return nil, fmt.Errorf("app %v has not authorized health authority issuer: %v", authApp.AppPackageName, ha.Issuer)
+	}
+
+	// Verify the HMAC.
+	if err := v.verifyHMAC(ctx, ha, publish, claims.SignedMAC); err != nil {
+		return nil, err
+	}
+
+	// Everything looks good. Return the relevant verified claims.
+	return &VerifiedClaims{
+		HealthAuthorityID:    ha.ID,
+		Jurisdiction:         ha.Jurisdiction,
+		ExportDelay:          ha.ExportDelay,
+		TestData:             claims.TestData,
+		ReportType:           claims.ReportType,
+		SymptomOnsetInterval: claims.SymptomOnsetInterval,
+	}, nil
+}
 
-		lookup := func() (*model.HealthAuthority, error) {
-			// Based on issuer, load the key versions.
-			ha, err := v.db.GetHealthAuthority(ctx, claims.Issuer)
-			// Special case not found so that we can cache it.
-			if errors.Is(err, database.ErrHealthAuthorityNotFound) {
-				logger.Warnw("requested issuer not found", "iss", claims.Issuer)
-				return nil, nil
-			}
-			if err != nil {
-				return nil, fmt.Errorf("error looking up issuer: %v : %w", claims.Issuer, err)
-			}
-			return ha, nil
+// issuer returns the first enabled provider that understands the payload and
+// the issuer of the payload.
+func (v *Verifier) issuer(payload string) (*namedProvider, string, error) {
+	for _, p := range v.providers {
+		issuer, err := p.Issuer(payload)
+		if errors.Is(err, ErrUnsupportedPayload) {
+			continue
 		}
-		ha, err := v.haCache.WriteThruLookup(claims.Issuer, lookup)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
+		return p, issuer, nil
+	}
+	return nil, "", fmt.Errorf("invalid verificationPayload: %w", ErrUnsupportedPayload)
+}
 
-		// Handle not found.
-		if ha == nil {
-			return nil, fmt.Errorf("issuer not found: %v", claims.Issuer)
-		}
+// healthAuthority returns the health authority with the given issuer, using
+// the cache.
+func (v *Verifier) healthAuthority(ctx context.Context, issuer string) (*model.HealthAuthority, error) {
+	logger := logging.FromContext(ctx)
 
-		// Advisory check the aud.
-		if claims.Audience != ha.Audience {
-			return nil, fmt.Errorf("audience mismatch for issuer: %v (+%s, -%s)", ha.Issuer, claims.Audience, ha.Audience)
+	lookup := func() (*model.HealthAuthority, error) {
+		// Based on issuer, load the key versions.
+		ha, err := v.db.GetHealthAuthority(ctx, issuer)
+		// Special case not found so that we can cache it.
+		if errors.Is(err, database.ErrHealthAuthorityNotFound) {
+			logger.Warnw("requested issuer not found", "iss", issuer)
+			return nil, nil
 		}
-
-		// Find a key version.
-		for _, hak := range ha.Keys {
-			// Key version matches and the key is valid based on the current time.
-			if hak.Version == kid && hak.IsValid() {
-				healthAuthorityID = ha.ID
-				// Extract the public key from the PEM block.
-				return hak.PublicKey()
-			}
+		if err != nil {
+			return nil, fmt.Errorf("error looking up issuer: %v : %w", issuer, err)
 		}
-		return nil, ErrNoPublicKeys
-	})
+		return ha, nil
+	}
+	ha, err := v.haCache.WriteThruLookup(issuer, lookup)
 	if err != nil {
-		// Check for specific errors in the bitmask that may exist and
-		// convert them to application local errors.
-		validationError := new(jwt.ValidationError)
-		if errors.As(err, &validationError) {
-			if mask := validationError.Errors; mask&jwt.ValidationErrorIssuedAt != 0 || mask&jwt.ValidationErrorNotValidYet != 0 {
-				return nil, ErrNotValidYet
-			}
-		}
 		return nil, err
 	}
 
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid verificationPayload")
-	}
-
-	// JWT is valid and signature is valid.
-	// This is chacked after the signature verification to prevent timing attacks.
-	if _, ok := authApp.AllowedHealthAuthorityIDs[healthAuthorityID]; !ok {
-		return nil, fmt.Errorf("app %v has not authorized health authority issuer: %v", authApp.AppPackageName, claims.Issuer)
-	}
-
-	// Verify our cutom claim types
-	if err := claims.CustomClaimsValid(); err != nil {
-		return nil, err
+	// Handle not found.
+	if ha == nil {
+		return nil, fmt.Errorf("issuer not found: %v", issuer)
 	}
+	return ha, nil
+}
 
-	// Verify the HMAC.
-	jwtHMAC, err := base64util.DecodeString(claims.SignedMAC)
+// verifyHMAC checks the HMAC of the keys in the certificate against the keys
+// in the publish request, canonicalized as the verification protocol requires
+// or by one of the alternate schemes the health authority accepts.
+func (v *Verifier) verifyHMAC(ctx context.Context, ha *model.HealthAuthority, publish *verifyapi.Publish, signedMAC string) error {
+	jwtHMAC, err := base64util.DecodeString(signedMAC)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding HMAC from claims: %w", err)
+		return fmt.Errorf("error decoding HMAC from claims: %w", err)
 	}
 	secret, err := base64util.DecodeString(publish.HMACKey)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding HMAC secret from publish request: %w", err)
+		return fmt.Errorf("error decoding HMAC secret from publish request: %w", err)
 	}
+
+	// The alternate schemes are checked first, since the standard
+	// calculation sorts the keys in place and the upload order would be lost.
+	for _, scheme := range ha.HMACSchemes {
+		opts, ok := model.HMACSchemes[scheme]
+		if !ok {
+			continue
+		}
+		validHMACs, err := utils.CalculateAllAllowedExposureKeyHMACWithOptions(publish.Keys, secret, opts)
+		if err != nil {
+			return fmt.Errorf("calculating expected HMAC: %w", err)
+		}
+		if anyHMACEqual(validHMACs, jwtHMAC) {
+			logging.FromContext(ctx).Warnw("accepted HMAC with alternate scheme", "iss", ha.Issuer, "scheme", scheme)
+			return nil
+		}
+	}
+
 	// Allow the HMAC to be calculated without transmission risk values IFF all transmission risks are zero.
 	validHMACs, err := utils.CalculateAllAllowedExposureKeyHMAC(publish.Keys, secret)
 	if err != nil {
-		return nil, fmt.Errorf("calculating expected HMAC: %w", err)
+		return fmt.Errorf("calculating expected HMAC: %w", err)
 	}
+	if !anyHMACEqual(validHMACs, jwtHMAC) {
+		return fmt.Errorf("HMAC mismatch, publish request does not match disgnosis verification certificate")
+	}
+	return nil
+}
 
+func anyHMACEqual(validHMACs [][]byte, got []byte) bool {
 	valid := false
 	for _, wantHMAC := range validHMACs {
-		valid = valid || hmac.Equal(wantHMAC, jwtHMAC)
-	}
-	if !valid {
-		return nil, fmt.Errorf("HMAC mismatch, publish request does not match disgnosis verification certificate")
+		valid = valid || hmac.Equal(wantHMAC, got)
 	}
-
-	// Everything looks good. Return the relevant verified claims.
-	return &VerifiedClaims{
-		HealthAuthorityID:    healthAuthorityID,
-		ReportType:           claims.ReportType,
-		SymptomOnsetInterval: claims.SymptomOnsetInterval,
-	}, nil
+	return valid
 }
@@ -52,6 +52,7 @@ func TestVerifyCertificate(t *testing.T) {
 		MacKeyAdjustment string
 		ChangeIssuer     string
 		ChangeAudience   string
+		Provider         string
 		Error            string
 	}{
 		{
@@ -67,6 +68,11 @@ func TestVerifyCertificate(t *testing.T) {
 			ChangeAudience: "bar",
 			Error:          "audience mismatch for issuer",
 		},
+		{
+			Name:     "other_provider",
+			Provider: "other",
+			Error:    "does not accept pha-jwt verification payloads",
+		},
 		{
 			Name:  "past",
 			Warp:  -1 * time.Hour,
@@ -134,6 +140,8 @@ func TestVerifyCertificate(t *testing.T) {
 						Issuer:   issuer,
 						Audience: audience,
 						Name:     "Very Real Health Authority",
+
+						VerificationProvider: tc.Provider,
 					}
 					if err := haDB.AddHealthAuthority(ctx, &healthAuthority); err != nil {
 						t.Fatal(err)
@@ -245,7 +253,7 @@ func TestVerifyCertificate(t *testing.T) {
 					publish.HMACKey = tc.MacKeyAdjustment + hmacKeyB64
 
 					// Actually test the verify code.
-					verifier, err := New(haDB, &Config{time.Nanosecond, "audience"})
+					verifier, err := New(haDB, &Config{CacheDuration: time.Nanosecond, StatsAudience: "audience"})
 					if err != nil {
 						t.Fatal(err)
 					}
@@ -271,3 +279,85 @@ func TestVerifyCertificate(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifier_verifyHMAC(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys are not in sorted order.
+	keys := func() []verifyapi.ExposureKey {
+		return []verifyapi.ExposureKey{
+			{Key: "z2Cx9hdz2SlxZ8GEgqTYpA==", IntervalNumber: 2650032, IntervalCount: 144},
+			{Key: "dPCphLzfG4uzXneNimkPRQ==", IntervalNumber: 2650176, IntervalCount: 144},
+		}
+	}
+
+	mac := func(opts utils.HMACOptions) string {
+		hmacs, err := utils.CalculateAllAllowedExposureKeyHMACWithOptions(keys(), secret, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(hmacs[0])
+	}
+
+	cases := []struct {
+		name    string
+		schemes []string
+		mac     string
+		err     string
+	}{
+		{
+			name: "standard",
+			mac:  mac(utils.HMACOptions{}),
+		},
+		{
+			name:    "standard_with_schemes",
+			schemes: []string{model.HMACSchemeUploadOrder},
+			mac:     mac(utils.HMACOptions{}),
+		},
+		{
+			name: "upload_order_not_accepted",
+			mac:  mac(utils.HMACOptions{UploadOrder: true}),
+			err:  "HMAC mismatch",
+		},
+		{
+			name:    "upload_order",
+			schemes: []string{model.HMACSchemeUploadOrder},
+			mac:     mac(utils.HMACOptions{UploadOrder: true}),
+		},
+		{
+			name:    "unpadded",
+			schemes: []string{model.HMACSchemeUploadOrder, model.HMACSchemeUnpadded},
+			mac:     mac(utils.HMACOptions{Unpadded: true}),
+		},
+		{
+			name:    "other_scheme",
+			schemes: []string{model.HMACSchemeUnpadded},
+			mac:     mac(utils.HMACOptions{UploadOrder: true, Unpadded: true}),
+			err:     "HMAC mismatch",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ha := &model.HealthAuthority{Issuer: "issuer", HMACSchemes: tc.schemes}
+			publish := &verifyapi.Publish{
+				Keys:    keys(),
+				HMACKey: base64.StdEncoding.EncodeToString(secret),
+			}
+
+			err := new(Verifier).verifyHMAC(ctx, ha, publish, tc.mac)
+			errcmp.MustMatch(t, err, tc.err)
+		})
+	}
+}
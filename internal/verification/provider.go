@@ -0,0 +1,112 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+)
+
+// ErrUnsupportedPayload is returned by a provider when a verification payload
+// is not in the format the provider verifies.
+var ErrUnsupportedPayload = errors.New("unsupported verification payload")
+
+// DefaultProvider is the name of the provider that verifies the standard PHA
+// JWT certificates. Health authorities without a provider use it.
+const DefaultProvider = "pha-jwt"
+
+// Provider verifies the verification payloads of one verification scheme,
+// e.g. PHA JWT certificates. Each health authority selects the provider that
+// checks the payloads it issues.
+type Provider interface {
+	// Issuer returns the issuer of the payload, without verifying it, so that
+	// the health authority can be looked up. It returns ErrUnsupportedPayload
+	// if the payload is not in the format of the provider.
+	Issuer(payload string) (string, error)
+
+	// Verify fully verifies the payload against the health authority that
+	// issued it and returns its claims.
+	Verify(ctx context.Context, ha *model.HealthAuthority, payload string) (*ProviderClaims, error)
+}
+
+// ProviderClaims are the claims of a verified payload. Whatever the scheme,
+// the payload must carry the HMAC of the uploaded keys, which is checked by
+// the Verifier.
+type ProviderClaims struct {
+	SignedMAC            string // base64 encoded HMAC of the keys.
+	TestData             bool   // true if the health authority or its key is not active yet.
+	ReportType           string // blank indicates no report type was present.
+	SymptomOnsetInterval uint32 // 0 indicates no symptom onset interval present.
+}
+
+// ProviderFunc is a func that returns a provider or error.
+type ProviderFunc func(*Config) (Provider, error)
+
+// providers is the list of registered providers.
+var (
+	providers     = make(map[string]ProviderFunc)
+	providersLock sync.RWMutex
+)
+
+// RegisterProvider registers a new provider with the given name. If a
+// provider is already registered with the given name, it panics. Providers
+// are usually registered via an init function.
+func RegisterProvider(name string, fn ProviderFunc) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("verification provider %q is already registered", name))
+	}
+	providers[name] = fn
+}
+
+// RegisteredProviders returns the list of the names of the registered
+// providers.
+func RegisteredProviders() []string {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+
+	list := make([]string, 0, len(providers))
+	for k := range providers {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// ProviderFor returns the provider with the given name, or an error if one
+// does not exist.
+func ProviderFor(name string, cfg *Config) (Provider, error) {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+
+	fn, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown or uncompiled verification provider %q", name)
+	}
+	return fn(cfg)
+}
+
+// namedProvider is an enabled provider and the name it was registered with.
+type namedProvider struct {
+	name string
+	Provider
+}
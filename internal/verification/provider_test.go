@@ -0,0 +1,120 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verification
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+// prefixProvider accepts payloads of the form "<prefix>:<issuer>".
+type prefixProvider struct {
+	prefix string
+}
+
+func (p *prefixProvider) Issuer(payload string) (string, error) {
+	issuer := strings.TrimPrefix(payload, p.prefix+":")
+	if issuer == payload {
+		return "", ErrUnsupportedPayload
+	}
+	return issuer, nil
+}
+
+func (p *prefixProvider) Verify(ctx context.Context, ha *model.HealthAuthority, payload string) (*ProviderClaims, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestVerifier_issuer(t *testing.T) {
+	t.Parallel()
+
+	v := &Verifier{
+		providers: []*namedProvider{
+			{name: "a", Provider: &prefixProvider{prefix: "a"}},
+			{name: "b", Provider: &prefixProvider{prefix: "b"}},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		payload  string
+		provider string
+		issuer   string
+		err      string
+	}{
+		{
+			name:     "first",
+			payload:  "a:issuer",
+			provider: "a",
+			issuer:   "issuer",
+		},
+		{
+			name:     "second",
+			payload:  "b:issuer",
+			provider: "b",
+			issuer:   "issuer",
+		},
+		{
+			name:    "unsupported",
+			payload: "c:issuer",
+			err:     ErrUnsupportedPayload.Error(),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, issuer, err := v.issuer(tc.payload)
+			errcmp.MustMatch(t, err, tc.err)
+			if err != nil {
+				return
+			}
+
+			if got, want := p.name, tc.provider; got != want {
+				t.Errorf("expected provider %q to be %q", got, want)
+			}
+			if got, want := issuer, tc.issuer; got != want {
+				t.Errorf("expected issuer %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestProviderFor(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ProviderFor(DefaultProvider, &Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ProviderFor("nope", &Config{})
+	errcmp.MustMatch(t, err, `unknown or uncompiled verification provider "nope"`)
+}
+
+func TestPHAJWTProvider_Issuer(t *testing.T) {
+	t.Parallel()
+
+	p := &phaJWTProvider{}
+	if _, err := p.Issuer("not a jwt"); !errors.Is(err, ErrUnsupportedPayload) {
+		t.Errorf("expected %v to be %v", err, ErrUnsupportedPayload)
+	}
+}
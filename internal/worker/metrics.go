@@ -0,0 +1,52 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const metricPrefix = metrics.MetricRoot + "worker"
+
+var (
+	mTaskLatencyMs = stats.Float64(metricPrefix+"/task/latency", "worker pool task latency", stats.UnitMilliseconds)
+
+	poolTagKey = tag.MustNewKey("pool")
+)
+
+func init() {
+	observability.CollectViews([]*view.View{
+		{
+			Name:        metricPrefix + "/task/count",
+			Description: "Number of tasks run by worker pools",
+			Measure:     mTaskLatencyMs,
+			TagKeys:     []tag.Key{poolTagKey, observability.ResultTagKey},
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/task/latency",
+			Description: "Distribution of worker pool task latency in milliseconds",
+			Measure:     mTaskLatencyMs,
+			TagKeys:     []tag.Key{poolTagKey, observability.ResultTagKey},
+			Aggregation: ochttp.DefaultLatencyDistribution,
+		},
+	}...)
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package worker runs tasks on a bounded pool of goroutines. A pool caps the
+// number of tasks that run at once, so the setup of each task, such as
+// downloading or building a file, is not repeated for an unbounded number of
+// tasks at the same time. Panics in tasks are returned as errors instead of
+// crashing the process, and the outcome and latency of each task are recorded
+// under the name of the pool.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	"github.com/hashicorp/go-multierror"
+	"go.opencensus.io/tag"
+)
+
+// ErrPanic is wrapped by the error of a task that panicked.
+var ErrPanic = errors.New("task panicked")
+
+// Task is a unit of work run by a pool.
+type Task func(ctx context.Context) error
+
+// Pool runs tasks with bounded concurrency. A pool is reusable once Wait
+// returns.
+type Pool struct {
+	name string
+	sem  chan struct{}
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	merr *multierror.Error
+}
+
+// New creates a pool that runs at most size tasks at once. The name identifies
+// the pool in logs and metrics, such as "export_files". A size less than 1 is
+// treated as 1.
+func New(name string, size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{
+		name: name,
+		sem:  make(chan struct{}, size),
+	}
+}
+
+// Size returns the number of tasks the pool runs at once.
+func (p *Pool) Size() int {
+	return cap(p.sem)
+}
+
+// Go runs task on the pool, blocking until fewer than Size tasks are running.
+// If ctx is done first, the task is not run and the context error is
+// returned. The error of the task is returned by Wait.
+func (p *Pool) Go(ctx context.Context, task Task) error {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		<-p.sem
+		return err
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+
+		if err := p.run(ctx, task); err != nil {
+			p.mu.Lock()
+			p.merr = multierror.Append(p.merr, err)
+			p.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until all the tasks started by Go have returned, and returns
+// their errors combined.
+func (p *Pool) Wait() error {
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.merr.ErrorOrNil()
+	p.merr = nil
+	return err
+}
+
+// run runs task, recovering from a panic, and records its outcome.
+func (p *Pool) run(ctx context.Context, task Task) (retErr error) {
+	start := time.Now()
+	result := observability.ResultOK
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(ctx).Named("worker").
+				Errorw("task panicked", "pool", p.name, "panic", r, "stack", string(debug.Stack()))
+			retErr = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+
+		switch {
+		case retErr == nil:
+		case errors.Is(retErr, ErrPanic):
+			result = observability.ResultError("PANIC")
+		case errors.Is(retErr, context.Canceled), errors.Is(retErr, context.DeadlineExceeded):
+			result = observability.ResultError("CANCELED")
+		default:
+			result = observability.ResultNotOK
+		}
+		mctx, err := tag.New(ctx, tag.Upsert(poolTagKey, p.name))
+		if err != nil {
+			mctx = ctx
+		}
+		observability.RecordLatency(mctx, start, mTaskLatencyMs, &result)
+	}()
+
+	return task(ctx)
+}
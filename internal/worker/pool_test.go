@@ -0,0 +1,136 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestPool_Bounded(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	const size = 3
+	p := New("test", size)
+
+	var running, max int32
+	for i := 0; i < 20; i++ {
+		if err := p.Go(ctx, func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt32(&max); got > size {
+		t.Errorf("expected at most %d concurrent tasks, got %d", size, got)
+	}
+}
+
+func TestPool_Errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	errTask := errors.New("task failed")
+
+	p := New("test", 2)
+	var ran int32
+	tasks := []Task{
+		func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		},
+		func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return errTask
+		},
+		func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			panic("boom")
+		},
+		func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		},
+	}
+	for _, task := range tasks {
+		if err := p.Go(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := p.Wait()
+	if !errors.Is(err, errTask) {
+		t.Errorf("expected %v, got %v", errTask, err)
+	}
+	if !errors.Is(err, ErrPanic) {
+		t.Errorf("expected %v, got %v", ErrPanic, err)
+	}
+	if got, want := atomic.LoadInt32(&ran), int32(len(tasks)); got != want {
+		t.Errorf("expected %d tasks to run, got %d", want, got)
+	}
+
+	// Errors are reset once they are returned.
+	if err := p.Wait(); err != nil {
+		t.Errorf("expected no error after wait, got %v", err)
+	}
+}
+
+func TestPool_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(project.TestContext(t))
+
+	p := New("test", 1)
+	release := make(chan struct{})
+	if err := p.Go(ctx, func(ctx context.Context) error {
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pool is full, so the next task waits until the context is canceled.
+	cancel()
+	if err := p.Go(ctx, func(ctx context.Context) error {
+		t.Error("expected canceled task not to run")
+		return nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	close(release)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
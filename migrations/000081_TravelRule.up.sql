@@ -0,0 +1,27 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+CREATE TABLE TravelRule (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  input_regions VARCHAR(5)[] NOT NULL DEFAULT '{}',
+  travelers_only BOOL NOT NULL DEFAULT true,
+  output_regions VARCHAR(5)[] NOT NULL,
+  apply_to_export BOOL NOT NULL DEFAULT true,
+  apply_to_federation BOOL NOT NULL DEFAULT true
+);
+
+END;
@@ -0,0 +1,25 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+CREATE TABLE ReportTypeTransition (
+  health_authority_id INT NOT NULL REFERENCES HealthAuthority(id),
+  from_report_type VARCHAR(20) NOT NULL,
+  to_report_type VARCHAR(20) NOT NULL,
+  allowed BOOL NOT NULL,
+  PRIMARY KEY(health_authority_id, from_report_type, to_report_type)
+);
+
+END;
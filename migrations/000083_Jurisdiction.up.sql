@@ -0,0 +1,33 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+ALTER TABLE HealthAuthority ADD COLUMN jurisdiction TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE Exposure ADD COLUMN jurisdiction TEXT;
+
+ALTER TABLE ExportConfig
+  ADD COLUMN include_jurisdictions TEXT[],
+  ADD COLUMN exclude_jurisdictions TEXT[];
+
+ALTER TABLE ExportBatch
+  ADD COLUMN include_jurisdictions TEXT[],
+  ADD COLUMN exclude_jurisdictions TEXT[];
+
+ALTER TABLE FederationOutAuthorization
+  ADD COLUMN include_jurisdictions TEXT[],
+  ADD COLUMN exclude_jurisdictions TEXT[];
+
+END;
@@ -0,0 +1,201 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+CREATE OR REPLACE FUNCTION AcquireLock(VARCHAR(100), INT) RETURNS TIMESTAMP AS $$
+  DECLARE
+    nowT TIMESTAMP;
+    expiresT TIMESTAMP;
+  BEGIN
+    nowT := CURRENT_TIMESTAMP;
+    expiresT := nowT + '1 SECOND'::interval * $2;
+
+    -- Create the lock row. If it already exists, do nothing.
+    INSERT INTO Lock (lock_id, expires) VALUES ($1, to_timestamp(0))
+      ON CONFLICT (lock_id) DO NOTHING;
+
+    -- Attempt to update the lock ttl if it's expired.
+    UPDATE Lock SET expires = expiresT WHERE lock_id = $1 AND expires <= nowT;
+    IF FOUND THEN
+      -- The lock was acquired, return the new expiration time.
+      RETURN expiresT;
+    ELSE
+      -- The current lock has not yet expired, return the sentinel value
+      -- indicating the lock was not acquired.
+      RETURN to_timestamp(0);
+    END IF;
+  END
+$$ LANGUAGE plpgsql;
+
+DROP POLICY tenant_isolation ON AuthorizedApp;
+ALTER TABLE AuthorizedApp NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE AuthorizedApp DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON ExportBatch;
+ALTER TABLE ExportBatch NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE ExportBatch DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON ExportConfig;
+ALTER TABLE ExportConfig NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE ExportConfig DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON ExportFile;
+ALTER TABLE ExportFile NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE ExportFile DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON ExportImport;
+ALTER TABLE ExportImport NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE ExportImport DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON Exposure;
+ALTER TABLE Exposure NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Exposure DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON FederationInQuery;
+ALTER TABLE FederationInQuery NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE FederationInQuery DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON FederationInSync;
+ALTER TABLE FederationInSync NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE FederationInSync DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON FederationOutAuthorization;
+ALTER TABLE FederationOutAuthorization NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE FederationOutAuthorization DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON HealthAuthority;
+ALTER TABLE HealthAuthority NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE HealthAuthority DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON HealthAuthorityKey;
+ALTER TABLE HealthAuthorityKey NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE HealthAuthorityKey DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON HealthAuthorityStats;
+ALTER TABLE HealthAuthorityStats NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE HealthAuthorityStats DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON ImportFile;
+ALTER TABLE ImportFile NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE ImportFile DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON ImportFilePublicKey;
+ALTER TABLE ImportFilePublicKey NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE ImportFilePublicKey DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON Lock;
+ALTER TABLE Lock NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Lock DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON Mirror;
+ALTER TABLE Mirror NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Mirror DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON MirrorFile;
+ALTER TABLE MirrorFile NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE MirrorFile DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON ReportTypeTransition;
+ALTER TABLE ReportTypeTransition NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE ReportTypeTransition DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON RevisionKeys;
+ALTER TABLE RevisionKeys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE RevisionKeys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON SignatureInfo;
+ALTER TABLE SignatureInfo NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE SignatureInfo DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON TravelRule;
+ALTER TABLE TravelRule NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE TravelRule DISABLE ROW LEVEL SECURITY;
+
+-- Rows from tenants other than the default tenant are removed, children first.
+DELETE FROM Exposure WHERE tenant != '';
+DELETE FROM FederationInSync WHERE tenant != '';
+DELETE FROM FederationInQuery WHERE tenant != '';
+DELETE FROM ExportFile WHERE tenant != '';
+DELETE FROM ExportBatch WHERE tenant != '';
+DELETE FROM ExportConfig WHERE tenant != '';
+DELETE FROM SignatureInfo WHERE tenant != '';
+DELETE FROM HealthAuthorityKey WHERE tenant != '';
+DELETE FROM HealthAuthorityStats WHERE tenant != '';
+DELETE FROM ReportTypeTransition WHERE tenant != '';
+DELETE FROM TravelRule WHERE tenant != '';
+DELETE FROM HealthAuthority WHERE tenant != '';
+DELETE FROM ImportFile WHERE tenant != '';
+DELETE FROM ImportFilePublicKey WHERE tenant != '';
+DELETE FROM ExportImport WHERE tenant != '';
+DELETE FROM MirrorFile WHERE tenant != '';
+DELETE FROM Mirror WHERE tenant != '';
+DELETE FROM AuthorizedApp WHERE tenant != '';
+DELETE FROM FederationOutAuthorization WHERE tenant != '';
+DELETE FROM Lock WHERE tenant != '';
+DELETE FROM RevisionKeys WHERE tenant != '';
+
+ALTER TABLE AuthorizedApp DROP CONSTRAINT authorized_app_pkey;
+ALTER TABLE AuthorizedApp ADD CONSTRAINT authorized_app_pkey PRIMARY KEY (app_package_name);
+
+ALTER TABLE FederationOutAuthorization DROP CONSTRAINT federation_authorization_pk;
+ALTER TABLE FederationOutAuthorization
+  ADD CONSTRAINT federation_authorization_pk PRIMARY KEY (oidc_issuer, oidc_subject);
+
+ALTER TABLE Lock DROP CONSTRAINT lock_pkey;
+ALTER TABLE Lock ADD CONSTRAINT lock_pkey PRIMARY KEY (lock_id);
+
+ALTER TABLE AuthorizedApp DROP COLUMN tenant;
+
+ALTER TABLE ExportBatch DROP COLUMN tenant;
+
+ALTER TABLE ExportConfig DROP COLUMN tenant;
+
+ALTER TABLE ExportFile DROP COLUMN tenant;
+
+ALTER TABLE ExportImport DROP COLUMN tenant;
+
+ALTER TABLE Exposure DROP COLUMN tenant;
+
+ALTER TABLE FederationInQuery DROP COLUMN tenant;
+
+ALTER TABLE FederationInSync DROP COLUMN tenant;
+
+ALTER TABLE FederationOutAuthorization DROP COLUMN tenant;
+
+ALTER TABLE HealthAuthority DROP COLUMN tenant;
+
+ALTER TABLE HealthAuthorityKey DROP COLUMN tenant;
+
+ALTER TABLE HealthAuthorityStats DROP COLUMN tenant;
+
+ALTER TABLE ImportFile DROP COLUMN tenant;
+
+ALTER TABLE ImportFilePublicKey DROP COLUMN tenant;
+
+ALTER TABLE Lock DROP COLUMN tenant;
+
+ALTER TABLE Mirror DROP COLUMN tenant;
+
+ALTER TABLE MirrorFile DROP COLUMN tenant;
+
+ALTER TABLE ReportTypeTransition DROP COLUMN tenant;
+
+ALTER TABLE RevisionKeys DROP COLUMN tenant;
+
+ALTER TABLE SignatureInfo DROP COLUMN tenant;
+
+ALTER TABLE TravelRule DROP COLUMN tenant;
+
+END;
@@ -0,0 +1,225 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+-- Every row belongs to a tenant. Connections set app.tenant (see DB_TENANT)
+-- and row level security limits them to that tenant's rows. Existing rows and
+-- connections that do not set a tenant use the default tenant, ''.
+
+ALTER TABLE AuthorizedApp
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE AuthorizedApp ENABLE ROW LEVEL SECURITY;
+ALTER TABLE AuthorizedApp FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON AuthorizedApp
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ExportBatch
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE ExportBatch ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ExportBatch FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ExportBatch
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ExportConfig
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE ExportConfig ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ExportConfig FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ExportConfig
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ExportFile
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE ExportFile ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ExportFile FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ExportFile
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ExportImport
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE ExportImport ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ExportImport FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ExportImport
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE Exposure
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE Exposure ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Exposure FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON Exposure
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE FederationInQuery
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE FederationInQuery ENABLE ROW LEVEL SECURITY;
+ALTER TABLE FederationInQuery FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON FederationInQuery
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE FederationInSync
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE FederationInSync ENABLE ROW LEVEL SECURITY;
+ALTER TABLE FederationInSync FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON FederationInSync
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE FederationOutAuthorization
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE FederationOutAuthorization ENABLE ROW LEVEL SECURITY;
+ALTER TABLE FederationOutAuthorization FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON FederationOutAuthorization
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE HealthAuthority
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE HealthAuthority ENABLE ROW LEVEL SECURITY;
+ALTER TABLE HealthAuthority FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON HealthAuthority
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE HealthAuthorityKey
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE HealthAuthorityKey ENABLE ROW LEVEL SECURITY;
+ALTER TABLE HealthAuthorityKey FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON HealthAuthorityKey
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE HealthAuthorityStats
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE HealthAuthorityStats ENABLE ROW LEVEL SECURITY;
+ALTER TABLE HealthAuthorityStats FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON HealthAuthorityStats
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ImportFile
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE ImportFile ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ImportFile FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ImportFile
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ImportFilePublicKey
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE ImportFilePublicKey ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ImportFilePublicKey FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ImportFilePublicKey
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE Lock
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE Lock ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Lock FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON Lock
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE Mirror
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE Mirror ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Mirror FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON Mirror
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE MirrorFile
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE MirrorFile ENABLE ROW LEVEL SECURITY;
+ALTER TABLE MirrorFile FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON MirrorFile
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ReportTypeTransition
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE ReportTypeTransition ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ReportTypeTransition FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ReportTypeTransition
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE RevisionKeys
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE RevisionKeys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE RevisionKeys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON RevisionKeys
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE SignatureInfo
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE SignatureInfo ENABLE ROW LEVEL SECURITY;
+ALTER TABLE SignatureInfo FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON SignatureInfo
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE TravelRule
+  ADD COLUMN tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), '');
+ALTER TABLE TravelRule ENABLE ROW LEVEL SECURITY;
+ALTER TABLE TravelRule FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON TravelRule
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+-- Identifiers chosen by operators may be reused across tenants.
+ALTER TABLE AuthorizedApp DROP CONSTRAINT authorized_app_pkey;
+ALTER TABLE AuthorizedApp ADD CONSTRAINT authorized_app_pkey PRIMARY KEY (tenant, app_package_name);
+
+ALTER TABLE FederationOutAuthorization DROP CONSTRAINT federation_authorization_pk;
+ALTER TABLE FederationOutAuthorization
+  ADD CONSTRAINT federation_authorization_pk PRIMARY KEY (tenant, oidc_issuer, oidc_subject);
+
+ALTER TABLE Lock DROP CONSTRAINT lock_pkey;
+ALTER TABLE Lock ADD CONSTRAINT lock_pkey PRIMARY KEY (tenant, lock_id);
+
+CREATE OR REPLACE FUNCTION AcquireLock(VARCHAR(100), INT) RETURNS TIMESTAMP AS $$
+  DECLARE
+    nowT TIMESTAMP;
+    expiresT TIMESTAMP;
+  BEGIN
+    nowT := CURRENT_TIMESTAMP;
+    expiresT := nowT + '1 SECOND'::interval * $2;
+
+    -- Create the lock row. If it already exists, do nothing.
+    INSERT INTO Lock (lock_id, expires) VALUES ($1, to_timestamp(0))
+      ON CONFLICT (tenant, lock_id) DO NOTHING;
+
+    -- Attempt to update the lock ttl if it's expired.
+    UPDATE Lock SET expires = expiresT WHERE lock_id = $1 AND expires <= nowT;
+    IF FOUND THEN
+      -- The lock was acquired, return the new expiration time.
+      RETURN expiresT;
+    ELSE
+      -- The current lock has not yet expired, return the sentinel value
+      -- indicating the lock was not acquired.
+      RETURN to_timestamp(0);
+    END IF;
+  END
+$$ LANGUAGE plpgsql;
+
+END;
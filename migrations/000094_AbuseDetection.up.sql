@@ -0,0 +1,78 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+-- PublishAbuseStats holds the hourly publish metadata for each authorized app
+-- that the abuse detector compares against a baseline.
+CREATE TABLE PublishAbuseStats (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  app_package_name VARCHAR(1000) NOT NULL,
+  hour TIMESTAMPTZ NOT NULL,
+  requests INT NOT NULL DEFAULT 0,
+  keys INT NOT NULL DEFAULT 0,
+  keys_per_request INT[] NOT NULL DEFAULT '{}',
+  rejected_keys INT NOT NULL DEFAULT 0,
+  skewed_requests INT NOT NULL DEFAULT 0,
+  region_mismatches INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant, app_package_name, hour)
+);
+
+-- PublishFingerprint counts publish requests with the same shape of keys and
+-- symptom onset, per authorized app and hour.
+CREATE TABLE PublishFingerprint (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  app_package_name VARCHAR(1000) NOT NULL,
+  hour TIMESTAMPTZ NOT NULL,
+  fingerprint VARCHAR(64) NOT NULL,
+  requests INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant, app_package_name, hour, fingerprint)
+);
+
+CREATE TABLE AbuseFinding (
+  id SERIAL PRIMARY KEY,
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  app_package_name VARCHAR(1000) NOT NULL,
+  detected_at TIMESTAMPTZ NOT NULL,
+  signal VARCHAR(100) NOT NULL,
+  observed DOUBLE PRECISION NOT NULL,
+  baseline DOUBLE PRECISION NOT NULL,
+  detail TEXT NOT NULL DEFAULT '',
+  resolved BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX abuse_finding_unresolved ON AbuseFinding (tenant, app_package_name) WHERE NOT resolved;
+
+ALTER TABLE AbuseFinding ENABLE ROW LEVEL SECURITY;
+ALTER TABLE AbuseFinding FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON AbuseFinding
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE PublishAbuseStats ENABLE ROW LEVEL SECURITY;
+ALTER TABLE PublishAbuseStats FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON PublishAbuseStats
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE PublishFingerprint ENABLE ROW LEVEL SECURITY;
+ALTER TABLE PublishFingerprint FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON PublishFingerprint
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE AuthorizedApp
+  ADD COLUMN throttled_until TIMESTAMPTZ;
+
+END;
@@ -0,0 +1,54 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- QuarantineCohort is a publish request whose keys scored as suspicious and
+-- are held out of exports until they are reviewed.
+CREATE TABLE QuarantineCohort (
+  id SERIAL PRIMARY KEY,
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  app_package_name VARCHAR(1000) NOT NULL,
+  health_authority_id INT,
+  created_at TIMESTAMPTZ NOT NULL,
+  score INT NOT NULL,
+  reasons TEXT[] NOT NULL DEFAULT '{}',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX quarantine_cohort_pending ON QuarantineCohort (tenant, created_at) WHERE status = 'pending';
+
+-- QuarantinedExposure has the same shape as Exposure. The export worker never
+-- reads from it.
+CREATE TABLE QuarantinedExposure (
+  LIKE Exposure INCLUDING DEFAULTS,
+  cohort_id INT NOT NULL REFERENCES QuarantineCohort(id) ON DELETE CASCADE,
+  PRIMARY KEY (exposure_key)
+);
+
+CREATE INDEX quarantined_exposure_cohort ON QuarantinedExposure (cohort_id);
+
+ALTER TABLE QuarantineCohort ENABLE ROW LEVEL SECURITY;
+ALTER TABLE QuarantineCohort FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON QuarantineCohort
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE QuarantinedExposure ENABLE ROW LEVEL SECURITY;
+ALTER TABLE QuarantinedExposure FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON QuarantinedExposure
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,33 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- Setting overrides a configuration value of a service while it is running.
+-- The name is the environment variable name without any monolith prefix.
+CREATE TABLE Setting (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  service VARCHAR(100) NOT NULL,
+  name VARCHAR(200) NOT NULL,
+  value TEXT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant, service, name)
+);
+
+ALTER TABLE Setting ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Setting FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON Setting
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,36 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- FeatureFlag gates a behavior that is being rolled out. A disabled flag is
+-- off for everyone. An enabled flag is on for the listed subjects (app package
+-- names or export regions) and for rollout_percent percent of the others.
+CREATE TABLE FeatureFlag (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  name VARCHAR(100) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  enabled BOOLEAN NOT NULL DEFAULT false,
+  rollout_percent INT NOT NULL DEFAULT 0 CHECK (rollout_percent BETWEEN 0 AND 100),
+  subjects TEXT[] NOT NULL DEFAULT '{}',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant, name)
+);
+
+ALTER TABLE FeatureFlag ENABLE ROW LEVEL SECURITY;
+ALTER TABLE FeatureFlag FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON FeatureFlag
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,24 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- Publish looks up existing keys by equality only. A hash index is smaller
+-- than the primary key's btree and compares fixed-size hashes instead of
+-- collated text, so lookups of random keys touch fewer pages. The primary key
+-- still enforces uniqueness.
+--
+-- The index is built concurrently so publish keeps accepting keys while it is
+-- built. CREATE INDEX CONCURRENTLY cannot run in a transaction, so this
+-- migration is a single statement without BEGIN and END. If the build fails, it
+-- leaves an invalid index behind; drop it before running the migration again.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS exposure_key_hash ON Exposure USING HASH (exposure_key);
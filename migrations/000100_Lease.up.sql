@@ -0,0 +1,34 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- Lease names the holder (usually a region) that runs a recurring job. The
+-- holder renews the lease each time it runs the job, and another holder can
+-- only take it over once it expires.
+CREATE TABLE Lease (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  name VARCHAR(100) NOT NULL,
+  holder VARCHAR(100) NOT NULL,
+  acquired_at TIMESTAMPTZ NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (tenant, name)
+);
+
+ALTER TABLE Lease ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Lease FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON Lease
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,27 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+ALTER TABLE ExportConfig
+  ADD COLUMN parent_config_id BIGINT REFERENCES ExportConfig(config_id),
+  ADD COLUMN filter_regions TEXT[],
+  ADD COLUMN filter_report_types TEXT[],
+  ADD COLUMN exclude_revised BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX exportconfig_parent_config_id ON ExportConfig(parent_config_id);
+
+CREATE INDEX exportbatch_config_id_window ON ExportBatch(config_id, start_timestamp, end_timestamp);
+
+END;
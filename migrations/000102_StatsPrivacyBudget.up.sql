@@ -0,0 +1,33 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+-- StatsPrivacyBudget is the differential privacy budget that each health
+-- authority has spent on the stats API in each budget period.
+CREATE TABLE StatsPrivacyBudget (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  health_authority_id INT NOT NULL REFERENCES HealthAuthority(id) ON DELETE CASCADE,
+  period_start TIMESTAMPTZ NOT NULL,
+  epsilon_spent DOUBLE PRECISION NOT NULL,
+  PRIMARY KEY (tenant, health_authority_id, period_start)
+);
+
+ALTER TABLE StatsPrivacyBudget ENABLE ROW LEVEL SECURITY;
+ALTER TABLE StatsPrivacyBudget FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON StatsPrivacyBudget
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
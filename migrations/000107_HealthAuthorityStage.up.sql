@@ -0,0 +1,36 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+ALTER TABLE HealthAuthority
+  ADD COLUMN stage TEXT NOT NULL DEFAULT 'active';
+
+ALTER TABLE HealthAuthorityKey
+  ADD COLUMN stage TEXT NOT NULL DEFAULT 'active';
+
+-- Keys verified by a health authority (or key) that is still onboarding are
+-- test data and only ever exported by test export configs.
+ALTER TABLE Exposure
+  ADD COLUMN test_data BOOL NOT NULL DEFAULT false;
+
+ALTER TABLE QuarantinedExposure
+  ADD COLUMN test_data BOOL NOT NULL DEFAULT false;
+
+ALTER TABLE ExportConfig
+  ADD COLUMN test_data BOOL NOT NULL DEFAULT false;
+
+ALTER TABLE ExportBatch
+  ADD COLUMN test_data BOOL NOT NULL DEFAULT false;
+
+END;
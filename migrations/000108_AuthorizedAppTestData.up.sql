@@ -0,0 +1,26 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+ALTER TABLE AuthorizedApp
+  ADD COLUMN test_data BOOL NOT NULL DEFAULT false;
+
+-- Federation clients only receive test data if they are authorized for it,
+-- and then they receive only test data.
+ALTER TABLE FederationOutAuthorization
+  ADD COLUMN test_data BOOL NOT NULL DEFAULT false;
+
+CREATE INDEX exposure_test_data ON Exposure (created_at) WHERE test_data;
+
+END;
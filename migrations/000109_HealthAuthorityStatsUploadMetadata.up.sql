@@ -0,0 +1,23 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- Optional upload metadata distributions, NULL for hours without any captured
+-- uploads. Index is the number of TEKs (0-15) or of days (0-29).
+ALTER TABLE HealthAuthorityStats
+  ADD COLUMN keys_per_upload BIGINT [],
+  ADD COLUMN confirmed_onset_age_days BIGINT [],
+  ADD COLUMN likely_onset_age_days BIGINT [];
+
+END;
@@ -0,0 +1,27 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- How strictly the metadata of imported export files is validated, one of
+-- OFF, FILE, or BATCH.
+ALTER TABLE ExportImport
+  ADD COLUMN strictness TEXT NOT NULL DEFAULT 'OFF';
+
+-- The timestamps of the last batch imported in BATCH strictness, so batches
+-- that go back in time are rejected across import runs.
+ALTER TABLE ExportImport
+  ADD COLUMN last_batch_start TIMESTAMPTZ,
+  ADD COLUMN last_batch_end TIMESTAMPTZ;
+
+END;
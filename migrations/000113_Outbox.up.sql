@@ -0,0 +1,40 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- Outbox holds side effects of database writes, such as notifications and
+-- cache invalidations. Events are written in the same transaction as the
+-- change they describe and delivered afterwards by the outbox relay.
+CREATE TABLE Outbox (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  event_id BIGSERIAL NOT NULL,
+  kind VARCHAR(100) NOT NULL,
+  payload JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL,
+  last_error TEXT,
+  delivered_at TIMESTAMPTZ,
+  PRIMARY KEY (tenant, event_id)
+);
+
+CREATE INDEX outbox_pending_idx ON Outbox (next_attempt_at) WHERE delivered_at IS NULL;
+
+ALTER TABLE Outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Outbox FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON Outbox
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
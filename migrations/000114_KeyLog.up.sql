@@ -0,0 +1,46 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- KeyLog is an append-only, hash-chained log of every health authority key and
+-- export signing key that was configured. Each entry's chain hash covers the
+-- previous entry's chain hash, so an auditor holding a signed tree head can
+-- detect entries that were changed or removed.
+CREATE TABLE KeyLog (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  idx BIGINT NOT NULL,
+  leaf BYTEA NOT NULL,
+  leaf_hash BYTEA NOT NULL,
+  chain_hash BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (tenant, idx)
+);
+
+CREATE OR REPLACE FUNCTION KeyLogAppendOnly() RETURNS TRIGGER AS $$
+	BEGIN
+		RAISE EXCEPTION 'KeyLog is append-only';
+	END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER keylog_append_only
+  BEFORE UPDATE OR DELETE ON KeyLog
+  FOR EACH ROW EXECUTE FUNCTION KeyLogAppendOnly();
+
+ALTER TABLE KeyLog ENABLE ROW LEVEL SECURITY;
+ALTER TABLE KeyLog FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON KeyLog
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,21 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- The JWS algorithm the key is pinned to. Certificates signed with any other
+-- algorithm are rejected.
+ALTER TABLE HealthAuthorityKey
+  ADD COLUMN algorithm TEXT NOT NULL DEFAULT 'ES256';
+
+END;
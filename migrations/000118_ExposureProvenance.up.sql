@@ -0,0 +1,43 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+-- How each key came to this server (publish, federation-in, export-import, or
+-- generated), and the regions it was first stored with. Revisions can add
+-- regions, but do not change the original regions.
+ALTER TABLE Exposure
+  ADD COLUMN provenance_source VARCHAR(20),
+  ADD COLUMN original_regions VARCHAR(5) [];
+
+ALTER TABLE QuarantinedExposure
+  ADD COLUMN provenance_source VARCHAR(20),
+  ADD COLUMN original_regions VARCHAR(5) [];
+
+-- Infer the source of the existing keys. Their original regions are unknown.
+UPDATE Exposure
+SET provenance_source = CASE
+    WHEN app_package_name = 'export-generated' THEN 'generated'
+    WHEN export_import_id IS NOT NULL THEN 'export-import'
+    WHEN NOT local_provenance THEN 'federation-in'
+    ELSE 'publish'
+  END;
+
+UPDATE QuarantinedExposure
+SET provenance_source = CASE
+    WHEN export_import_id IS NOT NULL THEN 'export-import'
+    WHEN NOT local_provenance THEN 'federation-in'
+    ELSE 'publish'
+  END;
+
+END;
@@ -0,0 +1,25 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+ALTER TABLE ExportBatch
+  ADD COLUMN IF NOT EXISTS run_id VARCHAR(64);
+
+ALTER TABLE ImportFile
+  ADD COLUMN IF NOT EXISTS run_id VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS exportbatch_run_id ON ExportBatch(run_id);
+CREATE INDEX IF NOT EXISTS importfile_run_id ON ImportFile(run_id);
+
+END;
@@ -0,0 +1,37 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS PublishCapture (
+  id BIGSERIAL PRIMARY KEY,
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  created_at TIMESTAMPTZ NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  health_authority_id VARCHAR(500) NOT NULL DEFAULT '',
+  platform VARCHAR(20) NOT NULL DEFAULT '',
+  status INT NOT NULL,
+  code VARCHAR(100) NOT NULL DEFAULT '',
+  error_message TEXT NOT NULL DEFAULT '',
+  request JSONB
+);
+
+CREATE INDEX IF NOT EXISTS publishcapture_expires_at ON PublishCapture(tenant, expires_at);
+
+ALTER TABLE PublishCapture ENABLE ROW LEVEL SECURITY;
+ALTER TABLE PublishCapture FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON PublishCapture
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,61 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+ALTER TABLE ExportFile
+  ADD COLUMN IF NOT EXISTS size_bytes BIGINT;
+
+CREATE TABLE IF NOT EXISTS ExportAudit (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  bucket_name VARCHAR(64) NOT NULL,
+  filename_root VARCHAR(100) NOT NULL,
+  config_ids BIGINT[] NOT NULL DEFAULT '{}',
+  run_id VARCHAR(64) NOT NULL DEFAULT '',
+  audited_at TIMESTAMPTZ NOT NULL,
+  files INT NOT NULL DEFAULT 0,
+  objects INT NOT NULL DEFAULT 0,
+  missing INT NOT NULL DEFAULT 0,
+  orphans INT NOT NULL DEFAULT 0,
+  size_mismatches INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant, bucket_name, filename_root)
+);
+
+CREATE TABLE IF NOT EXISTS ExportAuditFinding (
+  id BIGSERIAL PRIMARY KEY,
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  bucket_name VARCHAR(64) NOT NULL,
+  filename_root VARCHAR(100) NOT NULL,
+  filename VARCHAR(200) NOT NULL,
+  kind VARCHAR(20) NOT NULL,
+  batch_id BIGINT,
+  expected_size BIGINT,
+  actual_size BIGINT,
+  repair VARCHAR(20) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS exportauditfinding_location ON ExportAuditFinding(tenant, bucket_name, filename_root);
+
+ALTER TABLE ExportAudit ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ExportAudit FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ExportAudit
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+ALTER TABLE ExportAuditFinding ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ExportAuditFinding FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ExportAuditFinding
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,34 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS ImportFileQuarantine (
+  import_file_id INT PRIMARY KEY REFERENCES ImportFile(id) ON DELETE CASCADE,
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  export_import_id INT NOT NULL REFERENCES ExportImport(id) ON DELETE CASCADE,
+  zip_filename VARCHAR(500) NOT NULL,
+  reason VARCHAR(50) NOT NULL,
+  detail TEXT NOT NULL DEFAULT '',
+  quarantined_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS importfilequarantine_export_import ON ImportFileQuarantine (export_import_id, quarantined_at);
+
+ALTER TABLE ImportFileQuarantine ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ImportFileQuarantine FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ImportFileQuarantine
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,33 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS ClientConfig (
+  tenant TEXT NOT NULL DEFAULT coalesce(current_setting('app.tenant', true), ''),
+  app_package_name VARCHAR(1000) NOT NULL,
+  risk_weights JSONB NOT NULL DEFAULT '{}',
+  endpoints JSONB NOT NULL DEFAULT '{}',
+  features JSONB NOT NULL DEFAULT '{}',
+  version BIGINT NOT NULL DEFAULT 1,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant, app_package_name)
+);
+
+ALTER TABLE ClientConfig ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ClientConfig FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON ClientConfig
+  USING (tenant = coalesce(current_setting('app.tenant', true), ''))
+  WITH CHECK (tenant = coalesce(current_setting('app.tenant', true), ''));
+
+END;
@@ -0,0 +1,24 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrations embeds the schema migrations, so services can compare the
+// live schema with the one they were built for.
+package migrations
+
+import "embed"
+
+// FS holds the up and down migrations, named as for golang-migrate.
+//
+//go:embed *.sql
+var FS embed.FS
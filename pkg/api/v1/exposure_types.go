@@ -60,10 +60,32 @@ const (
 	// ErrorInvalidReportTypeTransition indicates an uploaded TEK tried to
 	// transition to an invalid state (like "positive" -> "likely").
 	ErrorInvalidReportTypeTransition = "invalid_report_type_transition"
+	// ErrorInvalidChunk indicates the `chunk` and `of` fields of a chunked upload
+	// are invalid or do not continue the upload in the revision token.
+	ErrorInvalidChunk = "invalid_chunk"
 	// ErrorPartialFailure indicates that some exposure keys in the publish
 	// request had invalid data (size, timing metadata) and were dropped. Other
 	// keys were saved.
 	ErrorPartialFailure = "partial_failure"
+	// ErrorInvalidRequestSignature indicates that the authorized app
+	// requires signed publish requests and the signature is missing, invalid,
+	// or too old.
+	ErrorInvalidRequestSignature = "invalid_request_signature"
+	// ErrorAddressNotAllowed indicates that the health authority only accepts
+	// publish requests from certain networks, and the request came from
+	// elsewhere.
+	ErrorAddressNotAllowed = "address_not_allowed"
+	// ErrorTemporarilyThrottled indicates that publish requests for the
+	// authorized app are temporarily rejected after anomalous uploads were
+	// detected. The Retry-After header says when to try again.
+	ErrorTemporarilyThrottled = "temporarily_throttled"
+
+	// HeaderSignature and HeaderSignatureTimestamp carry the signature of a
+	// signed publish request. The signature is the standard base64 encoding of
+	// HMAC-SHA256(secret, timestamp + "." + body), where the timestamp is the
+	// request time in Unix seconds.
+	HeaderSignature          = "X-Signature"
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
 )
 
 // Publish represents the body of the PublishInfectedIds API call. Please see
@@ -111,11 +133,36 @@ type Publish struct {
 	// installation).
 	Traveler bool `json:"traveler,omitempty"`
 
+	// FederationConsent (federationConsent) indicates if the user consented to
+	// the TEKs in this publish set being shared outside of the home region,
+	// through federation or as traveler keys in the exports of other regions.
+	// It applies to every key in the request. If omitted, the server's default
+	// sharing policy applies. Keys published with an explicit false are never
+	// shared outside of the home region.
+	FederationConsent *bool `json:"federationConsent,omitempty"`
+
 	// RevisionToken (revisionToken) is an opaque string that must be passed
 	// intact on additional publish requests from the same device, where the same
 	// TEKs may be published again.
 	RevisionToken string `json:"revisionToken"`
 
+	// DeviceNonce (deviceNonce) is an optional, base64-encoded random value of
+	// at most 32 bytes that the device keeps for as long as it keeps the
+	// revision token. If the server issues version 2 revision tokens, the
+	// returned token is bound to the nonce, and a request that presents the
+	// token must send the same nonce.
+	DeviceNonce string `json:"deviceNonce,omitempty"`
+
+	// Chunk (chunk) and Of (of) are used when a client must split its key
+	// history across multiple requests, for example due to platform API limits.
+	// Chunk is the 1-based index of this request and Of is the total number of
+	// requests. Only the first chunk requires a verification payload; each
+	// subsequent chunk must include the revision token returned from the
+	// previous chunk. The server treats all chunks as a single logical upload.
+	// Both are omitted for uploads sent in a single request.
+	Chunk int32 `json:"chunk,omitempty"`
+	Of    int32 `json:"of,omitempty"`
+
 	// Padding (padding) is random, base64-encoded data to obscure the request
 	// size. The server will not process this data in any way. The recommendation
 	// is that padding be at least 1kb in size with a random jitter of at least
@@ -138,13 +185,41 @@ type Publish struct {
 //
 // The Warnings field may be populated with a list of warnings. These are not
 // errors, but may indicate the server mutated the response.
+//
+// The Outcome field is only populated on successful responses, and only if
+// the server has enabled detailed responses for the health authority.
+//
+// The Message field is a short description of the Code that is safe to show
+// to users, in the language negotiated from the Accept-Language header. The
+// ErrorMessage field has the details, in English.
 type PublishResponse struct {
-	RevisionToken     string   `json:"revisionToken,omitempty"`
-	InsertedExposures int      `json:"insertedExposures,omitempty"`
-	ErrorMessage      string   `json:"error,omitempty"`
-	Code              string   `json:"code,omitempty"`
-	Padding           string   `json:"padding,omitempty"`
-	Warnings          []string `json:"warnings,omitempty"`
+	RevisionToken     string          `json:"revisionToken,omitempty"`
+	InsertedExposures int             `json:"insertedExposures,omitempty"`
+	ErrorMessage      string          `json:"error,omitempty"`
+	Code              string          `json:"code,omitempty"`
+	Message           string          `json:"message,omitempty"`
+	Padding           string          `json:"padding,omitempty"`
+	Warnings          []string        `json:"warnings,omitempty"`
+	Outcome           *PublishOutcome `json:"outcome,omitempty"`
+}
+
+// PublishOutcome describes what happened to the keys in a publish request.
+// It lets clients distinguish a request where every key was already known
+// from one where keys were newly inserted.
+type PublishOutcome struct {
+	// Inserted (inserted) is the number of keys that were new.
+	Inserted int `json:"inserted"`
+
+	// Revised (revised) is the number of existing keys that were revised.
+	Revised int `json:"revised"`
+
+	// Dropped (dropped) is the number of keys that were neither inserted nor
+	// revised, for example because they were invalid or already known.
+	Dropped int `json:"dropped"`
+
+	// PaddingBytes (paddingBytes) is the length of the padding field in this
+	// response.
+	PaddingBytes int `json:"paddingBytes"`
 }
 
 // ExposureKey is the 16 byte key, the start time of the key and the duration of
@@ -26,6 +26,11 @@ import (
 const (
 	// ErrorUnauthorized is returned if the provided bearer token is invalid.
 	ErrorUnauthorized = "unauthorized"
+
+	// ErrorPrivacyBudgetExhausted is returned if differential privacy is
+	// enabled and the health authority has spent its privacy budget for the
+	// current period.
+	ErrorPrivacyBudgetExhausted = "privacy_budget_exhausted"
 )
 
 // StatsRequest represents the request to retrieve publish metrics for a
@@ -60,12 +65,31 @@ type StatsResponse struct {
 	// Individual days. There may be gaps if a day does not have enough data.
 	Days StatsDays `json:"days,omitempty"`
 
+	// Privacy is set if differential privacy noise was added to the days.
+	Privacy *StatsPrivacy `json:"privacy,omitempty"`
+
 	ErrorMessage string `json:"error,omitempty"`
 	ErrorCode    string `json:"code,omitempty"`
 
+	// Message describes the ErrorCode in the language negotiated from the
+	// Accept-Language header.
+	Message string `json:"message,omitempty"`
+
 	Padding string `json:"padding"`
 }
 
+// StatsPrivacy describes the differential privacy noise added to a stats
+// response.
+type StatsPrivacy struct {
+	// Epsilon is the privacy loss of this response.
+	Epsilon float64 `json:"epsilon"`
+
+	// BudgetRemaining is the epsilon that is left to spend in the current
+	// budget period, and BudgetResetsAt is when the next period starts.
+	BudgetRemaining float64   `json:"budget_remaining"`
+	BudgetResetsAt  time.Time `json:"budget_resets_at"`
+}
+
 // StatsDay represents stats from an individual day. All stats represent only
 // successful requests.
 type StatsDay struct {
@@ -93,6 +117,21 @@ type StatsDay struct {
 	// date was provided. These request are not included in the onset to upload
 	// distribution.
 	RequestsMissingOnsetDate int64 `json:"requests_missing_onset_date"`
+
+	// The following are only present if the server captures upload metadata.
+
+	// KeysPerUploadDistribution shows a distribution of the number of TEKs in
+	// new uploads. The count at index 0-14 represents the number of uploads with
+	// that many TEKs. Index 15 represents >= 15 TEKs. For chunked uploads only
+	// the first chunk is counted.
+	KeysPerUploadDistribution []int64 `json:"keys_per_upload_distribution,omitempty"`
+
+	// ConfirmedOnsetToUploadDistribution and LikelyOnsetToUploadDistribution
+	// break down the onset to upload distribution by the report type of the
+	// verification certificate. Uploads with other report types are only in the
+	// combined distribution.
+	ConfirmedOnsetToUploadDistribution []int64 `json:"confirmed_onset_to_upload_distribution,omitempty"`
+	LikelyOnsetToUploadDistribution    []int64 `json:"likely_onset_to_upload_distribution,omitempty"`
 }
 
 func (s *StatsDay) IsEmpty() bool {
@@ -40,6 +40,27 @@ type Config struct {
 	PoolMaxConnLife    time.Duration `env:"DB_POOL_MAX_CONN_LIFETIME, default=5m" json:",omitempty"`
 	PoolMaxConnIdle    time.Duration `env:"DB_POOL_MAX_CONN_IDLE_TIME, default=1m" json:",omitempty"`
 	PoolHealthCheck    time.Duration `env:"DB_POOL_HEALTH_CHECK_PERIOD, default=1m" json:",omitempty"`
+
+	// StatementCacheMode is the per-connection statement cache mode, either
+	// "prepare" (server-side prepared statements), "describe" (for use
+	// behind a transaction-pooling proxy such as pgbouncer), or one of pgx's
+	// query exec modes such as "exec" or "simple_protocol".
+	// StatementCacheCapacity is the maximum number of cached statements per
+	// connection; 0 disables the cache.
+	StatementCacheMode     string `env:"DB_STATEMENT_CACHE_MODE, default=prepare" json:",omitempty"`
+	StatementCacheCapacity string `env:"DB_STATEMENT_CACHE_CAPACITY, default=512" json:",omitempty"`
+
+	// Tenant isolates several key servers that share one database. Every
+	// connection is scoped to the tenant's rows with row level security, so
+	// the database user must not be a superuser or have BYPASSRLS. Session
+	// settings are used, so connections must not go through a
+	// transaction-pooling proxy. The empty tenant is the default.
+	Tenant string `env:"DB_TENANT" json:",omitempty"`
+
+	// SchemaCheck compares the live schema with the migrations at startup:
+	// "fail" refuses to start on drift, "warn" logs it, and "off" skips the
+	// check.
+	SchemaCheck string `env:"DB_SCHEMA_CHECK, default=fail" json:",omitempty"`
 }
 
 func (c *Config) DatabaseConfig() *Config {
@@ -23,12 +23,14 @@ import (
 
 	"github.com/google/exposure-notifications-server/pkg/logging"
 
-	pgx "github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
+	pgx "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DB struct {
 	Pool *pgxpool.Pool
+
+	tenant string
 }
 
 // NewFromEnv sets up the database connections using the configuration in the
@@ -40,6 +42,9 @@ func NewFromEnv(ctx context.Context, cfg *Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to parse connection string: %w", err)
 	}
 
+	// Record the latency of every query and batch.
+	pgxConfig.ConnConfig.Tracer = &metricsTracer{}
+
 	// BeforeAcquire is called before before a connection is acquired from the
 	// pool. It must return true to allow the acquision or false to indicate that
 	// the connection should be destroyed and a different connection should be
@@ -50,12 +55,61 @@ func NewFromEnv(ctx context.Context, cfg *Config) (*DB, error) {
 		return conn.Ping(ctx) == nil
 	}
 
-	pool, err := pgxpool.ConnectConfig(ctx, pgxConfig)
+	// AfterConnect scopes new connections to the tenant. Row level security
+	// policies compare each row's tenant to this setting.
+	if cfg.Tenant != "" {
+		pgxConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			if _, err := conn.Exec(ctx, `SELECT set_config('app.tenant', $1, false)`, cfg.Tenant); err != nil {
+				return fmt.Errorf("failed to set tenant: %w", err)
+			}
+			return nil
+		}
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, pgxConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	return &DB{Pool: pool}, nil
+	// The pool connects lazily, so connect now to fail fast on a bad
+	// configuration.
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if cfg.Tenant != "" {
+		if err := checkRowSecurity(ctx, pool); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("tenant %q: %w", cfg.Tenant, err)
+		}
+	}
+
+	return &DB{Pool: pool, tenant: cfg.Tenant}, nil
+}
+
+// Tenant returns the tenant this database is scoped to. The empty string is
+// the default tenant.
+func (db *DB) Tenant() string {
+	return db.tenant
+}
+
+// checkRowSecurity returns an error if the current user is not subject to row
+// level security, since tenants would not be isolated.
+func checkRowSecurity(ctx context.Context, pool *pgxpool.Pool) error {
+	var user string
+	var bypass bool
+	row := pool.QueryRow(ctx, `
+		SELECT rolname, rolsuper OR rolbypassrls
+		FROM pg_roles
+		WHERE rolname = current_user`)
+	if err := row.Scan(&user, &bypass); err != nil {
+		return fmt.Errorf("failed to check database role: %w", err)
+	}
+	if bypass {
+		return fmt.Errorf("database user %q bypasses row level security", user)
+	}
+	return nil
 }
 
 // Close releases database connections.
@@ -111,5 +165,32 @@ func dbValues(cfg *Config) map[string]string {
 	setIfPositiveDuration(p, "pool_max_conn_lifetime", cfg.PoolMaxConnLife)
 	setIfPositiveDuration(p, "pool_max_conn_idle_time", cfg.PoolMaxConnIdle)
 	setIfPositiveDuration(p, "pool_health_check_period", cfg.PoolHealthCheck)
+	setStatementCache(p, cfg.StatementCacheMode, cfg.StatementCacheCapacity)
 	return p
 }
+
+// setStatementCache translates the statement cache mode and capacity to pgx's
+// query exec mode. "prepare" and "describe" cache prepared statements or only
+// their descriptions; any other mode is passed through as a pgx query exec
+// mode. A capacity of 0 disables the cache, so every query is described before
+// it is executed.
+func setStatementCache(m map[string]string, mode, capacity string) {
+	if mode == "" {
+		return
+	}
+
+	capacityKey := "statement_cache_capacity"
+	switch mode {
+	case "prepare":
+		mode = "cache_statement"
+	case "describe":
+		mode = "cache_describe"
+		capacityKey = "description_cache_capacity"
+	}
+	if capacity == "0" && (mode == "cache_statement" || mode == "cache_describe") {
+		m["default_query_exec_mode"] = "describe_exec"
+		return
+	}
+	m["default_query_exec_mode"] = mode
+	setIfNotEmpty(m, capacityKey, capacity)
+}
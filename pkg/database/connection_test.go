@@ -36,6 +36,46 @@ func TestNewFromEnv(t *testing.T) {
 	})
 }
 
+func TestNewFromEnv_Tenant(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	db, config := testDatabaseInstance.NewDatabase(t)
+
+	// The test user is a superuser, which bypasses row level security.
+	superConfig := *config
+	superConfig.Tenant = "a"
+	if _, err := NewFromEnv(ctx, &superConfig); err == nil {
+		t.Fatal("expected error for superuser")
+	}
+
+	dbA := NewTenantDatabase(t, db, config, "a")
+	dbB := NewTenantDatabase(t, db, config, "b")
+
+	if got, want := dbA.Tenant(), "a"; got != want {
+		t.Errorf("expected tenant %q to be %q", got, want)
+	}
+
+	// The same lock can be held by both tenants.
+	if _, err := dbA.Lock(ctx, "tenant-lock", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbB.Lock(ctx, "tenant-lock", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each tenant only sees its own rows.
+	for _, tdb := range []*DB{dbA, dbB} {
+		var count int
+		if err := tdb.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM Lock`).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("tenant %q: expected 1 lock, got %d", tdb.Tenant(), count)
+		}
+	}
+}
+
 func TestDBValues(t *testing.T) {
 	t.Parallel()
 
@@ -57,6 +97,9 @@ func TestDBValues(t *testing.T) {
 				Port:              "1234",
 				ConnectionTimeout: 5,
 				PoolHealthCheck:   5 * time.Minute,
+
+				StatementCacheMode:     "describe",
+				StatementCacheCapacity: "0",
 			},
 			want: map[string]string{
 				"dbname":                   "myDatabase",
@@ -65,6 +108,38 @@ func TestDBValues(t *testing.T) {
 				"user":                     "superuser",
 				"connect_timeout":          "5",
 				"pool_health_check_period": "5m0s",
+				"default_query_exec_mode":  "describe_exec",
+			},
+		},
+		{
+			name: "prepare",
+			config: Config{
+				StatementCacheMode:     "prepare",
+				StatementCacheCapacity: "128",
+			},
+			want: map[string]string{
+				"default_query_exec_mode":  "cache_statement",
+				"statement_cache_capacity": "128",
+			},
+		},
+		{
+			name: "describe",
+			config: Config{
+				StatementCacheMode:     "describe",
+				StatementCacheCapacity: "64",
+			},
+			want: map[string]string{
+				"default_query_exec_mode":    "cache_describe",
+				"description_cache_capacity": "64",
+			},
+		},
+		{
+			name: "pgx mode",
+			config: Config{
+				StatementCacheMode: "simple_protocol",
+			},
+			want: map[string]string{
+				"default_query_exec_mode": "simple_protocol",
 			},
 		},
 	}
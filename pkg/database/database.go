@@ -20,7 +20,7 @@ import (
 	"fmt"
 	"time"
 
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 var (
@@ -38,6 +38,28 @@ func NullableTime(t time.Time) *time.Time {
 	return &t
 }
 
+// Querier is the subset of a connection or transaction used for reads.
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Read runs the given function f on a pooled connection outside of an
+// explicit transaction. It is intended for single-statement reads on hot
+// paths, where the extra BEGIN and COMMIT round trips of InTx are a
+// significant part of the cost. Statements are prepared and cached per
+// connection (see DB_STATEMENT_CACHE_MODE), so callers should keep their SQL
+// text stable and pass all variable values as arguments.
+func (db *DB) Read(ctx context.Context, f func(q Querier) error) error {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Release()
+
+	return f(conn)
+}
+
 // InTx runs the given function f within a transaction with the provided
 // isolation level isoLevel.
 func (db *DB) InTx(ctx context.Context, isoLevel pgx.TxIsoLevel, f func(tx pgx.Tx) error) error {
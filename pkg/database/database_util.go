@@ -35,8 +35,8 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ory/dockertest"
 	"github.com/ory/dockertest/docker"
 	"github.com/sethvargo/go-retry"
@@ -254,7 +254,7 @@ func (i *TestInstance) NewDatabase(tb testing.TB) (*DB, *Config) {
 
 	// Establish a connection to the database.
 	ctx := context.Background()
-	dbpool, err := pgxpool.Connect(ctx, connectionURL.String())
+	dbpool, err := pgxpool.New(ctx, connectionURL.String())
 	if err != nil {
 		tb.Fatalf("failed to connect to database %q: %s", newDatabaseName, err)
 	}
@@ -296,6 +296,52 @@ func (i *TestInstance) NewDatabase(tb testing.TB) (*DB, *Config) {
 	}
 }
 
+// NewTenantDatabase connects to the database in config as the given tenant.
+// The test user is a superuser and bypasses row level security, so the
+// connection uses a new role that does not. db must be connected to the same
+// database as a superuser.
+func NewTenantDatabase(tb testing.TB, db *DB, config *Config, tenant string) *DB {
+	tb.Helper()
+
+	ctx := context.Background()
+
+	suffix, err := randomDatabaseName()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	role := "tenant_" + suffix
+	for _, q := range []string{
+		fmt.Sprintf(`CREATE ROLE %q LOGIN PASSWORD 'tenant'`, role),
+		fmt.Sprintf(`GRANT ALL ON ALL TABLES IN SCHEMA public TO %q`, role),
+		fmt.Sprintf(`GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO %q`, role),
+	} {
+		if _, err := db.Pool.Exec(ctx, q); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	tb.Cleanup(func() {
+		for _, q := range []string{
+			fmt.Sprintf(`DROP OWNED BY %q`, role),
+			fmt.Sprintf(`DROP ROLE %q`, role),
+		} {
+			if _, err := db.Pool.Exec(ctx, q); err != nil {
+				tb.Errorf("failed to drop role %q: %s", role, err)
+			}
+		}
+	})
+
+	tenantConfig := *config
+	tenantConfig.User = role
+	tenantConfig.Password = "tenant"
+	tenantConfig.Tenant = tenant
+	tdb, err := NewFromEnv(ctx, &tenantConfig)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { tdb.Close(ctx) })
+	return tdb
+}
+
 // clone creates a new database with a random name from the template instance.
 func (i *TestInstance) clone() (string, error) {
 	// Generate a random database name.
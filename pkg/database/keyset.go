@@ -0,0 +1,88 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/base64util"
+)
+
+// keysetPrefix marks encoded keysets so they can be told apart from other
+// cursor formats.
+const keysetPrefix = "ks1|"
+
+// ErrNotKeyset is returned by DecodeKeyset when the given string was not
+// produced by Keyset.Encode.
+var ErrNotKeyset = errors.New("not a keyset cursor")
+
+// Keyset is a position in a result set that is ordered by a timestamp column
+// and a unique tie-breaking column. Unlike OFFSET pagination, resuming from a
+// keyset does not require the database to read and discard all of the rows
+// that came before it, so the cost of fetching a page does not grow with the
+// position in the result set.
+type Keyset struct {
+	Time time.Time
+	Key  string
+}
+
+// Encode returns an opaque, URL-safe string representation of the keyset.
+func (k *Keyset) Encode() string {
+	s := keysetPrefix + strconv.FormatInt(k.Time.UnixNano(), 10) + "|" + k.Key
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// After returns a predicate that matches the rows strictly after the keyset
+// when ordered ascending by timeCol and keyCol, along with its arguments. The
+// placeholders are numbered starting at $n.
+func (k *Keyset) After(timeCol, keyCol string, n int) (string, []interface{}) {
+	return fmt.Sprintf("(%s, %s) > ($%d, $%d)", timeCol, keyCol, n, n+1),
+		[]interface{}{k.Time, k.Key}
+}
+
+// DecodeKeyset parses a keyset that was encoded with Encode. If s is a valid
+// base64 string that does not contain a keyset, the error is ErrNotKeyset.
+func DecodeKeyset(s string) (*Keyset, error) {
+	b, err := base64util.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding keyset: %w", err)
+	}
+
+	rest := string(b)
+	if !strings.HasPrefix(rest, keysetPrefix) {
+		return nil, ErrNotKeyset
+	}
+	rest = strings.TrimPrefix(rest, keysetPrefix)
+
+	parts := strings.SplitN(rest, "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("decoding keyset: missing key")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("decoding keyset time: %w", err)
+	}
+
+	return &Keyset{
+		Time: time.Unix(0, nanos).UTC(),
+		Key:  parts[1],
+	}, nil
+}
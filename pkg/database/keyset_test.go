@@ -0,0 +1,105 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestKeyset_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	want := &Keyset{
+		Time: time.Date(2021, 3, 4, 5, 6, 7, 891000, time.UTC),
+		Key:  "ab|c/d+e==",
+	}
+
+	got, err := DecodeKeyset(want.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestKeyset_After(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	k := &Keyset{Time: now, Key: "key"}
+
+	clause, args := k.After("created_at", "exposure_key", 3)
+	if got, want := clause, "(created_at, exposure_key) > ($3, $4)"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if diff := cmp.Diff([]interface{}{now, "key"}, args); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestDecodeKeyset(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		input     string
+		err       bool
+		notKeyset bool
+	}{
+		{
+			name:  "not_base64",
+			input: "!!!",
+			err:   true,
+		},
+		{
+			name:      "offset",
+			input:     base64.StdEncoding.EncodeToString([]byte("12")),
+			err:       true,
+			notKeyset: true,
+		},
+		{
+			name:  "missing_key",
+			input: base64.RawURLEncoding.EncodeToString([]byte(keysetPrefix + "12")),
+			err:   true,
+		},
+		{
+			name:  "bad_time",
+			input: base64.RawURLEncoding.EncodeToString([]byte(keysetPrefix + "abc|key")),
+			err:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := DecodeKeyset(tc.input)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if got := errors.Is(err, ErrNotKeyset); got != tc.notKeyset {
+				t.Errorf("expected ErrNotKeyset %t, got %v", tc.notKeyset, err)
+			}
+		})
+	}
+}
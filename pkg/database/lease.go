@@ -0,0 +1,95 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// ErrLeaseHeld is returned if another holder has an unexpired lease.
+var ErrLeaseHeld = errors.New("lease held by another holder")
+
+// AcquireLease makes holder the holder of the named lease until ttl from now.
+// It succeeds if the lease is free, expired, or already held by holder, so the
+// holder keeps the lease for as long as it renews it. It returns the current
+// holder, and ErrLeaseHeld if that is not holder.
+//
+// Unlike Lock, a lease is not released when a job finishes. It keeps a
+// recurring job with the same holder, such as one region of an active/active
+// deployment, and moves it to another holder only when the first one stops
+// renewing it.
+func (db *DB) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (string, error) {
+	if name == "" || holder == "" {
+		return "", fmt.Errorf("lease name and holder are required")
+	}
+
+	var current string
+	if err := db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO
+				Lease (name, holder, acquired_at, expires_at)
+			VALUES
+				($1, $2, now(), now() + $3 * INTERVAL '1 second')
+			ON CONFLICT (tenant, name) DO UPDATE
+				SET
+					holder = EXCLUDED.holder,
+					expires_at = EXCLUDED.expires_at,
+					acquired_at = CASE WHEN Lease.holder = EXCLUDED.holder
+						THEN Lease.acquired_at ELSE EXCLUDED.acquired_at END
+				WHERE
+					Lease.holder = EXCLUDED.holder OR Lease.expires_at <= now()
+			RETURNING holder
+		`, name, holder, int64(ttl.Seconds()))
+		err := row.Scan(&current)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to acquire lease: %w", err)
+		}
+
+		// Another holder has the lease.
+		row = tx.QueryRow(ctx, `SELECT holder FROM Lease WHERE name = $1`, name)
+		if err := row.Scan(&current); err != nil {
+			return fmt.Errorf("failed to read lease: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	if current != holder {
+		return current, ErrLeaseHeld
+	}
+	return current, nil
+}
+
+// ReleaseLease expires the named lease if holder holds it, so another holder
+// can take it over without waiting for it to expire.
+func (db *DB) ReleaseLease(ctx context.Context, name, holder string) error {
+	return db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			UPDATE Lease SET expires_at = now() WHERE name = $1 AND holder = $2
+		`, name, holder); err != nil {
+			return fmt.Errorf("failed to release lease: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestLease(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	const name = "create-batches"
+
+	if _, err := testDB.AcquireLease(ctx, name, "", time.Hour); err == nil {
+		t.Errorf("expected error for missing holder")
+	}
+
+	// Take a free lease, and renew it.
+	for i := 0; i < 2; i++ {
+		if holder, err := testDB.AcquireLease(ctx, name, "us-east1", time.Second); err != nil || holder != "us-east1" {
+			t.Fatalf("expected us-east1 to hold the lease, got %q, %v", holder, err)
+		}
+	}
+
+	// Another holder cannot take an unexpired lease.
+	holder, err := testDB.AcquireLease(ctx, name, "us-west1", time.Hour)
+	if !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("expected ErrLeaseHeld, got %v", err)
+	}
+	if holder != "us-east1" {
+		t.Errorf("expected us-east1 to hold the lease, got %q", holder)
+	}
+
+	// It takes over once the lease expires.
+	time.Sleep(1100 * time.Millisecond)
+	if holder, err := testDB.AcquireLease(ctx, name, "us-west1", time.Hour); err != nil || holder != "us-west1" {
+		t.Fatalf("expected us-west1 to hold the lease, got %q, %v", holder, err)
+	}
+
+	// Releasing hands it over immediately. Only the holder can release it.
+	if err := testDB.ReleaseLease(ctx, name, "us-east1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.AcquireLease(ctx, name, "us-east1", time.Hour); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("expected ErrLeaseHeld, got %v", err)
+	}
+	if err := testDB.ReleaseLease(ctx, name, "us-west1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.AcquireLease(ctx, name, "us-east1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+}
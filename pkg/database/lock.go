@@ -22,7 +22,7 @@ import (
 	"time"
 
 	"github.com/google/exposure-notifications-server/pkg/logging"
-	pgx "github.com/jackc/pgx/v4"
+	pgx "github.com/jackc/pgx/v5"
 )
 
 var (
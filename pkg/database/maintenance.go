@@ -0,0 +1,123 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// TableStats are the tuple counts and sizes of a table, from
+// pg_stat_user_tables. The tuple counts are estimates maintained by the
+// statistics collector.
+type TableStats struct {
+	Table      string
+	LiveTuples int64
+	DeadTuples int64
+	TableBytes int64
+	IndexBytes int64
+
+	// LastVacuum is the last manual or automatic vacuum, or nil if the table
+	// was never vacuumed.
+	LastVacuum *time.Time
+}
+
+// DeadRatio returns the fraction of tuples that are dead, a rough measure of
+// table bloat.
+func (s *TableStats) DeadRatio() float64 {
+	total := s.LiveTuples + s.DeadTuples
+	if total <= 0 {
+		return 0
+	}
+	return float64(s.DeadTuples) / float64(total)
+}
+
+// Maintenance statements are sent with the simple protocol, since VACUUM and
+// REINDEX CONCURRENTLY can't run in the implicit transaction of a prepared
+// statement.
+
+// tableIdentifier returns the quoted identifier of the table. Tables are
+// created with unquoted names, which Postgres folds to lower case.
+func tableIdentifier(table string) string {
+	return pgx.Identifier{strings.ToLower(table)}.Sanitize()
+}
+
+// TableStats returns the statistics of the table in the current schema, or
+// ErrNotFound if there is no such table.
+func (db *DB) TableStats(ctx context.Context, table string) (*TableStats, error) {
+	s := &TableStats{Table: table}
+	row := db.Pool.QueryRow(ctx, `
+		SELECT
+			n_live_tup, n_dead_tup, pg_table_size(relid), pg_indexes_size(relid),
+			GREATEST(last_vacuum, last_autovacuum)
+		FROM pg_stat_user_tables
+		WHERE schemaname = current_schema() AND relname = $1`,
+		strings.ToLower(table))
+	if err := row.Scan(&s.LiveTuples, &s.DeadTuples, &s.TableBytes, &s.IndexBytes, &s.LastVacuum); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("reading stats of %s: %w", table, err)
+	}
+	return s, nil
+}
+
+// SetAutovacuumScaleFactors sets the fractions of the table that must change
+// before autovacuum vacuums and analyzes it. The Postgres defaults of 0.2 and
+// 0.1 let large tables accumulate millions of dead tuples between vacuums.
+// It requires ownership of the table.
+func (db *DB) SetAutovacuumScaleFactors(ctx context.Context, table string, vacuum, analyze float64) error {
+	for _, f := range []float64{vacuum, analyze} {
+		if f <= 0 || f > 1 {
+			return fmt.Errorf("scale factor %v must be in (0, 1]", f)
+		}
+	}
+
+	// Storage parameters can't be bound, but they are formatted numbers.
+	q := fmt.Sprintf("ALTER TABLE %s SET (autovacuum_vacuum_scale_factor = %s, autovacuum_analyze_scale_factor = %s)",
+		tableIdentifier(table),
+		strconv.FormatFloat(vacuum, 'f', -1, 64),
+		strconv.FormatFloat(analyze, 'f', -1, 64))
+	if _, err := db.Pool.Exec(ctx, q, pgx.QueryExecModeSimpleProtocol); err != nil {
+		return fmt.Errorf("setting autovacuum scale factors of %s: %w", table, err)
+	}
+	return nil
+}
+
+// Vacuum vacuums and analyzes the table, making the space of dead tuples
+// available for reuse. It does not block reads or writes. Postgres skips the
+// table with a warning unless the user owns it.
+func (db *DB) Vacuum(ctx context.Context, table string) error {
+	if _, err := db.Pool.Exec(ctx, "VACUUM (ANALYZE) "+tableIdentifier(table), pgx.QueryExecModeSimpleProtocol); err != nil {
+		return fmt.Errorf("vacuuming %s: %w", table, err)
+	}
+	return nil
+}
+
+// ReindexConcurrently rebuilds the indexes of the table without blocking
+// writes, which shrinks indexes that vacuum can't. It requires Postgres 12 or
+// later and ownership of the table.
+func (db *DB) ReindexConcurrently(ctx context.Context, table string) error {
+	if _, err := db.Pool.Exec(ctx, "REINDEX TABLE CONCURRENTLY "+tableIdentifier(table), pgx.QueryExecModeSimpleProtocol); err != nil {
+		return fmt.Errorf("reindexing %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestTableStats_DeadRatio(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		live int64
+		dead int64
+		want float64
+	}{
+		{name: "empty", want: 0},
+		{name: "no_dead", live: 10, want: 0},
+		{name: "quarter", live: 30, dead: 10, want: 0.25},
+		{name: "all_dead", dead: 10, want: 1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &TableStats{LiveTuples: tc.live, DeadTuples: tc.dead}
+			if got := s.DeadRatio(); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	if _, err := testDB.TableStats(ctx, "NotATable"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := testDB.SetAutovacuumScaleFactors(ctx, "Exposure", 0.02, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDB.SetAutovacuumScaleFactors(ctx, "Exposure", 0, 0.01); err == nil {
+		t.Errorf("expected error for a zero scale factor")
+	}
+	if err := testDB.Vacuum(ctx, "Exposure"); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDB.ReindexConcurrently(ctx, "Exposure"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := testDB.TableStats(ctx, "Exposure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Even an empty index has a meta page.
+	if stats.IndexBytes <= 0 {
+		t.Errorf("expected index size, got %#v", stats)
+	}
+}
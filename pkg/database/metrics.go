@@ -0,0 +1,59 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/pkg/observability"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const metricPrefix = metrics.MetricRoot + "database"
+
+var (
+	mQueryLatencyMs = stats.Float64(metricPrefix+"/query/latency", "database query latency", stats.UnitMilliseconds)
+	mBatchSize      = stats.Int64(metricPrefix+"/batch/size", "queries per database batch", stats.UnitDimensionless)
+
+	statementTagKey = tag.MustNewKey("statement")
+)
+
+func init() {
+	observability.CollectViews([]*view.View{
+		{
+			Name:        metricPrefix + "/query/count",
+			Description: "Number of database queries and batches",
+			Measure:     mQueryLatencyMs,
+			TagKeys:     []tag.Key{statementTagKey, observability.ResultTagKey},
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/query/latency",
+			Description: "Distribution of database query and batch latency in milliseconds",
+			Measure:     mQueryLatencyMs,
+			TagKeys:     []tag.Key{statementTagKey, observability.ResultTagKey},
+			Aggregation: ochttp.DefaultLatencyDistribution,
+		},
+		{
+			Name:        metricPrefix + "/batch/size",
+			Description: "Distribution of the number of queries per database batch",
+			Measure:     mBatchSize,
+			Aggregation: view.Distribution(1, 2, 5, 10, 20, 50, 100, 200, 500, 1000),
+		},
+	}...)
+}
@@ -0,0 +1,161 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultPageLimit is the number of rows in a page if no limit is given.
+	DefaultPageLimit = 50
+
+	// MaxPageLimit is the largest number of rows in a page.
+	MaxPageLimit = 500
+)
+
+// PageParams selects one page of a searchable, sortable list, such as the
+// lists in the admin console. These lists are small, so pages are selected
+// with OFFSET instead of a Keyset.
+type PageParams struct {
+	// Search limits the list to rows where one of the list's search columns
+	// contains the text, ignoring case.
+	Search string
+
+	// Sort is one of the list's sort keys. If empty, the list's default sort
+	// is used. Desc reverses the order.
+	Sort string
+	Desc bool
+
+	// Page is the page number, starting at 1. Limit is the number of rows in a
+	// page, DefaultPageLimit if <= 0 and at most MaxPageLimit.
+	Page  int
+	Limit int
+}
+
+func (p *PageParams) number() int {
+	if p.Page < 1 {
+		return 1
+	}
+	return p.Page
+}
+
+func (p *PageParams) limit() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	default:
+		return p.Limit
+	}
+}
+
+// Page describes the page of a list that was returned.
+type Page struct {
+	// Number is the page number, starting at 1.
+	Number int
+	Limit  int
+
+	// Total is the number of rows that match the search, on all pages.
+	Total int64
+}
+
+// NewPage returns the page that p selects from a list with total rows.
+func NewPage(p *PageParams, total int64) *Page {
+	return &Page{
+		Number: p.number(),
+		Limit:  p.limit(),
+		Total:  total,
+	}
+}
+
+// Pages returns the number of pages, at least 1.
+func (p *Page) Pages() int {
+	if p.Total <= 0 {
+		return 1
+	}
+	return int((p.Total + int64(p.Limit) - 1) / int64(p.Limit))
+}
+
+// HasPrev returns true if there is a page before this one.
+func (p *Page) HasPrev() bool {
+	return p.Number > 1
+}
+
+// HasNext returns true if there is a page after this one.
+func (p *Page) HasNext() bool {
+	return p.Number < p.Pages()
+}
+
+// ListQuery describes how a table can be searched and sorted.
+type ListQuery struct {
+	// SearchColumns are the text expressions that Search is matched against.
+	SearchColumns []string
+
+	// SortColumns maps the sort keys to the expressions they order by.
+	SortColumns map[string]string
+
+	// DefaultSort is the sort key used if none is given.
+	DefaultSort string
+
+	// TieBreaker is a unique column that orders the rows with the same sort
+	// value, so pages don't overlap.
+	TieBreaker string
+}
+
+// Build returns the WHERE clause for the search, which may be empty, and the
+// ORDER BY, LIMIT and OFFSET clauses for the page, along with the arguments of
+// the WHERE clause. The placeholders are numbered starting at $1. Unknown sort
+// keys are an error.
+func (q *ListQuery) Build(p *PageParams) (string, string, []interface{}, error) {
+	sort := p.Sort
+	if sort == "" {
+		sort = q.DefaultSort
+	}
+	column, ok := q.SortColumns[sort]
+	if !ok {
+		return "", "", nil, fmt.Errorf("invalid sort %q", sort)
+	}
+
+	direction := "ASC"
+	if p.Desc {
+		direction = "DESC"
+	}
+
+	var where string
+	var args []interface{}
+	if search := strings.TrimSpace(p.Search); search != "" && len(q.SearchColumns) > 0 {
+		conditions := make([]string, 0, len(q.SearchColumns))
+		for _, c := range q.SearchColumns {
+			conditions = append(conditions, c+" ILIKE $1")
+		}
+		where = "WHERE (" + strings.Join(conditions, " OR ") + ")"
+		args = append(args, "%"+escapeLike(search)+"%")
+	}
+
+	limit := p.limit()
+	offset := (p.number() - 1) * limit
+	order := fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT %d OFFSET %d",
+		column, direction, q.TieBreaker, direction, limit, offset)
+
+	return where, order, args, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
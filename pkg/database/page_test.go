@@ -0,0 +1,129 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListQuery_Build(t *testing.T) {
+	t.Parallel()
+
+	q := &ListQuery{
+		SearchColumns: []string{"name", "region"},
+		SortColumns:   map[string]string{"name": "LOWER(name)", "region": "region"},
+		DefaultSort:   "name",
+		TieBreaker:    "id",
+	}
+
+	cases := []struct {
+		name      string
+		params    *PageParams
+		wantWhere string
+		wantOrder string
+		wantArgs  []interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "defaults",
+			params:    &PageParams{},
+			wantOrder: "ORDER BY LOWER(name) ASC, id ASC LIMIT 50 OFFSET 0",
+		},
+		{
+			name:      "sort_desc_page",
+			params:    &PageParams{Sort: "region", Desc: true, Page: 3, Limit: 20},
+			wantOrder: "ORDER BY region DESC, id DESC LIMIT 20 OFFSET 40",
+		},
+		{
+			name:      "max_limit",
+			params:    &PageParams{Limit: 10000},
+			wantOrder: "ORDER BY LOWER(name) ASC, id ASC LIMIT 500 OFFSET 0",
+		},
+		{
+			name:      "search",
+			params:    &PageParams{Search: " 50%_off\\ "},
+			wantWhere: "WHERE (name ILIKE $1 OR region ILIKE $1)",
+			wantOrder: "ORDER BY LOWER(name) ASC, id ASC LIMIT 50 OFFSET 0",
+			wantArgs:  []interface{}{`%50\%\_off\\%`},
+		},
+		{
+			name:    "invalid_sort",
+			params:  &PageParams{Sort: "name; DROP TABLE x"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			where, order, args, err := q.Build(tc.params)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if got, want := where, tc.wantWhere; got != want {
+				t.Errorf("expected where %q to be %q", got, want)
+			}
+			if got, want := order, tc.wantOrder; got != want {
+				t.Errorf("expected order %q to be %q", got, want)
+			}
+			if diff := cmp.Diff(tc.wantArgs, args); diff != "" {
+				t.Errorf("args mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		params    *PageParams
+		total     int64
+		wantPages int
+		wantPrev  bool
+		wantNext  bool
+	}{
+		{"empty", &PageParams{}, 0, 1, false, false},
+		{"one_page", &PageParams{Limit: 10}, 10, 1, false, false},
+		{"first_of_many", &PageParams{Limit: 10}, 11, 2, false, true},
+		{"middle", &PageParams{Page: 2, Limit: 10}, 25, 3, true, true},
+		{"last", &PageParams{Page: 3, Limit: 10}, 25, 3, true, false},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewPage(tc.params, tc.total)
+			if got, want := p.Pages(), tc.wantPages; got != want {
+				t.Errorf("expected %d pages to be %d", got, want)
+			}
+			if got, want := p.HasPrev(), tc.wantPrev; got != want {
+				t.Errorf("expected prev %t to be %t", got, want)
+			}
+			if got, want := p.HasNext(), tc.wantNext; got != want {
+				t.Errorf("expected next %t to be %t", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// PlanNode is a node of a query plan, as returned by EXPLAIN (FORMAT JSON).
+type PlanNode struct {
+	NodeType     string      `json:"Node Type"`
+	RelationName string      `json:"Relation Name"`
+	IndexName    string      `json:"Index Name"`
+	IndexCond    string      `json:"Index Cond"`
+	Plans        []*PlanNode `json:"Plans"`
+}
+
+// UnindexedScans returns the sorted, distinct relations that the plan scans
+// without an index condition: sequential scans, and full index scans, which
+// the planner picks over a sequential scan when they are disabled.
+func (n *PlanNode) UnindexedScans() []string {
+	seen := make(map[string]struct{})
+	var walk func(n *PlanNode)
+	walk = func(n *PlanNode) {
+		switch n.NodeType {
+		case "Seq Scan":
+			seen[n.RelationName] = struct{}{}
+		case "Index Scan", "Index Only Scan":
+			if n.IndexCond == "" {
+				seen[n.RelationName] = struct{}{}
+			}
+		}
+		for _, child := range n.Plans {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	scans := make([]string, 0, len(seen))
+	for r := range seen {
+		scans = append(scans, r)
+	}
+	sort.Strings(scans)
+	return scans
+}
+
+// ExplainIndexed returns the plan of the statement, without running it, with
+// sequential scans disabled. The planner then uses an index condition
+// wherever an index can serve the statement, whatever the size of the table,
+// so an unindexed scan in the plan means an index is missing.
+func (db *DB) ExplainIndexed(ctx context.Context, sql string, args ...interface{}) (*PlanNode, error) {
+	var plans []struct {
+		Plan *PlanNode `json:"Plan"`
+	}
+	if err := db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SET LOCAL enable_seqscan = off"); err != nil {
+			return fmt.Errorf("disabling sequential scans: %w", err)
+		}
+
+		var raw string
+		if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+sql, args...).Scan(&raw); err != nil {
+			return fmt.Errorf("explaining statement: %w", err)
+		}
+		if err := json.Unmarshal([]byte(raw), &plans); err != nil {
+			return fmt.Errorf("parsing plan: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(plans) != 1 || plans[0].Plan == nil {
+		return nil, fmt.Errorf("expected one plan, got %d", len(plans))
+	}
+	return plans[0].Plan, nil
+}
+
+// RequireIndexed fails the test if the plan of the statement scans any of the
+// tables without an index condition. It guards the queries on hot paths against migrations
+// that drop or change the indexes they rely on. The tables should be seeded
+// and analyzed first, so the plan matches production.
+func RequireIndexed(tb testing.TB, db *DB, tables []string, sql string, args ...interface{}) {
+	tb.Helper()
+
+	plan, err := db.ExplainIndexed(context.Background(), sql, args...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for _, scan := range plan.UnindexedScans() {
+		for _, table := range tables {
+			if strings.EqualFold(scan, table) {
+				tb.Errorf("query scans %s without an index:\n%s", scan, sql)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlanNode_UnindexedScans(t *testing.T) {
+	t.Parallel()
+
+	raw := `{
+		"Node Type": "Nested Loop",
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "exportfile"},
+			{"Node Type": "Index Scan", "Relation Name": "exportbatch", "Index Name": "exportbatch_pkey", "Index Cond": "(batch_id = ef.batch_id)"},
+			{"Node Type": "Index Only Scan", "Relation Name": "exportimport", "Index Name": "exportimport_pkey"},
+			{"Node Type": "Hash", "Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "exportfile"},
+				{"Node Type": "Seq Scan", "Relation Name": "exportconfig"}
+			]}
+		]
+	}`
+
+	var plan PlanNode
+	if err := json.Unmarshal([]byte(raw), &plan); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"exportconfig", "exportfile", "exportimport"}, plan.UnindexedScans()); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestExplainIndexed(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	// Lock has an index on lock_id. Setting has none on value, so it is
+	// scanned sequentially or through its whole primary key.
+	plan, err := testDB.ExplainIndexed(ctx, `SELECT * FROM Lock WHERE lock_id = $1`, "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scans := plan.UnindexedScans(); len(scans) > 0 {
+		t.Errorf("expected no unindexed scans, got %v", scans)
+	}
+
+	plan, err = testDB.ExplainIndexed(ctx, `SELECT * FROM Setting WHERE value = $1`, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"setting"}, plan.UnindexedScans()); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
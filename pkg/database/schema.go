@@ -0,0 +1,438 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// Schema check modes for DB_SCHEMA_CHECK.
+const (
+	// SchemaCheckOff does not compare the schema at startup.
+	SchemaCheckOff = "off"
+	// SchemaCheckWarn logs the differences between the live and expected
+	// schema at startup.
+	SchemaCheckWarn = "warn"
+	// SchemaCheckFail refuses to start when the live schema has drifted from
+	// the expected schema.
+	SchemaCheckFail = "fail"
+)
+
+// Schema is the migration version and the columns of each table of a
+// database. Table and column names are lower case, as Postgres folds unquoted
+// identifiers.
+type Schema struct {
+	Version uint
+	Dirty   bool
+	Tables  map[string]map[string]struct{}
+}
+
+// SchemaDrift is the difference between the live schema and the schema
+// expected from the migrations.
+type SchemaDrift struct {
+	ExpectedVersion uint
+	Version         uint
+	Dirty           bool
+
+	// MissingTables and MissingColumns are expected but not in the database.
+	// Columns are "table.column".
+	MissingTables  []string
+	MissingColumns []string
+
+	// ExtraColumns are in tables the migrations know about, but were not
+	// created by them.
+	ExtraColumns []string
+}
+
+// Failed returns true if services are expected to fail against the live
+// schema: a migration was partially applied, migrations are pending, or
+// expected tables or columns are missing. A database that is migrated past
+// this build, for example during a rollout, is not considered failed, since
+// migrations are additive.
+func (d *SchemaDrift) Failed() bool {
+	if d.Dirty || d.Version < d.ExpectedVersion {
+		return true
+	}
+	if d.Version > d.ExpectedVersion {
+		return false
+	}
+	return len(d.MissingTables) > 0 || len(d.MissingColumns) > 0
+}
+
+// Empty returns true if there is no difference at all.
+func (d *SchemaDrift) Empty() bool {
+	return !d.Dirty && d.Version == d.ExpectedVersion &&
+		len(d.MissingTables) == 0 && len(d.MissingColumns) == 0 && len(d.ExtraColumns) == 0
+}
+
+// String returns a diff of the live schema against the expected schema, one
+// difference per line. Lines starting with "-" are expected but missing,
+// lines starting with "+" are present but not expected.
+func (d *SchemaDrift) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "migration version %d, expected %d", d.Version, d.ExpectedVersion)
+	if d.Dirty {
+		b.WriteString(" (dirty: a migration was partially applied)")
+	}
+	b.WriteString("\n")
+	for _, t := range d.MissingTables {
+		fmt.Fprintf(&b, "- table %s\n", t)
+	}
+	for _, c := range d.MissingColumns {
+		fmt.Fprintf(&b, "- column %s\n", c)
+	}
+	for _, c := range d.ExtraColumns {
+		fmt.Fprintf(&b, "+ column %s\n", c)
+	}
+	return b.String()
+}
+
+// ExpectedSchema replays the up migrations in fsys, named as for
+// golang-migrate, and returns the resulting version, tables, and columns.
+// Only table and column definitions are tracked: CREATE TABLE, DROP TABLE,
+// and ALTER TABLE with ADD, DROP, or RENAME. Statements in functions and DO
+// blocks are ignored.
+func ExpectedSchema(fsys fs.FS) (*Schema, error) {
+	names, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil {
+		return nil, fmt.Errorf("listing migrations: %w", err)
+	}
+
+	type migration struct {
+		version uint
+		name    string
+	}
+	migrations := make([]migration, 0, len(names))
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(path.Base(name), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: missing version prefix", name)
+		}
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+		migrations = append(migrations, migration{uint(v), name})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	schema := &Schema{Tables: make(map[string]map[string]struct{})}
+	for _, m := range migrations {
+		b, err := fs.ReadFile(fsys, m.name)
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", m.name, err)
+		}
+		for _, stmt := range splitStatements(string(b)) {
+			if err := schema.apply(stmt); err != nil {
+				return nil, fmt.Errorf("migration %s: %w", m.name, err)
+			}
+		}
+		schema.Version = m.version
+	}
+	return schema, nil
+}
+
+// apply updates the schema with one statement.
+func (s *Schema) apply(stmt string) error {
+	words := strings.Fields(strings.ToLower(stmt))
+	words, ok := trimWords(words, "create", "table")
+	if ok {
+		words, _ = trimWords(words, "if", "not", "exists")
+		if len(words) == 0 {
+			return fmt.Errorf("missing table name: %q", stmt)
+		}
+		table, _, _ := strings.Cut(words[0], "(")
+		columns := make(map[string]struct{})
+		open := strings.Index(stmt, "(")
+		end := strings.LastIndex(stmt, ")")
+		if open < 0 || end < open {
+			return fmt.Errorf("missing columns of %s", table)
+		}
+		for _, def := range splitTopLevel(stmt[open+1:end], ',') {
+			fields := strings.Fields(strings.ToLower(def))
+			if len(fields) == 0 || isConstraint(fields[0]) {
+				continue
+			}
+			if fields[0] == "like" && len(fields) > 1 {
+				// LIKE copies the columns the other table has at this point.
+				for column := range s.Tables[fields[1]] {
+					columns[column] = struct{}{}
+				}
+				continue
+			}
+			columns[fields[0]] = struct{}{}
+		}
+		s.Tables[table] = columns
+		return nil
+	}
+
+	words = strings.Fields(strings.ToLower(stmt))
+	if words, ok := trimWords(words, "drop", "table"); ok {
+		words, _ = trimWords(words, "if", "exists")
+		for _, w := range words {
+			for _, table := range strings.Split(w, ",") {
+				delete(s.Tables, table)
+			}
+		}
+		return nil
+	}
+
+	words = strings.Fields(strings.ToLower(stmt))
+	words, ok = trimWords(words, "alter", "table")
+	if !ok {
+		return nil
+	}
+	words, _ = trimWords(words, "if", "exists")
+	words, _ = trimWords(words, "only")
+	if len(words) < 2 {
+		return fmt.Errorf("incomplete ALTER TABLE: %q", stmt)
+	}
+	table := words[0]
+	columns, ok := s.Tables[table]
+	if !ok {
+		return fmt.Errorf("ALTER TABLE of unknown table %s", table)
+	}
+
+	// Everything after the table name is a comma separated list of actions.
+	rest := strings.TrimSpace(strings.Join(words[1:], " "))
+	for _, action := range splitTopLevel(rest, ',') {
+		fields := strings.Fields(action)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "add":
+			fields, _ = trimWords(fields[1:], "column")
+			fields, _ = trimWords(fields, "if", "not", "exists")
+			if len(fields) == 0 || isConstraint(fields[0]) {
+				continue
+			}
+			columns[fields[0]] = struct{}{}
+		case "drop":
+			fields, _ = trimWords(fields[1:], "column")
+			fields, _ = trimWords(fields, "if", "exists")
+			if len(fields) == 0 || fields[0] == "constraint" {
+				continue
+			}
+			delete(columns, fields[0])
+		case "rename":
+			if fields, ok := trimWords(fields[1:], "to"); ok && len(fields) > 0 {
+				delete(s.Tables, table)
+				s.Tables[fields[0]] = columns
+				continue
+			}
+			fields, _ = trimWords(fields[1:], "column")
+			if len(fields) == 3 && fields[0] != "constraint" && fields[1] == "to" {
+				delete(columns, fields[0])
+				columns[fields[2]] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+// isConstraint returns true if the first word of a table element or ADD
+// action starts a constraint rather than a column.
+func isConstraint(word string) bool {
+	switch word {
+	case "constraint", "primary", "unique", "foreign", "check", "exclude":
+		return true
+	}
+	return false
+}
+
+// trimWords removes prefix from words, returning false if words does not start
+// with prefix.
+func trimWords(words []string, prefix ...string) ([]string, bool) {
+	if len(words) < len(prefix) {
+		return words, false
+	}
+	for i, p := range prefix {
+		if words[i] != p {
+			return words, false
+		}
+	}
+	return words[len(prefix):], true
+}
+
+// splitStatements splits SQL into statements on semicolons outside of quotes,
+// dollar quoted bodies, and parentheses. Comments are removed. Statements of
+// dollar quoted bodies, such as functions and DO blocks, are returned as part
+// of the enclosing statement.
+func splitStatements(sql string) []string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		lines = append(lines, line)
+	}
+	return splitTopLevel(strings.Join(lines, "\n"), ';')
+}
+
+// splitTopLevel splits s on sep outside of quotes, dollar quoted bodies, and
+// parentheses, and trims each part. Empty parts are dropped.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	dollar := ""
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case dollar != "":
+			if strings.HasPrefix(s[i:], dollar) {
+				i += len(dollar) - 1
+				dollar = ""
+			}
+		case inQuote:
+			if c == '\'' {
+				inQuote = false
+			}
+		case c == '\'':
+			inQuote = true
+		case c == '$':
+			if j := strings.IndexByte(s[i+1:], '$'); j >= 0 && isTag(s[i+1:i+1+j]) {
+				dollar = s[i : i+j+2]
+				i += j + 1
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// isTag returns true if s is a valid dollar quote tag, which may be empty.
+func isTag(s string) bool {
+	for _, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// LiveSchema reads the migration version and the tables and columns of the
+// current schema from the database.
+func (db *DB) LiveSchema(ctx context.Context) (*Schema, error) {
+	schema := &Schema{Tables: make(map[string]map[string]struct{})}
+
+	if err := db.Read(ctx, func(q Querier) error {
+		var hasMigrations bool
+		if err := q.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&hasMigrations); err != nil {
+			return fmt.Errorf("looking up schema_migrations: %w", err)
+		}
+		if hasMigrations {
+			var version int64
+			if err := q.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).
+				Scan(&version, &schema.Dirty); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("reading migration version: %w", err)
+			}
+			if version > 0 {
+				schema.Version = uint(version)
+			}
+		}
+
+		rows, err := q.Query(ctx, `
+			SELECT lower(table_name), lower(column_name)
+			FROM information_schema.columns
+			WHERE table_schema = current_schema()
+			`)
+		if err != nil {
+			return fmt.Errorf("listing columns: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var table, column string
+			if err := rows.Scan(&table, &column); err != nil {
+				return fmt.Errorf("scanning column: %w", err)
+			}
+			if schema.Tables[table] == nil {
+				schema.Tables[table] = make(map[string]struct{})
+			}
+			schema.Tables[table][column] = struct{}{}
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// CheckSchema compares the live schema with the schema expected from the up
+// migrations in fsys.
+func (db *DB) CheckSchema(ctx context.Context, fsys fs.FS) (*SchemaDrift, error) {
+	expected, err := ExpectedSchema(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("reading expected schema: %w", err)
+	}
+	live, err := db.LiveSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading live schema: %w", err)
+	}
+	return CompareSchema(expected, live), nil
+}
+
+// CompareSchema returns the drift of live from expected. Tables that are not
+// in expected, such as schema_migrations, are ignored.
+func CompareSchema(expected, live *Schema) *SchemaDrift {
+	drift := &SchemaDrift{
+		ExpectedVersion: expected.Version,
+		Version:         live.Version,
+		Dirty:           live.Dirty,
+	}
+	for table, columns := range expected.Tables {
+		liveColumns, ok := live.Tables[table]
+		if !ok {
+			drift.MissingTables = append(drift.MissingTables, table)
+			continue
+		}
+		for column := range columns {
+			if _, ok := liveColumns[column]; !ok {
+				drift.MissingColumns = append(drift.MissingColumns, table+"."+column)
+			}
+		}
+		for column := range liveColumns {
+			if _, ok := columns[column]; !ok {
+				drift.ExtraColumns = append(drift.ExtraColumns, table+"."+column)
+			}
+		}
+	}
+	sort.Strings(drift.MissingTables)
+	sort.Strings(drift.MissingColumns)
+	sort.Strings(drift.ExtraColumns)
+	return drift
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExpectedSchema(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"000001_Init.up.sql": {Data: []byte(`
+			-- Comments; are ignored.
+			CREATE TABLE Widget (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				price NUMERIC(10, 2),
+				CONSTRAINT widget_name UNIQUE (name)
+			);
+			CREATE TABLE Old (id INT);
+		`)},
+		"000001_Init.down.sql": {Data: []byte(`DROP TABLE Widget;`)},
+		"000002_Rename.up.sql": {Data: []byte(`
+			ALTER TABLE Widget RENAME COLUMN name TO title;
+			ALTER TABLE Widget ADD COLUMN IF NOT EXISTS color TEXT, ADD CONSTRAINT widget_color CHECK (color <> '');
+			ALTER TABLE Old RENAME TO Gadget;
+		`)},
+		"000010_Drop.up.sql": {Data: []byte(`
+			DO $$
+			BEGIN
+				ALTER TABLE Widget DROP COLUMN title;
+			END $$;
+			ALTER TABLE Widget DROP COLUMN price;
+			CREATE TABLE IF NOT EXISTS Copy (LIKE Widget INCLUDING DEFAULTS, extra INT, PRIMARY KEY (id));
+			DROP TABLE IF EXISTS Gadget;
+		`)},
+	}
+
+	schema, err := ExpectedSchema(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Schema{
+		Version: 10,
+		Tables: map[string]map[string]struct{}{
+			"widget": {"id": {}, "title": {}, "color": {}},
+			"copy":   {"id": {}, "title": {}, "color": {}, "extra": {}},
+		},
+	}
+	if diff := cmp.Diff(want, schema); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestExpectedSchema_Migrations(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ExpectedSchema(os.DirFS(dbMigrationsDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if schema.Version == 0 {
+		t.Errorf("expected a migration version")
+	}
+	for _, want := range []string{"exposure.exposure_key", "exposure.tenant", "quarantinedexposure.cohort_id", "lease.holder"} {
+		table, column, _ := strings.Cut(want, ".")
+		if _, ok := schema.Tables[table][column]; !ok {
+			t.Errorf("expected column %s", want)
+		}
+	}
+	if _, ok := schema.Tables["infection"]; ok {
+		t.Errorf("expected infection to be renamed")
+	}
+}
+
+func TestCompareSchema(t *testing.T) {
+	t.Parallel()
+
+	expected := &Schema{
+		Version: 3,
+		Tables: map[string]map[string]struct{}{
+			"widget": {"id": {}, "name": {}},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		live   *Schema
+		failed bool
+		empty  bool
+	}{
+		{
+			name: "match",
+			live: &Schema{Version: 3, Tables: map[string]map[string]struct{}{
+				"widget":            {"id": {}, "name": {}},
+				"schema_migrations": {"version": {}, "dirty": {}},
+			}},
+			empty: true,
+		},
+		{
+			name:   "dirty",
+			live:   &Schema{Version: 3, Dirty: true, Tables: expected.Tables},
+			failed: true,
+		},
+		{
+			name:   "pending",
+			live:   &Schema{Version: 2, Tables: expected.Tables},
+			failed: true,
+		},
+		{
+			name: "missing_column",
+			live: &Schema{Version: 3, Tables: map[string]map[string]struct{}{
+				"widget": {"id": {}},
+			}},
+			failed: true,
+		},
+		{
+			name:   "missing_table",
+			live:   &Schema{Version: 3, Tables: map[string]map[string]struct{}{}},
+			failed: true,
+		},
+		{
+			name: "extra_column",
+			live: &Schema{Version: 3, Tables: map[string]map[string]struct{}{
+				"widget": {"id": {}, "name": {}, "color": {}},
+			}},
+		},
+		{
+			name: "newer",
+			live: &Schema{Version: 4, Tables: map[string]map[string]struct{}{
+				"widget": {"id": {}},
+			}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			drift := CompareSchema(expected, tc.live)
+			if got := drift.Failed(); got != tc.failed {
+				t.Errorf("expected failed to be %t, got %t:\n%s", tc.failed, got, drift)
+			}
+			if got := drift.Empty(); got != tc.empty {
+				t.Errorf("expected empty to be %t, got %t:\n%s", tc.empty, got, drift)
+			}
+		})
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	drift, err := testDB.CheckSchema(ctx, os.DirFS(dbMigrationsDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift.Empty() {
+		t.Errorf("expected no drift after migrating, got:\n%s", drift)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/observability"
+	pgx "github.com/jackc/pgx/v5"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+var (
+	_ pgx.QueryTracer = (*metricsTracer)(nil)
+	_ pgx.BatchTracer = (*metricsTracer)(nil)
+)
+
+// metricsTracer records the latency of every query and batch, tagged with the
+// kind of statement. The SQL itself is not used as a tag, since it would have
+// too many values.
+type metricsTracer struct{}
+
+type traceStartKey struct{}
+
+type traceStart struct {
+	start     time.Time
+	statement string
+}
+
+func (t *metricsTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceStartKey{}, &traceStart{
+		start:     time.Now(),
+		statement: statementKind(data.SQL),
+	})
+}
+
+func (t *metricsTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	recordTrace(ctx, data.Err)
+}
+
+func (t *metricsTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if data.Batch != nil {
+		stats.Record(ctx, mBatchSize.M(int64(data.Batch.Len())))
+	}
+	return context.WithValue(ctx, traceStartKey{}, &traceStart{
+		start:     time.Now(),
+		statement: "BATCH",
+	})
+}
+
+func (t *metricsTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (t *metricsTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	recordTrace(ctx, data.Err)
+}
+
+func recordTrace(ctx context.Context, err error) {
+	ts, ok := ctx.Value(traceStartKey{}).(*traceStart)
+	if !ok {
+		return
+	}
+
+	result := observability.ResultOK
+	if err != nil {
+		result = observability.ResultNotOK
+	}
+	ctx, _ = tag.New(ctx, tag.Upsert(statementTagKey, ts.statement))
+	observability.RecordLatency(ctx, ts.start, mQueryLatencyMs, &result)
+}
+
+// statementKind returns the upper case first keyword of sql, or OTHER if it is
+// not a common statement.
+func statementKind(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "OTHER"
+	}
+
+	kind := strings.ToUpper(fields[0])
+	switch kind {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "BEGIN", "COMMIT", "ROLLBACK":
+		return kind
+	}
+	return "OTHER"
+}
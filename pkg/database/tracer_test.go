@@ -0,0 +1,70 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+func TestStatementKind(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "", want: "OTHER"},
+		{sql: "SELECT 1", want: "SELECT"},
+		{sql: "\n\t\tinsert INTO Exposure", want: "INSERT"},
+		{sql: "begin isolation level read committed", want: "BEGIN"},
+		{sql: "WITH keys AS (SELECT 1) SELECT * FROM keys", want: "WITH"},
+		{sql: "VACUUM Exposure", want: "OTHER"},
+	}
+
+	for _, tc := range cases {
+		if got := statementKind(tc.sql); got != tc.want {
+			t.Errorf("statementKind(%q): expected %q, got %q", tc.sql, tc.want, got)
+		}
+	}
+}
+
+func TestMetricsTracer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tracer := &metricsTracer{}
+
+	// Ending a trace that was not started is a no-op.
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	if ts, ok := qctx.Value(traceStartKey{}).(*traceStart); !ok || ts.statement != "SELECT" {
+		t.Errorf("expected a SELECT trace, got %#v", qctx.Value(traceStartKey{}))
+	}
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("failed")})
+
+	var batch pgx.Batch
+	batch.Queue("SELECT 1")
+	batch.Queue("SELECT 2")
+	bctx := tracer.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: &batch})
+	if ts, ok := bctx.Value(traceStartKey{}).(*traceStart); !ok || ts.statement != "BATCH" {
+		t.Errorf("expected a BATCH trace, got %#v", bctx.Value(traceStartKey{}))
+	}
+	tracer.TraceBatchEnd(bctx, nil, pgx.TraceBatchEndData{})
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/publish"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/exposure-notifications-server/internal/revision"
 	revisiondatabase "github.com/google/exposure-notifications-server/internal/revision/database"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
@@ -189,7 +190,7 @@ func NewServer(tb testing.TB, testDatabaseInstance *database.TestInstance) *Serv
 	publishConfig.MaxSameStartIntervalKeys = 2
 	publishConfig.MaxIntervalAge = 360 * time.Hour
 	publishConfig.CreatedAtTruncateWindow = time.Second
-	publishConfig.ReleaseSameDayKeys = true
+	publishConfig.SameDayPolicy = publishmodel.SameDayKeyPolicyRelease
 	publishConfig.RevisionKeyCacheDuration = time.Second
 
 	publishServer, err := publish.NewServer(ctx, publishConfig, env)
@@ -41,3 +41,55 @@ func ParseECDSAPublicKey(pemBlock string) (*ecdsa.PublicKey, error) {
 		return nil, fmt.Errorf("unsupported public key type: %T", typ)
 	}
 }
+
+// ParseECDSAPublicKeys decodes every PEM block in the input as an ECDSA public
+// key. It returns an error if there are no blocks or any block is not an ECDSA
+// public key.
+func ParseECDSAPublicKeys(pemBlocks string) ([]*ecdsa.PublicKey, error) {
+	var keys []*ecdsa.PublicKey
+	rest := []byte(pemBlocks)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		key, err := ParseECDSAPublicKey(string(pem.EncodeToMemory(block)))
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", len(keys), err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("unable to decode PEM block containing PUBLIC KEY")
+	}
+	return keys, nil
+}
+
+// ParseECDSAPrivateKey is a convenience function for decoding an ECDSA private
+// key in PEM format. Both SEC 1 ("EC PRIVATE KEY") and PKCS #8 ("PRIVATE KEY")
+// encodings are supported.
+func ParseECDSAPrivateKey(pemBlock string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemBlock))
+	if block == nil {
+		return nil, errors.New("unable to decode PEM block containing PRIVATE KEY")
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("x509.ParsePKCS8PrivateKey: %w", err)
+	}
+
+	switch typ := priv.(type) {
+	case *ecdsa.PrivateKey:
+		return typ, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type: %T", typ)
+	}
+}
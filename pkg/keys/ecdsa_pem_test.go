@@ -15,6 +15,8 @@
 package keys
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -48,3 +50,98 @@ func TestParseECDSAPublicKey_WrongKeyType(t *testing.T) {
 	_, err = ParseECDSAPublicKey(pemPublicKey)
 	errcmp.MustMatch(t, err, "x509.ParsePKIXPublicKey")
 }
+
+func TestParseECDSAPublicKeys(t *testing.T) {
+	t.Parallel()
+
+	var pemBlocks []byte
+	want := make([]*ecdsa.PublicKey, 0, 3)
+	for i := 0; i < 3; i++ {
+		pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&pk.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pemBlocks = append(pemBlocks, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+		want = append(want, &pk.PublicKey)
+	}
+
+	got, err := ParseECDSAPublicKeys(string(pemBlocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d keys, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("key %d does not match", i)
+		}
+	}
+
+	_, err = ParseECDSAPublicKeys("foo")
+	errcmp.MustMatch(t, err, "unable to decode PEM block")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})
+	_, err = ParseECDSAPublicKeys(string(pemBlocks) + string(rsaPEM))
+	errcmp.MustMatch(t, err, "key 3")
+}
+
+func TestParseECDSAPrivateKey(t *testing.T) {
+	t.Parallel()
+
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec1, err := x509.MarshalECPrivateKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range []*pem.Block{
+		{Type: "EC PRIVATE KEY", Bytes: sec1},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		got, err := ParseECDSAPrivateKey(string(pem.EncodeToMemory(block)))
+		if err != nil {
+			t.Fatalf("%s: %v", block.Type, err)
+		}
+		if !got.Equal(pk) {
+			t.Errorf("%s: parsed key does not match", block.Type)
+		}
+	}
+}
+
+func TestParseECDSAPrivateKey_WrongKeyType(t *testing.T) {
+	t.Parallel()
+
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemEncoded := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+
+	_, err = ParseECDSAPrivateKey(string(pemEncoded))
+	errcmp.MustMatch(t, err, "unsupported private key type")
+
+	_, err = ParseECDSAPrivateKey("foo")
+	errcmp.MustMatch(t, err, "unable to decode PEM block")
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+func TestParseRSAPublicKey(t *testing.T) {
+	t.Parallel()
+
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkix, err := x509.MarshalPKIXPublicKey(&pk.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range []*pem.Block{
+		{Type: "PUBLIC KEY", Bytes: pkix},
+		{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&pk.PublicKey)},
+	} {
+		got, err := ParseRSAPublicKey(string(pem.EncodeToMemory(block)))
+		if err != nil {
+			t.Fatalf("%s: %v", block.Type, err)
+		}
+		if !got.Equal(&pk.PublicKey) {
+			t.Errorf("%s: expected keys to be equal", block.Type)
+		}
+	}
+}
+
+func TestParseRSAPublicKey_DecodeError(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseRSAPublicKey("foo")
+	errcmp.MustMatch(t, err, "unable to decode PEM block")
+}
+
+func TestParseRSAPublicKey_WrongKeyType(t *testing.T) {
+	t.Parallel()
+
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&pk.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseRSAPublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	errcmp.MustMatch(t, err, "unsupported public key type")
+}
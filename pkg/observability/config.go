@@ -31,6 +31,17 @@ const (
 type Config struct {
 	ExporterType ExporterType `env:"OBSERVABILITY_EXPORTER, default=STACKDRIVER"`
 
+	// TagPolicy caps, hashes or drops metric tags with many values, such as
+	// regions, to bound the number of time series. Rules are of the form
+	// "[view:]tag=action", where the action is "keep", "drop", "cap:n" or
+	// "hash:n". See TagPolicy.
+	TagPolicy []string `env:"METRICS_TAG_POLICY"`
+
+	// TraceRouteProbability is the trace sampling probability of the HTTP
+	// requests under each path, such as "/v1/publish:0.01,/do-work:1". Other
+	// requests are sampled at TRACE_PROBABILITY.
+	TraceRouteProbability map[string]float64 `env:"TRACE_ROUTE_PROBABILITY"`
+
 	OpenCensus  *OpenCensusConfig
 	Stackdriver *StackdriverConfig
 }
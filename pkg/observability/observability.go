@@ -147,7 +147,7 @@ func CollectViews(views ...*view.View) {
 func AllViews() []*view.View {
 	collectedViews.Lock()
 	defer collectedViews.Unlock()
-	return append(collectedViews.views, defaultViews()...)
+	return tagPolicy().apply(append(collectedViews.views, defaultViews()...))
 }
 
 // Exporter defines the minimum shared functionality for an observability exporter
@@ -160,6 +160,18 @@ type Exporter interface {
 // NewFromEnv returns the observability exporter given the provided configuration, or an error
 // if it failed to be created.
 func NewFromEnv(ctx context.Context, config *Config) (Exporter, error) {
+	policy, err := ParseTagPolicy(config.TagPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid METRICS_TAG_POLICY: %w", err)
+	}
+	SetTagPolicy(policy)
+
+	routeSampler, err := NewRouteSampler(config.TraceRouteProbability)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACE_ROUTE_PROBABILITY: %w", err)
+	}
+	SetRouteSampler(routeSampler)
+
 	switch config.ExporterType {
 	case ExporterNoop:
 		return NewNoop(ctx)
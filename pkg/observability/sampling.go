@@ -0,0 +1,100 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opencensus.io/trace"
+)
+
+// routeSampler is the trace sampler of the requests under a path.
+type routeSampler struct {
+	path    string
+	sampler trace.Sampler
+}
+
+// RouteSampler picks the trace sampler of an HTTP request by its path, so
+// that routes can be traced at different rates than the rest of a service.
+type RouteSampler struct {
+	routes []*routeSampler
+}
+
+// NewRouteSampler creates a RouteSampler from the sampling probability of
+// each path. A path matches requests to it and to the paths under it, and the
+// longest matching path is used.
+func NewRouteSampler(probabilities map[string]float64) (*RouteSampler, error) {
+	routes := make([]*routeSampler, 0, len(probabilities))
+	for path, p := range probabilities {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("route %q must start with /", path)
+		}
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("route %q: probability %v must be between 0 and 1", path, p)
+		}
+		routes = append(routes, &routeSampler{
+			path:    strings.TrimSuffix(path, "/"),
+			sampler: trace.ProbabilitySampler(p),
+		})
+	}
+
+	// Longest paths first, so the most specific route matches.
+	sort.Slice(routes, func(i, j int) bool {
+		return len(routes[i].path) > len(routes[j].path)
+	})
+	return &RouteSampler{routes: routes}, nil
+}
+
+// Sampler returns the sampler of the path, or nil if no route matches it.
+func (s *RouteSampler) Sampler(path string) trace.Sampler {
+	if s == nil {
+		return nil
+	}
+	for _, r := range s.routes {
+		if r.path == "" || path == r.path || strings.HasPrefix(path, r.path+"/") {
+			return r.sampler
+		}
+	}
+	return nil
+}
+
+var currentRouteSampler = struct {
+	sampler *RouteSampler
+	sync.RWMutex
+}{}
+
+// SetRouteSampler sets the route sampler of the process.
+func SetRouteSampler(s *RouteSampler) {
+	currentRouteSampler.Lock()
+	defer currentRouteSampler.Unlock()
+	currentRouteSampler.sampler = s
+}
+
+// TraceStartOptions returns the options of the server span of an HTTP
+// request. Requests that don't match a route of the route sampler use the
+// default sampler of the exporter.
+func TraceStartOptions(r *http.Request) trace.StartOptions {
+	currentRouteSampler.RLock()
+	defer currentRouteSampler.RUnlock()
+
+	return trace.StartOptions{
+		Sampler:  currentRouteSampler.sampler.Sampler(r.URL.Path),
+		SpanKind: trace.SpanKindServer,
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestRouteSampler(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewRouteSampler(map[string]float64{
+		"/":                     0.5,
+		"/v1/publish":           0,
+		"/export/":              1,
+		"/export/create-events": 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path    string
+		sampled bool
+	}{
+		{path: "/v1/publish", sampled: false},
+		{path: "/v1/publish/extra", sampled: false},
+		{path: "/export/do-work", sampled: true},
+		{path: "/export", sampled: true},
+		{path: "/export/create-events", sampled: false},
+		// Matched by "/", which doesn't sample this trace ID.
+		{path: "/exporter", sampled: false},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			sampler := s.Sampler(tc.path)
+			if sampler == nil {
+				t.Fatalf("expected a sampler for %q", tc.path)
+			}
+			// The highest trace ID is only sampled at a probability of 1.
+			decision := sampler(trace.SamplingParameters{TraceID: trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}})
+			if got := decision.Sample; got != tc.sampled {
+				t.Errorf("expected sampled %t, got %t", tc.sampled, got)
+			}
+		})
+	}
+
+	empty, err := NewRouteSampler(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := empty.Sampler("/v1/publish"); got != nil {
+		t.Errorf("expected no sampler without routes")
+	}
+}
+
+func TestNewRouteSampler_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		probabilities map[string]float64
+	}{
+		{name: "relative_path", probabilities: map[string]float64{"v1/publish": 0.1}},
+		{name: "negative", probabilities: map[string]float64{"/v1/publish": -0.1}},
+		{name: "above_one", probabilities: map[string]float64{"/v1/publish": 2}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewRouteSampler(tc.probabilities); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
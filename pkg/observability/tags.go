@@ -0,0 +1,227 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// TagValueOther is the value of a capped tag once it has seen its maximum
+// number of distinct values.
+const TagValueOther = "OTHER"
+
+// tagAction is what a tag policy rule does with a tag.
+type tagAction string
+
+const (
+	tagKeep tagAction = "keep"
+	tagDrop tagAction = "drop"
+	tagCap  tagAction = "cap"
+	tagHash tagAction = "hash"
+)
+
+// tagValueRule caps or hashes the values of a tag.
+type tagValueRule struct {
+	action tagAction
+	n      int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// value returns the value that is recorded for v.
+func (r *tagValueRule) value(v string) string {
+	switch r.action {
+	case tagHash:
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(v))
+		return "h" + strconv.FormatUint(uint64(h.Sum32()%uint32(r.n)), 10)
+	case tagCap:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := r.seen[v]; ok {
+			return v
+		}
+		if len(r.seen) >= r.n {
+			return TagValueOther
+		}
+		r.seen[v] = struct{}{}
+		return v
+	default:
+		return v
+	}
+}
+
+// TagPolicy controls the cardinality of metric tags. The values of a tag can
+// be capped, so that values after the first n distinct ones are recorded as
+// TagValueOther, or hashed into n buckets. A tag can also be dropped from all
+// views, or from some of them, in which case the views don't aggregate by it.
+//
+// A TagPolicy is built from rules of the form "[view:]tag=action", where the
+// action is one of "keep", "drop", "cap:n" or "hash:n". Rules without a view
+// apply to all views, and rules with a view override them for that view.
+// Capping and hashing happen when the tag is set, so they can't be overridden
+// per view.
+type TagPolicy struct {
+	values  map[string]*tagValueRule
+	dropped map[string]bool
+	views   map[string]map[string]bool
+}
+
+// ParseTagPolicy parses the rules of a tag policy.
+func ParseTagPolicy(rules []string) (*TagPolicy, error) {
+	p := &TagPolicy{
+		values:  make(map[string]*tagValueRule),
+		dropped: make(map[string]bool),
+		views:   make(map[string]map[string]bool),
+	}
+
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		lhs, rhs, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("tag rule %q: missing action", rule)
+		}
+		viewName, tagName, perView := strings.Cut(lhs, ":")
+		if !perView {
+			viewName, tagName = "", lhs
+		}
+		if tagName == "" || (perView && viewName == "") {
+			return nil, fmt.Errorf("tag rule %q: missing tag or view", rule)
+		}
+
+		name, arg, hasArg := strings.Cut(rhs, ":")
+		action := tagAction(name)
+		switch action {
+		case tagKeep, tagDrop:
+			if hasArg {
+				return nil, fmt.Errorf("tag rule %q: %s does not take a limit", rule, action)
+			}
+			if perView {
+				if p.views[viewName] == nil {
+					p.views[viewName] = make(map[string]bool)
+				}
+				p.views[viewName][tagName] = action == tagKeep
+				continue
+			}
+			p.dropped[tagName] = action == tagDrop
+		case tagCap, tagHash:
+			if perView {
+				return nil, fmt.Errorf("tag rule %q: %s applies to all views, per-view rules can only keep or drop", rule, action)
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("tag rule %q: %s needs a positive limit", rule, action)
+			}
+			p.values[tagName] = &tagValueRule{
+				action: action,
+				n:      n,
+				seen:   make(map[string]struct{}, n),
+			}
+		default:
+			return nil, fmt.Errorf("tag rule %q: unknown action %q", rule, name)
+		}
+	}
+	return p, nil
+}
+
+// Value returns the value that is recorded for the tag key, after it is
+// capped or hashed.
+func (p *TagPolicy) Value(key tag.Key, value string) string {
+	if p == nil {
+		return value
+	}
+	if r, ok := p.values[key.Name()]; ok {
+		return r.value(value)
+	}
+	return value
+}
+
+// ViewTagKeys returns the tag keys of the view that are not dropped.
+func (p *TagPolicy) ViewTagKeys(v *view.View) []tag.Key {
+	if p == nil {
+		return v.TagKeys
+	}
+	overrides := p.views[v.Name]
+
+	keys := make([]tag.Key, 0, len(v.TagKeys))
+	for _, k := range v.TagKeys {
+		keep, ok := overrides[k.Name()]
+		if !ok {
+			keep = !p.dropped[k.Name()]
+		}
+		if keep {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// apply returns the views with their dropped tag keys removed. Views are
+// copied rather than changed.
+func (p *TagPolicy) apply(views []*view.View) []*view.View {
+	if p == nil {
+		return views
+	}
+
+	ret := make([]*view.View, 0, len(views))
+	for _, v := range views {
+		keys := p.ViewTagKeys(v)
+		if len(keys) != len(v.TagKeys) {
+			c := *v
+			c.TagKeys = keys
+			v = &c
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+var currentTagPolicy = struct {
+	policy *TagPolicy
+	sync.RWMutex
+}{}
+
+// SetTagPolicy sets the tag policy of the process. It must be set before the
+// views are registered.
+func SetTagPolicy(p *TagPolicy) {
+	currentTagPolicy.Lock()
+	defer currentTagPolicy.Unlock()
+	currentTagPolicy.policy = p
+}
+
+func tagPolicy() *TagPolicy {
+	currentTagPolicy.RLock()
+	defer currentTagPolicy.RUnlock()
+	return currentTagPolicy.policy
+}
+
+// Upsert returns a mutator that sets the tag key to value, capped or hashed by
+// the tag policy of the process. It is used instead of tag.Upsert for tags
+// that can have many values, such as regions.
+func Upsert(key tag.Key, value string) tag.Mutator {
+	return tag.Upsert(key, tagPolicy().Value(key, value))
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observability
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func TestParseTagPolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		rules []string
+		err   bool
+	}{
+		{name: "empty"},
+		{name: "valid", rules: []string{"region=cap:10", " healthAuthorityID=hash:8", "region=drop", "view/a:region=keep", ""}},
+		{name: "missing_action", rules: []string{"region"}, err: true},
+		{name: "missing_tag", rules: []string{"=drop"}, err: true},
+		{name: "missing_view", rules: []string{":region=drop"}, err: true},
+		{name: "unknown_action", rules: []string{"region=truncate"}, err: true},
+		{name: "missing_limit", rules: []string{"region=cap"}, err: true},
+		{name: "zero_limit", rules: []string{"region=hash:0"}, err: true},
+		{name: "drop_limit", rules: []string{"region=drop:1"}, err: true},
+		{name: "per_view_cap", rules: []string{"view/a:region=cap:10"}, err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseTagPolicy(tc.rules)
+			if got := err != nil; got != tc.err {
+				t.Errorf("expected error: %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestTagPolicy_Value(t *testing.T) {
+	t.Parallel()
+
+	regionKey := tag.MustNewKey("region")
+	haKey := tag.MustNewKey("healthAuthorityID")
+	otherKey := tag.MustNewKey("other")
+
+	p, err := ParseTagPolicy([]string{"region=cap:2", "healthAuthorityID=hash:4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{
+		p.Value(regionKey, "US"),
+		p.Value(regionKey, "CA"),
+		p.Value(regionKey, "MX"),
+		p.Value(regionKey, "US"),
+		p.Value(otherKey, "anything"),
+	}
+	want := []string{"US", "CA", TagValueOther, "US", "anything"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	buckets := make(map[string]struct{})
+	for _, v := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		h := p.Value(haKey, v)
+		if h != p.Value(haKey, v) {
+			t.Errorf("expected hash of %q to be stable", v)
+		}
+		buckets[h] = struct{}{}
+	}
+	if len(buckets) > 4 {
+		t.Errorf("expected at most 4 hash buckets, got %d", len(buckets))
+	}
+
+	var nilPolicy *TagPolicy
+	if got, want := nilPolicy.Value(regionKey, "US"), "US"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestTagPolicy_ViewTagKeys(t *testing.T) {
+	t.Parallel()
+
+	regionKey := tag.MustNewKey("region")
+	resultKey := tag.MustNewKey("result")
+	m := stats.Int64("test/measure", "test", stats.UnitDimensionless)
+
+	p, err := ParseTagPolicy([]string{"region=drop", "view/kept:region=keep", "view/dropped:result=drop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	views := []*view.View{
+		{Name: "view/default", Measure: m, TagKeys: []tag.Key{regionKey, resultKey}},
+		{Name: "view/kept", Measure: m, TagKeys: []tag.Key{regionKey, resultKey}},
+		{Name: "view/dropped", Measure: m, TagKeys: []tag.Key{regionKey, resultKey}},
+	}
+	got := make(map[string][]string)
+	for _, v := range p.apply(views) {
+		for _, k := range v.TagKeys {
+			got[v.Name] = append(got[v.Name], k.Name())
+		}
+	}
+
+	want := map[string][]string{
+		"view/default": {"result"},
+		"view/kept":    {"region", "result"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The collected views are not changed.
+	if got, want := len(views[0].TagKeys), 2; got != want {
+		t.Errorf("expected %d tag keys on original view, got %d", want, got)
+	}
+}
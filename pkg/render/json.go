@@ -17,6 +17,7 @@ package render
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -64,9 +65,15 @@ func (r *Renderer) RenderJSON(w http.ResponseWriter, code int, data interface{})
 		data = &multiError{Errors: msgs}
 	}
 
-	// If the provided value was an error, marshall accordingly.
+	// If the provided value was an error, marshall accordingly. Errors which
+	// carry a stable code (see internal/errcode) include it in the response.
 	if typ, ok := data.(error); ok {
-		data = &singleError{Error: typ.Error()}
+		e := &singleError{Error: typ.Error()}
+		var coded codedError
+		if errors.As(typ, &coded) {
+			e.Code = coded.ErrorCode()
+		}
+		data = e
 	}
 
 	// Acquire a renderer
@@ -98,6 +105,13 @@ const jsonOKResp = `{"ok":true}`
 
 type singleError struct {
 	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// codedError is an error with a stable, machine-readable code.
+type codedError interface {
+	error
+	ErrorCode() string
 }
 
 type multiError struct {
@@ -0,0 +1,193 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof" //nolint:gosec // handlers are only mounted when explicitly enabled
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+const (
+	// DebugPathPrefix is the path under which the debug endpoints are served.
+	DebugPathPrefix = "/debug/"
+
+	// debugPortEnv is the port on which to serve the debug endpoints on their
+	// own server.
+	debugPortEnv = "DEBUG_PORT"
+
+	// debugTokenEnv is the bearer token required to access the debug
+	// endpoints. When set without DEBUG_PORT, the endpoints are served on the
+	// service's own port.
+	debugTokenEnv = "DEBUG_TOKEN"
+)
+
+// DebugHandler returns a handler for runtime debugging endpoints:
+//
+//   - /debug/pprof/ for CPU, heap, and other profiles
+//   - /debug/vars for exported variables and memory statistics
+//   - /debug/goroutines for a dump of all goroutine stacks
+//
+// If token is not empty, requests must carry it as a bearer token in the
+// Authorization header.
+func DebugHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/goroutines", handleGoroutines)
+
+	if token == "" {
+		return mux
+	}
+
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// handleGoroutines writes the stacks of all goroutines in the same format as
+// an unrecovered panic.
+func handleGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "goroutines: %d\n\n", runtime.NumGoroutine())
+	if err := runtimepprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		logging.FromContext(r.Context()).Errorw("failed to write goroutine dump", "error", err)
+	}
+}
+
+// withDebugIfToken serves the debug endpoints alongside handler when
+// DEBUG_TOKEN is set and DEBUG_PORT is not. Otherwise it returns handler.
+func withDebugIfToken(handler http.Handler) http.Handler {
+	token := os.Getenv(debugTokenEnv)
+	if token == "" || os.Getenv(debugPortEnv) != "" {
+		return handler
+	}
+
+	debug := DebugHandler(token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, DebugPathPrefix) {
+			debug.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// DebugDoneFunc releases the debug server returned by ServeDebugIfEnabled.
+// The server is shut down when the last of the servers sharing it is done.
+// Calling it more than once has no effect.
+type DebugDoneFunc func() error
+
+// debugServer is the debug server of the process. The monolith runs every
+// service in one process with one DEBUG_PORT, so the services share it. It is
+// shut down when the last service is done with it.
+var debugServer struct {
+	sync.Mutex
+	srv  *http.Server
+	refs int
+}
+
+// ServeDebugIfEnabled serves the debug endpoints on their own server when
+// DEBUG_PORT is set. Requests must carry DEBUG_TOKEN, if it is set. Servers in
+// the same process share one debug server.
+func ServeDebugIfEnabled(ctx context.Context) (DebugDoneFunc, error) {
+	logger := logging.FromContext(ctx)
+
+	debugPort := os.Getenv(debugPortEnv)
+	if debugPort == "" {
+		return nil, nil
+	}
+
+	debugServer.Lock()
+	defer debugServer.Unlock()
+
+	if debugServer.srv == nil {
+		listener, err := net.Listen("tcp", ":"+debugPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on debug port %s: %w", debugPort, err)
+		}
+
+		srv := &http.Server{
+			ReadHeaderTimeout: 10 * time.Second,
+			Handler:           DebugHandler(os.Getenv(debugTokenEnv)),
+		}
+
+		// Start the server in the background.
+		go func() {
+			if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorw("failed to serve debug endpoints", "error", err)
+				return
+			}
+		}()
+		logger.Debugw("debug endpoints are running", "port", debugPort)
+
+		debugServer.srv = srv
+	}
+	debugServer.refs++
+
+	// Create the shutdown closer. Only the last one shuts down the server.
+	var once sync.Once
+	debugDone := func() error {
+		var srv *http.Server
+		once.Do(func() {
+			debugServer.Lock()
+			defer debugServer.Unlock()
+
+			debugServer.refs--
+			if debugServer.refs == 0 {
+				srv = debugServer.srv
+				debugServer.srv = nil
+			}
+		})
+		if srv == nil {
+			return nil
+		}
+
+		logger.Debugw("shutting down debug endpoints")
+
+		shutdownCtx, done := context.WithTimeout(context.Background(), 10*time.Second)
+		defer done()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shutdown debug endpoints: %w", err)
+		}
+		return nil
+	}
+
+	return debugDone, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+)
+
+func TestDebugHandler(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		token         string
+		authorization string
+		path          string
+		wantCode      int
+		wantBody      string
+	}{
+		{
+			name:     "vars",
+			path:     "/debug/vars",
+			wantCode: http.StatusOK,
+			wantBody: "memstats",
+		},
+		{
+			name:     "goroutines",
+			path:     "/debug/goroutines",
+			wantCode: http.StatusOK,
+			wantBody: "goroutine ",
+		},
+		{
+			name:     "pprof_index",
+			path:     "/debug/pprof/",
+			wantCode: http.StatusOK,
+			wantBody: "heap",
+		},
+		{
+			name:     "missing_token",
+			token:    "s3cr3t",
+			path:     "/debug/vars",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "wrong_token",
+			token:         "s3cr3t",
+			authorization: "Bearer nope",
+			path:          "/debug/vars",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "token",
+			token:         "s3cr3t",
+			authorization: "Bearer s3cr3t",
+			path:          "/debug/vars",
+			wantCode:      http.StatusOK,
+			wantBody:      "memstats",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			DebugHandler(tc.token).ServeHTTP(w, r)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected code %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("expected body to contain %q, got %s", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeDebugIfEnabled_Shared(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	if err := listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(debugPortEnv, port)
+
+	ctx := project.TestContext(t)
+	url := "http://127.0.0.1:" + port + "/debug/vars"
+
+	get := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected code %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		return nil
+	}
+
+	// Every service in the monolith serves the debug endpoints on the same
+	// port.
+	done1, err := ServeDebugIfEnabled(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := ServeDebugIfEnabled(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The debug server stays up until the last service is done.
+	if err := done1(); err != nil {
+		t.Fatal(err)
+	}
+	if err := done1(); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(); err != nil {
+		t.Fatalf("expected debug server to still serve: %v", err)
+	}
+
+	if err := done2(); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(); err == nil {
+		t.Errorf("expected debug server to be shut down")
+	}
+}
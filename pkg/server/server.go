@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/observability"
 	"github.com/hashicorp/go-multierror"
 
 	"go.opencensus.io/plugin/ochttp"
@@ -107,6 +108,12 @@ func (s *Server) ServeHTTP(ctx context.Context, srv *http.Server) error {
 		return fmt.Errorf("failed to serve metrics: %w", err)
 	}
 
+	// Serve the debug endpoints on their own port, if enabled.
+	debugDone, err := ServeDebugIfEnabled(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to serve debug endpoints: %w", err)
+	}
+
 	// Run the server. This will block until the provided context is closed.
 	if err := srv.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to serve: %w", err)
@@ -123,6 +130,13 @@ func (s *Server) ServeHTTP(ctx context.Context, srv *http.Server) error {
 		}
 	}
 
+	// Shutdown the debug endpoints.
+	if debugDone != nil {
+		if err := debugDone(); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("failed to close debug endpoints: %w", err))
+		}
+	}
+
 	// Return any errors that happened during shutdown.
 	if err := <-errCh; err != nil {
 		merr = multierror.Append(merr, fmt.Errorf("failed to shutdown server: %w", err))
@@ -132,14 +146,16 @@ func (s *Server) ServeHTTP(ctx context.Context, srv *http.Server) error {
 
 // ServeHTTPHandler is a convenience wrapper around ServeHTTP. It creates an
 // HTTP server using the provided handler, wrapped in OpenCensus for
-// observability.
+// observability. If DEBUG_TOKEN is set without DEBUG_PORT, the debug endpoints
+// are served under /debug/ as well.
 func (s *Server) ServeHTTPHandler(ctx context.Context, handler http.Handler) error {
 	return s.ServeHTTP(ctx, &http.Server{
 		ReadHeaderTimeout: 10 * time.Second,
 		Handler: &ochttp.Handler{
-			Handler:          handler,
+			Handler:          withDebugIfToken(handler),
 			IsPublicEndpoint: true,
 			Propagation:      &tracecontext.HTTPFormat{},
+			GetStartOptions:  observability.TraceStartOptions,
 		},
 	})
 }
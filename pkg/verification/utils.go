@@ -42,19 +42,63 @@ func CalculateAllAllowedExposureKeyHMAC(keys []verifyapi.ExposureKey, secret []b
 		return nil, fmt.Errorf("cannot calculate hmac on empty exposure keys")
 	}
 	// Sort by the key
+	sortKeys(keys)
+	return calculateHMACs(keys, secret, false)
+}
+
+// HMACOptions change how the exposure keys are canonicalized before the HMAC
+// is calculated. The zero value is the canonicalization of the verification
+// protocol. The other canonicalizations are only meant for interoperating with
+// clients that do not follow the protocol.
+type HMACOptions struct {
+	// UploadOrder keeps the keys in the order they were given instead of
+	// sorting them by key.
+	UploadOrder bool
+
+	// Unpadded strips the base64 padding from the key data.
+	Unpadded bool
+}
+
+// CalculateAllAllowedExposureKeyHMACWithOptions is like
+// CalculateAllAllowedExposureKeyHMAC, but canonicalizes the keys as described
+// by the options. Unlike CalculateAllAllowedExposureKeyHMAC, it does not sort
+// the given keys in place.
+func CalculateAllAllowedExposureKeyHMACWithOptions(keys []verifyapi.ExposureKey, secret []byte, opts HMACOptions) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("cannot calculate hmac on empty exposure keys")
+	}
+
+	if !opts.UploadOrder {
+		sorted := make([]verifyapi.ExposureKey, len(keys))
+		copy(sorted, keys)
+		sortKeys(sorted)
+		keys = sorted
+	}
+	return calculateHMACs(keys, secret, opts.Unpadded)
+}
+
+func sortKeys(keys []verifyapi.ExposureKey) {
 	sort.Slice(keys, func(i int, j int) bool {
 		return strings.Compare(keys[i].Key, keys[j].Key) <= 0
 	})
+}
 
+// calculateHMACs calculates the main and optional HMACs of the keys, in the
+// order given.
+func calculateHMACs(keys []verifyapi.ExposureKey, secret []byte, unpadded bool) ([][]byte, error) {
 	// Build the cleartext.
 	perKeyText := make([]string, 0, len(keys))
 	altPerKeyText := make([]string, 0, len(keys))
 	calculateAlt := true
 	for _, ek := range keys {
+		key := ek.Key
+		if unpadded {
+			key = strings.TrimRight(key, "=")
+		}
 		perKeyText = append(perKeyText,
-			fmt.Sprintf("%s.%d.%d.%d", ek.Key, ek.IntervalNumber, ek.IntervalCount, ek.TransmissionRisk))
+			fmt.Sprintf("%s.%d.%d.%d", key, ek.IntervalNumber, ek.IntervalCount, ek.TransmissionRisk))
 		altPerKeyText = append(altPerKeyText,
-			fmt.Sprintf("%s.%d.%d", ek.Key, ek.IntervalNumber, ek.IntervalCount))
+			fmt.Sprintf("%s.%d.%d", key, ek.IntervalNumber, ek.IntervalCount))
 		// The alt HMAC is only valid of all transmission risk are "omitted" (set to zero).
 		calculateAlt = calculateAlt && ek.TransmissionRisk == 0
 	}
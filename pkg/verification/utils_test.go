@@ -15,6 +15,8 @@
 package verification
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
 	"testing"
 
@@ -97,3 +99,79 @@ func TestCalculateDualHMACs(t *testing.T) {
 		t.Errorf("claims mismatch (-want, +got):\n%s", diff)
 	}
 }
+
+func TestCalculateHMACWithOptions(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	// Keys are not in sorted order.
+	keys := func() []verifyapi.ExposureKey {
+		return []verifyapi.ExposureKey{
+			{
+				Key:              "z2Cx9hdz2SlxZ8GEgqTYpA==",
+				IntervalNumber:   1,
+				IntervalCount:    144,
+				TransmissionRisk: 3,
+			},
+			{
+				Key:              "dPCphLzfG4uzXneNimkPRQ==",
+				IntervalNumber:   144,
+				IntervalCount:    144,
+				TransmissionRisk: 5,
+			},
+		}
+	}
+
+	sum := func(cleartext string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(cleartext))
+		return mac.Sum(nil)
+	}
+
+	cases := []struct {
+		name string
+		opts HMACOptions
+		want []byte
+	}{
+		{
+			name: "default",
+			want: sum("dPCphLzfG4uzXneNimkPRQ==.144.144.5,z2Cx9hdz2SlxZ8GEgqTYpA==.1.144.3"),
+		},
+		{
+			name: "upload_order",
+			opts: HMACOptions{UploadOrder: true},
+			want: sum("z2Cx9hdz2SlxZ8GEgqTYpA==.1.144.3,dPCphLzfG4uzXneNimkPRQ==.144.144.5"),
+		},
+		{
+			name: "unpadded",
+			opts: HMACOptions{Unpadded: true},
+			want: sum("dPCphLzfG4uzXneNimkPRQ.144.144.5,z2Cx9hdz2SlxZ8GEgqTYpA.1.144.3"),
+		},
+		{
+			name: "upload_order_unpadded",
+			opts: HMACOptions{UploadOrder: true, Unpadded: true},
+			want: sum("z2Cx9hdz2SlxZ8GEgqTYpA.1.144.3,dPCphLzfG4uzXneNimkPRQ.144.144.5"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := keys()
+			got, err := CalculateAllAllowedExposureKeyHMACWithOptions(input, secret, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([][]byte{tc.want}, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(keys(), input); diff != "" {
+				t.Errorf("keys were modified (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
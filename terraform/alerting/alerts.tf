@@ -22,6 +22,9 @@ locals {
 
   forward_progress_indicators = merge(
     {
+      # abuse-detector runs every 15m, alert after 4 failures
+      "abuse-detector" = { metric = "abuse-detector/success", window = 60 * local.minute + 5 * local.minute },
+
       # backup runs every 4h, alert after 2 failures
       "backup" = { metric = "backup/success", window = 8 * local.hour + 10 * local.minute },
 
@@ -38,6 +41,12 @@ locals {
       # after ~2 failures
       "export-worker" = { metric = "export/worker/success", window = 10 * local.minute + 2 * local.minute },
 
+      # export-relay-outbox runs every 1m, alert after ~10 failures
+      "export-relay-outbox" = { metric = "outbox/success", window = 10 * local.minute + 2 * local.minute },
+
+      # export-verifier runs every 15m, alert after 4 failures
+      "export-verifier" = { metric = "export-verifier/success", window = 60 * local.minute + 5 * local.minute },
+
       # export-importer-schedule runs every 15m, alert after 2 failures
       "export-importer-schedule" = { metric = "export-importer/schedule/success", window = 30 * local.minute + 5 * local.minute },
 
@@ -52,6 +61,9 @@ locals {
 
       # mirror runs every 5m but has a default lock time of 15m, alert after 2 failures
       "mirror" = { metric = "mirror/success", window = 30 * local.minute + 5 * local.minute },
+
+      # publish-consumer runs every 5m, alert after 3 failures
+      "publish-consumer" = { metric = "publish-consumer/success", window = 15 * local.minute + 3 * local.minute },
     },
     var.forward_progress_indicators,
   )
@@ -119,6 +131,46 @@ resource "google_monitoring_alert_policy" "ForwardProgress" {
   ]
 }
 
+resource "google_monitoring_alert_policy" "ExportVerificationFailed" {
+  project      = var.project
+  display_name = "ExportVerificationFailed"
+  combiner     = "OR"
+
+  conditions {
+    display_name = "Published exports failed verification"
+
+    condition_threshold {
+      filter   = "metric.type = \"${local.custom_prefix}/export-verifier/findings\" AND resource.type = \"generic_task\""
+      duration = "0s"
+
+      comparison      = "COMPARISON_GT"
+      threshold_value = 0
+
+      aggregations {
+        alignment_period     = "900s"
+        per_series_aligner   = "ALIGN_DELTA"
+        group_by_fields      = ["metric.labels.check"]
+        cross_series_reducer = "REDUCE_SUM"
+      }
+
+      trigger {
+        count = 1
+      }
+    }
+  }
+
+  documentation {
+    content   = "${local.playbook_prefix}/ExportVerificationFailed.md"
+    mime_type = "text/markdown"
+  }
+
+  notification_channels = [for x in values(google_monitoring_notification_channel.paging) : x.id]
+
+  depends_on = [
+    null_resource.manual-step-to-enable-workspace,
+  ]
+}
+
 resource "google_monitoring_alert_policy" "probers" {
   project = var.project
 
@@ -95,6 +95,11 @@ resource "google_cloud_run_service" "cleanup-export" {
           for_each = merge(
             local.common_cloudrun_env_vars,
 
+            {
+              // Matches the attempt deadline of the scheduler jobs.
+              SCHEDULER_ATTEMPT_DEADLINE = "600s",
+            },
+
             // This MUST come last to allow overrides!
             lookup(var.service_environment, "_all", {}),
             lookup(var.service_environment, "cleanup_export", {}),
@@ -190,3 +195,30 @@ resource "google_cloud_scheduler_job" "cleanup-export-worker" {
     google_project_service.services["cloudscheduler.googleapis.com"],
   ]
 }
+
+resource "google_cloud_scheduler_job" "cleanup-export-audit" {
+  name             = "cleanup-export-audit"
+  region           = var.cloudscheduler_location
+  schedule         = var.cleanup_export_audit_cron_schedule
+  time_zone        = var.cloud_scheduler_timezone
+  attempt_deadline = "600s"
+
+  retry_config {
+    retry_count = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloud_run_service.cleanup-export.status.0.url}/audit"
+    oidc_token {
+      audience              = google_cloud_run_service.cleanup-export.status.0.url
+      service_account_email = google_service_account.cleanup-export-invoker.email
+    }
+  }
+
+  depends_on = [
+    google_app_engine_application.app,
+    google_cloud_run_service_iam_member.cleanup-export-invoker,
+    google_project_service.services["cloudscheduler.googleapis.com"],
+  ]
+}
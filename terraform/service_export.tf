@@ -101,6 +101,11 @@ resource "google_cloud_run_service" "export" {
           for_each = merge(
             local.common_cloudrun_env_vars,
 
+            {
+              // Matches the attempt deadline of the scheduler jobs.
+              SCHEDULER_ATTEMPT_DEADLINE = "600s",
+            },
+
             // This MUST come last to allow overrides!
             lookup(var.service_environment, "_all", {}),
             lookup(var.service_environment, "export", {}),
@@ -227,3 +232,30 @@ resource "google_cloud_scheduler_job" "export-create-batches" {
     google_project_service.services["cloudscheduler.googleapis.com"],
   ]
 }
+
+resource "google_cloud_scheduler_job" "export-relay-outbox" {
+  name             = "export-relay-outbox"
+  region           = var.cloudscheduler_location
+  schedule         = var.export_relay_outbox_cron_schedule
+  time_zone        = var.cloud_scheduler_timezone
+  attempt_deadline = "600s"
+
+  retry_config {
+    retry_count = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloud_run_service.export.status.0.url}/relay-outbox"
+    oidc_token {
+      audience              = google_cloud_run_service.export.status.0.url
+      service_account_email = google_service_account.export-invoker.email
+    }
+  }
+
+  depends_on = [
+    google_app_engine_application.app,
+    google_cloud_run_service_iam_member.export-invoker,
+    google_project_service.services["cloudscheduler.googleapis.com"],
+  ]
+}
@@ -89,6 +89,11 @@ resource "google_cloud_run_service" "export-importer" {
           for_each = merge(
             local.common_cloudrun_env_vars,
 
+            {
+              // Matches the attempt deadline of the scheduler jobs.
+              SCHEDULER_ATTEMPT_DEADLINE = "600s",
+            },
+
             // This MUST come last to allow overrides!
             lookup(var.service_environment, "_all", {}),
             lookup(var.service_environment, "export-importer", {}),
@@ -0,0 +1,196 @@
+# Copyright 2026 the Exposure Notifications Server authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+#
+# Create and deploy the service
+#
+
+resource "google_service_account" "export-verifier" {
+  project      = data.google_project.project.project_id
+  account_id   = "en-export-verifier-sa"
+  display_name = "Exposure Notification Export Verifier"
+}
+
+resource "google_service_account_iam_member" "cloudbuild-deploy-export-verifier" {
+  service_account_id = google_service_account.export-verifier.id
+  role               = "roles/iam.serviceAccountUser"
+  member             = "serviceAccount:${data.google_project.project.number}@cloudbuild.gserviceaccount.com"
+
+  depends_on = [
+    google_project_service.services["cloudbuild.googleapis.com"],
+  ]
+}
+
+resource "google_secret_manager_secret_iam_member" "export-verifier-db" {
+  for_each = toset([
+    "sslcert",
+    "sslkey",
+    "sslrootcert",
+    "password",
+  ])
+
+  secret_id = google_secret_manager_secret.db-secret[each.key].id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.export-verifier.email}"
+}
+
+resource "google_kms_key_ring_iam_member" "export-verifier-publickeyviewer" {
+  key_ring_id = google_kms_key_ring.export-signing.id
+  role        = "roles/cloudkms.publicKeyViewer"
+  member      = "serviceAccount:${google_service_account.export-verifier.email}"
+}
+
+resource "google_project_iam_member" "export-verifier-observability" {
+  for_each = toset([
+    "roles/cloudtrace.agent",
+    "roles/logging.logWriter",
+    "roles/monitoring.metricWriter",
+    "roles/stackdriver.resourceMetadata.writer",
+  ])
+
+  project = var.project
+  role    = each.key
+  member  = "serviceAccount:${google_service_account.export-verifier.email}"
+}
+
+resource "google_cloud_run_service" "export-verifier" {
+  name     = "export-verifier"
+  location = var.cloudrun_location
+
+  autogenerate_revision_name = true
+
+  metadata {
+    annotations = merge(
+      local.default_service_annotations,
+      var.default_service_annotations_overrides,
+      lookup(var.service_annotations, "export_verifier", {}),
+    )
+  }
+  template {
+    spec {
+      service_account_name = google_service_account.export-verifier.email
+
+      containers {
+        image = "gcr.io/${data.google_project.project.project_id}/github.com/google/exposure-notifications-server/export-verifier:initial"
+
+        resources {
+          limits = {
+            cpu    = "1000m"
+            memory = "512Mi"
+          }
+        }
+
+        dynamic "env" {
+          for_each = merge(
+            local.common_cloudrun_env_vars,
+
+            // This MUST come last to allow overrides!
+            lookup(var.service_environment, "_all", {}),
+            lookup(var.service_environment, "export_verifier", {}),
+          )
+
+          content {
+            name  = env.key
+            value = env.value
+          }
+        }
+      }
+    }
+
+    metadata {
+      annotations = merge(
+        local.default_revision_annotations,
+        var.default_revision_annotations_overrides,
+        lookup(var.revision_annotations, "export_verifier", {}),
+      )
+    }
+  }
+
+  depends_on = [
+    google_project_service.services["run.googleapis.com"],
+    google_kms_key_ring_iam_member.export-verifier-publickeyviewer,
+    google_secret_manager_secret_iam_member.export-verifier-db,
+    null_resource.build,
+    null_resource.migrate,
+  ]
+
+  lifecycle {
+    ignore_changes = [
+      metadata[0].annotations["client.knative.dev/user-image"],
+      metadata[0].annotations["run.googleapis.com/client-name"],
+      metadata[0].annotations["run.googleapis.com/client-version"],
+      metadata[0].annotations["run.googleapis.com/ingress-status"],
+      metadata[0].annotations["run.googleapis.com/launch-stage"],
+      metadata[0].annotations["serving.knative.dev/creator"],
+      metadata[0].annotations["serving.knative.dev/lastModifier"],
+      metadata[0].labels["cloud.googleapis.com/location"],
+      template[0].metadata[0].annotations["client.knative.dev/user-image"],
+      template[0].metadata[0].annotations["run.googleapis.com/client-name"],
+      template[0].metadata[0].annotations["run.googleapis.com/client-version"],
+      template[0].metadata[0].annotations["run.googleapis.com/sandbox"],
+      template[0].metadata[0].annotations["serving.knative.dev/creator"],
+      template[0].metadata[0].annotations["serving.knative.dev/lastModifier"],
+      template[0].spec[0].containers[0].image,
+    ]
+  }
+}
+
+
+#
+# Create scheduler job to invoke the service on a fixed interval.
+#
+
+resource "google_service_account" "export-verifier-invoker" {
+  project      = data.google_project.project.project_id
+  account_id   = "en-export-verifier-invoker-sa"
+  display_name = "Exposure Notification Export Verifier Invoker"
+}
+
+resource "google_cloud_run_service_iam_member" "export-verifier-invoker" {
+  project  = google_cloud_run_service.export-verifier.project
+  location = google_cloud_run_service.export-verifier.location
+  service  = google_cloud_run_service.export-verifier.name
+  role     = "roles/run.invoker"
+  member   = "serviceAccount:${google_service_account.export-verifier-invoker.email}"
+}
+
+# Schedule to run every 15 minutes. Each run downloads the index and the
+# latest batch of each export config.
+
+resource "google_cloud_scheduler_job" "export-verifier-worker" {
+  name             = "export-verifier-worker"
+  region           = var.cloudscheduler_location
+  schedule         = "*/15 * * * *"
+  time_zone        = var.cloud_scheduler_timezone
+  attempt_deadline = "600s"
+
+  retry_config {
+    retry_count = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloud_run_service.export-verifier.status.0.url}/verify-exports"
+    oidc_token {
+      audience              = google_cloud_run_service.export-verifier.status.0.url
+      service_account_email = google_service_account.export-verifier-invoker.email
+    }
+  }
+
+  depends_on = [
+    google_app_engine_application.app,
+    google_cloud_run_service_iam_member.export-verifier-invoker,
+    google_project_service.services["cloudscheduler.googleapis.com"],
+  ]
+}
@@ -105,6 +105,10 @@ resource "google_cloud_run_service" "exposure" {
               "REVISION_TOKEN_KEY_ID" = google_kms_crypto_key.token-key.id
               "REVISION_TOKEN_AAD"    = "secret://${google_secret_manager_secret_version.revision_token_aad_secret_version.id}"
             },
+            var.enable_publish_queue ? {
+              "QUEUE"              = "GOOGLE_PUBSUB"
+              "QUEUE_PUBSUB_TOPIC" = google_pubsub_topic.publish-queue.id
+            } : {},
 
             // This MUST come last to allow overrides!
             lookup(var.service_environment, "_all", {}),
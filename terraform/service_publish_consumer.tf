@@ -0,0 +1,234 @@
+# Copyright 2020 the Exposure Notifications Server authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+#
+# Create and deploy the service
+#
+
+resource "google_service_account" "publish-consumer" {
+  project      = data.google_project.project.project_id
+  account_id   = "en-publish-consumer-sa"
+  display_name = "Exposure Notification Publish Consumer"
+}
+
+resource "google_service_account_iam_member" "cloudbuild-deploy-publish-consumer" {
+  service_account_id = google_service_account.publish-consumer.id
+  role               = "roles/iam.serviceAccountUser"
+  member             = "serviceAccount:${data.google_project.project.number}@cloudbuild.gserviceaccount.com"
+
+  depends_on = [
+    google_project_service.services["cloudbuild.googleapis.com"],
+  ]
+}
+
+resource "google_secret_manager_secret_iam_member" "publish-consumer-db" {
+  for_each = toset([
+    "sslcert",
+    "sslkey",
+    "sslrootcert",
+    "password",
+  ])
+
+  secret_id = google_secret_manager_secret.db-secret[each.key].id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.publish-consumer.email}"
+}
+
+resource "google_project_iam_member" "publish-consumer-observability" {
+  for_each = toset([
+    "roles/cloudtrace.agent",
+    "roles/logging.logWriter",
+    "roles/monitoring.metricWriter",
+    "roles/stackdriver.resourceMetadata.writer",
+  ])
+
+  project = var.project
+  role    = each.key
+  member  = "serviceAccount:${google_service_account.publish-consumer.email}"
+}
+
+resource "google_cloud_run_service" "publish-consumer" {
+  name     = "publish-consumer"
+  location = var.cloudrun_location
+
+  autogenerate_revision_name = true
+
+  metadata {
+    annotations = merge(
+      local.default_service_annotations,
+      var.default_service_annotations_overrides,
+      lookup(var.service_annotations, "publish_consumer", {}),
+    )
+  }
+  template {
+    spec {
+      service_account_name = google_service_account.publish-consumer.email
+
+      containers {
+        image = "gcr.io/${data.google_project.project.project_id}/github.com/google/exposure-notifications-server/publish-consumer:initial"
+
+        resources {
+          limits = {
+            cpu    = "1000m"
+            memory = "512Mi"
+          }
+        }
+
+        dynamic "env" {
+          for_each = merge(
+            local.common_cloudrun_env_vars,
+            {
+              "QUEUE"                     = "GOOGLE_PUBSUB"
+              "QUEUE_PUBSUB_SUBSCRIPTION" = google_pubsub_subscription.publish-consumer.id
+            },
+
+            // This MUST come last to allow overrides!
+            lookup(var.service_environment, "_all", {}),
+            lookup(var.service_environment, "publish_consumer", {}),
+          )
+
+          content {
+            name  = env.key
+            value = env.value
+          }
+        }
+      }
+    }
+
+    metadata {
+      annotations = merge(
+        local.default_revision_annotations,
+        var.default_revision_annotations_overrides,
+        lookup(var.revision_annotations, "publish_consumer", {}),
+      )
+    }
+  }
+
+  depends_on = [
+    google_project_service.services["run.googleapis.com"],
+    google_secret_manager_secret_iam_member.publish-consumer-db,
+    google_pubsub_subscription_iam_member.publish-consumer,
+    null_resource.build,
+    null_resource.migrate,
+  ]
+
+  lifecycle {
+    ignore_changes = [
+      metadata[0].annotations["client.knative.dev/user-image"],
+      metadata[0].annotations["run.googleapis.com/client-name"],
+      metadata[0].annotations["run.googleapis.com/client-version"],
+      metadata[0].annotations["run.googleapis.com/ingress-status"],
+      metadata[0].annotations["run.googleapis.com/launch-stage"],
+      metadata[0].annotations["serving.knative.dev/creator"],
+      metadata[0].annotations["serving.knative.dev/lastModifier"],
+      metadata[0].labels["cloud.googleapis.com/location"],
+      template[0].metadata[0].annotations["client.knative.dev/user-image"],
+      template[0].metadata[0].annotations["run.googleapis.com/client-name"],
+      template[0].metadata[0].annotations["run.googleapis.com/client-version"],
+      template[0].metadata[0].annotations["run.googleapis.com/sandbox"],
+      template[0].metadata[0].annotations["serving.knative.dev/creator"],
+      template[0].metadata[0].annotations["serving.knative.dev/lastModifier"],
+      template[0].spec[0].containers[0].image,
+    ]
+  }
+}
+
+
+#
+# Create the queue between publish and the consumer.
+#
+
+resource "google_pubsub_topic" "publish-queue" {
+  project = data.google_project.project.project_id
+  name    = "publish-queue"
+
+  depends_on = [
+    google_project_service.services["pubsub.googleapis.com"],
+  ]
+}
+
+resource "google_pubsub_subscription" "publish-consumer" {
+  project = data.google_project.project.project_id
+  name    = "publish-consumer"
+  topic   = google_pubsub_topic.publish-queue.id
+
+  ack_deadline_seconds       = 600
+  message_retention_duration = "604800s"
+
+  expiration_policy {
+    ttl = ""
+  }
+}
+
+resource "google_pubsub_topic_iam_member" "exposure-publish-queue" {
+  project = google_pubsub_topic.publish-queue.project
+  topic   = google_pubsub_topic.publish-queue.name
+  role    = "roles/pubsub.publisher"
+  member  = "serviceAccount:${google_service_account.exposure.email}"
+}
+
+resource "google_pubsub_subscription_iam_member" "publish-consumer" {
+  project      = google_pubsub_subscription.publish-consumer.project
+  subscription = google_pubsub_subscription.publish-consumer.name
+  role         = "roles/pubsub.subscriber"
+  member       = "serviceAccount:${google_service_account.publish-consumer.email}"
+}
+
+#
+# Create scheduler job to invoke the service on a fixed interval.
+#
+
+resource "google_service_account" "publish-consumer-invoker" {
+  project      = data.google_project.project.project_id
+  account_id   = "en-publish-consumer-invoker-sa"
+  display_name = "Exposure Notification Publish Consumer Invoker"
+}
+
+resource "google_cloud_run_service_iam_member" "publish-consumer-invoker" {
+  project  = google_cloud_run_service.publish-consumer.project
+  location = google_cloud_run_service.publish-consumer.location
+  service  = google_cloud_run_service.publish-consumer.name
+  role     = "roles/run.invoker"
+  member   = "serviceAccount:${google_service_account.publish-consumer-invoker.email}"
+}
+
+# Schedule to run every 5 minutes. Each run drains the subscription for up to
+# MAX_RUNTIME, so uploads are written within minutes.
+
+resource "google_cloud_scheduler_job" "publish-consumer-worker" {
+  name             = "publish-consumer-worker"
+  region           = var.cloudscheduler_location
+  schedule         = "*/5 * * * *"
+  time_zone        = var.cloud_scheduler_timezone
+  attempt_deadline = "600s"
+
+  retry_config {
+    retry_count = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloud_run_service.publish-consumer.status.0.url}/consume"
+    oidc_token {
+      audience              = google_cloud_run_service.publish-consumer.status.0.url
+      service_account_email = google_service_account.publish-consumer-invoker.email
+    }
+  }
+
+  depends_on = [
+    google_app_engine_application.app,
+    google_cloud_run_service_iam_member.publish-consumer-invoker,
+    google_project_service.services["cloudscheduler.googleapis.com"],
+  ]
+}
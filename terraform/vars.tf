@@ -197,6 +197,13 @@ variable "export_create_batches_cron_schedule" {
   description = "Schedule to execute the export create batches service."
 }
 
+variable "export_relay_outbox_cron_schedule" {
+  type    = string
+  default = "* * * * *"
+
+  description = "Schedule to deliver the events in the outbox, such as CDN purges and webhooks."
+}
+
 variable "cleanup_exposure_worker_cron_schedule" {
   type    = string
   default = "0 */4 * * *"
@@ -211,6 +218,13 @@ variable "cleanup_export_worker_cron_schedule" {
   description = "Schedule to execute the cleanup export worker service."
 }
 
+variable "cleanup_export_audit_cron_schedule" {
+  type    = string
+  default = "30 3 * * *"
+
+  description = "Schedule to execute the export bucket audit of the cleanup export service."
+}
+
 variable "generate_cron_schedule" {
   type    = string
   default = "0 0 1 1 0"
@@ -232,6 +246,13 @@ variable "enable_cdn_for_exports" {
   description = "Enable Cloud CDN on the export bucket."
 }
 
+variable "enable_publish_queue" {
+  type    = bool
+  default = false
+
+  description = "Send uploads from publish to a Pub/Sub topic, and write them to the database with the publish-consumer service."
+}
+
 variable "admin_console_invokers" {
   type    = list(string)
   default = []
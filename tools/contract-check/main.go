@@ -0,0 +1,76 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package checks the API contract with clients against the golden file
+// and prints the differences. It exits non-zero if a change would break client
+// compatibility, or with --strict on any difference. With --update it records
+// the current contract in the golden file instead.
+//
+// Run it from the root of the repository:
+//
+//	go run ./tools/contract-check
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/google/exposure-notifications-server/internal/contract"
+)
+
+var (
+	rootFlag   = flag.String("root", ".", "Path to the root of the repository.")
+	apiFlag    = flag.String("api", "pkg/api/v1", "Path of the API package, relative to the root.")
+	goldenFlag = flag.String("golden", "internal/contract/testdata/contract.golden", "Path of the golden file, relative to the root.")
+	update     = flag.Bool("update", false, "Write the current contract to the golden file.")
+	strict     = flag.Bool("strict", false, "Exit non-zero on any difference, including additions.")
+)
+
+func main() {
+	flag.Parse()
+
+	current, err := contract.Current(os.DirFS(*rootFlag), *apiFlag)
+	if err != nil {
+		log.Fatalf("failed to build contract: %v", err)
+	}
+
+	goldenPath := filepath.Join(*rootFlag, *goldenFlag)
+	if *update {
+		if err := os.WriteFile(goldenPath, current.Marshal(), 0o600); err != nil {
+			log.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	b, err := os.ReadFile(goldenPath)
+	if err != nil {
+		log.Fatalf("failed to read golden file: %v", err)
+	}
+	golden, err := contract.Parse(b)
+	if err != nil {
+		log.Fatalf("failed to parse golden file: %v", err)
+	}
+
+	diff := contract.Compare(golden, current)
+	fmt.Print(diff)
+	if !diff.Empty() {
+		fmt.Println("Record intended changes with --update.")
+	}
+	if diff.Breaking() || (*strict && !diff.Empty()) {
+		os.Exit(1)
+	}
+}
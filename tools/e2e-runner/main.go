@@ -0,0 +1,528 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package is an end-to-end conformance test runner. Against a target
+// environment it publishes keys with real verification certificates, triggers
+// export batching, downloads and verifies the export, revises the keys, and
+// asserts that the revised keys are exported. The results are written as a
+// JUnit-style report so operators can validate upgrades before rollout.
+//
+// Example:
+//
+//	go run ./tools/e2e-runner \
+//	  -publish-url https://publish.example.com \
+//	  -export-url https://export.example.com \
+//	  -download-url https://storage.googleapis.com/my-bucket \
+//	  -index exposure-keys/index.txt \
+//	  -ha com.example.app \
+//	  -signing-key ./ha-private.pem -issuer doh.example.gov -audience ens.example.com \
+//	  -export-public-key ./export-public.pem \
+//	  -junit report.xml
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/export"
+	exportpb "github.com/google/exposure-notifications-server/internal/pb/export"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+	"github.com/google/exposure-notifications-server/pkg/util"
+	"github.com/google/exposure-notifications-server/pkg/verification"
+	"github.com/sethvargo/go-retry"
+)
+
+var (
+	publishURL  = flag.String("publish-url", "http://localhost:8080", "base URL of the publish service, will add /v1/publish")
+	exportURL   = flag.String("export-url", "", "base URL of the export service, will add /create-batches and /do-work; if blank, export batching is not triggered and the runner waits for scheduled exports")
+	downloadURL = flag.String("download-url", "", "base URL from which export files are downloaded")
+	indexFile   = flag.String("index", "index.txt", "path of the export index file, relative to -download-url")
+
+	healthAuthority = flag.String("ha", "", "Health Authority ID to use in publish requests")
+	signingKeyPath  = flag.String("signing-key", "", "path to a PEM encoded ECDSA P256 private key of a test health authority")
+	keyVersion      = flag.String("key-version", "v1", "health authority key version, sent as the JWT kid header")
+	issuer          = flag.String("issuer", "", "issuer of the minted verification certificates")
+	audience        = flag.String("audience", "", "audience of the minted verification certificates")
+
+	exportPublicKey = flag.String("export-public-key", "", "path to the PEM encoded ECDSA public key that signs exports, if blank signatures are not verified")
+
+	numKeys     = flag.Int("num-keys", 10, "number of keys to publish")
+	waitTimeout = flag.Duration("export-timeout", 15*time.Minute, "maximum time to wait for keys to appear in the export")
+	pollPeriod  = flag.Duration("poll-period", 15*time.Second, "time between checks of the export")
+	junitPath   = flag.String("junit", "", "path to write a JUnit XML report, if blank only a summary is printed")
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func realMain(ctx context.Context) error {
+	flag.Parse()
+
+	if *healthAuthority == "" {
+		return fmt.Errorf("-ha must be provided")
+	}
+	if *downloadURL == "" {
+		return fmt.Errorf("-download-url must be provided")
+	}
+	if *signingKeyPath == "" {
+		return fmt.Errorf("-signing-key must be provided")
+	}
+
+	b, err := os.ReadFile(*signingKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read signing key: %w", err)
+	}
+	signer, err := keys.ParseECDSAPrivateKey(string(b))
+	if err != nil {
+		return fmt.Errorf("failed to parse signing key: %w", err)
+	}
+
+	var verifier *ecdsa.PublicKey
+	if *exportPublicKey != "" {
+		b, err := os.ReadFile(*exportPublicKey)
+		if err != nil {
+			return fmt.Errorf("failed to read export public key: %w", err)
+		}
+		verifier, err = keys.ParseECDSAPublicKey(string(b))
+		if err != nil {
+			return fmt.Errorf("failed to parse export public key: %w", err)
+		}
+	}
+
+	r := &runner{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		signer:   signer,
+		verifier: verifier,
+		suite:    &testSuite{Name: "exposure-notifications-server-e2e"},
+	}
+	r.run(ctx)
+
+	if *junitPath != "" {
+		if err := r.suite.writeFile(*junitPath); err != nil {
+			return err
+		}
+	}
+	r.suite.summary(os.Stdout)
+
+	if r.suite.Failures > 0 {
+		return fmt.Errorf("%d of %d steps failed", r.suite.Failures, r.suite.Tests)
+	}
+	return nil
+}
+
+type runner struct {
+	client   *http.Client
+	signer   *ecdsa.PrivateKey
+	verifier *ecdsa.PublicKey
+	suite    *testSuite
+
+	keys          []verifyapi.ExposureKey
+	revisionToken string
+}
+
+// run executes each step in order. Once a step fails, the remaining steps are
+// skipped since they depend on it.
+func (r *runner) run(ctx context.Context) {
+	steps := []struct {
+		name string
+		fn   func(context.Context) error
+	}{
+		{"publish", r.publish},
+		{"export", r.waitForExport(false)},
+		{"revise", r.revise},
+		{"export_revised", r.waitForExport(true)},
+	}
+
+	failed := false
+	for _, step := range steps {
+		if failed {
+			r.suite.add(testCase{Name: step.name, Skipped: &skipped{Message: "previous step failed"}})
+			continue
+		}
+
+		start := time.Now()
+		err := step.fn(ctx)
+		tc := testCase{Name: step.name, Time: time.Since(start).Seconds()}
+		if err != nil {
+			failed = true
+			tc.Failure = &failure{Message: err.Error()}
+		}
+		r.suite.add(tc)
+	}
+}
+
+// publish uploads new keys with a "likely" verification certificate.
+func (r *runner) publish(ctx context.Context) error {
+	midnight := timeutils.UTCMidnight(time.Now())
+	r.keys = make([]verifyapi.ExposureKey, 0, *numKeys)
+	for i := 1; i <= *numKeys; i++ {
+		interval := model.IntervalNumber(midnight.Add(time.Duration(-i) * 24 * time.Hour))
+		key, err := util.RandomExposureKey(interval, verifyapi.MaxIntervalCount, 0)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		r.keys = append(r.keys, key)
+	}
+
+	resp, err := r.sendPublish(ctx, verifyapi.ReportTypeClinical, "")
+	if err != nil {
+		return err
+	}
+	if got, want := resp.InsertedExposures, len(r.keys); got != want {
+		return fmt.Errorf("expected %d inserted exposures, got %d", want, got)
+	}
+	if resp.RevisionToken == "" {
+		return fmt.Errorf("response is missing a revision token")
+	}
+	r.revisionToken = resp.RevisionToken
+	return nil
+}
+
+// revise uploads the same keys with a "confirmed" verification certificate
+// and the revision token from the publish step.
+func (r *runner) revise(ctx context.Context) error {
+	if _, err := r.sendPublish(ctx, verifyapi.ReportTypeConfirmed, r.revisionToken); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *runner) sendPublish(ctx context.Context, reportType, revisionToken string) (*verifyapi.PublishResponse, error) {
+	payload, hmacKey, err := r.certificate(reportType)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := make([]byte, 1024)
+	if _, err := rand.Read(padding); err != nil {
+		return nil, fmt.Errorf("failed to generate padding: %w", err)
+	}
+
+	body, err := json.Marshal(&verifyapi.Publish{
+		Keys:                r.keys,
+		HealthAuthorityID:   *healthAuthority,
+		VerificationPayload: payload,
+		HMACKey:             hmacKey,
+		RevisionToken:       revisionToken,
+		Padding:             base64.StdEncoding.EncodeToString(padding),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal publish request: %w", err)
+	}
+
+	url := strings.TrimSuffix(*publishURL, "/") + "/v1/publish"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, status, err := r.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp verifyapi.PublishResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse publish response (status %d): %w", status, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("publish failed with status %d: %s: %s", status, resp.Code, resp.ErrorMessage)
+	}
+	return &resp, nil
+}
+
+// certificate mints a verification certificate for the keys, as a
+// verification server would, and returns it with the HMAC key.
+func (r *runner) certificate(reportType string) (string, string, error) {
+	hmacKey := make([]byte, 32)
+	if _, err := rand.Read(hmacKey); err != nil {
+		return "", "", fmt.Errorf("failed to generate hmac key: %w", err)
+	}
+	hmac, err := verification.CalculateExposureKeyHMAC(r.keys, hmacKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to calculate hmac: %w", err)
+	}
+
+	now := time.Now().UTC()
+	claims := verifyapi.NewVerificationClaims()
+	claims.Audience = *audience
+	claims.Issuer = *issuer
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Add(-1 * time.Second).Unix()
+	claims.ExpiresAt = now.Add(5 * time.Minute).Unix()
+	claims.ReportType = reportType
+	claims.SymptomOnsetInterval = uint32(model.IntervalNumber(timeutils.UTCMidnight(now).Add(-72 * time.Hour)))
+	claims.SignedMAC = base64.StdEncoding.EncodeToString(hmac)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header[verifyapi.KeyIDHeader] = *keyVersion
+	signed, err := token.SignedString(r.signer)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	return signed, base64.StdEncoding.EncodeToString(hmacKey), nil
+}
+
+// waitForExport triggers export batching and polls the export until all of
+// the published keys are present, either as keys or, if revised is true, as
+// revised keys with a confirmed report type.
+func (r *runner) waitForExport(revised bool) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, *waitTimeout)
+		defer cancel()
+
+		var lastErr error
+		b := retry.NewConstant(*pollPeriod)
+		if err := retry.Do(ctx, b, func(ctx context.Context) error {
+			if err := r.triggerExport(ctx); err != nil {
+				return retry.RetryableError(err)
+			}
+
+			exported, err := r.downloadExport(ctx, revised)
+			if err != nil {
+				return retry.RetryableError(err)
+			}
+
+			for _, k := range r.keys {
+				tek, ok := exported[k.Key]
+				if !ok {
+					lastErr = fmt.Errorf("key %s was not exported", k.Key)
+					return retry.RetryableError(lastErr)
+				}
+				if revised && tek.GetReportType() != exportpb.TemporaryExposureKey_CONFIRMED_TEST {
+					return fmt.Errorf("revised key %s has report type %s", k.Key, tek.GetReportType())
+				}
+				if got, want := tek.GetRollingStartIntervalNumber(), k.IntervalNumber; got != want {
+					return fmt.Errorf("key %s has interval number %d, expected %d", k.Key, got, want)
+				}
+			}
+			return nil
+		}); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && lastErr != nil {
+				return fmt.Errorf("timed out waiting for export: %w", lastErr)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// triggerExport asks the export service to create batches and do work, if
+// configured.
+func (r *runner) triggerExport(ctx context.Context) error {
+	if *exportURL == "" {
+		return nil
+	}
+
+	for _, p := range []string{"/create-batches", "/do-work"} {
+		url := strings.TrimSuffix(*exportURL, "/") + p
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to build request: %w", err)
+		}
+		if _, status, err := r.do(req); err != nil {
+			return err
+		} else if status != http.StatusOK {
+			return fmt.Errorf("%s failed with status %d", p, status)
+		}
+	}
+	return nil
+}
+
+// downloadExport downloads every export file in the index and returns the
+// exported keys by their base64 key data.
+func (r *runner) downloadExport(ctx context.Context, revised bool) (map[string]*exportpb.TemporaryExposureKey, error) {
+	index, err := r.download(ctx, *indexFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download index: %w", err)
+	}
+
+	exported := make(map[string]*exportpb.TemporaryExposureKey)
+	for _, name := range strings.Split(string(index), "\n") {
+		name = strings.TrimSpace(name)
+		if !strings.HasSuffix(name, ".zip") {
+			continue
+		}
+
+		blob, err := r.download(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", name, err)
+		}
+
+		contents, digest, err := export.UnmarshalExportFile(blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := r.verifySignature(blob, digest); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		teks := contents.GetKeys()
+		if revised {
+			teks = contents.GetRevisedKeys()
+		}
+		for _, tek := range teks {
+			exported[base64.StdEncoding.EncodeToString(tek.GetKeyData())] = tek
+		}
+	}
+	return exported, nil
+}
+
+// verifySignature checks that at least one signature in the export file was
+// made with the export public key, if one was provided.
+func (r *runner) verifySignature(blob []byte, digest []byte) error {
+	if r.verifier == nil {
+		return nil
+	}
+
+	sigs, err := export.UnmarshalSignatureFile(blob)
+	if err != nil {
+		return fmt.Errorf("failed to read signatures: %w", err)
+	}
+	for _, sig := range sigs.GetSignatures() {
+		if ecdsa.VerifyASN1(r.verifier, digest, sig.GetSignature()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no signature verified with the export public key")
+}
+
+func (r *runner) download(ctx context.Context, name string) ([]byte, error) {
+	url := strings.TrimSuffix(*downloadURL, "/") + "/" + strings.TrimPrefix(name, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	body, status, err := r.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("GET %s failed with status %d", url, status)
+	}
+	return body, nil
+}
+
+// do executes the request and returns the body and status code.
+func (r *runner) do(req *http.Request) ([]byte, int, error) {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read body: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// testSuite is a JUnit XML test suite.
+type testSuite struct {
+	XMLName   xml.Name   `xml:"testsuite"`
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Skipped   int        `xml:"skipped,attr"`
+	Time      float64    `xml:"time,attr"`
+	Timestamp string     `xml:"timestamp,attr"`
+	TestCases []testCase `xml:"testcase"`
+}
+
+type testCase struct {
+	Name    string   `xml:"name,attr"`
+	Time    float64  `xml:"time,attr"`
+	Failure *failure `xml:"failure,omitempty"`
+	Skipped *skipped `xml:"skipped,omitempty"`
+}
+
+type failure struct {
+	Message string `xml:"message,attr"`
+}
+
+type skipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func (s *testSuite) add(tc testCase) {
+	if s.Timestamp == "" {
+		s.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	s.Tests++
+	s.Time += tc.Time
+	if tc.Failure != nil {
+		s.Failures++
+	}
+	if tc.Skipped != nil {
+		s.Skipped++
+	}
+	s.TestCases = append(s.TestCases, tc)
+}
+
+func (s *testSuite) writeFile(path string) error {
+	b, err := xml.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	b = append([]byte(xml.Header), b...)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+func (s *testSuite) summary(w io.Writer) {
+	for _, tc := range s.TestCases {
+		switch {
+		case tc.Failure != nil:
+			fmt.Fprintf(w, "FAIL  %-16s %6.1fs  %s\n", tc.Name, tc.Time, tc.Failure.Message)
+		case tc.Skipped != nil:
+			fmt.Fprintf(w, "SKIP  %-16s          %s\n", tc.Name, tc.Skipped.Message)
+		default:
+			fmt.Fprintf(w, "PASS  %-16s %6.1fs\n", tc.Name, tc.Time)
+		}
+	}
+}
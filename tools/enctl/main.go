@@ -0,0 +1,42 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command enctl administers the configuration of a server deployment. It
+// replaces the single-purpose export-config, federationin-query, and
+// stats-recompute tools.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/exposure-notifications-server/internal/enctl"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger := logging.NewLoggerFromEnv().Named("tools.enctl")
+	ctx = logging.WithLogger(ctx, logger)
+
+	err := enctl.NewCommand().ExecuteContext(ctx)
+	done()
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
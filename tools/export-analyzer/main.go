@@ -12,44 +12,59 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// This tool displays the content of the export file.
+// This tool displays the content of the export file. It can optionally verify
+// each file's signatures against a set of public keys and cross-check the
+// files against an index.txt.
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/export"
 	exportpb "github.com/google/exposure-notifications-server/internal/pb/export"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/pkg/keys"
 	"github.com/hashicorp/go-multierror"
 )
 
 var (
 	showSig         = flag.Bool("sig", true, "show signature information from export bundle in json")
-	filePath        = flag.String("file", "", "path to the export files, supports file globs")
+	filePath        = flag.String("file", "", "path to the export files, supports file globs; if blank, the files listed in --index are analyzed")
 	printJSON       = flag.Bool("json", true, "show the export in json")
 	quiet           = flag.Bool("q", false, "run in quiet mode")
 	allowedTEKAge   = flag.Duration("tek-age", 14*24*time.Hour, "max TEK age in checks")
 	symptomDayLimit = flag.Int("symptom-days", 14, "magnitude of expected symptom onset day range")
 	fileAge         = flag.Duration("file-age", time.Duration(0), "file age is a positive duration that indicates how old a file is, this would be added to tek-age when validating the file and adjusts 'current time' for validing future keys.")
+	publicKeys      = flag.String("public-keys", "", "path to PEM encoded ECDSA public keys used to verify signatures, supports file globs and multiple keys per file; every file must have at least one signature that verifies")
+	indexPath       = flag.String("index", "", "path or URL of an index.txt to cross-check; every listed file must exist and, for local indexes, every export file must be listed")
+	indexRoot       = flag.String("index-root", "", "path or URL that entries in --index are relative to, defaults to the directory containing the index")
+	format          = flag.String("format", "text", "output format, one of text or json; json produces a machine-readable report")
+	showSummary     = flag.Bool("summary", false, "print distributions of the keys across all files instead of the content of each file")
 )
 
 func main() {
-	if err := realMain(); err != nil {
+	if err := realMain(context.Background()); err != nil {
 		printError("%s", err)
 		os.Exit(1)
 	}
 }
 
-func realMain() error {
+func realMain(ctx context.Context) error {
 	flag.Parse()
-	if *filePath == "" {
-		return fmt.Errorf("--file is required")
+	if *filePath == "" && *indexPath == "" {
+		return fmt.Errorf("--file or --index is required")
 	}
 	if *allowedTEKAge < time.Duration(0) {
 		return fmt.Errorf("--tek-age must be a positive duration, got %q", *allowedTEKAge)
@@ -60,25 +75,75 @@ func realMain() error {
 	if *fileAge < time.Duration(0) {
 		return fmt.Errorf("--file-age must be a positive duration, got %q", *fileAge)
 	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("--format must be text or json, got %q", *format)
+	}
 
-	matches, err := filepath.Glob(*filePath)
+	verifiers, err := loadPublicKeys(*publicKeys)
 	if err != nil {
-		return fmt.Errorf("failed to expand matches: %w", err)
+		return err
 	}
 
-	if len(matches) == 0 {
-		return fmt.Errorf("%q produced no matches (shell escaping?)", *filePath)
+	var matches []string
+	if *filePath != "" {
+		matches, err = filepath.Glob(*filePath)
+		if err != nil {
+			return fmt.Errorf("failed to expand matches: %w", err)
+		}
+
+		if len(matches) == 0 {
+			return fmt.Errorf("%q produced no matches (shell escaping?)", *filePath)
+		}
 	}
 
 	var errors *multierror.Error
+	var rep report
+
+	var sum *summary
+	if *showSummary {
+		sum = newSummary()
+		rep.Summary = sum
+	}
+
+	if *indexPath != "" {
+		idx, files, err := checkIndex(ctx, *indexPath, *indexRoot, matches)
+		if err != nil {
+			return err
+		}
+		rep.Index = idx
+		for _, m := range idx.Missing {
+			errors = multierror.Append(errors, fmt.Errorf("index: listed file %s does not exist", m))
+		}
+		for _, o := range idx.Orphans {
+			errors = multierror.Append(errors, fmt.Errorf("index: file %s is not listed", o))
+		}
+		if len(matches) == 0 {
+			matches = files
+		}
+	}
+
 	results := make([]*analysis, 0, len(matches))
 	for _, m := range matches {
-		result, err := analyzeOne(m, *showSig, *printJSON)
+		result, err := analyzeOne(ctx, m, *showSig && sum == nil, *printJSON && sum == nil, verifiers, sum)
 		if err != nil {
 			errors = multierror.Append(errors, fmt.Errorf("%s: %w", m, err))
+			rep.Files = append(rep.Files, &fileReport{Path: m, Error: err.Error()})
 			continue
 		}
-		results = append(results, result)
+		if sum == nil {
+			results = append(results, result)
+		}
+		rep.Files = append(rep.Files, result.report())
+	}
+
+	if *format == "json" {
+		rep.Valid = errors.ErrorOrNil() == nil
+		b, err := json.MarshalIndent(&rep, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		printMsg("%s", b)
+		return errors.ErrorOrNil()
 	}
 
 	for _, result := range results {
@@ -90,20 +155,70 @@ func realMain() error {
 		if !*quiet && len(result.export) > 0 {
 			printMsg("export: %s", result.export)
 		}
+		for _, v := range result.verified {
+			printMsg("signature %s/%s verified: %t", v.KeyID, v.KeyVersion, v.Verified)
+		}
 		printMsg("\n")
 	}
 
+	if sum != nil {
+		sum.print(os.Stdout)
+	}
+
+	if rep.Index != nil {
+		printMsg("index %s: %d listed, %d missing, %d orphaned", rep.Index.Location, rep.Index.Listed, len(rep.Index.Missing), len(rep.Index.Orphans))
+	}
+
 	return errors.ErrorOrNil()
 }
 
+// report is the machine-readable output of the analyzer.
+type report struct {
+	Valid   bool          `json:"valid"`
+	Index   *indexReport  `json:"index,omitempty"`
+	Summary *summary      `json:"summary,omitempty"`
+	Files   []*fileReport `json:"files"`
+}
+
+type indexReport struct {
+	Location string   `json:"location"`
+	Listed   int      `json:"listed"`
+	Missing  []string `json:"missing,omitempty"`
+	Orphans  []string `json:"orphans,omitempty"`
+}
+
+type fileReport struct {
+	Path       string            `json:"path"`
+	Error      string            `json:"error,omitempty"`
+	Signatures []*signatureCheck `json:"signatures,omitempty"`
+	Signature  json.RawMessage   `json:"signature,omitempty"`
+	Export     json.RawMessage   `json:"export,omitempty"`
+}
+
+type signatureCheck struct {
+	KeyID      string `json:"keyID"`
+	KeyVersion string `json:"keyVersion"`
+	Verified   bool   `json:"verified"`
+}
+
 type analysis struct {
-	path   string
-	sig    []byte
-	export []byte
+	path     string
+	sig      []byte
+	export   []byte
+	verified []*signatureCheck
+}
+
+func (a *analysis) report() *fileReport {
+	return &fileReport{
+		Path:       a.path,
+		Signatures: a.verified,
+		Signature:  a.sig,
+		Export:     a.export,
+	}
 }
 
-func analyzeOne(pth string, includeSig, includeExport bool) (*analysis, error) {
-	blob, err := os.ReadFile(pth)
+func analyzeOne(ctx context.Context, pth string, includeSig, includeExport bool, verifiers []*ecdsa.PublicKey, sum *summary) (*analysis, error) {
+	blob, err := readLocation(ctx, pth)
 	if err != nil {
 		return nil, fmt.Errorf("can't read export file: %w", err)
 	}
@@ -111,12 +226,12 @@ func analyzeOne(pth string, includeSig, includeExport bool) (*analysis, error) {
 	var result analysis
 	result.path = pth
 
-	if includeSig {
-		sigExport, err := export.UnmarshalSignatureFile(blob)
-		if err != nil {
-			return nil, fmt.Errorf("error unmarshaling export signature file: %w", err)
-		}
+	sigExport, err := export.UnmarshalSignatureFile(blob)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling export signature file: %w", err)
+	}
 
+	if includeSig {
 		prettyJSON, err := json.MarshalIndent(sigExport, "", " ")
 		if err != nil {
 			return nil, fmt.Errorf("error pretty printing export signature: %w", err)
@@ -124,11 +239,29 @@ func analyzeOne(pth string, includeSig, includeExport bool) (*analysis, error) {
 		result.sig = prettyJSON
 	}
 
-	keyExport, _, err := export.UnmarshalExportFile(blob)
+	keyExport, digest, err := export.UnmarshalExportFile(blob)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshaling export file: %w", err)
 	}
 
+	// Files are summarized even if they fail validation, so the summary
+	// reflects everything that was exported.
+	if sum != nil {
+		sum.add(pth, keyExport)
+	}
+
+	if len(verifiers) > 0 {
+		result.verified = verifySignatures(sigExport, digest, verifiers)
+
+		anyVerified := false
+		for _, v := range result.verified {
+			anyVerified = anyVerified || v.Verified
+		}
+		if !anyVerified {
+			return nil, fmt.Errorf("no signature verified with the provided public keys")
+		}
+	}
+
 	// Do some basic data validation.
 	if err := checkExportFile(keyExport); err != nil {
 		return nil, fmt.Errorf("export file contains errors: %w", err)
@@ -145,6 +278,185 @@ func analyzeOne(pth string, includeSig, includeExport bool) (*analysis, error) {
 	return &result, nil
 }
 
+// loadPublicKeys parses every public key in the files matching the glob.
+func loadPublicKeys(glob string) ([]*ecdsa.PublicKey, error) {
+	if glob == "" {
+		return nil, nil
+	}
+
+	matches, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand --public-keys: %w", err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("--public-keys %q produced no matches (shell escaping?)", glob)
+	}
+
+	var verifiers []*ecdsa.PublicKey
+	for _, m := range matches {
+		b, err := os.ReadFile(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public keys: %w", err)
+		}
+		parsed, err := keys.ParseECDSAPublicKeys(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public keys in %s: %w", m, err)
+		}
+		verifiers = append(verifiers, parsed...)
+	}
+	return verifiers, nil
+}
+
+// verifySignatures checks each signature in the export against every
+// provided public key.
+func verifySignatures(sigExport *exportpb.TEKSignatureList, digest []byte, verifiers []*ecdsa.PublicKey) []*signatureCheck {
+	checks := make([]*signatureCheck, 0, len(sigExport.GetSignatures()))
+	for _, sig := range sigExport.GetSignatures() {
+		check := &signatureCheck{
+			KeyID:      sig.GetSignatureInfo().GetVerificationKeyId(),
+			KeyVersion: sig.GetSignatureInfo().GetVerificationKeyVersion(),
+		}
+		for _, v := range verifiers {
+			if ecdsa.VerifyASN1(v, digest, sig.GetSignature()) {
+				check.Verified = true
+				break
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkIndex reads the index at location and checks that every listed file
+// exists. If the index is local, any export file that is in the index
+// directory (or in matches, if provided) but not listed is reported as an
+// orphan. It returns the locations of the listed files.
+func checkIndex(ctx context.Context, location, root string, matches []string) (*indexReport, []string, error) {
+	b, err := readLocation(ctx, location)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	remote := isURL(location)
+	if root == "" {
+		if remote {
+			root = location[:strings.LastIndex(location, "/")]
+		} else {
+			root = filepath.Dir(location)
+		}
+	}
+
+	rep := &indexReport{Location: location}
+	listed := make(map[string]struct{})
+	var files []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rep.Listed++
+
+		// Index entries are object names relative to the bucket, which share a
+		// prefix with the index when no root is given.
+		name := line
+		if *indexRoot == "" {
+			name = path.Base(line)
+		}
+
+		var loc string
+		if remote {
+			loc = strings.TrimSuffix(root, "/") + "/" + name
+		} else {
+			loc = filepath.Clean(filepath.Join(root, filepath.FromSlash(name)))
+		}
+		listed[loc] = struct{}{}
+
+		if ok, err := exists(ctx, loc); err != nil {
+			return nil, nil, fmt.Errorf("failed to check %s: %w", loc, err)
+		} else if !ok {
+			rep.Missing = append(rep.Missing, loc)
+			continue
+		}
+		files = append(files, loc)
+	}
+
+	if remote {
+		return rep, files, nil
+	}
+
+	candidates := matches
+	if len(candidates) == 0 {
+		candidates, err = filepath.Glob(filepath.Join(filepath.Dir(location), "*.zip"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list export files: %w", err)
+		}
+	}
+	for _, c := range candidates {
+		if _, ok := listed[filepath.Clean(c)]; !ok {
+			rep.Orphans = append(rep.Orphans, c)
+		}
+	}
+	sort.Strings(rep.Orphans)
+
+	return rep, files, nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// readLocation reads a local file or downloads a URL.
+func readLocation(ctx context.Context, loc string) ([]byte, error) {
+	if !isURL(loc) {
+		return os.ReadFile(loc)
+	}
+
+	resp, err := do(ctx, http.MethodGet, loc)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %d", loc, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// exists reports whether a local file or URL exists.
+func exists(ctx context.Context, loc string) (bool, error) {
+	if !isURL(loc) {
+		_, err := os.Stat(loc)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	resp, err := do(ctx, http.MethodHead, loc)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("HEAD %s: unexpected status %d", loc, resp.StatusCode)
+	}
+}
+
 func checkExportFile(export *exportpb.TemporaryExposureKeyExport) error {
 	now := time.Now().UTC().Add(-1 * *fileAge)
 	floor := model.IntervalNumber(now.Add(-1 * *allowedTEKAge))
@@ -0,0 +1,161 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	exportpb "github.com/google/exposure-notifications-server/internal/pb/export"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+)
+
+// maxDuplicateExamples is the maximum number of duplicate keys that are listed
+// individually in a summary.
+const maxDuplicateExamples = 25
+
+// summary holds distributions of the keys across all analyzed files.
+type summary struct {
+	Files             int            `json:"files"`
+	Keys              int            `json:"keys"`
+	RevisedKeys       int            `json:"revisedKeys"`
+	KeysPerDay        map[string]int `json:"keysPerDay"`
+	RevisedKeysPerDay map[string]int `json:"revisedKeysPerDay"`
+	ReportTypes       map[string]int `json:"reportTypes"`
+	OnsetDays         map[string]int `json:"onsetDays"`
+	RollingPeriods    map[string]int `json:"rollingPeriods"`
+	DuplicateKeys     int            `json:"duplicateKeys"`
+	Duplicates        []*duplicate   `json:"duplicates,omitempty"`
+
+	// seen maps each key to the first file it was exported in.
+	seen map[string]string
+}
+
+// duplicate is a key that is exported more than once, not counting revisions.
+type duplicate struct {
+	Key   string   `json:"key"`
+	Files []string `json:"files"`
+}
+
+func newSummary() *summary {
+	return &summary{
+		KeysPerDay:        make(map[string]int),
+		RevisedKeysPerDay: make(map[string]int),
+		ReportTypes:       make(map[string]int),
+		OnsetDays:         make(map[string]int),
+		RollingPeriods:    make(map[string]int),
+		seen:              make(map[string]string),
+	}
+}
+
+// add includes the keys from a single export file in the summary.
+func (s *summary) add(pth string, keyExport *exportpb.TemporaryExposureKeyExport) {
+	s.Files++
+
+	for _, k := range keyExport.GetKeys() {
+		s.Keys++
+		s.KeysPerDay[intervalDay(k.GetRollingStartIntervalNumber())]++
+		s.addDistributions(k)
+
+		key := base64.StdEncoding.EncodeToString(k.GetKeyData())
+		first, ok := s.seen[key]
+		if !ok {
+			s.seen[key] = pth
+			continue
+		}
+
+		s.DuplicateKeys++
+		if len(s.Duplicates) < maxDuplicateExamples {
+			s.Duplicates = append(s.Duplicates, &duplicate{Key: key, Files: []string{first, pth}})
+		}
+	}
+
+	for _, k := range keyExport.GetRevisedKeys() {
+		s.RevisedKeys++
+		s.RevisedKeysPerDay[intervalDay(k.GetRollingStartIntervalNumber())]++
+		s.addDistributions(k)
+	}
+}
+
+func (s *summary) addDistributions(k *exportpb.TemporaryExposureKey) {
+	s.ReportTypes[k.GetReportType().String()]++
+	s.RollingPeriods[strconv.Itoa(int(k.GetRollingPeriod()))]++
+	if k.DaysSinceOnsetOfSymptoms == nil {
+		s.OnsetDays["unset"]++
+	} else {
+		s.OnsetDays[strconv.Itoa(int(k.GetDaysSinceOnsetOfSymptoms()))]++
+	}
+}
+
+// print writes the summary in a human-readable form.
+func (s *summary) print(w io.Writer) {
+	fmt.Fprintf(w, "files:        %d\n", s.Files)
+	fmt.Fprintf(w, "keys:         %d\n", s.Keys)
+	fmt.Fprintf(w, "revised keys: %d\n", s.RevisedKeys)
+
+	printHistogram(w, "keys per day", s.KeysPerDay, false)
+	printHistogram(w, "revised keys per day", s.RevisedKeysPerDay, false)
+	printHistogram(w, "report types", s.ReportTypes, false)
+	printHistogram(w, "days since onset of symptoms", s.OnsetDays, true)
+	printHistogram(w, "rolling periods", s.RollingPeriods, true)
+
+	fmt.Fprintf(w, "\nduplicate keys: %d\n", s.DuplicateKeys)
+	for _, d := range s.Duplicates {
+		fmt.Fprintf(w, "  %s %v\n", d.Key, d.Files)
+	}
+	if s.DuplicateKeys > len(s.Duplicates) {
+		fmt.Fprintf(w, "  ... and %d more\n", s.DuplicateKeys-len(s.Duplicates))
+	}
+}
+
+// printHistogram writes the buckets in sorted order. If numeric is true,
+// buckets that are integers are sorted by value and any others sort last.
+func printHistogram(w io.Writer, title string, buckets map[string]int, numeric bool) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	if len(buckets) == 0 {
+		fmt.Fprintf(w, "  (none)\n")
+		return
+	}
+
+	names := make([]string, 0, len(buckets))
+	for k := range buckets {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if numeric {
+			a, aErr := strconv.Atoi(names[i])
+			b, bErr := strconv.Atoi(names[j])
+			if aErr == nil && bErr == nil {
+				return a < b
+			}
+			if (aErr == nil) != (bErr == nil) {
+				return aErr == nil
+			}
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-24s %d\n", name, buckets[name])
+	}
+}
+
+// intervalDay returns the UTC date of the interval number.
+func intervalDay(interval int32) string {
+	return model.TimeForIntervalNumber(interval).UTC().Format("2006-01-02")
+}
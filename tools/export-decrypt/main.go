@@ -0,0 +1,100 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool decrypts an export file that was encrypted for a private partner
+// channel. The key manager is configured from the environment in the same way
+// as the export service (KEY_MANAGER, etc.).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/google/exposure-notifications-server/internal/buildinfo"
+	"github.com/google/exposure-notifications-server/internal/export"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/sethvargo/go-envconfig"
+)
+
+var (
+	inFile  = flag.String("in", "", "path to the encrypted export file")
+	outFile = flag.String("out", "", "path to write the decrypted export file, defaults to --in without the .enc suffix")
+	keyID   = flag.String("key-id", "", "key manager key that the export file was encrypted with")
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger := logging.NewLoggerFromEnv().Named("tools.export-decrypt")
+	logger = logger.With("build_id", buildinfo.BuildID)
+	logger = logger.With("build_tag", buildinfo.BuildTag)
+	ctx = logging.WithLogger(ctx, logger)
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func realMain(ctx context.Context) error {
+	flag.Parse()
+	if *inFile == "" {
+		return fmt.Errorf("--in is required")
+	}
+	if *keyID == "" {
+		return fmt.Errorf("--key-id is required")
+	}
+	out := *outFile
+	if out == "" {
+		if !strings.HasSuffix(*inFile, export.EncryptedFilenameSuffix) {
+			return fmt.Errorf("--out is required when --in does not end in %s", export.EncryptedFilenameSuffix)
+		}
+		out = strings.TrimSuffix(*inFile, export.EncryptedFilenameSuffix)
+	}
+
+	logger := logging.FromContext(ctx)
+
+	var config keys.Config
+	if err := envconfig.Process(ctx, &config); err != nil {
+		return fmt.Errorf("failed to process config: %w", err)
+	}
+	km, err := keys.KeyManagerFor(ctx, &config)
+	if err != nil {
+		return fmt.Errorf("failed to create key manager: %w", err)
+	}
+
+	data, err := os.ReadFile(*inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *inFile, err)
+	}
+
+	plaintext, err := export.DecryptExportFile(ctx, km, *keyID, data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(out, plaintext, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	logger.Infow("decrypted export file", "out", out)
+	return nil
+}
@@ -12,37 +12,37 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// This utility generates test exports signed with local keys
+// This utility generates test exports signed with local keys. It can produce
+// every field in the v1.5+ export format, including revised keys, report types,
+// days since onset of symptoms, and multiple signatures. When --seed is set,
+// the generated keys and metadata are deterministic.
 package main
 
 import (
 	"crypto/ecdsa"
-	"crypto/x509"
-	"encoding/base64"
 	"encoding/json"
-	"encoding/pem"
-	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"math"
+	"math/rand"
 	"os"
+	"strings"
 	"time"
 
-	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
-
 	"github.com/google/exposure-notifications-server/internal/export"
 	"github.com/google/exposure-notifications-server/internal/export/model"
-
-	"github.com/google/exposure-notifications-server/pkg/util"
-
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/cryptorand"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
 )
 
 var (
-	signingKey     = flag.String("signing-key", "", "The path to a private key PEM to use for signing")
-	keyID          = flag.String("key-id", "some_id", "Value to use in verification_key_id")
-	keyVersion     = flag.String("key-version", "1", "Value to use in verification_key_version")
+	signingKey     = flag.String("signing-key", "", "The path to a private key PEM to use for signing. Multiple comma-separated paths produce multiple signatures.")
+	keyID          = flag.String("key-id", "some_id", "Value to use in verification_key_id, comma-separated to match each --signing-key")
+	keyVersion     = flag.String("key-version", "1", "Value to use in verification_key_version, comma-separated to match each --signing-key")
 	filenameRoot   = flag.String("filename-root", "/tmp/testExport-", "The root filename for the export file(s).")
 	region         = flag.String("region", "US", "The output region for the test export.")
 	startTimestamp = flag.String("start-timestamp", "", "The test export start timestamp (RFC3339, e.g. 2020-05-01T15:00:00Z). (default yesterday)")
@@ -50,6 +50,16 @@ var (
 	numKeys        = flag.Int("num-keys", 450, "Number of total random temporary exposure keys to generate. Ignored if tek-file set.")
 	tekFile        = flag.String("tek-file", "", "JSON file of TEKs in the same format as calling publish endpoint")
 	batchSize      = flag.Int("batches-size", 100, "Max number of keys in each file in the batch")
+
+	seed              = flag.Int64("seed", 0, "Seed for deterministic keys and metadata. Signatures are still randomized. Use with --end-timestamp for reproducible files. (default random)")
+	keyDays           = flag.Int("key-days", 1, "Keys are spread over this many days before the UTC day of the end timestamp.")
+	reportTypes       = flag.String("report-types", "", "Comma-separated report types (confirmed, likely, user-report) to assign at random. (default none)")
+	onsetRate         = flag.Float64("onset-rate", 0, "Fraction of keys, from 0 to 1, with days_since_onset_of_symptoms set.")
+	symptomDays       = flag.Int("symptom-days", 14, "Magnitude of the range of days_since_onset_of_symptoms.")
+	numRevisedKeys    = flag.Int("num-revised-keys", 0, "Number of additional keys to emit as revised keys.")
+	revisedReportType = flag.String("revised-report-type", verifyapi.ReportTypeConfirmed, "Report type of revised keys.")
+	travelerRate      = flag.Float64("traveler-rate", 0, "Fraction of keys, from 0 to 1, that are marked as travelers.")
+	onlyNonTravelers  = flag.Bool("only-non-travelers", false, "Exclude traveler keys, as an export config with only non-travelers would.")
 )
 
 const (
@@ -62,6 +72,32 @@ func main() {
 	if *signingKey == "" {
 		log.Fatal("--signing-key is required.")
 	}
+	if *onsetRate < 0 || *onsetRate > 1 {
+		log.Fatal("--onset-rate must be between 0 and 1.")
+	}
+	if *travelerRate < 0 || *travelerRate > 1 {
+		log.Fatal("--traveler-rate must be between 0 and 1.")
+	}
+	if *keyDays < 1 {
+		log.Fatal("--key-days must be at least 1.")
+	}
+
+	var types []string
+	if *reportTypes != "" {
+		types = strings.Split(*reportTypes, ",")
+	}
+	for _, rt := range append(types, *revisedReportType) {
+		if !verifyapi.ValidReportTypes[rt] {
+			log.Fatalf("invalid report type %q", rt)
+		}
+	}
+
+	//nolint:gosec // math/rand is used so output can be reproduced with --seed
+	r := rand.New(cryptorand.NewSource())
+	if *seed != 0 {
+		//nolint:gosec // math/rand is used so output can be reproduced with --seed
+		r = rand.New(rand.NewSource(*seed))
+	}
 
 	// set endTime default to now, startTime default to (now - 24h).
 	endTime := time.Now()
@@ -82,18 +118,21 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to parse --end-timestamp (use RFC3339): %v", err)
 		}
+		if *startTimestamp == "" {
+			startTime = endTime.Add(-time.Hour * 24)
+		}
 	}
 
-	// parse signing key.
-	var privateKey *ecdsa.PrivateKey
-	privateKey, err := getSigningKey(*signingKey)
+	// parse signing keys.
+	signers, err := getSigners(*signingKey, *keyID, *keyVersion)
 	if err != nil {
-		log.Fatalf("unable to generate signing key: %v", err)
+		log.Fatalf("unable to load signing keys: %v", err)
 	}
 
+	g := &generator{r: r, endTime: endTime, reportTypes: types}
+
 	// generate fake keys.
-	var actualNumKeys int
-	var exposureKeys []publishmodel.Exposure
+	var exposureKeys []*publishmodel.Exposure
 	if *tekFile != "" {
 		log.Printf("Using TEKs provided in: %s", *tekFile)
 		file, err := os.ReadFile(*tekFile)
@@ -107,85 +146,153 @@ func main() {
 		}
 		for _, k := range data.Keys {
 			settings := publishmodel.KeyTransform{
-				MinStartInterval:      0,
-				MaxStartInterval:      int32(time.Now().Unix() / 600),
-				CreatedAt:             time.Now(),
-				ReleaseStillValidKeys: false,
+				MinStartInterval: 0,
+				MaxStartInterval: int32(time.Now().Unix() / 600),
+				CreatedAt:        time.Now(),
+				SameDayKeyPolicy: publishmodel.SameDayKeyPolicyEmbargo,
 			}
 			ek, err := publishmodel.TransformExposureKey(k, "", []string{}, &settings)
 			if err != nil {
 				log.Fatalf("invalid exposure key: %v", err)
 			}
-			exposureKeys = append(exposureKeys, *ek)
+			g.decorate(ek)
+			exposureKeys = append(exposureKeys, ek)
 		}
-		actualNumKeys = len(exposureKeys)
 	} else {
-		tr, err := util.RandomTransmissionRisk()
-		if err != nil {
-			log.Fatalf("problem with random transmission risk: %v", err)
-		}
-
 		log.Printf("Generating %d random TEKs", *numKeys)
-		keys := util.GenerateExposureKeys(*numKeys, tr, false)
-		actualNumKeys = *numKeys
+		exposureKeys = g.exposures(*numKeys)
+	}
 
-		exposureKeys = make([]publishmodel.Exposure, actualNumKeys)
-		for i, k := range keys {
-			decoded, err := base64.StdEncoding.DecodeString(k.Key)
-			if err != nil {
-				log.Fatalf("unable to decode key: %v", k.Key)
-			}
-			exposureKeys[i].ExposureKey = decoded
-			exposureKeys[i].IntervalNumber = k.IntervalNumber - verifyapi.MaxIntervalCount // typically the key will be at least 1 day old
-			if exposureKeys[i].IntervalNumber < 0 {
-				exposureKeys[i].IntervalNumber = 0
-			}
-			exposureKeys[i].IntervalCount = k.IntervalCount
-			exposureKeys[i].TransmissionRisk = k.TransmissionRisk
-		}
+	log.Printf("Generating %d revised TEKs", *numRevisedKeys)
+	revisedKeys := g.exposures(*numRevisedKeys)
+	for _, exp := range revisedKeys {
+		g.revise(exp, *revisedReportType)
+	}
+
+	if *onlyNonTravelers {
+		exposureKeys = withoutTravelers(exposureKeys)
+		revisedKeys = withoutTravelers(revisedKeys)
 	}
 
-	// split up into batches.
+	// split up into batches. Keys and revised keys are spread over the same
+	// number of files.
 	eb := &model.ExportBatch{
-		FilenameRoot:   *filenameRoot,
-		StartTimestamp: startTime,
-		EndTimestamp:   endTime,
-		OutputRegion:   *region,
+		FilenameRoot:     *filenameRoot,
+		StartTimestamp:   startTime,
+		EndTimestamp:     endTime,
+		OutputRegion:     *region,
+		IncludeTravelers: !*onlyNonTravelers,
+		OnlyNonTravelers: *onlyNonTravelers,
 	}
-	numBatches := int(math.Ceil(float64(actualNumKeys) / float64(*batchSize)))
-	log.Printf("number of batches: %d", numBatches)
-	var b int32
-	currentBatch := []*publishmodel.Exposure{}
-	for i := 0; i < actualNumKeys; i++ {
-		currentBatch = append(currentBatch, &exposureKeys[i])
-		if len(currentBatch) == *batchSize {
-			b++
-			w := exportFileWriter{
-				exportBatch: eb,
-				exposures:   currentBatch,
-				curBatch:    b,
-				numBatches:  numBatches,
-				totalKeys:   actualNumKeys,
-				privateKey:  privateKey,
-			}
-			w.writeFile()
-			currentBatch = []*publishmodel.Exposure{}
-		}
+	totalKeys := len(exposureKeys) + len(revisedKeys)
+	largest := len(exposureKeys)
+	if len(revisedKeys) > largest {
+		largest = len(revisedKeys)
 	}
-	if len(currentBatch) > 0 {
-		b++
+	numBatches := int(math.Ceil(float64(largest) / float64(*batchSize)))
+	log.Printf("number of batches: %d", numBatches)
+	for b := 0; b < numBatches; b++ {
 		w := exportFileWriter{
 			exportBatch: eb,
-			exposures:   currentBatch,
-			curBatch:    b,
+			exposures:   batch(exposureKeys, b, *batchSize),
+			revisions:   batch(revisedKeys, b, *batchSize),
+			curBatch:    int32(b + 1),
 			numBatches:  numBatches,
-			totalKeys:   actualNumKeys,
-			privateKey:  privateKey,
+			totalKeys:   totalKeys,
+			signers:     signers,
 		}
 		w.writeFile()
 	}
 }
 
+// generator creates exposures with random metadata.
+type generator struct {
+	r           *rand.Rand
+	endTime     time.Time
+	reportTypes []string
+	tr          int
+}
+
+// exposures generates n random exposures.
+func (g *generator) exposures(n int) []*publishmodel.Exposure {
+	if g.tr == 0 {
+		g.tr = g.r.Intn(verifyapi.MaxTransmissionRisk) + 1
+	}
+
+	// Keys will normally align to UTC day boundries.
+	utcDay := timeutils.UTCMidnight(g.endTime)
+
+	exposures := make([]*publishmodel.Exposure, 0, n)
+	for i := 0; i < n; i++ {
+		key := make([]byte, verifyapi.KeyLength)
+		g.r.Read(key)
+
+		days := g.r.Intn(*keyDays) + 1
+		exp := &publishmodel.Exposure{
+			ExposureKey:      key,
+			IntervalNumber:   publishmodel.IntervalNumber(utcDay.Add(time.Duration(-days) * 24 * time.Hour)),
+			IntervalCount:    verifyapi.MaxIntervalCount,
+			TransmissionRisk: g.tr,
+		}
+		g.decorate(exp)
+		exposures = append(exposures, exp)
+	}
+	return exposures
+}
+
+// decorate assigns a random report type, days since onset of symptoms, and
+// traveler status according to the flags.
+func (g *generator) decorate(exp *publishmodel.Exposure) {
+	if len(g.reportTypes) > 0 {
+		exp.ReportType = g.reportTypes[g.r.Intn(len(g.reportTypes))]
+	}
+	if g.r.Float64() < *onsetRate {
+		exp.SetDaysSinceSymptomOnset(g.onsetDays())
+	}
+	exp.Traveler = g.r.Float64() < *travelerRate
+}
+
+// revise marks the exposure as revised to the given report type.
+func (g *generator) revise(exp *publishmodel.Exposure, reportType string) {
+	revisedAt := g.endTime
+	exp.RevisedAt = &revisedAt
+	exp.RevisedReportType = &reportType
+	if exp.DaysSinceSymptomOnset != nil {
+		days := *exp.DaysSinceSymptomOnset
+		exp.RevisedDaysSinceSymptomOnset = &days
+	} else if g.r.Float64() < *onsetRate {
+		days := g.onsetDays()
+		exp.RevisedDaysSinceSymptomOnset = &days
+	}
+}
+
+func (g *generator) onsetDays() int32 {
+	return int32(g.r.Intn(2**symptomDays+1) - *symptomDays)
+}
+
+func withoutTravelers(exposures []*publishmodel.Exposure) []*publishmodel.Exposure {
+	result := make([]*publishmodel.Exposure, 0, len(exposures))
+	for _, exp := range exposures {
+		if !exp.Traveler {
+			result = append(result, exp)
+		}
+	}
+	return result
+}
+
+// batch returns the b-th slice of size elements.
+func batch(exposures []*publishmodel.Exposure, b, size int) []*publishmodel.Exposure {
+	start := b * size
+	if start >= len(exposures) {
+		return nil
+	}
+	end := start + size
+	if end > len(exposures) {
+		end = len(exposures)
+	}
+	return exposures[start:end]
+}
+
 type exportFileWriter struct {
 	exportBatch *model.ExportBatch
 	exposures   []*publishmodel.Exposure
@@ -193,19 +300,11 @@ type exportFileWriter struct {
 	curBatch    int32
 	numBatches  int
 	totalKeys   int
-	privateKey  *ecdsa.PrivateKey
+	signers     []*export.Signer
 }
 
 func (e *exportFileWriter) writeFile() {
-	signatureInfo := &model.SignatureInfo{
-		SigningKeyID:      *keyID,
-		SigningKeyVersion: *keyVersion,
-	}
-	signer := &export.Signer{
-		SignatureInfo: signatureInfo,
-		Signer:        e.privateKey,
-	}
-	data, err := export.MarshalExportFile(e.exportBatch, e.exposures, e.revisions, e.curBatch, e.numBatches > 1, []*export.Signer{signer})
+	data, err := export.MarshalExportFile(e.exportBatch, e.exposures, e.revisions, e.curBatch, e.numBatches > 1, e.signers)
 	if err != nil {
 		log.Fatalf("error marshaling export file: %v", err)
 	}
@@ -217,35 +316,49 @@ func (e *exportFileWriter) writeFile() {
 	}
 }
 
-func getSigningKey(fileName string) (*ecdsa.PrivateKey, error) {
-	keyBytes, _ := os.ReadFile(fileName)
-	return ParseECPrivateKeyFromPEM(keyBytes)
-}
-
-// ParseECPrivateKeyFromPEM parses PEM encoded Elliptic Curve Private Key
-// structure.
-func ParseECPrivateKeyFromPEM(key []byte) (*ecdsa.PrivateKey, error) {
-	ErrNotECPrivateKey := errors.New("key is not a valid ECDSA private key")
-	ErrKeyMustBePEMEncoded := errors.New("invalid Key: Key must be PEM encoded PKCS1 or PKCS8 private key")
-	var err error
-
-	// Parse PEM block.
-	var block *pem.Block
-	if block, _ = pem.Decode(key); block == nil {
-		return nil, ErrKeyMustBePEMEncoded
+// getSigners loads a signer for each comma-separated key path. Key IDs and
+// versions are matched by position, and a single ID or version applies to
+// every key.
+func getSigners(paths, ids, versions string) ([]*export.Signer, error) {
+	keyPaths := strings.Split(paths, ",")
+	keyIDs := strings.Split(ids, ",")
+	keyVersions := strings.Split(versions, ",")
+	if len(keyIDs) != 1 && len(keyIDs) != len(keyPaths) {
+		return nil, fmt.Errorf("got %d key ids for %d signing keys", len(keyIDs), len(keyPaths))
 	}
-
-	// Parse the key.
-	var parsedKey interface{}
-	if parsedKey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
-		return nil, err
+	if len(keyVersions) != 1 && len(keyVersions) != len(keyPaths) {
+		return nil, fmt.Errorf("got %d key versions for %d signing keys", len(keyVersions), len(keyPaths))
 	}
 
-	var pkey *ecdsa.PrivateKey
-	var ok bool
-	if pkey, ok = parsedKey.(*ecdsa.PrivateKey); !ok {
-		return nil, ErrNotECPrivateKey
+	signers := make([]*export.Signer, 0, len(keyPaths))
+	for i, pth := range keyPaths {
+		privateKey, err := getSigningKey(pth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pth, err)
+		}
+
+		signatureInfo := &model.SignatureInfo{
+			SigningKeyID:      keyIDs[0],
+			SigningKeyVersion: keyVersions[0],
+		}
+		if len(keyIDs) > 1 {
+			signatureInfo.SigningKeyID = keyIDs[i]
+		}
+		if len(keyVersions) > 1 {
+			signatureInfo.SigningKeyVersion = keyVersions[i]
+		}
+		signers = append(signers, &export.Signer{
+			SignatureInfo: signatureInfo,
+			Signer:        privateKey,
+		})
 	}
+	return signers, nil
+}
 
-	return pkey, nil
+func getSigningKey(fileName string) (*ecdsa.PrivateKey, error) {
+	keyBytes, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	return keys.ParseECDSAPrivateKey(string(keyBytes))
 }
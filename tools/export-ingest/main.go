@@ -0,0 +1,303 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool reads export files and inserts their keys into a database,
+// bypassing verification, so staging environments can be seeded with
+// realistic data volumes from production exports. The database is configured
+// with the usual DB_* environment variables.
+//
+// Keys are converted with the same transforms that export file based
+// federation uses. Primary keys are inserted and revised keys revise any
+// matching keys that were already ingested.
+//
+// Example:
+//
+//	DB_NAME=staging go run ./tools/export-ingest \
+//	  -file './exports/*.zip' -region US -shift -randomize-keys
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/buildinfo"
+	"github.com/google/exposure-notifications-server/internal/export"
+	exportpb "github.com/google/exposure-notifications-server/internal/pb/export"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+	"github.com/google/exposure-notifications-server/pkg/util"
+	"go.uber.org/zap"
+)
+
+var (
+	filePath          = flag.String("file", "", "path to the export files, supports file globs")
+	region            = flag.String("region", "US", "region to assign to the ingested keys")
+	traveler          = flag.Bool("traveler", false, "mark the ingested keys as travelers")
+	appPackageName    = flag.String("app-package-name", "export-ingest", "app package name to assign to the ingested keys")
+	defaultReportType = flag.String("default-report-type", verifyapi.ReportTypeConfirmed, "report type for keys that do not have one")
+	maxSymptomDays    = flag.Int("symptom-days", 14, "magnitude of the valid days since onset of symptoms range")
+	shift             = flag.Bool("shift", false, "shift key intervals by whole days so that the newest key starts yesterday, keeping old exports within the retention period")
+	randomizeKeys     = flag.Bool("randomize-keys", false, "replace key data with random bytes, consistently across files, so ingested keys cannot be matched to real devices")
+	batchSize         = flag.Int("batch-size", 500, "maximum number of keys to insert in one transaction")
+	truncateWindow    = flag.Duration("truncate-window", time.Hour, "created at truncation window")
+	dryRun            = flag.Bool("dry-run", false, "read and transform the files, but do not write to the database")
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger := logging.NewLoggerFromEnv().Named("tools.export-ingest")
+	logger = logger.With("build_id", buildinfo.BuildID)
+	logger = logger.With("build_tag", buildinfo.BuildTag)
+	ctx = logging.WithLogger(ctx, logger)
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func realMain(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	flag.Parse()
+	if *filePath == "" {
+		return fmt.Errorf("--file is required")
+	}
+	if *batchSize < 1 {
+		return fmt.Errorf("--batch-size must be at least 1")
+	}
+	if !verifyapi.ValidReportTypes[*defaultReportType] {
+		return fmt.Errorf("--default-report-type %q is not valid", *defaultReportType)
+	}
+
+	matches, err := filepath.Glob(*filePath)
+	if err != nil {
+		return fmt.Errorf("failed to expand matches: %w", err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("%q produced no matches (shell escaping?)", *filePath)
+	}
+
+	ing := &ingester{
+		logger:    logger,
+		batchTime: time.Now().UTC().Truncate(*truncateWindow),
+		keyMap:    make(map[string][]byte),
+	}
+
+	if *shift {
+		ing.shift, err = shiftIntervals(matches)
+		if err != nil {
+			return err
+		}
+		logger.Infow("shifting intervals", "days", ing.shift/verifyapi.MaxIntervalCount)
+	}
+
+	if !*dryRun {
+		var config database.Config
+		env, err := setup.Setup(ctx, &config)
+		if err != nil {
+			return fmt.Errorf("failed to setup database: %w", err)
+		}
+		defer env.Close(ctx)
+		ing.db = publishdb.New(env.Database())
+	}
+
+	for _, m := range matches {
+		if err := ing.ingestFile(ctx, m); err != nil {
+			return fmt.Errorf("%s: %w", m, err)
+		}
+	}
+
+	logger.Infow("ingest complete",
+		"files", len(matches),
+		"inserted", ing.inserted,
+		"revised", ing.revised,
+		"dropped", ing.dropped)
+	return nil
+}
+
+type ingester struct {
+	db        *publishdb.PublishDB
+	logger    *zap.SugaredLogger
+	batchTime time.Time
+	shift     int32
+
+	// keyMap maps original key data to random key data when keys are
+	// randomized.
+	keyMap map[string][]byte
+
+	inserted uint32
+	revised  uint32
+	dropped  uint32
+}
+
+// ingestFile inserts the primary keys and then the revised keys of a single
+// export file.
+func (i *ingester) ingestFile(ctx context.Context, pth string) error {
+	tekExport, err := readExport(pth)
+	if err != nil {
+		return err
+	}
+
+	importConfig := &publishmodel.ExportImportConfig{
+		DefaultReportType:   *defaultReportType,
+		MaxSymptomOnsetDays: int32(*maxSymptomDays),
+		AllowSelfReport:     true,
+		AllowClinical:       true,
+		AllowRevoked:        false,
+	}
+	primary, err := i.transform(tekExport.GetKeys(), importConfig)
+	if err != nil {
+		return err
+	}
+	if err := i.insert(ctx, primary, &publishdb.InsertAndReviseExposuresRequest{
+		SkipRevisions: true,
+	}); err != nil {
+		return fmt.Errorf("failed to insert keys: %w", err)
+	}
+
+	// Revised keys can transition to revoked, but not to self report or
+	// clinical.
+	importConfig.AllowSelfReport = false
+	importConfig.AllowClinical = false
+	importConfig.AllowRevoked = true
+	revisions, err := i.transform(tekExport.GetRevisedKeys(), importConfig)
+	if err != nil {
+		return err
+	}
+	if err := i.insert(ctx, revisions, &publishdb.InsertAndReviseExposuresRequest{
+		OnlyRevisions: true,
+	}); err != nil {
+		return fmt.Errorf("failed to revise keys: %w", err)
+	}
+
+	i.logger.Infow("ingested file", "file", pth, "keys", len(primary), "revised_keys", len(revisions))
+	return nil
+}
+
+func (i *ingester) transform(keys []*exportpb.TemporaryExposureKey, config *publishmodel.ExportImportConfig) ([]*publishmodel.Exposure, error) {
+	exposures := make([]*publishmodel.Exposure, 0, len(keys))
+	for _, k := range keys {
+		exp, err := publishmodel.FromExportKey(k, config)
+		if err != nil {
+			i.logger.Warnw("skipping invalid key", "error", err)
+			i.dropped++
+			continue
+		}
+
+		if *randomizeKeys {
+			random, ok := i.keyMap[string(exp.ExposureKey)]
+			if !ok {
+				random, err = util.RandomTEK()
+				if err != nil {
+					return nil, fmt.Errorf("failed to generate key: %w", err)
+				}
+				i.keyMap[string(exp.ExposureKey)] = random
+			}
+			exp.ExposureKey = random
+		}
+
+		exp.IntervalNumber += i.shift
+		exp.AppPackageName = *appPackageName
+		exp.Regions = []string{*region}
+		exp.Traveler = *traveler
+		exp.CreatedAt = i.batchTime
+		exp.LocalProvenance = false
+		exp.ProvenanceSource = publishmodel.ProvenanceExportImport
+
+		// Adjust created at time, if this key is not yet expired.
+		if expTime := publishmodel.TimeForIntervalNumber(exp.IntervalNumber + exp.IntervalCount); exp.CreatedAt.Before(expTime) {
+			exp.CreatedAt = expTime.UTC().Add(*truncateWindow).Truncate(*truncateWindow)
+		}
+
+		exposures = append(exposures, exp)
+	}
+	return exposures, nil
+}
+
+// insert writes the exposures in batches, using the template for the options.
+func (i *ingester) insert(ctx context.Context, exposures []*publishmodel.Exposure, template *publishdb.InsertAndReviseExposuresRequest) error {
+	if i.db == nil {
+		return nil
+	}
+
+	for start := 0; start < len(exposures); start += *batchSize {
+		end := start + *batchSize
+		if end > len(exposures) {
+			end = len(exposures)
+		}
+		template.Incoming = exposures[start:end]
+
+		resp, err := i.db.InsertAndReviseExposures(ctx, template)
+		if err != nil {
+			return fmt.Errorf("publishDB.InsertAndReviseExposures: %w", err)
+		}
+		i.inserted += resp.Inserted
+		i.revised += resp.Revised
+		i.dropped += resp.Dropped
+	}
+	return nil
+}
+
+// shiftIntervals returns the whole number of days, in intervals, to add to
+// every key so that the newest key in the files starts yesterday.
+func shiftIntervals(matches []string) (int32, error) {
+	var newest int32
+	for _, m := range matches {
+		tekExport, err := readExport(m)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", m, err)
+		}
+		for _, keys := range [][]*exportpb.TemporaryExposureKey{tekExport.GetKeys(), tekExport.GetRevisedKeys()} {
+			for _, k := range keys {
+				if s := k.GetRollingStartIntervalNumber(); s > newest {
+					newest = s
+				}
+			}
+		}
+	}
+	if newest == 0 {
+		return 0, nil
+	}
+
+	yesterday := publishmodel.IntervalNumber(timeutils.UTCMidnight(time.Now()).Add(-24 * time.Hour))
+	days := (yesterday - newest) / verifyapi.MaxIntervalCount
+	return days * verifyapi.MaxIntervalCount, nil
+}
+
+func readExport(pth string) (*exportpb.TemporaryExposureKeyExport, error) {
+	blob, err := os.ReadFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export file: %w", err)
+	}
+	tekExport, _, err := export.UnmarshalExportFile(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal export file: %w", err)
+	}
+	return tekExport, nil
+}
@@ -13,6 +13,21 @@
 // limitations under the License.
 
 // This package is a CLI tool for generating test exposure key data.
+//
+// By default the keys are published without a verification certificate. To
+// exercise the full certificate path, either point the tool at a verification
+// server:
+//
+//	go run ./tools/exposure-client \
+//	  -verification-url https://apiserver.example.com \
+//	  -verification-admin-url https://adminapi.example.com \
+//	  -api-key <device API key> -admin-api-key <admin API key>
+//
+// or run an in-process mock verification server that signs certificates with
+// a local key, whose public key is registered for the health authority:
+//
+//	go run ./tools/exposure-client -mock-signing-key ./ha-private.pem \
+//	  -issuer doh.example.gov -audience ens.example.com
 package main
 
 import (
@@ -24,6 +39,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"os/signal"
 	"strings"
 	"syscall"
@@ -32,7 +48,9 @@ import (
 	"github.com/google/exposure-notifications-server/internal/buildinfo"
 	"github.com/google/exposure-notifications-server/internal/project"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/keys"
 	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
 	"github.com/google/exposure-notifications-server/pkg/util"
 )
 
@@ -42,6 +60,18 @@ var (
 	twice                = flag.Bool("twice", false, "send the same request twice w/ delay")
 	healthAuthority      = flag.String("ha", "Dept Of Health", "Health Authority ID to use in request")
 	transmissionRiskFlag = flag.Int("transmissionRisk", -1, "Transmission risk")
+
+	verificationURL      = flag.String("verification-url", "", "base URL of the verification server device API, will add /api/verify and /api/certificate; if blank, keys are published without a certificate")
+	verificationAdminURL = flag.String("verification-admin-url", "", "base URL of the verification server admin API, will add /api/issue; defaults to -verification-url")
+	apiKey               = flag.String("api-key", "", "device API key for the verification server")
+	adminAPIKey          = flag.String("admin-api-key", "", "admin API key for the verification server, used to issue codes")
+	testType             = flag.String("test-type", verifyapi.ReportTypeConfirmed, "test type of the issued verification code (confirmed|likely|negative|user-report)")
+	symptomDaysAgo       = flag.Int("symptom-days-ago", 3, "days before today of the symptom onset, negative to omit")
+
+	mockSigningKey = flag.String("mock-signing-key", "", "path to a PEM encoded ECDSA P256 private key; if set, certificates are signed by an in-process mock verification server and -verification-url is ignored")
+	keyVersion     = flag.String("key-version", "v1", "health authority key version used by the mock, sent as the JWT kid header")
+	issuer         = flag.String("issuer", "", "issuer of the certificates signed by the mock")
+	audience       = flag.String("audience", "", "audience of the certificates signed by the mock")
 )
 
 func main() {
@@ -88,6 +118,26 @@ func realMain(ctx context.Context) error {
 		Padding:           base64.RawStdEncoding.EncodeToString(padding),
 	}
 
+	client, closer, err := newVerificationClient()
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	if client != nil {
+		var symptomDate time.Time
+		if *symptomDaysAgo >= 0 {
+			symptomDate = timeutils.UTCMidnight(time.Now()).AddDate(0, 0, -*symptomDaysAgo)
+		}
+
+		cert, hmacKey, err := client.certify(ctx, exposureKeys, *testType, symptomDate)
+		if err != nil {
+			return fmt.Errorf("failed to obtain verification certificate: %w", err)
+		}
+		data.VerificationPayload = cert
+		data.HMACKey = hmacKey
+	}
+
 	body, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to generate JSON: %w", err)
@@ -108,6 +158,47 @@ func realMain(ctx context.Context) error {
 	return nil
 }
 
+// newVerificationClient returns a client for the configured verification
+// server, or nil if none is configured. The returned function releases any
+// resources held by the client.
+func newVerificationClient() (*verificationClient, func(), error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	if *mockSigningKey != "" {
+		b, err := os.ReadFile(*mockSigningKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read mock signing key: %w", err)
+		}
+		signer, err := keys.ParseECDSAPrivateKey(string(b))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse mock signing key: %w", err)
+		}
+
+		srv := newMockVerificationServer(signer, *keyVersion, *issuer, *audience)
+		return &verificationClient{
+			client:   httpClient,
+			apiURL:   srv.URL,
+			adminURL: srv.URL,
+		}, srv.Close, nil
+	}
+
+	if *verificationURL == "" {
+		return nil, func() {}, nil
+	}
+
+	adminURL := *verificationAdminURL
+	if adminURL == "" {
+		adminURL = *verificationURL
+	}
+	return &verificationClient{
+		client:      httpClient,
+		apiURL:      strings.TrimSuffix(*verificationURL, "/"),
+		adminURL:    strings.TrimSuffix(adminURL, "/"),
+		apiKey:      *apiKey,
+		adminAPIKey: *adminAPIKey,
+	}, func() {}, nil
+}
+
 func sendRequest(ctx context.Context, data io.Reader) ([]byte, error) {
 	url := strings.ReplaceAll(*host+"/v1/publish", "//v1", "/v1")
 	req, err := http.NewRequestWithContext(ctx, "POST", url, data)
@@ -0,0 +1,214 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+// mockVerificationServer is a minimal, in-process stand in for a
+// verification server. It issues single use codes and tokens and signs
+// certificates with a local key, so that the full certificate path of the
+// publish API can be exercised without a deployed verification server. The
+// public half of the signing key must be registered as a health authority
+// key on the target server.
+type mockVerificationServer struct {
+	signer     *ecdsa.PrivateKey
+	keyVersion string
+	issuer     string
+	audience   string
+
+	mu     sync.Mutex
+	codes  map[string]*mockClaims
+	tokens map[string]*mockClaims
+}
+
+type mockClaims struct {
+	testType    string
+	symptomDate time.Time
+}
+
+func newMockVerificationServer(signer *ecdsa.PrivateKey, keyVersion, issuer, audience string) *httptest.Server {
+	m := &mockVerificationServer{
+		signer:     signer,
+		keyVersion: keyVersion,
+		issuer:     issuer,
+		audience:   audience,
+		codes:      make(map[string]*mockClaims),
+		tokens:     make(map[string]*mockClaims),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/issue", m.handleIssue)
+	mux.HandleFunc("/api/verify", m.handleVerify)
+	mux.HandleFunc("/api/certificate", m.handleCertificate)
+	return httptest.NewServer(mux)
+}
+
+func (m *mockVerificationServer) handleIssue(w http.ResponseWriter, r *http.Request) {
+	var req issueCodeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if !verifyapi.ValidReportTypes[req.TestType] {
+		respond(w, http.StatusBadRequest, &issueCodeResponse{Error: "invalid test type", ErrorCode: "invalid_test_type"})
+		return
+	}
+
+	claims := &mockClaims{testType: req.TestType}
+	if req.SymptomDate != "" {
+		t, err := time.Parse(dateFormat, req.SymptomDate)
+		if err != nil {
+			respond(w, http.StatusBadRequest, &issueCodeResponse{Error: err.Error(), ErrorCode: "invalid_date"})
+			return
+		}
+		claims.symptomDate = t
+	}
+
+	code, err := m.store(m.codes, claims)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, &issueCodeResponse{Error: err.Error()})
+		return
+	}
+	respond(w, http.StatusOK, &issueCodeResponse{Code: code})
+}
+
+func (m *mockVerificationServer) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var req verifyCodeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	claims := m.take(m.codes, req.Code)
+	if claims == nil {
+		respond(w, http.StatusBadRequest, &verifyCodeResponse{Error: "verification code invalid", ErrorCode: "code_invalid"})
+		return
+	}
+	if len(req.AcceptTestTypes) > 0 && !contains(req.AcceptTestTypes, claims.testType) {
+		respond(w, http.StatusPreconditionFailed, &verifyCodeResponse{Error: "test type not accepted", ErrorCode: "unsupported_test_type"})
+		return
+	}
+
+	token, err := m.store(m.tokens, claims)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, &verifyCodeResponse{Error: err.Error()})
+		return
+	}
+
+	resp := &verifyCodeResponse{TestType: claims.testType, VerificationToken: token}
+	if !claims.symptomDate.IsZero() {
+		resp.SymptomDate = claims.symptomDate.Format(dateFormat)
+	}
+	respond(w, http.StatusOK, resp)
+}
+
+func (m *mockVerificationServer) handleCertificate(w http.ResponseWriter, r *http.Request) {
+	var req certificateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	claims := m.take(m.tokens, req.VerificationToken)
+	if claims == nil {
+		respond(w, http.StatusBadRequest, &certificateResponse{Error: "verification token invalid", ErrorCode: "token_invalid"})
+		return
+	}
+	if req.ExposureKeyHMAC == "" {
+		respond(w, http.StatusBadRequest, &certificateResponse{Error: "missing ekeyhmac", ErrorCode: "hmac_invalid"})
+		return
+	}
+
+	now := time.Now().UTC()
+	certClaims := verifyapi.NewVerificationClaims()
+	certClaims.Audience = m.audience
+	certClaims.Issuer = m.issuer
+	certClaims.IssuedAt = now.Unix()
+	certClaims.NotBefore = now.Add(-1 * time.Second).Unix()
+	certClaims.ExpiresAt = now.Add(5 * time.Minute).Unix()
+	certClaims.ReportType = claims.testType
+	certClaims.SignedMAC = req.ExposureKeyHMAC
+	if !claims.symptomDate.IsZero() {
+		certClaims.SymptomOnsetInterval = uint32(model.IntervalNumber(claims.symptomDate))
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, certClaims)
+	token.Header[verifyapi.KeyIDHeader] = m.keyVersion
+	signed, err := token.SignedString(m.signer)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, &certificateResponse{Error: err.Error()})
+		return
+	}
+	respond(w, http.StatusOK, &certificateResponse{Certificate: signed})
+}
+
+// store saves the claims under a new random value and returns it.
+func (m *mockVerificationServer) store(into map[string]*mockClaims, claims *mockClaims) (string, error) {
+	v, err := project.RandomBase64String(16)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	into[v] = claims
+	return v, nil
+}
+
+// take removes and returns the claims saved under v, or nil if there are
+// none. Codes and tokens are single use.
+func (m *mockVerificationServer) take(from map[string]*mockClaims, v string) *mockClaims {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claims := from[v]
+	delete(from, v)
+	return claims
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/verification"
+)
+
+const dateFormat = "2006-01-02"
+
+// The request and response types below mirror the subset of the verification
+// server API that a device uses to obtain a verification certificate. The
+// full API is defined in
+// https://github.com/google/exposure-notifications-verification-server/
+
+type issueCodeRequest struct {
+	TestType    string `json:"testType"`
+	SymptomDate string `json:"symptomDate,omitempty"`
+}
+
+type issueCodeResponse struct {
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"errorCode"`
+}
+
+type verifyCodeRequest struct {
+	Code            string   `json:"code"`
+	AcceptTestTypes []string `json:"accept,omitempty"`
+}
+
+type verifyCodeResponse struct {
+	TestType          string `json:"testtype"`
+	SymptomDate       string `json:"symptomDate"`
+	VerificationToken string `json:"token"`
+	Error             string `json:"error"`
+	ErrorCode         string `json:"errorCode"`
+}
+
+type certificateRequest struct {
+	VerificationToken string `json:"token"`
+	ExposureKeyHMAC   string `json:"ekeyhmac"`
+}
+
+type certificateResponse struct {
+	Certificate string `json:"certificate"`
+	Error       string `json:"error"`
+	ErrorCode   string `json:"errorCode"`
+}
+
+// verificationClient walks the verification code, token and certificate
+// exchange against a verification server, the same way a device would.
+type verificationClient struct {
+	client *http.Client
+
+	// apiURL is the base URL of the device facing API, which serves
+	// /api/verify and /api/certificate. adminURL is the base URL of the admin
+	// API, which serves /api/issue.
+	apiURL   string
+	adminURL string
+
+	apiKey      string
+	adminAPIKey string
+}
+
+// certify obtains a verification certificate for the given keys. It returns
+// the certificate and the base64 encoded HMAC key to use in the publish
+// request.
+func (c *verificationClient) certify(ctx context.Context, keys []verifyapi.ExposureKey, testType string, symptomDate time.Time) (string, string, error) {
+	code, err := c.issue(ctx, testType, symptomDate)
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := c.verify(ctx, code, testType)
+	if err != nil {
+		return "", "", err
+	}
+
+	hmacKey, err := project.RandomBytes(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate hmac key: %w", err)
+	}
+	hmac, err := verification.CalculateExposureKeyHMAC(keys, hmacKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to calculate hmac: %w", err)
+	}
+
+	cert, err := c.certificate(ctx, token, base64.StdEncoding.EncodeToString(hmac))
+	if err != nil {
+		return "", "", err
+	}
+	return cert, base64.StdEncoding.EncodeToString(hmacKey), nil
+}
+
+func (c *verificationClient) issue(ctx context.Context, testType string, symptomDate time.Time) (string, error) {
+	req := &issueCodeRequest{TestType: testType}
+	if !symptomDate.IsZero() {
+		req.SymptomDate = symptomDate.Format(dateFormat)
+	}
+
+	var resp issueCodeResponse
+	if err := c.post(ctx, c.adminURL+"/api/issue", c.adminAPIKey, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to issue code: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("failed to issue code: %s: %s", resp.ErrorCode, resp.Error)
+	}
+	return resp.Code, nil
+}
+
+func (c *verificationClient) verify(ctx context.Context, code, testType string) (string, error) {
+	req := &verifyCodeRequest{Code: code, AcceptTestTypes: []string{testType}}
+
+	var resp verifyCodeResponse
+	if err := c.post(ctx, c.apiURL+"/api/verify", c.apiKey, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to verify code: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("failed to verify code: %s: %s", resp.ErrorCode, resp.Error)
+	}
+	return resp.VerificationToken, nil
+}
+
+func (c *verificationClient) certificate(ctx context.Context, token, hmac string) (string, error) {
+	req := &certificateRequest{VerificationToken: token, ExposureKeyHMAC: hmac}
+
+	var resp certificateResponse
+	if err := c.post(ctx, c.apiURL+"/api/certificate", c.apiKey, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to get certificate: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("failed to get certificate: %s: %s", resp.ErrorCode, resp.Error)
+	}
+	return resp.Certificate, nil
+}
+
+// post sends the JSON encoded input to the url and decodes the response into
+// out.
+func (c *verificationClient) post(ctx context.Context, url, apiKey string, in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status %v, body: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -36,20 +36,29 @@ const (
 var (
 	testRegions = []string{"TEST", "PROBE"}
 
+	issuer   = flag.String("issuer", defaultIssuer, "The OIDC issuer. Signing keys are located through OIDC discovery on the issuer unless -jwks-uri is set.")
 	subject  = flag.String("subject", "", "(Required) The OIDC subject (for issuer https://accounts.google.com, this is the obfuscated Gaia ID.)")
+	jwksURI  = flag.String("jwks-uri", "", "The URI of the issuer's JWKS, if it does not support OIDC discovery.")
 	audience = flag.String("audience", federationin.DefaultAudience, "The OIDC audience; leaving this blank will cause server to not enforce the audience claim.")
 	note     = flag.String("note", "", "An open text note to include on the record.")
+	testData = flag.Bool("test-data", false, "Serve the client only test data, e.g. for integration testing with a partner.")
 )
 
 func main() {
 	var includeRegions, excludeRegions cflag.RegionListVar
 	flag.Var(&includeRegions, "regions", "A comma-separated list of regions to query. Leave blank for all regions.")
 	flag.Var(&excludeRegions, "exclude-regions", "A comma-separated list fo regions to exclude from the query.")
+	var includeJurisdictions, excludeJurisdictions cflag.RegionListVar
+	flag.Var(&includeJurisdictions, "jurisdictions", "A comma-separated list of health authority jurisdictions the client may read. Leave blank for all jurisdictions.")
+	flag.Var(&excludeJurisdictions, "exclude-jurisdictions", "A comma-separated list of health authority jurisdictions the client may not read.")
 	flag.Parse()
 
 	if *subject == "" {
 		log.Fatalf("--subject is required")
 	}
+	if *issuer == "" {
+		log.Fatalf("--issuer must not be blank")
+	}
 
 	// Issue warnings about missing test regions in excludeRegions.
 	var missingTestRegions []string
@@ -80,12 +89,18 @@ func main() {
 	db := database.New(env.Database())
 
 	auth := &model.FederationOutAuthorization{
-		Issuer:         defaultIssuer, // Authorization interceptor currently only supports defaultIssuer.
+		Issuer:         *issuer,
 		Subject:        *subject,
 		Audience:       *audience,
 		Note:           *note,
 		IncludeRegions: includeRegions,
 		ExcludeRegions: excludeRegions,
+
+		IncludeJurisdictions: includeJurisdictions,
+		ExcludeJurisdictions: excludeJurisdictions,
+
+		JWKSURI:  *jwksURI,
+		TestData: *testData,
 	}
 
 	if err := db.AddFederationOutAuthorization(ctx, auth); err != nil {
@@ -0,0 +1,523 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package is a load testing harness for the publish API. It sends publish
+// requests at a configurable, ramping rate with realistic key counts, and
+// optionally mints verification certificates from a test health authority
+// signing key and exercises the revision flow. Latency percentiles are
+// reported at the end of the run.
+//
+// Example:
+//
+//	go run ./tools/loadgen \
+//	  -host http://localhost:8080 \
+//	  -ha com.example.app \
+//	  -signing-key ./ha-private.pem -issuer doh.example.gov -audience ens.example.com \
+//	  -qps-start 1 -qps-end 50 -ramp 2m -duration 10m -revise-rate 0.2
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/buildinfo"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+	"github.com/google/exposure-notifications-server/pkg/util"
+	"github.com/google/exposure-notifications-server/pkg/verification"
+)
+
+var (
+	host            = flag.String("host", "http://localhost:8080", "http(s) destination of the publish server, will add /v1/publish")
+	healthAuthority = flag.String("ha", "Dept Of Health", "Health Authority ID to use in requests")
+
+	signingKeyPath = flag.String("signing-key", "", "path to a PEM encoded ECDSA P256 private key of a test health authority, if blank no verification certificates are sent")
+	keyVersion     = flag.String("key-version", "v1", "health authority key version, sent as the JWT kid header")
+	issuer         = flag.String("issuer", "", "issuer of the minted verification certificates")
+	audience       = flag.String("audience", "", "audience of the minted verification certificates")
+
+	qpsStart    = flag.Float64("qps-start", 1, "requests per second at the start of the run")
+	qpsEnd      = flag.Float64("qps-end", 10, "requests per second at the end of the ramp")
+	ramp        = flag.Duration("ramp", time.Minute, "time to ramp from -qps-start to -qps-end")
+	duration    = flag.Duration("duration", 5*time.Minute, "total duration of the run, including the ramp")
+	concurrency = flag.Int("concurrency", 50, "maximum number of in-flight requests")
+	timeout     = flag.Duration("timeout", 10*time.Second, "timeout for each request")
+
+	profileName = flag.String("profile", "typical", "key count distribution, one of: "+strings.Join(profileNames(), ", "))
+	reviseRate  = flag.Float64("revise-rate", 0, "fraction of successful uploads that are later revised from likely to confirmed")
+	reviseDelay = flag.Duration("revise-delay", 5*time.Second, "delay between an upload and its revision")
+)
+
+// keyCountBucket is a weighted range of key counts.
+type keyCountBucket struct {
+	weight   int
+	min, max int
+}
+
+// profiles are key count distributions. The "typical" profile reflects that
+// most uploads contain the full 14 day key history, with a tail of devices
+// that were only recently enabled.
+var profiles = map[string][]keyCountBucket{
+	"typical": {
+		{weight: 60, min: 14, max: 14},
+		{weight: 25, min: 7, max: 13},
+		{weight: 15, min: 1, max: 6},
+	},
+	"full": {
+		{weight: 100, min: 14, max: 14},
+	},
+	"sparse": {
+		{weight: 50, min: 1, max: 3},
+		{weight: 50, min: 4, max: 8},
+	},
+}
+
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for k := range profiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	logger := logging.NewLoggerFromEnv().
+		With("build_id", buildinfo.BuildID).
+		With("build_tag", buildinfo.BuildTag)
+	ctx = logging.WithLogger(ctx, logger)
+
+	defer func() {
+		done()
+		if r := recover(); r != nil {
+			logger.Fatalw("application panic", "panic", r)
+		}
+	}()
+
+	err := realMain(ctx)
+	done()
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func realMain(ctx context.Context) error {
+	flag.Parse()
+	logger := logging.FromContext(ctx)
+
+	profile, ok := profiles[*profileName]
+	if !ok {
+		return fmt.Errorf("unknown profile %q, must be one of: %s", *profileName, strings.Join(profileNames(), ", "))
+	}
+	if *qpsStart <= 0 || *qpsEnd <= 0 {
+		return fmt.Errorf("-qps-start and -qps-end must be > 0")
+	}
+	if *concurrency <= 0 {
+		return fmt.Errorf("-concurrency must be > 0")
+	}
+
+	var signer *ecdsa.PrivateKey
+	if *signingKeyPath != "" {
+		b, err := os.ReadFile(*signingKeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read signing key: %w", err)
+		}
+		signer, err = keys.ParseECDSAPrivateKey(string(b))
+		if err != nil {
+			return fmt.Errorf("failed to parse signing key: %w", err)
+		}
+	}
+
+	g := &generator{
+		client:  &http.Client{Timeout: *timeout},
+		url:     strings.ReplaceAll(*host+"/v1/publish", "//v1", "/v1"),
+		signer:  signer,
+		profile: profile,
+		results: newResults(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	logger.Infow("starting load",
+		"url", g.url,
+		"qps_start", *qpsStart,
+		"qps_end", *qpsEnd,
+		"ramp", *ramp,
+		"duration", *duration,
+		"profile", *profileName)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, *concurrency)
+	start := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			g.results.report(os.Stdout, time.Since(start))
+			return nil
+		default:
+		}
+
+		select {
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				g.upload(ctx, &wg)
+			}()
+		default:
+			// All workers are busy, the server cannot keep up with the target rate.
+			g.results.skip()
+		}
+
+		sleep := time.Duration(float64(time.Second) / currentQPS(time.Since(start)))
+		select {
+		case <-ctx.Done():
+		case <-time.After(sleep):
+		}
+	}
+}
+
+// currentQPS returns the target rate at the given point in the run.
+func currentQPS(elapsed time.Duration) float64 {
+	if *ramp <= 0 || elapsed >= *ramp {
+		return *qpsEnd
+	}
+	progress := float64(elapsed) / float64(*ramp)
+	return *qpsStart + (*qpsEnd-*qpsStart)*progress
+}
+
+type generator struct {
+	client  *http.Client
+	url     string
+	signer  *ecdsa.PrivateKey
+	profile []keyCountBucket
+	results *results
+}
+
+// upload sends a single publish request and, if selected, schedules the
+// revision of the same keys.
+func (g *generator) upload(ctx context.Context, wg *sync.WaitGroup) {
+	keys, err := g.keys()
+	if err != nil {
+		g.results.record("publish", 0, err)
+		return
+	}
+
+	n, err := util.RandomInt(10000)
+	if err != nil {
+		g.results.record("publish", 0, err)
+		return
+	}
+	revise := float64(n) < *reviseRate*10000
+	reportType := verifyapi.ReportTypeConfirmed
+	if revise {
+		reportType = verifyapi.ReportTypeClinical
+	}
+
+	resp, err := g.publish(ctx, "publish", keys, reportType, "")
+	if err != nil || !revise {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*reviseDelay):
+		}
+		_, _ = g.publish(ctx, "revise", keys, verifyapi.ReportTypeConfirmed, resp.RevisionToken)
+	}()
+}
+
+// keys generates TEKs for the previous days, with a count drawn from the
+// profile.
+func (g *generator) keys() ([]verifyapi.ExposureKey, error) {
+	n, err := g.keyCount()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]verifyapi.ExposureKey, 0, n)
+
+	midnight := timeutils.UTCMidnight(time.Now())
+	for i := 1; i <= n; i++ {
+		interval := model.IntervalNumber(midnight.Add(time.Duration(-i) * 24 * time.Hour))
+		key, err := util.RandomExposureKey(interval, verifyapi.MaxIntervalCount, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// keyCount picks a key count from the weighted profile.
+func (g *generator) keyCount() (int, error) {
+	total := 0
+	for _, b := range g.profile {
+		total += b.weight
+	}
+
+	n, err := util.RandomInt(total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pick key count: %w", err)
+	}
+	for _, b := range g.profile {
+		if n < b.weight {
+			offset, err := util.RandomInt(b.max - b.min + 1)
+			if err != nil {
+				return 0, fmt.Errorf("failed to pick key count: %w", err)
+			}
+			return b.min + offset, nil
+		}
+		n -= b.weight
+	}
+	return g.profile[len(g.profile)-1].max, nil
+}
+
+// publish sends a publish request for the keys and records the latency under
+// the given operation.
+func (g *generator) publish(ctx context.Context, op string, keys []verifyapi.ExposureKey, reportType, revisionToken string) (*verifyapi.PublishResponse, error) {
+	i, err := util.RandomInt(1024)
+	if err != nil {
+		g.results.record(op, 0, err)
+		return nil, err
+	}
+	padding, err := project.RandomBytes(i + 1024)
+	if err != nil {
+		g.results.record(op, 0, err)
+		return nil, err
+	}
+
+	data := verifyapi.Publish{
+		Keys:              keys,
+		HealthAuthorityID: *healthAuthority,
+		RevisionToken:     revisionToken,
+		Padding:           base64.RawStdEncoding.EncodeToString(padding),
+	}
+	if g.signer != nil {
+		data.VerificationPayload, data.HMACKey, err = g.certificate(keys, reportType)
+		if err != nil {
+			g.results.record(op, 0, err)
+			return nil, err
+		}
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		g.results.record(op, 0, err)
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		g.results.record(op, 0, err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	httpResp, err := g.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			g.results.record(op, time.Since(start), err)
+		}
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	latency := time.Since(start)
+	if err != nil {
+		g.results.record(op, latency, err)
+		return nil, err
+	}
+
+	var resp verifyapi.PublishResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		err = fmt.Errorf("status %d: failed to parse response: %w", httpResp.StatusCode, err)
+		g.results.record(op, latency, err)
+		return nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("status %d: %s", httpResp.StatusCode, resp.Code)
+		g.results.record(op, latency, err)
+		return nil, err
+	}
+
+	g.results.record(op, latency, nil)
+	return &resp, nil
+}
+
+// certificate mints a verification certificate for the keys, as a
+// verification server would, and returns it with the HMAC key.
+func (g *generator) certificate(keys []verifyapi.ExposureKey, reportType string) (string, string, error) {
+	hmacKey := make([]byte, 32)
+	if _, err := rand.Read(hmacKey); err != nil {
+		return "", "", fmt.Errorf("failed to generate hmac key: %w", err)
+	}
+	hmac, err := verification.CalculateExposureKeyHMAC(keys, hmacKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to calculate hmac: %w", err)
+	}
+
+	now := time.Now().UTC()
+	claims := verifyapi.NewVerificationClaims()
+	claims.Audience = *audience
+	claims.Issuer = *issuer
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Add(-1 * time.Second).Unix()
+	claims.ExpiresAt = now.Add(5 * time.Minute).Unix()
+	claims.ReportType = reportType
+	claims.SymptomOnsetInterval = uint32(model.IntervalNumber(timeutils.UTCMidnight(now).Add(-72 * time.Hour)))
+	claims.SignedMAC = base64.StdEncoding.EncodeToString(hmac)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header[verifyapi.KeyIDHeader] = *keyVersion
+	signed, err := token.SignedString(g.signer)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	return signed, base64.StdEncoding.EncodeToString(hmacKey), nil
+}
+
+// results collects the outcome of each request.
+type results struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]map[string]int
+	skipped   int
+}
+
+func newResults() *results {
+	return &results{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]map[string]int),
+	}
+}
+
+func (r *results) record(op string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if latency > 0 {
+		r.latencies[op] = append(r.latencies[op], latency)
+	}
+	if err != nil {
+		if r.errors[op] == nil {
+			r.errors[op] = make(map[string]int)
+		}
+		msg := err.Error()
+		var urlErr interface{ Timeout() bool }
+		if errors.As(err, &urlErr) && urlErr.Timeout() {
+			msg = "timeout"
+		}
+		r.errors[op][msg]++
+	}
+}
+
+func (r *results) skip() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped++
+}
+
+// report writes a summary of the run, including latency percentiles for each
+// operation.
+func (r *results) report(w io.Writer, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Fprintf(w, "duration: %s\n", elapsed.Round(time.Millisecond))
+	if r.skipped > 0 {
+		fmt.Fprintf(w, "skipped (concurrency limit reached): %d\n", r.skipped)
+	}
+
+	ops := make([]string, 0, len(r.latencies))
+	for op := range r.latencies {
+		ops = append(ops, op)
+	}
+	for op := range r.errors {
+		if _, ok := r.latencies[op]; !ok {
+			ops = append(ops, op)
+		}
+	}
+	sort.Strings(ops)
+
+	for _, op := range ops {
+		latencies := r.latencies[op]
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		failed := 0
+		for _, n := range r.errors[op] {
+			failed += n
+		}
+
+		fmt.Fprintf(w, "\n%s: %d requests, %d failed, %.2f qps\n",
+			op, len(latencies), failed, float64(len(latencies))/elapsed.Seconds())
+		if len(latencies) > 0 {
+			fmt.Fprintf(w, "  p50=%s p90=%s p95=%s p99=%s max=%s\n",
+				percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 95),
+				percentile(latencies, 99), latencies[len(latencies)-1])
+		}
+
+		msgs := make([]string, 0, len(r.errors[op]))
+		for msg := range r.errors[op] {
+			msgs = append(msgs, msg)
+		}
+		sort.Strings(msgs)
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "  %6d  %s\n", r.errors[op][msg], msg)
+		}
+	}
+}
+
+// percentile returns the p-th percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx].Round(time.Microsecond)
+}
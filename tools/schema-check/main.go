@@ -0,0 +1,63 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package compares the live database schema with the migrations and
+// prints the differences. It exits non-zero if services are expected to fail
+// against the live schema, or with --strict on any difference.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/migrations"
+	"github.com/google/exposure-notifications-server/pkg/database"
+)
+
+var (
+	pathFlag = flag.String("path", "", "Path to a migrations folder. Defaults to the migrations built into this tool.")
+	strict   = flag.Bool("strict", false, "Exit non-zero on any difference, including unexpected columns.")
+)
+
+func main() {
+	flag.Parse()
+
+	var fsys fs.FS = migrations.FS
+	if *pathFlag != "" {
+		fsys = os.DirFS(*pathFlag)
+	}
+
+	ctx := context.Background()
+	config := database.Config{SchemaCheck: database.SchemaCheckOff}
+	env, err := setup.Setup(ctx, &config)
+	if err != nil {
+		log.Fatalf("failed to setup: %v", err)
+	}
+	defer env.Close(ctx)
+
+	drift, err := env.Database().CheckSchema(ctx, fsys)
+	if err != nil {
+		log.Fatalf("Failure: %v", err)
+	}
+
+	fmt.Print(drift)
+	if drift.Failed() || (*strict && !drift.Empty()) {
+		os.Exit(1)
+	}
+}
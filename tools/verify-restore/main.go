@@ -0,0 +1,392 @@
+// Copyright 2026 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package checks a restored copy of the database, for example during a
+// disaster recovery drill. It validates referential integrity, compares the
+// stored keys with the recorded health authority stats, and checks that the
+// keys of a sample export batch can be reproduced from the restored keys.
+//
+// It exits non-zero when any check fails.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export"
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	publishdatabase "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/setup"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	batchID    = flag.Int64("batch-id", 0, "The export batch to reproduce, or 0 for the latest complete batch.")
+	statsDays  = flag.Int("stats-days", 7, "The number of full UTC days of stats to compare with the stored keys.")
+	tolerance  = flag.Float64("tolerance", 0.05, "The allowed relative difference between recorded stats and stored keys.")
+	skipExport = flag.Bool("skip-export", false, "Skip the export reproducibility check, e.g. when the blobstore is not reachable.")
+)
+
+// Config is the configuration for the restore verification.
+type Config struct {
+	Database database.Config
+	Storage  storage.Config
+}
+
+var (
+	_ setup.DatabaseConfigProvider  = (*Config)(nil)
+	_ setup.BlobstoreConfigProvider = (*Config)(nil)
+)
+
+func (c *Config) DatabaseConfig() *database.Config {
+	return &c.Database
+}
+
+func (c *Config) BlobstoreConfig() *storage.Config {
+	return &c.Storage
+}
+
+func main() {
+	flag.Parse()
+
+	if *statsDays < 0 {
+		log.Fatal("--stats-days must not be negative.")
+	}
+	if *tolerance < 0 {
+		log.Fatal("--tolerance must not be negative.")
+	}
+
+	ctx := context.Background()
+	// A restore of an older backup may be behind the migrations, which should
+	// not stop the checks.
+	config := Config{Database: database.Config{SchemaCheck: database.SchemaCheckWarn}}
+	env, err := setup.Setup(ctx, &config)
+	if err != nil {
+		log.Fatalf("failed to setup: %v", err)
+	}
+	defer env.Close(ctx)
+
+	db := env.Database()
+
+	var failures []string
+	checks := []struct {
+		name string
+		skip bool
+		run  func() ([]string, error)
+	}{
+		{"referential integrity", false, func() ([]string, error) { return checkIntegrity(ctx, db) }},
+		{"stats", *statsDays == 0, func() ([]string, error) { return checkStats(ctx, db, *statsDays, *tolerance) }},
+		{"export", *skipExport, func() ([]string, error) { return checkExport(ctx, db, env.Blobstore(), *batchID, *tolerance) }},
+	}
+	for _, c := range checks {
+		if c.skip {
+			log.Printf("SKIP %s", c.name)
+			continue
+		}
+		problems, err := c.run()
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		if len(problems) == 0 {
+			log.Printf("PASS %s", c.name)
+			continue
+		}
+		log.Printf("FAIL %s", c.name)
+		for _, p := range problems {
+			log.Printf("  %s", p)
+			failures = append(failures, fmt.Sprintf("%s: %s", c.name, p))
+		}
+	}
+
+	if len(failures) > 0 {
+		log.Printf("%d problems found, the restore is not consistent.", len(failures))
+		os.Exit(1)
+	}
+	log.Printf("All checks passed.")
+}
+
+// checkIntegrity reports foreign keys that are not validated and rows that
+// reference a missing parent. Restores that disable triggers, such as
+// pg_restore --disable-triggers, do not check foreign keys while loading.
+func checkIntegrity(ctx context.Context, db *database.DB) ([]string, error) {
+	type foreignKey struct {
+		name, table, column, parent, parentColumn string
+		validated                                 bool
+	}
+
+	var fks []foreignKey
+	var counts []string
+	if err := db.Read(ctx, func(q database.Querier) error {
+		rows, err := q.Query(ctx, `
+			SELECT
+				c.conname, ct.relname, a.attname, pt.relname, pa.attname, c.convalidated
+			FROM
+				pg_constraint c
+				JOIN pg_class ct ON ct.oid = c.conrelid
+				JOIN pg_class pt ON pt.oid = c.confrelid
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+				JOIN pg_attribute pa ON pa.attrelid = c.confrelid AND pa.attnum = c.confkey[1]
+			WHERE
+				c.contype = 'f' AND c.connamespace = 'public'::regnamespace AND
+				array_length(c.conkey, 1) = 1
+			ORDER BY ct.relname, c.conname
+			`)
+		if err != nil {
+			return fmt.Errorf("listing foreign keys: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var fk foreignKey
+			if err := rows.Scan(&fk.name, &fk.table, &fk.column, &fk.parent, &fk.parentColumn, &fk.validated); err != nil {
+				return fmt.Errorf("scanning foreign key: %w", err)
+			}
+			fks = append(fks, fk)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("listing foreign keys: %w", err)
+		}
+
+		for _, table := range []string{"Exposure", "HealthAuthority", "HealthAuthorityStats", "ExportConfig", "ExportBatch", "ExportFile"} {
+			var n int64
+			if err := q.QueryRow(ctx, `SELECT COUNT(*) FROM `+pgx.Identifier{strings.ToLower(table)}.Sanitize()).Scan(&n); err != nil {
+				return fmt.Errorf("counting %s: %w", table, err)
+			}
+			counts = append(counts, fmt.Sprintf("%s=%d", table, n))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	log.Printf("Row counts: %s", strings.Join(counts, " "))
+
+	var problems []string
+	for _, fk := range fks {
+		if !fk.validated {
+			problems = append(problems, fmt.Sprintf("foreign key %s on %s is not validated", fk.name, fk.table))
+		}
+
+		var orphans int64
+		if err := db.Read(ctx, func(q database.Querier) error {
+			child, col := pgx.Identifier{fk.table}.Sanitize(), pgx.Identifier{fk.column}.Sanitize()
+			parent, parentCol := pgx.Identifier{fk.parent}.Sanitize(), pgx.Identifier{fk.parentColumn}.Sanitize()
+			return q.QueryRow(ctx, fmt.Sprintf(`
+				SELECT COUNT(*) FROM %[1]s c
+				WHERE c.%[2]s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %[3]s p WHERE p.%[4]s = c.%[2]s)
+				`, child, col, parent, parentCol)).Scan(&orphans)
+		}); err != nil {
+			return nil, fmt.Errorf("checking %s: %w", fk.name, err)
+		}
+		if orphans > 0 {
+			problems = append(problems, fmt.Sprintf("%d rows of %s reference a missing %s (%s)", orphans, fk.table, fk.parent, fk.name))
+		}
+	}
+	return problems, nil
+}
+
+// checkStats compares the TEKs recorded in the health authority stats for the
+// last days full UTC days with the keys stored for the same hours. Keys are
+// counted when published and again when revised, as the stats count them.
+// Stats can legitimately drift from the stored keys, for example for keys that
+// were rejected as duplicates, so differences within tolerance are accepted.
+func checkStats(ctx context.Context, db *database.DB, days int, tolerance float64) ([]string, error) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.Add(-time.Duration(days) * 24 * time.Hour)
+
+	type counts struct {
+		recorded, stored int64
+	}
+	byHA := make(map[int64]*counts)
+
+	if err := db.Read(ctx, func(q database.Querier) error {
+		rows, err := q.Query(ctx, `
+			SELECT health_authority_id, SUM(teks), 0 FROM HealthAuthorityStats
+			WHERE hour >= $1 AND hour < $2
+			GROUP BY health_authority_id
+			UNION ALL
+			SELECT health_authority_id, 0, COUNT(*) FROM Exposure
+			WHERE created_at >= $1 AND created_at < $2 AND health_authority_id IS NOT NULL
+			GROUP BY health_authority_id
+			UNION ALL
+			SELECT health_authority_id, 0, COUNT(*) FROM Exposure
+			WHERE revised_at >= $1 AND revised_at < $2 AND health_authority_id IS NOT NULL
+			GROUP BY health_authority_id
+			`, from, to)
+		if err != nil {
+			return fmt.Errorf("counting keys: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id, recorded, stored int64
+			if err := rows.Scan(&id, &recorded, &stored); err != nil {
+				return fmt.Errorf("scanning counts: %w", err)
+			}
+			c, ok := byHA[id]
+			if !ok {
+				c = &counts{}
+				byHA[id] = c
+			}
+			c.recorded += recorded
+			c.stored += stored
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for id, c := range byHA {
+		log.Printf("Health authority %d: %d TEKs recorded, %d stored", id, c.recorded, c.stored)
+		if diff := relativeDiff(c.recorded, c.stored); diff > tolerance {
+			problems = append(problems, fmt.Sprintf("health authority %d recorded %d TEKs but %d are stored (%.1f%% difference)",
+				id, c.recorded, c.stored, 100*diff))
+		}
+	}
+	return problems, nil
+}
+
+func relativeDiff(a, b int64) float64 {
+	if a == b {
+		return 0
+	}
+	return math.Abs(float64(a-b)) / math.Max(float64(a), float64(b))
+}
+
+// checkExport downloads the files of an export batch and checks that the keys
+// the batch selects from the restored database are in them, so the batch could
+// be generated again from the restore. Keys that were cleaned up since the
+// batch was created cannot be checked, so use a recent batch.
+func checkExport(ctx context.Context, db *database.DB, blobstore storage.Blobstore, id int64, tolerance float64) ([]string, error) {
+	if id == 0 {
+		if err := db.Read(ctx, func(q database.Querier) error {
+			return q.QueryRow(ctx, `
+				SELECT batch_id FROM ExportBatch
+				WHERE status = $1 AND EXISTS (SELECT 1 FROM ExportFile f WHERE f.batch_id = ExportBatch.batch_id)
+				ORDER BY end_timestamp DESC, batch_id DESC
+				LIMIT 1
+				`, model.ExportBatchComplete).Scan(&id)
+		}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return []string{"no complete export batch with files to reproduce"}, nil
+			}
+			return nil, fmt.Errorf("finding latest batch: %w", err)
+		}
+	}
+
+	batch, err := exportdatabase.New(db).LookupExportBatch(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("looking up batch %d: %w", id, err)
+	}
+
+	var filenames []string
+	if err := db.Read(ctx, func(q database.Querier) error {
+		rows, err := q.Query(ctx, `SELECT filename FROM ExportFile WHERE batch_id = $1 ORDER BY filename`, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			filenames = append(filenames, name)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, fmt.Errorf("listing files of batch %d: %w", id, err)
+	}
+	log.Printf("Reproducing batch %d (%s to %s) from %d files", id,
+		batch.StartTimestamp.UTC().Format(time.RFC3339), batch.EndTimestamp.UTC().Format(time.RFC3339), len(filenames))
+
+	var problems []string
+	exported := make(map[string]struct{})
+	for _, name := range filenames {
+		if strings.HasSuffix(name, ".enc") {
+			// Encrypted copies hold the same keys as their plaintext file.
+			continue
+		}
+		data, err := blobstore.GetObject(ctx, batch.BucketName, name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("reading %s: %v", name, err))
+			continue
+		}
+		file, _, err := export.UnmarshalExportFile(data)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("parsing %s: %v", name, err))
+			continue
+		}
+		for _, k := range append(file.GetKeys(), file.GetRevisedKeys()...) {
+			exported[string(k.GetKeyData())] = struct{}{}
+		}
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	// Select the stored keys the way the export worker does. Travel rules,
+	// quarantine, and key caps are not applied, so a small number of selected
+	// keys may legitimately be missing from the files.
+	criteria := publishdatabase.IterateExposuresCriteria{
+		SinceTimestamp:       batch.StartTimestamp,
+		UntilTimestamp:       batch.EndTimestamp,
+		IncludeRegions:       batch.EffectiveInputRegions(),
+		IncludeTravelers:     batch.IncludeTravelers,
+		OnlyNonTravelers:     batch.OnlyNonTravelers,
+		ExcludeRegions:       batch.ExcludeRegions,
+		IncludeJurisdictions: batch.IncludeJurisdictions,
+		ExcludeJurisdictions: batch.ExcludeJurisdictions,
+		TravelerConsent:      publishdatabase.ConsentNotRefused,
+	}
+	var selected, missing int64
+	publishDB := publishdatabase.New(db)
+	for _, onlyRevised := range []bool{false, true} {
+		criteria.OnlyRevisedKeys = onlyRevised
+		if _, err := publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
+			if len(exp.ExposureKey) != verifyapi.KeyLength {
+				return nil
+			}
+			selected++
+			if _, ok := exported[string(exp.ExposureKey)]; !ok {
+				missing++
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("selecting keys of batch %d: %w", id, err)
+		}
+	}
+
+	// Exports are padded with generated keys, so the files can hold more keys
+	// than are selected.
+	log.Printf("Batch %d: %d keys exported, %d stored keys selected, %d of them missing from the files",
+		id, len(exported), selected, missing)
+	if selected > 0 && float64(missing)/float64(selected) > tolerance {
+		problems = append(problems, fmt.Sprintf("%d of %d stored keys of batch %d are missing from its files", missing, selected, id))
+	}
+	return problems, nil
+}